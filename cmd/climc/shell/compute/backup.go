@@ -38,6 +38,7 @@ func init() {
 	dbCmd.Create(&compute.DiskBackupCreateOptions{})
 	dbCmd.Perform("recovery", &compute.DiskBackupRecoveryOptions{})
 	dbCmd.Perform("syncstatus", &compute.DiskBackupSyncstatusOptions{})
+	dbCmd.Perform("verify", &compute.DiskBackupVerifyOptions{})
 
 	ibCmd := shell.NewResourceCmd(&modules.InstanceBackups)
 	ibCmd.List(&compute.InstanceBackupListOptions{})
@@ -15,6 +15,8 @@
 package compute
 
 import (
+	"yunion.io/x/jsonutils"
+
 	"yunion.io/x/onecloud/pkg/mcclient"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
@@ -73,4 +75,57 @@ func init() {
 		printBatchResults(results, modules.Cachedimages.GetColumns(s))
 		return nil
 	})
+
+	type CachedImageShareOptions struct {
+		ID                 string   `help:"ID or Name of the cached image to share"`
+		STORAGECACHE       string   `help:"Storagecache where the customized image is cached" json:"storagecache_id"`
+		TargetCloudaccount []string `help:"Target cloudaccount of the same provider" json:"target_cloudaccount_ids"`
+		TargetAccountId    []string `help:"Target account id on cloud provider, e.g. AWS account id" json:"target_account_ids"`
+	}
+	R(&CachedImageShareOptions{}, "cached-image-share", "Share customized image to other cloud accounts", func(s *mcclient.ClientSession, args *CachedImageShareOptions) error {
+		params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+		params.Remove("id")
+		result, err := modules.Cachedimages.PerformAction(s, args.ID, "share", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type CachedImageUnshareOptions struct {
+		ID              string   `help:"ID or Name of the cached image to unshare"`
+		STORAGECACHE    string   `help:"Storagecache where the customized image is cached" json:"storagecache_id"`
+		TargetAccountId []string `help:"Target account id on cloud provider, unshare all if not set" json:"target_account_ids"`
+	}
+	R(&CachedImageUnshareOptions{}, "cached-image-unshare", "Unshare customized image from cloud accounts", func(s *mcclient.ClientSession, args *CachedImageUnshareOptions) error {
+		params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+		params.Remove("id")
+		result, err := modules.Cachedimages.PerformAction(s, args.ID, "unshare", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type CachedImageShareListOptions struct {
+		options.BaseListOptions
+		Cachedimage        string   `help:"filter by cached image" json:"cachedimage_id"`
+		Storagecache       string   `help:"filter by storagecache" json:"storagecache_id"`
+		TargetAccountId    []string `help:"filter by target account id"`
+		TargetCloudaccount string   `help:"filter by target cloudaccount" json:"target_cloudaccount_id"`
+	}
+	R(&CachedImageShareListOptions{}, "cached-image-share-list", "List where customized images are shared", func(s *mcclient.ClientSession, args *CachedImageShareListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.CachedimageShares.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.CachedimageShares.GetColumns(s))
+		return nil
+	})
 }
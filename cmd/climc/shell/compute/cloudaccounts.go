@@ -129,6 +129,7 @@ func init() {
 	cmd.Perform("change-project", &options.ClouaccountChangeProjectOptions{})
 	cmd.Perform("create-subscription", &options.SubscriptionCreateOptions{})
 	cmd.Perform("project-mapping", &options.ClouaccountProjectMappingOptions{})
+	cmd.Perform("diagnose", &options.CloudaccountDiagnoseOptions{})
 
 	cmd.Get("change-owner-candidate-domains", &options.SCloudAccountIdOptions{})
 	cmd.Get("enrollment-accounts", &options.SCloudAccountIdOptions{})
@@ -29,6 +29,9 @@ func init() {
 		Region string `help:"ID or Name of Host"`
 
 		Capability []string `help:"capability filter" choices:"project|compute|network|loadbalancer|objectstore|rds|cache|event"`
+
+		SyncStatus []string `help:"sync status filter" choices:"idle|queued|queuing|syncing"`
+		SyncFailed *bool    `help:"filter regions whose last synchronization failed" negative:"sync_succeeded"`
 	}
 	R(&CloudproviderRegionListOptions{}, "cloud-provider-region-list", "List cloudprovider region synchronization status", func(s *mcclient.ClientSession, args *CloudproviderRegionListOptions) error {
 		var params *jsonutils.JSONDict
@@ -39,6 +42,12 @@ func init() {
 				return err
 
 			}
+			if len(args.SyncStatus) > 0 {
+				params.Add(jsonutils.NewStringArray(args.SyncStatus), "sync_status")
+			}
+			if args.SyncFailed != nil {
+				params.Add(jsonutils.NewBool(*args.SyncFailed), "sync_failed")
+			}
 		}
 		var result *modulebase.ListResult
 		var err error
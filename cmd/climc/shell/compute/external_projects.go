@@ -26,5 +26,7 @@ func init() {
 	cmd.List(&opts.ExternalProjectListOptions{})
 	cmd.Create(&opts.ExternalProjectCreateOptions{})
 	cmd.Show(&options.BaseIdOptions{})
+	cmd.Update(&opts.ExternalProjectUpdateOptions{})
 	cmd.Perform("change-project", &opts.ExterProjectChagneProjectOptions{})
+	cmd.Perform("migrate-resources", &opts.ExternalProjectMigrateResourcesOptions{})
 }
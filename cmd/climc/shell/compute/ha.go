@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+)
+
+func init() {
+	type HaStatusOptions struct {
+	}
+	R(&HaStatusOptions{}, "ha-status", "Show active-standby status of region service", func(s *mcclient.ClientSession, args *HaStatusOptions) error {
+		result, err := modules.Ha.GetStatus(s)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type HaFailoverOptions struct {
+	}
+	R(&HaFailoverOptions{}, "ha-failover", "Let the active region service resign and hand over to standby", func(s *mcclient.ClientSession, args *HaFailoverOptions) error {
+		result, err := modules.Ha.Failover(s)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
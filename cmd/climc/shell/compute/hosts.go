@@ -50,6 +50,8 @@ func init() {
 	cmd.BatchDelete(&options.BaseIdsOptions{})
 	cmd.Perform("remove-all-netifs", &options.BaseIdOptions{})
 	cmd.Perform("probe-isolated-devices", &options.BaseIdOptions{})
+	cmd.Perform("adopt-unmanaged-guests", &compute.HostAdoptUnmanagedGuestsOptions{})
+	cmd.Perform("libvirt-xml-to-desc", &compute.HostLibvirtXmlToDescOptions{})
 	cmd.Perform("class-metadata", &options.ResourceMetadataOptions{})
 	cmd.Perform("set-class-metadata", &options.ResourceMetadataOptions{})
 
@@ -67,6 +69,9 @@ func init() {
 	cmd.Get("vnc", &options.BaseIdOptions{})
 	cmd.Get("app-options", &options.BaseIdOptions{})
 	cmd.Get("tap-config", &options.BaseIdOptions{})
+	cmd.Get("unmanaged-guests", &options.BaseIdOptions{})
+	cmd.Get("network-probes", &options.BaseIdOptions{})
+	cmd.Get("network-probe-targets", &options.BaseIdOptions{})
 
 	R(&options.BaseIdOptions{}, "host-logininfo", "Get SSH login information of a host", func(s *mcclient.ClientSession, args *options.BaseIdOptions) error {
 		srvid, e := modules.Hosts.GetId(s, args.ID, nil)
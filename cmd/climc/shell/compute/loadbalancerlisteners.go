@@ -122,6 +122,18 @@ func init() {
 		printObject(lblistener)
 		return nil
 	})
+	R(&options.LoadbalancerListenerSetAccessLogOptions{}, "lblistener-set-access-log", "Enable or disable lblistener access log delivery", func(s *mcclient.ClientSession, opts *options.LoadbalancerListenerSetAccessLogOptions) error {
+		params, err := opts.Params()
+		if err != nil {
+			return err
+		}
+		lblistener, err := modules.LoadbalancerListeners.PerformAction(s, opts.ID, "set-access-log", params)
+		if err != nil {
+			return err
+		}
+		printObject(lblistener)
+		return nil
+	})
 	R(&options.LoadbalancerListenerActionSyncStatusOptions{}, "lblistener-syncstatus", "Sync lblistener status", func(s *mcclient.ClientSession, opts *options.LoadbalancerListenerActionSyncStatusOptions) error {
 		lblistener, err := modules.LoadbalancerListeners.PerformAction(s, opts.ID, "syncstatus", nil)
 		if err != nil {
@@ -0,0 +1,257 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+// 解析形如 "resize_disk grow_percent=20 max_size_mb=102400" 的动作
+func parseRunbookSteps(steps []string) (api.RunbookSteps, error) {
+	ret := api.RunbookSteps{}
+	for _, s := range steps {
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		step := api.RunbookStep{Action: fields[0], Params: map[string]string{}}
+		for _, kv := range fields[1:] {
+			idx := strings.Index(kv, "=")
+			if idx <= 0 {
+				return nil, fmt.Errorf("invalid step param %q, should be key=value", kv)
+			}
+			step.Params[kv[:idx]] = kv[idx+1:]
+		}
+		ret = append(ret, step)
+	}
+	return ret, nil
+}
+
+func init() {
+	type RunbookListOptions struct {
+		options.BaseListOptions
+	}
+	R(&RunbookListOptions{}, "runbook-list", "List runbooks", func(s *mcclient.ClientSession, args *RunbookListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.Runbooks.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.Runbooks.GetColumns(s))
+		return nil
+	})
+
+	type RunbookCreateOptions struct {
+		NAME            string
+		Step            []string `help:"action with params, e.g. 'resize_disk grow_percent=20 max_size_mb=102400', param value supports ${guest_id} ${alert_id} placeholders" json:"-"`
+		RequireApproval *bool    `help:"execution requires manual approval"`
+		CooldownMinutes *int     `help:"minimal interval in minutes between executions on the same guest"`
+		Desc            string   `help:"description"`
+	}
+	R(&RunbookCreateOptions{}, "runbook-create", "Create runbook", func(s *mcclient.ClientSession, args *RunbookCreateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		steps, err := parseRunbookSteps(args.Step)
+		if err != nil {
+			return err
+		}
+		params.Set("steps", jsonutils.Marshal(steps))
+		result, err := modules.Runbooks.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type RunbookUpdateOptions struct {
+		ID              string   `help:"ID or name of runbook"`
+		Step            []string `help:"replace steps, same format as runbook-create" json:"-"`
+		RequireApproval *bool    `help:"execution requires manual approval" negative:"no-require-approval"`
+		CooldownMinutes *int     `help:"minimal interval in minutes between executions on the same guest"`
+		Desc            string   `help:"description"`
+	}
+	R(&RunbookUpdateOptions{}, "runbook-update", "Update runbook", func(s *mcclient.ClientSession, args *RunbookUpdateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		if len(args.Step) > 0 {
+			steps, err := parseRunbookSteps(args.Step)
+			if err != nil {
+				return err
+			}
+			params.Set("steps", jsonutils.Marshal(steps))
+		}
+		result, err := modules.Runbooks.Update(s, args.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type RunbookIdOptions struct {
+		ID string `help:"ID or name of runbook"`
+	}
+	R(&RunbookIdOptions{}, "runbook-show", "Show runbook", func(s *mcclient.ClientSession, args *RunbookIdOptions) error {
+		result, err := modules.Runbooks.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&RunbookIdOptions{}, "runbook-delete", "Delete runbook", func(s *mcclient.ClientSession, args *RunbookIdOptions) error {
+		result, err := modules.Runbooks.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&RunbookIdOptions{}, "runbook-enable", "Enable runbook", func(s *mcclient.ClientSession, args *RunbookIdOptions) error {
+		result, err := modules.Runbooks.PerformAction(s, args.ID, "enable", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&RunbookIdOptions{}, "runbook-disable", "Disable runbook", func(s *mcclient.ClientSession, args *RunbookIdOptions) error {
+		result, err := modules.Runbooks.PerformAction(s, args.ID, "disable", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type RunbookTriggerOptions struct {
+		ID      string   `help:"ID or name of runbook"`
+		GUEST   string   `help:"ID or name of guest" json:"guest_id"`
+		AlertId string   `help:"ID of alert which triggers the runbook"`
+		Param   []string `help:"execution params, e.g. owner=user1" json:"-"`
+	}
+	R(&RunbookTriggerOptions{}, "runbook-trigger", "Trigger runbook manually", func(s *mcclient.ClientSession, args *RunbookTriggerOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		if len(args.Param) > 0 {
+			kvs := map[string]string{}
+			for _, kv := range args.Param {
+				idx := strings.Index(kv, "=")
+				if idx <= 0 {
+					return fmt.Errorf("invalid param %q, should be key=value", kv)
+				}
+				kvs[kv[:idx]] = kv[idx+1:]
+			}
+			params.Set("params", jsonutils.Marshal(kvs))
+		}
+		result, err := modules.Runbooks.PerformAction(s, args.ID, "trigger", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type RunbookExecutionListOptions struct {
+		options.BaseListOptions
+
+		RunbookId string `help:"filter by runbook"`
+		GuestId   string `help:"filter by guest"`
+		AlertId   string `help:"filter by alert"`
+	}
+	R(&RunbookExecutionListOptions{}, "runbook-execution-list", "List runbook executions", func(s *mcclient.ClientSession, args *RunbookExecutionListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.RunbookExecutions.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.RunbookExecutions.GetColumns(s))
+		return nil
+	})
+
+	type RunbookExecutionIdOptions struct {
+		ID string `help:"ID of runbook execution"`
+	}
+	R(&RunbookExecutionIdOptions{}, "runbook-execution-show", "Show runbook execution", func(s *mcclient.ClientSession, args *RunbookExecutionIdOptions) error {
+		result, err := modules.RunbookExecutions.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&RunbookExecutionIdOptions{}, "runbook-execution-delete", "Delete runbook execution", func(s *mcclient.ClientSession, args *RunbookExecutionIdOptions) error {
+		result, err := modules.RunbookExecutions.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type RunbookExecutionApproveOptions struct {
+		ID     string `help:"ID of runbook execution"`
+		Reason string `help:"approval comment"`
+	}
+	R(&RunbookExecutionApproveOptions{}, "runbook-execution-approve", "Approve pending runbook execution", func(s *mcclient.ClientSession, args *RunbookExecutionApproveOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		result, err := modules.RunbookExecutions.PerformAction(s, args.ID, "approve", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&RunbookExecutionApproveOptions{}, "runbook-execution-reject", "Reject pending runbook execution", func(s *mcclient.ClientSession, args *RunbookExecutionApproveOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		result, err := modules.RunbookExecutions.PerformAction(s, args.ID, "reject", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
@@ -37,4 +37,6 @@ func init() {
 	cmd.Perform("change-owner", &options.SecgroupChangeOwnerOptions{})
 	cmd.Perform("import-rules", &options.SecgroupImportRulesOptions{})
 	cmd.Get("references", &options.SecgroupIdOptions{})
+	cmd.Get("normalized-rules", &options.SecgroupNormalizedRulesOptions{})
+	cmd.GetProperty(&options.SecgroupIsolationAuditOptions{})
 }
@@ -78,8 +78,8 @@ func init() {
 		SERVER string `help:"ID or Name of server"`
 		DISK   string `help:"ID or Name of Disk"`
 		Driver string `help:"Driver of vDisk" choices:"virtio|ide|scsi|pvscsi"`
-		Cache  string `help:"Cache mode of vDisk" choices:"writethrough|none|writeback|directsync"`
-		Aio    string `help:"Asynchronous IO mode of vDisk" choices:"native|threads"`
+		Cache  string `help:"Cache mode of vDisk, take effect after restart" choices:"writethrough|none|writeback|directsync|unsafe"`
+		Aio    string `help:"Asynchronous IO mode of vDisk, take effect after restart" choices:"native|threads|io_uring"`
 		Index  int64  `help:"Index of vDisk" default:"-1"`
 	}
 	R(&ServerDiskUpdateOptions{}, "server-disk-update", "Update details of a virtual disk of a virtual server", func(s *mcclient.ClientSession, args *ServerDiskUpdateOptions) error {
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -67,6 +68,7 @@ func init() {
 	cmd.PrintObjectYAML().Perform("migrate-forecast", new(options.ServerMigrateForecastOptions))
 	cmd.Perform("migrate", new(options.ServerMigrateOptions))
 	cmd.Perform("live-migrate", new(options.ServerLiveMigrateOptions))
+	cmd.Perform("cold-migrate-with-devices", new(options.ServerColdMigrateWithDevicesOptions))
 	cmd.BatchPerform("cancel-live-migrate", new(options.ServerIdsOptions))
 	cmd.Perform("set-live-migrate-params", new(options.ServerSetLiveMigrateParamsOptions))
 	cmd.Perform("modify-src-check", new(options.ServerModifySrcCheckOptions))
@@ -79,6 +81,7 @@ func init() {
 	cmd.Perform("save-image", new(options.ServerSaveImageOptions))
 	cmd.Perform("save-guest-image", new(options.ServerSaveGuestImageOptions))
 	cmd.Perform("change-owner", new(options.ServerChangeOwnerOptions))
+	cmd.Perform("transfer-ownership", new(options.ServerTransferOwnershipOptions))
 	cmd.Perform("rebuild-root", new(options.ServerRebuildRootOptions))
 	cmd.Perform("change-config", new(options.ServerChangeConfigOptions))
 	cmd.Perform("ejectiso", new(options.ServerIdOptions))
@@ -100,6 +103,7 @@ func init() {
 	cmd.Perform("change-disk-storage", &options.ServerChangeDiskStorageOptions{})
 	cmd.PerformClass("batch-user-metadata", &options.ServerBatchMetadataOptions{})
 	cmd.PerformClass("batch-set-user-metadata", &options.ServerBatchMetadataOptions{})
+	cmd.PerformClass("batch-rotate-password", &options.ServerBatchRotatePasswordOptions{})
 	cmd.Perform("user-metadata", &baseoptions.ResourceMetadataOptions{})
 	cmd.Perform("set-user-metadata", &baseoptions.ResourceMetadataOptions{})
 	cmd.Perform("probe-isolated-devices", &options.ServerIdOptions{})
@@ -111,8 +115,42 @@ func init() {
 	cmd.BatchPerform("enable-memclean", new(options.ServerIdsOptions))
 	cmd.Perform("qga-set-password", &options.ServerQgaSetPassword{})
 	cmd.Perform("qga-command", &options.ServerQgaCommand{})
+	cmd.Perform("qga-exec", &options.ServerQgaExecOptions{})
+	cmd.Perform("qga-file-write", &options.ServerQgaFileWriteOptions{})
+	cmd.Perform("qga-fsfreeze", &options.ServerQgaFsfreezeOptions{})
+	cmd.Get("qga-network-interfaces", &options.ServerIdOptions{})
+	cmd.Get("confidential-vm-attestation", &options.ServerIdOptions{})
+	cmd.Perform("set-qga-firewall", &options.ServerSetQgaFirewallOptions{})
+	cmd.Perform("qga-sync-firewall", &options.ServerIdOptions{})
+	cmd.Get("monitor-agent", &options.ServerIdOptions{})
+	cmd.Perform("monitor-agent-heartbeat", &options.ServerMonitorAgentHeartbeatOptions{})
+	cmd.Get("deploy-verify", &options.ServerIdOptions{})
+	cmd.Perform("deploy-verify", &options.ServerIdOptions{})
+	cmd.Get("snapshot-tree", &options.ServerIdOptions{})
+	cmd.Perform("snapshot-gc", &options.ServerIdOptions{})
 	cmd.Perform("set-password", &options.ServerSetPasswordOptions{})
 	cmd.Perform("set-boot-index", &options.ServerSetBootIndexOptions{})
+	cmd.Perform("attach-shared-dir", &options.ServerAttachSharedDirOptions{})
+	cmd.Perform("detach-shared-dir", &options.ServerDetachSharedDirOptions{})
+	cmd.Perform("set-tpm", &options.ServerSetTpmOptions{})
+	cmd.Perform("set-watchdog", &options.ServerSetWatchdogOptions{})
+	cmd.Perform("set-sriov-failover", &options.ServerSetSriovFailoverOptions{})
+	cmd.Perform("set-nic-bandwidth", &options.ServerSetNicBandwidthOptions{})
+	cmd.Perform("set-hugepage", &options.ServerSetHugepageOptions{})
+	cmd.Perform("set-balloon", &options.ServerSetBalloonOptions{})
+	cmd.Perform("set-virtio-mem", &options.ServerSetVirtioMemOptions{})
+	cmd.Perform("set-secure-boot", &options.ServerSetSecureBootOptions{})
+	cmd.Perform("set-boot-order", &options.ServerSetBootOrderOptions{})
+	cmd.Perform("boot-once", &options.ServerBootOnceOptions{})
+	cmd.Perform("pxe-boot", &options.ServerPxeBootOptions{})
+	cmd.Perform("set-confidential-vm", &options.ServerSetConfidentialVmOptions{})
+	cmd.Perform("set-clock-policy", &options.ServerSetClockPolicyOptions{})
+	cmd.Perform("upgrade-machine-type", &options.ServerUpgradeMachineTypeOptions{})
+	cmd.Perform("set-vdi-options", &options.ServerSetVdiOptionsOptions{})
+	cmd.Perform("set-iothread-policy", &options.ServerSetIothreadPolicyOptions{})
+	cmd.Perform("set-cpu-pin-policy", &options.ServerSetCpuPinPolicyOptions{})
+	cmd.Perform("set-numa-policy", &options.ServerSetNumaPolicyOptions{})
+	cmd.Perform("resize-memory", &options.ServerResizeMemoryOptions{})
 
 	cmd.Get("vnc", new(options.ServerVncOptions))
 	cmd.Get("desc", new(options.ServerIdOptions))
@@ -615,6 +653,41 @@ func init() {
 		return nil
 	})
 
+	R(&options.ServerQgaFileReadOptions{}, "server-qga-file-read", "Read file in guest via qemu guest agent", func(s *mcclient.ClientSession, opts *options.ServerQgaFileReadOptions) error {
+		params, _ := opts.Params()
+		result, err := modules.Servers.PerformAction(s, opts.ID, "qga-file-read", params)
+		if err != nil {
+			return err
+		}
+		output := compute.ServerQgaFileReadOutput{}
+		result.Unmarshal(&output)
+		content, err := base64.StdEncoding.DecodeString(output.Content)
+		if err != nil {
+			return err
+		}
+		if len(opts.Output) > 0 {
+			if err := ioutil.WriteFile(opts.Output, content, 0644); err != nil {
+				return err
+			}
+		} else {
+			os.Stdout.Write(content)
+		}
+		if output.Truncated {
+			fmt.Fprintf(os.Stderr, "content truncated at %d bytes\n", output.Size)
+		}
+		return nil
+	})
+
+	R(&options.ServerIdOptions{}, "server-libvirt-xml", "Export libvirt domain xml of a kvm server", func(s *mcclient.ClientSession, opts *options.ServerIdOptions) error {
+		result, err := modules.Servers.GetSpecific(s, opts.ID, "libvirt-xml", nil)
+		if err != nil {
+			return err
+		}
+		xml, _ := result.GetString("xml")
+		fmt.Println(xml)
+		return nil
+	})
+
 	R(&options.ServerIdOptions{}, "server-remote-nics", "Show remote nics of a server", func(s *mcclient.ClientSession, opts *options.ServerIdOptions) error {
 		result, err := modules.Servers.GetSpecific(s, opts.ID, "remote-nics", nil)
 		if err != nil {
@@ -15,6 +15,9 @@
 package compute
 
 import (
+	"fmt"
+	"os"
+
 	"yunion.io/x/onecloud/cmd/climc/shell"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
@@ -32,6 +35,24 @@ func init() {
 	cmd.Update(&options.ServerSkusUpdateOptions{})
 	cmd.ClassShow(&options.ServerSkusListOptions{})
 	cmd.PerformClass("sync-skus", &options.SkuSyncOptions{})
+	cmd.Get("spot-prices", &options.ServerSkuSpotPricesOptions{})
+	cmd.PerformClass("import-catalog", &options.ServerSkuImportCatalogOptions{})
+
+	R(&options.ServerSkuExportCatalogOptions{}, "server-sku-export-catalog", "Export server skus catalog for offline import", func(s *mcclient.ClientSession, args *options.ServerSkuExportCatalogOptions) error {
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		result, err := modules.ServerSkus.Get(s, "export-catalog", params)
+		if err != nil {
+			return err
+		}
+		if len(args.Output) > 0 {
+			return os.WriteFile(args.Output, []byte(result.PrettyString()), 0644)
+		}
+		fmt.Println(result.PrettyString())
+		return nil
+	})
 
 	R(&options.SkuTaskQueryOptions{}, "server-sku-sync-task-show", "Show details of skus sync tasks", func(s *mcclient.ClientSession, args *options.SkuTaskQueryOptions) error {
 		params, err := args.Params()
@@ -0,0 +1,189 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type SnapshotArchivePolicyListOptions struct {
+		options.BaseListOptions
+
+		VaultCloudaccountId string `help:"filter by vault cloudaccount"`
+		DiskId              string `help:"filter by bound disk"`
+	}
+	R(&SnapshotArchivePolicyListOptions{}, "snapshot-archive-policy-list", "List snapshot archive policies", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.SnapshotArchivePolicies.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.SnapshotArchivePolicies.GetColumns(s))
+		return nil
+	})
+
+	type SnapshotArchivePolicyCreateOptions struct {
+		NAME               string
+		VAULT_CLOUDACCOUNT string `help:"vault cloudaccount which snapshot copies are archived to" json:"vault_cloudaccount_id"`
+		RetentionDays      int    `help:"compliance retention days, archives can not be deleted before expired"`
+		Desc               string `help:"description"`
+	}
+	R(&SnapshotArchivePolicyCreateOptions{}, "snapshot-archive-policy-create", "Create snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyCreateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.SnapshotArchivePolicies.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type SnapshotArchivePolicyUpdateOptions struct {
+		ID            string `help:"ID or name of snapshot archive policy"`
+		RetentionDays *int   `help:"compliance retention days, can only be extended"`
+		Desc          string `help:"description"`
+	}
+	R(&SnapshotArchivePolicyUpdateOptions{}, "snapshot-archive-policy-update", "Update snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyUpdateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		result, err := modules.SnapshotArchivePolicies.Update(s, args.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type SnapshotArchivePolicyIdOptions struct {
+		ID string `help:"ID or name of snapshot archive policy"`
+	}
+	R(&SnapshotArchivePolicyIdOptions{}, "snapshot-archive-policy-show", "Show snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyIdOptions) error {
+		result, err := modules.SnapshotArchivePolicies.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&SnapshotArchivePolicyIdOptions{}, "snapshot-archive-policy-delete", "Delete snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyIdOptions) error {
+		result, err := modules.SnapshotArchivePolicies.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type SnapshotArchivePolicyDisksOptions struct {
+		ID   string   `help:"ID or name of snapshot archive policy"`
+		Disk []string `help:"ID or name of disks" json:"disks"`
+	}
+	R(&SnapshotArchivePolicyDisksOptions{}, "snapshot-archive-policy-bind-disk", "Bind disks to snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyDisksOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.SnapshotArchivePolicies.PerformAction(s, args.ID, "bind-disks", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&SnapshotArchivePolicyDisksOptions{}, "snapshot-archive-policy-unbind-disk", "Unbind disks from snapshot archive policy", func(s *mcclient.ClientSession, args *SnapshotArchivePolicyDisksOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.SnapshotArchivePolicies.PerformAction(s, args.ID, "unbind-disks", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type SnapshotArchiveListOptions struct {
+		options.BaseListOptions
+
+		PolicyId            string `help:"filter by snapshot archive policy"`
+		DiskId              string `help:"filter by source disk"`
+		SnapshotId          string `help:"filter by source snapshot"`
+		VaultCloudaccountId string `help:"filter by vault cloudaccount"`
+		Retained            *bool  `help:"filter archives still in retention period" negative:"no-retained"`
+	}
+	R(&SnapshotArchiveListOptions{}, "snapshot-archive-list", "List snapshot archives", func(s *mcclient.ClientSession, args *SnapshotArchiveListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		result, err := modules.SnapshotArchives.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.SnapshotArchives.GetColumns(s))
+		return nil
+	})
+
+	type SnapshotArchiveIdOptions struct {
+		ID string `help:"ID of snapshot archive"`
+	}
+	R(&SnapshotArchiveIdOptions{}, "snapshot-archive-show", "Show snapshot archive", func(s *mcclient.ClientSession, args *SnapshotArchiveIdOptions) error {
+		result, err := modules.SnapshotArchives.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+	R(&SnapshotArchiveIdOptions{}, "snapshot-archive-delete", "Delete expired snapshot archive", func(s *mcclient.ClientSession, args *SnapshotArchiveIdOptions) error {
+		result, err := modules.SnapshotArchives.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type SnapshotArchiveOptions struct {
+		ID     string `help:"ID or name of snapshot"`
+		POLICY string `help:"ID or name of snapshot archive policy" json:"policy_id"`
+	}
+	R(&SnapshotArchiveOptions{}, "snapshot-archive", "Archive snapshot to vault cloudaccount", func(s *mcclient.ClientSession, args *SnapshotArchiveOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		result, err := modules.Snapshots.PerformAction(s, args.ID, "archive", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+}
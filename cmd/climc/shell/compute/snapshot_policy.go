@@ -57,6 +57,11 @@ func init() {
 		RetentionDays  int   `help:"snapshot retention days"`
 		RepeatWeekdays []int `help:"snapshot create days on week"`
 		TimePoints     []int `help:"snapshot create time points on one day"`
+
+		Gfs              bool `help:"enable GFS rotation, default keep 7 daily, 4 weekly and 12 monthly snapshots"`
+		RetentionDaily   int  `help:"GFS rotation daily snapshot count"`
+		RetentionWeekly  int  `help:"GFS rotation weekly snapshot count"`
+		RetentionMonthly int  `help:"GFS rotation monthly snapshot count"`
 	}
 
 	R(&SnapshotPolicyCreateOptions{}, "snapshot-policy-create", "Create snapshot policy", func(s *mcclient.ClientSession, args *SnapshotPolicyCreateOptions) error {
@@ -69,6 +74,28 @@ func init() {
 		return nil
 	})
 
+	type SnapshotPolicyUpdateOptions struct {
+		ID string `help:"ID or name of snapshot policy"`
+
+		RetentionDaily   *int `help:"GFS rotation daily snapshot count"`
+		RetentionWeekly  *int `help:"GFS rotation weekly snapshot count"`
+		RetentionMonthly *int `help:"GFS rotation monthly snapshot count"`
+	}
+
+	R(&SnapshotPolicyUpdateOptions{}, "snapshot-policy-update", "Update snapshot policy", func(s *mcclient.ClientSession, args *SnapshotPolicyUpdateOptions) error {
+		params, err := options.StructToParams(args)
+		if err != nil {
+			return err
+		}
+		params.Remove("id")
+		sp, err := modules.SnapshotPoliciy.Update(s, args.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(sp)
+		return nil
+	})
+
 	type SnapshotPolicyBindDisksOptions struct {
 		ID   string   `help:"ID"`
 		Disk []string `help:"ids of disk"`
@@ -103,4 +103,23 @@ func init() {
 		printObject(storage)
 		return nil
 	})
+
+	type StorageCacheVerifyImageOptions struct {
+		ID     string `help:"ID or name of storage cache"`
+		IMAGE  string `help:"ID or name of cached image"`
+		Repair bool   `help:"Re-fetch image from image service if cache is corrupted"`
+	}
+	R(&StorageCacheVerifyImageOptions{}, "storagecache-verify-image", "Verify checksum of a cached image", func(s *mcclient.ClientSession, args *StorageCacheVerifyImageOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.IMAGE), "image_id")
+		if args.Repair {
+			params.Add(jsonutils.JSONTrue, "repair")
+		}
+		storage, err := modules.Storagecaches.PerformAction(s, args.ID, "verify-image", params)
+		if err != nil {
+			return err
+		}
+		printObject(storage)
+		return nil
+	})
 }
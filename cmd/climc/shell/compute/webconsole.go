@@ -92,7 +92,11 @@ func init() {
 	})
 
 	R(&o.WebConsoleBaremetalOptions{}, "webconsole-baremetal", "Connect baremetal host webconsole", func(s *mcclient.ClientSession, args *o.WebConsoleBaremetalOptions) error {
-		ret, err := webconsole.WebConsole.DoBaremetalConnect(s, args.ID, nil)
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := webconsole.WebConsole.DoBaremetalConnect(s, args.ID, params)
 		if err != nil {
 			return err
 		}
@@ -114,7 +118,11 @@ func init() {
 	})
 
 	R(&o.WebConsoleServerOptions{}, "webconsole-server", "Connect server remote graphic console", func(s *mcclient.ClientSession, args *o.WebConsoleServerOptions) error {
-		ret, err := webconsole.WebConsole.DoServerConnect(s, args.ID, nil)
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := webconsole.WebConsole.DoServerConnect(s, args.ID, params)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type ImageChannelListOptions struct {
+		options.BaseListOptions
+
+		ImageId string `help:"channels image promoted to"`
+	}
+	R(&ImageChannelListOptions{}, "image-channel-list", "List image channels", func(s *mcclient.ClientSession, args *ImageChannelListOptions) error {
+		params, err := options.ListStructToParams(args)
+		if err != nil {
+			return err
+		}
+		ret, err := modules.ImageChannels.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(ret, modules.ImageChannels.GetColumns(s))
+		return nil
+	})
+
+	type ImageChannelCreateOptions struct {
+		NAME          string   `help:"name of image channel, e.g. ubuntu22"`
+		Stage         []string `help:"stages in promotion order, default dev,staging,prod" json:"stages"`
+		ApprovalStage []string `help:"stages require approval" json:"approval_stages"`
+		Desc          string   `help:"description" json:"description"`
+	}
+	R(&ImageChannelCreateOptions{}, "image-channel-create", "Create image channel", func(s *mcclient.ClientSession, args *ImageChannelCreateOptions) error {
+		params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+		params.Set("name", jsonutils.NewString(args.NAME))
+		ret, err := modules.ImageChannels.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelIdOptions struct {
+		ID string `help:"id or name of image channel"`
+	}
+	R(&ImageChannelIdOptions{}, "image-channel-show", "Show image channel", func(s *mcclient.ClientSession, args *ImageChannelIdOptions) error {
+		ret, err := modules.ImageChannels.Get(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	R(&ImageChannelIdOptions{}, "image-channel-delete", "Delete image channel", func(s *mcclient.ClientSession, args *ImageChannelIdOptions) error {
+		ret, err := modules.ImageChannels.Delete(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelUpdateOptions struct {
+		ID            string   `help:"id or name of image channel" json:"-"`
+		ApprovalStage []string `help:"stages require approval" json:"approval_stages"`
+	}
+	R(&ImageChannelUpdateOptions{}, "image-channel-update", "Update approval stages of image channel", func(s *mcclient.ClientSession, args *ImageChannelUpdateOptions) error {
+		params := jsonutils.NewDict()
+		params.Set("approval_stages", jsonutils.NewStringArray(args.ApprovalStage))
+		ret, err := modules.ImageChannels.Update(s, args.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelPromoteOptions struct {
+		ID     string `help:"id or name of image channel" json:"-"`
+		IMAGE  string `help:"id or name of image" json:"image_id"`
+		STAGE  string `help:"target stage" json:"stage"`
+		Reason string `help:"reason of promotion" json:"reason"`
+	}
+	R(&ImageChannelPromoteOptions{}, "image-channel-promote", "Promote image to stage of channel", func(s *mcclient.ClientSession, args *ImageChannelPromoteOptions) error {
+		ret, err := modules.ImageChannels.PerformAction(s, args.ID, "promote", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelReviewOptions struct {
+		ID        string `help:"id or name of image channel" json:"-"`
+		PROMOTION string `help:"id of pending promotion" json:"promotion_id"`
+		Reason    string `help:"review comment" json:"reason"`
+	}
+	R(&ImageChannelReviewOptions{}, "image-channel-approve", "Approve pending image promotion", func(s *mcclient.ClientSession, args *ImageChannelReviewOptions) error {
+		ret, err := modules.ImageChannels.PerformAction(s, args.ID, "approve", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	R(&ImageChannelReviewOptions{}, "image-channel-reject", "Reject pending image promotion", func(s *mcclient.ClientSession, args *ImageChannelReviewOptions) error {
+		ret, err := modules.ImageChannels.PerformAction(s, args.ID, "reject", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelPromotionsOptions struct {
+		ID     string `help:"id or name of image channel" json:"-"`
+		Status string `help:"filter by status" choices:"pending|approved|rejected" json:"status"`
+	}
+	R(&ImageChannelPromotionsOptions{}, "image-channel-promotions", "List promotions of image channel", func(s *mcclient.ClientSession, args *ImageChannelPromotionsOptions) error {
+		ret, err := modules.ImageChannels.GetSpecific(s, args.ID, "promotions", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ImageChannelResolveOptions struct {
+		REF string `help:"channel reference, e.g. ubuntu22-prod" json:"ref"`
+	}
+	R(&ImageChannelResolveOptions{}, "image-channel-resolve", "Resolve channel reference to image", func(s *mcclient.ClientSession, args *ImageChannelResolveOptions) error {
+		ret, err := modules.ImageChannels.Get(s, "resolve", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+}
@@ -470,4 +470,49 @@ func init() {
 		printObject(img)
 		return nil
 	})
+
+	type ImageAddArchVariantOptions struct {
+		ID      string `help:"ID or name of multi-arch image"`
+		VARIANT string `help:"ID or name of image of another arch"`
+	}
+	R(&ImageAddArchVariantOptions{}, "image-add-arch-variant", "Add image of another arch as variant of a multi-arch image", func(s *mcclient.ClientSession, opts *ImageAddArchVariantOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(opts.VARIANT), "variant_image_id")
+		img, err := modules.Images.PerformAction(s, opts.ID, "add-arch-variant", params)
+		if err != nil {
+			return err
+		}
+		printObject(img)
+		return nil
+	})
+
+	type ImageRemoveArchVariantOptions struct {
+		ID     string `help:"ID or name of multi-arch image"`
+		OSARCH string `help:"Arch of variant to remove" choices:"x86|arm"`
+	}
+	R(&ImageRemoveArchVariantOptions{}, "image-remove-arch-variant", "Remove arch variant of a multi-arch image", func(s *mcclient.ClientSession, opts *ImageRemoveArchVariantOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(opts.OSARCH), "os_arch")
+		img, err := modules.Images.PerformAction(s, opts.ID, "remove-arch-variant", params)
+		if err != nil {
+			return err
+		}
+		printObject(img)
+		return nil
+	})
+
+	type ImageArchVariantOptions struct {
+		ID     string `help:"ID or name of multi-arch image"`
+		OSARCH string `help:"Target arch, e.g. x86_64 or aarch64"`
+	}
+	R(&ImageArchVariantOptions{}, "image-arch-variant", "Show image matching the target arch", func(s *mcclient.ClientSession, opts *ImageArchVariantOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(opts.OSARCH), "os_arch")
+		ret, err := modules.Images.GetSpecific(s, opts.ID, "arch-variant", params)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
 }
@@ -0,0 +1,72 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package misc
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/drbundle"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+)
+
+func init() {
+	type PlatformExportOptions struct {
+		Kind   []string `help:"resource kinds to export, default all" choices:"domains|projects|policies|vpcs|wires|networks|secgroups|serverskus|images"`
+		Output string   `help:"file to save the bundle, print to stdout if not set" short-token:"o"`
+	}
+	R(&PlatformExportOptions{}, "platform-export", "Export declarative resource definitions for disaster recovery or environment cloning", func(s *mcclient.ClientSession, args *PlatformExportOptions) error {
+		bundle, err := drbundle.Export(s, args.Kind)
+		if err != nil {
+			return err
+		}
+		content := jsonutils.Marshal(bundle).PrettyString()
+		if len(args.Output) == 0 {
+			fmt.Println(content)
+			return nil
+		}
+		return ioutil.WriteFile(args.Output, []byte(content), 0600)
+	})
+
+	type PlatformImportOptions struct {
+		FILE   string `help:"bundle file generated by platform-export"`
+		DryRun bool   `help:"only check which resources would be created"`
+	}
+	R(&PlatformImportOptions{}, "platform-import", "Recreate resources from an exported definition bundle", func(s *mcclient.ClientSession, args *PlatformImportOptions) error {
+		content, err := ioutil.ReadFile(args.FILE)
+		if err != nil {
+			return err
+		}
+		obj, err := jsonutils.Parse(content)
+		if err != nil {
+			return err
+		}
+		bundle := &drbundle.SBundle{}
+		err = obj.Unmarshal(bundle)
+		if err != nil {
+			return err
+		}
+		results, err := drbundle.Import(s, bundle, args.DryRun)
+		data := make([]jsonutils.JSONObject, len(results))
+		for i := range results {
+			data[i] = jsonutils.Marshal(results[i])
+		}
+		printList(&modulebase.ListResult{Data: data, Total: len(data)}, []string{"kind", "name", "id", "status", "error"})
+		return err
+	})
+}
@@ -43,6 +43,10 @@ type APIHelper struct {
 
 	mcclientSession *mcclient.ClientSession
 
+	// 各资源增量拉取的 updated_at 水位
+	watermarks map[string]time.Time
+	fullSyncAt time.Time
+
 	tick *time.Timer
 }
 
@@ -52,6 +56,7 @@ func NewAPIHelper(opts *Options, modelSets IModelSets) (*APIHelper, error) {
 		opts:        opts,
 		modelSets:   modelSets,
 		modelSetsCh: modelSetsCh,
+		watermarks:  map[string]time.Time{},
 	}
 	return helper, nil
 }
@@ -154,19 +159,58 @@ func (h *APIHelper) doSync(ctx context.Context) (changed bool, err error) {
 	}
 
 	s := h.adminClientSession(ctx)
-	mss := h.modelSets.Copy()
-	r, err := SyncModelSets(mss, s, h.opts)
+	full := h.needFullSync()
+	var (
+		mss        IModelSets
+		watermarks map[string]time.Time
+	)
+	if full {
+		mss = h.modelSets.NewEmpty()
+		watermarks = map[string]time.Time{}
+	} else {
+		mss = h.modelSets.Copy()
+		watermarks = h.copyWatermarks()
+	}
+	r, err := SyncModelSetsWithWatermarks(mss, s, h.opts, watermarks)
 	if err != nil {
 		return false, err
 	}
-	h.modelSets = mss
 	if !r.Correct {
 		return false, errors.Wrap(ErrSync, "incorrect")
 	}
+	h.modelSets = mss
+	h.watermarks = watermarks
 	changed = r.Changed
+	if full {
+		log.Infof("apihelper: full sync done")
+		h.fullSyncAt = time.Now()
+		// 全量同步后数据集整体替换, 总是通知使用方
+		changed = true
+	}
 	return changed, nil
 }
 
+func (h *APIHelper) needFullSync() bool {
+	if h.fullSyncAt.IsZero() {
+		// 首次同步本身就是全量拉取
+		h.fullSyncAt = time.Now()
+		return false
+	}
+	intv := h.opts.FullSyncIntervalSeconds
+	if intv <= 0 {
+		return false
+	}
+	return time.Since(h.fullSyncAt) >= time.Duration(intv)*time.Second
+}
+
+func (h *APIHelper) copyWatermarks() map[string]time.Time {
+	r := make(map[string]time.Time, len(h.watermarks))
+	for k, v := range h.watermarks {
+		r[k] = v
+	}
+	return r
+}
+
 func (h *APIHelper) adminClientSession(ctx context.Context) *mcclient.ClientSession {
 	s := h.mcclientSession
 	if s != nil {
@@ -15,6 +15,8 @@
 package apihelper
 
 import (
+	"time"
+
 	"yunion.io/x/jsonutils"
 
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -55,17 +57,30 @@ type IModelListParam interface {
 }
 
 func SyncModelSets(mssOld IModelSets, s *mcclient.ClientSession, opt *Options) (r ModelSetsUpdateResult, err error) {
+	return SyncModelSetsWithWatermarks(mssOld, s, opt, nil)
+}
+
+// SyncModelSetsWithWatermarks 按各资源的 updated_at 水位增量拉取
+//
+// 水位取上次拉取到的最大 updated_at(含已删除记录), 未记录时取已有数据中的最大值。
+// 拉取成功后 watermarks 会被更新
+func SyncModelSetsWithWatermarks(mssOld IModelSets, s *mcclient.ClientSession, opt *Options, watermarks map[string]time.Time) (r ModelSetsUpdateResult, err error) {
 	mss := mssOld.ModelSetList()
 	mssNews := mssOld.NewEmpty()
+	newWatermarks := map[string]time.Time{}
 	for i, msNew := range mssNews.ModelSetList() {
 		var (
+			key             = msNew.ModelManager().KeyString()
 			minUpdatedAt    = ModelSetMaxUpdatedAt(mss[i])
 			includeEmulated = false
 		)
+		if watermark, ok := watermarks[key]; ok && watermark.After(minUpdatedAt) {
+			minUpdatedAt = watermark
+		}
 		if optProvider, ok := msNew.(IModelSetEmulatedIncluder); ok {
 			includeEmulated = optProvider.IncludeEmulated()
 		}
-		err = GetModels(&GetModelsOptions{
+		newWatermarks[key], err = getModels(&GetModelsOptions{
 			ClientSession: s,
 			ModelManager:  msNew.ModelManager(),
 			MinUpdatedAt:  minUpdatedAt,
@@ -81,5 +96,10 @@ func SyncModelSets(mssOld IModelSets, s *mcclient.ClientSession, opt *Options) (
 		}
 	}
 	r = mssOld.ApplyUpdates(mssNews)
+	if watermarks != nil {
+		for k, v := range newWatermarks {
+			watermarks[k] = v
+		}
+	}
 	return r, nil
 }
@@ -26,4 +26,7 @@ type Options struct {
 	ListBatchSize        int
 	IncludeDetails       bool
 	IncludeOtherCloudEnv bool
+
+	// 增量同步之外, 每隔该时间全量拉取一次以纠正偏差, 0 表示不做周期全量同步
+	FullSyncIntervalSeconds int
 }
@@ -46,6 +46,12 @@ type GetModelsOptions struct {
 }
 
 func GetModels(opts *GetModelsOptions) error {
+	_, err := getModels(opts)
+	return err
+}
+
+// getModels 返回本次拉取到的记录(含已删除及待删除记录)中最大的 updated_at, 作为下次增量拉取的水位
+func getModels(opts *GetModelsOptions) (time.Time, error) {
 	man := opts.ModelManager
 	manKeyPlural := man.KeyString()
 
@@ -133,7 +139,7 @@ func GetModels(opts *GetModelsOptions) error {
 	}
 	params, err := listOptions.Params()
 	if err != nil {
-		return fmt.Errorf("%s: making list params: %s", manKeyPlural, err)
+		return time.Time{}, fmt.Errorf("%s: making list params: %s", manKeyPlural, err)
 	}
 	if inter, ok := opts.ModelSet.(IModelListParam); ok {
 		filter := inter.ModelParamFilter()
@@ -147,7 +153,7 @@ func GetModels(opts *GetModelsOptions) error {
 		var err error
 		listResult, err := opts.ModelManager.List(opts.ClientSession, params)
 		if err != nil {
-			return fmt.Errorf("%s: list failed with updated_at.gt('%s'): %s",
+			return time.Time{}, fmt.Errorf("%s: list failed with updated_at.gt('%s'): %s",
 				manKeyPlural, minUpdatedAt, err)
 		}
 		entriesJson = append(entriesJson, listResult.Data...)
@@ -156,17 +162,25 @@ func GetModels(opts *GetModelsOptions) error {
 		}
 		minUpdatedAt, err = setNextListParams(params, minUpdatedAt, listResult)
 		if err != nil {
-			return fmt.Errorf("%s: %s", manKeyPlural, err)
+			return time.Time{}, fmt.Errorf("%s: %s", manKeyPlural, err)
 		}
 	}
 	{
 		err := InitializeModelSetFromJSON(opts.ModelSet, entriesJson)
 		if err != nil {
-			return fmt.Errorf("%s: initializing model set failed: %s",
+			return time.Time{}, fmt.Errorf("%s: initializing model set failed: %s",
 				manKeyPlural, err)
 		}
 	}
-	return nil
+	watermark := opts.MinUpdatedAt
+	for _, entryJson := range entriesJson {
+		updatedAt, err := entryJson.GetTime("updated_at")
+		if err == nil && watermark.Before(updatedAt) {
+			watermark = updatedAt
+		}
+	}
+	log.Debugf("%s: fetched %d entries since %s", manKeyPlural, len(entriesJson), opts.MinUpdatedAt)
+	return watermark, nil
 }
 
 func InitializeModelSetFromJSON(set IModelSet, entriesJson []jsonutils.JSONObject) error {
@@ -107,6 +107,10 @@ type DiskConfig struct {
 	// required: false
 	ImageId string `json:"image_id"`
 
+	// 镜像频道引用, 格式为 <频道名称>-<阶段>, 使用该阶段当前发布的镜像, 未指定 image_id 时生效
+	// example: ubuntu22-prod
+	ImageChannel string `json:"image_channel"`
+
 	// 镜像加密key ID
 	ImageEncryptKeyId string `json:"image_encrypt_key_id"`
 
@@ -307,6 +311,16 @@ type ServerConfigs struct {
 	// alias for InstanceType
 	Sku string `json:"sku" yunion-deprecated-by:"instance_type"`
 
+	// QoS等级, 仅KVM支持, 未指定时使用套餐的QoS等级
+	// enum: guaranteed, burstable, best-effort
+	QosClass string `json:"qos_class"`
+
+	// 部署到指定的公有云专有宿主机, 仅支持专有宿主机的平台有效
+	DedicatedHostId string `json:"dedicated_host_id"`
+
+	// 时延敏感虚机, 优先调度到与这些宿主机网络时延低的宿主机
+	NetworkLatencyPeerHosts []string `json:"network_latency_peer_hosts"`
+
 	// 虚拟机高可用(创建备机)
 	// default: false
 	// required: false
@@ -374,6 +388,19 @@ type ServerCreateInput struct {
 	VmemSize       int  `json:"vmem_size"`
 	EnableMemclean bool `json:"enable_memclean"`
 
+	// 启用虚拟 TPM 2.0 设备(swtpm), 仅 kvm 支持, Windows 11 镜像会自动启用
+	EnableTpm bool `json:"enable_tpm"`
+
+	// 启用 virtio-mem 内存在线扩缩, 仅 kvm 支持
+	EnableVirtioMem bool `json:"enable_virtio_mem"`
+
+	// 启用 UEFI 安全启动, 仅 kvm x86 支持, 要求 UEFI 引导及 q35 机型
+	EnableSecureBoot bool `json:"enable_secure_boot"`
+
+	// 元数据服务访问模式, 项目安全基线要求更严格时以基线为准
+	// enum: ["enabled", "token_required", "disabled"]
+	ImdsMode string `json:"imds_mode"`
+
 	// 虚拟机Cpu大小,若未指定instance_type,此参数为必传项
 	// default: 1
 	VcpuCount int `json:"vcpu_count"`
@@ -451,6 +478,8 @@ type ServerCreateInput struct {
 	AutoStart      bool            `json:"auto_start"`
 	DeployConfigs  []*DeployConfig `json:"deploy_configs"`
 	DeployTelegraf bool            `json:"deploy_telegraf"`
+	// 部署完成后验证端口连通性, agent 心跳及 cloud-init 完成情况, 验证通过才视为创建成功
+	DeployVerify bool `json:"deploy_verify"`
 
 	// 包年包月时长
 	//
@@ -44,6 +44,18 @@ const (
 	BACKUP_NOT_EXIST = "not_exist"
 )
 
+const (
+	// 基于 dirty bitmap 的全量备份, 作为增量备份链的起点
+	DISK_BACKUP_MODE_FULL = "full"
+	// 基于 dirty bitmap 的增量备份, backing file 为上一个备份
+	DISK_BACKUP_MODE_INCREMENTAL = "incremental"
+	// 根据增量链长度自动选择全量或增量备份
+	DISK_BACKUP_MODE_AUTO = "auto"
+
+	// 磁盘上一次基于 dirty bitmap 的备份 id
+	DISK_METADATA_LAST_BITMAP_BACKUP = "__last_bitmap_backup"
+)
+
 const (
 	BackupStorageOffline = "backup storage offline"
 )
@@ -93,6 +105,8 @@ type DiskBackupListInput struct {
 	BackupStorageId string `json:"backup_storage_id"`
 	// description: 是否为主机备份的一部分
 	IsInstanceBackup *bool `json:"is_instance_backup"`
+	// description: 父备份id
+	ParentBackupId string `json:"parent_backup_id"`
 }
 
 type DiskBackupDetails struct {
@@ -107,6 +121,8 @@ type DiskBackupDetails struct {
 	BackupStorageName string `json:"backup_storage_name"`
 	// description: 是否是子备份
 	IsSubBackup bool `json:"is_sub_backup"`
+	// description: 父备份名称
+	ParentBackupName string `json:"parent_backup_name"`
 }
 
 type DiskBackupCreateInput struct {
@@ -117,6 +133,10 @@ type DiskBackupCreateInput struct {
 	DiskId string `json:"disk_id"`
 	// description: backup storage id
 	BackupStorageId string `json:"back_storage_id"`
+	// description: 备份模式, 仅运行中的本地存储KVM虚拟机磁盘支持增量备份
+	// enum: full,incremental,auto
+	// default: auto
+	BackupMode string `json:"backup_mode"`
 	// swagger: ignore
 	CloudregionId string `json:"cloudregion_id"`
 	// swagger:ignore
@@ -62,3 +62,51 @@ type CachedimageDetails struct {
 type CachedImageSetClassMetadataInput struct {
 	ClassMetadata map[string]string `json:"class_metadata"`
 }
+
+type CachedimageShareInput struct {
+	// 镜像所在的存储缓存Id, 仅支持公有云自定义镜像
+	// required: true
+	StoragecacheId string `json:"storagecache_id"`
+
+	// 共享给平台内的云账号, 需与镜像所在云账号为同一平台
+	TargetCloudaccountIds []string `json:"target_cloudaccount_ids"`
+
+	// 共享给平台外的云上账号ID, 例如 AWS Account ID, 阿里云 UID
+	TargetAccountIds []string `json:"target_account_ids"`
+}
+
+type CachedimageUnshareInput struct {
+	// 镜像所在的存储缓存Id
+	// required: true
+	StoragecacheId string `json:"storagecache_id"`
+
+	// 取消共享的云上账号ID, 为空时取消所有共享
+	TargetAccountIds []string `json:"target_account_ids"`
+}
+
+type CachedimageShareListInput struct {
+	apis.StatusStandaloneResourceListInput
+	StoragecacheFilterListInput
+
+	// 镜像缓存Id
+	CachedimageId string `json:"cachedimage_id"`
+
+	// 云上账号ID
+	TargetAccountId []string `json:"target_account_id"`
+
+	// 平台内的目标云账号Id
+	TargetCloudaccountId string `json:"target_cloudaccount_id"`
+}
+
+type CachedimageShareDetails struct {
+	apis.StatusStandaloneResourceDetails
+	StoragecacheResourceInfo
+
+	SCachedimageShare
+
+	// 镜像缓存名称
+	Cachedimage string `json:"cachedimage"`
+
+	// 目标云账号名称
+	TargetCloudaccount string `json:"target_cloudaccount"`
+}
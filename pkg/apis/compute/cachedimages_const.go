@@ -28,6 +28,14 @@ const (
 	DOWNLOAD_SESSION_LENGTH = 3600 * 3 // 3 hour
 )
 
+const (
+	CACHEDIMAGE_SHARE_STATUS_SHARING        = "sharing"
+	CACHEDIMAGE_SHARE_STATUS_SHARED         = "shared"
+	CACHEDIMAGE_SHARE_STATUS_SHARE_FAILED   = "share_failed"
+	CACHEDIMAGE_SHARE_STATUS_UNSHARING      = "unsharing"
+	CACHEDIMAGE_SHARE_STATUS_UNSHARE_FAILED = "unshare_failed"
+)
+
 const (
 	CACHED_IMAGE_REFRESH_SECONDS                  = 1     // 1 second
 	CACHED_IMAGE_REFERENCE_SESSION_EXPIRE_SECONDS = 86400 // 1 day
@@ -355,7 +355,8 @@ type CloudaccountUpdateInput struct {
 	// 临时清除缺失的权限提示，云账号权限缺失依然会自动刷新
 	CleanLakeOfPermissions bool `json:"clean_lake_of_permissions"`
 
-	ReadOnly bool `json:"read_only"`
+	// 只读模式, 开启后平台不会对云上资源做任何变更, 资源同步不受影响, 可随时切换
+	ReadOnly *bool `json:"read_only"`
 }
 
 type CloudaccountPerformPublicInput struct {
@@ -521,6 +522,40 @@ type SyncRangeInput struct {
 	Resources []string `json:"resources" choices:"project|compute|network|eip|loadbalancer|objectstore|rds|cache|event|cloudid|dnszone|public_ip|intervpcnetwork|saml_auth|quota|nat|nas|waf|mongodb|es|kafka|app|cdn|container|ipv6_gateway|tablestore|modelarts|vpcpeer|misc"`
 }
 
+type CloudaccountDiagnoseInput struct {
+	// 仅诊断指定区域, 默认诊断每个子账号的第一个区域
+	Cloudregion string `json:"cloudregion"`
+
+	// 执行写权限检查, 会将已有资源的标签原样写回, 不会产生变更
+	WriteCheck bool `json:"write_check"`
+}
+
+type CloudaccountDiagnoseItem struct {
+	// 检查项名称
+	Name string `json:"name"`
+	// 子账号名称
+	Cloudprovider string `json:"cloudprovider,omitempty"`
+	// 区域名称
+	Cloudregion string `json:"cloudregion,omitempty"`
+	// 检查结果
+	// enum: pass, fail, skip
+	Status string `json:"status"`
+	// 失败原因或补充说明
+	Message string `json:"message,omitempty"`
+	// 耗时(毫秒)
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+type CloudaccountDiagnoseOutput struct {
+	// 整体结果, 任意检查项失败即为fail
+	Status string `json:"status"`
+
+	Items []CloudaccountDiagnoseItem `json:"items"`
+
+	// 同步过程中已记录的缺失权限
+	LakeOfPermissions *SAccountPermissions `json:"lake_of_permissions,omitempty"`
+}
+
 type SAccountPermission struct {
 	Permissions []string
 }
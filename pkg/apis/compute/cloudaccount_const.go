@@ -80,6 +80,10 @@ const (
 	CLOUD_ACCOUNT_WIRE_LEVEL_VCENTER    = "vcenter"
 	CLOUD_ACCOUNT_WIRE_LEVEL_DATACENTER = "datacenter"
 	CLOUD_ACCOUNT_WIRE_LEVEL_CLUSTER    = "cluster"
+
+	CLOUD_ACCOUNT_DIAGNOSE_PASS = "pass"
+	CLOUD_ACCOUNT_DIAGNOSE_FAIL = "fail"
+	CLOUD_ACCOUNT_DIAGNOSE_SKIP = "skip"
 )
 
 var CLOUD_ACCOUNT_WIRE_LEVELS = choices.NewChoices(
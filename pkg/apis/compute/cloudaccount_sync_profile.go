@@ -0,0 +1,102 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// 公有云规格按云账号区域同步, 不在同步范围的资源类型中
+	CLOUD_SYNC_PROFILE_RESOURCE_SKU = "sku"
+
+	CLOUD_SYNC_PROFILE_MIN_INTERVAL_SECONDS = 60
+)
+
+var CLOUD_SYNC_PROFILE_RESOURCES = []string{
+	cloudprovider.CLOUD_CAPABILITY_PROJECT,
+	cloudprovider.CLOUD_CAPABILITY_COMPUTE,
+	cloudprovider.CLOUD_CAPABILITY_NETWORK,
+	cloudprovider.CLOUD_CAPABILITY_EIP,
+	cloudprovider.CLOUD_CAPABILITY_LOADBALANCER,
+	cloudprovider.CLOUD_CAPABILITY_OBJECTSTORE,
+	cloudprovider.CLOUD_CAPABILITY_RDS,
+	cloudprovider.CLOUD_CAPABILITY_CACHE,
+	cloudprovider.CLOUD_CAPABILITY_EVENT,
+	cloudprovider.CLOUD_CAPABILITY_CLOUDID,
+	cloudprovider.CLOUD_CAPABILITY_DNSZONE,
+	cloudprovider.CLOUD_CAPABILITY_PUBLIC_IP,
+	cloudprovider.CLOUD_CAPABILITY_INTERVPCNETWORK,
+	cloudprovider.CLOUD_CAPABILITY_SAML_AUTH,
+	cloudprovider.CLOUD_CAPABILITY_QUOTA,
+	cloudprovider.CLOUD_CAPABILITY_NAT,
+	cloudprovider.CLOUD_CAPABILITY_NAS,
+	cloudprovider.CLOUD_CAPABILITY_WAF,
+	cloudprovider.CLOUD_CAPABILITY_MONGO_DB,
+	cloudprovider.CLOUD_CAPABILITY_ES,
+	cloudprovider.CLOUD_CAPABILITY_KAFKA,
+	cloudprovider.CLOUD_CAPABILITY_APP,
+	cloudprovider.CLOUD_CAPABILITY_CDN,
+	cloudprovider.CLOUD_CAPABILITY_CONTAINER,
+	cloudprovider.CLOUD_CAPABILITY_IPV6_GATEWAY,
+	cloudprovider.CLOUD_CAPABILITY_TABLESTORE,
+	cloudprovider.CLOUD_CAPABILITY_MODELARTES,
+	cloudprovider.CLOUD_CAPABILITY_VPC_PEER,
+	cloudprovider.CLOUD_CAPABILITY_MISC,
+	CLOUD_SYNC_PROFILE_RESOURCE_SKU,
+}
+
+type CloudaccountSyncProfileCreateInput struct {
+	apis.StandaloneAnonResourceCreateInput
+
+	// 云账号ID或名称
+	CloudaccountId string `json:"cloudaccount_id"`
+	// 资源类型, 与同步范围的 resources 一致, 另支持 sku
+	// example: compute
+	Resource string `json:"resource"`
+	// 同步间隔(秒), 不小于60
+	// example: 120
+	IntervalSeconds int `json:"interval_seconds"`
+}
+
+type CloudaccountSyncProfileUpdateInput struct {
+	apis.StandaloneAnonResourceBaseUpdateInput
+
+	IntervalSeconds *int `json:"interval_seconds"`
+}
+
+type CloudaccountSyncProfileListInput struct {
+	apis.StandaloneAnonResourceListInput
+
+	// 云账号ID或名称
+	CloudaccountId string `json:"cloudaccount_id"`
+	// 资源类型
+	Resource []string `json:"resource"`
+}
+
+type CloudaccountSyncProfileDetails struct {
+	apis.StandaloneAnonResourceDetails
+
+	SCloudaccountSyncProfile
+
+	// 云账号名称
+	Cloudaccount string `json:"cloudaccount"`
+	// 预计下次同步时间
+	NextSyncAt time.Time `json:"next_sync_at"`
+}
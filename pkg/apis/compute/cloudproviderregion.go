@@ -38,6 +38,9 @@ type CloudproviderregionDetails struct {
 
 	// 支持服务列表
 	Capabilities []string `json:"capabilities"`
+
+	// 正在同步时已耗时(秒)
+	SyncElapsedSeconds int `json:"sync_elapsed_seconds"`
 }
 
 type CloudproviderregionListInput struct {
@@ -49,4 +52,7 @@ type CloudproviderregionListInput struct {
 
 	// 是否启用
 	Enabled *bool `json:"enabled"`
+
+	// 最近一次同步是否有错误
+	SyncFailed *bool `json:"sync_failed"`
 }
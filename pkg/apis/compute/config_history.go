@@ -0,0 +1,92 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// 资源变更时记录
+	CONFIG_HISTORY_TRIGGER_CHANGE = "change"
+	// 定时巡检记录
+	CONFIG_HISTORY_TRIGGER_SCHEDULE = "schedule"
+
+	CONFIG_HISTORY_RESOURCE_SERVER      = "servers"
+	CONFIG_HISTORY_RESOURCE_SECGROUP    = "secgroups"
+	CONFIG_HISTORY_RESOURCE_LB_LISTENER = "loadbalancerlisteners"
+)
+
+var CONFIG_HISTORY_RESOURCE_TYPES = []string{
+	CONFIG_HISTORY_RESOURCE_SERVER,
+	CONFIG_HISTORY_RESOURCE_SECGROUP,
+	CONFIG_HISTORY_RESOURCE_LB_LISTENER,
+}
+
+type ConfigHistoryListInput struct {
+	apis.StandaloneAnonResourceListInput
+
+	ResourceType string `json:"resource_type"`
+	ResourceId   string `json:"resource_id"`
+	Trigger      string `json:"trigger"`
+	// 记录时间范围
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+type ConfigHistoryDetails struct {
+	apis.StandaloneAnonResourceDetails
+
+	SConfigHistory
+}
+
+// 对比同一资源的两个配置版本
+// 指定 from_version/to_version 时按版本对比, 否则取 since/until 时刻生效的版本对比, until 默认为当前
+type ConfigHistoryDiffInput struct {
+	ResourceType string `json:"resource_type"`
+	ResourceId   string `json:"resource_id"`
+
+	FromVersion int `json:"from_version"`
+	ToVersion   int `json:"to_version"`
+
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+type SConfigHistoryChange struct {
+	// 变更字段路径, 如 disks.0.size
+	Path string `json:"path"`
+	// added, removed, modified
+	Op  string               `json:"op"`
+	Old jsonutils.JSONObject `json:"old,omitempty"`
+	New jsonutils.JSONObject `json:"new,omitempty"`
+}
+
+type ConfigHistoryDiffOutput struct {
+	ResourceType string `json:"resource_type"`
+	ResourceId   string `json:"resource_id"`
+	ResourceName string `json:"resource_name"`
+
+	FromVersion int       `json:"from_version"`
+	FromTime    time.Time `json:"from_time"`
+	ToVersion   int       `json:"to_version"`
+	ToTime      time.Time `json:"to_time"`
+
+	Changes []SConfigHistoryChange `json:"changes"`
+}
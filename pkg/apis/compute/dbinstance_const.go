@@ -136,6 +136,10 @@ const (
 
 	// Azure
 	AZURE_DBINSTANCE_STORAGE_TYPE_DEFAULT = compute.AZURE_DBINSTANCE_STORAGE_TYPE_DEFAULT
+
+	// OpenStack Trove
+	OPENSTACK_DBINSTANCE_CATEGORY_SINGLE     = compute.OPENSTACK_DBINSTANCE_CATEGORY_SINGLE
+	OPENSTACK_DBINSTANCE_STORAGE_TYPE_VOLUME = compute.OPENSTACK_DBINSTANCE_STORAGE_TYPE_VOLUME
 )
 
 var (
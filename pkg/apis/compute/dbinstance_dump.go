@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	DBINSTANCE_DUMP_STATUS_DUMPING        = "dumping"
+	DBINSTANCE_DUMP_STATUS_DUMP_FAILED    = "dump_failed"
+	DBINSTANCE_DUMP_STATUS_READY          = "ready"
+	DBINSTANCE_DUMP_STATUS_RESTORING      = "restoring"
+	DBINSTANCE_DUMP_STATUS_RESTORE_FAILED = "restore_failed"
+
+	// 逻辑导出格式, 同格式的实例之间可以互相恢复
+	DBINSTANCE_DUMP_FORMAT_MYSQL      = "mysqldump"
+	DBINSTANCE_DUMP_FORMAT_POSTGRESQL = "pg_dump"
+
+	// 导出及恢复的默认超时时间, 单位秒
+	DBINSTANCE_DUMP_DEFAULT_TIMEOUT = 6 * 3600
+)
+
+// RDS实例逻辑导出, 由工作虚机执行导出并上传到对象存储, 可恢复到其他平台的RDS实例
+type DBInstanceDumpCreateInput struct {
+	apis.VirtualResourceCreateInput
+
+	// 源RDS实例名称或Id
+	// required: true
+	DBInstanceId string `json:"dbinstance_id"`
+	// 用于导出的RDS账号名称或Id, 需具备读取待导出数据库的权限
+	// required: true
+	DBInstanceaccountId string `json:"dbinstanceaccount_id"`
+	// 待导出的数据库, 默认为实例下全部数据库, PostgreSQL 仅支持单个数据库
+	Databases []string `json:"databases"`
+
+	// 存放导出文件的存储桶名称或Id
+	// required: true
+	BucketId string `json:"bucket_id"`
+	// 导出文件的对象键前缀
+	KeyPrefix string `json:"key_prefix"`
+
+	// 执行导出的工作虚机, 需安装 qemu-guest-agent, curl, gzip 及对应数据库客户端, 并能访问RDS实例和存储桶
+	// required: true
+	WorkerServerId string `json:"worker_server_id"`
+	// 超时时间, 单位秒, 默认6小时
+	Timeout int `json:"timeout"`
+}
+
+type DBInstanceDumpRestoreInput struct {
+	// 目标RDS实例名称或Id, 可以属于其他平台, 引擎需与导出格式一致
+	// required: true
+	DBInstanceId string `json:"dbinstance_id"`
+	// 目标实例上用于恢复的账号名称或Id, 需具备建库及写入权限
+	// required: true
+	DBInstanceaccountId string `json:"dbinstanceaccount_id"`
+	// 执行恢复的工作虚机, 默认为导出时使用的虚机
+	WorkerServerId string `json:"worker_server_id"`
+	// 超时时间, 单位秒, 默认6小时
+	Timeout int `json:"timeout"`
+}
+
+type DBInstanceDumpListInput struct {
+	apis.VirtualResourceListInput
+
+	DBInstanceFilterListInputBase
+
+	BucketId string `json:"bucket_id"`
+	Format   string `json:"format"`
+}
+
+type DBInstanceDumpDetails struct {
+	apis.VirtualResourceDetails
+
+	SDBInstanceDump
+
+	DBInstance string `json:"dbinstance"`
+	Bucket     string `json:"bucket"`
+}
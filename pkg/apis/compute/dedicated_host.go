@@ -0,0 +1,72 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	DEDICATED_HOST_STATUS_AVAILABLE     = "available"
+	DEDICATED_HOST_STATUS_CREATING      = "creating"
+	DEDICATED_HOST_STATUS_CREATE_FAILED = "create_failed"
+	DEDICATED_HOST_STATUS_DELETING      = "deleting"
+	DEDICATED_HOST_STATUS_DELETE_FAILED = "delete_failed"
+	DEDICATED_HOST_STATUS_UNKNOWN       = "unknown"
+)
+
+type DedicatedHostListInput struct {
+	apis.StatusInfrasResourceBaseListInput
+	apis.ExternalizedResourceBaseListInput
+	ManagedResourceListInput
+	ZonalFilterListInput
+
+	// 专有宿主机规格
+	HostType []string `json:"host_type"`
+}
+
+type DedicatedHostCreateInput struct {
+	apis.StatusInfrasResourceBaseCreateInput
+
+	// 可用区Id
+	ZoneId string `json:"zone_id"`
+	// 订阅Id
+	ManagerId string `json:"manager_id"`
+	// 专有宿主机规格, 例如 AWS 的 c5, 阿里云的 ddh.g6
+	HostType string `json:"host_type"`
+
+	// swagger:ignore
+	CloudregionId string `json:"cloudregion_id"`
+}
+
+type DedicatedHostDetails struct {
+	apis.StatusInfrasResourceBaseDetails
+	ManagedResourceInfo
+	ZoneResourceInfo
+
+	SDedicatedHost
+
+	// 部署在该专有宿主机上的虚机数量
+	GuestCount int `json:"guest_count"`
+	// 已分配CPU核数
+	UsedCpuCount int `json:"used_cpu_count"`
+	// 已分配内存, 单位MB
+	UsedMemSizeMb int `json:"used_mem_size_mb"`
+	// CPU分配率
+	CpuCommitRate float64 `json:"cpu_commit_rate"`
+	// 内存分配率
+	MemCommitRate float64 `json:"mem_commit_rate"`
+}
+
+type DedicatedHostSyncstatusInput struct {
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+)
+
+const (
+	DISK_CACHE_MODE_NONE         = "none"
+	DISK_CACHE_MODE_WRITEBACK    = "writeback"
+	DISK_CACHE_MODE_WRITETHROUGH = "writethrough"
+	DISK_CACHE_MODE_DIRECTSYNC   = "directsync"
+	DISK_CACHE_MODE_UNSAFE       = "unsafe"
+
+	DISK_AIO_MODE_NATIVE   = "native"
+	DISK_AIO_MODE_THREADS  = "threads"
+	DISK_AIO_MODE_IO_URING = "io_uring"
+)
+
+var (
+	DISK_CACHE_MODES = []string{DISK_CACHE_MODE_NONE, DISK_CACHE_MODE_WRITEBACK, DISK_CACHE_MODE_WRITETHROUGH, DISK_CACHE_MODE_DIRECTSYNC, DISK_CACHE_MODE_UNSAFE}
+	DISK_AIO_MODES   = []string{DISK_AIO_MODE_NATIVE, DISK_AIO_MODE_THREADS, DISK_AIO_MODE_IO_URING}
+
+	// 绕过宿主机页缓存(O_DIRECT)的缓存模式
+	DISK_DIRECT_CACHE_MODES = []string{DISK_CACHE_MODE_NONE, DISK_CACHE_MODE_DIRECTSYNC}
+)
+
+// DefaultDiskAioMode 返回缓存模式对应的默认 IO 后端, native 需要 O_DIRECT
+func DefaultDiskAioMode(cacheMode string) string {
+	if utils.IsInStringArray(cacheMode, DISK_DIRECT_CACHE_MODES) {
+		return DISK_AIO_MODE_NATIVE
+	}
+	return DISK_AIO_MODE_THREADS
+}
+
+// ValidateDiskCacheAioMode 校验 kvm 磁盘缓存模式, IO 后端与存储类型的组合
+//
+//   - aio=native 仅能与 cache=none/directsync 组合
+//   - 共享文件存储(nfs, gpfs)上宿主机页缓存在热迁移后会失效, 仅允许 none/directsync
+//   - rbd 由 librbd 在迁移时刷写缓存, 除 unsafe 外均允许
+//   - unsafe 忽略 flush 请求, 仅允许用于本地存储
+func ValidateDiskCacheAioMode(cacheMode, aioMode, storageType string) error {
+	if !utils.IsInStringArray(cacheMode, DISK_CACHE_MODES) {
+		return errors.Errorf("invalid cache_mode %q, want %s", cacheMode, DISK_CACHE_MODES)
+	}
+	if len(aioMode) > 0 && !utils.IsInStringArray(aioMode, DISK_AIO_MODES) {
+		return errors.Errorf("invalid aio_mode %q, want %s", aioMode, DISK_AIO_MODES)
+	}
+	if aioMode == DISK_AIO_MODE_NATIVE && !utils.IsInStringArray(cacheMode, DISK_DIRECT_CACHE_MODES) {
+		return errors.Errorf("aio_mode native requires cache_mode %s, got %s", DISK_DIRECT_CACHE_MODES, cacheMode)
+	}
+	switch {
+	case utils.IsInStringArray(storageType, SHARED_FILE_STORAGE):
+		if !utils.IsInStringArray(cacheMode, DISK_DIRECT_CACHE_MODES) {
+			return errors.Errorf("cache_mode %s is not safe for live migration on shared storage %s, want %s", cacheMode, storageType, DISK_DIRECT_CACHE_MODES)
+		}
+	case storageType == STORAGE_RBD:
+		if cacheMode == DISK_CACHE_MODE_UNSAFE {
+			return errors.Errorf("cache_mode unsafe is not allowed on shared storage %s", storageType)
+		}
+	case cacheMode == DISK_CACHE_MODE_UNSAFE && storageType != STORAGE_LOCAL:
+		return errors.Errorf("cache_mode unsafe is only allowed on local storage, got %s", storageType)
+	}
+	return nil
+}
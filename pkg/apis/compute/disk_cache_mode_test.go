@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+)
+
+func TestValidateDiskCacheAioMode(t *testing.T) {
+	cases := []struct {
+		cache   string
+		aio     string
+		storage string
+		wantErr bool
+	}{
+		{"none", "native", STORAGE_LOCAL, false},
+		{"writeback", "threads", STORAGE_LOCAL, false},
+		{"writeback", "io_uring", STORAGE_LOCAL, false},
+		{"unsafe", "threads", STORAGE_LOCAL, false},
+		{"directsync", "native", STORAGE_NFS, false},
+		{"writeback", "native", STORAGE_LOCAL, true},
+		{"writeback", "threads", STORAGE_NFS, true},
+		{"writethrough", "threads", STORAGE_GPFS, true},
+		{"none", "", STORAGE_GPFS, false},
+		{"writeback", "", STORAGE_RBD, false},
+		{"unsafe", "", STORAGE_RBD, true},
+		{"unsafe", "threads", STORAGE_BAREMETAL, true},
+		{"bogus", "", STORAGE_LOCAL, true},
+		{"none", "posix", STORAGE_LOCAL, true},
+	}
+	for _, c := range cases {
+		err := ValidateDiskCacheAioMode(c.cache, c.aio, c.storage)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ValidateDiskCacheAioMode(%s, %s, %s) error %v, wantErr %v", c.cache, c.aio, c.storage, err, c.wantErr)
+		}
+	}
+}
+
+func TestDefaultDiskAioMode(t *testing.T) {
+	for cache, want := range map[string]string{
+		"none":       "native",
+		"directsync": "native",
+		"writeback":  "threads",
+	} {
+		if got := DefaultDiskAioMode(cache); got != want {
+			t.Errorf("DefaultDiskAioMode(%s) = %s, want %s", cache, got, want)
+		}
+	}
+}
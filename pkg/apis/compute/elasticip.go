@@ -74,6 +74,10 @@ type SElasticipCreateInput struct {
 	BgpType string `json:"bgp_type"`
 
 	BandwidthMb int `json:"bandwidth"`
+
+	// 加入已有的共享带宽(云上Id), 目前仅华为云支持
+	// 指定后带宽大小及计费类型由共享带宽决定
+	SharedBandwidthId string `json:"shared_bandwidth_id"`
 }
 
 type ElasticipDetails struct {
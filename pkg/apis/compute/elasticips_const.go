@@ -103,6 +103,9 @@ type ElasticipListInput struct {
 	// 目前只有华为云此字段是必需填写的
 	BgpType []string `json:"bgp_type"`
 
+	// 共享带宽(云上Id)
+	SharedBandwidthId []string `json:"shared_bandwidth_id"`
+
 	// 是否跟随主机删除而自动释放
 	AutoDellocate *bool `json:"auto_dellocate"`
 }
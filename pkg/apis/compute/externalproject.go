@@ -43,6 +43,22 @@ type ExternalProjectDetails struct {
 
 type ExternalProjectChangeProjectInput struct {
 	apis.ProjectizedResourceInput
+
+	// 映射优先级, 本地项目映射多个云上项目时, 新建资源使用优先级最高的云上项目
+	Priority *int `json:"priority"`
+}
+
+type ExternalProjectUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// 映射优先级
+	Priority *int `json:"priority"`
+}
+
+type ExternalProjectMigrateResourcesInput struct {
+	// 需要迁移的资源类型, 默认全部
+	// enum: server, disk, eip
+	ResourceTypes []string `json:"resource_types"`
 }
 
 type ExternalProjectCreateInput struct {
@@ -0,0 +1,96 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/util/choices"
+)
+
+const (
+	FAILOVER_VIP_STATUS_AVAILABLE       = "available"
+	FAILOVER_VIP_STATUS_ATTACHED        = "attached"
+	FAILOVER_VIP_STATUS_FAILOVER        = "failover"
+	FAILOVER_VIP_STATUS_FAILOVER_FAILED = "failover_failed"
+)
+
+const (
+	// 主虚机不在运行状态或所在宿主机离线时切换
+	FAILOVER_VIP_HEALTH_CHECK_GUEST_STATUS = "guest_status"
+	// 从控制节点探测主虚机端口, 连续失败时切换
+	FAILOVER_VIP_HEALTH_CHECK_TCP = "tcp"
+)
+
+var FAILOVER_VIP_HEALTH_CHECK_TYPES = choices.NewChoices(
+	FAILOVER_VIP_HEALTH_CHECK_GUEST_STATUS,
+	FAILOVER_VIP_HEALTH_CHECK_TCP,
+)
+
+type FailoverVipHealthCheckInput struct {
+	// 是否开启健康检查自动切换
+	AutoFailover *bool `json:"auto_failover"`
+	// 健康检查类型
+	// enum: guest_status, tcp
+	HealthCheckType string `json:"health_check_type"`
+	// tcp健康检查端口
+	HealthCheckPort *int `json:"health_check_port"`
+	// 连续失败多少次后切换
+	HealthCheckFailures *int `json:"health_check_failures"`
+}
+
+type FailoverVipCreateInput struct {
+	apis.VirtualResourceCreateInput
+	NetworkResourceInput
+	FailoverVipHealthCheckInput
+
+	// 指定VIP地址, 不指定时自动分配
+	IpAddr string `json:"ip_addr"`
+}
+
+type FailoverVipUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+	FailoverVipHealthCheckInput
+}
+
+type FailoverVipListInput struct {
+	apis.VirtualResourceListInput
+	NetworkFilterListInput
+
+	IpAddr []string `json:"ip_addr"`
+	// 按主虚机或备虚机过滤
+	GuestId string `json:"guest_id"`
+}
+
+type FailoverVipDetails struct {
+	apis.VirtualResourceDetails
+	NetworkResourceInfo
+
+	SFailoverVip
+
+	ActiveGuest  string `json:"active_guest"`
+	StandbyGuest string `json:"standby_guest"`
+}
+
+type FailoverVipAttachInput struct {
+	// 主虚机(ID或Name), VIP绑定在该虚机网卡上
+	ActiveGuestId string `json:"active_guest_id"`
+	// 备虚机(ID或Name), 切换时VIP迁移到该虚机
+	StandbyGuestId string `json:"standby_guest_id"`
+}
+
+type FailoverVipFailoverInput struct {
+	// 即使备虚机不在运行状态也切换
+	Force bool `json:"force"`
+}
@@ -227,6 +227,12 @@ const (
 	VM_VIDEO_STANDARD = "std"
 	VM_VIDEO_QXL      = "qxl"
 	VM_VIDEO_VIRTIO   = "virtio"
+
+	// spice usb 重定向通道数, 受限于 ehci 端口数
+	VM_SPICE_USBREDIR_DEFAULT_CHANNELS = 2
+	VM_SPICE_USBREDIR_MAX_CHANNELS     = 6
+	// qxl/virtio 显卡最多支持的显示器数量
+	VM_VDI_MAX_MONITORS = 4
 )
 
 var VM_RUNNING_STATUS = []string{VM_START_START, VM_STARTING, VM_RUNNING, VM_BLOCK_STREAM, VM_BLOCK_STREAM_FAIL}
@@ -375,7 +381,116 @@ const (
 	VM_METADATA_OS_NAME             = "os_name"
 	VM_METADATA_OS_VERSION          = "os_version"
 	VM_METADATA_CGROUP_CPUSET       = "cgroup_cpuset"
+	VM_METADATA_CGROUP_PRIORITY     = "cgroup_priority"
+	VM_METADATA_CLOCK_POLICY        = "clock_policy"
 	VM_METADATA_ENABLE_MEMCLEAN     = "enable_memclean"
+	VM_METADATA_SHARED_DIRS         = "shared_dirs"
+	VM_METADATA_ENABLE_TPM          = "enable_tpm"
+	VM_METADATA_ENABLE_VIRTIO_MEM   = "enable_virtio_mem"
+	VM_METADATA_ENABLE_SECURE_BOOT  = "enable_secure_boot"
+	VM_METADATA_IMDS_MODE           = "imds_mode"
+	VM_METADATA_BOOT_ONCE           = "boot_once"
+	VM_METADATA_PXE_CHAINLOAD_URL   = "pxe_chainload_url"
+
+	// RTC 基准时间及时钟漂移修正
+	VM_CLOCK_BASE_UTC       = "utc"
+	VM_CLOCK_BASE_LOCALTIME = "localtime"
+	VM_CLOCK_DRIFTFIX_NONE  = "none"
+	VM_CLOCK_DRIFTFIX_SLEW  = "slew"
+	VM_CLOCK_HPET_ON        = "on"
+	VM_CLOCK_HPET_OFF       = "off"
+
+	// 机密计算虚机类型, 为空表示普通虚机
+	VM_METADATA_CONFIDENTIAL_VM = "confidential_vm"
+
+	// 与宿主机上报的 sys_info.confidential_computing 取值一致
+	CONFIDENTIAL_VM_TYPE_SEV     = "sev"
+	CONFIDENTIAL_VM_TYPE_SEV_ES  = "sev-es"
+	CONFIDENTIAL_VM_TYPE_SEV_SNP = "sev-snp"
+	CONFIDENTIAL_VM_TYPE_TDX     = "tdx"
+
+	// 看门狗设备型号, 超时动作及触发时是否通知
+	VM_METADATA_WATCHDOG_MODEL  = "watchdog_model"
+	VM_METADATA_WATCHDOG_ACTION = "watchdog_action"
+	VM_METADATA_WATCHDOG_NOTIFY = "watchdog_notify"
+
+	// SR-IOV 网卡是否配对 virtio 备用网卡(failover), 开启后可热迁移
+	VM_METADATA_SRIOV_FAILOVER = "sriov_failover"
+
+	// 通过 qga 在虚机内下发安全组规则所用的防火墙类型, 为空表示未开启
+	VM_METADATA_QGA_FIREWALL_BACKEND = "qga_firewall_backend"
+
+	// 虚机使用的大页大小(KB)及大页内存所在 NUMA 节点, 为空表示使用宿主机默认大页且不绑定节点
+	VM_METADATA_HUGEPAGE_SIZE_KB   = "hugepage_size_kb"
+	VM_METADATA_HUGEPAGE_NUMA_NODE = "hugepage_numa_node"
+
+	HUGEPAGE_SIZE_2M_KB = 2048
+	HUGEPAGE_SIZE_1G_KB = 1048576
+
+	// 宿主机内存不足时 virtio-balloon 最多可将虚机内存回收到的下限(MB), 为空时按宿主机默认比例计算
+	VM_METADATA_BALLOON_MIN_MEM_MB = "balloon_min_mem_mb"
+
+	// 公有云分配的主机名, 内网 DNS 名称及实际所在可用区
+	VM_METADATA_PROVIDER_HOSTNAME = "provider_hostname"
+	VM_METADATA_PRIVATE_DNS_NAME  = "private_dns_name"
+	VM_METADATA_PROVIDER_ZONE     = "provider_zone"
+
+	// 监控 agent 注册 token 及心跳状态
+	VM_METADATA_MONITOR_AGENT_TOKEN     = "__monitor_agent_token"
+	VM_METADATA_MONITOR_AGENT_STATUS    = "monitor_agent_status"
+	VM_METADATA_MONITOR_AGENT_HEARTBEAT = "monitor_agent_heartbeat_at"
+	VM_METADATA_MONITOR_AGENT_VERSION   = "monitor_agent_version"
+
+	// 部署后验证结果及失败分类
+	VM_METADATA_DEPLOY_VERIFY_STATUS = "deploy_verify_status"
+	VM_METADATA_DEPLOY_VERIFY_RESULT = "deploy_verify_result"
+	VM_METADATA_DEPLOY_ERROR_CLASS   = "deploy_error_class"
+
+	// 虚机当前所在的主机快照, 新建主机快照以其为父节点
+	VM_METADATA_CURRENT_INSTANCE_SNAPSHOT = "__current_instance_snapshot"
+
+	VM_METADATA_SPICE_USBREDIR_CHANNELS = "spice_usbredir_channels"
+	VM_METADATA_VDI_MONITORS            = "vdi_monitors"
+
+	// 固定的 QEMU 版本化机型, 如 pc-i440fx-6.2, 未设置时使用 pc/q35/virt 别名
+	VM_METADATA_MACHINE_TYPE = "__machine_type"
+	// 下次冷启动时升级到的机型
+	VM_METADATA_PENDING_MACHINE_TYPE = "__pending_machine_type"
+	// 升级前的机型
+	VM_METADATA_PREV_MACHINE_TYPE = "__prev_machine_type"
+
+	// iothread 分配策略, 未设置时所有 virtio-blk 磁盘共用一个 iothread
+	VM_METADATA_IOTHREAD_POLICY = "__iothread_policy"
+	// vCPU 绑定策略, 未设置时不绑定 vCPU
+	VM_METADATA_CPU_PIN_POLICY = "__cpu_pin_policy"
+	// 虚机 NUMA 策略, 未设置时虚机只有一个 NUMA 节点
+	VM_METADATA_NUMA_POLICY = "__numa_policy"
+	// 虚机 NUMA 节点数, 未设置时按容纳 vCPU 所需的宿主机节点数自动选择
+	VM_METADATA_NUMA_NODES = "__numa_nodes"
+)
+
+const (
+	VM_IOTHREAD_POLICY_SHARED = "shared"
+	// 每个 virtio-blk 磁盘及 virtio-scsi 控制器独占一个 iothread
+	VM_IOTHREAD_POLICY_PER_DEVICE = "per-device"
+
+	// 每个 vCPU 独占一个宿主机逻辑 CPU, 优先分配在同一 NUMA 节点
+	VM_CPU_PIN_POLICY_DEDICATED = "dedicated"
+	// vCPU 在同一 NUMA 节点内未被独占的 CPU 上浮动
+	VM_CPU_PIN_POLICY_SHARED = "shared"
+
+	// 虚机 NUMA 节点与宿主机节点对应, 内存严格绑定在对应的宿主机节点
+	VM_NUMA_POLICY_STRICT = "strict"
+	// 内存优先从对应的宿主机节点分配, 不足时使用其它节点
+	VM_NUMA_POLICY_PREFERRED = "preferred"
+)
+
+var (
+	VM_IOTHREAD_POLICIES = []string{VM_IOTHREAD_POLICY_SHARED, VM_IOTHREAD_POLICY_PER_DEVICE}
+	VM_CPU_PIN_POLICIES  = []string{VM_CPU_PIN_POLICY_DEDICATED, VM_CPU_PIN_POLICY_SHARED}
+	VM_NUMA_POLICIES     = []string{VM_NUMA_POLICY_STRICT, VM_NUMA_POLICY_PREFERRED}
+
+	CONFIDENTIAL_VM_TYPES = []string{CONFIDENTIAL_VM_TYPE_SEV, CONFIDENTIAL_VM_TYPE_SEV_ES, CONFIDENTIAL_VM_TYPE_SEV_SNP, CONFIDENTIAL_VM_TYPE_TDX}
 )
 
 func Hypervisors2HostTypes(hypervisors []string) []string {
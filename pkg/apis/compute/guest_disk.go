@@ -56,8 +56,12 @@ type GuestdiskUpdateInput struct {
 
 	Driver string `json:"driver"`
 
+	// 磁盘缓存模式, 修改后需重启虚机生效
+	// enum: ["none", "writeback", "writethrough", "directsync", "unsafe"]
 	CacheMode string `json:"cache_mode"`
 
+	// 磁盘IO后端, 修改后需重启虚机生效, 不指定时随缓存模式自动选择
+	// enum: ["native", "threads", "io_uring"]
 	AioMode string `json:"aio_mode"`
 
 	Iops *int `json:"iops"`
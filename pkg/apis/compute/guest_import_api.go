@@ -14,6 +14,8 @@
 
 package compute
 
+import "yunion.io/x/jsonutils"
+
 const (
 	SERVER_META_CONVERT_FROM_ESXI = "__server_convert_from_esxi"
 	SERVER_META_CONVERTED_SERVER  = "__server_converted_to"
@@ -87,6 +89,32 @@ type SLibvirtHostConfig struct {
 	HostIp      string                 `json:"host_ip"`
 }
 
+type HostAdoptUnmanagedGuestsInput struct {
+	// 待接管虚机网卡的 mac 与 ip 对应关系, 按 mac 匹配宿主机上运行中的虚机
+	Servers []SLibvirtServerConfig `json:"servers"`
+}
+
+type HostUnmanagedGuestsOutput struct {
+	// 宿主机上运行中但未被纳管的虚机
+	Servers []SImportGuestDesc `json:"servers"`
+}
+
+type SLibvirtXmlToDescInput struct {
+	// libvirt domain xml 内容
+	Xml string `json:"xml"`
+}
+
+type SLibvirtXmlToDescOutput struct {
+	// 转换得到的虚机描述
+	Desc jsonutils.JSONObject `json:"desc"`
+	// 未能转换的配置
+	Warnings []string `json:"warnings"`
+}
+
+type ServerLibvirtXmlOutput struct {
+	Xml string `json:"xml"`
+}
+
 type SLibvirtImportConfig struct {
 	Hosts []SLibvirtHostConfig `json:"hosts"`
 }
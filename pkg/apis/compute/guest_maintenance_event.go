@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	// 云平台计划中的维护事件
+	GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED = "scheduled"
+	// 云平台已执行或事件已消失
+	GUEST_MAINTENANCE_EVENT_STATUS_COMPLETED = "completed"
+	GUEST_MAINTENANCE_EVENT_STATUS_CANCELED  = "canceled"
+	// 通过关机再开机迁离故障硬件
+	GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATING      = "remediating"
+	GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATED       = "remediated"
+	GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED = "remediate_failed"
+
+	GUEST_MAINTENANCE_EVENT_TYPE_REBOOT      = "reboot"
+	GUEST_MAINTENANCE_EVENT_TYPE_REDEPLOY    = "redeploy"
+	GUEST_MAINTENANCE_EVENT_TYPE_RETIREMENT  = "retirement"
+	GUEST_MAINTENANCE_EVENT_TYPE_MAINTENANCE = "maintenance"
+)
+
+var GUEST_MAINTENANCE_EVENT_ACTIVE_STATUS = []string{
+	GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED,
+	GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED,
+}
+
+type GuestMaintenanceEventListInput struct {
+	apis.StatusStandaloneResourceListInput
+	apis.ExternalizedResourceBaseListInput
+	ServerFilterListInput
+
+	// 事件类型
+	EventType []string `json:"event_type"`
+	// 仅列出未处理的事件
+	Active *bool `json:"active"`
+}
+
+type GuestMaintenanceEventDetails struct {
+	apis.StatusStandaloneResourceDetails
+	GuestResourceInfo
+
+	SGuestMaintenanceEvent
+}
+
+type GuestMaintenanceEventRemediateInput struct {
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+type GuestPasswordPolicyListInput struct {
+	apis.DomainLevelResourceListInput
+}
+
+type GuestPasswordPolicyCreateInput struct {
+	apis.DomainLevelResourceCreateInput
+
+	// 密码最小长度, 不小于12
+	// default: 12
+	MinLength int `json:"min_length"`
+	// 必须包含数字
+	RequireDigits *bool `json:"require_digits"`
+	// 必须包含小写字母
+	RequireLowercases *bool `json:"require_lowercases"`
+	// 必须包含大写字母
+	RequireUppercases *bool `json:"require_uppercases"`
+	// 必须包含特殊字符
+	RequirePunctuats *bool `json:"require_punctuats"`
+	// 密码有效期(天), 0表示永不过期
+	ExpireDays int `json:"expire_days"`
+	// 密码过期前多少天开始提醒
+	// default: 7
+	RemindDays int `json:"remind_days"`
+}
+
+type GuestPasswordPolicyUpdateInput struct {
+	apis.DomainLevelResourceBaseUpdateInput
+
+	MinLength         *int  `json:"min_length"`
+	RequireDigits     *bool `json:"require_digits"`
+	RequireLowercases *bool `json:"require_lowercases"`
+	RequireUppercases *bool `json:"require_uppercases"`
+	RequirePunctuats  *bool `json:"require_punctuats"`
+	ExpireDays        *int  `json:"expire_days"`
+	RemindDays        *int  `json:"remind_days"`
+}
+
+type GuestPasswordPolicyDetails struct {
+	apis.DomainLevelResourceDetails
+
+	SGuestPasswordPolicy
+}
+
+type ServerBatchRotatePasswordInput struct {
+	// 指定域, 默认为当前用户所在域
+	ProjectDomain string `json:"project_domain"`
+	// 指定虚机列表, 为空时轮换当前域下所有密码已过期的虚机
+	ServerIds []string `json:"server_ids"`
+	// 仅轮换密码已过期的虚机
+	ExpiredOnly bool `json:"expired_only"`
+	// 部署完成后是否自动开机
+	AutoStart bool `json:"auto_start"`
+}
+
+type ServerBatchRotatePasswordOutput struct {
+	// 已发起密码重置的虚机
+	Rotated []string `json:"rotated"`
+	// 跳过的虚机及原因
+	Skipped map[string]string `json:"skipped"`
+}
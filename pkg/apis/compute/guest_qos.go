@@ -0,0 +1,102 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"math"
+)
+
+const (
+	// 独享型: 不超售，按超售比折算占用宿主机容量，cgroup权重最高
+	GUEST_QOS_CLASS_GUARANTEED = "guaranteed"
+	// 突发型: 默认行为，按宿主机超售比正常超售
+	GUEST_QOS_CLASS_BURSTABLE = "burstable"
+	// 尽力而为型: CPU按一半计入容量，cgroup权重最低，资源紧张时优先让出
+	GUEST_QOS_CLASS_BEST_EFFORT = "best-effort"
+
+	// 每个vcpu对应的 cpu.shares
+	QOS_CPU_SHARES_GUARANTEED  = 2048
+	QOS_CPU_SHARES_BURSTABLE   = 1024
+	QOS_CPU_SHARES_BEST_EFFORT = 256
+)
+
+const (
+	GUEST_CGROUP_PRIORITY_HIGH   = "high"
+	GUEST_CGROUP_PRIORITY_NORMAL = "normal"
+	GUEST_CGROUP_PRIORITY_LOW    = "low"
+)
+
+var GUEST_CGROUP_PRIORITIES = []string{
+	GUEST_CGROUP_PRIORITY_HIGH,
+	GUEST_CGROUP_PRIORITY_NORMAL,
+	GUEST_CGROUP_PRIORITY_LOW,
+}
+
+var GUEST_QOS_CLASSES = []string{
+	GUEST_QOS_CLASS_GUARANTEED,
+	GUEST_QOS_CLASS_BURSTABLE,
+	GUEST_QOS_CLASS_BEST_EFFORT,
+}
+
+// GetQosClassCpuShares 返回QoS等级下每个vcpu的cgroup cpu.shares
+func GetQosClassCpuShares(qosClass string) int {
+	switch qosClass {
+	case GUEST_QOS_CLASS_GUARANTEED:
+		return QOS_CPU_SHARES_GUARANTEED
+	case GUEST_QOS_CLASS_BEST_EFFORT:
+		return QOS_CPU_SHARES_BEST_EFFORT
+	default:
+		return QOS_CPU_SHARES_BURSTABLE
+	}
+}
+
+// GetCgroupPriorityFactor 返回优先级对 cgroup 权重的放大倍数
+func GetCgroupPriorityFactor(priority string) float64 {
+	switch priority {
+	case GUEST_CGROUP_PRIORITY_HIGH:
+		return 2
+	case GUEST_CGROUP_PRIORITY_LOW:
+		return 0.5
+	default:
+		return 1
+	}
+}
+
+// GetQosClassCpuUsage 返回QoS等级下vcpu占用的宿主机超售后CPU容量
+func GetQosClassCpuUsage(qosClass string, vcpuCount int64, cmtbound float32) int64 {
+	switch qosClass {
+	case GUEST_QOS_CLASS_GUARANTEED:
+		return qosGuaranteedUsage(vcpuCount, cmtbound)
+	case GUEST_QOS_CLASS_BEST_EFFORT:
+		return (vcpuCount + 1) / 2
+	default:
+		return vcpuCount
+	}
+}
+
+// GetQosClassMemUsage 返回QoS等级下内存占用的宿主机超售后内存容量，尽力而为型内存不打折
+func GetQosClassMemUsage(qosClass string, memSizeMb int64, cmtbound float32) int64 {
+	if qosClass == GUEST_QOS_CLASS_GUARANTEED {
+		return qosGuaranteedUsage(memSizeMb, cmtbound)
+	}
+	return memSizeMb
+}
+
+func qosGuaranteedUsage(size int64, cmtbound float32) int64 {
+	if cmtbound <= 1 {
+		return size
+	}
+	return int64(math.Ceil(float64(size) * float64(cmtbound)))
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "testing"
+
+func TestGetQosClassUsage(t *testing.T) {
+	cases := []struct {
+		class    string
+		cpu      int64
+		mem      int64
+		cmtbound float32
+		wantCpu  int64
+		wantMem  int64
+	}{
+		{"", 4, 1024, 8, 4, 1024},
+		{GUEST_QOS_CLASS_BURSTABLE, 4, 1024, 8, 4, 1024},
+		{GUEST_QOS_CLASS_GUARANTEED, 4, 1024, 8, 32, 8192},
+		{GUEST_QOS_CLASS_GUARANTEED, 3, 1000, 1.5, 5, 1500},
+		{GUEST_QOS_CLASS_GUARANTEED, 4, 1024, 0.5, 4, 1024},
+		{GUEST_QOS_CLASS_BEST_EFFORT, 3, 1024, 8, 2, 1024},
+		{GUEST_QOS_CLASS_BEST_EFFORT, 1, 1024, 8, 1, 1024},
+	}
+	for _, c := range cases {
+		if got := GetQosClassCpuUsage(c.class, c.cpu, c.cmtbound); got != c.wantCpu {
+			t.Errorf("cpu usage of %q %d x %v: want %d got %d", c.class, c.cpu, c.cmtbound, c.wantCpu, got)
+		}
+		if got := GetQosClassMemUsage(c.class, c.mem, c.cmtbound); got != c.wantMem {
+			t.Errorf("mem usage of %q %d x %v: want %d got %d", c.class, c.mem, c.cmtbound, c.wantMem, got)
+		}
+	}
+	if GetQosClassCpuShares(GUEST_QOS_CLASS_GUARANTEED) <= GetQosClassCpuShares("") ||
+		GetQosClassCpuShares(GUEST_QOS_CLASS_BEST_EFFORT) >= GetQosClassCpuShares("") {
+		t.Errorf("unexpected cpu shares order")
+	}
+}
@@ -94,6 +94,8 @@ type GuestnetworkJsonDesc struct {
 	Vectors    *int                 `json:"vectors"`
 	Vlan       int                  `json:"vlan"`
 	Bw         int                  `json:"bw"`
+	BwIngress  int                  `json:"bw_ingress"`
+	BwEgress   int                  `json:"bw_egress"`
 	Mtu        int                  `json:"mtu"`
 	Index      int8                 `json:"index"`
 	VirtualIps []string             `json:"virtual_ips"`
@@ -16,10 +16,15 @@ package compute
 
 import (
 	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/apis"
 	"yunion.io/x/onecloud/pkg/apis/billing"
@@ -446,6 +451,13 @@ type GuestMigrateInput struct {
 	IsRescueMode bool   `json:"rescue_mode"`
 }
 
+type ServerColdMigrateWithDevicesInput struct {
+	// 指定期望的迁移目标宿主机, 需有足够的同型号空闲直通设备
+	PreferHost string `json:"prefer_host"`
+	// 迁移完成后是否开机, 默认保持迁移前的运行状态
+	AutoStart *bool `json:"auto_start"`
+}
+
 type GuestLiveMigrateInput struct {
 	// 指定期望的迁移目标宿主机
 	PreferHost string `json:"prefer_host"`
@@ -461,6 +473,62 @@ type GuestLiveMigrateInput struct {
 	// 快速完成，内存同步一定周期后调整 downtime
 	QuicklyFinish         *bool `json:"quickly_finish"`
 	KeepDestGuestOnFailed *bool `json:"keep_dest_guest_on_failed"`
+
+	LiveMigrateTuningInput
+}
+
+const (
+	LIVE_MIGRATE_COMPRESSION_NONE = "none"
+	LIVE_MIGRATE_COMPRESSION_ZLIB = "zlib"
+	LIVE_MIGRATE_COMPRESSION_ZSTD = "zstd"
+)
+
+var LIVE_MIGRATE_COMPRESSIONS = []string{
+	LIVE_MIGRATE_COMPRESSION_NONE,
+	LIVE_MIGRATE_COMPRESSION_ZLIB,
+	LIVE_MIGRATE_COMPRESSION_ZSTD,
+}
+
+// 热迁移调优参数, 未指定的项使用宿主机配置
+type LiveMigrateTuningInput struct {
+	// multifd 并发通道数, 0 表示不启用 multifd
+	MultifdChannels *int `json:"multifd_channels"`
+	// multifd 压缩算法
+	// enum: ["none", "zlib", "zstd"]
+	MultifdCompression string `json:"multifd_compression"`
+	// 是否启用 auto-converge, 内存脏页过快时对虚机 CPU 限流
+	AutoConverge *bool `json:"auto_converge"`
+	// auto-converge 初始 CPU 限流百分比
+	CpuThrottleInitial *int `json:"cpu_throttle_initial"`
+	// auto-converge 每轮递增的 CPU 限流百分比
+	CpuThrottleIncrement *int `json:"cpu_throttle_increment"`
+	// 内存脏页同步超过该轮数仍未完成时切换为 postcopy, 0 表示不启用 postcopy
+	// 启用 postcopy 时不使用 multifd, 本地盘迁移不支持 postcopy
+	PostcopySwitchoverRounds *int `json:"postcopy_switchover_rounds"`
+}
+
+func (input LiveMigrateTuningInput) Validate() error {
+	if input.MultifdChannels != nil && (*input.MultifdChannels < 0 || *input.MultifdChannels > 255) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "multifd_channels should be in range 0-255")
+	}
+	if len(input.MultifdCompression) > 0 && !utils.IsInStringArray(input.MultifdCompression, LIVE_MIGRATE_COMPRESSIONS) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "multifd_compression should be one of %s", LIVE_MIGRATE_COMPRESSIONS)
+	}
+	if input.CpuThrottleInitial != nil && (*input.CpuThrottleInitial < 1 || *input.CpuThrottleInitial > 99) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "cpu_throttle_initial should be in range 1-99")
+	}
+	if input.CpuThrottleIncrement != nil && (*input.CpuThrottleIncrement < 1 || *input.CpuThrottleIncrement > 99) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "cpu_throttle_increment should be in range 1-99")
+	}
+	if input.PostcopySwitchoverRounds != nil && *input.PostcopySwitchoverRounds < 0 {
+		return errors.Wrapf(httperrors.ErrInputParameter, "postcopy_switchover_rounds should not be negative")
+	}
+	return nil
+}
+
+func (input LiveMigrateTuningInput) IsEmpty() bool {
+	return input.MultifdChannels == nil && len(input.MultifdCompression) == 0 && input.AutoConverge == nil &&
+		input.CpuThrottleInitial == nil && input.CpuThrottleIncrement == nil && input.PostcopySwitchoverRounds == nil
 }
 
 type GuestSetSecgroupInput struct {
@@ -771,8 +839,14 @@ type GuestJsonDesc struct {
 	IsVolatileHost bool   `json:"is_volatile_host"`
 	HostId         string `json:"host_id"`
 
+	// QoS等级, 决定cgroup cpu权重
+	QosClass string `json:"qos_class"`
+
 	IsolatedDevices []*IsolatedDeviceJsonDesc `json:"isolated_devices"`
 
+	// kvm virtio-fs 共享目录
+	SharedDirs []*GuestSharedDirJsonDesc `json:"shared_dirs"`
+
 	Domain string `json:"domain"`
 
 	Nics  []*GuestnetworkJsonDesc `json:"nics"`
@@ -825,6 +899,11 @@ type GuestJsonDesc struct {
 	EncryptKeyId string `json:"encrypt_key_id,omitempty"`
 
 	IsDaemon bool `json:"is_daemon"`
+
+	// spice usb 重定向通道数
+	SpiceUsbredirChannels *int `json:"spice_usbredir_channels,omitempty"`
+	// 显示器数量, 仅 qxl/virtio 显卡支持多显示器
+	VdiMonitors int `json:"vdi_monitors,omitempty"`
 }
 
 type ServerSetBootIndexInput struct {
@@ -849,6 +928,14 @@ type ServerChangeDiskStorageInternalInput struct {
 	TargetDiskDesc *GuestdiskJsonDesc `json:"target_disk_desc"`
 }
 
+type ServerBlockMirrorProgressInput struct {
+	DiskId string `json:"disk_id"`
+	// 已同步数据百分比
+	Progress float32 `json:"progress"`
+	// 同步速度
+	SpeedMbps float64 `json:"speed_mbps"`
+}
+
 type ServerSetExtraOptionInput struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -914,6 +1001,24 @@ type ServerCPUSetResp struct{}
 
 type ServerCPUSetRemoveInput struct{}
 
+type ServerCgroupPriorityInput struct {
+	// 调度优先级, 与QoS等级共同决定 cpu.weight/io.weight
+	// enum: high, normal, low
+	Priority string `json:"priority"`
+	// 直接指定 cgroup v2 cpu.weight, 优先于 priority
+	CpuWeight int `json:"cpu_weight"`
+	// 直接指定 cgroup v2 io.weight, 优先于 priority
+	IoWeight int `json:"io_weight"`
+}
+
+type ServerCgroupPriorityOutput struct {
+	CgroupVersion int    `json:"cgroup_version"`
+	Slice         string `json:"slice"`
+	CpuShares     int    `json:"cpu_shares"`
+	CpuWeight     int    `json:"cpu_weight"`
+	IoWeight      int    `json:"io_weight"`
+}
+
 type ServerCPUSetRemoveResp struct {
 	Done  bool   `json:"done"`
 	Error string `json:"error"`
@@ -927,6 +1032,307 @@ type ServerGetCPUSetCoresResp struct {
 	HostUsedCores []int `json:"host_used_cores"`
 }
 
+// virtio-fs 的 tag 长度上限为 36 字节
+var sharedDirTagReg = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,36}$`)
+
+type GuestSharedDirJsonDesc struct {
+	// 虚拟机内挂载时使用的标签, mount -t virtiofs <tag> <dir>
+	Tag string `json:"tag"`
+	// 宿主机上的共享目录, 必须为绝对路径
+	HostPath string `json:"host_path"`
+	// 是否只读共享
+	ReadOnly bool `json:"read_only"`
+}
+
+type ServerAttachSharedDirInput struct {
+	GuestSharedDirJsonDesc
+}
+
+func (o ServerAttachSharedDirInput) Validate() error {
+	if !sharedDirTagReg.MatchString(o.Tag) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "invalid tag %q, only 1-36 letters, digits, '-' and '_' allowed", o.Tag)
+	}
+	if !filepath.IsAbs(o.HostPath) {
+		return errors.Wrapf(httperrors.ErrInputParameter, "host_path %q must be an absolute path", o.HostPath)
+	}
+	if p := filepath.Clean(o.HostPath); p != o.HostPath || p == "/" || strings.ContainsAny(p, " \t\n'\"`$;&|<>\\") {
+		return errors.Wrapf(httperrors.ErrInputParameter, "invalid host_path %q", o.HostPath)
+	}
+	return nil
+}
+
+type ServerDetachSharedDirInput struct {
+	// 要移除的共享目录标签
+	Tag string `json:"tag"`
+}
+
+func (o ServerDetachSharedDirInput) Validate() error {
+	if len(o.Tag) == 0 {
+		return errors.Wrap(httperrors.ErrMissingParameter, "tag")
+	}
+	return nil
+}
+
+type ServerSetTpmInput struct {
+	// 是否启用虚拟 TPM 2.0 设备
+	Enable bool `json:"enable"`
+}
+
+const (
+	WATCHDOG_MODEL_I6300ESB = "i6300esb"
+	WATCHDOG_MODEL_IB700    = "ib700"
+
+	WATCHDOG_ACTION_RESET    = "reset"
+	WATCHDOG_ACTION_POWEROFF = "poweroff"
+	WATCHDOG_ACTION_SHUTDOWN = "shutdown"
+	WATCHDOG_ACTION_PAUSE    = "pause"
+	WATCHDOG_ACTION_NONE     = "none"
+)
+
+var (
+	WATCHDOG_MODELS  = []string{WATCHDOG_MODEL_I6300ESB, WATCHDOG_MODEL_IB700}
+	WATCHDOG_ACTIONS = []string{WATCHDOG_ACTION_RESET, WATCHDOG_ACTION_POWEROFF, WATCHDOG_ACTION_SHUTDOWN, WATCHDOG_ACTION_PAUSE, WATCHDOG_ACTION_NONE}
+)
+
+type ServerSetWatchdogInput struct {
+	// 看门狗设备型号, 为空时移除看门狗设备
+	// enum: i6300esb, ib700
+	Model string `json:"model"`
+	// 看门狗超时后的动作, 默认 reset
+	// enum: reset, poweroff, shutdown, pause, none
+	Action string `json:"action"`
+	// 看门狗触发时是否通知虚机所有者
+	Notify bool `json:"notify"`
+}
+
+type ServerSetHugepageInput struct {
+	// 大页大小, 单位KB, 支持 2048(2M) 和 1048576(1G), 0 表示使用宿主机默认大页, 重启后生效
+	PageSizeKb int `json:"page_size_kb"`
+	// 大页内存所在的宿主机 NUMA 节点, 为空或小于0表示不绑定
+	NumaNode *int `json:"numa_node"`
+}
+
+type ServerSetBalloonInput struct {
+	// 宿主机内存不足时虚机内存最多可被回收到的下限(MB), 0 表示使用宿主机默认比例
+	MinMemMb int `json:"min_mem_mb"`
+}
+
+type ServerSetNicBandwidthInput struct {
+	// 网卡IP地址，与mac/index三选一
+	IpAddr string `json:"ip_addr"`
+	// 网卡MAC地址
+	Mac string `json:"mac"`
+	// 网卡序号
+	Index *int64 `json:"index"`
+
+	// 入方向(宿主机到虚机)限速，单位mbps，0表示沿用网卡带宽
+	BwIngress *int `json:"bw_ingress"`
+	// 出方向(虚机到宿主机)限速，单位mbps，0表示沿用网卡带宽
+	BwEgress *int `json:"bw_egress"`
+}
+
+type ServerSetSriovFailoverInput struct {
+	// 是否为 SR-IOV 网卡配对 virtio 备用网卡, 重启后生效
+	Enable bool `json:"enable"`
+}
+
+type ServerDiskIoThrottle struct {
+	// 带宽限制, 单位MB/s, 0 表示不限制
+	Bps int `json:"bps"`
+	// IOPS 限制, 0 表示不限制
+	Iops int `json:"iops"`
+}
+
+type ServerIoThrottleInput struct {
+	// 所有磁盘的带宽限制, 单位MB/s
+	Bps *int `json:"bps"`
+	// 所有磁盘的 IOPS 限制
+	Iops *int `json:"iops"`
+
+	// 按磁盘设置限速, key 为磁盘ID或名称, 未指定的磁盘保持不变
+	Disks map[string]ServerDiskIoThrottle `json:"disks"`
+}
+
+type ServerSetVirtioMemInput struct {
+	// 是否启用 virtio-mem 内存在线扩缩, 下次启动生效
+	Enable bool `json:"enable"`
+}
+
+type ServerSetSecureBootInput struct {
+	// 是否启用 UEFI 安全启动, 下次启动生效
+	Enable bool `json:"enable"`
+}
+
+const (
+	BOOT_DEVICE_DISK    = "disk"
+	BOOT_DEVICE_CDROM   = "cdrom"
+	BOOT_DEVICE_NETWORK = "network"
+)
+
+// 启动设备与 boot_order 字符的对应关系
+var bootDeviceOrderChars = map[string]string{
+	BOOT_DEVICE_DISK:    "c",
+	BOOT_DEVICE_CDROM:   "d",
+	BOOT_DEVICE_NETWORK: "n",
+}
+
+// boot_order 由 c(disk), d(cdrom), n(network) 组成, 字符不可重复
+func ValidateBootOrder(order string) error {
+	if len(order) == 0 {
+		return errors.Wrap(httperrors.ErrInputParameter, "empty boot order")
+	}
+	for i, c := range order {
+		if !strings.ContainsRune("cdn", c) {
+			return errors.Wrapf(httperrors.ErrInputParameter, "invalid boot device %q in boot order %q", c, order)
+		}
+		if strings.ContainsRune(order[i+1:], c) {
+			return errors.Wrapf(httperrors.ErrInputParameter, "duplicate boot device %q in boot order %q", c, order)
+		}
+	}
+	return nil
+}
+
+func BootDeviceToOrderChar(device string) (string, error) {
+	c, ok := bootDeviceOrderChars[device]
+	if !ok {
+		return "", errors.Wrapf(httperrors.ErrInputParameter, "invalid boot device %q, should be one of disk, cdrom, network", device)
+	}
+	return c, nil
+}
+
+// 将启动设备调整为第一启动项, 其余设备保持原有顺序
+func PrependBootOrder(order string, c string) string {
+	return c + strings.ReplaceAll(order, c, "")
+}
+
+type ServerSetBootOrderInput struct {
+	// 启动设备顺序
+	// enum: ["disk", "cdrom", "network"]
+	Devices []string `json:"devices"`
+
+	// 由设备顺序转换得到的 boot_order
+	// swagger: ignore
+	BootOrder string `json:"boot_order"`
+}
+
+func (input *ServerSetBootOrderInput) Validate() error {
+	if len(input.Devices) == 0 {
+		return errors.Wrap(httperrors.ErrInputParameter, "empty devices")
+	}
+	order := ""
+	for _, dev := range input.Devices {
+		c, err := BootDeviceToOrderChar(dev)
+		if err != nil {
+			return err
+		}
+		order += c
+	}
+	if err := ValidateBootOrder(order); err != nil {
+		return err
+	}
+	input.BootOrder = order
+	return nil
+}
+
+type ServerBootOnceInput struct {
+	// 仅下一次启动使用的设备, 之后恢复原启动顺序
+	// enum: ["disk", "cdrom", "network"]
+	Device string `json:"device"`
+	// 运行中的虚拟机是否立即重启以生效
+	Restart bool `json:"restart"`
+}
+
+type ServerPxeBootInput struct {
+	// iPXE 链式加载地址, 支持 http, https, tftp
+	// example: http://10.168.222.100:8080/boot.ipxe
+	ChainloadUrl string `json:"chainload_url"`
+	// 仅下一次启动从网络引导, 否则将网络设置为第一启动设备
+	Once bool `json:"once"`
+	// 运行中的虚拟机是否立即重启以生效
+	Restart bool `json:"restart"`
+	// 关闭网络引导, 恢复磁盘优先启动
+	Disable bool `json:"disable"`
+}
+
+func (input *ServerPxeBootInput) Validate() error {
+	if input.Disable {
+		return nil
+	}
+	if len(input.ChainloadUrl) == 0 {
+		return errors.Wrap(httperrors.ErrMissingParameter, "chainload_url")
+	}
+	u, err := url.Parse(input.ChainloadUrl)
+	if err != nil {
+		return errors.Wrapf(httperrors.ErrInputParameter, "invalid chainload_url %q: %v", input.ChainloadUrl, err)
+	}
+	if !utils.IsInStringArray(u.Scheme, []string{"http", "https", "tftp"}) || len(u.Host) == 0 {
+		return errors.Wrapf(httperrors.ErrInputParameter, "invalid chainload_url %q, should be http, https or tftp url", input.ChainloadUrl)
+	}
+	return nil
+}
+
+type ServerSetClockPolicyInput struct {
+	// RTC 基准时间, 为空时 utc
+	// enum: ["utc", "localtime"]
+	Base string `json:"base,omitempty"`
+	// RTC 时钟漂移修正, 为空时 Windows 虚机为 slew, 其他为 none
+	// enum: ["none", "slew"]
+	Driftfix string `json:"driftfix,omitempty"`
+	// 是否启用 HPET, 为空时 Windows 虚机关闭, 其他保持 qemu 默认
+	// enum: ["on", "off"]
+	Hpet string `json:"hpet,omitempty"`
+}
+
+type ServerSetConfidentialVmInput struct {
+	// 机密计算类型, 为空表示关闭, 下次启动生效
+	// enum: ["sev", "sev-es", "sev-snp", "tdx"]
+	Type string `json:"type"`
+}
+
+type ServerConfidentialVmAttestationOutput struct {
+	Type string `json:"type"`
+	// base64 编码的 SEV 启动度量值, 用于校验虚机初始内存及固件
+	Measurement string `json:"measurement"`
+}
+
+type ServerSetVdiOptionsInput struct {
+	// spice usb 重定向通道数, 0 表示关闭 usb 重定向, 下次启动生效
+	SpiceUsbredirChannels *int `json:"spice_usbredir_channels"`
+	// 显示器数量, 1-4, 需要 spice 及 qxl/virtio 显卡, 下次启动生效
+	VdiMonitors *int `json:"vdi_monitors"`
+}
+
+type ServerUpgradeMachineTypeInput struct {
+	// 升级后的 QEMU 版本化机型, 须与当前机型同一系列且版本更新, 下次冷启动生效
+	// example: pc-q35-6.2
+	MachineType string `json:"machine_type"`
+}
+
+type ServerSetIothreadPolicyInput struct {
+	// iothread 分配策略, 为空时恢复默认, 下次启动生效
+	// enum: shared, per-device
+	Policy string `json:"policy"`
+}
+
+type ServerSetCpuPinPolicyInput struct {
+	// vCPU 绑定策略, 为空时取消绑定, 运行中的虚机同步后生效
+	// enum: dedicated, shared
+	Policy string `json:"policy"`
+}
+
+type ServerSetNumaPolicyInput struct {
+	// NUMA 策略, 为空时恢复单节点, 下次启动生效
+	// enum: strict, preferred
+	Policy string `json:"policy"`
+	// 虚机 NUMA 节点数, 0 表示按 vCPU 数自动选择
+	Nodes int `json:"nodes"`
+}
+
+type ServerResizeMemoryInput struct {
+	// 调整后的内存大小, 单位MB, 不能小于启动时内存
+	VmemSize int `json:"vmem_size"`
+}
+
 type ServerMonitorInput struct {
 	COMMAND string
 	QMP     bool
@@ -946,6 +1352,158 @@ type ServerQgaCommandInput struct {
 	Command string
 }
 
+type ServerQgaExecInput struct {
+	// 虚机内可执行文件路径或名称
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+	// 标准输入内容
+	Input string `json:"input"`
+	// 等待命令结束的超时时间, 单位秒, 默认 30 秒
+	Timeout int `json:"timeout"`
+}
+
+type ServerQgaExecOutput struct {
+	Pid      int    `json:"pid"`
+	Exited   bool   `json:"exited"`
+	Exitcode int    `json:"exitcode"`
+	Signal   int    `json:"signal"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	// 输出超出 qga 缓冲区大小时被截断
+	OutTruncated bool `json:"out_truncated"`
+	ErrTruncated bool `json:"err_truncated"`
+}
+
+type ServerQgaFileWriteInput struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Content 已经过 base64 编码
+	Base64 bool `json:"base64"`
+	// 追加写入, 默认覆盖
+	Append bool `json:"append"`
+}
+
+type ServerQgaFileReadInput struct {
+	Path string `json:"path"`
+	// 最多读取的字节数, 默认 1MB, 最大 16MB
+	MaxBytes int `json:"max_bytes"`
+}
+
+type ServerQgaFileReadOutput struct {
+	// base64 编码的文件内容
+	Content string `json:"content"`
+	Size    int    `json:"size"`
+	// 文件未读取完整
+	Truncated bool `json:"truncated"`
+}
+
+const (
+	QGA_FSFREEZE_FREEZE = "freeze"
+	QGA_FSFREEZE_THAW   = "thaw"
+	QGA_FSFREEZE_STATUS = "status"
+)
+
+type ServerQgaFsfreezeInput struct {
+	// freeze|thaw|status
+	Action string `json:"action"`
+}
+
+type ServerQgaFsfreezeOutput struct {
+	// frozen|thawed
+	Status string `json:"status"`
+	// 本次冻结或解冻的文件系统数量
+	Count int `json:"count"`
+}
+
+type ServerQgaIpAddress struct {
+	Type   string `json:"type"`
+	Ip     string `json:"ip"`
+	Prefix int    `json:"prefix"`
+}
+
+type ServerQgaNetworkInterface struct {
+	Name        string               `json:"name"`
+	Mac         string               `json:"mac"`
+	IpAddresses []ServerQgaIpAddress `json:"ip_addresses"`
+}
+
+type ServerQgaNetworkInterfacesOutput struct {
+	Interfaces []ServerQgaNetworkInterface `json:"interfaces"`
+}
+
+const (
+	MONITOR_AGENT_STATUS_PENDING = "pending"
+	MONITOR_AGENT_STATUS_ONLINE  = "online"
+	MONITOR_AGENT_STATUS_OFFLINE = "offline"
+
+	// 监控 agent token 及配置在虚机内的存放路径
+	MONITOR_AGENT_TOKEN_PATH        = "/etc/telegraf/.onecloud_agent_token"
+	MONITOR_AGENT_CONF_PATH         = "/etc/telegraf/telegraf.conf"
+	MONITOR_AGENT_WINDOWS_CONF_PATH = "/Program Files/Telegraf/telegraf.conf"
+)
+
+type ServerMonitorAgentHeartbeatInput struct {
+	// 部署时注入的注册 token
+	Token string `json:"token"`
+	// agent 版本
+	Version string `json:"version"`
+}
+
+type ServerMonitorAgentOutput struct {
+	Enabled       bool      `json:"enabled"`
+	Deployed      bool      `json:"deployed"`
+	Status        string    `json:"status"`
+	Version       string    `json:"version"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+const (
+	DEPLOY_VERIFY_CHECK_PORT       = "port"
+	DEPLOY_VERIFY_CHECK_AGENT      = "agent"
+	DEPLOY_VERIFY_CHECK_CLOUD_INIT = "cloud_init"
+
+	DEPLOY_VERIFY_STATUS_PENDING = "pending"
+	DEPLOY_VERIFY_STATUS_PASSED  = "passed"
+	DEPLOY_VERIFY_STATUS_FAILED  = "failed"
+	DEPLOY_VERIFY_STATUS_SKIPPED = "skipped"
+
+	// 部署失败分类
+	DEPLOY_ERROR_PORT_UNREACHABLE   = "port_unreachable"
+	DEPLOY_ERROR_AGENT_UNAVAILABLE  = "agent_unavailable"
+	DEPLOY_ERROR_CLOUD_INIT_TIMEOUT = "cloud_init_timeout"
+	DEPLOY_ERROR_CLOUD_INIT_FAILED  = "cloud_init_failed"
+
+	// cloud-init 完成标记及执行结果
+	CLOUD_INIT_BOOT_FINISHED_PATH = "/var/lib/cloud/instance/boot-finished"
+	CLOUD_INIT_RESULT_PATH        = "/var/lib/cloud/data/result.json"
+)
+
+type SDeployVerifyCheck struct {
+	// enum: ["port", "agent", "cloud_init"]
+	Check string `json:"check"`
+	// enum: ["pending", "passed", "failed", "skipped"]
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type ServerDeployVerifyOutput struct {
+	// enum: ["pending", "passed", "failed"]
+	Status string `json:"status"`
+	// 失败分类, 验证通过时为空
+	ErrorClass string               `json:"error_class,omitempty"`
+	Checks     []SDeployVerifyCheck `json:"checks"`
+	VerifiedAt time.Time            `json:"verified_at"`
+}
+
+type ServerSetQgaFirewallInput struct {
+	// 是否通过 qga 在虚机内下发安全组规则
+	Enable bool `json:"enable"`
+	// 虚机内防火墙类型, 默认 Linux 为 iptables, Windows 为 windows
+	// enum: iptables, nftables, windows
+	Backend string `json:"backend"`
+}
+
 type ServerSetPasswordInput struct {
 	Username string
 	Password string
@@ -969,3 +1527,10 @@ type ServerSetLiveMigrateParamsInput struct {
 	MaxBandwidthMB  *int64
 	DowntimeLimitMS *int64
 }
+
+type ServerTransferOwnershipInput struct {
+	apis.PerformChangeProjectOwnerInput
+
+	// 不迁移仅指向该虚拟机IP的DNS记录
+	SkipDnsRecords bool `json:"skip_dns_records"`
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+)
+
+func TestValidateBootOrder(t *testing.T) {
+	for order, valid := range map[string]bool{
+		"cdn": true,
+		"n":   true,
+		"dc":  true,
+		"":    false,
+		"cc":  false,
+		"cx":  false,
+	} {
+		err := ValidateBootOrder(order)
+		if valid != (err == nil) {
+			t.Errorf("boot order %q: valid %v, err %v", order, valid, err)
+		}
+	}
+}
+
+func TestServerSetBootOrderInputValidate(t *testing.T) {
+	input := ServerSetBootOrderInput{Devices: []string{BOOT_DEVICE_NETWORK, BOOT_DEVICE_DISK}}
+	if err := input.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if input.BootOrder != "nc" {
+		t.Errorf("boot order %q, want nc", input.BootOrder)
+	}
+	input = ServerSetBootOrderInput{Devices: []string{BOOT_DEVICE_DISK, "floppy"}}
+	if err := input.Validate(); err == nil {
+		t.Error("expect error for invalid device")
+	}
+	input = ServerSetBootOrderInput{Devices: []string{BOOT_DEVICE_DISK, BOOT_DEVICE_DISK}}
+	if err := input.Validate(); err == nil {
+		t.Error("expect error for duplicate device")
+	}
+}
+
+func TestPrependBootOrder(t *testing.T) {
+	for _, c := range []struct {
+		order string
+		dev   string
+		want  string
+	}{
+		{"cdn", "n", "ncd"},
+		{"ncd", "c", "cnd"},
+		{"dc", "n", "ndc"},
+		{"", "n", "n"},
+	} {
+		if got := PrependBootOrder(c.order, c.dev); got != c.want {
+			t.Errorf("PrependBootOrder(%q, %q) = %q, want %q", c.order, c.dev, got, c.want)
+		}
+	}
+}
+
+func TestServerPxeBootInputValidate(t *testing.T) {
+	for u, valid := range map[string]bool{
+		"http://10.168.222.100:8080/boot.ipxe": true,
+		"https://boot.example.com/menu.ipxe":   true,
+		"tftp://10.168.222.100/undionly.kpxe":  true,
+		"":                                     false,
+		"ftp://10.168.222.100/boot.ipxe":       false,
+		"http:///boot.ipxe":                    false,
+	} {
+		input := ServerPxeBootInput{ChainloadUrl: u}
+		err := input.Validate()
+		if valid != (err == nil) {
+			t.Errorf("chainload url %q: valid %v, err %v", u, valid, err)
+		}
+	}
+	input := ServerPxeBootInput{Disable: true}
+	if err := input.Validate(); err != nil {
+		t.Errorf("disable should not require chainload url: %v", err)
+	}
+}
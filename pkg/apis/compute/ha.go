@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "time"
+
+const (
+	HA_ROLE_ACTIVE  = "active"
+	HA_ROLE_STANDBY = "standby"
+)
+
+type HaLeaderInfo struct {
+	// 主节点所在站点
+	Site string `json:"site"`
+	// 主节点主机名
+	Hostname string `json:"hostname"`
+}
+
+type HaStatusOutput struct {
+	// 是否启用了主备模式
+	Enabled bool `json:"enabled"`
+	// 本节点所在站点
+	Site string `json:"site"`
+	// 本节点主机名
+	Hostname string `json:"hostname"`
+	// 本节点角色
+	// enum: active, standby
+	Role string `json:"role"`
+	// 进入当前角色的时间
+	Since time.Time `json:"since"`
+	// 备节点是否拒绝写请求
+	StandbyReadOnly bool `json:"standby_read_only"`
+	// 当前主节点
+	Leader *HaLeaderInfo `json:"leader"`
+	// 最近一次升主时以失败结束的中断任务数
+	InterruptedTasks int `json:"interrupted_tasks"`
+}
@@ -508,6 +508,8 @@ type SHostPingInput struct {
 	WithData bool `json:"with_data"`
 
 	MemoryUsedMb int `json:"memory_used_mb"`
+	// virtio-balloon 回收的虚机内存
+	BalloonReclaimedMb int `json:"balloon_reclaimed_mb"`
 
 	RootPartitionUsedCapacityMb int `json:"root_partition_used_capacity_mb"`
 
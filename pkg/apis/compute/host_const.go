@@ -165,3 +165,8 @@ const (
 const (
 	HOSTMETA_RESERVED_CPUS_INFO = "reserved_cpus_info"
 )
+
+const (
+	// 宿主机通过 virtio-balloon 从虚机回收的内存(MB), 由 host ping 上报
+	HOSTMETA_BALLOON_RECLAIMED_MB = "balloon_reclaimed_mb"
+)
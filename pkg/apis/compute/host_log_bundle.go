@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	HOST_LOG_BUNDLE_STATUS_COLLECTING     = "collecting"
+	HOST_LOG_BUNDLE_STATUS_COLLECT_FAILED = "collect_failed"
+	HOST_LOG_BUNDLE_STATUS_READY          = "ready"
+
+	// 默认收集最近24小时的日志
+	HOST_LOG_BUNDLE_DEFAULT_SINCE_HOURS = 24
+	// 下载链接默认及最长有效期, 单位秒
+	HOST_LOG_BUNDLE_DEFAULT_URL_EXPIRE = 3600
+	HOST_LOG_BUNDLE_MAX_URL_EXPIRE     = 7 * 86400
+)
+
+// 宿主机诊断日志包, 由宿主机收集并脱敏后上传到对象存储, 替代登录宿主机抓取日志
+type HostLogBundleCreateInput struct {
+	apis.StatusStandaloneResourceCreateInput
+
+	// 宿主机名称或Id
+	// required: true
+	HostResourceInput
+
+	// 收集该虚机的 qemu 日志及描述文件, 虚机需位于该宿主机
+	ServerId string `json:"server_id"`
+	// 是否收集 openvswitch 网桥及流表信息, 默认收集
+	IncludeOvs *bool `json:"include_ovs"`
+	// 收集最近多少小时的日志, 默认24小时
+	SinceHours int `json:"since_hours"`
+
+	// 存放日志包的存储桶名称或Id
+	// required: true
+	BucketId string `json:"bucket_id"`
+	// 日志包的对象键前缀
+	KeyPrefix string `json:"key_prefix"`
+}
+
+type HostLogBundleListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	HostFilterListInput
+
+	ServerId string `json:"server_id"`
+	BucketId string `json:"bucket_id"`
+}
+
+type HostLogBundleDetails struct {
+	apis.StatusStandaloneResourceDetails
+	HostResourceInfo
+
+	SHostLogBundle
+
+	Server string `json:"server"`
+	Bucket string `json:"bucket"`
+}
+
+type HostLogBundleDownloadUrlInput struct {
+	// 下载链接有效期, 单位秒, 默认1小时, 最长7天
+	ExpireSeconds int `json:"expire_seconds"`
+}
+
+type HostLogBundleDownloadUrlOutput struct {
+	Url      string    `json:"url"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// 下发给宿主机的收集参数
+type HostLogBundleCollectInput struct {
+	ServerId   string    `json:"server_id"`
+	IncludeOvs bool      `json:"include_ovs"`
+	Since      time.Time `json:"since"`
+	// 预签名的上传地址, 宿主机通过 HTTP PUT 上传日志包
+	UploadUrl string `json:"upload_url"`
+}
+
+type HostLogBundleCollectOutput struct {
+	SizeBytes int64 `json:"size_bytes"`
+	// 日志包内的文件列表
+	Files []string `json:"files"`
+}
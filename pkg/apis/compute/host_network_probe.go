@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+)
+
+// 宿主机之间的网络探测结果
+type HostNetworkProbeResult struct {
+	// 对端宿主机
+	PeerHostId string `json:"peer_host_id"`
+	// 平均时延(毫秒)
+	LatencyMs float64 `json:"latency_ms"`
+	// 时延抖动(毫秒)
+	JitterMs float64 `json:"jitter_ms"`
+	// 丢包率, 0-1
+	LossRate float64 `json:"loss_rate"`
+	// 带宽(Mbps), 未开启带宽探测时为0
+	BandwidthMbps float64 `json:"bandwidth_mbps"`
+}
+
+type HostNetworkProbeReportInput struct {
+	Results []HostNetworkProbeResult `json:"results"`
+}
+
+// 宿主机需要探测的对端
+type HostNetworkProbeTarget struct {
+	HostId        string `json:"host_id"`
+	Name          string `json:"name"`
+	ManagerUri    string `json:"manager_uri"`
+	ZoneId        string `json:"zone_id"`
+	CloudregionId string `json:"cloudregion_id"`
+}
+
+type HostNetworkProbeTargetsOutput struct {
+	Targets []HostNetworkProbeTarget `json:"targets"`
+}
+
+type HostNetworkProbeDetails struct {
+	HostNetworkProbeResult
+
+	PeerHostName      string    `json:"peer_host_name"`
+	PeerCloudregionId string    `json:"peer_cloudregion_id"`
+	ProbedAt          time.Time `json:"probed_at"`
+}
+
+type HostNetworkProbesOutput struct {
+	Probes []HostNetworkProbeDetails `json:"probes"`
+}
@@ -80,3 +80,33 @@ type InstanceSnapshotListInput struct {
 	// 包含内存快照
 	WithMemory *bool `json:"with_memory"`
 }
+
+type InstanceSnapshotTreeNode struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	WithMemory bool      `json:"with_memory"`
+	CreatedAt  time.Time `json:"created_at"`
+	// 是否为虚机当前所在的快照
+	Current bool `json:"current"`
+
+	Children []*InstanceSnapshotTreeNode `json:"children"`
+}
+
+type ServerSnapshotTreeOutput struct {
+	// 当前所在的主机快照Id
+	Current string `json:"current"`
+
+	Snapshots []*InstanceSnapshotTreeNode `json:"snapshots"`
+}
+
+type ServerSnapshotGcRequest struct {
+	// 磁盘Id 对应仍在使用的磁盘快照Id
+	Disks map[string][]string `json:"disks"`
+	// 仍在使用的内存快照(主机快照)Id
+	MemorySnapshots []string `json:"memory_snapshots"`
+}
+
+type ServerSnapshotGcOutput struct {
+	Removed []string `json:"removed"`
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	INTEGRITY_VERIFY_OK        = "ok"
+	INTEGRITY_VERIFY_CORRUPTED = "corrupted"
+	INTEGRITY_VERIFY_MISSING   = "missing"
+	INTEGRITY_VERIFY_REPAIRED  = "repaired"
+
+	BACKUP_INTEGRITY_VERIFY_FAILED      = "backup_integrity_verify_failed"
+	IMAGE_CACHE_INTEGRITY_VERIFY_FAILED = "image_cache_integrity_verify_failed"
+)
+
+type StoragecacheVerifyImageInput struct {
+	// 待校验的镜像ID
+	ImageId string `json:"image_id"`
+	// 校验失败时是否从镜像服务重新下载
+	Repair bool `json:"repair"`
+}
+
+type DiskBackupVerifyInput struct {
+}
+
+// 宿主机返回的完整性校验结果
+type IntegrityVerifyResult struct {
+	ImageId  string `json:"image_id"`
+	BackupId string `json:"backup_id"`
+	// 实际计算出的md5
+	Checksum string `json:"checksum"`
+	// 期望的md5
+	ExpectChecksum string `json:"expect_checksum"`
+	Size           int64  `json:"size"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason"`
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "time"
+
+const (
+	// 公网可访问的端口
+	ISOLATION_FINDING_INTERNET_EXPOSED = "internet_exposed"
+	// 其他项目的虚机可访问
+	ISOLATION_FINDING_CROSS_PROJECT = "cross_project"
+
+	ISOLATION_SEVERITY_HIGH   = "high"
+	ISOLATION_SEVERITY_MEDIUM = "medium"
+	ISOLATION_SEVERITY_LOW    = "low"
+
+	// 规则生效位置: vpc 内的 kvm 虚机由 ovn acl 生效, 经典网络由宿主机 iptables 生效, 公有云由平台安全组生效
+	ISOLATION_LAYER_OVN      = "ovn"
+	ISOLATION_LAYER_HOST     = "host"
+	ISOLATION_LAYER_PROVIDER = "provider"
+
+	NETWORK_ISOLATION_AUDIT_FINDING = "network_isolation_audit_finding"
+)
+
+var ISOLATION_SEVERITY_LEVELS = map[string]int{
+	ISOLATION_SEVERITY_LOW:    1,
+	ISOLATION_SEVERITY_MEDIUM: 2,
+	ISOLATION_SEVERITY_HIGH:   3,
+}
+
+type NetworkIsolationAuditInput struct {
+	// 审计的域, 默认为当前用户所在域
+	DomainId string `json:"domain_id"`
+	// 仅返回不低于该级别的问题
+	// enum: ["high", "medium", "low"]
+	Severity string `json:"severity"`
+}
+
+type SIsolationFinding struct {
+	// enum: ["internet_exposed", "cross_project"]
+	Type string `json:"type"`
+	// enum: ["high", "medium", "low"]
+	Severity string `json:"severity"`
+	// enum: ["ovn", "host", "provider"]
+	Layer string `json:"layer"`
+
+	GuestId   string `json:"guest_id"`
+	GuestName string `json:"guest_name"`
+	ProjectId string `json:"project_id"`
+
+	// 可访问该虚机的其他项目及其虚机数量
+	SourceProjectId  string `json:"source_project_id,omitempty"`
+	SourceGuestCount int    `json:"source_guest_count,omitempty"`
+	// 通过对端安全组放行
+	PeerSecgroupId string `json:"peer_secgroup_id,omitempty"`
+
+	Protocol string `json:"protocol"`
+	Ports    string `json:"ports"`
+	Cidr     string `json:"cidr,omitempty"`
+}
+
+type NetworkIsolationAuditReport struct {
+	DomainId    string    `json:"domain_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	GuestCount  int       `json:"guest_count"`
+	// 各级别问题数量
+	Summary  map[string]int      `json:"summary"`
+	Findings []SIsolationFinding `json:"findings"`
+}
@@ -166,6 +166,8 @@ type LoadbalancerCreateInput struct {
 	Eip string `json:"eip" yunion-deprecated-by:"eip_id"`
 	// EIP Id
 	EipId string `json:"eip_id"`
+	// EIP加入的共享带宽(云上Id), 目前仅华为云支持
+	SharedBandwidthId string `json:"shared_bandwidth_id"`
 
 	// LB的其他配置信息
 	LBInfo jsonutils.JSONObject `json:"lb_info"`
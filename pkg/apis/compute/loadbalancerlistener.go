@@ -428,3 +428,23 @@ func (self *LoadbalancerListenerUpdateInput) Validate() error {
 	}
 	return nil
 }
+
+type LoadbalancerListenerSetAccessLogInput struct {
+	// 是否开启访问日志
+	// enum: ["on", "off"]
+	AccessLogStatus string `json:"access_log_status"`
+	// 日志投递位置, 华为云为LTS日志组ID, 阿里云为SLS Project, AWS为S3 Bucket
+	AccessLogProject string `json:"access_log_project"`
+	// 日志主题, 华为云为LTS日志流ID, 阿里云为SLS Logstore, AWS为S3前缀
+	AccessLogTopic string `json:"access_log_topic"`
+}
+
+func (self *LoadbalancerListenerSetAccessLogInput) Validate() error {
+	if !utils.IsInStringArray(self.AccessLogStatus, []string{LB_BOOL_ON, LB_BOOL_OFF}) {
+		return httperrors.NewInputParameterError("invalid access_log_status %s", self.AccessLogStatus)
+	}
+	if self.AccessLogStatus == LB_BOOL_ON && len(self.AccessLogProject) == 0 {
+		return httperrors.NewMissingParameterError("access_log_project")
+	}
+	return nil
+}
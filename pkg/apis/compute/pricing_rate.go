@@ -0,0 +1,175 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	PRICING_RATE_STATUS_AVAILABLE = "available"
+
+	// 每 vCPU 每小时
+	PRICING_RESOURCE_VCPU = "vcpu"
+	// 每 GB 内存每小时
+	PRICING_RESOURCE_MEMORY = "memory"
+	// 每 GB 存储每月
+	PRICING_RESOURCE_STORAGE = "storage"
+	// 每块 GPU 每小时
+	PRICING_RESOURCE_GPU = "gpu"
+	// 公有云抢占式实例每台每小时, 价格取自同步的抢占式实例价格
+	PRICING_RESOURCE_SPOT_INSTANCE = "spot_instance"
+
+	PRICING_DEFAULT_CURRENCY = "CNY"
+
+	// 存储按月计价时每月的小时数
+	PRICING_HOURS_PER_MONTH = 720
+)
+
+var PRICING_RESOURCE_TYPES = []string{
+	PRICING_RESOURCE_VCPU,
+	PRICING_RESOURCE_MEMORY,
+	PRICING_RESOURCE_STORAGE,
+	PRICING_RESOURCE_GPU,
+}
+
+// 计价单位对应的小时数, 单价 / 单位小时数 = 每小时单价
+func PricingUnitHours(resourceType string) float64 {
+	if resourceType == PRICING_RESOURCE_STORAGE {
+		return PRICING_HOURS_PER_MONTH
+	}
+	return 1
+}
+
+type PricingRateCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 资源类型
+	// enum: vcpu, memory, storage, gpu
+	// required: true
+	ResourceType string `json:"resource_type"`
+
+	// 规格, 存储为存储类型(如 local, rbd), GPU 为型号, 为空表示该资源类型的默认价格
+	Spec string `json:"spec"`
+
+	// 单价, vcpu/memory/gpu 按小时, storage 按 GB 每月
+	// required: true
+	Price float64 `json:"price"`
+
+	// 货币
+	// default: CNY
+	Currency string `json:"currency"`
+
+	// 生效时间, 默认为当前时间
+	EffectiveDate time.Time `json:"effective_date"`
+}
+
+type PricingRateUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+}
+
+type PricingRateListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+
+	// 按资源类型过滤
+	ResourceType []string `json:"resource_type"`
+
+	// 按规格过滤
+	Spec []string `json:"spec"`
+
+	// 仅列出此时间点生效的价格
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+type PricingRateDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+}
+
+type PricingEstimateDisk struct {
+	// 磁盘大小, 单位MB
+	SizeMb int `json:"size_mb"`
+	// 存储类型
+	StorageType string `json:"storage_type"`
+}
+
+type PricingEstimateGpu struct {
+	// GPU 型号
+	Model string `json:"model"`
+	Count int    `json:"count"`
+}
+
+// 创建前的费用估算
+type PricingEstimateInput struct {
+	VcpuCount int `json:"vcpu_count"`
+	// 内存大小, 单位MB
+	VmemSize int `json:"vmem_size"`
+
+	Disks []PricingEstimateDisk `json:"disks"`
+	Gpus  []PricingEstimateGpu  `json:"gpus"`
+
+	// 公有云套餐, 同时指定 spot 时按抢占式实例最新价格估算
+	SkuId string `json:"sku_id"`
+	Spot  bool   `json:"spot"`
+
+	// 虚拟机数量
+	// default: 1
+	Count int `json:"count"`
+}
+
+type PricingCostItem struct {
+	ResourceType string  `json:"resource_type"`
+	Spec         string  `json:"spec"`
+	Amount       float64 `json:"amount"`
+	Price        float64 `json:"price"`
+	HourlyCost   float64 `json:"hourly_cost"`
+}
+
+type PricingEstimateOutput struct {
+	Currency    string            `json:"currency"`
+	HourlyCost  float64           `json:"hourly_cost"`
+	MonthlyCost float64           `json:"monthly_cost"`
+	Items       []PricingCostItem `json:"items"`
+
+	// 抢占式实例中断率及缺省出价
+	SpotInterruptionRate float64 `json:"spot_interruption_rate,omitempty"`
+	SpotBidPrice         float64 `json:"spot_bid_price,omitempty"`
+}
+
+// 按项目统计私有云 kvm 虚拟机在时间段内的费用
+type PricingChargebackInput struct {
+	ProjectId string `json:"project_id"`
+	// 默认为当前时间前 30 天
+	StartTime time.Time `json:"start_time"`
+	// 默认为当前时间
+	EndTime time.Time `json:"end_time"`
+}
+
+type PricingChargebackItem struct {
+	GuestId   string  `json:"guest_id"`
+	Guest     string  `json:"guest"`
+	ProjectId string  `json:"project_id"`
+	Hours     float64 `json:"hours"`
+	Cost      float64 `json:"cost"`
+}
+
+type PricingChargebackOutput struct {
+	Currency  string                  `json:"currency"`
+	StartTime time.Time               `json:"start_time"`
+	EndTime   time.Time               `json:"end_time"`
+	Total     float64                 `json:"total"`
+	Items     []PricingChargebackItem `json:"items"`
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	PROJECT_POSTURE_STATUS_AVAILABLE = "available"
+
+	// 虚拟机元数据服务(IMDS)访问模式
+	IMDS_MODE_ENABLED        = "enabled"
+	IMDS_MODE_TOKEN_REQUIRED = "token_required"
+	IMDS_MODE_DISABLED       = "disabled"
+)
+
+var IMDS_MODES = []string{IMDS_MODE_ENABLED, IMDS_MODE_TOKEN_REQUIRED, IMDS_MODE_DISABLED}
+
+// 返回两种IMDS访问模式中更严格的一种
+func StricterImdsMode(a, b string) string {
+	rank := func(mode string) int {
+		switch mode {
+		case IMDS_MODE_DISABLED:
+			return 2
+		case IMDS_MODE_TOKEN_REQUIRED:
+			return 1
+		}
+		return 0
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+type PostureIdList []string
+
+func (self PostureIdList) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self PostureIdList) IsZero() bool {
+	return len(self) == 0
+}
+
+type ProjectPostureCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 生效的项目, 为空时作为整个域的缺省安全基线
+	ProjectId string `json:"project_id"`
+
+	// 未指定安全组时使用的缺省安全组
+	DefaultSecgroups PostureIdList `json:"default_secgroups"`
+
+	// 强制标签, 值为空表示创建时必须由用户指定该标签
+	MandatoryTags TagPolicyTags `json:"mandatory_tags"`
+
+	// 缺省磁盘加密密钥
+	EncryptKeyId string `json:"encrypt_key_id"`
+
+	// 元数据服务访问模式
+	// enum: ["enabled", "token_required", "disabled"]
+	ImdsMode string `json:"imds_mode"`
+
+	// 允许使用的镜像, 为空表示不限制
+	AllowedImages PostureIdList `json:"allowed_images"`
+}
+
+type ProjectPostureUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	DefaultSecgroups PostureIdList `json:"default_secgroups"`
+
+	MandatoryTags TagPolicyTags `json:"mandatory_tags"`
+
+	EncryptKeyId *string `json:"encrypt_key_id"`
+
+	ImdsMode string `json:"imds_mode"`
+
+	AllowedImages PostureIdList `json:"allowed_images"`
+}
+
+type ProjectPostureListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+
+	// 按项目过滤
+	ProjectId string `json:"project_id"`
+}
+
+type ProjectPostureDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+
+	// 项目名称
+	Project string `json:"project"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&PostureIdList{}), func() gotypes.ISerializable {
+		return &PostureIdList{}
+	})
+}
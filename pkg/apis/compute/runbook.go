@@ -0,0 +1,173 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"os"
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// 重启虚拟机, 关机状态的虚拟机会被开机, 参数: is_force
+	RUNBOOK_ACTION_RESTART_GUEST = "restart_guest"
+	// 扩容虚拟机磁盘, 参数: disk_index, grow_size_mb, grow_percent, max_size_mb
+	RUNBOOK_ACTION_RESIZE_DISK = "resize_disk"
+	// 发送通知, 参数: receiver_ids(逗号分隔), message
+	RUNBOOK_ACTION_NOTIFY = "notify"
+
+	RUNBOOK_STATUS_READY = "ready"
+
+	RUNBOOK_EXECUTION_STATUS_PENDING_APPROVAL = "pending_approval"
+	RUNBOOK_EXECUTION_STATUS_RUNNING          = "running"
+	RUNBOOK_EXECUTION_STATUS_SUCCEEDED        = "succeeded"
+	RUNBOOK_EXECUTION_STATUS_FAILED           = "failed"
+	RUNBOOK_EXECUTION_STATUS_REJECTED         = "rejected"
+
+	// 执行时内置的参数
+	RUNBOOK_PARAM_GUEST_ID = "guest_id"
+	RUNBOOK_PARAM_ALERT_ID = "alert_id"
+)
+
+var RUNBOOK_ACTIONS = []string{
+	RUNBOOK_ACTION_RESTART_GUEST,
+	RUNBOOK_ACTION_RESIZE_DISK,
+	RUNBOOK_ACTION_NOTIFY,
+}
+
+type RunbookStep struct {
+	// 动作
+	// enum: restart_guest, resize_disk, notify
+	Action string `json:"action"`
+	// 动作参数, 值中的 ${name} 在执行时替换为触发参数
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Render 将参数值中的 ${name} 替换为执行参数, 未知的占位符保持原样
+func (self RunbookStep) Render(params map[string]string) RunbookStep {
+	ret := RunbookStep{Action: self.Action, Params: map[string]string{}}
+	for k, v := range self.Params {
+		ret.Params[k] = os.Expand(v, func(name string) string {
+			if val, ok := params[name]; ok {
+				return val
+			}
+			return "${" + name + "}"
+		})
+	}
+	return ret
+}
+
+type RunbookSteps []RunbookStep
+
+func (self RunbookSteps) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self RunbookSteps) IsZero() bool {
+	return len(self) == 0
+}
+
+type RunbookCreateInput struct {
+	apis.EnabledStatusStandaloneResourceCreateInput
+
+	// 按顺序执行的动作
+	Steps RunbookSteps `json:"steps"`
+	// 执行前是否需要人工审批
+	RequireApproval *bool `json:"require_approval"`
+	// 同一虚拟机两次执行的最小间隔(分钟), 避免告警抖动反复触发
+	// default: 30
+	CooldownMinutes *int `json:"cooldown_minutes"`
+}
+
+type RunbookUpdateInput struct {
+	apis.EnabledStatusStandaloneResourceBaseUpdateInput
+
+	Steps           RunbookSteps `json:"steps"`
+	RequireApproval *bool        `json:"require_approval"`
+	CooldownMinutes *int         `json:"cooldown_minutes"`
+}
+
+type RunbookListInput struct {
+	apis.EnabledStatusStandaloneResourceListInput
+}
+
+type RunbookDetails struct {
+	apis.EnabledStatusStandaloneResourceDetails
+
+	SRunbook
+
+	// 执行次数
+	ExecutionCount int `json:"execution_count"`
+}
+
+type RunbookTriggerInput struct {
+	// 虚拟机ID或Name
+	GuestId string `json:"guest_id"`
+	// 触发的告警ID
+	AlertId string `json:"alert_id"`
+	// 执行参数
+	Params map[string]string `json:"params"`
+}
+
+type RunbookExecutionListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	RunbookId string `json:"runbook_id"`
+	GuestId   string `json:"guest_id"`
+	AlertId   string `json:"alert_id"`
+}
+
+type RunbookExecutionDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	SRunbookExecution
+
+	Runbook string `json:"runbook"`
+	Guest   string `json:"guest"`
+}
+
+type RunbookStepResult struct {
+	Action  string `json:"action"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+type RunbookStepResults []RunbookStepResult
+
+func (self RunbookStepResults) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self RunbookStepResults) IsZero() bool {
+	return len(self) == 0
+}
+
+type RunbookExecutionApproveInput struct {
+	// 审批意见
+	Reason string `json:"reason"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&RunbookSteps{}), func() gotypes.ISerializable {
+		return &RunbookSteps{}
+	})
+	gotypes.RegisterSerializable(reflect.TypeOf(&RunbookStepResults{}), func() gotypes.ISerializable {
+		return &RunbookStepResults{}
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+)
+
+func TestRunbookStepRender(t *testing.T) {
+	step := RunbookStep{
+		Action: RUNBOOK_ACTION_NOTIFY,
+		Params: map[string]string{
+			"receiver_ids": "${owner}",
+			"message":      "guest ${guest_id} alert ${alert_id} ${unknown}",
+		},
+	}
+	got := step.Render(map[string]string{
+		"owner":                "u1,u2",
+		RUNBOOK_PARAM_GUEST_ID: "g1",
+		RUNBOOK_PARAM_ALERT_ID: "a1",
+	})
+	if got.Action != RUNBOOK_ACTION_NOTIFY {
+		t.Errorf("action: %s", got.Action)
+	}
+	if got.Params["receiver_ids"] != "u1,u2" {
+		t.Errorf("receiver_ids: %s", got.Params["receiver_ids"])
+	}
+	if want := "guest g1 alert a1 ${unknown}"; got.Params["message"] != want {
+		t.Errorf("message: got %s want %s", got.Params["message"], want)
+	}
+	if step.Params["receiver_ids"] != "${owner}" {
+		t.Errorf("render should not modify the original step")
+	}
+}
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -212,6 +213,8 @@ type SecurityGroupRuleListInput struct {
 	Ports string `json:"ports"`
 	// 根据ip模糊匹配安全组规则
 	Ip string `json:"ip"`
+	// 已采集命中统计且自该时间起未被命中的规则
+	NotHitSince time.Time `json:"not_hit_since"`
 }
 
 type SecgroupResourceInput struct {
@@ -333,3 +336,65 @@ type SSecurityGroupRef struct {
 func (self *SSecurityGroupRef) Sum() {
 	self.TotalCnt = self.GuestCnt + self.AdminGuestCnt + self.RdsCnt + self.RedisCnt
 }
+
+const (
+	// 平台不支持对端安全组
+	SECGROUP_RULE_DROP_PEER_NOT_SUPPORTED = "peer_secgroup_not_supported"
+	// 平台仅支持允许规则, 拒绝规则被折算进允许列表
+	SECGROUP_RULE_DROP_DENY_NOT_SUPPORTED = "deny_rule_not_supported"
+	// 规则被合并进其他规则
+	SECGROUP_RULE_DROP_MERGED = "merged"
+)
+
+type SecgroupNormalizedRulesInput struct {
+	// 指定平台, 默认为安全组已同步过的平台
+	Provider []string `json:"provider"`
+}
+
+type SSecgroupNormalizedRule struct {
+	// 对应的本地规则id, 由多条规则合并生成或平台默认规则时为空
+	RuleId string `json:"rule_id"`
+	Rule   string `json:"rule"`
+
+	Direction string `json:"direction"`
+	Action    string `json:"action"`
+	Protocol  string `json:"protocol"`
+	Ports     string `json:"ports"`
+	CIDR      string `json:"cidr"`
+
+	// 平台上的优先级
+	Priority int `json:"priority"`
+	// 本地规则优先级
+	OriginPriority int `json:"origin_priority"`
+
+	// 是否由多条规则合并(CIDR合并或折算为允许列表)而来
+	Merged bool `json:"merged"`
+	// 是否为补齐的平台默认规则
+	Default bool `json:"default"`
+}
+
+type SSecgroupDroppedRule struct {
+	RuleId   string `json:"rule_id"`
+	Rule     string `json:"rule"`
+	Priority int    `json:"priority"`
+	// enum: peer_secgroup_not_supported, deny_rule_not_supported, merged
+	Reason string `json:"reason"`
+}
+
+type SecgroupProviderNormalizedRules struct {
+	Provider string `json:"provider"`
+
+	MinPriority             int  `json:"min_priority"`
+	MaxPriority             int  `json:"max_priority"`
+	IsOnlySupportAllowRules bool `json:"is_only_support_allow_rules"`
+	IsSupportPeerSecgroup   bool `json:"is_support_peer_secgroup"`
+
+	Rules   []SSecgroupNormalizedRule `json:"rules"`
+	Dropped []SSecgroupDroppedRule    `json:"dropped"`
+}
+
+type SecgroupNormalizedRulesOutput struct {
+	Id        string                            `json:"id"`
+	Name      string                            `json:"name"`
+	Providers []SecgroupProviderNormalizedRules `json:"providers"`
+}
@@ -25,3 +25,19 @@ type SecgroupRuleDetails struct {
 	ProjectId    string `json:"tenant_id"`
 	PeerSecgroup string `json:"peer_secgroup"`
 }
+
+type SecgroupRuleHitCount struct {
+	// 安全组规则ID
+	Id string `json:"id"`
+	// 命中报文数
+	Packets int64 `json:"packets"`
+}
+
+type SecgroupRuleReportHitCountsInput struct {
+	// 统计来源, 如 ovn 或公有云流日志
+	Source string `json:"source"`
+	// 计数是否为累计值(如 OVN 流表计数), 否则作为增量累加
+	Cumulative bool `json:"cumulative"`
+
+	Rules []SecgroupRuleHitCount `json:"rules"`
+}
@@ -121,6 +121,14 @@ type ServerSkuCreateInput struct {
 
 	// swagger:ignore
 	Provider string
+
+	// QoS等级, 仅本地套餐支持
+	// enum: guaranteed, burstable, best-effort
+	QosClass string `json:"qos_class"`
+
+	// 私有云自定义套餐, 仅在本地记录, 不在云平台创建规格
+	// default: false
+	IsCustom *bool `json:"is_custom"`
 }
 
 type ServerSkuDetails struct {
@@ -185,4 +193,8 @@ type ServerSkuUpdateInput struct {
 	GpuCount *int `json:"gpu_count"`
 
 	GpuMaxCount *int `json:"gpu_max_count"`
+
+	// QoS等级, 仅本地套餐支持
+	// enum: guaranteed, burstable, best-effort
+	QosClass *string `json:"qos_class"`
 }
@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "time"
+
+const (
+	// 计算缺省出价时参考的历史价格天数
+	SPOT_BID_LOOKBACK_DAYS = 7
+)
+
+// 支持同步抢占式实例价格的平台
+var SPOT_PRICE_PROVIDERS = []string{
+	CLOUD_PROVIDER_ALIYUN,
+	CLOUD_PROVIDER_HUAWEI,
+}
+
+type ServerSkuSpotPriceInput struct {
+	// 起始时间, 默认为7天前
+	StartTime time.Time `json:"start_time"`
+	// 截止时间, 默认为当前时间
+	EndTime time.Time `json:"end_time"`
+}
+
+type ServerSkuSpotPricePoint struct {
+	// 抢占式实例每小时价格
+	Price float64 `json:"price"`
+	// 同规格按量付费每小时价格
+	OriginPrice float64   `json:"origin_price"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type ServerSkuSpotPriceOutput struct {
+	InstanceType string `json:"instance_type"`
+	ZoneId       string `json:"zone_id"`
+	Currency     string `json:"currency"`
+
+	LatestPrice float64 `json:"latest_price"`
+	MinPrice    float64 `json:"min_price"`
+	MaxPrice    float64 `json:"max_price"`
+	AvgPrice    float64 `json:"avg_price"`
+	OriginPrice float64 `json:"origin_price"`
+
+	// 中断率, 0-1
+	InterruptionRate float64 `json:"interruption_rate"`
+	// 缺省出价, 最近7天最高价上浮后不超过按量付费价格
+	SuggestedBidPrice float64 `json:"suggested_bid_price"`
+
+	History []ServerSkuSpotPricePoint `json:"history"`
+}
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"yunion.io/x/cloudmux/pkg/apis/compute"
+	"yunion.io/x/jsonutils"
 
 	"yunion.io/x/onecloud/pkg/apis"
 	"yunion.io/x/onecloud/pkg/apis/billing"
@@ -79,6 +80,9 @@ type ServerSkuListInput struct {
 
 	// 按套餐名称去重
 	Distinct bool `json:"distinct"`
+
+	// 抢占式实例中断率上限, 0-1
+	MaxSpotInterruptionRate *float64 `json:"max_spot_interruption_rate"`
 }
 
 type ElasticcacheSkuListInput struct {
@@ -161,6 +165,19 @@ type SkuSyncInput struct {
 	CloudregionIds []string `json:"cloudregion_ids"`
 }
 
+// 离线套餐目录, 格式为 {region_external_id: [sku, ...]}, 与在线同步的套餐文件格式一致
+type ServerSkuImportCatalogInput struct {
+	Catalog map[string][]jsonutils.JSONObject `json:"catalog"`
+}
+
+type ServerSkuExportCatalogInput struct {
+	// 云平台名称
+	Provider string `json:"provider,omitempty"`
+
+	// 区域ID
+	CloudregionIds []string `json:"cloudregion_ids"`
+}
+
 type SkuTaskQueryInput struct {
 	// 异步任务ID
 	TaskIds []string `json:"task_ids"`
@@ -56,6 +56,15 @@ type SSnapshotPolicyCreateInput struct {
 	RetentionDays  int   `json:"retention_days"`
 	RepeatWeekdays []int `json:"repeat_weekdays"`
 	TimePoints     []int `json:"time_points"`
+
+	// 启用 GFS 轮转保留, 未指定保留个数时默认保留 7 个日快照, 4 个周快照, 12 个月快照
+	Gfs bool `json:"gfs"`
+	// 保留最近多少天的日快照
+	RetentionDaily int `json:"retention_daily"`
+	// 保留最近多少周的周快照
+	RetentionWeekly int `json:"retention_weekly"`
+	// 保留最近多少月的月快照
+	RetentionMonthly int `json:"retention_monthly"`
 }
 
 type SSnapshotPolicyCreateInternalInput struct {
@@ -68,6 +77,21 @@ type SSnapshotPolicyCreateInternalInput struct {
 	RetentionDays  int
 	RepeatWeekdays uint8
 	TimePoints     uint32
+
+	RetentionDaily   int
+	RetentionWeekly  int
+	RetentionMonthly int
+}
+
+type SnapshotPolicyUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// 保留最近多少天的日快照
+	RetentionDaily *int `json:"retention_daily"`
+	// 保留最近多少周的周快照
+	RetentionWeekly *int `json:"retention_weekly"`
+	// 保留最近多少月的月快照
+	RetentionMonthly *int `json:"retention_monthly"`
 }
 
 type SnapshotListInput struct {
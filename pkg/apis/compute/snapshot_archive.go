@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	SNAPSHOT_ARCHIVE_STATUS_ARCHIVING      = "archiving"
+	SNAPSHOT_ARCHIVE_STATUS_ARCHIVED       = "archived"
+	SNAPSHOT_ARCHIVE_STATUS_ARCHIVE_FAILED = "archive_failed"
+	SNAPSHOT_ARCHIVE_STATUS_DELETING       = "deleting"
+	SNAPSHOT_ARCHIVE_STATUS_DELETE_FAILED  = "delete_failed"
+
+	// 合规保留期下限
+	SNAPSHOT_ARCHIVE_MIN_RETENTION_DAYS = 1
+)
+
+type SnapshotArchivePolicyCreateInput struct {
+	apis.EnabledStatusStandaloneResourceCreateInput
+
+	// 金库云账号(ID或Name), 快照副本复制到该账号下, 需与源账号为同一平台的不同账号
+	VaultCloudaccountId string `json:"vault_cloudaccount_id"`
+	// 合规保留天数, 保留期内副本不可删除
+	RetentionDays int `json:"retention_days"`
+}
+
+type SnapshotArchivePolicyUpdateInput struct {
+	apis.EnabledStatusStandaloneResourceBaseUpdateInput
+
+	// 合规保留天数, 只能延长不能缩短
+	RetentionDays *int `json:"retention_days"`
+}
+
+type SnapshotArchivePolicyListInput struct {
+	apis.EnabledStatusStandaloneResourceListInput
+
+	VaultCloudaccountId string `json:"vault_cloudaccount_id"`
+	// 按绑定的磁盘过滤
+	DiskId string `json:"disk_id"`
+}
+
+type SnapshotArchivePolicyDetails struct {
+	apis.EnabledStatusStandaloneResourceDetails
+
+	SSnapshotArchivePolicy
+
+	VaultCloudaccount string `json:"vault_cloudaccount"`
+	// 绑定的磁盘数量
+	DiskCount int `json:"disk_count"`
+	// 已归档的副本数量
+	ArchiveCount int `json:"archive_count"`
+}
+
+type SnapshotArchivePolicyDisksInput struct {
+	// 磁盘ID或Name, 仅支持公有云磁盘
+	Disks []string `json:"disks"`
+}
+
+type SnapshotArchiveListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	PolicyId            string `json:"policy_id"`
+	DiskId              string `json:"disk_id"`
+	SnapshotId          string `json:"snapshot_id"`
+	VaultCloudaccountId string `json:"vault_cloudaccount_id"`
+	// 仅列出仍在合规保留期内的副本
+	Retained *bool `json:"retained"`
+}
+
+type SnapshotArchiveDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	SSnapshotArchive
+
+	Policy            string `json:"policy"`
+	Disk              string `json:"disk"`
+	Snapshot          string `json:"snapshot"`
+	VaultCloudaccount string `json:"vault_cloudaccount"`
+}
+
+type SnapshotArchiveInput struct {
+	// 归档策略(ID或Name)
+	PolicyId string `json:"policy_id"`
+}
@@ -31,6 +31,15 @@ const (
 
 	SNAPSHOT_POLICY_CREATING = compute.SNAPSHOT_POLICY_CREATING
 
+	// GFS 轮转默认保留个数
+	SNAPSHOT_POLICY_GFS_DAILY   = 7
+	SNAPSHOT_POLICY_GFS_WEEKLY  = 4
+	SNAPSHOT_POLICY_GFS_MONTHLY = 12
+
+	SNAPSHOT_POLICY_GFS_DAILY_LIMIT   = 366
+	SNAPSHOT_POLICY_GFS_WEEKLY_LIMIT  = 260
+	SNAPSHOT_POLICY_GFS_MONTHLY_LIMIT = 120
+
 	SNAPSHOT_POLICY_READY         = compute.SNAPSHOT_POLICY_READY
 	SNAPSHOT_POLICY_UPDATING      = "updating"
 	SNAPSHOT_POLICY_UNKNOWN       = compute.SNAPSHOT_POLICY_UNKNOWN
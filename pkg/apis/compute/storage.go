@@ -208,6 +208,9 @@ type StorageUpdateInput struct {
 
 	RbdTimeoutInput
 
+	// 镜像预取的缓存预算,单位Mb, 0 表示使用全局默认值, -1 表示不预取
+	ImageCacheBudgetMb *int64 `json:"image_cache_budget_mb"`
+
 	// swagger:ignore
 	StorageConf *jsonutils.JSONDict
 
@@ -0,0 +1,201 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	TAG_BACKFILL_STATUS_READY     = "ready"
+	TAG_BACKFILL_STATUS_RUNNING   = "running"
+	TAG_BACKFILL_STATUS_PAUSED    = "paused"
+	TAG_BACKFILL_STATUS_COMPLETED = "completed"
+	TAG_BACKFILL_STATUS_FAILED    = "failed"
+
+	TAG_BACKFILL_RESOURCE_SERVER       = "server"
+	TAG_BACKFILL_RESOURCE_DISK         = "disk"
+	TAG_BACKFILL_RESOURCE_EIP          = "eip"
+	TAG_BACKFILL_RESOURCE_LOADBALANCER = "loadbalancer"
+
+	// 默认每秒调用云上接口次数
+	TAG_BACKFILL_DEFAULT_RATE_LIMIT = 5
+	// 报告中最多保留的失败资源条数
+	TAG_BACKFILL_MAX_FAILURES = 100
+)
+
+var TAG_BACKFILL_RESOURCE_TYPES = []string{
+	TAG_BACKFILL_RESOURCE_SERVER,
+	TAG_BACKFILL_RESOURCE_DISK,
+	TAG_BACKFILL_RESOURCE_EIP,
+	TAG_BACKFILL_RESOURCE_LOADBALANCER,
+}
+
+// 标签规范化规则
+type TagBackfillRule struct {
+	// 需要补齐的标签
+	Tags map[string]string `json:"tags"`
+	// 是否覆盖资源上已有的同名标签
+	Overwrite bool `json:"overwrite"`
+	// 标签键别名, 例如 {"Env": "env", "ENV": "env"}, 别名键会被重命名为规范键
+	KeyAliases map[string]string `json:"key_aliases"`
+}
+
+func (self TagBackfillRule) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self TagBackfillRule) IsZero() bool {
+	return len(self.Tags) == 0 && len(self.KeyAliases) == 0
+}
+
+// Normalize 返回规范化后的标签, 以及是否与原标签不同
+func (self TagBackfillRule) Normalize(tags map[string]string) (map[string]string, bool) {
+	ret := map[string]string{}
+	for k, v := range tags {
+		ret[k] = v
+	}
+	for alias, key := range self.KeyAliases {
+		v, ok := ret[alias]
+		if !ok || alias == key {
+			continue
+		}
+		delete(ret, alias)
+		if _, ok := ret[key]; !ok {
+			ret[key] = v
+		}
+	}
+	for k, v := range self.Tags {
+		if _, ok := ret[k]; !ok || self.Overwrite {
+			ret[k] = v
+		}
+	}
+	if len(ret) != len(tags) {
+		return ret, true
+	}
+	for k, v := range ret {
+		if old, ok := tags[k]; !ok || old != v {
+			return ret, true
+		}
+	}
+	return ret, false
+}
+
+// 每种资源处理到的最后一个资源Id, 任务按Id顺序处理以便中断后续跑
+type TagBackfillCursor map[string]string
+
+func (self TagBackfillCursor) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self TagBackfillCursor) IsZero() bool {
+	return len(self) == 0
+}
+
+type TagBackfillStat struct {
+	// 已处理资源数
+	Total int `json:"total"`
+	// 无需修改的合规资源数
+	Compliant int `json:"compliant"`
+	// 补齐标签的资源数
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+type TagBackfillFailure struct {
+	ResourceType string `json:"resource_type"`
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	Error        string `json:"error"`
+}
+
+// 合规报告
+type TagBackfillReport struct {
+	Stats    map[string]*TagBackfillStat `json:"stats"`
+	Failures []TagBackfillFailure        `json:"failures"`
+}
+
+func (self TagBackfillReport) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self TagBackfillReport) IsZero() bool {
+	return len(self.Stats) == 0 && len(self.Failures) == 0
+}
+
+func (self *TagBackfillReport) Stat(resType string) *TagBackfillStat {
+	if self.Stats == nil {
+		self.Stats = map[string]*TagBackfillStat{}
+	}
+	if _, ok := self.Stats[resType]; !ok {
+		self.Stats[resType] = &TagBackfillStat{}
+	}
+	return self.Stats[resType]
+}
+
+func (self *TagBackfillReport) AddFailure(failure TagBackfillFailure) {
+	self.Stat(failure.ResourceType).Failed += 1
+	if len(self.Failures) < TAG_BACKFILL_MAX_FAILURES {
+		self.Failures = append(self.Failures, failure)
+	}
+}
+
+type TagBackfillJobCreateInput struct {
+	apis.StatusStandaloneResourceCreateInput
+
+	// 云账号Id或Name
+	// required: true
+	CloudaccountId string `json:"cloudaccount_id"`
+	// 处理的资源类型, 默认全部
+	// enum: server, disk, eip, loadbalancer
+	ResourceTypes []string `json:"resource_types"`
+
+	Rule TagBackfillRule `json:"rule"`
+
+	// 每秒调用云上接口次数
+	// default: 5
+	RateLimit int `json:"rate_limit"`
+}
+
+type TagBackfillJobListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	CloudaccountId string `json:"cloudaccount_id"`
+}
+
+type TagBackfillJobDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	STagBackfillJob
+
+	Cloudaccount string `json:"cloudaccount"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&TagBackfillRule{}), func() gotypes.ISerializable {
+		return &TagBackfillRule{}
+	})
+	gotypes.RegisterSerializable(reflect.TypeOf(&TagBackfillCursor{}), func() gotypes.ISerializable {
+		return &TagBackfillCursor{}
+	})
+	gotypes.RegisterSerializable(reflect.TypeOf(&TagBackfillReport{}), func() gotypes.ISerializable {
+		return &TagBackfillReport{}
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagBackfillRuleNormalize(t *testing.T) {
+	rule := TagBackfillRule{
+		Tags:       map[string]string{"env": "prod", "owner": "ops"},
+		KeyAliases: map[string]string{"Env": "env", "ENV": "env"},
+	}
+	cases := []struct {
+		name    string
+		tags    map[string]string
+		want    map[string]string
+		changed bool
+	}{
+		{
+			name:    "empty",
+			tags:    map[string]string{},
+			want:    map[string]string{"env": "prod", "owner": "ops"},
+			changed: true,
+		},
+		{
+			name:    "alias renamed and keeps value",
+			tags:    map[string]string{"Env": "test"},
+			want:    map[string]string{"env": "test", "owner": "ops"},
+			changed: true,
+		},
+		{
+			name:    "alias dropped when canonical exists",
+			tags:    map[string]string{"ENV": "test", "env": "dev", "owner": "me"},
+			want:    map[string]string{"env": "dev", "owner": "me"},
+			changed: true,
+		},
+		{
+			name:    "compliant",
+			tags:    map[string]string{"env": "dev", "owner": "me", "other": "x"},
+			want:    map[string]string{"env": "dev", "owner": "me", "other": "x"},
+			changed: false,
+		},
+	}
+	for _, c := range cases {
+		got, changed := rule.Normalize(c.tags)
+		if changed != c.changed {
+			t.Errorf("%s: changed %v want %v", c.name, changed, c.changed)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v want %v", c.name, got, c.want)
+		}
+	}
+
+	rule.Overwrite = true
+	got, changed := rule.Normalize(map[string]string{"env": "dev", "owner": "ops"})
+	if !changed || got["env"] != "prod" {
+		t.Errorf("overwrite: got %v changed %v", got, changed)
+	}
+}
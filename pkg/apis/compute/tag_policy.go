@@ -0,0 +1,109 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	TAG_POLICY_STATUS_AVAILABLE = "available"
+)
+
+type TagPolicyTags map[string]string
+
+func (self TagPolicyTags) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self TagPolicyTags) IsZero() bool {
+	return len(self) == 0
+}
+
+// 多条标签策略合并后的结果
+type SMandatoryTags struct {
+	// 缺省标签, 资源未设置时补齐
+	Defaults map[string]string
+	// 强制标签, 始终覆盖资源上的同名标签
+	Enforced map[string]string
+}
+
+func (self SMandatoryTags) IsZero() bool {
+	return len(self.Defaults) == 0 && len(self.Enforced) == 0
+}
+
+// 将强制标签合并进资源标签, 返回新的标签集合
+func (self SMandatoryTags) Merge(tags map[string]string) map[string]string {
+	ret := map[string]string{}
+	for k, v := range tags {
+		ret[k] = v
+	}
+	for k, v := range self.Defaults {
+		if _, ok := ret[k]; !ok {
+			ret[k] = v
+		}
+	}
+	for k, v := range self.Enforced {
+		ret[k] = v
+	}
+	return ret
+}
+
+type TagPolicyCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 策略生效的项目, 为空时对整个域生效
+	ProjectId string `json:"project_id"`
+
+	// 强制标签
+	// required: true
+	Tags TagPolicyTags `json:"tags"`
+
+	// 是否强制覆盖用户设置的同名标签, 并在远端标签被修改后自动恢复
+	Enforce bool `json:"enforce"`
+}
+
+type TagPolicyUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	Tags TagPolicyTags `json:"tags"`
+
+	Enforce *bool `json:"enforce"`
+}
+
+type TagPolicyListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+
+	// 按项目过滤
+	ProjectId string `json:"project_id"`
+}
+
+type TagPolicyDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+
+	// 项目名称
+	Project string `json:"project"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&TagPolicyTags{}), func() gotypes.ISerializable {
+		return &TagPolicyTags{}
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSMandatoryTags_Merge(t *testing.T) {
+	cases := []struct {
+		name      string
+		mandatory SMandatoryTags
+		tags      map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "empty policy",
+			mandatory: SMandatoryTags{},
+			tags:      map[string]string{"env": "dev"},
+			want:      map[string]string{"env": "dev"},
+		},
+		{
+			name: "default does not override user tag",
+			mandatory: SMandatoryTags{
+				Defaults: map[string]string{"env": "prod", "owner": "ops"},
+			},
+			tags: map[string]string{"env": "dev"},
+			want: map[string]string{"env": "dev", "owner": "ops"},
+		},
+		{
+			name: "enforced overrides user tag",
+			mandatory: SMandatoryTags{
+				Defaults: map[string]string{"owner": "ops"},
+				Enforced: map[string]string{"cost_center": "rd"},
+			},
+			tags: map[string]string{"cost_center": "sales"},
+			want: map[string]string{"cost_center": "rd", "owner": "ops"},
+		},
+	}
+	for _, c := range cases {
+		got := c.mandatory.Merge(c.tags)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: want %v got %v", c.name, c.want, got)
+		}
+	}
+}
@@ -83,6 +83,14 @@ type VpcCreateInput struct {
 
 	// Vpc外网访问模式
 	ExternalAccessMode string `json:"external_access_mode"`
+
+	// VPC内网DNS域名后缀
+	// example: vpc1.internal
+	DnsDomain string `json:"dns_domain"`
+
+	// VPC内虚机网卡MTU, 不设置则按 underlay MTU 自动计算
+	// example: 1400
+	Mtu int `json:"mtu"`
 }
 
 type VpcUpdateInput struct {
@@ -90,6 +98,12 @@ type VpcUpdateInput struct {
 
 	// Vpc外网访问模式
 	ExternalAccessMode string `json:"external_access_mode"`
+
+	// VPC内网DNS域名后缀, 置空以取消
+	DnsDomain *string `json:"dns_domain"`
+
+	// VPC内虚机网卡MTU, 0 表示自动计算
+	Mtu *int `json:"mtu"`
 }
 
 type VpcResourceInput struct {
@@ -27,6 +27,22 @@ import (
 // total: 36 + 4x
 const VPC_OVN_ENCAP_COST = 60
 
+const (
+	VPC_MTU_MIN = 576
+	VPC_MTU_MAX = 9000
+)
+
+// VpcOvnMtu 返回 vpc 内虚机网卡实际使用的 MTU
+//
+// vpc 未设置 MTU 或设置值超出 underlay 去除封装开销后的上限时, 取该上限
+func VpcOvnMtu(underlayMtu, vpcMtu int) int {
+	maxMtu := underlayMtu - VPC_OVN_ENCAP_COST
+	if vpcMtu > 0 && vpcMtu < maxMtu {
+		return vpcMtu
+	}
+	return maxMtu
+}
+
 const (
 	VPC_EXTERNAL_ACCESS_MODE_DISTGW     = "distgw"                              // distgw only
 	VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW = "eip-distgw"                          // eip when available, distgw otherwise
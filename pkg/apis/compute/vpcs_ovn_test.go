@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+)
+
+func TestVpcOvnMtu(t *testing.T) {
+	cases := []struct {
+		underlay, vpc, want int
+	}{
+		{1500, 0, 1440},
+		{1500, 1400, 1400},
+		{1500, 1440, 1440},
+		{1500, 9000, 1440},
+		{9000, 8000, 8000},
+		{9000, 0, 8940},
+	}
+	for _, c := range cases {
+		if got := VpcOvnMtu(c.underlay, c.vpc); got != c.want {
+			t.Errorf("VpcOvnMtu(%d, %d) = %d, want %d", c.underlay, c.vpc, got, c.want)
+		}
+	}
+}
@@ -246,6 +246,18 @@ type SCachedimage struct {
 	ImageType string `json:"image_type"`
 }
 
+// SCachedimageShare is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCachedimageShare.
+type SCachedimageShare struct {
+	apis.SStatusStandaloneResourceBase
+	SStoragecacheResourceBase
+	// 镜像缓存Id
+	CachedimageId string `json:"cachedimage_id"`
+	// 云上目标账号ID
+	TargetAccountId string `json:"target_account_id"`
+	// 平台内的目标云账号Id
+	TargetCloudaccountId string `json:"target_cloudaccount_id"`
+}
+
 // SCloudaccount is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCloudaccount.
 type SCloudaccount struct {
 	apis.SEnabledStatusInfrasResourceBase
@@ -308,6 +320,17 @@ type SCloudaccount struct {
 	LakeOfPermissions *SAccountPermissions `json:"lake_of_permissions"`
 }
 
+// SCloudaccountSyncProfile is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCloudaccountSyncProfile.
+type SCloudaccountSyncProfile struct {
+	apis.SStandaloneAnonResourceBase
+	CloudaccountId string `json:"cloudaccount_id"`
+	Resource       string `json:"resource"`
+	// 同步间隔(秒)
+	IntervalSeconds int `json:"interval_seconds"`
+	// 最近一次按该配置触发同步的时间
+	LastSyncAt time.Time `json:"last_sync_at"`
+}
+
 // SCloudimage is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCloudimage.
 type SCloudimage struct {
 	apis.SStandaloneResourceBase
@@ -386,6 +409,10 @@ type SCloudproviderregion struct {
 	SyncResults    jsonutils.JSONObject `json:"sync_results"`
 	LastDeepSyncAt time.Time            `json:"last_deep_sync_at"`
 	LastAutoSyncAt time.Time            `json:"last_auto_sync_at"`
+	// 最近一次同步耗时(秒)
+	LastSyncCostSeconds int `json:"last_sync_cost_seconds"`
+	// 最近一次同步的错误信息, 同步成功时为空
+	LastSyncError string `json:"last_sync_error"`
 }
 
 // SCloudproviderschedtag is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SCloudproviderschedtag.
@@ -421,7 +448,19 @@ type SCloudregionschedtag struct {
 	SCloudregionResourceBase
 }
 
-// SDBInstance is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDBInstance.
+// SConfigHistory is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SConfigHistory.
+type SConfigHistory struct {
+	apis.SStandaloneAnonResourceBase
+	ResourceType string `json:"resource_type"`
+	ResourceId   string `json:"resource_id"`
+	ResourceName string `json:"resource_name"`
+	// 同一资源的配置版本号, 从1开始递增
+	Version  int                  `json:"version"`
+	Trigger  string               `json:"trigger"`
+	Checksum string               `json:"checksum"`
+	Spec     jsonutils.JSONObject `json:"spec"`
+}
+
 type SDBInstance struct {
 	apis.SVirtualResourceBase
 	apis.SExternalizedResourceBase
@@ -528,6 +567,25 @@ type SDBInstanceDatabase struct {
 	CharacterSet string `json:"character_set"`
 }
 
+// SDBInstanceDump is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDBInstanceDump.
+type SDBInstanceDump struct {
+	apis.SVirtualResourceBase
+	// 源RDS实例
+	DBInstanceId  string `json:"dbinstance_id"`
+	Engine        string `json:"engine"`
+	EngineVersion string `json:"engine_version"`
+	Format        string `json:"format"`
+	// 逗号分隔的数据库名称
+	Databases string `json:"databases"`
+	BucketId  string `json:"bucket_id"`
+	ObjectKey string `json:"object_key"`
+	// 压缩后的导出文件大小
+	SizeMb         int    `json:"size_mb"`
+	WorkerServerId string `json:"worker_server_id"`
+	// 最近一次恢复的目标实例
+	RestoredDBInstanceId string `json:"restored_dbinstance_id"`
+}
+
 // SDBInstanceJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDBInstanceJointsBase.
 type SDBInstanceJointsBase struct {
 	apis.SVirtualJointResourceBase
@@ -600,6 +658,22 @@ type SDBInstanceSku struct {
 	MultiAZ        *bool  `json:"multi_az,omitempty"`
 }
 
+// SDedicatedHost is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDedicatedHost.
+type SDedicatedHost struct {
+	apis.SStatusInfrasResourceBase
+	apis.SExternalizedResourceBase
+	SManagedResourceBase
+	SCloudregionResourceBase
+	SZoneResourceBase
+	// 专有宿主机规格
+	// example: ddh.g6
+	HostType string `json:"host_type"`
+	// 可分配CPU核数
+	CpuCount int `json:"cpu_count"`
+	// 可分配内存, 单位MB
+	MemSizeMb int `json:"mem_size_mb"`
+}
+
 // SDeletePreventableResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDeletePreventableResourceBase.
 type SDeletePreventableResourceBase struct {
 	// 是否开启删除保护
@@ -667,6 +741,16 @@ type SDiskBackup struct {
 	// 操作系统类型
 	OsType     string             `json:"os_type"`
 	DiskConfig *SBackupDiskConfig `json:"disk_config"`
+	// 备份文件md5
+	Checksum string `json:"checksum"`
+	// 最近一次完整性校验结果
+	VerifyStatus string `json:"verify_status"`
+	// 最近一次完整性校验时间
+	VerifiedAt time.Time `json:"verified_at"`
+	// 备份模式
+	BackupMode string `json:"backup_mode"`
+	// 增量备份的父备份
+	ParentBackupId string `json:"parent_backup_id"`
 }
 
 // SDiskResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDiskResourceBase.
@@ -1000,6 +1084,8 @@ type SElasticip struct {
 	BgpType string `json:"bgp_type"`
 	// 是否跟随主机删除而自动释放
 	AutoDellocate *bool `json:"auto_dellocate,omitempty"`
+	// 所在共享带宽的云上Id, 独享带宽时为空
+	SharedBandwidthId string `json:"shared_bandwidth_id"`
 }
 
 // SExternalProject is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SExternalProject.
@@ -1010,6 +1096,32 @@ type SExternalProject struct {
 	ExternalDomainId string `json:"external_domain_id"`
 	// 归属云账号ID
 	CloudaccountId string `json:"cloudaccount_id"`
+	// 映射优先级, 本地项目映射多个云上项目时优先使用优先级高的云上项目
+	Priority int `json:"priority"`
+	// 是否为手动指定的映射, 手动映射不会被同步自动修改
+	ManualMapping bool `json:"manual_mapping"`
+}
+
+// SFailoverVip is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SFailoverVip.
+type SFailoverVip struct {
+	apis.SVirtualResourceBase
+	SNetworkResourceBase
+	// VIP地址
+	IpAddr string `json:"ip_addr"`
+	// 当前绑定VIP的主虚机
+	ActiveGuestId string `json:"active_guest_id"`
+	// 备虚机
+	StandbyGuestId string `json:"standby_guest_id"`
+	// 是否根据健康检查自动切换
+	AutoFailover bool `json:"auto_failover"`
+	// 健康检查类型
+	HealthCheckType string `json:"health_check_type"`
+	// tcp健康检查端口
+	HealthCheckPort int `json:"health_check_port"`
+	// 连续失败多少次后切换
+	HealthCheckFailures int `json:"health_check_failures"`
+	// 最近一次切换时间
+	LastFailoverAt time.Time `json:"last_failover_at"`
 }
 
 // SFileSystem is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SFileSystem.
@@ -1138,7 +1250,12 @@ type SGuest struct {
 	// example: kvm
 	Hypervisor string `json:"hypervisor"`
 	// 套餐名称
-	InstanceType     string `json:"instance_type"`
+	InstanceType string `json:"instance_type"`
+	// QoS等级, 仅KVM有效
+	// example: burstable
+	QosClass string `json:"qos_class"`
+	// 所在专有宿主机Id
+	DedicatedHostId  string `json:"dedicated_host_id"`
 	SshableLastState *bool  `json:"sshable_last_state,omitempty"`
 	IsDaemon         *bool  `json:"is_daemon,omitempty"`
 	// 最大内网带宽
@@ -1156,6 +1273,38 @@ type SGuestJointsBase struct {
 	GuestId string `json:"guest_id"`
 }
 
+// SGuestMaintenanceEvent is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestMaintenanceEvent.
+type SGuestMaintenanceEvent struct {
+	apis.SStatusStandaloneResourceBase
+	apis.SExternalizedResourceBase
+	SGuestResourceBase
+	// 事件类型
+	// example: retirement
+	EventType string `json:"event_type"`
+	// 计划执行时间窗口
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// 处理完成时间
+	RemediatedAt time.Time `json:"remediated_at"`
+}
+
+// SGuestPasswordPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestPasswordPolicy.
+type SGuestPasswordPolicy struct {
+	apis.SDomainLevelResourceBase
+	MinLength         int  `json:"min_length"`
+	RequireDigits     bool `json:"require_digits"`
+	RequireLowercases bool `json:"require_lowercases"`
+	RequireUppercases bool `json:"require_uppercases"`
+	RequirePunctuats  bool `json:"require_punctuats"`
+	ExpireDays        int  `json:"expire_days"`
+	RemindDays        int  `json:"remind_days"`
+}
+
+// SGuestResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestResourceBase.
+type SGuestResourceBase struct {
+	GuestId string `json:"guest_id"`
+}
+
 // SGuestTemplate is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestTemplate.
 type SGuestTemplate struct {
 	apis.SSharableVirtualResourceBase
@@ -1219,6 +1368,10 @@ type SGuestnetwork struct {
 	NumQueues int `json:"num_queues"`
 	// 带宽限制，单位mbps
 	BwLimit int `json:"bw_limit"`
+	// 入方向(宿主机到虚机)限速，单位mbps，0表示沿用BwLimit
+	IngressBwLimit int `json:"ingress_bw_limit"`
+	// 出方向(虚机到宿主机)限速，单位mbps，0表示沿用BwLimit
+	EgressBwLimit int `json:"egress_bw_limit"`
 	// 网卡序号
 	Index byte `json:"index"`
 	// 是否为虚拟接口（无IP）
@@ -1334,6 +1487,22 @@ type SHostJointsBase struct {
 	apis.SJointResourceBase
 }
 
+// SHostLogBundle is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SHostLogBundle.
+type SHostLogBundle struct {
+	apis.SStatusStandaloneResourceBase
+	SHostResourceBase
+	// 收集 qemu 日志的虚机
+	GuestId    string `json:"guest_id"`
+	IncludeOvs bool   `json:"include_ovs"`
+	SinceHours int    `json:"since_hours"`
+	BucketId   string `json:"bucket_id"`
+	ObjectKey  string `json:"object_key"`
+	SizeBytes  int64  `json:"size_bytes"`
+	// 日志包内的文件数
+	FileCount   int       `json:"file_count"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
 // SHostResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SHostResourceBase.
 type SHostResourceBase struct {
 	HostId string `json:"host_id"`
@@ -1501,6 +1670,8 @@ type SInstanceSnapshot struct {
 	MemoryFilePath string `json:"memory_file_path"`
 	// 内存文件校验和
 	MemoryFileChecksum string `json:"memory_file_checksum"`
+	// 父主机快照Id
+	ParentId string `json:"parent_id"`
 }
 
 // SInterVpcNetwork is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SInterVpcNetwork.
@@ -1898,6 +2069,10 @@ type SLoadbalancerListener struct {
 	AclType            string `json:"acl_type"`
 	SLoadbalancerAclResourceBase
 	CachedAclId string `json:"cached_acl_id"`
+	// 访问日志投递, 云上按负载均衡实例生效
+	AccessLogStatus  string `json:"access_log_status"`
+	AccessLogProject string `json:"access_log_project"`
+	AccessLogTopic   string `json:"access_log_topic"`
 	SLoadbalancerRateLimiter
 	SLoadbalancerTCPListener
 	SLoadbalancerUDPListener
@@ -2317,6 +2492,21 @@ type SPolicyDefinitionResourceBase struct {
 	PolicydefinitionId string `json:"policydefinition_id"`
 }
 
+// SPricingRate is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SPricingRate.
+type SPricingRate struct {
+	apis.SEnabledStatusInfrasResourceBase
+	// 资源类型
+	ResourceType string `json:"resource_type"`
+	// 规格, 为空表示该资源类型的默认价格
+	Spec string `json:"spec"`
+	// 单价
+	Price float64 `json:"price"`
+	// 货币
+	Currency string `json:"currency"`
+	// 生效时间
+	EffectiveDate time.Time `json:"effective_date"`
+}
+
 // SProjectMapping is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SProjectMapping.
 type SProjectMapping struct {
 	apis.SEnabledStatusInfrasResourceBase
@@ -2328,6 +2518,21 @@ type SProjectMappingResourceBase struct {
 	ProjectMappingId string `json:"project_mapping_id"`
 }
 
+// SProjectPosture is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SProjectPosture.
+type SProjectPosture struct {
+	apis.SEnabledStatusInfrasResourceBase
+	// 生效的项目, 为空时作为整个域的缺省基线
+	ProjectId        string         `json:"project_id"`
+	DefaultSecgroups *PostureIdList `json:"default_secgroups"`
+	// 强制标签, 值为空表示必须由用户指定
+	MandatoryTags *TagPolicyTags `json:"mandatory_tags"`
+	// 缺省磁盘加密密钥
+	EncryptKeyId string `json:"encrypt_key_id"`
+	ImdsMode     string `json:"imds_mode"`
+	// 允许使用的镜像, 为空表示不限制
+	AllowedImages *PostureIdList `json:"allowed_images"`
+}
+
 // SQcloudCachedLb is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SQcloudCachedLb.
 type SQcloudCachedLb struct {
 	apis.SVirtualResourceBase
@@ -2406,6 +2611,37 @@ type SRouteTableRouteSet struct {
 	ExtNextHopId string `json:"ext_next_hop_id"`
 }
 
+// SRunbook is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SRunbook.
+type SRunbook struct {
+	apis.SEnabledStatusStandaloneResourceBase
+	// 按顺序执行的动作
+	Steps *RunbookSteps `json:"steps"`
+	// 执行前是否需要人工审批
+	RequireApproval *bool `json:"require_approval,omitempty"`
+	// 同一虚拟机两次执行的最小间隔(分钟)
+	CooldownMinutes int `json:"cooldown_minutes"`
+}
+
+// SRunbookExecution is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SRunbookExecution.
+type SRunbookExecution struct {
+	apis.SStatusStandaloneResourceBase
+	RunbookId string `json:"runbook_id"`
+	// 虚拟机删除后仍保留以便审计
+	GuestId string `json:"guest_id"`
+	AlertId string `json:"alert_id"`
+	// 触发时的动作快照, 手册修改不影响已触发的执行
+	Steps *RunbookSteps `json:"steps"`
+	// 执行参数
+	Params jsonutils.JSONObject `json:"params"`
+	// 当前执行到的动作序号
+	StepIndex int `json:"step_index"`
+	// 每个动作的执行结果
+	Results    *RunbookStepResults `json:"results"`
+	ApprovedBy string              `json:"approved_by"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+}
+
 // SScalingActivity is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingActivity.
 type SScalingActivity struct {
 	apis.SStatusStandaloneResourceBase
@@ -2587,6 +2823,12 @@ type SSecurityGroupRule struct {
 	Description    string `json:"description"`
 	PeerSecgroupId string `json:"peer_secgroup_id"`
 	IsDirty        bool   `json:"is_dirty"`
+	// 命中报文数, 由 vpcagent 汇总 OVN ACL 计数或公有云流日志上报
+	HitCount          int64     `json:"hit_count"`
+	LastHitAt         time.Time `json:"last_hit_at"`
+	HitCountUpdatedAt time.Time `json:"hit_count_updated_at"`
+	// 累计型计数上次上报的原始值, 用于计算增量
+	HitCountRaw int64 `json:"hit_count_raw"`
 }
 
 // SServerSku is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SServerSku.
@@ -2626,6 +2868,15 @@ type SServerSku struct {
 	GpuCount           int    `json:"gpu_count"`
 	GpuMaxCount        int    `json:"gpu_max_count"`
 	Provider           string `json:"provider"`
+	// 本地套餐的QoS等级 guaranteed|burstable|best-effort, 决定cgroup权重和调度时的超售计算
+	QosClass string `json:"qos_class"`
+	// 抢占式实例最新价格
+	SpotPrice float64 `json:"spot_price"`
+	// 抢占式实例中断率, 0-1
+	SpotInterruptionRate float64   `json:"spot_interruption_rate"`
+	SpotPriceUpdatedAt   time.Time `json:"spot_price_updated_at"`
+	// 私有云自定义套餐, 不在云平台创建, 也不会被同步删除
+	IsCustom *bool `json:"is_custom,omitempty"`
 }
 
 // SServiceCatalog is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SServiceCatalog.
@@ -2662,6 +2913,34 @@ type SSnapshot struct {
 	ExpiredAt     time.Time `json:"expired_at"`
 }
 
+// SSnapshotArchive is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotArchive.
+type SSnapshotArchive struct {
+	apis.SStatusStandaloneResourceBase
+	apis.SExternalizedResourceBase
+	PolicyId   string `json:"policy_id"`
+	SnapshotId string `json:"snapshot_id"`
+	// 源磁盘Id, 源磁盘删除后仍保留以便审计
+	DiskId               string `json:"disk_id"`
+	SourceCloudaccountId string `json:"source_cloudaccount_id"`
+	VaultCloudaccountId  string `json:"vault_cloudaccount_id"`
+	VaultCloudproviderId string `json:"vault_cloudprovider_id"`
+	CloudregionId        string `json:"cloudregion_id"`
+	// 快照大小, 单位MB
+	SizeMb     int       `json:"size_mb"`
+	ArchivedAt time.Time `json:"archived_at"`
+	// 合规保留截止时间, 之前不允许删除
+	RetainUntil time.Time `json:"retain_until"`
+}
+
+// SSnapshotArchivePolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotArchivePolicy.
+type SSnapshotArchivePolicy struct {
+	apis.SEnabledStatusStandaloneResourceBase
+	// 金库云账号Id
+	VaultCloudaccountId string `json:"vault_cloudaccount_id"`
+	// 合规保留天数
+	RetentionDays int `json:"retention_days"`
+}
+
 // SSnapshotPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotPolicy.
 type SSnapshotPolicy struct {
 	apis.SVirtualResourceBase
@@ -2671,6 +2950,12 @@ type SSnapshotPolicy struct {
 	// 0~23
 	TimePoints  uint32 `json:"time_points"`
 	IsActivated *bool  `json:"is_activated,omitempty"`
+	// GFS 轮转保留的日快照个数, 0 表示不保留日快照
+	RetentionDaily int `json:"retention_daily"`
+	// GFS 轮转保留的周快照个数
+	RetentionWeekly int `json:"retention_weekly"`
+	// GFS 轮转保留的月快照个数
+	RetentionMonthly int `json:"retention_monthly"`
 }
 
 // SSnapshotPolicyCache is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotPolicyCache.
@@ -2725,6 +3010,8 @@ type SStorage struct {
 	// 是否可以用作系统盘存储
 	// example: true
 	IsSysDiskStore *bool `json:"is_sys_disk_store,omitempty"`
+	// 镜像预取的缓存预算,单位Mb, 0 表示使用全局默认值, -1 表示不预取
+	ImageCacheBudgetMb int64 `json:"image_cache_budget_mb"`
 }
 
 // SStorageResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SStorageResourceBase.
@@ -2761,6 +3048,12 @@ type SStoragecachedimage struct {
 	LastDownload time.Time `json:"last_download"`
 	// 下载引用次数
 	DownloadRefcnt int `json:"download_refcnt"`
+	// 缓存文件md5
+	Checksum string `json:"checksum"`
+	// 最近一次完整性校验结果
+	VerifyStatus string `json:"verify_status"`
+	// 最近一次完整性校验时间
+	VerifiedAt time.Time `json:"verified_at"`
 }
 
 // SStorageschedtag is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SStorageschedtag.
@@ -2785,6 +3078,31 @@ type STablestore struct {
 	SManagedResourceBase
 }
 
+// STagBackfillJob is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.STagBackfillJob.
+type STagBackfillJob struct {
+	apis.SStatusStandaloneResourceBase
+	CloudaccountId string `json:"cloudaccount_id"`
+	// 逗号分隔的资源类型
+	ResourceTypes string           `json:"resource_types"`
+	Rule          *TagBackfillRule `json:"rule"`
+	// 每秒调用云上接口次数
+	RateLimit  int                `json:"rate_limit"`
+	Cursor     *TagBackfillCursor `json:"cursor"`
+	Report     *TagBackfillReport `json:"report"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+}
+
+// STagPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.STagPolicy.
+type STagPolicy struct {
+	apis.SEnabledStatusInfrasResourceBase
+	// 生效的项目, 为空时对整个域生效
+	ProjectId string         `json:"project_id"`
+	Tags      *TagPolicyTags `json:"tags"`
+	// 是否强制覆盖同名标签
+	Enforce *bool `json:"enforce,omitempty"`
+}
+
 // STimer is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.STimer.
 type STimer struct {
 	// Cycle type
@@ -2817,6 +3135,12 @@ type SVpc struct {
 	ExternalAccessMode string `json:"external_access_mode"`
 	// Can it be connected directly
 	Direct bool `json:"direct"`
+	// VPC内网DNS域名后缀, 虚机可通过 <主机名>.<后缀> 相互解析
+	// example: vpc1.internal
+	DnsDomain string `json:"dns_domain"`
+	// VPC内虚机网卡MTU, 0 表示按 underlay MTU 减去封装开销自动计算
+	// example: 1400
+	Mtu int `json:"mtu"`
 }
 
 // SVpcPeeringConnection is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SVpcPeeringConnection.
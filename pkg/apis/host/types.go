@@ -32,8 +32,37 @@ type HostCPUInfo struct {
 	*cpu.Info
 }
 
+type GuestSetNicBandwidthRequest struct {
+	Mac string `json:"mac"`
+	// 入方向(宿主机->虚机)限速，单位mbps，0表示不限速
+	BwIngress int `json:"bw_ingress"`
+	// 出方向(虚机->宿主机)限速，单位mbps，0表示不限速
+	BwEgress int `json:"bw_egress"`
+}
+
 type GuestSetPasswordRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Crypted  bool   `json:"crypted"`
 }
+
+const (
+	GUEST_START_CHECK_VNC_PORT = "vnc_port"
+	GUEST_START_CHECK_HUGEPAGE = "hugepage"
+	GUEST_START_CHECK_BRIDGE   = "bridge"
+	GUEST_START_CHECK_OVMF     = "ovmf"
+	GUEST_START_CHECK_CGROUP   = "cgroup"
+	GUEST_START_CHECK_DISK     = "disk"
+
+	GUEST_START_CHECK_ISOLATED_DEVICE = "isolated_device"
+)
+
+// 虚机启动前资源检查失败项
+type GuestStartCheckFailure struct {
+	// 检查项, 例如 vnc_port, hugepage, bridge
+	Item string `json:"item"`
+	// 失败原因
+	Reason string `json:"reason"`
+	// 处理建议
+	Suggestion string `json:"suggestion"`
+}
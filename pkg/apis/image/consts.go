@@ -65,6 +65,7 @@ const (
 	IMAGE_INSTALLED_CLOUDINIT = "installed_cloud_init"
 	IMAGE_DISABLE_USB_KBD     = "disable_usb_kbd"
 	IMAGE_VDI_PROTOCOL        = "vdi_protocol"
+	IMAGE_TPM_REQUIRED        = "tpm_required"
 
 	IMAGE_STATUS_UPDATING = "updating"
 )
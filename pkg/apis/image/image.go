@@ -75,6 +75,8 @@ type ImageDetails struct {
 	AutoDeleteAt time.Time `json:"auto_delete_at"`
 	// 删除保护
 	DisableDelete bool `json:"disable_delete"`
+	// 多架构镜像的各架构版本
+	ArchVariants []ImageArchVariant `json:"arch_variants,omitempty"`
 	//OssChecksum   string    `json:"oss_checksum"`
 }
 
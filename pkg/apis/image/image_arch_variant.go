@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+type ImageArchVariantAddInput struct {
+	// 对应架构的镜像ID或名称
+	VariantImageId string `json:"variant_image_id"`
+}
+
+type ImageArchVariantRemoveInput struct {
+	// 要移除的架构, 例如 x86, arm
+	OsArch string `json:"os_arch"`
+}
+
+type ImageArchVariantInput struct {
+	// 目标架构, 例如 x86_64, aarch64
+	OsArch string `json:"os_arch"`
+}
+
+type ImageArchVariant struct {
+	// 架构, x86 或 arm
+	OsArch    string `json:"os_arch"`
+	ImageId   string `json:"image_id"`
+	ImageName string `json:"image_name"`
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	IMAGE_CHANNEL_STAGE_DEV     = "dev"
+	IMAGE_CHANNEL_STAGE_STAGING = "staging"
+	IMAGE_CHANNEL_STAGE_PROD    = "prod"
+
+	IMAGE_CHANNEL_PROMOTION_PENDING  = "pending"
+	IMAGE_CHANNEL_PROMOTION_APPROVED = "approved"
+	IMAGE_CHANNEL_PROMOTION_REJECTED = "rejected"
+)
+
+var IMAGE_CHANNEL_DEFAULT_STAGES = []string{
+	IMAGE_CHANNEL_STAGE_DEV,
+	IMAGE_CHANNEL_STAGE_STAGING,
+	IMAGE_CHANNEL_STAGE_PROD,
+}
+
+type ImageChannelCreateInput struct {
+	apis.SharableVirtualResourceCreateInput
+
+	// 发布阶段, 按顺序晋升, 默认为 dev,staging,prod
+	Stages []string `json:"stages"`
+	// 需要审批才能晋升的阶段
+	// example: prod
+	ApprovalStages []string `json:"approval_stages"`
+}
+
+type ImageChannelUpdateInput struct {
+	apis.SharableVirtualResourceBaseUpdateInput
+
+	ApprovalStages []string `json:"approval_stages"`
+}
+
+type ImageChannelListInput struct {
+	apis.SharableVirtualResourceListInput
+
+	// 当前发布了此镜像的频道
+	ImageId string `json:"image_id"`
+}
+
+type ImageChannelStageImage struct {
+	Stage      string    `json:"stage"`
+	ImageId    string    `json:"image_id"`
+	ImageName  string    `json:"image_name"`
+	PromotedAt time.Time `json:"promoted_at"`
+	PromotedBy string    `json:"promoted_by"`
+}
+
+type ImageChannelDetails struct {
+	apis.SharableVirtualResourceDetails
+
+	SImageChannel
+
+	StageImages []ImageChannelStageImage `json:"stage_images"`
+	// 待审批的晋升数量
+	PendingPromotions int `json:"pending_promotions"`
+}
+
+type ImageChannelPromoteInput struct {
+	// 晋升的镜像, 除第一个阶段外, 镜像必须已发布到上一个阶段
+	ImageId string `json:"image_id"`
+	// 目标阶段
+	Stage string `json:"stage"`
+	// 晋升原因
+	Reason string `json:"reason"`
+}
+
+type ImageChannelApproveInput struct {
+	PromotionId string `json:"promotion_id"`
+	Reason      string `json:"reason"`
+}
+
+type ImageChannelResolveInput struct {
+	// 频道引用, 格式为 <频道名称>-<阶段>, 例如 ubuntu22-prod
+	Ref string `json:"ref"`
+}
+
+type ImageChannelResolveOutput struct {
+	ChannelId string `json:"channel_id"`
+	Channel   string `json:"channel"`
+	Stage     string `json:"stage"`
+	ImageId   string `json:"image_id"`
+	ImageName string `json:"image_name"`
+}
+
+type ImageChannelPromotionsInput struct {
+	// 按状态过滤, 例如 pending
+	Status string `json:"status"`
+}
+
+type ImageChannelPromotionDetails struct {
+	SImageChannelPromotion
+
+	ImageName string `json:"image_name"`
+}
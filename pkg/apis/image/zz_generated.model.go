@@ -15,6 +15,8 @@
 package image
 
 import (
+	"time"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -68,6 +70,48 @@ type SImage struct {
 	EncryptStatus string `json:"encrypt_status"`
 }
 
+// SImageArchVariant is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageArchVariant.
+type SImageArchVariant struct {
+	apis.SResourceBase
+	ImageId        string `json:"image_id"`
+	OsArch         string `json:"os_arch"`
+	VariantImageId string `json:"variant_image_id"`
+}
+
+// SImageChannel is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageChannel.
+type SImageChannel struct {
+	apis.SSharableVirtualResourceBase
+	// 发布阶段, 逗号分隔, 按晋升顺序排列
+	Stages string `json:"stages"`
+	// 需要审批的阶段, 逗号分隔
+	ApprovalStages string `json:"approval_stages"`
+}
+
+// SImageChannelPromotion is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageChannelPromotion.
+type SImageChannelPromotion struct {
+	apis.SStandaloneAnonResourceBase
+	ChannelId     string `json:"channel_id"`
+	ImageId       string `json:"image_id"`
+	FromStage     string `json:"from_stage"`
+	Stage         string `json:"stage"`
+	Status        string `json:"status"`
+	RequestedBy   string `json:"requested_by"`
+	RequestedById string `json:"requested_by_id"`
+	Reason        string `json:"reason"`
+	ReviewedBy    string `json:"reviewed_by"`
+	ReviewReason  string `json:"review_reason"`
+}
+
+// SImageChannelStage is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageChannelStage.
+type SImageChannelStage struct {
+	apis.SResourceBase
+	ChannelId  string    `json:"channel_id"`
+	Stage      string    `json:"stage"`
+	ImageId    string    `json:"image_id"`
+	PromotedAt time.Time `json:"promoted_at"`
+	PromotedBy string    `json:"promoted_by"`
+}
+
 // SImageMember is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageMember.
 type SImageMember struct {
 	SImagePeripheral
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	GUEST_FLOW_DIRECTION_IN  = "in"
+	GUEST_FLOW_DIRECTION_OUT = "out"
+)
+
+// 宿主机从 conntrack 采样到的一条虚拟机流量, Port 为服务端端口
+type SGuestFlowSample struct {
+	GuestId   string `json:"guest_id"`
+	IpAddr    string `json:"ip_addr"`
+	VpcId     string `json:"vpc_id"`
+	Direction string `json:"direction"`
+	PeerIp    string `json:"peer_ip"`
+	Protocol  string `json:"protocol"`
+	Port      int    `json:"port"`
+	// 本次采样到的连接数
+	Connections int `json:"connections"`
+}
+
+type GuestFlowReportInput struct {
+	HostId  string             `json:"host_id"`
+	Samples []SGuestFlowSample `json:"samples"`
+}
+
+type GuestFlowListInput struct {
+	apis.StandaloneAnonResourceListInput
+
+	GuestId   []string `json:"guest_id"`
+	HostId    string   `json:"host_id"`
+	PeerIp    string   `json:"peer_ip"`
+	Direction string   `json:"direction"`
+	Protocol  string   `json:"protocol"`
+	Port      []int    `json:"port"`
+}
+
+type GuestFlowDetails struct {
+	apis.StandaloneAnonResourceDetails
+
+	SGuestFlow
+}
+
+type GuestFlowTopologyInput struct {
+	// 虚拟机id
+	GuestId string `json:"guest_id"`
+	// 只统计最近多少小时内出现过的流量, 默认24
+	Hours int `json:"hours"`
+}
+
+type SGuestFlowPeer struct {
+	// 对端虚拟机id, 对端不是已采样的虚拟机时为空
+	PeerGuestId string `json:"peer_guest_id"`
+	PeerIp      string `json:"peer_ip"`
+	// in: 对端访问该虚拟机, out: 该虚拟机访问对端
+	Direction   string    `json:"direction"`
+	Protocol    string    `json:"protocol"`
+	Ports       []int     `json:"ports"`
+	Connections int64     `json:"connections"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+type GuestFlowTopologyOutput struct {
+	GuestId string           `json:"guest_id"`
+	Peers   []SGuestFlowPeer `json:"peers"`
+}
@@ -36,6 +36,7 @@ const (
 	AlertNotificationTypeFeishu        = "feishu"
 	AlertNotificationTypeAutoScaling   = "autoscaling"
 	AlertNotificationTypeAutoMigration = "automigration"
+	AlertNotificationTypeRunbook       = "runbook"
 )
 
 type NotificationCreateInput struct {
@@ -109,3 +110,11 @@ type NotificationSettingFeishu struct {
 type NotificationSettingAutoMigration struct {
 	AlertId string `json:"alert_id"`
 }
+
+type NotificationSettingRunbook struct {
+	// 告警触发执行的运维手册
+	RunbookId string `json:"runbook_id"`
+	// 从告警匹配的标签中获取虚拟机Id的标签名
+	// default: vm_id
+	ResourceTag string `json:"resource_tag"`
+}
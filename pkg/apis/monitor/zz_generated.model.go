@@ -156,6 +156,23 @@ type SDataSource struct {
 	IsDefault *bool  `json:"is_default,omitempty"`
 }
 
+// SGuestFlow is an autogenerated struct via yunion.io/x/onecloud/pkg/monitor/models.SGuestFlow.
+type SGuestFlow struct {
+	apis.SStandaloneAnonResourceBase
+	HostId    string `json:"host_id"`
+	GuestId   string `json:"guest_id"`
+	IpAddr    string `json:"ip_addr"`
+	VpcId     string `json:"vpc_id"`
+	Direction string `json:"direction"`
+	PeerIp    string `json:"peer_ip"`
+	Protocol  string `json:"protocol"`
+	Port      int    `json:"port"`
+	// 累计采样到的连接数
+	Connections int64     `json:"connections"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
 // SMeterAlert is an autogenerated struct via yunion.io/x/onecloud/pkg/monitor/models.SMeterAlert.
 type SMeterAlert struct {
 	SV1Alert
@@ -54,6 +54,10 @@ var (
 
 	ActionExceedCount SAction = "exceed_count"
 
+	ActionPasswordExpireSoon SAction = "password_expire_soon"
+
+	ActionScheduledMaintenance SAction = "scheduled_maintenance"
+
 	ResultFailed  SResult = "failed"
 	ResultSucceed SResult = "succeed"
 )
@@ -34,4 +34,14 @@ const (
 	APSARA    = "apsara"
 	JDCLOUD   = "jdcloud"
 	CLOUDPODS = "cloudpods"
+
+	// 物理机串口控制台(serial over lan)
+	SOL = "sol"
+)
+
+const (
+	// 通过 ipmitool sol activate 连接 BMC
+	BAREMETAL_SOL_METHOD_IPMITOOL = "ipmitool"
+	// 通过 BMC 的 ssh 串口控制台(redfish Manager.SerialConsole)连接
+	BAREMETAL_SOL_METHOD_REDFISH = "redfish"
 )
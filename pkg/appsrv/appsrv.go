@@ -311,7 +311,11 @@ func (t *appTask) Run() {
 				}
 			}()
 			t.ctx = context.WithValue(t.ctx, appctx.APP_CONTEXT_KEY_TRACE, span)
-			t.hand.handler(t.ctx, &t.fw, t.r)
+			handler := t.hand.handler
+			for i := len(t.app.middlewares) - 1; i >= 0; i-- {
+				handler = t.app.middlewares[i](handler)
+			}
+			handler(t.ctx, &t.fw, t.r)
 		}()
 	} // otherwise, the task has been timeout
 	t.fw.closeChannels()
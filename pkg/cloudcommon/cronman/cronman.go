@@ -334,12 +334,17 @@ func (self *SCronJobManager) next(now time.Time) {
 }
 
 func (self *SCronJobManager) Start2(ctx context.Context, electObj *elect.Elect) {
-	ctx, self.stopFunc = context.WithCancel(ctx)
 	if electObj == nil {
+		ctx, self.stopFunc = context.WithCancel(ctx)
 		self.start(ctx)
 		return
 	}
-	electObj.SubscribeWithAction(ctx, func() { self.start(ctx) }, self.Stop)
+	// 每次当选使用独立的 context, 失去主节点身份后仍可在再次当选时恢复运行
+	electObj.SubscribeWithAction(ctx, func() {
+		var runCtx context.Context
+		runCtx, self.stopFunc = context.WithCancel(ctx)
+		self.start(runCtx)
+	}, self.Stop)
 }
 
 func (self *SCronJobManager) Start() {
@@ -360,7 +365,9 @@ func (self *SCronJobManager) start(ctx context.Context) {
 }
 
 func (self *SCronJobManager) Stop() {
-	self.stopFunc()
+	if self.stopFunc != nil {
+		self.stopFunc()
+	}
 }
 
 func (self *SCronJobManager) init() {
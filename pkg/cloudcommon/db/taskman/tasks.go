@@ -948,3 +948,28 @@ func (manager *STaskManager) FetchTasksOfObject(obj db.IStandaloneModel, since t
 	}
 	return tasks, nil
 }
+
+// FailInterruptedTasks 以失败状态回调创建于 since 之后, 且在 before 之前就不再更新的未完成任务,
+// 用于控制面主备切换后让原主节点上中断的任务进入各自的失败处理流程
+func (manager *STaskManager) FailInterruptedTasks(ctx context.Context, since, before time.Time, reason string) (int, error) {
+	q := manager.Query().NotIn("stage", []string{TASK_STAGE_COMPLETE, TASK_STAGE_FAILED})
+	q = q.GE("created_at", since).LT("updated_at", before)
+	tasks := make([]STask, 0)
+	err := db.FetchModelObjects(manager, q, &tasks)
+	if err != nil {
+		return 0, errors.Wrap(err, "FetchModelObjects")
+	}
+	cnt := 0
+	for i := range tasks {
+		data := jsonutils.NewDict()
+		data.Set("__status__", jsonutils.NewString("error"))
+		data.Set("__reason__", jsonutils.NewString(reason))
+		err := runTask(tasks[i].Id, data)
+		if err != nil {
+			log.Errorf("fail interrupted task %s(%s): %v", tasks[i].TaskName, tasks[i].Id, err)
+			continue
+		}
+		cnt += 1
+	}
+	return cnt, nil
+}
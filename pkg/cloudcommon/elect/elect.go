@@ -74,6 +74,10 @@ type Elect struct {
 	mutex       *sync.Mutex
 	latestEv    electEvent
 	subscribers []chan electEvent
+
+	// value 当选后写入 leader key, 供其他节点查询当前主节点
+	value   string
+	current *ticket
 }
 
 type ticket struct {
@@ -135,6 +139,8 @@ func (elect *Elect) Start(ctx context.Context) {
 				ticket.tearup(ctx)
 				now = electEventLost
 				log.Errorf("elect error: %v", err)
+			} else {
+				elect.setCurrent(ctx, ticket)
 			}
 			if now != prev {
 				log.Infof("notify elect event: %s -> %s", prev, now)
@@ -144,9 +150,18 @@ func (elect *Elect) Start(ctx context.Context) {
 			if err == nil {
 				select {
 				case <-ctx.Done():
-					ticket.tearup(ctx)
 				case <-ticket.session.Done():
 				}
+				elect.setCurrent(ctx, nil)
+				if ctx.Err() != nil {
+					ticket.tearup(ctx)
+				}
+				// session 失效后先放弃主节点身份再重新参选, 避免阻塞在 Lock 期间仍以主节点运行
+				if prev == electEventWin {
+					log.Infof("notify elect event: %s -> %s", prev, electEventLost)
+					prev = electEventLost
+					elect.notify(ctx, prev)
+				}
 			} else {
 				time.Sleep(3 * time.Second)
 			}
@@ -176,6 +191,59 @@ func (elect *Elect) do(ctx context.Context) (*ticket, error) {
 	return r, err
 }
 
+func (elect *Elect) leaderPath() string {
+	return elect.path + "-leader"
+}
+
+func (elect *Elect) setCurrent(ctx context.Context, t *ticket) {
+	elect.mutex.Lock()
+	defer elect.mutex.Unlock()
+	elect.current = t
+	if t == nil || len(elect.value) == 0 {
+		return
+	}
+	_, err := elect.cli.Put(ctx, elect.leaderPath(), elect.value, clientv3.WithLease(t.session.Lease()))
+	if err != nil {
+		log.Errorf("put elect leader value: %v", err)
+	}
+}
+
+// SetValue 设置本节点当选后对外公布的信息, 须在 Start 之前调用
+func (elect *Elect) SetValue(val string) {
+	elect.mutex.Lock()
+	defer elect.mutex.Unlock()
+	elect.value = val
+}
+
+// Leader 返回当前主节点公布的信息, 没有主节点时返回空字符串
+func (elect *Elect) Leader(ctx context.Context) (string, error) {
+	resp, err := elect.cli.Get(ctx, elect.leaderPath())
+	if err != nil {
+		return "", errors.Wrap(err, "get elect leader")
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// IsLeader 本节点当前是否为主节点
+func (elect *Elect) IsLeader() bool {
+	elect.mutex.Lock()
+	defer elect.mutex.Unlock()
+	return elect.latestEv == electEventWin
+}
+
+// Resign 主动放弃主节点身份, 由排队中的其他节点接管, 本节点随后重新排队参选
+func (elect *Elect) Resign() error {
+	elect.mutex.Lock()
+	defer elect.mutex.Unlock()
+	if elect.current == nil || elect.current.session == nil {
+		return errors.Error("not leader")
+	}
+	return elect.current.session.Close()
+}
+
 func (elect *Elect) subscribe(ctx context.Context, ch chan electEvent) {
 	elect.mutex.Lock()
 	defer elect.mutex.Unlock()
@@ -33,10 +33,13 @@ const (
 	SERVER_REBUILD_ROOT  = "SERVER_REBUILD_ROOT"
 	SERVER_CHANGE_FLAVOR = "SERVER_CHANGE_FLAVOR"
 	SERVER_PANICKED      = "SERVER_PANICKED"
+	SERVER_WATCHDOG      = "SERVER_WATCHDOG"
 
 	IMAGE_ACTIVED = "IMAGE_ACTIVED"
 
 	USER_LOGIN_EXCEPTION = "USER_LOGIN_EXCEPTION"
+
+	RUNBOOK_NOTIFY = "RUNBOOK_NOTIFY"
 )
 
 var (
@@ -62,6 +65,10 @@ var (
 	ActionSyncCreate = api.ActionSyncCreate
 	ActionSyncUpdate = api.ActionSyncUpdate
 	ActionSyncDelete = api.ActionSyncDelete
+
+	ActionPasswordExpireSoon = api.ActionPasswordExpireSoon
+
+	ActionScheduledMaintenance = api.ActionScheduledMaintenance
 )
 
 type SEvent struct {
@@ -33,6 +33,8 @@ type CloudMonOptions struct {
 
 	CloudAccountCollectMetricsBatchCount  int `help:"Cloud Account Collect Metrics Batch Count" default:"10"`
 	CloudResourceCollectMetricsBatchCount int `help:"Cloud Resource Collect Metrics BatchC ount" default:"40"`
+
+	MetricBackfillHours int64 `help:"Hours of history metrics to backfill for newly onboarded cloud providers, limited by provider retention, 0 to disable" default:"72"`
 }
 
 type PingProbeOptions struct {
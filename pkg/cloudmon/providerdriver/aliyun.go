@@ -36,6 +36,10 @@ func (self *AliyunCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *AliyunCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 30 * 24
+}
+
 func (self *AliyunCollect) CollectAccountMetrics(ctx context.Context, account api.CloudaccountDetail) (influxdb.SMetricData, error) {
 	metric := influxdb.SMetricData{
 		Name:      string(cloudprovider.METRIC_RESOURCE_TYPE_CLOUD_ACCOUNT),
@@ -36,6 +36,10 @@ func (self *AwsCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *AwsCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 15 * 24
+}
+
 func init() {
 	Register(&AwsCollect{})
 }
@@ -15,6 +15,8 @@
 package providerdriver
 
 import (
+	"time"
+
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 )
 
@@ -30,6 +32,10 @@ func (self *AzureCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *AzureCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 93 * 24
+}
+
 func init() {
 	Register(&AzureCollect{})
 }
@@ -38,6 +38,10 @@ func (self *SBaseCollectDriver) GetDelayDuration() time.Duration {
 	return 6 * time.Minute
 }
 
+func (self *SBaseCollectDriver) GetMetricRetention() time.Duration {
+	return 0
+}
+
 func (self *SBaseCollectDriver) IsSupportMetrics() bool {
 	return false
 }
@@ -30,6 +30,8 @@ var driverTable map[string]ICollectDriver = map[string]ICollectDriver{}
 type ICollectDriver interface {
 	GetProvider() string
 	GetDelayDuration() time.Duration
+	// 云平台监控数据保留时长, 用于新纳管资源的历史数据回填, 0 表示不支持回填
+	GetMetricRetention() time.Duration
 	IsSupportMetrics() bool
 	CollectAccountMetrics(ctx context.Context, account api.CloudaccountDetail) (influxdb.SMetricData, error)
 	CollectDBInstanceMetrics(ctx context.Context, manager api.CloudproviderDetails, provider cloudprovider.ICloudProvider, res map[string]api.DBInstanceDetails, start, end time.Time) error
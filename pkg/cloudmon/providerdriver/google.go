@@ -32,6 +32,10 @@ func (self *GoogleCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *GoogleCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 42 * 24
+}
+
 func (self *GoogleCollect) GetDelayDuration() time.Duration {
 	return time.Minute * 3
 }
@@ -40,6 +40,10 @@ func (self *HuaweiCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *HuaweiCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 2 * 24
+}
+
 func init() {
 	Register(&HuaweiCollect{})
 }
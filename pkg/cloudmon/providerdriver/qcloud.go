@@ -41,6 +41,10 @@ func (self *QcloudCollect) IsSupportMetrics() bool {
 	return true
 }
 
+func (self *QcloudCollect) GetMetricRetention() time.Duration {
+	return time.Hour * 30 * 24
+}
+
 func init() {
 	Register(&QcloudCollect{})
 }
@@ -60,6 +60,10 @@ type SBaseResources struct {
 
 	providerLock      sync.Mutex
 	ProviderResources map[string]map[string]jsonutils.JSONObject
+
+	// 是否记录增量同步中新出现的资源
+	trackIncrements bool
+	increments      map[string]jsonutils.JSONObject
 }
 
 func (self *SBaseResources) getResources(managerId string) map[string]jsonutils.JSONObject {
@@ -185,6 +189,9 @@ func (self *SBaseResources) increment() error {
 			key = baseInfo.Id
 		}
 		self.resourceLock.Lock()
+		if _, ok := self.Resources[key]; !ok && self.trackIncrements {
+			self.increments[key] = ret[i]
+		}
 		self.Resources[key] = ret[i]
 		self.resourceLock.Unlock()
 		if len(baseInfo.ManagerId) > 0 {
@@ -317,11 +324,21 @@ func (self *SBaseResources) update() error {
 	return nil
 }
 
+// 返回并清空上次调用后增量同步中新出现的资源
+func (self *SBaseResources) popIncrements() map[string]jsonutils.JSONObject {
+	self.resourceLock.Lock()
+	defer self.resourceLock.Unlock()
+	ret := self.increments
+	self.increments = map[string]jsonutils.JSONObject{}
+	return ret
+}
+
 func NewBaseResources(manager modulebase.Manager) *SBaseResources {
 	return &SBaseResources{
 		manager:           manager,
 		Resources:         map[string]jsonutils.JSONObject{},
 		ProviderResources: map[string]map[string]jsonutils.JSONObject{},
+		increments:        map[string]jsonutils.JSONObject{},
 	}
 }
 
@@ -331,6 +348,7 @@ type TResource interface {
 	decrement() error
 	update() error
 	getResources(managerId string) map[string]jsonutils.JSONObject
+	popIncrements() map[string]jsonutils.JSONObject
 }
 
 type SResources struct {
@@ -346,12 +364,18 @@ type SResources struct {
 	Storages       TResource
 	ModelartsPool  TResource
 	Wires          TResource
+
+	// 待回填历史监控数据的新纳管云订阅
+	backfillLock      sync.Mutex
+	backfillProviders map[string]bool
 }
 
 func NewResources() *SResources {
+	cloudproviders := NewBaseResources(&compute.Cloudproviders)
+	cloudproviders.trackIncrements = true
 	return &SResources{
 		Cloudaccounts:  NewBaseResources(&compute.Cloudaccounts),
-		Cloudproviders: NewBaseResources(&compute.Cloudproviders),
+		Cloudproviders: cloudproviders,
 		DBInstances:    NewBaseResources(&compute.DBInstance),
 		Servers:        NewBaseResources(&compute.Servers),
 		Hosts:          NewBaseResources(&compute.Hosts),
@@ -362,6 +386,8 @@ func NewResources() *SResources {
 		KubeClusters:   NewBaseResources(&compute.KubeClusters),
 		ModelartsPool:  NewBaseResources(&compute.ModelartsPools),
 		Wires:          NewBaseResources(&compute.Wires),
+
+		backfillProviders: map[string]bool{},
 	}
 }
 
@@ -439,6 +465,15 @@ func (self *SResources) IncrementSync(ctx context.Context, userCred mcclient.Tok
 		if err != nil {
 			errs = append(errs, errors.Wrapf(err, "Cloudproviders.increment"))
 		}
+		if options.Options.MetricBackfillHours > 0 {
+			self.backfillLock.Lock()
+			for id := range self.Cloudproviders.popIncrements() {
+				self.backfillProviders[id] = true
+			}
+			self.backfillLock.Unlock()
+		} else {
+			self.Cloudproviders.popIncrements()
+		}
 		err = self.DBInstances.increment()
 		if err != nil {
 			errs = append(errs, errors.Wrapf(err, "DBInstances.increment"))
@@ -640,123 +675,7 @@ func (self *SResources) CollectMetrics(ctx context.Context, userCred mcclient.To
 			endTime := _endTime.Add(-1 * duration)
 			startTime := _startTime.Add(-1 * duration).Add(time.Second * -59)
 
-			resources = self.DBInstances.getResources(manager.Id)
-			dbinstances := map[string]api.DBInstanceDetails{}
-			err = jsonutils.Update(&dbinstances, resources)
-			if err != nil {
-				log.Errorf("unmarsha rds resources error: %v", err)
-			}
-			err = driver.CollectDBInstanceMetrics(ctx, manager, provider, dbinstances, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectDBInstanceMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Servers.getResources(manager.Id)
-			servers := map[string]api.ServerDetails{}
-			err = jsonutils.Update(&servers, resources)
-			if err != nil {
-				log.Errorf("unmarsha server resources error: %v", err)
-			}
-			err = driver.CollectServerMetrics(ctx, manager, provider, servers, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectServerMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Hosts.getResources(manager.Id)
-			hosts := map[string]api.HostDetails{}
-			err = jsonutils.Update(&hosts, resources)
-			if err != nil {
-				log.Errorf("unmarsha host resources error: %v", err)
-			}
-
-			err = driver.CollectHostMetrics(ctx, manager, provider, hosts, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectHostMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Storages.getResources(manager.Id)
-			storages := map[string]api.StorageDetails{}
-			err = jsonutils.Update(&storages, resources)
-			if err != nil {
-				log.Errorf("unmarsha storage resources error: %v", err)
-			}
-			err = driver.CollectStorageMetrics(ctx, manager, provider, storages, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectStorageMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Redis.getResources(manager.Id)
-			caches := map[string]api.ElasticcacheDetails{}
-			err = jsonutils.Update(&caches, resources)
-			if err != nil {
-				log.Errorf("unmarsha redis resources error: %v", err)
-			}
-
-			err = driver.CollectRedisMetrics(ctx, manager, provider, caches, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectRedisMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Loadbalancers.getResources(manager.Id)
-			lbs := map[string]api.LoadbalancerDetails{}
-			err = jsonutils.Update(&lbs, resources)
-			if err != nil {
-				log.Errorf("unmarsha lb resources error: %v", err)
-			}
-
-			err = driver.CollectLoadbalancerMetrics(ctx, manager, provider, lbs, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectLoadbalancerMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Buckets.getResources(manager.Id)
-			buckets := map[string]api.BucketDetails{}
-			err = jsonutils.Update(&buckets, resources)
-			if err != nil {
-				log.Errorf("unmarsha bucket resources error: %v", err)
-			}
-
-			err = driver.CollectBucketMetrics(ctx, manager, provider, buckets, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectBucketMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.KubeClusters.getResources(manager.Id)
-			clusters := map[string]api.KubeClusterDetails{}
-			err = jsonutils.Update(&clusters, resources)
-			if err != nil {
-				log.Errorf("unmarsha k8s resources error: %v", err)
-			}
-
-			err = driver.CollectK8sMetrics(ctx, manager, provider, clusters, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectK8sMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.ModelartsPool.getResources(manager.Id)
-			pools := map[string]api.ModelartsPoolDetails{}
-			err = jsonutils.Update(&pools, resources)
-			if err != nil {
-				log.Errorf("unmarsha modelarts resources error: %v", err)
-			}
-
-			err = driver.CollectModelartsPoolMetrics(ctx, manager, provider, pools, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectModelartsPoolMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
-			resources = self.Wires.getResources(manager.Id)
-			wires := map[string]api.WireDetails{}
-			err = jsonutils.Update(&wires, resources)
-			if err != nil {
-				log.Errorf("unmarsha wires resources error: %v", err)
-			}
-
-			err = driver.CollectWireMetrics(ctx, manager, provider, wires, startTime, endTime)
-			if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
-				log.Errorf("CollectWireMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
-			}
-
+			self.collectProviderMetrics(ctx, manager, driver, provider, startTime, endTime, false)
 		}(cloudproviders[i])
 	}
 	wg.Wait()
@@ -790,3 +709,197 @@ func (self *SResources) CollectMetrics(ctx context.Context, userCred mcclient.To
 	influxdb.SendMetrics(urls, "meter_db", metrics, false)
 	return
 }
+
+// 为新纳管的云订阅回填云平台保留期内的历史监控数据
+func (self *SResources) BackfillMetrics(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	if isStart || options.Options.MetricBackfillHours <= 0 {
+		return
+	}
+	resources := self.Cloudproviders.getResources("")
+	cloudproviders := map[string]api.CloudproviderDetails{}
+	jsonutils.Update(&cloudproviders, resources)
+
+	pending := []api.CloudproviderDetails{}
+	self.backfillLock.Lock()
+	for id := range self.backfillProviders {
+		manager, ok := cloudproviders[id]
+		if !ok {
+			delete(self.backfillProviders, id)
+			continue
+		}
+		// 等待云订阅完成首次同步, 且同步后的资源已增量加载到本地缓存
+		if manager.LastSyncEndAt.IsZero() || time.Since(manager.LastSyncEndAt) < time.Duration(options.Options.ResourcesSyncInterval)*time.Minute {
+			continue
+		}
+		delete(self.backfillProviders, id)
+		pending = append(pending, manager)
+	}
+	self.backfillLock.Unlock()
+
+	s := auth.GetAdminSession(context.Background(), options.Options.Region)
+	for _, manager := range pending {
+		err := self.backfillProviderMetrics(ctx, s, manager)
+		if err != nil {
+			log.Errorf("backfill metrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+		}
+	}
+}
+
+func (self *SResources) backfillProviderMetrics(ctx context.Context, s *mcclient.ClientSession, manager api.CloudproviderDetails) error {
+	if strings.Contains(strings.ToLower(options.Options.SkipMetricPullProviders), strings.ToLower(manager.Provider)) {
+		return nil
+	}
+	driver, err := providerdriver.GetDriver(manager.Provider)
+	if err != nil {
+		return errors.Wrapf(err, "GetDriver")
+	}
+	retention := driver.GetMetricRetention()
+	if !driver.IsSupportMetrics() || retention <= 0 {
+		log.Infof("%s not support metrics backfill, skip", driver.GetProvider())
+		return nil
+	}
+	if backfill := time.Duration(options.Options.MetricBackfillHours) * time.Hour; backfill < retention {
+		retention = backfill
+	}
+	provider, err := compute.Cloudproviders.GetProvider(ctx, s, manager.Id)
+	if err != nil {
+		return errors.Wrapf(err, "GetProvider")
+	}
+	sh, _ := time.LoadLocation("Asia/Shanghai")
+	// 最近一个采集周期的数据由定时采集任务负责
+	endTime := time.Now().In(sh).Add(-1 * driver.GetDelayDuration()).Add(-1 * time.Minute * time.Duration(options.Options.CollectMetricInterval))
+	startTime := endTime.Add(-1 * retention)
+	log.Infof("backfill metrics for %s(%s) from %s to %s", manager.Name, manager.Provider, startTime, endTime)
+	// 按小时分段拉取, 避免单次请求数据点超过云平台限制
+	for start := startTime; start.Before(endTime); start = start.Add(time.Hour) {
+		end := start.Add(time.Hour)
+		if end.After(endTime) {
+			end = endTime
+		}
+		self.collectProviderMetrics(ctx, manager, driver, provider, start, end, true)
+	}
+	return nil
+}
+
+// 采集云订阅下各类资源在指定时间段内的监控数据, 回填历史数据时跳过仅有当前值的资源
+func (self *SResources) collectProviderMetrics(ctx context.Context, manager api.CloudproviderDetails, driver providerdriver.ICollectDriver, provider cloudprovider.ICloudProvider, startTime, endTime time.Time, isBackfill bool) {
+	resources := self.DBInstances.getResources(manager.Id)
+	dbinstances := map[string]api.DBInstanceDetails{}
+	err := jsonutils.Update(&dbinstances, resources)
+	if err != nil {
+		log.Errorf("unmarsha rds resources error: %v", err)
+	}
+	err = driver.CollectDBInstanceMetrics(ctx, manager, provider, dbinstances, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectDBInstanceMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.Servers.getResources(manager.Id)
+	servers := map[string]api.ServerDetails{}
+	err = jsonutils.Update(&servers, resources)
+	if err != nil {
+		log.Errorf("unmarsha server resources error: %v", err)
+	}
+	err = driver.CollectServerMetrics(ctx, manager, provider, servers, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectServerMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.Hosts.getResources(manager.Id)
+	hosts := map[string]api.HostDetails{}
+	err = jsonutils.Update(&hosts, resources)
+	if err != nil {
+		log.Errorf("unmarsha host resources error: %v", err)
+	}
+
+	err = driver.CollectHostMetrics(ctx, manager, provider, hosts, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectHostMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	// 存储监控数据为当前容量, 无历史数据可回填
+	if !isBackfill {
+		resources = self.Storages.getResources(manager.Id)
+		storages := map[string]api.StorageDetails{}
+		err = jsonutils.Update(&storages, resources)
+		if err != nil {
+			log.Errorf("unmarsha storage resources error: %v", err)
+		}
+		err = driver.CollectStorageMetrics(ctx, manager, provider, storages, startTime, endTime)
+		if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+			log.Errorf("CollectStorageMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+		}
+	}
+
+	resources = self.Redis.getResources(manager.Id)
+	caches := map[string]api.ElasticcacheDetails{}
+	err = jsonutils.Update(&caches, resources)
+	if err != nil {
+		log.Errorf("unmarsha redis resources error: %v", err)
+	}
+
+	err = driver.CollectRedisMetrics(ctx, manager, provider, caches, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectRedisMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.Loadbalancers.getResources(manager.Id)
+	lbs := map[string]api.LoadbalancerDetails{}
+	err = jsonutils.Update(&lbs, resources)
+	if err != nil {
+		log.Errorf("unmarsha lb resources error: %v", err)
+	}
+
+	err = driver.CollectLoadbalancerMetrics(ctx, manager, provider, lbs, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectLoadbalancerMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.Buckets.getResources(manager.Id)
+	buckets := map[string]api.BucketDetails{}
+	err = jsonutils.Update(&buckets, resources)
+	if err != nil {
+		log.Errorf("unmarsha bucket resources error: %v", err)
+	}
+
+	err = driver.CollectBucketMetrics(ctx, manager, provider, buckets, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectBucketMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.KubeClusters.getResources(manager.Id)
+	clusters := map[string]api.KubeClusterDetails{}
+	err = jsonutils.Update(&clusters, resources)
+	if err != nil {
+		log.Errorf("unmarsha k8s resources error: %v", err)
+	}
+
+	err = driver.CollectK8sMetrics(ctx, manager, provider, clusters, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectK8sMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.ModelartsPool.getResources(manager.Id)
+	pools := map[string]api.ModelartsPoolDetails{}
+	err = jsonutils.Update(&pools, resources)
+	if err != nil {
+		log.Errorf("unmarsha modelarts resources error: %v", err)
+	}
+
+	err = driver.CollectModelartsPoolMetrics(ctx, manager, provider, pools, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectModelartsPoolMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+
+	resources = self.Wires.getResources(manager.Id)
+	wires := map[string]api.WireDetails{}
+	err = jsonutils.Update(&wires, resources)
+	if err != nil {
+		log.Errorf("unmarsha wires resources error: %v", err)
+	}
+
+	err = driver.CollectWireMetrics(ctx, manager, provider, wires, startTime, endTime)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+		log.Errorf("CollectWireMetrics for %s(%s) error: %v", manager.Name, manager.Provider, err)
+	}
+}
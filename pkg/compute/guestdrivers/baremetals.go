@@ -28,6 +28,7 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
@@ -443,6 +444,10 @@ func (self *SBaremetalGuestDriver) GetGuestVncInfo(ctx context.Context, userCred
 	ret.HostId = host.Id
 	zone, _ := host.GetZone()
 	ret.Zone = zone.Name
+	// 物理机没有 vnc, 由 webconsole 通过 BMC 串口控制台代理
+	ret.Protocol = webconsole_api.SOL
+	ret.InstanceName = guest.Name
+	ret.Hypervisor = api.HYPERVISOR_BAREMETAL
 	return ret, nil
 }
 
@@ -237,6 +237,10 @@ func (self *SBaseGuestDriver) RequestChangeVmConfig(ctx context.Context, guest *
 	return fmt.Errorf("Not Implement")
 }
 
+func (self *SBaseGuestDriver) RequestResizeMemory(ctx context.Context, guest *models.SGuest, task taskman.ITask, vmemSize int64) error {
+	return fmt.Errorf("Not Implement")
+}
+
 func (self *SBaseGuestDriver) NeedRequestGuestHotAddIso(ctx context.Context, guest *models.SGuest) bool {
 	return false
 }
@@ -301,6 +305,11 @@ func (self *SBaseGuestDriver) RequestRenewInstance(ctx context.Context, guest *m
 	return time.Time{}, nil
 }
 
+// 本地虚拟机启动顺序保存在数据库中, 下次启动生效
+func (self *SBaseGuestDriver) RequestSetBootOrder(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, order string) error {
+	return nil
+}
+
 func (self *SBaseGuestDriver) IsSupportEip() bool {
 	return false
 }
@@ -483,6 +492,10 @@ func (self *SBaseGuestDriver) RequestCPUSetRemove(ctx context.Context, userCred
 	return httperrors.ErrNotImplemented
 }
 
+func (self *SBaseGuestDriver) RequestSetNicBandwidth(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, gn *models.SGuestnetwork) error {
+	return httperrors.ErrNotImplemented
+}
+
 func (self *SBaseGuestDriver) QgaRequestGuestPing(ctx context.Context, task taskman.ITask, host *models.SHost, guest *models.SGuest) error {
 	return httperrors.ErrNotImplemented
 }
@@ -495,6 +508,22 @@ func (self *SBaseGuestDriver) RequestQgaCommand(ctx context.Context, userCred mc
 	return nil, httperrors.ErrNotImplemented
 }
 
+func (self *SBaseGuestDriver) RequestQgaAction(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject, host *models.SHost, guest *models.SGuest) (jsonutils.JSONObject, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
+func (self *SBaseGuestDriver) RequestLibvirtXml(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerLibvirtXmlOutput, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
+func (self *SBaseGuestDriver) RequestSnapshotGc(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, input api.ServerSnapshotGcRequest) (*api.ServerSnapshotGcOutput, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
+func (self *SBaseGuestDriver) RequestConfidentialVmAttestation(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerConfidentialVmAttestationOutput, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
 func (self *SBaseGuestDriver) FetchMonitorUrl(ctx context.Context, guest *models.SGuest) string {
 	s := auth.GetAdminSessionWithPublic(ctx, consts.GetRegion())
 	influxdbUrl, err := s.GetServiceURL(apis.SERVICE_TYPE_INFLUXDB, options.Options.MonitorEndpointType)
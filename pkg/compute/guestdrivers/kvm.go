@@ -424,6 +424,16 @@ func (self *SKVMGuestDriver) RequestChangeVmConfig(ctx context.Context, guest *m
 	}
 }
 
+func (self *SKVMGuestDriver) RequestResizeMemory(ctx context.Context, guest *models.SGuest, task taskman.ITask, vmemSize int64) error {
+	header := task.GetTaskRequestHeader()
+	body := jsonutils.NewDict()
+	body.Set("vmem_size", jsonutils.NewInt(vmemSize))
+	host, _ := guest.GetHost()
+	url := fmt.Sprintf("%s/servers/%s/resize-virtio-mem", host.ManagerUri, guest.Id)
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	return err
+}
+
 func (self *SKVMGuestDriver) RequestSoftReset(ctx context.Context, guest *models.SGuest, task taskman.ITask) error {
 	_, err := guest.SendMonitorCommand(
 		ctx, task.GetUserCred(),
@@ -783,7 +793,7 @@ func (self *SKVMGuestDriver) CheckMigrate(ctx context.Context, guest *models.SGu
 		return errors.Wrapf(err, "GetIsolatedDevices")
 	}
 	if len(devices) > 0 {
-		return httperrors.NewBadRequestError("Cannot migrate with isolated devices")
+		return httperrors.NewBadRequestError("Cannot migrate with isolated devices, try server-cold-migrate-with-devices")
 	}
 	if len(input.PreferHost) > 0 {
 		err := checkAssignHost(ctx, userCred, input.PreferHost)
@@ -806,12 +816,13 @@ func (self *SKVMGuestDriver) CheckLiveMigrate(ctx context.Context, guest *models
 		if cdrom != nil && len(cdrom.ImageId) > 0 {
 			return httperrors.NewBadRequestError("Cannot live migrate with cdrom")
 		}
-		devices, err := guest.GetIsolatedDevices()
-		if err != nil {
-			return errors.Wrapf(err, "GetIsolatedDevices")
+		// SR-IOV 网卡开启备用切换后可随迁移摘除重挂, 其余直通设备不允许热迁移
+		if err := guest.ValidateSriovFailoverMigrate(ctx); err != nil {
+			return err
 		}
-		if len(devices) > 0 {
-			return httperrors.NewBadRequestError("Cannot live migrate with isolated devices")
+		// 机密计算虚机内存已加密, 不支持热迁移
+		if len(guest.GetConfidentialVmType(ctx)) > 0 {
+			return httperrors.NewBadRequestError("Cannot live migrate confidential vm")
 		}
 		if !guest.CheckQemuVersion(guest.GetQemuVersion(userCred), "1.1.2") {
 			return httperrors.NewBadRequestError("Cannot do live migrate, too low qemu version")
@@ -951,6 +962,11 @@ func (self *SKVMGuestDriver) ValidateCreateData(ctx context.Context, userCred mc
 			return nil, errors.Wrap(err, "validateMachineType")
 		}
 	}
+	for _, disk := range input.Disks {
+		if disk != nil && len(disk.Cache) > 0 && !utils.IsInStringArray(disk.Cache, api.DISK_CACHE_MODES) {
+			return nil, httperrors.NewInputParameterError("invalid disk cache mode %q, must be one of %s", disk.Cache, api.DISK_CACHE_MODES)
+		}
+	}
 	return input, nil
 }
 
@@ -1018,6 +1034,22 @@ func (self *SKVMGuestDriver) RequestCPUSetRemove(ctx context.Context, userCred m
 	return nil
 }
 
+func (self *SKVMGuestDriver) RequestSetNicBandwidth(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, gn *models.SGuestnetwork) error {
+	url := fmt.Sprintf("%s/servers/%s/set-nic-bandwidth", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	body := jsonutils.Marshal(&host_api.GuestSetNicBandwidthRequest{
+		Mac:       gn.MacAddr,
+		BwIngress: gn.IngressBwLimit,
+		BwEgress:  gn.EgressBwLimit,
+	})
+	_, _, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, body, false)
+	if err != nil {
+		return errors.Wrap(err, "host request")
+	}
+	return nil
+}
+
 func (self *SKVMGuestDriver) QgaRequestGuestPing(ctx context.Context, task taskman.ITask, host *models.SHost, guest *models.SGuest) error {
 	url := fmt.Sprintf("%s/servers/%s/qga-guest-ping", host.ManagerUri, guest.Id)
 	httpClient := httputils.GetDefaultClient()
@@ -1052,6 +1084,62 @@ func (self *SKVMGuestDriver) RequestQgaCommand(ctx context.Context, userCred mcc
 	return res, nil
 }
 
+func (self *SKVMGuestDriver) RequestQgaAction(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject, host *models.SHost, guest *models.SGuest) (jsonutils.JSONObject, error) {
+	url := fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, guest.Id, action)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, body, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	return res, nil
+}
+
+func (self *SKVMGuestDriver) RequestSnapshotGc(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, input api.ServerSnapshotGcRequest) (*api.ServerSnapshotGcOutput, error) {
+	url := fmt.Sprintf("%s/servers/%s/snapshot-gc", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, jsonutils.Marshal(input), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	output := &api.ServerSnapshotGcOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+	return output, nil
+}
+
+func (self *SKVMGuestDriver) RequestConfidentialVmAttestation(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerConfidentialVmAttestationOutput, error) {
+	url := fmt.Sprintf("%s/servers/%s/cvm-attestation", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, nil, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	output := &api.ServerConfidentialVmAttestationOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+	return output, nil
+}
+
+func (self *SKVMGuestDriver) RequestLibvirtXml(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerLibvirtXmlOutput, error) {
+	url := fmt.Sprintf("%s/servers/%s/libvirt-xml", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "GET", url, header, nil, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	output := &api.ServerLibvirtXmlOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal libvirt xml")
+	}
+	return output, nil
+}
+
 func (self *SKVMGuestDriver) FetchMonitorUrl(ctx context.Context, guest *models.SGuest) string {
 	if options.Options.KvmMonitorAgentUseMetadataService {
 		return apis.MetaServiceMonitorAgentUrl
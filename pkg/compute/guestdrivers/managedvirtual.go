@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
@@ -135,6 +136,13 @@ func (self *SManagedVirtualizedGuestDriver) GetJsonDescAtHost(ctx context.Contex
 		}
 	}
 
+	if config.EnableMonitorAgent {
+		config.UserData, err = guest.MergeMonitorAgentUserData(ctx, userCred, config.UserData, config.OsType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "MergeMonitorAgentUserData")
+		}
+	}
+
 	// 避免因同步包年包月实例billing_cycle失败,导致重置虚拟机密码异常
 	if guest.BillingType == billing_api.BILLING_TYPE_PREPAID && len(guest.BillingCycle) > 0 {
 		bc, err := billing.ParseBillingCycle(guest.BillingCycle)
@@ -383,6 +391,13 @@ func (self *SManagedVirtualizedGuestDriver) RequestDeployGuestOnHost(ctx context
 	}
 
 	desc.Tags, _ = guest.GetAllUserMetadata()
+	if action, _ := config.GetString("action"); action == "create" {
+		tags, err := guest.ApplyTagPolicy(ctx, task.GetUserCred())
+		if err != nil {
+			return errors.Wrapf(err, "ApplyTagPolicy")
+		}
+		desc.Tags = tags
+	}
 
 	//创建并同步安全组规则, 仅新建的安全组会同步规则
 	{
@@ -474,7 +489,7 @@ func (self *SManagedVirtualizedGuestDriver) GetGuestInitialStateAfterRebuild() s
 	return api.VM_READY
 }
 
-func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, desc cloudprovider.SManagedVMCreateConfig) (jsonutils.JSONObject, error) {
+func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, desc cloudprovider.SManagedVMCreateConfig) (data jsonutils.JSONObject, err error) {
 	ihost, err := host.GetIHost(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "RemoteDeployGuestForCreate.GetIHost")
@@ -485,8 +500,9 @@ func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx conte
 		defer lockman.ReleaseObject(ctx, guest)
 
 		iVM, err := func() (cloudprovider.ICloudVM, error) {
-			iVM, err := ihost.CreateVM(&desc)
-			if err == nil || !options.Options.EnableAutoSwitchServerSku {
+			iVM, err := guest.CreateVMOnIHost(ihost, &desc)
+			// 专有宿主机规格固定, 不自动切换套餐
+			if err == nil || !options.Options.EnableAutoSwitchServerSku || len(guest.DedicatedHostId) > 0 {
 				return iVM, err
 			}
 			skus, e := models.ServerSkuManager.GetSkus(host.GetProviderName(), guest.VcpuCount, guest.VmemSize)
@@ -498,7 +514,7 @@ func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx conte
 				if skus[i].Name != oldSku {
 					desc.InstanceType = skus[i].Name
 					log.Infof("try switch server sku from %s to %s for create %s", oldSku, desc.InstanceType, guest.Name)
-					iVM, err = ihost.CreateVM(&desc)
+					iVM, err = guest.CreateVMOnIHost(ihost, &desc)
 					if err == nil {
 						db.Update(guest, func() error {
 							guest.InstanceType = desc.InstanceType
@@ -520,6 +536,13 @@ func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx conte
 	if err != nil {
 		return nil, err
 	}
+	// 虚拟机已创建, 后续步骤失败时清理已创建的云上资源, 避免资源泄露
+	createdVM := iVM
+	defer func() {
+		if err != nil && !options.Options.DisableCleanupOnCreateFailed {
+			self.cleanupRemoteGuestForCreateFailed(ctx, userCred, guest, createdVM, desc, err)
+		}
+	}()
 	// iVM 实际所在的ihost 可能和 调度选择的host不是同一个,此处根据iVM实际所在host，重新同步
 	ihost, err = guest.GetDriver().RemoteDeployGuestSyncHost(ctx, userCred, guest, host, iVM)
 	if err != nil {
@@ -536,9 +559,9 @@ func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx conte
 	}
 	log.Debugf("VMcreated %s, and status is running", iVM.GetGlobalId())
 
-	iVM, err = ihost.GetIVMById(iVM.GetGlobalId())
+	iVM, err = ihost.GetIVMById(createdVM.GetGlobalId())
 	if err != nil {
-		return nil, errors.Wrapf(err, "GetIVMById(%s)", iVM.GetGlobalId())
+		return nil, errors.Wrapf(err, "GetIVMById(%s)", createdVM.GetGlobalId())
 	}
 
 	if guest.GetDriver().GetMaxSecurityGroupCount() > 0 {
@@ -594,10 +617,61 @@ func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestForCreate(ctx conte
 
 	guest.GetDriver().RemoteActionAfterGuestCreated(ctx, userCred, guest, host, iVM, &desc)
 
-	data := fetchIVMinfo(desc, iVM, guest.Id, desc.Account, desc.Password, desc.PublicKey, "create")
+	data = fetchIVMinfo(desc, iVM, guest.Id, desc.Account, desc.Password, desc.PublicKey, "create")
 	return data, nil
 }
 
+// 清理创建失败时已在云上创建的虚拟机、随虚拟机创建的EIP及不随虚拟机释放的磁盘
+func (self *SManagedVirtualizedGuestDriver) cleanupRemoteGuestForCreateFailed(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, iVM cloudprovider.ICloudVM, desc cloudprovider.SManagedVMCreateConfig, reason error) {
+	log.Infof("cleanup remote resources of guest %s(%s) after create failed: %v", guest.Name, guest.Id, reason)
+
+	var eip cloudprovider.ICloudEIP
+	if desc.PublicIpBw > 0 {
+		eip, _ = iVM.GetIEIP()
+	}
+	idisks, err := iVM.GetIDisks()
+	if err != nil {
+		log.Warningf("GetIDisks for %s error: %v", guest.Name, err)
+	}
+
+	notes := []string{}
+	err = iVM.DeleteVM(ctx)
+	if err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+		// 虚拟机删除失败时保留外部ID, 便于用户手动删除
+		logclient.AddSimpleActionLog(guest, logclient.ACT_DELETE, errors.Wrapf(err, "cleanup after create failed"), userCred, false)
+		return
+	}
+	err = cloudprovider.WaitDeleted(iVM, time.Second*10, time.Minute*5)
+	if err != nil {
+		log.Warningf("wait vm %s deleted error: %v", iVM.GetGlobalId(), err)
+	}
+	notes = append(notes, fmt.Sprintf("vm %s", iVM.GetGlobalId()))
+
+	if eip != nil {
+		err = eip.Delete()
+		if err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+			log.Warningf("delete eip %s error: %v", eip.GetIpAddr(), err)
+		} else {
+			notes = append(notes, fmt.Sprintf("eip %s", eip.GetIpAddr()))
+		}
+	}
+
+	for i := range idisks {
+		if idisks[i].GetIsAutoDelete() {
+			continue
+		}
+		err = idisks[i].Delete(ctx)
+		if err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+			log.Warningf("delete disk %s error: %v", idisks[i].GetGlobalId(), err)
+			continue
+		}
+		notes = append(notes, fmt.Sprintf("disk %s", idisks[i].GetGlobalId()))
+	}
+
+	db.SetExternalId(guest, userCred, "")
+	logclient.AddSimpleActionLog(guest, logclient.ACT_DELETE, fmt.Sprintf("cleanup after create failed: %s", strings.Join(notes, ", ")), userCred, true)
+}
+
 func (self *SManagedVirtualizedGuestDriver) RemoteDeployGuestSyncHost(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, iVM cloudprovider.ICloudVM) (cloudprovider.ICloudHost, error) {
 	if hostId := iVM.GetIHostId(); len(hostId) > 0 {
 		nh, err := db.FetchByExternalIdAndManagerId(models.HostManager, hostId, func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
@@ -1071,6 +1145,7 @@ func (self *SManagedVirtualizedGuestDriver) OnGuestDeployTaskDataReceived(ctx co
 	}
 
 	guest.SaveDeployInfo(ctx, task.GetUserCred(), data)
+	guest.SaveProviderPlacementInfo(ctx, task.GetUserCred(), data)
 
 	iVM, err := guest.GetIVM(ctx)
 	if err != nil {
@@ -1137,6 +1212,18 @@ func (self *SManagedVirtualizedGuestDriver) RequestSyncConfigOnHost(ctx context.
 	return nil
 }
 
+func (self *SManagedVirtualizedGuestDriver) RequestSetBootOrder(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, order string) error {
+	iVM, err := guest.GetIVM(ctx)
+	if err != nil {
+		return errors.Wrap(err, "GetIVM")
+	}
+	vm, ok := iVM.(cloudprovider.ICloudVMBootOrder)
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "set boot order for %s", guest.Hypervisor)
+	}
+	return vm.SetBootOrder(ctx, order)
+}
+
 func (self *SManagedVirtualizedGuestDriver) RequestRenewInstance(ctx context.Context, guest *models.SGuest, bc billing.SBillingCycle) (time.Time, error) {
 	iVM, err := guest.GetIVM(ctx)
 	if err != nil {
@@ -1310,9 +1397,9 @@ func (self *SManagedVirtualizedGuestDriver) RequestRemoteUpdate(ctx context.Cont
 			}
 			return errors.Wrap(err, "iVM.GetTags()")
 		}
-		tags, err := guest.GetAllUserMetadata()
+		tags, err := guest.GetTagPolicyMergedTags()
 		if err != nil {
-			return errors.Wrapf(err, "GetAllUserMetadata")
+			return errors.Wrapf(err, "GetTagPolicyMergedTags")
 		}
 		tagsUpdateInfo := cloudprovider.TagsUpdateInfo{OldTags: oldTags, NewTags: tags}
 
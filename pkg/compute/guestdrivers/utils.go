@@ -45,6 +45,11 @@ type SDiskInfo struct {
 	Metadata map[string]string
 }
 
+// 部分平台的虚机可返回内网 DNS 名称
+type iPrivateDnsNameVM interface {
+	GetPrivateDnsName() string
+}
+
 func fetchIVMinfo(desc cloudprovider.SManagedVMCreateConfig, iVM cloudprovider.ICloudVM, guestId string, account, passwd string, publicKey string, action string) *jsonutils.JSONDict {
 	data := jsonutils.NewDict()
 
@@ -115,6 +120,18 @@ func fetchIVMinfo(desc cloudprovider.SManagedVMCreateConfig, iVM cloudprovider.I
 	}
 
 	data.Add(jsonutils.NewString(iVM.GetGlobalId()), "uuid")
+	if hostname := iVM.GetHostname(); len(hostname) > 0 {
+		data.Add(jsonutils.NewString(hostname), "hostname")
+	}
+	if vm, ok := iVM.(iPrivateDnsNameVM); ok {
+		if dnsName := vm.GetPrivateDnsName(); len(dnsName) > 0 {
+			data.Add(jsonutils.NewString(dnsName), "private_dns_name")
+		}
+	}
+	// 用于校验实际所在可用区与调度结果是否一致
+	if hostId := iVM.GetIHostId(); len(hostId) > 0 {
+		data.Add(jsonutils.NewString(hostId), "host_external_id")
+	}
 	sysTags := iVM.GetSysTags()
 	tags, _ := iVM.GetTags()
 	metadataDict := jsonutils.NewDict()
@@ -0,0 +1,35 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ha 实现 region 服务跨站点主备(active-standby)部署
+//
+// 两个站点各部署一组 region 服务, 共用同一套 etcd 集群, 数据库通过主从复制同步,
+// 任务状态保存在数据库 tasks_tbl 中, 随数据库复制到备站点。
+//
+// 启用方式: 各站点设置不同的 ha_site_name, 并将 lockman_method 设置为 etcd。
+// 所有节点通过 etcd 选主, 当选节点为 active:
+//   - 运行定时任务;
+//   - 在 etcd 中公布所在站点及主机名;
+//   - 将上一任主节点中断的任务(超过 ha_interrupted_task_timeout_minutes 未更新)以失败回调,
+//     进入各任务的失败处理流程, 使资源状态可恢复。
+//
+// 其余节点为 standby, 只处理读请求, 写请求返回 503 并在 X-Ha-Active-Site 中给出主节点站点。
+//
+// 故障切换流程:
+//  1. active 节点失联后 etcd 会话过期(etcd_lock_ttl 秒), 排队中的 standby 自动当选;
+//  2. 运维人员将数据库主从切换到新站点, 并将 keystone 中 compute 服务的 endpoint 指向新站点;
+//  3. 计划内切换可调用 POST /ha/failover, 当前 active 主动让出, 由 standby 接管,
+//     原 active 重新排队成为 standby;
+//  4. 通过 GET /ha/status 查询各节点角色及当前主节点。
+package ha // import "yunion.io/x/onecloud/pkg/compute/ha"
@@ -0,0 +1,194 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ha
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/elect"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	HA_ACTIVE_SITE_HEADER = "X-Ha-Active-Site"
+
+	// 升主时只处理该时间窗口内创建的中断任务
+	interruptedTaskWindow = 24 * time.Hour
+)
+
+type SHaManager struct {
+	elect    *elect.Elect
+	site     string
+	hostname string
+
+	standbyReadOnly bool
+	taskTimeout     time.Duration
+
+	lock             sync.Mutex
+	role             string
+	since            time.Time
+	interruptedTasks int
+}
+
+// Manager 未启用主备模式时为 nil
+var Manager *SHaManager
+
+func newHaManager(electObj *elect.Elect, opts *options.ComputeOptions) *SHaManager {
+	hostname, _ := os.Hostname()
+	return &SHaManager{
+		elect:           electObj,
+		site:            opts.HaSiteName,
+		hostname:        hostname,
+		standbyReadOnly: opts.HaStandbyReadOnly,
+		taskTimeout:     time.Duration(opts.HaInterruptedTaskTimeoutMinutes) * time.Minute,
+		role:            api.HA_ROLE_STANDBY,
+		since:           time.Now().UTC(),
+	}
+}
+
+// Init 启用主备模式, 须在 electObj.Start 之前调用
+func Init(ctx context.Context, app *appsrv.Application, electObj *elect.Elect, opts *options.ComputeOptions) error {
+	if len(opts.HaSiteName) == 0 {
+		return nil
+	}
+	if electObj == nil {
+		return errors.Wrap(httperrors.ErrNotSupported, "ha mode requires lockman_method etcd")
+	}
+	man := newHaManager(electObj, opts)
+	electObj.SetValue(jsonutils.Marshal(api.HaLeaderInfo{Site: man.site, Hostname: man.hostname}).String())
+	electObj.SubscribeWithAction(ctx, func() { man.onWin(ctx) }, man.onLost)
+	app.RegisterMiddleware(man.standbyGuard)
+	Manager = man
+	return nil
+}
+
+func (man *SHaManager) setRole(role string) {
+	man.lock.Lock()
+	defer man.lock.Unlock()
+	if man.role == role {
+		return
+	}
+	log.Infof("ha site %s role: %s -> %s", man.site, man.role, role)
+	man.role = role
+	man.since = time.Now().UTC()
+}
+
+func (man *SHaManager) IsActive() bool {
+	man.lock.Lock()
+	defer man.lock.Unlock()
+	return man.role == api.HA_ROLE_ACTIVE
+}
+
+func (man *SHaManager) onWin(ctx context.Context) {
+	man.setRole(api.HA_ROLE_ACTIVE)
+	if man.taskTimeout <= 0 {
+		return
+	}
+	now := time.Now().UTC()
+	reason := "task interrupted by control plane failover to site " + man.site
+	cnt, err := taskman.TaskManager.FailInterruptedTasks(ctx, now.Add(-interruptedTaskWindow), now.Add(-man.taskTimeout), reason)
+	if err != nil {
+		log.Errorf("fail interrupted tasks: %v", err)
+		return
+	}
+	if cnt > 0 {
+		log.Infof("ha site %s failed %d interrupted tasks on promotion", man.site, cnt)
+	}
+	man.lock.Lock()
+	defer man.lock.Unlock()
+	man.interruptedTasks = cnt
+}
+
+func (man *SHaManager) onLost() {
+	man.setRole(api.HA_ROLE_STANDBY)
+}
+
+func (man *SHaManager) leader(ctx context.Context) *api.HaLeaderInfo {
+	val, err := man.elect.Leader(ctx)
+	if err != nil {
+		log.Errorf("get ha leader: %v", err)
+		return nil
+	}
+	if len(val) == 0 {
+		return nil
+	}
+	info := &api.HaLeaderInfo{}
+	obj, err := jsonutils.ParseString(val)
+	if err != nil {
+		log.Errorf("parse ha leader %q: %v", val, err)
+		return nil
+	}
+	obj.Unmarshal(info)
+	return info
+}
+
+func (man *SHaManager) GetStatus(ctx context.Context) *api.HaStatusOutput {
+	man.lock.Lock()
+	ret := &api.HaStatusOutput{
+		Enabled:          true,
+		Site:             man.site,
+		Hostname:         man.hostname,
+		Role:             man.role,
+		Since:            man.since,
+		StandbyReadOnly:  man.standbyReadOnly,
+		InterruptedTasks: man.interruptedTasks,
+	}
+	man.lock.Unlock()
+	ret.Leader = man.leader(ctx)
+	return ret
+}
+
+// Failover 当前主节点主动让出, 由 standby 接管
+func (man *SHaManager) Failover() error {
+	if !man.IsActive() {
+		return httperrors.NewInvalidStatusError("site %s is not active", man.site)
+	}
+	return man.elect.Resign()
+}
+
+func isReadRequest(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/ha/")
+}
+
+func (man *SHaManager) standbyGuard(f func(context.Context, http.ResponseWriter, *http.Request)) func(context.Context, http.ResponseWriter, *http.Request) {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		if !man.standbyReadOnly || isReadRequest(r) || man.IsActive() {
+			f(ctx, w, r)
+			return
+		}
+		if leader := man.leader(ctx); leader != nil {
+			w.Header().Set(HA_ACTIVE_SITE_HEADER, leader.Site)
+		}
+		httperrors.HTTPError(ctx, w, "region service at site "+man.site+" is standby", http.StatusServiceUnavailable, "ServiceUnavailableError", httputils.Error{})
+	}
+}
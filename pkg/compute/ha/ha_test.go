@@ -0,0 +1,41 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ha
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsReadRequest(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{"GET", "/servers", true},
+		{"HEAD", "/servers", true},
+		{"POST", "/servers", false},
+		{"DELETE", "/servers/abc", false},
+		{"PUT", "/servers/abc", false},
+		{"POST", "/ha/failover", true},
+	}
+	for _, c := range cases {
+		r, _ := http.NewRequest(c.method, "http://localhost"+c.path, nil)
+		if got := isReadRequest(r); got != c.want {
+			t.Errorf("%s %s: want %v got %v", c.method, c.path, c.want, got)
+		}
+	}
+}
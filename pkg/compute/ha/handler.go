@@ -0,0 +1,66 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+)
+
+func AddHaHandler(prefix string, app *appsrv.Application) {
+	prefix = fmt.Sprintf("%s/ha", prefix)
+	app.AddHandler2("GET", fmt.Sprintf("%s/status", prefix), auth.Authenticate(haStatusHandler), nil, "get_ha_status", nil)
+	app.AddHandler2("POST", fmt.Sprintf("%s/failover", prefix), auth.Authenticate(haFailoverHandler), nil, "ha_failover", nil)
+}
+
+func haStatusHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	if !userCred.HasSystemAdminPrivilege() {
+		httperrors.ForbiddenError(ctx, w, "not allow to get ha status")
+		return
+	}
+	status := &api.HaStatusOutput{}
+	if Manager != nil {
+		status = Manager.GetStatus(ctx)
+	}
+	appsrv.SendJSON(w, jsonutils.Marshal(status))
+}
+
+func haFailoverHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	if !userCred.HasSystemAdminPrivilege() {
+		httperrors.ForbiddenError(ctx, w, "not allow to failover")
+		return
+	}
+	if Manager == nil {
+		httperrors.JsonClientError(ctx, w, httperrors.NewNotSupportedError("ha mode is not enabled"))
+		return
+	}
+	err := Manager.Failover()
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	appsrv.SendJSON(w, jsonutils.Marshal(Manager.GetStatus(ctx)))
+}
@@ -55,6 +55,10 @@ func (self *SBaseHostDriver) RequestDetachStorage(ctx context.Context, host *mod
 	return httperrors.NewNotImplementedError("Not Implement RequestDetachStorage")
 }
 
+func (self *SBaseHostDriver) RequestVerifyCachedImage(ctx context.Context, host *models.SHost, storageCache *models.SStoragecache, task taskman.ITask) error {
+	return httperrors.NewNotImplementedError("Not Implement RequestVerifyCachedImage")
+}
+
 func (self *SBaseHostDriver) ValidateDiskSize(storage *models.SStorage, sizeGb int) error {
 	return fmt.Errorf("Not Implement ValidateDiskSize")
 }
@@ -269,3 +273,7 @@ func (driver *SBaseHostDriver) RequestSyncOnHost(ctx context.Context, host *mode
 func (driver *SBaseHostDriver) RequestProbeIsolatedDevices(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, input jsonutils.JSONObject) (*jsonutils.JSONArray, error) {
 	return nil, nil
 }
+
+func (driver *SBaseHostDriver) RequestCollectLogBundle(ctx context.Context, host *models.SHost, input api.HostLogBundleCollectInput, task taskman.ITask) error {
+	return httperrors.NewNotSupportedError("collect log bundle not supported by host type %s", host.HostType)
+}
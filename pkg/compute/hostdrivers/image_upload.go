@@ -0,0 +1,170 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdrivers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/chunkdownload"
+)
+
+const (
+	IMAGE_UPLOAD_TMP_DIR = "image-upload"
+
+	rateLimitBurst = 1024 * 1024
+)
+
+func getImageUploadBandwidthMb(provider string) int {
+	for _, conf := range options.Options.CacheImageUploadBandwidthMb {
+		parts := strings.SplitN(conf, ":", 2)
+		if len(parts) != 2 || !strings.EqualFold(strings.TrimSpace(parts[0]), provider) {
+			continue
+		}
+		mb, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			log.Warningf("invalid cache_image_upload_bandwidth_mb %q", conf)
+			continue
+		}
+		return mb
+	}
+	return 0
+}
+
+type sRateLimitReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rate.Limiter
+}
+
+func newRateLimitReader(ctx context.Context, reader io.Reader, mb int) io.Reader {
+	if mb <= 0 {
+		return reader
+	}
+	limiter := rate.NewLimiter(rate.Limit(mb*1024*1024), rateLimitBurst)
+	return &sRateLimitReader{ctx: ctx, reader: reader, limiter: limiter}
+}
+
+func (r *sRateLimitReader) Read(p []byte) (int, error) {
+	if len(p) > rateLimitBurst {
+		p = p[:rateLimitBurst]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// 上传至云平台前先将镜像分块并行拉取到本地临时目录并记录断点,
+// 上传失败时保留已下载的数据, 重试时只需拉取缺失的部分
+type sImageUploadSource struct {
+	ctx      context.Context
+	session  *mcclient.ClientSession
+	provider string
+
+	lock  sync.Mutex
+	files []string
+	fds   []*os.File
+}
+
+func newImageUploadSource(ctx context.Context, s *mcclient.ClientSession, provider string) *sImageUploadSource {
+	return &sImageUploadSource{ctx: ctx, session: s, provider: provider}
+}
+
+func (src *sImageUploadSource) GetReader(imageId, format string) (io.Reader, int64, error) {
+	bwMb := getImageUploadBandwidthMb(src.provider)
+	rc, size, err := modules.Images.DownloadRange(src.session, imageId, format, 0, 0)
+	if err != nil {
+		if errors.Cause(err) != httperrors.ErrNotSupported {
+			return nil, -1, errors.Wrapf(err, "probe image %s size", imageId)
+		}
+		log.Warningf("image service not support range download, fallback to stream")
+		_, reader, size, err := modules.Images.Download(src.session, imageId, format, false)
+		if err != nil {
+			return nil, -1, err
+		}
+		return newRateLimitReader(src.ctx, reader, bwMb), size, nil
+	}
+	rc.Close()
+
+	dir := path.Join(options.Options.TempPath, IMAGE_UPLOAD_TMP_DIR)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, -1, errors.Wrapf(err, "mkdir %s", dir)
+	}
+	dest := path.Join(dir, fmt.Sprintf("%s.%s", imageId, format))
+	src.lock.Lock()
+	src.files = append(src.files, dest)
+	src.lock.Unlock()
+
+	fetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+		rc, _, err := modules.Images.DownloadRange(src.session, imageId, format, start, end)
+		return rc, err
+	}
+	opts := chunkdownload.SOptions{
+		ChunkSizeMb:   options.Options.CacheImageFetchChunkSizeMb,
+		Parallel:      options.Options.CacheImageFetchParallel,
+		Retry:         options.Options.CacheImageFetchRetry,
+		RetryInterval: 5 * time.Second,
+	}
+	progress := func(percent float32) {
+		log.Debugf("fetch image %s(%s) for uploading to %s: %.2f%%", imageId, format, src.provider, percent)
+	}
+	if err := chunkdownload.Download(src.ctx, fetch, size, dest, opts, progress); err != nil {
+		return nil, -1, errors.Wrapf(err, "fetch image %s", imageId)
+	}
+	fd, err := os.Open(dest)
+	if err != nil {
+		return nil, -1, errors.Wrapf(err, "open %s", dest)
+	}
+	src.lock.Lock()
+	src.fds = append(src.fds, fd)
+	src.lock.Unlock()
+	return newRateLimitReader(src.ctx, fd, bwMb), size, nil
+}
+
+// Close 上传成功后清理临时文件, 失败时保留以便续传
+func (src *sImageUploadSource) Close(success bool) {
+	src.lock.Lock()
+	defer src.lock.Unlock()
+	for _, fd := range src.fds {
+		fd.Close()
+	}
+	src.fds = nil
+	if success {
+		for _, fn := range src.files {
+			chunkdownload.Cleanup(fn)
+		}
+		src.files = nil
+	}
+}
@@ -245,6 +245,34 @@ func (self *SKVMHostDriver) RequestUncacheImage(ctx context.Context, host *model
 	return nil
 }
 
+func (self *SKVMHostDriver) RequestVerifyCachedImage(ctx context.Context, host *models.SHost, storageCache *models.SStoragecache, task taskman.ITask) error {
+	params := task.GetParams()
+	imageId, err := params.GetString("image_id")
+	if err != nil {
+		return err
+	}
+	content := jsonutils.NewDict()
+	content.Set("image_id", jsonutils.NewString(imageId))
+	content.Set("storagecache_id", jsonutils.NewString(storageCache.Id))
+	if checksum, _ := params.GetString("checksum"); len(checksum) > 0 {
+		content.Set("checksum", jsonutils.NewString(checksum))
+	}
+	if jsonutils.QueryBoolean(params, "repair", false) {
+		content.Set("repair", jsonutils.JSONTrue)
+	}
+
+	url := fmt.Sprintf("%s/disks/image_cache/verify", host.ManagerUri)
+	body := jsonutils.NewDict()
+	body.Add(content, "disk")
+
+	header := task.GetTaskRequestHeader()
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	if err != nil {
+		return errors.Wrapf(err, "POST %s", url)
+	}
+	return nil
+}
+
 func (self *SKVMHostDriver) RequestAllocateDiskOnStorage(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, storage *models.SStorage, disk *models.SDisk, task taskman.ITask, input api.DiskAllocateInput) error {
 	header := task.GetTaskRequestHeader()
 	if len(input.SnapshotId) > 0 {
@@ -629,6 +657,14 @@ func (self *SKVMHostDriver) RequestSyncOnHost(ctx context.Context, host *models.
 	return err
 }
 
+// 宿主机异步收集日志包并上传, 完成后回调任务
+func (self *SKVMHostDriver) RequestCollectLogBundle(ctx context.Context, host *models.SHost, input api.HostLogBundleCollectInput, task taskman.ITask) error {
+	url := fmt.Sprintf("/hosts/%s/collect-log-bundle", host.Id)
+	header := task.GetTaskRequestHeader()
+	_, err := host.Request(ctx, task.GetUserCred(), "POST", url, header, jsonutils.Marshal(input))
+	return err
+}
+
 func (self *SKVMHostDriver) GetJsonFromHost(ctx context.Context, host *models.SHost) *jsonutils.JSONDict {
 	desc := jsonutils.NewDict()
 	desc.Add(jsonutils.NewString(host.Name), "name")
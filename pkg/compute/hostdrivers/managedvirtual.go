@@ -17,7 +17,6 @@ package hostdrivers
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"time"
 
@@ -129,12 +128,12 @@ func (self *SManagedVirtualizationHostDriver) CheckAndSetCacheImage(ctx context.
 				image.MinRamMb = int(minRamMb)
 				image.TmpPath = options.Options.TempPath
 
-				image.GetReader = func(imageId, format string) (io.Reader, int64, error) {
-					_, reader, sizeByte, err := modules.Images.Download(s, imageId, format, false)
-					return reader, sizeByte, err
-				}
+				source := newImageUploadSource(ctx, s, providerName)
+				image.GetReader = source.GetReader
 				log.Debugf("UploadImage: no external ID")
-				return iStorageCache.UploadImage(ctx, image, callback)
+				externalId, err := iStorageCache.UploadImage(ctx, image, callback)
+				source.Close(err == nil)
+				return externalId, err
 			}()
 			log.Infof("upload image %s id: %s", image.ImageName, image.ExternalId)
 		} else {
@@ -19,7 +19,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/tristate"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
@@ -35,6 +38,8 @@ func AddMiscHandler(prefix string, app *appsrv.Application) {
 	prefix = fmt.Sprintf("%s/misc", prefix)
 	addHandler("GET", fmt.Sprintf("%s/bm-agent-url", prefix), getBmAgentUrl, app)
 	addHandler("GET", fmt.Sprintf("%s/bm-prepare-script", prefix), getBmPrepareScript, app)
+
+	app.AddDefaultHandler("GET", "/cloud_api_stats", cloudApiStatsHandler, "cloud_api_stats")
 }
 
 func addHandler(method, prefix string, f appsrv.FilterHandler, app *appsrv.Application) {
@@ -42,6 +47,21 @@ func addHandler(method, prefix string, f appsrv.FilterHandler, app *appsrv.Appli
 	app.AddHandler(method, prefix, handler)
 }
 
+// 各云平台 API 调用次数, 耗时及错误码统计, 可通过 provider 参数过滤
+func cloudApiStatsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get("provider")
+	stats := []cloudprovider.SApiCallStat{}
+	for _, stat := range cloudprovider.GetApiCallStats() {
+		if len(provider) > 0 && !strings.EqualFold(stat.Provider, provider) {
+			continue
+		}
+		stats = append(stats, stat)
+	}
+	result := jsonutils.NewDict()
+	result.Add(jsonutils.Marshal(stats), "cloud_api_stats")
+	appsrv.SendJSON(w, result)
+}
+
 func getBmAgentUrl(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	var err error
 	ipAddr := r.URL.Query().Get("ssh_ip")
@@ -0,0 +1,357 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// ICloudImageSharer 云平台原生的镜像共享能力, 例如 AWS AMI launch permission, 阿里云 ModifyImageSharePermission
+// 由各云平台的镜像实现, 未实现的平台不支持共享
+type ICloudImageSharer interface {
+	ShareToAccounts(ctx context.Context, accountIds []string) error
+	UnshareFromAccounts(ctx context.Context, accountIds []string) error
+}
+
+// 记录公有云自定义镜像共享到了哪些云上账号
+type SCachedimageShareManager struct {
+	db.SStatusStandaloneResourceBaseManager
+	SStoragecacheResourceBaseManager
+}
+
+var CachedimageShareManager *SCachedimageShareManager
+
+func init() {
+	CachedimageShareManager = &SCachedimageShareManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SCachedimageShare{},
+			"cachedimage_shares_tbl",
+			"cachedimage_share",
+			"cachedimage_shares",
+		),
+	}
+	CachedimageShareManager.SetVirtualObject(CachedimageShareManager)
+}
+
+type SCachedimageShare struct {
+	db.SStatusStandaloneResourceBase
+	SStoragecacheResourceBase
+
+	// 镜像缓存Id
+	CachedimageId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 云上目标账号ID
+	TargetAccountId string `width:"128" charset:"utf8" nullable:"false" list:"admin"`
+	// 平台内的目标云账号Id
+	TargetCloudaccountId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+}
+
+func (manager *SCachedimageShareManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("use cachedimage share action instead")
+}
+
+func (self *SCachedimageShare) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	return httperrors.NewUnsupportOperationError("use cachedimage unshare action instead")
+}
+
+func (manager *SCachedimageShareManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CachedimageShareListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SStoragecacheResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StoragecacheFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStoragecacheResourceBaseManager.ListItemFilter")
+	}
+	if len(query.CachedimageId) > 0 {
+		imgObj, err := CachedimageManager.FetchByIdOrName(userCred, query.CachedimageId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CachedimageManager.Keyword(), query.CachedimageId)
+		}
+		q = q.Equals("cachedimage_id", imgObj.GetId())
+	}
+	if len(query.TargetAccountId) > 0 {
+		q = q.In("target_account_id", query.TargetAccountId)
+	}
+	if len(query.TargetCloudaccountId) > 0 {
+		account, err := CloudaccountManager.FetchByIdOrName(userCred, query.TargetCloudaccountId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), query.TargetCloudaccountId)
+		}
+		q = q.Equals("target_cloudaccount_id", account.GetId())
+	}
+	return q, nil
+}
+
+func (manager *SCachedimageShareManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CachedimageShareListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (manager *SCachedimageShareManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return manager.SStoragecacheResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SCachedimageShareManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.CachedimageShareDetails {
+	rows := make([]api.CachedimageShareDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	scRows := manager.SStoragecacheResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	imageIds := make([]string, len(objs))
+	accountIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		if scRows != nil {
+			rows[i].StoragecacheResourceInfo = scRows[i]
+		}
+		share := objs[i].(*SCachedimageShare)
+		imageIds[i] = share.CachedimageId
+		accountIds[i] = share.TargetCloudaccountId
+	}
+	images := make(map[string]SCachedimage)
+	if err := db.FetchStandaloneObjectsByIds(CachedimageManager, imageIds, images); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds cachedimages fail %s", err)
+		return rows
+	}
+	accounts := make(map[string]SCloudaccount)
+	if err := db.FetchStandaloneObjectsByIds(CloudaccountManager, accountIds, accounts); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds cloudaccounts fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if img, ok := images[imageIds[i]]; ok {
+			rows[i].Cachedimage = img.Name
+		}
+		if account, ok := accounts[accountIds[i]]; ok {
+			rows[i].TargetCloudaccount = account.Name
+		}
+	}
+	return rows
+}
+
+func (manager *SCachedimageShareManager) GetShares(cachedimageId, storagecacheId string, targetAccountIds []string) ([]SCachedimageShare, error) {
+	q := manager.Query().Equals("cachedimage_id", cachedimageId).Equals("storagecache_id", storagecacheId)
+	if len(targetAccountIds) > 0 {
+		q = q.In("target_account_id", targetAccountIds)
+	}
+	shares := []SCachedimageShare{}
+	err := db.FetchModelObjects(manager, q, &shares)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return shares, nil
+}
+
+func (manager *SCachedimageShareManager) register(ctx context.Context, cachedimageId, storagecacheId, targetAccountId, targetCloudaccountId string) (*SCachedimageShare, error) {
+	shares, err := manager.GetShares(cachedimageId, storagecacheId, []string{targetAccountId})
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) > 0 {
+		share := &shares[0]
+		_, err := db.Update(share, func() error {
+			share.Status = api.CACHEDIMAGE_SHARE_STATUS_SHARING
+			if len(targetCloudaccountId) > 0 {
+				share.TargetCloudaccountId = targetCloudaccountId
+			}
+			return nil
+		})
+		return share, err
+	}
+	share := &SCachedimageShare{
+		CachedimageId:        cachedimageId,
+		TargetAccountId:      targetAccountId,
+		TargetCloudaccountId: targetCloudaccountId,
+	}
+	share.StoragecacheId = storagecacheId
+	share.Name = targetAccountId
+	share.Status = api.CACHEDIMAGE_SHARE_STATUS_SHARING
+	share.SetModelManager(manager, share)
+	return share, manager.TableSpec().Insert(ctx, share)
+}
+
+func (self *SCachedimage) getShareableStoragecachedimage(storagecacheId string) (*SStoragecache, *SStoragecachedimage, error) {
+	if cloudprovider.TImageType(self.ImageType) != cloudprovider.ImageTypeCustomized {
+		return nil, nil, httperrors.NewUnsupportOperationError("only customized image can be shared")
+	}
+	scObj, err := StoragecacheManager.FetchById(storagecacheId)
+	if err != nil {
+		return nil, nil, httperrors.NewResourceNotFoundError2(StoragecacheManager.Keyword(), storagecacheId)
+	}
+	sc := scObj.(*SStoragecache)
+	if !sc.IsManaged() {
+		return nil, nil, httperrors.NewUnsupportOperationError("storagecache %s is not managed by cloud provider", sc.Name)
+	}
+	scimg := StoragecachedimageManager.GetStoragecachedimage(sc.Id, self.Id)
+	if scimg == nil || len(scimg.ExternalId) == 0 {
+		return nil, nil, httperrors.NewInvalidStatusError("image %s not cached in storagecache %s", self.Name, sc.Name)
+	}
+	if scimg.Status != api.CACHED_IMAGE_STATUS_ACTIVE {
+		return nil, nil, httperrors.NewInvalidStatusError("image %s in storagecache %s is %s", self.Name, sc.Name, scimg.Status)
+	}
+	return sc, scimg, nil
+}
+
+// 共享公有云自定义镜像至其他云上账号
+func (self *SCachedimage) PerformShare(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CachedimageShareInput) (jsonutils.JSONObject, error) {
+	sc, _, err := self.getShareableStoragecachedimage(input.StoragecacheId)
+	if err != nil {
+		return nil, err
+	}
+	srcAccount := sc.GetCloudaccount()
+	if srcAccount == nil {
+		return nil, httperrors.NewInvalidStatusError("storagecache %s has no cloudaccount", sc.Name)
+	}
+
+	targets := map[string]string{}
+	for _, id := range input.TargetCloudaccountIds {
+		accountObj, err := CloudaccountManager.FetchByIdOrName(userCred, id)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), id)
+		}
+		account := accountObj.(*SCloudaccount)
+		if account.Provider != srcAccount.Provider {
+			return nil, httperrors.NewInputParameterError("cloudaccount %s is %s, image belongs to %s", account.Name, account.Provider, srcAccount.Provider)
+		}
+		if account.Id == srcAccount.Id {
+			return nil, httperrors.NewInputParameterError("cannot share image to its own cloudaccount %s", account.Name)
+		}
+		if len(account.AccountId) == 0 {
+			return nil, httperrors.NewInputParameterError("cloudaccount %s has no account id", account.Name)
+		}
+		targets[account.AccountId] = account.Id
+	}
+	for _, id := range input.TargetAccountIds {
+		if len(id) == 0 || id == srcAccount.AccountId {
+			return nil, httperrors.NewInputParameterError("invalid target account id %q", id)
+		}
+		if _, ok := targets[id]; !ok {
+			targets[id] = ""
+		}
+	}
+	if len(targets) == 0 {
+		return nil, httperrors.NewMissingParameterError("target_cloudaccount_ids or target_account_ids")
+	}
+
+	shareIds := []string{}
+	for accountId, cloudaccountId := range targets {
+		share, err := CachedimageShareManager.register(ctx, self.Id, sc.Id, accountId, cloudaccountId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "register share to %s", accountId)
+		}
+		shareIds = append(shareIds, share.Id)
+	}
+	return nil, self.startShareTask(ctx, userCred, "CachedimageShareTask", sc.Id, shareIds)
+}
+
+// 取消共享
+func (self *SCachedimage) PerformUnshare(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CachedimageUnshareInput) (jsonutils.JSONObject, error) {
+	sc, _, err := self.getShareableStoragecachedimage(input.StoragecacheId)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := CachedimageShareManager.GetShares(self.Id, sc.Id, input.TargetAccountIds)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	shareIds := []string{}
+	for i := range shares {
+		if utils.IsInStringArray(shares[i].Status, []string{api.CACHEDIMAGE_SHARE_STATUS_SHARING, api.CACHEDIMAGE_SHARE_STATUS_UNSHARING}) {
+			return nil, httperrors.NewInvalidStatusError("share to %s is %s", shares[i].TargetAccountId, shares[i].Status)
+		}
+		shares[i].SetStatus(userCred, api.CACHEDIMAGE_SHARE_STATUS_UNSHARING, "")
+		shareIds = append(shareIds, shares[i].Id)
+	}
+	return nil, self.startShareTask(ctx, userCred, "CachedimageUnshareTask", sc.Id, shareIds)
+}
+
+func (self *SCachedimage) startShareTask(ctx context.Context, userCred mcclient.TokenCredential, taskName string, storagecacheId string, shareIds []string) error {
+	params := jsonutils.NewDict()
+	params.Set("storagecache_id", jsonutils.NewString(storagecacheId))
+	params.Set("share_ids", jsonutils.NewStringArray(shareIds))
+	task, err := taskman.TaskManager.NewTask(ctx, taskName, self, userCred, params, "", "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask %s", taskName)
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SCachedimage) GetICloudImageSharer(ctx context.Context, storagecacheId string) (ICloudImageSharer, error) {
+	sc, scimg, err := self.getShareableStoragecachedimage(storagecacheId)
+	if err != nil {
+		return nil, err
+	}
+	iCache, err := sc.GetIStorageCache(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetIStorageCache")
+	}
+	iImg, err := iCache.GetIImageById(scimg.ExternalId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetIImageById(%s)", scimg.ExternalId)
+	}
+	sharer, ok := iImg.(ICloudImageSharer)
+	if !ok {
+		return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "%s image sharing", sc.GetProviderName())
+	}
+	return sharer, nil
+}
+
+func (manager *SCachedimageShareManager) FetchShares(ids []string) ([]SCachedimageShare, error) {
+	shares := []SCachedimageShare{}
+	err := db.FetchModelObjects(manager, manager.Query().In("id", ids), &shares)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return shares, nil
+}
+
+func (self *SCachedimageShare) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type sCloudaccountDiagnoser struct {
+	output api.CloudaccountDiagnoseOutput
+}
+
+func (d *sCloudaccountDiagnoser) check(provider, region, name string, f func() error) bool {
+	start := time.Now()
+	item := api.CloudaccountDiagnoseItem{
+		Name:          name,
+		Cloudprovider: provider,
+		Cloudregion:   region,
+		Status:        api.CLOUD_ACCOUNT_DIAGNOSE_PASS,
+	}
+	err := f()
+	item.ElapsedMs = time.Since(start).Milliseconds()
+	if err != nil {
+		item.Status = api.CLOUD_ACCOUNT_DIAGNOSE_FAIL
+		if errors.Cause(err) == cloudprovider.ErrNotSupported || errors.Cause(err) == cloudprovider.ErrNotImplemented {
+			item.Status = api.CLOUD_ACCOUNT_DIAGNOSE_SKIP
+		}
+		item.Message = err.Error()
+	}
+	d.output.Items = append(d.output.Items, item)
+	return item.Status != api.CLOUD_ACCOUNT_DIAGNOSE_FAIL
+}
+
+func (d *sCloudaccountDiagnoser) skip(provider, region, name, reason string) {
+	d.output.Items = append(d.output.Items, api.CloudaccountDiagnoseItem{
+		Name:          name,
+		Cloudprovider: provider,
+		Cloudregion:   region,
+		Status:        api.CLOUD_ACCOUNT_DIAGNOSE_SKIP,
+		Message:       reason,
+	})
+}
+
+func (d *sCloudaccountDiagnoser) diagnoseRegion(provider string, iregion cloudprovider.ICloudRegion, writeCheck bool) {
+	region := iregion.GetName()
+	d.check(provider, region, "describe_zones", func() error {
+		_, err := iregion.GetIZones()
+		return err
+	})
+	d.check(provider, region, "describe_vpcs", func() error {
+		_, err := iregion.GetIVpcs()
+		return err
+	})
+	d.check(provider, region, "describe_storages", func() error {
+		_, err := iregion.GetIStorages()
+		return err
+	})
+	var ivm cloudprovider.ICloudVM
+	d.check(provider, region, "describe_instances", func() error {
+		ihosts, err := iregion.GetIHosts()
+		if err != nil {
+			return errors.Wrapf(err, "GetIHosts")
+		}
+		for i := range ihosts {
+			ivms, err := ihosts[i].GetIVMs()
+			if err != nil {
+				return errors.Wrapf(err, "GetIVMs")
+			}
+			if len(ivms) > 0 && ivm == nil {
+				ivm = ivms[0]
+			}
+		}
+		return nil
+	})
+	if !writeCheck {
+		d.skip(provider, region, "create_tag", "write check disabled")
+	} else if ivm == nil {
+		d.skip(provider, region, "create_tag", "no instance found to verify tag permission")
+	} else {
+		d.check(provider, region, "create_tag", func() error {
+			tags, err := ivm.GetTags()
+			if err != nil {
+				return errors.Wrapf(err, "GetTags")
+			}
+			// write back the existing tags so the remote resource stays unchanged
+			return ivm.SetTags(tags, false)
+		})
+	}
+	// none of the provider drivers exposes a dry-run disk creation
+	d.skip(provider, region, "create_disk_dry_run", "dry-run disk creation is not supported by provider driver")
+}
+
+func (self *SCloudaccount) diagnoseNetwork() error {
+	if len(self.AccessUrl) == 0 {
+		return errors.Wrap(cloudprovider.ErrNotSupported, "account has no access url, use provider default endpoint")
+	}
+	host, port, err := self.getHostPort()
+	if err != nil {
+		return errors.Wrapf(err, "parse access url %s", self.AccessUrl)
+	}
+	if port == 0 {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "unknown port of access url %s", self.AccessUrl)
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, fmt.Sprintf("%d", port)), 5*time.Second)
+	if err != nil {
+		return errors.Wrapf(err, "dial %s:%d", host, port)
+	}
+	conn.Close()
+	return nil
+}
+
+// 诊断云账号的网络连通性及读写权限
+func (self *SCloudaccount) PerformDiagnose(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CloudaccountDiagnoseInput) (api.CloudaccountDiagnoseOutput, error) {
+	d := &sCloudaccountDiagnoser{}
+	d.output.LakeOfPermissions = self.LakeOfPermissions
+
+	regionExtId := ""
+	if len(input.Cloudregion) > 0 {
+		regionObj, err := CloudregionManager.FetchByIdOrName(userCred, input.Cloudregion)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return d.output, httperrors.NewResourceNotFoundError2(CloudregionManager.Keyword(), input.Cloudregion)
+			}
+			return d.output, httperrors.NewGeneralError(err)
+		}
+		regionExtId = regionObj.(*SCloudregion).ExternalId
+	}
+
+	d.check("", "", "network", self.diagnoseNetwork)
+
+	if d.check("", "", "authentication", func() error {
+		_, err := self.GetSubAccounts(ctx)
+		return err
+	}) {
+		providers := self.GetEnabledCloudproviders()
+		if len(providers) == 0 {
+			d.skip("", "", "describe_regions", "no enabled cloudprovider")
+		}
+		for i := range providers {
+			var iregions []cloudprovider.ICloudRegion
+			if !d.check(providers[i].Name, "", "describe_regions", func() error {
+				driver, err := providers[i].GetProvider(ctx)
+				if err != nil {
+					return errors.Wrapf(err, "GetProvider")
+				}
+				iregions = driver.GetIRegions()
+				if len(iregions) == 0 {
+					return fmt.Errorf("no region available")
+				}
+				return nil
+			}) {
+				continue
+			}
+			for j := range iregions {
+				if len(regionExtId) > 0 && iregions[j].GetGlobalId() != regionExtId {
+					continue
+				}
+				d.diagnoseRegion(providers[i].Name, iregions[j], input.WriteCheck)
+				if len(regionExtId) == 0 {
+					break
+				}
+			}
+		}
+	}
+
+	d.output.Status = api.CLOUD_ACCOUNT_DIAGNOSE_PASS
+	for _, item := range d.output.Items {
+		if item.Status == api.CLOUD_ACCOUNT_DIAGNOSE_FAIL {
+			d.output.Status = api.CLOUD_ACCOUNT_DIAGNOSE_FAIL
+			break
+		}
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_DIAGNOSE, d.output, userCred, d.output.Status == api.CLOUD_ACCOUNT_DIAGNOSE_PASS)
+	return d.output, nil
+}
@@ -0,0 +1,292 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var (
+	CloudaccountSyncProfileManager *SCloudaccountSyncProfileManager
+)
+
+type SCloudaccountSyncProfileManager struct {
+	db.SStandaloneAnonResourceBaseManager
+}
+
+func init() {
+	CloudaccountSyncProfileManager = &SCloudaccountSyncProfileManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SCloudaccountSyncProfile{},
+			"cloudaccount_sync_profiles_tbl",
+			"cloudaccount_sync_profile",
+			"cloudaccount_sync_profiles",
+		),
+	}
+	CloudaccountSyncProfileManager.SetVirtualObject(CloudaccountSyncProfileManager)
+}
+
+// 云账号按资源类型的同步间隔, 未配置的资源类型不自动同步
+type SCloudaccountSyncProfile struct {
+	db.SStandaloneAnonResourceBase
+
+	CloudaccountId string `width:"36" charset:"ascii" nullable:"false" list:"domain" create:"domain_required" index:"true" json:"cloudaccount_id"`
+	Resource       string `width:"32" charset:"ascii" nullable:"false" list:"domain" create:"domain_required" json:"resource"`
+	// 同步间隔(秒)
+	IntervalSeconds int `nullable:"false" list:"domain" create:"domain_required" update:"domain" json:"interval_seconds"`
+	// 最近一次按该配置触发同步的时间
+	LastSyncAt time.Time `nullable:"true" list:"domain" json:"last_sync_at"`
+}
+
+func validateSyncProfileInterval(interval int) error {
+	if interval < api.CLOUD_SYNC_PROFILE_MIN_INTERVAL_SECONDS {
+		return httperrors.NewInputParameterError("interval_seconds must not be less than %d", api.CLOUD_SYNC_PROFILE_MIN_INTERVAL_SECONDS)
+	}
+	return nil
+}
+
+func (manager *SCloudaccountSyncProfileManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.CloudaccountSyncProfileCreateInput) (api.CloudaccountSyncProfileCreateInput, error) {
+	var err error
+	input.StandaloneAnonResourceCreateInput, err = manager.SStandaloneAnonResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StandaloneAnonResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if len(input.CloudaccountId) == 0 {
+		return input, httperrors.NewMissingParameterError("cloudaccount_id")
+	}
+	accountObj, err := CloudaccountManager.FetchByIdOrName(userCred, input.CloudaccountId)
+	if err != nil {
+		if errors.Cause(err) == sqlchemy.ErrEmptyQuery || errors.Cause(err) == errors.ErrNotFound {
+			return input, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), input.CloudaccountId)
+		}
+		return input, httperrors.NewGeneralError(err)
+	}
+	input.CloudaccountId = accountObj.GetId()
+	if !utils.IsInStringArray(input.Resource, api.CLOUD_SYNC_PROFILE_RESOURCES) {
+		return input, httperrors.NewInputParameterError("invalid resource %q", input.Resource)
+	}
+	err = validateSyncProfileInterval(input.IntervalSeconds)
+	if err != nil {
+		return input, err
+	}
+	cnt, err := manager.Query().Equals("cloudaccount_id", input.CloudaccountId).Equals("resource", input.Resource).CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewDuplicateResourceError("sync profile of %s for cloudaccount %s already exists", input.Resource, accountObj.GetName())
+	}
+	return input, nil
+}
+
+func (self *SCloudaccountSyncProfile) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CloudaccountSyncProfileUpdateInput) (api.CloudaccountSyncProfileUpdateInput, error) {
+	var err error
+	input.StandaloneAnonResourceBaseUpdateInput, err = self.SStandaloneAnonResourceBase.ValidateUpdateData(ctx, userCred, query, input.StandaloneAnonResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.IntervalSeconds != nil {
+		err = validateSyncProfileInterval(*input.IntervalSeconds)
+		if err != nil {
+			return input, err
+		}
+	}
+	return input, nil
+}
+
+func (manager *SCloudaccountSyncProfileManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.CloudaccountSyncProfileListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemFilter")
+	}
+	if len(query.CloudaccountId) > 0 {
+		accountObj, err := CloudaccountManager.FetchByIdOrName(userCred, query.CloudaccountId)
+		if err != nil {
+			if errors.Cause(err) == sqlchemy.ErrEmptyQuery || errors.Cause(err) == errors.ErrNotFound {
+				return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), query.CloudaccountId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Equals("cloudaccount_id", accountObj.GetId())
+	}
+	if len(query.Resource) > 0 {
+		q = q.In("resource", query.Resource)
+	}
+	return q, nil
+}
+
+func (manager *SCloudaccountSyncProfileManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.CloudaccountSyncProfileDetails {
+	rows := make([]api.CloudaccountSyncProfileDetails, len(objs))
+	stdRows := manager.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	accountIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StandaloneAnonResourceDetails = stdRows[i]
+		profile := objs[i].(*SCloudaccountSyncProfile)
+		accountIds[i] = profile.CloudaccountId
+		rows[i].NextSyncAt = profile.nextSyncAt()
+	}
+	accounts := make(map[string]SCloudaccount)
+	err := db.FetchStandaloneObjectsByIds(CloudaccountManager, accountIds, &accounts)
+	if err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if account, ok := accounts[accountIds[i]]; ok {
+			rows[i].Cloudaccount = account.Name
+		}
+	}
+	return rows
+}
+
+func (self *SCloudaccountSyncProfile) nextSyncAt() time.Time {
+	if self.LastSyncAt.IsZero() {
+		return time.Time{}
+	}
+	return self.LastSyncAt.Add(time.Duration(self.IntervalSeconds) * time.Second)
+}
+
+func (self *SCloudaccountSyncProfile) isDue(now time.Time) bool {
+	if self.IntervalSeconds <= 0 {
+		return false
+	}
+	return self.LastSyncAt.IsZero() || !now.Before(self.nextSyncAt())
+}
+
+func (self *SCloudaccountSyncProfile) markSynced(now time.Time) error {
+	_, err := db.Update(self, func() error {
+		self.LastSyncAt = now
+		return nil
+	})
+	return err
+}
+
+// DispatchSyncs 按各云账号的同步配置, 对到期的资源类型发起同步
+func (manager *SCloudaccountSyncProfileManager) DispatchSyncs(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	profiles := make([]SCloudaccountSyncProfile, 0)
+	err := db.FetchModelObjects(manager, manager.Query(), &profiles)
+	if err != nil {
+		log.Errorf("fetch cloudaccount sync profiles: %v", err)
+		return
+	}
+	groups := map[string][]*SCloudaccountSyncProfile{}
+	for i := range profiles {
+		groups[profiles[i].CloudaccountId] = append(groups[profiles[i].CloudaccountId], &profiles[i])
+	}
+	now := time.Now().UTC()
+	for accountId, ps := range groups {
+		accountObj, err := CloudaccountManager.FetchById(accountId)
+		if err != nil {
+			log.Errorf("fetch cloudaccount %s of sync profiles: %v", accountId, err)
+			continue
+		}
+		account := accountObj.(*SCloudaccount)
+		if !account.GetEnabled() || account.Status != api.CLOUD_PROVIDER_CONNECTED || !account.CanSync() {
+			continue
+		}
+		due := []*SCloudaccountSyncProfile{}
+		resources := []string{}
+		syncSku := false
+		for _, p := range ps {
+			if !p.isDue(now) {
+				continue
+			}
+			due = append(due, p)
+			if p.Resource == api.CLOUD_SYNC_PROFILE_RESOURCE_SKU {
+				syncSku = true
+			} else {
+				resources = append(resources, p.Resource)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+		if len(resources) > 0 {
+			syncRange := &SSyncRange{SyncRangeInput: api.SyncRangeInput{Resources: resources}}
+			err := account.StartSyncCloudProviderInfoTask(ctx, userCred, syncRange, "")
+			if err != nil {
+				log.Errorf("start sync %s of cloudaccount %s: %v", resources, account.Name, err)
+				continue
+			}
+		}
+		if syncSku {
+			account.submitSyncSkus(ctx, userCred)
+		}
+		for _, p := range due {
+			err := p.markSynced(now)
+			if err != nil {
+				log.Errorf("mark sync profile %s synced: %v", p.Id, err)
+			}
+		}
+	}
+}
+
+func (account *SCloudaccount) submitSyncSkus(ctx context.Context, userCred mcclient.TokenCredential) {
+	if !account.IsPublicCloud.Bool() {
+		// 私有云规格随 compute 资源同步
+		return
+	}
+	RunSyncCloudAccountTask(ctx, func() {
+		meta, err := FetchSkuResourcesMeta()
+		if err != nil {
+			log.Errorf("FetchSkuResourcesMeta: %v", err)
+			return
+		}
+		providers := account.GetCloudproviders()
+		for i := range providers {
+			if !providers[i].GetEnabled() {
+				continue
+			}
+			cprs := providers[i].GetCloudproviderRegions()
+			for j := range cprs {
+				if !cprs[j].Enabled {
+					continue
+				}
+				region, err := cprs[j].GetRegion()
+				if err != nil {
+					log.Errorf("GetRegion for cloudproviderregion %d: %v", cprs[j].RowId, err)
+					continue
+				}
+				result := SyncServerSkusByRegion(ctx, userCred, region, meta)
+				log.Debugf("sync skus of region %s for cloudaccount %s: %s", region.Name, account.Name, result.Result())
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncProfileIsDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		interval int
+		last     time.Time
+		want     bool
+	}{
+		{"never synced", 120, time.Time{}, true},
+		{"not yet", 120, now.Add(-time.Minute), false},
+		{"exactly due", 120, now.Add(-2 * time.Minute), true},
+		{"overdue", 86400, now.Add(-25 * time.Hour), true},
+		{"disabled", 0, time.Time{}, false},
+	}
+	for _, c := range cases {
+		p := &SCloudaccountSyncProfile{IntervalSeconds: c.interval, LastSyncAt: c.last}
+		if got := p.isDue(now); got != c.want {
+			t.Errorf("%s: want %v got %v", c.name, c.want, got)
+		}
+	}
+}
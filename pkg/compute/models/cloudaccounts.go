@@ -213,6 +213,21 @@ func (self *SCloudaccount) ValidateDeleteCondition(ctx context.Context, info jso
 			return httperrors.NewInvalidStatusError("provider %s: %v", cloudproviders[i].Name, err)
 		}
 	}
+	// 金库账号中的合规副本必须先过保留期并清理
+	cnt, err := SnapshotArchivePolicyManager.Query().Equals("vault_cloudaccount_id", self.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("account is vault of %d snapshot archive policies", cnt)
+	}
+	cnt, err = SnapshotArchiveManager.Query().Equals("vault_cloudaccount_id", self.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("account holds %d snapshot archives", cnt)
+	}
 
 	return self.SEnabledStatusInfrasResourceBase.ValidateDeleteCondition(ctx, nil)
 }
@@ -342,6 +357,14 @@ func (self *SCloudaccount) PostUpdate(ctx context.Context, userCred mcclient.Tok
 	}
 }
 
+// 只读账号仅同步资源, 不允许平台对云上资源做任何变更
+func (self *SCloudaccount) ValidateWritable() error {
+	if self.ReadOnly {
+		return httperrors.NewForbiddenError("cloudaccount %s is in read-only mode, modifying cloud resources is not allowed", self.Name)
+	}
+	return nil
+}
+
 func (manager *SCloudaccountManager) ValidateCreateData(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -2574,18 +2597,25 @@ func (account *SCloudaccount) GetUsages() []db.IUsage {
 	}
 }
 
+// 优先使用映射到本地项目且优先级最高的云上项目, 其次使用未手动映射的同名云上项目
 func GetAvailableExternalProject(local *db.STenant, projects []SExternalProject) *SExternalProject {
-	var ret *SExternalProject = nil
+	var ret, mapped *SExternalProject = nil, nil
 	for i := 0; i < len(projects); i++ {
 		if projects[i].Status == api.EXTERNAL_PROJECT_STATUS_AVAILABLE {
 			if projects[i].ProjectId == local.Id {
-				return &projects[i]
+				if mapped == nil || projects[i].Priority > mapped.Priority {
+					mapped = &projects[i]
+				}
+				continue
 			}
-			if projects[i].Name == local.Name {
+			if projects[i].Name == local.Name && !projects[i].ManualMapping {
 				ret = &projects[i]
 			}
 		}
 	}
+	if mapped != nil {
+		return mapped
+	}
 	return ret
 }
 
@@ -19,9 +19,11 @@ import (
 	"fmt"
 	"sort"
 
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 )
@@ -117,6 +119,43 @@ func (manager *SCloudproviderCapabilityManager) getRegionCapabilities(cloudprovi
 	return capaStrs, nil
 }
 
+// ValidateCapability 按能力矩阵校验云账号是否支持指定资源的创建, 区域级能力优先于账号级能力
+// 用于私有云变体(如专有云)在请求下发前拦截不支持的功能, 避免运行时失败
+func (manager *SCloudproviderCapabilityManager) ValidateCapability(cloudproviderId, cloudregionId, capability string) error {
+	capas, err := manager.getRegionCapabilities(cloudproviderId, cloudregionId)
+	if err != nil {
+		return errors.Wrap(err, "getRegionCapabilities")
+	}
+	if len(capas) == 0 && len(cloudregionId) > 0 {
+		capas, err = manager.getCapabilities(cloudproviderId)
+		if err != nil {
+			return errors.Wrap(err, "getCapabilities")
+		}
+	}
+	supported, readOnly := checkCapability(capas, capability)
+	if !supported {
+		return httperrors.NewUnsupportOperationError("cloudprovider %s does not support %s", cloudproviderId, capability)
+	}
+	if readOnly {
+		return httperrors.NewUnsupportOperationError("%s of cloudprovider %s is read only", capability, cloudproviderId)
+	}
+	return nil
+}
+
+func checkCapability(capas []string, capability string) (bool, bool) {
+	for _, capa := range capas {
+		if capa == capability {
+			return true, false
+		}
+	}
+	for _, capa := range capas {
+		if capa == capability+cloudprovider.READ_ONLY_SUFFIX {
+			return true, true
+		}
+	}
+	return false, false
+}
+
 func (manager *SCloudproviderCapabilityManager) removeCapabilities(ctx context.Context, userCred mcclient.TokenCredential, cloudproviderId string) error {
 	return manager.removeRegionCapabilities(ctx, userCred, cloudproviderId, "")
 }
@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+)
+
+func TestCheckCapability(t *testing.T) {
+	capas := []string{
+		cloudprovider.CLOUD_CAPABILITY_COMPUTE,
+		cloudprovider.CLOUD_CAPABILITY_EIP + cloudprovider.READ_ONLY_SUFFIX,
+	}
+	cases := []struct {
+		capability string
+		supported  bool
+		readOnly   bool
+	}{
+		{cloudprovider.CLOUD_CAPABILITY_COMPUTE, true, false},
+		{cloudprovider.CLOUD_CAPABILITY_EIP, true, true},
+		{cloudprovider.CLOUD_CAPABILITY_LOADBALANCER, false, false},
+	}
+	for _, c := range cases {
+		supported, readOnly := checkCapability(capas, c.capability)
+		if supported != c.supported || readOnly != c.readOnly {
+			t.Errorf("%s: want (%v, %v) got (%v, %v)", c.capability, c.supported, c.readOnly, supported, readOnly)
+		}
+	}
+}
@@ -18,8 +18,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
@@ -83,6 +86,11 @@ type SCloudproviderregion struct {
 
 	LastDeepSyncAt time.Time `list:"domain"`
 	LastAutoSyncAt time.Time `list:"domain"`
+
+	// 最近一次同步耗时(秒)
+	LastSyncCostSeconds int `nullable:"true" list:"domain"`
+	// 最近一次同步的错误信息, 同步成功时为空
+	LastSyncError string `charset:"utf8" nullable:"true" list:"domain"`
 }
 
 func (manager *SCloudproviderregionManager) GetMasterFieldName() string {
@@ -127,7 +135,11 @@ func (manager *SCloudproviderregionManager) FetchCustomizeColumns(
 	for i := range rows {
 		rows[i].JointResourceBaseDetails = jointRows[i]
 		rows[i].CloudregionResourceInfo = regionRows[i]
-		rows[i].Capabilities, _ = objs[i].(*SCloudproviderregion).getCapabilities()
+		cpr := objs[i].(*SCloudproviderregion)
+		rows[i].Capabilities, _ = cpr.getCapabilities()
+		if cpr.SyncStatus == api.CLOUD_PROVIDER_SYNC_STATUS_SYNCING && !cpr.LastSync.IsZero() {
+			rows[i].SyncElapsedSeconds = int(time.Since(cpr.LastSync).Seconds())
+		}
 		managerIds[i] = objs[i].(*SCloudproviderregion).CloudproviderId
 	}
 
@@ -307,9 +319,9 @@ func (self *SCloudproviderregion) markSyncing(userCred mcclient.TokenCredential)
 	return nil
 }
 
-func (self *SCloudproviderregion) markEndSync(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, deepSync *bool) error {
+func (self *SCloudproviderregion) markEndSync(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, deepSync *bool, syncErr error) error {
 	log.Debugf("markEndSync deepSync %v", *deepSync)
-	err := self.markEndSyncInternal(userCred, syncResults, deepSync)
+	err := self.markEndSyncInternal(userCred, syncResults, deepSync, syncErr)
 	if err != nil {
 		return errors.Wrapf(err, "markEndSyncInternal")
 	}
@@ -324,10 +336,19 @@ func (self *SCloudproviderregion) markEndSync(ctx context.Context, userCred mccl
 	return nil
 }
 
-func (self *SCloudproviderregion) markEndSyncInternal(userCred mcclient.TokenCredential, syncResults SSyncResultSet, deepSync *bool) error {
+func (self *SCloudproviderregion) markEndSyncInternal(userCred mcclient.TokenCredential, syncResults SSyncResultSet, deepSync *bool, syncErr error) error {
+	errMsgs := []string{}
+	if syncErr != nil {
+		errMsgs = append(errMsgs, syncErr.Error())
+	}
+	errMsgs = append(errMsgs, syncResults.ErrorSummary()...)
 	_, err := db.Update(self, func() error {
 		self.SyncStatus = api.CLOUD_PROVIDER_SYNC_STATUS_IDLE
 		self.LastSyncEndAt = timeutils.UtcNow()
+		if !self.LastSync.IsZero() {
+			self.LastSyncCostSeconds = int(self.LastSyncEndAt.Sub(self.LastSync).Seconds())
+		}
+		self.LastSyncError = strings.Join(errMsgs, "; ")
 		self.SyncResults = jsonutils.Marshal(syncResults)
 		if deepSync != nil && *deepSync {
 			self.LastDeepSyncAt = timeutils.UtcNow()
@@ -403,7 +424,25 @@ func (set SSyncResultSet) Add(manager db.IModelManager, result compare.SyncResul
 	res.DelErrCnt += result.DelErrCnt
 }
 
-func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.TokenCredential, syncRange SSyncRange) error {
+// 汇总同步结果中出错的资源类型
+func (set SSyncResultSet) ErrorSummary() []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := []string{}
+	for _, k := range keys {
+		res := set[k]
+		if res.AddErrCnt+res.UpdateErrCnt+res.DelErrCnt == 0 {
+			continue
+		}
+		ret = append(ret, fmt.Sprintf("%s: add %d update %d delete %d failed", k, res.AddErrCnt, res.UpdateErrCnt, res.DelErrCnt))
+	}
+	return ret
+}
+
+func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.TokenCredential, syncRange SSyncRange) (err error) {
 	syncResults := SSyncResultSet{}
 
 	localRegion, err := self.GetRegion()
@@ -418,9 +457,9 @@ func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.
 	self.markSyncing(userCred)
 
 	defer func() {
-		err := self.markEndSync(ctx, userCred, syncResults, &syncRange.DeepSync)
-		if err != nil {
-			log.Errorf("markEndSync for %s(%s) : %v", localRegion.Name, provider.Name, err)
+		e := self.markEndSync(ctx, userCred, syncResults, &syncRange.DeepSync, err)
+		if e != nil {
+			log.Errorf("markEndSync for %s(%s) : %v", localRegion.Name, provider.Name, e)
 		}
 	}()
 
@@ -439,7 +478,8 @@ func (self *SCloudproviderregion) DoSync(ctx context.Context, userCred mcclient.
 	log.Debugf("need to do deep sync? ... %v", syncRange.DeepSync)
 
 	if localRegion.isManaged() {
-		remoteRegion, err := driver.GetIRegionById(localRegion.ExternalId)
+		var remoteRegion cloudprovider.ICloudRegion
+		remoteRegion, err = driver.GetIRegionById(localRegion.ExternalId)
 		if err != nil {
 			return errors.Wrap(err, "GetIRegionById")
 		}
@@ -463,13 +503,15 @@ func (self *SCloudproviderregion) getSyncTaskKey() string {
 
 func (self *SCloudproviderregion) submitSyncTask(ctx context.Context, userCred mcclient.TokenCredential, syncRange SSyncRange) {
 	self.markStartSync(userCred)
-	RunSyncCloudproviderRegionTask(ctx, self.getSyncTaskKey(), func() {
+	if !RunSyncCloudproviderRegionTask(ctx, self.getSyncTaskKey(), func() {
 		ctx = context.WithValue(ctx, "provider-region", fmt.Sprintf("%d", self.RowId))
 		err := self.DoSync(ctx, userCred, syncRange)
 		if err != nil {
 			log.Errorf("DoSync faild %v", err)
 		}
-	})
+	}) {
+		log.Warningf("sync task for cloudproviderregion %d not submitted", self.RowId)
+	}
 }
 
 func (cpr *SCloudproviderregion) resetAutoSync() {
@@ -525,6 +567,14 @@ func (manager *SCloudproviderregionManager) ListItemFilter(
 		}
 	}
 
+	if query.SyncFailed != nil {
+		if *query.SyncFailed {
+			q = q.IsNotEmpty("last_sync_error")
+		} else {
+			q = q.IsNullOrEmpty("last_sync_error")
+		}
+	}
+
 	if len(query.Capability) > 0 {
 		subq := CloudproviderCapabilityManager.Query().SubQuery()
 		q = q.Join(subq, sqlchemy.AND(
@@ -895,6 +895,14 @@ func (self *SCloudprovider) savePassword(secret string) error {
 	return err
 }
 
+func (self *SCloudprovider) ValidateWritable() error {
+	account, err := self.GetCloudaccount()
+	if err != nil {
+		return errors.Wrapf(err, "GetCloudaccount")
+	}
+	return account.ValidateWritable()
+}
+
 func (self *SCloudprovider) GetCloudaccount() (*SCloudaccount, error) {
 	obj, err := CloudaccountManager.FetchById(self.CloudaccountId)
 	if err != nil {
@@ -307,6 +307,31 @@ func syncRegionAccessGroups(ctx context.Context, userCred mcclient.TokenCredenti
 	log.Infof("Sync Access Group Caches for region %s result: %s", localRegion.Name, result.Result())
 }
 
+func syncRegionDedicatedHosts(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion) {
+	dhRegion, ok := remoteRegion.(ICloudDedicatedHostRegion)
+	if !ok {
+		return
+	}
+	hosts, err := func() ([]ICloudDedicatedHost, error) {
+		defer syncResults.AddRequestCost(DedicatedHostManager)()
+		return dhRegion.GetIDedicatedHosts()
+	}()
+	if err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotImplemented || errors.Cause(err) == cloudprovider.ErrNotSupported {
+			return
+		}
+		log.Errorf("GetIDedicatedHosts for region %s error: %v", localRegion.Name, err)
+		return
+	}
+
+	result := func() compare.SyncResult {
+		defer syncResults.AddSqlCost(DedicatedHostManager)()
+		return localRegion.SyncDedicatedHosts(ctx, userCred, provider, hosts)
+	}()
+	syncResults.Add(DedicatedHostManager, result)
+	log.Infof("Sync Dedicated Hosts for region %s result: %s", localRegion.Name, result.Result())
+}
+
 func syncRegionFileSystems(ctx context.Context, userCred mcclient.TokenCredential, syncResults SSyncResultSet, provider *SCloudprovider, localRegion *SCloudregion, remoteRegion cloudprovider.ICloudRegion, syncRange *SSyncRange) {
 	filesystems, err := func() ([]cloudprovider.ICloudFileSystem, error) {
 		defer syncResults.AddRequestCost(FileSystemManager)()
@@ -1820,6 +1845,8 @@ func syncPublicCloudProviderInfo(
 		if syncRange.NeedSyncResource(cloudprovider.CLOUD_CAPABILITY_COMPUTE) {
 			// sync snapshot policies before sync disks
 			syncRegionSnapshotPolicies(ctx, userCred, syncResults, provider, localRegion, remoteRegion, syncRange)
+			// sync dedicated hosts before sync guests
+			syncRegionDedicatedHosts(ctx, userCred, syncResults, provider, localRegion, remoteRegion)
 
 			for j := 0; j < len(localZones); j += 1 {
 
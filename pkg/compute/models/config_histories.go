@@ -0,0 +1,505 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var (
+	ConfigHistoryManager *SConfigHistoryManager
+)
+
+type SConfigHistoryManager struct {
+	db.SStandaloneAnonResourceBaseManager
+}
+
+func init() {
+	ConfigHistoryManager = &SConfigHistoryManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SConfigHistory{},
+			"config_histories_tbl",
+			"config_history",
+			"config_histories",
+		),
+	}
+	ConfigHistoryManager.SetVirtualObject(ConfigHistoryManager)
+}
+
+// 资源配置的历史版本, 配置未变化时不重复记录
+type SConfigHistory struct {
+	db.SStandaloneAnonResourceBase
+
+	ResourceType string `width:"64" charset:"ascii" nullable:"false" list:"user" index:"true" json:"resource_type"`
+	ResourceId   string `width:"128" charset:"ascii" nullable:"false" list:"user" index:"true" json:"resource_id"`
+	ResourceName string `width:"256" charset:"utf8" nullable:"true" list:"user" json:"resource_name"`
+
+	// 同一资源的配置版本号, 从1开始递增
+	Version int    `nullable:"false" default:"1" list:"user" json:"version"`
+	Trigger string `width:"16" charset:"ascii" nullable:"false" list:"user" json:"trigger"`
+
+	Checksum string               `width:"32" charset:"ascii" nullable:"false" list:"user" json:"checksum"`
+	Spec     jsonutils.JSONObject `nullable:"true" get:"user" json:"spec"`
+}
+
+func (manager *SConfigHistoryManager) ListItemFilter(
+	ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ConfigHistoryListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ResourceType) > 0 {
+		q = q.Equals("resource_type", query.ResourceType)
+	}
+	if len(query.ResourceId) > 0 {
+		q = q.Equals("resource_id", query.ResourceId)
+	}
+	if len(query.Trigger) > 0 {
+		q = q.Equals("trigger", query.Trigger)
+	}
+	if !query.Since.IsZero() {
+		q = q.GE("created_at", query.Since)
+	}
+	if !query.Until.IsZero() {
+		q = q.LE("created_at", query.Until)
+	}
+	return q, nil
+}
+
+func (manager *SConfigHistoryManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ConfigHistoryDetails {
+	rows := make([]api.ConfigHistoryDetails, len(objs))
+	stdRows := manager.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i].StandaloneAnonResourceDetails = stdRows[i]
+	}
+	return rows
+}
+
+func guestConfigSpec(guest *SGuest) (jsonutils.JSONObject, error) {
+	spec := jsonutils.NewDict()
+	spec.Set("name", jsonutils.NewString(guest.Name))
+	spec.Set("vcpu_count", jsonutils.NewInt(int64(guest.VcpuCount)))
+	spec.Set("vmem_size", jsonutils.NewInt(int64(guest.VmemSize)))
+	spec.Set("instance_type", jsonutils.NewString(guest.InstanceType))
+	spec.Set("host_id", jsonutils.NewString(guest.HostId))
+
+	gds, err := guest.GetGuestDisks()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetGuestDisks")
+	}
+	sort.Slice(gds, func(i, j int) bool { return gds[i].Index < gds[j].Index })
+	disks := jsonutils.NewArray()
+	for i := range gds {
+		disk := jsonutils.NewDict()
+		disk.Set("disk_id", jsonutils.NewString(gds[i].DiskId))
+		disk.Set("index", jsonutils.NewInt(int64(gds[i].Index)))
+		disk.Set("driver", jsonutils.NewString(gds[i].Driver))
+		disk.Set("cache_mode", jsonutils.NewString(gds[i].CacheMode))
+		if d := gds[i].GetDisk(); d != nil {
+			disk.Set("disk_size", jsonutils.NewInt(int64(d.DiskSize)))
+			disk.Set("disk_type", jsonutils.NewString(d.DiskType))
+			disk.Set("storage_id", jsonutils.NewString(d.StorageId))
+		}
+		disks.Add(disk)
+	}
+	spec.Set("disks", disks)
+
+	gns, err := guest.GetNetworks("")
+	if err != nil {
+		return nil, errors.Wrap(err, "GetNetworks")
+	}
+	sort.Slice(gns, func(i, j int) bool { return gns[i].Index < gns[j].Index })
+	nics := jsonutils.NewArray()
+	for i := range gns {
+		nic := jsonutils.NewDict()
+		nic.Set("network_id", jsonutils.NewString(gns[i].NetworkId))
+		nic.Set("index", jsonutils.NewInt(int64(gns[i].Index)))
+		nic.Set("mac_addr", jsonutils.NewString(gns[i].MacAddr))
+		nic.Set("ip_addr", jsonutils.NewString(gns[i].IpAddr))
+		nic.Set("ip6_addr", jsonutils.NewString(gns[i].Ip6Addr))
+		nic.Set("bw_limit", jsonutils.NewInt(int64(gns[i].BwLimit)))
+		nics.Add(nic)
+	}
+	spec.Set("nics", nics)
+
+	secgroups, err := guest.GetSecgroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecgroups")
+	}
+	secgroupIds := []string{}
+	for i := range secgroups {
+		secgroupIds = append(secgroupIds, secgroups[i].Id)
+	}
+	sort.Strings(secgroupIds)
+	spec.Set("secgroups", jsonutils.NewStringArray(secgroupIds))
+	return spec, nil
+}
+
+func secgroupConfigSpec(secgroup *SSecurityGroup) (jsonutils.JSONObject, error) {
+	rules := []SSecurityGroupRule{}
+	q := SecurityGroupRuleManager.Query().Equals("secgroup_id", secgroup.Id)
+	err := db.FetchModelObjects(SecurityGroupRuleManager, q, &rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	items := []jsonutils.JSONObject{}
+	for i := range rules {
+		rule := jsonutils.NewDict()
+		rule.Set("direction", jsonutils.NewString(rules[i].Direction))
+		rule.Set("priority", jsonutils.NewInt(rules[i].Priority))
+		rule.Set("action", jsonutils.NewString(rules[i].Action))
+		rule.Set("protocol", jsonutils.NewString(rules[i].Protocol))
+		rule.Set("ports", jsonutils.NewString(rules[i].Ports))
+		rule.Set("cidr", jsonutils.NewString(rules[i].CIDR))
+		rule.Set("peer_secgroup_id", jsonutils.NewString(rules[i].PeerSecgroupId))
+		rule.Set("description", jsonutils.NewString(rules[i].Description))
+		items = append(items, rule)
+	}
+	// 规则无固定顺序, 按内容排序以保证相同配置的摘要一致
+	sort.Slice(items, func(i, j int) bool { return items[i].String() < items[j].String() })
+	spec := jsonutils.NewDict()
+	spec.Set("name", jsonutils.NewString(secgroup.Name))
+	spec.Set("rules", jsonutils.NewArray(items...))
+	return spec, nil
+}
+
+// 监听器状态及同步相关的字段不属于配置
+var lbListenerVolatileKeys = []string{
+	"status", "progress", "created_at", "updated_at", "update_version",
+	"deleted", "deleted_at", "pending_deleted", "pending_deleted_at",
+	"external_id", "source", "imported_at",
+}
+
+func lbListenerConfigSpec(lblis *SLoadbalancerListener) (jsonutils.JSONObject, error) {
+	spec := jsonutils.Marshal(lblis).(*jsonutils.JSONDict)
+	for _, key := range lbListenerVolatileKeys {
+		spec.Remove(key)
+	}
+	return spec, nil
+}
+
+func configSpecOf(obj db.IModel) (jsonutils.JSONObject, error) {
+	switch res := obj.(type) {
+	case *SGuest:
+		return guestConfigSpec(res)
+	case *SSecurityGroup:
+		return secgroupConfigSpec(res)
+	case *SLoadbalancerListener:
+		return lbListenerConfigSpec(res)
+	}
+	return nil, errors.Wrapf(errors.ErrNotSupported, "config history of %s", obj.KeywordPlural())
+}
+
+func (manager *SConfigHistoryManager) fetchLatest(resType, resId string) (*SConfigHistory, error) {
+	q := manager.Query().Equals("resource_type", resType).Equals("resource_id", resId).Desc("version").Limit(1)
+	ret := &SConfigHistory{}
+	ret.SetModelManager(manager, ret)
+	err := q.First(ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// 记录资源当前配置, 与最近一个版本相同时跳过
+func (manager *SConfigHistoryManager) Record(ctx context.Context, obj db.IModel, trigger string) error {
+	spec, err := configSpecOf(obj)
+	if err != nil {
+		return err
+	}
+	resType, resId := obj.KeywordPlural(), obj.GetId()
+	checksum := stringutils2.GetMD5Hash(spec.String())
+
+	lockman.LockRawObject(ctx, manager.Keyword(), resType+"/"+resId)
+	defer lockman.ReleaseRawObject(ctx, manager.Keyword(), resType+"/"+resId)
+
+	version := 1
+	latest, err := manager.fetchLatest(resType, resId)
+	if err != nil && errors.Cause(err) != sqlchemy.ErrEmptyQuery {
+		return errors.Wrap(err, "fetchLatest")
+	}
+	if latest != nil {
+		if latest.Checksum == checksum {
+			return nil
+		}
+		version = latest.Version + 1
+	}
+	history := &SConfigHistory{
+		ResourceType: resType,
+		ResourceId:   resId,
+		ResourceName: obj.GetName(),
+		Version:      version,
+		Trigger:      trigger,
+		Checksum:     checksum,
+		Spec:         spec,
+	}
+	history.SetModelManager(manager, history)
+	return manager.TableSpec().Insert(ctx, history)
+}
+
+// 资源变更后记录配置历史, 失败不影响变更本身
+func recordConfigHistory(ctx context.Context, obj db.IModel) {
+	err := ConfigHistoryManager.Record(ctx, obj, api.CONFIG_HISTORY_TRIGGER_CHANGE)
+	if err != nil {
+		log.Errorf("record config history of %s %s: %v", obj.KeywordPlural(), obj.GetId(), err)
+	}
+}
+
+func flattenConfigSpec(prefix string, obj jsonutils.JSONObject, leaves map[string]jsonutils.JSONObject) {
+	join := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := obj.(type) {
+	case *jsonutils.JSONDict:
+		m, _ := v.GetMap()
+		if len(m) == 0 && len(prefix) > 0 {
+			leaves[prefix] = v
+		}
+		for key, val := range m {
+			flattenConfigSpec(join(key), val, leaves)
+		}
+	case *jsonutils.JSONArray:
+		arr, _ := v.GetArray()
+		if len(arr) == 0 && len(prefix) > 0 {
+			leaves[prefix] = v
+		}
+		for i, val := range arr {
+			flattenConfigSpec(join(fmt.Sprintf("%d", i)), val, leaves)
+		}
+	default:
+		if obj != nil && len(prefix) > 0 {
+			leaves[prefix] = obj
+		}
+	}
+}
+
+// 按字段路径对比两个配置版本, old 为空时所有字段均视为新增
+func diffConfigSpec(old, new jsonutils.JSONObject) []api.SConfigHistoryChange {
+	oldLeaves, newLeaves := map[string]jsonutils.JSONObject{}, map[string]jsonutils.JSONObject{}
+	if old != nil {
+		flattenConfigSpec("", old, oldLeaves)
+	}
+	if new != nil {
+		flattenConfigSpec("", new, newLeaves)
+	}
+	changes := []api.SConfigHistoryChange{}
+	for path, ov := range oldLeaves {
+		nv, ok := newLeaves[path]
+		if !ok {
+			changes = append(changes, api.SConfigHistoryChange{Path: path, Op: "removed", Old: ov})
+		} else if ov.String() != nv.String() {
+			changes = append(changes, api.SConfigHistoryChange{Path: path, Op: "modified", Old: ov, New: nv})
+		}
+	}
+	for path, nv := range newLeaves {
+		if _, ok := oldLeaves[path]; !ok {
+			changes = append(changes, api.SConfigHistoryChange{Path: path, Op: "added", New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func (manager *SConfigHistoryManager) fetchVersion(resType, resId string, version int) (*SConfigHistory, error) {
+	q := manager.Query().Equals("resource_type", resType).Equals("resource_id", resId).Equals("version", version)
+	ret := &SConfigHistory{}
+	ret.SetModelManager(manager, ret)
+	err := q.First(ret)
+	if err != nil {
+		if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+			return nil, httperrors.NewResourceNotFoundError2(manager.Keyword(), fmt.Sprintf("%s/%s@%d", resType, resId, version))
+		}
+		return nil, errors.Wrapf(err, "fetch version %d", version)
+	}
+	return ret, nil
+}
+
+// 取指定时刻生效的版本, 该时刻之前没有记录时返回 nil
+func (manager *SConfigHistoryManager) fetchAt(resType, resId string, at time.Time) (*SConfigHistory, error) {
+	q := manager.Query().Equals("resource_type", resType).Equals("resource_id", resId).LE("created_at", at).Desc("version").Limit(1)
+	ret := &SConfigHistory{}
+	ret.SetModelManager(manager, ret)
+	err := q.First(ret)
+	if err != nil {
+		if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fetch version at %s", at)
+	}
+	return ret, nil
+}
+
+// 对比资源两个版本或两个时刻之间的配置变化
+func (manager *SConfigHistoryManager) GetPropertyDiff(ctx context.Context, userCred mcclient.TokenCredential, query api.ConfigHistoryDiffInput) (*api.ConfigHistoryDiffOutput, error) {
+	if !utils.IsInStringArray(query.ResourceType, api.CONFIG_HISTORY_RESOURCE_TYPES) {
+		return nil, httperrors.NewInputParameterError("invalid resource_type %q, must be one of %s", query.ResourceType, api.CONFIG_HISTORY_RESOURCE_TYPES)
+	}
+	if len(query.ResourceId) == 0 {
+		return nil, httperrors.NewMissingParameterError("resource_id")
+	}
+	var from, to *SConfigHistory
+	var err error
+	if query.FromVersion > 0 || query.ToVersion > 0 {
+		if query.FromVersion <= 0 || query.ToVersion <= 0 {
+			return nil, httperrors.NewInputParameterError("from_version and to_version must be specified together")
+		}
+		from, err = manager.fetchVersion(query.ResourceType, query.ResourceId, query.FromVersion)
+		if err != nil {
+			return nil, err
+		}
+		to, err = manager.fetchVersion(query.ResourceType, query.ResourceId, query.ToVersion)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if query.Since.IsZero() {
+			return nil, httperrors.NewMissingParameterError("since")
+		}
+		until := query.Until
+		if until.IsZero() {
+			until = time.Now().UTC()
+		}
+		if until.Before(query.Since) {
+			return nil, httperrors.NewInputParameterError("until is earlier than since")
+		}
+		from, err = manager.fetchAt(query.ResourceType, query.ResourceId, query.Since)
+		if err != nil {
+			return nil, err
+		}
+		to, err = manager.fetchAt(query.ResourceType, query.ResourceId, until)
+		if err != nil {
+			return nil, err
+		}
+		if to == nil {
+			return nil, httperrors.NewResourceNotFoundError2(manager.Keyword(), fmt.Sprintf("%s/%s", query.ResourceType, query.ResourceId))
+		}
+	}
+
+	ret := &api.ConfigHistoryDiffOutput{
+		ResourceType: query.ResourceType,
+		ResourceId:   query.ResourceId,
+		ResourceName: to.ResourceName,
+		ToVersion:    to.Version,
+		ToTime:       to.CreatedAt,
+	}
+	var fromSpec jsonutils.JSONObject
+	if from != nil {
+		ret.FromVersion = from.Version
+		ret.FromTime = from.CreatedAt
+		fromSpec = from.Spec
+	}
+	ret.Changes = diffConfigSpec(fromSpec, to.Spec)
+	return ret, nil
+}
+
+func recordScheduledConfigHistory(ctx context.Context, obj db.IModel) {
+	err := ConfigHistoryManager.Record(ctx, obj, api.CONFIG_HISTORY_TRIGGER_SCHEDULE)
+	if err != nil {
+		log.Errorf("ConfigHistorySnapshot %s %s: %v", obj.KeywordPlural(), obj.GetId(), err)
+	}
+}
+
+// 定时记录资源配置, 补齐同步或直接改库等未经过变更接口的配置变化
+func ConfigHistorySnapshot(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	guests := []SGuest{}
+	err := db.FetchModelObjects(GuestManager, GuestManager.Query().IsFalse("pending_deleted"), &guests)
+	if err != nil {
+		log.Errorf("ConfigHistorySnapshot fetch guests: %v", err)
+	}
+	for i := range guests {
+		recordScheduledConfigHistory(ctx, &guests[i])
+	}
+
+	secgroups := []SSecurityGroup{}
+	err = db.FetchModelObjects(SecurityGroupManager, SecurityGroupManager.Query(), &secgroups)
+	if err != nil {
+		log.Errorf("ConfigHistorySnapshot fetch secgroups: %v", err)
+	}
+	for i := range secgroups {
+		recordScheduledConfigHistory(ctx, &secgroups[i])
+	}
+
+	listeners := []SLoadbalancerListener{}
+	err = db.FetchModelObjects(LoadbalancerListenerManager, LoadbalancerListenerManager.Query().IsFalse("pending_deleted"), &listeners)
+	if err != nil {
+		log.Errorf("ConfigHistorySnapshot fetch loadbalancer listeners: %v", err)
+	}
+	for i := range listeners {
+		recordScheduledConfigHistory(ctx, &listeners[i])
+	}
+
+	ConfigHistoryManager.pruneExpired(ctx, userCred)
+}
+
+// 删除超过保留期的历史版本, 每个资源最新的版本始终保留作为对比基线
+func (manager *SConfigHistoryManager) pruneExpired(ctx context.Context, userCred mcclient.TokenCredential) {
+	days := options.Options.ConfigHistoryRetentionDays
+	if days <= 0 {
+		return
+	}
+	sq := manager.Query()
+	sq = sq.GroupBy(sq.Field("resource_type"), sq.Field("resource_id"))
+	sq = sq.AppendField(sq.Field("resource_type"), sq.Field("resource_id"), sqlchemy.MAX("version", sq.Field("version")))
+	latest := sq.SubQuery()
+	q := manager.Query().LT("created_at", time.Now().UTC().Add(-time.Duration(days)*24*time.Hour))
+	q = q.Join(latest, sqlchemy.AND(
+		sqlchemy.Equals(q.Field("resource_type"), latest.Field("resource_type")),
+		sqlchemy.Equals(q.Field("resource_id"), latest.Field("resource_id")),
+	))
+	q = q.Filter(sqlchemy.LT(q.Field("version"), latest.Field("version")))
+
+	histories := []SConfigHistory{}
+	err := db.FetchModelObjects(manager, q, &histories)
+	if err != nil {
+		log.Errorf("fetch expired config histories: %v", err)
+		return
+	}
+	for i := range histories {
+		err := db.DeleteModel(ctx, userCred, &histories[i])
+		if err != nil {
+			log.Errorf("delete expired config history %s: %v", histories[i].Id, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestDiffConfigSpec(t *testing.T) {
+	old, _ := jsonutils.ParseString(`{"name":"vm","vcpu_count":2,"disks":[{"index":0,"disk_size":10240}],"secgroups":["a"]}`)
+	new, _ := jsonutils.ParseString(`{"name":"vm","vcpu_count":4,"disks":[{"index":0,"disk_size":10240},{"index":1,"disk_size":20480}],"secgroups":[]}`)
+
+	changes := diffConfigSpec(old, new)
+	want := []struct {
+		path string
+		op   string
+	}{
+		{"disks.1.disk_size", "added"},
+		{"disks.1.index", "added"},
+		{"secgroups", "added"},
+		{"secgroups.0", "removed"},
+		{"vcpu_count", "modified"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("want %d changes, got %s", len(want), jsonutils.Marshal(changes))
+	}
+	for i := range want {
+		if changes[i].Path != want[i].path || changes[i].Op != want[i].op {
+			t.Errorf("change %d: want %s %s, got %s %s", i, want[i].op, want[i].path, changes[i].Op, changes[i].Path)
+		}
+	}
+	if changes[4].Old.String() != "2" || changes[4].New.String() != "4" {
+		t.Errorf("vcpu_count: got %s -> %s", changes[4].Old, changes[4].New)
+	}
+
+	if changes := diffConfigSpec(old, old); len(changes) != 0 {
+		t.Errorf("same spec should have no changes, got %s", jsonutils.Marshal(changes))
+	}
+	if changes := diffConfigSpec(nil, old); len(changes) != 5 {
+		t.Errorf("all fields should be added, got %s", jsonutils.Marshal(changes))
+	}
+}
@@ -0,0 +1,644 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// RDS实例的逻辑导出, 工作虚机通过 qemu-guest-agent 执行 mysqldump/pg_dump 并上传到对象存储,
+// 不依赖各平台的物理备份格式, 可恢复到其他平台的同类实例
+type SDBInstanceDumpManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+var DBInstanceDumpManager *SDBInstanceDumpManager
+
+func init() {
+	DBInstanceDumpManager = &SDBInstanceDumpManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SDBInstanceDump{},
+			"dbinstance_dumps_tbl",
+			"dbinstance_dump",
+			"dbinstance_dumps",
+		),
+	}
+	DBInstanceDumpManager.SetVirtualObject(DBInstanceDumpManager)
+}
+
+type SDBInstanceDump struct {
+	db.SVirtualResourceBase
+
+	// 源RDS实例
+	DBInstanceId  string `width:"36" charset:"ascii" name:"dbinstance_id" nullable:"false" list:"user" index:"true" json:"dbinstance_id"`
+	Engine        string `width:"16" charset:"ascii" nullable:"false" list:"user" json:"engine"`
+	EngineVersion string `width:"64" charset:"ascii" nullable:"false" list:"user" json:"engine_version"`
+	Format        string `width:"16" charset:"ascii" nullable:"false" list:"user" json:"format"`
+	// 逗号分隔的数据库名称
+	Databases string `width:"512" charset:"utf8" nullable:"true" list:"user" json:"databases"`
+
+	BucketId  string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true" json:"bucket_id"`
+	ObjectKey string `width:"512" charset:"utf8" nullable:"false" list:"user" json:"object_key"`
+	// 压缩后的导出文件大小
+	SizeMb int `nullable:"false" default:"0" list:"user" json:"size_mb"`
+
+	WorkerServerId string `width:"36" charset:"ascii" nullable:"true" list:"user" json:"worker_server_id"`
+	// 最近一次恢复的目标实例
+	RestoredDBInstanceId string `width:"36" charset:"ascii" name:"restored_dbinstance_id" nullable:"true" list:"user" json:"restored_dbinstance_id"`
+}
+
+func dbinstanceDumpFormat(engine string) string {
+	switch engine {
+	case api.DBINSTANCE_TYPE_MYSQL, api.DBINSTANCE_TYPE_MARIADB, api.DBINSTANCE_TYPE_PERCONA:
+		return api.DBINSTANCE_DUMP_FORMAT_MYSQL
+	case api.DBINSTANCE_TYPE_POSTGRESQL:
+		return api.DBINSTANCE_DUMP_FORMAT_POSTGRESQL
+	}
+	return ""
+}
+
+func fetchDumpAccount(userCred mcclient.TokenCredential, instance *SDBInstance, idOrName string) (*SDBInstanceAccount, error) {
+	if len(idOrName) == 0 {
+		return nil, httperrors.NewMissingParameterError("dbinstanceaccount_id")
+	}
+	accounts, err := instance.GetDBInstanceAccounts()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	for i := range accounts {
+		if accounts[i].Id == idOrName || accounts[i].Name == idOrName {
+			return &accounts[i], nil
+		}
+	}
+	return nil, httperrors.NewResourceNotFoundError("account %s not found in dbinstance %s", idOrName, instance.Name)
+}
+
+func fetchDumpInstance(userCred mcclient.TokenCredential, idOrName string) (*SDBInstance, error) {
+	if len(idOrName) == 0 {
+		return nil, httperrors.NewMissingParameterError("dbinstance_id")
+	}
+	obj, err := DBInstanceManager.FetchByIdOrName(userCred, idOrName)
+	if err != nil {
+		if errors.Cause(err) == sqlchemy.ErrEmptyQuery || errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError2(DBInstanceManager.Keyword(), idOrName)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	instance := obj.(*SDBInstance)
+	if instance.Status != api.DBINSTANCE_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("dbinstance %s status is %s, require %s", instance.Name, instance.Status, api.DBINSTANCE_RUNNING)
+	}
+	if len(instance.ConnectionStr) == 0 && len(instance.InternalConnectionStr) == 0 {
+		return nil, httperrors.NewInvalidStatusError("dbinstance %s has no connection address", instance.Name)
+	}
+	return instance, nil
+}
+
+func fetchDumpWorker(userCred mcclient.TokenCredential, idOrName string) (*SGuest, error) {
+	if len(idOrName) == 0 {
+		return nil, httperrors.NewMissingParameterError("worker_server_id")
+	}
+	obj, err := GuestManager.FetchByIdOrName(userCred, idOrName)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), idOrName)
+	}
+	worker := obj.(*SGuest)
+	if worker.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewUnsupportOperationError("worker server %s must be a kvm server with qemu-guest-agent", worker.Name)
+	}
+	if worker.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("worker server %s status is %s, require %s", worker.Name, worker.Status, api.VM_RUNNING)
+	}
+	return worker, nil
+}
+
+func (manager *SDBInstanceDumpManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.DBInstanceDumpCreateInput) (*jsonutils.JSONDict, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := fetchDumpInstance(userCred, input.DBInstanceId)
+	if err != nil {
+		return nil, err
+	}
+	input.DBInstanceId = instance.Id
+	format := dbinstanceDumpFormat(instance.Engine)
+	if len(format) == 0 {
+		return nil, httperrors.NewUnsupportOperationError("not support dump %s dbinstance", instance.Engine)
+	}
+	account, err := fetchDumpAccount(userCred, instance, input.DBInstanceaccountId)
+	if err != nil {
+		return nil, err
+	}
+	input.DBInstanceaccountId = account.Id
+
+	if len(input.Databases) == 0 {
+		databases, err := instance.GetDBInstanceDatabases()
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		for i := range databases {
+			input.Databases = append(input.Databases, databases[i].Name)
+		}
+	}
+	if len(input.Databases) == 0 {
+		return nil, httperrors.NewMissingParameterError("databases")
+	}
+	if format == api.DBINSTANCE_DUMP_FORMAT_POSTGRESQL && len(input.Databases) > 1 {
+		return nil, httperrors.NewInputParameterError("only one database can be dumped from postgresql dbinstance at a time")
+	}
+
+	if len(input.BucketId) == 0 {
+		return nil, httperrors.NewMissingParameterError("bucket_id")
+	}
+	bucketObj, err := BucketManager.FetchByIdOrName(userCred, input.BucketId)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(BucketManager.Keyword(), input.BucketId)
+	}
+	input.BucketId = bucketObj.GetId()
+
+	worker, err := fetchDumpWorker(userCred, input.WorkerServerId)
+	if err != nil {
+		return nil, err
+	}
+	input.WorkerServerId = worker.Id
+	if input.Timeout <= 0 {
+		input.Timeout = api.DBINSTANCE_DUMP_DEFAULT_TIMEOUT
+	}
+	input.Status = api.DBINSTANCE_DUMP_STATUS_DUMPING
+
+	data := input.JSON(input)
+	data.Set("engine", jsonutils.NewString(instance.Engine))
+	data.Set("engine_version", jsonutils.NewString(instance.EngineVersion))
+	data.Set("format", jsonutils.NewString(format))
+	data.Set("databases", jsonutils.NewString(strings.Join(input.Databases, ",")))
+	name := fmt.Sprintf("%s-%s.sql.gz", instance.Name, time.Now().UTC().Format("20060102150405"))
+	data.Set("object_key", jsonutils.NewString(path.Join(input.KeyPrefix, name)))
+	return data, nil
+}
+
+func (self *SDBInstanceDump) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SVirtualResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	err := self.StartDumpTask(ctx, userCred, data.(*jsonutils.JSONDict), "")
+	if err != nil {
+		log.Errorf("StartDumpTask for %s error: %v", self.Name, err)
+	}
+}
+
+func (self *SDBInstanceDump) StartDumpTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "DBInstanceDumpCreateTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.DBINSTANCE_DUMP_STATUS_DUMP_FAILED, err.Error())
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// 将导出文件恢复到指定RDS实例, 目标实例可以属于其他平台
+func (self *SDBInstanceDump) PerformRestore(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DBInstanceDumpRestoreInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_DUMP_STATUS_READY, api.DBINSTANCE_DUMP_STATUS_RESTORE_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot restore dump in status %s", self.Status)
+	}
+	instance, err := fetchDumpInstance(userCred, input.DBInstanceId)
+	if err != nil {
+		return nil, err
+	}
+	if format := dbinstanceDumpFormat(instance.Engine); format != self.Format {
+		return nil, httperrors.NewUnsupportOperationError("cannot restore %s dump to %s dbinstance %s", self.Format, instance.Engine, instance.Name)
+	}
+	input.DBInstanceId = instance.Id
+	account, err := fetchDumpAccount(userCred, instance, input.DBInstanceaccountId)
+	if err != nil {
+		return nil, err
+	}
+	input.DBInstanceaccountId = account.Id
+	if len(input.WorkerServerId) == 0 {
+		input.WorkerServerId = self.WorkerServerId
+	}
+	worker, err := fetchDumpWorker(userCred, input.WorkerServerId)
+	if err != nil {
+		return nil, err
+	}
+	input.WorkerServerId = worker.Id
+	if input.Timeout <= 0 {
+		input.Timeout = api.DBINSTANCE_DUMP_DEFAULT_TIMEOUT
+	}
+	return nil, self.StartRestoreTask(ctx, userCred, input, "")
+}
+
+func (self *SDBInstanceDump) StartRestoreTask(ctx context.Context, userCred mcclient.TokenCredential, input api.DBInstanceDumpRestoreInput, parentTaskId string) error {
+	self.SetStatus(userCred, api.DBINSTANCE_DUMP_STATUS_RESTORING, "")
+	params := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	task, err := taskman.TaskManager.NewTask(ctx, "DBInstanceDumpRestoreTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.DBINSTANCE_DUMP_STATUS_RESTORE_FAILED, err.Error())
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SDBInstanceDump) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if utils.IsInStringArray(self.Status, []string{api.DBINSTANCE_DUMP_STATUS_DUMPING, api.DBINSTANCE_DUMP_STATUS_RESTORING}) {
+		return httperrors.NewInvalidStatusError("dump %s is %s", self.Name, self.Status)
+	}
+	return self.SVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+// 删除时一并清理存储桶中的导出文件, 清理失败不阻止删除
+func (self *SDBInstanceDump) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if bucket, err := self.GetBucket(); err == nil {
+		iBucket, err := bucket.GetIBucket(ctx)
+		if err == nil {
+			err = iBucket.DeleteObject(ctx, self.ObjectKey)
+		}
+		if err != nil {
+			log.Warningf("delete dump object %s of %s: %v", self.ObjectKey, self.Name, err)
+		}
+	}
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDBInstanceDump) GetBucket() (*SBucket, error) {
+	obj, err := BucketManager.FetchById(self.BucketId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "BucketManager.FetchById(%s)", self.BucketId)
+	}
+	return obj.(*SBucket), nil
+}
+
+func (self *SDBInstanceDump) GetDatabases() []string {
+	if len(self.Databases) == 0 {
+		return nil
+	}
+	return strings.Split(self.Databases, ",")
+}
+
+type sDBDumpEndpoint struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+}
+
+func dbDumpEndpointOf(instance *SDBInstance, account *SDBInstanceAccount) (sDBDumpEndpoint, error) {
+	ep := sDBDumpEndpoint{Port: instance.Port, User: account.Name}
+	var err error
+	ep.Password, err = account.GetPassword()
+	if err != nil {
+		return ep, errors.Wrapf(err, "GetPassword of %s", account.Name)
+	}
+	// 工作虚机通常与RDS实例不在同一VPC, 优先使用外网地址
+	ep.Host = instance.ConnectionStr
+	if len(ep.Host) == 0 {
+		ep.Host = instance.InternalConnectionStr
+	}
+	if pos := strings.LastIndexByte(ep.Host, ':'); pos > 0 {
+		if port, err := strconv.Atoi(ep.Host[pos+1:]); err == nil {
+			ep.Host, ep.Port = ep.Host[:pos], port
+		}
+	}
+	if ep.Port <= 0 {
+		switch dbinstanceDumpFormat(instance.Engine) {
+		case api.DBINSTANCE_DUMP_FORMAT_POSTGRESQL:
+			ep.Port = 5432
+		default:
+			ep.Port = 3306
+		}
+	}
+	return ep, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellQuoteAll(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i := range strs {
+		quoted[i] = shellQuote(strs[i])
+	}
+	return strings.Join(quoted, " ")
+}
+
+// 生成工作虚机内执行的脚本, 结束后将 "退出码 文件大小" 写入 statusPath
+// 退出码 1 表示数据库客户端执行失败, 2 表示上传或下载失败
+func dbDumpWorkerScript(restore bool, format, engine string, ep sDBDumpEndpoint, databases []string, url, dataPath, statusPath string) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/bash\nset -o pipefail\n")
+	fmt.Fprintf(&sb, "DATA=%s\n", shellQuote(dataPath))
+	sb.WriteString("run() {\n")
+	switch format {
+	case api.DBINSTANCE_DUMP_FORMAT_POSTGRESQL:
+		conn := fmt.Sprintf("-h %s -p %d -U %s", shellQuote(ep.Host), ep.Port, shellQuote(ep.User))
+		fmt.Fprintf(&sb, "\texport PGPASSWORD=%s\n", shellQuote(ep.Password))
+		if restore {
+			fmt.Fprintf(&sb, "\tcurl -sSf -o \"$DATA\" %s || return 2\n", shellQuote(url))
+			fmt.Fprintf(&sb, "\tcreatedb %s %s 2>/dev/null\n", conn, shellQuote(databases[0]))
+			fmt.Fprintf(&sb, "\tgunzip -c \"$DATA\" | psql -v ON_ERROR_STOP=1 %s -d %s || return 1\n", conn, shellQuote(databases[0]))
+		} else {
+			fmt.Fprintf(&sb, "\tpg_dump %s --no-owner --no-acl %s | gzip > \"$DATA\" || return 1\n", conn, shellQuote(databases[0]))
+			fmt.Fprintf(&sb, "\tcurl -sSf -T \"$DATA\" %s || return 2\n", shellQuote(url))
+		}
+	default:
+		conn := fmt.Sprintf("-h %s -P %d -u %s", shellQuote(ep.Host), ep.Port, shellQuote(ep.User))
+		fmt.Fprintf(&sb, "\texport MYSQL_PWD=%s\n", shellQuote(ep.Password))
+		if restore {
+			fmt.Fprintf(&sb, "\tcurl -sSf -o \"$DATA\" %s || return 2\n", shellQuote(url))
+			fmt.Fprintf(&sb, "\tgunzip -c \"$DATA\" | mysql %s || return 1\n", conn)
+		} else {
+			opts := "--single-transaction --routines --triggers --events --hex-blob"
+			// 目标平台通常不允许设置 GTID_PURGED
+			if engine == api.DBINSTANCE_TYPE_MYSQL {
+				opts += " --set-gtid-purged=OFF"
+			}
+			fmt.Fprintf(&sb, "\tmysqldump %s %s --databases %s | gzip > \"$DATA\" || return 1\n", conn, opts, shellQuoteAll(databases))
+			fmt.Fprintf(&sb, "\tcurl -sSf -T \"$DATA\" %s || return 2\n", shellQuote(url))
+		}
+	}
+	sb.WriteString("}\nrun\nCODE=$?\n")
+	sb.WriteString("SIZE=$(stat -c %s \"$DATA\" 2>/dev/null || echo 0)\n")
+	sb.WriteString("rm -f \"$DATA\" \"$0\"\n")
+	fmt.Fprintf(&sb, "echo \"$CODE $SIZE\" > %s\n", shellQuote(statusPath))
+	return sb.String()
+}
+
+func parseDBDumpWorkerStatus(content string) (int, int64, error) {
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return 0, 0, errors.Errorf("invalid status %q", content)
+	}
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid exit code %q", fields[0])
+	}
+	size, _ := strconv.ParseInt(fields[1], 10, 64)
+	return code, size, nil
+}
+
+func workerQgaAction(ctx context.Context, userCred mcclient.TokenCredential, worker *SGuest, action string, input interface{}) (jsonutils.JSONObject, error) {
+	host, err := worker.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	return worker.GetDriver().RequestQgaAction(ctx, userCred, action, jsonutils.Marshal(input), host, worker)
+}
+
+func readWorkerFile(ctx context.Context, userCred mcclient.TokenCredential, worker *SGuest, filePath string) (string, error) {
+	res, err := workerQgaAction(ctx, userCred, worker, "qga-file-read", &api.ServerQgaFileReadInput{Path: filePath, MaxBytes: 64 * 1024})
+	if err != nil {
+		return "", err
+	}
+	output := api.ServerQgaFileReadOutput{}
+	err = res.Unmarshal(&output)
+	if err != nil {
+		return "", errors.Wrap(err, "unmarshal qga file read output")
+	}
+	content, err := base64.StdEncoding.DecodeString(output.Content)
+	if err != nil {
+		return "", errors.Wrap(err, "decode qga file content")
+	}
+	return string(content), nil
+}
+
+// 在工作虚机后台执行脚本并轮询结果, 返回传输文件的字节数
+func (self *SDBInstanceDump) runWorkerScript(ctx context.Context, userCred mcclient.TokenCredential, worker *SGuest, stage string, timeout int, script func(dataPath, statusPath string) string) (int64, error) {
+	prefix := fmt.Sprintf("/tmp/rds-%s-%s", stage, self.Id)
+	scriptPath, dataPath, statusPath, logPath := prefix+".sh", prefix+".sql.gz", prefix+".status", prefix+".log"
+	_, err := workerQgaAction(ctx, userCred, worker, "qga-file-write", &api.ServerQgaFileWriteInput{
+		Path:    scriptPath,
+		Content: base64.StdEncoding.EncodeToString([]byte(script(dataPath, statusPath))),
+		Base64:  true,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "write worker script")
+	}
+	cmd := fmt.Sprintf("rm -f %s; chmod 700 %s; nohup /bin/bash %s > %s 2>&1 < /dev/null &", statusPath, scriptPath, scriptPath, logPath)
+	_, err = workerQgaAction(ctx, userCred, worker, "qga-exec", &api.ServerQgaExecInput{Path: "/bin/sh", Args: []string{"-c", cmd}})
+	if err != nil {
+		return 0, errors.Wrap(err, "start worker script")
+	}
+	defer func() {
+		cleanup := &api.ServerQgaExecInput{Path: "/bin/rm", Args: []string{"-f", scriptPath, dataPath, statusPath, logPath}}
+		if _, err := workerQgaAction(ctx, userCred, worker, "qga-exec", cleanup); err != nil {
+			log.Warningf("cleanup worker %s files %s: %v", worker.Name, prefix, err)
+		}
+	}()
+
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(10 * time.Second)
+		content, err := readWorkerFile(ctx, userCred, worker, statusPath)
+		if err != nil {
+			// 脚本未结束时状态文件不存在
+			continue
+		}
+		code, size, err := parseDBDumpWorkerStatus(content)
+		if err != nil {
+			return 0, err
+		}
+		if code == 0 {
+			return size, nil
+		}
+		output, _ := readWorkerFile(ctx, userCred, worker, logPath)
+		if len(output) > 1024 {
+			output = output[len(output)-1024:]
+		}
+		return 0, errors.Errorf("worker script exit %d: %s", code, strings.TrimSpace(output))
+	}
+	return 0, errors.Wrapf(errors.ErrTimeout, "wait worker script %s", scriptPath)
+}
+
+func (self *SDBInstanceDump) tempUrl(ctx context.Context, method string, timeout int) (string, error) {
+	bucket, err := self.GetBucket()
+	if err != nil {
+		return "", err
+	}
+	iBucket, err := bucket.GetIBucket(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "GetIBucket")
+	}
+	// 预留一小时, 避免传输途中签名过期
+	return iBucket.GetTempUrl(method, self.ObjectKey, time.Duration(timeout)*time.Second+time.Hour)
+}
+
+// 由工作虚机导出源实例数据并上传到存储桶
+func (self *SDBInstanceDump) Dump(ctx context.Context, userCred mcclient.TokenCredential, accountId string, timeout int) error {
+	instance, err := fetchDumpInstance(userCred, self.DBInstanceId)
+	if err != nil {
+		return err
+	}
+	account, err := fetchDumpAccount(userCred, instance, accountId)
+	if err != nil {
+		return err
+	}
+	ep, err := dbDumpEndpointOf(instance, account)
+	if err != nil {
+		return err
+	}
+	worker, err := fetchDumpWorker(userCred, self.WorkerServerId)
+	if err != nil {
+		return err
+	}
+	url, err := self.tempUrl(ctx, "PUT", timeout)
+	if err != nil {
+		return errors.Wrap(err, "GetTempUrl")
+	}
+	size, err := self.runWorkerScript(ctx, userCred, worker, "dump", timeout, func(dataPath, statusPath string) string {
+		return dbDumpWorkerScript(false, self.Format, self.Engine, ep, self.GetDatabases(), url, dataPath, statusPath)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Update(self, func() error {
+		self.SizeMb = int((size + 1024*1024 - 1) / (1024 * 1024))
+		return nil
+	})
+	return err
+}
+
+// 由工作虚机下载导出文件并恢复到目标实例
+func (self *SDBInstanceDump) Restore(ctx context.Context, userCred mcclient.TokenCredential, input api.DBInstanceDumpRestoreInput) error {
+	instance, err := fetchDumpInstance(userCred, input.DBInstanceId)
+	if err != nil {
+		return err
+	}
+	account, err := fetchDumpAccount(userCred, instance, input.DBInstanceaccountId)
+	if err != nil {
+		return err
+	}
+	ep, err := dbDumpEndpointOf(instance, account)
+	if err != nil {
+		return err
+	}
+	worker, err := fetchDumpWorker(userCred, input.WorkerServerId)
+	if err != nil {
+		return err
+	}
+	url, err := self.tempUrl(ctx, "GET", input.Timeout)
+	if err != nil {
+		return errors.Wrap(err, "GetTempUrl")
+	}
+	_, err = self.runWorkerScript(ctx, userCred, worker, "restore", input.Timeout, func(dataPath, statusPath string) string {
+		return dbDumpWorkerScript(true, self.Format, instance.Engine, ep, self.GetDatabases(), url, dataPath, statusPath)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Update(self, func() error {
+		self.RestoredDBInstanceId = instance.Id
+		return nil
+	})
+	return err
+}
+
+func (manager *SDBInstanceDumpManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DBInstanceDumpListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	if len(query.DBInstanceId) > 0 {
+		instance, err := DBInstanceManager.FetchByIdOrName(userCred, query.DBInstanceId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(DBInstanceManager.Keyword(), query.DBInstanceId)
+		}
+		q = q.Equals("dbinstance_id", instance.GetId())
+	}
+	if len(query.BucketId) > 0 {
+		bucket, err := BucketManager.FetchByIdOrName(userCred, query.BucketId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(BucketManager.Keyword(), query.BucketId)
+		}
+		q = q.Equals("bucket_id", bucket.GetId())
+	}
+	if len(query.Format) > 0 {
+		q = q.Equals("format", query.Format)
+	}
+	return q, nil
+}
+
+func (manager *SDBInstanceDumpManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DBInstanceDumpListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+}
+
+func (manager *SDBInstanceDumpManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SDBInstanceDumpManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DBInstanceDumpDetails {
+	rows := make([]api.DBInstanceDumpDetails, len(objs))
+	virtRows := manager.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	instanceIds, bucketIds := make([]string, len(objs)), make([]string, len(objs))
+	for i := range rows {
+		rows[i].VirtualResourceDetails = virtRows[i]
+		dump := objs[i].(*SDBInstanceDump)
+		instanceIds[i], bucketIds[i] = dump.DBInstanceId, dump.BucketId
+	}
+	instances := make(map[string]SDBInstance)
+	if err := db.FetchStandaloneObjectsByIds(DBInstanceManager, instanceIds, instances); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds dbinstances fail %s", err)
+		return rows
+	}
+	buckets := make(map[string]SBucket)
+	if err := db.FetchStandaloneObjectsByIds(BucketManager, bucketIds, buckets); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds buckets fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if instance, ok := instances[instanceIds[i]]; ok {
+			rows[i].DBInstance = instance.Name
+		}
+		if bucket, ok := buckets[bucketIds[i]]; ok {
+			rows[i].Bucket = bucket.Name
+		}
+	}
+	return rows
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestDBDumpWorkerScript(t *testing.T) {
+	ep := sDBDumpEndpoint{Host: "rds.example.com", Port: 3306, User: "admin", Password: "p'w"}
+	script := dbDumpWorkerScript(false, api.DBINSTANCE_DUMP_FORMAT_MYSQL, api.DBINSTANCE_TYPE_MYSQL, ep, []string{"db1", "db2"}, "https://oss/key?sig=1&x=2", "/tmp/d.sql.gz", "/tmp/d.status")
+	for _, want := range []string{
+		`export MYSQL_PWD='p'\''w'`,
+		`mysqldump -h 'rds.example.com' -P 3306 -u 'admin'`,
+		`--set-gtid-purged=OFF --databases 'db1' 'db2' | gzip > "$DATA" || return 1`,
+		`curl -sSf -T "$DATA" 'https://oss/key?sig=1&x=2' || return 2`,
+		`echo "$CODE $SIZE" > '/tmp/d.status'`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("mysql dump script missing %q:\n%s", want, script)
+		}
+	}
+
+	script = dbDumpWorkerScript(false, api.DBINSTANCE_DUMP_FORMAT_MYSQL, api.DBINSTANCE_TYPE_MARIADB, ep, []string{"db1"}, "url", "/tmp/d", "/tmp/s")
+	if strings.Contains(script, "gtid") {
+		t.Errorf("mariadb dump should not set gtid option:\n%s", script)
+	}
+
+	ep = sDBDumpEndpoint{Host: "pg.example.com", Port: 5432, User: "postgres", Password: "pw"}
+	script = dbDumpWorkerScript(true, api.DBINSTANCE_DUMP_FORMAT_POSTGRESQL, api.DBINSTANCE_TYPE_POSTGRESQL, ep, []string{"app"}, "url", "/tmp/d", "/tmp/s")
+	for _, want := range []string{
+		`export PGPASSWORD='pw'`,
+		`curl -sSf -o "$DATA" 'url' || return 2`,
+		`createdb -h 'pg.example.com' -p 5432 -U 'postgres' 'app'`,
+		`gunzip -c "$DATA" | psql -v ON_ERROR_STOP=1 -h 'pg.example.com' -p 5432 -U 'postgres' -d 'app' || return 1`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("postgresql restore script missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestParseDBDumpWorkerStatus(t *testing.T) {
+	code, size, err := parseDBDumpWorkerStatus("0 1048577\n")
+	if err != nil || code != 0 || size != 1048577 {
+		t.Errorf("got %d %d %v", code, size, err)
+	}
+	code, _, err = parseDBDumpWorkerStatus("2 0")
+	if err != nil || code != 2 {
+		t.Errorf("got %d %v", code, err)
+	}
+	if _, _, err := parseDBDumpWorkerStatus(""); err == nil {
+		t.Errorf("empty status should fail")
+	}
+}
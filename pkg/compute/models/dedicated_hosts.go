@@ -0,0 +1,645 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 公有云专有宿主机(AWS Dedicated Hosts, 阿里云DDH)
+// 使用类型别名, 云平台驱动无需引用本包即可实现
+type ICloudDedicatedHost = interface {
+	GetGlobalId() string
+	GetName() string
+	GetStatus() string
+	GetZoneId() string
+	GetHostType() string
+	GetCpuCount() int
+	GetMemSizeMb() int
+	Delete() error
+}
+
+// 支持专有宿主机的区域驱动
+type ICloudDedicatedHostRegion interface {
+	GetIDedicatedHosts() ([]ICloudDedicatedHost, error)
+	GetIDedicatedHostById(id string) (ICloudDedicatedHost, error)
+	CreateIDedicatedHost(zoneId, hostType, name string) (ICloudDedicatedHost, error)
+}
+
+// 支持在专有宿主机上创建虚机的宿主机驱动
+type ICloudDedicatedVMCreator interface {
+	CreateVMOnDedicatedHost(dedicatedHostId string, desc *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error)
+}
+
+// 云上虚机所在的专有宿主机
+type ICloudVMDedicatedHostGetter interface {
+	GetDedicatedHostId() string
+}
+
+type SDedicatedHostManager struct {
+	db.SStatusInfrasResourceBaseManager
+	db.SExternalizedResourceBaseManager
+	SManagedResourceBaseManager
+	SZoneResourceBaseManager
+}
+
+var DedicatedHostManager *SDedicatedHostManager
+
+func init() {
+	DedicatedHostManager = &SDedicatedHostManager{
+		SStatusInfrasResourceBaseManager: db.NewStatusInfrasResourceBaseManager(
+			SDedicatedHost{},
+			"dedicated_hosts_tbl",
+			"dedicated_host",
+			"dedicated_hosts",
+		),
+	}
+	DedicatedHostManager.SetVirtualObject(DedicatedHostManager)
+}
+
+type SDedicatedHost struct {
+	db.SStatusInfrasResourceBase
+	db.SExternalizedResourceBase
+	SManagedResourceBase
+	SCloudregionResourceBase
+	SZoneResourceBase
+
+	// 专有宿主机规格
+	// example: ddh.g6
+	HostType string `width:"64" charset:"ascii" nullable:"false" list:"user" create:"required"`
+	// 可分配CPU核数
+	CpuCount int `nullable:"false" default:"0" list:"user"`
+	// 可分配内存, 单位MB
+	MemSizeMb int `nullable:"false" default:"0" list:"user"`
+}
+
+func (self *SDedicatedHost) GetCloudproviderId() string {
+	return self.ManagerId
+}
+
+func (manager *SDedicatedHostManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DedicatedHostListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "SStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SExternalizedResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ExternalizedResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "SExternalizedResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SManagedResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ManagedResourceListInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "SManagedResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SZoneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "SZoneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.HostType) > 0 {
+		q = q.In("host_type", query.HostType)
+	}
+	return q, nil
+}
+
+func (manager *SDedicatedHostManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DedicatedHostListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SManagedResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ManagedResourceListInput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "SManagedResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SZoneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ZonalFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SZoneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SDedicatedHostManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SManagedResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SZoneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SDedicatedHostManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusInfrasResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusInfrasResourceBaseManager.ListItemExportKeys")
+	}
+	if keys.ContainsAny(manager.SManagedResourceBaseManager.GetExportKeys()...) {
+		q, err = manager.SManagedResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+		if err != nil {
+			return nil, errors.Wrap(err, "SManagedResourceBaseManager.ListItemExportKeys")
+		}
+	}
+	if keys.ContainsAny(manager.SZoneResourceBaseManager.GetExportKeys()...) {
+		q, err = manager.SZoneResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+		if err != nil {
+			return nil, errors.Wrap(err, "SZoneResourceBaseManager.ListItemExportKeys")
+		}
+	}
+	return q, nil
+}
+
+type sDedicatedHostUsage struct {
+	DedicatedHostId string
+	GuestCount      int
+	UsedCpuCount    int
+	UsedMemSizeMb   int
+}
+
+func (manager *SDedicatedHostManager) fetchUsages(ids []string) (map[string]sDedicatedHostUsage, error) {
+	guests := GuestManager.Query().SubQuery()
+	q := guests.Query(
+		guests.Field("dedicated_host_id"),
+		sqlchemy.COUNT("guest_count"),
+		sqlchemy.SUM("used_cpu_count", guests.Field("vcpu_count")),
+		sqlchemy.SUM("used_mem_size_mb", guests.Field("vmem_size")),
+	).Filter(sqlchemy.In(guests.Field("dedicated_host_id"), ids)).GroupBy(guests.Field("dedicated_host_id"))
+	usages := []sDedicatedHostUsage{}
+	err := q.All(&usages)
+	if err != nil {
+		return nil, errors.Wrap(err, "query dedicated host usages")
+	}
+	ret := map[string]sDedicatedHostUsage{}
+	for i := range usages {
+		ret[usages[i].DedicatedHostId] = usages[i]
+	}
+	return ret, nil
+}
+
+func commitRate(used, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total)
+}
+
+func (manager *SDedicatedHostManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DedicatedHostDetails {
+	rows := make([]api.DedicatedHostDetails, len(objs))
+	stdRows := manager.SStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	mRows := manager.SManagedResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	zoneRows := manager.SZoneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	ids := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.DedicatedHostDetails{
+			StatusInfrasResourceBaseDetails: stdRows[i],
+			ManagedResourceInfo:             mRows[i],
+			ZoneResourceInfo:                zoneRows[i],
+		}
+		ids[i] = objs[i].(*SDedicatedHost).Id
+	}
+	usages, err := manager.fetchUsages(ids)
+	if err != nil {
+		log.Errorf("fetch dedicated host usages: %v", err)
+		return rows
+	}
+	for i := range rows {
+		host := objs[i].(*SDedicatedHost)
+		usage := usages[host.Id]
+		rows[i].GuestCount = usage.GuestCount
+		rows[i].UsedCpuCount = usage.UsedCpuCount
+		rows[i].UsedMemSizeMb = usage.UsedMemSizeMb
+		rows[i].CpuCommitRate = commitRate(usage.UsedCpuCount, host.CpuCount)
+		rows[i].MemCommitRate = commitRate(usage.UsedMemSizeMb, host.MemSizeMb)
+	}
+	return rows
+}
+
+func (manager *SDedicatedHostManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.DedicatedHostCreateInput) (api.DedicatedHostCreateInput, error) {
+	if len(input.ZoneId) == 0 {
+		return input, httperrors.NewMissingParameterError("zone_id")
+	}
+	_zone, err := validators.ValidateModel(userCred, ZoneManager, &input.ZoneId)
+	if err != nil {
+		return input, err
+	}
+	zone := _zone.(*SZone)
+	input.CloudregionId = zone.CloudregionId
+
+	if len(input.ManagerId) == 0 {
+		return input, httperrors.NewMissingParameterError("manager_id")
+	}
+	_, err = validators.ValidateModel(userCred, CloudproviderManager, &input.ManagerId)
+	if err != nil {
+		return input, err
+	}
+	if len(input.HostType) == 0 {
+		return input, httperrors.NewMissingParameterError("host_type")
+	}
+
+	input.StatusInfrasResourceBaseCreateInput, err = manager.SStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SDedicatedHost) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStatusInfrasResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	self.StartCreateTask(ctx, userCred, "")
+}
+
+func (self *SDedicatedHost) StartCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	var err = func() error {
+		task, err := taskman.TaskManager.NewTask(ctx, "DedicatedHostCreateTask", self, userCred, nil, parentTaskId, "", nil)
+		if err != nil {
+			return errors.Wrapf(err, "NewTask")
+		}
+		return task.ScheduleRun(nil)
+	}()
+	if err != nil {
+		self.SetStatus(userCred, api.DEDICATED_HOST_STATUS_CREATE_FAILED, err.Error())
+		return err
+	}
+	self.SetStatus(userCred, api.DEDICATED_HOST_STATUS_CREATING, "")
+	return nil
+}
+
+func (self *SDedicatedHost) GetGuestCount() (int, error) {
+	return GuestManager.Query().Equals("dedicated_host_id", self.Id).CountWithError()
+}
+
+func (self *SDedicatedHost) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	cnt, err := self.GetGuestCount()
+	if err != nil {
+		return httperrors.NewInternalServerError("GetGuestCount fail %s", err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("dedicated host has %d guests", cnt)
+	}
+	return self.SStatusInfrasResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *SDedicatedHost) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartDeleteTask(ctx, userCred, "")
+}
+
+func (self *SDedicatedHost) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	var err = func() error {
+		task, err := taskman.TaskManager.NewTask(ctx, "DedicatedHostDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+		if err != nil {
+			return errors.Wrapf(err, "NewTask")
+		}
+		return task.ScheduleRun(nil)
+	}()
+	if err != nil {
+		self.SetStatus(userCred, api.DEDICATED_HOST_STATUS_DELETE_FAILED, err.Error())
+		return nil
+	}
+	self.SetStatus(userCred, api.DEDICATED_HOST_STATUS_DELETING, "")
+	return nil
+}
+
+func (self *SDedicatedHost) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (self *SDedicatedHost) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SStatusInfrasResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDedicatedHost) GetIRegion(ctx context.Context) (ICloudDedicatedHostRegion, error) {
+	provider, err := self.GetDriver(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "self.GetDriver")
+	}
+	region, err := self.SCloudregionResourceBase.GetRegion()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetRegion")
+	}
+	iRegion, err := provider.GetIRegionById(region.ExternalId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "provider.GetIRegionById")
+	}
+	dhRegion, ok := iRegion.(ICloudDedicatedHostRegion)
+	if !ok {
+		return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "region %s does not support dedicated host", region.Name)
+	}
+	return dhRegion, nil
+}
+
+func (self *SDedicatedHost) GetICloudDedicatedHost(ctx context.Context) (ICloudDedicatedHost, error) {
+	if len(self.ExternalId) == 0 {
+		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "empty externalId")
+	}
+	iRegion, err := self.GetIRegion(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "self.GetIRegion")
+	}
+	return iRegion.GetIDedicatedHostById(self.ExternalId)
+}
+
+func (self *SCloudregion) GetDedicatedHosts(managerId string) ([]SDedicatedHost, error) {
+	ret := []SDedicatedHost{}
+	q := DedicatedHostManager.Query().Equals("cloudregion_id", self.Id).Equals("manager_id", managerId)
+	err := db.FetchModelObjects(DedicatedHostManager, q, &ret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "db.FetchModelObjects")
+	}
+	return ret, nil
+}
+
+func (self *SCloudregion) SyncDedicatedHosts(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, exts []ICloudDedicatedHost) compare.SyncResult {
+	lockman.LockRawObject(ctx, self.Id, DedicatedHostManager.Keyword())
+	defer lockman.ReleaseRawObject(ctx, self.Id, DedicatedHostManager.Keyword())
+
+	result := compare.SyncResult{}
+
+	dbHosts, err := self.GetDedicatedHosts(provider.Id)
+	if err != nil {
+		result.Error(errors.Wrapf(err, "GetDedicatedHosts"))
+		return result
+	}
+
+	removed := make([]SDedicatedHost, 0)
+	commondb := make([]SDedicatedHost, 0)
+	commonext := make([]ICloudDedicatedHost, 0)
+	added := make([]ICloudDedicatedHost, 0)
+	err = compare.CompareSets(dbHosts, exts, &removed, &commondb, &commonext, &added)
+	if err != nil {
+		result.Error(errors.Wrapf(err, "compare.CompareSets"))
+		return result
+	}
+
+	for i := 0; i < len(removed); i += 1 {
+		err = removed[i].syncRemove(ctx, userCred)
+		if err != nil {
+			result.DeleteError(err)
+			continue
+		}
+		result.Delete()
+	}
+	for i := 0; i < len(commondb); i += 1 {
+		err = commondb[i].SyncWithCloudDedicatedHost(ctx, userCred, commonext[i])
+		if err != nil {
+			result.UpdateError(err)
+			continue
+		}
+		result.Update()
+	}
+	for i := 0; i < len(added); i += 1 {
+		_, err := self.newFromCloudDedicatedHost(ctx, userCred, provider, added[i])
+		if err != nil {
+			result.AddError(err)
+			continue
+		}
+		result.Add()
+	}
+	return result
+}
+
+func (self *SDedicatedHost) syncRemove(ctx context.Context, userCred mcclient.TokenCredential) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	err := self.ValidateDeleteCondition(ctx, nil)
+	if err != nil { // cannot delete
+		return self.SetStatus(userCred, api.DEDICATED_HOST_STATUS_UNKNOWN, "sync to delete")
+	}
+	err = self.RealDelete(ctx, userCred)
+	if err != nil {
+		return err
+	}
+	notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+		Obj:    self,
+		Action: notifyclient.ActionSyncDelete,
+	})
+	return nil
+}
+
+func (self *SDedicatedHost) SyncWithCloudDedicatedHost(ctx context.Context, userCred mcclient.TokenCredential, ext ICloudDedicatedHost) error {
+	diff, err := db.Update(self, func() error {
+		self.Status = ext.GetStatus()
+		self.HostType = ext.GetHostType()
+		self.CpuCount = ext.GetCpuCount()
+		self.MemSizeMb = ext.GetMemSizeMb()
+		if zoneId := ext.GetZoneId(); len(zoneId) > 0 {
+			region, err := self.SCloudregionResourceBase.GetRegion()
+			if err != nil {
+				return errors.Wrapf(err, "GetRegion")
+			}
+			self.ZoneId, _ = region.getZoneIdBySuffix(zoneId)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "db.Update")
+	}
+	if len(diff) > 0 {
+		notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+			Obj:    self,
+			Action: notifyclient.ActionSyncUpdate,
+		})
+	}
+	return nil
+}
+
+func (self *SCloudregion) newFromCloudDedicatedHost(ctx context.Context, userCred mcclient.TokenCredential, provider *SCloudprovider, ext ICloudDedicatedHost) (*SDedicatedHost, error) {
+	host := SDedicatedHost{}
+	host.SetModelManager(DedicatedHostManager, &host)
+	host.ExternalId = ext.GetGlobalId()
+	host.CloudregionId = self.Id
+	host.ManagerId = provider.Id
+	host.DomainId = provider.DomainId
+	host.Status = ext.GetStatus()
+	host.HostType = ext.GetHostType()
+	host.CpuCount = ext.GetCpuCount()
+	host.MemSizeMb = ext.GetMemSizeMb()
+	if zoneId := ext.GetZoneId(); len(zoneId) > 0 {
+		host.ZoneId, _ = self.getZoneIdBySuffix(zoneId)
+	}
+	err := func() error {
+		lockman.LockRawObject(ctx, DedicatedHostManager.Keyword(), "name")
+		defer lockman.ReleaseRawObject(ctx, DedicatedHostManager.Keyword(), "name")
+
+		var err error
+		host.Name, err = db.GenerateName(ctx, DedicatedHostManager, userCred, ext.GetName())
+		if err != nil {
+			return errors.Wrapf(err, "db.GenerateName")
+		}
+		return DedicatedHostManager.TableSpec().Insert(ctx, &host)
+	}()
+	if err != nil {
+		return nil, errors.Wrapf(err, "insert dedicated host %s", host.ExternalId)
+	}
+	notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+		Obj:    &host,
+		Action: notifyclient.ActionSyncCreate,
+	})
+	return &host, nil
+}
+
+// 检查专有宿主机剩余容量是否满足虚机规格, 容量未知时不做限制
+func checkDedicatedHostCapacity(total, used, request int, resource string) error {
+	if total <= 0 {
+		return nil
+	}
+	if used+request > total {
+		return httperrors.NewInsufficientResourceError("dedicated host %s is not enough, total %d used %d request %d", resource, total, used, request)
+	}
+	return nil
+}
+
+func (self *SDedicatedHost) checkCapacity(vcpuCount, vmemSizeMb int) error {
+	usages, err := DedicatedHostManager.fetchUsages([]string{self.Id})
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	usage := usages[self.Id]
+	if err := checkDedicatedHostCapacity(self.CpuCount, usage.UsedCpuCount, vcpuCount, "cpu"); err != nil {
+		return err
+	}
+	return checkDedicatedHostCapacity(self.MemSizeMb, usage.UsedMemSizeMb, vmemSizeMb, "memory")
+}
+
+// 指定专有宿主机时限定调度的云订阅和可用区, 并检查剩余容量
+func (manager *SGuestManager) validateDedicatedHost(userCred mcclient.TokenCredential, input *api.ServerCreateInput) error {
+	obj, err := DedicatedHostManager.FetchByIdOrName(userCred, input.DedicatedHostId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return httperrors.NewResourceNotFoundError2(DedicatedHostManager.Keyword(), input.DedicatedHostId)
+		}
+		return httperrors.NewGeneralError(err)
+	}
+	dh := obj.(*SDedicatedHost)
+	if dh.Status != api.DEDICATED_HOST_STATUS_AVAILABLE {
+		return httperrors.NewInvalidStatusError("dedicated host %s status is %s", dh.Name, dh.Status)
+	}
+	if len(input.PreferManager) > 0 && input.PreferManager != dh.ManagerId {
+		return httperrors.NewConflictError("dedicated host %s does not belong to cloudprovider %s", dh.Name, input.PreferManager)
+	}
+	if len(input.PreferZone) > 0 && input.PreferZone != dh.ZoneId {
+		return httperrors.NewConflictError("dedicated host %s is not in zone %s", dh.Name, input.PreferZone)
+	}
+	if err := dh.checkCapacity(input.VcpuCount, input.VmemSize); err != nil {
+		return err
+	}
+	input.DedicatedHostId = dh.Id
+	input.PreferManager = dh.ManagerId
+	input.PreferZone = dh.ZoneId
+	return nil
+}
+
+func (self *SGuest) GetDedicatedHost() (*SDedicatedHost, error) {
+	obj, err := DedicatedHostManager.FetchById(self.DedicatedHostId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DedicatedHostManager.FetchById(%s)", self.DedicatedHostId)
+	}
+	return obj.(*SDedicatedHost), nil
+}
+
+// CreateVMOnIHost 在云上创建虚机, 指定了专有宿主机时需要宿主机驱动支持
+func (self *SGuest) CreateVMOnIHost(ihost cloudprovider.ICloudHost, desc *cloudprovider.SManagedVMCreateConfig) (cloudprovider.ICloudVM, error) {
+	if len(self.DedicatedHostId) == 0 {
+		return ihost.CreateVM(desc)
+	}
+	dh, err := self.GetDedicatedHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDedicatedHost")
+	}
+	creator, ok := ihost.(ICloudDedicatedVMCreator)
+	if !ok {
+		return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "host %s can not create vm on dedicated host", ihost.GetName())
+	}
+	return creator.CreateVMOnDedicatedHost(dh.ExternalId, desc)
+}
+
+func (self *SGuest) syncDedicatedHost(ctx context.Context, userCred mcclient.TokenCredential, extVM cloudprovider.ICloudVM, managerId string) {
+	getter, ok := extVM.(ICloudVMDedicatedHostGetter)
+	if !ok {
+		return
+	}
+	dedicatedHostId := ""
+	if extId := getter.GetDedicatedHostId(); len(extId) > 0 {
+		dh := &SDedicatedHost{}
+		dh.SetModelManager(DedicatedHostManager, dh)
+		err := DedicatedHostManager.Query().Equals("external_id", extId).Equals("manager_id", managerId).First(dh)
+		if err != nil {
+			if errors.Cause(err) != sql.ErrNoRows {
+				log.Errorf("fetch dedicated host %s of guest %s: %v", extId, self.Name, err)
+			}
+			return
+		}
+		dedicatedHostId = dh.Id
+	}
+	if dedicatedHostId == self.DedicatedHostId {
+		return
+	}
+	_, err := db.Update(self, func() error {
+		self.DedicatedHostId = dedicatedHostId
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update dedicated host of guest %s: %v", self.Name, err)
+		return
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, fmt.Sprintf("dedicated host changed to %q", dedicatedHostId), userCred)
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestCheckDedicatedHostCapacity(t *testing.T) {
+	cases := []struct {
+		total   int
+		used    int
+		request int
+		wantErr bool
+	}{
+		{0, 100, 8, false},
+		{64, 32, 32, false},
+		{64, 32, 33, true},
+		{64, 0, 8, false},
+	}
+	for _, c := range cases {
+		err := checkDedicatedHostCapacity(c.total, c.used, c.request, "cpu")
+		if (err != nil) != c.wantErr {
+			t.Errorf("checkDedicatedHostCapacity(%d, %d, %d) error = %v, wantErr %v", c.total, c.used, c.request, err, c.wantErr)
+		}
+	}
+}
+
+func TestCommitRate(t *testing.T) {
+	if got := commitRate(16, 64); got != 0.25 {
+		t.Errorf("commitRate(16, 64) = %f, want 0.25", got)
+	}
+	if got := commitRate(16, 0); got != 0 {
+		t.Errorf("commitRate(16, 0) = %f, want 0", got)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 仅运行中的本地存储 qcow2 KVM 磁盘可以通过 dirty bitmap 备份
+func (db *SDiskBackup) isBitmapBackupSupported(disk *SDisk) bool {
+	if disk.DiskFormat != "qcow2" {
+		return false
+	}
+	guest := disk.GetGuest()
+	if guest == nil || guest.Hypervisor != api.HYPERVISOR_KVM || guest.Status != api.VM_RUNNING {
+		return false
+	}
+	storage, err := disk.GetStorage()
+	if err != nil || storage.StorageType != api.STORAGE_LOCAL {
+		return false
+	}
+	return true
+}
+
+// 上一次 bitmap 备份, 仅当其仍可用且位于同一备份存储时才能作为增量备份的父备份
+func (db *SDiskBackup) getLastBitmapBackup(ctx context.Context, disk *SDisk) (*SDiskBackup, error) {
+	lastId := disk.GetMetadata(ctx, api.DISK_METADATA_LAST_BITMAP_BACKUP, nil)
+	if len(lastId) == 0 || lastId == db.Id {
+		return nil, nil
+	}
+	obj, err := DiskBackupManager.FetchById(lastId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fetch backup %s", lastId)
+	}
+	last := obj.(*SDiskBackup)
+	if last.Status != api.BACKUP_STATUS_READY || last.BackupStorageId != db.BackupStorageId || len(last.BackupMode) == 0 {
+		return nil, nil
+	}
+	return last, nil
+}
+
+// 增量备份链长度, 即距离最近一次全量备份的增量备份个数
+func (db *SDiskBackup) getIncrementalChainLength() (int, error) {
+	length := 0
+	cur := db
+	for cur.BackupMode == api.DISK_BACKUP_MODE_INCREMENTAL && len(cur.ParentBackupId) > 0 {
+		length++
+		obj, err := DiskBackupManager.FetchById(cur.ParentBackupId)
+		if err != nil {
+			return 0, errors.Wrapf(err, "fetch parent backup %s", cur.ParentBackupId)
+		}
+		cur = obj.(*SDiskBackup)
+	}
+	return length, nil
+}
+
+func selectDiskBackupMode(mode string, hasParent bool, chainLength, maxChain int) (string, error) {
+	switch mode {
+	case api.DISK_BACKUP_MODE_FULL:
+		return api.DISK_BACKUP_MODE_FULL, nil
+	case api.DISK_BACKUP_MODE_INCREMENTAL:
+		if !hasParent {
+			return "", httperrors.NewBadRequestError("no available full backup for incremental backup")
+		}
+		return api.DISK_BACKUP_MODE_INCREMENTAL, nil
+	default:
+		if hasParent && chainLength < maxChain {
+			return api.DISK_BACKUP_MODE_INCREMENTAL, nil
+		}
+		return api.DISK_BACKUP_MODE_FULL, nil
+	}
+}
+
+// PrepareBitmapBackup 根据请求的备份模式决定是否使用 dirty bitmap 备份,
+// 返回空的 mode 表示使用快照方式备份
+func (db *SDiskBackup) PrepareBitmapBackup(ctx context.Context, mode string) (string, string, error) {
+	disk, err := db.GetDisk()
+	if err != nil {
+		return "", "", errors.Wrap(err, "GetDisk")
+	}
+	if !db.isBitmapBackupSupported(disk) {
+		if mode == api.DISK_BACKUP_MODE_INCREMENTAL {
+			return "", "", httperrors.NewUnsupportOperationError("disk %s not support incremental backup", disk.Name)
+		}
+		return "", "", nil
+	}
+	parentId, chainLength := "", 0
+	parent, err := db.getLastBitmapBackup(ctx, disk)
+	if err != nil {
+		return "", "", err
+	}
+	if parent != nil {
+		parentId = parent.Id
+		chainLength, err = parent.getIncrementalChainLength()
+		if err != nil {
+			return "", "", err
+		}
+	}
+	mode, err = selectDiskBackupMode(mode, parent != nil, chainLength, options.Options.DiskBackupMaxIncrementalChain)
+	if err != nil {
+		return "", "", err
+	}
+	if mode == api.DISK_BACKUP_MODE_FULL {
+		parentId = ""
+	}
+	return mode, parentId, nil
+}
+
+// OnBitmapBackupSaved 记录 bitmap 备份结果, 下一次增量备份以本备份为父备份
+func (db *SDiskBackup) OnBitmapBackupSaved(ctx context.Context, userCred mcclient.TokenCredential, mode, parentId string, sizeMb int, checksum string) error {
+	_, err := db.GetModelManager().TableSpec().Update(ctx, db, func() error {
+		db.BackupMode = mode
+		db.ParentBackupId = parentId
+		db.SizeMb = sizeMb
+		db.Checksum = checksum
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update backup")
+	}
+	disk, err := db.GetDisk()
+	if err != nil {
+		return errors.Wrap(err, "GetDisk")
+	}
+	return disk.SetMetadata(ctx, api.DISK_METADATA_LAST_BITMAP_BACKUP, db.Id, userCred)
+}
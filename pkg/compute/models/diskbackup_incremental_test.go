@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSelectDiskBackupMode(t *testing.T) {
+	cases := []struct {
+		mode      string
+		hasParent bool
+		chain     int
+		want      string
+		wantErr   bool
+	}{
+		{api.DISK_BACKUP_MODE_AUTO, false, 0, api.DISK_BACKUP_MODE_FULL, false},
+		{api.DISK_BACKUP_MODE_AUTO, true, 0, api.DISK_BACKUP_MODE_INCREMENTAL, false},
+		{api.DISK_BACKUP_MODE_AUTO, true, 6, api.DISK_BACKUP_MODE_INCREMENTAL, false},
+		{api.DISK_BACKUP_MODE_AUTO, true, 7, api.DISK_BACKUP_MODE_FULL, false},
+		{api.DISK_BACKUP_MODE_FULL, true, 0, api.DISK_BACKUP_MODE_FULL, false},
+		{api.DISK_BACKUP_MODE_INCREMENTAL, true, 10, api.DISK_BACKUP_MODE_INCREMENTAL, false},
+		{api.DISK_BACKUP_MODE_INCREMENTAL, false, 0, "", true},
+	}
+	for _, c := range cases {
+		got, err := selectDiskBackupMode(c.mode, c.hasParent, c.chain, 7)
+		if (err != nil) != c.wantErr {
+			t.Errorf("selectDiskBackupMode(%s, %v, %d) error %v", c.mode, c.hasParent, c.chain, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("selectDiskBackupMode(%s, %v, %d) = %s, want %s", c.mode, c.hasParent, c.chain, got, c.want)
+		}
+	}
+}
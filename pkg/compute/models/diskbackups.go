@@ -64,6 +64,16 @@ type SDiskBackup struct {
 	// 操作系统类型
 	OsType     string `width:"32" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 	DiskConfig *SBackupDiskConfig
+	// 备份文件md5
+	Checksum string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	// 最近一次完整性校验结果
+	VerifyStatus string `width:"16" charset:"ascii" nullable:"true" list:"user"`
+	// 最近一次完整性校验时间
+	VerifiedAt time.Time `nullable:"true" list:"user"`
+	// 备份模式
+	BackupMode string `width:"16" charset:"ascii" nullable:"true" list:"user"`
+	// 增量备份的父备份
+	ParentBackupId string `width:"36" charset:"ascii" nullable:"true" list:"user" index:"true"`
 }
 
 var DiskBackupManager *SDiskBackupManager
@@ -120,6 +130,9 @@ func (dm *SDiskBackupManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQ
 	if input.BackupStorageId != "" {
 		q = q.Equals("backup_storage_id", input.BackupStorageId)
 	}
+	if input.ParentBackupId != "" {
+		q = q.Equals("parent_backup_id", input.ParentBackupId)
+	}
 	if input.IsInstanceBackup != nil {
 		insjsq := InstanceBackupJointManager.Query().SubQuery()
 		if !*input.IsInstanceBackup {
@@ -143,6 +156,13 @@ func (self *SDiskBackup) ValidateDeleteCondition(ctx context.Context, info jsonu
 	if is {
 		return httperrors.NewBadRequestError("disk backup referenced by instance backup")
 	}
+	cnt, err := DiskBackupManager.Query().Equals("parent_backup_id", self.Id).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "query incremental backups")
+	}
+	if cnt > 0 {
+		return httperrors.NewBadRequestError("disk backup has %d incremental backups", cnt)
+	}
 	return nil
 }
 
@@ -182,6 +202,11 @@ func (db *SDiskBackup) getMoreDetails(out api.DiskBackupDetails) api.DiskBackupD
 	if t, _ := InstanceBackupJointManager.IsSubBackup(db.Id); t {
 		out.IsSubBackup = true
 	}
+	if len(db.ParentBackupId) > 0 {
+		if parent, _ := DiskBackupManager.FetchById(db.ParentBackupId); parent != nil {
+			out.ParentBackupName = parent.GetName()
+		}
+	}
 	return out
 }
 
@@ -264,6 +289,13 @@ func (dm *SDiskBackupManager) ValidateCreateData(
 	if err != nil {
 		return input, err
 	}
+	switch input.BackupMode {
+	case "":
+		input.BackupMode = api.DISK_BACKUP_MODE_AUTO
+	case api.DISK_BACKUP_MODE_AUTO, api.DISK_BACKUP_MODE_FULL, api.DISK_BACKUP_MODE_INCREMENTAL:
+	default:
+		return input, httperrors.NewInputParameterError("invalid backup_mode %s", input.BackupMode)
+	}
 	bs := ibs.(*SBackupStorage)
 	if bs.Status != api.BACKUPSTORAGE_STATUS_ONLINE {
 		return input, httperrors.NewForbiddenError("can't backup guest to backup storage with status %s", bs.Status)
@@ -315,7 +347,12 @@ func (db *SDiskBackup) PostCreate(ctx context.Context, userCred mcclient.TokenCr
 	if err != nil {
 		log.Errorf("unable to inherit from disk %s to backup %s: %s", disk.GetId(), db.GetId(), err.Error())
 	}
-	db.StartBackupCreateTask(ctx, userCred, nil, "")
+	params := jsonutils.NewDict()
+	mode, _ := data.GetString("backup_mode")
+	if len(mode) > 0 {
+		params.Set("backup_mode", jsonutils.NewString(mode))
+	}
+	db.StartBackupCreateTask(ctx, userCred, params, "")
 }
 
 func (db *SDiskBackup) StartBackupCreateTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
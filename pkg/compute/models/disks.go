@@ -979,7 +979,7 @@ func (disk *SDisk) PerformResize(ctx context.Context, userCred mcclient.TokenCre
 	if err != nil {
 		return nil, err
 	}
-	err = disk.doResize(ctx, userCred, sizeMb, guest)
+	err = disk.doResize(ctx, userCred, sizeMb, guest, "")
 	if err != nil {
 		return nil, err
 	}
@@ -1020,7 +1020,7 @@ func (disk *SDisk) GetQuotaKeys() (quotas.IQuotaKeys, error) {
 	), nil
 }
 
-func (disk *SDisk) doResize(ctx context.Context, userCred mcclient.TokenCredential, sizeMb int, guest *SGuest) error {
+func (disk *SDisk) doResize(ctx context.Context, userCred mcclient.TokenCredential, sizeMb int, guest *SGuest, parentTaskId string) error {
 	if disk.Status != api.DISK_READY {
 		return httperrors.NewResourceNotReadyError("Resize disk when disk is READY")
 	}
@@ -1064,9 +1064,9 @@ func (disk *SDisk) doResize(ctx context.Context, userCred mcclient.TokenCredenti
 	}
 
 	if guest != nil {
-		return guest.StartGuestDiskResizeTask(ctx, userCred, disk.Id, int64(sizeMb), "", &pendingUsage)
+		return guest.StartGuestDiskResizeTask(ctx, userCred, disk.Id, int64(sizeMb), parentTaskId, &pendingUsage)
 	} else {
-		return disk.StartDiskResizeTask(ctx, userCred, int64(sizeMb), "", &pendingUsage)
+		return disk.StartDiskResizeTask(ctx, userCred, int64(sizeMb), parentTaskId, &pendingUsage)
 	}
 }
 
@@ -1908,6 +1908,11 @@ func parseDiskInfo(ctx context.Context, userCred mcclient.TokenCredential, info
 			return nil, errors.Wrap(err, "fillDiskConfigByBackup")
 		}
 	}
+	if info.ImageChannel != "" && info.ImageId == "" {
+		if err := fillDiskConfigByImageChannel(ctx, userCred, info, info.ImageChannel); err != nil {
+			return nil, errors.Wrap(err, "fillDiskConfigByImageChannel")
+		}
+	}
 	if info.ImageId != "" {
 		if err := fillDiskConfigByImage(ctx, userCred, info, info.ImageId); err != nil {
 			if len(info.SnapshotId) == 0 && len(info.BackupId) == 0 {
@@ -1980,6 +1985,24 @@ func fillDiskConfigByBackup(ctx context.Context, userCred mcclient.TokenCredenti
 	return nil
 }
 
+func fillDiskConfigByImageChannel(ctx context.Context, userCred mcclient.TokenCredential, diskConfig *api.DiskConfig, ref string) error {
+	if userCred == nil {
+		return httperrors.NewMissingParameterError("image_id")
+	}
+	s := auth.GetSession(ctx, userCred, options.Options.Region)
+	ret, err := image.ImageChannels.Get(s, "resolve", jsonutils.Marshal(map[string]string{"ref": ref}))
+	if err != nil {
+		return errors.Wrapf(err, "resolve image channel %s", ref)
+	}
+	resolved := imageapi.ImageChannelResolveOutput{}
+	ret.Unmarshal(&resolved)
+	if len(resolved.ImageId) == 0 {
+		return httperrors.NewNotFoundError("no image promoted for image channel %s", ref)
+	}
+	diskConfig.ImageId = resolved.ImageId
+	return nil
+}
+
 func fillDiskConfigByImage(ctx context.Context, userCred mcclient.TokenCredential,
 	diskConfig *api.DiskConfig, imageId string) error {
 	if userCred == nil {
@@ -2749,7 +2772,8 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 			goto onFail
 		}
 		// if auto snapshot count gt max auto snapshot count, do clean overdued snapshots
-		cleanOverdueSnapshots = snapCount > autoSnapshotCount
+		// GFS policies are rotated by PruneGFSSnapshots instead
+		cleanOverdueSnapshots = snapCount > autoSnapshotCount && !snapshotPolicy.IsGFS()
 		if cleanOverdueSnapshots {
 			disk.CleanOverdueSnapshots(ctx, userCred, snapshotPolicy, now)
 		}
@@ -55,6 +55,11 @@ type SElasticipManager struct {
 
 var ElasticipManager *SElasticipManager
 
+// 支持共享带宽的云上EIP
+type ICloudSharedBandwidthEip interface {
+	GetSharedBandwidthId() string
+}
+
 func init() {
 	ElasticipManager = &SElasticipManager{
 		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
@@ -108,6 +113,9 @@ type SElasticip struct {
 	// 是否跟随主机删除而自动释放
 	AutoDellocate tristate.TriState `default:"false" get:"user" create:"optional" update:"user"`
 
+	// 所在共享带宽的云上Id, 独享带宽时为空
+	SharedBandwidthId string `width:"128" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+
 	// 区域Id
 	// CloudregionId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
 }
@@ -272,6 +280,9 @@ func (manager *SElasticipManager) ListItemFilter(
 	if len(query.BgpType) > 0 {
 		q = q.In("bgp_type", query.BgpType)
 	}
+	if len(query.SharedBandwidthId) > 0 {
+		q = q.In("shared_bandwidth_id", query.SharedBandwidthId)
+	}
 	if query.AutoDellocate != nil {
 		if *query.AutoDellocate {
 			q = q.IsTrue("auto_dellocate")
@@ -521,6 +532,9 @@ func (self *SElasticip) SyncWithCloudEip(ctx context.Context, userCred mcclient.
 		if chargeType := ext.GetInternetChargeType(); len(chargeType) > 0 {
 			self.ChargeType = chargeType
 		}
+		if sbw, ok := ext.(ICloudSharedBandwidthEip); ok {
+			self.SharedBandwidthId = sbw.GetSharedBandwidthId()
+		}
 
 		factory, _ := provider.GetProviderFactory()
 		if factory != nil && factory.IsSupportPrepaidResources() {
@@ -582,6 +596,9 @@ func (manager *SElasticipManager) newFromCloudEip(ctx context.Context, userCred
 		eip.ChargeType = api.EIP_CHARGE_TYPE_BY_TRAFFIC
 	}
 	eip.Bandwidth = extEip.GetBandwidth()
+	if sbw, ok := extEip.(ICloudSharedBandwidthEip); ok {
+		eip.SharedBandwidthId = sbw.GetSharedBandwidthId()
+	}
 	if networkId := extEip.GetINetworkId(); len(networkId) > 0 {
 		network, err := db.FetchByExternalIdAndManagerId(NetworkManager, networkId, func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
 			wire := WireManager.Query().SubQuery()
@@ -976,6 +993,10 @@ func (manager *SElasticipManager) ValidateCreateData(ctx context.Context, userCr
 		return input, err
 	}
 
+	if len(input.SharedBandwidthId) > 0 && regionDriver.GetProvider() != api.CLOUD_PROVIDER_HUAWEI {
+		return input, httperrors.NewUnsupportOperationError("shared bandwidth is not supported by %s", regionDriver.GetProvider())
+	}
+
 	err = regionDriver.ValidateCreateEipData(ctx, userCred, &input)
 	if err != nil {
 		return input, err
@@ -1696,15 +1717,13 @@ func (self *SElasticip) PerformChangeBandwidth(ctx context.Context, userCred mcc
 		return nil, httperrors.NewInputParameterError("Invalid bandwidth")
 	}
 
-	if self.IsManaged() {
-		factory, err := self.GetProviderFactory()
-		if err != nil {
-			return nil, err
-		}
-
-		if err := factory.ValidateChangeBandwidth(self.AssociateId, bandwidth); err != nil {
-			return nil, httperrors.NewInputParameterError("%v", err)
-		}
+	region, err := self.GetRegion()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetRegion"))
+	}
+	err = region.GetDriver().ValidateChangeEipBandwidth(ctx, userCred, self, int(bandwidth))
+	if err != nil {
+		return nil, err
 	}
 
 	err = self.StartEipChangeBandwidthTask(ctx, userCred, bandwidth)
@@ -1730,25 +1749,27 @@ func (self *SElasticip) StartEipChangeBandwidthTask(ctx context.Context, userCre
 	return nil
 }
 
-func (self *SElasticip) DoChangeBandwidth(userCred mcclient.TokenCredential, bandwidth int) error {
-	changes := jsonutils.NewDict()
-	changes.Add(jsonutils.NewInt(int64(self.Bandwidth)), "obw")
+// 更新本地带宽并记录计量事件, 计量服务依据 obw/nbw 切分计费区间
+func (self *SElasticip) DoChangeBandwidth(ctx context.Context, userCred mcclient.TokenCredential, bandwidth int) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
 
+	oldBandwidth := self.Bandwidth
 	_, err := db.Update(self, func() error {
 		self.Bandwidth = bandwidth
 		return nil
 	})
-
-	self.SetStatus(userCred, api.EIP_STATUS_READY, "finish change bandwidth")
-
 	if err != nil {
-		log.Errorf("DoChangeBandwidth update fail %s", err)
-		return err
+		return errors.Wrapf(err, "db.Update")
 	}
 
-	changes.Add(jsonutils.NewInt(int64(bandwidth)), "nbw")
-	db.OpsLog.LogEvent(self, db.ACT_CHANGE_BANDWIDTH, changes, userCred)
-
+	if oldBandwidth != bandwidth {
+		changes := jsonutils.NewDict()
+		changes.Add(jsonutils.NewInt(int64(oldBandwidth)), "obw")
+		changes.Add(jsonutils.NewInt(int64(bandwidth)), "nbw")
+		db.OpsLog.LogEvent(self, db.ACT_CHANGE_BANDWIDTH, changes, userCred)
+	}
+	self.SetStatus(userCred, api.EIP_STATUS_READY, "finish change bandwidth")
 	return nil
 }
 
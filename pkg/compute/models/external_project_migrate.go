@@ -0,0 +1,205 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	EXTERNAL_PROJECT_RESOURCE_SERVER = "server"
+	EXTERNAL_PROJECT_RESOURCE_DISK   = "disk"
+	EXTERNAL_PROJECT_RESOURCE_EIP    = "eip"
+)
+
+var externalProjectMigrateResourceTypes = []string{
+	// 虚拟机需要先迁移, 部分平台会连同挂载的磁盘及EIP一起迁移
+	EXTERNAL_PROJECT_RESOURCE_SERVER,
+	EXTERNAL_PROJECT_RESOURCE_DISK,
+	EXTERNAL_PROJECT_RESOURCE_EIP,
+}
+
+func (self *SExternalProject) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ExternalProjectUpdateInput) (api.ExternalProjectUpdateInput, error) {
+	var err error
+	if input.Priority != nil && *input.Priority < 0 {
+		return input, httperrors.NewInputParameterError("invalid priority %d", *input.Priority)
+	}
+	input.VirtualResourceBaseUpdateInput, err = self.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (self *SExternalProject) getMigrateProviderIds() ([]string, error) {
+	if len(self.ManagerId) > 0 {
+		return []string{self.ManagerId}, nil
+	}
+	account, err := self.GetCloudaccount()
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, provider := range account.GetCloudproviders() {
+		ret = append(ret, provider.Id)
+	}
+	return ret, nil
+}
+
+// 将本地项目下的云上资源迁移到此云上项目
+func (self *SExternalProject) PerformMigrateResources(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ExternalProjectMigrateResourcesInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.EXTERNAL_PROJECT_STATUS_AVAILABLE {
+		return nil, httperrors.NewInvalidStatusError("external project %s status is %s", self.Name, self.Status)
+	}
+	for _, resType := range input.ResourceTypes {
+		if !utils.IsInStringArray(resType, externalProjectMigrateResourceTypes) {
+			return nil, httperrors.NewInputParameterError("invalid resource type %s, support %s", resType, externalProjectMigrateResourceTypes)
+		}
+	}
+	if len(input.ResourceTypes) == 0 {
+		input.ResourceTypes = externalProjectMigrateResourceTypes
+	}
+	return nil, self.StartMigrateResourcesTask(ctx, userCred, input.ResourceTypes, "")
+}
+
+func (self *SExternalProject) StartMigrateResourcesTask(ctx context.Context, userCred mcclient.TokenCredential, resourceTypes []string, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("resource_types", jsonutils.NewStringArray(resourceTypes))
+	task, err := taskman.TaskManager.NewTask(ctx, "ExternalProjectMigrateResourcesTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+type SExternalProjectMigrateResult struct {
+	Migrated int
+	Skipped  int
+	Failed   int
+}
+
+// 云上资源已在目标项目中返回false
+func migrateCloudResourceProject(ext cloudprovider.IVirtualResource, extProjectId string) (bool, error) {
+	if ext.GetProjectId() == extProjectId {
+		return false, nil
+	}
+	res, ok := ext.(ICloudProjectMigratable)
+	if !ok {
+		return false, errors.Wrapf(cloudprovider.ErrNotSupported, "migrate %s to project %s", ext.GetName(), extProjectId)
+	}
+	return true, res.SetProjectId(extProjectId)
+}
+
+func (self *SExternalProject) migrateResources(ctx context.Context, resType string, providerIds []string, result *SExternalProjectMigrateResult) error {
+	errs := []error{}
+	migrate := func(name string, getExt func() (cloudprovider.IVirtualResource, error)) {
+		ext, err := getExt()
+		if err != nil {
+			result.Failed++
+			errs = append(errs, errors.Wrapf(err, "%s %s", resType, name))
+			return
+		}
+		migrated, err := migrateCloudResourceProject(ext, self.ExternalId)
+		if err != nil {
+			result.Failed++
+			errs = append(errs, errors.Wrapf(err, "%s %s", resType, name))
+			return
+		}
+		if migrated {
+			result.Migrated++
+		} else {
+			result.Skipped++
+		}
+	}
+	switch resType {
+	case EXTERNAL_PROJECT_RESOURCE_SERVER:
+		hosts := HostManager.Query("id").In("manager_id", providerIds).SubQuery()
+		q := GuestManager.Query().Equals("tenant_id", self.ProjectId).In("host_id", hosts).IsNotEmpty("external_id")
+		guests := []SGuest{}
+		err := db.FetchModelObjects(GuestManager, q, &guests)
+		if err != nil {
+			return errors.Wrapf(err, "FetchModelObjects")
+		}
+		for i := range guests {
+			migrate(guests[i].Name, func() (cloudprovider.IVirtualResource, error) {
+				return guests[i].GetIVM(ctx)
+			})
+		}
+	case EXTERNAL_PROJECT_RESOURCE_DISK:
+		storages := StorageManager.Query("id").In("manager_id", providerIds).SubQuery()
+		q := DiskManager.Query().Equals("tenant_id", self.ProjectId).In("storage_id", storages).IsNotEmpty("external_id")
+		disks := []SDisk{}
+		err := db.FetchModelObjects(DiskManager, q, &disks)
+		if err != nil {
+			return errors.Wrapf(err, "FetchModelObjects")
+		}
+		for i := range disks {
+			migrate(disks[i].Name, func() (cloudprovider.IVirtualResource, error) {
+				return disks[i].GetIDisk(ctx)
+			})
+		}
+	case EXTERNAL_PROJECT_RESOURCE_EIP:
+		q := ElasticipManager.Query().Equals("tenant_id", self.ProjectId).In("manager_id", providerIds).IsNotEmpty("external_id")
+		q = q.Filter(sqlchemy.IsFalse(q.Field("is_emulated")))
+		eips := []SElasticip{}
+		err := db.FetchModelObjects(ElasticipManager, q, &eips)
+		if err != nil {
+			return errors.Wrapf(err, "FetchModelObjects")
+		}
+		for i := range eips {
+			migrate(eips[i].Name, func() (cloudprovider.IVirtualResource, error) {
+				return eips[i].GetIEip(ctx)
+			})
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// 迁移本地项目下的虚拟机、磁盘及EIP到此云上项目, 单个资源迁移失败不影响其他资源
+func (self *SExternalProject) MigrateResources(ctx context.Context, userCred mcclient.TokenCredential, resourceTypes []string) (*SExternalProjectMigrateResult, error) {
+	result := &SExternalProjectMigrateResult{}
+	providerIds, err := self.getMigrateProviderIds()
+	if err != nil {
+		return result, errors.Wrapf(err, "getMigrateProviderIds")
+	}
+	if len(providerIds) == 0 {
+		return result, nil
+	}
+	errs := []error{}
+	for _, resType := range externalProjectMigrateResourceTypes {
+		if !utils.IsInStringArray(resType, resourceTypes) {
+			continue
+		}
+		err := self.migrateResources(ctx, resType, providerIds, result)
+		if err != nil {
+			log.Errorf("migrate %s to external project %s(%s): %v", resType, self.Name, self.ExternalId, err)
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.NewAggregate(errs)
+}
@@ -71,6 +71,11 @@ type SExternalProject struct {
 	ExternalDomainId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
 	// 归属云账号ID
 	CloudaccountId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+
+	// 映射优先级, 本地项目映射多个云上项目时优先使用优先级高的云上项目
+	Priority int `nullable:"false" default:"0" list:"user" update:"domain"`
+	// 是否为手动指定的映射, 手动映射不会被同步自动修改
+	ManualMapping bool `nullable:"false" default:"false" list:"user"`
 }
 
 func (manager *SExternalProjectManager) ValidateCreateData(
@@ -267,6 +272,10 @@ func (self *SExternalProject) SyncWithCloudProject(ctx context.Context, userCred
 			}
 			return nil
 		}
+		// 手动映射的项目不随云上项目名称变化
+		if self.ManualMapping {
+			return nil
+		}
 		if account.AutoCreateProject && options.Options.EnableAutoRenameProject {
 			tenant, err := db.TenantCacheManager.FetchTenantById(ctx, self.ProjectId)
 			if err != nil {
@@ -440,7 +449,11 @@ func (self *SExternalProject) PerformChangeProject(ctx context.Context, userCred
 		return nil, httperrors.NewNotFoundError("project %s not found", input.ProjectId)
 	}
 
-	if self.ProjectId == tenant.Id {
+	if input.Priority != nil && *input.Priority < 0 {
+		return nil, httperrors.NewInputParameterError("invalid priority %d", *input.Priority)
+	}
+
+	if self.ProjectId == tenant.Id && (input.Priority == nil || *input.Priority == self.Priority) {
 		return nil, nil
 	}
 
@@ -474,6 +487,10 @@ func (self *SExternalProject) PerformChangeProject(ctx context.Context, userCred
 	_, err = db.Update(self, func() error {
 		self.ProjectId = tenant.Id
 		self.DomainId = tenant.DomainId
+		self.ManualMapping = true
+		if input.Priority != nil {
+			self.Priority = *input.Priority
+		}
 		return nil
 	})
 	if err != nil {
@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+func TestGetAvailableExternalProject(t *testing.T) {
+	newProject := func(id, name, projectId string, priority int, manual bool) SExternalProject {
+		proj := SExternalProject{Priority: priority, ManualMapping: manual}
+		proj.Id = id
+		proj.Name = name
+		proj.ProjectId = projectId
+		proj.Status = api.EXTERNAL_PROJECT_STATUS_AVAILABLE
+		return proj
+	}
+	local := &db.STenant{}
+	local.Id = "p1"
+	local.Name = "dev"
+
+	cases := []struct {
+		name     string
+		projects []SExternalProject
+		want     string
+	}{
+		{
+			name:     "same name",
+			projects: []SExternalProject{newProject("e1", "dev", "p2", 0, false)},
+			want:     "e1",
+		},
+		{
+			name:     "manual mapping with same name",
+			projects: []SExternalProject{newProject("e1", "dev", "p2", 0, true)},
+			want:     "",
+		},
+		{
+			name: "mapped first",
+			projects: []SExternalProject{
+				newProject("e1", "dev", "p2", 0, false),
+				newProject("e2", "test", "p1", 0, false),
+			},
+			want: "e2",
+		},
+		{
+			name: "many to one priority",
+			projects: []SExternalProject{
+				newProject("e1", "a", "p1", 1, true),
+				newProject("e2", "b", "p1", 5, true),
+				newProject("e3", "c", "p1", 3, false),
+			},
+			want: "e2",
+		},
+	}
+	for _, c := range cases {
+		got := GetAvailableExternalProject(local, c.projects)
+		gotId := ""
+		if got != nil {
+			gotId = got.Id
+		}
+		if gotId != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, gotId, c.want)
+		}
+	}
+}
@@ -0,0 +1,661 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+const (
+	FAILOVER_VIP_DEFAULT_HEALTH_CHECK_FAILURES = 3
+	FAILOVER_VIP_TCP_CHECK_TIMEOUT             = 3 * time.Second
+)
+
+// 浮动VIP, 绑定在主虚机网卡上, 切换时迁移到备虚机
+// 绑定关系通过 networkaddress 的 sub_ip 实现: 私有云由 vpcagent/hostman 更新端口地址, 公有云调用辅助IP接口迁移
+type SFailoverVipManager struct {
+	db.SVirtualResourceBaseManager
+	SNetworkResourceBaseManager
+
+	// vip id -> 连续健康检查失败次数
+	healthCheckFailures map[string]int
+	healthCheckLock     sync.Mutex
+}
+
+var FailoverVipManager *SFailoverVipManager
+
+func init() {
+	FailoverVipManager = &SFailoverVipManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SFailoverVip{},
+			"failover_vips_tbl",
+			"failover_vip",
+			"failover_vips",
+		),
+		healthCheckFailures: map[string]int{},
+	}
+	FailoverVipManager.SetVirtualObject(FailoverVipManager)
+}
+
+type SFailoverVip struct {
+	db.SVirtualResourceBase
+	SNetworkResourceBase `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+
+	// VIP地址
+	IpAddr string `width:"16" charset:"ascii" nullable:"false" list:"user" create:"optional"`
+
+	// 当前绑定VIP的主虚机
+	ActiveGuestId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	// 备虚机
+	StandbyGuestId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+
+	// 是否根据健康检查自动切换
+	AutoFailover bool `nullable:"false" default:"false" list:"user" create:"optional" update:"user"`
+	// 健康检查类型
+	HealthCheckType string `width:"16" charset:"ascii" nullable:"false" default:"guest_status" list:"user" create:"optional" update:"user"`
+	// tcp健康检查端口
+	HealthCheckPort int `nullable:"false" default:"0" list:"user" create:"optional" update:"user"`
+	// 连续失败多少次后切换
+	HealthCheckFailures int `nullable:"false" default:"3" list:"user" create:"optional" update:"user"`
+
+	// 最近一次切换时间
+	LastFailoverAt time.Time `nullable:"true" list:"user"`
+}
+
+func validateFailoverVipHealthCheck(checkType string, port int, failures int) error {
+	if !api.FAILOVER_VIP_HEALTH_CHECK_TYPES.Has(checkType) {
+		return httperrors.NewInputParameterError("invalid health_check_type %q, expect %s", checkType, api.FAILOVER_VIP_HEALTH_CHECK_TYPES)
+	}
+	if checkType == api.FAILOVER_VIP_HEALTH_CHECK_TCP && (port <= 0 || port > 65535) {
+		return httperrors.NewInputParameterError("invalid health_check_port %d for tcp health check", port)
+	}
+	if failures <= 0 {
+		return httperrors.NewInputParameterError("health_check_failures should be positive")
+	}
+	return nil
+}
+
+func (manager *SFailoverVipManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.FailoverVipCreateInput) (api.FailoverVipCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if len(input.NetworkId) == 0 {
+		return input, httperrors.NewMissingParameterError("network_id")
+	}
+	network, nInput, err := ValidateNetworkResourceInput(userCred, input.NetworkResourceInput)
+	if err != nil {
+		return input, err
+	}
+	input.NetworkResourceInput = nInput
+
+	if len(input.HealthCheckType) == 0 {
+		input.HealthCheckType = api.FAILOVER_VIP_HEALTH_CHECK_GUEST_STATUS
+	}
+	port, failures := 0, FAILOVER_VIP_DEFAULT_HEALTH_CHECK_FAILURES
+	if input.HealthCheckPort != nil {
+		port = *input.HealthCheckPort
+	}
+	if input.HealthCheckFailures != nil {
+		failures = *input.HealthCheckFailures
+	}
+	if err := validateFailoverVipHealthCheck(input.HealthCheckType, port, failures); err != nil {
+		return input, err
+	}
+
+	tryReserved := len(input.IpAddr) > 0
+	input.IpAddr, err = network.GetFreeIPWithLock(ctx, userCred, nil, nil, input.IpAddr, "", tryReserved)
+	if err != nil {
+		return input, httperrors.NewInputParameterError("allocate ip addr: %v", err)
+	}
+	return input, nil
+}
+
+func (vip *SFailoverVip) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	vip.SVirtualResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	vip.SetStatus(userCred, api.FAILOVER_VIP_STATUS_AVAILABLE, "")
+}
+
+func (vip *SFailoverVip) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.FailoverVipUpdateInput) (api.FailoverVipUpdateInput, error) {
+	var err error
+	input.VirtualResourceBaseUpdateInput, err = vip.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	checkType, port, failures := vip.HealthCheckType, vip.HealthCheckPort, vip.HealthCheckFailures
+	if len(input.HealthCheckType) > 0 {
+		checkType = input.HealthCheckType
+	}
+	if input.HealthCheckPort != nil {
+		port = *input.HealthCheckPort
+	}
+	if input.HealthCheckFailures != nil {
+		failures = *input.HealthCheckFailures
+	}
+	if err := validateFailoverVipHealthCheck(checkType, port, failures); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (vip *SFailoverVip) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if len(vip.ActiveGuestId) > 0 {
+		return httperrors.NewResourceBusyError("failover vip is attached to guest %s, detach it first", vip.ActiveGuestId)
+	}
+	return vip.SVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (manager *SFailoverVipManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FailoverVipListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SNetworkResourceBaseManager.ListItemFilter(ctx, q, userCred, query.NetworkFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SNetworkResourceBaseManager.ListItemFilter")
+	}
+	if len(query.IpAddr) > 0 {
+		q = q.In("ip_addr", query.IpAddr)
+	}
+	if len(query.GuestId) > 0 {
+		guest, err := GuestManager.FetchByIdOrName(userCred, query.GuestId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), query.GuestId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		q = q.Filter(sqlchemy.OR(
+			sqlchemy.Equals(q.Field("active_guest_id"), guest.GetId()),
+			sqlchemy.Equals(q.Field("standby_guest_id"), guest.GetId()),
+		))
+	}
+	return q, nil
+}
+
+func (manager *SFailoverVipManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FailoverVipListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SNetworkResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.NetworkFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SNetworkResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SFailoverVipManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SNetworkResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SFailoverVipManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.FailoverVipDetails {
+	rows := make([]api.FailoverVipDetails, len(objs))
+
+	virtRows := manager.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	netRows := manager.SNetworkResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	guestIds := []string{}
+	for i := range objs {
+		vip := objs[i].(*SFailoverVip)
+		for _, id := range []string{vip.ActiveGuestId, vip.StandbyGuestId} {
+			if len(id) > 0 {
+				guestIds = append(guestIds, id)
+			}
+		}
+	}
+	guestNames, err := db.FetchIdNameMap2(GuestManager, guestIds)
+	if err != nil {
+		log.Errorf("FetchIdNameMap2 guests: %v", err)
+	}
+
+	for i := range rows {
+		vip := objs[i].(*SFailoverVip)
+		rows[i] = api.FailoverVipDetails{
+			VirtualResourceDetails: virtRows[i],
+			NetworkResourceInfo:    netRows[i],
+			ActiveGuest:            guestNames[vip.ActiveGuestId],
+			StandbyGuest:           guestNames[vip.StandbyGuestId],
+		}
+	}
+	return rows
+}
+
+func (manager *SFailoverVipManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	return db.ApplyListItemExportKeys(ctx, q, userCred, keys,
+		&manager.SVirtualResourceBaseManager,
+		&manager.SNetworkResourceBaseManager,
+	)
+}
+
+// 已绑定的VIP由 networkaddress 占用地址, 这里只统计未绑定的VIP
+func (manager *SFailoverVipManager) usedAddressQuery(args *usedAddressQueryArgs) *sqlchemy.SQuery {
+	var (
+		naq  = NetworkAddressManager.Query("ip_addr").Equals("network_id", args.network.Id).SubQuery()
+		q    = manager.Query().Equals("network_id", args.network.Id)
+		retq *sqlchemy.SQuery
+	)
+	q = q.Filter(sqlchemy.NotIn(q.Field("ip_addr"), naq))
+	baseq := q.SubQuery()
+	if args.addrOnly {
+		retq = baseq.Query(
+			baseq.Field("ip_addr"),
+		)
+	} else {
+		ownerq := manager.FilterByOwner(manager.Query().Equals("network_id", args.network.Id), args.owner, args.scope).SubQuery()
+		retq = baseq.Query(
+			baseq.Field("ip_addr"),
+			sqlchemy.NewStringField("").Label("mac_addr"),
+			sqlchemy.NewStringField(manager.KeywordPlural()).Label("owner_type"),
+			ownerq.Field("id").Label("owner_id"),
+			ownerq.Field("status").Label("owner_status"),
+			ownerq.Field("name").Label("owner"),
+			sqlchemy.NewStringField("").Label("associate_id"),
+			sqlchemy.NewStringField("").Label("associate_type"),
+			baseq.Field("created_at"),
+		).LeftJoin(
+			ownerq,
+			sqlchemy.Equals(
+				baseq.Field("id"),
+				ownerq.Field("id"),
+			),
+		)
+	}
+	return retq
+}
+
+func (vip *SFailoverVip) getNetworkAddress() (*SNetworkAddress, error) {
+	q := NetworkAddressManager.Query().
+		Equals("network_id", vip.NetworkId).
+		Equals("ip_addr", vip.IpAddr).
+		Equals("parent_type", api.NetworkAddressParentTypeGuestnetwork)
+	na := &SNetworkAddress{}
+	na.SetModelManager(NetworkAddressManager, na)
+	err := q.First(na)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "fetch network address")
+	}
+	return na, nil
+}
+
+func (vip *SFailoverVip) getGuestnetwork(guest *SGuest) (*SGuestnetwork, error) {
+	gns, err := guest.GetNetworks(vip.NetworkId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetNetworks of guest %s", guest.Name)
+	}
+	if len(gns) == 0 {
+		return nil, httperrors.NewInputParameterError("guest %s has no nic in network %s", guest.Name, vip.NetworkId)
+	}
+	return &gns[0], nil
+}
+
+func (vip *SFailoverVip) fetchGuest(userCred mcclient.TokenCredential, guestId string) (*SGuest, error) {
+	obj, err := GuestManager.FetchByIdOrName(userCred, guestId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), guestId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	guest := obj.(*SGuest)
+	if _, err := vip.getGuestnetwork(guest); err != nil {
+		return nil, err
+	}
+	return guest, nil
+}
+
+// 通知地址变更: 私有云同步虚机配置刷新端口地址, 公有云调用辅助IP接口
+func (vip *SFailoverVip) syncGuestAddress(ctx context.Context, userCred mcclient.TokenCredential, na *SNetworkAddress, guest *SGuest, assign bool) error {
+	if len(guest.ExternalId) > 0 {
+		if assign {
+			return na.remoteAssignAddress(ctx, userCred)
+		}
+		return na.remoteUnassignAddress(ctx, userCred)
+	}
+	return guest.StartSyncTaskWithoutSyncstatus(ctx, userCred, false, "")
+}
+
+// 将VIP迁移到目标虚机网卡, 地址记录原地更新, 迁移过程中地址不会被其他资源分配
+func (vip *SFailoverVip) moveTo(ctx context.Context, userCred mcclient.TokenCredential, target *SGuest) error {
+	gn, err := vip.getGuestnetwork(target)
+	if err != nil {
+		return err
+	}
+	network, err := vip.GetNetwork()
+	if err != nil {
+		return errors.Wrap(err, "GetNetwork")
+	}
+	lockman.LockObject(ctx, network)
+	defer lockman.ReleaseObject(ctx, network)
+
+	na, err := vip.getNetworkAddress()
+	if err != nil {
+		return err
+	}
+	var source *SGuest
+	if na != nil {
+		source, _ = na.getGuest(ctx, userCred)
+		if source != nil && len(source.ExternalId) > 0 {
+			if err := vip.syncGuestAddress(ctx, userCred, na, source, false); err != nil {
+				return errors.Wrapf(err, "unassign address from guest %s", source.Name)
+			}
+		}
+		_, err = db.Update(na, func() error {
+			na.ParentId = strconv.FormatInt(gn.RowId, 10)
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "update network address")
+		}
+	} else {
+		na = &SNetworkAddress{}
+		na.SetModelManager(NetworkAddressManager, na)
+		na.ParentType = api.NetworkAddressParentTypeGuestnetwork
+		na.ParentId = strconv.FormatInt(gn.RowId, 10)
+		na.Type = api.NetworkAddressTypeSubIP
+		na.NetworkId = vip.NetworkId
+		na.IpAddr = vip.IpAddr
+		if err := NetworkAddressManager.TableSpec().Insert(ctx, na); err != nil {
+			return errors.Wrap(err, "insert network address")
+		}
+	}
+
+	if err := vip.syncGuestAddress(ctx, userCred, na, target, true); err != nil {
+		return errors.Wrapf(err, "assign address to guest %s", target.Name)
+	}
+	if source != nil && source.Id != target.Id && len(source.ExternalId) == 0 {
+		// 原主虚机可能已不可达, 同步失败不影响切换
+		if err := vip.syncGuestAddress(ctx, userCred, na, source, false); err != nil {
+			log.Warningf("sync guest %s after vip %s moved: %v", source.Name, vip.IpAddr, err)
+		}
+	}
+	return nil
+}
+
+func (vip *SFailoverVip) release(ctx context.Context, userCred mcclient.TokenCredential) error {
+	na, err := vip.getNetworkAddress()
+	if err != nil {
+		return err
+	}
+	if na == nil {
+		return nil
+	}
+	if err := na.remoteUnassignAddress(ctx, userCred); err != nil {
+		return errors.Wrap(err, "unassign address")
+	}
+	return db.DeleteModel(ctx, userCred, na)
+}
+
+func (vip *SFailoverVip) PerformAttach(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.FailoverVipAttachInput) (jsonutils.JSONObject, error) {
+	if len(vip.ActiveGuestId) > 0 {
+		return nil, httperrors.NewInvalidStatusError("failover vip is already attached to guest %s", vip.ActiveGuestId)
+	}
+	if len(input.ActiveGuestId) == 0 {
+		return nil, httperrors.NewMissingParameterError("active_guest_id")
+	}
+	if len(input.StandbyGuestId) == 0 {
+		return nil, httperrors.NewMissingParameterError("standby_guest_id")
+	}
+	active, err := vip.fetchGuest(userCred, input.ActiveGuestId)
+	if err != nil {
+		return nil, err
+	}
+	standby, err := vip.fetchGuest(userCred, input.StandbyGuestId)
+	if err != nil {
+		return nil, err
+	}
+	if active.Id == standby.Id {
+		return nil, httperrors.NewInputParameterError("active and standby guest should be different")
+	}
+	if (len(active.ExternalId) > 0) != (len(standby.ExternalId) > 0) {
+		return nil, httperrors.NewInputParameterError("active and standby guest should be both on-premise or both managed")
+	}
+
+	if err := vip.moveTo(ctx, userCred, active); err != nil {
+		logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_ATTACH, err, userCred, false)
+		return nil, httperrors.NewGeneralError(err)
+	}
+	_, err = db.Update(vip, func() error {
+		vip.ActiveGuestId = active.Id
+		vip.StandbyGuestId = standby.Id
+		vip.Status = api.FAILOVER_VIP_STATUS_ATTACHED
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update failover vip")
+	}
+	FailoverVipManager.resetHealthCheckFailures(vip.Id)
+	db.OpsLog.LogEvent(vip, db.ACT_ATTACH, input, userCred)
+	logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_ATTACH, input, userCred, true)
+	return nil, nil
+}
+
+func (vip *SFailoverVip) PerformDetach(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if len(vip.ActiveGuestId) == 0 {
+		return nil, nil
+	}
+	if err := vip.release(ctx, userCred); err != nil {
+		logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_DETACH, err, userCred, false)
+		return nil, httperrors.NewGeneralError(err)
+	}
+	_, err := db.Update(vip, func() error {
+		vip.ActiveGuestId = ""
+		vip.StandbyGuestId = ""
+		vip.Status = api.FAILOVER_VIP_STATUS_AVAILABLE
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update failover vip")
+	}
+	FailoverVipManager.resetHealthCheckFailures(vip.Id)
+	db.OpsLog.LogEvent(vip, db.ACT_DETACH, nil, userCred)
+	logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_DETACH, nil, userCred, true)
+	return nil, nil
+}
+
+func (vip *SFailoverVip) PerformFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.FailoverVipFailoverInput) (jsonutils.JSONObject, error) {
+	if len(vip.ActiveGuestId) == 0 || len(vip.StandbyGuestId) == 0 {
+		return nil, httperrors.NewInvalidStatusError("failover vip is not attached")
+	}
+	standby, err := vip.fetchGuest(userCred, vip.StandbyGuestId)
+	if err != nil {
+		return nil, err
+	}
+	if !input.Force && !isFailoverVipGuestHealthy(standby) {
+		return nil, httperrors.NewInvalidStatusError("standby guest %s is %s", standby.Name, standby.Status)
+	}
+	if err := vip.failover(ctx, userCred, standby, "manual"); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+func (vip *SFailoverVip) failover(ctx context.Context, userCred mcclient.TokenCredential, standby *SGuest, reason string) error {
+	lockman.LockObject(ctx, vip)
+	defer lockman.ReleaseObject(ctx, vip)
+
+	notes := jsonutils.NewDict()
+	notes.Set("from", jsonutils.NewString(vip.ActiveGuestId))
+	notes.Set("to", jsonutils.NewString(standby.Id))
+	notes.Set("reason", jsonutils.NewString(reason))
+
+	vip.SetStatus(userCred, api.FAILOVER_VIP_STATUS_FAILOVER, reason)
+	if err := vip.moveTo(ctx, userCred, standby); err != nil {
+		vip.SetStatus(userCred, api.FAILOVER_VIP_STATUS_FAILOVER_FAILED, err.Error())
+		logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_FAILOVER, err, userCred, false)
+		return err
+	}
+	_, err := db.Update(vip, func() error {
+		vip.StandbyGuestId = vip.ActiveGuestId
+		vip.ActiveGuestId = standby.Id
+		vip.LastFailoverAt = time.Now().UTC()
+		vip.Status = api.FAILOVER_VIP_STATUS_ATTACHED
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update failover vip")
+	}
+	FailoverVipManager.resetHealthCheckFailures(vip.Id)
+	db.OpsLog.LogEvent(vip, db.ACT_UPDATE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, vip, logclient.ACT_VIP_FAILOVER, notes, userCred, true)
+	return nil
+}
+
+func isFailoverVipGuestHealthy(guest *SGuest) bool {
+	if guest.Status != api.VM_RUNNING {
+		return false
+	}
+	if len(guest.ExternalId) > 0 {
+		return true
+	}
+	host, err := guest.GetHost()
+	if err != nil {
+		return false
+	}
+	return host.HostStatus == api.HOST_ONLINE
+}
+
+func (vip *SFailoverVip) checkActiveGuest(ctx context.Context, userCred mcclient.TokenCredential) error {
+	obj, err := GuestManager.FetchById(vip.ActiveGuestId)
+	if err != nil {
+		return errors.Wrapf(err, "fetch active guest %s", vip.ActiveGuestId)
+	}
+	guest := obj.(*SGuest)
+	if !isFailoverVipGuestHealthy(guest) {
+		return errors.Errorf("active guest %s is %s", guest.Name, guest.Status)
+	}
+	if vip.HealthCheckType != api.FAILOVER_VIP_HEALTH_CHECK_TCP {
+		return nil
+	}
+	gn, err := vip.getGuestnetwork(guest)
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(gn.IpAddr, fmt.Sprintf("%d", vip.HealthCheckPort))
+	conn, err := net.DialTimeout("tcp", addr, FAILOVER_VIP_TCP_CHECK_TIMEOUT)
+	if err != nil {
+		return errors.Wrapf(err, "dial %s", addr)
+	}
+	conn.Close()
+	return nil
+}
+
+func (manager *SFailoverVipManager) resetHealthCheckFailures(id string) {
+	manager.healthCheckLock.Lock()
+	defer manager.healthCheckLock.Unlock()
+	delete(manager.healthCheckFailures, id)
+}
+
+func (manager *SFailoverVipManager) incHealthCheckFailures(id string) int {
+	manager.healthCheckLock.Lock()
+	defer manager.healthCheckLock.Unlock()
+	manager.healthCheckFailures[id] += 1
+	return manager.healthCheckFailures[id]
+}
+
+// 定时检查开启自动切换的VIP, 主虚机连续检查失败且备虚机正常时切换
+func (manager *SFailoverVipManager) CheckHealth(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsTrue("auto_failover").Equals("status", api.FAILOVER_VIP_STATUS_ATTACHED)
+	vips := []SFailoverVip{}
+	if err := db.FetchModelObjects(manager, q, &vips); err != nil {
+		log.Errorf("fetch failover vips: %v", err)
+		return
+	}
+	for i := range vips {
+		vip := &vips[i]
+		err := vip.checkActiveGuest(ctx, userCred)
+		if err == nil {
+			manager.resetHealthCheckFailures(vip.Id)
+			continue
+		}
+		failures := manager.incHealthCheckFailures(vip.Id)
+		log.Warningf("failover vip %s(%s) health check failed %d/%d: %v", vip.Name, vip.IpAddr, failures, vip.HealthCheckFailures, err)
+		if failures < vip.HealthCheckFailures {
+			continue
+		}
+		obj, err := GuestManager.FetchById(vip.StandbyGuestId)
+		if err != nil {
+			log.Errorf("fetch standby guest %s of failover vip %s: %v", vip.StandbyGuestId, vip.Name, err)
+			continue
+		}
+		standby := obj.(*SGuest)
+		if !isFailoverVipGuestHealthy(standby) {
+			log.Errorf("standby guest %s of failover vip %s is %s, skip failover", standby.Name, vip.Name, standby.Status)
+			continue
+		}
+		if err := vip.failover(ctx, auth.AdminCredential(), standby, "health check failed"); err != nil {
+			log.Errorf("failover vip %s to %s: %v", vip.Name, standby.Name, err)
+		}
+	}
+}
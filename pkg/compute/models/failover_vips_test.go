@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestValidateFailoverVipHealthCheck(t *testing.T) {
+	cases := []struct {
+		checkType string
+		port      int
+		failures  int
+		valid     bool
+	}{
+		{api.FAILOVER_VIP_HEALTH_CHECK_GUEST_STATUS, 0, 3, true},
+		{api.FAILOVER_VIP_HEALTH_CHECK_TCP, 80, 3, true},
+		{api.FAILOVER_VIP_HEALTH_CHECK_TCP, 0, 3, false},
+		{api.FAILOVER_VIP_HEALTH_CHECK_TCP, 65536, 3, false},
+		{api.FAILOVER_VIP_HEALTH_CHECK_GUEST_STATUS, 0, 0, false},
+		{"http", 80, 3, false},
+	}
+	for _, c := range cases {
+		err := validateFailoverVipHealthCheck(c.checkType, c.port, c.failures)
+		if (err == nil) != c.valid {
+			t.Errorf("type %s port %d failures %d valid %v got %v", c.checkType, c.port, c.failures, c.valid, err)
+		}
+	}
+}
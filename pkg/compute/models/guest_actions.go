@@ -88,6 +88,9 @@ func (self *SGuest) GetDetailsVnc(ctx context.Context, userCred mcclient.TokenCr
 	return ret, nil
 }
 
+// 只读云账号下的虚拟机仅允许不变更云上资源的操作
+var guestReadOnlyAllowedActions = []string{"syncstatus", "sync", "purge", "freeze", "unfreeze"}
+
 func (self *SGuest) PreCheckPerformAction(
 	ctx context.Context, userCred mcclient.TokenCredential,
 	action string, query jsonutils.JSONObject, data jsonutils.JSONObject,
@@ -95,8 +98,15 @@ func (self *SGuest) PreCheckPerformAction(
 	if err := self.SVirtualResourceBase.PreCheckPerformAction(ctx, userCred, action, query, data); err != nil {
 		return err
 	}
+	host, _ := self.GetHost()
+	if host != nil && !utils.IsInStringArray(action, guestReadOnlyAllowedActions) {
+		if provider := host.GetCloudprovider(); provider != nil {
+			if err := provider.ValidateWritable(); err != nil {
+				return err
+			}
+		}
+	}
 	if self.Hypervisor == api.HYPERVISOR_KVM {
-		host, _ := self.GetHost()
 		if host != nil && (host.HostStatus == api.HOST_OFFLINE || !host.Enabled.Bool()) &&
 			utils.IsInStringArray(action,
 				[]string{
@@ -147,6 +157,8 @@ func (self *SGuest) PerformEvent(ctx context.Context, userCred mcclient.TokenCre
 			notify.NotifyPriorityNormal,
 			false, kwargs, true,
 		)
+	} else if event == "WATCHDOG" {
+		self.onWatchdogEvent(ctx, userCred, data)
 	}
 	return nil, nil
 }
@@ -557,12 +569,16 @@ func (self *SGuest) PerformLiveMigrate(ctx context.Context, userCred mcclient.To
 	if err := self.validateMigrate(ctx, userCred, nil, input); err != nil {
 		return nil, err
 	}
+	if err := input.LiveMigrateTuningInput.Validate(); err != nil {
+		return nil, err
+	}
 	if input.EnableTLS == nil {
 		input.EnableTLS = &options.Options.EnableTlsMigration
 	}
 	return nil, self.StartGuestLiveMigrateTask(ctx, userCred,
 		self.Status, input.PreferHost, input.SkipCpuCheck,
-		input.SkipKernelCheck, input.EnableTLS, input.QuicklyFinish, input.MaxBandwidthMb, input.KeepDestGuestOnFailed, "",
+		input.SkipKernelCheck, input.EnableTLS, input.QuicklyFinish, input.MaxBandwidthMb, input.KeepDestGuestOnFailed,
+		&input.LiveMigrateTuningInput, "",
 	)
 }
 
@@ -570,7 +586,8 @@ func (self *SGuest) StartGuestLiveMigrateTask(
 	ctx context.Context, userCred mcclient.TokenCredential,
 	guestStatus, preferHostId string,
 	skipCpuCheck, skipKernelCheck, enableTLS, quicklyFinish *bool,
-	maxBandwidthMb *int64, keepDestGuestOnFailed *bool, parentTaskId string,
+	maxBandwidthMb *int64, keepDestGuestOnFailed *bool,
+	tuning *api.LiveMigrateTuningInput, parentTaskId string,
 ) error {
 	self.SetStatus(userCred, api.VM_START_MIGRATE, "")
 	data := jsonutils.NewDict()
@@ -595,6 +612,9 @@ func (self *SGuest) StartGuestLiveMigrateTask(
 	if keepDestGuestOnFailed != nil {
 		data.Set("keep_dest_guest_on_failed", jsonutils.NewBool(*keepDestGuestOnFailed))
 	}
+	if tuning != nil && !tuning.IsEmpty() {
+		data.Set("live_migrate_tuning", jsonutils.Marshal(tuning))
+	}
 
 	data.Set("guest_status", jsonutils.NewString(guestStatus))
 	dedicateMigrateTask := "GuestLiveMigrateTask"
@@ -738,7 +758,10 @@ func (self *SGuest) PerformSetPassword(ctx context.Context, userCred mcclient.To
 			}
 		}
 		if inputQga.Password == "" && input.ResetPassword {
-			inputQga.Password = seclib2.RandomPassword2(12)
+			inputQga.Password = self.generatePassword()
+			if inputQga.Password == "" {
+				inputQga.Password = seclib2.RandomPassword2(12)
+			}
 		}
 		return self.PerformQgaSetPassword(ctx, userCred, query, inputQga)
 	} else {
@@ -808,7 +831,7 @@ func (self *SGuest) PerformDeploy(ctx context.Context, userCred mcclient.TokenCr
 	}
 
 	if len(input.Password) > 0 {
-		err := seclib2.ValidatePassword(input.Password)
+		err := self.validatePassword(input.Password)
 		if err != nil {
 			return nil, err
 		}
@@ -1580,7 +1603,7 @@ func (self *SGuest) PerformRebuildRoot(ctx context.Context, userCred mcclient.To
 	}
 	passwd := input.Password
 	if len(passwd) > 0 {
-		err = seclib2.ValidatePassword(passwd)
+		err = self.validatePassword(passwd)
 		if err != nil {
 			return nil, err
 		}
@@ -2430,6 +2453,58 @@ func (self *SGuest) PerformChangeBandwidth(ctx context.Context, userCred mcclien
 	return nil, nil
 }
 
+// 设置网卡入/出方向限速，运行中的KVM虚机直接在宿主机上生效，无需重启
+func (self *SGuest) PerformSetNicBandwidth(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetNicBandwidthInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewBadRequestError("Cannot set nic bandwidth in status %s", self.Status)
+	}
+	if self.GetHypervisor() != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewUnsupportOperationError("Not support set nic bandwidth for hypervisor %s", self.GetHypervisor())
+	}
+	if input.BwIngress == nil && input.BwEgress == nil {
+		return nil, httperrors.NewMissingParameterError("bw_ingress or bw_egress")
+	}
+	for _, bw := range []*int{input.BwIngress, input.BwEgress} {
+		if bw != nil && (*bw < 0 || *bw > api.MAX_BANDWIDTH) {
+			return nil, httperrors.NewInputParameterError("bandwidth must be in range 0-%d", api.MAX_BANDWIDTH)
+		}
+	}
+	index := int64(-1)
+	if input.Index != nil {
+		index = *input.Index
+	}
+	gn, err := self.findGuestnetworkByInfo(input.IpAddr, input.Mac, index)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := db.Update(gn, func() error {
+		if input.BwIngress != nil {
+			gn.IngressBwLimit = *input.BwIngress
+		}
+		if input.BwEgress != nil {
+			gn.EgressBwLimit = *input.BwEgress
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update guestnetwork")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_CHANGE_BANDWIDTH, diff, userCred)
+	if self.Status == api.VM_RUNNING {
+		host, err := self.GetHost()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetHost")
+		}
+		err = self.GetDriver().RequestSetNicBandwidth(ctx, userCred, host, self, gn)
+		if err != nil {
+			logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_CHANGE_BANDWIDTH, err, userCred, false)
+			return nil, errors.Wrap(err, "RequestSetNicBandwidth")
+		}
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_CHANGE_BANDWIDTH, diff, userCred, true)
+	return nil, nil
+}
+
 func (self *SGuest) PerformModifySrcCheck(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
 		return nil, httperrors.NewBadRequestError("Cannot change setting in status %s", self.Status)
@@ -3396,6 +3471,30 @@ func (self *SGuest) PerformBlockMirrorReady(ctx context.Context, userCred mcclie
 	return nil, nil
 }
 
+// 在线迁移磁盘存储时, host 定期上报 drive-mirror 的同步进度
+func (self *SGuest) PerformBlockMirrorProgress(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerBlockMirrorProgressInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.VM_DISK_CHANGE_STORAGE {
+		return nil, nil
+	}
+	disk := DiskManager.FetchDiskById(input.DiskId)
+	if disk == nil {
+		return nil, httperrors.NewNotFoundError("disk %s not found", input.DiskId)
+	}
+	progress := input.Progress
+	if progress > 100 {
+		progress = 100
+	}
+	self.SetProgress(progress)
+	taskId := disk.GetMetadata(ctx, api.DISK_CLONE_TASK_ID, userCred)
+	if task := taskman.TaskManager.FetchTaskById(taskId); task != nil {
+		targetDiskId, _ := task.GetParams().GetString("target_disk_id")
+		if targetDisk := DiskManager.FetchDiskById(targetDiskId); targetDisk != nil {
+			targetDisk.SetProgress(progress)
+		}
+	}
+	return nil, nil
+}
+
 func (self *SGuest) StartMirrorJob(ctx context.Context, userCred mcclient.TokenCredential, nbdServerPort int64, parentTaskId string) error {
 	taskData := jsonutils.NewDict()
 	taskData.Set("nbd_server_port", jsonutils.NewInt(nbdServerPort))
@@ -4062,6 +4161,30 @@ func (self *SGuest) importDisks(ctx context.Context, userCred mcclient.TokenCred
 	return nil
 }
 
+func validateImportServersMacIp(servers []api.SLibvirtServerConfig) error {
+	for _, server := range servers {
+		for mac, ip := range server.MacIp {
+			_, err := net.ParseMAC(mac)
+			if err != nil {
+				return httperrors.NewBadRequestError("Invalid server mac address %s", mac)
+			}
+			nIp := net.ParseIP(ip)
+			if nIp == nil {
+				return httperrors.NewBadRequestError("Invalid server ip address %s", ip)
+			}
+			q := GuestnetworkManager.Query()
+			count := q.Filter(sqlchemy.OR(
+				sqlchemy.Equals(q.Field("mac_addr"), mac),
+				sqlchemy.Equals(q.Field("ip_addr"), ip)),
+			).Count()
+			if count > 0 {
+				return httperrors.NewInputParameterError("ip %s or mac %s has been registered", mac, ip)
+			}
+		}
+	}
+	return nil
+}
+
 func (manager *SGuestManager) PerformImportFromLibvirt(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	host := &api.SLibvirtHostConfig{}
 	if err := data.Unmarshal(host); err != nil {
@@ -4078,25 +4201,8 @@ func (manager *SGuestManager) PerformImportFromLibvirt(ctx context.Context, user
 		return nil, httperrors.NewInputParameterError("Invalid host ip %s", host.HostIp)
 	}
 
-	for _, server := range host.Servers {
-		for mac, ip := range server.MacIp {
-			_, err = net.ParseMAC(mac)
-			if err != nil {
-				return nil, httperrors.NewBadRequestError("Invalid server mac address %s", mac)
-			}
-			nIp := net.ParseIP(ip)
-			if nIp == nil {
-				return nil, httperrors.NewBadRequestError("Invalid server ip address %s", ip)
-			}
-			q := GuestnetworkManager.Query()
-			count := q.Filter(sqlchemy.OR(
-				sqlchemy.Equals(q.Field("mac_addr"), mac),
-				sqlchemy.Equals(q.Field("ip_addr"), ip)),
-			).Count()
-			if count > 0 {
-				return nil, httperrors.NewInputParameterError("ip %s or mac %s has been registered", mac, ip)
-			}
-		}
+	if err := validateImportServersMacIp(host.Servers); err != nil {
+		return nil, err
 	}
 
 	taskData := jsonutils.NewDict()
@@ -4112,6 +4218,18 @@ func (manager *SGuestManager) PerformImportFromLibvirt(ctx context.Context, user
 	return nil, nil
 }
 
+// 导出虚机的 libvirt domain xml
+func (self *SGuest) GetDetailsLibvirtXml(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerLibvirtXmlOutput, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewBadRequestError("Hypervisor %s can't export libvirt xml", self.Hypervisor)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	return self.GetDriver().RequestLibvirtXml(ctx, userCred, host, self)
+}
+
 func (self *SGuest) GetDetailsVirtInstall(
 	ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject,
 ) (jsonutils.JSONObject, error) {
@@ -4478,7 +4596,7 @@ func (guest *SGuest) PerformResizeDisk(ctx context.Context, userCred mcclient.To
 	if err != nil {
 		return nil, err
 	}
-	err = disk.doResize(ctx, userCred, sizeMb, guest)
+	err = disk.doResize(ctx, userCred, sizeMb, guest, "")
 	if err != nil {
 		return nil, err
 	}
@@ -4498,34 +4616,98 @@ func (guest *SGuest) StartGuestDiskResizeTask(ctx context.Context, userCred mccl
 	return nil
 }
 
-func (self *SGuest) PerformIoThrottle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+func (self *SGuest) PerformIoThrottle(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerIoThrottleInput) (jsonutils.JSONObject, error) {
 	if self.Hypervisor != api.HYPERVISOR_KVM {
 		return nil, httperrors.NewBadRequestError("Hypervisor %s can't do io throttle", self.Hypervisor)
 	}
-	if self.Status != api.VM_RUNNING {
+	if !utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_READY}) {
 		return nil, httperrors.NewServerStatusError("Cannot do io throttle in status %s", self.Status)
 	}
-	bpsMb, err := data.Int("bps")
+	throttles, err := self.getDiskIoThrottles(input)
+	if err != nil {
+		return nil, err
+	}
+	if self.Status == api.VM_READY {
+		// 关机状态仅保存配置, 下次启动时通过 -drive throttling.* 参数生效
+		return nil, self.SetDiskIoThrottles(ctx, userCred, throttles)
+	}
+	if input.Bps != nil && input.Iops != nil && len(input.Disks) == 0 {
+		self.SetMetadata(ctx, "bps", *input.Bps, userCred)
+		self.SetMetadata(ctx, "iops", *input.Iops, userCred)
+	}
+	return nil, self.StartBlockIoThrottleTask(ctx, userCred, throttles)
+}
+
+// 返回 disk_id => throttle
+func (self *SGuest) getDiskIoThrottles(input api.ServerIoThrottleInput) (map[string]api.ServerDiskIoThrottle, error) {
+	gds, err := self.GetGuestDisks()
 	if err != nil {
-		return nil, httperrors.NewMissingParameterError("bps")
+		return nil, errors.Wrap(err, "GetGuestDisks")
+	}
+	ret := map[string]api.ServerDiskIoThrottle{}
+	if len(input.Disks) == 0 {
+		if input.Bps == nil {
+			return nil, httperrors.NewMissingParameterError("bps")
+		}
+		if input.Iops == nil {
+			return nil, httperrors.NewMissingParameterError("iops")
+		}
+		for i := range gds {
+			ret[gds[i].DiskId] = api.ServerDiskIoThrottle{Bps: *input.Bps, Iops: *input.Iops}
+		}
+	}
+	for key, throttle := range input.Disks {
+		found := false
+		for i := range gds {
+			disk := gds[i].GetDisk()
+			if gds[i].DiskId == key || (disk != nil && disk.Name == key) {
+				ret[gds[i].DiskId] = throttle
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, httperrors.NewResourceNotFoundError2("disk", key)
+		}
 	}
-	if bpsMb < 0 {
-		return nil, httperrors.NewInputParameterError("bps must > 0")
+	for diskId, throttle := range ret {
+		if throttle.Bps < 0 {
+			return nil, httperrors.NewInputParameterError("disk %s bps must >= 0", diskId)
+		}
+		if throttle.Iops < 0 {
+			return nil, httperrors.NewInputParameterError("disk %s iops must >= 0", diskId)
+		}
 	}
-	iops, err := data.Int("iops")
+	return ret, nil
+}
+
+func (self *SGuest) SetDiskIoThrottles(ctx context.Context, userCred mcclient.TokenCredential, throttles map[string]api.ServerDiskIoThrottle) error {
+	gds, err := self.GetGuestDisks()
 	if err != nil {
-		return nil, httperrors.NewMissingParameterError("iops")
+		return errors.Wrap(err, "GetGuestDisks")
 	}
-	if iops < 0 {
-		return nil, httperrors.NewInputParameterError("iops must > 0")
+	for i := 0; i < len(gds); i++ {
+		throttle, ok := throttles[gds[i].DiskId]
+		if !ok {
+			continue
+		}
+		_, err := db.Update(&gds[i], func() error {
+			gds[i].Iops = throttle.Iops
+			gds[i].Bps = throttle.Bps
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "update guestdisk %s", gds[i].DiskId)
+		}
 	}
-	return nil, self.StartBlockIoThrottleTask(ctx, userCred, bpsMb, iops)
+	db.OpsLog.LogEvent(self, db.ACT_VM_IO_THROTTLE, throttles, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_IO_THROTTLE, throttles, userCred, true)
+	return nil
 }
 
-func (self *SGuest) StartBlockIoThrottleTask(ctx context.Context, userCred mcclient.TokenCredential, bpsMb, iops int64) error {
+func (self *SGuest) StartBlockIoThrottleTask(ctx context.Context, userCred mcclient.TokenCredential, throttles map[string]api.ServerDiskIoThrottle) error {
 	params := jsonutils.NewDict()
-	params.Set("bps", jsonutils.NewInt(bpsMb))
-	params.Set("iops", jsonutils.NewInt(iops))
+	params.Set("disks", jsonutils.Marshal(throttles))
 	params.Set("old_status", jsonutils.NewString(self.Status))
 	self.SetStatus(userCred, api.VM_IO_THROTTLE, "start block io throttle task")
 	task, err := taskman.TaskManager.NewTask(ctx, "GuestBlockIoThrottleTask", self, userCred, params, "", "", nil)
@@ -4634,7 +4816,7 @@ func (manager *SGuestManager) PerformBatchMigrate(ctx context.Context, userCred
 			if guests[i].Status == api.VM_RUNNING {
 				err = guests[i].StartGuestLiveMigrateTask(ctx, userCred,
 					guests[i].Status, preferHostId, &params.SkipCpuCheck, &params.SkipKernelCheck,
-					params.EnableTLS, params.QuciklyFinish, params.MaxBandwidthMb, nil, "",
+					params.EnableTLS, params.QuciklyFinish, params.MaxBandwidthMb, nil, nil, "",
 				)
 			} else {
 				err = guests[i].StartMigrateTask(ctx, userCred, guests[i].Status == api.VM_UNKNOWN,
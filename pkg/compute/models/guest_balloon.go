@@ -0,0 +1,52 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 设置宿主机内存不足时虚机可被 virtio-balloon 回收到的内存下限
+func (self *SGuest) PerformSetBalloon(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetBalloonInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.QosClass == api.GUEST_QOS_CLASS_GUARANTEED {
+		return nil, httperrors.NewNotAcceptableError("memory of %s guest is never reclaimed", self.QosClass)
+	}
+	if input.MinMemMb < 0 || input.MinMemMb > self.VmemSize {
+		return nil, httperrors.NewOutOfRangeError("min_mem_mb should be in range [0, %d]", self.VmemSize)
+	}
+	var err error
+	if input.MinMemMb > 0 {
+		err = self.SetMetadata(ctx, api.VM_METADATA_BALLOON_MIN_MEM_MB, fmt.Sprintf("%d", input.MinMemMb), userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_BALLOON_MIN_MEM_MB, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set balloon metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_BALLOON, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
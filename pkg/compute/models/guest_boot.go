@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 设置启动顺序, 本地虚拟机下次启动生效, 云上虚拟机同步至平台
+func (self *SGuest) PerformSetBootOrder(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetBootOrderInput) (jsonutils.JSONObject, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("Can't set boot order when guest is %s", self.Status)
+	}
+	err := self.GetDriver().RequestSetBootOrder(ctx, userCred, self, input.BootOrder)
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_BOOT_ORDER, err, userCred, false)
+		if errors.Cause(err) == cloudprovider.ErrNotSupported {
+			return nil, httperrors.NewNotSupportedError("%s", err.Error())
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	_, err = db.Update(self, func() error {
+		self.BootOrder = input.BootOrder
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update boot order")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_BOOT_ORDER, input, userCred, true)
+	return nil, nil
+}
+
+// 指定下一次启动使用的设备, 启动成功后自动清除, 之后恢复原启动顺序
+func (self *SGuest) PerformBootOnce(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerBootOnceInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("Can't set boot once device when guest is %s", self.Status)
+	}
+	order, err := api.BootDeviceToOrderChar(input.Device)
+	if err != nil {
+		return nil, err
+	}
+	if input.Device == api.BOOT_DEVICE_CDROM {
+		cdroms, err := self.getCdroms()
+		if err != nil {
+			return nil, errors.Wrap(err, "getCdroms")
+		}
+		mounted := false
+		for i := range cdroms {
+			if len(cdroms[i].ImageId) > 0 {
+				mounted = true
+				break
+			}
+		}
+		if !mounted {
+			return nil, httperrors.NewInputParameterError("no iso mounted, can't boot from cdrom")
+		}
+	}
+	err = self.SetMetadata(ctx, api.VM_METADATA_BOOT_ONCE, order, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "set boot once metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_BOOT_ONCE, input, userCred, true)
+	if self.Status == api.VM_RUNNING && input.Restart {
+		return nil, self.GetDriver().StartGuestRestartTask(self, ctx, userCred, false, "")
+	}
+	return nil, nil
+}
+
+// 从网络引导, 宿主机 DHCP 为 PXE 请求下发 iPXE 链式加载地址
+func (self *SGuest) PerformPxeBoot(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerPxeBootInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("Can't set pxe boot when guest is %s", self.Status)
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if !input.Disable {
+		// qemu 中 bootindex 与 -boot order 互斥, 网络引导依赖启动顺序
+		hasBootIndex, err := self.hasBootIndex()
+		if err != nil {
+			return nil, errors.Wrap(err, "hasBootIndex")
+		}
+		if hasBootIndex {
+			return nil, httperrors.NewConflictError("disk or cdrom boot index is set, reset it before pxe boot")
+		}
+	}
+	bootOrder := self.BootOrder
+	if input.Disable {
+		bootOrder = api.PrependBootOrder(bootOrder, "c")
+		self.RemoveMetadata(ctx, api.VM_METADATA_PXE_CHAINLOAD_URL, userCred)
+		self.RemoveMetadata(ctx, api.VM_METADATA_BOOT_ONCE, userCred)
+	} else {
+		err := self.SetMetadata(ctx, api.VM_METADATA_PXE_CHAINLOAD_URL, input.ChainloadUrl, userCred)
+		if err != nil {
+			return nil, errors.Wrap(err, "set pxe chainload url metadata")
+		}
+		if input.Once {
+			err = self.SetMetadata(ctx, api.VM_METADATA_BOOT_ONCE, "n", userCred)
+			if err != nil {
+				return nil, errors.Wrap(err, "set boot once metadata")
+			}
+		} else {
+			bootOrder = api.PrependBootOrder(bootOrder, "n")
+		}
+	}
+	if bootOrder != self.BootOrder {
+		_, err := db.Update(self, func() error {
+			self.BootOrder = bootOrder
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "update boot order")
+		}
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_PXE_BOOT, input, userCred, true)
+	if self.Status == api.VM_RUNNING {
+		if input.Restart {
+			return nil, self.GetDriver().StartGuestRestartTask(self, ctx, userCred, false, "")
+		}
+		// 同步描述信息, 使宿主机 DHCP 获取到最新的链式加载地址
+		return nil, self.StartSyncTask(ctx, userCred, false, "")
+	}
+	return nil, nil
+}
+
+func (self *SGuest) hasBootIndex() (bool, error) {
+	disks, err := self.GetGuestDisks()
+	if err != nil {
+		return false, errors.Wrap(err, "GetGuestDisks")
+	}
+	for i := range disks {
+		if disks[i].BootIndex >= 0 {
+			return true, nil
+		}
+	}
+	cdroms, err := self.getCdroms()
+	if err != nil {
+		return false, errors.Wrap(err, "getCdroms")
+	}
+	for i := range cdroms {
+		if cdroms[i].BootIndex >= 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (self *SGuest) OnBootOnceApplied(ctx context.Context, userCred mcclient.TokenCredential) {
+	if len(self.GetMetadata(ctx, api.VM_METADATA_BOOT_ONCE, nil)) == 0 {
+		return
+	}
+	self.RemoveMetadata(ctx, api.VM_METADATA_BOOT_ONCE, userCred)
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 设置 RTC 基准时间、漂移修正及 HPET, 参数全部为空时恢复默认, 下次启动生效
+// 迁移时目标端使用同样的时钟参数启动, 避免 Windows 虚机迁移后时间漂移
+func (self *SGuest) PerformSetClockPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetClockPolicyInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if len(input.Base) > 0 && !utils.IsInStringArray(input.Base, []string{api.VM_CLOCK_BASE_UTC, api.VM_CLOCK_BASE_LOCALTIME}) {
+		return nil, httperrors.NewInputParameterError("invalid base %s", input.Base)
+	}
+	if len(input.Driftfix) > 0 && !utils.IsInStringArray(input.Driftfix, []string{api.VM_CLOCK_DRIFTFIX_NONE, api.VM_CLOCK_DRIFTFIX_SLEW}) {
+		return nil, httperrors.NewInputParameterError("invalid driftfix %s", input.Driftfix)
+	}
+	if len(input.Hpet) > 0 && !utils.IsInStringArray(input.Hpet, []string{api.VM_CLOCK_HPET_ON, api.VM_CLOCK_HPET_OFF}) {
+		return nil, httperrors.NewInputParameterError("invalid hpet %s", input.Hpet)
+	}
+	var err error
+	if len(input.Base) == 0 && len(input.Driftfix) == 0 && len(input.Hpet) == 0 {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_CLOCK_POLICY, userCred)
+	} else {
+		err = self.SetMetadata(ctx, api.VM_METADATA_CLOCK_POLICY, jsonutils.Marshal(input).String(), userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set clock policy metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_CLOCK_POLICY, input, userCred, true)
+	return nil, nil
+}
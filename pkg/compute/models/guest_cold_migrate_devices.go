@@ -0,0 +1,244 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 直通设备按型号和二层网络匹配, 非网卡设备的 wire 为空
+func isolatedDeviceRequirementKey(model, wireId string) string {
+	return fmt.Sprintf("%s/%s", model, wireId)
+}
+
+func isolatedDeviceRequirements(confs []api.IsolatedDeviceConfig) map[string]int {
+	reqs := map[string]int{}
+	for i := range confs {
+		reqs[isolatedDeviceRequirementKey(confs[i].Model, confs[i].WireId)] += 1
+	}
+	return reqs
+}
+
+// 从空闲设备中挑选能满足全部设备需求的宿主机, 空闲设备越多越优先
+func selectHostsByDeviceAvailability(reqs map[string]int, free []SIsolatedDevice, excludeHostId string) []string {
+	hostFree := map[string]map[string]int{}
+	for i := range free {
+		if free[i].HostId == excludeHostId {
+			continue
+		}
+		if _, ok := hostFree[free[i].HostId]; !ok {
+			hostFree[free[i].HostId] = map[string]int{}
+		}
+		hostFree[free[i].HostId][isolatedDeviceRequirementKey(free[i].Model, free[i].WireId)] += 1
+	}
+	spares := map[string]int{}
+	hostIds := []string{}
+	for hostId, counts := range hostFree {
+		spare, ok := 0, true
+		for key, cnt := range reqs {
+			if counts[key] < cnt {
+				ok = false
+				break
+			}
+			spare += counts[key] - cnt
+		}
+		if ok {
+			hostIds = append(hostIds, hostId)
+			spares[hostId] = spare
+		}
+	}
+	sort.Slice(hostIds, func(i, j int) bool {
+		if spares[hostIds[i]] != spares[hostIds[j]] {
+			return spares[hostIds[i]] > spares[hostIds[j]]
+		}
+		return hostIds[i] < hostIds[j]
+	})
+	return hostIds
+}
+
+func (self *SGuest) getIsolatedDeviceConfigs() ([]api.IsolatedDeviceConfig, []SIsolatedDevice, error) {
+	devs, err := self.GetIsolatedDevices()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetIsolatedDevices")
+	}
+	confs := make([]api.IsolatedDeviceConfig, len(devs))
+	for i := range devs {
+		confs[i] = api.IsolatedDeviceConfig{
+			DevType: devs[i].DevType,
+			Model:   devs[i].Model,
+			Vendor:  devs[i].getVendor(),
+			WireId:  devs[i].WireId,
+		}
+		if devs[i].DevType == api.NIC_TYPE {
+			networkIndex := devs[i].NetworkIndex
+			confs[i].NetworkIndex = &networkIndex
+		}
+	}
+	return confs, devs, nil
+}
+
+// 按设备可用性选择冷迁移目标宿主机, 指定宿主机时校验其空闲设备是否足够
+func (self *SGuest) ChooseColdMigrateHostByDevices(confs []api.IsolatedDeviceConfig, preferHostId string) (string, error) {
+	reqs := isolatedDeviceRequirements(confs)
+	models := []string{}
+	for i := range confs {
+		if !utils.IsInStringArray(confs[i].Model, models) {
+			models = append(models, confs[i].Model)
+		}
+	}
+	free, err := IsolatedDeviceManager.FindUnusedByModels(models)
+	if err != nil {
+		return "", errors.Wrap(err, "FindUnusedByModels")
+	}
+	candidates := selectHostsByDeviceAvailability(reqs, free, self.HostId)
+	if len(candidates) > 0 {
+		hosts := []SHost{}
+		q := HostManager.Query().In("id", candidates).IsTrue("enabled").
+			Equals("host_status", api.HOST_ONLINE).Equals("host_type", api.HOST_TYPE_HYPERVISOR)
+		if err := db.FetchModelObjects(HostManager, q, &hosts); err != nil {
+			return "", errors.Wrap(err, "FetchModelObjects")
+		}
+		usable := map[string]bool{}
+		for i := range hosts {
+			usable[hosts[i].Id] = true
+		}
+		filtered := []string{}
+		for _, hostId := range candidates {
+			if usable[hostId] {
+				filtered = append(filtered, hostId)
+			}
+		}
+		candidates = filtered
+	}
+	if len(preferHostId) > 0 {
+		if !utils.IsInStringArray(preferHostId, candidates) {
+			return "", httperrors.NewInsufficientResourceError("Host %s has not enough free isolated devices for guest %s", preferHostId, self.Name)
+		}
+		return preferHostId, nil
+	}
+	if len(candidates) == 0 {
+		return "", httperrors.NewInsufficientResourceError("No available host has enough free isolated devices for guest %s", self.Name)
+	}
+	return candidates[0], nil
+}
+
+// 迁移前摘除虚机全部直通设备, 返回设备配置用于在目标宿主机按型号重新分配
+func (self *SGuest) DetachIsolatedDevicesForMigrate(ctx context.Context, userCred mcclient.TokenCredential) ([]api.IsolatedDeviceConfig, error) {
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	confs, devs, err := self.getIsolatedDeviceConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devs {
+		if err := self.detachIsolateDevice(ctx, userCred, &devs[i]); err != nil {
+			return nil, errors.Wrapf(err, "detach isolated device %s", devs[i].Addr)
+		}
+	}
+	if len(devs) > 0 {
+		go host.ClearSchedDescCache()
+	}
+	return confs, nil
+}
+
+// 在虚机当前所在宿主机上按型号重新分配直通设备
+func (self *SGuest) AttachIsolatedDevicesByConfigs(ctx context.Context, userCred mcclient.TokenCredential, confs []api.IsolatedDeviceConfig) error {
+	host, err := self.GetHost()
+	if err != nil {
+		return errors.Wrap(err, "GetHost")
+	}
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	defer func() { go host.ClearSchedDescCache() }()
+	for i := range confs {
+		err := IsolatedDeviceManager.attachHostDeviceToGuestByModel(ctx, self, host, &confs[i], userCred)
+		if err != nil {
+			return errors.Wrapf(err, "attach isolated device %s on host %s", confs[i].Model, host.Name)
+		}
+	}
+	return nil
+}
+
+func (self *SGuest) StartIsolatedDevicesSyncTask(ctx context.Context, userCred mcclient.TokenCredential, autoStart bool, parentTaskId string) error {
+	return self.startIsolatedDevicesSyncTask(ctx, userCred, autoStart, parentTaskId)
+}
+
+// 带直通设备的虚机无法热迁移, 关机后迁移到有同型号空闲设备的宿主机并重新分配设备
+func (self *SGuest) PerformColdMigrateWithDevices(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerColdMigrateWithDevicesInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot cold migrate guest in status %s", self.Status)
+	}
+	if len(self.BackupHostId) > 0 {
+		return nil, httperrors.NewBadRequestError("Guest have backup, can't migrate")
+	}
+	confs, _, err := self.getIsolatedDeviceConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if len(confs) == 0 {
+		return nil, httperrors.NewBadRequestError("Guest has no isolated devices, use server-migrate instead")
+	}
+	if len(input.PreferHost) > 0 {
+		iHost, _ := HostManager.FetchByIdOrName(userCred, input.PreferHost)
+		if iHost == nil {
+			return nil, httperrors.NewBadRequestError("Host %s not found", input.PreferHost)
+		}
+		input.PreferHost = iHost.GetId()
+	}
+	hostId, err := self.ChooseColdMigrateHostByDevices(confs, input.PreferHost)
+	if err != nil {
+		return nil, err
+	}
+	autoStart := self.Status == api.VM_RUNNING
+	if input.AutoStart != nil {
+		autoStart = *input.AutoStart
+	}
+	return nil, self.StartColdMigrateWithDevicesTask(ctx, userCred, hostId, autoStart, "")
+}
+
+func (self *SGuest) StartColdMigrateWithDevicesTask(ctx context.Context, userCred mcclient.TokenCredential, preferHostId string, autoStart bool, parentTaskId string) error {
+	data := jsonutils.NewDict()
+	data.Set("prefer_host_id", jsonutils.NewString(preferHostId))
+	data.Set("auto_start", jsonutils.NewBool(autoStart))
+	data.Set("guest_status", jsonutils.NewString(self.Status))
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestColdMigrateWithDevicesTask", self, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	self.SetStatus(userCred, api.VM_START_MIGRATE, "")
+	return task.ScheduleRun(nil)
+}
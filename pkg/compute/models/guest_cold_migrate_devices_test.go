@@ -0,0 +1,66 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSelectHostsByDeviceAvailability(t *testing.T) {
+	dev := func(hostId, model, wireId string) SIsolatedDevice {
+		d := SIsolatedDevice{}
+		d.HostId = hostId
+		d.Model = model
+		d.WireId = wireId
+		return d
+	}
+	free := []SIsolatedDevice{
+		dev("src", "A100", ""), dev("src", "A100", ""),
+		dev("h1", "A100", ""),
+		dev("h2", "A100", ""), dev("h2", "A100", ""), dev("h2", "A100", ""),
+		dev("h3", "A100", ""), dev("h3", "A100", ""), dev("h3", "CX5-VF", "wire1"),
+		dev("h4", "A100", ""), dev("h4", "A100", ""), dev("h4", "CX5-VF", "wire2"),
+	}
+	cases := []struct {
+		name  string
+		confs []api.IsolatedDeviceConfig
+		want  []string
+	}{
+		{
+			name:  "two gpus",
+			confs: []api.IsolatedDeviceConfig{{Model: "A100"}, {Model: "A100"}},
+			want:  []string{"h2", "h3", "h4"},
+		},
+		{
+			name:  "gpu and vf on same wire",
+			confs: []api.IsolatedDeviceConfig{{Model: "A100"}, {Model: "CX5-VF", WireId: "wire1"}},
+			want:  []string{"h3"},
+		},
+		{
+			name:  "not enough",
+			confs: []api.IsolatedDeviceConfig{{Model: "A100"}, {Model: "A100"}, {Model: "A100"}, {Model: "A100"}},
+			want:  []string{},
+		},
+	}
+	for _, c := range cases {
+		got := selectHostsByDeviceAvailability(isolatedDeviceRequirements(c.confs), free, "src")
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) GetConfidentialVmType(ctx context.Context) string {
+	return self.GetMetadata(ctx, api.VM_METADATA_CONFIDENTIAL_VM, nil)
+}
+
+// 宿主机上报的可用机密计算类型
+func (host *SHost) getConfidentialComputingTypes() []string {
+	types := []string{}
+	if host.SysInfo != nil {
+		host.SysInfo.Unmarshal(&types, "confidential_computing")
+	}
+	return types
+}
+
+// 设置机密计算类型(AMD SEV/Intel TDX), 需要 UEFI 引导及 q35 机型, 且所在宿主机支持该类型
+func (self *SGuest) PerformSetConfidentialVm(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetConfidentialVmInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set confidential vm when guest is %s", self.Status)
+	}
+	var err error
+	if len(input.Type) > 0 {
+		if !utils.IsInStringArray(input.Type, api.CONFIDENTIAL_VM_TYPES) {
+			return nil, httperrors.NewInputParameterError("invalid confidential vm type %s, choices %v", input.Type, api.CONFIDENTIAL_VM_TYPES)
+		}
+		if self.Bios != "UEFI" {
+			return nil, httperrors.NewUnsupportOperationError("confidential vm requires UEFI boot mode")
+		}
+		if self.Machine != api.VM_MACHINE_TYPE_Q35 {
+			return nil, httperrors.NewUnsupportOperationError("confidential vm requires machine type %s", api.VM_MACHINE_TYPE_Q35)
+		}
+		host, err := self.GetHost()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetHost")
+		}
+		if !utils.IsInStringArray(input.Type, host.getConfidentialComputingTypes()) {
+			return nil, httperrors.NewUnsupportOperationError("host %s not support confidential vm type %s", host.Name, input.Type)
+		}
+		err = self.SetMetadata(ctx, api.VM_METADATA_CONFIDENTIAL_VM, input.Type, userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_CONFIDENTIAL_VM, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set confidential vm metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_CONFIDENTIAL_VM, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
+
+// 获取 SEV 虚机的启动度量值, 由租户与预期的固件度量比对完成远程证明
+func (self *SGuest) GetDetailsConfidentialVmAttestation(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerConfidentialVmAttestationOutput, error) {
+	if len(self.GetConfidentialVmType(ctx)) == 0 {
+		return nil, httperrors.NewBadRequestError("guest is not a confidential vm")
+	}
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("Can't get attestation when guest is %s", self.Status)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	return self.GetDriver().RequestConfidentialVmAttestation(ctx, userCred, host, self)
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 设置磁盘 iothread 分配策略, 下次启动生效
+func (self *SGuest) PerformSetIothreadPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetIothreadPolicyInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	var err error
+	if len(input.Policy) > 0 {
+		if !utils.IsInStringArray(input.Policy, api.VM_IOTHREAD_POLICIES) {
+			return nil, httperrors.NewInputParameterError("invalid iothread policy %s, choices: %s", input.Policy, api.VM_IOTHREAD_POLICIES)
+		}
+		err = self.SetMetadata(ctx, api.VM_METADATA_IOTHREAD_POLICY, input.Policy, userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_IOTHREAD_POLICY, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set iothread policy metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_IOTHREAD_POLICY, input, userCred, true)
+	return nil, nil
+}
+
+// 设置 vCPU 绑定策略, 运行中的虚机同步后由宿主机重新分配绑定
+func (self *SGuest) PerformSetCpuPinPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetCpuPinPolicyInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	var err error
+	if len(input.Policy) > 0 {
+		if !utils.IsInStringArray(input.Policy, api.VM_CPU_PIN_POLICIES) {
+			return nil, httperrors.NewInputParameterError("invalid cpu pin policy %s, choices: %s", input.Policy, api.VM_CPU_PIN_POLICIES)
+		}
+		if input.Policy == api.VM_CPU_PIN_POLICY_DEDICATED {
+			host, _ := self.GetHost()
+			if host != nil && self.VcpuCount > host.CpuCount {
+				return nil, httperrors.NewInsufficientResourceError("guest vcpu count %d exceeds host cpu count %d", self.VcpuCount, host.CpuCount)
+			}
+		}
+		err = self.SetMetadata(ctx, api.VM_METADATA_CPU_PIN_POLICY, input.Policy, userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_CPU_PIN_POLICY, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set cpu pin policy metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_CPU_PIN_POLICY, input, userCred, true)
+	if self.Status == api.VM_RUNNING {
+		return nil, self.StartSyncTask(ctx, userCred, false, "")
+	}
+	return nil, nil
+}
+
+// 设置虚机 NUMA 策略, 虚机 NUMA 节点在启动时按宿主机节点放置, 下次启动生效
+func (self *SGuest) PerformSetNumaPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetNumaPolicyInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if len(input.Policy) == 0 {
+		for _, key := range []string{api.VM_METADATA_NUMA_POLICY, api.VM_METADATA_NUMA_NODES} {
+			if err := self.RemoveMetadata(ctx, key, userCred); err != nil {
+				return nil, errors.Wrapf(err, "remove %s", key)
+			}
+		}
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_NUMA_POLICY, input, userCred, true)
+		return nil, nil
+	}
+	if !utils.IsInStringArray(input.Policy, api.VM_NUMA_POLICIES) {
+		return nil, httperrors.NewInputParameterError("invalid numa policy %s, choices: %s", input.Policy, api.VM_NUMA_POLICIES)
+	}
+	if input.Nodes < 0 || input.Nodes > self.VcpuCount {
+		return nil, httperrors.NewInputParameterError("numa nodes %d should between 0 and vcpu count %d", input.Nodes, self.VcpuCount)
+	}
+	nodes := "none"
+	if input.Nodes > 0 {
+		nodes = fmt.Sprintf("%d", input.Nodes)
+	}
+	err := self.SetAllMetadata(ctx, map[string]interface{}{
+		api.VM_METADATA_NUMA_POLICY: input.Policy,
+		api.VM_METADATA_NUMA_NODES:  nodes,
+	}, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "set numa policy metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_NUMA_POLICY, input, userCred, true)
+	return nil, nil
+}
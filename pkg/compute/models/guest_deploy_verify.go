@@ -0,0 +1,259 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 部署验证检查项对应的失败分类
+var deployVerifyErrorClasses = map[string]string{
+	api.DEPLOY_VERIFY_CHECK_PORT:       api.DEPLOY_ERROR_PORT_UNREACHABLE,
+	api.DEPLOY_VERIFY_CHECK_AGENT:      api.DEPLOY_ERROR_AGENT_UNAVAILABLE,
+	api.DEPLOY_VERIFY_CHECK_CLOUD_INIT: api.DEPLOY_ERROR_CLOUD_INIT_FAILED,
+}
+
+// 汇总各检查项结果, timeout 为 true 时仍处于 pending 的检查项视为失败
+func classifyDeployVerify(checks []api.SDeployVerifyCheck, timeout bool) (string, string) {
+	pending := false
+	for _, check := range checks {
+		switch check.Status {
+		case api.DEPLOY_VERIFY_STATUS_FAILED:
+			return api.DEPLOY_VERIFY_STATUS_FAILED, deployVerifyErrorClasses[check.Check]
+		case api.DEPLOY_VERIFY_STATUS_PENDING:
+			if !timeout {
+				pending = true
+				continue
+			}
+			if check.Check == api.DEPLOY_VERIFY_CHECK_CLOUD_INIT {
+				return api.DEPLOY_VERIFY_STATUS_FAILED, api.DEPLOY_ERROR_CLOUD_INIT_TIMEOUT
+			}
+			return api.DEPLOY_VERIFY_STATUS_FAILED, deployVerifyErrorClasses[check.Check]
+		}
+	}
+	if pending {
+		return api.DEPLOY_VERIFY_STATUS_PENDING, ""
+	}
+	return api.DEPLOY_VERIFY_STATUS_PASSED, ""
+}
+
+// 获取用于端口检查的地址, 优先使用弹性公网IP, 其次经典网络的内网IP
+func (self *SGuest) getDeployVerifyAddr() (string, error) {
+	if eip, err := self.GetEipOrPublicIp(); err == nil && eip != nil && len(eip.IpAddr) > 0 {
+		return eip.IpAddr, nil
+	}
+	gns, err := self.GetNetworks("")
+	if err != nil {
+		return "", errors.Wrap(err, "GetNetworks")
+	}
+	for i := range gns {
+		network := gns[i].GetNetwork()
+		if network == nil {
+			continue
+		}
+		vpc, _ := network.GetVpc()
+		if vpc != nil && (vpc.Id == api.DEFAULT_VPC_ID || vpc.Direct) && len(gns[i].IpAddr) > 0 {
+			return gns[i].IpAddr, nil
+		}
+	}
+	return "", nil
+}
+
+func (self *SGuest) deployVerifyPort(ctx context.Context, userCred mcclient.TokenCredential) api.SDeployVerifyCheck {
+	check := api.SDeployVerifyCheck{Check: api.DEPLOY_VERIFY_CHECK_PORT}
+	addr, err := self.getDeployVerifyAddr()
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, err.Error()
+		return check
+	}
+	if len(addr) == 0 {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_SKIPPED, "no reachable address"
+		return check
+	}
+	port := 3389
+	if !self.IsWindows() {
+		port = self.GetSshPort(ctx, userCred)
+	}
+	endpoint := net.JoinHostPort(addr, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", endpoint, 5*time.Second)
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, err.Error()
+		return check
+	}
+	conn.Close()
+	check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PASSED, endpoint
+	return check
+}
+
+func (self *SGuest) deployVerifyAgent(ctx context.Context, userCred mcclient.TokenCredential) api.SDeployVerifyCheck {
+	check := api.SDeployVerifyCheck{Check: api.DEPLOY_VERIFY_CHECK_AGENT}
+	if self.Hypervisor == api.HYPERVISOR_KVM {
+		host, err := self.GetHost()
+		if err != nil {
+			check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, err.Error()
+			return check
+		}
+		input := &api.ServerQgaCommandInput{Command: `{"execute":"guest-ping"}`}
+		_, err = self.GetDriver().RequestQgaCommand(ctx, userCred, jsonutils.Marshal(input), host, self)
+		if err != nil {
+			check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, err.Error()
+			return check
+		}
+		check.Status = api.DEPLOY_VERIFY_STATUS_PASSED
+		return check
+	}
+	// 公有云虚机依赖监控 agent 心跳
+	metadata, err := self.GetAllMetadata(ctx, userCred)
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, err.Error()
+		return check
+	}
+	if len(metadata[api.VM_METADATA_MONITOR_AGENT_TOKEN]) == 0 {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_SKIPPED, "monitor agent not enabled"
+		return check
+	}
+	lastHeartbeat, _ := time.Parse(time.RFC3339, metadata[api.VM_METADATA_MONITOR_AGENT_HEARTBEAT])
+	timeout := time.Duration(options.Options.MonitorAgentHeartbeatTimeoutMinutes) * time.Minute
+	status := getMonitorAgentStatus(metadata[api.VM_METADATA_MONITOR_AGENT_STATUS], lastHeartbeat, time.Now(), timeout)
+	if status != api.MONITOR_AGENT_STATUS_ONLINE {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, fmt.Sprintf("monitor agent %s", status)
+		return check
+	}
+	check.Status = api.DEPLOY_VERIFY_STATUS_PASSED
+	return check
+}
+
+// 解析 cloud-init result.json 中的错误信息
+func parseCloudInitResult(content []byte) ([]string, error) {
+	obj, err := jsonutils.Parse(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse cloud-init result")
+	}
+	result := struct {
+		Errors []string `json:"errors"`
+	}{}
+	v1, err := obj.Get("v1")
+	if err != nil {
+		return nil, errors.Wrap(err, "get v1")
+	}
+	if err := v1.Unmarshal(&result); err != nil {
+		return nil, errors.Wrap(err, "unmarshal cloud-init result")
+	}
+	return result.Errors, nil
+}
+
+func (self *SGuest) deployVerifyCloudInit(ctx context.Context, userCred mcclient.TokenCredential) api.SDeployVerifyCheck {
+	check := api.SDeployVerifyCheck{Check: api.DEPLOY_VERIFY_CHECK_CLOUD_INIT}
+	// 仅 KVM Linux 虚机且注入了 userdata 时通过 qga 检查
+	if self.Hypervisor != api.HYPERVISOR_KVM || self.IsWindows() || len(self.GetMetadata(ctx, "user_data", userCred)) == 0 {
+		check.Status = api.DEPLOY_VERIFY_STATUS_SKIPPED
+		return check
+	}
+	_, err := self.requestQgaAction(ctx, userCred, "qga-file-read", &api.ServerQgaFileReadInput{Path: api.CLOUD_INIT_BOOT_FINISHED_PATH})
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PENDING, "cloud-init not finished"
+		return check
+	}
+	res, err := self.requestQgaAction(ctx, userCred, "qga-file-read", &api.ServerQgaFileReadInput{Path: api.CLOUD_INIT_RESULT_PATH})
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_PASSED, "result not found"
+		return check
+	}
+	output := &api.ServerQgaFileReadOutput{}
+	res.Unmarshal(output)
+	content, err := base64.StdEncoding.DecodeString(output.Content)
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_FAILED, err.Error()
+		return check
+	}
+	errs, err := parseCloudInitResult(content)
+	if err != nil {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_FAILED, err.Error()
+		return check
+	}
+	if len(errs) > 0 {
+		check.Status, check.Message = api.DEPLOY_VERIFY_STATUS_FAILED, errs[0]
+		return check
+	}
+	check.Status = api.DEPLOY_VERIFY_STATUS_PASSED
+	return check
+}
+
+// RunDeployVerify 执行一轮部署验证, timeout 为 true 时未完成的检查项视为失败
+func (self *SGuest) RunDeployVerify(ctx context.Context, userCred mcclient.TokenCredential, timeout bool) *api.ServerDeployVerifyOutput {
+	ret := &api.ServerDeployVerifyOutput{
+		Checks: []api.SDeployVerifyCheck{
+			self.deployVerifyPort(ctx, userCred),
+			self.deployVerifyAgent(ctx, userCred),
+			self.deployVerifyCloudInit(ctx, userCred),
+		},
+		VerifiedAt: time.Now().UTC(),
+	}
+	ret.Status, ret.ErrorClass = classifyDeployVerify(ret.Checks, timeout)
+	return ret
+}
+
+func (self *SGuest) SaveDeployVerifyResult(ctx context.Context, userCred mcclient.TokenCredential, result *api.ServerDeployVerifyOutput) error {
+	return self.SetAllMetadata(ctx, map[string]interface{}{
+		api.VM_METADATA_DEPLOY_VERIFY_STATUS: result.Status,
+		api.VM_METADATA_DEPLOY_VERIFY_RESULT: jsonutils.Marshal(result).String(),
+		api.VM_METADATA_DEPLOY_ERROR_CLASS:   result.ErrorClass,
+	}, userCred)
+}
+
+func (self *SGuest) GetDetailsDeployVerify(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerDeployVerifyOutput, error) {
+	result := self.GetMetadata(ctx, api.VM_METADATA_DEPLOY_VERIFY_RESULT, userCred)
+	if len(result) == 0 {
+		return nil, httperrors.NewNotFoundError("guest %s has not been verified", self.Name)
+	}
+	ret := &api.ServerDeployVerifyOutput{}
+	obj, err := jsonutils.ParseString(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse deploy verify result")
+	}
+	return ret, obj.Unmarshal(ret)
+}
+
+// 手动触发部署验证
+func (self *SGuest) PerformDeployVerify(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("can't verify guest in status %s", self.Status)
+	}
+	return nil, self.StartDeployVerifyTask(ctx, userCred, "")
+}
+
+func (self *SGuest) StartDeployVerifyTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetMetadata(ctx, api.VM_METADATA_DEPLOY_VERIFY_STATUS, api.DEPLOY_VERIFY_STATUS_PENDING, userCred)
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestDeployVerifyTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestClassifyDeployVerify(t *testing.T) {
+	check := func(name, status string) api.SDeployVerifyCheck {
+		return api.SDeployVerifyCheck{Check: name, Status: status}
+	}
+	cases := []struct {
+		name       string
+		checks     []api.SDeployVerifyCheck
+		timeout    bool
+		status     string
+		errorClass string
+	}{
+		{
+			name: "all passed",
+			checks: []api.SDeployVerifyCheck{
+				check(api.DEPLOY_VERIFY_CHECK_PORT, api.DEPLOY_VERIFY_STATUS_PASSED),
+				check(api.DEPLOY_VERIFY_CHECK_AGENT, api.DEPLOY_VERIFY_STATUS_SKIPPED),
+			},
+			status: api.DEPLOY_VERIFY_STATUS_PASSED,
+		},
+		{
+			name: "pending",
+			checks: []api.SDeployVerifyCheck{
+				check(api.DEPLOY_VERIFY_CHECK_PORT, api.DEPLOY_VERIFY_STATUS_PENDING),
+			},
+			status: api.DEPLOY_VERIFY_STATUS_PENDING,
+		},
+		{
+			name: "port timeout",
+			checks: []api.SDeployVerifyCheck{
+				check(api.DEPLOY_VERIFY_CHECK_PORT, api.DEPLOY_VERIFY_STATUS_PENDING),
+			},
+			timeout:    true,
+			status:     api.DEPLOY_VERIFY_STATUS_FAILED,
+			errorClass: api.DEPLOY_ERROR_PORT_UNREACHABLE,
+		},
+		{
+			name: "cloud-init timeout",
+			checks: []api.SDeployVerifyCheck{
+				check(api.DEPLOY_VERIFY_CHECK_PORT, api.DEPLOY_VERIFY_STATUS_PASSED),
+				check(api.DEPLOY_VERIFY_CHECK_CLOUD_INIT, api.DEPLOY_VERIFY_STATUS_PENDING),
+			},
+			timeout:    true,
+			status:     api.DEPLOY_VERIFY_STATUS_FAILED,
+			errorClass: api.DEPLOY_ERROR_CLOUD_INIT_TIMEOUT,
+		},
+		{
+			name: "cloud-init failed",
+			checks: []api.SDeployVerifyCheck{
+				check(api.DEPLOY_VERIFY_CHECK_AGENT, api.DEPLOY_VERIFY_STATUS_PENDING),
+				check(api.DEPLOY_VERIFY_CHECK_CLOUD_INIT, api.DEPLOY_VERIFY_STATUS_FAILED),
+			},
+			status:     api.DEPLOY_VERIFY_STATUS_FAILED,
+			errorClass: api.DEPLOY_ERROR_CLOUD_INIT_FAILED,
+		},
+	}
+	for _, c := range cases {
+		status, errorClass := classifyDeployVerify(c.checks, c.timeout)
+		if status != c.status || errorClass != c.errorClass {
+			t.Errorf("%s: got %s/%s, want %s/%s", c.name, status, errorClass, c.status, c.errorClass)
+		}
+	}
+}
+
+func TestParseCloudInitResult(t *testing.T) {
+	errs, err := parseCloudInitResult([]byte(`{"v1": {"datasource": "DataSourceNoCloud", "errors": ["module failed"]}}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(errs) != 1 || errs[0] != "module failed" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	errs, err = parseCloudInitResult([]byte(`{"v1": {"errors": []}}`))
+	if err != nil || len(errs) != 0 {
+		t.Errorf("unexpected %v %v", errs, err)
+	}
+}
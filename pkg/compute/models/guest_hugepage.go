@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 宿主机上报的可用大页大小, 旧版本宿主机仅有默认大页
+func (host *SHost) getHugepageSizesKb() []int {
+	sizes := []int{}
+	if host.SysInfo != nil {
+		host.SysInfo.Unmarshal(&sizes, "hugepage_sizes_kb")
+	}
+	if len(sizes) == 0 && host.IsHugePage() {
+		sizes = append(sizes, host.PageSizeKB)
+	}
+	return sizes
+}
+
+func isHugepageSizeAvailable(host *SHost, sizeKb int) bool {
+	for _, size := range host.getHugepageSizesKb() {
+		if size == sizeKb {
+			return true
+		}
+	}
+	return false
+}
+
+// 设置虚机大页大小及绑定的 NUMA 节点, 大页内存在启动时挂载, 因此仅允许关机状态设置
+func (self *SGuest) PerformSetHugepage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetHugepageInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set hugepage when guest is %s", self.Status)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	if !host.IsHugePage() {
+		return nil, httperrors.NewNotAcceptableError("host %s native hugepages not enabled", host.Name)
+	}
+	meta := map[string]string{}
+	if input.PageSizeKb > 0 {
+		if input.PageSizeKb != api.HUGEPAGE_SIZE_2M_KB && input.PageSizeKb != api.HUGEPAGE_SIZE_1G_KB {
+			return nil, httperrors.NewInputParameterError("unsupported hugepage size %dKB", input.PageSizeKb)
+		}
+		if !isHugepageSizeAvailable(host, input.PageSizeKb) {
+			return nil, httperrors.NewNotAcceptableError("host %s has no %dKB hugepage pool", host.Name, input.PageSizeKb)
+		}
+		meta[api.VM_METADATA_HUGEPAGE_SIZE_KB] = fmt.Sprintf("%d", input.PageSizeKb)
+	}
+	pageSizeKb := input.PageSizeKb
+	if pageSizeKb == 0 {
+		pageSizeKb = host.PageSizeKB
+	}
+	if (self.VmemSize*1024)%pageSizeKb != 0 {
+		return nil, httperrors.NewInputParameterError("memory %dMB is not aligned to hugepage size %dKB", self.VmemSize, pageSizeKb)
+	}
+	if input.NumaNode != nil && *input.NumaNode >= 0 {
+		meta[api.VM_METADATA_HUGEPAGE_NUMA_NODE] = fmt.Sprintf("%d", *input.NumaNode)
+	}
+	for _, key := range []string{api.VM_METADATA_HUGEPAGE_SIZE_KB, api.VM_METADATA_HUGEPAGE_NUMA_NODE} {
+		if val, ok := meta[key]; ok {
+			err = self.SetMetadata(ctx, key, val, userCred)
+		} else {
+			err = self.RemoveMetadata(ctx, key, userCred)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "set metadata %s", key)
+		}
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_HUGEPAGE, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
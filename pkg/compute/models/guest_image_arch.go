@@ -0,0 +1,86 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+func isArmArch(arch string) bool {
+	arch = strings.ToLower(arch)
+	return apis.IsARM(arch) || strings.Contains(arch, "aarch") || strings.Contains(arch, "arm")
+}
+
+// getTargetOsArch 获取创建主机的目标架构, 优先级: 指定架构 > 指定宿主机 > 套餐
+func (manager *SGuestManager) getTargetOsArch(userCred mcclient.TokenCredential, input *api.ServerCreateInput) string {
+	if len(input.OsArch) > 0 {
+		return input.OsArch
+	}
+	if len(input.PreferHost) > 0 {
+		hostObj, err := HostManager.FetchByIdOrName(userCred, input.PreferHost)
+		if err == nil && len(hostObj.(*SHost).CpuArchitecture) > 0 {
+			return hostObj.(*SHost).CpuArchitecture
+		}
+	}
+	if len(input.InstanceType) > 0 && len(input.Hypervisor) > 0 {
+		provider := GetDriver(input.Hypervisor).GetProvider()
+		sku, err := ServerSkuManager.FetchSkuByNameAndProvider(input.InstanceType, provider, true)
+		if err == nil && len(sku.CpuArch) > 0 {
+			return sku.CpuArch
+		}
+	}
+	return ""
+}
+
+// fillRootDiskImageArchVariant 系统盘使用多架构镜像且架构与目标架构不一致时, 替换为对应架构的镜像
+func (manager *SGuestManager) fillRootDiskImageArchVariant(ctx context.Context, userCred mcclient.TokenCredential, input *api.ServerCreateInput, diskConfig *api.DiskConfig) error {
+	if len(diskConfig.ImageId) == 0 || len(diskConfig.SnapshotId) > 0 || len(diskConfig.BackupId) > 0 {
+		return nil
+	}
+	targetArch := manager.getTargetOsArch(userCred, input)
+	if len(targetArch) == 0 || isArmArch(targetArch) == isArmArch(diskConfig.ImageProperties[imageapi.IMAGE_OS_ARCH]) {
+		return nil
+	}
+	s := auth.GetSession(ctx, userCred, options.Options.Region)
+	ret, err := image.Images.GetSpecific(s, diskConfig.ImageId, "arch-variant", jsonutils.Marshal(map[string]string{"os_arch": targetArch}))
+	if err != nil {
+		if e, ok := err.(*httputils.JSONClientError); ok && e.Code == 404 {
+			// 非多架构镜像, 由后续架构检查报错
+			return nil
+		}
+		return errors.Wrapf(err, "get arch variant of image %s", diskConfig.ImageId)
+	}
+	variant := imageapi.ImageArchVariant{}
+	ret.Unmarshal(&variant)
+	if len(variant.ImageId) == 0 || variant.ImageId == diskConfig.ImageId {
+		return nil
+	}
+	log.Infof("use image %s(%s) for arch %s instead of %s", variant.ImageName, variant.ImageId, targetArch, diskConfig.ImageId)
+	return fillDiskConfigByImage(ctx, userCred, diskConfig, variant.ImageId)
+}
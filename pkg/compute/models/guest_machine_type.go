@@ -0,0 +1,173 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+var machineTypeRegexp = regexp.MustCompile(`^(pc-i440fx|pc-q35|virt)-(.+)$`)
+
+// parseMachineType 将 QEMU 版本化机型解析为机型系列及版本, 如 pc-q35-6.2 => q35, 6.2
+func parseMachineType(machineType string) (string, string, error) {
+	m := machineTypeRegexp.FindStringSubmatch(machineType)
+	if len(m) != 3 {
+		return "", "", errors.Errorf("invalid versioned machine type %q", machineType)
+	}
+	family := api.VM_MACHINE_TYPE_ARM_VIRT
+	switch m[1] {
+	case "pc-i440fx":
+		family = api.VM_MACHINE_TYPE_PC
+	case "pc-q35":
+		family = api.VM_MACHINE_TYPE_Q35
+	}
+	return family, m[2], nil
+}
+
+// compareMachineVersion 按数字逐段比较机型版本, 如 6.2 < 6.10 < 7.0
+func compareMachineVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(strings.TrimLeft(as[i], "rhelv"))
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(strings.TrimLeft(bs[i], "rhelv"))
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (self *SGuest) getMachineFamily(host *SHost) string {
+	if host != nil && host.IsArmHost() {
+		return api.VM_MACHINE_TYPE_ARM_VIRT
+	}
+	return self.getMachine()
+}
+
+func (host *SHost) getQemuMachines() []string {
+	ret := []string{}
+	if host.SysInfo == nil {
+		return ret
+	}
+	host.SysInfo.Unmarshal(&ret, "qemu_machines")
+	return ret
+}
+
+// 升级机型不会立即生效, 下次冷启动时使用新机型, 启动失败则回滚到原机型
+func (self *SGuest) PerformUpgradeMachineType(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerUpgradeMachineTypeInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("Can't upgrade machine type when guest is %s", self.Status)
+	}
+	family, version, err := parseMachineType(input.MachineType)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("%v", err)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	if cur := self.getMachineFamily(host); cur != family {
+		return nil, httperrors.NewInputParameterError("machine type %s not belongs to current machine %s", input.MachineType, cur)
+	}
+	if cur := self.GetMetadata(ctx, api.VM_METADATA_MACHINE_TYPE, nil); len(cur) > 0 {
+		_, curVersion, err := parseMachineType(cur)
+		if err == nil && compareMachineVersion(version, curVersion) <= 0 {
+			return nil, httperrors.NewInputParameterError("machine type %s is not newer than current %s", input.MachineType, cur)
+		}
+	}
+	machines := host.getQemuMachines()
+	if !utils.IsInStringArray(input.MachineType, machines) {
+		return nil, httperrors.NewUnsupportOperationError("machine type %s not supported by host %s", input.MachineType, host.Name)
+	}
+	err = self.SetMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, input.MachineType, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "set pending machine type")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_UPGRADE_MACHINE_TYPE, input, userCred, true)
+	return nil, nil
+}
+
+// OnStartMachineTypeApplied 冷启动成功后, 宿主机实际使用了待升级机型则固定为当前机型
+func (self *SGuest) OnStartMachineTypeApplied(ctx context.Context, userCred mcclient.TokenCredential, machineType string) {
+	pending := self.GetMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, nil)
+	if len(pending) == 0 || len(machineType) == 0 {
+		return
+	}
+	notes := map[string]string{"machine_type": pending}
+	if machineType != pending {
+		notes["reason"] = "not applied by host"
+		self.RemoveMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, userCred)
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_UPGRADE_MACHINE_TYPE, notes, userCred, false)
+		return
+	}
+	meta := map[string]interface{}{
+		api.VM_METADATA_MACHINE_TYPE: pending,
+	}
+	if prev := self.GetMetadata(ctx, api.VM_METADATA_MACHINE_TYPE, nil); len(prev) > 0 {
+		meta[api.VM_METADATA_PREV_MACHINE_TYPE] = prev
+	}
+	err := self.SetAllMetadata(ctx, meta, userCred)
+	if err == nil {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, userCred)
+	}
+	if err != nil {
+		log.Errorf("guest %s apply machine type %s: %v", self.Name, pending, err)
+		return
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_UPGRADE_MACHINE_TYPE, notes, userCred, true)
+}
+
+// RollbackMachineTypeUpgrade 使用待升级机型启动失败时撤销升级, 返回是否需要以原机型重新启动
+func (self *SGuest) RollbackMachineTypeUpgrade(ctx context.Context, userCred mcclient.TokenCredential, reason string) bool {
+	pending := self.GetMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, nil)
+	if len(pending) == 0 {
+		return false
+	}
+	err := self.RemoveMetadata(ctx, api.VM_METADATA_PENDING_MACHINE_TYPE, userCred)
+	if err != nil {
+		log.Errorf("guest %s remove pending machine type: %v", self.Name, err)
+		return false
+	}
+	notes := map[string]string{"machine_type": pending, "reason": reason}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_UPGRADE_MACHINE_TYPE, notes, userCred, false)
+	return true
+}
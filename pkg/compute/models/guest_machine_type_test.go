@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestParseMachineType(t *testing.T) {
+	cases := []struct {
+		machine string
+		family  string
+		version string
+		wantErr bool
+	}{
+		{"pc-i440fx-6.2", "pc", "6.2", false},
+		{"pc-q35-7.1", "q35", "7.1", false},
+		{"virt-6.2", "virt", "6.2", false},
+		{"pc", "", "", true},
+		{"q35", "", "", true},
+	}
+	for _, c := range cases {
+		family, version, err := parseMachineType(c.machine)
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseMachineType(%q) error %v", c.machine, err)
+			continue
+		}
+		if family != c.family || version != c.version {
+			t.Errorf("parseMachineType(%q) = %s %s, want %s %s", c.machine, family, version, c.family, c.version)
+		}
+	}
+}
+
+func TestCompareMachineVersion(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"6.2", "6.2", 0},
+		{"6.2", "6.10", -1},
+		{"7.0", "6.2", 1},
+		{"2.12", "2.11", 1},
+		{"4", "4.0", 0},
+	}
+	for _, c := range cases {
+		if got := compareMachineVersion(c.a, c.b); got != c.want {
+			t.Errorf("compareMachineVersion(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 云平台上报的虚机维护事件(AWS instance events, 阿里云系统事件, Azure scheduled events)
+// 使用类型别名, 云平台驱动无需引用本包即可实现
+type ICloudMaintenanceEvent = interface {
+	GetGlobalId() string
+	// reboot, redeploy, retirement, maintenance
+	GetEventType() string
+	GetDescription() string
+	// scheduled, completed, canceled
+	GetStatus() string
+	GetNotBefore() time.Time
+	GetNotAfter() time.Time
+}
+
+type ICloudVMMaintenanceEventGetter interface {
+	GetMaintenanceEvents() ([]ICloudMaintenanceEvent, error)
+}
+
+type SGuestMaintenanceEventManager struct {
+	db.SStatusStandaloneResourceBaseManager
+	db.SExternalizedResourceBaseManager
+	SGuestResourceBaseManager
+}
+
+var GuestMaintenanceEventManager *SGuestMaintenanceEventManager
+
+func init() {
+	GuestMaintenanceEventManager = &SGuestMaintenanceEventManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SGuestMaintenanceEvent{},
+			"guest_maintenance_events_tbl",
+			"guest_maintenance_event",
+			"guest_maintenance_events",
+		),
+	}
+	GuestMaintenanceEventManager.SetVirtualObject(GuestMaintenanceEventManager)
+}
+
+type SGuestMaintenanceEvent struct {
+	db.SStatusStandaloneResourceBase
+	db.SExternalizedResourceBase
+	SGuestResourceBase `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true"`
+
+	// 事件类型
+	// example: retirement
+	EventType string `width:"32" charset:"ascii" nullable:"false" list:"user"`
+	// 计划执行时间窗口
+	NotBefore time.Time `nullable:"true" list:"user"`
+	NotAfter  time.Time `nullable:"true" list:"user"`
+	// 处理完成时间
+	RemediatedAt time.Time `nullable:"true" list:"user"`
+}
+
+func (manager *SGuestMaintenanceEventManager) ResourceScope() rbacutils.TRbacScope {
+	return rbacutils.ScopeProject
+}
+
+func (manager *SGuestMaintenanceEventManager) FilterByOwner(q *sqlchemy.SQuery, userCred mcclient.IIdentityProvider, scope rbacutils.TRbacScope) *sqlchemy.SQuery {
+	if userCred != nil {
+		sq := GuestManager.Query("id")
+		switch scope {
+		case rbacutils.ScopeProject:
+			sq = sq.Equals("tenant_id", userCred.GetProjectId())
+			return q.In("guest_id", sq.SubQuery())
+		case rbacutils.ScopeDomain:
+			sq = sq.Equals("domain_id", userCred.GetProjectDomainId())
+			return q.In("guest_id", sq.SubQuery())
+		}
+	}
+	return q
+}
+
+func (self *SGuestMaintenanceEvent) GetOwnerId() mcclient.IIdentityProvider {
+	guest, err := self.GetGuest()
+	if err != nil {
+		log.Errorf("failed to get guest for maintenance event %s(%s)", self.Name, self.Id)
+		return nil
+	}
+	return guest.GetOwnerId()
+}
+
+func (manager *SGuestMaintenanceEventManager) FetchOwnerId(ctx context.Context, data jsonutils.JSONObject) (mcclient.IIdentityProvider, error) {
+	guestId, _ := data.GetString("guest_id")
+	if len(guestId) > 0 {
+		guest, err := db.FetchById(GuestManager, guestId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "db.FetchById(GuestManager, %s)", guestId)
+		}
+		return guest.(*SGuest).GetOwnerId(), nil
+	}
+	return db.FetchProjectInfo(ctx, data)
+}
+
+func (manager *SGuestMaintenanceEventManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("maintenance events are synchronized from cloud providers")
+}
+
+// 维护事件列表
+func (manager *SGuestMaintenanceEventManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.GuestMaintenanceEventListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SExternalizedResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ExternalizedResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SExternalizedResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SGuestResourceBaseManager.ListItemFilter(ctx, q, userCred, query.ServerFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SGuestResourceBaseManager.ListItemFilter")
+	}
+	if len(query.EventType) > 0 {
+		q = q.In("event_type", query.EventType)
+	}
+	if query.Active != nil {
+		if *query.Active {
+			q = q.In("status", api.GUEST_MAINTENANCE_EVENT_ACTIVE_STATUS)
+		} else {
+			q = q.NotIn("status", api.GUEST_MAINTENANCE_EVENT_ACTIVE_STATUS)
+		}
+	}
+	return q, nil
+}
+
+func (manager *SGuestMaintenanceEventManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.GuestMaintenanceEventListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SGuestResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ServerFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SGuestResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SGuestMaintenanceEventManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SGuestResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SGuestMaintenanceEventManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.GuestMaintenanceEventDetails {
+	rows := make([]api.GuestMaintenanceEventDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	guestRows := manager.SGuestResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.GuestMaintenanceEventDetails{
+			StatusStandaloneResourceDetails: stdRows[i],
+			GuestResourceInfo:               guestRows[i],
+		}
+	}
+	return rows
+}
+
+func (self *SGuest) GetMaintenanceEvents() ([]SGuestMaintenanceEvent, error) {
+	q := GuestMaintenanceEventManager.Query().Equals("guest_id", self.Id)
+	events := []SGuestMaintenanceEvent{}
+	err := db.FetchModelObjects(GuestMaintenanceEventManager, q, &events)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return events, nil
+}
+
+// 云上事件状态统一为本地状态, 未知状态按计划中处理
+func normalizeMaintenanceEventStatus(status string) string {
+	switch status {
+	case api.GUEST_MAINTENANCE_EVENT_STATUS_COMPLETED, api.GUEST_MAINTENANCE_EVENT_STATUS_CANCELED:
+		return status
+	}
+	return api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED
+}
+
+// 本地已处理或处理中的事件不再被云上状态覆盖
+func isMaintenanceEventHandled(status string) bool {
+	return utils.IsInStringArray(status, []string{
+		api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATING,
+		api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATED,
+	})
+}
+
+func (self *SGuest) syncMaintenanceEvents(ctx context.Context, userCred mcclient.TokenCredential, extVM cloudprovider.ICloudVM) {
+	getter, ok := extVM.(ICloudVMMaintenanceEventGetter)
+	if !ok {
+		return
+	}
+	events, err := getter.GetMaintenanceEvents()
+	if err != nil {
+		if errors.Cause(err) != cloudprovider.ErrNotImplemented && errors.Cause(err) != cloudprovider.ErrNotSupported {
+			log.Errorf("get maintenance events of guest %s: %v", self.Name, err)
+		}
+		return
+	}
+	result := GuestMaintenanceEventManager.SyncEvents(ctx, userCred, self, events)
+	if result.IsError() {
+		log.Errorf("sync maintenance events of guest %s: %s", self.Name, result.Result())
+	}
+}
+
+// SyncEvents 同步虚机的云上维护事件, 新事件通知虚机所有者, 云上已消失的计划事件置为完成
+func (manager *SGuestMaintenanceEventManager) SyncEvents(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, extEvents []ICloudMaintenanceEvent) compare.SyncResult {
+	lockman.LockRawObject(ctx, manager.Keyword(), guest.Id)
+	defer lockman.ReleaseRawObject(ctx, manager.Keyword(), guest.Id)
+
+	result := compare.SyncResult{}
+	dbEvents, err := guest.GetMaintenanceEvents()
+	if err != nil {
+		result.Error(err)
+		return result
+	}
+	locals := map[string]*SGuestMaintenanceEvent{}
+	for i := range dbEvents {
+		locals[dbEvents[i].ExternalId] = &dbEvents[i]
+	}
+	remotes := map[string]bool{}
+	for i := range extEvents {
+		extId := extEvents[i].GetGlobalId()
+		remotes[extId] = true
+		if local, ok := locals[extId]; ok {
+			err := local.syncWithCloudEvent(extEvents[i])
+			if err != nil {
+				result.UpdateError(err)
+				continue
+			}
+			result.Update()
+			continue
+		}
+		event, err := manager.newFromCloudEvent(ctx, userCred, guest, extEvents[i])
+		if err != nil {
+			result.AddError(err)
+			continue
+		}
+		result.Add()
+		if event.Status == api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED {
+			event.notify(ctx, userCred, guest)
+		}
+	}
+	for extId, local := range locals {
+		if remotes[extId] || !utils.IsInStringArray(local.Status, api.GUEST_MAINTENANCE_EVENT_ACTIVE_STATUS) {
+			continue
+		}
+		err := local.SetStatus(userCred, api.GUEST_MAINTENANCE_EVENT_STATUS_COMPLETED, "event disappeared from cloud")
+		if err != nil {
+			result.DeleteError(err)
+			continue
+		}
+		result.Delete()
+	}
+	return result
+}
+
+func (manager *SGuestMaintenanceEventManager) newFromCloudEvent(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, ext ICloudMaintenanceEvent) (*SGuestMaintenanceEvent, error) {
+	event := &SGuestMaintenanceEvent{}
+	event.SetModelManager(manager, event)
+	event.GuestId = guest.Id
+	event.ExternalId = ext.GetGlobalId()
+	event.EventType = ext.GetEventType()
+	event.Description = ext.GetDescription()
+	event.Status = normalizeMaintenanceEventStatus(ext.GetStatus())
+	event.NotBefore = ext.GetNotBefore()
+	event.NotAfter = ext.GetNotAfter()
+
+	err := func() error {
+		lockman.LockRawObject(ctx, manager.Keyword(), "name")
+		defer lockman.ReleaseRawObject(ctx, manager.Keyword(), "name")
+
+		var err error
+		event.Name, err = db.GenerateName(ctx, manager, nil, fmt.Sprintf("%s-%s", guest.Name, event.EventType))
+		if err != nil {
+			return errors.Wrap(err, "GenerateName")
+		}
+		return manager.TableSpec().Insert(ctx, event)
+	}()
+	if err != nil {
+		return nil, errors.Wrapf(err, "insert maintenance event %s", event.ExternalId)
+	}
+	db.OpsLog.LogEvent(event, db.ACT_CREATE, event.GetShortDesc(ctx), userCred)
+	return event, nil
+}
+
+func (self *SGuestMaintenanceEvent) syncWithCloudEvent(ext ICloudMaintenanceEvent) error {
+	_, err := db.Update(self, func() error {
+		self.EventType = ext.GetEventType()
+		self.Description = ext.GetDescription()
+		self.NotBefore = ext.GetNotBefore()
+		self.NotAfter = ext.GetNotAfter()
+		if !isMaintenanceEventHandled(self.Status) {
+			status := normalizeMaintenanceEventStatus(ext.GetStatus())
+			if status != api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED || self.Status != api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED {
+				self.Status = status
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (self *SGuestMaintenanceEvent) notify(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) {
+	notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+		Obj:    guest,
+		Action: notifyclient.ActionScheduledMaintenance,
+		ObjDetailsDecorator: func(ctx context.Context, details *jsonutils.JSONDict) {
+			details.Set("event_type", jsonutils.NewString(self.EventType))
+			details.Set("event_description", jsonutils.NewString(self.Description))
+			if !self.NotBefore.IsZero() {
+				details.Set("not_before", jsonutils.NewTimeString(self.NotBefore))
+			}
+			if !self.NotAfter.IsZero() {
+				details.Set("not_after", jsonutils.NewTimeString(self.NotAfter))
+			}
+		},
+	})
+}
+
+// 一键处理维护事件, 通过关机再开机让云平台将虚机迁离故障硬件
+func (self *SGuestMaintenanceEvent) PerformRemediate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.GuestMaintenanceEventRemediateInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, api.GUEST_MAINTENANCE_EVENT_ACTIVE_STATUS) {
+		return nil, httperrors.NewInvalidStatusError("can not remediate maintenance event in status %s", self.Status)
+	}
+	guest, err := self.GetGuest()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetGuest"))
+	}
+	if guest.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("can not remediate guest in status %s", guest.Status)
+	}
+	return nil, self.StartRemediateTask(ctx, userCred, "")
+}
+
+func (self *SGuestMaintenanceEvent) StartRemediateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetStatus(userCred, api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestMaintenanceRemediateTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED, err.Error())
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SGuestMaintenanceEvent) MarkRemediated(ctx context.Context, userCred mcclient.TokenCredential) error {
+	_, err := db.Update(self, func() error {
+		self.RemediatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update remediated_at")
+	}
+	return self.SetStatus(userCred, api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATED, "")
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+type fakeMaintenanceEvent struct {
+	status string
+}
+
+func (e fakeMaintenanceEvent) GetGlobalId() string { return "evt-1" }
+func (e fakeMaintenanceEvent) GetEventType() string {
+	return api.GUEST_MAINTENANCE_EVENT_TYPE_RETIREMENT
+}
+func (e fakeMaintenanceEvent) GetDescription() string  { return "hardware degraded" }
+func (e fakeMaintenanceEvent) GetStatus() string       { return e.status }
+func (e fakeMaintenanceEvent) GetNotBefore() time.Time { return time.Time{} }
+func (e fakeMaintenanceEvent) GetNotAfter() time.Time  { return time.Time{} }
+
+type fakeMaintenanceVM struct{}
+
+func (vm fakeMaintenanceVM) GetMaintenanceEvents() ([]interface {
+	GetGlobalId() string
+	GetEventType() string
+	GetDescription() string
+	GetStatus() string
+	GetNotBefore() time.Time
+	GetNotAfter() time.Time
+}, error) {
+	return []ICloudMaintenanceEvent{fakeMaintenanceEvent{}}, nil
+}
+
+func TestMaintenanceEventGetter(t *testing.T) {
+	// 云平台驱动使用相同方法集的匿名接口即可实现
+	var vm interface{} = fakeMaintenanceVM{}
+	if _, ok := vm.(ICloudVMMaintenanceEventGetter); !ok {
+		t.Fatalf("fakeMaintenanceVM should implement ICloudVMMaintenanceEventGetter")
+	}
+}
+
+func TestNormalizeMaintenanceEventStatus(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED},
+		{"Executing", api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED},
+		{api.GUEST_MAINTENANCE_EVENT_STATUS_COMPLETED, api.GUEST_MAINTENANCE_EVENT_STATUS_COMPLETED},
+		{api.GUEST_MAINTENANCE_EVENT_STATUS_CANCELED, api.GUEST_MAINTENANCE_EVENT_STATUS_CANCELED},
+		{api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATED, api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED},
+	}
+	for _, c := range cases {
+		if got := normalizeMaintenanceEventStatus(fakeMaintenanceEvent{status: c.in}.GetStatus()); got != c.want {
+			t.Errorf("normalizeMaintenanceEventStatus(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestIsMaintenanceEventHandled(t *testing.T) {
+	for status, want := range map[string]bool{
+		api.GUEST_MAINTENANCE_EVENT_STATUS_SCHEDULED:        false,
+		api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED: false,
+		api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATING:      true,
+		api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATED:       true,
+	} {
+		if got := isMaintenanceEventHandled(status); got != want {
+			t.Errorf("isMaintenanceEventHandled(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/osprofile"
+	"yunion.io/x/pkg/util/stringutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	devtool_utils "yunion.io/x/onecloud/pkg/devtool/utils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/cloudinit"
+)
+
+// 获取监控 agent 注册 token, 不存在时生成并记录到虚机元数据
+func (self *SGuest) getMonitorAgentToken(ctx context.Context, userCred mcclient.TokenCredential) (string, error) {
+	token := self.GetMetadata(ctx, api.VM_METADATA_MONITOR_AGENT_TOKEN, userCred)
+	if len(token) > 0 {
+		return token, nil
+	}
+	token = stringutils.UUID4()
+	err := self.SetAllMetadata(ctx, map[string]interface{}{
+		api.VM_METADATA_MONITOR_AGENT_TOKEN:  token,
+		api.VM_METADATA_MONITOR_AGENT_STATUS: api.MONITOR_AGENT_STATUS_PENDING,
+	}, userCred)
+	if err != nil {
+		return "", errors.Wrap(err, "save monitor agent token")
+	}
+	return token, nil
+}
+
+func (self *SGuest) getMonitorAgentTelegrafConf(ctx context.Context, userCred mcclient.TokenCredential, osType string) (string, error) {
+	influxdbUrl := self.GetDriver().FetchMonitorUrl(ctx, self)
+	serverDetails, err := self.getDetails(ctx, userCred)
+	if err != nil {
+		return "", errors.Wrap(err, "get details")
+	}
+	return devtool_utils.GenerateTelegrafConf(serverDetails, influxdbUrl, osType, self.Hypervisor)
+}
+
+func getMonitorAgentTokenPath(osType string) string {
+	if strings.EqualFold(osType, osprofile.OS_TYPE_WINDOWS) {
+		return "/Program Files/Telegraf/.onecloud_agent_token"
+	}
+	return api.MONITOR_AGENT_TOKEN_PATH
+}
+
+// GetMonitorAgentDeployConfigs 返回 KVM 虚机通过 deploy agent 注入的 token 文件
+func (self *SGuest) GetMonitorAgentDeployConfigs(ctx context.Context, userCred mcclient.TokenCredential) ([]*api.DeployConfig, error) {
+	token, err := self.getMonitorAgentToken(ctx, userCred)
+	if err != nil {
+		return nil, err
+	}
+	return []*api.DeployConfig{
+		{
+			Action:  "create",
+			Path:    getMonitorAgentTokenPath(self.OsType),
+			Content: token,
+		},
+	}, nil
+}
+
+// MergeMonitorAgentUserData 在公有云虚机的 cloud-init userdata 中追加监控 agent 的安装及注册 token,
+// 目前仅支持 Linux
+func (self *SGuest) MergeMonitorAgentUserData(ctx context.Context, userCred mcclient.TokenCredential, userData string, osType string) (string, error) {
+	if strings.EqualFold(osType, osprofile.OS_TYPE_WINDOWS) {
+		return userData, nil
+	}
+	var conf *cloudinit.SCloudConfig
+	if len(userData) > 0 {
+		var err error
+		conf, err = cloudinit.ParseUserData(userData)
+		if err != nil {
+			// 用户自定义的非 cloud-config 脚本, 不做修改
+			log.Warningf("guest %s userdata is not cloud-config, skip inject monitor agent", self.Name)
+			return userData, nil
+		}
+	} else {
+		conf = &cloudinit.SCloudConfig{}
+	}
+	token, err := self.getMonitorAgentToken(ctx, userCred)
+	if err != nil {
+		return "", err
+	}
+	telegrafConf, err := self.getMonitorAgentTelegrafConf(ctx, userCred, osprofile.OS_TYPE_LINUX)
+	if err != nil {
+		return "", errors.Wrap(err, "get telegraf conf")
+	}
+	agentConf := &cloudinit.SCloudConfig{
+		WriteFiles: []cloudinit.SWriteFile{
+			cloudinit.NewWriteFile(api.MONITOR_AGENT_CONF_PATH, telegrafConf, "0644", "root", true),
+			cloudinit.NewWriteFile(api.MONITOR_AGENT_TOKEN_PATH, token, "0600", "root", false),
+		},
+	}
+	if len(options.Options.MonitorAgentInstallUrl) > 0 {
+		agentConf.Runcmd = append(agentConf.Runcmd, fmt.Sprintf("curl -fsSL '%s' | sh -s -- --conf %s --token-file %s",
+			options.Options.MonitorAgentInstallUrl, api.MONITOR_AGENT_CONF_PATH, api.MONITOR_AGENT_TOKEN_PATH))
+	} else {
+		agentConf.Packages = append(agentConf.Packages, "telegraf")
+		agentConf.Runcmd = append(agentConf.Runcmd, "systemctl enable --now telegraf")
+	}
+	conf.Merge(agentConf)
+	return conf.UserData(), nil
+}
+
+func (self *SGuest) PerformMonitorAgentHeartbeat(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerMonitorAgentHeartbeatInput) (jsonutils.JSONObject, error) {
+	if len(input.Token) == 0 {
+		return nil, httperrors.NewMissingParameterError("token")
+	}
+	token := self.GetMetadata(ctx, api.VM_METADATA_MONITOR_AGENT_TOKEN, userCred)
+	if len(token) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(input.Token)) != 1 {
+		return nil, httperrors.NewForbiddenError("invalid monitor agent token")
+	}
+	info := map[string]interface{}{
+		api.VM_METADATA_MONITOR_AGENT_STATUS:    api.MONITOR_AGENT_STATUS_ONLINE,
+		api.VM_METADATA_MONITOR_AGENT_HEARTBEAT: time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(input.Version) > 0 {
+		info[api.VM_METADATA_MONITOR_AGENT_VERSION] = input.Version
+	}
+	return nil, self.SetAllMetadata(ctx, info, userCred)
+}
+
+// 超过心跳超时时间未上报的 agent 视为离线
+func getMonitorAgentStatus(status string, lastHeartbeat time.Time, now time.Time, timeout time.Duration) string {
+	if status != api.MONITOR_AGENT_STATUS_ONLINE {
+		return status
+	}
+	if lastHeartbeat.IsZero() || now.Sub(lastHeartbeat) > timeout {
+		return api.MONITOR_AGENT_STATUS_OFFLINE
+	}
+	return status
+}
+
+func (self *SGuest) GetDetailsMonitorAgent(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerMonitorAgentOutput, error) {
+	metadata, err := self.GetAllMetadata(ctx, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetAllMetadata")
+	}
+	ret := &api.ServerMonitorAgentOutput{
+		Enabled:  len(metadata[api.VM_METADATA_MONITOR_AGENT_TOKEN]) > 0,
+		Deployed: metadata["telegraf_deployed"] == "true",
+		Version:  metadata[api.VM_METADATA_MONITOR_AGENT_VERSION],
+	}
+	if hb := metadata[api.VM_METADATA_MONITOR_AGENT_HEARTBEAT]; len(hb) > 0 {
+		ret.LastHeartbeat, _ = time.Parse(time.RFC3339, hb)
+	}
+	timeout := time.Duration(options.Options.MonitorAgentHeartbeatTimeoutMinutes) * time.Minute
+	ret.Status = getMonitorAgentStatus(metadata[api.VM_METADATA_MONITOR_AGENT_STATUS], ret.LastHeartbeat, time.Now(), timeout)
+	return ret, nil
+}
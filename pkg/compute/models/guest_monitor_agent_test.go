@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestGetMonitorAgentStatus(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeout := 10 * time.Minute
+	cases := []struct {
+		status    string
+		heartbeat time.Time
+		want      string
+	}{
+		{api.MONITOR_AGENT_STATUS_PENDING, time.Time{}, api.MONITOR_AGENT_STATUS_PENDING},
+		{api.MONITOR_AGENT_STATUS_ONLINE, now.Add(-time.Minute), api.MONITOR_AGENT_STATUS_ONLINE},
+		{api.MONITOR_AGENT_STATUS_ONLINE, now.Add(-time.Hour), api.MONITOR_AGENT_STATUS_OFFLINE},
+		{api.MONITOR_AGENT_STATUS_ONLINE, time.Time{}, api.MONITOR_AGENT_STATUS_OFFLINE},
+		{"", time.Time{}, ""},
+	}
+	for _, c := range cases {
+		got := getMonitorAgentStatus(c.status, c.heartbeat, now, timeout)
+		if got != c.want {
+			t.Errorf("status %q heartbeat %s: want %q got %q", c.status, c.heartbeat, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/timeutils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+const (
+	GUEST_PASSWORD_DEFAULT_REMIND_DAYS = 7
+	GUEST_PASSWORD_MAX_LENGTH          = 64
+)
+
+// 域级别的虚机密码策略, 平台在创建/重装/重置密码时按策略生成及校验登录密码
+type SGuestPasswordPolicyManager struct {
+	db.SDomainLevelResourceBaseManager
+}
+
+var GuestPasswordPolicyManager *SGuestPasswordPolicyManager
+
+func init() {
+	GuestPasswordPolicyManager = &SGuestPasswordPolicyManager{
+		SDomainLevelResourceBaseManager: db.NewDomainLevelResourceBaseManager(
+			SGuestPasswordPolicy{},
+			"guest_password_policies_tbl",
+			"guest_password_policy",
+			"guest_password_policies",
+		),
+	}
+	GuestPasswordPolicyManager.SetVirtualObject(GuestPasswordPolicyManager)
+}
+
+type SGuestPasswordPolicy struct {
+	db.SDomainLevelResourceBase
+
+	// 密码最小长度
+	MinLength int `nullable:"false" default:"12" list:"domain" create:"domain_optional" update:"domain"`
+	// 必须包含数字
+	RequireDigits bool `nullable:"false" default:"true" list:"domain" create:"domain_optional" update:"domain"`
+	// 必须包含小写字母
+	RequireLowercases bool `nullable:"false" default:"true" list:"domain" create:"domain_optional" update:"domain"`
+	// 必须包含大写字母
+	RequireUppercases bool `nullable:"false" default:"true" list:"domain" create:"domain_optional" update:"domain"`
+	// 必须包含特殊字符
+	RequirePunctuats bool `nullable:"false" default:"true" list:"domain" create:"domain_optional" update:"domain"`
+	// 密码有效期(天), 0表示永不过期
+	ExpireDays int `nullable:"false" default:"0" list:"domain" create:"domain_optional" update:"domain"`
+	// 过期前提醒天数
+	RemindDays int `nullable:"false" default:"7" list:"domain" create:"domain_optional" update:"domain"`
+}
+
+func validatePasswordPolicyLength(minLength int) error {
+	if minLength < seclib2.DEFAULT_PASSWORD_LENGTH || minLength > GUEST_PASSWORD_MAX_LENGTH {
+		return httperrors.NewOutOfRangeError("min_length should be in range [%d, %d]", seclib2.DEFAULT_PASSWORD_LENGTH, GUEST_PASSWORD_MAX_LENGTH)
+	}
+	return nil
+}
+
+func validatePasswordPolicyDays(expireDays, remindDays int) error {
+	if expireDays < 0 {
+		return httperrors.NewInputParameterError("expire_days should not be negative")
+	}
+	if remindDays < 0 || (expireDays > 0 && remindDays > expireDays) {
+		return httperrors.NewInputParameterError("remind_days should be in range [0, %d]", expireDays)
+	}
+	return nil
+}
+
+func (manager *SGuestPasswordPolicyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.GuestPasswordPolicyCreateInput) (api.GuestPasswordPolicyCreateInput, error) {
+	var err error
+	input.DomainLevelResourceCreateInput, err = manager.SDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.DomainLevelResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	policy, err := manager.FetchByDomainId(ownerId.GetProjectDomainId())
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if policy != nil {
+		return input, httperrors.NewDuplicateResourceError("domain %s already has guest password policy %s", ownerId.GetProjectDomain(), policy.Name)
+	}
+	if input.MinLength == 0 {
+		input.MinLength = seclib2.DEFAULT_PASSWORD_LENGTH
+	}
+	if err := validatePasswordPolicyLength(input.MinLength); err != nil {
+		return input, err
+	}
+	if input.RemindDays == 0 {
+		input.RemindDays = GUEST_PASSWORD_DEFAULT_REMIND_DAYS
+		if input.ExpireDays > 0 && input.RemindDays > input.ExpireDays {
+			input.RemindDays = input.ExpireDays
+		}
+	}
+	if err := validatePasswordPolicyDays(input.ExpireDays, input.RemindDays); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SGuestPasswordPolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.GuestPasswordPolicyUpdateInput) (api.GuestPasswordPolicyUpdateInput, error) {
+	var err error
+	input.DomainLevelResourceBaseUpdateInput, err = self.SDomainLevelResourceBase.ValidateUpdateData(ctx, userCred, query, input.DomainLevelResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.MinLength != nil {
+		if err := validatePasswordPolicyLength(*input.MinLength); err != nil {
+			return input, err
+		}
+	}
+	expireDays, remindDays := self.ExpireDays, self.RemindDays
+	if input.ExpireDays != nil {
+		expireDays = *input.ExpireDays
+	}
+	if input.RemindDays != nil {
+		remindDays = *input.RemindDays
+	}
+	if err := validatePasswordPolicyDays(expireDays, remindDays); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SGuestPasswordPolicy) PerformChangeOwner(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input apis.PerformChangeDomainOwnerInput) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("guest password policy can not change domain")
+}
+
+// 虚机密码策略列表
+func (manager *SGuestPasswordPolicyManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.GuestPasswordPolicyListInput) (*sqlchemy.SQuery, error) {
+	return manager.SDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, query.DomainLevelResourceListInput)
+}
+
+func (manager *SGuestPasswordPolicyManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.GuestPasswordPolicyListInput) (*sqlchemy.SQuery, error) {
+	return manager.SDomainLevelResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.DomainLevelResourceListInput)
+}
+
+func (manager *SGuestPasswordPolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SDomainLevelResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SGuestPasswordPolicyManager) FetchCustomizeColumns(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, objs []interface{}, fields stringutils2.SSortedStrings, isList bool) []api.GuestPasswordPolicyDetails {
+	rows := make([]api.GuestPasswordPolicyDetails, len(objs))
+	domainRows := manager.SDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.GuestPasswordPolicyDetails{
+			DomainLevelResourceDetails: domainRows[i],
+		}
+	}
+	return rows
+}
+
+// FetchByDomainId 获取域的密码策略, 未设置时返回nil
+func (manager *SGuestPasswordPolicyManager) FetchByDomainId(domainId string) (*SGuestPasswordPolicy, error) {
+	policy := &SGuestPasswordPolicy{}
+	policy.SetModelManager(manager, policy)
+	err := manager.Query().Equals("domain_id", domainId).First(policy)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fetch password policy of domain %s", domainId)
+	}
+	return policy, nil
+}
+
+func (self *SGuestPasswordPolicy) GetPasswordPolicy() seclib2.SPasswordPolicy {
+	return seclib2.SPasswordPolicy{
+		MinLength:         self.MinLength,
+		RequireDigits:     self.RequireDigits,
+		RequireLowercases: self.RequireLowercases,
+		RequireUppercases: self.RequireUppercases,
+		RequirePunctuats:  self.RequirePunctuats,
+	}
+}
+
+// GetPasswordExpireAt 返回密码过期时间, 未设置有效期时返回零值
+func (self *SGuestPasswordPolicy) GetPasswordExpireAt(updatedAt time.Time) time.Time {
+	if self.ExpireDays <= 0 || updatedAt.IsZero() {
+		return time.Time{}
+	}
+	return updatedAt.AddDate(0, 0, self.ExpireDays)
+}
+
+func validateDomainGuestPassword(domainId, passwd string) error {
+	err := seclib2.ValidatePassword(passwd)
+	if err != nil {
+		return err
+	}
+	policy, err := GuestPasswordPolicyManager.FetchByDomainId(domainId)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if policy != nil {
+		return policy.GetPasswordPolicy().Validate(passwd)
+	}
+	return nil
+}
+
+func (self *SGuest) validatePassword(passwd string) error {
+	return validateDomainGuestPassword(self.DomainId, passwd)
+}
+
+// generatePassword 按所属域的密码策略生成登录密码, 未设置策略时返回空, 由部署端自行生成
+func (self *SGuest) generatePassword() string {
+	policy, err := GuestPasswordPolicyManager.FetchByDomainId(self.DomainId)
+	if err != nil {
+		log.Errorf("fetch password policy for guest %s: %v", self.Name, err)
+	}
+	if policy == nil {
+		return ""
+	}
+	return policy.GetPasswordPolicy().RandomPassword()
+}
+
+// getPasswordUpdatedAt 登录密码最近一次更新时间, 来自 login_key_timestamp
+func (self *SGuest) getPasswordUpdatedAt(ctx context.Context) time.Time {
+	ts := self.GetMetadata(ctx, api.VM_METADATA_LOGIN_KEY_TIMESTAMP, nil)
+	if len(ts) == 0 || ts == "none" {
+		return time.Time{}
+	}
+	updatedAt, err := timeutils.ParseTimeStr(ts)
+	if err != nil {
+		return time.Time{}
+	}
+	return updatedAt
+}
+
+func (self *SGuest) isPasswordExpired(ctx context.Context, policy *SGuestPasswordPolicy, now time.Time) bool {
+	expireAt := policy.GetPasswordExpireAt(self.getPasswordUpdatedAt(ctx))
+	return !expireAt.IsZero() && !now.Before(expireAt)
+}
+
+func (manager *SGuestPasswordPolicyManager) fetchExpirablePolicies() ([]SGuestPasswordPolicy, error) {
+	policies := make([]SGuestPasswordPolicy, 0)
+	q := manager.Query().GT("expire_days", 0)
+	err := db.FetchModelObjects(manager, q, &policies)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return policies, nil
+}
+
+func (self *SGuestPasswordPolicy) fetchGuests(ids []string) ([]SGuest, error) {
+	guests := make([]SGuest, 0)
+	q := GuestManager.Query().Equals("domain_id", self.DomainId)
+	if len(ids) > 0 {
+		q = q.In("id", ids)
+	}
+	err := db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return guests, nil
+}
+
+// CheckGuestPasswordExpire 对即将过期或已过期的虚机登录密码发送提醒
+func (manager *SGuestPasswordPolicyManager) CheckGuestPasswordExpire(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	policies, err := manager.fetchExpirablePolicies()
+	if err != nil {
+		log.Errorf("CheckGuestPasswordExpire: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	for i := range policies {
+		policy := &policies[i]
+		guests, err := policy.fetchGuests(nil)
+		if err != nil {
+			log.Errorf("fetch guests of domain %s: %v", policy.DomainId, err)
+			continue
+		}
+		for j := range guests {
+			expireAt := policy.GetPasswordExpireAt(guests[j].getPasswordUpdatedAt(ctx))
+			if expireAt.IsZero() || now.Before(expireAt.AddDate(0, 0, -policy.RemindDays)) {
+				continue
+			}
+			advanceDays := int(expireAt.Sub(now).Hours() / 24)
+			if advanceDays < 0 {
+				advanceDays = 0
+			}
+			notifyclient.EventNotify(ctx, userCred, notifyclient.SEventNotifyParam{
+				Obj:         &guests[j],
+				Action:      notifyclient.ActionPasswordExpireSoon,
+				AdvanceDays: advanceDays,
+			})
+		}
+	}
+}
+
+// 批量轮换虚机登录密码, 通过 deploy 重置密码流程按域密码策略生成新密码
+func (manager *SGuestManager) PerformBatchRotatePassword(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerBatchRotatePasswordInput) (api.ServerBatchRotatePasswordOutput, error) {
+	output := api.ServerBatchRotatePasswordOutput{
+		Rotated: []string{},
+		Skipped: map[string]string{},
+	}
+	domainId := userCred.GetProjectDomainId()
+	if len(input.ProjectDomain) > 0 {
+		domain, err := db.TenantCacheManager.FetchDomainByIdOrName(ctx, input.ProjectDomain)
+		if err != nil {
+			return output, httperrors.NewResourceNotFoundError2("domain", input.ProjectDomain)
+		}
+		domainId = domain.GetId()
+	}
+	pwPolicy, err := GuestPasswordPolicyManager.FetchByDomainId(domainId)
+	if err != nil {
+		return output, httperrors.NewGeneralError(err)
+	}
+	if pwPolicy == nil {
+		// 未设置策略时仍允许轮换, 使用默认复杂度
+		pwPolicy = &SGuestPasswordPolicy{}
+		pwPolicy.DomainId = domainId
+	}
+	if len(input.ServerIds) == 0 && pwPolicy.ExpireDays <= 0 {
+		return output, httperrors.NewMissingParameterError("server_ids")
+	}
+	if len(input.ServerIds) == 0 {
+		input.ExpiredOnly = true
+	}
+	guests, err := pwPolicy.fetchGuests(input.ServerIds)
+	if err != nil {
+		return output, httperrors.NewGeneralError(err)
+	}
+	if len(input.ServerIds) > 0 && len(guests) != len(input.ServerIds) {
+		return output, httperrors.NewBadRequestError("some servers not found in domain %s", domainId)
+	}
+	now := time.Now().UTC()
+	for i := range guests {
+		guest := &guests[i]
+		if input.ExpiredOnly && !guest.isPasswordExpired(ctx, pwPolicy, now) {
+			continue
+		}
+		err := func() error {
+			lockman.LockObject(ctx, guest)
+			defer lockman.ReleaseObject(ctx, guest)
+
+			err := db.IsObjectRbacAllowed(ctx, guest, userCred, policy.PolicyActionPerform, "deploy")
+			if err != nil {
+				return err
+			}
+			_, err = guest.PerformDeploy(ctx, userCred, nil, api.ServerDeployInput{
+				ResetPassword: true,
+				AutoStart:     input.AutoStart,
+			})
+			return err
+		}()
+		if err != nil {
+			output.Skipped[guest.Id] = err.Error()
+			continue
+		}
+		output.Rotated = append(output.Rotated, guest.Id)
+	}
+	return output, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePasswordPolicyDays(t *testing.T) {
+	cases := []struct {
+		expireDays int
+		remindDays int
+		valid      bool
+	}{
+		{0, 7, true},
+		{90, 7, true},
+		{90, 90, true},
+		{5, 7, false},
+		{-1, 0, false},
+		{30, -1, false},
+	}
+	for _, c := range cases {
+		err := validatePasswordPolicyDays(c.expireDays, c.remindDays)
+		if (err == nil) != c.valid {
+			t.Errorf("expire %d remind %d valid %v got %v", c.expireDays, c.remindDays, c.valid, err)
+		}
+	}
+	for _, l := range []int{11, 65} {
+		if validatePasswordPolicyLength(l) == nil {
+			t.Errorf("min_length %d should be invalid", l)
+		}
+	}
+}
+
+func TestGuestPasswordPolicyExpireAt(t *testing.T) {
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := &SGuestPasswordPolicy{ExpireDays: 30}
+	if got := policy.GetPasswordExpireAt(updatedAt); !got.Equal(time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expire at %s", got)
+	}
+	if !policy.GetPasswordExpireAt(time.Time{}).IsZero() {
+		t.Errorf("password without timestamp should not expire")
+	}
+	policy.ExpireDays = 0
+	if !policy.GetPasswordExpireAt(updatedAt).IsZero() {
+		t.Errorf("policy without expire days should not expire")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/osprofile"
+	"yunion.io/x/pkg/util/secrules"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/guestfirewall"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func fetchSecgroupRulesByPriority(secgroupIds []string) ([]secrules.SecurityRule, error) {
+	ret := []secrules.SecurityRule{}
+	if len(secgroupIds) == 0 {
+		return ret, nil
+	}
+	q := SecurityGroupRuleManager.Query()
+	q = q.Filter(sqlchemy.In(q.Field("secgroup_id"), secgroupIds)).Desc(q.Field("priority"), q.Field("action"))
+	rules := []SSecurityGroupRule{}
+	if err := db.FetchModelObjects(SecurityGroupRuleManager, q, &rules); err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range rules {
+		rule, err := rules[i].toRule()
+		if err != nil {
+			log.Errorf("invalid security group rule %s: %v", rules[i].Id, err)
+			continue
+		}
+		ret = append(ret, *rule)
+	}
+	return ret, nil
+}
+
+// 管理员安全组规则优先, 其余安全组规则按优先级降序排列
+func (self *SGuest) getQgaFirewallRules() ([]secrules.SecurityRule, error) {
+	adminIds := []string{}
+	if len(self.AdminSecgrpId) > 0 {
+		adminIds = append(adminIds, self.AdminSecgrpId)
+	}
+	ret, err := fetchSecgroupRulesByPriority(adminIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch admin secgroup rules")
+	}
+	secgroups, err := self.GetSecgroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetSecgroups")
+	}
+	secgroupIds := []string{}
+	for _, secgroup := range secgroups {
+		secgroupIds = append(secgroupIds, secgroup.Id)
+	}
+	rules, err := fetchSecgroupRulesByPriority(secgroupIds)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch secgroup rules")
+	}
+	return append(ret, rules...), nil
+}
+
+func (self *SGuest) getQgaFirewallExecInput(backend, script string) *api.ServerQgaExecInput {
+	input := &api.ServerQgaExecInput{
+		Input:   script,
+		Timeout: 60,
+	}
+	if backend == guestfirewall.BACKEND_WINDOWS {
+		input.Path = "powershell.exe"
+		input.Args = []string{"-NoProfile", "-NonInteractive", "-Command", "-"}
+	} else {
+		input.Path = "/bin/sh"
+	}
+	return input
+}
+
+// SyncQgaFirewall 将安全组规则渲染为虚机内防火墙规则并通过 qga 下发
+func (self *SGuest) SyncQgaFirewall(ctx context.Context, userCred mcclient.TokenCredential) error {
+	backend := self.GetMetadata(ctx, api.VM_METADATA_QGA_FIREWALL_BACKEND, userCred)
+	if len(backend) == 0 {
+		return httperrors.NewInvalidStatusError("in-guest firewall is not enabled")
+	}
+	rules, err := self.getQgaFirewallRules()
+	if err != nil {
+		return errors.Wrap(err, "getQgaFirewallRules")
+	}
+	script, err := guestfirewall.Render(backend, rules)
+	if err != nil {
+		return errors.Wrap(err, "render firewall rules")
+	}
+	res, err := self.requestQgaAction(ctx, userCred, "qga-exec", self.getQgaFirewallExecInput(backend, script))
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_SYNC_FIREWALL, err, userCred, false)
+		return errors.Wrap(err, "qga exec")
+	}
+	output := &api.ServerQgaExecOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return errors.Wrap(err, "unmarshal qga exec output")
+	}
+	if !output.Exited || output.Exitcode != 0 {
+		reason := strings.TrimSpace(output.Stderr)
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_SYNC_FIREWALL, reason, userCred, false)
+		return errors.Errorf("apply %s rules exit code %d: %s", backend, output.Exitcode, reason)
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_SYNC_FIREWALL, backend, userCred, true)
+	return nil
+}
+
+func (self *SGuest) PerformSetQgaFirewall(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetQgaFirewallInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !input.Enable {
+		return nil, self.RemoveMetadata(ctx, api.VM_METADATA_QGA_FIREWALL_BACKEND, userCred)
+	}
+	if len(input.Backend) == 0 {
+		input.Backend = guestfirewall.BACKEND_IPTABLES
+		if strings.EqualFold(self.OsType, osprofile.OS_TYPE_WINDOWS) {
+			input.Backend = guestfirewall.BACKEND_WINDOWS
+		}
+	}
+	if !utils.IsInStringArray(input.Backend, guestfirewall.BACKENDS) {
+		return nil, httperrors.NewInputParameterError("invalid firewall backend %q, support %v", input.Backend, guestfirewall.BACKENDS)
+	}
+	err := self.SetMetadata(ctx, api.VM_METADATA_QGA_FIREWALL_BACKEND, input.Backend, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "set firewall backend")
+	}
+	if self.Status == api.VM_RUNNING {
+		return nil, self.SyncQgaFirewall(ctx, userCred)
+	}
+	return nil, nil
+}
+
+func (self *SGuest) PerformQgaSyncFirewall(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, self.SyncQgaFirewall(ctx, userCred)
+}
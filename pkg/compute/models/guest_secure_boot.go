@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) IsSecureBootEnabled(ctx context.Context) bool {
+	return self.GetMetadata(ctx, api.VM_METADATA_ENABLE_SECURE_BOOT, nil) == "true"
+}
+
+// 开启安全启动需要 UEFI 引导及 q35 机型, 宿主机会为虚拟机单独生成带密钥的 OVMF 变量文件
+func (self *SGuest) PerformSetSecureBoot(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetSecureBootInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set secure boot when guest is %s", self.Status)
+	}
+	var err error
+	if input.Enable {
+		if self.Bios != "UEFI" {
+			return nil, httperrors.NewUnsupportOperationError("secure boot requires UEFI boot mode")
+		}
+		if self.Machine != api.VM_MACHINE_TYPE_Q35 {
+			return nil, httperrors.NewUnsupportOperationError("secure boot requires machine type %s", api.VM_MACHINE_TYPE_Q35)
+		}
+		err = self.SetMetadata(ctx, api.VM_METADATA_ENABLE_SECURE_BOOT, "true", userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_ENABLE_SECURE_BOOT, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set secure boot metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_SECURE_BOOT, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) GetSharedDirs(ctx context.Context) []*api.GuestSharedDirJsonDesc {
+	dirs := make([]*api.GuestSharedDirJsonDesc, 0)
+	obj := self.GetMetadataJson(ctx, api.VM_METADATA_SHARED_DIRS, nil)
+	if obj == nil {
+		return dirs
+	}
+	if err := obj.Unmarshal(&dirs); err != nil {
+		log.Errorf("guest %s unmarshal shared dirs %s: %s", self.Name, obj, err)
+	}
+	return dirs
+}
+
+func (self *SGuest) setSharedDirs(ctx context.Context, userCred mcclient.TokenCredential, dirs []*api.GuestSharedDirJsonDesc) error {
+	if len(dirs) == 0 {
+		return self.RemoveMetadata(ctx, api.VM_METADATA_SHARED_DIRS, userCred)
+	}
+	return self.SetMetadata(ctx, api.VM_METADATA_SHARED_DIRS, jsonutils.Marshal(dirs), userCred)
+}
+
+func (self *SGuest) validateSharedDirStatus() error {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if !utils.IsInStringArray(self.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return httperrors.NewInvalidStatusError("Can't change shared dirs when guest is %s", self.Status)
+	}
+	return nil
+}
+
+func (self *SGuest) PerformAttachSharedDir(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerAttachSharedDirInput) (jsonutils.JSONObject, error) {
+	if err := self.validateSharedDirStatus(); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	dirs := self.GetSharedDirs(ctx)
+	for _, dir := range dirs {
+		if dir.Tag == input.Tag {
+			return nil, httperrors.NewDuplicateNameError("tag", input.Tag)
+		}
+	}
+	dirs = append(dirs, &input.GuestSharedDirJsonDesc)
+	if err := self.setSharedDirs(ctx, userCred, dirs); err != nil {
+		return nil, errors.Wrap(err, "setSharedDirs")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_ATTACH_SHARED_DIR, fmt.Sprintf("%s:%s", input.Tag, input.HostPath), userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
+
+func (self *SGuest) PerformDetachSharedDir(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerDetachSharedDirInput) (jsonutils.JSONObject, error) {
+	if err := self.validateSharedDirStatus(); err != nil {
+		return nil, err
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	dirs := self.GetSharedDirs(ctx)
+	newDirs := make([]*api.GuestSharedDirJsonDesc, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir.Tag != input.Tag {
+			newDirs = append(newDirs, dir)
+		}
+	}
+	if len(newDirs) == len(dirs) {
+		return nil, httperrors.NewResourceNotFoundError2("shared_dir", input.Tag)
+	}
+	if err := self.setSharedDirs(ctx, userCred, newDirs); err != nil {
+		return nil, errors.Wrap(err, "setSharedDirs")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_DETACH_SHARED_DIR, input.Tag, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
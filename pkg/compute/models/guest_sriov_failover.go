@@ -0,0 +1,176 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) IsSriovFailoverEnabled(ctx context.Context) bool {
+	return self.GetMetadata(ctx, api.VM_METADATA_SRIOV_FAILOVER, nil) == "true"
+}
+
+// 为 SR-IOV 网卡配对 virtio 备用网卡, 备用网卡需在虚机启动时创建, 因此仅允许关机状态设置
+func (self *SGuest) PerformSetSriovFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetSriovFailoverInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set sriov failover when guest is %s", self.Status)
+	}
+	var err error
+	if input.Enable {
+		err = self.SetMetadata(ctx, api.VM_METADATA_SRIOV_FAILOVER, "true", userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_SRIOV_FAILOVER, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set sriov failover metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_SRIOV_FAILOVER, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
+
+// 热迁移前检查直通设备, 仅开启备用切换的 SR-IOV 网卡可随迁移摘除并在目标宿主机重新分配
+func (self *SGuest) ValidateSriovFailoverMigrate(ctx context.Context) error {
+	devs, err := self.GetIsolatedDevices()
+	if err != nil {
+		return errors.Wrap(err, "GetIsolatedDevices")
+	}
+	if len(devs) == 0 {
+		return nil
+	}
+	for i := range devs {
+		if devs[i].DevType != api.NIC_TYPE {
+			return httperrors.NewNotSupportedError("Cannot live migrate with passthrough device %s (%s), try server-cold-migrate-with-devices", devs[i].Model, devs[i].DevType)
+		}
+	}
+	if !self.IsSriovFailoverEnabled(ctx) {
+		return httperrors.NewBadRequestError("Cannot live migrate with sriov nics, enable sriov failover and restart guest first")
+	}
+	// 暂停的虚机无法响应 VF 热拔出
+	if self.Status != api.VM_RUNNING {
+		return httperrors.NewInvalidStatusError("Cannot live migrate with sriov nics when guest is %s", self.Status)
+	}
+	return nil
+}
+
+func (self *SGuest) getSriovFailoverVfConfigs() ([]api.IsolatedDeviceConfig, []SIsolatedDevice, error) {
+	devs, err := self.GetIsolatedDevices()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetIsolatedDevices")
+	}
+	confs := []api.IsolatedDeviceConfig{}
+	vfs := []SIsolatedDevice{}
+	for i := range devs {
+		if devs[i].DevType != api.NIC_TYPE {
+			continue
+		}
+		networkIndex := devs[i].NetworkIndex
+		confs = append(confs, api.IsolatedDeviceConfig{
+			DevType:      devs[i].DevType,
+			Model:        devs[i].Model,
+			Vendor:       devs[i].getVendor(),
+			WireId:       devs[i].WireId,
+			NetworkIndex: &networkIndex,
+		})
+		vfs = append(vfs, devs[i])
+	}
+	return confs, vfs, nil
+}
+
+// 迁移前从虚机摘除 SR-IOV VF, 返回摘除的 VF 配置用于在目标宿主机重新分配
+func (self *SGuest) DetachSriovFailoverVfs(ctx context.Context, userCred mcclient.TokenCredential) ([]api.IsolatedDeviceConfig, error) {
+	if !self.IsSriovFailoverEnabled(ctx) {
+		return nil, nil
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	confs, vfs, err := self.getSriovFailoverVfConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range vfs {
+		if err := self.detachIsolateDevice(ctx, userCred, &vfs[i]); err != nil {
+			return nil, errors.Wrapf(err, "detach vf %s", vfs[i].Addr)
+		}
+	}
+	if len(vfs) > 0 {
+		go host.ClearSchedDescCache()
+	}
+	return confs, nil
+}
+
+// 在虚机当前所在宿主机上按型号和二层网络重新分配 VF, 已有 VF 的网卡跳过
+func (self *SGuest) AttachSriovFailoverVfs(ctx context.Context, userCred mcclient.TokenCredential, confs []api.IsolatedDeviceConfig) error {
+	host, err := self.GetHost()
+	if err != nil {
+		return errors.Wrap(err, "GetHost")
+	}
+	lockman.LockObject(ctx, host)
+	defer lockman.ReleaseObject(ctx, host)
+
+	devs, err := self.GetIsolatedDevices()
+	if err != nil {
+		return errors.Wrap(err, "GetIsolatedDevices")
+	}
+	attached := map[int8]bool{}
+	for i := range devs {
+		if devs[i].DevType == api.NIC_TYPE {
+			attached[devs[i].NetworkIndex] = true
+		}
+	}
+	defer func() { go host.ClearSchedDescCache() }()
+	for i := range confs {
+		if confs[i].NetworkIndex != nil && attached[*confs[i].NetworkIndex] {
+			continue
+		}
+		err := IsolatedDeviceManager.attachHostDeviceToGuestByModel(ctx, self, host, &confs[i], userCred)
+		if err != nil {
+			return errors.Wrapf(err, "attach vf %s on host %s", confs[i].Model, host.Name)
+		}
+	}
+	return nil
+}
+
+// 按数据库中的直通设备同步到宿主机, 运行中的虚机会热插拔 VF, 不改变迁移中的虚机状态
+func (self *SGuest) StartSriovFailoverSyncTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	return self.StartSyncTaskWithoutSyncstatus(ctx, userCred, false, parentTaskId)
+}
+
+func (self *SGuest) RecoverSriovFailoverVfs(ctx context.Context, userCred mcclient.TokenCredential, confs []api.IsolatedDeviceConfig) {
+	if err := self.AttachSriovFailoverVfs(ctx, userCred, confs); err != nil {
+		log.Errorf("guest %s recover sriov vfs: %s", self.Name, err)
+		return
+	}
+	if err := self.StartSriovFailoverSyncTask(ctx, userCred, ""); err != nil {
+		log.Errorf("guest %s start isolated device sync task: %s", self.Name, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	imageapi "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// Windows 11 内部版本号从 22000 开始
+const windows11MinBuild = 22000
+
+// 镜像显式声明需要 TPM, 或者是 Windows 11 镜像
+func isImageRequireTpm(imgProperties map[string]string) bool {
+	if imgProperties[imageapi.IMAGE_TPM_REQUIRED] == "true" {
+		return true
+	}
+	if !strings.EqualFold(imgProperties[imageapi.IMAGE_OS_TYPE], "windows") {
+		return false
+	}
+	distro := strings.ToLower(imgProperties[imageapi.IMAGE_OS_DISTRO])
+	if strings.Contains(distro, "windows 11") || strings.Contains(distro, "win11") {
+		return true
+	}
+	version := imgProperties[imageapi.IMAGE_OS_VERSION]
+	if version == "11" || strings.HasPrefix(version, "11.") {
+		return true
+	}
+	// e.g. 10.0.22621
+	parts := strings.Split(version, ".")
+	if len(parts) >= 3 && parts[0] == "10" {
+		if build, err := strconv.Atoi(parts[2]); err == nil && build >= windows11MinBuild {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *SGuest) PerformSetTpm(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetTpmInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set tpm when guest is %s", self.Status)
+	}
+	var err error
+	if input.Enable {
+		err = self.SetMetadata(ctx, api.VM_METADATA_ENABLE_TPM, "true", userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_ENABLE_TPM, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set tpm metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_TPM, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
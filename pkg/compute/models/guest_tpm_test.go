@@ -0,0 +1,36 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestIsImageRequireTpm(t *testing.T) {
+	cases := []struct {
+		props map[string]string
+		want  bool
+	}{
+		{map[string]string{"os_type": "Linux", "os_distribution": "Ubuntu"}, false},
+		{map[string]string{"os_type": "Windows", "os_version": "10.0.19045"}, false},
+		{map[string]string{"os_type": "Windows", "os_version": "10.0.22621"}, true},
+		{map[string]string{"os_type": "Windows", "os_distribution": "Windows 11 Pro"}, true},
+		{map[string]string{"os_type": "Windows", "os_version": "11"}, true},
+		{map[string]string{"os_type": "Linux", "tpm_required": "true"}, true},
+	}
+	for _, c := range cases {
+		if got := isImageRequireTpm(c.props); got != c.want {
+			t.Errorf("isImageRequireTpm(%v) = %v, want %v", c.props, got, c.want)
+		}
+	}
+}
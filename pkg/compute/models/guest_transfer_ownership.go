@@ -0,0 +1,258 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 支持迁移到其他云上项目(企业项目/资源组)的云上资源
+type ICloudProjectMigratable interface {
+	SetProjectId(projectId string) error
+}
+
+type iOwnerTransferable interface {
+	db.IVirtualModel
+
+	PerformChangeOwner(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input apis.PerformChangeProjectOwnerInput) (jsonutils.JSONObject, error)
+}
+
+// 随虚拟机一起迁移的资源: 磁盘及其快照, EIP, 主机快照, 仅指向虚拟机IP的DNS记录
+func (guest *SGuest) getOwnershipDependencies(skipDnsRecords bool) ([]iOwnerTransferable, error) {
+	ret := []iOwnerTransferable{}
+	disks, err := guest.GetDisks()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDisks")
+	}
+	for i := range disks {
+		cnt, err := disks[i].GetGuestsCount()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetGuestsCount")
+		}
+		if cnt > 1 {
+			return nil, httperrors.NewUnsupportOperationError("disk %s is attached to multiple servers", disks[i].Name)
+		}
+		snapshots := []SSnapshot{}
+		q := SnapshotManager.Query().Equals("disk_id", disks[i].Id)
+		err = db.FetchModelObjects(SnapshotManager, q, &snapshots)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch snapshots of disk %s", disks[i].Id)
+		}
+		for j := range snapshots {
+			ret = append(ret, &snapshots[j])
+		}
+		ret = append(ret, &disks[i])
+	}
+	if eip, _ := guest.GetEipOrPublicIp(); eip != nil {
+		ret = append(ret, eip)
+	}
+	isps, err := guest.GetInstanceSnapshots()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetInstanceSnapshots")
+	}
+	for i := range isps {
+		ret = append(ret, &isps[i])
+	}
+	if !skipDnsRecords {
+		records, err := guest.getExclusiveDnsRecords()
+		if err != nil {
+			return nil, errors.Wrapf(err, "getExclusiveDnsRecords")
+		}
+		for i := range records {
+			ret = append(ret, &records[i])
+		}
+	}
+	return ret, nil
+}
+
+// 同项目下, 所有A/AAAA记录都指向虚拟机IP的DNS记录
+func (guest *SGuest) getExclusiveDnsRecords() ([]SDnsRecord, error) {
+	ips := guest.GetRealIPs()
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	q := DnsRecordManager.Query().Equals("tenant_id", guest.ProjectId).IsFalse("is_public")
+	conds := []sqlchemy.ICondition{}
+	for _, ip := range ips {
+		conds = append(conds, sqlchemy.Contains(q.Field("records"), ip))
+	}
+	q = q.Filter(sqlchemy.OR(conds...))
+	records := []SDnsRecord{}
+	err := db.FetchModelObjects(DnsRecordManager, q, &records)
+	if err != nil {
+		return nil, err
+	}
+	ret := []SDnsRecord{}
+	for i := range records {
+		if isDnsRecordsOnlyForIps(records[i].GetInfo(), ips) {
+			ret = append(ret, records[i])
+		}
+	}
+	return ret, nil
+}
+
+func isDnsRecordsOnlyForIps(infos []string, ips []string) bool {
+	for _, info := range infos {
+		parts := strings.SplitN(info, ":", 2)
+		if len(parts) != 2 || (parts[0] != "A" && parts[0] != "AAAA") || !utils.IsInStringArray(parts[1], ips) {
+			return false
+		}
+	}
+	return len(infos) > 0
+}
+
+func validateOwnershipTransfer(ownerId mcclient.IIdentityProvider, items []iOwnerTransferable) error {
+	for _, item := range items {
+		if item.IsShared() {
+			return httperrors.NewForbiddenError("%s %s is shared", item.Keyword(), item.GetName())
+		}
+		if item.GetPendingDeleted() {
+			return httperrors.NewInvalidStatusError("%s %s is pending deleted", item.Keyword(), item.GetName())
+		}
+		manager := item.GetModelManager()
+		q := manager.Query().Equals("name", item.GetName())
+		q = manager.FilterByOwner(q, ownerId, manager.NamespaceScope())
+		q = manager.FilterBySystemAttributes(q, nil, nil, manager.ResourceScope())
+		q = q.NotEquals("id", item.GetId())
+		cnt, err := q.CountWithError()
+		if err != nil {
+			return httperrors.NewInternalServerError("check name duplication error: %s", err)
+		}
+		if cnt > 0 {
+			return httperrors.NewDuplicateNameError(item.Keyword(), item.GetName())
+		}
+	}
+	return nil
+}
+
+func changeOwnerWithLock(ctx context.Context, userCred mcclient.TokenCredential, item iOwnerTransferable, input apis.PerformChangeProjectOwnerInput) error {
+	lockman.LockObject(ctx, item)
+	defer lockman.ReleaseObject(ctx, item)
+
+	_, err := item.PerformChangeOwner(ctx, userCred, jsonutils.NewDict(), input)
+	return err
+}
+
+// 虚拟机连同依赖资源一起迁移项目, 任一资源失败则已迁移的资源回退到原项目
+func (guest *SGuest) PerformTransferOwnership(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerTransferOwnershipInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(guest.Status, []string{api.VM_READY, api.VM_RUNNING}) {
+		return nil, httperrors.NewInvalidStatusError("cannot transfer ownership in status %s", guest.Status)
+	}
+	ownerId, err := GuestManager.FetchOwnerId(ctx, jsonutils.Marshal(input.PerformChangeProjectOwnerInput))
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if ownerId == nil || len(ownerId.GetProjectId()) == 0 {
+		return nil, httperrors.NewInputParameterError("missing new project/tenant")
+	}
+	if ownerId.GetProjectId() == guest.ProjectId {
+		return nil, nil
+	}
+
+	items, err := guest.getOwnershipDependencies(input.SkipDnsRecords)
+	if err != nil {
+		return nil, err
+	}
+	err = validateOwnershipTransfer(ownerId, append(items, guest))
+	if err != nil {
+		return nil, err
+	}
+
+	former := apis.PerformChangeProjectOwnerInput{}
+	former.ProjectId = guest.ProjectId
+	rollback := func(done []iOwnerTransferable) {
+		for i := len(done) - 1; i >= 0; i-- {
+			err := changeOwnerWithLock(ctx, userCred, done[i], former)
+			if err != nil {
+				log.Errorf("rollback owner of %s %s error: %v", done[i].Keyword(), done[i].GetId(), err)
+			}
+		}
+	}
+
+	for i := range items {
+		err := changeOwnerWithLock(ctx, userCred, items[i], input.PerformChangeProjectOwnerInput)
+		if err != nil {
+			rollback(items[:i])
+			return nil, errors.Wrapf(err, "change owner of %s %s", items[i].Keyword(), items[i].GetName())
+		}
+	}
+	_, err = guest.SVirtualResourceBase.PerformChangeOwner(ctx, userCred, query, input.PerformChangeProjectOwnerInput)
+	if err != nil {
+		rollback(items)
+		return nil, err
+	}
+
+	host, _ := guest.GetHost()
+	if host != nil && host.GetCloudprovider() != nil {
+		return nil, guest.StartTransferOwnershipTask(ctx, userCred, "")
+	}
+	return nil, guest.StartSyncTask(ctx, userCred, false, "")
+}
+
+func (guest *SGuest) StartTransferOwnershipTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestTransferOwnershipTask", guest, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// 通过SyncProject获取本地项目映射的云上项目, 并将云上虚拟机迁移过去
+func (guest *SGuest) SyncCloudProjectMapping(ctx context.Context, userCred mcclient.TokenCredential) error {
+	host, err := guest.GetHost()
+	if err != nil {
+		return errors.Wrapf(err, "GetHost")
+	}
+	provider := host.GetCloudprovider()
+	if provider == nil {
+		return nil
+	}
+	extProjectId, err := provider.SyncProject(ctx, userCred, guest.ProjectId)
+	if err != nil {
+		return errors.Wrapf(err, "SyncProject")
+	}
+	// 平台不支持项目
+	if len(extProjectId) == 0 {
+		return nil
+	}
+	iVM, err := guest.GetIVM(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "GetIVM")
+	}
+	if iVM.GetProjectId() == extProjectId {
+		return nil
+	}
+	vm, ok := iVM.(ICloudProjectMigratable)
+	if !ok {
+		log.Warningf("%s not support migrate server %s to project %s", provider.Provider, guest.Name, extProjectId)
+		return nil
+	}
+	return vm.SetProjectId(extProjectId)
+}
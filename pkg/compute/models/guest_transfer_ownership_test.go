@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestIsDnsRecordsOnlyForIps(t *testing.T) {
+	ips := []string{"10.0.0.2", "fd00::2"}
+	cases := []struct {
+		infos []string
+		want  bool
+	}{
+		{[]string{"A:10.0.0.2"}, true},
+		{[]string{"A:10.0.0.2", "AAAA:fd00::2"}, true},
+		{[]string{"A:10.0.0.2", "A:10.0.0.3"}, false},
+		{[]string{"CNAME:www.example.com"}, false},
+		{[]string{"SRV:host:80:0:0"}, false},
+		{[]string{}, false},
+	}
+	for _, c := range cases {
+		if got := isDnsRecordsOnlyForIps(c.infos, ips); got != c.want {
+			t.Errorf("isDnsRecordsOnlyForIps(%v) = %v, want %v", c.infos, got, c.want)
+		}
+	}
+}
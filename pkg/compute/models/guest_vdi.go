@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strconv"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// fillVdiJsonDesc 将 spice usb 重定向及多显示器配置写入虚拟机描述
+func (self *SGuest) fillVdiJsonDesc(ctx context.Context, desc *api.GuestJsonDesc) {
+	if self.GetVdi() != api.VM_VDI_PROTOCOL_SPICE {
+		return
+	}
+	if val := self.GetMetadata(ctx, api.VM_METADATA_SPICE_USBREDIR_CHANNELS, nil); len(val) > 0 {
+		if channels, err := strconv.Atoi(val); err == nil {
+			desc.SpiceUsbredirChannels = &channels
+		}
+	}
+	if val := self.GetMetadata(ctx, api.VM_METADATA_VDI_MONITORS, nil); len(val) > 0 {
+		desc.VdiMonitors, _ = strconv.Atoi(val)
+	}
+}
+
+// 设置 spice usb 重定向通道数及显示器数量, 下次启动生效
+func (self *SGuest) PerformSetVdiOptions(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetVdiOptionsInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.GetVdi() != api.VM_VDI_PROTOCOL_SPICE {
+		return nil, httperrors.NewUnsupportOperationError("vdi options requires vdi protocol %s", api.VM_VDI_PROTOCOL_SPICE)
+	}
+	meta := map[string]interface{}{}
+	if input.SpiceUsbredirChannels != nil {
+		channels := *input.SpiceUsbredirChannels
+		if channels < 0 || channels > api.VM_SPICE_USBREDIR_MAX_CHANNELS {
+			return nil, httperrors.NewOutOfRangeError("spice_usbredir_channels should be in range 0-%d", api.VM_SPICE_USBREDIR_MAX_CHANNELS)
+		}
+		meta[api.VM_METADATA_SPICE_USBREDIR_CHANNELS] = strconv.Itoa(channels)
+	}
+	if input.VdiMonitors != nil {
+		monitors := *input.VdiMonitors
+		if monitors < 1 || monitors > api.VM_VDI_MAX_MONITORS {
+			return nil, httperrors.NewOutOfRangeError("vdi_monitors should be in range 1-%d", api.VM_VDI_MAX_MONITORS)
+		}
+		if monitors > 1 && !utils.IsInStringArray(self.getVga(), []string{api.VM_VIDEO_QXL, api.VM_VIDEO_VIRTIO}) {
+			return nil, httperrors.NewUnsupportOperationError("multiple monitors requires vga %s or %s", api.VM_VIDEO_QXL, api.VM_VIDEO_VIRTIO)
+		}
+		meta[api.VM_METADATA_VDI_MONITORS] = strconv.Itoa(monitors)
+	}
+	if len(meta) == 0 {
+		return nil, httperrors.NewMissingParameterError("spice_usbredir_channels or vdi_monitors")
+	}
+	err := self.SetAllMetadata(ctx, meta, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "set vdi options metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_VDI_OPTIONS, input, userCred, true)
+	return nil, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/scheduler"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) IsVirtioMemEnabled(ctx context.Context) bool {
+	return self.GetMetadata(ctx, api.VM_METADATA_ENABLE_VIRTIO_MEM, nil) == "true"
+}
+
+func (self *SGuest) PerformSetVirtioMem(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetVirtioMemInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set virtio-mem when guest is %s", self.Status)
+	}
+	var err error
+	if input.Enable {
+		err = self.SetMetadata(ctx, api.VM_METADATA_ENABLE_VIRTIO_MEM, "true", userCred)
+	} else {
+		err = self.RemoveMetadata(ctx, api.VM_METADATA_ENABLE_VIRTIO_MEM, userCred)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "set virtio-mem metadata")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_VIRTIO_MEM, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
+
+// 通过 virtio-mem 在线扩缩内存, 具体的可调整范围(机器类型上限, 块大小对齐)由宿主机校验
+func (self *SGuest) PerformResizeMemory(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerResizeMemoryInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("Can't resize memory when guest is %s", self.Status)
+	}
+	if !self.IsVirtioMemEnabled(ctx) {
+		return nil, httperrors.NewUnsupportOperationError("virtio-mem is not enabled, use change-config instead")
+	}
+	if input.VmemSize <= 0 {
+		return nil, httperrors.NewInputParameterError("invalid vmem_size %d", input.VmemSize)
+	}
+	addMem := input.VmemSize - self.VmemSize
+	if addMem == 0 {
+		return nil, nil
+	}
+
+	pendingUsage := &SQuota{}
+	if addMem > 0 {
+		schedDesc := self.changeConfToSchedDesc(0, addMem, nil)
+		s := auth.GetAdminSession(ctx, options.Options.Region)
+		canChangeConf, res, err := scheduler.SchedManager.DoScheduleForecast(s, schedDesc, 1)
+		if err != nil {
+			return nil, err
+		}
+		if !canChangeConf {
+			return nil, httperrors.NewInsufficientResourceError(res.String())
+		}
+
+		pendingUsage.Memory = addMem
+		keys, err := self.GetQuotaKeys()
+		if err != nil {
+			return nil, err
+		}
+		pendingUsage.SetKeys(keys)
+		err = quotas.CheckSetPendingQuota(ctx, userCred, pendingUsage)
+		if err != nil {
+			return nil, httperrors.NewOutOfQuotaError("%v", err)
+		}
+	}
+
+	err := self.StartResizeMemoryTask(ctx, userCred, int64(input.VmemSize), pendingUsage)
+	if err != nil {
+		if addMem > 0 {
+			quotas.CancelPendingUsage(ctx, userCred, pendingUsage, pendingUsage, false)
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (self *SGuest) StartResizeMemoryTask(ctx context.Context, userCred mcclient.TokenCredential, vmemSize int64, pendingUsage quotas.IQuota) error {
+	params := jsonutils.NewDict()
+	params.Set("vmem_size", jsonutils.NewInt(vmemSize))
+	self.SetStatus(userCred, api.VM_CHANGE_FLAVOR, fmt.Sprintf("resize memory to %dM", vmemSize))
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestResizeMemoryTask", self, userCred, params, "", "", pendingUsage)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/notify"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func (self *SGuest) PerformSetWatchdog(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetWatchdogInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set watchdog when guest is %s", self.Status)
+	}
+	var err error
+	if len(input.Model) == 0 {
+		for _, k := range []string{api.VM_METADATA_WATCHDOG_MODEL, api.VM_METADATA_WATCHDOG_ACTION, api.VM_METADATA_WATCHDOG_NOTIFY} {
+			if err = self.RemoveMetadata(ctx, k, userCred); err != nil {
+				return nil, errors.Wrapf(err, "remove metadata %s", k)
+			}
+		}
+	} else {
+		if !utils.IsInStringArray(input.Model, api.WATCHDOG_MODELS) {
+			return nil, httperrors.NewInputParameterError("invalid watchdog model %q, support %v", input.Model, api.WATCHDOG_MODELS)
+		}
+		// ib700 是 ISA 设备, 仅 x86 机型可用
+		if input.Model == api.WATCHDOG_MODEL_IB700 && apis.IsARM(self.OsArch) {
+			return nil, httperrors.NewNotSupportedError("watchdog model %s not supported on %s", input.Model, self.OsArch)
+		}
+		if len(input.Action) == 0 {
+			input.Action = api.WATCHDOG_ACTION_RESET
+		}
+		if !utils.IsInStringArray(input.Action, api.WATCHDOG_ACTIONS) {
+			return nil, httperrors.NewInputParameterError("invalid watchdog action %q, support %v", input.Action, api.WATCHDOG_ACTIONS)
+		}
+		err = self.SetAllMetadata(ctx, map[string]interface{}{
+			api.VM_METADATA_WATCHDOG_MODEL:  input.Model,
+			api.VM_METADATA_WATCHDOG_ACTION: input.Action,
+			api.VM_METADATA_WATCHDOG_NOTIFY: input.Notify,
+		}, userCred)
+		if err != nil {
+			return nil, errors.Wrap(err, "set watchdog metadata")
+		}
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_SET_WATCHDOG, input, userCred, true)
+	return nil, self.StartSyncTask(ctx, userCred, false, "")
+}
+
+// 宿主机上报的看门狗触发事件, 记录到虚机事件并按需通知所有者
+func (self *SGuest) onWatchdogEvent(ctx context.Context, userCred mcclient.TokenCredential, data jsonutils.JSONObject) {
+	db.OpsLog.LogEvent(self, db.ACT_GUEST_WATCHDOG, data.String(), userCred)
+	logclient.AddSimpleActionLog(self, logclient.ACT_GUEST_WATCHDOG, data.String(), userCred, true)
+	if self.GetMetadata(ctx, api.VM_METADATA_WATCHDOG_NOTIFY, userCred) != "true" {
+		return
+	}
+	kwargs := jsonutils.NewDict()
+	kwargs.Set("reason", data)
+	self.NotifyServerEvent(
+		ctx,
+		userCred,
+		notifyclient.SERVER_WATCHDOG,
+		notify.NotifyPriorityNormal,
+		false, kwargs, false,
+	)
+}
@@ -16,6 +16,7 @@ package models
 
 import (
 	"context"
+	"fmt"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -64,7 +65,7 @@ type SGuestdisk struct {
 
 	Driver    string `width:"32" charset:"ascii" nullable:"true" list:"user" update:"user"` // Column(VARCHAR(32, charset='ascii'), nullable=True)
 	CacheMode string `width:"32" charset:"ascii" nullable:"true" list:"user" update:"user"` // Column(VARCHAR(32, charset='ascii'), nullable=True)
-	AioMode   string `width:"32" charset:"ascii" nullable:"true" get:"user" update:"user"`  // Column(VARCHAR(32, charset='ascii'), nullable=True)
+	AioMode   string `width:"32" charset:"ascii" nullable:"true" list:"user" update:"user"` // Column(VARCHAR(32, charset='ascii'), nullable=True)
 	Iops      int    `nullable:"true" default:"0"`
 	Bps       int    `nullable:"true" default:"0"` // Mb
 
@@ -92,6 +93,13 @@ func (self *SGuestdisk) ValidateUpdateData(ctx context.Context, userCred mcclien
 			return input, httperrors.NewInputParameterError("DISK Index %d has been occupied", index)
 		}
 	}
+	if len(input.CacheMode) > 0 || len(input.AioMode) > 0 {
+		var err error
+		input, err = self.validateCacheAioMode(input)
+		if err != nil {
+			return input, err
+		}
+	}
 	var err error
 	input.GuestJointBaseUpdateInput, err = self.SGuestJointsBase.ValidateUpdateData(ctx, userCred, query, input.GuestJointBaseUpdateInput)
 	if err != nil {
@@ -100,6 +108,53 @@ func (self *SGuestdisk) ValidateUpdateData(ctx context.Context, userCred mcclien
 	return input, nil
 }
 
+func (self *SGuestdisk) validateCacheAioMode(input api.GuestdiskUpdateInput) (api.GuestdiskUpdateInput, error) {
+	guest := self.getGuest()
+	if guest == nil || guest.Hypervisor != api.HYPERVISOR_KVM {
+		return input, httperrors.NewUnsupportOperationError("cache_mode and aio_mode are only supported by kvm guests")
+	}
+	disk := self.GetDisk()
+	if disk == nil {
+		return input, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), self.DiskId)
+	}
+	storage, err := disk.GetStorage()
+	if err != nil {
+		return input, httperrors.NewGeneralError(errors.Wrapf(err, "GetStorage"))
+	}
+	cacheMode := self.CacheMode
+	if len(input.CacheMode) > 0 {
+		cacheMode = input.CacheMode
+	}
+	aioMode := input.AioMode
+	if len(aioMode) == 0 {
+		aioMode = self.AioMode
+		if len(input.CacheMode) > 0 && input.CacheMode != self.CacheMode {
+			// 仅修改缓存模式时随之调整 IO 后端
+			aioMode = api.DefaultDiskAioMode(cacheMode)
+			if self.AioMode == api.DISK_AIO_MODE_IO_URING {
+				aioMode = api.DISK_AIO_MODE_IO_URING
+			}
+			input.AioMode = aioMode
+		}
+	}
+	if err := api.ValidateDiskCacheAioMode(cacheMode, aioMode, storage.StorageType); err != nil {
+		return input, httperrors.NewInputParameterError("%v", err)
+	}
+	return input, nil
+}
+
+func (self *SGuestdisk) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SGuestJointsBase.PostUpdate(ctx, userCred, query, data)
+	if !data.Contains("cache_mode") && !data.Contains("aio_mode") {
+		return
+	}
+	guest := self.getGuest()
+	if guest != nil && guest.Status != api.VM_READY {
+		notes := fmt.Sprintf("disk %s cache_mode=%s aio_mode=%s, take effect after restart", self.DiskId, self.CacheMode, self.AioMode)
+		db.OpsLog.LogEvent(guest, db.ACT_UPDATE, notes, userCred)
+	}
+}
+
 func (manager *SGuestdiskManager) FetchCustomizeColumns(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -156,11 +211,7 @@ func (self *SGuestdisk) DoSave(ctx context.Context, driver string, cache string,
 	}
 	self.Driver = driver
 	self.CacheMode = cache
-	if cache == "none" {
-		self.AioMode = "native"
-	} else {
-		self.AioMode = "threads"
-	}
+	self.AioMode = api.DefaultDiskAioMode(cache)
 	return GuestdiskManager.TableSpec().Insert(ctx, self)
 }
 
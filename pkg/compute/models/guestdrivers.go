@@ -57,6 +57,7 @@ type IGuestDriver interface {
 	IsSupportPostpaidExpire() bool
 
 	RequestRenewInstance(ctx context.Context, guest *SGuest, bc billing.SBillingCycle) (time.Time, error)
+	RequestSetBootOrder(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, order string) error
 
 	GetJsonDescAtHost(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, host *SHost, params *jsonutils.JSONDict) (jsonutils.JSONObject, error)
 
@@ -163,6 +164,7 @@ type IGuestDriver interface {
 	AllowReconfigGuest() bool
 	DoGuestCreateDisksTask(ctx context.Context, guest *SGuest, task taskman.ITask) error
 	RequestChangeVmConfig(ctx context.Context, guest *SGuest, task taskman.ITask, instanceType string, vcpuCount, vmemSize int64) error
+	RequestResizeMemory(ctx context.Context, guest *SGuest, task taskman.ITask, vmemSize int64) error
 
 	NeedRequestGuestHotAddIso(ctx context.Context, guest *SGuest) bool
 	RequestGuestHotAddIso(ctx context.Context, guest *SGuest, path string, boot bool, task taskman.ITask) error
@@ -226,12 +228,19 @@ type IGuestDriver interface {
 
 	RequestCPUSet(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest, input *api.ServerCPUSetInput) (*api.ServerCPUSetResp, error)
 	RequestCPUSetRemove(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest, input *api.ServerCPUSetRemoveInput) error
+	RequestSetNicBandwidth(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest, gn *SGuestnetwork) error
 
 	QgaRequestGuestPing(ctx context.Context, task taskman.ITask, host *SHost, guest *SGuest) error
 	QgaRequestSetUserPassword(ctx context.Context, task taskman.ITask, host *SHost, guest *SGuest, input *api.ServerQgaSetPasswordInput) error
 	RequestQgaCommand(ctx context.Context, userCred mcclient.TokenCredential, body jsonutils.JSONObject, host *SHost, guest *SGuest) (jsonutils.JSONObject, error)
+	RequestQgaAction(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject, host *SHost, guest *SGuest) (jsonutils.JSONObject, error)
+
+	RequestLibvirtXml(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest) (*api.ServerLibvirtXmlOutput, error)
 
 	FetchMonitorUrl(ctx context.Context, guest *SGuest) string
+
+	RequestSnapshotGc(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest, input api.ServerSnapshotGcRequest) (*api.ServerSnapshotGcOutput, error)
+	RequestConfidentialVmAttestation(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, guest *SGuest) (*api.ServerConfidentialVmAttestationOutput, error)
 }
 
 var guestDrivers map[string]IGuestDriver
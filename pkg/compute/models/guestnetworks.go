@@ -89,6 +89,10 @@ type SGuestnetwork struct {
 	NumQueues int `nullable:"true" default:"1" list:"user" update:"user"`
 	// 带宽限制，单位mbps
 	BwLimit int `nullable:"false" default:"0" list:"user"`
+	// 入方向(宿主机到虚机)限速，单位mbps，0表示沿用BwLimit
+	IngressBwLimit int `nullable:"false" default:"0" list:"user"`
+	// 出方向(虚机到宿主机)限速，单位mbps，0表示沿用BwLimit
+	EgressBwLimit int `nullable:"false" default:"0" list:"user"`
 	// 网卡序号
 	Index int8 `nullable:"false" default:"0" list:"user" update:"user"`
 	// 是否为虚拟接口（无IP）
@@ -567,6 +571,8 @@ func (self *SGuestnetwork) getJsonDesc() *api.GuestnetworkJsonDesc {
 	desc.NumQueues = self.NumQueues
 	desc.Vlan = net.VlanId
 	desc.Bw = self.getBandwidth()
+	desc.BwIngress = self.IngressBwLimit
+	desc.BwEgress = self.EgressBwLimit
 	desc.Mtu = self.getMtu(net)
 	desc.Index = self.Index
 	desc.VirtualIps = self.GetVirtualIPs()
@@ -164,6 +164,11 @@ type SGuest struct {
 
 	// 套餐名称
 	InstanceType string `width:"64" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// QoS等级, 仅KVM有效
+	// example: burstable
+	QosClass string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 所在专有宿主机Id
+	DedicatedHostId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional" index:"true"`
 
 	SshableLastState tristate.TriState `default:"false" list:"user"`
 
@@ -843,6 +848,11 @@ func (guest *SGuest) ValidateDeleteCondition(ctx context.Context, info jsonutils
 		if host.HostStatus != api.HOST_ONLINE {
 			return httperrors.NewInputParameterError("Cannot delete server on offline host")
 		}
+		if provider := host.GetCloudprovider(); provider != nil {
+			if err := provider.ValidateWritable(); err != nil {
+				return err
+			}
+		}
 	}
 	return guest.validateDeleteCondition(ctx, false)
 }
@@ -1158,6 +1168,12 @@ func (self *SGuest) ValidateUpdateData(ctx context.Context, userCred mcclient.To
 		}
 	}
 
+	if input.BootOrder != nil {
+		if err := api.ValidateBootOrder(*input.BootOrder); err != nil {
+			return input, err
+		}
+	}
+
 	var err error
 	input, err = self.GetDriver().ValidateUpdateData(ctx, self, userCred, input)
 	if err != nil {
@@ -1271,6 +1287,21 @@ func (manager *SGuestManager) validateCreateData(
 		return nil, httperrors.NewInputParameterError("metdata must less then 20")
 	}
 
+	posture, err := ProjectPostureManager.GetPosture(ownerId.GetProjectDomainId(), ownerId.GetProjectId())
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetPosture")
+	}
+	if len(input.ImdsMode) > 0 && !utils.IsInStringArray(input.ImdsMode, api.IMDS_MODES) {
+		return nil, httperrors.NewInputParameterError("invalid imds_mode %s", input.ImdsMode)
+	}
+	if posture != nil {
+		input.Metadata, err = posture.mergeMandatoryTags(input.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		input.ImdsMode = api.StricterImdsMode(input.ImdsMode, posture.ImdsMode)
+	}
+
 	if len(input.InstanceSnapshotId) > 0 {
 		inputMem := input.VmemSize
 		inputCpu := input.VcpuCount
@@ -1316,7 +1347,7 @@ func (manager *SGuestManager) validateCreateData(
 
 	passwd := input.Password
 	if len(passwd) > 0 {
-		err = seclib2.ValidatePassword(passwd)
+		err = validateDomainGuestPassword(ownerId.GetProjectDomainId(), passwd)
 		if err != nil {
 			return nil, err
 		}
@@ -1356,6 +1387,7 @@ func (manager *SGuestManager) validateCreateData(
 	var hypervisor string
 	// var rootStorageType string
 	var osProf osprofile.SOSProfile
+	var imgRequireTpm bool
 	hypervisor = input.Hypervisor
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		if len(input.Disks) == 0 && input.Cdrom == "" {
@@ -1370,6 +1402,10 @@ func (manager *SGuestManager) validateCreateData(
 			if err != nil {
 				return nil, httperrors.NewInputParameterError("Invalid root image: %s", err)
 			}
+			err = manager.fillRootDiskImageArchVariant(ctx, userCred, input, diskConfig)
+			if err != nil {
+				return nil, httperrors.NewInputParameterError("Invalid root image: %s", err)
+			}
 			input.Disks[0] = diskConfig
 			imgEncryptKeyId = diskConfig.ImageEncryptKeyId
 			imgProperties = diskConfig.ImageProperties
@@ -1388,6 +1424,12 @@ func (manager *SGuestManager) validateCreateData(
 				imgProperties = image.Properties
 			}
 		}
+		if posture != nil {
+			err = posture.validateServerImages(input)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		// check boot indexes
 		bm := bitmap.NewBitMap(128)
@@ -1441,6 +1483,10 @@ func (manager *SGuestManager) validateCreateData(
 			imgProperties = map[string]string{"os_type": "Linux"}
 		}
 		input.DisableUsbKbd = imgProperties[imageapi.IMAGE_DISABLE_USB_KBD] == "true"
+		imgRequireTpm = isImageRequireTpm(imgProperties)
+		if imgRequireTpm && len(input.Bios) == 0 && (imgSupportUEFI == nil || *imgSupportUEFI) {
+			input.Bios = "UEFI"
+		}
 
 		if vdi, ok := imgProperties[imageapi.IMAGE_VDI_PROTOCOL]; ok && len(vdi) > 0 && len(input.Vdi) == 0 {
 			input.Vdi = vdi
@@ -1448,6 +1494,8 @@ func (manager *SGuestManager) validateCreateData(
 
 		if input.EncryptKeyId == nil && len(imgEncryptKeyId) > 0 {
 			input.EncryptKeyId = &imgEncryptKeyId
+		} else if input.EncryptKeyId == nil && input.EncryptKeyNew == nil && posture != nil && len(posture.EncryptKeyId) > 0 {
+			input.EncryptKeyId = &posture.EncryptKeyId
 		}
 		if input.EncryptKeyId != nil || input.EncryptKeyNew != nil {
 			input.EncryptedResourceCreateInput, err = manager.SEncryptedResourceManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EncryptedResourceCreateInput)
@@ -1490,6 +1538,33 @@ func (manager *SGuestManager) validateCreateData(
 	}
 
 	hypervisor = input.Hypervisor
+	if imgRequireTpm && hypervisor == api.HYPERVISOR_KVM {
+		input.EnableTpm = true
+	}
+	if input.EnableTpm && hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewInputParameterError("enable_tpm is not supported by hypervisor %s", hypervisor)
+	}
+	if input.EnableVirtioMem && hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewInputParameterError("enable_virtio_mem is not supported by hypervisor %s", hypervisor)
+	}
+	if input.EnableSecureBoot {
+		if hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewInputParameterError("enable_secure_boot is not supported by hypervisor %s", hypervisor)
+		}
+		if input.OsArch == apis.OS_ARCH_AARCH64 {
+			return nil, httperrors.NewInputParameterError("enable_secure_boot is not supported by arch %s", input.OsArch)
+		}
+		if len(input.Bios) == 0 {
+			input.Bios = "UEFI"
+		} else if input.Bios != "UEFI" {
+			return nil, httperrors.NewInputParameterError("secure boot requires UEFI boot mode")
+		}
+		if len(input.Machine) == 0 {
+			input.Machine = api.VM_MACHINE_TYPE_Q35
+		} else if input.Machine != api.VM_MACHINE_TYPE_Q35 {
+			return nil, httperrors.NewInputParameterError("secure boot requires machine type %s", api.VM_MACHINE_TYPE_Q35)
+		}
+	}
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		// support sku here
 		var sku *SServerSku
@@ -1520,6 +1595,30 @@ func (manager *SGuestManager) validateCreateData(
 			input.VcpuCount = vcpuCount
 		}
 
+		if len(input.QosClass) == 0 && sku != nil && hypervisor == api.HYPERVISOR_KVM {
+			input.QosClass = sku.QosClass
+		}
+		if len(input.QosClass) > 0 {
+			if hypervisor != api.HYPERVISOR_KVM {
+				return nil, httperrors.NewUnsupportOperationError("qos_class is not supported by hypervisor %s", hypervisor)
+			}
+			if !utils.IsInStringArray(input.QosClass, api.GUEST_QOS_CLASSES) {
+				return nil, httperrors.NewInputParameterError("qos_class shoud be one of %s", api.GUEST_QOS_CLASSES)
+			}
+		}
+		if len(input.DedicatedHostId) > 0 {
+			err = manager.validateDedicatedHost(userCred, input)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(input.NetworkLatencyPeerHosts) > 0 {
+			err = manager.validateNetworkLatencyPeerHosts(userCred, input)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		dataDiskDefs := []*api.DiskConfig{}
 		if sku != nil && sku.AttachedDiskCount > 0 {
 			if sku.AttachedDiskSizeGB == 0 {
@@ -1712,6 +1811,9 @@ func (manager *SGuestManager) validateCreateData(
 			return nil, httperrors.NewResourceNotFoundError("Secgroup %s not found", secGrpId)
 		}
 		input.SecgroupId = secGrpObj.GetId()
+	} else if defaults := posture.getDefaultSecgroups(); len(defaults) > 0 {
+		input.SecgroupId = defaults[0]
+		input.Secgroups = defaults[1:]
 	} else {
 		input.SecgroupId = options.Options.DefaultSecurityGroupId
 	}
@@ -1940,6 +2042,7 @@ func (self *SGuest) PostUpdate(ctx context.Context, userCred mcclient.TokenCrede
 			log.Errorf("unable to set sshport for guest %s", self.GetId())
 		}
 	}
+	recordConfigHistory(ctx, self)
 }
 
 func (manager *SGuestManager) checkCreateQuota(
@@ -2093,9 +2196,21 @@ func (guest *SGuest) PostCreate(ctx context.Context, userCred mcclient.TokenCred
 	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_MEMCLEAN, false) {
 		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_MEMCLEAN, "true", userCred)
 	}
+	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_TPM, false) && guest.Hypervisor == api.HYPERVISOR_KVM {
+		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_TPM, "true", userCred)
+	}
+	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_VIRTIO_MEM, false) && guest.Hypervisor == api.HYPERVISOR_KVM {
+		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_VIRTIO_MEM, "true", userCred)
+	}
+	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_SECURE_BOOT, false) && guest.Hypervisor == api.HYPERVISOR_KVM {
+		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_SECURE_BOOT, "true", userCred)
+	}
 	if jsonutils.QueryBoolean(data, imageapi.IMAGE_DISABLE_USB_KBD, false) {
 		guest.SetMetadata(ctx, imageapi.IMAGE_DISABLE_USB_KBD, "true", userCred)
 	}
+	if imdsMode, _ := data.GetString("imds_mode"); len(imdsMode) > 0 && imdsMode != api.IMDS_MODE_ENABLED {
+		guest.SetMetadata(ctx, api.VM_METADATA_IMDS_MODE, imdsMode, userCred)
+	}
 
 	userData, _ := data.GetString("user_data")
 	if len(userData) > 0 {
@@ -2935,6 +3050,8 @@ func (self *SGuest) syncWithCloudVM(ctx context.Context, userCred mcclient.Token
 
 	syncVirtualResourceMetadata(ctx, userCred, self, extVM)
 	SyncCloudProject(userCred, self, syncOwnerId, extVM, host.ManagerId)
+	self.syncMaintenanceEvents(ctx, userCred, extVM)
+	self.syncDedicatedHost(ctx, userCred, extVM, host.ManagerId)
 
 	if provider.GetFactory().IsSupportPrepaidResources() && recycle {
 		vhost, _ := self.GetHost()
@@ -4468,6 +4585,17 @@ func (self *SGuest) GetDeployConfigOnHost(ctx context.Context, userCred mcclient
 		return nil, err
 	}
 
+	deployTelegraf := jsonutils.QueryBoolean(params, "deploy_telegraf", false)
+	// 开启监控 agent 时, KVM 虚机通过 deploy agent 注入 telegraf 及注册 token
+	if options.Options.EnableMonitorAgent && self.Hypervisor == api.HYPERVISOR_KVM {
+		deployTelegraf = true
+		agentDeploys, err := self.GetMonitorAgentDeployConfigs(ctx, userCred)
+		if err != nil {
+			return nil, errors.Wrap(err, "GetMonitorAgentDeployConfigs")
+		}
+		deploys = append(deploys, agentDeploys...)
+	}
+
 	if len(deploys) > 0 {
 		config.Add(jsonutils.Marshal(deploys), "deploys")
 	}
@@ -4491,6 +4619,9 @@ func (self *SGuest) GetDeployConfigOnHost(ctx context.Context, userCred mcclient
 	if resetPasswd {
 		config.Add(jsonutils.JSONTrue, "reset_password")
 		passwd, _ := params.GetString("password")
+		if len(passwd) == 0 {
+			passwd = self.generatePassword()
+		}
 		if len(passwd) > 0 {
 			config.Add(jsonutils.NewString(passwd), "password")
 		}
@@ -4532,7 +4663,7 @@ func (self *SGuest) GetDeployConfigOnHost(ctx context.Context, userCred mcclient
 
 	config.Add(jsonutils.NewString(onFinish), "on_finish")
 
-	if jsonutils.QueryBoolean(params, "deploy_telegraf", false) {
+	if deployTelegraf {
 		influxdbUrl := self.GetDriver().FetchMonitorUrl(ctx, self)
 		config.Add(jsonutils.JSONTrue, "deploy_telegraf")
 		serverDetails, err := self.getDetails(ctx, userCred)
@@ -4666,6 +4797,8 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		SrcMacCheck: self.SrcMacCheck.Bool(),
 		HostId:      host.Id,
 
+		QosClass: self.QosClass,
+
 		EncryptKeyId: self.EncryptKeyId,
 
 		IsDaemon: self.IsDaemon.Bool(),
@@ -4685,12 +4818,18 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		desc.IsVolatileHost = true
 	}
 
+	self.fillVdiJsonDesc(ctx, desc)
+
 	// isolated devices
 	isolatedDevs, _ := self.GetIsolatedDevices()
 	for _, dev := range isolatedDevs {
 		desc.IsolatedDevices = append(desc.IsolatedDevices, dev.getDesc())
 	}
 
+	if self.Hypervisor == api.HYPERVISOR_KVM {
+		desc.SharedDirs = self.GetSharedDirs(ctx)
+	}
+
 	// nics, domain
 	desc.Domain = options.Options.DNSDomain
 	nics, _ := self.GetNetworks("")
@@ -5138,6 +5277,74 @@ func (self *SGuest) SaveDeployInfo(ctx context.Context, userCred mcclient.TokenC
 	self.saveOldPassword(ctx, userCred)
 }
 
+type sProviderPlacementInfo struct {
+	Hostname       string
+	PrivateDnsName string
+	HostExternalId string
+}
+
+// SaveProviderPlacementInfo 保存公有云分配的主机名及内网 DNS 名称,
+// 并在虚机实际所在可用区与调度结果不一致时将虚机归位到实际所在的宿主机
+func (self *SGuest) SaveProviderPlacementInfo(ctx context.Context, userCred mcclient.TokenCredential, data jsonutils.JSONObject) {
+	placement := sProviderPlacementInfo{}
+	data.Unmarshal(&placement)
+	info := make(map[string]interface{})
+	if len(placement.Hostname) > 0 {
+		info[api.VM_METADATA_PROVIDER_HOSTNAME] = placement.Hostname
+	}
+	if len(placement.PrivateDnsName) > 0 {
+		info[api.VM_METADATA_PRIVATE_DNS_NAME] = placement.PrivateDnsName
+	}
+	if len(placement.HostExternalId) > 0 {
+		zone, err := self.reconcileProviderHost(ctx, userCred, placement.HostExternalId)
+		if err != nil {
+			log.Errorf("reconcile guest %s provider host %s: %v", self.Name, placement.HostExternalId, err)
+		} else if zone != nil {
+			info[api.VM_METADATA_PROVIDER_ZONE] = zone.Name
+		}
+	}
+	if len(info) > 0 {
+		self.SetAllMetadata(ctx, info, userCred)
+	}
+}
+
+func (self *SGuest) reconcileProviderHost(ctx context.Context, userCred mcclient.TokenCredential, hostExternalId string) (*SZone, error) {
+	curHost, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	host, err := db.FetchByExternalIdAndManagerId(HostManager, hostExternalId, func(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+		return q.Equals("manager_id", curHost.ManagerId)
+	})
+	if err != nil {
+		// 宿主机尚未同步到本地, 等待下次同步归位
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fetch host by external id %s", hostExternalId)
+	}
+	realHost := host.(*SHost)
+	zone, err := realHost.GetZone()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetZone")
+	}
+	if realHost.Id == curHost.Id {
+		return zone, nil
+	}
+	if realHost.ZoneId != curHost.ZoneId {
+		log.Warningf("guest %s scheduled to zone %s but provider placed it in zone %s", self.Name, curHost.ZoneId, realHost.ZoneId)
+	}
+	diff, err := db.Update(self, func() error {
+		self.HostId = realHost.Id
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update host_id")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, diff, userCred)
+	return zone, nil
+}
+
 func (self *SGuest) isAllDisksReady() bool {
 	ready := true
 	disks, _ := self.GetGuestDisks()
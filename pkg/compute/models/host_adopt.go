@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+func (self *SHost) validateAdoptUnmanagedGuests() error {
+	if self.HostType != api.HOST_TYPE_HYPERVISOR {
+		return httperrors.NewNotSupportedError("host type %s not support adopt unmanaged guests", self.HostType)
+	}
+	if self.HostStatus != api.HOST_ONLINE {
+		return httperrors.NewInvalidStatusError("host %s is not online", self.Name)
+	}
+	return nil
+}
+
+// 列出宿主机上运行中但未被纳管的虚机
+func (self *SHost) GetDetailsUnmanagedGuests(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (api.HostUnmanagedGuestsOutput, error) {
+	output := api.HostUnmanagedGuestsOutput{}
+	if err := self.validateAdoptUnmanagedGuests(); err != nil {
+		return output, err
+	}
+	ret, err := self.Request(ctx, userCred, httputils.GET, "/servers/unmanaged-guests", mcclient.GetTokenHeaders(userCred), nil)
+	if err != nil {
+		return output, errors.Wrap(err, "request unmanaged guests")
+	}
+	if err := ret.Unmarshal(&output); err != nil {
+		return output, errors.Wrap(err, "unmarshal unmanaged guests")
+	}
+	return output, nil
+}
+
+// 接管宿主机上运行中的虚机, 无需重启
+func (self *SHost) PerformAdoptUnmanagedGuests(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostAdoptUnmanagedGuestsInput) (jsonutils.JSONObject, error) {
+	if err := self.validateAdoptUnmanagedGuests(); err != nil {
+		return nil, err
+	}
+	if len(input.Servers) == 0 {
+		return nil, httperrors.NewMissingParameterError("servers")
+	}
+	if err := validateImportServersMacIp(input.Servers); err != nil {
+		return nil, err
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "HostAdoptUnmanagedGuestsTask", self, userCred,
+		jsonutils.Marshal(input).(*jsonutils.JSONDict), "", "", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil, nil
+}
+
+// 将 libvirt domain xml 尽力转换为虚机描述, 用于从 libvirt 环境迁移前的核对
+func (self *SHost) PerformLibvirtXmlToDesc(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SLibvirtXmlToDescInput) (*api.SLibvirtXmlToDescOutput, error) {
+	if self.HostType != api.HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewNotSupportedError("host type %s not support libvirt xml", self.HostType)
+	}
+	if len(input.Xml) == 0 {
+		return nil, httperrors.NewMissingParameterError("xml")
+	}
+	ret, err := self.Request(ctx, userCred, httputils.POST, "/servers/libvirt-xml-to-desc", mcclient.GetTokenHeaders(userCred), jsonutils.Marshal(input))
+	if err != nil {
+		return nil, errors.Wrap(err, "request libvirt xml to desc")
+	}
+	output := &api.SLibvirtXmlToDescOutput{}
+	if err := ret.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal libvirt xml desc")
+	}
+	return output, nil
+}
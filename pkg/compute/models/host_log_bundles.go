@@ -0,0 +1,345 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 宿主机诊断日志包, 由宿主机收集 qemu 日志, hostagent 日志, ovs 信息及虚机描述文件,
+// 脱敏打包后上传到对象存储, 通过预签名链接下载
+type SHostLogBundleManager struct {
+	db.SStatusStandaloneResourceBaseManager
+	SHostResourceBaseManager
+}
+
+var HostLogBundleManager *SHostLogBundleManager
+
+func init() {
+	HostLogBundleManager = &SHostLogBundleManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SHostLogBundle{},
+			"host_log_bundles_tbl",
+			"host_log_bundle",
+			"host_log_bundles",
+		),
+	}
+	HostLogBundleManager.SetVirtualObject(HostLogBundleManager)
+}
+
+type SHostLogBundle struct {
+	db.SStatusStandaloneResourceBase
+	SHostResourceBase
+
+	// 收集 qemu 日志的虚机
+	GuestId    string `width:"36" charset:"ascii" nullable:"true" list:"user" json:"guest_id"`
+	IncludeOvs bool   `nullable:"false" default:"true" list:"user" json:"include_ovs"`
+	SinceHours int    `nullable:"false" default:"24" list:"user" json:"since_hours"`
+
+	BucketId  string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true" json:"bucket_id"`
+	ObjectKey string `width:"512" charset:"utf8" nullable:"false" list:"user" json:"object_key"`
+	SizeBytes int64  `nullable:"false" default:"0" list:"user" json:"size_bytes"`
+	// 日志包内的文件数
+	FileCount   int       `nullable:"false" default:"0" list:"user" json:"file_count"`
+	CollectedAt time.Time `nullable:"true" list:"user" json:"collected_at"`
+}
+
+func (manager *SHostLogBundleManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.HostLogBundleCreateInput) (*jsonutils.JSONDict, error) {
+	var err error
+	input.StatusStandaloneResourceCreateInput, err = manager.SStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusStandaloneResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.HostId) == 0 {
+		return nil, httperrors.NewMissingParameterError("host_id")
+	}
+	host, hostInput, err := ValidateHostResourceInput(userCred, input.HostResourceInput)
+	if err != nil {
+		return nil, err
+	}
+	input.HostResourceInput = hostInput
+	if host.HostType != api.HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewNotSupportedError("collect log bundle not supported by host type %s", host.HostType)
+	}
+	if host.HostStatus != api.HOST_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("host %s is %s", host.Name, host.HostStatus)
+	}
+	if len(input.ServerId) > 0 {
+		guestObj, err := GuestManager.FetchByIdOrName(userCred, input.ServerId)
+		if err != nil {
+			if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+				return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), input.ServerId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		guest := guestObj.(*SGuest)
+		if guest.HostId != host.Id {
+			return nil, httperrors.NewInputParameterError("server %s is not on host %s", guest.Name, host.Name)
+		}
+		input.ServerId = guest.Id
+	}
+	if input.SinceHours <= 0 {
+		input.SinceHours = api.HOST_LOG_BUNDLE_DEFAULT_SINCE_HOURS
+	}
+	if input.SinceHours > 30*24 {
+		return nil, httperrors.NewOutOfRangeError("since_hours should not be greater than %d", 30*24)
+	}
+	if input.IncludeOvs == nil {
+		includeOvs := true
+		input.IncludeOvs = &includeOvs
+	}
+	if len(input.BucketId) == 0 {
+		return nil, httperrors.NewMissingParameterError("bucket_id")
+	}
+	bucketObj, err := BucketManager.FetchByIdOrName(userCred, input.BucketId)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(BucketManager.Keyword(), input.BucketId)
+	}
+	input.BucketId = bucketObj.GetId()
+	input.Status = api.HOST_LOG_BUNDLE_STATUS_COLLECTING
+
+	data := input.JSON(input)
+	data.Set("guest_id", jsonutils.NewString(input.ServerId))
+	name := fmt.Sprintf("%s-%s.tar.gz", host.Name, time.Now().UTC().Format("20060102150405"))
+	data.Set("object_key", jsonutils.NewString(path.Join(input.KeyPrefix, name)))
+	return data, nil
+}
+
+func (self *SHostLogBundle) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	err := self.StartCollectTask(ctx, userCred, "")
+	if err != nil {
+		log.Errorf("StartCollectTask for %s error: %v", self.Name, err)
+	}
+}
+
+func (self *SHostLogBundle) StartCollectTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "HostLogBundleCollectTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.HOST_LOG_BUNDLE_STATUS_COLLECT_FAILED, err.Error())
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SHostLogBundle) GetBucket() (*SBucket, error) {
+	obj, err := BucketManager.FetchById(self.BucketId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "BucketManager.FetchById(%s)", self.BucketId)
+	}
+	return obj.(*SBucket), nil
+}
+
+func (self *SHostLogBundle) tempUrl(ctx context.Context, method string, expire time.Duration) (string, error) {
+	bucket, err := self.GetBucket()
+	if err != nil {
+		return "", err
+	}
+	iBucket, err := bucket.GetIBucket(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "GetIBucket")
+	}
+	return iBucket.GetTempUrl(method, self.ObjectKey, expire)
+}
+
+// 宿主机收集日志包并通过预签名地址上传
+func (self *SHostLogBundle) RequestCollect(ctx context.Context, task taskman.ITask) error {
+	hostObj, err := HostManager.FetchById(self.HostId)
+	if err != nil {
+		return errors.Wrapf(err, "HostManager.FetchById(%s)", self.HostId)
+	}
+	host := hostObj.(*SHost)
+	// 上传地址需覆盖宿主机收集及上传耗时
+	uploadUrl, err := self.tempUrl(ctx, "PUT", 2*time.Hour)
+	if err != nil {
+		return errors.Wrap(err, "upload url")
+	}
+	input := api.HostLogBundleCollectInput{
+		ServerId:   self.GuestId,
+		IncludeOvs: self.IncludeOvs,
+		Since:      self.CreatedAt.Add(-time.Duration(self.SinceHours) * time.Hour),
+		UploadUrl:  uploadUrl,
+	}
+	return host.GetHostDriver().RequestCollectLogBundle(ctx, host, input, task)
+}
+
+func (self *SHostLogBundle) SaveCollectResult(output api.HostLogBundleCollectOutput) error {
+	_, err := db.Update(self, func() error {
+		self.SizeBytes = output.SizeBytes
+		self.FileCount = len(output.Files)
+		self.CollectedAt = time.Now().UTC()
+		return nil
+	})
+	return err
+}
+
+// 获取日志包的预签名下载链接
+func (self *SHostLogBundle) GetDetailsDownloadUrl(ctx context.Context, userCred mcclient.TokenCredential, input api.HostLogBundleDownloadUrlInput) (*api.HostLogBundleDownloadUrlOutput, error) {
+	if self.Status != api.HOST_LOG_BUNDLE_STATUS_READY {
+		return nil, httperrors.NewInvalidStatusError("log bundle %s is %s", self.Name, self.Status)
+	}
+	if input.ExpireSeconds <= 0 {
+		input.ExpireSeconds = api.HOST_LOG_BUNDLE_DEFAULT_URL_EXPIRE
+	}
+	if input.ExpireSeconds > api.HOST_LOG_BUNDLE_MAX_URL_EXPIRE {
+		return nil, httperrors.NewOutOfRangeError("expire_seconds should not be greater than %d", api.HOST_LOG_BUNDLE_MAX_URL_EXPIRE)
+	}
+	expire := time.Duration(input.ExpireSeconds) * time.Second
+	url, err := self.tempUrl(ctx, "GET", expire)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return &api.HostLogBundleDownloadUrlOutput{
+		Url:      url,
+		ExpireAt: time.Now().Add(expire),
+	}, nil
+}
+
+func (self *SHostLogBundle) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if self.Status == api.HOST_LOG_BUNDLE_STATUS_COLLECTING {
+		return httperrors.NewInvalidStatusError("log bundle %s is %s", self.Name, self.Status)
+	}
+	return self.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+// 删除时一并清理存储桶中的日志包, 清理失败不阻止删除
+func (self *SHostLogBundle) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if self.SizeBytes > 0 {
+		if bucket, err := self.GetBucket(); err == nil {
+			iBucket, err := bucket.GetIBucket(ctx)
+			if err == nil {
+				err = iBucket.DeleteObject(ctx, self.ObjectKey)
+			}
+			if err != nil {
+				log.Warningf("delete log bundle object %s of %s: %v", self.ObjectKey, self.Name, err)
+			}
+		}
+	}
+	return self.SStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
+
+func (manager *SHostLogBundleManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.HostLogBundleListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SHostResourceBaseManager.ListItemFilter(ctx, q, userCred, query.HostFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SHostResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ServerId) > 0 {
+		guestObj, err := GuestManager.FetchByIdOrName(userCred, query.ServerId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), query.ServerId)
+		}
+		q = q.Equals("guest_id", guestObj.GetId())
+	}
+	if len(query.BucketId) > 0 {
+		bucketObj, err := BucketManager.FetchByIdOrName(userCred, query.BucketId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(BucketManager.Keyword(), query.BucketId)
+		}
+		q = q.Equals("bucket_id", bucketObj.GetId())
+	}
+	return q, nil
+}
+
+func (manager *SHostLogBundleManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.HostLogBundleListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SHostResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.HostFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SHostResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SHostLogBundleManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SHostResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SHostLogBundleManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.HostLogBundleDetails {
+	rows := make([]api.HostLogBundleDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	hostRows := manager.SHostResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	guestIds, bucketIds := make([]string, len(objs)), make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		rows[i].HostResourceInfo = hostRows[i]
+		bundle := objs[i].(*SHostLogBundle)
+		guestIds[i], bucketIds[i] = bundle.GuestId, bundle.BucketId
+	}
+	guests := make(map[string]SGuest)
+	if err := db.FetchStandaloneObjectsByIds(GuestManager, guestIds, guests); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds guests fail %s", err)
+		return rows
+	}
+	buckets := make(map[string]SBucket)
+	if err := db.FetchStandaloneObjectsByIds(BucketManager, bucketIds, buckets); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds buckets fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if guest, ok := guests[guestIds[i]]; ok {
+			rows[i].Server = guest.Name
+		}
+		if bucket, ok := buckets[bucketIds[i]]; ok {
+			rows[i].Bucket = bucket.Name
+		}
+	}
+	return rows
+}
@@ -0,0 +1,277 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// +onecloud:swagger-gen-ignore
+type SHostNetworkProbeManager struct {
+	db.SResourceBaseManager
+}
+
+var HostNetworkProbeManager *SHostNetworkProbeManager
+
+func init() {
+	HostNetworkProbeManager = &SHostNetworkProbeManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SHostNetworkProbe{},
+			"host_network_probes_tbl",
+			"host_network_probe",
+			"host_network_probes",
+		),
+	}
+	HostNetworkProbeManager.SetVirtualObject(HostNetworkProbeManager)
+}
+
+// SHostNetworkProbe 宿主机到对端宿主机最近一次的网络探测结果
+type SHostNetworkProbe struct {
+	db.SResourceBase
+
+	HostId            string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	PeerHostId        string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	PeerCloudregionId string `width:"36" charset:"ascii" nullable:"false" default:""`
+
+	LatencyMs     float64 `nullable:"false" default:"0"`
+	JitterMs      float64 `nullable:"false" default:"0"`
+	LossRate      float64 `nullable:"false" default:"0"`
+	BandwidthMbps float64 `nullable:"false" default:"0"`
+
+	ProbedAt time.Time `nullable:"false"`
+}
+
+func (self *SHostNetworkProbe) GetId() string {
+	return fmt.Sprintf("%s/%s", self.HostId, self.PeerHostId)
+}
+
+func (self *SHostNetworkProbe) GetName() string {
+	return self.GetId()
+}
+
+func (manager *SHostNetworkProbeManager) saveResults(ctx context.Context, host *SHost, results []api.HostNetworkProbeResult) error {
+	now := time.Now()
+	for _, result := range results {
+		peerObj, err := HostManager.FetchById(result.PeerHostId)
+		if err != nil {
+			log.Warningf("network probe peer host %s: %v", result.PeerHostId, err)
+			continue
+		}
+		peer := peerObj.(*SHost)
+		probe := SHostNetworkProbe{
+			HostId:        host.Id,
+			PeerHostId:    peer.Id,
+			LatencyMs:     result.LatencyMs,
+			JitterMs:      result.JitterMs,
+			LossRate:      result.LossRate,
+			BandwidthMbps: result.BandwidthMbps,
+			ProbedAt:      now,
+		}
+		if zone, err := peer.GetZone(); err == nil {
+			probe.PeerCloudregionId = zone.CloudregionId
+		}
+		probe.SetModelManager(manager, &probe)
+		if err := manager.TableSpec().InsertOrUpdate(ctx, &probe); err != nil {
+			return errors.Wrapf(err, "save network probe %s", probe.GetId())
+		}
+	}
+	return nil
+}
+
+func (manager *SHostNetworkProbeManager) removeProbesByHost(ctx context.Context, userCred mcclient.TokenCredential, hostId string) error {
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.Equals(q.Field("host_id"), hostId),
+		sqlchemy.Equals(q.Field("peer_host_id"), hostId),
+	))
+	probes := make([]SHostNetworkProbe, 0)
+	if err := db.FetchModelObjects(manager, q, &probes); err != nil {
+		return errors.Wrap(err, "db.FetchModelObjects")
+	}
+	for i := range probes {
+		if err := probes[i].Delete(ctx, userCred); err != nil {
+			return errors.Wrapf(err, "delete network probe %s", probes[i].GetId())
+		}
+	}
+	return nil
+}
+
+// GetLatencies 返回未过期的探测结果, 按 宿主机 => 对端宿主机 => 时延(毫秒) 组织, 两个方向的结果都会记录
+func (manager *SHostNetworkProbeManager) GetLatencies(peerHostIds []string, expire time.Duration) (map[string]map[string]float64, error) {
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.In(q.Field("host_id"), peerHostIds),
+		sqlchemy.In(q.Field("peer_host_id"), peerHostIds),
+	))
+	q = q.GE("probed_at", time.Now().Add(-expire)).LT("loss_rate", 1)
+	probes := make([]SHostNetworkProbe, 0)
+	if err := db.FetchModelObjects(manager, q, &probes); err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	ret := map[string]map[string]float64{}
+	set := func(src, dst string, latency float64) {
+		if _, ok := ret[src]; !ok {
+			ret[src] = map[string]float64{}
+		}
+		// 双向都有结果时取较小值
+		if old, ok := ret[src][dst]; !ok || latency < old {
+			ret[src][dst] = latency
+		}
+	}
+	for _, p := range probes {
+		set(p.HostId, p.PeerHostId, p.LatencyMs)
+		set(p.PeerHostId, p.HostId, p.LatencyMs)
+	}
+	return ret, nil
+}
+
+// 同区域内的宿主机两两探测, 其他区域各选一台宿主机作为代表
+func (manager *SHostNetworkProbeManager) getProbeTargets(host *SHost) ([]api.HostNetworkProbeTarget, error) {
+	zone, err := host.GetZone()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetZone")
+	}
+	hosts := HostManager.Query().SubQuery()
+	zones := ZoneManager.Query().SubQuery()
+	q := hosts.Query(
+		hosts.Field("id", "host_id"),
+		hosts.Field("name"),
+		hosts.Field("manager_uri"),
+		hosts.Field("zone_id"),
+		zones.Field("cloudregion_id"),
+	).Join(zones, sqlchemy.Equals(hosts.Field("zone_id"), zones.Field("id")))
+	q = q.Filter(sqlchemy.AND(
+		sqlchemy.NotEquals(hosts.Field("id"), host.Id),
+		sqlchemy.Equals(hosts.Field("host_type"), api.HOST_TYPE_HYPERVISOR),
+		sqlchemy.Equals(hosts.Field("host_status"), api.HOST_ONLINE),
+		sqlchemy.IsTrue(hosts.Field("enabled")),
+		sqlchemy.IsNotEmpty(hosts.Field("manager_uri")),
+	))
+	q = q.Asc(hosts.Field("id"))
+	candidates := make([]api.HostNetworkProbeTarget, 0)
+	if err := q.All(&candidates); err != nil {
+		return nil, errors.Wrap(err, "query probe targets")
+	}
+	return selectProbeTargets(candidates, zone.CloudregionId, options.Options.NetworkProbeMaxRegionPeers), nil
+}
+
+func selectProbeTargets(candidates []api.HostNetworkProbeTarget, regionId string, maxRegionPeers int) []api.HostNetworkProbeTarget {
+	ret := make([]api.HostNetworkProbeTarget, 0)
+	regionPeers := 0
+	otherRegions := map[string]bool{}
+	for _, c := range candidates {
+		if c.CloudregionId == regionId {
+			if maxRegionPeers > 0 && regionPeers >= maxRegionPeers {
+				continue
+			}
+			regionPeers++
+			ret = append(ret, c)
+		} else if !otherRegions[c.CloudregionId] {
+			otherRegions[c.CloudregionId] = true
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (manager *SGuestManager) validateNetworkLatencyPeerHosts(userCred mcclient.TokenCredential, input *api.ServerCreateInput) error {
+	hostIds := make([]string, 0, len(input.NetworkLatencyPeerHosts))
+	for _, idOrName := range input.NetworkLatencyPeerHosts {
+		obj, err := HostManager.FetchByIdOrName(userCred, idOrName)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return httperrors.NewResourceNotFoundError2(HostManager.Keyword(), idOrName)
+			}
+			return httperrors.NewGeneralError(err)
+		}
+		hostIds = append(hostIds, obj.GetId())
+	}
+	input.NetworkLatencyPeerHosts = hostIds
+	return nil
+}
+
+func (self *SHost) GetDetailsNetworkProbeTargets(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (api.HostNetworkProbeTargetsOutput, error) {
+	output := api.HostNetworkProbeTargetsOutput{}
+	if self.HostType != api.HOST_TYPE_HYPERVISOR {
+		return output, httperrors.NewNotSupportedError("host type %s not support network probe", self.HostType)
+	}
+	targets, err := HostNetworkProbeManager.getProbeTargets(self)
+	if err != nil {
+		return output, errors.Wrap(err, "getProbeTargets")
+	}
+	output.Targets = targets
+	return output, nil
+}
+
+func (self *SHost) PerformNetworkProbeReport(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostNetworkProbeReportInput) (jsonutils.JSONObject, error) {
+	if self.HostType != api.HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewNotSupportedError("host type %s not support network probe", self.HostType)
+	}
+	for _, result := range input.Results {
+		if result.LossRate < 0 || result.LossRate > 1 {
+			return nil, httperrors.NewInputParameterError("invalid loss_rate %f of peer %s", result.LossRate, result.PeerHostId)
+		}
+	}
+	if err := HostNetworkProbeManager.saveResults(ctx, self, input.Results); err != nil {
+		return nil, errors.Wrap(err, "saveResults")
+	}
+	return nil, nil
+}
+
+func (self *SHost) GetDetailsNetworkProbes(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (api.HostNetworkProbesOutput, error) {
+	output := api.HostNetworkProbesOutput{}
+	probes := make([]SHostNetworkProbe, 0)
+	q := HostNetworkProbeManager.Query().Equals("host_id", self.Id).Asc("peer_host_id")
+	if err := db.FetchModelObjects(HostNetworkProbeManager, q, &probes); err != nil {
+		return output, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	peerIds := make([]string, 0, len(probes))
+	for _, p := range probes {
+		peerIds = append(peerIds, p.PeerHostId)
+	}
+	peers := map[string]SHost{}
+	if err := db.FetchStandaloneObjectsByIds(HostManager, peerIds, &peers); err != nil {
+		return output, errors.Wrap(err, "FetchStandaloneObjectsByIds")
+	}
+	for _, p := range probes {
+		output.Probes = append(output.Probes, api.HostNetworkProbeDetails{
+			HostNetworkProbeResult: api.HostNetworkProbeResult{
+				PeerHostId:    p.PeerHostId,
+				LatencyMs:     p.LatencyMs,
+				JitterMs:      p.JitterMs,
+				LossRate:      p.LossRate,
+				BandwidthMbps: p.BandwidthMbps,
+			},
+			PeerHostName:      peers[p.PeerHostId].Name,
+			PeerCloudregionId: p.PeerCloudregionId,
+			ProbedAt:          p.ProbedAt,
+		})
+	}
+	return output, nil
+}
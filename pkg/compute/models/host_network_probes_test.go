@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSelectProbeTargets(t *testing.T) {
+	candidates := []api.HostNetworkProbeTarget{
+		{HostId: "h1", CloudregionId: "r1"},
+		{HostId: "h2", CloudregionId: "r2"},
+		{HostId: "h3", CloudregionId: "r1"},
+		{HostId: "h4", CloudregionId: "r2"},
+		{HostId: "h5", CloudregionId: "r1"},
+		{HostId: "h6", CloudregionId: "r3"},
+	}
+	cases := []struct {
+		name     string
+		maxPeers int
+		want     []string
+	}{
+		{"no limit", 0, []string{"h1", "h2", "h3", "h5", "h6"}},
+		{"limit region peers", 2, []string{"h1", "h2", "h3", "h6"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := selectProbeTargets(candidates, "r1", c.maxPeers)
+			ids := make([]string, 0, len(got))
+			for _, g := range got {
+				ids = append(ids, g.HostId)
+			}
+			if len(ids) != len(c.want) {
+				t.Fatalf("want %v, got %v", c.want, ids)
+			}
+			for i := range ids {
+				if ids[i] != c.want[i] {
+					t.Fatalf("want %v, got %v", c.want, ids)
+				}
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ type IHostDriver interface {
 
 	CheckAndSetCacheImage(ctx context.Context, host *SHost, storagecache *SStoragecache, task taskman.ITask) error
 	RequestUncacheImage(ctx context.Context, host *SHost, storageCache *SStoragecache, task taskman.ITask) error
+	RequestVerifyCachedImage(ctx context.Context, host *SHost, storageCache *SStoragecache, task taskman.ITask) error
 
 	ValidateUpdateDisk(ctx context.Context, userCred mcclient.TokenCredential, input api.DiskUpdateInput) (api.DiskUpdateInput, error)
 	ValidateResetDisk(ctx context.Context, userCred mcclient.TokenCredential, disk *SDisk, snapshot *SSnapshot, guests []SGuest, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error)
@@ -64,6 +65,7 @@ type IHostDriver interface {
 	RequestDetachStorage(ctx context.Context, host *SHost, storage *SStorage, task taskman.ITask) error
 	RequestSyncOnHost(ctx context.Context, host *SHost, task taskman.ITask) error
 	RequestProbeIsolatedDevices(ctx context.Context, userCred mcclient.TokenCredential, host *SHost, input jsonutils.JSONObject) (*jsonutils.JSONArray, error)
+	RequestCollectLogBundle(ctx context.Context, host *SHost, input api.HostLogBundleCollectInput, task taskman.ITask) error
 }
 
 var hostDrivers map[string]IHostDriver
@@ -806,6 +806,10 @@ func (self *SHost) RealDelete(ctx context.Context, userCred mcclient.TokenCreden
 
 	IsolatedDeviceManager.DeleteDevicesByHost(ctx, userCred, self)
 
+	if err := HostNetworkProbeManager.removeProbesByHost(ctx, userCred, self.Id); err != nil {
+		return errors.Wrap(err, "HostNetworkProbeManager.removeProbesByHost")
+	}
+
 	for _, hoststorage := range self.GetHoststorages() {
 		storage := hoststorage.GetStorage()
 		if storage != nil && storage.IsLocal() {
@@ -4119,6 +4123,7 @@ func (self *SHost) PerformPing(ctx context.Context, userCred mcclient.TokenCrede
 		}
 		self.SetMetadata(ctx, "root_partition_used_capacity_mb", input.RootPartitionUsedCapacityMb, userCred)
 		self.SetMetadata(ctx, "memory_used_mb", input.MemoryUsedMb, userCred)
+		self.SetMetadata(ctx, api.HOSTMETA_BALLOON_RECLAIMED_MB, input.BalloonReclaimedMb, userCred)
 	}
 	if self.HostStatus != api.HOST_ONLINE {
 		self.PerformOnline(ctx, userCred, query, nil)
@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// SetGuestCurrent 将虚机当前所在快照指向该主机快照, 创建或回滚成功后调用
+func (self *SInstanceSnapshot) SetGuestCurrent(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) {
+	if guest == nil {
+		return
+	}
+	err := guest.SetMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, self.Id, userCred)
+	if err != nil {
+		log.Errorf("set guest %s current instance snapshot %s: %v", guest.Name, self.Id, err)
+	}
+}
+
+// 删除快照前将子快照挂到其父快照下, 若虚机当前位于该快照则回退到父快照
+func (self *SInstanceSnapshot) detachFromTree(ctx context.Context, userCred mcclient.TokenCredential) error {
+	children := make([]SInstanceSnapshot, 0)
+	q := InstanceSnapshotManager.Query().Equals("parent_id", self.Id)
+	err := db.FetchModelObjects(InstanceSnapshotManager, q, &children)
+	if err != nil {
+		return errors.Wrap(err, "fetch children")
+	}
+	for i := range children {
+		_, err := db.Update(&children[i], func() error {
+			children[i].ParentId = self.ParentId
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "reparent %s", children[i].Id)
+		}
+	}
+	guest := GuestManager.FetchGuestById(self.GuestId)
+	if guest != nil && guest.GetMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, userCred) == self.Id {
+		if len(self.ParentId) > 0 {
+			err = guest.SetMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, self.ParentId, userCred)
+		} else {
+			err = guest.RemoveMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, userCred)
+		}
+		if err != nil {
+			return errors.Wrap(err, "update guest current instance snapshot")
+		}
+	}
+	return nil
+}
+
+// 按父子关系组装快照树, 父快照已不存在的节点作为根节点
+func buildInstanceSnapshotTree(snapshots []SInstanceSnapshot, current string) []*api.InstanceSnapshotTreeNode {
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+	nodes := make(map[string]*api.InstanceSnapshotTreeNode, len(snapshots))
+	for i := range snapshots {
+		isp := snapshots[i]
+		nodes[isp.Id] = &api.InstanceSnapshotTreeNode{
+			Id:         isp.Id,
+			Name:       isp.Name,
+			Status:     isp.Status,
+			WithMemory: isp.WithMemory,
+			CreatedAt:  isp.CreatedAt,
+			Current:    isp.Id == current,
+			Children:   []*api.InstanceSnapshotTreeNode{},
+		}
+	}
+	roots := []*api.InstanceSnapshotTreeNode{}
+	for i := range snapshots {
+		node := nodes[snapshots[i].Id]
+		parent, ok := nodes[snapshots[i].ParentId]
+		if !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
+func (self *SGuest) GetDetailsSnapshotTree(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerSnapshotTreeOutput, error) {
+	snapshots, err := self.GetInstanceSnapshots()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetInstanceSnapshots")
+	}
+	current := self.GetMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, userCred)
+	return &api.ServerSnapshotTreeOutput{
+		Current:   current,
+		Snapshots: buildInstanceSnapshotTree(snapshots, current),
+	}, nil
+}
+
+// PerformSnapshotGc 清理宿主机上已无快照记录引用的磁盘快照及内存快照文件
+func (self *SGuest) PerformSnapshotGc(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (*api.ServerSnapshotGcOutput, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, httperrors.NewInvalidStatusError("guest has no host")
+	}
+	input := api.ServerSnapshotGcRequest{
+		Disks: map[string][]string{},
+	}
+	disks, err := self.GetDisks()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDisks")
+	}
+	for i := range disks {
+		snapshots := make([]SSnapshot, 0)
+		q := SnapshotManager.Query().Equals("disk_id", disks[i].Id)
+		err := db.FetchModelObjects(SnapshotManager, q, &snapshots)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch snapshots of disk %s", disks[i].Id)
+		}
+		ids := make([]string, 0, len(snapshots))
+		for j := range snapshots {
+			ids = append(ids, snapshots[j].Id)
+		}
+		input.Disks[disks[i].Id] = ids
+	}
+	instanceSnapshots, err := self.GetInstanceSnapshots()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetInstanceSnapshots")
+	}
+	for i := range instanceSnapshots {
+		if instanceSnapshots[i].WithMemory {
+			input.MemorySnapshots = append(input.MemorySnapshots, instanceSnapshots[i].Id)
+		}
+	}
+	output, err := self.GetDriver().RequestSnapshotGc(ctx, userCred, host, self, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "RequestSnapshotGc")
+	}
+	if len(output.Removed) > 0 {
+		db.OpsLog.LogEvent(self, db.ACT_DELETE, output.Removed, userCred)
+	}
+	return output, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildInstanceSnapshotTree(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newIsp := func(id, parent string, minutes int) SInstanceSnapshot {
+		isp := SInstanceSnapshot{ParentId: parent}
+		isp.Id = id
+		isp.Name = id
+		isp.CreatedAt = base.Add(time.Duration(minutes) * time.Minute)
+		return isp
+	}
+	// a -> b -> c, a -> d, e 的父快照已删除
+	snapshots := []SInstanceSnapshot{
+		newIsp("d", "a", 3),
+		newIsp("c", "b", 2),
+		newIsp("a", "", 0),
+		newIsp("b", "a", 1),
+		newIsp("e", "deleted", 4),
+	}
+	roots := buildInstanceSnapshotTree(snapshots, "c")
+	if len(roots) != 2 || roots[0].Id != "a" || roots[1].Id != "e" {
+		t.Fatalf("unexpected roots %#v", roots)
+	}
+	a := roots[0]
+	if len(a.Children) != 2 || a.Children[0].Id != "b" || a.Children[1].Id != "d" {
+		t.Fatalf("unexpected children of a %#v", a.Children)
+	}
+	b := a.Children[0]
+	if len(b.Children) != 1 || b.Children[0].Id != "c" || !b.Children[0].Current {
+		t.Fatalf("unexpected children of b %#v", b.Children)
+	}
+	if a.Current || b.Current {
+		t.Errorf("only c should be current")
+	}
+}
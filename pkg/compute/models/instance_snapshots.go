@@ -91,6 +91,8 @@ type SInstanceSnapshot struct {
 	MemoryFilePath string `width:"512" charset:"utf8" nullable:"true" get:"user" list:"user"`
 	// 内存文件校验和
 	MemoryFileChecksum string `width:"32" charset:"ascii" nullable:"true" get:"user" list:"user"`
+	// 父主机快照Id
+	ParentId string `width:"36" charset:"ascii" nullable:"true" get:"user" list:"user"`
 }
 
 type SInstanceSnapshotManager struct {
@@ -404,6 +406,7 @@ func (manager *SInstanceSnapshotManager) CreateInstanceSnapshot(ctx context.Cont
 	instanceSnapshot.SizeMb = guest.getDiskSize()
 	instanceSnapshot.WithMemory = withMemory
 	instanceSnapshot.MemoryFileHostId = guest.HostId
+	instanceSnapshot.ParentId = guest.GetMetadata(ctx, api.VM_METADATA_CURRENT_INSTANCE_SNAPSHOT, userCred)
 	err := manager.TableSpec().Insert(ctx, instanceSnapshot)
 	if err != nil {
 		return nil, errors.Wrap(err, "Insert")
@@ -581,6 +584,9 @@ func (self *SInstanceSnapshot) StartInstanceSnapshotDeleteTask(
 }
 
 func (self *SInstanceSnapshot) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if err := self.detachFromTree(ctx, userCred); err != nil {
+		return errors.Wrap(err, "detachFromTree")
+	}
 	return db.DeleteModel(ctx, userCred, self)
 }
 
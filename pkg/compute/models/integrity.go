@@ -0,0 +1,191 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 完整性校验失败(文件损坏或丢失)
+func isIntegrityVerifyFailed(status string) bool {
+	return status == api.INTEGRITY_VERIFY_CORRUPTED || status == api.INTEGRITY_VERIFY_MISSING
+}
+
+// PerformVerify 校验备份文件md5, 检测静默损坏或传输截断
+func (self *SDiskBackup) PerformVerify(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DiskBackupVerifyInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.BACKUP_STATUS_READY {
+		return nil, httperrors.NewInvalidStatusError("cannot verify backup in status %s", self.Status)
+	}
+	return nil, self.StartVerifyTask(ctx, userCred, "")
+}
+
+func (self *SDiskBackup) StartVerifyTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "DiskBackupVerifyTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SDiskBackup) SetVerifyResult(ctx context.Context, userCred mcclient.TokenCredential, result api.IntegrityVerifyResult) error {
+	_, err := db.Update(self, func() error {
+		// 早期备份未记录md5, 以首次校验结果作为基准
+		if len(self.Checksum) == 0 && result.Status == api.INTEGRITY_VERIFY_OK {
+			self.Checksum = result.Checksum
+		}
+		self.VerifyStatus = result.Status
+		self.VerifiedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	if isIntegrityVerifyFailed(result.Status) {
+		notifyclient.NotifySystemErrorWithCtx(ctx, self.Id, self.Name, api.BACKUP_INTEGRITY_VERIFY_FAILED, integrityVerifyReason(result))
+	}
+	return nil
+}
+
+// PerformVerifyImage 校验缓存镜像md5, repair 为 true 时损坏的缓存会从镜像服务重新下载
+func (self *SStoragecache) PerformVerifyImage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.StoragecacheVerifyImageInput) (jsonutils.JSONObject, error) {
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+	imgObj, err := CachedimageManager.FetchByIdOrName(nil, input.ImageId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(CachedimageManager.Keyword(), input.ImageId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	scimg := StoragecachedimageManager.GetStoragecachedimage(self.Id, imgObj.GetId())
+	if scimg == nil {
+		return nil, httperrors.NewResourceNotFoundError("storage not cache image")
+	}
+	if scimg.Status != api.CACHED_IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("cannot verify cached image in status %s", scimg.Status)
+	}
+	return nil, self.StartImageVerifyTask(ctx, userCred, imgObj.GetId(), input.Repair, "")
+}
+
+func (self *SStoragecache) StartImageVerifyTask(ctx context.Context, userCred mcclient.TokenCredential, imageId string, repair bool, parentTaskId string) error {
+	data := jsonutils.NewDict()
+	data.Set("image_id", jsonutils.NewString(imageId))
+	if repair {
+		data.Set("repair", jsonutils.JSONTrue)
+	}
+	if obj, err := CachedimageManager.FetchById(imageId); err == nil {
+		image, err := obj.(*SCachedimage).GetImage()
+		if err == nil && len(image.Checksum) > 0 {
+			data.Set("checksum", jsonutils.NewString(image.Checksum))
+		}
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "StorageCacheImageVerifyTask", self, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SStoragecachedimage) SetVerifyResult(ctx context.Context, userCred mcclient.TokenCredential, result api.IntegrityVerifyResult) error {
+	_, err := db.Update(self, func() error {
+		if len(result.Checksum) > 0 && !isIntegrityVerifyFailed(result.Status) {
+			self.Checksum = result.Checksum
+		}
+		self.VerifyStatus = result.Status
+		self.VerifiedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	if isIntegrityVerifyFailed(result.Status) {
+		name := self.CachedimageId
+		if sc := self.GetStoragecache(); sc != nil {
+			name = fmt.Sprintf("%s/%s", sc.Name, self.CachedimageId)
+		}
+		notifyclient.NotifySystemErrorWithCtx(ctx, self.StoragecacheId, name, api.IMAGE_CACHE_INTEGRITY_VERIFY_FAILED, integrityVerifyReason(result))
+	}
+	return nil
+}
+
+func integrityVerifyReason(result api.IntegrityVerifyResult) string {
+	if len(result.Reason) > 0 {
+		return result.Reason
+	}
+	return fmt.Sprintf("integrity verify %s", result.Status)
+}
+
+func integrityScrubFilter(q *sqlchemy.SQuery) *sqlchemy.SQuery {
+	cutoff := time.Now().Add(-time.Duration(options.Options.IntegrityScrubIntervalDays) * 24 * time.Hour)
+	return q.Filter(sqlchemy.OR(
+		sqlchemy.IsNull(q.Field("verified_at")),
+		sqlchemy.LT(q.Field("verified_at"), cutoff),
+	)).Asc("verified_at").Limit(options.Options.IntegrityScrubBatchSize)
+}
+
+// IntegrityScrub 定期巡检本地镜像缓存和磁盘备份, 发现损坏时告警并按需重新下载
+func IntegrityScrub(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	if options.Options.IntegrityScrubIntervalDays <= 0 || options.Options.IntegrityScrubBatchSize <= 0 {
+		return
+	}
+
+	storages := StorageManager.Query("storagecache_id").In("storage_type", api.FIEL_STORAGE).IsNullOrEmpty("manager_id")
+	q := StoragecachedimageManager.Query().Equals("status", api.CACHED_IMAGE_STATUS_ACTIVE).In("storagecache_id", storages.SubQuery())
+	scimgs := []SStoragecachedimage{}
+	err := db.FetchModelObjects(StoragecachedimageManager, integrityScrubFilter(q), &scimgs)
+	if err != nil {
+		log.Errorf("IntegrityScrub fetch cached images: %v", err)
+	}
+	for i := range scimgs {
+		sc := scimgs[i].GetStoragecache()
+		if sc == nil {
+			continue
+		}
+		err := sc.StartImageVerifyTask(ctx, userCred, scimgs[i].CachedimageId, options.Options.IntegrityScrubAutoRepair, "")
+		if err != nil {
+			log.Errorf("IntegrityScrub verify image %s on storagecache %s: %v", scimgs[i].CachedimageId, sc.Id, err)
+		}
+	}
+
+	q = DiskBackupManager.Query().Equals("status", api.BACKUP_STATUS_READY).IsNullOrEmpty("manager_id")
+	backups := []SDiskBackup{}
+	err = db.FetchModelObjects(DiskBackupManager, integrityScrubFilter(q), &backups)
+	if err != nil {
+		log.Errorf("IntegrityScrub fetch disk backups: %v", err)
+	}
+	for i := range backups {
+		err := backups[i].StartVerifyTask(ctx, userCred, "")
+		if err != nil {
+			log.Errorf("IntegrityScrub verify backup %s: %v", backups[i].Id, err)
+		}
+	}
+}
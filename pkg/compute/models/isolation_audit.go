@@ -0,0 +1,473 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/secrules"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 对公网开放时视为高危的端口
+var isolationSensitivePorts = []int{22, 23, 135, 139, 445, 1433, 1521, 2375, 3306, 3389, 5432, 5900, 6379, 9200, 11211, 27017}
+
+type sIsolationAuditAddr struct {
+	IpAddr string
+	VpcId  string
+}
+
+type sIsolationAuditPeerRule struct {
+	Rule           secrules.SecurityRule
+	PeerSecgroupId string
+}
+
+type sIsolationAuditGuest struct {
+	Id        string
+	Name      string
+	ProjectId string
+	DomainId  string
+	Layer     string
+	Public    bool
+	Addrs     []sIsolationAuditAddr
+	Secgroups []string
+	// 按生效顺序排列的入方向规则, 管理员安全组优先
+	Rules     secrules.SecurityRuleSet
+	PeerRules []sIsolationAuditPeerRule
+}
+
+func isolationRuleAllPorts(rule *secrules.SecurityRule) bool {
+	if rule.Protocol == secrules.PROTO_ANY {
+		return true
+	}
+	if rule.Protocol == secrules.PROTO_ICMP {
+		return false
+	}
+	return rule.PortStart <= 0 && len(rule.Ports) == 0
+}
+
+func isolationRuleCoversPort(rule *secrules.SecurityRule, port int) bool {
+	if isolationRuleAllPorts(rule) {
+		return true
+	}
+	if rule.PortStart > 0 && rule.PortStart <= port && port <= rule.PortEnd {
+		return true
+	}
+	for _, p := range rule.Ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+func isolationRuleIsAny(rule *secrules.SecurityRule) bool {
+	if rule.IPNet == nil {
+		return true
+	}
+	ones, _ := rule.IPNet.Mask.Size()
+	return ones == 0
+}
+
+func internetExposedSeverity(rule *secrules.SecurityRule) string {
+	if rule.Protocol == secrules.PROTO_ICMP {
+		return api.ISOLATION_SEVERITY_LOW
+	}
+	if isolationRuleAllPorts(rule) {
+		return api.ISOLATION_SEVERITY_HIGH
+	}
+	for _, port := range isolationSensitivePorts {
+		if isolationRuleCoversPort(rule, port) {
+			return api.ISOLATION_SEVERITY_HIGH
+		}
+	}
+	return api.ISOLATION_SEVERITY_MEDIUM
+}
+
+func crossProjectSeverity(rule *secrules.SecurityRule) string {
+	if isolationRuleAllPorts(rule) {
+		return api.ISOLATION_SEVERITY_MEDIUM
+	}
+	return api.ISOLATION_SEVERITY_LOW
+}
+
+func newIsolationFinding(guest *sIsolationAuditGuest, findingType, severity string, rule *secrules.SecurityRule) api.SIsolationFinding {
+	finding := api.SIsolationFinding{
+		Type:      findingType,
+		Severity:  severity,
+		Layer:     guest.Layer,
+		GuestId:   guest.Id,
+		GuestName: guest.Name,
+		ProjectId: guest.ProjectId,
+		Protocol:  rule.Protocol,
+		Ports:     rule.GetPortsString(),
+	}
+	if rule.IPNet != nil {
+		finding.Cidr = rule.IPNet.String()
+	}
+	return finding
+}
+
+// 统计与 guest 同 vpc 且属于其他项目, 并满足 match 的虚机数量
+func countCrossProjectSources(guest *sIsolationAuditGuest, vpcGuests map[string][]*sIsolationAuditGuest, match func(src *sIsolationAuditGuest, addr sIsolationAuditAddr) bool) map[string]int {
+	sources := map[string]map[string]bool{}
+	for _, addr := range guest.Addrs {
+		for _, src := range vpcGuests[addr.VpcId] {
+			if src.Id == guest.Id || src.ProjectId == guest.ProjectId {
+				continue
+			}
+			for _, srcAddr := range src.Addrs {
+				if srcAddr.VpcId != addr.VpcId || !match(src, srcAddr) {
+					continue
+				}
+				if _, ok := sources[src.ProjectId]; !ok {
+					sources[src.ProjectId] = map[string]bool{}
+				}
+				sources[src.ProjectId][src.Id] = true
+				break
+			}
+		}
+	}
+	ret := map[string]int{}
+	for projectId, guests := range sources {
+		ret[projectId] = len(guests)
+	}
+	return ret
+}
+
+func appendCrossProjectFindings(findings []api.SIsolationFinding, guest *sIsolationAuditGuest, rule *secrules.SecurityRule, peerSecgroupId string, sources map[string]int) []api.SIsolationFinding {
+	projectIds := []string{}
+	for projectId := range sources {
+		projectIds = append(projectIds, projectId)
+	}
+	sort.Strings(projectIds)
+	for _, projectId := range projectIds {
+		finding := newIsolationFinding(guest, api.ISOLATION_FINDING_CROSS_PROJECT, crossProjectSeverity(rule), rule)
+		finding.SourceProjectId = projectId
+		finding.SourceGuestCount = sources[projectId]
+		if len(peerSecgroupId) > 0 {
+			finding.PeerSecgroupId = peerSecgroupId
+			finding.Cidr = ""
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// 分析 targets 中虚机的公网暴露端口及可被其他项目虚机访问的规则, all 为参与可达性分析的全部虚机
+func auditNetworkIsolation(targets []*sIsolationAuditGuest, all []*sIsolationAuditGuest) []api.SIsolationFinding {
+	vpcGuests := map[string][]*sIsolationAuditGuest{}
+	secgroupGuests := map[string]map[string]bool{}
+	for _, guest := range all {
+		vpcIds := map[string]bool{}
+		for _, addr := range guest.Addrs {
+			if !vpcIds[addr.VpcId] {
+				vpcIds[addr.VpcId] = true
+				vpcGuests[addr.VpcId] = append(vpcGuests[addr.VpcId], guest)
+			}
+		}
+		for _, secgroupId := range guest.Secgroups {
+			if _, ok := secgroupGuests[secgroupId]; !ok {
+				secgroupGuests[secgroupId] = map[string]bool{}
+			}
+			secgroupGuests[secgroupId][guest.Id] = true
+		}
+	}
+
+	findings := []api.SIsolationFinding{}
+	for _, guest := range targets {
+		allowList := guest.Rules.AllowList()
+		for i := range allowList {
+			rule := &allowList[i]
+			if guest.Public && isolationRuleIsAny(rule) {
+				findings = append(findings, newIsolationFinding(guest, api.ISOLATION_FINDING_INTERNET_EXPOSED, internetExposedSeverity(rule), rule))
+			}
+			sources := countCrossProjectSources(guest, vpcGuests, func(src *sIsolationAuditGuest, addr sIsolationAuditAddr) bool {
+				ip := net.ParseIP(addr.IpAddr)
+				return ip != nil && (rule.IPNet == nil || rule.IPNet.Contains(ip))
+			})
+			findings = appendCrossProjectFindings(findings, guest, rule, "", sources)
+		}
+		// 对端安全组规则按放行处理, 不考虑更高优先级的拒绝规则
+		for i := range guest.PeerRules {
+			peer := &guest.PeerRules[i]
+			if peer.Rule.Action != secrules.SecurityRuleAllow {
+				continue
+			}
+			sources := countCrossProjectSources(guest, vpcGuests, func(src *sIsolationAuditGuest, addr sIsolationAuditAddr) bool {
+				return secgroupGuests[peer.PeerSecgroupId][src.Id]
+			})
+			findings = appendCrossProjectFindings(findings, guest, &peer.Rule, peer.PeerSecgroupId, sources)
+		}
+	}
+	return findings
+}
+
+func isolationAuditLayer(hypervisor string, vpcId string) string {
+	if hypervisor != api.HYPERVISOR_KVM {
+		return api.ISOLATION_LAYER_PROVIDER
+	}
+	if vpcId == api.DEFAULT_VPC_ID {
+		return api.ISOLATION_LAYER_HOST
+	}
+	return api.ISOLATION_LAYER_OVN
+}
+
+// 加载所有受安全组保护的虚机及其地址和入方向规则
+func loadIsolationAuditGuests() ([]*sIsolationAuditGuest, error) {
+	q := GuestManager.Query().NotIn("hypervisor", []string{api.HYPERVISOR_CONTAINER, api.HYPERVISOR_BAREMETAL, api.HYPERVISOR_ESXI}).IsFalse("pending_deleted")
+	guests := []SGuest{}
+	err := db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch guests")
+	}
+
+	gns := GuestnetworkManager.Query().SubQuery()
+	networks := NetworkManager.Query().SubQuery()
+	wires := WireManager.Query().SubQuery()
+	addrQ := gns.Query(gns.Field("guest_id"), gns.Field("ip_addr"), wires.Field("vpc_id")).
+		Join(networks, sqlchemy.Equals(gns.Field("network_id"), networks.Field("id"))).
+		Join(wires, sqlchemy.Equals(networks.Field("wire_id"), wires.Field("id")))
+	addrs := []struct {
+		GuestId string
+		IpAddr  string
+		VpcId   string
+	}{}
+	err = addrQ.All(&addrs)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch guest addresses")
+	}
+
+	eips := []struct {
+		AssociateId string
+	}{}
+	err = ElasticipManager.Query("associate_id").Equals("associate_type", api.EIP_ASSOCIATE_TYPE_SERVER).IsNotEmpty("associate_id").All(&eips)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch eips")
+	}
+	publics := map[string]bool{}
+	for _, eip := range eips {
+		publics[eip.AssociateId] = true
+	}
+
+	gsecs := []struct {
+		GuestId    string
+		SecgroupId string
+	}{}
+	err = GuestsecgroupManager.Query("guest_id", "secgroup_id").All(&gsecs)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch guest secgroups")
+	}
+	guestSecgroups := map[string][]string{}
+	for _, gsec := range gsecs {
+		guestSecgroups[gsec.GuestId] = append(guestSecgroups[gsec.GuestId], gsec.SecgroupId)
+	}
+
+	rules := []SSecurityGroupRule{}
+	err = db.FetchModelObjects(SecurityGroupRuleManager, SecurityGroupRuleManager.Query().Equals("direction", secrules.DIR_IN).Desc("priority"), &rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch secgroup rules")
+	}
+	secgroupRules := map[string][]SSecurityGroupRule{}
+	for i := range rules {
+		secgroupRules[rules[i].SecgroupId] = append(secgroupRules[rules[i].SecgroupId], rules[i])
+	}
+
+	ret := []*sIsolationAuditGuest{}
+	index := map[string]*sIsolationAuditGuest{}
+	for i := range guests {
+		guest := &guests[i]
+		item := &sIsolationAuditGuest{
+			Id:        guest.Id,
+			Name:      guest.Name,
+			ProjectId: guest.ProjectId,
+			DomainId:  guest.DomainId,
+			Public:    publics[guest.Id],
+		}
+		secgroupIds := []string{}
+		for _, id := range append([]string{guest.SecgrpId}, guestSecgroups[guest.Id]...) {
+			if len(id) > 0 && !utils.IsInStringArray(id, secgroupIds) {
+				secgroupIds = append(secgroupIds, id)
+			}
+		}
+		item.Secgroups = secgroupIds
+		normal := secrules.SecurityRuleSet{}
+		for _, secgroupId := range secgroupIds {
+			for j := range secgroupRules[secgroupId] {
+				rule := &secgroupRules[secgroupId][j]
+				r, err := rule.toRule()
+				if err != nil {
+					log.Warningf("secgroup rule %s: %v", rule.Id, err)
+					continue
+				}
+				if len(rule.PeerSecgroupId) > 0 {
+					item.PeerRules = append(item.PeerRules, sIsolationAuditPeerRule{Rule: *r, PeerSecgroupId: rule.PeerSecgroupId})
+					continue
+				}
+				normal = append(normal, *r)
+			}
+		}
+		sort.Stable(normal)
+		if len(guest.AdminSecgrpId) > 0 {
+			for j := range secgroupRules[guest.AdminSecgrpId] {
+				r, err := secgroupRules[guest.AdminSecgrpId][j].toRule()
+				if err == nil && len(secgroupRules[guest.AdminSecgrpId][j].PeerSecgroupId) == 0 {
+					item.Rules = append(item.Rules, *r)
+				}
+			}
+		}
+		item.Rules = append(item.Rules, normal...)
+		ret = append(ret, item)
+		index[guest.Id] = item
+	}
+	for _, addr := range addrs {
+		item, ok := index[addr.GuestId]
+		if !ok || len(addr.IpAddr) == 0 {
+			continue
+		}
+		item.Addrs = append(item.Addrs, sIsolationAuditAddr{IpAddr: addr.IpAddr, VpcId: addr.VpcId})
+	}
+	for i := range guests {
+		item := index[guests[i].Id]
+		vpcId := ""
+		if len(item.Addrs) > 0 {
+			vpcId = item.Addrs[0].VpcId
+		}
+		item.Layer = isolationAuditLayer(guests[i].Hypervisor, vpcId)
+	}
+	return ret, nil
+}
+
+func newNetworkIsolationReport(domainId string, guests []*sIsolationAuditGuest, minSeverity string) *api.NetworkIsolationAuditReport {
+	targets := []*sIsolationAuditGuest{}
+	for _, guest := range guests {
+		if guest.DomainId == domainId {
+			targets = append(targets, guest)
+		}
+	}
+	report := &api.NetworkIsolationAuditReport{
+		DomainId:    domainId,
+		GeneratedAt: time.Now().UTC(),
+		GuestCount:  len(targets),
+		Summary:     map[string]int{},
+		Findings:    []api.SIsolationFinding{},
+	}
+	level := api.ISOLATION_SEVERITY_LEVELS[minSeverity]
+	for _, finding := range auditNetworkIsolation(targets, guests) {
+		if api.ISOLATION_SEVERITY_LEVELS[finding.Severity] < level {
+			continue
+		}
+		report.Summary[finding.Severity] += 1
+		report.Findings = append(report.Findings, finding)
+	}
+	return report
+}
+
+// 生成域内虚机的网络隔离审计报告
+func (manager *SSecurityGroupManager) GetPropertyIsolationAudit(ctx context.Context, userCred mcclient.TokenCredential, query api.NetworkIsolationAuditInput) (*api.NetworkIsolationAuditReport, error) {
+	if len(query.Severity) > 0 {
+		if _, ok := api.ISOLATION_SEVERITY_LEVELS[query.Severity]; !ok {
+			return nil, httperrors.NewInputParameterError("invalid severity %q", query.Severity)
+		}
+	}
+	domainId := userCred.GetProjectDomainId()
+	if len(query.DomainId) > 0 {
+		domain, err := db.TenantCacheManager.FetchDomainByIdOrName(ctx, query.DomainId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("domain", query.DomainId)
+		}
+		domainId = domain.Id
+	}
+	if db.IsAdminAllowList(userCred, manager).Result.IsDeny() {
+		if db.IsDomainAllowList(userCred, manager).Result.IsDeny() || domainId != userCred.GetProjectDomainId() {
+			return nil, httperrors.NewForbiddenError("not allow to audit network isolation of domain %s", domainId)
+		}
+	}
+	guests, err := loadIsolationAuditGuests()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return newNetworkIsolationReport(domainId, guests, query.Severity), nil
+}
+
+func isolationFindingReason(finding api.SIsolationFinding) string {
+	target := strings.TrimSpace(fmt.Sprintf("%s %s", finding.Protocol, finding.Ports))
+	switch finding.Type {
+	case api.ISOLATION_FINDING_INTERNET_EXPOSED:
+		return fmt.Sprintf("%s exposed to internet via %s", target, finding.Layer)
+	default:
+		return fmt.Sprintf("%s reachable from %d guests of project %s via %s", target, finding.SourceGuestCount, finding.SourceProjectId, finding.Layer)
+	}
+}
+
+// NetworkIsolationAudit 定期审计各域的网络隔离情况, 并按需将问题以告警事件发出
+func NetworkIsolationAudit(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	guests, err := loadIsolationAuditGuests()
+	if err != nil {
+		log.Errorf("NetworkIsolationAudit load guests: %v", err)
+		return
+	}
+	domainIds := []string{}
+	for _, guest := range guests {
+		if !utils.IsInStringArray(guest.DomainId, domainIds) {
+			domainIds = append(domainIds, guest.DomainId)
+		}
+	}
+	notifySeverity := options.Options.NetworkIsolationAuditNotifySeverity
+	for _, domainId := range domainIds {
+		report := newNetworkIsolationReport(domainId, guests, api.ISOLATION_SEVERITY_LOW)
+		log.Infof("NetworkIsolationAudit domain %s: %d guests, findings %v", domainId, report.GuestCount, report.Summary)
+		if _, ok := api.ISOLATION_SEVERITY_LEVELS[notifySeverity]; !ok {
+			continue
+		}
+		// 同一虚机的问题合并为一条告警, 级别取最高
+		reasons := map[string][]string{}
+		severities := map[string]string{}
+		names := map[string]string{}
+		for _, finding := range report.Findings {
+			if api.ISOLATION_SEVERITY_LEVELS[finding.Severity] < api.ISOLATION_SEVERITY_LEVELS[notifySeverity] {
+				continue
+			}
+			reasons[finding.GuestId] = append(reasons[finding.GuestId], isolationFindingReason(finding))
+			names[finding.GuestId] = finding.GuestName
+			if api.ISOLATION_SEVERITY_LEVELS[finding.Severity] > api.ISOLATION_SEVERITY_LEVELS[severities[finding.GuestId]] {
+				severities[finding.GuestId] = finding.Severity
+			}
+		}
+		for guestId, reason := range reasons {
+			msg := strings.Join(reason, "; ")
+			if severities[guestId] == api.ISOLATION_SEVERITY_HIGH {
+				notifyclient.NotifySystemErrorWithCtx(ctx, guestId, names[guestId], api.NETWORK_ISOLATION_AUDIT_FINDING, msg)
+			} else {
+				notifyclient.NotifySystemWarningWithCtx(ctx, guestId, names[guestId], api.NETWORK_ISOLATION_AUDIT_FINDING, msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/util/secrules"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestAuditNetworkIsolation(t *testing.T) {
+	ruleSet := func(rules ...string) secrules.SecurityRuleSet {
+		ret := secrules.SecurityRuleSet{}
+		for i, r := range rules {
+			rule := secrules.MustParseSecurityRule(r)
+			rule.Priority = len(rules) - i
+			ret = append(ret, *rule)
+		}
+		return ret
+	}
+	web := &sIsolationAuditGuest{
+		Id: "web", ProjectId: "p1", DomainId: "d1", Layer: api.ISOLATION_LAYER_OVN, Public: true,
+		Addrs: []sIsolationAuditAddr{{IpAddr: "10.0.0.2", VpcId: "vpc1"}},
+		Rules: ruleSet("in:deny any", "in:allow tcp 443"),
+	}
+	mysql := &sIsolationAuditGuest{
+		Id: "db", ProjectId: "p1", DomainId: "d1", Layer: api.ISOLATION_LAYER_OVN, Public: true,
+		Addrs:     []sIsolationAuditAddr{{IpAddr: "10.0.0.3", VpcId: "vpc1"}},
+		Secgroups: []string{"sg-db"},
+		Rules:     ruleSet("in:allow tcp 3306", "in:allow 10.0.1.0/24 any"),
+	}
+	other := &sIsolationAuditGuest{
+		Id: "other", ProjectId: "p2", DomainId: "d1", Layer: api.ISOLATION_LAYER_OVN,
+		Addrs:     []sIsolationAuditAddr{{IpAddr: "10.0.1.5", VpcId: "vpc1"}},
+		Secgroups: []string{"sg-other"},
+		PeerRules: []sIsolationAuditPeerRule{
+			{Rule: *secrules.MustParseSecurityRule("in:allow tcp 22"), PeerSecgroupId: "sg-db"},
+		},
+	}
+	isolated := &sIsolationAuditGuest{
+		Id: "isolated", ProjectId: "p3", DomainId: "d1", Layer: api.ISOLATION_LAYER_OVN,
+		Addrs: []sIsolationAuditAddr{{IpAddr: "10.0.1.6", VpcId: "vpc2"}},
+	}
+	all := []*sIsolationAuditGuest{web, mysql, other, isolated}
+	findings := auditNetworkIsolation(all, all)
+
+	type key struct {
+		findingType, guestId, severity, sourceProject string
+	}
+	got := map[key]bool{}
+	for _, f := range findings {
+		got[key{f.Type, f.GuestId, f.Severity, f.SourceProjectId}] = true
+	}
+	want := []key{
+		// web 的拒绝规则优先级更高, 443 端口不对外开放
+		{api.ISOLATION_FINDING_INTERNET_EXPOSED, "db", api.ISOLATION_SEVERITY_HIGH, ""},
+		{api.ISOLATION_FINDING_CROSS_PROJECT, "db", api.ISOLATION_SEVERITY_LOW, "p2"},
+		{api.ISOLATION_FINDING_CROSS_PROJECT, "db", api.ISOLATION_SEVERITY_MEDIUM, "p2"},
+		{api.ISOLATION_FINDING_CROSS_PROJECT, "other", api.ISOLATION_SEVERITY_LOW, "p1"},
+	}
+	for _, k := range want {
+		if !got[k] {
+			t.Errorf("missing finding %+v", k)
+		}
+	}
+	if len(findings) != len(want) {
+		t.Errorf("expect %d findings, got %d: %+v", len(want), len(findings), findings)
+	}
+}
+
+func TestInternetExposedSeverity(t *testing.T) {
+	cases := map[string]string{
+		"in:allow any":           api.ISOLATION_SEVERITY_HIGH,
+		"in:allow tcp 22":        api.ISOLATION_SEVERITY_HIGH,
+		"in:allow tcp 3000-4000": api.ISOLATION_SEVERITY_HIGH,
+		"in:allow tcp 80,443":    api.ISOLATION_SEVERITY_MEDIUM,
+		"in:allow icmp":          api.ISOLATION_SEVERITY_LOW,
+	}
+	for r, want := range cases {
+		if got := internetExposedSeverity(secrules.MustParseSecurityRule(r)); got != want {
+			t.Errorf("%s: want %s got %s", r, want, got)
+		}
+	}
+}
@@ -148,6 +148,11 @@ type SLoadbalancerListener struct {
 	AclType                      string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"`
 	SLoadbalancerAclResourceBase `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 
+	// 访问日志投递, 云上按负载均衡实例生效
+	AccessLogStatus  string `width:"16" charset:"ascii" nullable:"true" list:"user" default:"off"`
+	AccessLogProject string `width:"128" charset:"utf8" nullable:"true" list:"user"`
+	AccessLogTopic   string `width:"128" charset:"utf8" nullable:"true" list:"user"`
+
 	SLoadbalancerRateLimiter
 
 	SLoadbalancerTCPListener
@@ -458,6 +463,7 @@ func (lblis *SLoadbalancerListener) ValidateUpdateData(ctx context.Context, user
 
 func (lblis *SLoadbalancerListener) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	lblis.SStatusStandaloneResourceBase.PostUpdate(ctx, userCred, query, data)
+	recordConfigHistory(ctx, lblis)
 
 	if account := lblis.GetCloudaccount(); account != nil && !account.IsOnPremise {
 		lblis.StartLoadBalancerListenerSyncTask(ctx, userCred, data, "")
@@ -542,6 +548,7 @@ func (manager *SLoadbalancerListenerManager) FetchCustomizeColumns(
 
 func (lblis *SLoadbalancerListener) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	lblis.SStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	recordConfigHistory(ctx, lblis)
 	lblis.StartLoadBalancerListenerCreateTask(ctx, userCred, data.(*jsonutils.JSONDict), "")
 }
 
@@ -567,6 +574,64 @@ func (lblis *SLoadbalancerListener) PerformSync(ctx context.Context, userCred mc
 	return nil, lblis.StartLoadBalancerListenerSyncTask(ctx, userCred, nil, "")
 }
 
+func (lblis *SLoadbalancerListener) PerformSetAccessLog(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.LoadbalancerListenerSetAccessLogInput) (jsonutils.JSONObject, error) {
+	err := input.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if lblis.GetCloudprovider() == nil {
+		return nil, httperrors.NewUnsupportOperationError("access log is only supported for public cloud loadbalancer listener")
+	}
+	diff, err := db.Update(lblis, func() error {
+		lblis.AccessLogStatus = input.AccessLogStatus
+		lblis.AccessLogProject = input.AccessLogProject
+		lblis.AccessLogTopic = input.AccessLogTopic
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "db.Update")
+	}
+	db.OpsLog.LogEvent(lblis, db.ACT_UPDATE, diff, userCred)
+	return nil, lblis.StartLoadBalancerListenerSetAccessLogTask(ctx, userCred, "")
+}
+
+func (lblis *SLoadbalancerListener) StartLoadBalancerListenerSetAccessLogTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	if utils.IsInStringArray(lblis.Status, []string{api.LB_STATUS_ENABLED, api.LB_STATUS_DISABLED}) {
+		params.Add(jsonutils.NewString(lblis.Status), "origin_status")
+	}
+	lblis.SetStatus(userCred, api.LB_SYNC_CONF, "set access log")
+	task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerListenerSetAccessLogTask", lblis, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// 访问日志在云上按负载均衡实例生效, 同一负载均衡下任一监听开启即开启
+func (lblis *SLoadbalancerListener) GetCloudLoadbalancerAccessLog() (*cloudprovider.SLoadbalancerAccessLog, error) {
+	ret := &cloudprovider.SLoadbalancerAccessLog{}
+	if lblis.AccessLogStatus == api.LB_BOOL_ON {
+		ret.Enabled, ret.Project, ret.Topic = true, lblis.AccessLogProject, lblis.AccessLogTopic
+		return ret, nil
+	}
+	lb, err := lblis.GetLoadbalancer()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetLoadbalancer")
+	}
+	listeners, err := lb.GetLoadbalancerListeners()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetLoadbalancerListeners")
+	}
+	for i := range listeners {
+		if listeners[i].Id != lblis.Id && listeners[i].AccessLogStatus == api.LB_BOOL_ON {
+			ret.Enabled, ret.Project, ret.Topic = true, listeners[i].AccessLogProject, listeners[i].AccessLogTopic
+			break
+		}
+	}
+	return ret, nil
+}
+
 func (lblis *SLoadbalancerListener) StartLoadBalancerListenerDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
 	err := func() error {
 		task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerListenerDeleteTask", lblis, userCred, params, parentTaskId, "", nil)
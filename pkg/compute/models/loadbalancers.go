@@ -530,6 +530,15 @@ func (man *SLoadbalancerManager) ValidateCreateData(
 		return nil, err
 	}
 
+	if len(input.SharedBandwidthId) > 0 {
+		if region.GetDriver().GetProvider() != api.CLOUD_PROVIDER_HUAWEI {
+			return nil, httperrors.NewUnsupportOperationError("shared bandwidth is not supported by %s", region.GetDriver().GetProvider())
+		}
+		if len(input.EipId) == 0 {
+			return nil, httperrors.NewMissingParameterError("eip_id")
+		}
+	}
+
 	input, err = region.GetDriver().ValidateCreateLoadbalancerData(ctx, userCred, ownerId, input)
 	if err != nil {
 		return nil, err
@@ -106,13 +106,13 @@ type SNetwork struct {
 
 	GuestDomain string `width:"128" charset:"ascii" nullable:"true" get:"user" update:"user"`
 
-	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6End   string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6Mask  int8   `nullable:"true"`
-	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true"`
-	GuestDns6     string `width:"64" charset:"ascii" nullable:"true"`
+	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	GuestIp6End   string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	GuestIp6Mask  int8   `nullable:"true" list:"user"`
+	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true" list:"user"`
+	GuestDns6     string `width:"64" charset:"ascii" nullable:"true" list:"user"`
 
-	GuestDomain6 string `width:"128" charset:"ascii" nullable:"true"`
+	GuestDomain6 string `width:"128" charset:"ascii" nullable:"true" list:"user"`
 
 	VlanId int `nullable:"false" default:"1" list:"user" update:"user" create:"optional"`
 
@@ -148,7 +148,11 @@ func (self *SNetwork) getMtu() int {
 	wire, _ := self.GetWire()
 	if wire != nil {
 		if IsOneCloudVpcResource(wire) {
-			return options.Options.OvnUnderlayMtu - api.VPC_OVN_ENCAP_COST
+			vpcMtu := 0
+			if vpcObj, _ := VpcManager.FetchById(wire.VpcId); vpcObj != nil {
+				vpcMtu = vpcObj.(*SVpc).Mtu
+			}
+			return api.VpcOvnMtu(options.Options.OvnUnderlayMtu, vpcMtu)
 		} else if wire.Mtu != 0 {
 			return wire.Mtu
 		} else {
@@ -44,6 +44,7 @@ var usedAddressQueryProviders = []usedAddressQueryProvider{
 	NetworkinterfacenetworkManager,
 	DBInstanceManager,
 	NetworkAddressManager,
+	FailoverVipManager,
 }
 
 func (manager *SGuestnetworkManager) usedAddressQuery(args *usedAddressQueryArgs) *sqlchemy.SQuery {
@@ -0,0 +1,439 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/timeutils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SPricingRateManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+}
+
+var PricingRateManager *SPricingRateManager
+
+func init() {
+	PricingRateManager = &SPricingRateManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SPricingRate{},
+			"pricing_rates_tbl",
+			"pricing_rate",
+			"pricing_rates",
+		),
+	}
+	PricingRateManager.SetVirtualObject(PricingRateManager)
+}
+
+// 私有云资源单价, 同一资源类型及规格可按生效时间存在多个版本
+type SPricingRate struct {
+	db.SEnabledStatusInfrasResourceBase
+
+	// 资源类型
+	ResourceType string `width:"16" charset:"ascii" nullable:"false" index:"true" list:"user" create:"admin_required"`
+	// 规格, 为空表示该资源类型的默认价格
+	Spec string `width:"64" charset:"utf8" nullable:"false" default:"" list:"user" create:"admin_optional"`
+	// 单价
+	Price float64 `nullable:"false" list:"user" create:"admin_required" width:"20" precision:"6"`
+	// 货币
+	Currency string `width:"8" charset:"ascii" nullable:"false" list:"user" create:"admin_optional"`
+	// 生效时间
+	EffectiveDate time.Time `nullable:"false" index:"true" list:"user" create:"admin_optional"`
+}
+
+func (manager *SPricingRateManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.PricingRateListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ResourceType) > 0 {
+		q = q.In("resource_type", query.ResourceType)
+	}
+	if len(query.Spec) > 0 {
+		q = q.In("spec", query.Spec)
+	}
+	if !query.EffectiveAt.IsZero() {
+		// 每个资源类型及规格仅保留该时间点生效的版本
+		sq := manager.Query().LE("effective_date", query.EffectiveAt).IsTrue("enabled")
+		sq = sq.GroupBy(sq.Field("resource_type"), sq.Field("spec"))
+		sq = sq.AppendField(sq.Field("resource_type"), sq.Field("spec"), sqlchemy.MAX("effective_date", sq.Field("effective_date")))
+		sub := sq.SubQuery()
+		q = q.Join(sub, sqlchemy.AND(
+			sqlchemy.Equals(q.Field("resource_type"), sub.Field("resource_type")),
+			sqlchemy.Equals(q.Field("spec"), sub.Field("spec")),
+			sqlchemy.Equals(q.Field("effective_date"), sub.Field("effective_date")),
+		))
+	}
+	return q, nil
+}
+
+func (manager *SPricingRateManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.PricingRateCreateInput,
+) (api.PricingRateCreateInput, error) {
+	if !utils.IsInStringArray(input.ResourceType, api.PRICING_RESOURCE_TYPES) {
+		return input, httperrors.NewInputParameterError("invalid resource_type %q, want one of %s", input.ResourceType, api.PRICING_RESOURCE_TYPES)
+	}
+	if input.Price < 0 {
+		return input, httperrors.NewInputParameterError("price must not be negative")
+	}
+	currency, err := manager.getCurrency()
+	if err != nil {
+		return input, err
+	}
+	if len(input.Currency) == 0 {
+		input.Currency = currency
+	} else if input.Currency != currency && manager.Query().Count() > 0 {
+		return input, httperrors.NewConflictError("currency %s mismatch with existing pricing rates %s", input.Currency, currency)
+	}
+	if input.EffectiveDate.IsZero() {
+		input.EffectiveDate = timeutils.UtcNow()
+	}
+	input.SetEnabled()
+	input.Status = api.PRICING_RATE_STATUS_AVAILABLE
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SPricingRate) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.PricingRateUpdateInput) (api.PricingRateUpdateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = self.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	return input, err
+}
+
+func (manager *SPricingRateManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.PricingRateDetails {
+	rows := make([]api.PricingRateDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.PricingRateDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+		}
+	}
+	return rows
+}
+
+func (manager *SPricingRateManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SPricingRateManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.PricingRateListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SPricingRateManager) getCurrency() (string, error) {
+	rate := SPricingRate{}
+	rate.SetModelManager(manager, &rate)
+	err := manager.Query().Asc("created_at").First(&rate)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return api.PRICING_DEFAULT_CURRENCY, nil
+		}
+		return "", errors.Wrap(err, "First")
+	}
+	return rate.Currency, nil
+}
+
+// 获取已启用的所有价格, 按生效时间升序
+func (manager *SPricingRateManager) fetchEnabledRates() (sPricingRates, error) {
+	rates := []SPricingRate{}
+	q := manager.Query().IsTrue("enabled").Asc("effective_date")
+	err := db.FetchModelObjects(manager, q, &rates)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return rates, nil
+}
+
+// 按生效时间升序排列的价格
+type sPricingRates []SPricingRate
+
+// 获取时间点 t 生效的价格, 规格未定价时使用该资源类型的默认价格
+func (rates sPricingRates) rateAt(resourceType, spec string, t time.Time) *SPricingRate {
+	var ret, def *SPricingRate
+	for i := range rates {
+		if rates[i].ResourceType != resourceType || rates[i].EffectiveDate.After(t) {
+			continue
+		}
+		if rates[i].Spec == spec {
+			ret = &rates[i]
+		} else if len(rates[i].Spec) == 0 {
+			def = &rates[i]
+		}
+	}
+	if ret != nil {
+		return ret
+	}
+	return def
+}
+
+// 计算 amount 个单位的资源在 [start, end) 内的费用, 区间按价格生效时间切分
+func (rates sPricingRates) cost(resourceType, spec string, amount float64, start, end time.Time) float64 {
+	if !start.Before(end) || amount <= 0 {
+		return 0
+	}
+	points := []time.Time{start}
+	for i := range rates {
+		if rates[i].ResourceType != resourceType {
+			continue
+		}
+		if rates[i].EffectiveDate.After(start) && rates[i].EffectiveDate.Before(end) {
+			points = append(points, rates[i].EffectiveDate)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+	points = append(points, end)
+
+	unitHours := api.PricingUnitHours(resourceType)
+	total := 0.0
+	for i := 0; i+1 < len(points); i++ {
+		rate := rates.rateAt(resourceType, spec, points[i])
+		if rate == nil {
+			continue
+		}
+		hours := points[i+1].Sub(points[i]).Hours()
+		total += rate.Price / unitHours * amount * hours
+	}
+	return total
+}
+
+func (rates sPricingRates) estimateItem(resourceType, spec string, amount float64, t time.Time) api.PricingCostItem {
+	item := api.PricingCostItem{
+		ResourceType: resourceType,
+		Spec:         spec,
+		Amount:       amount,
+	}
+	if rate := rates.rateAt(resourceType, spec, t); rate != nil {
+		item.Price = rate.Price
+		item.HourlyCost = rate.Price / api.PricingUnitHours(resourceType) * amount
+	}
+	return item
+}
+
+// 创建虚拟机前的费用估算
+func (manager *SPricingRateManager) GetPropertyEstimate(ctx context.Context, userCred mcclient.TokenCredential, query api.PricingEstimateInput) (*api.PricingEstimateOutput, error) {
+	if query.Count <= 0 {
+		query.Count = 1
+	}
+	rates, err := manager.fetchEnabledRates()
+	if err != nil {
+		return nil, err
+	}
+	currency, err := manager.getCurrency()
+	if err != nil {
+		return nil, err
+	}
+	now := timeutils.UtcNow()
+	count := float64(query.Count)
+	items := []api.PricingCostItem{}
+	if query.VcpuCount > 0 {
+		items = append(items, rates.estimateItem(api.PRICING_RESOURCE_VCPU, "", float64(query.VcpuCount)*count, now))
+	}
+	if query.VmemSize > 0 {
+		items = append(items, rates.estimateItem(api.PRICING_RESOURCE_MEMORY, "", float64(query.VmemSize)/1024*count, now))
+	}
+	for _, disk := range query.Disks {
+		if disk.SizeMb <= 0 {
+			continue
+		}
+		items = append(items, rates.estimateItem(api.PRICING_RESOURCE_STORAGE, disk.StorageType, float64(disk.SizeMb)/1024*count, now))
+	}
+	for _, gpu := range query.Gpus {
+		if gpu.Count <= 0 {
+			continue
+		}
+		items = append(items, rates.estimateItem(api.PRICING_RESOURCE_GPU, gpu.Model, float64(gpu.Count)*count, now))
+	}
+	ret := &api.PricingEstimateOutput{
+		Currency: currency,
+	}
+	if query.Spot && len(query.SkuId) > 0 {
+		skuObj, err := ServerSkuManager.FetchByIdOrName(userCred, query.SkuId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(ServerSkuManager.Keyword(), query.SkuId)
+		}
+		sku := skuObj.(*SServerSku)
+		if sku.SpotPrice <= 0 {
+			return nil, httperrors.NewNotSupportedError("no spot price for sku %s", sku.Name)
+		}
+		items = append(items, api.PricingCostItem{
+			ResourceType: api.PRICING_RESOURCE_SPOT_INSTANCE,
+			Spec:         sku.Name,
+			Amount:       count,
+			Price:        sku.SpotPrice,
+			HourlyCost:   sku.SpotPrice * count,
+		})
+		ret.SpotInterruptionRate = sku.SpotInterruptionRate
+		ret.SpotBidPrice, err = sku.GetSpotBidPrice()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetSpotBidPrice")
+		}
+	}
+	ret.Items = items
+	for _, item := range items {
+		ret.HourlyCost += item.HourlyCost
+	}
+	ret.MonthlyCost = ret.HourlyCost * api.PRICING_HOURS_PER_MONTH
+	return ret, nil
+}
+
+// 按项目统计 kvm 虚拟机在时间段内的费用, 已删除的虚拟机按删除前的配置计算至删除时间
+func (manager *SPricingRateManager) GetPropertyChargeback(ctx context.Context, userCred mcclient.TokenCredential, query api.PricingChargebackInput) (*api.PricingChargebackOutput, error) {
+	if query.EndTime.IsZero() {
+		query.EndTime = timeutils.UtcNow()
+	}
+	if query.StartTime.IsZero() {
+		query.StartTime = query.EndTime.Add(-30 * 24 * time.Hour)
+	}
+	if !query.StartTime.Before(query.EndTime) {
+		return nil, httperrors.NewInputParameterError("start_time must be before end_time")
+	}
+	if len(query.ProjectId) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrName(ctx, query.ProjectId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("project", query.ProjectId)
+		}
+		query.ProjectId = tenant.Id
+	}
+	if db.IsAdminAllowList(userCred, GuestManager).Result.IsDeny() {
+		if len(query.ProjectId) > 0 && query.ProjectId != userCred.GetProjectId() {
+			return nil, httperrors.NewForbiddenError("not allow to query chargeback of project %s", query.ProjectId)
+		}
+		query.ProjectId = userCred.GetProjectId()
+	}
+
+	rates, err := manager.fetchEnabledRates()
+	if err != nil {
+		return nil, err
+	}
+	currency, err := manager.getCurrency()
+	if err != nil {
+		return nil, err
+	}
+
+	q := GuestManager.RawQuery().Equals("hypervisor", api.HYPERVISOR_KVM).LT("created_at", query.EndTime)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsFalse(q.Field("deleted")),
+		sqlchemy.GT(q.Field("deleted_at"), query.StartTime),
+	))
+	if len(query.ProjectId) > 0 {
+		q = q.Equals("tenant_id", query.ProjectId)
+	}
+	guests := []SGuest{}
+	err = db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+
+	ret := &api.PricingChargebackOutput{
+		Currency:  currency,
+		StartTime: query.StartTime,
+		EndTime:   query.EndTime,
+		Items:     []api.PricingChargebackItem{},
+	}
+	for i := range guests {
+		guest := &guests[i]
+		start, end := query.StartTime, query.EndTime
+		if guest.CreatedAt.After(start) {
+			start = guest.CreatedAt
+		}
+		if guest.Deleted && guest.DeletedAt.Before(end) {
+			end = guest.DeletedAt
+		}
+		if !start.Before(end) {
+			continue
+		}
+		cost := rates.cost(api.PRICING_RESOURCE_VCPU, "", float64(guest.VcpuCount), start, end)
+		cost += rates.cost(api.PRICING_RESOURCE_MEMORY, "", float64(guest.VmemSize)/1024, start, end)
+		if !guest.Deleted {
+			disks, err := guest.GetDisks()
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetDisks for %s", guest.Name)
+			}
+			for j := range disks {
+				storageType := ""
+				if storage, _ := disks[j].GetStorage(); storage != nil {
+					storageType = storage.StorageType
+				}
+				cost += rates.cost(api.PRICING_RESOURCE_STORAGE, storageType, float64(disks[j].DiskSize)/1024, start, end)
+			}
+			devs, err := guest.GetIsolatedDevices()
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetIsolatedDevices for %s", guest.Name)
+			}
+			for j := range devs {
+				if utils.IsInStringArray(devs[j].DevType, api.VALID_GPU_TYPES) {
+					cost += rates.cost(api.PRICING_RESOURCE_GPU, devs[j].Model, 1, start, end)
+				}
+			}
+		}
+		ret.Items = append(ret.Items, api.PricingChargebackItem{
+			GuestId:   guest.Id,
+			Guest:     guest.Name,
+			ProjectId: guest.ProjectId,
+			Hours:     end.Sub(start).Hours(),
+			Cost:      cost,
+		})
+		ret.Total += cost
+	}
+	return ret, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestPricingRatesCost(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rates := sPricingRates{
+		{ResourceType: api.PRICING_RESOURCE_VCPU, Price: 0.1, EffectiveDate: t0},
+		{ResourceType: api.PRICING_RESOURCE_VCPU, Price: 0.2, EffectiveDate: t0.Add(10 * time.Hour)},
+		{ResourceType: api.PRICING_RESOURCE_STORAGE, Price: 72, EffectiveDate: t0},
+		{ResourceType: api.PRICING_RESOURCE_STORAGE, Spec: "ssd", Price: 144, EffectiveDate: t0.Add(5 * time.Hour)},
+	}
+	cases := []struct {
+		name         string
+		resourceType string
+		spec         string
+		amount       float64
+		start        time.Time
+		end          time.Time
+		want         float64
+	}{
+		{
+			name:         "split at effective date",
+			resourceType: api.PRICING_RESOURCE_VCPU,
+			amount:       2,
+			start:        t0,
+			end:          t0.Add(20 * time.Hour),
+			want:         2*0.1*10 + 2*0.2*10,
+		},
+		{
+			name:         "before first rate",
+			resourceType: api.PRICING_RESOURCE_VCPU,
+			amount:       1,
+			start:        t0.Add(-10 * time.Hour),
+			end:          t0.Add(5 * time.Hour),
+			want:         0.5,
+		},
+		{
+			name:         "spec falls back to default",
+			resourceType: api.PRICING_RESOURCE_STORAGE,
+			spec:         "ssd",
+			amount:       10,
+			start:        t0,
+			end:          t0.Add(10 * time.Hour),
+			want:         10*72/720.0*5 + 10*144/720.0*5,
+		},
+		{
+			name:         "no rate",
+			resourceType: api.PRICING_RESOURCE_GPU,
+			amount:       1,
+			start:        t0,
+			end:          t0.Add(10 * time.Hour),
+			want:         0,
+		},
+	}
+	for _, c := range cases {
+		got := rates.cost(c.resourceType, c.spec, c.amount, c.start, c.end)
+		if math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: want %f got %f", c.name, c.want, got)
+		}
+	}
+}
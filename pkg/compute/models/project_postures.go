@@ -0,0 +1,366 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SProjectPostureManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+}
+
+var ProjectPostureManager *SProjectPostureManager
+
+func init() {
+	ProjectPostureManager = &SProjectPostureManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SProjectPosture{},
+			"project_postures_tbl",
+			"project_posture",
+			"project_postures",
+		),
+	}
+	ProjectPostureManager.SetVirtualObject(ProjectPostureManager)
+}
+
+// 项目安全基线, 在创建虚拟机时统一注入缺省安全组、强制标签、磁盘加密及元数据服务访问模式, 并限制可用镜像
+type SProjectPosture struct {
+	db.SEnabledStatusInfrasResourceBase
+
+	// 生效的项目, 为空时作为整个域的缺省基线
+	ProjectId string `width:"128" charset:"ascii" nullable:"true" index:"true" list:"domain" create:"domain_optional"`
+
+	DefaultSecgroups *api.PostureIdList `list:"domain" update:"domain" create:"domain_optional"`
+
+	// 强制标签, 值为空表示必须由用户指定
+	MandatoryTags *api.TagPolicyTags `list:"domain" update:"domain" create:"domain_optional"`
+
+	// 缺省磁盘加密密钥
+	EncryptKeyId string `width:"128" charset:"ascii" nullable:"true" list:"domain" update:"domain" create:"domain_optional"`
+
+	ImdsMode string `width:"16" charset:"ascii" default:"enabled" list:"domain" update:"domain" create:"domain_optional"`
+
+	// 允许使用的镜像, 为空表示不限制
+	AllowedImages *api.PostureIdList `list:"domain" update:"domain" create:"domain_optional"`
+}
+
+func (manager *SProjectPostureManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ProjectPostureListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ProjectId) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrName(ctx, query.ProjectId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("project", query.ProjectId)
+		}
+		q = q.Equals("project_id", tenant.Id)
+	}
+	return q, nil
+}
+
+func validatePostureSecgroups(userCred mcclient.TokenCredential, secgroups api.PostureIdList) (api.PostureIdList, error) {
+	ret := api.PostureIdList{}
+	for _, secgroup := range secgroups {
+		secObj, err := SecurityGroupManager.FetchByIdOrName(userCred, secgroup)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(SecurityGroupManager.Keyword(), secgroup)
+		}
+		if !utils.IsInStringArray(secObj.GetId(), ret) {
+			ret = append(ret, secObj.GetId())
+		}
+	}
+	return ret, nil
+}
+
+func validatePostureImages(ctx context.Context, userCred mcclient.TokenCredential, images api.PostureIdList) (api.PostureIdList, error) {
+	ret := api.PostureIdList{}
+	for _, image := range images {
+		img, err := CachedimageManager.GetImageInfo(ctx, userCred, image, false)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("image", image)
+		}
+		if !utils.IsInStringArray(img.Id, ret) {
+			ret = append(ret, img.Id)
+		}
+	}
+	return ret, nil
+}
+
+func validatePostureTags(tags api.TagPolicyTags) error {
+	for k := range tags {
+		if len(k) == 0 {
+			return httperrors.NewInputParameterError("empty tag key")
+		}
+	}
+	return nil
+}
+
+func validatePostureEncryptKey(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, keyId string) (string, error) {
+	input := apis.EncryptedResourceCreateInput{EncryptKeyId: &keyId}
+	input, err := (&db.SEncryptedResourceManager{}).ValidateCreateData(ctx, userCred, ownerId, nil, input)
+	if err != nil {
+		return "", errors.Wrap(err, "validate encrypt key")
+	}
+	return *input.EncryptKeyId, nil
+}
+
+func (manager *SProjectPostureManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.ProjectPostureCreateInput,
+) (api.ProjectPostureCreateInput, error) {
+	var err error
+	if len(input.ProjectId) > 0 {
+		projectInput := apis.ProjectizedResourceInput{ProjectId: input.ProjectId}
+		tenant, _, err := db.ValidateProjectizedResourceInput(ctx, projectInput)
+		if err != nil {
+			return input, err
+		}
+		if tenant.DomainId != ownerId.GetProjectDomainId() {
+			return input, httperrors.NewInputParameterError("project %s not in domain %s", tenant.Name, ownerId.GetProjectDomain())
+		}
+		input.ProjectId = tenant.Id
+	}
+	q := manager.Query().Equals("domain_id", ownerId.GetProjectDomainId())
+	if len(input.ProjectId) > 0 {
+		q = q.Equals("project_id", input.ProjectId)
+	} else {
+		q = q.IsNullOrEmpty("project_id")
+	}
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return input, httperrors.NewInternalServerError("CountWithError %v", err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewDuplicateResourceError("project posture for %s already exists", input.ProjectId)
+	}
+	input.DefaultSecgroups, err = validatePostureSecgroups(userCred, input.DefaultSecgroups)
+	if err != nil {
+		return input, err
+	}
+	input.AllowedImages, err = validatePostureImages(ctx, userCred, input.AllowedImages)
+	if err != nil {
+		return input, err
+	}
+	err = validatePostureTags(input.MandatoryTags)
+	if err != nil {
+		return input, err
+	}
+	if len(input.EncryptKeyId) > 0 {
+		input.EncryptKeyId, err = validatePostureEncryptKey(ctx, userCred, ownerId, input.EncryptKeyId)
+		if err != nil {
+			return input, err
+		}
+	}
+	if len(input.ImdsMode) == 0 {
+		input.ImdsMode = api.IMDS_MODE_ENABLED
+	}
+	if !utils.IsInStringArray(input.ImdsMode, api.IMDS_MODES) {
+		return input, httperrors.NewInputParameterError("invalid imds_mode %s", input.ImdsMode)
+	}
+	input.SetEnabled()
+	input.Status = api.PROJECT_POSTURE_STATUS_AVAILABLE
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SProjectPosture) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ProjectPostureUpdateInput) (api.ProjectPostureUpdateInput, error) {
+	var err error
+	if input.DefaultSecgroups != nil {
+		input.DefaultSecgroups, err = validatePostureSecgroups(userCred, input.DefaultSecgroups)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.AllowedImages != nil {
+		input.AllowedImages, err = validatePostureImages(ctx, userCred, input.AllowedImages)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.MandatoryTags != nil {
+		err = validatePostureTags(input.MandatoryTags)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.EncryptKeyId != nil && len(*input.EncryptKeyId) > 0 {
+		keyId, err := validatePostureEncryptKey(ctx, userCred, userCred, *input.EncryptKeyId)
+		if err != nil {
+			return input, err
+		}
+		input.EncryptKeyId = &keyId
+	}
+	if len(input.ImdsMode) > 0 && !utils.IsInStringArray(input.ImdsMode, api.IMDS_MODES) {
+		return input, httperrors.NewInputParameterError("invalid imds_mode %s", input.ImdsMode)
+	}
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = self.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	return input, err
+}
+
+func (manager *SProjectPostureManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ProjectPostureDetails {
+	rows := make([]api.ProjectPostureDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	projectIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.ProjectPostureDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+		}
+		projectIds[i] = objs[i].(*SProjectPosture).ProjectId
+	}
+	projects := db.DefaultProjectsFetcher(ctx, projectIds, false)
+	for i := range rows {
+		if project, ok := projects[projectIds[i]]; ok {
+			rows[i].Project = project.Name
+		}
+	}
+	return rows
+}
+
+func (manager *SProjectPostureManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SProjectPostureManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ProjectPostureListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+// 获取项目适用的安全基线, 项目级基线优先于域级基线, 均未配置时返回nil
+func (manager *SProjectPostureManager) GetPosture(domainId, projectId string) (*SProjectPosture, error) {
+	q := manager.Query().Equals("domain_id", domainId).IsTrue("enabled")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsNullOrEmpty(q.Field("project_id")),
+		sqlchemy.Equals(q.Field("project_id"), projectId),
+	))
+	postures := []SProjectPosture{}
+	err := db.FetchModelObjects(manager, q, &postures)
+	if err != nil {
+		return nil, errors.Wrapf(err, "db.FetchModelObjects")
+	}
+	var ret *SProjectPosture
+	for i := range postures {
+		if len(postures[i].ProjectId) > 0 || ret == nil {
+			ret = &postures[i]
+		}
+	}
+	if ret != nil {
+		ret.SetModelManager(manager, ret)
+	}
+	return ret, nil
+}
+
+func (self *SProjectPosture) getDefaultSecgroups() []string {
+	if self == nil || self.DefaultSecgroups == nil {
+		return nil
+	}
+	return *self.DefaultSecgroups
+}
+
+// 校验镜像是否在基线允许的范围内
+func (self *SProjectPosture) isImageAllowed(imageId string) bool {
+	if self.AllowedImages == nil || len(*self.AllowedImages) == 0 || len(imageId) == 0 {
+		return true
+	}
+	return utils.IsInStringArray(imageId, *self.AllowedImages)
+}
+
+// 将强制标签合并进创建参数中的标签, 缺少必填标签时报错
+func (self *SProjectPosture) mergeMandatoryTags(meta map[string]string) (map[string]string, error) {
+	if self.MandatoryTags == nil || len(*self.MandatoryTags) == 0 {
+		return meta, nil
+	}
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	keys := map[string]string{}
+	for k := range meta {
+		keys[strings.TrimPrefix(k, db.USER_TAG_PREFIX)] = k
+	}
+	for k, v := range *self.MandatoryTags {
+		if _, ok := keys[k]; ok {
+			continue
+		}
+		if len(v) == 0 {
+			return nil, httperrors.NewInputParameterError("project posture %s requires tag %s", self.Name, k)
+		}
+		meta[db.USER_TAG_PREFIX+k] = v
+	}
+	return meta, nil
+}
+
+// 校验虚拟机的系统盘镜像及光盘镜像是否在基线允许的范围内
+func (self *SProjectPosture) validateServerImages(input *api.ServerCreateInput) error {
+	imageIds := []string{}
+	if len(input.Disks) > 0 && len(input.Disks[0].ImageId) > 0 {
+		imageIds = append(imageIds, input.Disks[0].ImageId)
+	}
+	if len(input.Cdrom) > 0 {
+		imageIds = append(imageIds, input.Cdrom)
+	}
+	for _, imageId := range imageIds {
+		if !self.isImageAllowed(imageId) {
+			return httperrors.NewForbiddenError("image %s is not allowed by project posture %s", imageId, self.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSProjectPosture_mergeMandatoryTags(t *testing.T) {
+	posture := &SProjectPosture{
+		MandatoryTags: &api.TagPolicyTags{
+			"env":   "prod",
+			"owner": "",
+		},
+	}
+	cases := []struct {
+		name    string
+		meta    map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "missing required",
+			meta:    map[string]string{"env": "dev"},
+			wantErr: true,
+		},
+		{
+			name: "inject default",
+			meta: map[string]string{"user:owner": "alice"},
+			want: map[string]string{"user:owner": "alice", "user:env": "prod"},
+		},
+		{
+			name: "keep user value",
+			meta: map[string]string{"env": "dev", "owner": "bob"},
+			want: map[string]string{"env": "dev", "owner": "bob"},
+		},
+	}
+	for _, c := range cases {
+		got, err := posture.mergeMandatoryTags(c.meta)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", c.name, err, c.wantErr)
+			continue
+		}
+		if !c.wantErr && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSProjectPosture_isImageAllowed(t *testing.T) {
+	posture := &SProjectPosture{}
+	if !posture.isImageAllowed("img1") {
+		t.Errorf("empty allowed images should allow all")
+	}
+	posture.AllowedImages = &api.PostureIdList{"img1"}
+	if !posture.isImageAllowed("img1") || posture.isImageAllowed("img2") {
+		t.Errorf("allowed images not honored")
+	}
+}
+
+func TestStricterImdsMode(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"", api.IMDS_MODE_ENABLED, ""},
+		{"", api.IMDS_MODE_TOKEN_REQUIRED, api.IMDS_MODE_TOKEN_REQUIRED},
+		{api.IMDS_MODE_DISABLED, api.IMDS_MODE_TOKEN_REQUIRED, api.IMDS_MODE_DISABLED},
+		{api.IMDS_MODE_ENABLED, api.IMDS_MODE_DISABLED, api.IMDS_MODE_DISABLED},
+	}
+	for _, c := range cases {
+		if got := api.StricterImdsMode(c.a, c.b); got != c.want {
+			t.Errorf("StricterImdsMode(%q, %q) = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
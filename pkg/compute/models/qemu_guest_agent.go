@@ -16,16 +16,18 @@ package models
 
 import (
 	"context"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
-	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/logclient"
 )
 
 func (self *SGuest) UpdateQgaStatus(status string) error {
@@ -54,7 +56,7 @@ func (self *SGuest) PerformQgaSetPassword(
 	if input.Password == "" {
 		return nil, httperrors.NewMissingParameterError("password")
 	}
-	err := seclib2.ValidatePassword(input.Password)
+	err := self.validatePassword(input.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -89,3 +91,121 @@ func (self *SGuest) PerformQgaCommand(
 
 	return self.GetDriver().RequestQgaCommand(ctx, userCred, jsonutils.Marshal(input), host, self)
 }
+
+func (self *SGuest) requestQgaAction(ctx context.Context, userCred mcclient.TokenCredential, action string, input interface{}) (jsonutils.JSONObject, error) {
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewBadRequestError("can't use qga in vm status: %s", self.Status)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetHost")
+	}
+	self.UpdateQgaStatus(api.QGA_STATUS_EXCUTING)
+	res, err := self.GetDriver().RequestQgaAction(ctx, userCred, action, jsonutils.Marshal(input), host, self)
+	if err != nil {
+		self.UpdateQgaStatus(api.QGA_STATUS_EXECUTE_FAILED)
+		return nil, err
+	}
+	self.UpdateQgaStatus(api.QGA_STATUS_AVAILABLE)
+	return res, nil
+}
+
+// 通过 qemu-guest-agent 在虚机内执行命令并等待结果
+func (self *SGuest) PerformQgaExec(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input *api.ServerQgaExecInput,
+) (*api.ServerQgaExecOutput, error) {
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	notes := strings.Join(append([]string{input.Path}, input.Args...), " ")
+	res, err := self.requestQgaAction(ctx, userCred, "qga-exec", input)
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_EXEC, notes, userCred, false)
+		return nil, err
+	}
+	output := &api.ServerQgaExecOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal qga exec output")
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_EXEC, notes, userCred, true)
+	return output, nil
+}
+
+// 通过 qemu-guest-agent 写入虚机内文件
+func (self *SGuest) PerformQgaFileWrite(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input *api.ServerQgaFileWriteInput,
+) (jsonutils.JSONObject, error) {
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	_, err := self.requestQgaAction(ctx, userCred, "qga-file-write", input)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_FILE_WRITE, input.Path, userCred, err == nil)
+	return nil, err
+}
+
+// 通过 qemu-guest-agent 读取虚机内文件
+func (self *SGuest) PerformQgaFileRead(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input *api.ServerQgaFileReadInput,
+) (*api.ServerQgaFileReadOutput, error) {
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	res, err := self.requestQgaAction(ctx, userCred, "qga-file-read", input)
+	if err != nil {
+		return nil, err
+	}
+	output := &api.ServerQgaFileReadOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal qga file read output")
+	}
+	return output, nil
+}
+
+// 冻结, 解冻或查询虚机内文件系统状态, 冻结超过 10 分钟会被自动解冻
+func (self *SGuest) PerformQgaFsfreeze(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input *api.ServerQgaFsfreezeInput,
+) (*api.ServerQgaFsfreezeOutput, error) {
+	if len(input.Action) == 0 {
+		input.Action = api.QGA_FSFREEZE_STATUS
+	}
+	if !utils.IsInStringArray(input.Action, []string{api.QGA_FSFREEZE_FREEZE, api.QGA_FSFREEZE_THAW, api.QGA_FSFREEZE_STATUS}) {
+		return nil, httperrors.NewInputParameterError("invalid fsfreeze action %q", input.Action)
+	}
+	res, err := self.requestQgaAction(ctx, userCred, "qga-fsfreeze", input)
+	if input.Action != api.QGA_FSFREEZE_STATUS {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_QGA_FSFREEZE, input.Action, userCred, err == nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	output := &api.ServerQgaFsfreezeOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal qga fsfreeze output")
+	}
+	return output, nil
+}
+
+// 通过 qemu-guest-agent 获取虚机内网卡及 IP 地址
+func (self *SGuest) GetDetailsQgaNetworkInterfaces(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.ServerQgaNetworkInterfacesOutput, error) {
+	res, err := self.requestQgaAction(ctx, userCred, "qga-network-interfaces", nil)
+	if err != nil {
+		return nil, err
+	}
+	output := &api.ServerQgaNetworkInterfacesOutput{}
+	if err := res.Unmarshal(output); err != nil {
+		return nil, errors.Wrap(err, "unmarshal qga network interfaces")
+	}
+	return output, nil
+}
@@ -77,6 +77,7 @@ type IRegionDriver interface {
 	RequestStopLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *SLoadbalancerListener, task taskman.ITask) error
 	RequestSyncstatusLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *SLoadbalancerListener, task taskman.ITask) error
 	RequestSyncLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *SLoadbalancerListener, task taskman.ITask) error
+	RequestSetLoadbalancerListenerAccessLog(ctx context.Context, userCred mcclient.TokenCredential, lblis *SLoadbalancerListener, task taskman.ITask) error
 
 	IsSupportLoadbalancerListenerRuleRedirect() bool
 	ValidateCreateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerRuleCreateInput) (*api.LoadbalancerListenerRuleCreateInput, error)
@@ -114,7 +115,9 @@ type IRegionDriver interface {
 
 	RequestSyncDiskBackupStatus(ctx context.Context, userCred mcclient.TokenCredential, backup *SDiskBackup, task taskman.ITask) error
 	RequestCreateBackup(ctx context.Context, backup *SDiskBackup, snapshotId string, task taskman.ITask) error
+	RequestCreateIncrementalBackup(ctx context.Context, backup *SDiskBackup, parentBackupId string, task taskman.ITask) error
 	RequestDeleteBackup(ctx context.Context, backup *SDiskBackup, task taskman.ITask) error
+	RequestVerifyDiskBackup(ctx context.Context, backup *SDiskBackup, task taskman.ITask) error
 	RequestCreateInstanceBackup(ctx context.Context, guest *SGuest, ib *SInstanceBackup, task taskman.ITask, params *jsonutils.JSONDict) error
 	RequestDeleteInstanceBackup(ctx context.Context, ib *SInstanceBackup, task taskman.ITask) error
 	RequestSyncInstanceBackupStatus(ctx context.Context, userCred mcclient.TokenCredential, ib *SInstanceBackup, task taskman.ITask) error
@@ -268,6 +271,9 @@ type IElasticcacheBackup interface {
 
 type IElasticIpDriver interface {
 	RequestAssociateEip(ctx context.Context, userCred mcclient.TokenCredential, eip *SElasticip, input api.ElasticipAssociateInput, obj db.IStatusStandaloneModel, task taskman.ITask) error
+
+	ValidateChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *SElasticip, bandwidth int) error
+	RequestChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *SElasticip, bandwidth int, task taskman.ITask) error
 }
 
 var regionDrivers map[string]IRegionDriver
@@ -0,0 +1,418 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 运维手册的执行记录
+type SRunbookExecutionManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var RunbookExecutionManager *SRunbookExecutionManager
+
+func init() {
+	RunbookExecutionManager = &SRunbookExecutionManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SRunbookExecution{},
+			"runbook_executions_tbl",
+			"runbook_execution",
+			"runbook_executions",
+		),
+	}
+	RunbookExecutionManager.SetVirtualObject(RunbookExecutionManager)
+}
+
+type SRunbookExecution struct {
+	db.SStatusStandaloneResourceBase
+
+	RunbookId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 虚拟机删除后仍保留以便审计
+	GuestId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	AlertId string `width:"36" charset:"ascii" nullable:"true" list:"admin" index:"true"`
+
+	// 触发时的动作快照, 手册修改不影响已触发的执行
+	Steps *api.RunbookSteps `length:"text" nullable:"true" list:"admin"`
+	// 执行参数
+	Params jsonutils.JSONObject `length:"text" nullable:"true" list:"admin"`
+	// 当前执行到的动作序号
+	StepIndex int `nullable:"false" default:"0" list:"admin"`
+	// 每个动作的执行结果
+	Results *api.RunbookStepResults `length:"text" nullable:"true" list:"admin"`
+
+	ApprovedBy string    `width:"128" charset:"utf8" nullable:"true" list:"admin"`
+	StartedAt  time.Time `nullable:"true" list:"admin"`
+	FinishedAt time.Time `nullable:"true" list:"admin"`
+}
+
+func (manager *SRunbookExecutionManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("use runbook trigger action instead")
+}
+
+func (self *SRunbookExecution) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if self.Status == api.RUNBOOK_EXECUTION_STATUS_RUNNING {
+		return httperrors.NewInvalidStatusError("runbook execution %s is running", self.Name)
+	}
+	return self.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (manager *SRunbookExecutionManager) createExecution(ctx context.Context, userCred mcclient.TokenCredential, runbook *SRunbook, guest *SGuest, input api.RunbookTriggerInput) (*SRunbookExecution, error) {
+	params := map[string]string{}
+	for k, v := range input.Params {
+		params[k] = v
+	}
+	params[api.RUNBOOK_PARAM_GUEST_ID] = guest.Id
+	params[api.RUNBOOK_PARAM_ALERT_ID] = input.AlertId
+
+	steps := runbook.GetSteps()
+	execution := &SRunbookExecution{
+		RunbookId: runbook.Id,
+		GuestId:   guest.Id,
+		AlertId:   input.AlertId,
+		Steps:     &steps,
+		Params:    jsonutils.Marshal(params),
+		Results:   &api.RunbookStepResults{},
+	}
+	execution.SetModelManager(manager, execution)
+	execution.Name = fmt.Sprintf("%s-%s", runbook.Name, guest.Name)
+	execution.Status = api.RUNBOOK_EXECUTION_STATUS_RUNNING
+	if runbook.RequireApproval.IsTrue() {
+		execution.Status = api.RUNBOOK_EXECUTION_STATUS_PENDING_APPROVAL
+	}
+	err := manager.TableSpec().Insert(ctx, execution)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "Insert"))
+	}
+	return execution, nil
+}
+
+func (self *SRunbookExecution) GetRunbook() (*SRunbook, error) {
+	runbookObj, err := RunbookManager.FetchById(self.RunbookId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.RunbookId)
+	}
+	return runbookObj.(*SRunbook), nil
+}
+
+func (self *SRunbookExecution) GetGuest() (*SGuest, error) {
+	guestObj, err := GuestManager.FetchById(self.GuestId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.GuestId)
+	}
+	return guestObj.(*SGuest), nil
+}
+
+func (self *SRunbookExecution) GetSteps() api.RunbookSteps {
+	if self.Steps == nil {
+		return nil
+	}
+	return *self.Steps
+}
+
+func (self *SRunbookExecution) GetParams() map[string]string {
+	params := map[string]string{}
+	if self.Params != nil {
+		self.Params.Unmarshal(&params)
+	}
+	return params
+}
+
+func (self *SRunbookExecution) StartExecuteTask(ctx context.Context, userCred mcclient.TokenCredential) error {
+	_, err := db.Update(self, func() error {
+		self.Status = api.RUNBOOK_EXECUTION_STATUS_RUNNING
+		self.StartedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "RunbookExecuteTask", self, userCred, nil, "", "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.RUNBOOK_EXECUTION_STATUS_FAILED, err.Error())
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// 审批通过后开始执行
+func (self *SRunbookExecution) PerformApprove(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RunbookExecutionApproveInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.RUNBOOK_EXECUTION_STATUS_PENDING_APPROVAL {
+		return nil, httperrors.NewInvalidStatusError("runbook execution %s is %s", self.Name, self.Status)
+	}
+	_, err := db.Update(self, func() error {
+		self.ApprovedBy = userCred.GetUserName()
+		return nil
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_RUNBOOK_APPROVE, input.Reason, userCred, true)
+	return nil, self.StartExecuteTask(ctx, userCred)
+}
+
+func (self *SRunbookExecution) PerformReject(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RunbookExecutionApproveInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.RUNBOOK_EXECUTION_STATUS_PENDING_APPROVAL {
+		return nil, httperrors.NewInvalidStatusError("runbook execution %s is %s", self.Name, self.Status)
+	}
+	_, err := db.Update(self, func() error {
+		self.ApprovedBy = userCred.GetUserName()
+		self.FinishedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	self.SetStatus(userCred, api.RUNBOOK_EXECUTION_STATUS_REJECTED, input.Reason)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_RUNBOOK_REJECT, input.Reason, userCred, true)
+	return nil, nil
+}
+
+// 记录当前动作结果并前进到下一个动作
+func (self *SRunbookExecution) AppendResult(result api.RunbookStepResult) error {
+	_, err := db.Update(self, func() error {
+		results := api.RunbookStepResults{}
+		if self.Results != nil {
+			results = *self.Results
+		}
+		results = append(results, result)
+		self.Results = &results
+		self.StepIndex += 1
+		return nil
+	})
+	return err
+}
+
+func (self *SRunbookExecution) MarkFinished(userCred mcclient.TokenCredential, status string, reason string) error {
+	_, err := db.Update(self, func() error {
+		self.FinishedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return self.SetStatus(userCred, status, reason)
+}
+
+// ExecuteStep 执行单个动作, 返回 true 表示动作以子任务异步执行, 完成后回调父任务
+func (self *SRunbookExecution) ExecuteStep(ctx context.Context, userCred mcclient.TokenCredential, step api.RunbookStep, parentTaskId string) (bool, error) {
+	step = step.Render(self.GetParams())
+	guest, err := self.GetGuest()
+	if err != nil {
+		return false, errors.Wrap(err, "GetGuest")
+	}
+	switch step.Action {
+	case api.RUNBOOK_ACTION_RESTART_GUEST:
+		return true, self.restartGuest(ctx, userCred, guest, step, parentTaskId)
+	case api.RUNBOOK_ACTION_RESIZE_DISK:
+		return true, self.resizeGuestDisk(ctx, userCred, guest, step, parentTaskId)
+	case api.RUNBOOK_ACTION_NOTIFY:
+		return false, self.notify(ctx, guest, step)
+	}
+	return false, errors.Wrapf(errors.ErrNotSupported, "action %s", step.Action)
+}
+
+func (self *SRunbookExecution) restartGuest(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, step api.RunbookStep, parentTaskId string) error {
+	switch guest.Status {
+	case api.VM_RUNNING:
+		isForce, _ := strconv.ParseBool(step.Params["is_force"])
+		return guest.GetDriver().StartGuestRestartTask(guest, ctx, userCred, isForce, parentTaskId)
+	case api.VM_READY:
+		return guest.StartGueststartTask(ctx, userCred, nil, parentTaskId)
+	}
+	return httperrors.NewInvalidStatusError("cannot restart guest %s in status %s", guest.Name, guest.Status)
+}
+
+// 计算扩容后的磁盘大小, 不超过 max_size_mb
+func runbookGrowDiskSize(sizeMb int, params map[string]string) (int, error) {
+	growMb := 0
+	if val := params["grow_size_mb"]; len(val) > 0 {
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid grow_size_mb %q", val)
+		}
+		growMb = v
+	} else if val := params["grow_percent"]; len(val) > 0 {
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid grow_percent %q", val)
+		}
+		growMb = sizeMb * v / 100
+	}
+	newSize := sizeMb + growMb
+	if val := params["max_size_mb"]; len(val) > 0 {
+		maxMb, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid max_size_mb %q", val)
+		}
+		if maxMb > 0 && newSize > maxMb {
+			newSize = maxMb
+		}
+	}
+	if newSize <= sizeMb {
+		return 0, errors.Errorf("disk size %dMB cannot grow any more", sizeMb)
+	}
+	return newSize, nil
+}
+
+func (self *SRunbookExecution) resizeGuestDisk(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, step api.RunbookStep, parentTaskId string) error {
+	disks, err := guest.GetDisks()
+	if err != nil {
+		return errors.Wrap(err, "GetDisks")
+	}
+	idx := 0
+	if val := step.Params["disk_index"]; len(val) > 0 {
+		idx, err = strconv.Atoi(val)
+		if err != nil {
+			return errors.Wrapf(err, "invalid disk_index %q", val)
+		}
+	}
+	if idx < 0 || idx >= len(disks) {
+		return httperrors.NewInputParameterError("guest %s has no disk at index %d", guest.Name, idx)
+	}
+	disk := &disks[idx]
+	sizeMb, err := runbookGrowDiskSize(disk.DiskSize, step.Params)
+	if err != nil {
+		return err
+	}
+	return disk.doResize(ctx, userCred, sizeMb, guest, parentTaskId)
+}
+
+func (self *SRunbookExecution) notify(ctx context.Context, guest *SGuest, step api.RunbookStep) error {
+	receivers := []string{}
+	for _, id := range strings.Split(step.Params["receiver_ids"], ",") {
+		if id = strings.TrimSpace(id); len(id) > 0 {
+			receivers = append(receivers, id)
+		}
+	}
+	if len(receivers) == 0 {
+		return httperrors.NewMissingParameterError("receiver_ids")
+	}
+	runbookName := self.RunbookId
+	if runbook, err := self.GetRunbook(); err == nil {
+		runbookName = runbook.Name
+	}
+	data := jsonutils.NewDict()
+	data.Set("runbook", jsonutils.NewString(runbookName))
+	data.Set("execution_id", jsonutils.NewString(self.Id))
+	data.Set("guest_id", jsonutils.NewString(guest.Id))
+	data.Set("guest", jsonutils.NewString(guest.Name))
+	data.Set("alert_id", jsonutils.NewString(self.AlertId))
+	data.Set("message", jsonutils.NewString(step.Params["message"]))
+	notifyclient.NotifyImportantWithCtx(ctx, receivers, false, notifyclient.RUNBOOK_NOTIFY, data)
+	return nil
+}
+
+func (manager *SRunbookExecutionManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RunbookExecutionListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.RunbookId) > 0 {
+		runbook, err := RunbookManager.FetchByIdOrName(userCred, query.RunbookId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(RunbookManager.Keyword(), query.RunbookId)
+		}
+		q = q.Equals("runbook_id", runbook.GetId())
+	}
+	if len(query.GuestId) > 0 {
+		// 虚拟机可能已删除, 无法解析时按Id直接过滤
+		if guest, err := GuestManager.FetchByIdOrName(userCred, query.GuestId); err == nil {
+			q = q.Equals("guest_id", guest.GetId())
+		} else {
+			q = q.Equals("guest_id", query.GuestId)
+		}
+	}
+	if len(query.AlertId) > 0 {
+		q = q.Equals("alert_id", query.AlertId)
+	}
+	return q, nil
+}
+
+func (manager *SRunbookExecutionManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RunbookExecutionListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (manager *SRunbookExecutionManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SRunbookExecutionManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.RunbookExecutionDetails {
+	rows := make([]api.RunbookExecutionDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	runbookIds := make([]string, len(objs))
+	guestIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		execution := objs[i].(*SRunbookExecution)
+		runbookIds[i] = execution.RunbookId
+		guestIds[i] = execution.GuestId
+	}
+	runbooks := make(map[string]SRunbook)
+	if err := db.FetchStandaloneObjectsByIds(RunbookManager, runbookIds, runbooks); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds runbooks fail %s", err)
+		return rows
+	}
+	guests := make(map[string]SGuest)
+	if err := db.FetchStandaloneObjectsByIds(GuestManager, guestIds, guests); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds guests fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if runbook, ok := runbooks[runbookIds[i]]; ok {
+			rows[i].Runbook = runbook.Name
+		}
+		if guest, ok := guests[guestIds[i]]; ok {
+			rows[i].Guest = guest.Name
+		}
+	}
+	return rows
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 告警自动处置手册, 绑定告警后按顺序执行重启虚拟机、扩容磁盘、通知等动作
+type SRunbookManager struct {
+	db.SEnabledStatusStandaloneResourceBaseManager
+}
+
+var RunbookManager *SRunbookManager
+
+func init() {
+	RunbookManager = &SRunbookManager{
+		SEnabledStatusStandaloneResourceBaseManager: db.NewEnabledStatusStandaloneResourceBaseManager(
+			SRunbook{},
+			"runbooks_tbl",
+			"runbook",
+			"runbooks",
+		),
+	}
+	RunbookManager.SetVirtualObject(RunbookManager)
+}
+
+type SRunbook struct {
+	db.SEnabledStatusStandaloneResourceBase
+
+	// 按顺序执行的动作
+	Steps *api.RunbookSteps `length:"text" nullable:"false" list:"admin" create:"admin_required" update:"admin"`
+	// 执行前是否需要人工审批
+	RequireApproval tristate.TriState `default:"false" list:"admin" create:"admin_optional" update:"admin"`
+	// 同一虚拟机两次执行的最小间隔(分钟)
+	CooldownMinutes int `nullable:"false" default:"30" list:"admin" create:"admin_optional" update:"admin"`
+}
+
+func validateRunbookSteps(steps api.RunbookSteps) error {
+	if len(steps) == 0 {
+		return httperrors.NewMissingParameterError("steps")
+	}
+	for i, step := range steps {
+		if !utils.IsInStringArray(step.Action, api.RUNBOOK_ACTIONS) {
+			return httperrors.NewInputParameterError("steps.%d: invalid action %q, support %v", i, step.Action, api.RUNBOOK_ACTIONS)
+		}
+		switch step.Action {
+		case api.RUNBOOK_ACTION_RESIZE_DISK:
+			if len(step.Params["grow_size_mb"]) == 0 && len(step.Params["grow_percent"]) == 0 {
+				return httperrors.NewMissingParameterError(fmt.Sprintf("steps.%d.params.grow_size_mb or grow_percent", i))
+			}
+			for _, key := range []string{"disk_index", "grow_size_mb", "grow_percent", "max_size_mb"} {
+				val, ok := step.Params[key]
+				if !ok || isRunbookPlaceholder(val) {
+					continue
+				}
+				if v, err := strconv.Atoi(val); err != nil || v < 0 {
+					return httperrors.NewInputParameterError("steps.%d.params.%s: invalid number %q", i, key, val)
+				}
+			}
+		case api.RUNBOOK_ACTION_NOTIFY:
+			if len(step.Params["receiver_ids"]) == 0 {
+				return httperrors.NewMissingParameterError(fmt.Sprintf("steps.%d.params.receiver_ids", i))
+			}
+		}
+	}
+	return nil
+}
+
+func isRunbookPlaceholder(val string) bool {
+	return len(val) > 3 && val[0] == '$' && val[1] == '{' && val[len(val)-1] == '}'
+}
+
+func (manager *SRunbookManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.RunbookCreateInput) (api.RunbookCreateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceCreateInput, err = manager.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	err = validateRunbookSteps(input.Steps)
+	if err != nil {
+		return input, err
+	}
+	if input.CooldownMinutes != nil && *input.CooldownMinutes < 0 {
+		return input, httperrors.NewInputParameterError("cooldown_minutes must not be negative")
+	}
+	input.Status = api.RUNBOOK_STATUS_READY
+	return input, nil
+}
+
+func (self *SRunbook) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RunbookUpdateInput) (api.RunbookUpdateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = self.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.Steps != nil {
+		err = validateRunbookSteps(input.Steps)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.CooldownMinutes != nil && *input.CooldownMinutes < 0 {
+		return input, httperrors.NewInputParameterError("cooldown_minutes must not be negative")
+	}
+	return input, nil
+}
+
+func (self *SRunbook) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	cnt, err := RunbookExecutionManager.Query().Equals("runbook_id", self.Id).
+		In("status", []string{api.RUNBOOK_EXECUTION_STATUS_PENDING_APPROVAL, api.RUNBOOK_EXECUTION_STATUS_RUNNING}).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("runbook %s still has %d unfinished executions", self.Name, cnt)
+	}
+	return self.SEnabledStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *SRunbook) GetSteps() api.RunbookSteps {
+	if self.Steps == nil {
+		return nil
+	}
+	return *self.Steps
+}
+
+// 告警触发执行手册, 冷却期内对同一虚拟机的重复触发会被忽略
+func (self *SRunbook) PerformTrigger(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.RunbookTriggerInput) (jsonutils.JSONObject, error) {
+	if !self.GetEnabled() {
+		return nil, httperrors.NewInvalidStatusError("runbook %s is disabled", self.Name)
+	}
+	if len(input.GuestId) == 0 {
+		return nil, httperrors.NewMissingParameterError("guest_id")
+	}
+	guestObj, err := GuestManager.FetchByIdOrName(userCred, input.GuestId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(GuestManager.Keyword(), input.GuestId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	guest := guestObj.(*SGuest)
+
+	if self.CooldownMinutes > 0 {
+		since := time.Now().Add(-time.Duration(self.CooldownMinutes) * time.Minute)
+		cnt, err := RunbookExecutionManager.Query().Equals("runbook_id", self.Id).Equals("guest_id", guest.Id).
+			NotEquals("status", api.RUNBOOK_EXECUTION_STATUS_REJECTED).GE("created_at", since).CountWithError()
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		if cnt > 0 {
+			log.Infof("runbook %s for guest %s is cooling down, skip alert %s", self.Name, guest.Name, input.AlertId)
+			return jsonutils.Marshal(map[string]bool{"skipped": true}), nil
+		}
+	}
+
+	execution, err := RunbookExecutionManager.createExecution(ctx, userCred, self, guest, input)
+	if err != nil {
+		return nil, err
+	}
+	if !self.RequireApproval.IsTrue() {
+		err = execution.StartExecuteTask(ctx, userCred)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	}
+	return jsonutils.Marshal(execution), nil
+}
+
+func (manager *SRunbookManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RunbookListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	return q, nil
+}
+
+func (manager *SRunbookManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.RunbookListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+}
+
+func (manager *SRunbookManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+type sRunbookExecutionCount struct {
+	RunbookId string
+	Count     int
+}
+
+func (manager *SRunbookManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.RunbookDetails {
+	rows := make([]api.RunbookDetails, len(objs))
+	stdRows := manager.SEnabledStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	runbookIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].EnabledStatusStandaloneResourceDetails = stdRows[i]
+		runbookIds[i] = objs[i].(*SRunbook).Id
+	}
+	sq := RunbookExecutionManager.Query().SubQuery()
+	q := sq.Query(
+		sq.Field("runbook_id"),
+		sqlchemy.COUNT("count"),
+	).Filter(sqlchemy.In(sq.Field("runbook_id"), runbookIds)).GroupBy(sq.Field("runbook_id"))
+	counts := []sRunbookExecutionCount{}
+	err := q.All(&counts)
+	if err != nil {
+		log.Errorf("count runbook executions fail %s", err)
+		return rows
+	}
+	cnt := map[string]int{}
+	for i := range counts {
+		cnt[counts[i].RunbookId] = counts[i].Count
+	}
+	for i := range rows {
+		rows[i].ExecutionCount = cnt[runbookIds[i]]
+	}
+	return rows
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestRunbookGrowDiskSize(t *testing.T) {
+	cases := []struct {
+		name    string
+		sizeMb  int
+		params  map[string]string
+		want    int
+		wantErr bool
+	}{
+		{"grow size", 10240, map[string]string{"grow_size_mb": "2048"}, 12288, false},
+		{"grow percent", 10240, map[string]string{"grow_percent": "50"}, 15360, false},
+		{"capped by max", 10240, map[string]string{"grow_percent": "50", "max_size_mb": "12000"}, 12000, false},
+		{"already at max", 12000, map[string]string{"grow_size_mb": "1024", "max_size_mb": "12000"}, 0, true},
+		{"invalid number", 10240, map[string]string{"grow_size_mb": "abc"}, 0, true},
+	}
+	for _, c := range cases {
+		got, err := runbookGrowDiskSize(c.sizeMb, c.params)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: err %v, wantErr %v", c.name, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %d want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestValidateRunbookSteps(t *testing.T) {
+	cases := []struct {
+		name    string
+		steps   api.RunbookSteps
+		wantErr bool
+	}{
+		{"empty", api.RunbookSteps{}, true},
+		{"invalid action", api.RunbookSteps{{Action: "reboot_host"}}, true},
+		{"restart", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_RESTART_GUEST}}, false},
+		{"resize missing grow", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_RESIZE_DISK}}, true},
+		{"resize placeholder", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_RESIZE_DISK, Params: map[string]string{"grow_size_mb": "${grow}"}}}, false},
+		{"resize invalid number", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_RESIZE_DISK, Params: map[string]string{"grow_percent": "-1"}}}, true},
+		{"notify missing receivers", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_NOTIFY}}, true},
+		{"notify", api.RunbookSteps{{Action: api.RUNBOOK_ACTION_NOTIFY, Params: map[string]string{"receiver_ids": "u1"}}}, false},
+	}
+	for _, c := range cases {
+		err := validateRunbookSteps(c.steps)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: err %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
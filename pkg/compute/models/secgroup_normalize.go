@@ -0,0 +1,199 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"sort"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/secrules"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+func newNormalizedRule(r cloudprovider.SecurityRule) api.SSecgroupNormalizedRule {
+	ret := api.SSecgroupNormalizedRule{
+		Rule:      r.String(),
+		Direction: string(r.Direction),
+		Action:    string(r.Action),
+		Protocol:  r.Protocol,
+		Ports:     r.GetPortsString(),
+		Priority:  r.Priority,
+	}
+	if r.IPNet != nil {
+		ret.CIDR = r.IPNet.String()
+	}
+	return ret
+}
+
+// 模拟本地规则同步到一个空的平台安全组后的结果
+func normalizeSecgroupRules(provider string, rules []SSecurityGroupRule, src, dest cloudprovider.SecRuleInfo) (*api.SecgroupProviderNormalizedRules, error) {
+	ret := &api.SecgroupProviderNormalizedRules{
+		Provider:                provider,
+		MinPriority:             dest.MinPriority,
+		MaxPriority:             dest.MaxPriority,
+		IsOnlySupportAllowRules: dest.IsOnlySupportAllowRules,
+		IsSupportPeerSecgroup:   dest.IsSupportPeerSecgroup,
+		Rules:                   []api.SSecgroupNormalizedRule{},
+		Dropped:                 []api.SSecgroupDroppedRule{},
+	}
+	local := map[string]*SSecurityGroupRule{}
+	src.Rules = cloudprovider.SecurityRuleSet{}
+	for i := range rules {
+		rule, err := rules[i].toRule()
+		if err != nil {
+			return nil, errors.Wrapf(err, "toRule %s", rules[i].Id)
+		}
+		if len(rules[i].PeerSecgroupId) > 0 && !dest.IsSupportPeerSecgroup {
+			ret.Dropped = append(ret.Dropped, api.SSecgroupDroppedRule{
+				RuleId:   rules[i].Id,
+				Rule:     rules[i].String(),
+				Priority: int(rules[i].Priority),
+				Reason:   api.SECGROUP_RULE_DROP_PEER_NOT_SUPPORTED,
+			})
+			continue
+		}
+		local[rules[i].Id] = &rules[i]
+		src.Rules = append(src.Rules, cloudprovider.SecurityRule{
+			SecurityRule:   *rule,
+			ExternalId:     rules[i].Id,
+			PeerSecgroupId: rules[i].PeerSecgroupId,
+		})
+	}
+	dest.Rules = cloudprovider.SecurityRuleSet{}
+
+	_, inAdds, outAdds, _, _ := cloudprovider.CompareRules(src, dest, false)
+
+	isDefault := func(r cloudprovider.SecurityRule) bool {
+		if r.Id == cloudprovider.DEFAULT_SRC_RULE_ID || r.ExternalId == cloudprovider.DEFAULT_DEST_RULE_ID {
+			return true
+		}
+		defaultRule := src.InDefaultRule
+		if r.Direction == secrules.DIR_OUT {
+			defaultRule = src.OutDefaultRule
+		}
+		return r.SecurityRule.String() == defaultRule.SecurityRule.String()
+	}
+
+	applied := map[string]bool{}
+	for _, adds := range []struct {
+		rules       cloudprovider.SecurityRuleSet
+		defaultRule cloudprovider.SecurityRule
+	}{
+		{inAdds, dest.InDefaultRule},
+		{outAdds, dest.OutDefaultRule},
+	} {
+		// 该方向与平台默认规则等价, 不需要添加任何规则
+		if len(adds.rules) == 0 {
+			rule := newNormalizedRule(adds.defaultRule)
+			rule.Priority = dest.MinPriority
+			rule.Default = true
+			ret.Rules = append(ret.Rules, rule)
+			continue
+		}
+		cloudprovider.SortSecurityRule(adds.rules, false, dest)
+		for _, r := range adds.rules {
+			rule := newNormalizedRule(r)
+			if localRule, ok := local[r.ExternalId]; ok {
+				rule.RuleId = localRule.Id
+				rule.OriginPriority = int(localRule.Priority)
+				applied[localRule.Id] = true
+			} else if isDefault(r) {
+				rule.Default = true
+			} else {
+				rule.Merged = true
+			}
+			ret.Rules = append(ret.Rules, rule)
+		}
+	}
+
+	for i := range rules {
+		if _, ok := local[rules[i].Id]; !ok || applied[rules[i].Id] {
+			continue
+		}
+		reason := api.SECGROUP_RULE_DROP_MERGED
+		if rules[i].Action == string(secrules.SecurityRuleDeny) && dest.IsOnlySupportAllowRules {
+			reason = api.SECGROUP_RULE_DROP_DENY_NOT_SUPPORTED
+		}
+		ret.Dropped = append(ret.Dropped, api.SSecgroupDroppedRule{
+			RuleId:   rules[i].Id,
+			Rule:     rules[i].String(),
+			Priority: int(rules[i].Priority),
+			Reason:   reason,
+		})
+	}
+	return ret, nil
+}
+
+// 获取安全组规则在各平台归一化后的规则列表(丢弃的规则, 优先级映射, 合并的CIDR)
+func (self *SSecurityGroup) GetDetailsNormalizedRules(ctx context.Context, userCred mcclient.TokenCredential, query api.SecgroupNormalizedRulesInput) (*api.SecgroupNormalizedRulesOutput, error) {
+	providers := []string{}
+	for _, provider := range query.Provider {
+		if _, ok := regionDrivers[provider]; !ok {
+			return nil, httperrors.NewNotSupportedError("unsupported provider %s", provider)
+		}
+		if !utils.IsInStringArray(provider, providers) {
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) == 0 {
+		caches, err := self.GetSecurityGroupCaches()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetSecurityGroupCaches")
+		}
+		for i := range caches {
+			provider := caches[i].GetProviderName()
+			if _, ok := regionDrivers[provider]; !ok || provider == api.CLOUD_PROVIDER_ONECLOUD {
+				continue
+			}
+			if !utils.IsInStringArray(provider, providers) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	if len(providers) == 0 {
+		for provider := range regionDrivers {
+			if provider != api.CLOUD_PROVIDER_ONECLOUD {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	sort.Strings(providers)
+
+	rules, err := self.getSecurityRules()
+	if err != nil {
+		return nil, errors.Wrapf(err, "getSecurityRules")
+	}
+	ret := &api.SecgroupNormalizedRulesOutput{
+		Id:        self.Id,
+		Name:      self.Name,
+		Providers: []api.SecgroupProviderNormalizedRules{},
+	}
+	src := cloudprovider.NewSecRuleInfo(GetRegionDriver(api.CLOUD_PROVIDER_ONECLOUD))
+	for _, provider := range providers {
+		dest := cloudprovider.NewSecRuleInfo(regionDrivers[provider])
+		result, err := normalizeSecgroupRules(provider, rules, src, dest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "normalizeSecgroupRules for %s", provider)
+		}
+		ret.Providers = append(ret.Providers, *result)
+	}
+	return ret, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/pkg/util/secrules"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestNormalizeSecgroupRules(t *testing.T) {
+	ruleInfo := func(in, out string, min, max int, onlyAllow, peer bool) cloudprovider.SecRuleInfo {
+		return cloudprovider.SecRuleInfo{
+			InDefaultRule:           cloudprovider.SecurityRule{SecurityRule: *secrules.MustParseSecurityRule(in)},
+			OutDefaultRule:          cloudprovider.SecurityRule{SecurityRule: *secrules.MustParseSecurityRule(out)},
+			MinPriority:             min,
+			MaxPriority:             max,
+			IsOnlySupportAllowRules: onlyAllow,
+			IsSupportPeerSecgroup:   peer,
+		}
+	}
+	src := ruleInfo("in:deny any", "out:allow any", 1, 100, false, true)
+	rule := func(id string, priority int64, direction, action, protocol, ports, cidr, peer string) SSecurityGroupRule {
+		r := SSecurityGroupRule{
+			Priority:       priority,
+			Direction:      direction,
+			Action:         action,
+			Protocol:       protocol,
+			Ports:          ports,
+			CIDR:           cidr,
+			PeerSecgroupId: peer,
+		}
+		r.Id = id
+		return r
+	}
+	rules := []SSecurityGroupRule{
+		rule("deny", 100, "in", "deny", "tcp", "22", "10.0.0.5", ""),
+		rule("ssh1", 90, "in", "allow", "tcp", "22", "10.0.0.0/25", ""),
+		rule("ssh2", 90, "in", "allow", "tcp", "22", "10.0.0.128/25", ""),
+		rule("peer", 80, "in", "allow", "any", "", "", "sg2"),
+	}
+
+	cases := []struct {
+		name    string
+		dest    cloudprovider.SecRuleInfo
+		dropped map[string]string
+		applied []string
+	}{
+		{
+			name: "allow-only",
+			dest: ruleInfo("in:deny any", "out:deny any", 0, 0, true, false),
+			dropped: map[string]string{
+				"peer": api.SECGROUP_RULE_DROP_PEER_NOT_SUPPORTED,
+				"deny": api.SECGROUP_RULE_DROP_DENY_NOT_SUPPORTED,
+				"ssh1": api.SECGROUP_RULE_DROP_MERGED,
+				"ssh2": api.SECGROUP_RULE_DROP_MERGED,
+			},
+			applied: []string{},
+		},
+		{
+			name:    "reversed-priority",
+			dest:    ruleInfo("in:deny any", "out:deny any", 4096, 100, false, true),
+			dropped: map[string]string{},
+			applied: []string{"deny", "ssh2", "ssh1", "peer"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ret, err := normalizeSecgroupRules(c.name, rules, src, c.dest)
+			if err != nil {
+				t.Fatalf("normalizeSecgroupRules: %v", err)
+			}
+			dropped := map[string]string{}
+			for _, r := range ret.Dropped {
+				dropped[r.RuleId] = r.Reason
+			}
+			if len(dropped) != len(c.dropped) {
+				t.Fatalf("dropped %v want %v", dropped, c.dropped)
+			}
+			for id, reason := range c.dropped {
+				if dropped[id] != reason {
+					t.Errorf("rule %s dropped reason %q want %q", id, dropped[id], reason)
+				}
+			}
+			applied := []string{}
+			for _, r := range ret.Rules {
+				if len(r.RuleId) > 0 {
+					applied = append(applied, r.RuleId)
+				}
+				if r.Direction == "in" && r.Action == "allow" && r.CIDR == "10.0.0.5/32" {
+					t.Errorf("denied address should not be allowed: %s", r.Rule)
+				}
+				if c.dest.MinPriority > c.dest.MaxPriority && (r.Priority > c.dest.MinPriority || r.Priority < c.dest.MaxPriority) {
+					t.Errorf("rule %s priority %d out of range", r.Rule, r.Priority)
+				}
+			}
+			if strings.Join(applied, ",") != strings.Join(c.applied, ",") {
+				t.Errorf("applied rules %v want %v", applied, c.applied)
+			}
+			last := ret.Rules[len(ret.Rules)-1]
+			if !last.Default || last.Rule != "out:allow any" {
+				t.Errorf("out direction should keep default allow rule, got %+v", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 根据上报的计数计算本次新增的命中数
+// 累计型计数(OVN 流表)变小说明流表被重建或虚机迁移, 此时按计数清零处理
+func hitCountDelta(raw, packets int64, cumulative bool) (delta int64, newRaw int64) {
+	if !cumulative {
+		return packets, raw
+	}
+	if packets >= raw {
+		return packets - raw, packets
+	}
+	return packets, packets
+}
+
+func (self *SSecurityGroupRule) updateHitCount(packets int64, cumulative bool, now time.Time) error {
+	_, err := db.Update(self, func() error {
+		delta, raw := hitCountDelta(self.HitCountRaw, packets, cumulative)
+		self.HitCountRaw = raw
+		self.HitCount += delta
+		if delta > 0 {
+			self.LastHitAt = now
+		}
+		self.HitCountUpdatedAt = now
+		return nil
+	})
+	return err
+}
+
+// 上报安全组规则命中统计, 由 vpcagent 或流日志采集程序调用
+func (manager *SSecurityGroupRuleManager) PerformReportHitCounts(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SecgroupRuleReportHitCountsInput) (jsonutils.JSONObject, error) {
+	if len(input.Rules) == 0 {
+		return nil, httperrors.NewMissingParameterError("rules")
+	}
+	ids := make([]string, 0, len(input.Rules))
+	packets := map[string]int64{}
+	for _, r := range input.Rules {
+		if _, ok := packets[r.Id]; !ok {
+			ids = append(ids, r.Id)
+		}
+		packets[r.Id] += r.Packets
+	}
+	rules := []SSecurityGroupRule{}
+	q := manager.Query().In("id", ids)
+	if err := db.FetchModelObjects(manager, q, &rules); err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	now := time.Now()
+	for i := range rules {
+		if err := rules[i].updateHitCount(packets[rules[i].Id], input.Cumulative, now); err != nil {
+			log.Errorf("update secgroup rule %s hit count from %s: %v", rules[i].Id, input.Source, err)
+		}
+	}
+	return nil, nil
+}
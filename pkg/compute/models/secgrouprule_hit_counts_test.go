@@ -0,0 +1,41 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestHitCountDelta(t *testing.T) {
+	cases := []struct {
+		name       string
+		raw        int64
+		packets    int64
+		cumulative bool
+		delta      int64
+		newRaw     int64
+	}{
+		{"cumulative increase", 100, 150, true, 50, 150},
+		{"cumulative unchanged", 100, 100, true, 0, 100},
+		{"cumulative reset", 100, 20, true, 20, 20},
+		{"incremental", 100, 30, false, 30, 100},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			delta, raw := hitCountDelta(c.raw, c.packets, c.cumulative)
+			if delta != c.delta || raw != c.newRaw {
+				t.Errorf("want (%d, %d), got (%d, %d)", c.delta, c.newRaw, delta, raw)
+			}
+		})
+	}
+}
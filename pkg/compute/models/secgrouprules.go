@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
@@ -73,6 +74,13 @@ type SSecurityGroupRule struct {
 	PeerSecgroupId string `width:"128" charset:"ascii" create:"optional" list:"user" update:"user"`
 
 	IsDirty bool `nullable:"false" default:"false"`
+
+	// 命中报文数, 由 vpcagent 汇总 OVN ACL 计数或公有云流日志上报
+	HitCount          int64     `nullable:"false" default:"0" list:"user"`
+	LastHitAt         time.Time `nullable:"true" list:"user"`
+	HitCountUpdatedAt time.Time `nullable:"true" list:"user"`
+	// 累计型计数上次上报的原始值, 用于计算增量
+	HitCountRaw int64 `nullable:"false" default:"0"`
 }
 
 func (self *SSecurityGroupRule) GetId() string {
@@ -158,6 +166,12 @@ func (manager *SSecurityGroupRuleManager) ListItemFilter(
 	if len(query.Ip) > 0 {
 		sql = sql.Like("cidr", "%"+query.Ip+"%")
 	}
+	if !query.NotHitSince.IsZero() {
+		sql = sql.IsNotNull("hit_count_updated_at").Filter(sqlchemy.OR(
+			sqlchemy.IsNull(sql.Field("last_hit_at")),
+			sqlchemy.LT(sql.Field("last_hit_at"), query.NotHitSince),
+		))
+	}
 
 	return sql, nil
 }
@@ -251,7 +265,14 @@ func (manager *SSecurityGroupRuleManager) QueryDistinctExtraField(q *sqlchemy.SQ
 }
 
 func (self *SSecurityGroupRule) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
-	return db.DeleteModel(ctx, userCred, self)
+	err := db.DeleteModel(ctx, userCred, self)
+	if err != nil {
+		return err
+	}
+	if secgroup := self.GetSecGroup(); secgroup != nil {
+		recordConfigHistory(ctx, secgroup)
+	}
+	return nil
 }
 
 func (self *SSecurityGroupRule) BeforeInsert() {
@@ -414,6 +435,7 @@ func (self *SSecurityGroupRule) PostCreate(ctx context.Context, userCred mcclien
 	if secgroup := self.GetSecGroup(); secgroup != nil {
 		logclient.AddSimpleActionLog(secgroup, logclient.ACT_ALLOCATE, data, userCred, true)
 		secgroup.DoSync(ctx, userCred)
+		recordConfigHistory(ctx, secgroup)
 	}
 }
 
@@ -460,6 +482,7 @@ func (self *SSecurityGroupRule) PostUpdate(ctx context.Context, userCred mcclien
 	if secgroup := self.GetSecGroup(); secgroup != nil {
 		logclient.AddSimpleActionLog(secgroup, logclient.ACT_UPDATE, data, userCred, true)
 		secgroup.DoSync(ctx, userCred)
+		recordConfigHistory(ctx, secgroup)
 	}
 }
 
@@ -1213,8 +1213,16 @@ func (manager *SSecurityGroupManager) DelaySync(ctx context.Context, userCred mc
 		if err != nil {
 			return errors.Wrapf(err, "GetKvmGuests")
 		}
-		for _, guest := range guests {
-			guest.StartSyncTask(ctx, userCred, true, "")
+		for i := range guests {
+			guests[i].StartSyncTask(ctx, userCred, true, "")
+			guest := &guests[i]
+			if guest.Status == api.VM_RUNNING && len(guest.GetMetadata(ctx, api.VM_METADATA_QGA_FIREWALL_BACKEND, userCred)) > 0 {
+				go func() {
+					if err := guest.SyncQgaFirewall(ctx, userCred); err != nil {
+						log.Errorf("sync guest %s in-guest firewall: %v", guest.Name, err)
+					}
+				}()
+			}
 		}
 	}
 	return secgrp.StartSecurityGroupSyncRulesTask(ctx, userCred, "")
@@ -0,0 +1,362 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 云平台返回的抢占式实例历史价格, ZoneId 为可用区的外部ID
+type SCloudSpotPrice struct {
+	InstanceType string
+	ZoneId       string
+	Price        float64
+	OriginPrice  float64
+	Currency     string
+	Timestamp    time.Time
+}
+
+// 云平台返回的抢占式实例中断率, 0-1
+type SCloudSpotInterruption struct {
+	InstanceType     string
+	ZoneId           string
+	InterruptionRate float64
+}
+
+type ICloudSpotPriceRegion interface {
+	GetSpotPriceHistory(instanceTypes []string, start, end time.Time) ([]SCloudSpotPrice, error)
+}
+
+type ICloudSpotInterruptionRegion interface {
+	GetSpotInterruptionRates(instanceTypes []string) ([]SCloudSpotInterruption, error)
+}
+
+// +onecloud:swagger-gen-ignore
+type SServerSkuSpotPriceManager struct {
+	db.SResourceBaseManager
+}
+
+var ServerSkuSpotPriceManager *SServerSkuSpotPriceManager
+
+func init() {
+	ServerSkuSpotPriceManager = &SServerSkuSpotPriceManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SServerSkuSpotPrice{},
+			"serversku_spot_prices_tbl",
+			"serversku_spot_price",
+			"serversku_spot_prices",
+		),
+	}
+	ServerSkuSpotPriceManager.SetVirtualObject(ServerSkuSpotPriceManager)
+}
+
+// SServerSkuSpotPrice 套餐在所属可用区的抢占式实例历史价格
+type SServerSkuSpotPrice struct {
+	db.SResourceBase
+
+	SkuId     string    `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	Timestamp time.Time `nullable:"false" primary:"true"`
+
+	CloudregionId string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+
+	Price       float64 `nullable:"false" default:"0"`
+	OriginPrice float64 `nullable:"false" default:"0"`
+	Currency    string  `width:"8" charset:"ascii" nullable:"true"`
+}
+
+func (self *SServerSkuSpotPrice) GetId() string {
+	return fmt.Sprintf("%s/%d", self.SkuId, self.Timestamp.Unix())
+}
+
+func (self *SServerSkuSpotPrice) GetName() string {
+	return self.GetId()
+}
+
+func spotSkuKey(instanceType, zoneExtId string) string {
+	return instanceType + "/" + zoneExtId
+}
+
+// 获取区域内可用于同步价格的云订阅
+func (manager *SServerSkuSpotPriceManager) getRegionProvider(region *SCloudregion) (*SCloudprovider, error) {
+	sq := CloudproviderRegionManager.Query("cloudprovider_id").Equals("cloudregion_id", region.Id).SubQuery()
+	providers := []SCloudprovider{}
+	q := CloudproviderManager.Query().In("id", sq)
+	err := db.FetchModelObjects(CloudproviderManager, q, &providers)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range providers {
+		if providers[i].IsAvailable() {
+			return &providers[i], nil
+		}
+	}
+	return nil, errors.Wrapf(errors.ErrNotFound, "no available cloudprovider for region %s", region.Name)
+}
+
+func (manager *SServerSkuSpotPriceManager) getLatestTimestamp(regionId string) (time.Time, error) {
+	price := SServerSkuSpotPrice{}
+	err := manager.Query().Equals("cloudregion_id", regionId).Desc("timestamp").First(&price)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, errors.Wrap(err, "First")
+	}
+	return price.Timestamp, nil
+}
+
+func (manager *SServerSkuSpotPriceManager) syncRegionSpotPrices(ctx context.Context, region *SCloudregion) error {
+	provider, err := manager.getRegionProvider(region)
+	if err != nil {
+		return err
+	}
+	driver, err := provider.GetProvider(ctx)
+	if err != nil {
+		return errors.Wrap(err, "GetProvider")
+	}
+	iRegion, err := driver.GetIRegionById(region.ExternalId)
+	if err != nil {
+		return errors.Wrapf(err, "GetIRegionById(%s)", region.ExternalId)
+	}
+	priceRegion, ok := iRegion.(ICloudSpotPriceRegion)
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "spot price history of %s", region.Provider)
+	}
+
+	skus := []SServerSku{}
+	q := ServerSkuManager.Query().Equals("cloudregion_id", region.Id).IsTrue("enabled").Equals("postpaid_status", api.SkuStatusAvailable)
+	err = db.FetchModelObjects(ServerSkuManager, q, &skus)
+	if err != nil {
+		return errors.Wrap(err, "fetch skus")
+	}
+	if len(skus) == 0 {
+		return nil
+	}
+	zones, err := region.GetZones()
+	if err != nil {
+		return errors.Wrap(err, "GetZones")
+	}
+	zoneExtIds := map[string]string{}
+	for i := range zones {
+		zoneExtIds[zones[i].Id] = zones[i].ExternalId
+	}
+	skuMap := map[string]*SServerSku{}
+	instanceTypes := []string{}
+	for i := range skus {
+		if !utils.IsInStringArray(skus[i].Name, instanceTypes) {
+			instanceTypes = append(instanceTypes, skus[i].Name)
+		}
+		skuMap[spotSkuKey(skus[i].Name, zoneExtIds[skus[i].ZoneId])] = &skus[i]
+	}
+
+	end := time.Now().UTC()
+	start, err := manager.getLatestTimestamp(region.Id)
+	if err != nil {
+		return err
+	}
+	if start.IsZero() || end.Sub(start) > time.Duration(options.Options.SpotPriceHistoryDays)*24*time.Hour {
+		start = end.Add(-24 * time.Hour)
+	}
+	prices, err := priceRegion.GetSpotPriceHistory(instanceTypes, start, end)
+	if err != nil {
+		return errors.Wrap(err, "GetSpotPriceHistory")
+	}
+	latest := map[string]SCloudSpotPrice{}
+	for _, price := range prices {
+		sku, ok := skuMap[spotSkuKey(price.InstanceType, price.ZoneId)]
+		if !ok {
+			continue
+		}
+		point := SServerSkuSpotPrice{
+			SkuId:         sku.Id,
+			Timestamp:     price.Timestamp.UTC(),
+			CloudregionId: region.Id,
+			Price:         price.Price,
+			OriginPrice:   price.OriginPrice,
+			Currency:      price.Currency,
+		}
+		point.SetModelManager(manager, &point)
+		err = manager.TableSpec().InsertOrUpdate(ctx, &point)
+		if err != nil {
+			return errors.Wrapf(err, "save spot price %s", point.GetId())
+		}
+		if old, ok := latest[sku.Id]; !ok || old.Timestamp.Before(price.Timestamp) {
+			latest[sku.Id] = price
+		}
+	}
+
+	rates := map[string]float64{}
+	if interruptRegion, ok := iRegion.(ICloudSpotInterruptionRegion); ok {
+		interruptions, err := interruptRegion.GetSpotInterruptionRates(instanceTypes)
+		if err != nil {
+			log.Warningf("GetSpotInterruptionRates for region %s: %v", region.Name, err)
+		}
+		for _, interruption := range interruptions {
+			if sku, ok := skuMap[spotSkuKey(interruption.InstanceType, interruption.ZoneId)]; ok {
+				rates[sku.Id] = interruption.InterruptionRate
+			}
+		}
+	}
+
+	for i := range skus {
+		sku := &skus[i]
+		price, hasPrice := latest[sku.Id]
+		rate, hasRate := rates[sku.Id]
+		if !hasPrice && !hasRate {
+			continue
+		}
+		_, err = db.Update(sku, func() error {
+			if hasPrice {
+				sku.SpotPrice = price.Price
+			}
+			if hasRate {
+				sku.SpotInterruptionRate = rate
+			}
+			sku.SpotPriceUpdatedAt = end
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "update sku %s spot price", sku.Name)
+		}
+	}
+	return nil
+}
+
+// 删除超过保留天数的历史价格
+func (manager *SServerSkuSpotPriceManager) purgeExpired() error {
+	expire := time.Now().UTC().AddDate(0, 0, -options.Options.SpotPriceHistoryDays)
+	_, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"delete from %s where timestamp < ?",
+			manager.TableSpec().Name(),
+		), expire,
+	)
+	return err
+}
+
+// 同步阿里云、华为云抢占式实例的历史价格及中断率
+func (manager *SServerSkuSpotPriceManager) SyncSpotPrices(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	regions := []SCloudregion{}
+	q := CloudregionManager.Query().In("provider", api.SPOT_PRICE_PROVIDERS).IsTrue("enabled")
+	err := db.FetchModelObjects(CloudregionManager, q, &regions)
+	if err != nil {
+		log.Errorf("SyncSpotPrices fetch regions: %v", err)
+		return
+	}
+	for i := range regions {
+		err = manager.syncRegionSpotPrices(ctx, &regions[i])
+		if err != nil && errors.Cause(err) != errors.ErrNotFound {
+			log.Errorf("SyncSpotPrices region %s: %v", regions[i].Name, err)
+		}
+	}
+	err = manager.purgeExpired()
+	if err != nil {
+		log.Errorf("SyncSpotPrices purge expired: %v", err)
+	}
+}
+
+func (manager *SServerSkuSpotPriceManager) fetchPrices(skuId string, start, end time.Time) ([]SServerSkuSpotPrice, error) {
+	q := manager.Query().Equals("sku_id", skuId).GE("timestamp", start).LE("timestamp", end).Asc("timestamp")
+	prices := []SServerSkuSpotPrice{}
+	err := db.FetchModelObjects(manager, q, &prices)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return prices, nil
+}
+
+// 统计价格区间并计算缺省出价: 最高价上浮 markup, 且不超过按量付费价格
+func summarizeSpotPrices(prices []SServerSkuSpotPrice, markup float64) api.ServerSkuSpotPriceOutput {
+	ret := api.ServerSkuSpotPriceOutput{
+		History: []api.ServerSkuSpotPricePoint{},
+	}
+	if len(prices) == 0 {
+		return ret
+	}
+	ret.MinPrice = prices[0].Price
+	sum := 0.0
+	for _, price := range prices {
+		ret.History = append(ret.History, api.ServerSkuSpotPricePoint{
+			Price:       price.Price,
+			OriginPrice: price.OriginPrice,
+			Timestamp:   price.Timestamp,
+		})
+		if price.Price < ret.MinPrice {
+			ret.MinPrice = price.Price
+		}
+		if price.Price > ret.MaxPrice {
+			ret.MaxPrice = price.Price
+		}
+		sum += price.Price
+	}
+	last := prices[len(prices)-1]
+	ret.LatestPrice = last.Price
+	ret.OriginPrice = last.OriginPrice
+	ret.Currency = last.Currency
+	ret.AvgPrice = sum / float64(len(prices))
+	ret.SuggestedBidPrice = ret.MaxPrice * (1 + markup)
+	if ret.OriginPrice > 0 && ret.SuggestedBidPrice > ret.OriginPrice {
+		ret.SuggestedBidPrice = ret.OriginPrice
+	}
+	return ret
+}
+
+// 获取套餐的抢占式实例缺省出价, 基于最近7天的价格
+func (self *SServerSku) GetSpotBidPrice() (float64, error) {
+	end := time.Now().UTC()
+	prices, err := ServerSkuSpotPriceManager.fetchPrices(self.Id, end.AddDate(0, 0, -api.SPOT_BID_LOOKBACK_DAYS), end)
+	if err != nil {
+		return 0, err
+	}
+	return summarizeSpotPrices(prices, options.Options.SpotBidPriceMarkup).SuggestedBidPrice, nil
+}
+
+// 获取套餐的抢占式实例历史价格及中断率
+func (self *SServerSku) GetDetailsSpotPrices(ctx context.Context, userCred mcclient.TokenCredential, query api.ServerSkuSpotPriceInput) (*api.ServerSkuSpotPriceOutput, error) {
+	if query.EndTime.IsZero() {
+		query.EndTime = time.Now().UTC()
+	}
+	if query.StartTime.IsZero() {
+		query.StartTime = query.EndTime.AddDate(0, 0, -api.SPOT_BID_LOOKBACK_DAYS)
+	}
+	prices, err := ServerSkuSpotPriceManager.fetchPrices(self.Id, query.StartTime, query.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	ret := summarizeSpotPrices(prices, options.Options.SpotBidPriceMarkup)
+	ret.InstanceType = self.Name
+	ret.ZoneId = self.ZoneId
+	ret.InterruptionRate = self.SpotInterruptionRate
+	bid, err := self.GetSpotBidPrice()
+	if err != nil {
+		return nil, err
+	}
+	ret.SuggestedBidPrice = bid
+	return &ret, nil
+}
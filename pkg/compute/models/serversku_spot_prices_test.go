@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSummarizeSpotPrices(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	newPrice := func(hour int, price, origin float64) SServerSkuSpotPrice {
+		return SServerSkuSpotPrice{
+			Timestamp:   base.Add(time.Duration(hour) * time.Hour),
+			Price:       price,
+			OriginPrice: origin,
+			Currency:    "CNY",
+		}
+	}
+	cases := []struct {
+		name   string
+		prices []SServerSkuSpotPrice
+		latest float64
+		bid    float64
+	}{
+		{"empty", nil, 0, 0},
+		{"markup", []SServerSkuSpotPrice{newPrice(0, 0.2, 1), newPrice(1, 0.4, 1), newPrice(2, 0.3, 1)}, 0.3, 0.44},
+		{"capped by on-demand", []SServerSkuSpotPrice{newPrice(0, 0.95, 1), newPrice(1, 0.9, 1)}, 0.9, 1},
+	}
+	for _, c := range cases {
+		ret := summarizeSpotPrices(c.prices, 0.1)
+		if len(ret.History) != len(c.prices) {
+			t.Errorf("%s: history len %d, want %d", c.name, len(ret.History), len(c.prices))
+		}
+		if ret.LatestPrice != c.latest {
+			t.Errorf("%s: latest %v, want %v", c.name, ret.LatestPrice, c.latest)
+		}
+		if math.Abs(ret.SuggestedBidPrice-c.bid) > 1e-9 {
+			t.Errorf("%s: bid %v, want %v", c.name, ret.SuggestedBidPrice, c.bid)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
@@ -109,6 +110,18 @@ type SServerSku struct {
 	GpuMaxCount   int               `nullable:"true" list:"user" create:"admin_optional" update:"admin"`
 
 	Provider string `width:"64" charset:"ascii" nullable:"true" list:"user" default:"OneCloud" create:"admin_optional"`
+
+	// 本地套餐的QoS等级 guaranteed|burstable|best-effort, 决定cgroup权重和调度时的超售计算
+	QosClass string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"admin_optional" update:"admin"`
+
+	// 抢占式实例最新价格
+	SpotPrice float64 `nullable:"true" list:"user"`
+	// 抢占式实例中断率, 0-1
+	SpotInterruptionRate float64   `nullable:"true" list:"user"`
+	SpotPriceUpdatedAt   time.Time `nullable:"true" list:"user"`
+
+	// 私有云自定义套餐, 不在云平台创建, 也不会被同步删除
+	IsCustom tristate.TriState `default:"false" list:"user" create:"admin_optional"`
 }
 
 func (manager *SServerSkuManager) FetchUniqValues(ctx context.Context, data jsonutils.JSONObject) jsonutils.JSONObject {
@@ -339,7 +352,11 @@ func (self *SServerSkuManager) ValidateCreateData(ctx context.Context, userCred
 	if region != nil {
 		input.Provider = region.Provider
 	}
-	if input.Provider == api.CLOUD_PROVIDER_ONECLOUD {
+	if input.IsCustom != nil && *input.IsCustom {
+		if !utils.IsInStringArray(input.Provider, api.PRIVATE_CLOUD_PROVIDERS) {
+			return input, httperrors.NewUnsupportOperationError("is_custom only supported by private cloud sku")
+		}
+	} else if input.Provider == api.CLOUD_PROVIDER_ONECLOUD {
 	} else if utils.IsInStringArray(input.Provider, api.PRIVATE_CLOUD_PROVIDERS) {
 		input.Status = api.SkuStatusCreating
 	} else {
@@ -349,6 +366,15 @@ func (self *SServerSkuManager) ValidateCreateData(ctx context.Context, userCred
 	input.LocalCategory = input.InstanceTypeCategory
 	input.InstanceTypeFamily = api.InstanceFamilies[input.InstanceTypeCategory]
 
+	if len(input.QosClass) > 0 {
+		if input.Provider != api.CLOUD_PROVIDER_ONECLOUD {
+			return input, httperrors.NewUnsupportOperationError("qos_class only supported by %s sku", api.CLOUD_PROVIDER_ONECLOUD)
+		}
+		if !utils.IsInStringArray(input.QosClass, api.GUEST_QOS_CLASSES) {
+			return input, httperrors.NewInputParameterError("qos_class shoud be one of %s", api.GUEST_QOS_CLASSES)
+		}
+	}
+
 	var err error
 	if len(input.Name) == 0 {
 		// 格式 ecs.g1.c1m1
@@ -378,7 +404,7 @@ func (self *SServerSkuManager) ValidateCreateData(ctx context.Context, userCred
 
 func (self *SServerSku) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SEnabledStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
-	if self.Provider != api.CLOUD_PROVIDER_ONECLOUD {
+	if self.Provider != api.CLOUD_PROVIDER_ONECLOUD && !self.IsCustom.Bool() {
 		self.StartSkuCreateTask(ctx, userCred)
 	}
 }
@@ -598,6 +624,15 @@ func (self *SServerSku) ValidateUpdateData(ctx context.Context, userCred mcclien
 		return input, httperrors.NewUnsupportOperationError("Cannot change server sku name")
 	}
 
+	if input.QosClass != nil && len(*input.QosClass) > 0 {
+		if self.Provider != api.CLOUD_PROVIDER_ONECLOUD {
+			return input, httperrors.NewUnsupportOperationError("qos_class only supported by %s sku", api.CLOUD_PROVIDER_ONECLOUD)
+		}
+		if !utils.IsInStringArray(*input.QosClass, api.GUEST_QOS_CLASSES) {
+			return input, httperrors.NewInputParameterError("qos_class shoud be one of %s", api.GUEST_QOS_CLASSES)
+		}
+	}
+
 	var err error
 	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = self.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
 	if err != nil {
@@ -813,6 +848,9 @@ func (manager *SServerSkuManager) ListItemFilter(
 	if len(query.CpuCoreCount) > 0 {
 		q = q.In("cpu_core_count", query.CpuCoreCount)
 	}
+	if query.MaxSpotInterruptionRate != nil {
+		q = q.GT("spot_price", 0).LE("spot_interruption_rate", *query.MaxSpotInterruptionRate)
+	}
 
 	return q, err
 }
@@ -859,6 +897,16 @@ func (manager *SServerSkuManager) GetMatchedSku(regionId string, cpu int64, memM
 	}
 	region := _region.(*SCloudregion)
 	if utils.IsInStringArray(region.Provider, api.PRIVATE_CLOUD_PROVIDERS) {
+		// 优先使用区域内的自定义套餐
+		q := manager.Query().Equals("cloudregion_id", regionId).IsTrue("is_custom").IsTrue("enabled")
+		q = q.Equals("cpu_core_count", cpu).Equals("memory_size_mb", memMB).Equals("postpaid_status", api.SkuStatusAvailable)
+		err = q.First(ret)
+		if err == nil {
+			return ret, nil
+		}
+		if errors.Cause(err) != sql.ErrNoRows {
+			return nil, errors.Wrap(err, "ServerSkuManager.GetMatchedSku.custom")
+		}
 		regionId = api.DEFAULT_REGION_ID
 	}
 
@@ -1003,11 +1051,18 @@ func (manager *SServerSkuManager) SyncPrivateCloudSkus(ctx context.Context, user
 
 	result := compare.SyncResult{}
 
-	dbSkus, err := region.GetServerSkus()
+	regionSkus, err := region.GetServerSkus()
 	if err != nil {
 		result.Error(errors.Wrapf(err, "GetServerSkus"))
 		return result
 	}
+	dbSkus := make([]SServerSku, 0, len(regionSkus))
+	for i := range regionSkus {
+		// 自定义套餐不在云平台上
+		if !regionSkus[i].IsCustom.Bool() {
+			dbSkus = append(dbSkus, regionSkus[i])
+		}
+	}
 
 	removed := make([]SServerSku, 0)
 	commondb := make([]SServerSku, 0)
@@ -1194,6 +1249,21 @@ func (manager *SServerSkuManager) SyncServerSkus(ctx context.Context, userCred m
 		return syncResult
 	}
 
+	// 私有云区域只能离线导入自定义套餐, 不影响从云平台同步的规格
+	if utils.IsInStringArray(region.Provider, api.PRIVATE_CLOUD_PROVIDERS) {
+		customSkus := make([]SServerSku, 0, len(dbSkus))
+		for i := range dbSkus {
+			if dbSkus[i].IsCustom.Bool() {
+				customSkus = append(customSkus, dbSkus[i])
+			}
+		}
+		dbSkus = customSkus
+		for i := range extSkus {
+			extSkus[i].IsCustom = tristate.True
+			extSkus[i].Provider = region.Provider
+		}
+	}
+
 	removed := make([]SServerSku, 0)
 	commondb := make([]SServerSku, 0)
 	commonext := make([]SServerSku, 0)
@@ -0,0 +1,124 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 导入离线套餐目录, 用于无法访问外网的环境
+func (manager *SServerSkuManager) PerformImportCatalog(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSkuImportCatalogInput) (jsonutils.JSONObject, error) {
+	if len(input.Catalog) == 0 {
+		return nil, httperrors.NewMissingParameterError("catalog")
+	}
+	regionExtIds := make([]string, 0, len(input.Catalog))
+	for extId := range input.Catalog {
+		regionExtIds = append(regionExtIds, extId)
+	}
+	sort.Strings(regionExtIds)
+
+	regions := make([]*SCloudregion, 0, len(regionExtIds))
+	for _, extId := range regionExtIds {
+		region, err := db.FetchByExternalId(CloudregionManager, extId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2(CloudregionManager.Keyword(), extId)
+			}
+			return nil, httperrors.NewGeneralError(err)
+		}
+		regions = append(regions, region.(*SCloudregion))
+	}
+
+	meta := NewOfflineSkuResourcesMeta(input.Catalog)
+	ret := jsonutils.NewDict()
+	for _, region := range regions {
+		result := manager.SyncServerSkus(ctx, userCred, region, meta)
+		ret.Set(region.ExternalId, jsonutils.NewString(result.Result()))
+		logclient.AddSimpleActionLog(region, logclient.ACT_CLOUD_SYNC, result.Result(), userCred, !result.IsError())
+	}
+	return ret, nil
+}
+
+// 导出套餐目录, 格式与导入一致
+func (manager *SServerSkuManager) GetPropertyExportCatalog(ctx context.Context, userCred mcclient.TokenCredential, query api.ServerSkuExportCatalogInput) (jsonutils.JSONObject, error) {
+	if len(query.Provider) == 0 && len(query.CloudregionIds) == 0 {
+		return nil, httperrors.NewMissingParameterError("must specific one of `provider` or `cloudregion_ids`")
+	}
+
+	q := CloudregionManager.Query().IsNotEmpty("external_id")
+	if len(query.Provider) > 0 {
+		q = q.Equals("provider", query.Provider)
+	}
+	if len(query.CloudregionIds) > 0 {
+		q = q.Filter(sqlchemy.OR(sqlchemy.In(q.Field("id"), query.CloudregionIds), sqlchemy.In(q.Field("name"), query.CloudregionIds)))
+	}
+	regions := []SCloudregion{}
+	err := db.FetchModelObjects(CloudregionManager, q, &regions)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	ret := jsonutils.NewDict()
+	for i := range regions {
+		skus, err := regions[i].exportServerSkuCatalog()
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		ret.Set(regions[i].ExternalId, jsonutils.Marshal(skus))
+	}
+	return ret, nil
+}
+
+func (self *SCloudregion) exportServerSkuCatalog() ([]SServerSku, error) {
+	zones, err := self.GetZones()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetZones")
+	}
+	zoneExtIds := map[string]string{}
+	for _, zone := range zones {
+		zoneExtIds[zone.Id] = zone.ExternalId
+	}
+	skus, err := ServerSkuManager.FetchSkusByRegion(self.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchSkusByRegion")
+	}
+	ret := make([]SServerSku, 0, len(skus))
+	for i := range skus {
+		sku := skus[i]
+		if len(sku.ZoneId) > 0 {
+			extId, ok := zoneExtIds[sku.ZoneId]
+			if !ok {
+				continue
+			}
+			sku.ZoneId = extId
+		}
+		sku.Id = ""
+		sku.CloudregionId = ""
+		ret = append(ret, sku)
+	}
+	return ret, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestOfflineSkuResourcesMeta(t *testing.T) {
+	sku := jsonutils.Marshal(map[string]interface{}{"name": "ecs.g1.c1m1", "cpu_core_count": 1, "memory_size_mb": 1024})
+	meta := NewOfflineSkuResourcesMeta(map[string][]jsonutils.JSONObject{
+		"Aliyun/cn-beijing": {sku},
+	})
+
+	objs, err := meta.getObjsByRegion(meta.ServerBase, "Aliyun/cn-beijing")
+	if err != nil {
+		t.Fatalf("getObjsByRegion: %v", err)
+	}
+	if len(objs) != 1 {
+		t.Fatalf("want 1 sku, got %d", len(objs))
+	}
+	name, _ := objs[0].GetString("name")
+	if name != "ecs.g1.c1m1" {
+		t.Errorf("want ecs.g1.c1m1, got %s", name)
+	}
+
+	if _, err := meta.getObjsByRegion(meta.ServerBase, "Aliyun/cn-shanghai"); err == nil {
+		t.Errorf("region not in catalog should fail")
+	}
+	if _, err := meta.getObjsByRegion(meta.ImageBase, "Aliyun/cn-beijing"); err == nil {
+		t.Errorf("other resources should not be served from server catalog")
+	}
+}
@@ -54,6 +54,23 @@ type SSkuResourcesMeta struct {
 	NatBase          string `json:"nat_base"`
 	NasBase          string `json:"nas_base"`
 	WafBase          string `json:"waf_base"`
+
+	// 离线套餐目录, key为套餐文件路径
+	offline map[string][]jsonutils.JSONObject
+}
+
+const OFFLINE_SKU_SERVER_BASE = "offline://server"
+
+// 使用离线导入的套餐目录代替在线下载, 用于无法访问外网的环境
+func NewOfflineSkuResourcesMeta(servers map[string][]jsonutils.JSONObject) *SSkuResourcesMeta {
+	meta := &SSkuResourcesMeta{
+		ServerBase: OFFLINE_SKU_SERVER_BASE,
+		offline:    map[string][]jsonutils.JSONObject{},
+	}
+	for region, objs := range servers {
+		meta.offline[fmt.Sprintf("%s/%s.json", meta.ServerBase, region)] = objs
+	}
+	return meta
 }
 
 var skuIndex = map[string]string{}
@@ -372,6 +389,13 @@ func (self *SSkuResourcesMeta) GetElasticCacheSkusByRegionExternalId(regionExter
 
 func (self *SSkuResourcesMeta) getObjsByRegion(base string, region string) ([]jsonutils.JSONObject, error) {
 	url := fmt.Sprintf("%s/%s.json", base, region)
+	if self.offline != nil {
+		items, ok := self.offline[url]
+		if !ok {
+			return nil, errors.Wrapf(errors.ErrNotFound, "offline %s", url)
+		}
+		return items, nil
+	}
 	items, err := self._get(url)
 	if err != nil {
 		return nil, errors.Wrap(err, "getSkusByRegion.get")
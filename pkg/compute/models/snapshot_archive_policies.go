@@ -0,0 +1,235 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 快照合规归档策略, 将绑定磁盘的快照复制到独立的金库云账号并按保留期锁定
+type SSnapshotArchivePolicyManager struct {
+	db.SEnabledStatusStandaloneResourceBaseManager
+}
+
+var SnapshotArchivePolicyManager *SSnapshotArchivePolicyManager
+
+func init() {
+	SnapshotArchivePolicyManager = &SSnapshotArchivePolicyManager{
+		SEnabledStatusStandaloneResourceBaseManager: db.NewEnabledStatusStandaloneResourceBaseManager(
+			SSnapshotArchivePolicy{},
+			"snapshot_archive_policies_tbl",
+			"snapshot_archive_policy",
+			"snapshot_archive_policies",
+		),
+	}
+	SnapshotArchivePolicyManager.SetVirtualObject(SnapshotArchivePolicyManager)
+}
+
+type SSnapshotArchivePolicy struct {
+	db.SEnabledStatusStandaloneResourceBase
+
+	// 金库云账号Id
+	VaultCloudaccountId string `width:"36" charset:"ascii" nullable:"false" list:"admin" create:"admin_required" index:"true"`
+	// 合规保留天数
+	RetentionDays int `nullable:"false" default:"30" list:"admin" create:"admin_optional" update:"admin"`
+}
+
+func (manager *SSnapshotArchivePolicyManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.SnapshotArchivePolicyCreateInput) (api.SnapshotArchivePolicyCreateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceCreateInput, err = manager.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	if len(input.VaultCloudaccountId) == 0 {
+		return input, httperrors.NewMissingParameterError("vault_cloudaccount_id")
+	}
+	accountObj, err := CloudaccountManager.FetchByIdOrName(userCred, input.VaultCloudaccountId)
+	if err != nil {
+		return input, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), input.VaultCloudaccountId)
+	}
+	account := accountObj.(*SCloudaccount)
+	if !account.GetEnabled() {
+		return input, httperrors.NewInvalidStatusError("vault cloudaccount %s is disabled", account.Name)
+	}
+	if !account.IsPublicCloud.IsTrue() {
+		return input, httperrors.NewUnsupportOperationError("vault cloudaccount %s is not a public cloud account", account.Name)
+	}
+	input.VaultCloudaccountId = account.Id
+	if input.RetentionDays == 0 {
+		input.RetentionDays = 30
+	}
+	if input.RetentionDays < api.SNAPSHOT_ARCHIVE_MIN_RETENTION_DAYS {
+		return input, httperrors.NewInputParameterError("retention_days must be at least %d", api.SNAPSHOT_ARCHIVE_MIN_RETENTION_DAYS)
+	}
+	input.Status = api.SNAPSHOT_POLICY_READY
+	return input, nil
+}
+
+func (self *SSnapshotArchivePolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SnapshotArchivePolicyUpdateInput) (api.SnapshotArchivePolicyUpdateInput, error) {
+	var err error
+	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = self.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	// 合规要求保留期只能延长, 避免通过修改策略提前释放副本
+	if input.RetentionDays != nil && *input.RetentionDays < self.RetentionDays {
+		return input, httperrors.NewForbiddenError("retention_days can only be extended, current is %d", self.RetentionDays)
+	}
+	return input, nil
+}
+
+func (self *SSnapshotArchivePolicy) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	cnt, err := SnapshotArchiveManager.Query().Equals("policy_id", self.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("policy %s still has %d archives", self.Name, cnt)
+	}
+	return self.SEnabledStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *SSnapshotArchivePolicy) PostDelete(ctx context.Context, userCred mcclient.TokenCredential) {
+	self.SEnabledStatusStandaloneResourceBase.PostDelete(ctx, userCred)
+	err := SnapshotArchivePolicyDiskManager.removeByPolicy(ctx, userCred, self.Id)
+	if err != nil {
+		log.Errorf("remove disks of snapshot archive policy %s: %v", self.Name, err)
+	}
+}
+
+func (self *SSnapshotArchivePolicy) GetVaultCloudaccount() (*SCloudaccount, error) {
+	accountObj, err := CloudaccountManager.FetchById(self.VaultCloudaccountId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.VaultCloudaccountId)
+	}
+	return accountObj.(*SCloudaccount), nil
+}
+
+func (manager *SSnapshotArchivePolicyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SnapshotArchivePolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.VaultCloudaccountId) > 0 {
+		account, err := CloudaccountManager.FetchByIdOrName(userCred, query.VaultCloudaccountId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), query.VaultCloudaccountId)
+		}
+		q = q.Equals("vault_cloudaccount_id", account.GetId())
+	}
+	if len(query.DiskId) > 0 {
+		disk, err := DiskManager.FetchByIdOrName(userCred, query.DiskId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), query.DiskId)
+		}
+		sq := SnapshotArchivePolicyDiskManager.Query("policy_id").Equals("disk_id", disk.GetId())
+		q = q.In("id", sq.SubQuery())
+	}
+	return q, nil
+}
+
+func (manager *SSnapshotArchivePolicyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SnapshotArchivePolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+}
+
+func (manager *SSnapshotArchivePolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SEnabledStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SSnapshotArchivePolicyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.SnapshotArchivePolicyDetails {
+	rows := make([]api.SnapshotArchivePolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	policyIds := make([]string, len(objs))
+	accountIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].EnabledStatusStandaloneResourceDetails = stdRows[i]
+		policy := objs[i].(*SSnapshotArchivePolicy)
+		policyIds[i] = policy.Id
+		accountIds[i] = policy.VaultCloudaccountId
+	}
+	accounts := make(map[string]SCloudaccount)
+	if err := db.FetchStandaloneObjectsByIds(CloudaccountManager, accountIds, accounts); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds cloudaccounts fail %s", err)
+		return rows
+	}
+	diskCnt, err := fetchSnapshotArchivePolicyCounts(SnapshotArchivePolicyDiskManager, policyIds)
+	if err != nil {
+		log.Errorf("count snapshot archive policy disks fail %s", err)
+	}
+	archiveCnt, err := fetchSnapshotArchivePolicyCounts(SnapshotArchiveManager, policyIds)
+	if err != nil {
+		log.Errorf("count snapshot archives fail %s", err)
+	}
+	for i := range rows {
+		if account, ok := accounts[accountIds[i]]; ok {
+			rows[i].VaultCloudaccount = account.Name
+		}
+		rows[i].DiskCount = diskCnt[policyIds[i]]
+		rows[i].ArchiveCount = archiveCnt[policyIds[i]]
+	}
+	return rows
+}
+
+type sSnapshotArchivePolicyCount struct {
+	PolicyId string
+	Count    int
+}
+
+// 按策略统计绑定的磁盘数或归档副本数
+func fetchSnapshotArchivePolicyCounts(manager db.IModelManager, policyIds []string) (map[string]int, error) {
+	sq := manager.Query().SubQuery()
+	q := sq.Query(
+		sq.Field("policy_id"),
+		sqlchemy.COUNT("count"),
+	).Filter(sqlchemy.In(sq.Field("policy_id"), policyIds)).GroupBy(sq.Field("policy_id"))
+	counts := []sSnapshotArchivePolicyCount{}
+	err := q.All(&counts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "count %s", manager.KeywordPlural())
+	}
+	ret := map[string]int{}
+	for i := range counts {
+		ret[counts[i].PolicyId] = counts[i].Count
+	}
+	return ret, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// +onecloud:swagger-gen-ignore
+type SSnapshotArchivePolicyDiskManager struct {
+	db.SResourceBaseManager
+}
+
+var SnapshotArchivePolicyDiskManager *SSnapshotArchivePolicyDiskManager
+
+func init() {
+	SnapshotArchivePolicyDiskManager = &SSnapshotArchivePolicyDiskManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SSnapshotArchivePolicyDisk{},
+			"snapshot_archive_policy_disks_tbl",
+			"snapshot_archive_policy_disk",
+			"snapshot_archive_policy_disks",
+		),
+	}
+	SnapshotArchivePolicyDiskManager.SetVirtualObject(SnapshotArchivePolicyDiskManager)
+}
+
+// +onecloud:swagger-gen-ignore
+type SSnapshotArchivePolicyDisk struct {
+	db.SResourceBase
+
+	PolicyId string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	DiskId   string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+}
+
+func (self *SSnapshotArchivePolicyDisk) GetId() string {
+	return self.PolicyId + "/" + self.DiskId
+}
+
+func (manager *SSnapshotArchivePolicyDiskManager) GetDiskIds(policyId string) ([]string, error) {
+	bindings := []SSnapshotArchivePolicyDisk{}
+	err := db.FetchModelObjects(manager, manager.Query().Equals("policy_id", policyId), &bindings)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	ret := make([]string, len(bindings))
+	for i := range bindings {
+		ret[i] = bindings[i].DiskId
+	}
+	return ret, nil
+}
+
+func (manager *SSnapshotArchivePolicyDiskManager) removeByPolicy(ctx context.Context, userCred mcclient.TokenCredential, policyId string) error {
+	bindings := []SSnapshotArchivePolicyDisk{}
+	err := db.FetchModelObjects(manager, manager.Query().Equals("policy_id", policyId), &bindings)
+	if err != nil {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range bindings {
+		err := bindings[i].Delete(ctx, userCred)
+		if err != nil {
+			return errors.Wrapf(err, "delete binding %s", bindings[i].GetId())
+		}
+	}
+	return nil
+}
+
+func (self *SSnapshotArchivePolicy) fetchDisks(userCred mcclient.TokenCredential, ids []string) ([]*SDisk, error) {
+	vault, err := self.GetVaultCloudaccount()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	disks := []*SDisk{}
+	for _, id := range ids {
+		diskObj, err := DiskManager.FetchByIdOrName(userCred, id)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), id)
+		}
+		disk := diskObj.(*SDisk)
+		provider := disk.GetCloudprovider()
+		if provider == nil {
+			return nil, httperrors.NewUnsupportOperationError("disk %s is not managed by cloud provider", disk.Name)
+		}
+		account, err := provider.GetCloudaccount()
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		if account.Id == vault.Id {
+			return nil, httperrors.NewInputParameterError("disk %s belongs to vault cloudaccount %s", disk.Name, vault.Name)
+		}
+		if account.Provider != vault.Provider {
+			return nil, httperrors.NewInputParameterError("disk %s is %s, vault cloudaccount is %s", disk.Name, account.Provider, vault.Provider)
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+// 绑定需要归档快照的磁盘
+func (self *SSnapshotArchivePolicy) PerformBindDisks(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SnapshotArchivePolicyDisksInput) (jsonutils.JSONObject, error) {
+	if len(input.Disks) == 0 {
+		return nil, httperrors.NewMissingParameterError("disks")
+	}
+	disks, err := self.fetchDisks(userCred, input.Disks)
+	if err != nil {
+		return nil, err
+	}
+	diskIds := []string{}
+	for _, disk := range disks {
+		binding := &SSnapshotArchivePolicyDisk{PolicyId: self.Id, DiskId: disk.Id}
+		binding.SetModelManager(SnapshotArchivePolicyDiskManager, binding)
+		err := SnapshotArchivePolicyDiskManager.TableSpec().InsertOrUpdate(ctx, binding)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(errors.Wrapf(err, "bind disk %s", disk.Name))
+		}
+		diskIds = append(diskIds, disk.Id)
+	}
+	db.OpsLog.LogEvent(self, db.ACT_ATTACH, diskIds, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_BIND_DISK, diskIds, userCred, true)
+	return nil, nil
+}
+
+// 解绑磁盘, 已归档的副本仍按保留期保留
+func (self *SSnapshotArchivePolicy) PerformUnbindDisks(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SnapshotArchivePolicyDisksInput) (jsonutils.JSONObject, error) {
+	if len(input.Disks) == 0 {
+		return nil, httperrors.NewMissingParameterError("disks")
+	}
+	diskIds := []string{}
+	for _, id := range input.Disks {
+		diskObj, err := DiskManager.FetchByIdOrName(userCred, id)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), id)
+		}
+		diskIds = append(diskIds, diskObj.GetId())
+	}
+	bindings := []SSnapshotArchivePolicyDisk{}
+	q := SnapshotArchivePolicyDiskManager.Query().Equals("policy_id", self.Id).In("disk_id", diskIds)
+	err := db.FetchModelObjects(SnapshotArchivePolicyDiskManager, q, &bindings)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	for i := range bindings {
+		err := bindings[i].Delete(ctx, userCred)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	}
+	db.OpsLog.LogEvent(self, db.ACT_DETACH, diskIds, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_UNBIND_DISK, diskIds, userCred, true)
+	return nil, nil
+}
@@ -0,0 +1,476 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// ICloudSnapshotSharer 源账号快照共享给其他云上账号的能力, 例如 AWS ModifySnapshotAttribute createVolumePermission
+type ICloudSnapshotSharer interface {
+	ShareToAccount(ctx context.Context, accountId string) error
+}
+
+type SnapshotVaultCopyOptions struct {
+	// 源快照的云上Id
+	SourceSnapshotId string
+	// 源快照所属云上账号
+	SourceAccountId string
+	Name            string
+	Desc            string
+}
+
+// ICloudSnapshotVaultRegion 金库账号在同一区域复制共享快照的能力, 复制后的快照归金库账号所有
+type ICloudSnapshotVaultRegion interface {
+	CopySharedSnapshot(ctx context.Context, opts *SnapshotVaultCopyOptions) (cloudprovider.ICloudSnapshot, error)
+}
+
+// 快照在金库账号中的合规副本
+type SSnapshotArchiveManager struct {
+	db.SStatusStandaloneResourceBaseManager
+	db.SExternalizedResourceBaseManager
+}
+
+var SnapshotArchiveManager *SSnapshotArchiveManager
+
+func init() {
+	SnapshotArchiveManager = &SSnapshotArchiveManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SSnapshotArchive{},
+			"snapshot_archives_tbl",
+			"snapshot_archive",
+			"snapshot_archives",
+		),
+	}
+	SnapshotArchiveManager.SetVirtualObject(SnapshotArchiveManager)
+}
+
+type SSnapshotArchive struct {
+	db.SStatusStandaloneResourceBase
+	db.SExternalizedResourceBase
+
+	PolicyId   string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	SnapshotId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 源磁盘Id, 源磁盘删除后仍保留以便审计
+	DiskId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+
+	SourceCloudaccountId string `width:"36" charset:"ascii" nullable:"false" list:"admin"`
+	VaultCloudaccountId  string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	VaultCloudproviderId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+	CloudregionId        string `width:"36" charset:"ascii" nullable:"false" list:"admin"`
+
+	// 快照大小, 单位MB
+	SizeMb     int       `nullable:"false" list:"admin"`
+	ArchivedAt time.Time `nullable:"true" list:"admin"`
+	// 合规保留截止时间, 之前不允许删除
+	RetainUntil time.Time `nullable:"true" list:"admin"`
+}
+
+func snapshotArchiveRetainUntil(archivedAt time.Time, retentionDays int) time.Time {
+	return archivedAt.AddDate(0, 0, retentionDays)
+}
+
+func (self *SSnapshotArchive) isRetained(now time.Time) bool {
+	// 归档未完成前保留期尚未开始, 按被锁定处理
+	if self.RetainUntil.IsZero() {
+		return self.Status == api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVING
+	}
+	return now.Before(self.RetainUntil)
+}
+
+func (manager *SSnapshotArchiveManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return nil, httperrors.NewUnsupportOperationError("use snapshot archive action instead")
+}
+
+func (self *SSnapshotArchive) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if self.isRetained(time.Now()) {
+		return httperrors.NewForbiddenError("snapshot archive %s is retained until %s", self.Name, self.RetainUntil.Format(time.RFC3339))
+	}
+	if self.Status == api.SNAPSHOT_ARCHIVE_STATUS_DELETING {
+		return httperrors.NewInvalidStatusError("snapshot archive %s is %s", self.Name, self.Status)
+	}
+	return self.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *SSnapshotArchive) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartDeleteTask(ctx, userCred)
+}
+
+func (self *SSnapshotArchive) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential) error {
+	var err = func() error {
+		task, err := taskman.TaskManager.NewTask(ctx, "SnapshotArchiveDeleteTask", self, userCred, nil, "", "", nil)
+		if err != nil {
+			return errors.Wrapf(err, "NewTask")
+		}
+		return task.ScheduleRun(nil)
+	}()
+	if err != nil {
+		self.SetStatus(userCred, api.SNAPSHOT_ARCHIVE_STATUS_DELETE_FAILED, err.Error())
+		return err
+	}
+	self.SetStatus(userCred, api.SNAPSHOT_ARCHIVE_STATUS_DELETING, "")
+	return nil
+}
+
+func (self *SSnapshotArchive) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (self *SSnapshotArchive) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SSnapshotArchive) GetPolicy() (*SSnapshotArchivePolicy, error) {
+	policyObj, err := SnapshotArchivePolicyManager.FetchById(self.PolicyId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.PolicyId)
+	}
+	return policyObj.(*SSnapshotArchivePolicy), nil
+}
+
+func (self *SSnapshotArchive) GetSnapshot() (*SSnapshot, error) {
+	snapshotObj, err := SnapshotManager.FetchById(self.SnapshotId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.SnapshotId)
+	}
+	return snapshotObj.(*SSnapshot), nil
+}
+
+// 金库账号下与源快照同区域的云上区域
+func (self *SSnapshotArchive) GetVaultIRegion(ctx context.Context) (cloudprovider.ICloudRegion, error) {
+	regionObj, err := CloudregionManager.FetchById(self.CloudregionId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.CloudregionId)
+	}
+	region := regionObj.(*SCloudregion)
+	providerObj, err := CloudproviderManager.FetchById(self.VaultCloudproviderId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "FetchById(%s)", self.VaultCloudproviderId)
+	}
+	provider, err := providerObj.(*SCloudprovider).GetProvider(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetProvider")
+	}
+	return provider.GetIRegionById(region.ExternalId)
+}
+
+func (manager *SSnapshotArchiveManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SnapshotArchiveListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.PolicyId) > 0 {
+		policy, err := SnapshotArchivePolicyManager.FetchByIdOrName(userCred, query.PolicyId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(SnapshotArchivePolicyManager.Keyword(), query.PolicyId)
+		}
+		q = q.Equals("policy_id", policy.GetId())
+	}
+	if len(query.DiskId) > 0 {
+		// 源磁盘可能已删除, 无法解析时按Id直接过滤
+		if disk, err := DiskManager.FetchByIdOrName(userCred, query.DiskId); err == nil {
+			q = q.Equals("disk_id", disk.GetId())
+		} else {
+			q = q.Equals("disk_id", query.DiskId)
+		}
+	}
+	if len(query.SnapshotId) > 0 {
+		if snapshot, err := SnapshotManager.FetchByIdOrName(userCred, query.SnapshotId); err == nil {
+			q = q.Equals("snapshot_id", snapshot.GetId())
+		} else {
+			q = q.Equals("snapshot_id", query.SnapshotId)
+		}
+	}
+	if len(query.VaultCloudaccountId) > 0 {
+		account, err := CloudaccountManager.FetchByIdOrName(userCred, query.VaultCloudaccountId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), query.VaultCloudaccountId)
+		}
+		q = q.Equals("vault_cloudaccount_id", account.GetId())
+	}
+	if query.Retained != nil {
+		if *query.Retained {
+			q = q.GT("retain_until", time.Now())
+		} else {
+			q = q.LE("retain_until", time.Now())
+		}
+	}
+	return q, nil
+}
+
+func (manager *SSnapshotArchiveManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SnapshotArchiveListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (manager *SSnapshotArchiveManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *SSnapshotArchiveManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.SnapshotArchiveDetails {
+	rows := make([]api.SnapshotArchiveDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	policyIds := make([]string, len(objs))
+	diskIds := make([]string, len(objs))
+	snapshotIds := make([]string, len(objs))
+	accountIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		archive := objs[i].(*SSnapshotArchive)
+		policyIds[i] = archive.PolicyId
+		diskIds[i] = archive.DiskId
+		snapshotIds[i] = archive.SnapshotId
+		accountIds[i] = archive.VaultCloudaccountId
+	}
+	policies := make(map[string]SSnapshotArchivePolicy)
+	if err := db.FetchStandaloneObjectsByIds(SnapshotArchivePolicyManager, policyIds, policies); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds snapshot archive policies fail %s", err)
+		return rows
+	}
+	disks := make(map[string]SDisk)
+	if err := db.FetchStandaloneObjectsByIds(DiskManager, diskIds, disks); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds disks fail %s", err)
+		return rows
+	}
+	snapshots := make(map[string]SSnapshot)
+	if err := db.FetchStandaloneObjectsByIds(SnapshotManager, snapshotIds, snapshots); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds snapshots fail %s", err)
+		return rows
+	}
+	accounts := make(map[string]SCloudaccount)
+	if err := db.FetchStandaloneObjectsByIds(CloudaccountManager, accountIds, accounts); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds cloudaccounts fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if policy, ok := policies[policyIds[i]]; ok {
+			rows[i].Policy = policy.Name
+		}
+		if disk, ok := disks[diskIds[i]]; ok {
+			rows[i].Disk = disk.Name
+		}
+		if snapshot, ok := snapshots[snapshotIds[i]]; ok {
+			rows[i].Snapshot = snapshot.Name
+		}
+		if account, ok := accounts[accountIds[i]]; ok {
+			rows[i].VaultCloudaccount = account.Name
+		}
+	}
+	return rows
+}
+
+func (manager *SSnapshotArchiveManager) FetchBySnapshot(policyId, snapshotId string) (*SSnapshotArchive, error) {
+	q := manager.Query().Equals("policy_id", policyId).Equals("snapshot_id", snapshotId)
+	archive := &SSnapshotArchive{}
+	archive.SetModelManager(manager, archive)
+	err := q.First(archive)
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (manager *SSnapshotArchiveManager) archiveSnapshot(ctx context.Context, userCred mcclient.TokenCredential, policy *SSnapshotArchivePolicy, snapshot *SSnapshot) (*SSnapshotArchive, error) {
+	if snapshot.Status != api.SNAPSHOT_READY {
+		return nil, httperrors.NewInvalidStatusError("snapshot %s is %s", snapshot.Name, snapshot.Status)
+	}
+	if !policy.GetEnabled() {
+		return nil, httperrors.NewInvalidStatusError("snapshot archive policy %s is disabled", policy.Name)
+	}
+	archive, err := manager.FetchBySnapshot(policy.Id, snapshot.Id)
+	if err == nil {
+		if archive.Status != api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVE_FAILED {
+			return nil, httperrors.NewDuplicateResourceError("snapshot %s already archived by policy %s", snapshot.Name, policy.Name)
+		}
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	provider := snapshot.GetCloudprovider()
+	if provider == nil {
+		return nil, httperrors.NewUnsupportOperationError("snapshot %s is not managed by cloud provider", snapshot.Name)
+	}
+	source, err := provider.GetCloudaccount()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	vault, err := policy.GetVaultCloudaccount()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if source.Id == vault.Id || source.Provider != vault.Provider {
+		return nil, httperrors.NewInputParameterError("snapshot %s of cloudaccount %s cannot be archived to vault %s", snapshot.Name, source.Name, vault.Name)
+	}
+	vaultProviders := vault.GetEnabledCloudproviders()
+	if len(vaultProviders) == 0 {
+		return nil, httperrors.NewInvalidStatusError("vault cloudaccount %s has no enabled cloudprovider", vault.Name)
+	}
+
+	if archive == nil {
+		archive = &SSnapshotArchive{
+			PolicyId:   policy.Id,
+			SnapshotId: snapshot.Id,
+		}
+		archive.SetModelManager(manager, archive)
+		archive.Name = snapshot.Name
+		archive.DiskId = snapshot.DiskId
+		archive.SourceCloudaccountId = source.Id
+		archive.VaultCloudaccountId = vault.Id
+		archive.VaultCloudproviderId = vaultProviders[0].Id
+		archive.CloudregionId = snapshot.CloudregionId
+		archive.SizeMb = snapshot.Size
+		archive.Status = api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVING
+		err = manager.TableSpec().Insert(ctx, archive)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(errors.Wrap(err, "Insert"))
+		}
+	} else {
+		archive.SetStatus(userCred, api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVING, "retry")
+	}
+
+	task, err := taskman.TaskManager.NewTask(ctx, "SnapshotArchiveTask", archive, userCred, nil, "", "", nil)
+	if err != nil {
+		archive.SetStatus(userCred, api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVE_FAILED, err.Error())
+		return nil, errors.Wrap(err, "NewTask")
+	}
+	return archive, task.ScheduleRun(nil)
+}
+
+// 将快照按归档策略复制到金库账号
+func (self *SSnapshot) PerformArchive(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SnapshotArchiveInput) (jsonutils.JSONObject, error) {
+	if len(input.PolicyId) == 0 {
+		return nil, httperrors.NewMissingParameterError("policy_id")
+	}
+	policyObj, err := SnapshotArchivePolicyManager.FetchByIdOrName(userCred, input.PolicyId)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(SnapshotArchivePolicyManager.Keyword(), input.PolicyId)
+	}
+	_, err = SnapshotArchiveManager.archiveSnapshot(ctx, userCred, policyObj.(*SSnapshotArchivePolicy), self)
+	return nil, err
+}
+
+// 定时归档绑定磁盘上尚未归档的快照
+func (manager *SSnapshotArchivePolicyManager) ArchivePolicySnapshots(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	policies := []SSnapshotArchivePolicy{}
+	err := db.FetchModelObjects(manager, manager.Query().IsTrue("enabled"), &policies)
+	if err != nil {
+		log.Errorf("ArchivePolicySnapshots fetch policies: %v", err)
+		return
+	}
+	for i := range policies {
+		policy := &policies[i]
+		diskIds, err := SnapshotArchivePolicyDiskManager.GetDiskIds(policy.Id)
+		if err != nil {
+			log.Errorf("ArchivePolicySnapshots policy %s GetDiskIds: %v", policy.Name, err)
+			continue
+		}
+		if len(diskIds) == 0 {
+			continue
+		}
+		archived := SnapshotArchiveManager.Query("snapshot_id").Equals("policy_id", policy.Id)
+		q := SnapshotManager.Query().In("disk_id", diskIds).Equals("status", api.SNAPSHOT_READY).NotIn("id", archived.SubQuery())
+		snapshots := []SSnapshot{}
+		err = db.FetchModelObjects(SnapshotManager, q, &snapshots)
+		if err != nil {
+			log.Errorf("ArchivePolicySnapshots policy %s fetch snapshots: %v", policy.Name, err)
+			continue
+		}
+		for j := range snapshots {
+			_, err := SnapshotArchiveManager.archiveSnapshot(ctx, userCred, policy, &snapshots[j])
+			if err != nil {
+				log.Errorf("ArchivePolicySnapshots archive snapshot %s by policy %s: %v", snapshots[j].Name, policy.Name, err)
+			}
+		}
+	}
+}
+
+// 清理保留期已过的副本
+func (manager *SSnapshotArchiveManager) CleanupExpiredArchives(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().Equals("status", api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVED).LE("retain_until", time.Now())
+	archives := []SSnapshotArchive{}
+	err := db.FetchModelObjects(manager, q, &archives)
+	if err != nil {
+		log.Errorf("CleanupExpiredArchives: %v", err)
+		return
+	}
+	for i := range archives {
+		err := archives[i].StartDeleteTask(ctx, userCred)
+		if err != nil {
+			log.Errorf("CleanupExpiredArchives %s: %v", archives[i].Name, err)
+		}
+	}
+}
+
+// 归档完成, 开始计算合规保留期
+func (self *SSnapshotArchive) MarkArchived(ctx context.Context, userCred mcclient.TokenCredential, externalId string, retentionDays int) error {
+	now := time.Now().UTC()
+	_, err := db.Update(self, func() error {
+		self.ExternalId = externalId
+		self.ArchivedAt = now
+		self.RetainUntil = snapshotArchiveRetainUntil(now, retentionDays)
+		self.Status = api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVED
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, self.GetShortDesc(ctx), userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_SNAPSHOT_ARCHIVE, self.GetShortDesc(ctx), userCred, true)
+	return nil
+}
+
+func (self *SSnapshotArchive) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
+	desc := self.SStatusStandaloneResourceBase.GetShortDesc(ctx)
+	desc.Set("snapshot_id", jsonutils.NewString(self.SnapshotId))
+	desc.Set("disk_id", jsonutils.NewString(self.DiskId))
+	desc.Set("vault_cloudaccount_id", jsonutils.NewString(self.VaultCloudaccountId))
+	desc.Set("external_id", jsonutils.NewString(self.ExternalId))
+	if !self.RetainUntil.IsZero() {
+		desc.Set("retain_until", jsonutils.NewTimeString(self.RetainUntil))
+	}
+	return desc
+}
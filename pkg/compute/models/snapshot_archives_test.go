@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSSnapshotArchive_isRetained(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	newArchive := func(status string, archivedAt time.Time, days int) *SSnapshotArchive {
+		archive := &SSnapshotArchive{}
+		archive.Status = status
+		if !archivedAt.IsZero() {
+			archive.ArchivedAt = archivedAt
+			archive.RetainUntil = snapshotArchiveRetainUntil(archivedAt, days)
+		}
+		return archive
+	}
+	cases := []struct {
+		name    string
+		archive *SSnapshotArchive
+		want    bool
+	}{
+		{"archiving", newArchive(api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVING, time.Time{}, 0), true},
+		{"archive failed", newArchive(api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVE_FAILED, time.Time{}, 0), false},
+		{"in retention", newArchive(api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVED, now.AddDate(0, 0, -29), 30), true},
+		{"leap day", newArchive(api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVED, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 29), false},
+		{"expired", newArchive(api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVED, now.AddDate(0, 0, -31), 30), false},
+	}
+	for _, c := range cases {
+		if got := c.archive.isRetained(now); got != c.want {
+			t.Errorf("%s: isRetained = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -61,6 +61,13 @@ type SSnapshotPolicy struct {
 	// 0~23
 	TimePoints  uint32            `charset:"utf8" create:"required" list:"user" get:"user"`
 	IsActivated tristate.TriState `list:"user" get:"user" create:"optional" default:"true"`
+
+	// GFS 轮转保留的日快照个数, 0 表示不保留日快照
+	RetentionDaily int `nullable:"false" default:"0" list:"user" get:"user" create:"optional" update:"user"`
+	// GFS 轮转保留的周快照个数
+	RetentionWeekly int `nullable:"false" default:"0" list:"user" get:"user" create:"optional" update:"user"`
+	// GFS 轮转保留的月快照个数
+	RetentionMonthly int `nullable:"false" default:"0" list:"user" get:"user" create:"optional" update:"user"`
 }
 
 var SnapshotPolicyManager *SSnapshotPolicyManager
@@ -179,6 +186,23 @@ func (manager *SSnapshotPolicyManager) ValidateCreateData(ctx context.Context, u
 		return nil, err
 	}
 
+	if input.Gfs && input.RetentionDaily == 0 && input.RetentionWeekly == 0 && input.RetentionMonthly == 0 {
+		input.RetentionDaily = api.SNAPSHOT_POLICY_GFS_DAILY
+		input.RetentionWeekly = api.SNAPSHOT_POLICY_GFS_WEEKLY
+		input.RetentionMonthly = api.SNAPSHOT_POLICY_GFS_MONTHLY
+	}
+	err = validateGFSRetention(input.RetentionDaily, input.RetentionWeekly, input.RetentionMonthly)
+	if err != nil {
+		return nil, err
+	}
+	if input.RetentionDaily > 0 || input.RetentionWeekly > 0 || input.RetentionMonthly > 0 {
+		// GFS 轮转模式下快照不按天数过期, 由轮转任务清理
+		if input.RetentionDays > 0 {
+			return nil, httperrors.NewConflictError("retention_days conflicts with GFS retention")
+		}
+		input.RetentionDays = -1
+	}
+
 	if input.RetentionDays < -1 || input.RetentionDays == 0 || input.RetentionDays > options.Options.RetentionDaysLimit {
 		return nil, httperrors.NewInputParameterError("Retention days must in 1~%d or -1", options.Options.RetentionDaysLimit)
 	}
@@ -703,6 +727,10 @@ func (manager *SSnapshotPolicyManager) sSnapshotPolicyCreateInputToInternal(inpu
 		ProjectId:     input.ProjectId,
 		DomainId:      input.DomainId,
 		RetentionDays: input.RetentionDays,
+
+		RetentionDaily:   input.RetentionDaily,
+		RetentionWeekly:  input.RetentionWeekly,
+		RetentionMonthly: input.RetentionMonthly,
 	}
 
 	ret.RepeatWeekdays = manager.RepeatWeekdaysParseIntArray(input.RepeatWeekdays)
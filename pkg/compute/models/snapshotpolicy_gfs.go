@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+func validateGFSRetention(daily, weekly, monthly int) error {
+	if daily < 0 || daily > api.SNAPSHOT_POLICY_GFS_DAILY_LIMIT {
+		return httperrors.NewInputParameterError("retention_daily must in 0~%d", api.SNAPSHOT_POLICY_GFS_DAILY_LIMIT)
+	}
+	if weekly < 0 || weekly > api.SNAPSHOT_POLICY_GFS_WEEKLY_LIMIT {
+		return httperrors.NewInputParameterError("retention_weekly must in 0~%d", api.SNAPSHOT_POLICY_GFS_WEEKLY_LIMIT)
+	}
+	if monthly < 0 || monthly > api.SNAPSHOT_POLICY_GFS_MONTHLY_LIMIT {
+		return httperrors.NewInputParameterError("retention_monthly must in 0~%d", api.SNAPSHOT_POLICY_GFS_MONTHLY_LIMIT)
+	}
+	return nil
+}
+
+func (sp *SSnapshotPolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SnapshotPolicyUpdateInput) (api.SnapshotPolicyUpdateInput, error) {
+	daily, weekly, monthly := sp.RetentionDaily, sp.RetentionWeekly, sp.RetentionMonthly
+	if input.RetentionDaily != nil {
+		daily = *input.RetentionDaily
+	}
+	if input.RetentionWeekly != nil {
+		weekly = *input.RetentionWeekly
+	}
+	if input.RetentionMonthly != nil {
+		monthly = *input.RetentionMonthly
+	}
+	err := validateGFSRetention(daily, weekly, monthly)
+	if err != nil {
+		return input, err
+	}
+	if sp.IsGFS() && daily == 0 && weekly == 0 && monthly == 0 {
+		return input, httperrors.NewInputParameterError("can not disable GFS retention, at least one of retention_daily, retention_weekly or retention_monthly is required")
+	}
+	if !sp.IsGFS() && (daily > 0 || weekly > 0 || monthly > 0) && sp.RetentionDays > 0 {
+		return input, httperrors.NewConflictError("retention_days %d conflicts with GFS retention", sp.RetentionDays)
+	}
+	input.VirtualResourceBaseUpdateInput, err = sp.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	return input, err
+}
+
+// 是否启用 GFS(Grandfather-Father-Son) 轮转保留
+func (sp *SSnapshotPolicy) IsGFS() bool {
+	return sp.RetentionDaily > 0 || sp.RetentionWeekly > 0 || sp.RetentionMonthly > 0
+}
+
+// 计算 GFS 轮转需要保留的快照, 每天/周/月仅保留最新的一个快照
+func gfsRetainedSnapshots(createdAt []time.Time, daily, weekly, monthly int) []bool {
+	idx := make([]int, len(createdAt))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return createdAt[idx[i]].After(createdAt[idx[j]])
+	})
+	keep := make([]bool, len(createdAt))
+	mark := func(limit int, bucket func(t time.Time) string) {
+		seen := map[string]bool{}
+		for _, i := range idx {
+			key := bucket(createdAt[i].UTC())
+			if seen[key] {
+				continue
+			}
+			if len(seen) >= limit {
+				break
+			}
+			seen[key] = true
+			keep[i] = true
+		}
+	}
+	mark(daily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	mark(weekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+	mark(monthly, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+	return keep
+}
+
+// 获取磁盘在 GFS 轮转中需要清理的快照
+// 跨区域复制的快照按区域分别轮转, 被实例快照引用或作为其他磁盘 backing 的快照不会被清理
+func (sp *SSnapshotPolicy) getGFSPrunableSnapshots(ctx context.Context, disk *SDisk) ([]SSnapshot, error) {
+	q := SnapshotManager.Query().Equals("disk_id", disk.Id).Equals("created_by", api.SNAPSHOT_AUTO).
+		Equals("fake_deleted", false).Equals("status", api.SNAPSHOT_READY)
+	snapshots := []SSnapshot{}
+	err := db.FetchModelObjects(SnapshotManager, q, &snapshots)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	regions := map[string][]int{}
+	for i := range snapshots {
+		regions[snapshots[i].CloudregionId] = append(regions[snapshots[i].CloudregionId], i)
+	}
+	ret := []SSnapshot{}
+	for _, idxs := range regions {
+		createdAt := make([]time.Time, len(idxs))
+		for i, idx := range idxs {
+			createdAt[i] = snapshots[idx].CreatedAt
+		}
+		keep := gfsRetainedSnapshots(createdAt, sp.RetentionDaily, sp.RetentionWeekly, sp.RetentionMonthly)
+		for i, idx := range idxs {
+			if keep[i] {
+				continue
+			}
+			snapshot := &snapshots[idx]
+			if snapshot.RefCount > 0 {
+				continue
+			}
+			if err := snapshot.ValidatePurgeCondition(ctx); err != nil {
+				log.Debugf("skip prune snapshot %s(%s): %v", snapshot.Name, snapshot.Id, err)
+				continue
+			}
+			ret = append(ret, *snapshot)
+		}
+	}
+	return ret, nil
+}
+
+func (disk *SDisk) StartSnapshotGFSPruneTask(ctx context.Context, userCred mcclient.TokenCredential, sp *SSnapshotPolicy, snapshots []SSnapshot) error {
+	snapshotIds := make([]string, len(snapshots))
+	for i := range snapshots {
+		snapshotIds[i] = snapshots[i].Id
+	}
+	params := jsonutils.NewDict()
+	params.Set("snapshotpolicy_id", jsonutils.NewString(sp.Id))
+	params.Set("snapshot_ids", jsonutils.NewStringArray(snapshotIds))
+	task, err := taskman.TaskManager.NewTask(ctx, "DiskSnapshotGFSPruneTask", disk, userCred, params, "", "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// 定时按 GFS 策略轮转清理自动快照
+func (manager *SSnapshotPolicyManager) PruneGFSSnapshots(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsTrue("is_activated")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.GT(q.Field("retention_daily"), 0),
+		sqlchemy.GT(q.Field("retention_weekly"), 0),
+		sqlchemy.GT(q.Field("retention_monthly"), 0),
+	))
+	sps := []SSnapshotPolicy{}
+	err := db.FetchModelObjects(manager, q, &sps)
+	if err != nil {
+		log.Errorf("PruneGFSSnapshots fetch snapshot policies: %v", err)
+		return
+	}
+	for i := range sps {
+		sp := &sps[i]
+		spds, err := SnapshotPolicyDiskManager.FetchAllBySnapshotpolicyID(ctx, userCred, sp.Id)
+		if err != nil {
+			log.Errorf("PruneGFSSnapshots fetch disks of snapshot policy %s: %v", sp.Name, err)
+			continue
+		}
+		for j := range spds {
+			disk := DiskManager.FetchDiskById(spds[j].DiskId)
+			if disk == nil {
+				continue
+			}
+			snapshots, err := sp.getGFSPrunableSnapshots(ctx, disk)
+			if err != nil {
+				log.Errorf("PruneGFSSnapshots for disk %s: %v", disk.Name, err)
+				continue
+			}
+			if len(snapshots) == 0 {
+				continue
+			}
+			err = disk.StartSnapshotGFSPruneTask(ctx, userCred, sp, snapshots)
+			if err != nil {
+				log.Errorf("StartSnapshotGFSPruneTask for disk %s: %v", disk.Name, err)
+			}
+		}
+	}
+}
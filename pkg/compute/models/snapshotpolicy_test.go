@@ -206,3 +206,49 @@ func TestSSnapshotPolicy_ComputeNextSyncTime(t *testing.T) {
 		}
 	})
 }
+
+func TestGfsRetainedSnapshots(t *testing.T) {
+	// 2026-03-31 is Tuesday, one snapshot per day at 01:00 and 13:00 for 120 days
+	end := time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC)
+	createdAt := []time.Time{}
+	for i := 0; i < 240; i++ {
+		createdAt = append(createdAt, end.Add(-time.Duration(i)*12*time.Hour))
+	}
+	keep := gfsRetainedSnapshots(createdAt, 7, 4, 12)
+	kept := []time.Time{}
+	for i := range keep {
+		if keep[i] {
+			kept = append(kept, createdAt[i])
+		}
+	}
+	// 7 daily, the weekly and monthly buckets of the newest snapshots overlap with the daily ones
+	// weekly: 03-22, 03-15 are not covered by daily, monthly: 02-28, 01-31, 12-31
+	if len(kept) != 7+2+3 {
+		t.Fatalf("want %d snapshots kept, got %d: %v", 12, len(kept), kept)
+	}
+	for i := 0; i < 7; i++ {
+		if !keep[i*2] {
+			t.Errorf("daily snapshot %s should be kept", createdAt[i*2])
+		}
+		if keep[i*2+1] {
+			t.Errorf("snapshot %s should be pruned", createdAt[i*2+1])
+		}
+	}
+	for _, want := range []time.Time{
+		time.Date(2026, 3, 22, 13, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 15, 13, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 28, 13, 0, 0, 0, time.UTC),
+		time.Date(2025, 12, 31, 13, 0, 0, 0, time.UTC),
+	} {
+		found := false
+		for _, k := range kept {
+			if k.Equal(want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("snapshot %s should be kept", want)
+		}
+	}
+}
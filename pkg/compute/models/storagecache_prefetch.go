@@ -0,0 +1,395 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 镜像预取: 按可用区及宿主机统计近期以镜像创建磁盘的次数, 在业务低峰时段
+// 将热点镜像提前缓存到宿主机本地存储缓存, 超出存储的缓存预算时淘汰冷镜像
+
+type sImageUsageCount struct {
+	StorageId  string
+	ZoneId     string
+	TemplateId string
+	Count      int
+}
+
+type sImagePrefetchCandidate struct {
+	ImageId string
+	Size    int64
+	Score   int
+}
+
+type sCachedImageState struct {
+	ImageId  string
+	Size     int64
+	LastUsed time.Time
+	// 仍被本缓存所在存储上的磁盘或光驱引用
+	InUse bool
+	// 非 active 状态(缓存中, 删除中等), 不参与淘汰
+	Busy bool
+}
+
+type sPrefetchTarget struct {
+	cache      *SStoragecache
+	hostId     string
+	zoneId     string
+	storageIds []string
+	budget     int64
+}
+
+// inHourWindow 判断是否处于 [start, end) 小时区间内, 支持跨零点, start == end 表示全天
+func inHourWindow(hour, start, end int) bool {
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// rankImagesForCache 按可用区使用次数加本机使用次数排序, 本机使用计入两次以优先本机热点
+func rankImagesForCache(zoneUsage, cacheUsage map[string]int, minUsage, topN int) []sImagePrefetchCandidate {
+	ret := []sImagePrefetchCandidate{}
+	for imageId, cnt := range zoneUsage {
+		if cnt < minUsage {
+			continue
+		}
+		ret = append(ret, sImagePrefetchCandidate{
+			ImageId: imageId,
+			Score:   cnt + cacheUsage[imageId],
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score > ret[j].Score
+		}
+		return ret[i].ImageId < ret[j].ImageId
+	})
+	if topN > 0 && len(ret) > topN {
+		ret = ret[:topN]
+	}
+	return ret
+}
+
+// planStoragecachePrefetch 在预算内依次预取候选镜像, 空间不足时按最近使用时间
+// 淘汰未被引用且 coldBefore 之后未使用的非热点镜像, 淘汰后仍放不下则跳过
+func planStoragecachePrefetch(candidates []sImagePrefetchCandidate, cached []sCachedImageState, budget int64, coldBefore time.Time) ([]string, []string) {
+	var (
+		used      int64
+		present   = map[string]bool{}
+		hot       = map[string]bool{}
+		evictable []sCachedImageState
+		prefetch  = []string{}
+		evict     = []string{}
+	)
+	for _, c := range candidates {
+		hot[c.ImageId] = true
+	}
+	for _, c := range cached {
+		used += c.Size
+		present[c.ImageId] = true
+		if hot[c.ImageId] || c.InUse || c.Busy || c.LastUsed.After(coldBefore) {
+			continue
+		}
+		evictable = append(evictable, c)
+	}
+	sort.Slice(evictable, func(i, j int) bool {
+		if !evictable[i].LastUsed.Equal(evictable[j].LastUsed) {
+			return evictable[i].LastUsed.Before(evictable[j].LastUsed)
+		}
+		return evictable[i].ImageId < evictable[j].ImageId
+	})
+	for _, c := range candidates {
+		if present[c.ImageId] || c.Size <= 0 || c.Size > budget {
+			continue
+		}
+		var reclaimable int64
+		for _, e := range evictable {
+			reclaimable += e.Size
+		}
+		if used+c.Size-reclaimable > budget {
+			continue
+		}
+		for used+c.Size > budget {
+			used -= evictable[0].Size
+			evict = append(evict, evictable[0].ImageId)
+			evictable = evictable[1:]
+		}
+		used += c.Size
+		prefetch = append(prefetch, c.ImageId)
+	}
+	return prefetch, evict
+}
+
+func (manager *SStoragecacheManager) fetchImageUsages(since time.Time) ([]sImageUsageCount, error) {
+	disks := DiskManager.RawQuery().SubQuery()
+	storages := StorageManager.Query().SubQuery()
+	q := disks.Query(
+		disks.Field("storage_id"),
+		storages.Field("zone_id"),
+		disks.Field("template_id"),
+		sqlchemy.COUNT("count"),
+	).Join(storages, sqlchemy.Equals(disks.Field("storage_id"), storages.Field("id")))
+	q = q.Filter(sqlchemy.GE(disks.Field("created_at"), since))
+	q = q.Filter(sqlchemy.IsNotEmpty(disks.Field("template_id")))
+	q = q.GroupBy(disks.Field("storage_id"), storages.Field("zone_id"), disks.Field("template_id"))
+	usages := []sImageUsageCount{}
+	err := q.All(&usages)
+	if err != nil {
+		return nil, errors.Wrap(err, "query image usages")
+	}
+	return usages, nil
+}
+
+func (manager *SStoragecacheManager) fetchPrefetchTargets() ([]*sPrefetchTarget, error) {
+	hosts := HostManager.Query().SubQuery()
+	hoststorages := HoststorageManager.Query().SubQuery()
+	q := StorageManager.Query().Equals("storage_type", api.STORAGE_LOCAL).IsTrue("enabled").
+		Equals("status", api.STORAGE_ONLINE).IsNotEmpty("storagecache_id")
+	q = q.Join(hoststorages, sqlchemy.Equals(q.Field("id"), hoststorages.Field("storage_id")))
+	q = q.Join(hosts, sqlchemy.Equals(hoststorages.Field("host_id"), hosts.Field("id")))
+	q = q.Filter(sqlchemy.IsTrue(hosts.Field("enabled")))
+	q = q.Filter(sqlchemy.Equals(hosts.Field("host_status"), api.HOST_ONLINE))
+	q = q.Filter(sqlchemy.Equals(hosts.Field("host_type"), api.HOST_TYPE_HYPERVISOR))
+	q = q.AppendField(q.QueryFields()...)
+	q = q.AppendField(hosts.Field("id", "host_id"))
+	storages := []struct {
+		SStorage
+		HostId string
+	}{}
+	err := q.All(&storages)
+	if err != nil {
+		return nil, errors.Wrap(err, "query local storages")
+	}
+	defBudget := int64(options.Options.ImagePrefetchCacheBudgetGb) * 1024 * 1024 * 1024
+	targets := map[string]*sPrefetchTarget{}
+	ret := []*sPrefetchTarget{}
+	for i := range storages {
+		storage := storages[i]
+		budget := storage.ImageCacheBudgetMb * 1024 * 1024
+		if storage.ImageCacheBudgetMb == 0 {
+			budget = defBudget
+		}
+		target, ok := targets[storage.StoragecacheId]
+		if !ok {
+			cache := manager.FetchStoragecacheById(storage.StoragecacheId)
+			if cache == nil {
+				continue
+			}
+			target = &sPrefetchTarget{
+				cache:  cache,
+				hostId: storage.HostId,
+				zoneId: storage.ZoneId,
+				budget: budget,
+			}
+			targets[storage.StoragecacheId] = target
+			ret = append(ret, target)
+		} else if storage.ImageCacheBudgetMb < 0 || (target.budget >= 0 && budget > target.budget) {
+			// 共用缓存的存储中任一禁止预取则整体禁止, 否则取最大预算
+			target.budget = budget
+		}
+		target.storageIds = append(target.storageIds, storage.Id)
+	}
+	return ret, nil
+}
+
+func (target *sPrefetchTarget) fetchCachedImageStates() ([]sCachedImageState, error) {
+	scimgs := []SStoragecachedimage{}
+	q := StoragecachedimageManager.Query().Equals("storagecache_id", target.cache.Id)
+	err := db.FetchModelObjects(StoragecachedimageManager, q, &scimgs)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch storagecachedimages")
+	}
+	if len(scimgs) == 0 {
+		return nil, nil
+	}
+	imageIds := make([]string, len(scimgs))
+	for i := range scimgs {
+		imageIds[i] = scimgs[i].CachedimageId
+	}
+	images := []SCachedimage{}
+	err = db.FetchModelObjects(CachedimageManager, CachedimageManager.Query().In("id", imageIds), &images)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch cachedimages")
+	}
+	sizes := map[string]int64{}
+	for i := range images {
+		sizes[images[i].Id] = images[i].Size
+	}
+
+	inUse := map[string]bool{}
+	disks := DiskManager.Query("template_id").In("storage_id", target.storageIds).In("template_id", imageIds).Distinct()
+	diskRefs := []struct{ TemplateId string }{}
+	if err := disks.All(&diskRefs); err != nil {
+		return nil, errors.Wrap(err, "query disk references")
+	}
+	for _, ref := range diskRefs {
+		inUse[ref.TemplateId] = true
+	}
+	guests := GuestManager.Query().Equals("host_id", target.hostId).SubQuery()
+	cdroms := GuestcdromManager.Query().SubQuery()
+	cq := cdroms.Query(cdroms.Field("image_id")).Join(guests, sqlchemy.Equals(cdroms.Field("id"), guests.Field("id"))).
+		Filter(sqlchemy.In(cdroms.Field("image_id"), imageIds)).Distinct()
+	cdromRefs := []struct{ ImageId string }{}
+	if err := cq.All(&cdromRefs); err != nil {
+		return nil, errors.Wrap(err, "query cdrom references")
+	}
+	for _, ref := range cdromRefs {
+		inUse[ref.ImageId] = true
+	}
+
+	ret := make([]sCachedImageState, 0, len(scimgs))
+	for i := range scimgs {
+		lastUsed := scimgs[i].LastDownload
+		if lastUsed.IsZero() {
+			lastUsed = scimgs[i].CreatedAt
+		}
+		ret = append(ret, sCachedImageState{
+			ImageId:  scimgs[i].CachedimageId,
+			Size:     sizes[scimgs[i].CachedimageId],
+			LastUsed: lastUsed,
+			InUse:    inUse[scimgs[i].CachedimageId],
+			Busy:     scimgs[i].Status != api.CACHED_IMAGE_STATUS_ACTIVE,
+		})
+	}
+	return ret, nil
+}
+
+// PrefetchHotImages 低峰时段将热点镜像预取到宿主机本地存储缓存
+func (manager *SStoragecacheManager) PrefetchHotImages(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	opts := options.Options
+	now := time.Now()
+	if !inHourWindow(now.Hour(), opts.ImagePrefetchStartHour, opts.ImagePrefetchEndHour) {
+		return
+	}
+	since := now.AddDate(0, 0, -opts.ImagePrefetchUsageDays)
+	usages, err := manager.fetchImageUsages(since)
+	if err != nil {
+		log.Errorf("PrefetchHotImages: %v", err)
+		return
+	}
+	if len(usages) == 0 {
+		return
+	}
+	targets, err := manager.fetchPrefetchTargets()
+	if err != nil {
+		log.Errorf("PrefetchHotImages: %v", err)
+		return
+	}
+	storageCache := map[string]string{}
+	for _, target := range targets {
+		for _, storageId := range target.storageIds {
+			storageCache[storageId] = target.cache.Id
+		}
+	}
+	zoneUsage := map[string]map[string]int{}
+	cacheUsage := map[string]map[string]int{}
+	hotIds := []string{}
+	for _, u := range usages {
+		if _, ok := zoneUsage[u.ZoneId]; !ok {
+			zoneUsage[u.ZoneId] = map[string]int{}
+		}
+		zoneUsage[u.ZoneId][u.TemplateId] += u.Count
+		hotIds = append(hotIds, u.TemplateId)
+		if cacheId, ok := storageCache[u.StorageId]; ok {
+			if _, ok := cacheUsage[cacheId]; !ok {
+				cacheUsage[cacheId] = map[string]int{}
+			}
+			cacheUsage[cacheId][u.TemplateId] += u.Count
+		}
+	}
+
+	images := []SCachedimage{}
+	q := CachedimageManager.Query().In("id", hotIds).Equals("status", api.CACHED_IMAGE_STATUS_ACTIVE).
+		Equals("image_type", string(cloudprovider.ImageTypeCustomized))
+	if err := db.FetchModelObjects(CachedimageManager, q, &images); err != nil {
+		log.Errorf("PrefetchHotImages fetch cachedimages: %v", err)
+		return
+	}
+	sizes := map[string]int64{}
+	for i := range images {
+		sizes[images[i].Id] = images[i].Size
+	}
+
+	tasks := 0
+	for _, target := range targets {
+		if target.budget <= 0 {
+			continue
+		}
+		if opts.ImagePrefetchMaxTasks > 0 && tasks >= opts.ImagePrefetchMaxTasks {
+			log.Infof("PrefetchHotImages: reach max tasks %d, left to next round", opts.ImagePrefetchMaxTasks)
+			break
+		}
+		candidates := rankImagesForCache(zoneUsage[target.zoneId], cacheUsage[target.cache.Id], opts.ImagePrefetchMinUsage, opts.ImagePrefetchHotImageCount)
+		valid := candidates[:0]
+		for _, c := range candidates {
+			if size, ok := sizes[c.ImageId]; ok {
+				c.Size = size
+				valid = append(valid, c)
+			}
+		}
+		if len(valid) == 0 {
+			continue
+		}
+		cached, err := target.fetchCachedImageStates()
+		if err != nil {
+			log.Errorf("PrefetchHotImages storagecache %s: %v", target.cache.Id, err)
+			continue
+		}
+		prefetch, evict := planStoragecachePrefetch(valid, cached, target.budget, since)
+		for _, imageId := range evict {
+			scimg := StoragecachedimageManager.GetStoragecachedimage(target.cache.Id, imageId)
+			if scimg == nil {
+				continue
+			}
+			if err := scimg.markDeleting(ctx, userCred, false); err != nil {
+				log.Warningf("PrefetchHotImages mark %s deleting on storagecache %s: %v", imageId, target.cache.Id, err)
+				continue
+			}
+			log.Infof("PrefetchHotImages evict cold image %s from storagecache %s", imageId, target.cache.Id)
+			if err := target.cache.StartImageUncacheTask(ctx, userCred, imageId, false, ""); err != nil {
+				log.Errorf("PrefetchHotImages uncache %s: %v", imageId, err)
+			}
+		}
+		for _, imageId := range prefetch {
+			if opts.ImagePrefetchMaxTasks > 0 && tasks >= opts.ImagePrefetchMaxTasks {
+				break
+			}
+			log.Infof("PrefetchHotImages prefetch image %s to storagecache %s", imageId, target.cache.Id)
+			err := target.cache.StartImageCacheTask(ctx, userCred, api.CacheImageInput{ImageId: imageId})
+			if err != nil {
+				log.Errorf("PrefetchHotImages cache %s: %v", imageId, err)
+				continue
+			}
+			tasks++
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInHourWindow(t *testing.T) {
+	cases := []struct {
+		hour, start, end int
+		want             bool
+	}{
+		{2, 1, 6, true},
+		{6, 1, 6, false},
+		{0, 1, 6, false},
+		{23, 22, 4, true},
+		{3, 22, 4, true},
+		{12, 22, 4, false},
+		{15, 3, 3, true},
+	}
+	for _, c := range cases {
+		if got := inHourWindow(c.hour, c.start, c.end); got != c.want {
+			t.Errorf("inHourWindow(%d, %d, %d) = %v, want %v", c.hour, c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func TestRankImagesForCache(t *testing.T) {
+	zone := map[string]int{"img-a": 10, "img-b": 6, "img-c": 2, "img-d": 6}
+	cache := map[string]int{"img-b": 5, "img-c": 2}
+	got := rankImagesForCache(zone, cache, 3, 2)
+	want := []sImagePrefetchCandidate{
+		{ImageId: "img-b", Score: 11},
+		{ImageId: "img-a", Score: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = rankImagesForCache(zone, nil, 3, 0)
+	if len(got) != 3 || got[1].ImageId != "img-b" || got[2].ImageId != "img-d" {
+		t.Errorf("unexpected order %v", got)
+	}
+}
+
+func TestPlanStoragecachePrefetch(t *testing.T) {
+	now := time.Now()
+	coldBefore := now.AddDate(0, 0, -7)
+	old := now.AddDate(0, 0, -30)
+	older := now.AddDate(0, 0, -60)
+
+	cases := []struct {
+		name         string
+		candidates   []sImagePrefetchCandidate
+		cached       []sCachedImageState
+		budget       int64
+		wantPrefetch []string
+		wantEvict    []string
+	}{
+		{
+			name: "fit in budget",
+			candidates: []sImagePrefetchCandidate{
+				{ImageId: "hot1", Size: 30},
+				{ImageId: "hot2", Size: 30},
+			},
+			cached: []sCachedImageState{
+				{ImageId: "hot1", Size: 30, LastUsed: old},
+			},
+			budget:       100,
+			wantPrefetch: []string{"hot2"},
+			wantEvict:    []string{},
+		},
+		{
+			name: "evict oldest cold first",
+			candidates: []sImagePrefetchCandidate{
+				{ImageId: "hot1", Size: 50},
+			},
+			cached: []sCachedImageState{
+				{ImageId: "cold1", Size: 40, LastUsed: old},
+				{ImageId: "cold2", Size: 40, LastUsed: older},
+			},
+			budget:       100,
+			wantPrefetch: []string{"hot1"},
+			wantEvict:    []string{"cold2"},
+		},
+		{
+			name: "keep used, busy and recent images",
+			candidates: []sImagePrefetchCandidate{
+				{ImageId: "hot1", Size: 50},
+				{ImageId: "hot2", Size: 10},
+			},
+			cached: []sCachedImageState{
+				{ImageId: "inuse", Size: 40, LastUsed: older, InUse: true},
+				{ImageId: "busy", Size: 20, LastUsed: older, Busy: true},
+				{ImageId: "recent", Size: 20, LastUsed: now},
+			},
+			budget:       100,
+			wantPrefetch: []string{"hot2"},
+			wantEvict:    []string{},
+		},
+		{
+			name: "larger than budget",
+			candidates: []sImagePrefetchCandidate{
+				{ImageId: "huge", Size: 200},
+				{ImageId: "hot1", Size: 10},
+			},
+			budget:       100,
+			wantPrefetch: []string{"hot1"},
+			wantEvict:    []string{},
+		},
+	}
+	for _, c := range cases {
+		prefetch, evict := planStoragecachePrefetch(c.candidates, c.cached, c.budget, coldBefore)
+		if !reflect.DeepEqual(prefetch, c.wantPrefetch) {
+			t.Errorf("%s: prefetch %v, want %v", c.name, prefetch, c.wantPrefetch)
+		}
+		if !reflect.DeepEqual(evict, c.wantEvict) {
+			t.Errorf("%s: evict %v, want %v", c.name, evict, c.wantEvict)
+		}
+	}
+}
@@ -81,6 +81,12 @@ type SStoragecachedimage struct {
 	LastDownload time.Time `get:"admin"`
 	// 下载引用次数
 	DownloadRefcnt int `get:"admin"`
+	// 缓存文件md5
+	Checksum string `width:"32" charset:"ascii" nullable:"true" list:"admin"`
+	// 最近一次完整性校验结果
+	VerifyStatus string `width:"16" charset:"ascii" nullable:"true" list:"admin"`
+	// 最近一次完整性校验时间
+	VerifiedAt time.Time `nullable:"true" list:"admin"`
 }
 
 func (manager *SStoragecachedimageManager) GetMasterFieldName() string {
@@ -236,6 +242,16 @@ func (self *SStoragecachedimage) Delete(ctx context.Context, userCred mcclient.T
 }
 
 func (self *SStoragecachedimage) Detach(ctx context.Context, userCred mcclient.TokenCredential) error {
+	// 云上镜像删除后共享关系随之失效
+	shares, err := CachedimageShareManager.GetShares(self.CachedimageId, self.StoragecacheId, nil)
+	if err != nil {
+		return errors.Wrap(err, "GetShares")
+	}
+	for i := range shares {
+		if err := shares[i].RealDelete(ctx, userCred); err != nil {
+			return errors.Wrapf(err, "delete share %s", shares[i].Id)
+		}
+	}
 	return db.DetachJoint(ctx, userCred, self)
 }
 
@@ -97,6 +97,9 @@ type SStorage struct {
 	// 是否可以用作系统盘存储
 	// example: true
 	IsSysDiskStore tristate.TriState `default:"true" list:"user" create:"optional" update:"domain"`
+
+	// 镜像预取的缓存预算,单位Mb, 0 表示使用全局默认值, -1 表示不预取
+	ImageCacheBudgetMb int64 `nullable:"false" default:"0" list:"domain" update:"domain"`
 }
 
 func (manager *SStorageManager) GetContextManagers() [][]db.IModelManager {
@@ -112,6 +115,9 @@ func (self *SStorage) ValidateUpdateData(ctx context.Context, userCred mcclient.
 	if err != nil {
 		return input, err
 	}
+	if input.ImageCacheBudgetMb != nil && *input.ImageCacheBudgetMb < -1 {
+		return input, httperrors.NewInputParameterError("invalid image_cache_budget_mb %d", *input.ImageCacheBudgetMb)
+	}
 	input.StorageConf = jsonutils.NewDict()
 	if self.StorageConf != nil {
 		input.StorageConf.Update(jsonutils.Marshal(self.StorageConf))
@@ -18,9 +18,7 @@ import (
 	"context"
 	"fmt"
 	"runtime/debug"
-	"strconv"
-
-	"github.com/serialx/hashring"
+	"sync"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -32,23 +30,20 @@ import (
 
 var (
 	syncAccountWorker *appsrv.SWorkerManager
-	syncWorkers       []*appsrv.SWorkerManager
-	syncWorkerRing    *hashring.HashRing
+	syncRegionWorker  *appsrv.SWorkerManager
+
+	// 正在排队或同步中的区域任务, 避免同一区域被并发同步
+	syncRegionTasks = &sync.Map{}
 )
 
 func InitSyncWorkers(count int) {
-	syncWorkers = make([]*appsrv.SWorkerManager, count)
-	syncWorkerIndexes := make([]string, count)
-	for i := range syncWorkers {
-		syncWorkers[i] = appsrv.NewWorkerManager(
-			fmt.Sprintf("syncWorkerManager-%d", i+1),
-			1,
-			2048,
-			true,
-		)
-		syncWorkerIndexes[i] = strconv.Itoa(i)
-	}
-	syncWorkerRing = hashring.New(syncWorkerIndexes)
+	// 各区域在有界的工作池中并发同步, 避免单个慢区域阻塞其他区域
+	syncRegionWorker = appsrv.NewWorkerManager(
+		"syncWorkerManager",
+		count,
+		2048,
+		true,
+	)
 	syncAccountWorker = appsrv.NewWorkerManager(
 		"cloudAccountProbeWorkerManager",
 		1,
@@ -70,15 +65,20 @@ func (t *resSyncTask) Dump() string {
 	return fmt.Sprintf("key: %s", t.key)
 }
 
-func RunSyncCloudproviderRegionTask(ctx context.Context, key string, syncFunc func()) {
-	nodeIdxStr, _ := syncWorkerRing.GetNode(key)
-	nodeIdx, _ := strconv.Atoi(nodeIdxStr)
+func RunSyncCloudproviderRegionTask(ctx context.Context, key string, syncFunc func()) bool {
+	if _, loaded := syncRegionTasks.LoadOrStore(key, true); loaded {
+		log.Debugf("sync task %s is already queued or running, skip", key)
+		return false
+	}
 	task := resSyncTask{
-		syncFunc: syncFunc,
-		key:      key,
+		syncFunc: func() {
+			defer syncRegionTasks.Delete(key)
+			syncFunc()
+		},
+		key: key,
 	}
-	log.Debugf("run sync task at %d len %d", nodeIdx, len(syncWorkers))
-	syncWorkers[nodeIdx].Run(&task, nil, func(err error) {
+	log.Debugf("run sync task %s", key)
+	ok := syncRegionWorker.Run(&task, nil, func(err error) {
 		data := jsonutils.NewDict()
 		data.Add(jsonutils.NewString("SyncCloudproviderRegion"), "task_name")
 		data.Add(jsonutils.NewString(key), "task_id")
@@ -86,6 +86,10 @@ func RunSyncCloudproviderRegionTask(ctx context.Context, key string, syncFunc fu
 		data.Add(jsonutils.NewString(err.Error()), "error")
 		notifyclient.SystemExceptionNotify(context.TODO(), api.ActionSystemPanic, api.TOPIC_RESOURCE_TASK, data)
 	})
+	if !ok {
+		syncRegionTasks.Delete(key)
+	}
+	return ok
 }
 
 func RunSyncCloudAccountTask(ctx context.Context, probeFunc func()) {
@@ -0,0 +1,436 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+const tagBackfillBatchSize = 50
+
+// 批量补齐存量云资源标签的任务, 按资源Id顺序处理并记录进度, 中断后可续跑
+type STagBackfillJobManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var TagBackfillJobManager *STagBackfillJobManager
+
+func init() {
+	TagBackfillJobManager = &STagBackfillJobManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			STagBackfillJob{},
+			"tag_backfill_jobs_tbl",
+			"tag_backfill_job",
+			"tag_backfill_jobs",
+		),
+	}
+	TagBackfillJobManager.SetVirtualObject(TagBackfillJobManager)
+}
+
+type STagBackfillJob struct {
+	db.SStatusStandaloneResourceBase
+
+	CloudaccountId string `width:"36" charset:"ascii" nullable:"false" list:"admin" create:"admin_required" index:"true"`
+	// 逗号分隔的资源类型
+	ResourceTypes string `width:"128" charset:"ascii" nullable:"false" list:"admin"`
+
+	Rule *api.TagBackfillRule `length:"text" nullable:"false" list:"admin" create:"admin_required"`
+	// 每秒调用云上接口次数
+	RateLimit int `nullable:"false" default:"5" list:"admin" create:"admin_optional"`
+
+	Cursor *api.TagBackfillCursor `length:"text" nullable:"true" list:"admin"`
+	Report *api.TagBackfillReport `length:"text" nullable:"true" list:"admin"`
+
+	StartedAt  time.Time `nullable:"true" list:"admin"`
+	FinishedAt time.Time `nullable:"true" list:"admin"`
+}
+
+func (manager *STagBackfillJobManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.TagBackfillJobCreateInput) (*jsonutils.JSONDict, error) {
+	var err error
+	input.StatusStandaloneResourceCreateInput, err = manager.SStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusStandaloneResourceCreateInput)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.CloudaccountId) == 0 {
+		return nil, httperrors.NewMissingParameterError("cloudaccount_id")
+	}
+	accountObj, err := CloudaccountManager.FetchByIdOrName(userCred, input.CloudaccountId)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), input.CloudaccountId)
+	}
+	input.CloudaccountId = accountObj.GetId()
+	if len(input.ResourceTypes) == 0 {
+		input.ResourceTypes = api.TAG_BACKFILL_RESOURCE_TYPES
+	}
+	for _, resType := range input.ResourceTypes {
+		if !utils.IsInStringArray(resType, api.TAG_BACKFILL_RESOURCE_TYPES) {
+			return nil, httperrors.NewInputParameterError("invalid resource type %q, support %v", resType, api.TAG_BACKFILL_RESOURCE_TYPES)
+		}
+	}
+	if input.Rule.IsZero() {
+		return nil, httperrors.NewMissingParameterError("rule.tags")
+	}
+	for k := range input.Rule.Tags {
+		if len(k) == 0 {
+			return nil, httperrors.NewInputParameterError("empty tag key")
+		}
+	}
+	for alias, key := range input.Rule.KeyAliases {
+		if len(alias) == 0 || len(key) == 0 {
+			return nil, httperrors.NewInputParameterError("empty key alias %q => %q", alias, key)
+		}
+	}
+	if input.RateLimit <= 0 {
+		input.RateLimit = api.TAG_BACKFILL_DEFAULT_RATE_LIMIT
+	}
+	input.Status = api.TAG_BACKFILL_STATUS_READY
+	data := input.JSON(input)
+	data.Set("resource_types", jsonutils.NewString(strings.Join(input.ResourceTypes, ",")))
+	return data, nil
+}
+
+func (self *STagBackfillJob) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	err := self.StartBackfillTask(ctx, userCred, "")
+	if err != nil {
+		log.Errorf("StartBackfillTask for %s error: %v", self.Name, err)
+	}
+}
+
+func (self *STagBackfillJob) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if self.Status == api.TAG_BACKFILL_STATUS_RUNNING {
+		return httperrors.NewInvalidStatusError("tag backfill job %s is running, pause it first", self.Name)
+	}
+	return self.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *STagBackfillJob) GetResourceTypes() []string {
+	if len(self.ResourceTypes) == 0 {
+		return api.TAG_BACKFILL_RESOURCE_TYPES
+	}
+	return strings.Split(self.ResourceTypes, ",")
+}
+
+func (self *STagBackfillJob) StartBackfillTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	_, err := db.Update(self, func() error {
+		if self.StartedAt.IsZero() {
+			self.StartedAt = time.Now().UTC()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "db.Update")
+	}
+	self.SetStatus(userCred, api.TAG_BACKFILL_STATUS_RUNNING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "TagBackfillTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		self.SetStatus(userCred, api.TAG_BACKFILL_STATUS_FAILED, err.Error())
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// 继续执行暂停或失败的任务, 已完成的任务从头重新检查
+func (self *STagBackfillJob) PerformStart(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(self.Status, []string{api.TAG_BACKFILL_STATUS_READY, api.TAG_BACKFILL_STATUS_PAUSED, api.TAG_BACKFILL_STATUS_FAILED, api.TAG_BACKFILL_STATUS_COMPLETED}) {
+		return nil, httperrors.NewInvalidStatusError("cannot start tag backfill job in status %s", self.Status)
+	}
+	if self.Status == api.TAG_BACKFILL_STATUS_COMPLETED {
+		_, err := db.Update(self, func() error {
+			self.Cursor = &api.TagBackfillCursor{}
+			self.Report = &api.TagBackfillReport{}
+			self.StartedAt = time.Time{}
+			self.FinishedAt = time.Time{}
+			return nil
+		})
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	}
+	return nil, self.StartBackfillTask(ctx, userCred, "")
+}
+
+// 暂停任务, 当前批次处理完后退出
+func (self *STagBackfillJob) PerformPause(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Status != api.TAG_BACKFILL_STATUS_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("tag backfill job %s is not running", self.Name)
+	}
+	return nil, self.SetStatus(userCred, api.TAG_BACKFILL_STATUS_PAUSED, "pause requested")
+}
+
+func (self *STagBackfillJob) isPaused() bool {
+	obj, err := TagBackfillJobManager.FetchById(self.Id)
+	if err != nil {
+		return false
+	}
+	return obj.(*STagBackfillJob).Status == api.TAG_BACKFILL_STATUS_PAUSED
+}
+
+type iTagBackfillTarget interface {
+	db.IModel
+	GetAllUserMetadata() (map[string]string, error)
+}
+
+func fetchTagBackfillTargets(resType string, providerIds []string, cursor string) ([]iTagBackfillTarget, error) {
+	ret := []iTagBackfillTarget{}
+	switch resType {
+	case api.TAG_BACKFILL_RESOURCE_SERVER:
+		hosts := HostManager.Query("id").In("manager_id", providerIds).SubQuery()
+		q := GuestManager.Query().In("host_id", hosts).IsFalse("pending_deleted").GT("id", cursor).Asc("id").Limit(tagBackfillBatchSize)
+		guests := []SGuest{}
+		err := db.FetchModelObjects(GuestManager, q, &guests)
+		if err != nil {
+			return nil, err
+		}
+		for i := range guests {
+			ret = append(ret, &guests[i])
+		}
+	case api.TAG_BACKFILL_RESOURCE_DISK:
+		storages := StorageManager.Query("id").In("manager_id", providerIds).SubQuery()
+		q := DiskManager.Query().In("storage_id", storages).IsFalse("pending_deleted").GT("id", cursor).Asc("id").Limit(tagBackfillBatchSize)
+		disks := []SDisk{}
+		err := db.FetchModelObjects(DiskManager, q, &disks)
+		if err != nil {
+			return nil, err
+		}
+		for i := range disks {
+			ret = append(ret, &disks[i])
+		}
+	case api.TAG_BACKFILL_RESOURCE_EIP:
+		q := ElasticipManager.Query().In("manager_id", providerIds).IsFalse("pending_deleted").GT("id", cursor).Asc("id").Limit(tagBackfillBatchSize)
+		eips := []SElasticip{}
+		err := db.FetchModelObjects(ElasticipManager, q, &eips)
+		if err != nil {
+			return nil, err
+		}
+		for i := range eips {
+			ret = append(ret, &eips[i])
+		}
+	case api.TAG_BACKFILL_RESOURCE_LOADBALANCER:
+		q := LoadbalancerManager.Query().In("manager_id", providerIds).IsFalse("pending_deleted").GT("id", cursor).Asc("id").Limit(tagBackfillBatchSize)
+		lbs := []SLoadbalancer{}
+		err := db.FetchModelObjects(LoadbalancerManager, q, &lbs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range lbs {
+			ret = append(ret, &lbs[i])
+		}
+	default:
+		return nil, errors.Wrapf(errors.ErrNotSupported, "resource type %s", resType)
+	}
+	return ret, nil
+}
+
+func getTagBackfillICloudResource(ctx context.Context, obj iTagBackfillTarget) (cloudprovider.ICloudResource, error) {
+	switch res := obj.(type) {
+	case *SGuest:
+		return res.GetIVM(ctx)
+	case *SDisk:
+		return res.GetIDisk(ctx)
+	case *SElasticip:
+		return res.GetIEip(ctx)
+	case *SLoadbalancer:
+		return res.GetILoadbalancer(ctx)
+	}
+	return nil, errors.Wrapf(errors.ErrNotSupported, "%s", obj.KeywordPlural())
+}
+
+// 规范化单个资源的标签, 先更新云上标签再同步本地标签, 避免下次同步时被云上覆盖
+func (self *STagBackfillJob) backfillResource(ctx context.Context, userCred mcclient.TokenCredential, limiter *rate.Limiter, obj iTagBackfillTarget) (bool, error) {
+	tags, err := obj.GetAllUserMetadata()
+	if err != nil {
+		return false, errors.Wrapf(err, "GetAllUserMetadata")
+	}
+	newTags, changed := self.Rule.Normalize(tags)
+	if !changed {
+		return false, nil
+	}
+	err = limiter.Wait(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "limiter.Wait")
+	}
+	iRes, err := getTagBackfillICloudResource(ctx, obj)
+	if err != nil {
+		return false, errors.Wrapf(err, "get cloud resource")
+	}
+	err = iRes.SetTags(newTags, true)
+	if err != nil {
+		return false, errors.Wrapf(err, "SetTags")
+	}
+	values := map[string]interface{}{}
+	for k, v := range newTags {
+		if old, ok := tags[k]; !ok || old != v {
+			values[db.USER_TAG_PREFIX+k] = v
+		}
+	}
+	for k := range tags {
+		if _, ok := newTags[k]; !ok {
+			values[db.USER_TAG_PREFIX+k] = "none"
+		}
+	}
+	return true, db.Metadata.SetValuesWithLog(ctx, obj, values, userCred)
+}
+
+// Backfill 执行标签补齐, 返回 true 表示任务被暂停
+func (self *STagBackfillJob) Backfill(ctx context.Context, userCred mcclient.TokenCredential) (bool, error) {
+	accountObj, err := CloudaccountManager.FetchById(self.CloudaccountId)
+	if err != nil {
+		return false, errors.Wrapf(err, "FetchById(%s)", self.CloudaccountId)
+	}
+	providers := accountObj.(*SCloudaccount).GetCloudproviders()
+	providerIds := []string{}
+	for i := range providers {
+		providerIds = append(providerIds, providers[i].Id)
+	}
+	if self.Rule == nil {
+		return false, errors.Errorf("empty rule")
+	}
+	cursor := api.TagBackfillCursor{}
+	if self.Cursor != nil {
+		for k, v := range *self.Cursor {
+			cursor[k] = v
+		}
+	}
+	report := api.TagBackfillReport{}
+	if self.Report != nil {
+		report = *self.Report
+	}
+	limiter := rate.NewLimiter(rate.Limit(self.RateLimit), 1)
+	for _, resType := range self.GetResourceTypes() {
+		for {
+			if self.isPaused() {
+				return true, nil
+			}
+			objs, err := fetchTagBackfillTargets(resType, providerIds, cursor[resType])
+			if err != nil {
+				return false, errors.Wrapf(err, "fetch %s", resType)
+			}
+			if len(objs) == 0 {
+				break
+			}
+			for _, obj := range objs {
+				stat := report.Stat(resType)
+				stat.Total += 1
+				updated, err := self.backfillResource(ctx, userCred, limiter, obj)
+				if err != nil {
+					report.AddFailure(api.TagBackfillFailure{
+						ResourceType: resType,
+						Id:           obj.GetId(),
+						Name:         obj.GetName(),
+						Error:        err.Error(),
+					})
+				} else if updated {
+					stat.Updated += 1
+				} else {
+					stat.Compliant += 1
+				}
+				cursor[resType] = obj.GetId()
+			}
+			_, err = db.Update(self, func() error {
+				self.Cursor = &cursor
+				self.Report = &report
+				return nil
+			})
+			if err != nil {
+				return false, errors.Wrapf(err, "save progress")
+			}
+		}
+	}
+	_, err = db.Update(self, func() error {
+		self.FinishedAt = time.Now().UTC()
+		return nil
+	})
+	return false, err
+}
+
+func (manager *STagBackfillJobManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.TagBackfillJobListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.CloudaccountId) > 0 {
+		account, err := CloudaccountManager.FetchByIdOrName(userCred, query.CloudaccountId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(CloudaccountManager.Keyword(), query.CloudaccountId)
+		}
+		q = q.Equals("cloudaccount_id", account.GetId())
+	}
+	return q, nil
+}
+
+func (manager *STagBackfillJobManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.TagBackfillJobListInput,
+) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+}
+
+func (manager *STagBackfillJobManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (manager *STagBackfillJobManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.TagBackfillJobDetails {
+	rows := make([]api.TagBackfillJobDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	accountIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		accountIds[i] = objs[i].(*STagBackfillJob).CloudaccountId
+	}
+	accounts := make(map[string]SCloudaccount)
+	if err := db.FetchStandaloneObjectsByIds(CloudaccountManager, accountIds, accounts); err != nil {
+		log.Errorf("FetchStandaloneObjectsByIds cloudaccounts fail %s", err)
+		return rows
+	}
+	for i := range rows {
+		if account, ok := accounts[accountIds[i]]; ok {
+			rows[i].Cloudaccount = account.Name
+		}
+	}
+	return rows
+}
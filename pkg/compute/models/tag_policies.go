@@ -0,0 +1,307 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type STagPolicyManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+}
+
+var TagPolicyManager *STagPolicyManager
+
+func init() {
+	TagPolicyManager = &STagPolicyManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			STagPolicy{},
+			"tag_policies_tbl",
+			"tag_policy",
+			"tag_policies",
+		),
+	}
+	TagPolicyManager.SetVirtualObject(TagPolicyManager)
+}
+
+// 标签策略, 在资源创建时为虚拟机及其磁盘、EIP、安全组补齐项目/域级别的强制标签
+type STagPolicy struct {
+	db.SEnabledStatusInfrasResourceBase
+
+	// 生效的项目, 为空时对整个域生效
+	ProjectId string `width:"128" charset:"ascii" nullable:"true" index:"true" list:"domain" create:"domain_optional"`
+
+	Tags *api.TagPolicyTags `list:"domain" update:"domain" create:"required"`
+
+	// 是否强制覆盖同名标签
+	Enforce tristate.TriState `default:"false" list:"domain" update:"domain" create:"domain_optional"`
+}
+
+func (manager *STagPolicyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.TagPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ProjectId) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrName(ctx, query.ProjectId)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("project", query.ProjectId)
+		}
+		q = q.Equals("project_id", tenant.Id)
+	}
+	return q, nil
+}
+
+func validateTagPolicyTags(tags api.TagPolicyTags) error {
+	if len(tags) == 0 {
+		return httperrors.NewMissingParameterError("tags")
+	}
+	for k := range tags {
+		if len(k) == 0 {
+			return httperrors.NewInputParameterError("empty tag key")
+		}
+	}
+	return nil
+}
+
+func (manager *STagPolicyManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.TagPolicyCreateInput,
+) (api.TagPolicyCreateInput, error) {
+	err := validateTagPolicyTags(input.Tags)
+	if err != nil {
+		return input, err
+	}
+	if len(input.ProjectId) > 0 {
+		projectInput := apis.ProjectizedResourceInput{ProjectId: input.ProjectId}
+		tenant, _, err := db.ValidateProjectizedResourceInput(ctx, projectInput)
+		if err != nil {
+			return input, err
+		}
+		if tenant.DomainId != ownerId.GetProjectDomainId() {
+			return input, httperrors.NewInputParameterError("project %s not in domain %s", tenant.Name, ownerId.GetProjectDomain())
+		}
+		input.ProjectId = tenant.Id
+	}
+	input.SetEnabled()
+	input.Status = api.TAG_POLICY_STATUS_AVAILABLE
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *STagPolicy) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.TagPolicyUpdateInput) (api.TagPolicyUpdateInput, error) {
+	if input.Tags != nil {
+		err := validateTagPolicyTags(input.Tags)
+		if err != nil {
+			return input, err
+		}
+	}
+	var err error
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = self.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	return input, err
+}
+
+func (manager *STagPolicyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.TagPolicyDetails {
+	rows := make([]api.TagPolicyDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	projectIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.TagPolicyDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+		}
+		projectIds[i] = objs[i].(*STagPolicy).ProjectId
+	}
+	projects := db.DefaultProjectsFetcher(ctx, projectIds, false)
+	for i := range rows {
+		if project, ok := projects[projectIds[i]]; ok {
+			rows[i].Project = project.Name
+		}
+	}
+	return rows
+}
+
+func (manager *STagPolicyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *STagPolicyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.TagPolicyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+// 获取项目适用的强制标签, 项目级策略优先于域级策略
+func (manager *STagPolicyManager) GetMandatoryTags(domainId, projectId string) (api.SMandatoryTags, error) {
+	ret := api.SMandatoryTags{
+		Defaults: map[string]string{},
+		Enforced: map[string]string{},
+	}
+	q := manager.Query().Equals("domain_id", domainId).IsTrue("enabled")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsNullOrEmpty(q.Field("project_id")),
+		sqlchemy.Equals(q.Field("project_id"), projectId),
+	))
+	policies := []STagPolicy{}
+	err := db.FetchModelObjects(manager, q, &policies)
+	if err != nil {
+		return ret, errors.Wrapf(err, "db.FetchModelObjects")
+	}
+	// 先合并域级策略, 再由项目级策略覆盖
+	for _, projectLevel := range []bool{false, true} {
+		for i := range policies {
+			if (len(policies[i].ProjectId) > 0) != projectLevel || policies[i].Tags == nil {
+				continue
+			}
+			for k, v := range *policies[i].Tags {
+				if policies[i].Enforce.IsTrue() {
+					ret.Enforced[k] = v
+					delete(ret.Defaults, k)
+				} else {
+					ret.Defaults[k] = v
+					delete(ret.Enforced, k)
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+type iTagPolicyTarget interface {
+	db.IModel
+	GetAllUserMetadata() (map[string]string, error)
+}
+
+func applyMandatoryTags(ctx context.Context, userCred mcclient.TokenCredential, obj iTagPolicyTarget, mandatory api.SMandatoryTags) error {
+	tags, err := obj.GetAllUserMetadata()
+	if err != nil {
+		return errors.Wrapf(err, "GetAllUserMetadata")
+	}
+	merged := mandatory.Merge(tags)
+	changed := map[string]interface{}{}
+	for k, v := range merged {
+		if old, ok := tags[k]; !ok || old != v {
+			changed[db.USER_TAG_PREFIX+k] = v
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return db.Metadata.SetValuesWithLog(ctx, obj, changed, userCred)
+}
+
+// 将强制标签写入虚拟机及其磁盘、EIP和同项目安全组的本地标签, 返回虚拟机合并后的标签
+func (guest *SGuest) ApplyTagPolicy(ctx context.Context, userCred mcclient.TokenCredential) (map[string]string, error) {
+	mandatory, err := TagPolicyManager.GetMandatoryTags(guest.DomainId, guest.ProjectId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetMandatoryTags")
+	}
+	if mandatory.IsZero() {
+		return guest.GetAllUserMetadata()
+	}
+	err = applyMandatoryTags(ctx, userCred, guest, mandatory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "apply to guest")
+	}
+	disks, err := guest.GetDisks()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetDisks")
+	}
+	for i := range disks {
+		err = applyMandatoryTags(ctx, userCred, &disks[i], mandatory)
+		if err != nil {
+			log.Errorf("apply tag policy to disk %s error: %v", disks[i].Name, err)
+		}
+	}
+	eip, _ := guest.GetElasticIp()
+	if eip != nil {
+		err = applyMandatoryTags(ctx, userCred, eip, mandatory)
+		if err != nil {
+			log.Errorf("apply tag policy to eip %s error: %v", eip.Name, err)
+		}
+	}
+	secgroups, err := guest.GetSecgroups()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetSecgroups")
+	}
+	for i := range secgroups {
+		// 共享安全组可能被多个项目使用, 仅处理与虚拟机同项目的安全组
+		if secgroups[i].ProjectId != guest.ProjectId {
+			continue
+		}
+		err = applyMandatoryTags(ctx, userCred, &secgroups[i], mandatory)
+		if err != nil {
+			log.Errorf("apply tag policy to secgroup %s error: %v", secgroups[i].Name, err)
+		}
+	}
+	return guest.GetAllUserMetadata()
+}
+
+// 获取虚拟机经强制标签策略合并后的标签, 用于远端标签同步
+func (guest *SGuest) GetTagPolicyMergedTags() (map[string]string, error) {
+	tags, err := guest.GetAllUserMetadata()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetAllUserMetadata")
+	}
+	mandatory, err := TagPolicyManager.GetMandatoryTags(guest.DomainId, guest.ProjectId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetMandatoryTags")
+	}
+	return mandatory.Merge(tags), nil
+}
@@ -28,6 +28,7 @@ import (
 	"yunion.io/x/pkg/tristate"
 	"yunion.io/x/pkg/util/compare"
 	"yunion.io/x/pkg/util/netutils"
+	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
@@ -92,6 +93,14 @@ type SVpc struct {
 
 	// Can it be connected directly
 	Direct bool `default:"false" list:"user" update:"user"`
+
+	// VPC内网DNS域名后缀, 虚机可通过 <主机名>.<后缀> 相互解析
+	// example: vpc1.internal
+	DnsDomain string `width:"128" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+
+	// VPC内虚机网卡MTU, 0 表示按 underlay MTU 减去封装开销自动计算
+	// example: 1400
+	Mtu int `nullable:"false" default:"0" list:"user" update:"domain" create:"domain_optional"`
 }
 
 func (manager *SVpcManager) GetContextManagers() [][]db.IModelManager {
@@ -208,12 +217,35 @@ func (self *SVpc) ValidateUpdateData(ctx context.Context, userCred mcclient.Toke
 				input.ExternalAccessMode, api.VPC_EXTERNAL_ACCESS_MODES)
 		}
 	}
+	if input.Mtu != nil {
+		if err := validateVpcMtu(*input.Mtu); err != nil {
+			return input, err
+		}
+	}
+	if input.DnsDomain != nil && len(*input.DnsDomain) > 0 {
+		if !regutils.MatchDomainName(*input.DnsDomain) {
+			return input, httperrors.NewInputParameterError("invalid dns_domain %q", *input.DnsDomain)
+		}
+	}
 	if _, err := self.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput); err != nil {
 		return input, err
 	}
 	return input, nil
 }
 
+func validateVpcMtu(mtu int) error {
+	if mtu == 0 {
+		return nil
+	}
+	if mtu < api.VPC_MTU_MIN || mtu > api.VPC_MTU_MAX {
+		return httperrors.NewOutOfRangeError("mtu %d out of range [%d, %d]", mtu, api.VPC_MTU_MIN, api.VPC_MTU_MAX)
+	}
+	if maxMtu := api.VpcOvnMtu(options.Options.OvnUnderlayMtu, 0); mtu > maxMtu {
+		return httperrors.NewOutOfRangeError("mtu %d exceeds %d allowed by ovn underlay mtu %d", mtu, maxMtu, options.Options.OvnUnderlayMtu)
+	}
+	return nil
+}
+
 func (self *SVpc) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
 	if self.Id == api.DEFAULT_VPC_ID {
 		return httperrors.NewProtectedResourceError("not allow to delete default vpc")
@@ -787,6 +819,13 @@ func (manager *SVpcManager) ValidateCreateData(
 			input.Status, api.VPC_EXTERNAL_ACCESS_MODES)
 	}
 
+	if len(input.DnsDomain) > 0 && !regutils.MatchDomainName(input.DnsDomain) {
+		return input, httperrors.NewInputParameterError("invalid dns_domain %q", input.DnsDomain)
+	}
+	if err := validateVpcMtu(input.Mtu); err != nil {
+		return input, err
+	}
+
 	cidrBlock := input.CidrBlock
 	if len(cidrBlock) > 0 {
 		blocks := strings.Split(cidrBlock, ",")
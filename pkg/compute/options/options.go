@@ -53,6 +53,30 @@ type ComputeOptions struct {
 	ImageCacheStoragePolicy string `default:"least_used" choices:"best_fit|least_used" help:"Policy to choose storage for image cache, best_fit or least_used"`
 	MetricsRetentionDays    int32  `default:"30" help:"Retention days for monitoring metrics in influxdb"`
 
+	CacheImageFetchChunkSizeMb  int      `default:"64" help:"Chunk size in MB when fetching image from image service before uploading to cloud provider"`
+	CacheImageFetchParallel     int      `default:"4" help:"Parallel chunks when fetching image from image service before uploading to cloud provider"`
+	CacheImageFetchRetry        int      `default:"5" help:"Retry times of each failed chunk when fetching image from image service"`
+	CacheImageUploadBandwidthMb []string `help:"Bandwidth limit in MB/s when uploading image to cloud provider, e.g. Aliyun:100, default no limit"`
+
+	EnableImagePrefetch        bool `default:"false" help:"Prefetch hot images to local storagecaches of hosts in off-peak window"`
+	ImagePrefetchStartHour     int  `default:"1" help:"Start hour of off-peak window for image prefetch"`
+	ImagePrefetchEndHour       int  `default:"6" help:"End hour (exclusive) of off-peak window for image prefetch, may wrap around midnight"`
+	ImagePrefetchUsageDays     int  `default:"7" help:"Days of disk creation history used to find hot images"`
+	ImagePrefetchMinUsage      int  `default:"3" help:"Minimal usage count in zone for an image to be prefetched"`
+	ImagePrefetchHotImageCount int  `default:"5" help:"Max number of hot images kept in each storagecache"`
+	ImagePrefetchCacheBudgetGb int  `default:"100" help:"Default image cache budget of local storage in GB, overridden by image_cache_budget_mb of storage"`
+	ImagePrefetchMaxTasks      int  `default:"20" help:"Max number of image cache tasks started in one prefetch round"`
+
+	IntegrityScrubIntervalDays int  `default:"7" help:"How often to re-verify checksums of cached images and disk backups, in days"`
+	IntegrityScrubBatchSize    int  `default:"20" help:"Max number of cached images and disk backups to verify in one scrub round"`
+	IntegrityScrubAutoRepair   bool `default:"false" help:"Re-fetch corrupted cached images from image service automatically"`
+
+	NetworkIsolationAuditIntervalDays   int    `default:"1" help:"How often to audit cross-project reachability and internet exposed ports of guests, in days, 0 to disable"`
+	NetworkIsolationAuditNotifySeverity string `help:"Send network isolation audit findings not lower than this severity (high, medium or low) as events, empty to disable"`
+
+	ConfigHistorySnapshotIntervalHours int `default:"24" help:"How often to snapshot configuration of guests, secgroups and loadbalancer listeners into config history, in hours, 0 to disable"`
+	ConfigHistoryRetentionDays         int `default:"90" help:"Days to keep config history versions, the latest version of each resource is always kept, 0 to keep forever"`
+
 	DefaultBandwidth int `default:"1000" help:"Default bandwidth"`
 	DefaultMtu       int `default:"1500" help:"Default network mtu"`
 	OvnUnderlayMtu   int `help:"mtu of ovn underlay network" default:"1500"`
@@ -114,6 +138,10 @@ type ComputeOptions struct {
 	SyncSkusDay  int `default:"1" help:"Days auto sync skus data, default 1 day"`
 	SyncSkusHour int `default:"3" help:"What hour start sync skus, default 03:00"`
 
+	// spot price sync
+	SpotPriceHistoryDays int     `default:"30" help:"Days of spot price history to keep, default 30 days"`
+	SpotBidPriceMarkup   float64 `default:"0.1" help:"Markup over the highest spot price of last 7 days used as default spot bid price"`
+
 	ConvertHypervisorDefaultTemplate string `help:"Kvm baremetal convert option"`
 	ConvertEsxiDefaultTemplate       string `help:"ESXI baremetal convert option"`
 	ConvertKubeletDockerVolumeSize   string `default:"256g" help:"Docker volume size"`
@@ -126,6 +154,9 @@ type ComputeOptions struct {
 	// 创建虚拟机失败后, 自动使用其他相同配置套餐
 	EnableAutoSwitchServerSku bool `help:"If the vm creation fails, use the same configuration server sku"`
 
+	// 创建虚拟机失败后, 不自动清理已在云上创建的虚拟机、EIP及磁盘
+	DisableCleanupOnCreateFailed bool `help:"Do not clean up partially created cloud vm, eip and disks when vm creation fails"`
+
 	DefaultImageCacheDir string `default:"image_cache"`
 
 	SnapshotCreateDiskProtocol string `help:"Snapshot create disk protocol" choices:"url|fuse" default:"fuse"`
@@ -135,12 +166,14 @@ type ComputeOptions struct {
 
 	MinimalIpAddrReusedIntervalSeconds int `help:"Minimal seconds when a release IP address can be reallocate" default:"30"`
 
-	CloudSyncWorkerCount         int `help:"how many current synchronization threads" default:"5"`
+	CloudSyncWorkerCount         int `help:"how many regions are synchronized concurrently" default:"5"`
 	CloudProviderSyncWorkerCount int `help:"how many current providers synchronize their regions, practically no limit" default:"10"`
 	CloudAutoSyncIntervalSeconds int `help:"frequency to check auto sync tasks" default:"30"`
 	DefaultSyncIntervalSeconds   int `help:"minimal synchronization interval, default 15 minutes" default:"900"`
 	MaxCloudAccountErrorCount    int `help:"maximal consecutive error count allow for a cloud account" default:"5"`
 
+	CloudSyncProfileCheckIntervalSeconds int `help:"frequency to check per cloudaccount sync profiles, 0 to disable" default:"60"`
+
 	NameSyncResources []string `help:"resources that need synchronization of name"`
 
 	SyncPurgeRemovedResources []string `help:"resources that shoud be purged immediately if found removed" default:"server"`
@@ -164,6 +197,16 @@ type ComputeOptions struct {
 	SyncExtDiskSnapshotIntervalMinutes int  `help:"sync snapshot for external disk" default:"20"`
 	AutoReconcileBackupServers         bool `help:"auto reconcile backup servers" default:"false"`
 
+	DiskBackupMaxIncrementalChain int `help:"max incremental disk backups after a full backup" default:"7"`
+
+	FailoverVipHealthCheckIntervalSeconds int `help:"interval of health check for auto failover vips" default:"10"`
+
+	NetworkProbeMaxRegionPeers int `help:"max peer hosts in the same region each host probes, 0 means no limit" default:"16"`
+
+	HaSiteName                      string `help:"site name of this region service in an active-standby deployment across sites, empty to disable ha mode"`
+	HaStandbyReadOnly               bool   `help:"reject write requests while this region service is standby" default:"true"`
+	HaInterruptedTaskTimeoutMinutes int    `help:"on promotion, fail unfinished tasks not updated for this many minutes, 0 to disable" default:"30"`
+
 	SCapabilityOptions
 	SASControllerOptions
 	common_options.CommonOptions
@@ -192,6 +235,12 @@ type ComputeOptions struct {
 
 	EnableMonitorAgent bool `help:"enable public cloud vm monitor agent" default:"false"`
 
+	MonitorAgentInstallUrl              string `help:"url of monitor agent install script, fetched by cloud-init when monitor agent is enabled"`
+	MonitorAgentHeartbeatTimeoutMinutes int    `help:"monitor agent is considered offline without heartbeat in this minutes" default:"10"`
+
+	DeployVerifyTimeoutSeconds  int `help:"timeout of post deploy verification of guest" default:"600"`
+	DeployVerifyIntervalSeconds int `help:"interval between post deploy verification retries" default:"15"`
+
 	EnableTlsMigration bool `help:"Enable TLS migration" default:"false"`
 
 	AliyunResourceGroups []string `help:"Only sync indicate resource group resource"`
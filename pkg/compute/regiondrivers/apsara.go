@@ -15,8 +15,13 @@
 package regiondrivers
 
 import (
+	"context"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
 )
 
 type SApsaraRegionDriver struct {
@@ -31,3 +36,19 @@ func init() {
 func (self *SApsaraRegionDriver) GetProvider() string {
 	return api.CLOUD_PROVIDER_APSARA
 }
+
+func (self *SApsaraRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerCreateInput) (*api.LoadbalancerCreateInput, error) {
+	err := validateProviderCapability(input.ManagerId, input.CloudregionId, cloudprovider.CLOUD_CAPABILITY_LOADBALANCER)
+	if err != nil {
+		return nil, err
+	}
+	return self.SAliyunRegionDriver.ValidateCreateLoadbalancerData(ctx, userCred, ownerId, input)
+}
+
+func (self *SApsaraRegionDriver) ValidateCreateEipData(ctx context.Context, userCred mcclient.TokenCredential, input *api.SElasticipCreateInput) error {
+	err := validateProviderCapability(input.ManagerId, input.CloudregionId, cloudprovider.CLOUD_CAPABILITY_EIP)
+	if err != nil {
+		return err
+	}
+	return self.SAliyunRegionDriver.ValidateCreateEipData(ctx, userCred, input)
+}
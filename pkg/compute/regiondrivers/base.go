@@ -145,6 +145,10 @@ func (self *SBaseRegionDriver) RequestSyncLoadbalancerListener(ctx context.Conte
 	return fmt.Errorf("Not Implement RequestSyncLoadbalancerListener")
 }
 
+func (self *SBaseRegionDriver) RequestSetLoadbalancerListenerAccessLog(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestSetLoadbalancerListenerAccessLog")
+}
+
 func (self *SBaseRegionDriver) ValidateCreateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerRuleCreateInput) (*api.LoadbalancerListenerRuleCreateInput, error) {
 	return input, errors.Wrapf(cloudprovider.ErrNotImplemented, "ValidateCreateLoadbalancerListenerRuleData")
 }
@@ -471,6 +475,14 @@ func (self *SBaseRegionDriver) RequestAssociateEip(ctx context.Context, userCred
 	return httperrors.NewNotImplementedError("RequestAssociateEip")
 }
 
+func (self *SBaseRegionDriver) ValidateChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, bandwidth int) error {
+	return nil
+}
+
+func (self *SBaseRegionDriver) RequestChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, bandwidth int, task taskman.ITask) error {
+	return httperrors.NewNotImplementedError("RequestChangeEipBandwidth")
+}
+
 func (self *SBaseRegionDriver) RequestSyncAccessGroup(ctx context.Context, userCred mcclient.TokenCredential, fs *models.SFileSystem, mt *models.SMountTarget, ag *models.SAccessGroup, task taskman.ITask) error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestSyncAccessGroup")
 }
@@ -491,6 +503,10 @@ func (self *SBaseRegionDriver) RequestCreateBackup(ctx context.Context, backup *
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateBackup")
 }
 
+func (self *SBaseRegionDriver) RequestCreateIncrementalBackup(ctx context.Context, backup *models.SDiskBackup, parentBackupId string, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestCreateIncrementalBackup")
+}
+
 func (self *SBaseRegionDriver) RequestDeleteBackup(ctx context.Context, backup *models.SDiskBackup, task taskman.ITask) error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestDeleteBackup")
 }
@@ -511,6 +527,10 @@ func (self *SBaseRegionDriver) RequestSyncInstanceBackupStatus(ctx context.Conte
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "SyncInstanceBackupStatus")
 }
 
+func (self *SBaseRegionDriver) RequestVerifyDiskBackup(ctx context.Context, backup *models.SDiskBackup, task taskman.ITask) error {
+	return errors.Wrapf(cloudprovider.ErrNotImplemented, "RequestVerifyDiskBackup")
+}
+
 func (self *SBaseRegionDriver) RequestSyncBackupStorageStatus(ctx context.Context, userCred mcclient.TokenCredential, bs *models.SBackupStorage, task taskman.ITask) error {
 	return errors.Wrapf(cloudprovider.ErrNotImplemented, "SyncBackupStorageStatus")
 }
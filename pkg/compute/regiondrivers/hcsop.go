@@ -15,8 +15,13 @@
 package regiondrivers
 
 import (
+	"context"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
 )
 
 type SHCSOPRegionDriver struct {
@@ -47,3 +52,19 @@ func (self *SHCSOPRegionDriver) IsSupportedElasticcacheSecgroup() bool {
 func (self *SHCSOPRegionDriver) GetMaxElasticcacheSecurityGroupCount() int {
 	return 1
 }
+
+func (self *SHCSOPRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerCreateInput) (*api.LoadbalancerCreateInput, error) {
+	err := validateProviderCapability(input.ManagerId, input.CloudregionId, cloudprovider.CLOUD_CAPABILITY_LOADBALANCER)
+	if err != nil {
+		return nil, err
+	}
+	return self.SHuaWeiRegionDriver.ValidateCreateLoadbalancerData(ctx, userCred, ownerId, input)
+}
+
+func (self *SHCSOPRegionDriver) ValidateCreateEipData(ctx context.Context, userCred mcclient.TokenCredential, input *api.SElasticipCreateInput) error {
+	err := validateProviderCapability(input.ManagerId, input.CloudregionId, cloudprovider.CLOUD_CAPABILITY_EIP)
+	if err != nil {
+		return err
+	}
+	return self.SHuaWeiRegionDriver.ValidateCreateEipData(ctx, userCred, input)
+}
@@ -152,6 +152,7 @@ func (self *SHuaWeiRegionDriver) RequestCreateLoadbalancerListener(ctx context.C
 			EgressMbps:              lblis.EgressMbps,
 			EstablishedTimeout:      lblis.BackendConnectTimeout,
 			AccessControlListStatus: lblis.AclStatus,
+			AccessControlListType:   lblis.AclType,
 			BackendGroupId:          lbbg.ExternalId,
 
 			ClientRequestTimeout:  lblis.ClientRequestTimeout,
@@ -183,6 +184,10 @@ func (self *SHuaWeiRegionDriver) RequestCreateLoadbalancerListener(ctx context.C
 			TLSCipherPolicy:   lblis.TLSCipherPolicy,
 			Gzip:              lblis.Gzip,
 		}
+		opts.AccessControlListId, err = self.getLoadbalancerListenerAclExternalId(ctx, userCred, lblis)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getLoadbalancerListenerAclExternalId")
+		}
 		iLis, err := iLb.CreateILoadBalancerListener(ctx, opts)
 		if err != nil {
 			return nil, errors.Wrapf(err, "CreateILoadBalancerListener")
@@ -263,12 +268,70 @@ func (self *SHuaWeiRegionDriver) RequestDeleteLoadbalancerListener(ctx context.C
 }
 
 func (self *SHuaWeiRegionDriver) ValidateCreateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerRuleCreateInput) (*api.LoadbalancerListenerRuleCreateInput, error) {
-	if input.Domain == "" && input.Path == "" {
-		return input, fmt.Errorf("'domain' or 'path' should not be empty.")
+	if input.Domain == "" && input.Path == "" && input.Condition == "" {
+		return input, httperrors.NewMissingParameterError("domain, path or condition")
+	}
+	if len(input.Condition) > 0 {
+		err := models.ValidateListenerRuleConditions(input.Condition)
+		if err != nil {
+			return input, httperrors.NewInputParameterError("%s", err)
+		}
+	}
+	if input.Redirect == api.LB_REDIRECT_RAW {
+		// 华为云仅支持将HTTP监听器重定向到同一负载均衡的HTTPS监听器
+		if input.RedirectScheme != api.LB_REDIRECT_SCHEME_HTTPS || len(input.RedirectHost) > 0 || len(input.RedirectPath) > 0 {
+			return input, httperrors.NewUnsupportOperationError("%s only support redirect to https listener", self.GetProvider())
+		}
+		listenerObj, err := models.LoadbalancerListenerManager.FetchById(input.ListenerId)
+		if err != nil {
+			return input, errors.Wrapf(err, "FetchById(%s)", input.ListenerId)
+		}
+		listener := listenerObj.(*models.SLoadbalancerListener)
+		if listener.ListenerType != api.LB_LISTENER_TYPE_HTTP {
+			return input, httperrors.NewInputParameterError("only http listener support redirect")
+		}
+		_, err = getHuaweiRedirectListener(listener)
+		if err != nil {
+			return input, err
+		}
+		input.BackendGroupId = ""
+	} else {
+		if len(input.BackendGroupId) == 0 {
+			return input, httperrors.NewMissingParameterError("backend_group_id")
+		}
+		_, err := validators.ValidateModel(userCred, models.LoadbalancerBackendGroupManager, &input.BackendGroupId)
+		if err != nil {
+			return input, err
+		}
 	}
 	return input, nil
 }
 
+// 重定向的目标为同一负载均衡下唯一的HTTPS监听器
+func getHuaweiRedirectListener(listener *models.SLoadbalancerListener) (*models.SLoadbalancerListener, error) {
+	lb, err := listener.GetLoadbalancer()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetLoadbalancer")
+	}
+	listeners, err := lb.GetLoadbalancerListeners()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetLoadbalancerListeners")
+	}
+	ret := []models.SLoadbalancerListener{}
+	for i := range listeners {
+		if listeners[i].ListenerType == api.LB_LISTENER_TYPE_HTTPS {
+			ret = append(ret, listeners[i])
+		}
+	}
+	if len(ret) == 0 {
+		return nil, httperrors.NewResourceNotFoundError("no https listener found for loadbalancer %s", lb.Name)
+	}
+	if len(ret) > 1 {
+		return nil, httperrors.NewDuplicateResourceError("loadbalancer %s has %d https listeners", lb.Name, len(ret))
+	}
+	return &ret[0], nil
+}
+
 func (self *SHuaWeiRegionDriver) ValidateUpdateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, input *api.LoadbalancerListenerRuleUpdateInput) (*api.LoadbalancerListenerRuleUpdateInput, error) {
 	return input, nil
 }
@@ -314,9 +377,64 @@ func (self *SHuaWeiRegionDriver) RequestSyncLoadbalancerBackendGroup(ctx context
 	return nil
 }
 
+// 监听器访问控制对应华为云IP地址组, 未缓存到云上时先创建地址组
+func (self *SHuaWeiRegionDriver) getLoadbalancerListenerAclExternalId(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener) (string, error) {
+	if lblis.AclStatus != api.LB_BOOL_ON || len(lblis.AclId) == 0 {
+		return "", nil
+	}
+	acl := lblis.GetLoadbalancerAcl()
+	if acl == nil {
+		return "", errors.Wrapf(httperrors.ErrResourceNotFound, "acl %s", lblis.AclId)
+	}
+	provider := lblis.GetCloudprovider()
+	if provider == nil {
+		return "", errors.Wrapf(httperrors.ErrInvalidStatus, "failed to find provider for lblis %s", lblis.Name)
+	}
+	lbacl, err := models.CachedLoadbalancerAclManager.GetOrCreateCachedAcl(ctx, userCred, provider, lblis, acl)
+	if err != nil {
+		return "", errors.Wrapf(err, "GetOrCreateCachedAcl")
+	}
+	if len(lbacl.ExternalId) == 0 {
+		_, err = self.createLoadbalancerAcl(ctx, userCred, lbacl)
+		if err != nil {
+			return "", errors.Wrapf(err, "createLoadbalancerAcl")
+		}
+	}
+	return lbacl.ExternalId, nil
+}
+
 func (self *SHuaWeiRegionDriver) RequestSyncLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+		params, err := lblis.GetLoadbalancerListenerParams()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancerListenerParams")
+		}
+		params.AccessControlListType = lblis.AclType
+		params.AccessControlListId, err = self.getLoadbalancerListenerAclExternalId(ctx, userCred, lblis)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getLoadbalancerListenerAclExternalId")
+		}
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		iListener, err := iLb.GetILoadBalancerListenerById(lblis.ExternalId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadBalancerListenerById(%s)", lblis.ExternalId)
+		}
+		err = iListener.Sync(ctx, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "iListener.Sync")
+		}
+		err = iListener.Refresh()
+		if err != nil {
+			return nil, errors.Wrapf(err, "iListener.Refresh")
+		}
+		return nil, lblis.SyncWithCloudLoadbalancerListener(ctx, userCred, lb, iListener, lb.GetOwnerId(), lb.GetCloudprovider())
 	})
 	return nil
 }
@@ -344,7 +462,69 @@ func (self *SHuaWeiRegionDriver) RequestCreateLoadbalancerBackend(ctx context.Co
 
 func (self *SHuaWeiRegionDriver) RequestCreateLoadbalancerListenerRule(ctx context.Context, userCred mcclient.TokenCredential, lbr *models.SLoadbalancerListenerRule, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+		listener, err := lbr.GetLoadbalancerListener()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancerListener")
+		}
+		lb, err := listener.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		iListener, err := iLb.GetILoadBalancerListenerById(listener.ExternalId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadBalancerListenerById(%s)", listener.ExternalId)
+		}
+		opts := &cloudprovider.SLoadbalancerListenerRule{
+			Name:      lbr.Name,
+			Domain:    lbr.Domain,
+			Path:      lbr.Path,
+			Condition: lbr.Condition,
+		}
+		if lbr.Redirect == api.LB_REDIRECT_RAW {
+			target, err := getHuaweiRedirectListener(listener)
+			if err != nil {
+				return nil, errors.Wrapf(err, "getHuaweiRedirectListener")
+			}
+			if len(target.ExternalId) == 0 {
+				return nil, errors.Wrapf(cloudprovider.ErrNotFound, "listener %s not created on cloud", target.Name)
+			}
+			opts.RedirectListenerId = target.ExternalId
+		} else {
+			lbbg := lbr.GetLoadbalancerBackendGroup()
+			if lbbg == nil {
+				return nil, fmt.Errorf("failed to find backend group for listener rule %s", lbr.Name)
+			}
+			if len(lbbg.ExternalId) == 0 {
+				lbbgOpts := &cloudprovider.SLoadbalancerBackendGroup{
+					Name:      lbbg.Name,
+					Scheduler: listener.Scheduler,
+					Protocol:  listener.ListenerType,
+				}
+				iLbbg, err := iLb.CreateILoadBalancerBackendGroup(lbbgOpts)
+				if err != nil {
+					return nil, errors.Wrapf(err, "CreateILoadBalancerBackendGroup")
+				}
+				err = db.SetExternalId(lbbg, userCred, iLbbg.GetGlobalId())
+				if err != nil {
+					return nil, errors.Wrapf(err, "db.SetExternalId")
+				}
+			}
+			opts.BackendGroupId = lbbg.ExternalId
+			opts.BackendGroupType = lbbg.Type
+		}
+		iRule, err := iListener.CreateILoadBalancerListenerRule(opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CreateILoadBalancerListenerRule")
+		}
+		err = db.SetExternalId(lbr, userCred, iRule.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "db.SetExternalId")
+		}
+		return nil, lbr.SyncWithCloudLoadbalancerListenerRule(ctx, userCred, iRule, listener.GetOwnerId(), lb.GetCloudprovider())
 	})
 	return nil
 }
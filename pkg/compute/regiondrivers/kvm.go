@@ -547,6 +547,14 @@ func (self *SKVMRegionDriver) ValidateEipChargeType(chargeType string) error {
 	return nil
 }
 
+// 本地EIP带宽仅记录在库中, 无需调用外部接口
+func (self *SKVMRegionDriver) RequestChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, bandwidth int, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SKVMRegionDriver) ValidateCreateEipData(ctx context.Context, userCred mcclient.TokenCredential, input *api.SElasticipCreateInput) error {
 	if err := self.ValidateEipChargeType(input.ChargeType); err != nil {
 		return err
@@ -1376,6 +1384,38 @@ func (self *SKVMRegionDriver) RequestDeleteBackup(ctx context.Context, backup *m
 	return nil
 }
 
+func (self *SKVMRegionDriver) RequestVerifyDiskBackup(ctx context.Context, backup *models.SDiskBackup, task taskman.ITask) error {
+	backupStroage, err := backup.GetBackupStorage()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backupStorage")
+	}
+	storage, _ := backup.GetStorage()
+	var host *models.SHost
+	if storage != nil {
+		host, _ = storage.GetMasterHost()
+	}
+	if host == nil {
+		host, err = models.HostManager.GetEnabledKvmHost()
+		if err != nil {
+			return errors.Wrap(err, "unable to GetEnabledKvmHost")
+		}
+	}
+	url := fmt.Sprintf("%s/storages/verify-backup", host.ManagerUri)
+	body := jsonutils.NewDict()
+	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
+	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
+	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	if len(backup.Checksum) > 0 {
+		body.Set("checksum", jsonutils.NewString(backup.Checksum))
+	}
+	header := task.GetTaskRequestHeader()
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	if err != nil {
+		return errors.Wrap(err, "unable to verify backup")
+	}
+	return nil
+}
+
 func (self *SKVMRegionDriver) RequestCreateBackup(ctx context.Context, backup *models.SDiskBackup, snapshotId string, task taskman.ITask) error {
 	backupStroage, err := backup.GetBackupStorage()
 	if err != nil {
@@ -1411,6 +1451,40 @@ func (self *SKVMRegionDriver) RequestCreateBackup(ctx context.Context, backup *m
 	return nil
 }
 
+func (self *SKVMRegionDriver) RequestCreateIncrementalBackup(ctx context.Context, backup *models.SDiskBackup, parentBackupId string, task taskman.ITask) error {
+	backupStroage, err := backup.GetBackupStorage()
+	if err != nil {
+		return errors.Wrap(err, "unable to get backupStorage")
+	}
+	disk, err := backup.GetDisk()
+	if err != nil {
+		return errors.Wrap(err, "unable to get disk")
+	}
+	guest := disk.GetGuest()
+	if guest == nil {
+		return errors.Wrapf(errors.ErrNotFound, "guest of disk %s", disk.Id)
+	}
+	host, err := guest.GetHost()
+	if err != nil {
+		return errors.Wrap(err, "unable to get host")
+	}
+	url := fmt.Sprintf("%s/servers/%s/disk-incremental-backup", host.ManagerUri, guest.Id)
+	body := jsonutils.NewDict()
+	body.Set("disk_id", jsonutils.NewString(disk.Id))
+	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
+	if len(parentBackupId) > 0 {
+		body.Set("parent_backup_id", jsonutils.NewString(parentBackupId))
+	}
+	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
+	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	header := task.GetTaskRequestHeader()
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	if err != nil {
+		return errors.Wrap(err, "unable to incremental backup")
+	}
+	return nil
+}
+
 func (self *SKVMRegionDriver) RequestAssociateEip(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, input api.ElasticipAssociateInput, obj db.IStatusStandaloneModel, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
 		switch input.InstanceType {
@@ -169,6 +169,7 @@ func (self *SManagedVirtualizationRegionDriver) RequestCreateLoadbalancerInstanc
 			}
 			eip := eipObj.(*models.SElasticip)
 			params.EipId = eip.ExternalId
+			params.SharedBandwidthId = input.SharedBandwidthId
 		}
 
 		if len(lb.ZoneId) > 0 {
@@ -841,6 +842,29 @@ func (self *SManagedVirtualizationRegionDriver) RequestStartLoadbalancerListener
 	return nil
 }
 
+func (self *SManagedVirtualizationRegionDriver) RequestSetLoadbalancerListenerAccessLog(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		accessLog, ok := iLb.(cloudprovider.ICloudLoadbalancerAccessLog)
+		if !ok {
+			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "%s loadbalancer access log", lb.SManagedResourceBase.GetProviderName())
+		}
+		opts, err := lblis.GetCloudLoadbalancerAccessLog()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetCloudLoadbalancerAccessLog")
+		}
+		return nil, accessLog.SetAccessLog(ctx, opts)
+	})
+	return nil
+}
+
 func (self *SManagedVirtualizationRegionDriver) RequestSyncLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
 		{
@@ -1089,6 +1113,83 @@ func (self *SManagedVirtualizationRegionDriver) ValidateCreateEipData(ctx contex
 	return nil
 }
 
+// 各平台按量付费EIP的带宽上限(Mbps), 按计费方式区分, 未列出的平台不做限制
+var managedEipBandwidthLimits = map[string]map[string]int{
+	api.CLOUD_PROVIDER_ALIYUN: {
+		api.EIP_CHARGE_TYPE_BY_TRAFFIC:   200,
+		api.EIP_CHARGE_TYPE_BY_BANDWIDTH: 500,
+	},
+	api.CLOUD_PROVIDER_APSARA: {
+		api.EIP_CHARGE_TYPE_BY_TRAFFIC:   200,
+		api.EIP_CHARGE_TYPE_BY_BANDWIDTH: 500,
+	},
+	api.CLOUD_PROVIDER_QCLOUD: {
+		api.EIP_CHARGE_TYPE_BY_TRAFFIC:   200,
+		api.EIP_CHARGE_TYPE_BY_BANDWIDTH: 1000,
+	},
+	api.CLOUD_PROVIDER_HUAWEI: {
+		api.EIP_CHARGE_TYPE_BY_TRAFFIC:   300,
+		api.EIP_CHARGE_TYPE_BY_BANDWIDTH: 2000,
+	},
+}
+
+func getManagedEipBandwidthLimit(provider, chargeType string) int {
+	limits, ok := managedEipBandwidthLimits[provider]
+	if !ok {
+		return 0
+	}
+	return limits[chargeType]
+}
+
+func (self *SManagedVirtualizationRegionDriver) ValidateChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, bandwidth int) error {
+	if bandwidth == eip.Bandwidth {
+		return httperrors.NewInputParameterError("eip %s bandwidth is already %d Mbps", eip.Name, bandwidth)
+	}
+	// 共享带宽包内的EIP带宽由带宽包决定
+	if len(eip.SharedBandwidthId) > 0 {
+		return httperrors.NewUnsupportOperationError("eip %s is in shared bandwidth %s, change the shared bandwidth instead", eip.Name, eip.SharedBandwidthId)
+	}
+	provider := eip.GetProviderName()
+	if limit := getManagedEipBandwidthLimit(provider, eip.ChargeType); limit > 0 && bandwidth > limit {
+		return httperrors.NewOutOfRangeError("%s eip charged by %s supports at most %d Mbps bandwidth", provider, eip.ChargeType, limit)
+	}
+	factory, err := eip.GetProviderFactory()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	err = factory.ValidateChangeBandwidth(eip.AssociateId, int64(bandwidth))
+	if err != nil {
+		return httperrors.NewInputParameterError("%v", err)
+	}
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestChangeEipBandwidth(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, bandwidth int, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		iEip, err := eip.GetIEip(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "eip.GetIEip")
+		}
+		err = iEip.ChangeBandwidth(bandwidth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "iEip.ChangeBandwidth")
+		}
+		// 部分平台调整为异步生效, 等待云上带宽刷新
+		err = cloudprovider.Wait(3*time.Second, 60*time.Second, func() (bool, error) {
+			err := iEip.Refresh()
+			if err != nil {
+				return false, errors.Wrapf(err, "iEip.Refresh")
+			}
+			return iEip.GetBandwidth() == bandwidth, nil
+		})
+		if err != nil {
+			log.Warningf("wait eip %s bandwidth change to %d: %v", eip.Name, bandwidth, err)
+		}
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SManagedVirtualizationRegionDriver) RequestCreateVpc(ctx context.Context, userCred mcclient.TokenCredential, region *models.SCloudregion, vpc *models.SVpc, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
 		iregion, err := vpc.GetIRegion(ctx)
@@ -3248,3 +3349,11 @@ func (self *SManagedVirtualizationRegionDriver) RequestCreateNetwork(ctx context
 
 	return net.SyncWithCloudNetwork(ctx, userCred, inet, nil, nil)
 }
+
+// 私有云变体的API能力不完整, 创建前按能力矩阵校验
+func validateProviderCapability(managerId, cloudregionId, capability string) error {
+	if len(managerId) == 0 {
+		return nil
+	}
+	return models.CloudproviderCapabilityManager.ValidateCapability(managerId, cloudregionId, capability)
+}
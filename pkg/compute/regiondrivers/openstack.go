@@ -22,9 +22,13 @@ import (
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/secrules"
+	"yunion.io/x/pkg/utils"
 
+	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -99,10 +103,6 @@ func (self *SOpenStackRegionDriver) RequestCreateLoadbalancerAcl(ctx context.Con
 	return nil
 }
 
-func (self *SOpenStackRegionDriver) ValidateCreateLoadbalancerCertificateData(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
-	return nil, httperrors.NewNotImplementedError("%s does not currently support creating loadbalancer certificate", self.GetProvider())
-}
-
 func (self *SOpenStackRegionDriver) ValidateCreateEipData(ctx context.Context, userCred mcclient.TokenCredential, input *api.SElasticipCreateInput) error {
 	if len(input.NetworkId) == 0 {
 		return httperrors.NewMissingParameterError("network_id")
@@ -135,19 +135,164 @@ func (self *SOpenStackRegionDriver) ValidateCreateEipData(ctx context.Context, u
 func (self *SOpenStackRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context.Context, userCred mcclient.TokenCredential,
 	ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerCreateInput,
 	lb *models.SLoadbalancer, lbbg *models.SLoadbalancerBackendGroup) (*api.LoadbalancerListenerCreateInput, error) {
-	return input, httperrors.NewNotImplementedError("ValidateCreateLoadbalancerListenerData")
+	if input.ListenerType == api.LB_LISTENER_TYPE_TERMINATED_HTTPS && len(input.CertificateId) == 0 {
+		return input, httperrors.NewMissingParameterError("certificate_id")
+	}
+	return input, nil
+}
+
+// Octavia 创建pool时需要指定协议及调度算法, 因此pool延迟到关联监听或转发策略时才在云上创建
+func (self *SOpenStackRegionDriver) ensureLoadbalancerBackendGroup(ctx context.Context, userCred mcclient.TokenCredential, iLb cloudprovider.ICloudLoadbalancer, lbbg *models.SLoadbalancerBackendGroup, lblis *models.SLoadbalancerListener) error {
+	if len(lbbg.ExternalId) > 0 {
+		return nil
+	}
+	opts := &cloudprovider.SLoadbalancerBackendGroup{
+		Name:      lbbg.Name,
+		GroupType: lbbg.Type,
+		Scheduler: lblis.Scheduler,
+		Protocol:  lblis.ListenerType,
+	}
+	iLbbg, err := iLb.CreateILoadBalancerBackendGroup(opts)
+	if err != nil {
+		return errors.Wrapf(err, "CreateILoadBalancerBackendGroup")
+	}
+	err = db.SetExternalId(lbbg, userCred, iLbbg.GetGlobalId())
+	if err != nil {
+		return errors.Wrapf(err, "db.SetExternalId")
+	}
+	// pool创建之前添加的后端服务器
+	backends, err := lbbg.GetBackends()
+	if err != nil {
+		return errors.Wrapf(err, "GetBackends")
+	}
+	for i := range backends {
+		if len(backends[i].ExternalId) > 0 {
+			continue
+		}
+		guest := backends[i].GetGuest()
+		if guest == nil {
+			continue
+		}
+		iBackend, err := iLbbg.AddBackendServer(guest.ExternalId, backends[i].Weight, backends[i].Port)
+		if err != nil {
+			return errors.Wrapf(err, "AddBackendServer(%s)", guest.Name)
+		}
+		err = db.SetExternalId(&backends[i], userCred, iBackend.GetGlobalId())
+		if err != nil {
+			return errors.Wrapf(err, "db.SetExternalId")
+		}
+	}
+	return nil
+}
+
+// TERMINATED_HTTPS 监听使用barbican证书容器, 未缓存到云上时先创建
+func (self *SOpenStackRegionDriver) getLoadbalancerListenerCertExternalId(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener) (string, error) {
+	if lblis.ListenerType != api.LB_LISTENER_TYPE_TERMINATED_HTTPS || len(lblis.CertificateId) == 0 {
+		return "", nil
+	}
+	provider := lblis.GetCloudprovider()
+	if provider == nil {
+		return "", errors.Wrapf(httperrors.ErrInvalidStatus, "failed to find provider for lblis %s", lblis.Name)
+	}
+	cert, err := models.LoadbalancerCertificateManager.FetchById(lblis.CertificateId)
+	if err != nil {
+		return "", errors.Wrapf(err, "LoadbalancerCertificateManager.FetchById(%s)", lblis.CertificateId)
+	}
+	lbcert, err := models.CachedLoadbalancerCertificateManager.GetOrCreateCachedCertificate(ctx, userCred, provider, lblis, cert.(*models.SLoadbalancerCertificate))
+	if err != nil {
+		return "", errors.Wrapf(err, "GetOrCreateCachedCertificate")
+	}
+	if len(lbcert.ExternalId) == 0 {
+		_, err = self.createLoadbalancerCertificate(ctx, userCred, lbcert)
+		if err != nil {
+			return "", errors.Wrapf(err, "createLoadbalancerCertificate")
+		}
+	}
+	return lbcert.ExternalId, nil
 }
 
 func (self *SOpenStackRegionDriver) RequestCreateLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		if len(lblis.BackendGroupId) > 0 {
+			lbbg, err := lblis.GetLoadbalancerBackendGroup()
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetLoadbalancerBackendGroup")
+			}
+			err = self.ensureLoadbalancerBackendGroup(ctx, userCred, iLb, lbbg, lblis)
+			if err != nil {
+				return nil, errors.Wrapf(err, "ensureLoadbalancerBackendGroup")
+			}
+		}
+		params, err := lblis.GetLoadbalancerListenerParams()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancerListenerParams")
+		}
+		params.CertificateId, err = self.getLoadbalancerListenerCertExternalId(ctx, userCred, lblis)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getLoadbalancerListenerCertExternalId")
+		}
+		iListener, err := iLb.CreateILoadBalancerListener(ctx, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CreateILoadBalancerListener")
+		}
+		err = db.SetExternalId(lblis, userCred, iListener.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "db.SetExternalId")
+		}
+		return nil, lblis.SyncWithCloudLoadbalancerListener(ctx, userCred, lb, iListener, lb.GetOwnerId(), lb.GetCloudprovider())
 	})
 	return nil
 }
 
 func (self *SOpenStackRegionDriver) RequestSyncLoadbalancerListener(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		if len(lblis.BackendGroupId) > 0 {
+			lbbg, err := lblis.GetLoadbalancerBackendGroup()
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetLoadbalancerBackendGroup")
+			}
+			err = self.ensureLoadbalancerBackendGroup(ctx, userCred, iLb, lbbg, lblis)
+			if err != nil {
+				return nil, errors.Wrapf(err, "ensureLoadbalancerBackendGroup")
+			}
+		}
+		params, err := lblis.GetLoadbalancerListenerParams()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancerListenerParams")
+		}
+		params.CertificateId, err = self.getLoadbalancerListenerCertExternalId(ctx, userCred, lblis)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getLoadbalancerListenerCertExternalId")
+		}
+		iListener, err := iLb.GetILoadBalancerListenerById(lblis.ExternalId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadBalancerListenerById(%s)", lblis.ExternalId)
+		}
+		err = iListener.Sync(ctx, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "iListener.Sync")
+		}
+		err = iListener.Refresh()
+		if err != nil {
+			return nil, errors.Wrapf(err, "iListener.Refresh")
+		}
+		return nil, lblis.SyncWithCloudLoadbalancerListener(ctx, userCred, lb, iListener, lb.GetOwnerId(), lb.GetCloudprovider())
 	})
 	return nil
 }
@@ -162,23 +307,50 @@ func (self *SOpenStackRegionDriver) ValidateUpdateLoadbalancerListenerRuleData(c
 
 func (self *SOpenStackRegionDriver) RequestCreateLoadbalancerListenerRule(ctx context.Context, userCred mcclient.TokenCredential, lbr *models.SLoadbalancerListenerRule, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
-	})
-	return nil
-}
-
-func (self *SOpenStackRegionDriver) RequestDeleteLoadbalancerListenerRule(ctx context.Context, userCred mcclient.TokenCredential, lbr *models.SLoadbalancerListenerRule, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
-	})
-	return nil
-}
-
-func (self *SOpenStackRegionDriver) RequestSyncLoadbalancerBackendGroup(ctx context.Context, userCred mcclient.TokenCredential, lblis *models.SLoadbalancerListener, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+		listener, err := lbr.GetLoadbalancerListener()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancerListener")
+		}
+		lb, err := listener.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetLoadbalancer")
+		}
+		iLb, err := lb.GetILoadbalancer(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadbalancer")
+		}
+		iListener, err := iLb.GetILoadBalancerListenerById(listener.ExternalId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetILoadBalancerListenerById(%s)", listener.ExternalId)
+		}
+		opts := &cloudprovider.SLoadbalancerListenerRule{
+			Name:      lbr.Name,
+			Domain:    lbr.Domain,
+			Path:      lbr.Path,
+			Condition: lbr.Condition,
+		}
+		if len(lbr.BackendGroupId) > 0 {
+			lbbg := lbr.GetLoadbalancerBackendGroup()
+			if lbbg == nil {
+				return nil, fmt.Errorf("failed to find backend group for listener rule %s", lbr.Name)
+			}
+			err = self.ensureLoadbalancerBackendGroup(ctx, userCred, iLb, lbbg, listener)
+			if err != nil {
+				return nil, errors.Wrapf(err, "ensureLoadbalancerBackendGroup")
+			}
+			opts.BackendGroupId = lbbg.ExternalId
+			opts.BackendGroupType = lbbg.Type
+		}
+		iRule, err := iListener.CreateILoadBalancerListenerRule(opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CreateILoadBalancerListenerRule")
+		}
+		err = db.SetExternalId(lbr, userCred, iRule.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "db.SetExternalId")
+		}
+		return nil, lbr.SyncWithCloudLoadbalancerListenerRule(ctx, userCred, iRule, listener.GetOwnerId(), lb.GetCloudprovider())
 	})
-
 	return nil
 }
 
@@ -196,30 +368,129 @@ func (self *SOpenStackRegionDriver) ValidateDeleteLoadbalancerBackendGroupCondit
 	return nil
 }
 
-func (self *SOpenStackRegionDriver) RequestDeleteLoadbalancerBackendGroup(ctx context.Context, userCred mcclient.TokenCredential, lbbg *models.SLoadbalancerBackendGroup, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
-	})
-	return nil
+func (self *SOpenStackRegionDriver) RequestCreateLoadbalancerBackendGroup(ctx context.Context, userCred mcclient.TokenCredential, lbbg *models.SLoadbalancerBackendGroup, task taskman.ITask) error {
+	return task.ScheduleRun(nil)
 }
 
 func (self *SOpenStackRegionDriver) RequestCreateLoadbalancerBackend(ctx context.Context, userCred mcclient.TokenCredential, lbb *models.SLoadbalancerBackend, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
-	})
-	return nil
+	lbbg, err := lbb.GetLoadbalancerBackendGroup()
+	if err != nil {
+		return errors.Wrapf(err, "GetLoadbalancerBackendGroup")
+	}
+	// pool尚未在云上创建, 关联监听时随pool一起添加
+	if len(lbbg.ExternalId) == 0 {
+		return task.ScheduleRun(nil)
+	}
+	return self.SManagedVirtualizationRegionDriver.RequestCreateLoadbalancerBackend(ctx, userCred, lbb, task)
 }
 
 func (self *SOpenStackRegionDriver) RequestSyncLoadbalancerBackend(ctx context.Context, userCred mcclient.TokenCredential, lbb *models.SLoadbalancerBackend, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
-	})
-	return nil
+	if len(lbb.ExternalId) == 0 {
+		return task.ScheduleRun(nil)
+	}
+	return self.SManagedVirtualizationRegionDriver.RequestSyncLoadbalancerBackend(ctx, userCred, lbb, task)
 }
 
 func (self *SOpenStackRegionDriver) RequestDeleteLoadbalancerBackend(ctx context.Context, userCred mcclient.TokenCredential, lbb *models.SLoadbalancerBackend, task taskman.ITask) error {
-	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
-		return nil, cloudprovider.ErrNotImplemented
+	if len(lbb.ExternalId) == 0 {
+		return task.ScheduleRun(nil)
+	}
+	return self.SManagedVirtualizationRegionDriver.RequestDeleteLoadbalancerBackend(ctx, userCred, lbb, task)
+}
+
+func (self *SOpenStackRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SOpenStackRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	return input, nil
+}
+
+// OpenStack 浮动IP在创建 port forwarding 时才关联 router, 此处仅更新本地关联关系
+func (self *SOpenStackRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
+	_, err := db.Update(eip, func() error {
+		eip.AssociateType = api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY
+		eip.AssociateId = nat.Id
+		return nil
 	})
+	if err != nil {
+		return errors.Wrapf(err, "db.Update")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SOpenStackRegionDriver) OnNatEntryDeleteComplete(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip) error {
+	return models.StartResourceSyncStatusTask(ctx, userCred, eip, "EipSyncstatusTask", "")
+}
+
+func (self *SOpenStackRegionDriver) IsSupportedDBInstance() bool {
+	return true
+}
+
+func (self *SOpenStackRegionDriver) ValidateCreateDBInstanceData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input api.DBInstanceCreateInput, skus []models.SDBInstanceSku, network *models.SNetwork) (api.DBInstanceCreateInput, error) {
+	if input.BillingType == billing_api.BILLING_TYPE_PREPAID {
+		return input, httperrors.NewInputParameterError("OpenStack dbinstance not support prepaid billing type")
+	}
+	if !utils.IsInStringArray(input.Engine, []string{api.DBINSTANCE_TYPE_MYSQL, api.DBINSTANCE_TYPE_MARIADB, api.DBINSTANCE_TYPE_PERCONA, api.DBINSTANCE_TYPE_POSTGRESQL}) {
+		return input, httperrors.NewInputParameterError("OpenStack trove not support %s engine", input.Engine)
+	}
+	if input.DiskSizeGB < 1 || input.DiskSizeGB > 2048 {
+		return input, httperrors.NewInputParameterError("disk size gb must in range 1 ~ 2048 Gb")
+	}
+	return input, nil
+}
+
+// trove 不允许通过用户接口创建 root 账号, 创建实例时附带 admin 账号
+func (self *SOpenStackRegionDriver) InitDBInstanceUser(ctx context.Context, instance *models.SDBInstance, task taskman.ITask, desc *cloudprovider.SManagedDBInstanceCreateConfig) error {
+	desc.Username = "admin"
+
+	account := models.SDBInstanceAccount{}
+	account.DBInstanceId = instance.Id
+	account.Name = desc.Username
+	account.Host = "%"
+	account.Status = api.DBINSTANCE_USER_AVAILABLE
+	account.SetModelManager(models.DBInstanceAccountManager, &account)
+	err := models.DBInstanceAccountManager.TableSpec().Insert(ctx, &account)
+	if err != nil {
+		return err
+	}
+
+	return account.SetPassword(desc.Password)
+}
+
+func (self *SOpenStackRegionDriver) ValidateCreateDBInstanceAccountData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, instance *models.SDBInstance, input api.DBInstanceAccountCreateInput) (api.DBInstanceAccountCreateInput, error) {
+	if utils.IsInStringArray(input.Name, []string{"root", "os_admin"}) {
+		return input, httperrors.NewInputParameterError("account name %s is reserved by trove", input.Name)
+	}
+	return input, nil
+}
+
+func (self *SOpenStackRegionDriver) ValidateCreateDBInstanceDatabaseData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, instance *models.SDBInstance, input api.DBInstanceDatabaseCreateInput) (api.DBInstanceDatabaseCreateInput, error) {
+	return input, nil
+}
+
+func (self *SOpenStackRegionDriver) ValidateCreateDBInstanceBackupData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, instance *models.SDBInstance, input api.DBInstanceBackupCreateInput) (api.DBInstanceBackupCreateInput, error) {
+	if len(input.Databases) > 0 {
+		return input, httperrors.NewInputParameterError("OpenStack trove not support backup specific databases")
+	}
+	return input, nil
+}
+
+func (self *SOpenStackRegionDriver) ValidateDBInstanceAccountPrivilege(ctx context.Context, userCred mcclient.TokenCredential, instance *models.SDBInstance, account string, privilege string) error {
+	if privilege != api.DATABASE_PRIVILEGE_RW {
+		return httperrors.NewInputParameterError("OpenStack trove only support %s privilege", api.DATABASE_PRIVILEGE_RW)
+	}
 	return nil
 }
+
+func (self *SOpenStackRegionDriver) IsSupportDBInstancePublicConnection() bool {
+	return false
+}
+
+func (self *SOpenStackRegionDriver) IsSupportKeepDBInstanceManualBackup() bool {
+	return true
+}
+
+func (self *SOpenStackRegionDriver) ValidateDBInstanceRecovery(ctx context.Context, userCred mcclient.TokenCredential, instance *models.SDBInstance, backup *models.SDBInstanceBackup, input api.SDBInstanceRecoveryConfigInput) error {
+	return httperrors.NewNotSupportedError("OpenStack trove only support create new dbinstance from backup")
+}
@@ -23,6 +23,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/compute/capabilities"
+	"yunion.io/x/onecloud/pkg/compute/ha"
 	"yunion.io/x/onecloud/pkg/compute/misc"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/compute/options"
@@ -51,6 +52,7 @@ func InitHandlers(app *appsrv.Application) {
 	sshkeys.AddSshKeysHandler("", app)
 	taskman.AddTaskHandler("", app)
 	misc.AddMiscHandler("", app)
+	ha.AddHaHandler("", app)
 
 	app_common.ExportOptionsHandler(app, &options.Options)
 
@@ -86,6 +88,9 @@ func InitHandlers(app *appsrv.Application) {
 		models.InfrasPendingUsageManager,
 
 		models.CloudproviderCapabilityManager,
+		models.SnapshotArchivePolicyDiskManager,
+		models.HostNetworkProbeManager,
+		models.ServerSkuSpotPriceManager,
 
 		models.ScalingTimerManager,
 		models.ScalingAlarmManager,
@@ -117,9 +122,22 @@ func InitHandlers(app *appsrv.Application) {
 		models.StorageManager,
 		models.StoragecacheManager,
 		models.CachedimageManager,
+		models.CachedimageShareManager,
+		models.SnapshotArchivePolicyManager,
+		models.SnapshotArchiveManager,
+		models.RunbookManager,
+		models.RunbookExecutionManager,
+		models.TagBackfillJobManager,
+		models.ConfigHistoryManager,
+		models.CloudaccountSyncProfileManager,
+		models.HostLogBundleManager,
 		models.HostManager,
 		models.SchedtagManager,
 		models.GuestManager,
+		models.GuestPasswordPolicyManager,
+		models.FailoverVipManager,
+		models.GuestMaintenanceEventManager,
+		models.DedicatedHostManager,
 		models.GroupManager,
 		models.DiskManager,
 		models.NetworkManager,
@@ -165,6 +183,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.NetworkInterfaceManager,
 		models.DBInstanceManager,
 		models.DBInstanceBackupManager,
+		models.DBInstanceDumpManager,
 		models.DBInstanceParameterManager,
 		models.DBInstanceDatabaseManager,
 		models.DBInstanceAccountManager,
@@ -207,6 +226,9 @@ func InitHandlers(app *appsrv.Application) {
 		models.MountTargetManager,
 
 		models.ProjectMappingManager,
+		models.TagPolicyManager,
+		models.ProjectPostureManager,
+		models.PricingRateManager,
 
 		models.WafRuleGroupManager,
 		models.WafRuleGroupCacheManager,
@@ -37,6 +37,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/etcd"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	_ "yunion.io/x/onecloud/pkg/compute/guestdrivers"
+	"yunion.io/x/onecloud/pkg/compute/ha"
 	_ "yunion.io/x/onecloud/pkg/compute/hostdrivers"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/compute/options"
@@ -114,7 +115,12 @@ func StartService() {
 		if err != nil {
 			log.Fatalf("new elect instance: %v", err)
 		}
+		if err := ha.Init(ctx, app, electObj, opts); err != nil {
+			log.Fatalf("init ha: %v", err)
+		}
 		go electObj.Start(ctx)
+	} else if len(opts.HaSiteName) > 0 {
+		log.Fatalf("ha_site_name requires lockman_method %s", common_options.LockMethodEtcd)
 	}
 
 	if opts.EnableHostHealthCheck {
@@ -156,6 +162,9 @@ func StartService() {
 		cron.AddJobAtIntervalsWithStartRun("CalculateDomainQuotaUsages", time.Duration(opts.CalculateQuotaUsageIntervalSeconds)*time.Second, models.DomainQuotaManager.CalculateQuotaUsages, true)
 		cron.AddJobAtIntervalsWithStartRun("CalculateInfrasQuotaUsages", time.Duration(opts.CalculateQuotaUsageIntervalSeconds)*time.Second, models.InfrasQuotaManager.CalculateQuotaUsages, true)
 		cron.AddJobAtIntervalsWithStartRun("AutoSyncCloudaccountStatusTask", time.Duration(opts.CloudAutoSyncIntervalSeconds)*time.Second, models.CloudaccountManager.AutoSyncCloudaccountStatusTask, true)
+		if opts.CloudSyncProfileCheckIntervalSeconds > 0 {
+			cron.AddJobAtIntervals("DispatchCloudaccountSyncProfiles", time.Duration(opts.CloudSyncProfileCheckIntervalSeconds)*time.Second, models.CloudaccountSyncProfileManager.DispatchSyncs)
+		}
 
 		if opts.AutoReconcileBackupServers {
 			cron.AddJobAtIntervalsWithStartRun("ReconcileBackupGuests", time.Duration(opts.ReconcileGuestBackupIntervalSeconds)*time.Second, models.GuestManager.ReconcileBackupGuests, true)
@@ -169,8 +178,19 @@ func StartService() {
 
 		cron.AddJobEveryFewHour("AutoDiskSnapshot", 1, 5, 0, models.DiskManager.AutoDiskSnapshot, false)
 		cron.AddJobEveryFewHour("SnapshotsCleanup", 1, 35, 0, models.SnapshotManager.CleanupSnapshots, false)
+		cron.AddJobEveryFewHour("SnapshotsGFSPrune", 1, 45, 0, models.SnapshotPolicyManager.PruneGFSSnapshots, false)
+		cron.AddJobEveryFewDays("IntegrityScrub", 1, 3, 30, 0, models.IntegrityScrub, false)
+		if opts.NetworkIsolationAuditIntervalDays > 0 {
+			cron.AddJobEveryFewDays("NetworkIsolationAudit", opts.NetworkIsolationAuditIntervalDays, 4, 0, 0, models.NetworkIsolationAudit, false)
+		}
+		if opts.ConfigHistorySnapshotIntervalHours > 0 {
+			cron.AddJobEveryFewHour("ConfigHistorySnapshot", opts.ConfigHistorySnapshotIntervalHours, 50, 0, models.ConfigHistorySnapshot, false)
+		}
 
 		cron.AddJobEveryFewHour("AutoCleanImageCache", 1, 5, 0, models.CachedimageManager.AutoCleanImageCaches, false)
+		if opts.EnableImagePrefetch {
+			cron.AddJobEveryFewHour("PrefetchHotImages", 1, 25, 0, models.StoragecacheManager.PrefetchHotImages, false)
+		}
 
 		cron.AddJobAtIntervalsWithStartRun("SyncSkus", time.Duration(opts.ServerSkuSyncIntervalMinutes)*time.Minute, models.SyncServerSkus, true)
 		cron.AddJobAtIntervalsWithStartRun("SyncManagedWafGroups", time.Duration(opts.ServerSkuSyncIntervalMinutes)*time.Minute, models.SyncWafGroups, true)
@@ -186,6 +206,11 @@ func StartService() {
 		cron.AddJobEveryFewHour("InspectAllTemplate", 1, 0, 0, models.GuestTemplateManager.InspectAllTemplate, true)
 
 		cron.AddJobEveryFewHour("CheckBillingResourceExpireAt", 1, 0, 0, models.CheckBillingResourceExpireAt, true)
+		cron.AddJobEveryFewDays("CheckGuestPasswordExpire", 1, 9, 0, 0, models.GuestPasswordPolicyManager.CheckGuestPasswordExpire, false)
+		cron.AddJobEveryFewHour("ArchivePolicySnapshots", 1, 30, 0, models.SnapshotArchivePolicyManager.ArchivePolicySnapshots, false)
+		cron.AddJobEveryFewDays("CleanupExpiredSnapshotArchives", 1, 3, 0, 0, models.SnapshotArchiveManager.CleanupExpiredArchives, false)
+		cron.AddJobEveryFewHour("SyncSpotPrices", 1, 15, 0, models.ServerSkuSpotPriceManager.SyncSpotPrices, false)
+		cron.AddJobAtIntervals("CheckFailoverVipHealth", time.Duration(opts.FailoverVipHealthCheckIntervalSeconds)*time.Second, models.FailoverVipManager.CheckHealth)
 		go cron.Start2(ctx, electObj)
 
 		// init auto scaling controller
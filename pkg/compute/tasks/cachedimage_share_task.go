@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type CachedimageShareTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(CachedimageShareTask{})
+}
+
+func (self *CachedimageShareTask) taskFailed(ctx context.Context, image *models.SCachedimage, shares []models.SCachedimageShare, err error) {
+	for i := range shares {
+		shares[i].SetStatus(self.GetUserCred(), api.CACHEDIMAGE_SHARE_STATUS_SHARE_FAILED, err.Error())
+	}
+	logclient.AddActionLogWithContext(ctx, image, logclient.ACT_SHARE_IMAGE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *CachedimageShareTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	image := obj.(*models.SCachedimage)
+
+	storagecacheId, _ := self.GetParams().GetString("storagecache_id")
+	shareIds := jsonutils.GetQueryStringArray(self.GetParams(), "share_ids")
+	shares, err := models.CachedimageShareManager.FetchShares(shareIds)
+	if err != nil {
+		self.taskFailed(ctx, image, nil, errors.Wrap(err, "FetchShares"))
+		return
+	}
+
+	sharer, err := image.GetICloudImageSharer(ctx, storagecacheId)
+	if err != nil {
+		self.taskFailed(ctx, image, shares, errors.Wrap(err, "GetICloudImageSharer"))
+		return
+	}
+
+	accountIds := []string{}
+	for i := range shares {
+		accountIds = append(accountIds, shares[i].TargetAccountId)
+	}
+	err = sharer.ShareToAccounts(ctx, accountIds)
+	if err != nil {
+		self.taskFailed(ctx, image, shares, errors.Wrapf(err, "ShareToAccounts(%s)", accountIds))
+		return
+	}
+
+	for i := range shares {
+		shares[i].SetStatus(self.GetUserCred(), api.CACHEDIMAGE_SHARE_STATUS_SHARED, "")
+	}
+	logclient.AddActionLogWithContext(ctx, image, logclient.ACT_SHARE_IMAGE, accountIds, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
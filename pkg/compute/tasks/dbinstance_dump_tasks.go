@@ -0,0 +1,106 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DBInstanceDumpCreateTask struct {
+	taskman.STask
+}
+
+type DBInstanceDumpRestoreTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DBInstanceDumpCreateTask{})
+	taskman.RegisterTask(DBInstanceDumpRestoreTask{})
+}
+
+func (self *DBInstanceDumpCreateTask) taskFailed(ctx context.Context, dump *models.SDBInstanceDump, err jsonutils.JSONObject) {
+	dump.SetStatus(self.UserCred, api.DBINSTANCE_DUMP_STATUS_DUMP_FAILED, err.String())
+	logclient.AddActionLogWithStartable(self, dump, logclient.ACT_DUMP, err, self.UserCred, false)
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *DBInstanceDumpCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	dump := obj.(*models.SDBInstanceDump)
+
+	accountId, _ := self.Params.GetString("dbinstanceaccount_id")
+	timeout, _ := self.Params.Int("timeout")
+	if timeout <= 0 {
+		timeout = api.DBINSTANCE_DUMP_DEFAULT_TIMEOUT
+	}
+	self.SetStage("OnDumpComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, dump.Dump(ctx, self.UserCred, accountId, int(timeout))
+	})
+}
+
+func (self *DBInstanceDumpCreateTask) OnDumpComplete(ctx context.Context, dump *models.SDBInstanceDump, data jsonutils.JSONObject) {
+	dump.SetStatus(self.UserCred, api.DBINSTANCE_DUMP_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, dump, logclient.ACT_DUMP, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DBInstanceDumpCreateTask) OnDumpCompleteFailed(ctx context.Context, dump *models.SDBInstanceDump, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, dump, data)
+}
+
+func (self *DBInstanceDumpRestoreTask) taskFailed(ctx context.Context, dump *models.SDBInstanceDump, err jsonutils.JSONObject) {
+	dump.SetStatus(self.UserCred, api.DBINSTANCE_DUMP_STATUS_RESTORE_FAILED, err.String())
+	logclient.AddActionLogWithStartable(self, dump, logclient.ACT_RESTORE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *DBInstanceDumpRestoreTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	dump := obj.(*models.SDBInstanceDump)
+
+	input := api.DBInstanceDumpRestoreInput{}
+	self.Params.Unmarshal(&input)
+	self.SetStage("OnRestoreComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, dump.Restore(ctx, self.UserCred, input)
+	})
+}
+
+func (self *DBInstanceDumpRestoreTask) OnRestoreComplete(ctx context.Context, dump *models.SDBInstanceDump, data jsonutils.JSONObject) {
+	dump.SetStatus(self.UserCred, api.DBINSTANCE_DUMP_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, dump, logclient.ACT_RESTORE, self.Params, self.UserCred, true)
+	// 同步目标实例, 获取恢复出的数据库
+	instanceId, _ := self.Params.GetString("dbinstance_id")
+	if obj, err := models.DBInstanceManager.FetchById(instanceId); err == nil {
+		err = obj.(*models.SDBInstance).StartDBInstanceSyncTask(ctx, self.UserCred, "")
+		if err != nil {
+			log.Errorf("StartDBInstanceSyncTask for %s: %v", instanceId, err)
+		}
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DBInstanceDumpRestoreTask) OnRestoreCompleteFailed(ctx context.Context, dump *models.SDBInstanceDump, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, dump, data)
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DedicatedHostCreateTask struct {
+	taskman.STask
+}
+
+type DedicatedHostDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DedicatedHostCreateTask{})
+	taskman.RegisterTask(DedicatedHostDeleteTask{})
+}
+
+func (self *DedicatedHostCreateTask) taskFailed(ctx context.Context, dh *models.SDedicatedHost, err error) {
+	dh.SetStatus(self.UserCred, api.DEDICATED_HOST_STATUS_CREATE_FAILED, err.Error())
+	logclient.AddActionLogWithStartable(self, dh, logclient.ACT_ALLOCATE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *DedicatedHostCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	dh := obj.(*models.SDedicatedHost)
+
+	iRegion, err := dh.GetIRegion(ctx)
+	if err != nil {
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "GetIRegion"))
+		return
+	}
+	zone, err := dh.GetZone()
+	if err != nil {
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "GetZone"))
+		return
+	}
+	region, _ := dh.SCloudregionResourceBase.GetRegion()
+	zoneId := zone.ExternalId
+	if region != nil {
+		zoneId = strings.TrimPrefix(zone.ExternalId, region.ExternalId+"/")
+	}
+
+	iHost, err := iRegion.CreateIDedicatedHost(zoneId, dh.HostType, dh.Name)
+	if err != nil {
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "CreateIDedicatedHost"))
+		return
+	}
+	db.SetExternalId(dh, self.GetUserCred(), iHost.GetGlobalId())
+
+	err = dh.SyncWithCloudDedicatedHost(ctx, self.GetUserCred(), iHost)
+	if err != nil {
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "SyncWithCloudDedicatedHost"))
+		return
+	}
+
+	logclient.AddActionLogWithStartable(self, dh, logclient.ACT_ALLOCATE, nil, self.UserCred, true)
+	notifyclient.EventNotify(ctx, self.UserCred, notifyclient.SEventNotifyParam{
+		Obj:    dh,
+		Action: notifyclient.ActionCreate,
+	})
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DedicatedHostDeleteTask) taskFailed(ctx context.Context, dh *models.SDedicatedHost, err error) {
+	dh.SetStatus(self.UserCred, api.DEDICATED_HOST_STATUS_DELETE_FAILED, err.Error())
+	logclient.AddActionLogWithStartable(self, dh, logclient.ACT_DELOCATE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *DedicatedHostDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	dh := obj.(*models.SDedicatedHost)
+
+	if len(dh.ExternalId) == 0 {
+		self.taskComplete(ctx, dh)
+		return
+	}
+	iHost, err := dh.GetICloudDedicatedHost(ctx)
+	if err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			self.taskComplete(ctx, dh)
+			return
+		}
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "GetICloudDedicatedHost"))
+		return
+	}
+	err = iHost.Delete()
+	if err != nil {
+		self.taskFailed(ctx, dh, errors.Wrapf(err, "iHost.Delete"))
+		return
+	}
+	self.taskComplete(ctx, dh)
+}
+
+func (self *DedicatedHostDeleteTask) taskComplete(ctx context.Context, dh *models.SDedicatedHost) {
+	dh.RealDelete(ctx, self.GetUserCred())
+	logclient.AddActionLogWithStartable(self, dh, logclient.ACT_DELOCATE, nil, self.UserCred, true)
+	notifyclient.EventNotify(ctx, self.UserCred, notifyclient.SEventNotifyParam{
+		Obj:    dh,
+		Action: notifyclient.ActionDelete,
+	})
+	self.SetStageComplete(ctx, nil)
+}
@@ -75,6 +75,17 @@ func (self *DiskBackupCreateTask) OnInit(ctx context.Context, obj db.IStandalone
 		self.OnSnapshot(ctx, backup, nil)
 		return
 	}
+	if reqMode, _ := self.Params.GetString("backup_mode"); len(reqMode) > 0 {
+		mode, parentId, err := backup.PrepareBitmapBackup(ctx, reqMode)
+		if err != nil {
+			self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()), api.BACKUP_STATUS_CREATE_FAILED)
+			return
+		}
+		if len(mode) > 0 {
+			self.startBitmapBackup(ctx, backup, mode, parentId)
+			return
+		}
+	}
 	backup.SetStatus(self.UserCred, api.BACKUP_STATUS_SNAPSHOT, "")
 	snapshot, err := self.CreateSnapshot(ctx, backup)
 	if err != nil {
@@ -91,6 +102,40 @@ func (self *DiskBackupCreateTask) OnInit(ctx context.Context, obj db.IStandalone
 	}
 }
 
+func (self *DiskBackupCreateTask) startBitmapBackup(ctx context.Context, backup *models.SDiskBackup, mode, parentId string) {
+	backup.SetStatus(self.UserCred, api.BACKUP_STATUS_SAVING, mode)
+	params := jsonutils.NewDict()
+	params.Set("bitmap_backup_mode", jsonutils.NewString(mode))
+	params.Set("parent_backup_id", jsonutils.NewString(parentId))
+	self.SetStage("OnBitmapBackup", params)
+	rd, err := backup.GetRegionDriver()
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()), api.BACKUP_STATUS_SAVE_FAILED)
+		return
+	}
+	if err := rd.RequestCreateIncrementalBackup(ctx, backup, parentId, self); err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()), api.BACKUP_STATUS_SAVE_FAILED)
+		return
+	}
+}
+
+func (self *DiskBackupCreateTask) OnBitmapBackup(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	mode, _ := self.Params.GetString("bitmap_backup_mode")
+	parentId, _ := self.Params.GetString("parent_backup_id")
+	sizeMb, _ := data.Int("size_mb")
+	checksum, _ := data.GetString("checksum")
+	err := backup.OnBitmapBackupSaved(ctx, self.UserCred, mode, parentId, int(sizeMb), checksum)
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()), api.BACKUP_STATUS_SAVE_FAILED)
+		return
+	}
+	self.taksSuccess(ctx, backup, nil)
+}
+
+func (self *DiskBackupCreateTask) OnBitmapBackupFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, backup, data, api.BACKUP_STATUS_SAVE_FAILED)
+}
+
 func (self *DiskBackupCreateTask) OnSnapshot(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
 	snapshotId, _ := self.Params.GetString("snapshot_id")
 	if self.Params.Contains("only_snapshot") {
@@ -129,8 +174,10 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 	}
 	log.Infof("data from RequestCreateBackup: %s", data)
 	sizeMb, _ := data.Int("size_mb")
+	checksum, _ := data.GetString("checksum")
 	db.Update(backup, func() error {
 		backup.SizeMb = int(sizeMb)
+		backup.Checksum = checksum
 		return nil
 	})
 	snapshot := snapshotModel.(*models.SSnapshot)
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// 按 GFS 策略逐个删除磁盘的过期自动快照, 串行删除以免同一快照链并发合并
+type DiskSnapshotGFSPruneTask struct {
+	SDiskBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(DiskSnapshotGFSPruneTask{})
+}
+
+func (self *DiskSnapshotGFSPruneTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.deleteNextSnapshot(ctx)
+}
+
+func (self *DiskSnapshotGFSPruneTask) deleteNextSnapshot(ctx context.Context) {
+	snapshotIds := []string{}
+	self.Params.Unmarshal(&snapshotIds, "snapshot_ids")
+	for len(snapshotIds) > 0 {
+		snapshotId := snapshotIds[0]
+		snapshotIds = snapshotIds[1:]
+		self.Params.Set("snapshot_ids", jsonutils.NewStringArray(snapshotIds))
+
+		snapshot, err := models.SnapshotManager.FetchById(snapshotId)
+		if err != nil {
+			log.Warningf("fetch snapshot %s: %v", snapshotId, err)
+			continue
+		}
+		snap := snapshot.(*models.SSnapshot)
+		// 快照状态可能在任务排队期间发生变化, 删除前重新校验
+		if snap.RefCount > 0 || snap.FakeDeleted {
+			continue
+		}
+		if err := snap.ValidatePurgeCondition(ctx); err != nil {
+			log.Infof("skip prune snapshot %s: %v", snap.Name, err)
+			continue
+		}
+		self.SetStage("OnSnapshotDelete", nil)
+		err = snap.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.GetId())
+		if err != nil {
+			log.Errorf("start delete snapshot %s: %v", snap.Name, err)
+			continue
+		}
+		return
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DiskSnapshotGFSPruneTask) OnSnapshotDelete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.deleteNextSnapshot(ctx)
+}
+
+func (self *DiskSnapshotGFSPruneTask) OnSnapshotDeleteFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	log.Errorf("prune snapshot failed: %s", data)
+	self.deleteNextSnapshot(ctx)
+}
@@ -81,6 +81,8 @@ func (self *EipAllocateTask) OnInit(ctx context.Context, obj db.IStandaloneModel
 		ChargeType:    eip.ChargeType,
 		BGPType:       eip.BgpType,
 		IP:            eip.IpAddr,
+
+		SharedBandwidthId: eip.SharedBandwidthId,
 	}
 
 	if eip.NetworkId != "" {
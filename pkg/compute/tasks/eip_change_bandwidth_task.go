@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -51,25 +52,22 @@ func (self *EipChangeBandwidthTask) OnInit(ctx context.Context, obj db.IStandalo
 		return
 	}
 
-	if eip.IsManaged() {
-		extEip, err := eip.GetIEip(ctx)
-		if err != nil {
-			msg := fmt.Sprintf("fail to find iEip %s", err)
-			self.TaskFail(ctx, eip, jsonutils.NewString(msg))
-			return
-		}
-
-		err = extEip.ChangeBandwidth(int(bandwidth))
-
-		if err != nil {
-			msg := fmt.Sprintf("fail to find iEip %s", err)
-			self.TaskFail(ctx, eip, jsonutils.NewString(msg))
-			return
-		}
-
+	region, err := eip.GetRegion()
+	if err != nil {
+		self.TaskFail(ctx, eip, jsonutils.NewString(errors.Wrapf(err, "GetRegion").Error()))
+		return
+	}
+	self.SetStage("OnChangeBandwidthComplete", nil)
+	err = region.GetDriver().RequestChangeEipBandwidth(ctx, self.UserCred, eip, int(bandwidth), self)
+	if err != nil {
+		self.TaskFail(ctx, eip, jsonutils.NewString(errors.Wrapf(err, "RequestChangeEipBandwidth").Error()))
+		return
 	}
+}
 
-	if err := eip.DoChangeBandwidth(self.UserCred, int(bandwidth)); err != nil {
+func (self *EipChangeBandwidthTask) OnChangeBandwidthComplete(ctx context.Context, eip *models.SElasticip, data jsonutils.JSONObject) {
+	bandwidth, _ := self.Params.Int("bandwidth")
+	if err := eip.DoChangeBandwidth(ctx, self.UserCred, int(bandwidth)); err != nil {
 		msg := fmt.Sprintf("fail to synchronize iEip bandwidth %s", err)
 		self.TaskFail(ctx, eip, jsonutils.NewString(msg))
 		return
@@ -77,3 +75,7 @@ func (self *EipChangeBandwidthTask) OnInit(ctx context.Context, obj db.IStandalo
 	logclient.AddActionLogWithStartable(self, eip, logclient.ACT_CHANGE_BANDWIDTH, nil, self.UserCred, true)
 	self.SetStageComplete(ctx, nil)
 }
+
+func (self *EipChangeBandwidthTask) OnChangeBandwidthCompleteFailed(ctx context.Context, eip *models.SElasticip, data jsonutils.JSONObject) {
+	self.TaskFail(ctx, eip, data)
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type ExternalProjectMigrateResourcesTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ExternalProjectMigrateResourcesTask{})
+}
+
+func (self *ExternalProjectMigrateResourcesTask) taskFailed(ctx context.Context, project *models.SExternalProject, err jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, project, logclient.ACT_MIGRATE, err, self.GetUserCred(), false)
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *ExternalProjectMigrateResourcesTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	project := obj.(*models.SExternalProject)
+	resourceTypes := jsonutils.GetQueryStringArray(self.GetParams(), "resource_types")
+	self.SetStage("OnResourcesMigrated", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		result, err := project.MigrateResources(ctx, self.GetUserCred(), resourceTypes)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(result), nil
+	})
+}
+
+func (self *ExternalProjectMigrateResourcesTask) OnResourcesMigrated(ctx context.Context, project *models.SExternalProject, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, project, logclient.ACT_MIGRATE, data, self.GetUserCred(), true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ExternalProjectMigrateResourcesTask) OnResourcesMigratedFailed(ctx context.Context, project *models.SExternalProject, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, project, data)
+}
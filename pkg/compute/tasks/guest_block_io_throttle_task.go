@@ -43,10 +43,8 @@ func (self *GuestBlockIoThrottleTask) OnInit(ctx context.Context, obj db.IStanda
 	self.SetStage("OnIoThrottle", nil)
 
 	params := jsonutils.NewDict()
-	iops, _ := self.Params.Get("iops")
-	bps, _ := self.Params.Get("bps")
-	params.Set("iops", iops)
-	params.Set("bps", bps)
+	disks, _ := self.Params.Get("disks")
+	params.Set("disks", disks)
 	_, err := host.Request(ctx, self.UserCred, "POST", url, headers, params)
 	if err != nil {
 		self.OnIoThrottleFailed(ctx, guest, jsonutils.NewString(err.Error()))
@@ -54,21 +52,13 @@ func (self *GuestBlockIoThrottleTask) OnInit(ctx context.Context, obj db.IStanda
 }
 
 func (self *GuestBlockIoThrottleTask) OnIoThrottle(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
-	iops, _ := self.Params.Int("iops")
-	bps, _ := self.Params.Int("bps")
-
-	gds, _ := guest.GetGuestDisks()
-	for i := 0; i < len(gds); i++ {
-		db.Update(&gds[i], func() error {
-			gds[i].Iops = int(iops)
-			gds[i].Bps = int(bps)
-			return nil
-		})
+	throttles := map[string]api.ServerDiskIoThrottle{}
+	self.Params.Unmarshal(&throttles, "disks")
+	err := guest.SetDiskIoThrottles(ctx, self.UserCred, throttles)
+	if err != nil {
+		self.OnIoThrottleFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
 	}
-	guest.SetMetadata(ctx, "iops", iops, self.UserCred)
-	guest.SetMetadata(ctx, "bps", bps, self.UserCred)
-	db.OpsLog.LogEvent(guest, db.ACT_VM_IO_THROTTLE, "", self.UserCred)
-	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_VM_IO_THROTTLE, "", self.UserCred, true)
 	self.SetStage("OnGuestSync", nil)
 	guest.StartSyncstatus(ctx, self.UserCred, self.Id)
 }
@@ -327,12 +327,42 @@ func (self *GuestChangeConfigTask) OnGuestChangeCpuMemSpecComplete(ctx context.C
 }
 
 func (self *GuestChangeConfigTask) OnGuestChangeCpuMemSpecCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	if jsonutils.QueryBoolean(data, "cpu_hotplug_unsupported", false) {
+		// guest os or machine type doesn't support cpu hotplug, change spec by restarting guest
+		db.OpsLog.LogEvent(guest, db.ACT_CHANGE_FLAVOR, fmt.Sprintf("cpu hotplug unsupported, fallback to change config offline: %s", data), self.UserCred)
+		self.Params.Set("guest_online", jsonutils.JSONFalse)
+		self.Params.Set("auto_start", jsonutils.JSONTrue)
+		self.SetStage("OnGuestStopForChangeCpuMemSpec", nil)
+		err := guest.StartGuestStopTask(ctx, self.UserCred, false, false, self.GetTaskId())
+		if err != nil {
+			self.markStageFailed(ctx, guest, jsonutils.NewString(fmt.Sprintf("StartGuestStopTask fail %s", err)))
+		}
+		return
+	}
 	if err := guest.GetDriver().OnGuestChangeCpuMemFailed(ctx, guest, data.(*jsonutils.JSONDict), self); err != nil {
 		log.Errorln(err)
 	}
 	self.markStageFailed(ctx, guest, data)
 }
 
+func (self *GuestChangeConfigTask) OnGuestStopForChangeCpuMemSpec(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	instanceType, _ := self.Params.GetString("instance_type")
+	vcpuCount, _ := self.Params.Int("vcpu_count")
+	vmemSize, _ := self.Params.Int("vmem_size")
+	if vcpuCount == 0 {
+		vcpuCount = int64(guest.VcpuCount)
+	}
+	if vmemSize == 0 {
+		vmemSize = int64(guest.VmemSize)
+	}
+	self.SetStage("OnGuestChangeCpuMemSpecComplete", nil)
+	self.startGuestChangeCpuMemSpec(ctx, guest, instanceType, vcpuCount, vmemSize)
+}
+
+func (self *GuestChangeConfigTask) OnGuestStopForChangeCpuMemSpecFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.markStageFailed(ctx, guest, data)
+}
+
 func (self *GuestChangeConfigTask) OnGuestChangeCpuMemSpecFinish(ctx context.Context, guest *models.SGuest) {
 	models.HostManager.ClearSchedDescCache(guest.HostId)
 	self.SetStage("OnSyncConfigComplete", nil)
@@ -410,5 +440,9 @@ func (self *GuestChangeConfigTask) SetStageComplete(ctx context.Context, data *j
 	defer lockman.ReleaseRawObject(ctx, models.HostManager.KeywordPlural(), hostId)
 	models.HostManager.ClearSchedDescSessionCache(hostId, sessionId)
 
+	err := models.ConfigHistoryManager.Record(ctx, guest, api.CONFIG_HISTORY_TRIGGER_CHANGE)
+	if err != nil {
+		log.Errorf("record config history of guest %s: %v", guest.Id, err)
+	}
 	self.SSchedTask.SetStageComplete(ctx, data)
 }
@@ -93,6 +93,8 @@ func (t *GuestChangeDiskStorageTask) ChangeDiskStorage(ctx context.Context, gues
 
 	// set target disk's status to clone
 	targetDisk.SetStatus(t.GetUserCred(), api.DISK_CLONE, "")
+	targetDisk.SetProgress(0)
+	guest.SetProgress(0)
 
 	err = sourceDisk.SetMetadata(ctx, api.DISK_CLONE_TASK_ID, t.GetId(), t.GetUserCred())
 	if err != nil {
@@ -310,6 +312,7 @@ func (t *GuestChangeDiskStorageTask) OnTargetDiskAttachCompleteFailed(ctx contex
 }
 
 func (t *GuestChangeDiskStorageTask) TaskComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	guest.SetProgress(100)
 	logclient.AddActionLogWithStartable(t, guest, logclient.ACT_DISK_CHANGE_STORAGE, nil, t.GetUserCred(), true)
 	t.SetStageComplete(ctx, nil)
 }
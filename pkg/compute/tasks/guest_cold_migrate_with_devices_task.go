@@ -0,0 +1,124 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 直通设备虚机冷迁移: 关机 -> 摘除设备 -> 迁移 -> 在目标宿主机按型号重新分配设备 -> 按需开机
+type GuestColdMigrateWithDevicesTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestColdMigrateWithDevicesTask{})
+}
+
+func (self *GuestColdMigrateWithDevicesTask) taskFailed(ctx context.Context, guest *models.SGuest, reason jsonutils.JSONObject) {
+	guest.SetStatus(self.UserCred, api.VM_MIGRATE_FAILED, reason.String())
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_MIGRATE, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *GuestColdMigrateWithDevicesTask) getDeviceConfigs() []api.IsolatedDeviceConfig {
+	confs := []api.IsolatedDeviceConfig{}
+	self.Params.Unmarshal(&confs, "isolated_devices")
+	return confs
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	guestStatus, _ := self.Params.GetString("guest_status")
+	if guestStatus == api.VM_RUNNING {
+		self.SetStage("OnStopComplete", nil)
+		if err := guest.StartGuestStopTask(ctx, self.UserCred, false, false, self.GetTaskId()); err != nil {
+			self.taskFailed(ctx, guest, jsonErrorObj(err))
+		}
+		return
+	}
+	self.OnStopComplete(ctx, guest, nil)
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnStopComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	confs, err := guest.DetachIsolatedDevicesForMigrate(ctx, self.UserCred)
+	if err != nil {
+		self.taskFailed(ctx, guest, jsonErrorObj(err))
+		return
+	}
+	// 发起任务后设备可能已被占用, 关机后重新按可用性确认目标宿主机
+	preferHostId, _ := self.Params.GetString("prefer_host_id")
+	hostId, err := guest.ChooseColdMigrateHostByDevices(confs, preferHostId)
+	if err != nil {
+		if err := guest.AttachIsolatedDevicesByConfigs(ctx, self.UserCred, confs); err != nil {
+			log.Errorf("guest %s recover isolated devices: %s", guest.Name, err)
+		}
+		self.taskFailed(ctx, guest, jsonErrorObj(err))
+		return
+	}
+	self.SetStage("OnMigrateComplete", jsonutils.Marshal(map[string]interface{}{
+		"isolated_devices": confs,
+		"target_host_id":   hostId,
+	}).(*jsonutils.JSONDict))
+	err = guest.StartMigrateTask(ctx, self.UserCred, false, false, api.VM_READY, hostId, self.GetTaskId())
+	if err != nil {
+		self.OnMigrateCompleteFailed(ctx, guest, jsonErrorObj(err))
+	}
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnStopCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, guest, data)
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnMigrateComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	if err := guest.AttachIsolatedDevicesByConfigs(ctx, self.UserCred, self.getDeviceConfigs()); err != nil {
+		self.taskFailed(ctx, guest, jsonErrorObj(err))
+		return
+	}
+	self.SetStage("OnSyncIsolatedDevicesComplete", nil)
+	autoStart := jsonutils.QueryBoolean(self.Params, "auto_start", false)
+	if err := guest.StartIsolatedDevicesSyncTask(ctx, self.UserCred, autoStart, self.GetTaskId()); err != nil {
+		self.taskFailed(ctx, guest, jsonErrorObj(err))
+	}
+}
+
+// 迁移失败时在虚机当前宿主机上恢复设备
+func (self *GuestColdMigrateWithDevicesTask) OnMigrateCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	if err := guest.AttachIsolatedDevicesByConfigs(ctx, self.UserCred, self.getDeviceConfigs()); err != nil {
+		log.Errorf("guest %s recover isolated devices: %s", guest.Name, err)
+	} else if err := guest.StartIsolatedDevicesSyncTask(ctx, self.UserCred, false, ""); err != nil {
+		log.Errorf("guest %s start isolated device sync task: %s", guest.Name, err)
+	}
+	self.taskFailed(ctx, guest, data)
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnSyncIsolatedDevicesComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_MIGRATE, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestColdMigrateWithDevicesTask) OnSyncIsolatedDevicesCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_MIGRATE, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
@@ -237,9 +237,32 @@ func (self *GuestCreateTask) OnDeployEipCompleteFailed(ctx context.Context, obj
 
 func (self *GuestCreateTask) OnAutoStartGuest(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	guest := obj.(*models.SGuest)
+	if jsonutils.QueryBoolean(self.GetParams(), "deploy_verify", false) {
+		self.SetStage("OnDeployVerifyComplete", nil)
+		if err := guest.StartDeployVerifyTask(ctx, self.GetUserCred(), self.GetTaskId()); err != nil {
+			self.OnDeployVerifyCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		}
+		return
+	}
 	self.TaskComplete(ctx, guest)
 }
 
+func (self *GuestCreateTask) OnDeployVerifyComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.TaskComplete(ctx, guest)
+}
+
+// 部署验证失败时虚机保持运行, 仅将创建任务标记为失败
+func (self *GuestCreateTask) OnDeployVerifyCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	db.OpsLog.LogEvent(guest, db.ACT_ALLOCATE_FAIL, data, self.UserCred)
+	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_ALLOCATE, data, self.UserCred, false)
+	notifyclient.EventNotify(ctx, self.GetUserCred(), notifyclient.SEventNotifyParam{
+		Obj:    guest,
+		Action: notifyclient.ActionCreate,
+		IsFail: true,
+	})
+	self.SetStageFailed(ctx, data)
+}
+
 func (self *GuestCreateTask) OnSyncStatusComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	guest := obj.(*models.SGuest)
 	self.TaskComplete(ctx, guest)
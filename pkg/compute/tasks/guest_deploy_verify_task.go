@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type GuestDeployVerifyTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestDeployVerifyTask{})
+}
+
+func (self *GuestDeployVerifyTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	self.SetStage("OnDeployVerifyComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		timeout := time.Duration(options.Options.DeployVerifyTimeoutSeconds) * time.Second
+		interval := time.Duration(options.Options.DeployVerifyIntervalSeconds) * time.Second
+		deadline := time.Now().Add(timeout)
+		for {
+			expired := !time.Now().Before(deadline)
+			result := guest.RunDeployVerify(ctx, self.UserCred, expired)
+			if result.Status != api.DEPLOY_VERIFY_STATUS_PENDING {
+				if err := guest.SaveDeployVerifyResult(ctx, self.UserCred, result); err != nil {
+					log.Errorf("save guest %s deploy verify result: %v", guest.Name, err)
+				}
+				if result.Status == api.DEPLOY_VERIFY_STATUS_FAILED {
+					return nil, errors.Errorf("deploy verify failed: %s", result.ErrorClass)
+				}
+				return jsonutils.Marshal(result), nil
+			}
+			time.Sleep(interval)
+		}
+	})
+}
+
+func (self *GuestDeployVerifyTask) OnDeployVerifyComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_DEPLOY_VERIFY, data, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestDeployVerifyTask) OnDeployVerifyCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_DEPLOY_VERIFY, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
@@ -187,6 +187,32 @@ func (self *GuestMigrateTask) OnCachedImageComplete(ctx context.Context, guest *
 }
 
 func (self *GuestMigrateTask) OnCachedCdromComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	guestStatus, _ := self.Params.GetString("guest_status")
+	if !self.isRescueMode() && guestStatus == api.VM_RUNNING && !self.Params.Contains("sriov_failover_vfs") {
+		// 迁移前摘除 SR-IOV VF, 虚机流量切换到 virtio 备用网卡
+		vfs, err := guest.DetachSriovFailoverVfs(ctx, self.UserCred)
+		if err != nil {
+			self.TaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+			return
+		}
+		if len(vfs) > 0 {
+			self.SetStage("OnSriovVfDetachComplete", jsonutils.Marshal(map[string]interface{}{
+				"sriov_failover_vfs": vfs,
+			}).(*jsonutils.JSONDict))
+			if err := guest.StartSriovFailoverSyncTask(ctx, self.UserCred, self.GetTaskId()); err != nil {
+				self.TaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+			}
+			return
+		}
+	}
+	self.OnSriovVfDetachComplete(ctx, guest, nil)
+}
+
+func (self *GuestMigrateTask) OnSriovVfDetachCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.TaskFailed(ctx, guest, data)
+}
+
+func (self *GuestMigrateTask) OnSriovVfDetachComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
 	header := self.GetTaskRequestHeader()
 	body := jsonutils.NewDict()
 	guestStatus, _ := self.Params.GetString("guest_status")
@@ -248,6 +274,21 @@ func (self *GuestMigrateTask) OnSrcPrepareComplete(ctx context.Context, guest *m
 	guestStatus, _ := self.Params.GetString("guest_status")
 	if !self.isRescueMode() && (guestStatus == api.VM_RUNNING || guestStatus == api.VM_SUSPEND) {
 		body.Set("live_migrate", jsonutils.JSONTrue)
+		// 目标宿主机结合自身配置确定最终调优参数, 并在启动完成后返回给源端
+		if self.Params.Contains("live_migrate_tuning") {
+			tuning, _ := self.Params.Get("live_migrate_tuning")
+			body.Set("live_migrate_tuning", tuning)
+		}
+	}
+	// swtpm state lives in guest home dir, carry it to target host
+	if data != nil && data.Contains("tpm_state") {
+		tpmState, _ := data.Get("tpm_state")
+		body.Set("tpm_state", tpmState)
+	}
+	// per guest OVMF varstore, keeps uefi boot entries and secure boot keys
+	if data != nil && data.Contains("nvram") {
+		nvram, _ := data.Get("nvram")
+		body.Set("nvram", nvram)
 	}
 
 	headers := self.GetTaskRequestHeader()
@@ -472,6 +513,10 @@ func (self *GuestLiveMigrateTask) OnStartDestComplete(ctx context.Context, guest
 		maxBandwidthMb, _ := self.Params.Get("max_bandwidth_mb")
 		body.Set("max_bandwidth_mb", maxBandwidthMb)
 	}
+	if data.Contains("live_migrate_tuning") {
+		tuning, _ := data.Get("live_migrate_tuning")
+		body.Set("live_migrate_tuning", tuning)
+	}
 
 	headers := self.GetTaskRequestHeader()
 
@@ -602,6 +647,30 @@ func (self *GuestLiveMigrateTask) OnResumeDestGuestComplete(ctx context.Context,
 
 func (self *GuestLiveMigrateTask) OnUndeploySrcGuestComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
 	db.OpsLog.LogEvent(guest, db.ACT_MIGRATE, "OnUndeploySrcGuestComplete", self.UserCred)
+	vfs := self.getSriovFailoverVfs()
+	if len(vfs) > 0 {
+		// 在目标宿主机重新分配 VF 并热插, 虚机流量切回 VF
+		if err := guest.AttachSriovFailoverVfs(ctx, self.UserCred, vfs); err != nil {
+			self.OnSriovVfAttachCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
+			return
+		}
+		self.SetStage("OnSriovVfAttachComplete", nil)
+		if err := guest.StartSriovFailoverSyncTask(ctx, self.UserCred, self.GetTaskId()); err != nil {
+			self.OnSriovVfAttachCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		}
+		return
+	}
+	self.OnSriovVfAttachComplete(ctx, guest, nil)
+}
+
+// VF 重挂失败不影响迁移结果, 虚机继续使用备用网卡通信
+func (self *GuestLiveMigrateTask) OnSriovVfAttachCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	db.OpsLog.LogEvent(guest, db.ACT_GUEST_ATTACH_ISOLATED_DEVICE_FAIL, data, self.UserCred)
+	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_GUEST_ATTACH_ISOLATED_DEVICE, data, self.UserCred, false)
+	self.OnSriovVfAttachComplete(ctx, guest, nil)
+}
+
+func (self *GuestLiveMigrateTask) OnSriovVfAttachComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
 	status, _ := self.Params.GetString("guest_status")
 	if status != guest.Status {
 		self.SetStage("OnGuestSyncStatus", nil)
@@ -654,8 +723,20 @@ func (self *GuestMigrateTask) TaskComplete(ctx context.Context, guest *models.SG
 	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_MIGRATE, self.Params, self.UserCred, true)
 }
 
+func (self *GuestMigrateTask) getSriovFailoverVfs() []api.IsolatedDeviceConfig {
+	vfs := []api.IsolatedDeviceConfig{}
+	if self.Params.Contains("sriov_failover_vfs") {
+		self.Params.Unmarshal(&vfs, "sriov_failover_vfs")
+	}
+	return vfs
+}
+
 func (self *GuestMigrateTask) TaskFailed(ctx context.Context, guest *models.SGuest, reason jsonutils.JSONObject) {
 	self.markFailed(ctx, guest, reason)
+	if vfs := self.getSriovFailoverVfs(); len(vfs) > 0 {
+		// 迁移失败, 在虚机当前所在宿主机上恢复 VF
+		guest.RecoverSriovFailoverVfs(ctx, self.UserCred, vfs)
+	}
 	self.SetStageFailed(ctx, reason)
 }
 
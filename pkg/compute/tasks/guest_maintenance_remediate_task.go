@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+func init() {
+	taskman.RegisterTask(GuestMaintenanceRemediateTask{})
+}
+
+// 关机再开机, 由云平台将虚机调度到健康的物理机上
+type GuestMaintenanceRemediateTask struct {
+	taskman.STask
+}
+
+func (self *GuestMaintenanceRemediateTask) taskFailed(ctx context.Context, event *models.SGuestMaintenanceEvent, guest *models.SGuest, reason jsonutils.JSONObject) {
+	event.SetStatus(self.UserCred, api.GUEST_MAINTENANCE_EVENT_STATUS_REMEDIATE_FAILED, reason.String())
+	if guest != nil {
+		logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_REMEDIATE_MAINTENANCE, reason, self.UserCred, false)
+	}
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *GuestMaintenanceRemediateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	event := obj.(*models.SGuestMaintenanceEvent)
+	guest, err := event.GetGuest()
+	if err != nil {
+		self.taskFailed(ctx, event, nil, jsonutils.NewString(err.Error()))
+		return
+	}
+	self.SetStage("OnServerStopComplete", nil)
+	err = guest.StartGuestStopTask(ctx, self.UserCred, false, false, self.GetTaskId())
+	if err != nil {
+		self.taskFailed(ctx, event, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *GuestMaintenanceRemediateTask) OnServerStopComplete(ctx context.Context, event *models.SGuestMaintenanceEvent, data jsonutils.JSONObject) {
+	guest, err := event.GetGuest()
+	if err != nil {
+		self.taskFailed(ctx, event, nil, jsonutils.NewString(err.Error()))
+		return
+	}
+	self.SetStage("OnServerStartComplete", nil)
+	err = guest.StartGueststartTask(ctx, self.UserCred, nil, self.GetTaskId())
+	if err != nil {
+		self.taskFailed(ctx, event, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *GuestMaintenanceRemediateTask) OnServerStopCompleteFailed(ctx context.Context, event *models.SGuestMaintenanceEvent, data jsonutils.JSONObject) {
+	guest, _ := event.GetGuest()
+	self.taskFailed(ctx, event, guest, data)
+}
+
+func (self *GuestMaintenanceRemediateTask) OnServerStartComplete(ctx context.Context, event *models.SGuestMaintenanceEvent, data jsonutils.JSONObject) {
+	err := event.MarkRemediated(ctx, self.UserCred)
+	if err != nil {
+		self.taskFailed(ctx, event, nil, jsonutils.NewString(err.Error()))
+		return
+	}
+	if guest, err := event.GetGuest(); err == nil {
+		logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_REMEDIATE_MAINTENANCE, event.ExternalId, self.UserCred, true)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestMaintenanceRemediateTask) OnServerStartCompleteFailed(ctx context.Context, event *models.SGuestMaintenanceEvent, data jsonutils.JSONObject) {
+	guest, _ := event.GetGuest()
+	self.taskFailed(ctx, event, guest, data)
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type GuestResizeMemoryTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestResizeMemoryTask{})
+}
+
+func (self *GuestResizeMemoryTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	vmemSize, _ := self.Params.Int("vmem_size")
+
+	self.SetStage("OnResizeMemoryComplete", nil)
+	err := guest.GetDriver().RequestResizeMemory(ctx, guest, self, vmemSize)
+	if err != nil {
+		self.markStageFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
+	}
+}
+
+func (self *GuestResizeMemoryTask) OnResizeMemoryComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	vmemSize, _ := self.Params.Int("vmem_size")
+	addMem := int(vmemSize) - guest.VmemSize
+
+	_, err := db.Update(guest, func() error {
+		guest.VmemSize = int(vmemSize)
+		return nil
+	})
+	if err != nil {
+		self.markStageFailed(ctx, guest, jsonutils.NewString(fmt.Sprintf("update vmem_size: %s", err)))
+		return
+	}
+	db.OpsLog.LogEvent(guest, db.ACT_CHANGE_FLAVOR, fmt.Sprintf("resize memory to %dM", vmemSize), self.UserCred)
+
+	keys, err := guest.GetQuotaKeys()
+	if err != nil {
+		self.markStageFailed(ctx, guest, jsonutils.NewString(fmt.Sprintf("guest.GetQuotaKeys %s", err)))
+		return
+	}
+	lockman.LockClass(ctx, guest.GetModelManager(), guest.ProjectId)
+	defer lockman.ReleaseClass(ctx, guest.GetModelManager(), guest.ProjectId)
+
+	if addMem > 0 {
+		var pendingUsage models.SQuota
+		self.GetPendingUsage(&pendingUsage, 0)
+		cancelUsage := models.SQuota{Memory: addMem}
+		cancelUsage.SetKeys(keys)
+		err = quotas.CancelPendingUsage(ctx, self.UserCred, &pendingUsage, &cancelUsage, true)
+		if err != nil {
+			self.markStageFailed(ctx, guest, jsonutils.NewString(fmt.Sprintf("CancelPendingUsage fail %s", err)))
+			return
+		}
+		self.SetPendingUsage(&pendingUsage, 0)
+	} else if addMem < 0 {
+		reduceUsage := models.SQuota{Memory: -addMem}
+		reduceUsage.SetKeys(keys)
+		quotas.CancelUsages(ctx, self.UserCred, []db.IUsage{&reduceUsage})
+	}
+
+	models.HostManager.ClearSchedDescCache(guest.HostId)
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_RESIZE_MEMORY, data, self.UserCred, true)
+	self.SetStage("OnGuestSyncstatusComplete", nil)
+	guest.StartSyncstatus(ctx, self.UserCred, self.GetTaskId())
+}
+
+func (self *GuestResizeMemoryTask) OnResizeMemoryCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.markStageFailed(ctx, guest, data)
+}
+
+func (self *GuestResizeMemoryTask) OnGuestSyncstatusComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestResizeMemoryTask) OnGuestSyncstatusCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, data)
+}
+
+func (self *GuestResizeMemoryTask) markStageFailed(ctx context.Context, guest *models.SGuest, reason jsonutils.JSONObject) {
+	var pendingUsage models.SQuota
+	if err := self.GetPendingUsage(&pendingUsage, 0); err == nil && !pendingUsage.IsEmpty() {
+		quotas.CancelPendingUsage(ctx, self.UserCred, &pendingUsage, &pendingUsage, false)
+	}
+	// guest keeps running with the original memory size
+	guest.SetStatus(self.UserCred, api.VM_RUNNING, reason.String())
+	db.OpsLog.LogEvent(guest, db.ACT_CHANGE_FLAVOR_FAIL, reason, self.UserCred)
+	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_RESIZE_MEMORY, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
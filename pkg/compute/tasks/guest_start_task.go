@@ -68,6 +68,11 @@ func (task *GuestStartTask) OnStartComplete(ctx context.Context, obj db.IStandal
 			log.Errorf("vpcagent.VpcAgent.DoSync fail %s", err)
 		}
 	}
+	if data != nil {
+		machineType, _ := data.GetString("machine_type")
+		guest.OnStartMachineTypeApplied(ctx, task.UserCred, machineType)
+	}
+	guest.OnBootOnceApplied(ctx, task.UserCred)
 	db.OpsLog.LogEvent(guest, db.ACT_START, guest.GetShortDesc(ctx), task.UserCred)
 	logclient.AddActionLogWithStartable(task, guest, logclient.ACT_VM_START, guest.GetShortDesc(ctx), task.UserCred, true)
 	task.taskComplete(ctx, guest)
@@ -75,6 +80,14 @@ func (task *GuestStartTask) OnStartComplete(ctx context.Context, obj db.IStandal
 
 func (self *GuestStartTask) OnStartCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
 	guest := obj.(*models.SGuest)
+	// 使用升级后的机型启动失败, 回滚到原机型重新启动一次
+	if !jsonutils.QueryBoolean(self.Params, "machine_type_rollback", false) && guest.RollbackMachineTypeUpgrade(ctx, self.UserCred, err.String()) {
+		params := jsonutils.NewDict()
+		params.Set("machine_type_rollback", jsonutils.JSONTrue)
+		self.SetStage("OnStartComplete", params)
+		self.RequestStart(ctx, guest)
+		return
+	}
 	guest.SetStatus(self.UserCred, api.VM_START_FAILED, err.String())
 	db.OpsLog.LogEvent(guest, db.ACT_START_FAIL, err, self.UserCred)
 	logclient.AddActionLogWithStartable(self, guest, logclient.ACT_VM_START, err, self.UserCred, false)
@@ -50,7 +50,7 @@ func (self *HostGuestsMigrateTask) OnInit(ctx context.Context, objs []db.IStanda
 		if guests[i].LiveMigrate {
 			err := guest.StartGuestLiveMigrateTask(ctx, self.UserCred,
 				guests[i].OldStatus, preferHostId, &guests[i].SkipCpuCheck, &guests[i].SkipKernelCheck,
-				guests[i].EnableTLS, guests[i].QuciklyFinish, guests[i].MaxBandwidthMb, nil, self.Id,
+				guests[i].EnableTLS, guests[i].QuciklyFinish, guests[i].MaxBandwidthMb, nil, nil, self.Id,
 			)
 			if err != nil {
 				log.Errorln(err)
@@ -34,6 +34,7 @@ import (
 func init() {
 	taskman.RegisterTask(HostImportLibvirtServersTask{})
 	taskman.RegisterTask(CreateImportedLibvirtGuestTask{})
+	taskman.RegisterTask(HostAdoptUnmanagedGuestsTask{})
 }
 
 type HostImportLibvirtServersTask struct {
@@ -265,3 +266,20 @@ func (self *CreateImportedLibvirtGuestTask) TaskFailed(
 		logclient.ACT_GUEST_CREATE_FROM_IMPORT, reason, self.UserCred, false)
 	self.SetStageFailed(ctx, reason)
 }
+
+// 接管宿主机上运行中的非纳管虚机, 由 host 解析 qemu 命令行生成虚机描述, 后续流程与 libvirt 导入一致
+type HostAdoptUnmanagedGuestsTask struct {
+	HostImportLibvirtServersTask
+}
+
+func (self *HostAdoptUnmanagedGuestsTask) OnInit(
+	ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject,
+) {
+	host := obj.(*models.SHost)
+	self.SetStage("OnRequestHostPrepareImport", nil)
+	header := self.GetTaskRequestHeader()
+	if _, err := host.Request(ctx, self.UserCred, "POST",
+		"/servers/prepare-import-from-running", header, self.Params); err != nil {
+		self.TaskFailed(ctx, host, jsonutils.NewString(err.Error()))
+	}
+}
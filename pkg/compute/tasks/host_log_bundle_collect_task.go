@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type HostLogBundleCollectTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(HostLogBundleCollectTask{})
+}
+
+func (self *HostLogBundleCollectTask) taskFailed(ctx context.Context, bundle *models.SHostLogBundle, err jsonutils.JSONObject) {
+	bundle.SetStatus(self.UserCred, api.HOST_LOG_BUNDLE_STATUS_COLLECT_FAILED, err.String())
+	logclient.AddActionLogWithStartable(self, bundle, logclient.ACT_HOST_COLLECT_LOG_BUNDLE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, err)
+}
+
+func (self *HostLogBundleCollectTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	bundle := obj.(*models.SHostLogBundle)
+	self.SetStage("OnCollectComplete", nil)
+	if err := bundle.RequestCollect(ctx, self); err != nil {
+		self.taskFailed(ctx, bundle, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *HostLogBundleCollectTask) OnCollectComplete(ctx context.Context, bundle *models.SHostLogBundle, data jsonutils.JSONObject) {
+	output := api.HostLogBundleCollectOutput{}
+	data.Unmarshal(&output)
+	if err := bundle.SaveCollectResult(output); err != nil {
+		self.taskFailed(ctx, bundle, jsonutils.NewString(err.Error()))
+		return
+	}
+	bundle.SetStatus(self.UserCred, api.HOST_LOG_BUNDLE_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, bundle, logclient.ACT_HOST_COLLECT_LOG_BUNDLE, data, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostLogBundleCollectTask) OnCollectCompleteFailed(ctx context.Context, bundle *models.SHostLogBundle, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, bundle, data)
+}
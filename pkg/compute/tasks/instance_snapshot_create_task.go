@@ -73,6 +73,7 @@ func (self *InstanceSnapshotCreateTask) taskComplete(
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
 	isp.SetStatus(self.UserCred, compute.INSTANCE_SNAPSHOT_READY, "")
+	isp.SetGuestCurrent(ctx, self.UserCred, guest)
 	guest.StartSyncstatus(ctx, self.UserCred, "")
 
 	db.OpsLog.LogEvent(isp, db.ACT_ALLOCATE, "instance snapshot create success", self.UserCred)
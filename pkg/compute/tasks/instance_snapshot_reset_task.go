@@ -57,6 +57,7 @@ func (self *InstanceSnapshotResetTask) taskComplete(
 	if guest == nil {
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
+	isp.SetGuestCurrent(ctx, self.UserCred, guest)
 	guest.StartSyncstatus(ctx, self.UserCred, "")
 
 	db.OpsLog.LogEvent(isp, db.ACT_VM_RESET_SNAPSHOT, "instance snapshot reset success", self.UserCred)
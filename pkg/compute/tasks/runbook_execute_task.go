@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type RunbookExecuteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(RunbookExecuteTask{})
+}
+
+func (self *RunbookExecuteTask) taskFailed(ctx context.Context, execution *models.SRunbookExecution, err error) {
+	execution.MarkFinished(self.UserCred, api.RUNBOOK_EXECUTION_STATUS_FAILED, err.Error())
+	logclient.AddActionLogWithStartable(self, execution, logclient.ACT_RUNBOOK_EXECUTE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *RunbookExecuteTask) taskComplete(ctx context.Context, execution *models.SRunbookExecution) {
+	execution.MarkFinished(self.UserCred, api.RUNBOOK_EXECUTION_STATUS_SUCCEEDED, "")
+	logclient.AddActionLogWithStartable(self, execution, logclient.ACT_RUNBOOK_EXECUTE, execution.Results, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *RunbookExecuteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	execution := obj.(*models.SRunbookExecution)
+	self.runStep(ctx, execution)
+}
+
+// 依次执行动作, 同步动作直接进入下一步, 异步动作等待子任务回调
+func (self *RunbookExecuteTask) runStep(ctx context.Context, execution *models.SRunbookExecution) {
+	steps := execution.GetSteps()
+	for execution.StepIndex < len(steps) {
+		idx, step := execution.StepIndex, steps[execution.StepIndex]
+		self.SetStage("OnStepComplete", nil)
+		async, err := execution.ExecuteStep(ctx, self.UserCred, step, self.GetTaskId())
+		if err != nil {
+			execution.AppendResult(api.RunbookStepResult{Action: step.Action, Message: err.Error()})
+			self.taskFailed(ctx, execution, fmt.Errorf("step %d %s: %v", idx, step.Action, err))
+			return
+		}
+		if async {
+			return
+		}
+		err = execution.AppendResult(api.RunbookStepResult{Action: step.Action, Success: true})
+		if err != nil {
+			self.taskFailed(ctx, execution, err)
+			return
+		}
+	}
+	self.taskComplete(ctx, execution)
+}
+
+func (self *RunbookExecuteTask) OnStepComplete(ctx context.Context, execution *models.SRunbookExecution, data jsonutils.JSONObject) {
+	steps := execution.GetSteps()
+	if execution.StepIndex < len(steps) {
+		err := execution.AppendResult(api.RunbookStepResult{Action: steps[execution.StepIndex].Action, Success: true})
+		if err != nil {
+			self.taskFailed(ctx, execution, err)
+			return
+		}
+	}
+	self.runStep(ctx, execution)
+}
+
+func (self *RunbookExecuteTask) OnStepCompleteFailed(ctx context.Context, execution *models.SRunbookExecution, data jsonutils.JSONObject) {
+	steps := execution.GetSteps()
+	action := ""
+	if execution.StepIndex < len(steps) {
+		action = steps[execution.StepIndex].Action
+		execution.AppendResult(api.RunbookStepResult{Action: action, Message: data.String()})
+	}
+	self.taskFailed(ctx, execution, fmt.Errorf("step %s: %s", action, data.String()))
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type SnapshotArchiveDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(SnapshotArchiveDeleteTask{})
+}
+
+func (self *SnapshotArchiveDeleteTask) taskFailed(ctx context.Context, archive *models.SSnapshotArchive, err error) {
+	archive.SetStatus(self.UserCred, api.SNAPSHOT_ARCHIVE_STATUS_DELETE_FAILED, err.Error())
+	db.OpsLog.LogEvent(archive, db.ACT_DELOCATE_FAIL, err, self.UserCred)
+	logclient.AddActionLogWithStartable(self, archive, logclient.ACT_SNAPSHOT_ARCHIVE_DELETE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *SnapshotArchiveDeleteTask) taskComplete(ctx context.Context, archive *models.SSnapshotArchive) {
+	logclient.AddActionLogWithStartable(self, archive, logclient.ACT_SNAPSHOT_ARCHIVE_DELETE, archive.GetShortDesc(ctx), self.UserCred, true)
+	archive.RealDelete(ctx, self.GetUserCred())
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *SnapshotArchiveDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	archive := obj.(*models.SSnapshotArchive)
+
+	if len(archive.ExternalId) == 0 {
+		self.taskComplete(ctx, archive)
+		return
+	}
+
+	iRegion, err := archive.GetVaultIRegion(ctx)
+	if err != nil {
+		self.taskFailed(ctx, archive, errors.Wrap(err, "GetVaultIRegion"))
+		return
+	}
+	iSnapshot, err := iRegion.GetISnapshotById(archive.ExternalId)
+	if err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			self.taskComplete(ctx, archive)
+			return
+		}
+		self.taskFailed(ctx, archive, errors.Wrapf(err, "GetISnapshotById(%s)", archive.ExternalId))
+		return
+	}
+	err = iSnapshot.Delete()
+	if err != nil {
+		self.taskFailed(ctx, archive, errors.Wrap(err, "iSnapshot.Delete"))
+		return
+	}
+	err = cloudprovider.WaitDeleted(iSnapshot, 10*time.Second, 5*time.Minute)
+	if err != nil {
+		self.taskFailed(ctx, archive, errors.Wrap(err, "cloudprovider.WaitDeleted"))
+		return
+	}
+	self.taskComplete(ctx, archive)
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type SnapshotArchiveTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(SnapshotArchiveTask{})
+}
+
+func (self *SnapshotArchiveTask) taskFailed(ctx context.Context, archive *models.SSnapshotArchive, err error) {
+	archive.SetStatus(self.UserCred, api.SNAPSHOT_ARCHIVE_STATUS_ARCHIVE_FAILED, err.Error())
+	db.OpsLog.LogEvent(archive, db.ACT_ALLOCATE_FAIL, err, self.UserCred)
+	logclient.AddActionLogWithStartable(self, archive, logclient.ACT_SNAPSHOT_ARCHIVE, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *SnapshotArchiveTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	archive := obj.(*models.SSnapshotArchive)
+
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, self.copyToVault(ctx, archive)
+	})
+}
+
+func (self *SnapshotArchiveTask) copyToVault(ctx context.Context, archive *models.SSnapshotArchive) error {
+	snapshot, err := archive.GetSnapshot()
+	if err != nil {
+		return errors.Wrap(err, "GetSnapshot")
+	}
+	policy, err := archive.GetPolicy()
+	if err != nil {
+		return errors.Wrap(err, "GetPolicy")
+	}
+	vault, err := policy.GetVaultCloudaccount()
+	if err != nil {
+		return errors.Wrap(err, "GetVaultCloudaccount")
+	}
+	sourceAccountObj, err := models.CloudaccountManager.FetchById(archive.SourceCloudaccountId)
+	if err != nil {
+		return errors.Wrapf(err, "FetchById(%s)", archive.SourceCloudaccountId)
+	}
+	source := sourceAccountObj.(*models.SCloudaccount)
+
+	iRegion, err := snapshot.GetISnapshotRegion(ctx)
+	if err != nil {
+		return errors.Wrap(err, "GetISnapshotRegion")
+	}
+	iSnapshot, err := iRegion.GetISnapshotById(snapshot.ExternalId)
+	if err != nil {
+		return errors.Wrapf(err, "GetISnapshotById(%s)", snapshot.ExternalId)
+	}
+	sharer, ok := iSnapshot.(models.ICloudSnapshotSharer)
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "%s snapshot sharing", source.Provider)
+	}
+	err = sharer.ShareToAccount(ctx, vault.AccountId)
+	if err != nil {
+		return errors.Wrapf(err, "ShareToAccount(%s)", vault.AccountId)
+	}
+
+	vaultRegion, err := archive.GetVaultIRegion(ctx)
+	if err != nil {
+		return errors.Wrap(err, "GetVaultIRegion")
+	}
+	copier, ok := vaultRegion.(models.ICloudSnapshotVaultRegion)
+	if !ok {
+		return errors.Wrapf(cloudprovider.ErrNotSupported, "%s snapshot vault copy", vault.Provider)
+	}
+	iCopy, err := copier.CopySharedSnapshot(ctx, &models.SnapshotVaultCopyOptions{
+		SourceSnapshotId: snapshot.ExternalId,
+		SourceAccountId:  source.AccountId,
+		Name:             snapshot.Name,
+		Desc:             fmt.Sprintf("archive of %s by policy %s", snapshot.ExternalId, policy.Name),
+	})
+	if err != nil {
+		return errors.Wrap(err, "CopySharedSnapshot")
+	}
+	err = cloudprovider.WaitStatus(iCopy, api.SNAPSHOT_READY, 15*time.Second, 2*time.Hour)
+	if err != nil {
+		return errors.Wrapf(err, "wait snapshot copy %s ready", iCopy.GetGlobalId())
+	}
+	return archive.MarkArchived(ctx, self.UserCred, iCopy.GetGlobalId(), policy.RetentionDays)
+}
+
+func (self *SnapshotArchiveTask) OnInitComplete(ctx context.Context, archive *models.SSnapshotArchive, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *SnapshotArchiveTask) OnInitCompleteFailed(ctx context.Context, archive *models.SSnapshotArchive, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, archive, errors.Error(data.String()))
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type StorageCacheImageVerifyTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(StorageCacheImageVerifyTask{})
+}
+
+func (self *StorageCacheImageVerifyTask) taskFailed(ctx context.Context, storageCache *models.SStoragecache, reason jsonutils.JSONObject) {
+	body := jsonutils.NewDict()
+	body.Add(reason, "reason")
+	imageId, _ := self.Params.GetString("image_id")
+	body.Add(jsonutils.NewString(imageId), "image_id")
+	logclient.AddActionLogWithStartable(self, storageCache, logclient.ACT_INTEGRITY_VERIFY, body, self.UserCred, false)
+	self.SetStageFailed(ctx, body)
+}
+
+func (self *StorageCacheImageVerifyTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	storageCache := obj.(*models.SStoragecache)
+
+	host, err := storageCache.GetHost()
+	if err != nil {
+		self.taskFailed(ctx, storageCache, jsonutils.NewString(err.Error()))
+		return
+	}
+	if host == nil {
+		self.taskFailed(ctx, storageCache, jsonutils.NewString("no available host"))
+		return
+	}
+
+	self.SetStage("OnImageVerifyComplete", nil)
+	err = host.GetHostDriver().RequestVerifyCachedImage(ctx, host, storageCache, self)
+	if err != nil {
+		self.taskFailed(ctx, storageCache, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *StorageCacheImageVerifyTask) OnImageVerifyComplete(ctx context.Context, storageCache *models.SStoragecache, data jsonutils.JSONObject) {
+	imageId, _ := self.Params.GetString("image_id")
+	result := api.IntegrityVerifyResult{}
+	data.Unmarshal(&result)
+	scimg := models.StoragecachedimageManager.GetStoragecachedimage(storageCache.Id, imageId)
+	if scimg != nil {
+		err := scimg.SetVerifyResult(ctx, self.UserCred, result)
+		if err != nil {
+			self.taskFailed(ctx, storageCache, jsonutils.NewString(err.Error()))
+			return
+		}
+	}
+	success := result.Status == api.INTEGRITY_VERIFY_OK || result.Status == api.INTEGRITY_VERIFY_REPAIRED
+	logclient.AddActionLogWithStartable(self, storageCache, logclient.ACT_INTEGRITY_VERIFY, data, self.UserCred, success)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *StorageCacheImageVerifyTask) OnImageVerifyCompleteFailed(ctx context.Context, storageCache *models.SStoragecache, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, storageCache, data)
+}
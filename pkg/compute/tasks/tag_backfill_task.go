@@ -0,0 +1,69 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type TagBackfillTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(TagBackfillTask{})
+}
+
+func (self *TagBackfillTask) taskFailed(ctx context.Context, job *models.STagBackfillJob, err error) {
+	job.SetStatus(self.UserCred, api.TAG_BACKFILL_STATUS_FAILED, err.Error())
+	logclient.AddActionLogWithStartable(self, job, logclient.ACT_TAG_BACKFILL, err, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *TagBackfillTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	job := obj.(*models.STagBackfillJob)
+
+	self.SetStage("OnBackfillComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		paused, err := job.Backfill(ctx, self.UserCred)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(map[string]bool{"paused": paused}), nil
+	})
+}
+
+func (self *TagBackfillTask) OnBackfillComplete(ctx context.Context, job *models.STagBackfillJob, data jsonutils.JSONObject) {
+	if paused, _ := data.Bool("paused"); paused {
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+	job.SetStatus(self.UserCred, api.TAG_BACKFILL_STATUS_COMPLETED, "")
+	logclient.AddActionLogWithStartable(self, job, logclient.ACT_TAG_BACKFILL, job.Report, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *TagBackfillTask) OnBackfillCompleteFailed(ctx context.Context, job *models.STagBackfillJob, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, job, errors.Errorf("%s", data.String()))
+}
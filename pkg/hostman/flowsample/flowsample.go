@@ -0,0 +1,164 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowsample
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
+	"yunion.io/x/onecloud/pkg/hostman/guestman"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/monitor"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+var (
+	// tcp,orig=(src=10.0.0.2,dst=10.0.0.3,sport=45678,dport=22),reply=(...),zone=5,protoinfo=(state=ESTABLISHED)
+	conntrackOrigRe = regexp.MustCompile(`^(\w+),orig=\(src=([^,]+),dst=([^,)]+)(?:,sport=(\d+),dport=(\d+))?`)
+)
+
+type sConntrackEntry struct {
+	Protocol string
+	Src      string
+	Dst      string
+	Sport    int
+	Dport    int
+}
+
+type sLocalAddr struct {
+	GuestId string
+	VpcId   string
+}
+
+// 解析 ovs-appctl dpctl/dump-conntrack 输出
+// 同一连接在不同 zone 中可能出现多次, 按五元组去重
+func parseConntrackEntries(output string) []sConntrackEntry {
+	ret := []sConntrackEntry{}
+	seen := map[sConntrackEntry]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		m := conntrackOrigRe.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) < 4 {
+			continue
+		}
+		entry := sConntrackEntry{Protocol: m[1], Src: m[2], Dst: m[3]}
+		if len(m[4]) > 0 && len(m[5]) > 0 {
+			entry.Sport, _ = strconv.Atoi(m[4])
+			entry.Dport, _ = strconv.Atoi(m[5])
+		}
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		ret = append(ret, entry)
+	}
+	return ret
+}
+
+// 以本机虚拟机为视角汇总连接, 源端口不计入, 端口取连接的目的端口
+func aggregateFlowSamples(entries []sConntrackEntry, addrs map[string]sLocalAddr) []monitor.SGuestFlowSample {
+	samples := map[monitor.SGuestFlowSample]int{}
+	add := func(ip string, addr sLocalAddr, direction, peerIp string, entry sConntrackEntry) {
+		key := monitor.SGuestFlowSample{
+			GuestId:   addr.GuestId,
+			IpAddr:    ip,
+			VpcId:     addr.VpcId,
+			Direction: direction,
+			PeerIp:    peerIp,
+			Protocol:  entry.Protocol,
+			Port:      entry.Dport,
+		}
+		samples[key]++
+	}
+	for _, entry := range entries {
+		if addr, ok := addrs[entry.Src]; ok {
+			add(entry.Src, addr, monitor.GUEST_FLOW_DIRECTION_OUT, entry.Dst, entry)
+		}
+		if addr, ok := addrs[entry.Dst]; ok {
+			add(entry.Dst, addr, monitor.GUEST_FLOW_DIRECTION_IN, entry.Src, entry)
+		}
+	}
+	ret := make([]monitor.SGuestFlowSample, 0, len(samples))
+	for sample, cnt := range samples {
+		sample.Connections = cnt
+		ret = append(ret, sample)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].GuestId != ret[j].GuestId {
+			return ret[i].GuestId < ret[j].GuestId
+		}
+		if ret[i].PeerIp != ret[j].PeerIp {
+			return ret[i].PeerIp < ret[j].PeerIp
+		}
+		return ret[i].Port < ret[j].Port
+	})
+	return ret
+}
+
+func getLocalGuestAddrs() map[string]sLocalAddr {
+	ret := map[string]sLocalAddr{}
+	guestman.GetGuestManager().Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*guestman.SKVMGuestInstance)
+		if !guest.IsValid() || !guest.IsRunning() {
+			return true
+		}
+		for _, nic := range guest.Desc.Nics {
+			if len(nic.Ip) > 0 {
+				ret[nic.Ip] = sLocalAddr{GuestId: guest.GetId(), VpcId: nic.Vpc.Id}
+			}
+		}
+		return true
+	})
+	return ret
+}
+
+// Sample 采样本机 OVS conntrack 中虚拟机之间的连接, 上报 monitor 服务
+func Sample(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	hostId := hostinfo.Instance().HostId
+	if len(hostId) == 0 {
+		return
+	}
+	addrs := getLocalGuestAddrs()
+	if len(addrs) == 0 {
+		return
+	}
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("ovs-appctl", "dpctl/dump-conntrack").Output()
+	if err != nil {
+		log.Errorf("dump conntrack: %v %s", err, output)
+		return
+	}
+	samples := aggregateFlowSamples(parseConntrackEntries(string(output)), addrs)
+	if len(samples) == 0 {
+		return
+	}
+	input := monitor.GuestFlowReportInput{
+		HostId:  hostId,
+		Samples: samples,
+	}
+	session := auth.GetAdminSession(ctx, consts.GetRegion())
+	_, err = modules.GuestFlowManager.PerformClassAction(session, "report", jsonutils.Marshal(input))
+	if err != nil {
+		log.Errorf("report guest flow samples: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowsample
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+func TestAggregateFlowSamples(t *testing.T) {
+	output := `tcp,orig=(src=10.0.0.2,dst=10.0.0.3,sport=45678,dport=3306),reply=(src=10.0.0.3,dst=10.0.0.2,sport=3306,dport=45678),zone=5,protoinfo=(state=ESTABLISHED)
+tcp,orig=(src=10.0.0.2,dst=10.0.0.3,sport=45678,dport=3306),reply=(src=10.0.0.3,dst=10.0.0.2,sport=3306,dport=45678),zone=6,protoinfo=(state=ESTABLISHED)
+tcp,orig=(src=10.0.0.2,dst=10.0.0.3,sport=45679,dport=3306),reply=(src=10.0.0.3,dst=10.0.0.2,sport=3306,dport=45679),zone=5,protoinfo=(state=ESTABLISHED)
+udp,orig=(src=10.0.0.3,dst=114.114.114.114,sport=5353,dport=53),reply=(src=114.114.114.114,dst=10.0.0.3,sport=53,dport=5353),zone=6
+icmp,orig=(src=192.168.1.10,dst=10.0.0.2,id=1,type=8,code=0),reply=(src=10.0.0.2,dst=192.168.1.10,id=1,type=0,code=0),zone=5
+tcp,orig=(src=172.16.0.1,dst=172.16.0.2,sport=1000,dport=22),reply=(src=172.16.0.2,dst=172.16.0.1,sport=22,dport=1000)
+garbage line
+`
+	entries := parseConntrackEntries(output)
+	if len(entries) != 5 {
+		t.Fatalf("want 5 entries, got %d: %#v", len(entries), entries)
+	}
+	addrs := map[string]sLocalAddr{
+		"10.0.0.2": {GuestId: "web", VpcId: "vpc1"},
+		"10.0.0.3": {GuestId: "db", VpcId: "vpc1"},
+	}
+	sample := func(guestId, ip, direction, peerIp, protocol string, port, conns int) monitor.SGuestFlowSample {
+		return monitor.SGuestFlowSample{
+			GuestId: guestId, IpAddr: ip, VpcId: "vpc1", Direction: direction,
+			PeerIp: peerIp, Protocol: protocol, Port: port, Connections: conns,
+		}
+	}
+	want := []monitor.SGuestFlowSample{
+		sample("db", "10.0.0.3", monitor.GUEST_FLOW_DIRECTION_IN, "10.0.0.2", "tcp", 3306, 2),
+		sample("db", "10.0.0.3", monitor.GUEST_FLOW_DIRECTION_OUT, "114.114.114.114", "udp", 53, 1),
+		sample("web", "10.0.0.2", monitor.GUEST_FLOW_DIRECTION_OUT, "10.0.0.3", "tcp", 3306, 2),
+		sample("web", "10.0.0.2", monitor.GUEST_FLOW_DIRECTION_IN, "192.168.1.10", "icmp", 0, 1),
+	}
+	got := aggregateFlowSamples(entries, addrs)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
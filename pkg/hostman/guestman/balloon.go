@@ -0,0 +1,220 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+)
+
+const balloonQueryTimeout = 10 * time.Second
+
+// 大页内存不能通过 balloon 归还给宿主机, 开启大页时不添加 balloon 设备
+func (s *SKVMGuestInstance) initBalloonDesc(pciRoot *desc.PCIController) {
+	if !options.HostOptions.EnableMemoryBalloon || s.manager.host.IsHugepagesEnabled() {
+		return
+	}
+	s.Desc.Balloon = &desc.SGuestBalloon{
+		PCIDevice: desc.NewPCIDevice(pciRoot.CType, "virtio-balloon-pci", "balloon0"),
+	}
+}
+
+// 可回收的优先级, 越小越先回收, guaranteed 的虚机不参与回收
+func getBalloonPriority(qosClass string) (int, bool) {
+	switch qosClass {
+	case api.GUEST_QOS_CLASS_GUARANTEED:
+		return 0, false
+	case api.GUEST_QOS_CLASS_BEST_EFFORT:
+		return 0, true
+	default:
+		return 1, true
+	}
+}
+
+// 虚机内存可回收到的下限
+func (s *SKVMGuestInstance) getBalloonMinMemMb() int64 {
+	memMb := s.Desc.Mem
+	if v, ok := s.Desc.Metadata[api.VM_METADATA_BALLOON_MIN_MEM_MB]; ok {
+		if minMb, err := strconv.ParseInt(v, 10, 64); err == nil && minMb > 0 {
+			if minMb > memMb {
+				return memMb
+			}
+			return minMb
+		}
+	}
+	return memMb * int64(options.HostOptions.BalloonDefaultMinMemPercent) / 100
+}
+
+func (s *SKVMGuestInstance) queryBalloonActualMb() (int64, error) {
+	ch := make(chan struct{}, 1)
+	var (
+		actual int64
+		errMsg string
+	)
+	s.Monitor.QueryBalloon(func(actualMB int64, err string) {
+		actual, errMsg = actualMB, err
+		ch <- struct{}{}
+	})
+	select {
+	case <-ch:
+		if len(errMsg) > 0 {
+			return 0, errors.Error(errMsg)
+		}
+		return actual, nil
+	case <-time.After(balloonQueryTimeout):
+		return 0, errors.Errorf("query balloon timeout")
+	}
+}
+
+type sBalloonGuest struct {
+	Id       string
+	Priority int
+	MemMb    int64
+	MinMb    int64
+	ActualMb int64
+}
+
+// planBalloonTargets 根据宿主机可用内存计算需要调整的虚机 balloon 目标大小:
+// 低于 lowMb 时按优先级从虚机回收内存, 高于 highMb 时按相反顺序归还已回收的内存
+func planBalloonTargets(guests []sBalloonGuest, availMb, lowMb, highMb int64) map[string]int64 {
+	targets := map[string]int64{}
+	if availMb < lowMb {
+		need := lowMb - availMb
+		sort.SliceStable(guests, func(i, j int) bool {
+			if guests[i].Priority != guests[j].Priority {
+				return guests[i].Priority < guests[j].Priority
+			}
+			return guests[i].ActualMb-guests[i].MinMb > guests[j].ActualMb-guests[j].MinMb
+		})
+		for i := 0; i < len(guests) && need > 0; i++ {
+			can := guests[i].ActualMb - guests[i].MinMb
+			if can <= 0 {
+				continue
+			}
+			if can > need {
+				can = need
+			}
+			targets[guests[i].Id] = guests[i].ActualMb - can
+			need -= can
+		}
+	} else if availMb > highMb {
+		surplus := availMb - highMb
+		sort.SliceStable(guests, func(i, j int) bool {
+			return guests[i].Priority > guests[j].Priority
+		})
+		for i := 0; i < len(guests) && surplus > 0; i++ {
+			reclaimed := guests[i].MemMb - guests[i].ActualMb
+			if reclaimed <= 0 {
+				continue
+			}
+			if reclaimed > surplus {
+				reclaimed = surplus
+			}
+			targets[guests[i].Id] = guests[i].ActualMb + reclaimed
+			surplus -= reclaimed
+		}
+	}
+	return targets
+}
+
+func (m *SGuestManager) StartBalloonManager() {
+	if !options.HostOptions.EnableMemoryBalloon || m.host.IsHugepagesEnabled() {
+		return
+	}
+	interval := options.HostOptions.BalloonCheckIntervalSeconds
+	if interval <= 0 {
+		interval = 30
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				debug.PrintStack()
+				log.Errorf("Balloon manager failed %s", r)
+			}
+		}()
+		for {
+			time.Sleep(time.Duration(interval) * time.Second)
+			m.balloonCheck()
+		}
+	}()
+}
+
+func (m *SGuestManager) balloonCheck() {
+	info, err := mem.VirtualMemory()
+	if err != nil {
+		log.Errorf("balloon get host memory: %v", err)
+		return
+	}
+	availMb := int64(info.Available / 1024 / 1024)
+
+	guests := map[string]*SKVMGuestInstance{}
+	balloonGuests := []sBalloonGuest{}
+	var reclaimed int64
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if guest.Desc.Balloon == nil || !guest.IsRunning() || !guest.IsMonitorAlive() {
+			return true
+		}
+		priority, ok := getBalloonPriority(guest.Desc.QosClass)
+		if !ok {
+			return true
+		}
+		actual, err := guest.queryBalloonActualMb()
+		if err != nil {
+			log.Errorf("guest %s query balloon: %v", guest.GetName(), err)
+			return true
+		}
+		guests[guest.Id] = guest
+		balloonGuests = append(balloonGuests, sBalloonGuest{
+			Id:       guest.Id,
+			Priority: priority,
+			MemMb:    guest.Desc.Mem,
+			MinMb:    guest.getBalloonMinMemMb(),
+			ActualMb: actual,
+		})
+		reclaimed += guest.Desc.Mem - actual
+		return true
+	})
+
+	targets := planBalloonTargets(balloonGuests, availMb,
+		int64(options.HostOptions.BalloonLowFreeMemMb), int64(options.HostOptions.BalloonHighFreeMemMb))
+	for i := range balloonGuests {
+		target, ok := targets[balloonGuests[i].Id]
+		if !ok {
+			continue
+		}
+		guest := guests[balloonGuests[i].Id]
+		log.Infof("host available memory %dMB, balloon guest %s memory %dMB -> %dMB", availMb, guest.GetName(), balloonGuests[i].ActualMb, target)
+		guest.Monitor.Balloon(target, func(res string) {
+			if len(res) > 0 {
+				log.Errorf("guest %s set balloon %dMB: %s", guest.GetName(), target, res)
+			}
+		})
+		reclaimed += balloonGuests[i].ActualMb - target
+	}
+	hostinfo.Instance().SetBalloonReclaimedMb(reclaimed)
+}
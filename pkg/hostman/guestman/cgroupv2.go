@@ -0,0 +1,183 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostconsts"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils"
+)
+
+// getCgroupPriority 返回 metadata 中保存的优先级配置
+func (s *SKVMGuestInstance) getCgroupPriority() *api.ServerCgroupPriorityInput {
+	input := new(api.ServerCgroupPriorityInput)
+	val, ok := s.Desc.Metadata[api.VM_METADATA_CGROUP_PRIORITY]
+	if !ok || len(val) == 0 {
+		return input
+	}
+	obj, err := jsonutils.ParseString(val)
+	if err != nil {
+		// 兼容直接写入优先级名称
+		input.Priority = val
+		return input
+	}
+	if err := obj.Unmarshal(input); err != nil {
+		log.Errorf("failed unmarshal server %s cgroup priority %s: %s", s.Id, val, err)
+	}
+	return input
+}
+
+// guestCgroupWeights 根据QoS等级、vcpu数和优先级计算 v1 cpu.shares 及 v2 cpu.weight/io.weight
+func guestCgroupWeights(qosClass string, cpu int64, input *api.ServerCgroupPriorityInput) (int, int, int) {
+	factor := api.GetCgroupPriorityFactor(input.Priority)
+	perCpu := float64(api.GetQosClassCpuShares(qosClass)) * factor
+	if cpu < 1 {
+		cpu = 1
+	}
+	cpuShares := int(perCpu * float64(cpu))
+	cpuWeight := cgrouputils.CpuSharesToWeight(cpuShares)
+	ioWeight := cgrouputils.ClampCgroupV2Weight(int(cgrouputils.CgroupV2WeightDefault * perCpu / cgrouputils.CgroupsSharesWeight))
+	if input.CpuWeight > 0 {
+		cpuWeight = cgrouputils.ClampCgroupV2Weight(input.CpuWeight)
+	}
+	if input.IoWeight > 0 {
+		ioWeight = cgrouputils.ClampCgroupV2Weight(input.IoWeight)
+	}
+	return cpuShares, cpuWeight, ioWeight
+}
+
+// cgroupV2SliceName 每个虚机在 hostagent slice 下独占一个 slice
+func (s *SKVMGuestInstance) cgroupV2SliceName() string {
+	return fmt.Sprintf("%s/server_%s.slice", hostconsts.HOST_CGROUP, s.Id)
+}
+
+func (s *SKVMGuestInstance) setCgroupV2() error {
+	slice := cgrouputils.NewCgroupV2Slice(s.cgroupV2SliceName())
+	if err := slice.Create(); err != nil {
+		return errors.Wrap(err, "create slice")
+	}
+	if err := s.applyCgroupV2Weights(slice); err != nil {
+		return err
+	}
+	if cpus := s.getCgroupV2Cpuset(); len(cpus) > 0 {
+		if err := slice.SetParam(cgrouputils.CPUSET_CPUS, cpus); err != nil {
+			log.Errorf("server %s set cgroup v2 cpuset: %s", s.Id, err)
+		}
+	}
+	if err := slice.AddProcess(strconv.Itoa(s.cgroupPid)); err != nil {
+		return errors.Wrap(err, "add process")
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) applyCgroupV2Weights(slice *cgrouputils.SCgroupV2Slice) error {
+	_, cpuWeight, ioWeight := guestCgroupWeights(s.Desc.QosClass, s.Desc.Cpu, s.getCgroupPriority())
+	if err := slice.SetParam(cgrouputils.CPU_WEIGHT, strconv.Itoa(cpuWeight)); err != nil {
+		return err
+	}
+	// io 控制器依赖块设备调度器, 未开启时忽略
+	if utils.IsInStringArray("io", strings.Fields(slice.GetParam(cgrouputils.CGROUP_V2_CONTROLLERS))) {
+		if err := slice.SetParam(cgrouputils.IO_WEIGHT, fmt.Sprintf("default %d", ioWeight)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) getCgroupV2Cpuset() string {
+	cpuset, ok := s.Desc.Metadata[api.VM_METADATA_CGROUP_CPUSET]
+	if !ok {
+		return ""
+	}
+	input := new(api.ServerCPUSetInput)
+	obj, err := jsonutils.ParseString(cpuset)
+	if err == nil {
+		err = obj.Unmarshal(input)
+	}
+	if err != nil {
+		log.Errorf("failed parse server %s cpuset %s: %s", s.Id, cpuset, err)
+		return ""
+	}
+	return cpusetString(input.CPUS)
+}
+
+func cpusetString(cpus []int) string {
+	strs := make([]string, len(cpus))
+	for i := range cpus {
+		strs[i] = strconv.Itoa(cpus[i])
+	}
+	return strings.Join(strs, ",")
+}
+
+func (s *SKVMGuestInstance) clearCgroupV2() {
+	slice := cgrouputils.NewCgroupV2Slice(s.cgroupV2SliceName())
+	if err := slice.Destroy(); err != nil {
+		log.Errorf("server %s destroy cgroup v2 slice: %s", s.Id, err)
+	}
+}
+
+// SetCgroupPriority 运行时调整虚机的 cgroup 权重, 配置保存到 desc metadata, 重启后依然生效
+func (s *SKVMGuestInstance) SetCgroupPriority(ctx context.Context, input *api.ServerCgroupPriorityInput) (*api.ServerCgroupPriorityOutput, error) {
+	if len(input.Priority) > 0 && !utils.IsInStringArray(input.Priority, api.GUEST_CGROUP_PRIORITIES) {
+		return nil, httperrors.NewInputParameterError("invalid priority %s, must be one of %s", input.Priority, api.GUEST_CGROUP_PRIORITIES)
+	}
+	if input.CpuWeight < 0 || input.CpuWeight > cgrouputils.CgroupV2WeightMax || input.IoWeight < 0 || input.IoWeight > cgrouputils.CgroupV2WeightMax {
+		return nil, httperrors.NewOutOfRangeError("weight must be in range [%d, %d]", cgrouputils.CgroupV2WeightMin, cgrouputils.CgroupV2WeightMax)
+	}
+	if s.Desc.Metadata == nil {
+		s.Desc.Metadata = map[string]string{}
+	}
+	s.Desc.Metadata[api.VM_METADATA_CGROUP_PRIORITY] = jsonutils.Marshal(input).String()
+	if err := s.SaveLiveDesc(s.Desc); err != nil {
+		return nil, errors.Wrap(err, "save desc after update metadata")
+	}
+
+	cpuShares, cpuWeight, ioWeight := guestCgroupWeights(s.Desc.QosClass, s.Desc.Cpu, input)
+	output := &api.ServerCgroupPriorityOutput{
+		CgroupVersion: 1,
+		CpuShares:     cpuShares,
+		CpuWeight:     cpuWeight,
+		IoWeight:      ioWeight,
+	}
+	if cgrouputils.IsCgroupV2() {
+		output.CgroupVersion = 2
+		output.Slice = s.cgroupV2SliceName()
+	}
+	if !s.IsRunning() || options.HostOptions.DisableSetCgroup {
+		return output, nil
+	}
+	s.cgroupPid = s.GetPid()
+	if output.CgroupVersion == 2 {
+		slice := cgrouputils.NewCgroupV2Slice(output.Slice)
+		if !slice.Exists() {
+			return output, s.setCgroupV2()
+		}
+		return output, s.applyCgroupV2Weights(slice)
+	}
+	s.setCgroupCpu()
+	return output, nil
+}
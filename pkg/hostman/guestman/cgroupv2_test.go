@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestGuestCgroupWeights(t *testing.T) {
+	cases := []struct {
+		qosClass   string
+		cpu        int64
+		input      api.ServerCgroupPriorityInput
+		wantShares int
+		wantCpu    int
+		wantIo     int
+	}{
+		{"", 2, api.ServerCgroupPriorityInput{}, 2048, 79, 100},
+		{api.GUEST_QOS_CLASS_GUARANTEED, 2, api.ServerCgroupPriorityInput{Priority: api.GUEST_CGROUP_PRIORITY_HIGH}, 8192, 313, 400},
+		{api.GUEST_QOS_CLASS_BEST_EFFORT, 4, api.ServerCgroupPriorityInput{Priority: api.GUEST_CGROUP_PRIORITY_LOW}, 512, 20, 12},
+		{"", 0, api.ServerCgroupPriorityInput{CpuWeight: 500, IoWeight: 20000}, 1024, 500, 10000},
+	}
+	for _, c := range cases {
+		shares, cpuWeight, ioWeight := guestCgroupWeights(c.qosClass, c.cpu, &c.input)
+		if shares != c.wantShares || cpuWeight != c.wantCpu || ioWeight != c.wantIo {
+			t.Errorf("%s/%d/%#v want %d/%d/%d got %d/%d/%d", c.qosClass, c.cpu, c.input,
+				c.wantShares, c.wantCpu, c.wantIo, shares, cpuWeight, ioWeight)
+		}
+	}
+}
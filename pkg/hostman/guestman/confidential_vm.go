@@ -0,0 +1,197 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	// SEV 策略位: bit0 禁止调试, bit2 启用 SEV-ES
+	SEV_POLICY_NODBG = 0x1
+	SEV_POLICY_ES    = 0x4
+	// SNP 策略 bit17 必须置位, bit16 允许 SMT
+	SEV_SNP_POLICY = 0x30000
+
+	sevLaunchMeasureTimeout = 10 * time.Second
+)
+
+// 机密计算仅支持 x86 q35 + UEFI
+func (s *SKVMGuestInstance) getConfidentialVmType() string {
+	cvmType := s.Desc.Metadata[api.VM_METADATA_CONFIDENTIAL_VM]
+	if len(cvmType) == 0 {
+		return ""
+	}
+	if s.Desc.Bios != qemu.BIOS_UEFI || s.manager.host.IsAarch64() ||
+		s.getMachine() != api.VM_MACHINE_TYPE_Q35 {
+		return ""
+	}
+	return cvmType
+}
+
+func (s *SKVMGuestInstance) isConfidentialVm() bool {
+	return s.Desc.ConfidentialVm != nil
+}
+
+func (s *SKVMGuestInstance) initConfidentialVmDesc() error {
+	cvmType := s.getConfidentialVmType()
+	if len(cvmType) == 0 {
+		s.Desc.ConfidentialVm = nil
+		return nil
+	}
+	// 宿主机不支持时拒绝启动, 避免虚机以未加密方式运行
+	if !s.manager.host.IsConfidentialComputingSupported(cvmType) {
+		return errors.Errorf("host not support confidential vm type %s", cvmType)
+	}
+	cvm := &desc.SGuestConfidentialVm{Type: cvmType}
+	switch cvmType {
+	case api.CONFIDENTIAL_VM_TYPE_TDX:
+		cvm.Object = &desc.Object{ObjType: "tdx-guest", Id: "tdx0"}
+		s.Desc.MachineDesc.KernelIrqchip = "split"
+	case api.CONFIDENTIAL_VM_TYPE_SEV_SNP:
+		cvm.Object = &desc.Object{
+			ObjType: "sev-snp-guest",
+			Id:      "sev0",
+			Options: s.getSevOptions(SEV_SNP_POLICY),
+		}
+	case api.CONFIDENTIAL_VM_TYPE_SEV_ES:
+		cvm.Object = &desc.Object{
+			ObjType: "sev-guest",
+			Id:      "sev0",
+			Options: s.getSevOptions(SEV_POLICY_NODBG | SEV_POLICY_ES),
+		}
+	case api.CONFIDENTIAL_VM_TYPE_SEV:
+		cvm.Object = &desc.Object{
+			ObjType: "sev-guest",
+			Id:      "sev0",
+			Options: s.getSevOptions(SEV_POLICY_NODBG),
+		}
+	default:
+		return errors.Errorf("unknown confidential vm type %s", cvmType)
+	}
+	s.Desc.ConfidentialVm = cvm
+	s.Desc.MachineDesc.ConfidentialGuestSupport = cvm.Id
+	s.setConfidentialVirtioOptions()
+	return nil
+}
+
+func (s *SKVMGuestInstance) getSevOptions(policy int) map[string]string {
+	return map[string]string{
+		"cbitpos":           strconv.Itoa(options.HostOptions.SevCbitpos),
+		"reduced-phys-bits": strconv.Itoa(options.HostOptions.SevReducedPhysBits),
+		"policy":            "0x" + strconv.FormatInt(int64(policy), 16),
+	}
+}
+
+// 加密内存无法被 qemu 直接访问, virtio 设备需经 iommu 使用虚机内的共享缓冲区
+func (s *SKVMGuestInstance) setConfidentialVirtioOptions() {
+	devs := []*desc.PCIDevice{}
+	if s.Desc.VirtioSerial != nil {
+		devs = append(devs, s.Desc.VirtioSerial.PCIDevice)
+	}
+	if s.Desc.VirtioScsi != nil {
+		devs = append(devs, s.Desc.VirtioScsi.PCIDevice)
+	}
+	if s.Desc.VgaDevice != nil {
+		devs = append(devs, s.Desc.VgaDevice.PCIDevice)
+	}
+	for i := range s.Desc.Disks {
+		devs = append(devs, s.Desc.Disks[i].Pci)
+	}
+	for i := range s.Desc.Nics {
+		devs = append(devs, s.Desc.Nics[i].Pci)
+	}
+	if s.Desc.Rng != nil {
+		devs = append(devs, s.Desc.Rng.PCIDevice)
+	}
+	if s.Desc.Balloon != nil {
+		devs = append(devs, s.Desc.Balloon.PCIDevice)
+	}
+	for _, dev := range devs {
+		if dev == nil || !strings.HasPrefix(dev.DevType, "virtio-") {
+			continue
+		}
+		if dev.Options == nil {
+			dev.Options = map[string]string{}
+		}
+		dev.Options["iommu_platform"] = "on"
+	}
+}
+
+// sev-snp/tdx 固件不保存 UEFI 变量, 通过 -bios 加载
+func (s *SKVMGuestInstance) getConfidentialVmFirmware() string {
+	if !s.isConfidentialVm() {
+		return ""
+	}
+	switch s.Desc.ConfidentialVm.Type {
+	case api.CONFIDENTIAL_VM_TYPE_SEV_SNP, api.CONFIDENTIAL_VM_TYPE_TDX:
+		return options.HostOptions.OvmfConfidentialPath
+	}
+	return ""
+}
+
+func (s *SKVMGuestInstance) querySevLaunchMeasure() (string, error) {
+	ch := make(chan struct{}, 1)
+	var measurement, errMsg string
+	s.Monitor.QuerySevLaunchMeasure(func(m string, err string) {
+		measurement, errMsg = m, err
+		ch <- struct{}{}
+	})
+	select {
+	case <-ch:
+		if len(errMsg) > 0 {
+			return "", errors.Error(errMsg)
+		}
+		return measurement, nil
+	case <-time.After(sevLaunchMeasureTimeout):
+		return "", errors.Errorf("query sev launch measure timeout")
+	}
+}
+
+// ConfidentialVmAttestation 返回 SEV 虚机的启动度量值, sev-snp/tdx 的证明报告由虚机内部生成
+func (m *SGuestManager) ConfidentialVmAttestation(sid string) (*api.ServerConfidentialVmAttestationOutput, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found guest by id %s", sid)
+	}
+	if !guest.IsRunning() || guest.Monitor == nil {
+		return nil, httperrors.NewInvalidStatusError("guest %s is not running", sid)
+	}
+	if !guest.isConfidentialVm() {
+		return nil, httperrors.NewBadRequestError("guest %s is not a confidential vm", sid)
+	}
+	cvmType := guest.Desc.ConfidentialVm.Type
+	if cvmType != api.CONFIDENTIAL_VM_TYPE_SEV && cvmType != api.CONFIDENTIAL_VM_TYPE_SEV_ES {
+		return nil, httperrors.NewUnsupportOperationError("launch measurement is not available for %s", cvmType)
+	}
+	measurement, err := guest.querySevLaunchMeasure()
+	if err != nil {
+		return nil, errors.Wrap(err, "query-sev-launch-measure")
+	}
+	return &api.ServerConfidentialVmAttestationOutput{
+		Type:        cvmType,
+		Measurement: measurement,
+	}, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"sort"
+	"sync"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	// 控制台端口序号上限, 实际监听端口为 VNC_PORT_BASE + port
+	CONSOLE_PORT_MAX = 5000
+
+	// QMP 监听端口相对 HMP 的偏移, 见 GetQmpMonitorPort
+	QMP_MONITOR_PORT_OFFSET = 200
+)
+
+// 每个控制台端口序号会同时占用 VNC/SPICE, HMP, QMP 三个监听端口
+func isConsolePortListening(port int) bool {
+	return netutils2.IsTcpPortUsed("0.0.0.0", VNC_PORT_BASE+port) ||
+		netutils2.IsTcpPortUsed("127.0.0.1", MONITOR_PORT_BASE+port) ||
+		netutils2.IsTcpPortUsed("127.0.0.1", MONITOR_PORT_BASE+QMP_MONITOR_PORT_OFFSET+port)
+}
+
+type SConsolePortUsage struct {
+	Total       int            `json:"total"`
+	Used        int            `json:"used"`
+	Free        int            `json:"free"`
+	Utilization float64        `json:"utilization"`
+	Ports       map[int]string `json:"ports"`
+}
+
+// 记录主机上控制台端口的分配情况, 端口按虚拟机回收
+type sConsolePortRegistry struct {
+	lock     sync.Mutex
+	maxPort  int
+	lastUsed int
+	owners   map[int]string
+	ports    map[string]int
+
+	isListening func(port int) bool
+}
+
+func newConsolePortRegistry(maxPort int, isListening func(port int) bool) *sConsolePortRegistry {
+	return &sConsolePortRegistry{
+		maxPort:     maxPort,
+		owners:      map[int]string{},
+		ports:       map[string]int{},
+		isListening: isListening,
+	}
+}
+
+// port 的 HMP 与 port-200 的 QMP 相同, QMP 与 port+200 的 HMP 相同
+func (r *sConsolePortRegistry) isConflict(owner string, port int) bool {
+	for _, p := range []int{port, port - QMP_MONITOR_PORT_OFFSET, port + QMP_MONITOR_PORT_OFFSET} {
+		if o, ok := r.owners[p]; ok && o != owner {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *sConsolePortRegistry) release(owner string) {
+	if port, ok := r.ports[owner]; ok {
+		delete(r.owners, port)
+		delete(r.ports, owner)
+	}
+}
+
+// 为虚拟机分配端口, 会先回收该虚拟机之前占用的端口
+func (r *sConsolePortRegistry) Allocate(owner string) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.release(owner)
+	for i := 1; i <= r.maxPort; i++ {
+		port := (r.lastUsed+i-1)%r.maxPort + 1
+		if r.isConflict(owner, port) {
+			continue
+		}
+		if r.isListening != nil && r.isListening(port) {
+			continue
+		}
+		r.owners[port] = owner
+		r.ports[owner] = port
+		r.lastUsed = port
+		return port, nil
+	}
+	return -1, errors.Wrapf(errors.ErrNotFound, "no free console port in 1-%d", r.maxPort)
+}
+
+// 登记已运行虚拟机正在使用的端口
+func (r *sConsolePortRegistry) Reserve(owner string, port int) {
+	if port <= 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.isConflict(owner, port) {
+		log.Warningf("console port %d of %s conflicts with %s", port, owner, r.owners[port])
+	}
+	r.release(owner)
+	r.owners[port] = owner
+	r.ports[owner] = port
+}
+
+func (r *sConsolePortRegistry) Release(owner string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.release(owner)
+}
+
+func (r *sConsolePortRegistry) Usage() SConsolePortUsage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	usage := SConsolePortUsage{
+		Total: r.maxPort,
+		Used:  len(r.owners),
+		Ports: make(map[int]string, len(r.owners)),
+	}
+	for port, owner := range r.owners {
+		usage.Ports[port] = owner
+	}
+	usage.Free = usage.Total - usage.Used
+	if usage.Total > 0 {
+		usage.Utilization = float64(usage.Used) / float64(usage.Total)
+	}
+	return usage
+}
+
+func (r *sConsolePortRegistry) usedPorts() []int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ports := make([]int, 0, len(r.owners))
+	for port := range r.owners {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func (m *SGuestManager) initConsolePorts() {
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest, ok := v.(*SKVMGuestInstance)
+		if ok && guest.IsRunning() {
+			m.consolePorts.Reserve(guest.Id, guest.GetVncPort())
+		}
+		return true
+	})
+	log.Infof("console port registry initialized, ports in use: %v", m.consolePorts.usedPorts())
+}
+
+func (m *SGuestManager) AllocVncPort(sid string) (int, error) {
+	return m.consolePorts.Allocate(sid)
+}
+
+func (m *SGuestManager) ReleaseVncPort(sid string) {
+	m.consolePorts.Release(sid)
+}
+
+func (m *SGuestManager) GetConsolePortUsage() SConsolePortUsage {
+	return m.consolePorts.Usage()
+}
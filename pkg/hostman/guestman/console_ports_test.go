@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"testing"
+)
+
+func TestConsolePortRegistryAllocate(t *testing.T) {
+	listening := map[int]bool{2: true}
+	r := newConsolePortRegistry(300, func(port int) bool { return listening[port] })
+
+	p1, err := r.Allocate("g1")
+	if err != nil || p1 != 1 {
+		t.Fatalf("g1 got %d, %v", p1, err)
+	}
+	// 端口 2 已被其他进程监听
+	p2, _ := r.Allocate("g2")
+	if p2 != 3 {
+		t.Errorf("g2 got %d, want 3", p2)
+	}
+	// 重新分配会回收旧端口
+	p1, _ = r.Allocate("g1")
+	if p1 != 4 {
+		t.Errorf("g1 reallocate got %d, want 4", p1)
+	}
+	if usage := r.Usage(); usage.Used != 2 || usage.Free != 298 {
+		t.Errorf("usage %#v", usage)
+	}
+	r.Release("g1")
+	r.Release("g2")
+	if usage := r.Usage(); usage.Used != 0 {
+		t.Errorf("usage after release %#v", usage)
+	}
+}
+
+func TestConsolePortRegistryMonitorConflict(t *testing.T) {
+	r := newConsolePortRegistry(300, nil)
+	r.Reserve("g1", 201)
+	// 1 的 QMP 端口与 201 的 HMP 端口相同
+	for i := 0; i < 10; i++ {
+		port, err := r.Allocate("g2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port == 1 || port == 201 || port == 401 {
+			t.Fatalf("allocated conflict port %d", port)
+		}
+	}
+}
+
+func TestConsolePortRegistryExhausted(t *testing.T) {
+	r := newConsolePortRegistry(2, nil)
+	r.Allocate("g1")
+	r.Allocate("g2")
+	if _, err := r.Allocate("g3"); err == nil {
+		t.Error("expect error when ports exhausted")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpupin
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+type SNumaNode struct {
+	Id int
+	// 逻辑 CPU 按物理核排列, 同一物理核的超线程相邻
+	Cpus []int
+}
+
+type sNode struct {
+	id         int
+	cpus       []int
+	sharedLoad int
+}
+
+// SAllocator 在宿主机可用 CPU 上为虚机分配 vCPU 绑定
+// dedicated 策略独占逻辑 CPU, shared 策略在 NUMA 节点内未被独占的 CPU 上浮动
+type SAllocator struct {
+	nodes     []*sNode
+	pool      cpuset.CPUSet
+	dedicated map[int]bool
+}
+
+// NewAllocator 根据 NUMA 拓扑创建分配器, reserved 中的 CPU 不参与分配
+func NewAllocator(nodes []SNumaNode, reserved cpuset.CPUSet) *SAllocator {
+	a := &SAllocator{
+		dedicated: map[int]bool{},
+	}
+	builder := cpuset.NewBuilder()
+	for _, node := range nodes {
+		n := &sNode{id: node.Id}
+		for _, cpu := range node.Cpus {
+			if reserved.Contains(cpu) {
+				continue
+			}
+			n.cpus = append(n.cpus, cpu)
+			builder.Add(cpu)
+		}
+		if len(n.cpus) > 0 {
+			a.nodes = append(a.nodes, n)
+		}
+	}
+	a.pool = builder.Result()
+	return a
+}
+
+// Pool 返回参与分配的全部 CPU
+func (a *SAllocator) Pool() cpuset.CPUSet {
+	return a.pool
+}
+
+func (a *SAllocator) nodeFree(n *sNode) []int {
+	free := []int{}
+	for _, cpu := range n.cpus {
+		if !a.dedicated[cpu] {
+			free = append(free, cpu)
+		}
+	}
+	return free
+}
+
+func (a *SAllocator) nodeOf(cpu int) *sNode {
+	for _, n := range a.nodes {
+		for _, c := range n.cpus {
+			if c == cpu {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// Claim 登记已存在的绑定, 绑定的 CPU 已不可用或与其它独占绑定冲突时返回 false, 需重新分配
+// 应先登记所有 dedicated 绑定, 再登记 shared 绑定
+func (a *SAllocator) Claim(policy string, pins []cpuset.CPUSet) bool {
+	if len(pins) == 0 {
+		return false
+	}
+	switch policy {
+	case compute.VM_CPU_PIN_POLICY_DEDICATED:
+		claimed := map[int]bool{}
+		for _, pin := range pins {
+			if pin.Size() != 1 || !pin.IsSubsetOf(a.pool) {
+				return false
+			}
+			cpu := pin.ToSlice()[0]
+			if a.dedicated[cpu] || claimed[cpu] {
+				return false
+			}
+			claimed[cpu] = true
+		}
+		for cpu := range claimed {
+			a.dedicated[cpu] = true
+		}
+		return true
+	case compute.VM_CPU_PIN_POLICY_SHARED:
+		for _, pin := range pins {
+			if pin.IsEmpty() || !pin.IsSubsetOf(a.pool) {
+				return false
+			}
+			for _, cpu := range pin.ToSlice() {
+				if a.dedicated[cpu] {
+					return false
+				}
+			}
+		}
+		if n := a.nodeOf(pins[0].ToSlice()[0]); n != nil {
+			n.sharedLoad += len(pins)
+		}
+		return true
+	}
+	return false
+}
+
+// Allocate 按策略为 vcpus 个 vCPU 分配绑定, 返回值下标为 vCPU 序号
+func (a *SAllocator) Allocate(policy string, vcpus int) ([]cpuset.CPUSet, error) {
+	if vcpus <= 0 {
+		return nil, errors.Errorf("invalid vcpu count %d", vcpus)
+	}
+	switch policy {
+	case compute.VM_CPU_PIN_POLICY_DEDICATED:
+		return a.allocateDedicated(vcpus)
+	case compute.VM_CPU_PIN_POLICY_SHARED:
+		return a.allocateShared(vcpus)
+	}
+	return nil, errors.Errorf("unknown cpu pin policy %q", policy)
+}
+
+func (a *SAllocator) allocateDedicated(vcpus int) ([]cpuset.CPUSet, error) {
+	nodes := make([]*sNode, len(a.nodes))
+	copy(nodes, a.nodes)
+	// 优先选择能容纳全部 vCPU 且空闲 CPU 最少的节点, 减少碎片
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return len(a.nodeFree(nodes[i])) < len(a.nodeFree(nodes[j]))
+	})
+	cpus := []int{}
+	for _, n := range nodes {
+		if free := a.nodeFree(n); len(free) >= vcpus {
+			cpus = free[:vcpus]
+			break
+		}
+	}
+	if len(cpus) == 0 {
+		// 单个节点不足时跨节点分配, 从空闲最多的节点开始
+		for i := len(nodes) - 1; i >= 0 && len(cpus) < vcpus; i-- {
+			free := a.nodeFree(nodes[i])
+			if need := vcpus - len(cpus); len(free) > need {
+				free = free[:need]
+			}
+			cpus = append(cpus, free...)
+		}
+	}
+	if len(cpus) < vcpus {
+		return nil, errors.Errorf("insufficient free cpus for %d dedicated vcpus, %d available", vcpus, len(cpus))
+	}
+	pins := make([]cpuset.CPUSet, vcpus)
+	for i, cpu := range cpus {
+		a.dedicated[cpu] = true
+		pins[i] = cpuset.NewCPUSet(cpu)
+	}
+	return pins, nil
+}
+
+func (a *SAllocator) allocateShared(vcpus int) ([]cpuset.CPUSet, error) {
+	var (
+		best     *sNode
+		bestFree []int
+	)
+	// 选择 shared 负载与空闲 CPU 比例最低的节点
+	for _, n := range a.nodes {
+		free := a.nodeFree(n)
+		if len(free) == 0 {
+			continue
+		}
+		if best == nil || n.sharedLoad*len(bestFree) < best.sharedLoad*len(free) {
+			best, bestFree = n, free
+		}
+	}
+	if best == nil {
+		return nil, errors.Errorf("no shared cpus available")
+	}
+	set := cpuset.NewCPUSet(bestFree...)
+	if len(bestFree) < vcpus {
+		// 节点内 CPU 少于 vCPU 数时在全部未独占的 CPU 上浮动
+		set = a.pool.Filter(func(cpu int) bool { return !a.dedicated[cpu] })
+	}
+	best.sharedLoad += vcpus
+	pins := make([]cpuset.CPUSet, vcpus)
+	for i := range pins {
+		pins[i] = set
+	}
+	return pins, nil
+}
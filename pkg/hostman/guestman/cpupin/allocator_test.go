@@ -0,0 +1,87 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpupin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+func newTestAllocator() *SAllocator {
+	nodes := []SNumaNode{
+		{Id: 0, Cpus: []int{0, 8, 1, 9, 2, 10, 3, 11}},
+		{Id: 1, Cpus: []int{4, 12, 5, 13, 6, 14, 7, 15}},
+	}
+	return NewAllocator(nodes, cpuset.NewCPUSet(0, 8))
+}
+
+func pinsString(pins []cpuset.CPUSet) []string {
+	ret := make([]string, len(pins))
+	for i := range pins {
+		ret[i] = pins[i].String()
+	}
+	return ret
+}
+
+func TestAllocateDedicated(t *testing.T) {
+	a := newTestAllocator()
+	assert.Equal(t, "1-7,9-15", a.Pool().String())
+
+	// node 0 has 6 free cpus, best fit for 4 vcpus
+	pins, err := a.Allocate(compute.VM_CPU_PIN_POLICY_DEDICATED, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "9", "2", "10"}, pinsString(pins))
+
+	// node 0 has 2 free cpus left, node 1 fits
+	pins, err = a.Allocate(compute.VM_CPU_PIN_POLICY_DEDICATED, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "12", "5", "13", "6", "14"}, pinsString(pins))
+
+	// spread across nodes
+	pins, err = a.Allocate(compute.VM_CPU_PIN_POLICY_DEDICATED, 3)
+	assert.NoError(t, err)
+	assert.Len(t, pins, 3)
+
+	_, err = a.Allocate(compute.VM_CPU_PIN_POLICY_DEDICATED, 2)
+	assert.Error(t, err)
+}
+
+func TestAllocateShared(t *testing.T) {
+	a := newTestAllocator()
+	assert.True(t, a.Claim(compute.VM_CPU_PIN_POLICY_DEDICATED,
+		[]cpuset.CPUSet{cpuset.NewCPUSet(4), cpuset.NewCPUSet(12)}))
+	// conflicts with claimed dedicated cpus
+	assert.False(t, a.Claim(compute.VM_CPU_PIN_POLICY_DEDICATED, []cpuset.CPUSet{cpuset.NewCPUSet(4)}))
+	// reserved cpu is not available
+	assert.False(t, a.Claim(compute.VM_CPU_PIN_POLICY_SHARED, []cpuset.CPUSet{cpuset.NewCPUSet(0, 1)}))
+
+	pins, err := a.Allocate(compute.VM_CPU_PIN_POLICY_SHARED, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1-3,9-11", "1-3,9-11"}, pinsString(pins))
+
+	// node 0 is loaded, next shared guest goes to node 1
+	pins, err = a.Allocate(compute.VM_CPU_PIN_POLICY_SHARED, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5-7,13-15", "5-7,13-15"}, pinsString(pins))
+
+	// vcpus more than node cpus float on all shared cpus
+	pins, err = a.Allocate(compute.VM_CPU_PIN_POLICY_SHARED, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, "1-3,5-7,9-11,13-15", pins[0].String())
+}
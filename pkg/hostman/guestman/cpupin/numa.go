@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpupin
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+// SGuestNumaLayout 虚机 NUMA 节点的 CPU 范围 [CpuStart, CpuEnd) 及内存, 绑定到宿主机节点 HostNode
+type SGuestNumaLayout struct {
+	HostNode int
+	CpuStart int
+	CpuEnd   int
+	SizeMB   int64
+}
+
+func (l SGuestNumaLayout) Cpus() cpuset.CPUSet {
+	cpus := make([]int, 0, l.CpuEnd-l.CpuStart)
+	for i := l.CpuStart; i < l.CpuEnd; i++ {
+		cpus = append(cpus, i)
+	}
+	return cpuset.NewCPUSet(cpus...)
+}
+
+// PlanGuestNuma 将启动 vCPU 及内存平均分配到 hostNodes 对应的虚机节点, 热插拔 CPU 归入最后一个节点,
+// 每个节点内存按 alignMB 对齐, 余数归入第一个节点; 内存不足以对齐时减少节点数
+func PlanGuestNuma(hostNodes []int, vcpus, maxCpus int, memMB, alignMB int64) ([]SGuestNumaLayout, error) {
+	if len(hostNodes) == 0 {
+		return nil, errors.Errorf("no host numa nodes")
+	}
+	if vcpus <= 0 || maxCpus < vcpus {
+		return nil, errors.Errorf("invalid vcpus %d maxcpus %d", vcpus, maxCpus)
+	}
+	if alignMB <= 0 {
+		alignMB = 1
+	}
+	n := len(hostNodes)
+	if n > vcpus {
+		n = vcpus
+	}
+	for n > 1 && memMB/int64(n)/alignMB == 0 {
+		n--
+	}
+	perMem := memMB / int64(n) / alignMB * alignMB
+	if perMem == 0 || memMB%alignMB != 0 {
+		return nil, errors.Errorf("memory %dMB not aligned to %dMB", memMB, alignMB)
+	}
+	layouts := make([]SGuestNumaLayout, n)
+	start := 0
+	for i := 0; i < n; i++ {
+		count := vcpus / n
+		if i < vcpus%n {
+			count++
+		}
+		layouts[i] = SGuestNumaLayout{
+			HostNode: hostNodes[i],
+			CpuStart: start,
+			CpuEnd:   start + count,
+			SizeMB:   perMem,
+		}
+		start += count
+	}
+	layouts[n-1].CpuEnd = maxCpus
+	layouts[0].SizeMB += memMB - perMem*int64(n)
+	return layouts, nil
+}
+
+// NodeCpus 返回宿主机 NUMA 节点上参与分配的 CPU
+func (a *SAllocator) NodeCpus(id int) cpuset.CPUSet {
+	for _, n := range a.nodes {
+		if n.id == id {
+			return cpuset.NewCPUSet(n.cpus...)
+		}
+	}
+	return cpuset.NewCPUSet()
+}
+
+// SelectNumaNodes 选择空闲 CPU 最多的宿主机节点放置虚机 NUMA 节点,
+// count 为 0 时选择能容纳全部 vCPU 的最少节点数; count 超过宿主机节点数时节点被重复使用
+func (a *SAllocator) SelectNumaNodes(vcpus, count int) ([]int, error) {
+	if len(a.nodes) == 0 {
+		return nil, errors.Errorf("no numa nodes available")
+	}
+	nodes := make([]*sNode, len(a.nodes))
+	copy(nodes, a.nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return len(a.nodeFree(nodes[i])) > len(a.nodeFree(nodes[j]))
+	})
+	if count <= 0 {
+		n, free := 0, 0
+		for n < len(nodes) && free < vcpus {
+			free += len(a.nodeFree(nodes[n]))
+			n++
+		}
+		ids := make([]int, n)
+		for i := range ids {
+			ids[i] = nodes[i].id
+		}
+		sort.Ints(ids)
+		return ids, nil
+	}
+	ids := make([]int, count)
+	for i := range ids {
+		ids[i] = nodes[i%len(nodes)].id
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// AllocateOnNodes 在 vcpuNodes 指定的宿主机节点上分配绑定, vcpuNodes 下标为 vCPU 序号
+func (a *SAllocator) AllocateOnNodes(policy string, vcpuNodes []int) ([]cpuset.CPUSet, error) {
+	if len(vcpuNodes) == 0 {
+		return nil, errors.Errorf("empty vcpu nodes")
+	}
+	pins := make([]cpuset.CPUSet, len(vcpuNodes))
+	switch policy {
+	case compute.VM_CPU_PIN_POLICY_DEDICATED:
+		used := map[int]int{}
+		for i, id := range vcpuNodes {
+			free := a.nodeFree(a.nodeById(id))
+			if used[id] >= len(free) {
+				return nil, errors.Errorf("insufficient free cpus on numa node %d", id)
+			}
+			pins[i] = cpuset.NewCPUSet(free[used[id]])
+			used[id]++
+		}
+		for _, pin := range pins {
+			a.dedicated[pin.ToSlice()[0]] = true
+		}
+	case compute.VM_CPU_PIN_POLICY_SHARED:
+		for i, id := range vcpuNodes {
+			n := a.nodeById(id)
+			free := a.nodeFree(n)
+			if len(free) == 0 {
+				return nil, errors.Errorf("no shared cpus on numa node %d", id)
+			}
+			pins[i] = cpuset.NewCPUSet(free...)
+			n.sharedLoad++
+		}
+	default:
+		return nil, errors.Errorf("unknown cpu pin policy %q", policy)
+	}
+	return pins, nil
+}
+
+// NodesOf 返回 cpus 所在的宿主机 NUMA 节点
+func (a *SAllocator) NodesOf(cpus cpuset.CPUSet) []int {
+	ids := []int{}
+	for _, n := range a.nodes {
+		for _, cpu := range n.cpus {
+			if cpus.Contains(cpu) {
+				ids = append(ids, n.id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func (a *SAllocator) nodeById(id int) *sNode {
+	for _, n := range a.nodes {
+		if n.id == id {
+			return n
+		}
+	}
+	return &sNode{id: id}
+}
+
+// EmulatorCpus 返回 qemu 主线程及 iothread 可用的 CPU: 优先使用 nodes 上未被独占且不属于 exclude 的 CPU,
+// 其次为全部未被独占且不属于 exclude 的 CPU, 都没有时使用未被独占的 CPU
+func (a *SAllocator) EmulatorCpus(nodes []int, exclude cpuset.CPUSet) cpuset.CPUSet {
+	shared := a.pool.Filter(func(cpu int) bool { return !a.dedicated[cpu] })
+	builder := cpuset.NewBuilder()
+	for _, id := range nodes {
+		builder.Add(a.nodeFree(a.nodeById(id))...)
+	}
+	if set := builder.Result().Difference(exclude); !set.IsEmpty() {
+		return set
+	}
+	if set := shared.Difference(exclude); !set.IsEmpty() {
+		return set
+	}
+	if !shared.IsEmpty() {
+		return shared
+	}
+	return a.pool
+}
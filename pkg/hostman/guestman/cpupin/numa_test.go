@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cpupin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+func TestPlanGuestNuma(t *testing.T) {
+	layouts, err := PlanGuestNuma([]int{0, 1}, 5, 8, 4097, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []SGuestNumaLayout{
+		{HostNode: 0, CpuStart: 0, CpuEnd: 3, SizeMB: 2049},
+		{HostNode: 1, CpuStart: 3, CpuEnd: 8, SizeMB: 2048},
+	}, layouts)
+	assert.Equal(t, "3-7", layouts[1].Cpus().String())
+
+	// 1G 大页下内存不足两个节点时只生成一个节点
+	layouts, err = PlanGuestNuma([]int{0, 1}, 4, 4, 1024, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, []SGuestNumaLayout{{HostNode: 0, CpuStart: 0, CpuEnd: 4, SizeMB: 1024}}, layouts)
+
+	// 节点数不超过 vCPU 数
+	layouts, err = PlanGuestNuma([]int{0, 1}, 1, 2, 2048, 1)
+	assert.NoError(t, err)
+	assert.Len(t, layouts, 1)
+
+	_, err = PlanGuestNuma([]int{0, 1}, 4, 4, 3000, 1024)
+	assert.Error(t, err)
+}
+
+func TestSelectNumaNodes(t *testing.T) {
+	a := newTestAllocator()
+	// node 0 has 6 free cpus, node 1 has 8
+	ids, err := a.SelectNumaNodes(4, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, ids)
+
+	ids, err = a.SelectNumaNodes(10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, ids)
+
+	ids, err = a.SelectNumaNodes(4, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 1}, ids)
+}
+
+func TestAllocateOnNodes(t *testing.T) {
+	a := newTestAllocator()
+	pins, err := a.AllocateOnNodes(compute.VM_CPU_PIN_POLICY_DEDICATED, []int{0, 0, 1, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "9", "4", "12"}, pinsString(pins))
+
+	pins, err = a.AllocateOnNodes(compute.VM_CPU_PIN_POLICY_SHARED, []int{0, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2-3,10-11", "5-7,13-15"}, pinsString(pins))
+
+	_, err = a.AllocateOnNodes(compute.VM_CPU_PIN_POLICY_DEDICATED, []int{0, 0, 0, 0, 0})
+	assert.Error(t, err)
+
+	// qemu 线程避开虚机 vCPU 及其它虚机独占的 CPU
+	assert.Equal(t, "3,11", a.EmulatorCpus([]int{0}, cpuset.NewCPUSet(2, 10)).String())
+	// 节点上没有可用 CPU 时使用其它节点
+	assert.Equal(t, "5-7,13-15", a.EmulatorCpus([]int{0}, cpuset.NewCPUSet(2, 3, 10, 11)).String())
+}
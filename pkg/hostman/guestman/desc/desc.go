@@ -15,6 +15,8 @@
 package desc
 
 import (
+	"fmt"
+
 	"yunion.io/x/jsonutils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
@@ -62,6 +64,32 @@ type SGuestMem struct {
 	Mem    *Object `json:",omitempty"`
 
 	MemSlots []*SMemSlot `json:",omitempty"`
+
+	VirtioMem *SGuestVirtioMem `json:",omitempty"`
+
+	// 多个 NUMA 节点时第一个节点使用 Mem 作为内存后端
+	NumaNodes []*SGuestNumaNode `json:",omitempty"`
+}
+
+// 虚机 NUMA 节点及其绑定的宿主机节点
+type SGuestNumaNode struct {
+	NodeId   int
+	HostNode int
+	// cpuset 格式的虚机 CPU 序号, 包含热插拔 CPU
+	Cpus   string
+	SizeMB int64
+	MemObj *Object
+}
+
+// virtio-mem 设备，在 SizeMB 大小的区域内按块热插拔/热拔内存
+type SGuestVirtioMem struct {
+	*PCIDevice `json:",omitempty"`
+
+	MemObj *Object
+
+	SizeMB          int64
+	RequestedSizeMB int64
+	BlockSizeMB     int64
 }
 
 type SGuestHardwareDesc struct {
@@ -89,6 +117,11 @@ type SGuestHardwareDesc struct {
 	Vdi       string
 	VdiDevice *SGuestVdi `json:",omitempty"`
 
+	// spice usb redirect channels, default 2
+	SpiceUsbredirChannels *int `json:"spice_usbredir_channels,omitempty"`
+	// spice monitors, qxl and virtio vga only
+	VdiMonitors int `json:"vdi_monitors,omitempty"`
+
 	VirtioScsi      *SGuestVirtioScsi       `json:",omitempty"`
 	PvScsi          *SGuestPvScsi           `json:",omitempty"`
 	Cdroms          []*SGuestCdrom          `json:"cdroms,omitempty"`
@@ -97,17 +130,41 @@ type SGuestHardwareDesc struct {
 	Nics            []*SGuestNetwork        `json:",omitempty"`
 	NicsStandby     []*SGuestNetwork        `json:",omitempty"`
 	IsolatedDevices []*SGuestIsolatedDevice `json:",omitempty"`
+	SharedDirs      []*SGuestSharedDir      `json:",omitempty"`
 
 	// Random Number Generator Device
 	Rng       *SGuestRng       `json:",omitempty"`
 	Qga       *SGuestQga       `json:",omitempty"`
 	Pvpanic   *SGuestPvpanic   `json:",omitempty"`
 	IsaSerial *SGuestIsaSerial `json:",omitempty"`
+	Tpm       *SGuestTpm       `json:",omitempty"`
+	Watchdog  *SGuestWatchdog  `json:",omitempty"`
+	Balloon   *SGuestBalloon   `json:",omitempty"`
+
+	ConfidentialVm *SGuestConfidentialVm `json:",omitempty"`
 
 	Usb            *UsbController   `json:",omitempty"`
 	PCIControllers []*PCIController `json:",omitempty"`
 
 	AnonymousPCIDevs []*PCIDevice `json:",omitempty"`
+
+	// 按 iothread 分配策略生成的 iothread 对象
+	IOThreads []string `json:",omitempty"`
+	// vCPU 绑定结果, 由宿主机按绑定策略分配
+	VcpuPin *SGuestVcpuPin `json:",omitempty"`
+}
+
+type SGuestVcpuPin struct {
+	Policy string
+	Vcpus  []SVcpuPin
+	// qemu 主线程及 iothread 使用的宿主机 CPU, 避开 vCPU 所在的 CPU
+	EmulatorPcpus string `json:",omitempty"`
+}
+
+type SVcpuPin struct {
+	Vcpu int
+	// cpuset 格式的宿主机逻辑 CPU, 如 3 或 0-7,16-23
+	Pcpus string
 }
 
 type SGuestIsaSerial struct {
@@ -115,6 +172,34 @@ type SGuestIsaSerial struct {
 	Id  string
 }
 
+// TPM 2.0 emulated by swtpm, state persisted in StateDir
+type SGuestTpm struct {
+	Id       string
+	DevType  string
+	Socket   *CharDev
+	StateDir string
+}
+
+// i6300esb 为 PCI 设备, ib700 为 ISA 设备没有 PCI 地址
+type SGuestWatchdog struct {
+	Pci    *PCIDevice `json:",omitempty"`
+	Id     string
+	Model  string
+	Action string
+}
+
+// AMD SEV/Intel TDX 机密计算, 虚机内存由 CPU 加密, 宿主机无法读取
+type SGuestConfidentialVm struct {
+	*Object
+
+	Type string
+}
+
+// virtio-balloon 设备, 宿主机内存不足时用于回收虚机内存
+type SGuestBalloon struct {
+	*PCIDevice `json:",omitempty"`
+}
+
 type SGuestQga struct {
 	Socket     *CharDev
 	SerialPort *VirtSerialPort
@@ -146,8 +231,19 @@ type PCIController struct {
 type SGuestMachine struct {
 	Accel string
 
+	// versioned machine type, e.g. pc-i440fx-6.2, use machine alias if empty
+	Type string `json:",omitempty"`
+
 	// arm only
 	GicVersion *string `json:",omitempty"`
+
+	// x86 secure boot requires smm
+	Smm bool `json:",omitempty"`
+
+	// 机密计算对象 id, 如 sev0/tdx0
+	ConfidentialGuestSupport string `json:",omitempty"`
+	// tdx 要求 split irqchip
+	KernelIrqchip string `json:",omitempty"`
 }
 
 type SGuestDisk struct {
@@ -159,6 +255,21 @@ type SGuestDisk struct {
 	Scsi *SCSIDevice `json:",omitempty"`
 	// disk driver ide/sata
 	Ide *IDEDevice `json:",omitempty"`
+
+	// virtio-blk 磁盘使用的 iothread
+	IOThread string `json:",omitempty"`
+}
+
+// Bps 单位为 MB/s, 转换为 qemu -drive throttling 参数
+func (d *SGuestDisk) ThrottlingOptions() map[string]string {
+	opts := map[string]string{}
+	if d.Bps > 0 {
+		opts["throttling.bps-total"] = fmt.Sprintf("%d", int64(d.Bps)*1024*1024)
+	}
+	if d.Iops > 0 {
+		opts["throttling.iops-total"] = fmt.Sprintf("%d", d.Iops)
+	}
+	return opts
 }
 
 // -device ide-cd,drive=ide0-cd0,bus=ide.1
@@ -205,8 +316,19 @@ type SGuestIsolatedDevice struct {
 	Usb      *UsbDevice
 }
 
+// virtio-fs shared dir, backend served by virtiofsd over vhost-user socket
+type SGuestSharedDir struct {
+	api.GuestSharedDirJsonDesc
+
+	Socket *CharDev
+	Pci    *PCIDevice `json:",omitempty"`
+}
+
 type SGuestVga struct {
 	*PCIDevice `json:",omitempty"`
+
+	// secondary qxl heads for multiple monitors
+	Heads []*PCIDevice `json:",omitempty"`
 }
 
 type SGuestRng struct {
@@ -278,6 +400,8 @@ type UsbRedirctDesc struct {
 
 	UsbRedirDev1 *UsbRedir
 	UsbRedirDev2 *UsbRedir
+
+	ExtraUsbRedirDevs []*UsbRedir `json:",omitempty"`
 }
 
 type UsbRedir struct {
@@ -330,6 +454,9 @@ type SGuestControlDesc struct {
 	SrcMacCheck        bool
 
 	EncryptKeyId string
+
+	// guaranteed|burstable|best-effort
+	QosClass string
 }
 
 type SGuestMetaDesc struct {
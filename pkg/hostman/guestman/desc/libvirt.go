@@ -0,0 +1,510 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package desc
+
+import (
+	"fmt"
+	"strings"
+
+	libvirtxml "github.com/libvirt/libvirt-go-xml"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+// SLibvirtDomainOptions 导出 libvirt domain 时需要的宿主机相关信息
+type SLibvirtDomainOptions struct {
+	Emulator string
+	Arch     string
+	Kvm      bool
+	VncPort  int
+}
+
+var libvirtVideoModels = map[string]string{
+	api.VM_VIDEO_STANDARD: "vga",
+	api.VM_VIDEO_QXL:      "qxl",
+	api.VM_VIDEO_VIRTIO:   "virtio",
+	"vmware":              "vmvga",
+	"cirrus":              "cirrus",
+}
+
+var libvirtBootDevs = map[byte]string{
+	'c': "hd",
+	'd': "cdrom",
+	'n': "network",
+}
+
+func libvirtDiskBus(driver string) string {
+	switch driver {
+	case api.DISK_DRIVER_VIRTIO:
+		return "virtio"
+	case api.DISK_DRIVER_IDE:
+		return "ide"
+	case api.DISK_DRIVER_SATA:
+		return "sata"
+	default:
+		return "scsi"
+	}
+}
+
+func libvirtDiskDev(bus string, idx int) string {
+	prefix := "sd"
+	switch bus {
+	case "virtio":
+		prefix = "vd"
+	case "ide":
+		prefix = "hd"
+	}
+	name := ""
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = string(rune('a'+(idx-1)%26)) + name
+	}
+	return prefix + name
+}
+
+func libvirtDiskSource(path string) *libvirtxml.DomainDiskSource {
+	if strings.HasPrefix(path, "rbd:") {
+		// rbd:pool/image:mon_host=...:key=..., 认证信息由使用者在 libvirt secret 中补充
+		name := strings.Split(strings.TrimPrefix(path, "rbd:"), ":")[0]
+		return &libvirtxml.DomainDiskSource{
+			Network: &libvirtxml.DomainDiskSourceNetwork{Protocol: "rbd", Name: name},
+		}
+	}
+	if strings.HasPrefix(path, "/dev/") {
+		return &libvirtxml.DomainDiskSource{Block: &libvirtxml.DomainDiskSourceBlock{Dev: path}}
+	}
+	return &libvirtxml.DomainDiskSource{File: &libvirtxml.DomainDiskSourceFile{File: path}}
+}
+
+// ToLibvirtDomain 将虚机描述转换为 libvirt domain 定义,
+// 仅覆盖 virsh 调试及迁移所需的主要设备, 云平台特有的配置(如网络脚本, 元数据服务等)不会导出
+func ToLibvirtDomain(guest *SGuestDesc, opts SLibvirtDomainOptions) *libvirtxml.Domain {
+	domain := &libvirtxml.Domain{
+		Type:          "qemu",
+		Name:          guest.Name,
+		UUID:          guest.Uuid,
+		Memory:        &libvirtxml.DomainMemory{Value: uint(guest.Mem), Unit: "MiB"},
+		CurrentMemory: &libvirtxml.DomainCurrentMemory{Value: uint(guest.Mem), Unit: "MiB"},
+		VCPU:          &libvirtxml.DomainVCPU{Placement: "static", Value: int(guest.Cpu)},
+		OS: &libvirtxml.DomainOS{
+			Type: &libvirtxml.DomainOSType{Type: "hvm", Arch: opts.Arch, Machine: guest.Machine},
+		},
+		Features: &libvirtxml.DomainFeatureList{
+			ACPI: &libvirtxml.DomainFeature{},
+			APIC: &libvirtxml.DomainFeatureAPIC{},
+		},
+		OnPoweroff: "destroy",
+		OnReboot:   "restart",
+		OnCrash:    "destroy",
+		Devices:    &libvirtxml.DomainDeviceList{Emulator: opts.Emulator},
+	}
+	if opts.Kvm {
+		domain.Type = "kvm"
+	}
+	if guest.Bios == "UEFI" {
+		domain.OS.Firmware = "efi"
+	}
+	if guest.MachineDesc != nil && guest.MachineDesc.Smm {
+		domain.Features.SMM = &libvirtxml.DomainFeatureSMM{State: "on"}
+	}
+	for i := 0; i < len(guest.BootOrder); i++ {
+		if dev, ok := libvirtBootDevs[guest.BootOrder[i]]; ok {
+			domain.OS.BootDevices = append(domain.OS.BootDevices, libvirtxml.DomainBootDevice{Dev: dev})
+		}
+	}
+
+	if cpu := guest.CpuDesc; cpu != nil {
+		domain.CPU = &libvirtxml.DomainCPU{}
+		if cpu.Model == "host" || len(cpu.Model) == 0 {
+			domain.CPU.Mode = "host-passthrough"
+		} else {
+			domain.CPU.Mode = "custom"
+			domain.CPU.Match = "exact"
+			domain.CPU.Model = &libvirtxml.DomainCPUModel{Value: cpu.Model, Fallback: "allow"}
+		}
+		if cpu.Sockets > 0 {
+			domain.CPU.Topology = &libvirtxml.DomainCPUTopology{
+				Sockets: int(cpu.Sockets), Cores: int(cpu.Cores), Threads: int(cpu.Threads),
+			}
+		}
+		if cpu.MaxCpus > uint(guest.Cpu) {
+			domain.VCPU.Current = fmt.Sprintf("%d", guest.Cpu)
+			domain.VCPU.Value = int(cpu.MaxCpus)
+		}
+	}
+	if guest.VcpuPin != nil && len(guest.VcpuPin.Vcpus) > 0 {
+		domain.CPUTune = &libvirtxml.DomainCPUTune{}
+		for _, pin := range guest.VcpuPin.Vcpus {
+			domain.CPUTune.VCPUPin = append(domain.CPUTune.VCPUPin, libvirtxml.DomainCPUTuneVCPUPin{
+				VCPU: uint(pin.Vcpu), CPUSet: pin.Pcpus,
+			})
+		}
+	}
+
+	// libvirt 的 iothread 以从 1 开始的序号标识
+	iothreadIds := map[string]uint{}
+	if len(guest.IOThreads) > 0 {
+		domain.IOThreadIDs = &libvirtxml.DomainIOThreadIDs{}
+		for i, id := range guest.IOThreads {
+			iothreadIds[id] = uint(i + 1)
+			domain.IOThreadIDs.IOThreads = append(domain.IOThreadIDs.IOThreads, libvirtxml.DomainIOThread{ID: uint(i + 1)})
+		}
+		domain.IOThreads = uint(len(guest.IOThreads))
+	}
+
+	busIdx := map[string]int{}
+	for _, disk := range guest.Disks {
+		bus := libvirtDiskBus(disk.Driver)
+		d := libvirtxml.DomainDisk{
+			Device: "disk",
+			Driver: &libvirtxml.DomainDiskDriver{Name: "qemu", Type: disk.Format, Cache: disk.CacheMode, IO: disk.AioMode},
+			Source: libvirtDiskSource(disk.Path),
+			Target: &libvirtxml.DomainDiskTarget{Dev: libvirtDiskDev(bus, busIdx[bus]), Bus: bus},
+		}
+		busIdx[bus]++
+		if bus == "virtio" {
+			if id, ok := iothreadIds[disk.IOThread]; ok {
+				d.Driver.IOThread = &id
+			}
+		}
+		if disk.BootIndex != nil && *disk.BootIndex >= 0 && len(domain.OS.BootDevices) == 0 {
+			d.Boot = &libvirtxml.DomainDeviceBoot{Order: uint(*disk.BootIndex) + 1}
+		}
+		domain.Devices.Disks = append(domain.Devices.Disks, d)
+	}
+	for _, cdrom := range guest.Cdroms {
+		bus := "ide"
+		if cdrom.Scsi != nil {
+			bus = "scsi"
+		}
+		d := libvirtxml.DomainDisk{
+			Device:   "cdrom",
+			Driver:   &libvirtxml.DomainDiskDriver{Name: "qemu", Type: "raw"},
+			Target:   &libvirtxml.DomainDiskTarget{Dev: libvirtDiskDev(bus, busIdx[bus]), Bus: bus},
+			ReadOnly: &libvirtxml.DomainDiskReadOnly{},
+		}
+		busIdx[bus]++
+		if len(cdrom.Path) > 0 {
+			d.Source = libvirtDiskSource(cdrom.Path)
+		}
+		domain.Devices.Disks = append(domain.Devices.Disks, d)
+	}
+	if busIdx["scsi"] > 0 {
+		model := "virtio-scsi"
+		if guest.PvScsi != nil {
+			model = "vmpvscsi"
+		}
+		domain.Devices.Controllers = append(domain.Devices.Controllers, libvirtxml.DomainController{
+			Type: "scsi", Model: model,
+		})
+	}
+
+	for _, nic := range guest.Nics {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+			continue
+		}
+		iface := libvirtxml.DomainInterface{
+			MAC:   &libvirtxml.DomainInterfaceMAC{Address: nic.Mac},
+			Model: &libvirtxml.DomainInterfaceModel{Type: nic.Driver},
+		}
+		if len(nic.Bridge) > 0 {
+			iface.Source = &libvirtxml.DomainInterfaceSource{
+				Bridge: &libvirtxml.DomainInterfaceSourceBridge{Bridge: nic.Bridge},
+			}
+			iface.VirtualPort = &libvirtxml.DomainInterfaceVirtualPort{
+				Params: &libvirtxml.DomainInterfaceVirtualPortParams{
+					OpenVSwitch: &libvirtxml.DomainInterfaceVirtualPortParamsOpenVSwitch{},
+				},
+			}
+		} else {
+			iface.Source = &libvirtxml.DomainInterfaceSource{Ethernet: &libvirtxml.DomainInterfaceSourceEthernet{}}
+		}
+		if len(nic.Ifname) > 0 {
+			iface.Target = &libvirtxml.DomainInterfaceTarget{Dev: nic.Ifname}
+		}
+		if nic.Vlan > 1 {
+			iface.VLan = &libvirtxml.DomainInterfaceVLan{Tags: []libvirtxml.DomainInterfaceVLanTag{{ID: uint(nic.Vlan)}}}
+		}
+		if nic.Mtu > 0 {
+			iface.MTU = &libvirtxml.DomainInterfaceMTU{Size: uint(nic.Mtu)}
+		}
+		domain.Devices.Interfaces = append(domain.Devices.Interfaces, iface)
+	}
+
+	if model, ok := libvirtVideoModels[guest.Vga]; ok {
+		video := libvirtxml.DomainVideo{Model: libvirtxml.DomainVideoModel{Type: model, Primary: "yes"}}
+		if guest.VdiMonitors > 1 {
+			video.Model.Heads = uint(guest.VdiMonitors)
+		}
+		domain.Devices.Videos = append(domain.Devices.Videos, video)
+	}
+	if guest.Vdi == api.VM_VDI_PROTOCOL_SPICE {
+		spice := &libvirtxml.DomainGraphicSpice{AutoPort: "yes"}
+		if opts.VncPort > 0 {
+			spice = &libvirtxml.DomainGraphicSpice{Port: 5900 + opts.VncPort, AutoPort: "no"}
+		}
+		domain.Devices.Graphics = append(domain.Devices.Graphics, libvirtxml.DomainGraphic{Spice: spice})
+	} else {
+		vnc := &libvirtxml.DomainGraphicVNC{AutoPort: "yes"}
+		if opts.VncPort > 0 {
+			vnc = &libvirtxml.DomainGraphicVNC{Port: 5900 + opts.VncPort, AutoPort: "no"}
+		}
+		domain.Devices.Graphics = append(domain.Devices.Graphics, libvirtxml.DomainGraphic{VNC: vnc})
+	}
+	if guest.Qga != nil {
+		domain.Devices.Channels = append(domain.Devices.Channels, libvirtxml.DomainChannel{
+			Source: &libvirtxml.DomainChardevSource{UNIX: &libvirtxml.DomainChardevSourceUNIX{Mode: "bind"}},
+			Target: &libvirtxml.DomainChannelTarget{
+				VirtIO: &libvirtxml.DomainChannelTargetVirtIO{Name: "org.qemu.guest_agent.0"},
+			},
+		})
+	}
+	if guest.Rng != nil {
+		domain.Devices.RNGs = append(domain.Devices.RNGs, libvirtxml.DomainRNG{
+			Model:   "virtio",
+			Backend: &libvirtxml.DomainRNGBackend{Random: &libvirtxml.DomainRNGBackendRandom{Device: "/dev/urandom"}},
+		})
+	}
+	return domain
+}
+
+func libvirtMemoryToMb(value uint, unit string) (int64, error) {
+	if len(unit) == 0 {
+		unit = "KiB"
+	}
+	var bytes uint64
+	switch strings.ToLower(unit[:1]) {
+	case "b":
+		bytes = uint64(value)
+	case "k":
+		bytes = uint64(value) * 1024
+	case "m":
+		bytes = uint64(value) * 1024 * 1024
+	case "g":
+		bytes = uint64(value) * 1024 * 1024 * 1024
+	case "t":
+		bytes = uint64(value) * 1024 * 1024 * 1024 * 1024
+	default:
+		return 0, errors.Errorf("unknown memory unit %s", unit)
+	}
+	return int64((bytes + 1024*1024 - 1) / (1024 * 1024)), nil
+}
+
+func libvirtDiskSourcePath(src *libvirtxml.DomainDiskSource) string {
+	switch {
+	case src == nil:
+		return ""
+	case src.File != nil:
+		return src.File.File
+	case src.Block != nil:
+		return src.Block.Dev
+	case src.Network != nil && src.Network.Protocol == "rbd":
+		return "rbd:" + src.Network.Name
+	}
+	return ""
+}
+
+// FromLibvirtDomain 尽力将 libvirt domain 定义转换为虚机描述,
+// 无法识别的设备不会中断转换, 以提示信息的形式返回
+func FromLibvirtDomain(domain *libvirtxml.Domain) (*SGuestDesc, []string, error) {
+	if domain == nil {
+		return nil, nil, errors.Errorf("libvirt domain is nil")
+	}
+	warnings := []string{}
+	guest := &SGuestDesc{}
+	guest.Name = domain.Name
+	guest.Uuid = domain.UUID
+
+	switch {
+	case domain.CurrentMemory != nil:
+		mem, err := libvirtMemoryToMb(domain.CurrentMemory.Value, domain.CurrentMemory.Unit)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "current memory")
+		}
+		guest.Mem = mem
+	case domain.Memory != nil:
+		mem, err := libvirtMemoryToMb(domain.Memory.Value, domain.Memory.Unit)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "memory")
+		}
+		guest.Mem = mem
+	default:
+		return nil, nil, errors.Errorf("libvirt domain missing memory config")
+	}
+	if domain.VCPU == nil {
+		return nil, nil, errors.Errorf("libvirt domain missing vcpu config")
+	}
+	guest.Cpu = int64(domain.VCPU.Value)
+	cpuDesc := &SGuestCpu{Cpus: uint(domain.VCPU.Value), MaxCpus: uint(domain.VCPU.Value)}
+	if len(domain.VCPU.Current) > 0 {
+		var current int64
+		if _, err := fmt.Sscanf(domain.VCPU.Current, "%d", &current); err == nil && current > 0 {
+			guest.Cpu = current
+			cpuDesc.Cpus = uint(current)
+		}
+	}
+	if domain.CPU != nil {
+		if domain.CPU.Mode == "host-passthrough" || domain.CPU.Mode == "host-model" {
+			cpuDesc.Model = "host"
+		} else if domain.CPU.Model != nil {
+			cpuDesc.Model = domain.CPU.Model.Value
+		}
+		if topo := domain.CPU.Topology; topo != nil {
+			cpuDesc.Sockets = uint(topo.Sockets)
+			cpuDesc.Cores = uint(topo.Cores)
+			cpuDesc.Threads = uint(topo.Threads)
+		}
+	}
+	guest.CpuDesc = cpuDesc
+	if domain.CPUTune != nil && len(domain.CPUTune.VCPUPin) > 0 {
+		guest.VcpuPin = &SGuestVcpuPin{}
+		for _, pin := range domain.CPUTune.VCPUPin {
+			guest.VcpuPin.Vcpus = append(guest.VcpuPin.Vcpus, SVcpuPin{Vcpu: int(pin.VCPU), Pcpus: pin.CPUSet})
+		}
+	}
+
+	if domain.OS != nil {
+		if domain.OS.Type != nil {
+			guest.Machine = domain.OS.Type.Machine
+			// libvirt 中的机型常带版本号, 如 pc-q35-6.2, pc-i440fx-6.2
+			if strings.Contains(guest.Machine, "q35") {
+				guest.Machine = api.VM_MACHINE_TYPE_Q35
+			} else if strings.HasPrefix(guest.Machine, "pc") {
+				guest.Machine = api.VM_MACHINE_TYPE_PC
+			}
+		}
+		if domain.OS.Firmware == "efi" || (domain.OS.Loader != nil && domain.OS.Loader.Type == "pflash") {
+			guest.Bios = "UEFI"
+		} else {
+			guest.Bios = "BIOS"
+		}
+		for _, boot := range domain.OS.BootDevices {
+			for c, dev := range libvirtBootDevs {
+				if dev == boot.Dev {
+					guest.BootOrder += string(c)
+				}
+			}
+		}
+	}
+
+	iothreads := map[uint]string{}
+	if domain.IOThreadIDs != nil {
+		for _, t := range domain.IOThreadIDs.IOThreads {
+			iothreads[t.ID] = fmt.Sprintf("iothread%d", t.ID)
+		}
+	} else {
+		for i := uint(1); i <= domain.IOThreads; i++ {
+			iothreads[i] = fmt.Sprintf("iothread%d", i)
+		}
+	}
+	for i := uint(1); i <= uint(len(iothreads)); i++ {
+		if id, ok := iothreads[i]; ok {
+			guest.IOThreads = append(guest.IOThreads, id)
+		}
+	}
+
+	if domain.Devices == nil {
+		return guest, warnings, nil
+	}
+	for _, disk := range domain.Devices.Disks {
+		path := libvirtDiskSourcePath(disk.Source)
+		switch disk.Device {
+		case "cdrom":
+			guest.Cdroms = append(guest.Cdroms, &SGuestCdrom{
+				Id: fmt.Sprintf("cd%d", len(guest.Cdroms)), Path: path, Ordinal: int64(len(guest.Cdroms)),
+			})
+			continue
+		case "", "disk":
+		default:
+			warnings = append(warnings, fmt.Sprintf("ignore %s device %s", disk.Device, path))
+			continue
+		}
+		if len(path) == 0 {
+			warnings = append(warnings, "ignore disk without supported source")
+			continue
+		}
+		d := &SGuestDisk{}
+		d.Path = path
+		d.Index = int8(len(guest.Disks))
+		d.Driver = api.DISK_DRIVER_SCSI
+		if disk.Target != nil {
+			switch disk.Target.Bus {
+			case "virtio", "ide", "sata":
+				d.Driver = disk.Target.Bus
+			}
+		}
+		if disk.Driver != nil {
+			d.Format = disk.Driver.Type
+			d.CacheMode = disk.Driver.Cache
+			d.AioMode = disk.Driver.IO
+			if disk.Driver.IOThread != nil {
+				d.IOThread = iothreads[*disk.Driver.IOThread]
+			}
+		}
+		if disk.Boot != nil && disk.Boot.Order > 0 {
+			idx := int8(disk.Boot.Order - 1)
+			d.BootIndex = &idx
+		}
+		guest.Disks = append(guest.Disks, d)
+	}
+	for _, iface := range domain.Devices.Interfaces {
+		if iface.MAC == nil {
+			warnings = append(warnings, "ignore interface without mac address")
+			continue
+		}
+		nic := &SGuestNetwork{}
+		nic.Mac = iface.MAC.Address
+		nic.Index = int8(len(guest.Nics))
+		nic.Driver = api.NETWORK_DRIVER_VIRTIO
+		if iface.Model != nil && len(iface.Model.Type) > 0 {
+			nic.Driver = iface.Model.Type
+		}
+		if iface.Source != nil && iface.Source.Bridge != nil {
+			nic.Bridge = iface.Source.Bridge.Bridge
+		}
+		if iface.Target != nil {
+			nic.Ifname = iface.Target.Dev
+		}
+		if iface.VLan != nil && len(iface.VLan.Tags) > 0 {
+			nic.Vlan = int(iface.VLan.Tags[0].ID)
+		}
+		if iface.MTU != nil {
+			nic.Mtu = int(iface.MTU.Size)
+		}
+		guest.Nics = append(guest.Nics, nic)
+	}
+	for _, video := range domain.Devices.Videos {
+		for vga, model := range libvirtVideoModels {
+			if model == video.Model.Type {
+				guest.Vga = vga
+			}
+		}
+		if video.Model.Heads > 1 {
+			guest.VdiMonitors = int(video.Model.Heads)
+		}
+	}
+	for _, graphic := range domain.Devices.Graphics {
+		if graphic.Spice != nil {
+			guest.Vdi = api.VM_VDI_PROTOCOL_SPICE
+		} else if graphic.VNC != nil && len(guest.Vdi) == 0 {
+			guest.Vdi = api.VM_VDI_PROTOCOL_VNC
+		}
+	}
+	if len(domain.Devices.Hostdevs) > 0 {
+		warnings = append(warnings, fmt.Sprintf("ignore %d host devices, attach isolated devices manually", len(domain.Devices.Hostdevs)))
+	}
+	return guest, warnings, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package desc
+
+import (
+	"testing"
+
+	libvirtxml "github.com/libvirt/libvirt-go-xml"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestLibvirtDiskDev(t *testing.T) {
+	cases := []struct {
+		bus  string
+		idx  int
+		want string
+	}{
+		{"virtio", 0, "vda"},
+		{"scsi", 1, "sdb"},
+		{"ide", 25, "hdz"},
+		{"virtio", 26, "vdaa"},
+	}
+	for _, c := range cases {
+		if got := libvirtDiskDev(c.bus, c.idx); got != c.want {
+			t.Errorf("libvirtDiskDev(%s, %d) = %s, want %s", c.bus, c.idx, got, c.want)
+		}
+	}
+}
+
+func TestLibvirtDomainRoundTrip(t *testing.T) {
+	bootIdx := int8(0)
+	guest := &SGuestDesc{}
+	guest.Name = "vm1"
+	guest.Uuid = "7f0f3e1c-2b1a-4c7e-9a37-6e0d6f1f8b21"
+	guest.Cpu = 2
+	guest.Mem = 2048
+	guest.CpuDesc = &SGuestCpu{Cpus: 2, MaxCpus: 4, Sockets: 1, Cores: 4, Threads: 1, Model: "host"}
+	guest.Machine = api.VM_MACHINE_TYPE_Q35
+	guest.Bios = "UEFI"
+	guest.BootOrder = "cdn"
+	guest.Vdi = api.VM_VDI_PROTOCOL_SPICE
+	guest.Vga = api.VM_VIDEO_QXL
+	guest.IOThreads = []string{"iothread0", "iothread_drive_1"}
+	guest.VcpuPin = &SGuestVcpuPin{Vcpus: []SVcpuPin{{Vcpu: 0, Pcpus: "2"}, {Vcpu: 1, Pcpus: "3"}}}
+
+	sys := &SGuestDisk{IOThread: "iothread_drive_1"}
+	sys.Driver = api.DISK_DRIVER_VIRTIO
+	sys.Path = "/opt/cloud/workspace/disks/sys"
+	sys.Format = "qcow2"
+	sys.CacheMode = "none"
+	sys.BootIndex = &bootIdx
+	data := &SGuestDisk{}
+	data.Driver = api.DISK_DRIVER_SCSI
+	data.Path = "rbd:pool/data:mon_host=10.0.0.1"
+	data.Format = "raw"
+	guest.Disks = []*SGuestDisk{sys, data}
+
+	nic := &SGuestNetwork{}
+	nic.Mac = "00:22:11:33:44:55"
+	nic.Driver = api.NETWORK_DRIVER_VIRTIO
+	nic.Bridge = "br0"
+	nic.Ifname = "vnet-1"
+	nic.Vlan = 100
+	nic.Mtu = 1450
+	guest.Nics = []*SGuestNetwork{nic}
+
+	xml, err := ToLibvirtDomain(guest, SLibvirtDomainOptions{Arch: "x86_64", Kvm: true, VncPort: 1}).Marshal()
+	if err != nil {
+		t.Fatalf("marshal domain: %s", err)
+	}
+	domain := &libvirtxml.Domain{}
+	if err := domain.Unmarshal(xml); err != nil {
+		t.Fatalf("unmarshal domain: %s", err)
+	}
+	if domain.Type != "kvm" || domain.OS.Firmware != "efi" {
+		t.Errorf("unexpected domain type %s firmware %s", domain.Type, domain.OS.Firmware)
+	}
+	if port := domain.Devices.Graphics[0].Spice.Port; port != 5901 {
+		t.Errorf("spice port = %d", port)
+	}
+
+	got, warnings, err := FromLibvirtDomain(domain)
+	if err != nil {
+		t.Fatalf("FromLibvirtDomain: %s", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if got.Name != guest.Name || got.Uuid != guest.Uuid || got.Cpu != 2 || got.Mem != 2048 {
+		t.Errorf("basic attributes mismatch: %s %s %d %d", got.Name, got.Uuid, got.Cpu, got.Mem)
+	}
+	if got.CpuDesc.MaxCpus != 4 || got.CpuDesc.Cores != 4 || got.CpuDesc.Model != "host" {
+		t.Errorf("cpu desc mismatch %#v", got.CpuDesc)
+	}
+	if got.Machine != guest.Machine || got.Bios != guest.Bios || got.BootOrder != guest.BootOrder {
+		t.Errorf("machine %s bios %s boot order %s", got.Machine, got.Bios, got.BootOrder)
+	}
+	if got.Vdi != guest.Vdi || got.Vga != guest.Vga {
+		t.Errorf("vdi %s vga %s", got.Vdi, got.Vga)
+	}
+	if len(got.VcpuPin.Vcpus) != 2 || got.VcpuPin.Vcpus[1].Pcpus != "3" {
+		t.Errorf("vcpu pin mismatch %#v", got.VcpuPin)
+	}
+	if len(got.Disks) != 2 {
+		t.Fatalf("disks count %d", len(got.Disks))
+	}
+	if d := got.Disks[0]; d.Path != sys.Path || d.Driver != sys.Driver || d.Format != "qcow2" || d.CacheMode != "none" || d.IOThread != "iothread2" {
+		t.Errorf("sys disk mismatch %#v", d)
+	}
+	if d := got.Disks[1]; d.Path != "rbd:pool/data" || d.Driver != api.DISK_DRIVER_SCSI {
+		t.Errorf("data disk mismatch %#v", d)
+	}
+	if len(got.Nics) != 1 {
+		t.Fatalf("nics count %d", len(got.Nics))
+	}
+	if n := got.Nics[0]; n.Mac != nic.Mac || n.Bridge != nic.Bridge || n.Ifname != nic.Ifname || n.Vlan != 100 || n.Mtu != 1450 {
+		t.Errorf("nic mismatch %#v", n)
+	}
+}
+
+func TestFromLibvirtDomainMemoryUnit(t *testing.T) {
+	domain := &libvirtxml.Domain{
+		Name:   "vm",
+		Memory: &libvirtxml.DomainMemory{Value: 1048577},
+		VCPU:   &libvirtxml.DomainVCPU{Value: 1},
+	}
+	guest, _, err := FromLibvirtDomain(domain)
+	if err != nil {
+		t.Fatalf("FromLibvirtDomain: %s", err)
+	}
+	if guest.Mem != 1025 {
+		t.Errorf("mem = %d, want 1025", guest.Mem)
+	}
+	domain.VCPU = nil
+	if _, _, err := FromLibvirtDomain(domain); err == nil {
+		t.Errorf("expect error for missing vcpu")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/host"
+)
+
+// 预留透传设备, 占用者已不再使用该设备时回收其预留
+func (s *SKVMGuestInstance) reserveIsolatedDevice(addr string) error {
+	manager := s.manager.GetHost().GetIsolatedDeviceManager()
+	err := manager.ReserveDevice(addr, s.Id)
+	if err == nil {
+		return nil
+	}
+	owner := manager.GetDeviceReservation(addr)
+	if guest, ok := s.manager.GetServer(owner); ok && guest.hasIsolatedDevice(addr) {
+		return err
+	}
+	log.Infof("release stale reservation of device %s by guest %s", addr, owner)
+	manager.ReleaseDevice(addr, owner)
+	return manager.ReserveDevice(addr, s.Id)
+}
+
+func (s *SKVMGuestInstance) releaseIsolatedDevice(addr string) {
+	s.manager.GetHost().GetIsolatedDeviceManager().ReleaseDevice(addr, s.Id)
+}
+
+func (s *SKVMGuestInstance) hasIsolatedDevice(addr string) bool {
+	for _, dev := range s.Desc.IsolatedDevices {
+		if dev.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SKVMGuestInstance) reserveIsolatedDevices() []error {
+	errs := []error{}
+	for _, dev := range s.Desc.IsolatedDevices {
+		if err := s.reserveIsolatedDevice(dev.Addr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (s *SKVMGuestInstance) releaseIsolatedDevices() {
+	for _, dev := range s.Desc.IsolatedDevices {
+		s.releaseIsolatedDevice(dev.Addr)
+	}
+}
+
+func (s *SKVMGuestInstance) checkStartIsolatedDevices() []host.GuestStartCheckFailure {
+	ret := []host.GuestStartCheckFailure{}
+	for _, err := range s.reserveIsolatedDevices() {
+		ret = append(ret, host.GuestStartCheckFailure{
+			Item:       host.GUEST_START_CHECK_ISOLATED_DEVICE,
+			Reason:     err.Error(),
+			Suggestion: "detach the device from the other guest first",
+		})
+	}
+	return ret
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/backupstorage"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+const (
+	// 持久化 dirty bitmap 名称, 记录上一次备份之后的脏块
+	DISK_BACKUP_DIRTY_BITMAP = "onecloud_backup"
+
+	diskBackupJobTimeout = 24 * time.Hour
+)
+
+type SDiskIncrementalBackup struct {
+	Sid                     string
+	DiskId                  string
+	BackupId                string
+	ParentBackupId          string
+	BackupStorageId         string
+	BackupStorageAccessInfo *jsonutils.JSONDict
+
+	Disk storageman.IDisk
+}
+
+func (m *SGuestManager) DiskIncrementalBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	input, ok := params.(*SDiskIncrementalBackup)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	guest, ok := m.GetServer(input.Sid)
+	if !ok {
+		return nil, errors.Wrapf(errors.ErrNotFound, "guest %s", input.Sid)
+	}
+	return guest.diskIncrementalBackup(ctx, input)
+}
+
+func (s *SKVMGuestInstance) diskIncrementalBackup(ctx context.Context, input *SDiskIncrementalBackup) (jsonutils.JSONObject, error) {
+	if !s.IsRunning() || s.Monitor == nil {
+		return nil, errors.Errorf("guest %s is not running", s.GetName())
+	}
+	var diskIndex = -1
+	for i := range s.Desc.Disks {
+		if s.Desc.Disks[i].DiskId == input.DiskId {
+			diskIndex = int(s.Desc.Disks[i].Index)
+			break
+		}
+	}
+	if diskIndex < 0 {
+		return nil, errors.Wrapf(errors.ErrNotFound, "disk %s", input.DiskId)
+	}
+	diskImg, err := qemuimg.NewQemuImage(input.Disk.GetPath())
+	if err != nil {
+		return nil, errors.Wrap(err, "open disk image")
+	}
+	if diskImg.Format != qemuimg.QCOW2 {
+		return nil, errors.Errorf("disk format %s not support dirty bitmap", diskImg.Format)
+	}
+
+	backupDir := input.Disk.GetBackupDir()
+	if !fileutils2.Exists(backupDir) {
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return nil, errors.Wrapf(err, "mkdir %s", backupDir)
+		}
+	}
+	backupPath := path.Join(backupDir, input.BackupId)
+	target, err := qemuimg.NewQemuImage(backupPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewQemuImage")
+	}
+	if err := target.CreateQcow2(int(diskImg.SizeBytes/1024/1024), false, "", "", "", ""); err != nil {
+		return nil, errors.Wrap(err, "create backup target")
+	}
+	defer os.Remove(backupPath)
+
+	drive := fmt.Sprintf("drive_%d", diskIndex)
+	isFull := len(input.ParentBackupId) == 0
+	if isFull {
+		// 全量备份重新建立 bitmap, 忽略旧 bitmap 不存在的错误
+		s.blockDirtyBitmapRemove(drive)
+	}
+	if err := s.runDriveBackup(drive, backupPath, isFull); err != nil {
+		return nil, err
+	}
+
+	backupStorage, err := backupstorage.GetBackupStorage(input.BackupStorageId, input.BackupStorageAccessInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetBackupStorage")
+	}
+	if !isFull {
+		// 先在本地设置 backing file, 复制到备份存储后与上一个备份组成备份链
+		if err := target.Rebase(input.ParentBackupId, true); err != nil {
+			return nil, errors.Wrap(err, "rebase backup")
+		}
+	}
+	target, _ = qemuimg.NewQemuImage(backupPath)
+	sizeMb := target.GetActualSizeMB()
+	checksum, err := fileutils2.MD5(backupPath)
+	if err != nil {
+		log.Errorf("unable to calculate checksum of backup %s: %v", backupPath, err)
+	}
+	if err := backupStorage.CopyBackupFrom(backupPath, input.BackupId); err != nil {
+		return nil, errors.Wrap(err, "CopyBackupFrom")
+	}
+
+	data := jsonutils.NewDict()
+	data.Set("size_mb", jsonutils.NewInt(int64(sizeMb)))
+	if len(checksum) > 0 {
+		data.Set("checksum", jsonutils.NewString(checksum))
+	}
+	if isFull {
+		data.Set("backup_mode", jsonutils.NewString(api.DISK_BACKUP_MODE_FULL))
+	} else {
+		data.Set("backup_mode", jsonutils.NewString(api.DISK_BACKUP_MODE_INCREMENTAL))
+	}
+	return data, nil
+}
+
+func (s *SKVMGuestInstance) blockDirtyBitmapRemove(drive string) {
+	ch := make(chan string)
+	s.Monitor.BlockDirtyBitmapRemove(drive, DISK_BACKUP_DIRTY_BITMAP, func(res string) {
+		ch <- res
+	})
+	if res := <-ch; len(res) > 0 {
+		log.Debugf("remove dirty bitmap %s of %s: %s", DISK_BACKUP_DIRTY_BITMAP, drive, res)
+	}
+}
+
+func (s *SKVMGuestInstance) runDriveBackup(drive, target string, isFull bool) error {
+	done := make(chan string, 1)
+	if _, loaded := s.backupJobs.LoadOrStore(drive, done); loaded {
+		return errors.Errorf("backup job of %s is running", drive)
+	}
+	defer s.backupJobs.Delete(drive)
+
+	ch := make(chan string)
+	s.Monitor.DriveBackup(drive, target, string(qemuimg.QCOW2), DISK_BACKUP_DIRTY_BITMAP, isFull, func(res string) {
+		ch <- res
+	})
+	if res := <-ch; len(res) > 0 {
+		if !isFull {
+			return errors.Errorf("incremental backup of %s failed, dirty bitmap may be lost, need a full backup: %s", drive, res)
+		}
+		return errors.Errorf("drive backup %s: %s", drive, res)
+	}
+
+	select {
+	case res := <-done:
+		if len(res) > 0 {
+			return errors.Errorf("backup job of %s failed: %s", drive, res)
+		}
+		return nil
+	case <-time.After(diskBackupJobTimeout):
+		return errors.Errorf("wait backup job of %s timeout", drive)
+	}
+}
+
+func (s *SKVMGuestInstance) onBackupJobCompleted(event *monitor.Event) {
+	device, _ := event.Data["device"].(string)
+	v, ok := s.backupJobs.Load(device)
+	if !ok {
+		return
+	}
+	errMsg, _ := event.Data["error"].(string)
+	log.Infof("guest %s backup job %s completed %s", s.GetName(), device, errMsg)
+	v.(chan string) <- errMsg
+}
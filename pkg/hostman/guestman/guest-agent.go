@@ -16,12 +16,16 @@ package guestman
 
 import (
 	"context"
+	"encoding/base64"
 	"time"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/monitor/qga"
 	"yunion.io/x/onecloud/pkg/httperrors"
 )
 
@@ -113,3 +117,252 @@ func (m *SGuestManager) QgaCommand(cmd *monitor.Command, sid string) (string, er
 	err = qgaExec(QGA_EXEC_TIMEOUT, f)
 	return string(res), err
 }
+
+const (
+	// qga 单条命令缓冲区有限, 文件按块读写
+	QGA_FILE_CHUNK_SIZE    = 1024 * 1024
+	QGA_FILE_READ_DEFAULT  = 1024 * 1024
+	QGA_FILE_READ_MAX      = 16 * 1024 * 1024
+	QGA_EXEC_WAIT_DEFAULT  = 30
+	QGA_EXEC_WAIT_MAX      = 600
+	QGA_FSFREEZE_AUTO_THAW = 10 * time.Minute
+)
+
+func (s *SKVMGuestInstance) qgaDo(f func(agent *qga.QemuGuestAgent) error) error {
+	return qgaExec(QGA_EXEC_TIMEOUT, func(c chan error) {
+		if s.guestAgent.TryLock(QGA_LOCK_TIMEOUT) {
+			defer s.guestAgent.Unlock()
+			c <- f(s.guestAgent)
+		} else {
+			c <- errors.Errorf("qga unfinished last cmd, is qga unavailable?")
+		}
+	})
+}
+
+func (m *SGuestManager) QgaExec(sid string, input *api.ServerQgaExecInput) (*api.ServerQgaExecOutput, error) {
+	guest, err := m.checkAndInitGuestQga(sid)
+	if err != nil {
+		return nil, err
+	}
+	timeout := input.Timeout
+	if timeout <= 0 {
+		timeout = QGA_EXEC_WAIT_DEFAULT
+	} else if timeout > QGA_EXEC_WAIT_MAX {
+		timeout = QGA_EXEC_WAIT_MAX
+	}
+	var exec *qga.GuestExec
+	err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		inputData := ""
+		if len(input.Input) > 0 {
+			inputData = base64.StdEncoding.EncodeToString([]byte(input.Input))
+		}
+		var err error
+		exec, err = agent.GuestExecCommand(input.Path, input.Args, input.Env, inputData, true)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "guest-exec")
+	}
+
+	output := &api.ServerQgaExecOutput{Pid: exec.Pid}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for {
+		var status *qga.GuestExecStatus
+		err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+			var err error
+			status, err = agent.GuestExecStatusCommand(exec.Pid)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "guest-exec-status")
+		}
+		if status.Exited {
+			output.Exited = true
+			output.Exitcode = status.Exitcode
+			output.Signal = status.Signal
+			output.OutTruncated = status.OutTruncated
+			output.ErrTruncated = status.ErrTruncated
+			stdout, _ := base64.StdEncoding.DecodeString(status.OutData)
+			stderr, _ := base64.StdEncoding.DecodeString(status.ErrData)
+			output.Stdout = string(stdout)
+			output.Stderr = string(stderr)
+			return output, nil
+		}
+		if time.Now().After(deadline) {
+			// 命令仍在虚机内运行, 返回 pid 供调用方自行处理
+			return output, nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (m *SGuestManager) QgaFileWrite(sid string, input *api.ServerQgaFileWriteInput) error {
+	guest, err := m.checkAndInitGuestQga(sid)
+	if err != nil {
+		return err
+	}
+	content := []byte(input.Content)
+	if input.Base64 {
+		content, err = base64.StdEncoding.DecodeString(input.Content)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid base64 content: %s", err)
+		}
+	}
+	mode := "w"
+	if input.Append {
+		mode = "a"
+	}
+	var handle int
+	err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		var err error
+		handle, err = agent.GuestFileOpen(input.Path, mode)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "open %s", input.Path)
+	}
+	defer guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		return agent.GuestFileClose(handle)
+	})
+	for offset := 0; offset < len(content); offset += QGA_FILE_CHUNK_SIZE {
+		end := offset + QGA_FILE_CHUNK_SIZE
+		if end > len(content) {
+			end = len(content)
+		}
+		chunk := base64.StdEncoding.EncodeToString(content[offset:end])
+		err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+			_, err := agent.GuestFileWrite(handle, chunk)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "write %s", input.Path)
+		}
+	}
+	return nil
+}
+
+func (m *SGuestManager) QgaFileRead(sid string, input *api.ServerQgaFileReadInput) (*api.ServerQgaFileReadOutput, error) {
+	guest, err := m.checkAndInitGuestQga(sid)
+	if err != nil {
+		return nil, err
+	}
+	maxBytes := input.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = QGA_FILE_READ_DEFAULT
+	} else if maxBytes > QGA_FILE_READ_MAX {
+		maxBytes = QGA_FILE_READ_MAX
+	}
+	var handle int
+	err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		var err error
+		handle, err = agent.GuestFileOpen(input.Path, "r")
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", input.Path)
+	}
+	defer guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		return agent.GuestFileClose(handle)
+	})
+	content := []byte{}
+	eof := false
+	for !eof && len(content) < maxBytes {
+		count := maxBytes - len(content)
+		if count > QGA_FILE_CHUNK_SIZE {
+			count = QGA_FILE_CHUNK_SIZE
+		}
+		var res *qga.GuestFileRead
+		err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+			var err error
+			res, err = agent.GuestFileRead(handle, count)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", input.Path)
+		}
+		data, err := base64.StdEncoding.DecodeString(res.BufB64)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode file content")
+		}
+		content = append(content, data...)
+		eof = res.Eof || res.Count == 0
+	}
+	return &api.ServerQgaFileReadOutput{
+		Content:   base64.StdEncoding.EncodeToString(content),
+		Size:      len(content),
+		Truncated: !eof,
+	}, nil
+}
+
+func (m *SGuestManager) QgaFsfreeze(sid string, input *api.ServerQgaFsfreezeInput) (*api.ServerQgaFsfreezeOutput, error) {
+	guest, err := m.checkAndInitGuestQga(sid)
+	if err != nil {
+		return nil, err
+	}
+	output := &api.ServerQgaFsfreezeOutput{}
+	err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		var err error
+		switch input.Action {
+		case api.QGA_FSFREEZE_FREEZE:
+			output.Count, err = agent.GuestFsfreezeFreeze()
+		case api.QGA_FSFREEZE_THAW:
+			output.Count, err = agent.GuestFsfreezeThaw()
+		}
+		if err != nil {
+			return err
+		}
+		output.Status, err = agent.GuestFsfreezeStatus()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "fsfreeze %s", input.Action)
+	}
+	if input.Action == api.QGA_FSFREEZE_FREEZE {
+		// 文件系统长时间冻结会导致虚机内业务挂起, 超时后自动解冻
+		time.AfterFunc(QGA_FSFREEZE_AUTO_THAW, func() {
+			if !guest.IsRunning() || guest.guestAgent == nil {
+				return
+			}
+			err := guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+				status, err := agent.GuestFsfreezeStatus()
+				if err != nil || status != "frozen" {
+					return err
+				}
+				log.Warningf("guest %s filesystems still frozen after %s, thaw", guest.GetName(), QGA_FSFREEZE_AUTO_THAW)
+				_, err = agent.GuestFsfreezeThaw()
+				return err
+			})
+			if err != nil {
+				log.Errorf("auto thaw guest %s filesystems: %s", guest.GetName(), err)
+			}
+		})
+	}
+	return output, nil
+}
+
+func (m *SGuestManager) QgaNetworkInterfaces(sid string) (*api.ServerQgaNetworkInterfacesOutput, error) {
+	guest, err := m.checkAndInitGuestQga(sid)
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []qga.GuestNetworkInterface
+	err = guest.qgaDo(func(agent *qga.QemuGuestAgent) error {
+		var err error
+		ifaces, err = agent.GuestNetworkGetInterfaces()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "guest-network-get-interfaces")
+	}
+	output := &api.ServerQgaNetworkInterfacesOutput{}
+	for _, iface := range ifaces {
+		nic := api.ServerQgaNetworkInterface{Name: iface.Name, Mac: iface.HardwareAddress}
+		for _, addr := range iface.IpAddresses {
+			nic.IpAddresses = append(nic.IpAddresses, api.ServerQgaIpAddress{
+				Type: addr.IpAddressType, Ip: addr.IpAddress, Prefix: addr.Prefix,
+			})
+		}
+		output.Interfaces = append(output.Interfaces, nic)
+	}
+	return output, nil
+}
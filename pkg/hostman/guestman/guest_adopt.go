@@ -0,0 +1,150 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+// GetUnmanagedGuests 扫描宿主机上运行中但未被纳管的 qemu 进程, 由命令行还原虚机描述
+func (m *SGuestManager) GetUnmanagedGuests() ([]*compute.SImportGuestDesc, error) {
+	output, err := procutils.NewCommand("ps", "-A", "-o", "pid=,args=").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "ps: %s", output)
+	}
+
+	managed := map[string]bool{}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		managed[guest.Id] = true
+		if originId := guest.getOriginId(); len(originId) > 0 {
+			managed[originId] = true
+		}
+		return true
+	})
+
+	guests := []*compute.SImportGuestDesc{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 || !qemutils.IsQemuBinary(fields[1]) {
+			continue
+		}
+		cmdGuest, err := qemutils.ParseGuestFromCmdline(fields[1])
+		if err != nil {
+			log.Warningf("parse qemu process %s cmdline: %s", fields[0], err)
+			continue
+		}
+		if managed[cmdGuest.Uuid] {
+			continue
+		}
+		guestDesc, err := cmdlineGuestToGuestDesc(cmdGuest)
+		if err != nil {
+			log.Warningf("qemu process %s guest %s: %s", fields[0], cmdGuest.Uuid, err)
+			continue
+		}
+		guests = append(guests, guestDesc)
+	}
+	return guests, nil
+}
+
+func cmdlineGuestToGuestDesc(cmdGuest *qemutils.SCmdlineGuest) (*compute.SImportGuestDesc, error) {
+	if len(cmdGuest.Disks) == 0 {
+		return nil, errors.Errorf("no disks found")
+	}
+	if len(cmdGuest.Nics) == 0 {
+		return nil, errors.Errorf("no network interfaces found")
+	}
+	guestDesc := &compute.SImportGuestDesc{
+		Id:        cmdGuest.Uuid,
+		Name:      cmdGuest.Name,
+		Cpu:       cmdGuest.Cpu,
+		MemSizeMb: cmdGuest.MemSizeMb,
+	}
+	if len(guestDesc.Name) == 0 {
+		guestDesc.Name = cmdGuest.Uuid
+	}
+	if guestDesc.Cpu == 0 {
+		guestDesc.Cpu = 1
+	}
+	for _, disk := range cmdGuest.Disks {
+		img, err := qemuimg.NewQemuImage(disk.File)
+		if err != nil {
+			return nil, errors.Wrapf(err, "open disk %s", disk.File)
+		}
+		driver := disk.Driver
+		if driver != "virtio" {
+			driver = "scsi"
+		}
+		guestDesc.Disks = append(guestDesc.Disks, compute.SImportDisk{
+			Index:      disk.Index,
+			AccessPath: disk.File,
+			Driver:     driver,
+			SizeMb:     img.GetSizeMB(),
+			Format:     img.Format.String(),
+		})
+	}
+	for _, nic := range cmdGuest.Nics {
+		guestDesc.Nics = append(guestDesc.Nics, compute.SImportNic{
+			Index:  nic.Index,
+			Mac:    nic.Mac,
+			Driver: nic.Driver,
+		})
+	}
+	// 监控 socket 需在 host 服务内可访问才能免重启接管
+	if len(cmdGuest.MonitorPath) > 0 && fileutils2.Exists(cmdGuest.MonitorPath) {
+		guestDesc.MonitorPath = cmdGuest.MonitorPath
+	}
+	return guestDesc, nil
+}
+
+// PrepareImportFromRunning 按 mac 匹配待接管的运行中虚机, 返回格式与 PrepareImportFromLibvirt 一致
+func (m *SGuestManager) PrepareImportFromRunning(
+	ctx context.Context, params interface{},
+) (jsonutils.JSONObject, error) {
+	config, ok := params.(*compute.SLibvirtHostConfig)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	guests, err := m.GetUnmanagedGuests()
+	if err != nil {
+		return nil, err
+	}
+	matched := []*compute.SImportGuestDesc{}
+	for _, guest := range guests {
+		idx, err := setAttributeFromLibvirtConfig(guest, config)
+		if err != nil {
+			continue
+		}
+		config.Servers = append(config.Servers[:idx], config.Servers[idx+1:]...)
+		matched = append(matched, guest)
+	}
+
+	ret := jsonutils.NewDict()
+	ret.Set("servers_not_match", jsonutils.Marshal(config.Servers))
+	ret.Set("servers_matched", jsonutils.Marshal(matched))
+	return ret, nil
+}
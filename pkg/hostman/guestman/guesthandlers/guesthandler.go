@@ -22,6 +22,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 
 	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
@@ -57,44 +58,75 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			fmt.Sprintf("%s/%s/prepare-import-from-libvirt", prefix, keyWord),
 			auth.Authenticate(guestPrepareImportFormLibvirt))
 
+		app.AddHandler("GET",
+			fmt.Sprintf("%s/%s/unmanaged-guests", prefix, keyWord),
+			auth.Authenticate(guestListUnmanaged))
+
+		app.AddHandler("GET",
+			fmt.Sprintf("%s/%s/console-ports", prefix, keyWord),
+			auth.Authenticate(getConsolePortUsage))
+
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/prepare-import-from-running", prefix, keyWord),
+			auth.Authenticate(guestPrepareImportFromRunning))
+
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/libvirt-xml-to-desc", prefix, keyWord),
+			auth.Authenticate(guestLibvirtXmlToDesc))
+
+		app.AddHandler("GET",
+			fmt.Sprintf("%s/%s/<sid>/libvirt-xml", prefix, keyWord),
+			auth.Authenticate(guestExportLibvirtXml))
+
 		app.AddHandler("DELETE",
 			fmt.Sprintf("%s/%s/<sid>", prefix, keyWord),
 			auth.Authenticate(deleteGuest))
 
 		for action, f := range map[string]actionFunc{
-			"create":                guestCreate,
-			"deploy":                guestDeploy,
-			"rebuild":               guestRebuild,
-			"start":                 guestStart,
-			"stop":                  guestStop,
-			"monitor":               guestMonitor,
-			"sync":                  guestSync,
-			"suspend":               guestSuspend,
-			"io-throttle":           guestIoThrottle,
-			"snapshot":              guestSnapshot,
-			"delete-snapshot":       guestDeleteSnapshot,
-			"reload-disk-snapshot":  guestReloadDiskSnapshot,
-			"src-prepare-migrate":   guestSrcPrepareMigrate,
-			"dest-prepare-migrate":  guestDestPrepareMigrate,
-			"live-migrate":          guestLiveMigrate,
-			"resume":                guestResume,
-			"drive-mirror":          guestDriveMirror,
-			"hotplug-cpu-mem":       guestHotplugCpuMem,
-			"cancel-block-jobs":     guestCancelBlockJobs,
-			"create-from-libvirt":   guestCreateFromLibvirt,
-			"create-form-esxi":      guestCreateFromEsxi,
-			"open-forward":          guestOpenForward,
-			"list-forward":          guestListForward,
-			"close-forward":         guestCloseForward,
-			"storage-clone-disk":    guestStorageCloneDisk,
-			"live-change-disk":      guestLiveChangeDisk,
-			"cpuset":                guestCPUSet,
-			"cpuset-remove":         guestCPUSetRemove,
-			"memory-snapshot":       guestMemorySnapshot,
-			"memory-snapshot-reset": guestMemorySnapshotReset,
-			"qga-set-password":      qgaGuestSetPassword,
-			"qga-guest-ping":        qgaGuestPing,
-			"qga-command":           qgaCommand,
+			"create":                  guestCreate,
+			"deploy":                  guestDeploy,
+			"rebuild":                 guestRebuild,
+			"start":                   guestStart,
+			"stop":                    guestStop,
+			"monitor":                 guestMonitor,
+			"sync":                    guestSync,
+			"suspend":                 guestSuspend,
+			"io-throttle":             guestIoThrottle,
+			"snapshot":                guestSnapshot,
+			"delete-snapshot":         guestDeleteSnapshot,
+			"disk-incremental-backup": guestDiskIncrementalBackup,
+			"reload-disk-snapshot":    guestReloadDiskSnapshot,
+			"src-prepare-migrate":     guestSrcPrepareMigrate,
+			"dest-prepare-migrate":    guestDestPrepareMigrate,
+			"live-migrate":            guestLiveMigrate,
+			"resume":                  guestResume,
+			"drive-mirror":            guestDriveMirror,
+			"hotplug-cpu-mem":         guestHotplugCpuMem,
+			"resize-virtio-mem":       guestResizeVirtioMem,
+			"cancel-block-jobs":       guestCancelBlockJobs,
+			"create-from-libvirt":     guestCreateFromLibvirt,
+			"create-form-esxi":        guestCreateFromEsxi,
+			"open-forward":            guestOpenForward,
+			"list-forward":            guestListForward,
+			"close-forward":           guestCloseForward,
+			"storage-clone-disk":      guestStorageCloneDisk,
+			"live-change-disk":        guestLiveChangeDisk,
+			"cpuset":                  guestCPUSet,
+			"cpuset-remove":           guestCPUSetRemove,
+			"cgroup-priority":         guestSetCgroupPriority,
+			"memory-snapshot":         guestMemorySnapshot,
+			"memory-snapshot-reset":   guestMemorySnapshotReset,
+			"qga-set-password":        qgaGuestSetPassword,
+			"qga-guest-ping":          qgaGuestPing,
+			"qga-command":             qgaCommand,
+			"qga-exec":                qgaExec,
+			"qga-file-write":          qgaFileWrite,
+			"qga-file-read":           qgaFileRead,
+			"qga-fsfreeze":            qgaFsfreeze,
+			"qga-network-interfaces":  qgaNetworkInterfaces,
+			"snapshot-gc":             guestSnapshotGc,
+			"set-nic-bandwidth":       guestSetNicBandwidth,
+			"cvm-attestation":         guestConfidentialVmAttestation,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
@@ -134,6 +166,10 @@ func getStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	hostutils.ResponseOk(ctx, w)
 }
 
+func getConsolePortUsage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	hostutils.Response(ctx, w, guestman.GetGuestManager().GetConsolePortUsage())
+}
+
 func cpusetBalance(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	hostutils.DelayTask(ctx, guestman.GetGuestManager().CpusetBalance, nil)
 	hostutils.ResponseOk(ctx, w)
@@ -276,19 +312,24 @@ func guestIoThrottle(ctx context.Context, userCred mcclient.TokenCredential, sid
 	if !guest.IsRunning() {
 		return nil, httperrors.NewInvalidStatusError("Not running")
 	}
-	bps, err := body.Int("bps")
-	if err != nil {
-		return nil, httperrors.NewMissingParameterError("bps")
-	}
-	iops, err := body.Int("iops")
-	if err != nil {
-		return nil, httperrors.NewMissingParameterError("iops")
+	params := &guestman.SGuestIoThrottle{Sid: sid}
+	if body.Contains("disks") {
+		params.Disks = map[string]guestman.SDiskIoThrottle{}
+		if err := body.Unmarshal(&params.Disks, "disks"); err != nil {
+			return nil, httperrors.NewInputParameterError("unmarshal disks: %v", err)
+		}
+	} else {
+		bps, err := body.Int("bps")
+		if err != nil {
+			return nil, httperrors.NewMissingParameterError("bps")
+		}
+		iops, err := body.Int("iops")
+		if err != nil {
+			return nil, httperrors.NewMissingParameterError("iops")
+		}
+		params.BPS, params.IOPS = bps, iops
 	}
-	hostutils.DelayTaskWithoutReqctx(ctx, guestman.GetGuestManager().GuestIoThrottle, &guestman.SGuestIoThrottle{
-		Sid:  sid,
-		BPS:  bps,
-		IOPS: iops,
-	})
+	hostutils.DelayTaskWithoutReqctx(ctx, guestman.GetGuestManager().GuestIoThrottle, params)
 	return nil, nil
 }
 
@@ -345,7 +386,13 @@ func guestDestPrepareMigrateInternal(ctx context.Context, userCred mcclient.Toke
 	params.QemuVersion = qemuVersion
 	params.LiveMigrate = liveMigrate
 	params.SourceQemuCmdline = qemuCmdline
+	params.IsLocal = isLocal
 	params.EnableTLS = jsonutils.QueryBoolean(body, "enable_tls", false)
+	if body.Contains("live_migrate_tuning") {
+		if err := body.Unmarshal(&params.LiveMigrateTuning, "live_migrate_tuning"); err != nil {
+			return httperrors.NewInputParameterError("unmarshal live_migrate_tuning: %s", err)
+		}
+	}
 	if params.EnableTLS {
 		certsObj, err := body.Get("migrate_certs")
 		if err != nil {
@@ -357,6 +404,20 @@ func guestDestPrepareMigrateInternal(ctx context.Context, userCred mcclient.Toke
 		}
 		params.MigrateCerts = certs
 	}
+	if body.Contains("tpm_state") {
+		tpmState := map[string]string{}
+		if err := body.Unmarshal(&tpmState, "tpm_state"); err != nil {
+			return httperrors.NewInputParameterError("unmarshal tpm_state to map: %s", err)
+		}
+		params.TpmState = tpmState
+	}
+	if body.Contains("nvram") {
+		nvram := map[string]string{}
+		if err := body.Unmarshal(&nvram, "nvram"); err != nil {
+			return httperrors.NewInputParameterError("unmarshal nvram to map: %s", err)
+		}
+		params.Nvram = nvram
+	}
 	if isLocal {
 		serverUrl, err := body.GetString("server_url")
 		if err != nil {
@@ -451,6 +512,12 @@ func guestLiveMigrate(ctx context.Context, userCred mcclient.TokenCredential, si
 		maxBandwidthMb, _ := body.Int("max_bandwidth_mb")
 		params.MaxBandwidthMB = &maxBandwidthMb
 	}
+	if body.Contains("live_migrate_tuning") {
+		params.Tuning = new(guestman.SLiveMigrateTuning)
+		if err := body.Unmarshal(params.Tuning, "live_migrate_tuning"); err != nil {
+			return nil, httperrors.NewInputParameterError("unmarshal live_migrate_tuning: %s", err)
+		}
+	}
 
 	hostutils.DelayTaskWithoutReqctx(ctx, guestman.GetGuestManager().LiveMigrate, params)
 	return nil, nil
@@ -525,6 +592,27 @@ func guestHotplugCpuMem(ctx context.Context, userCred mcclient.TokenCredential,
 	return nil, nil
 }
 
+func guestResizeVirtioMem(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	if !guestman.GetGuestManager().IsGuestExist(sid) {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+
+	if guestman.GetGuestManager().Status(sid) != "running" {
+		return nil, httperrors.NewBadRequestError("Guest %s not running", sid)
+	}
+
+	memSize, err := body.Int("vmem_size")
+	if err != nil || memSize <= 0 {
+		return nil, httperrors.NewMissingParameterError("vmem_size")
+	}
+	hostutils.DelayTaskWithoutReqctx(ctx, guestman.GetGuestManager().ResizeVirtioMem,
+		&guestman.SGuestResizeVirtioMem{
+			Sid:     sid,
+			MemSize: memSize,
+		})
+	return nil, nil
+}
+
 func guestReloadDiskSnapshot(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	diskId, err := body.GetString("disk_id")
 	if err != nil {
@@ -593,6 +681,48 @@ func guestSnapshot(ctx context.Context, userCred mcclient.TokenCredential, sid s
 	return nil, nil
 }
 
+func guestDiskIncrementalBackup(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := &guestman.SDiskIncrementalBackup{Sid: sid}
+	var err error
+	input.DiskId, err = body.GetString("disk_id")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("disk_id")
+	}
+	input.BackupId, err = body.GetString("backup_id")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("backup_id")
+	}
+	input.BackupStorageId, err = body.GetString("backup_storage_id")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("backup_storage_id")
+	}
+	accessInfo, err := body.Get("backup_storage_access_info")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("backup_storage_access_info")
+	}
+	input.BackupStorageAccessInfo = accessInfo.(*jsonutils.JSONDict)
+	input.ParentBackupId, _ = body.GetString("parent_backup_id")
+
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	for _, d := range guest.Desc.Disks {
+		if d.DiskId == input.DiskId {
+			input.Disk, err = storageman.GetManager().GetDiskByPath(d.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "GetDiskByPath(%s)", d.Path)
+			}
+			break
+		}
+	}
+	if input.Disk == nil {
+		return nil, httperrors.NewNotFoundError("Disk not found")
+	}
+	hostutils.DelayTask(ctx, guestman.GetGuestManager().DiskIncrementalBackup, input)
+	return nil, nil
+}
+
 func guestDeleteSnapshot(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	deleteSnapshot, err := body.GetString("delete_snapshot")
 	if err != nil {
@@ -714,6 +844,33 @@ func guestCPUSetRemove(ctx context.Context, userCred mcclient.TokenCredential, s
 	return nil, nil
 }
 
+func guestSetCgroupPriority(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerCgroupPriorityInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	gm := guestman.GetGuestManager()
+	return gm.SetCgroupPriority(ctx, sid, input)
+}
+
+func guestSetNicBandwidth(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(hostapi.GuestSetNicBandwidthRequest)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if input.Mac == "" {
+		return nil, httperrors.NewMissingParameterError("mac")
+	}
+	if input.BwIngress < 0 || input.BwEgress < 0 {
+		return nil, httperrors.NewInputParameterError("bandwidth must be non-negative")
+	}
+	gm := guestman.GetGuestManager()
+	if err := gm.SetNicBandwidth(ctx, sid, input); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 func guestMemorySnapshot(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	input := new(hostapi.GuestMemorySnapshotRequest)
 	if err := body.Unmarshal(input); err != nil {
@@ -809,3 +966,63 @@ func qgaCommand(ctx context.Context, userCred mcclient.TokenCredential, sid stri
 	}
 	return gm.QgaCommand(qgaCmd, sid)
 }
+
+func qgaExec(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerQgaExecInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	return guestman.GetGuestManager().QgaExec(sid, input)
+}
+
+func qgaFileWrite(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerQgaFileWriteInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	return nil, guestman.GetGuestManager().QgaFileWrite(sid, input)
+}
+
+func qgaFileRead(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerQgaFileReadInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if input.Path == "" {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	return guestman.GetGuestManager().QgaFileRead(sid, input)
+}
+
+func qgaFsfreeze(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerQgaFsfreezeInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, err
+	}
+	if !utils.IsInStringArray(input.Action, []string{computeapi.QGA_FSFREEZE_FREEZE, computeapi.QGA_FSFREEZE_THAW, computeapi.QGA_FSFREEZE_STATUS}) {
+		return nil, httperrors.NewInputParameterError("invalid fsfreeze action %q", input.Action)
+	}
+	return guestman.GetGuestManager().QgaFsfreeze(sid, input)
+}
+
+func guestSnapshotGc(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := new(computeapi.ServerSnapshotGcRequest)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal input: %v", err)
+	}
+	return guestman.GetGuestManager().SnapshotGc(sid, input)
+}
+
+func guestConfidentialVmAttestation(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	return guestman.GetGuestManager().ConfidentialVmAttestation(sid)
+}
+
+func qgaNetworkInterfaces(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	return guestman.GetGuestManager().QgaNetworkInterfaces(sid)
+}
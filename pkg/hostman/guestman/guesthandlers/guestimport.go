@@ -93,6 +93,64 @@ func guestPrepareImportFormLibvirt(ctx context.Context, w http.ResponseWriter, r
 	hostutils.ResponseOk(ctx, w)
 }
 
+func guestListUnmanaged(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guests, err := guestman.GetGuestManager().GetUnmanagedGuests()
+	if err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
+	hostutils.Response(ctx, w, map[string]interface{}{"servers": guests})
+}
+
+func guestPrepareImportFromRunning(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, _, body := appsrv.FetchEnv(ctx, w, r)
+	config := &compute.SLibvirtHostConfig{}
+	if err := body.Unmarshal(config); err != nil {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError("Parse params to import config error %s", err))
+		return
+	}
+	if len(config.Servers) == 0 {
+		hostutils.Response(ctx, w, httperrors.NewMissingParameterError("servers"))
+		return
+	}
+	hostutils.DelayTask(ctx, guestman.GetGuestManager().PrepareImportFromRunning, config)
+	hostutils.ResponseOk(ctx, w)
+}
+
+func guestExportLibvirtXml(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	params, _, _ := appsrv.FetchEnv(ctx, w, r)
+	sid := params["<sid>"]
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		hostutils.Response(ctx, w, httperrors.NewNotFoundError("Guest %s not found", sid))
+		return
+	}
+	xml, err := guest.ExportLibvirtXml()
+	if err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
+	hostutils.Response(ctx, w, &compute.ServerLibvirtXmlOutput{Xml: xml})
+}
+
+func guestLibvirtXmlToDesc(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, _, body := appsrv.FetchEnv(ctx, w, r)
+	input := &compute.SLibvirtXmlToDescInput{}
+	if body != nil {
+		body.Unmarshal(input)
+	}
+	if len(input.Xml) == 0 {
+		hostutils.Response(ctx, w, httperrors.NewMissingParameterError("xml"))
+		return
+	}
+	output, err := guestman.GetGuestManager().LibvirtXmlToGuestDesc(input.Xml)
+	if err != nil {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError("%s", err))
+		return
+	}
+	hostutils.Response(ctx, w, output)
+}
+
 func guestCreateFromLibvirt(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	err := guestman.GetGuestManager().PrepareCreate(sid)
 	if err != nil {
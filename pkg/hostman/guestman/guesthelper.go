@@ -18,6 +18,7 @@ import (
 	"yunion.io/x/cloudmux/pkg/multicloud/esxi/vcenter"
 	"yunion.io/x/jsonutils"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
@@ -50,12 +51,17 @@ type SDestPrepareMigrate struct {
 	SourceQemuCmdline string
 	MigrateCerts      map[string]string
 	EnableTLS         bool
+	TpmState          map[string]string
+	Nvram             map[string]string
 	SnapshotsUri      string
 	DisksUri          string
 	// TargetStorageId string
 	TargetStorageIds []string
 	LiveMigrate      bool
 	RebaseDisks      bool
+	IsLocal          bool
+	// 热迁移调优参数, 由目标端结合宿主机配置确定
+	LiveMigrateTuning api.LiveMigrateTuningInput
 
 	Desc             *desc.SGuestDesc
 	DisksBackingFile jsonutils.JSONObject
@@ -75,6 +81,8 @@ type SLiveMigrate struct {
 	EnableTLS      bool
 	MaxBandwidthMB *int64
 	QuicklyFinish  bool
+	// 目标端确定的调优参数, 为空时按旧版本行为
+	Tuning *SLiveMigrateTuning
 }
 
 type SDriverMirror struct {
@@ -89,6 +97,11 @@ type SGuestHotplugCpuMem struct {
 	AddMemSize  int64
 }
 
+type SGuestResizeVirtioMem struct {
+	Sid     string
+	MemSize int64
+}
+
 type SReloadDisk struct {
 	Sid  string
 	Disk storageman.IDisk
@@ -147,10 +160,19 @@ type SGuestCreateFromLibvirt struct {
 	DisksPath   *jsonutils.JSONDict
 }
 
+type SDiskIoThrottle struct {
+	// MB/s
+	Bps  int64 `json:"bps"`
+	Iops int64 `json:"iops"`
+}
+
 type SGuestIoThrottle struct {
 	Sid  string
 	BPS  int64
 	IOPS int64
+
+	// disk_id => throttle, 未指定时 BPS/IOPS 作用于所有磁盘
+	Disks map[string]SDiskIoThrottle
 }
 
 type SGuestCreateFromEsxi struct {
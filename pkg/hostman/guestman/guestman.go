@@ -41,8 +41,10 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	fwd "yunion.io/x/onecloud/pkg/hostman/guestman/forwarder"
 	fwdpb "yunion.io/x/onecloud/pkg/hostman/guestman/forwarder/api"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/types"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostconsts"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
 	"yunion.io/x/onecloud/pkg/hostman/options"
@@ -59,7 +61,6 @@ import (
 )
 
 var (
-	LAST_USED_PORT            = 0
 	LAST_USED_NBD_SERVER_PORT = 0
 	LAST_USED_MIGRATE_PORT    = 0
 	NbdWorker                 = appsrv.NewWorkerManager("nbd_worker", 1, appsrv.DEFAULT_BACKLOG, false)
@@ -83,6 +84,8 @@ type SGuestManager struct {
 	UnknownServers   *sync.Map
 	ServersLock      *sync.Mutex
 	portsInUse       *sync.Map
+	mgmtFirewall     *sMgmtFirewall
+	consolePorts     *sConsolePortRegistry
 
 	GuestStartWorker *appsrv.SWorkerManager
 
@@ -93,7 +96,10 @@ type SGuestManager struct {
 	dirtyServersChan chan struct{}
 
 	qemuMachineCpuMax map[string]uint
+	qemuMachines      []monitor.MachineInfo
 	qemuMaxMem        int
+
+	vcpuPinLock sync.Mutex
 }
 
 func NewGuestManager(host hostutils.IHost, serversPath string) *SGuestManager {
@@ -102,6 +108,8 @@ func NewGuestManager(host hostutils.IHost, serversPath string) *SGuestManager {
 	manager.ServersPath = serversPath
 	manager.Servers = new(sync.Map)
 	manager.portsInUse = new(sync.Map)
+	manager.mgmtFirewall = newMgmtFirewall()
+	manager.consolePorts = newConsolePortRegistry(CONSOLE_PORT_MAX, isConsolePortListening)
 	manager.CandidateServers = make(map[string]*SKVMGuestInstance, 0)
 	manager.UnknownServers = new(sync.Map)
 	manager.ServersLock = &sync.Mutex{}
@@ -120,6 +128,7 @@ func (m *SGuestManager) InitQemuMaxCpus(machineCaps []monitor.MachineInfo, kvmMa
 	m.qemuMachineCpuMax[compute.VM_MACHINE_TYPE_PC] = arch.X86_MAX_CPUS
 	m.qemuMachineCpuMax[compute.VM_MACHINE_TYPE_Q35] = arch.X86_MAX_CPUS
 	m.qemuMachineCpuMax[compute.VM_MACHINE_TYPE_ARM_VIRT] = arch.ARM_MAX_CPUS
+	m.qemuMachines = machineCaps
 	if len(machineCaps) == 0 {
 		return
 	}
@@ -151,6 +160,17 @@ func (m *SGuestManager) InitQemuMaxCpus(machineCaps []monitor.MachineInfo, kvmMa
 
 }
 
+// resolveQemuMachine 返回 qemu 实际使用的版本化机型, 机型不支持时返回空
+func (m *SGuestManager) resolveQemuMachine(machine string) string {
+	for i := range m.qemuMachines {
+		if m.qemuMachines[i].Name == machine ||
+			(m.qemuMachines[i].Alias != nil && *m.qemuMachines[i].Alias == machine) {
+			return m.qemuMachines[i].Name
+		}
+	}
+	return ""
+}
+
 func (m *SGuestManager) InitQemuMaxMems(maxMems uint) {
 	if maxMems > arch.X86_MAX_MEM_MB {
 		arch.X86_MAX_MEM_MB = maxMems
@@ -269,7 +289,12 @@ func (m *SGuestManager) OnLoadExistingGuestsComplete() {
 		log.Fatalf("put host online failed %s", err)
 	}
 
+	m.initConsolePorts()
+	m.initMgmtFirewall()
+
 	go m.verifyDirtyServers()
+	go m.startVcpuPinReconciler()
+	go m.startTlsCertRotator()
 
 	if !options.HostOptions.EnableCpuBinding {
 		m.ClenaupCpuset()
@@ -316,8 +341,13 @@ func (m *SGuestManager) StartCpusetBalancer() {
 }
 
 func (m *SGuestManager) cpusetBalance() {
-	if !options.HostOptions.DisableSetCgroup {
-		cgrouputils.RebalanceProcesses(nil)
+	if !options.HostOptions.DisableSetCgroup && !cgrouputils.IsCgroupV2() {
+		pids, pinned := m.getUnpinnedGuestPids()
+		if !pinned {
+			cgrouputils.RebalanceProcesses(nil)
+		} else if len(pids) > 0 {
+			cgrouputils.RebalanceProcesses(pids)
+		}
 	}
 }
 
@@ -337,6 +367,22 @@ func (m *SGuestManager) CPUSetRemove(ctx context.Context, sid string) error {
 	return guest.CPUSetRemove(ctx)
 }
 
+func (m *SGuestManager) SetCgroupPriority(ctx context.Context, sid string, input *compute.ServerCgroupPriorityInput) (*compute.ServerCgroupPriorityOutput, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found")
+	}
+	return guest.SetCgroupPriority(ctx, input)
+}
+
+func (m *SGuestManager) SetNicBandwidth(ctx context.Context, sid string, input *hostapi.GuestSetNicBandwidthRequest) error {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return httperrors.NewNotFoundError("Not found")
+	}
+	return guest.SetNicBandwidth(ctx, input)
+}
+
 func (m *SGuestManager) IsGuestDir(f os.FileInfo) bool {
 	if !regutils.MatchUUID(f.Name()) {
 		return false
@@ -880,7 +926,14 @@ func (m *SGuestManager) GuestIoThrottle(ctx context.Context, params interface{})
 	}
 	guest, _ := m.GetServer(guestIoThrottle.Sid)
 	if guest.IsRunning() {
-		return nil, guest.BlockIoThrottle(ctx, guestIoThrottle.BPS, guestIoThrottle.IOPS)
+		throttles := guestIoThrottle.Disks
+		if len(throttles) == 0 {
+			throttles = map[string]SDiskIoThrottle{}
+			for _, disk := range guest.Desc.Disks {
+				throttles[disk.DiskId] = SDiskIoThrottle{Bps: guestIoThrottle.BPS, Iops: guestIoThrottle.IOPS}
+			}
+		}
+		return nil, guest.BlockIoThrottle(ctx, throttles)
 	}
 	return nil, httperrors.NewInvalidStatusError("Guest not running")
 }
@@ -907,6 +960,26 @@ func (m *SGuestManager) SrcPrepareMigrate(ctx context.Context, params interface{
 		}
 		ret.Set("migrate_certs", jsonutils.Marshal(certs))
 	}
+
+	if guest.Desc.Tpm != nil {
+		tpmState, err := guest.PrepareTpmState()
+		if err != nil {
+			return nil, errors.Wrap(err, "PrepareTpmState")
+		}
+		if len(tpmState) > 0 {
+			ret.Set("tpm_state", jsonutils.Marshal(tpmState))
+		}
+	}
+
+	if guest.Desc.Bios == qemu.BIOS_UEFI {
+		nvram, err := guest.PrepareNvram()
+		if err != nil {
+			return nil, errors.Wrap(err, "PrepareNvram")
+		}
+		if len(nvram) > 0 {
+			ret.Set("nvram", jsonutils.Marshal(nvram))
+		}
+	}
 	return ret, nil
 }
 
@@ -921,6 +994,17 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		return nil, err
 	}
 
+	if len(migParams.TpmState) > 0 {
+		if err := guest.WriteTpmState(migParams.TpmState); err != nil {
+			return nil, errors.Wrap(err, "WriteTpmState")
+		}
+	}
+	if len(migParams.Nvram) > 0 {
+		if err := guest.WriteNvram(migParams.Nvram); err != nil {
+			return nil, errors.Wrap(err, "WriteNvram")
+		}
+	}
+
 	disks := migParams.Desc.Disks
 	if len(migParams.TargetStorageIds) > 0 {
 		var encInfo *apis.SEncryptInfo
@@ -969,6 +1053,8 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		startParams.Set("need_migrate", jsonutils.JSONTrue)
 		startParams.Set("source_qemu_cmdline", jsonutils.NewString(migParams.SourceQemuCmdline))
 		startParams.Set("live_migrate_use_tls", jsonutils.NewBool(migParams.EnableTLS))
+		tuning := resolveLiveMigrateTuning(migParams.LiveMigrateTuning, hostLiveMigrateTuning(), migParams.QemuVersion, migParams.IsLocal)
+		startParams.Set("live_migrate_tuning", jsonutils.Marshal(tuning))
 		if len(migParams.MigrateCerts) > 0 {
 			if err := guest.WriteMigrateCerts(migParams.MigrateCerts); err != nil {
 				return nil, errors.Wrap(err, "write migrate certs")
@@ -1074,36 +1160,6 @@ func (m *SGuestManager) GetNBDServerFreePort() int {
 	return port
 }
 
-func (m *SGuestManager) GetFreeVncPort() int {
-	vncPorts := make(map[int]struct{}, 0)
-	m.Servers.Range(func(k, v interface{}) bool {
-		guest := v.(*SKVMGuestInstance)
-		inUsePort := guest.GetVncPort()
-		if inUsePort > 0 {
-			vncPorts[inUsePort] = struct{}{}
-		}
-		return true
-	})
-	var port = LAST_USED_PORT + 1
-	for {
-		if _, ok := vncPorts[port]; ok ||
-			netutils2.IsTcpPortUsed("0.0.0.0", VNC_PORT_BASE+port) ||
-			netutils2.IsTcpPortUsed("127.0.0.1", MONITOR_PORT_BASE+port) {
-			port += 1
-		} else {
-			if !m.checkAndSetPort(port) {
-				continue
-			}
-			break
-		}
-	}
-	LAST_USED_PORT = port
-	if LAST_USED_PORT > 5000 {
-		LAST_USED_PORT = 0
-	}
-	return port
-}
-
 func (m *SGuestManager) ReloadDiskSnapshot(
 	ctx context.Context, params interface{},
 ) (jsonutils.JSONObject, error) {
@@ -1273,7 +1329,11 @@ func (m *SGuestManager) ExitGuestCleanup() {
 		return true
 	})
 	if !options.HostOptions.DisableSetCgroup {
-		cgrouputils.CgroupCleanAll()
+		if cgrouputils.IsCgroupV2() {
+			cgrouputils.CleanupCgroupV2Slices(hostconsts.HOST_CGROUP)
+		} else {
+			cgrouputils.CgroupCleanAll()
+		}
 	}
 }
 
@@ -1345,6 +1405,7 @@ func Init(host hostutils.IHost, serversPath string) {
 		guestManager = NewGuestManager(host, serversPath)
 		types.HealthCheckReactor = guestManager
 		types.GuestDescGetter = guestManager
+		guestManager.StartBalloonManager()
 	}
 }
 
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +28,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
@@ -39,11 +41,11 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 	"yunion.io/x/onecloud/pkg/util/timeutils2"
-	"yunion.io/x/onecloud/pkg/util/version"
 )
 
 type IGuestTasks interface {
@@ -482,6 +484,9 @@ func (d *SGuestDiskSyncTask) startAddDisk(disk *desc.SGuestDisk) {
 		params["encrypt.format"] = "luks"
 		params["encrypt.key-secret"] = "sec0"
 	}
+	for k, v := range disk.ThrottlingOptions() {
+		params[k] = v
+	}
 
 	var bus string
 	var pciRoot *desc.PCIController
@@ -549,6 +554,20 @@ func (d *SGuestDiskSyncTask) onAddDiskSucc(disk *desc.SGuestDisk, results string
 	} else if DISK_DRIVER_IDE == diskDriver {
 		params["unit"] = strconv.Itoa(int(diskIndex % 2))
 	}
+	if diskDriver == DISK_DRIVER_VIRTIO {
+		disk.IOThread = d.guest.allocDiskIOThread(disk)
+		params["iothread"] = disk.IOThread
+		if disk.IOThread != defaultIOThread && !utils.IsInStringArray(disk.IOThread, d.guest.Desc.IOThreads) {
+			d.guest.Desc.IOThreads = append(d.guest.Desc.IOThreads, disk.IOThread)
+			d.guest.Monitor.ObjectAdd("iothread", map[string]string{"id": disk.IOThread}, func(res string) {
+				if len(res) > 0 {
+					log.Warningf("add iothread %s: %s", disk.IOThread, res)
+				}
+				d.guest.Monitor.DeviceAdd(devType, params, d.onAddDeviceSucc)
+			})
+			return
+		}
+	}
 	d.guest.Monitor.DeviceAdd(devType, params, d.onAddDeviceSucc)
 }
 
@@ -808,6 +827,7 @@ func (t *SGuestIsolatedDeviceSyncTask) removeDevice(dev *desc.SGuestIsolatedDevi
 				// remove device
 				t.guest.Desc.IsolatedDevices = append(t.guest.Desc.IsolatedDevices[:i], t.guest.Desc.IsolatedDevices[i+1:]...)
 			}
+			t.guest.releaseIsolatedDevice(dev.Addr)
 		}
 		t.syncDevice()
 	}
@@ -850,7 +870,15 @@ func (t *SGuestIsolatedDeviceSyncTask) addDevice(dev *desc.SGuestIsolatedDevice)
 		}
 	}
 
+	if err := t.guest.reserveIsolatedDevice(dev.Addr); err != nil {
+		log.Errorln(err)
+		t.errors = append(t.errors, err)
+		t.syncDevice()
+		return
+	}
+
 	onFail := func(err error) {
+		t.guest.releaseIsolatedDevice(dev.Addr)
 		for i := 0; i < len(dev.VfioDevs); i++ {
 			if dev.VfioDevs[i].PCIAddr != nil {
 				if eRelease := t.guest.pciAddrs.ReleasePCIAddress(dev.VfioDevs[i].PCIAddr); eRelease != nil {
@@ -865,6 +893,10 @@ func (t *SGuestIsolatedDeviceSyncTask) addDevice(dev *desc.SGuestIsolatedDevice)
 	}
 
 	if dev.DevType == api.USB_TYPE {
+		if t.guest.Desc.Usb == nil {
+			onFail(errors.Errorf("guest has no usb controller"))
+			return
+		}
 		dev.Usb = desc.NewUsbDevice("usb-host", devObj.GetQemuId())
 		dev.Usb.Options = devObj.GetPassthroughOptions()
 	} else {
@@ -881,6 +913,7 @@ func (t *SGuestIsolatedDeviceSyncTask) addDevice(dev *desc.SGuestIsolatedDevice)
 			pciRoot.CType, "vfio-pci", id, devObj.GetAddr(), devObj.GetDeviceType() == api.GPU_VGA_TYPE,
 		)
 		dev.VfioDevs = append(dev.VfioDevs, vfioDev)
+		t.guest.setSriovFailoverPair(dev)
 
 		groupDevAddrs := devObj.GetIOMMUGroupRestAddrs()
 		for j := 0; j < len(groupDevAddrs); j++ {
@@ -966,6 +999,133 @@ func (t *SGuestIsolatedDeviceSyncTask) delDeviceCallBack(opts []*isolated_device
 	})
 }
 
+/**
+ *  GuestSharedDirSyncTask
+**/
+
+type SGuestSharedDirSyncTask struct {
+	guest   *SKVMGuestInstance
+	delDirs []*desc.SGuestSharedDir
+	addDirs []*desc.SGuestSharedDir
+	errors  []error
+
+	callback func(...error)
+}
+
+func NewGuestSharedDirSyncTask(guest *SKVMGuestInstance, delDirs, addDirs []*desc.SGuestSharedDir) *SGuestSharedDirSyncTask {
+	return &SGuestSharedDirSyncTask{guest, delDirs, addDirs, make([]error, 0), nil}
+}
+
+func (t *SGuestSharedDirSyncTask) Start(cb func(...error)) {
+	t.callback = cb
+	t.syncSharedDir()
+}
+
+func (t *SGuestSharedDirSyncTask) syncSharedDir() {
+	if len(t.delDirs) > 0 {
+		dir := t.delDirs[len(t.delDirs)-1]
+		t.delDirs = t.delDirs[:len(t.delDirs)-1]
+		t.removeSharedDir(dir)
+	} else if len(t.addDirs) > 0 {
+		dir := t.addDirs[len(t.addDirs)-1]
+		t.addDirs = t.addDirs[:len(t.addDirs)-1]
+		t.addSharedDir(dir)
+	} else {
+		t.callback(t.errors...)
+	}
+}
+
+func (t *SGuestSharedDirSyncTask) onFail(err error) {
+	log.Errorln(err)
+	t.errors = append(t.errors, err)
+	t.syncSharedDir()
+}
+
+func (t *SGuestSharedDirSyncTask) removeSharedDir(dir *desc.SGuestSharedDir) {
+	cb := func(res string) {
+		if len(res) > 0 {
+			t.onFail(errors.Errorf("shared dir %s device del failed: %s", dir.Tag, res))
+			return
+		}
+		t.guest.Monitor.ChardevRemove(dir.Socket.Id, func(res string) {
+			if len(res) > 0 {
+				log.Warningf("shared dir %s chardev remove: %s", dir.Tag, res)
+			}
+			output, err := procutils.NewRemoteCommandAsFarAsPossible("sh", "-c", t.guest.generateSharedDirStopScript(dir)).Output()
+			if err != nil {
+				log.Errorf("stop virtiofsd for %s: %s %s", dir.Tag, output, err)
+			}
+			for i := 0; i < len(t.guest.Desc.SharedDirs); i++ {
+				if t.guest.Desc.SharedDirs[i].Tag == dir.Tag {
+					if pciaddr := t.guest.Desc.SharedDirs[i].Pci.PCIAddr; pciaddr != nil {
+						if e := t.guest.pciAddrs.ReleasePCIAddress(pciaddr); e != nil {
+							log.Errorf("failed release shared dir pci address %s", pciaddr)
+						}
+					}
+					t.guest.Desc.SharedDirs = append(t.guest.Desc.SharedDirs[:i], t.guest.Desc.SharedDirs[i+1:]...)
+					break
+				}
+			}
+			t.syncSharedDir()
+		})
+	}
+	t.guest.Monitor.DeviceDel(dir.Pci.Id, cb)
+}
+
+func (t *SGuestSharedDirSyncTask) addSharedDir(dir *desc.SGuestSharedDir) {
+	if !t.guest.isMemShared() {
+		t.onFail(errors.Errorf("guest memory is not shared, restart guest to attach shared dir %s", dir.Tag))
+		return
+	}
+	pciRoot := t.guest.getHotPlugPciController()
+	if pciRoot == nil {
+		t.onFail(errors.Errorf("no hotplugable pci controller found"))
+		return
+	}
+	t.guest.initSharedDirDesc(dir, pciRoot)
+	if err := t.guest.ensureDevicePciAddress(dir.Pci, -1, nil); err != nil {
+		t.onFail(errors.Wrapf(err, "ensure shared dir %s pci address", dir.Tag))
+		return
+	}
+
+	onFail := func(err error) {
+		if e := t.guest.pciAddrs.ReleasePCIAddress(dir.Pci.PCIAddr); e != nil {
+			log.Errorf("failed release shared dir pci address %s: %s", dir.Pci.PCIAddr, e)
+		}
+		procutils.NewRemoteCommandAsFarAsPossible("sh", "-c", t.guest.generateSharedDirStopScript(dir)).Run()
+		t.onFail(err)
+	}
+
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("sh", "-c", t.guest.generateSharedDirStartScript(dir)).Output()
+	if err != nil {
+		onFail(errors.Wrapf(err, "start virtiofsd for %s: %s", dir.Tag, output))
+		return
+	}
+
+	t.guest.Monitor.ChardevAdd(dir.Socket.Backend, dir.Socket.Id, dir.Socket.Options, func(res string) {
+		if len(res) > 0 {
+			onFail(errors.Errorf("shared dir %s chardev add failed: %s", dir.Tag, res))
+			return
+		}
+		params := map[string]string{
+			"id":      dir.Pci.Id,
+			"bus":     dir.Pci.BusStr(),
+			"addr":    dir.Pci.SlotFunc(),
+			"chardev": dir.Socket.Id,
+			"tag":     dir.Tag,
+		}
+		t.guest.Monitor.DeviceAdd(dir.Pci.DevType, params, func(res string) {
+			if len(res) > 0 {
+				t.guest.Monitor.ChardevRemove(dir.Socket.Id, func(string) {})
+				onFail(errors.Errorf("shared dir %s device add failed: %s", dir.Tag, res))
+				return
+			}
+			t.guest.Desc.SharedDirs = append(t.guest.Desc.SharedDirs, dir)
+			t.syncSharedDir()
+		})
+	})
+}
+
 /**
  *  GuestLiveMigrateTask
 **/
@@ -983,6 +1143,8 @@ type SGuestLiveMigrateTask struct {
 
 	expectDowntime int64
 	dirtySyncCount int64
+
+	postcopyStarted bool
 }
 
 func NewGuestLiveMigrateTask(
@@ -1004,30 +1166,24 @@ func (s *SGuestLiveMigrateTask) onSetZeroBlocks(res string) {
 		return
 	}
 
-	// https://wiki.qemu.org/Features/AutoconvergeLiveMigration
-	s.Monitor.MigrateSetCapability("auto-converge", "on", s.onSetAutoConverge)
-}
-
-func (s *SGuestLiveMigrateTask) onSetAutoConverge(res string) {
-	if strings.Contains(strings.ToLower(res), "error") {
-		s.migrateFailed(fmt.Sprintf("Migrate set capability auto-converge error: %s", res))
-		return
+	if s.params.Tuning == nil {
+		s.params.Tuning = legacyLiveMigrateTuning(s.QemuVersion)
 	}
-
-	if version.LT(s.QemuVersion, "4.0.0") {
-		s.startMigrate()
-		return
-	}
-
-	cb := func(res string) {
-		if strings.Contains(strings.ToLower(res), "error") {
-			s.migrateFailed(fmt.Sprintf("Migrate set capability auto-converge error: %s", res))
+	t := s.params.Tuning
+	log.Infof("migrate src guest %s tuning %+v", s.GetName(), *t)
+	s.migrateSetCapabilities(t.srcCapabilities(s.QemuVersion), func(err error) {
+		if err != nil {
+			s.migrateFailed(err.Error())
 			return
 		}
-		s.startMigrate()
-	}
-	log.Infof("migrate src guest enable multifd")
-	s.Monitor.MigrateSetCapability("multifd", "on", cb)
+		s.migrateSetParameters(t.srcParameters(), func(err error) {
+			if err != nil {
+				s.migrateFailed(err.Error())
+				return
+			}
+			s.startMigrate()
+		})
+	})
 }
 
 func (s *SGuestLiveMigrateTask) startRamMigrateTimeout() {
@@ -1150,7 +1306,7 @@ func (s *SGuestLiveMigrateTask) onGetMigrateStatus(stats *monitor.MigrationInfo)
 		jsonStats := jsonutils.Marshal(stats)
 		log.Infof("migration info %s", jsonStats)
 		s.migrateComplete(jsonStats)
-	} else if status == "failed" || status == "cancelled" {
+	} else if status == "failed" || status == "cancelled" || status == "postcopy-paused" {
 		s.migrateFailed(fmt.Sprintf("Query migrate got status: %s", status))
 	} else if status == "active" {
 		var (
@@ -1172,6 +1328,13 @@ func (s *SGuestLiveMigrateTask) onGetMigrateStatus(stats *monitor.MigrationInfo)
 		progress := (1 - float64(diskRemain+ramRemain)/float64(diskTotal+ramTotal)) * 100.0
 		hostutils.UpdateServerProgress(context.Background(), s.Id, progress, mbps)
 
+		if s.shouldStartPostcopy(stats) {
+			s.postcopyStarted = true
+			log.Infof("migrate %s switch to postcopy after %d dirty sync rounds", s.GetName(), stats.RAM.DirtySyncCount)
+			s.Monitor.MigrateStartPostcopy(s.onMigrateStartPostcopy)
+			return
+		}
+
 		if s.params.QuicklyFinish && stats.RAM != nil && stats.RAM.Remaining > 0 {
 			if stats.CPUThrottlePercentage == nil {
 				// qemu do not enable cpu throttle, don't need set downtime
@@ -1207,6 +1370,17 @@ func (s *SGuestLiveMigrateTask) onGetMigrateStatus(stats *monitor.MigrationInfo)
 	}
 }
 
+// 内存同步轮数超过阈值仍未完成时切换为 postcopy, 保证大内存虚机能够收敛
+func (s *SGuestLiveMigrateTask) shouldStartPostcopy(stats *monitor.MigrationInfo) bool {
+	if s.postcopyStarted || s.params.Tuning == nil || !s.params.Tuning.Postcopy() {
+		return false
+	}
+	if stats.RAM == nil || stats.RAM.Remaining == 0 {
+		return false
+	}
+	return stats.RAM.DirtySyncCount >= int64(s.params.Tuning.PostcopySwitchoverRounds)
+}
+
 func (s *SGuestLiveMigrateTask) onMigrateStartPostcopy(res string) {
 	if strings.Contains(strings.ToLower(res), "error") {
 		s.migrateFailed(fmt.Sprintf("onMigrateStartPostcopy error: %s", res))
@@ -1304,7 +1478,9 @@ func (s *SGuestResumeTask) Start() {
 		s.Monitor.ObjectDel("tls0", func(res string) {
 			log.Infof("Clean %s tls0 object: %s", s.GetName(), res)
 			pkiPath := s.getPKIDirPath()
-			if err := os.RemoveAll(pkiPath); err != nil {
+			if s.isPKIDirInUse() {
+				log.Infof("Keep tls pki dir %s used by monitor or display", pkiPath)
+			} else if err := os.RemoveAll(pkiPath); err != nil {
 				log.Warningf("Remove tls pki dir %s error: %v", pkiPath, err)
 			}
 			s.confirmRunning()
@@ -1444,6 +1620,8 @@ func (s *SGuestResumeTask) onStartRunning() {
 				s.taskFailed(err.Error())
 				return
 			}
+		} else if machineType := s.getQemuMachineType(); len(machineType) > 0 {
+			data = jsonutils.Marshal(map[string]string{"machine_type": machineType})
 		}
 		hostutils.TaskComplete(s.ctx, data)
 	}
@@ -2030,8 +2208,10 @@ type SGuestHotplugCpuMemTask struct {
 	addCpuCount int
 	addMemSize  int
 
-	originalCpuCount int
-	addedCpuCount    int
+	originalCpuCount      int
+	addedCpuCount         int
+	hotpluggableCpus      []monitor.HotpluggableCPU
+	cpuHotplugUnsupported bool
 
 	addedMemSize    int
 	memSlotNewIndex *int
@@ -2061,7 +2241,85 @@ func (task *SGuestHotplugCpuMemTask) Start() {
 }
 
 func (task *SGuestHotplugCpuMemTask) startAddCpu() {
-	task.Monitor.GetCpuCount(task.onGetCpuCount)
+	if !task.isCpuHotplugSupported() {
+		task.onCpuHotplugUnsupported(fmt.Sprintf("guest os %s %s doesn't support cpu hotplug", task.GetOsName(), task.getOsDistribution()))
+		return
+	}
+	task.Monitor.QueryHotpluggableCpus(task.onQueryHotpluggableCpus)
+}
+
+func (task *SGuestHotplugCpuMemTask) onQueryHotpluggableCpus(cpus []monitor.HotpluggableCPU, reason string) {
+	if len(reason) > 0 || len(cpus) == 0 {
+		// fallback to legacy cpu-add on qemu without query-hotpluggable-cpus
+		log.Infof("guest %s query hotpluggable cpus %q, fallback to cpu-add", task.GetName(), reason)
+		task.Monitor.GetCpuCount(task.onGetCpuCount)
+		return
+	}
+	task.hotpluggableCpus = make([]monitor.HotpluggableCPU, 0)
+	var vcpus int
+	for i := range cpus {
+		if cpus[i].QomPath != nil {
+			task.originalCpuCount += int(cpus[i].VcpusCount)
+			continue
+		}
+		if vcpus < task.addCpuCount {
+			task.hotpluggableCpus = append(task.hotpluggableCpus, cpus[i])
+			vcpus += int(cpus[i].VcpusCount)
+		}
+	}
+	if vcpus < task.addCpuCount {
+		task.onCpuHotplugUnsupported(fmt.Sprintf("only %d hotpluggable cpus left, machine %s can't add %d cpus", vcpus, task.getMachine(), task.addCpuCount))
+		return
+	}
+	sort.Slice(task.hotpluggableCpus, func(i, j int) bool {
+		return cpuPropsLess(task.hotpluggableCpus[i].Props, task.hotpluggableCpus[j].Props)
+	})
+	task.doDeviceAddCpu()
+}
+
+func cpuPropsLess(a, b monitor.CpuInstanceProperties) bool {
+	for _, ids := range [][2]*int64{
+		{a.NodeId, b.NodeId}, {a.SocketId, b.SocketId}, {a.DieId, b.DieId},
+		{a.CoreId, b.CoreId}, {a.ThreadId, b.ThreadId},
+	} {
+		if ids[0] == nil || ids[1] == nil || *ids[0] == *ids[1] {
+			continue
+		}
+		return *ids[0] < *ids[1]
+	}
+	return false
+}
+
+func (task *SGuestHotplugCpuMemTask) doDeviceAddCpu() {
+	if task.addedCpuCount >= task.addCpuCount || len(task.hotpluggableCpus) == 0 {
+		task.startAddMem()
+		return
+	}
+	cpu := task.hotpluggableCpus[0]
+	task.hotpluggableCpus = task.hotpluggableCpus[1:]
+	params := map[string]string{
+		"id": fmt.Sprintf("vcpu%d", task.originalCpuCount+task.addedCpuCount),
+	}
+	for k, v := range map[string]*int64{
+		"node-id":   cpu.Props.NodeId,
+		"socket-id": cpu.Props.SocketId,
+		"die-id":    cpu.Props.DieId,
+		"core-id":   cpu.Props.CoreId,
+		"thread-id": cpu.Props.ThreadId,
+	} {
+		if v != nil {
+			params[k] = strconv.FormatInt(*v, 10)
+		}
+	}
+	task.Monitor.DeviceAdd(cpu.Type, params, func(reason string) {
+		if len(reason) > 0 {
+			log.Errorf("device_add %s %v: %s", cpu.Type, params, reason)
+			task.onFail(reason)
+			return
+		}
+		task.addedCpuCount += int(cpu.VcpusCount)
+		task.doDeviceAddCpu()
+	})
 }
 
 func (task *SGuestHotplugCpuMemTask) onGetCpuCount(count int) {
@@ -2080,13 +2338,24 @@ func (task *SGuestHotplugCpuMemTask) doAddCpu() {
 func (task *SGuestHotplugCpuMemTask) onAddCpu(reason string) {
 	if len(reason) > 0 {
 		log.Errorln(reason)
-		task.onFail(reason)
+		if task.addedCpuCount == 0 {
+			task.onCpuHotplugUnsupported(reason)
+		} else {
+			task.onFail(reason)
+		}
 		return
 	}
 	task.addedCpuCount += 1
 	task.doAddCpu()
 }
 
+// nothing changed, tell region to change config by restarting guest
+func (task *SGuestHotplugCpuMemTask) onCpuHotplugUnsupported(reason string) {
+	log.Warningf("guest %s cpu hotplug unsupported: %s", task.GetName(), reason)
+	task.cpuHotplugUnsupported = true
+	task.onFail(reason)
+}
+
 func (task *SGuestHotplugCpuMemTask) startAddMem() {
 	if task.addMemSize > 0 {
 		task.Monitor.GeMemtSlotIndex(task.onGetSlotIndex)
@@ -2114,7 +2383,7 @@ func (task *SGuestHotplugCpuMemTask) onGetSlotIndex(index int) {
 			return
 		}
 		err = procutils.NewRemoteCommandAsFarAsPossible("mount", "-t", "hugetlbfs", "-o",
-			fmt.Sprintf("pagesize=%dK,size=%dM", task.manager.host.HugepageSizeKb(), task.addMemSize),
+			fmt.Sprintf("pagesize=%dK,size=%dM", task.getHugepageSizeKb(), task.addMemSize),
 			fmt.Sprintf("hugetlbfs-%s-%d", task.GetId(), index),
 			memPath,
 		).Run()
@@ -2132,6 +2401,16 @@ func (task *SGuestHotplugCpuMemTask) onGetSlotIndex(index int) {
 			"share":    "on",
 			"prealloc": "on",
 		}
+		if node := task.getHugepageNumaNode(); node >= 0 {
+			options["host-nodes"] = strconv.Itoa(node)
+			options["policy"] = "bind"
+		}
+	} else if task.isMemShared() {
+		objType = "memory-backend-memfd"
+		options = map[string]string{
+			"size":  fmt.Sprintf("%dM", task.addMemSize),
+			"share": "on",
+		}
 	} else {
 		objType = "memory-backend-ram"
 		options = map[string]string{
@@ -2192,6 +2471,9 @@ func (task *SGuestHotplugCpuMemTask) onAddMemDevice(reason string) {
 
 func (task *SGuestHotplugCpuMemTask) updateGuestDesc() {
 	task.Desc.Cpu += int64(task.addedCpuCount)
+	if task.Desc.CpuDesc != nil {
+		task.Desc.CpuDesc.Cpus += uint(task.addedCpuCount)
+	}
 	task.Desc.Mem += int64(task.addedMemSize)
 	if task.addedMemSize > 0 {
 		if task.Desc.MemDesc.MemSlots == nil {
@@ -2202,7 +2484,7 @@ func (task *SGuestHotplugCpuMemTask) updateGuestDesc() {
 	if task.addedCpuCount > 0 || task.addedMemSize > 0 {
 		task.SaveLiveDesc(task.Desc)
 	}
-	if task.addedMemSize > 0 {
+	if task.addedCpuCount > 0 || task.addedMemSize > 0 {
 		vncPort := task.GetVncPort()
 		data := jsonutils.NewDict()
 		data.Set("vnc_port", jsonutils.NewInt(int64(vncPort)))
@@ -2215,7 +2497,9 @@ func (task *SGuestHotplugCpuMemTask) updateGuestDesc() {
 
 func (task *SGuestHotplugCpuMemTask) onFail(reason string) {
 	body := jsonutils.NewDict()
-	if task.addedCpuCount < task.addCpuCount {
+	if task.cpuHotplugUnsupported {
+		body.Set("cpu_hotplug_unsupported", jsonutils.JSONTrue)
+	} else if task.addedCpuCount < task.addCpuCount {
 		body.Set("add_cpu_failed", jsonutils.JSONTrue)
 		body.Set("added_cpu", jsonutils.NewInt(int64(task.addedCpuCount)))
 	} else if task.memSlotNewIndex != nil {
@@ -2233,9 +2517,11 @@ func (task *SGuestHotplugCpuMemTask) onSucc() {
 type SGuestBlockIoThrottleTask struct {
 	*SKVMGuestInstance
 
-	ctx  context.Context
-	bps  int64
-	iops int64
+	ctx context.Context
+	// disk_id => throttle
+	throttles map[string]SDiskIoThrottle
+
+	disks []*desc.SGuestDisk
 }
 
 func (task *SGuestBlockIoThrottleTask) Start() error {
@@ -2254,8 +2540,18 @@ func (task *SGuestBlockIoThrottleTask) onBlockDriversSucc(blocks []monitor.QemuB
 			drivers = append(drivers, blocks[i].Device)
 		}
 	}
-	log.Infof("Drivers %s do io throttle bps %d iops %d", drivers, task.bps, task.iops)
-	task.doIoThrottle(drivers)
+	task.disks = make([]*desc.SGuestDisk, 0)
+	for _, disk := range task.Desc.Disks {
+		if _, ok := task.throttles[disk.DiskId]; !ok {
+			continue
+		}
+		if !utils.IsInStringArray(fmt.Sprintf("drive_%d", disk.Index), drivers) {
+			continue
+		}
+		task.disks = append(task.disks, disk)
+	}
+	log.Infof("Drivers %s do io throttle %v", drivers, task.throttles)
+	task.doIoThrottle(0)
 }
 
 func (task *SGuestBlockIoThrottleTask) taskFail(reason string) {
@@ -2272,21 +2568,27 @@ func (task *SGuestBlockIoThrottleTask) taskComplete(data jsonutils.JSONObject) {
 	}
 }
 
-func (task *SGuestBlockIoThrottleTask) doIoThrottle(drivers []string) {
-	if len(drivers) == 0 {
+func (task *SGuestBlockIoThrottleTask) doIoThrottle(idx int) {
+	if idx >= len(task.disks) {
+		// 保存到 desc, 重启后由启动参数 throttling.* 继续生效
+		if err := task.SaveLiveDesc(task.Desc); err != nil {
+			log.Errorf("guest %s save live desc: %s", task.GetName(), err)
+		}
 		task.taskComplete(nil)
-	} else {
-		driver := drivers[0]
-		drivers = drivers[1:]
-		_cb := func(res string) {
-			if len(res) > 0 {
-				task.taskFail(res)
-			} else {
-				task.doIoThrottle(drivers)
-			}
+		return
+	}
+	disk := task.disks[idx]
+	throttle := task.throttles[disk.DiskId]
+	_cb := func(res string) {
+		if len(res) > 0 {
+			task.taskFail(res)
+		} else {
+			disk.Bps = int(throttle.Bps)
+			disk.Iops = int(throttle.Iops)
+			task.doIoThrottle(idx + 1)
 		}
-		task.Monitor.BlockIoThrottle(driver, task.bps, task.iops, _cb)
 	}
+	task.Monitor.BlockIoThrottle(fmt.Sprintf("drive_%d", disk.Index), throttle.Bps*1024*1024, throttle.Iops, _cb)
 }
 
 type SCancelBlockJobs struct {
@@ -2411,6 +2713,7 @@ func (t *SGuestStorageCloneDiskTask) Start(guestRunning bool) {
 			timeutils2.AddTimeout(time.Second*3, func() {
 				t.SyncStatus("drive mirror started")
 			})
+			go t.reportMirrorProgress(fmt.Sprintf("drive_%d", diskIndex), t.params.SourceDisk.GetId())
 		}
 	}
 
@@ -2425,6 +2728,51 @@ func (t *SGuestStorageCloneDiskTask) Start(guestRunning bool) {
 	)
 }
 
+const mirrorProgressReportInterval = 10 * time.Second
+
+// 定期上报 drive-mirror 同步进度, 直到 mirror job 进入 ready 状态或者消失
+func (t *SGuestStorageCloneDiskTask) reportMirrorProgress(drive, diskId string) {
+	var preOffset int64
+	for {
+		time.Sleep(mirrorProgressReportInterval)
+		if !t.IsRunning() {
+			return
+		}
+		ch := make(chan []monitor.BlockJob, 1)
+		t.Monitor.GetBlockJobs(func(jobs []monitor.BlockJob) {
+			ch <- jobs
+		})
+		var job *monitor.BlockJob
+		for _, j := range <-ch {
+			if j.Device == drive && j.Type == "mirror" {
+				job = &j
+				break
+			}
+		}
+		if job == nil {
+			return
+		}
+		var progress float32 = 100
+		if job.Len > 0 && !job.Ready {
+			progress = float32(job.Offset) * 100 / float32(job.Len)
+		}
+		input := api.ServerBlockMirrorProgressInput{
+			DiskId:    diskId,
+			Progress:  progress,
+			SpeedMbps: float64(job.Offset-preOffset) / mirrorProgressReportInterval.Seconds() / 1024 / 1024,
+		}
+		preOffset = job.Offset
+		_, err := modules.Servers.PerformAction(hostutils.GetComputeSession(context.Background()),
+			t.GetId(), "block-mirror-progress", jsonutils.Marshal(input))
+		if err != nil {
+			log.Errorf("Server %s report block mirror progress failed: %s", t.GetId(), err)
+		}
+		if job.Ready {
+			return
+		}
+	}
+}
+
 type SGuestLiveChangeDisk struct {
 	*SKVMGuestInstance
 
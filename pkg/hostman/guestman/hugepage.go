@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"strconv"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/sysutils"
+)
+
+// 虚机指定的大页大小, 未指定时使用宿主机默认大页
+func (s *SKVMGuestInstance) getHugepageSizeKb() int {
+	if v, ok := s.Desc.Metadata[api.VM_METADATA_HUGEPAGE_SIZE_KB]; ok {
+		if sizeKb, err := strconv.Atoi(v); err == nil && sizeKb > 0 {
+			return sizeKb
+		}
+	}
+	return s.manager.host.HugepageSizeKb()
+}
+
+// 大页内存绑定的 NUMA 节点, -1 表示不绑定
+func (s *SKVMGuestInstance) getHugepageNumaNode() int {
+	if v, ok := s.Desc.Metadata[api.VM_METADATA_HUGEPAGE_NUMA_NODE]; ok {
+		if node, err := strconv.Atoi(v); err == nil && node >= 0 {
+			return node
+		}
+	}
+	return -1
+}
+
+// 启动前检查大页池是否有足够的空闲页, 避免 qemu 预分配内存时失败
+func (s *SKVMGuestInstance) checkHugepages() error {
+	var (
+		pages sysutils.THugepages
+		err   error
+	)
+	if s.hasNumaNodes() && s.getNumaPolicy() == api.VM_NUMA_POLICY_STRICT {
+		return s.checkNumaHugepages()
+	}
+	node := s.getHugepageNumaNode()
+	if node >= 0 {
+		pages, err = sysutils.GetNodeHugepages(node)
+	} else {
+		pages, err = sysutils.GetHugepages()
+	}
+	if err != nil {
+		return errors.Wrap(err, "get hugepages")
+	}
+	sizeKb := s.getHugepageSizeKb()
+	if err := pages.CheckFree(sizeKb, s.Desc.Mem); err != nil {
+		if node >= 0 {
+			return errors.Wrapf(err, "numa node %d", node)
+		}
+		return err
+	}
+	return nil
+}
+
+// 严格绑定的 NUMA 节点需在各自的宿主机节点上有足够的空闲大页
+func (s *SKVMGuestInstance) checkNumaHugepages() error {
+	need := map[int]int64{}
+	for _, node := range s.Desc.MemDesc.NumaNodes {
+		need[node.HostNode] += node.SizeMB
+	}
+	sizeKb := s.getHugepageSizeKb()
+	for hostNode, sizeMB := range need {
+		pages, err := sysutils.GetNodeHugepages(hostNode)
+		if err != nil {
+			return errors.Wrapf(err, "get numa node %d hugepages", hostNode)
+		}
+		if err := pages.CheckFree(sizeKb, sizeMB); err != nil {
+			return errors.Wrapf(err, "numa node %d", hostNode)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+)
+
+const defaultIOThread = "iothread0"
+
+func (s *SKVMGuestInstance) isIOThreadPerDevice() bool {
+	return s.Desc.Metadata[compute.VM_METADATA_IOTHREAD_POLICY] == compute.VM_IOTHREAD_POLICY_PER_DEVICE
+}
+
+func (s *SKVMGuestInstance) allocDiskIOThread(disk *desc.SGuestDisk) string {
+	if !s.isIOThreadPerDevice() {
+		return defaultIOThread
+	}
+	return fmt.Sprintf("iothread_drive_%d", disk.Index)
+}
+
+// initGuestIOThreads 按 iothread 策略为 virtio-blk 磁盘及 virtio-scsi 控制器分配 iothread
+func (s *SKVMGuestInstance) initGuestIOThreads() {
+	s.Desc.IOThreads = []string{defaultIOThread}
+	for i := range s.Desc.Disks {
+		if s.Desc.Disks[i].Driver != DISK_DRIVER_VIRTIO {
+			continue
+		}
+		s.Desc.Disks[i].IOThread = s.allocDiskIOThread(s.Desc.Disks[i])
+		if s.Desc.Disks[i].IOThread != defaultIOThread {
+			s.Desc.IOThreads = append(s.Desc.IOThreads, s.Desc.Disks[i].IOThread)
+		}
+	}
+	if s.Desc.VirtioScsi != nil && s.isIOThreadPerDevice() {
+		if s.Desc.VirtioScsi.Options == nil {
+			s.Desc.VirtioScsi.Options = map[string]string{}
+		}
+		s.Desc.VirtioScsi.Options["iothread"] = "iothread_scsi"
+		s.Desc.IOThreads = append(s.Desc.IOThreads, "iothread_scsi")
+	}
+}
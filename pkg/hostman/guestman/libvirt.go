@@ -31,11 +31,14 @@ import (
 	"yunion.io/x/pkg/util/netutils"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
 )
 
 func (m *SGuestManager) GuestCreateFromLibvirt(
@@ -72,7 +75,8 @@ func (m *SGuestManager) GuestCreateFromLibvirt(
 	}
 
 	if len(createConfig.MonitorPath) > 0 {
-		if pid := findGuestProcessPid(guest.getOriginId(), "[q]emu-kvm"); len(pid) > 0 {
+		// 接管的虚机可能由 qemu-system-* 启动, 不限定 qemu-kvm
+		if pid := findGuestProcessPid(guest.getOriginId(), "[q]emu"); len(pid) > 0 {
 			fileutils2.FilePutContents(guest.GetPidFilePath(), pid, false)
 			guest.StartMonitorWithImportGuestSocketFile(ctx, createConfig.MonitorPath, nil)
 			stopScript := guest.generateStopScript(nil)
@@ -356,3 +360,39 @@ func (m *SGuestManager) LibvirtDomainInterfaceToNicConfig(
 	}
 	return nicConfigs, nil
 }
+
+// ExportLibvirtXml 将虚机描述导出为 libvirt domain xml, 便于使用 virsh 调试或迁移到 libvirt 环境
+func (s *SKVMGuestInstance) ExportLibvirtXml() (string, error) {
+	opts := desc.SLibvirtDomainOptions{
+		Emulator: qemutils.GetQemu(s.GetQemuVersionStr()),
+		Arch:     "x86_64",
+		Kvm:      s.IsKvmSupport() && !options.HostOptions.DisableKVM,
+		VncPort:  s.GetVncPort(),
+	}
+	if len(opts.Emulator) == 0 {
+		opts.Emulator = qemutils.GetQemu("")
+	}
+	if s.manager.host.IsAarch64() {
+		opts.Arch = "aarch64"
+	}
+	xml, err := desc.ToLibvirtDomain(s.Desc, opts).Marshal()
+	if err != nil {
+		return "", errors.Wrap(err, "marshal libvirt domain")
+	}
+	return xml, nil
+}
+
+func (m *SGuestManager) LibvirtXmlToGuestDesc(xml string) (*compute.SLibvirtXmlToDescOutput, error) {
+	domain := &libvirtxml.Domain{}
+	if err := domain.Unmarshal(strings.TrimSpace(xml)); err != nil {
+		return nil, errors.Wrap(err, "unmarshal libvirt domain xml")
+	}
+	guestDesc, warnings, err := desc.FromLibvirtDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	return &compute.SLibvirtXmlToDescOutput{
+		Desc:     jsonutils.Marshal(guestDesc),
+		Warnings: warnings,
+	}, nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	MGMT_FIREWALL_TABLE = "onecloud_mgmt"
+)
+
+// 管理面防火墙, 通过 nftables 限制虚机 VNC/QMP/HMP/迁移/NBD 端口只允许平台服务网段访问
+type sMgmtFirewall struct {
+	lock    sync.Mutex
+	enabled bool
+
+	allowV4 []string
+	allowV6 []string
+	// guest id -> 该虚机占用的管理端口
+	guestPorts map[string][]int
+}
+
+func newMgmtFirewall() *sMgmtFirewall {
+	fw := &sMgmtFirewall{
+		guestPorts: map[string][]int{},
+	}
+	if !options.HostOptions.EnableMgmtFirewall {
+		return fw
+	}
+	if len(options.HostOptions.MgmtFirewallAllowCidrs) == 0 {
+		log.Warningf("enable_mgmt_firewall is set but mgmt_firewall_allow_cidrs is empty, skip management plane firewall")
+		return fw
+	}
+	fw.allowV4, fw.allowV6 = splitMgmtFirewallCidrs(options.HostOptions.MgmtFirewallAllowCidrs)
+	fw.enabled = true
+	return fw
+}
+
+func splitMgmtFirewallCidrs(cidrs []string) ([]string, []string) {
+	v4, v6 := []string{}, []string{}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if len(cidr) == 0 {
+			continue
+		}
+		if strings.Contains(cidr, ":") {
+			v6 = append(v6, cidr)
+		} else {
+			v4 = append(v4, cidr)
+		}
+	}
+	return v4, v6
+}
+
+func nftElements(elems []string) string {
+	return fmt.Sprintf("{ %s }", strings.Join(elems, ", "))
+}
+
+func nftPorts(ports []int) string {
+	elems := make([]string, len(ports))
+	for i := range ports {
+		elems[i] = fmt.Sprintf("%d", ports[i])
+	}
+	return nftElements(elems)
+}
+
+// 重建整张表, 每条命令作为一次 nft 调用的参数
+func generateMgmtFirewallCommands(allowV4, allowV6 []string, ports []int) [][]string {
+	tbl := []string{"inet", MGMT_FIREWALL_TABLE}
+	join := func(prefix []string, args ...string) []string {
+		ret := append([]string{}, prefix...)
+		return append(ret, args...)
+	}
+	cmds := [][]string{
+		join([]string{"add", "table"}, tbl...),
+		join([]string{"delete", "table"}, tbl...),
+		join([]string{"add", "table"}, tbl...),
+		join(join([]string{"add", "set"}, tbl...), "allow_v4", "{ type ipv4_addr; flags interval; }"),
+		join(join([]string{"add", "set"}, tbl...), "allow_v6", "{ type ipv6_addr; flags interval; }"),
+		join(join([]string{"add", "set"}, tbl...), "ports", "{ type inet_service; }"),
+	}
+	if len(allowV4) > 0 {
+		cmds = append(cmds, join(join([]string{"add", "element"}, tbl...), "allow_v4", nftElements(allowV4)))
+	}
+	if len(allowV6) > 0 {
+		cmds = append(cmds, join(join([]string{"add", "element"}, tbl...), "allow_v6", nftElements(allowV6)))
+	}
+	if len(ports) > 0 {
+		cmds = append(cmds, join(join([]string{"add", "element"}, tbl...), "ports", nftPorts(ports)))
+	}
+	rule := join([]string{"add", "rule"}, tbl...)
+	cmds = append(cmds,
+		join(join([]string{"add", "chain"}, tbl...), "input", "{ type filter hook input priority -10; policy accept; }"),
+		join(rule, "input", "iifname", "lo", "accept"),
+		join(rule, "input", "tcp", "dport", "@ports", "ip", "saddr", "@allow_v4", "accept"),
+		join(rule, "input", "tcp", "dport", "@ports", "ip6", "saddr", "@allow_v6", "accept"),
+		join(rule, "input", "tcp", "dport", "@ports", "drop"),
+	)
+	return cmds
+}
+
+func (fw *sMgmtFirewall) nft(args ...string) error {
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("nft", args...).Output()
+	if err != nil {
+		return errors.Wrapf(err, "nft %s: %s", strings.Join(args, " "), output)
+	}
+	return nil
+}
+
+func (fw *sMgmtFirewall) allPorts(excludeGuestId string) map[int]struct{} {
+	ret := map[int]struct{}{}
+	for guestId, ports := range fw.guestPorts {
+		if guestId == excludeGuestId {
+			continue
+		}
+		for _, port := range ports {
+			ret[port] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// 根据已运行虚机的端口重建规则
+func (fw *sMgmtFirewall) Init(guestPorts map[string][]int) error {
+	if fw == nil || !fw.enabled {
+		return nil
+	}
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	fw.guestPorts = guestPorts
+	ports := []int{}
+	for port := range fw.allPorts("") {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, cmd := range generateMgmtFirewallCommands(fw.allowV4, fw.allowV6, ports) {
+		if err := fw.nft(cmd...); err != nil {
+			return err
+		}
+	}
+	log.Infof("management plane firewall initialized with %d ports", len(ports))
+	return nil
+}
+
+func (m *SGuestManager) initMgmtFirewall() {
+	guestPorts := map[string][]int{}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest, ok := v.(*SKVMGuestInstance)
+		if ok && guest.IsRunning() {
+			if ports := guest.getMgmtFirewallPorts(guest.GetVncPort()); len(ports) > 0 {
+				guestPorts[guest.Id] = ports
+			}
+		}
+		return true
+	})
+	if err := m.mgmtFirewall.Init(guestPorts); err != nil {
+		log.Errorf("init management plane firewall: %v", err)
+	}
+}
+
+func (s *SKVMGuestInstance) getMgmtFirewallPorts(vncPort int) []int {
+	if vncPort <= 0 {
+		return nil
+	}
+	ports := []int{VNC_PORT_BASE + vncPort, s.GetHmpMonitorPort(vncPort), s.GetQmpMonitorPort(vncPort)}
+	if s.LiveMigrateDestPort != nil {
+		ports = append(ports, int(*s.LiveMigrateDestPort))
+	}
+	return ports
+}
+
+func (fw *sMgmtFirewall) AddGuestPorts(guestId string, ports ...int) {
+	if fw == nil || !fw.enabled || len(ports) == 0 {
+		return
+	}
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	fw.guestPorts[guestId] = append(fw.guestPorts[guestId], ports...)
+	err := fw.nft("add", "element", "inet", MGMT_FIREWALL_TABLE, "ports", nftPorts(ports))
+	if err != nil {
+		log.Errorf("add guest %s management ports %v to firewall: %v", guestId, ports, err)
+	}
+}
+
+func (fw *sMgmtFirewall) RemoveGuestPorts(guestId string) {
+	if fw == nil || !fw.enabled {
+		return
+	}
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+
+	ports, ok := fw.guestPorts[guestId]
+	if !ok {
+		return
+	}
+	delete(fw.guestPorts, guestId)
+	// 端口可能已被其他虚机复用
+	inUse := fw.allPorts("")
+	for _, port := range ports {
+		if _, ok := inUse[port]; ok {
+			continue
+		}
+		err := fw.nft("delete", "element", "inet", MGMT_FIREWALL_TABLE, "ports", nftPorts([]int{port}))
+		if err != nil {
+			log.Warningf("remove guest %s management port %d from firewall: %v", guestId, port, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMgmtFirewallCidrs(t *testing.T) {
+	v4, v6 := splitMgmtFirewallCidrs([]string{"10.0.0.0/8", " fd00::/8 ", "", "192.168.1.10"})
+	if !reflect.DeepEqual(v4, []string{"10.0.0.0/8", "192.168.1.10"}) {
+		t.Fatalf("unexpected v4 cidrs %v", v4)
+	}
+	if !reflect.DeepEqual(v6, []string{"fd00::/8"}) {
+		t.Fatalf("unexpected v6 cidrs %v", v6)
+	}
+}
+
+func TestGenerateMgmtFirewallCommands(t *testing.T) {
+	cmds := generateMgmtFirewallCommands([]string{"10.0.0.0/8"}, nil, []int{5901, 55901})
+	lines := make([]string, len(cmds))
+	for i := range cmds {
+		lines[i] = strings.Join(cmds[i], " ")
+	}
+	for _, want := range []string{
+		"delete table inet onecloud_mgmt",
+		"add element inet onecloud_mgmt allow_v4 { 10.0.0.0/8 }",
+		"add element inet onecloud_mgmt ports { 5901, 55901 }",
+		"add rule inet onecloud_mgmt input tcp dport @ports drop",
+	} {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing nft command %q in %v", want, lines)
+		}
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "allow_v6 {") && strings.HasPrefix(line, "add element") {
+			t.Errorf("unexpected empty v6 element command %q", line)
+		}
+	}
+	// 丢弃规则必须在放行规则之后
+	if !strings.HasSuffix(lines[len(lines)-1], "drop") {
+		t.Errorf("drop rule should be the last command, got %q", lines[len(lines)-1])
+	}
+}
+
+func TestMgmtFirewallDisabled(t *testing.T) {
+	var fw *sMgmtFirewall
+	fw.AddGuestPorts("guest", 5901)
+	fw.RemoveGuestPorts("guest")
+	if err := fw.Init(nil); err != nil {
+		t.Fatalf("init disabled firewall: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/version"
+)
+
+// SLiveMigrateTuning 目标端确定的热迁移调优参数, 源端和目标端必须保持一致
+type SLiveMigrateTuning struct {
+	MultifdChannels          int    `json:"multifd_channels"`
+	MultifdCompression       string `json:"multifd_compression"`
+	AutoConverge             bool   `json:"auto_converge"`
+	CpuThrottleInitial       int    `json:"cpu_throttle_initial"`
+	CpuThrottleIncrement     int    `json:"cpu_throttle_increment"`
+	PostcopySwitchoverRounds int    `json:"postcopy_switchover_rounds"`
+}
+
+type sMigrateCapability struct {
+	Name string
+	On   bool
+}
+
+type sMigrateParameter struct {
+	Key string
+	Val interface{}
+}
+
+func hostLiveMigrateTuning() SLiveMigrateTuning {
+	return SLiveMigrateTuning{
+		MultifdChannels:          options.HostOptions.LiveMigrateMultifdChannels,
+		MultifdCompression:       options.HostOptions.LiveMigrateMultifdCompression,
+		AutoConverge:             options.HostOptions.LiveMigrateAutoConverge,
+		CpuThrottleInitial:       options.HostOptions.LiveMigrateCpuThrottleInitial,
+		CpuThrottleIncrement:     options.HostOptions.LiveMigrateCpuThrottleIncrement,
+		PostcopySwitchoverRounds: options.HostOptions.LiveMigratePostcopySwitchoverRounds,
+	}
+}
+
+// 合并 API 参数与宿主机配置, 并去掉 qemu 版本或迁移方式不支持的特性
+func resolveLiveMigrateTuning(input api.LiveMigrateTuningInput, defaults SLiveMigrateTuning, qemuVersion string, blockMigrate bool) *SLiveMigrateTuning {
+	t := defaults
+	if input.MultifdChannels != nil {
+		t.MultifdChannels = *input.MultifdChannels
+	}
+	if len(input.MultifdCompression) > 0 {
+		t.MultifdCompression = input.MultifdCompression
+	}
+	if input.AutoConverge != nil {
+		t.AutoConverge = *input.AutoConverge
+	}
+	if input.CpuThrottleInitial != nil {
+		t.CpuThrottleInitial = *input.CpuThrottleInitial
+	}
+	if input.CpuThrottleIncrement != nil {
+		t.CpuThrottleIncrement = *input.CpuThrottleIncrement
+	}
+	if input.PostcopySwitchoverRounds != nil {
+		t.PostcopySwitchoverRounds = *input.PostcopySwitchoverRounds
+	}
+
+	if t.PostcopySwitchoverRounds < 0 {
+		t.PostcopySwitchoverRounds = 0
+	}
+	// qemu 不支持块迁移与 postcopy 同时使用
+	if blockMigrate && t.PostcopySwitchoverRounds > 0 {
+		log.Warningf("postcopy is not compatible with block migration, disable it")
+		t.PostcopySwitchoverRounds = 0
+	}
+	// postcopy 优先, 避免与 multifd 组合在旧版本 qemu 上报错
+	if t.PostcopySwitchoverRounds > 0 {
+		t.MultifdChannels = 0
+	}
+	if t.MultifdChannels < 0 || version.LT(qemuVersion, "4.0.0") {
+		t.MultifdChannels = 0
+	}
+	if t.MultifdChannels > 255 {
+		t.MultifdChannels = 255
+	}
+	// multifd-compression 参数 qemu 5.0 开始支持
+	if t.MultifdChannels == 0 || version.LT(qemuVersion, "5.0.0") ||
+		(t.MultifdCompression != api.LIVE_MIGRATE_COMPRESSION_ZLIB && t.MultifdCompression != api.LIVE_MIGRATE_COMPRESSION_ZSTD) {
+		t.MultifdCompression = ""
+	}
+	if !t.AutoConverge || t.CpuThrottleInitial < 1 || t.CpuThrottleInitial > 99 {
+		t.CpuThrottleInitial = 0
+	}
+	if !t.AutoConverge || t.CpuThrottleIncrement < 1 || t.CpuThrottleIncrement > 99 {
+		t.CpuThrottleIncrement = 0
+	}
+	return &t
+}
+
+func (t *SLiveMigrateTuning) Postcopy() bool {
+	return t.PostcopySwitchoverRounds > 0
+}
+
+// 两端都需要设置的 capability
+func (t *SLiveMigrateTuning) channelCapabilities(qemuVersion string) []sMigrateCapability {
+	caps := []sMigrateCapability{}
+	if !version.LT(qemuVersion, "4.0.0") {
+		caps = append(caps, sMigrateCapability{"multifd", t.MultifdChannels > 0})
+	}
+	caps = append(caps, sMigrateCapability{"postcopy-ram", t.Postcopy()})
+	return caps
+}
+
+func (t *SLiveMigrateTuning) channelParameters() []sMigrateParameter {
+	params := []sMigrateParameter{}
+	if t.MultifdChannels > 0 {
+		params = append(params, sMigrateParameter{"multifd-channels", t.MultifdChannels})
+	}
+	if len(t.MultifdCompression) > 0 {
+		params = append(params, sMigrateParameter{"multifd-compression", t.MultifdCompression})
+	}
+	return params
+}
+
+func (t *SLiveMigrateTuning) destCapabilities(qemuVersion string) []sMigrateCapability {
+	return t.channelCapabilities(qemuVersion)
+}
+
+func (t *SLiveMigrateTuning) destParameters() []sMigrateParameter {
+	return t.channelParameters()
+}
+
+func (t *SLiveMigrateTuning) srcCapabilities(qemuVersion string) []sMigrateCapability {
+	// https://wiki.qemu.org/Features/AutoconvergeLiveMigration
+	caps := []sMigrateCapability{{"auto-converge", t.AutoConverge}}
+	return append(caps, t.channelCapabilities(qemuVersion)...)
+}
+
+func (t *SLiveMigrateTuning) srcParameters() []sMigrateParameter {
+	params := t.channelParameters()
+	if t.CpuThrottleInitial > 0 {
+		params = append(params, sMigrateParameter{"cpu-throttle-initial", t.CpuThrottleInitial})
+	}
+	if t.CpuThrottleIncrement > 0 {
+		params = append(params, sMigrateParameter{"cpu-throttle-increment", t.CpuThrottleIncrement})
+	}
+	return params
+}
+
+// 目标端未返回调优参数时(旧版本宿主机)保持原有行为
+func legacyLiveMigrateTuning(qemuVersion string) *SLiveMigrateTuning {
+	t := &SLiveMigrateTuning{AutoConverge: true}
+	if !version.LT(qemuVersion, "4.0.0") {
+		t.MultifdChannels = 2
+	}
+	return t
+}
+
+func isMigrateCmdError(res string) bool {
+	return strings.Contains(strings.ToLower(res), "error")
+}
+
+func (s *SKVMGuestInstance) migrateSetCapabilities(caps []sMigrateCapability, callback func(error)) {
+	if len(caps) == 0 {
+		callback(nil)
+		return
+	}
+	state := "off"
+	if caps[0].On {
+		state = "on"
+	}
+	s.Monitor.MigrateSetCapability(caps[0].Name, state, func(res string) {
+		if isMigrateCmdError(res) {
+			callback(errors.Errorf("migrate set capability %s %s: %s", caps[0].Name, state, res))
+			return
+		}
+		s.migrateSetCapabilities(caps[1:], callback)
+	})
+}
+
+func (s *SKVMGuestInstance) migrateSetParameters(params []sMigrateParameter, callback func(error)) {
+	if len(params) == 0 {
+		callback(nil)
+		return
+	}
+	s.Monitor.MigrateSetParameter(params[0].Key, params[0].Val, func(res string) {
+		if isMigrateCmdError(res) {
+			callback(errors.Errorf("migrate set parameter %s=%v: %s", params[0].Key, params[0].Val, res))
+			return
+		}
+		s.migrateSetParameters(params[1:], callback)
+	})
+}
+
+func (s *SKVMGuestInstance) migrateApplyDestTuning() error {
+	if s.LiveMigrateTuning == nil {
+		s.LiveMigrateTuning = legacyLiveMigrateTuning(s.QemuVersion)
+	}
+	t := s.LiveMigrateTuning
+	log.Infof("migrate dest guest %s tuning %+v", s.GetName(), *t)
+	var err = make(chan error)
+	s.migrateSetCapabilities(t.destCapabilities(s.QemuVersion), func(e error) {
+		if e != nil {
+			err <- e
+			return
+		}
+		s.migrateSetParameters(t.destParameters(), func(e error) {
+			err <- e
+		})
+	})
+	return <-err
+}
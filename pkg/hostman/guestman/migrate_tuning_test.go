@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"reflect"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestResolveLiveMigrateTuning(t *testing.T) {
+	defaults := SLiveMigrateTuning{
+		MultifdChannels:      2,
+		MultifdCompression:   "none",
+		AutoConverge:         true,
+		CpuThrottleInitial:   20,
+		CpuThrottleIncrement: 10,
+	}
+	intPtr := func(i int) *int { return &i }
+	boolPtr := func(b bool) *bool { return &b }
+	cases := []struct {
+		name         string
+		input        api.LiveMigrateTuningInput
+		qemuVersion  string
+		blockMigrate bool
+		want         SLiveMigrateTuning
+	}{
+		{
+			name:        "host defaults",
+			qemuVersion: "4.2.0",
+			want:        SLiveMigrateTuning{MultifdChannels: 2, AutoConverge: true, CpuThrottleInitial: 20, CpuThrottleIncrement: 10},
+		},
+		{
+			name:        "multifd with zstd",
+			input:       api.LiveMigrateTuningInput{MultifdChannels: intPtr(8), MultifdCompression: "zstd"},
+			qemuVersion: "6.2.0",
+			want:        SLiveMigrateTuning{MultifdChannels: 8, MultifdCompression: "zstd", AutoConverge: true, CpuThrottleInitial: 20, CpuThrottleIncrement: 10},
+		},
+		{
+			name:        "compression unsupported by qemu",
+			input:       api.LiveMigrateTuningInput{MultifdCompression: "zlib"},
+			qemuVersion: "4.2.0",
+			want:        SLiveMigrateTuning{MultifdChannels: 2, AutoConverge: true, CpuThrottleInitial: 20, CpuThrottleIncrement: 10},
+		},
+		{
+			name:        "multifd unsupported by qemu",
+			input:       api.LiveMigrateTuningInput{MultifdChannels: intPtr(4)},
+			qemuVersion: "2.12.1",
+			want:        SLiveMigrateTuning{AutoConverge: true, CpuThrottleInitial: 20, CpuThrottleIncrement: 10},
+		},
+		{
+			name:        "postcopy disables multifd",
+			input:       api.LiveMigrateTuningInput{PostcopySwitchoverRounds: intPtr(5), AutoConverge: boolPtr(false)},
+			qemuVersion: "6.2.0",
+			want:        SLiveMigrateTuning{PostcopySwitchoverRounds: 5},
+		},
+		{
+			name:         "postcopy with block migration",
+			input:        api.LiveMigrateTuningInput{PostcopySwitchoverRounds: intPtr(5)},
+			qemuVersion:  "6.2.0",
+			blockMigrate: true,
+			want:         SLiveMigrateTuning{MultifdChannels: 2, AutoConverge: true, CpuThrottleInitial: 20, CpuThrottleIncrement: 10},
+		},
+	}
+	for _, c := range cases {
+		got := resolveLiveMigrateTuning(c.input, defaults, c.qemuVersion, c.blockMigrate)
+		if !reflect.DeepEqual(*got, c.want) {
+			t.Errorf("%s: got %+v, want %+v", c.name, *got, c.want)
+		}
+	}
+}
+
+func TestSLiveMigrateTuningSrcSettings(t *testing.T) {
+	tuning := &SLiveMigrateTuning{MultifdChannels: 4, MultifdCompression: "zlib", AutoConverge: true, CpuThrottleInitial: 30}
+	wantCaps := []sMigrateCapability{{"auto-converge", true}, {"multifd", true}, {"postcopy-ram", false}}
+	if caps := tuning.srcCapabilities("6.2.0"); !reflect.DeepEqual(caps, wantCaps) {
+		t.Errorf("srcCapabilities got %v, want %v", caps, wantCaps)
+	}
+	wantParams := []sMigrateParameter{{"multifd-channels", 4}, {"multifd-compression", "zlib"}, {"cpu-throttle-initial", 30}}
+	if params := tuning.srcParameters(); !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("srcParameters got %v, want %v", params, wantParams)
+	}
+	wantDestCaps := []sMigrateCapability{{"postcopy-ram", false}}
+	if caps := tuning.destCapabilities("2.12.1"); !reflect.DeepEqual(caps, wantDestCaps) {
+		t.Errorf("destCapabilities got %v, want %v", caps, wantDestCaps)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"os"
+	"path"
+	"runtime/debug"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
+	qemucerts "yunion.io/x/onecloud/pkg/hostman/guestman/qemu/certs"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const (
+	MONITOR_TLS_FLAG = "monitor_tls"
+	DISPLAY_TLS_FLAG = "display_tls"
+)
+
+// 标记文件记录 qemu 启动时 monitor/显示端口是否开启了 TLS, 宿主机选项变化后以此为准直到虚机重启
+func (s *SKVMGuestInstance) getTlsFlagPath(flag string) string {
+	return path.Join(s.HomeDir(), flag)
+}
+
+func (s *SKVMGuestInstance) isMonitorTlsEnabled() bool {
+	return fileutils2.Exists(s.getTlsFlagPath(MONITOR_TLS_FLAG))
+}
+
+func (s *SKVMGuestInstance) isDisplayTlsEnabled() bool {
+	return fileutils2.Exists(s.getTlsFlagPath(DISPLAY_TLS_FLAG))
+}
+
+// pki 目录仍被 monitor 或显示端口使用时不能在迁移完成后删除
+func (s *SKVMGuestInstance) isPKIDirInUse() bool {
+	return s.isMonitorTlsEnabled() || s.isDisplayTlsEnabled()
+}
+
+func (s *SKVMGuestInstance) setTlsFlag(flag string, enable bool) error {
+	fp := s.getTlsFlagPath(flag)
+	if enable {
+		return fileutils2.FilePutContents(fp, "", false)
+	}
+	if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "remove %s", fp)
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) prepareStartTls(input *qemu.GenerateStartOptionsInput) error {
+	enableMonitor := options.HostOptions.EnableMonitorTls || options.HostOptions.MonitorTlsOnly
+	enableDisplay := options.HostOptions.EnableDisplayTls
+	if enableMonitor || enableDisplay {
+		if err := s.makePKIDir(); err != nil {
+			return errors.Wrap(err, "make pki dir")
+		}
+		if err := qemucerts.CreateDefaultCerts(s.getPKIDirPath()); err != nil {
+			return errors.Wrap(err, "create tls certs")
+		}
+		input.TlsPKIDir = s.getPKIDirPath()
+		input.EnableMonitorTls = enableMonitor
+		input.EnableDisplayTls = enableDisplay
+	}
+	if err := s.setTlsFlag(MONITOR_TLS_FLAG, enableMonitor); err != nil {
+		return err
+	}
+	return s.setTlsFlag(DISPLAY_TLS_FLAG, enableDisplay)
+}
+
+func (s *SKVMGuestInstance) connectQmpMonitor(mon monitor.Monitor, port int) error {
+	if s.isMonitorTlsEnabled() {
+		cfg, err := qemucerts.ClientTLSConfig(s.getPKIDirPath())
+		if err != nil {
+			return errors.Wrap(err, "load monitor tls config")
+		}
+		return mon.ConnectTls("127.0.0.1", port, cfg)
+	}
+	if options.HostOptions.MonitorTlsOnly {
+		return errors.Errorf("monitor of %s is not tls protected, restart guest to enable tls", s.GetName())
+	}
+	return mon.Connect("127.0.0.1", port)
+}
+
+// rotateTlsCerts 重新签发过旧的 server/client 证书, 已加载旧证书的 qemu 仍使用同一 CA 校验,
+// VNC 通过 display-reload 加载新证书, 不支持的 qemu 版本在下次启动时生效
+func (s *SKVMGuestInstance) rotateTlsCerts(maxAge time.Duration) {
+	if !s.isPKIDirInUse() {
+		return
+	}
+	rotated, err := qemucerts.RotateLeafCerts(s.getPKIDirPath(), maxAge, time.Now())
+	if err != nil {
+		log.Errorf("guest %s rotate tls certs: %v", s.GetName(), err)
+		return
+	}
+	if !rotated {
+		return
+	}
+	log.Infof("guest %s tls certs rotated", s.GetName())
+	if !s.isDisplayTlsEnabled() || s.IsVdiSpice() || !s.IsMonitorAlive() {
+		return
+	}
+	cmd := `{"execute":"display-reload","arguments":{"type":"vnc","tls-certs":true}}`
+	err = s.Monitor.QemuMonitorCommand(cmd, func(res string) {
+		if len(res) > 0 {
+			log.Warningf("guest %s display-reload: %s", s.GetName(), res)
+		}
+	})
+	if err != nil {
+		log.Warningf("guest %s display-reload: %v", s.GetName(), err)
+	}
+}
+
+func (m *SGuestManager) startTlsCertRotator() {
+	if options.HostOptions.GuestTlsCertRotateDays <= 0 || options.HostOptions.GuestTlsCertCheckMinutes <= 0 {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			debug.PrintStack()
+			log.Errorf("tls cert rotator failed %s", r)
+		}
+	}()
+	maxAge := time.Duration(options.HostOptions.GuestTlsCertRotateDays) * 24 * time.Hour
+	for {
+		time.Sleep(time.Duration(options.HostOptions.GuestTlsCertCheckMinutes) * time.Minute)
+		m.Servers.Range(func(k, v interface{}) bool {
+			if guest, ok := v.(*SKVMGuestInstance); ok {
+				guest.rotateTlsCerts(maxAge)
+			}
+			return true
+		})
+	}
+}
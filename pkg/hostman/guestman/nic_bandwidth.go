@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func (s *SKVMGuestInstance) findNicByMac(mac string) *desc.SGuestNetwork {
+	for i := range s.Desc.Nics {
+		if netutils2.MacEqual(s.Desc.Nics[i].Mac, mac) {
+			return s.Desc.Nics[i]
+		}
+	}
+	return nil
+}
+
+// 运行时调整网卡限速，更新desc和ifup脚本后直接在tap设备上重新下发tc/ovs规则，无需重启虚机
+func (s *SKVMGuestInstance) SetNicBandwidth(ctx context.Context, input *hostapi.GuestSetNicBandwidthRequest) error {
+	nic := s.findNicByMac(input.Mac)
+	if nic == nil {
+		return httperrors.NewNotFoundError("nic %s not found", input.Mac)
+	}
+	nic.BwIngress = input.BwIngress
+	nic.BwEgress = input.BwEgress
+	if err := s.SaveLiveDesc(s.Desc); err != nil {
+		return errors.Wrap(err, "save desc")
+	}
+	if nic.Manual != nil && *nic.Manual {
+		return nil
+	}
+	if err := s.generateNicScripts(nic); err != nil {
+		return errors.Wrap(err, "generateNicScripts")
+	}
+	if !s.IsRunning() {
+		return nil
+	}
+	dev := s.manager.GetHost().GetBridgeDev(nic.Bridge)
+	if dev == nil {
+		return errors.Errorf("Can't find bridge %s", nic.Bridge)
+	}
+	script, err := dev.GetBandwidthScripts(nic)
+	if err != nil {
+		return errors.Wrap(err, "GetBandwidthScripts")
+	}
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("bash", "-c", script).Output()
+	if err != nil {
+		return errors.Wrapf(err, "apply nic %s bandwidth: %s", nic.Ifname, output)
+	}
+	log.Infof("guest %s nic %s bandwidth set to ingress %dmbps egress %dmbps", s.GetName(), nic.Ifname, nic.BwIngress, nic.BwEgress)
+	return nil
+}
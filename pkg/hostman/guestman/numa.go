@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"strconv"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/cpupin"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+func (s *SKVMGuestInstance) getNumaPolicy() string {
+	policy := s.Desc.Metadata[api.VM_METADATA_NUMA_POLICY]
+	if len(policy) == 0 && s.migrateNumaNodes > 1 {
+		// 迁移目标端须与源端虚机 NUMA 拓扑一致
+		policy = api.VM_NUMA_POLICY_PREFERRED
+	}
+	return policy
+}
+
+func (s *SKVMGuestInstance) getNumaNodeCount() int {
+	if s.migrateNumaNodes > 0 {
+		return s.migrateNumaNodes
+	}
+	if v, ok := s.Desc.Metadata[api.VM_METADATA_NUMA_NODES]; ok {
+		if nodes, err := strconv.Atoi(v); err == nil && nodes > 0 {
+			return nodes
+		}
+	}
+	return 0
+}
+
+func (s *SKVMGuestInstance) hasNumaNodes() bool {
+	return s.Desc.MemDesc != nil && len(s.Desc.MemDesc.NumaNodes) > 0
+}
+
+// initNumaDesc 按宿主机 NUMA 放置生成虚机 NUMA 节点, 每个节点的内存后端绑定到对应的宿主机节点,
+// 节点内的 vCPU 由 vCPU 绑定协调器固定在对应宿主机节点的 CPU 上
+func (s *SKVMGuestInstance) initNumaDesc() error {
+	policy := s.getNumaPolicy()
+	if len(policy) == 0 {
+		return nil
+	}
+	memDesc := s.Desc.MemDesc
+	allocator, err := s.manager.getClaimedVcpuPinAllocator(s.Id)
+	if err != nil {
+		return errors.Wrap(err, "get vcpu pin allocator")
+	}
+	hostNodes, err := allocator.SelectNumaNodes(int(s.Desc.Cpu), s.getNumaNodeCount())
+	if err != nil {
+		return errors.Wrap(err, "select host numa nodes")
+	}
+	alignMB := int64(1)
+	if s.manager.host.IsHugepagesEnabled() && s.getHugepageSizeKb() > 1024 {
+		alignMB = int64(s.getHugepageSizeKb() / 1024)
+	}
+	layouts, err := cpupin.PlanGuestNuma(hostNodes, int(s.Desc.Cpu), int(s.Desc.CpuDesc.MaxCpus), memDesc.SizeMB, alignMB)
+	if err != nil {
+		return errors.Wrap(err, "plan guest numa nodes")
+	}
+	memPolicy := "bind"
+	if policy == api.VM_NUMA_POLICY_PREFERRED {
+		memPolicy = "preferred"
+	}
+	nodes := make([]*desc.SGuestNumaNode, len(layouts))
+	for i, layout := range layouts {
+		memObj := memDesc.Mem
+		if i > 0 {
+			memObj = desc.NewObject(memDesc.Mem.ObjType, fmt.Sprintf("numamem%d", i))
+			memObj.Options = map[string]string{}
+			for k, v := range memDesc.Mem.Options {
+				memObj.Options[k] = v
+			}
+		}
+		memObj.Options["size"] = fmt.Sprintf("%dM", layout.SizeMB)
+		memObj.Options["host-nodes"] = strconv.Itoa(layout.HostNode)
+		memObj.Options["policy"] = memPolicy
+		nodes[i] = &desc.SGuestNumaNode{
+			NodeId:   i,
+			HostNode: layout.HostNode,
+			Cpus:     layout.Cpus().String(),
+			SizeMB:   layout.SizeMB,
+			MemObj:   memObj,
+		}
+	}
+	memDesc.NumaNodes = nodes
+	log.Infof("guest %s numa nodes on host nodes %v", s.GetName(), hostNodes)
+	return nil
+}
+
+// getVcpuHostNodes 返回每个 vCPU 所在虚机 NUMA 节点绑定的宿主机节点, 下标为 vCPU 序号
+func (s *SKVMGuestInstance) getVcpuHostNodes() []int {
+	if !s.hasNumaNodes() {
+		return nil
+	}
+	ret := make([]int, s.Desc.Cpu)
+	for i := range ret {
+		ret[i] = -1
+	}
+	for _, node := range s.Desc.MemDesc.NumaNodes {
+		cpus, err := cpuset.Parse(node.Cpus)
+		if err != nil {
+			return nil
+		}
+		for _, cpu := range cpus.ToSlice() {
+			if cpu < len(ret) {
+				ret[cpu] = node.HostNode
+			}
+		}
+	}
+	for _, node := range ret {
+		if node < 0 {
+			return nil
+		}
+	}
+	return ret
+}
+
+// vcpuPinsMatchNuma 校验 vCPU 绑定是否位于其虚机 NUMA 节点对应的宿主机节点上
+func (s *SKVMGuestInstance) vcpuPinsMatchNuma(allocator *cpupin.SAllocator, pins []cpuset.CPUSet) bool {
+	if !s.hasNumaNodes() {
+		return true
+	}
+	nodes := s.getVcpuHostNodes()
+	if len(nodes) != len(pins) {
+		return false
+	}
+	for i := range pins {
+		if !pins[i].IsSubsetOf(allocator.NodeCpus(nodes[i])) {
+			return false
+		}
+	}
+	return true
+}
@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
@@ -55,9 +56,13 @@ func (s *SKVMGuestInstance) initGuestDesc() error {
 		return err
 	}
 	s.initMemDesc(s.Desc.Mem)
+	if err := s.initNumaDesc(); err != nil {
+		return errors.Wrap(err, "init numa desc")
+	}
 	s.initMachineDesc()
 
 	pciRoot, pciBridge := s.initGuestPciControllers()
+	s.initVirtioMemDesc(pciRoot)
 	err = s.initGuestPciAddresses()
 	if err != nil {
 		return errors.Wrap(err, "init guest pci addresses")
@@ -77,16 +82,24 @@ func (s *SKVMGuestInstance) initGuestDesc() error {
 	s.initCdromDesc()
 	s.initFloppyDesc()
 	s.initGuestDisks(pciRoot, pciBridge)
+	s.initGuestIOThreads()
 	if err = s.initGuestNetworks(pciRoot, pciBridge); err != nil {
 		return errors.Wrap(err, "init guest networks")
 	}
 
 	s.initIsolatedDevices(pciRoot, pciBridge)
+	s.initSharedDirs(pciRoot, pciBridge)
 	s.initUsbController(pciRoot)
 	s.initRandomDevice(pciRoot, options.HostOptions.EnableVirtioRngDevice)
+	s.initBalloonDesc(pciRoot)
 	s.initQgaDesc()
 	s.initPvpanicDesc()
 	s.initIsaSerialDesc()
+	s.initTpmDesc()
+	s.initWatchdogDesc(pciRoot)
+	if err := s.initConfidentialVmDesc(); err != nil {
+		return errors.Wrap(err, "init confidential vm desc")
+	}
 
 	return s.ensurePciAddresses()
 }
@@ -233,6 +246,26 @@ func (s *SKVMGuestInstance) initRandomDevice(pciRoot *desc.PCIController, enable
 	}
 }
 
+func (s *SKVMGuestInstance) initWatchdogDesc(pciRoot *desc.PCIController) {
+	model := s.Desc.Metadata[computeapi.VM_METADATA_WATCHDOG_MODEL]
+	if len(model) == 0 {
+		s.Desc.Watchdog = nil
+		return
+	}
+	action := s.Desc.Metadata[computeapi.VM_METADATA_WATCHDOG_ACTION]
+	if len(action) == 0 {
+		action = computeapi.WATCHDOG_ACTION_RESET
+	}
+	s.Desc.Watchdog = &desc.SGuestWatchdog{
+		Id:     "watchdog0",
+		Model:  model,
+		Action: action,
+	}
+	if model == computeapi.WATCHDOG_MODEL_I6300ESB {
+		s.Desc.Watchdog.Pci = desc.NewPCIDevice(pciRoot.CType, model, s.Desc.Watchdog.Id)
+	}
+}
+
 func (s *SKVMGuestInstance) initUsbController(pciRoot *desc.PCIController) {
 	contType := s.getUsbControllerType()
 	s.Desc.Usb = &desc.UsbController{
@@ -286,6 +319,10 @@ func (s *SKVMGuestInstance) initGuestNetworks(pciRoot, pciBridge *desc.PCIContro
 			case "vmxnet3":
 				s.Desc.Nics[i].Pci = desc.NewPCIDevice(cont.CType, "vmxnet3", id)
 			}
+		} else if s.isSriovFailoverEnabled() {
+			if err := s.initSriovFailoverStandby(s.Desc.Nics[i], cont); err != nil {
+				return errors.Wrapf(err, "initSriovFailoverStandby for nic: %v", s.Desc.Nics[i])
+			}
 		}
 	}
 	return nil
@@ -309,6 +346,7 @@ func (s *SKVMGuestInstance) initIsolatedDevices(pciRoot, pciBridge *desc.PCICont
 				cont.CType, "vfio-pci", id, dev.GetAddr(), dev.GetDeviceType() == api.GPU_VGA_TYPE,
 			)
 			s.Desc.IsolatedDevices[i].VfioDevs = append(s.Desc.IsolatedDevices[i].VfioDevs, vfioDev)
+			s.setSriovFailoverPair(s.Desc.IsolatedDevices[i])
 
 			groupDevAddrs := dev.GetIOMMUGroupRestAddrs()
 			for j := 0; j < len(groupDevAddrs); j++ {
@@ -322,6 +360,31 @@ func (s *SKVMGuestInstance) initIsolatedDevices(pciRoot, pciBridge *desc.PCICont
 	}
 }
 
+func (s *SKVMGuestInstance) initSharedDirs(pciRoot, pciBridge *desc.PCIController) {
+	cont := pciRoot
+	if pciBridge != nil {
+		cont = pciBridge
+	}
+	for i := 0; i < len(s.Desc.SharedDirs); i++ {
+		s.initSharedDirDesc(s.Desc.SharedDirs[i], cont)
+	}
+}
+
+func (s *SKVMGuestInstance) initSharedDirDesc(dir *desc.SGuestSharedDir, cont *desc.PCIController) {
+	dir.Socket = &desc.CharDev{
+		Backend: "socket",
+		Id:      fmt.Sprintf("charfs-%s", dir.Tag),
+		Options: map[string]string{
+			"path": s.getSharedDirSocketPath(dir.Tag),
+		},
+	}
+	dir.Pci = desc.NewPCIDevice(cont.CType, "vhost-user-fs-pci", fmt.Sprintf("fs-%s", dir.Tag))
+	dir.Pci.Options = map[string]string{
+		"chardev": dir.Socket.Id,
+		"tag":     dir.Tag,
+	}
+}
+
 func (s *SKVMGuestInstance) initCdromDesc() {
 	if s.Desc.Cdroms == nil {
 		s.Desc.Cdroms = make([]*desc.SGuestCdrom, options.HostOptions.CdromCount)
@@ -450,9 +513,46 @@ func (s *SKVMGuestInstance) initGuestVga(pciRoot *desc.PCIController) {
 	if vgaDevName != "none" {
 		s.Desc.VgaDevice.PCIDevice = desc.NewPCIDevice(pciRoot.CType, vgaDevName, "video0")
 		s.Desc.VgaDevice.PCIDevice.Options = options
+		s.initGuestVgaHeads(pciRoot)
+	}
+}
+
+// initGuestVgaHeads spice 多显示器, qxl 每个显示器一个设备, virtio 通过 max_outputs 指定
+func (s *SKVMGuestInstance) initGuestVgaHeads(pciRoot *desc.PCIController) {
+	monitors := s.Desc.VdiMonitors
+	if !s.IsVdiSpice() || monitors <= 1 {
+		return
+	}
+	if monitors > computeapi.VM_VDI_MAX_MONITORS {
+		monitors = computeapi.VM_VDI_MAX_MONITORS
+	}
+	switch s.Desc.Vga {
+	case "qxl":
+		for i := 1; i < monitors; i++ {
+			head := desc.NewPCIDevice(pciRoot.CType, "qxl", fmt.Sprintf("video%d", i))
+			head.Options = map[string]string{
+				"ram_size":  "141557760",
+				"vram_size": "141557760",
+			}
+			s.Desc.VgaDevice.Heads = append(s.Desc.VgaDevice.Heads, head)
+		}
+	case "virtio", "virtio-gpu":
+		if s.Desc.VgaDevice.Options == nil {
+			s.Desc.VgaDevice.Options = map[string]string{}
+		}
+		s.Desc.VgaDevice.Options["max_outputs"] = strconv.Itoa(monitors)
 	}
 }
 
+func (s *SKVMGuestInstance) findVgaHead(id string) *desc.PCIDevice {
+	for i := range s.Desc.VgaDevice.Heads {
+		if s.Desc.VgaDevice.Heads[i].Id == id {
+			return s.Desc.VgaDevice.Heads[i]
+		}
+	}
+	return nil
+}
+
 func (s *SKVMGuestInstance) initSpiceDevices(pciRoot *desc.PCIController) {
 	spice := new(desc.SSpiceDesc)
 	spice.IntelHDA = &desc.SoundCard{
@@ -470,13 +570,26 @@ func (s *SKVMGuestInstance) initSpiceDevices(pciRoot *desc.PCIController) {
 		UHCI2: desc.NewUsbController(ehciId, 2),
 		UHCI3: desc.NewUsbController(ehciId, 4),
 	}
-	spice.UsbRedirct.UsbRedirDev1 = &desc.UsbRedir{
-		Id:     "usbredirdev1",
-		Source: desc.NewCharDev("spicevmc", "usbredirchardev1", "usbredir"),
+	channels := computeapi.VM_SPICE_USBREDIR_DEFAULT_CHANNELS
+	if s.Desc.SpiceUsbredirChannels != nil {
+		channels = *s.Desc.SpiceUsbredirChannels
 	}
-	spice.UsbRedirct.UsbRedirDev2 = &desc.UsbRedir{
-		Id:     "usbredirdev2",
-		Source: desc.NewCharDev("spicevmc", "usbredirchardev2", "usbredir"),
+	if channels > computeapi.VM_SPICE_USBREDIR_MAX_CHANNELS {
+		channels = computeapi.VM_SPICE_USBREDIR_MAX_CHANNELS
+	}
+	for i := 1; i <= channels; i++ {
+		dev := &desc.UsbRedir{
+			Id:     fmt.Sprintf("usbredirdev%d", i),
+			Source: desc.NewCharDev("spicevmc", fmt.Sprintf("usbredirchardev%d", i), "usbredir"),
+		}
+		switch i {
+		case 1:
+			spice.UsbRedirct.UsbRedirDev1 = dev
+		case 2:
+			spice.UsbRedirct.UsbRedirDev2 = dev
+		default:
+			spice.UsbRedirct.ExtraUsbRedirDevs = append(spice.UsbRedirct.ExtraUsbRedirDevs, dev)
+		}
 	}
 	spice.UsbRedirct.EHCI1.PCIDevice = desc.NewPCIDevice(pciRoot.CType, "ich9-usb-ehci1", ehciId)
 	spice.UsbRedirct.UHCI1.PCIDevice = desc.NewPCIDevice(pciRoot.CType, "ich9-usb-uhci1", "uhci1")
@@ -556,6 +669,12 @@ func (s *SKVMGuestInstance) ensurePciAddresses() error {
 		if err != nil {
 			return errors.Wrap(err, "ensure vga pci address")
 		}
+		for i := range s.Desc.VgaDevice.Heads {
+			err = s.ensureDevicePciAddress(s.Desc.VgaDevice.Heads[i], -1, nil)
+			if err != nil {
+				return errors.Wrapf(err, "ensure vga head %s pci address", s.Desc.VgaDevice.Heads[i].Id)
+			}
+		}
 	}
 	if s.Desc.VirtioSerial != nil {
 		err = s.ensureDevicePciAddress(s.Desc.VirtioSerial.PCIDevice, -1, nil)
@@ -648,6 +767,15 @@ func (s *SKVMGuestInstance) ensurePciAddresses() error {
 		}
 	}
 
+	for i := 0; i < len(s.Desc.SharedDirs); i++ {
+		if s.Desc.SharedDirs[i].Pci != nil {
+			err = s.ensureDevicePciAddress(s.Desc.SharedDirs[i].Pci, -1, nil)
+			if err != nil {
+				return errors.Wrapf(err, "ensure shared dir %s pci address", s.Desc.SharedDirs[i].Tag)
+			}
+		}
+	}
+
 	if s.Desc.Usb != nil {
 		err = s.ensureDevicePciAddress(s.Desc.Usb.PCIDevice, -1, nil)
 		if err != nil {
@@ -662,6 +790,27 @@ func (s *SKVMGuestInstance) ensurePciAddresses() error {
 		}
 	}
 
+	if s.Desc.Watchdog != nil && s.Desc.Watchdog.Pci != nil {
+		err = s.ensureDevicePciAddress(s.Desc.Watchdog.Pci, -1, nil)
+		if err != nil {
+			return errors.Wrap(err, "ensure watchdog pci address")
+		}
+	}
+
+	if s.Desc.Balloon != nil {
+		err = s.ensureDevicePciAddress(s.Desc.Balloon.PCIDevice, -1, nil)
+		if err != nil {
+			return errors.Wrap(err, "ensure balloon pci address")
+		}
+	}
+
+	if s.Desc.MemDesc != nil && s.Desc.MemDesc.VirtioMem != nil {
+		err = s.ensureDevicePciAddress(s.Desc.MemDesc.VirtioMem.PCIDevice, -1, nil)
+		if err != nil {
+			return errors.Wrap(err, "ensure virtio-mem device pci address")
+		}
+	}
+
 	for i := 0; i < len(s.Desc.AnonymousPCIDevs); i++ {
 		err = s.ensureDevicePciAddress(s.Desc.AnonymousPCIDevs[i], -1, nil)
 		if err != nil {
@@ -715,6 +864,7 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 	s.initIsolatedDevices(pciRoot, nil)
 	s.initUsbController(pciRoot)
 	s.initRandomDevice(pciRoot, options.HostOptions.EnableVirtioRngDevice)
+	s.initBalloonDesc(pciRoot)
 	s.initQgaDesc()
 	s.initPvpanicDesc()
 	s.initIsaSerialDesc()
@@ -746,6 +896,19 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 			if err != nil {
 				return errors.Wrap(err, "ensure vga pci address")
 			}
+		case "watchdog0":
+			if s.Desc.Watchdog == nil || s.Desc.Watchdog.Pci == nil {
+				s.Desc.Watchdog = &desc.SGuestWatchdog{
+					Pci:   desc.NewPCIDevice(pciRoot.CType, computeapi.WATCHDOG_MODEL_I6300ESB, "watchdog0"),
+					Id:    "watchdog0",
+					Model: computeapi.WATCHDOG_MODEL_I6300ESB,
+				}
+			}
+			s.Desc.Watchdog.Pci.PCIAddr = pciAddr
+			err = s.ensureDevicePciAddress(s.Desc.Watchdog.Pci, -1, nil)
+			if err != nil {
+				return errors.Wrap(err, "ensure watchdog pci address")
+			}
 		case "random0":
 			if s.Desc.Rng == nil {
 				// in case rng device disable by host options
@@ -756,6 +919,17 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 			if err != nil {
 				return errors.Wrap(err, "ensure random device pci address")
 			}
+		case "balloon0":
+			if s.Desc.Balloon == nil {
+				s.Desc.Balloon = &desc.SGuestBalloon{
+					PCIDevice: desc.NewPCIDevice(pciRoot.CType, "virtio-balloon-pci", "balloon0"),
+				}
+			}
+			s.Desc.Balloon.PCIAddr = pciAddr
+			err = s.ensureDevicePciAddress(s.Desc.Balloon.PCIDevice, -1, nil)
+			if err != nil {
+				return errors.Wrap(err, "ensure balloon pci address")
+			}
 		case "usb":
 			s.Desc.Usb.PCIAddr = pciAddr
 			err = s.ensureDevicePciAddress(s.Desc.Usb.PCIDevice, -1, nil)
@@ -833,6 +1007,14 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 						}
 					}
 				}
+			case strings.HasPrefix(pciInfoList[0].Devices[i].QdevID, "video") && s.Desc.VgaDevice != nil &&
+				s.findVgaHead(pciInfoList[0].Devices[i].QdevID) != nil:
+				head := s.findVgaHead(pciInfoList[0].Devices[i].QdevID)
+				head.PCIAddr = pciAddr
+				err = s.ensureDevicePciAddress(head, -1, nil)
+				if err != nil {
+					return errors.Wrapf(err, "ensure vga head %s pci address", head.Id)
+				}
 			case strings.HasPrefix(pciInfoList[0].Devices[i].QdevID, "netdev-"):
 				ifname := strings.TrimPrefix(pciInfoList[0].Devices[i].QdevID, "netdev-")
 				for i := 0; i < len(s.Desc.Nics); i++ {
@@ -24,7 +24,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -43,6 +43,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/arch"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
 	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
 	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
@@ -85,6 +86,9 @@ type SKVMInstanceRuntime struct {
 
 	LiveMigrateDestPort *int64
 	LiveMigrateUseTls   bool
+	LiveMigrateTuning   *SLiveMigrateTuning
+	// 迁移源端虚机的 NUMA 节点数, 目标端按此生成相同的虚机 NUMA 拓扑
+	migrateNumaNodes int
 
 	syncMeta *jsonutils.JSONDict
 
@@ -94,12 +98,17 @@ type SKVMInstanceRuntime struct {
 	stopping            bool
 	needSyncStreamDisks bool
 	blockJobTigger      map[string]chan struct{}
+	// 增量备份任务, drive -> chan error message
+	backupJobs sync.Map
 
 	StartupTask *SGuestResumeTask
 	MigrateTask *SGuestLiveMigrateTask
 
 	pciUninitialized bool
 	pciAddrs         *desc.SGuestPCIAddresses
+
+	qemuProc     *sQemuProcess
+	qemuRestarts []time.Time
 }
 
 type SKVMGuestInstance struct {
@@ -151,6 +160,7 @@ func (s *SKVMGuestInstance) updateGuestDesc() error {
 	if err != nil {
 		return err
 	}
+	s.applyPendingMachineType()
 
 	return s.SaveLiveDesc(s.Desc)
 }
@@ -457,6 +467,11 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 		return nil, errors.Wrap(err, "fuse mount")
 	}
 
+	s.migrateNumaNodes = 0
+	if jsonutils.QueryBoolean(data, "need_migrate", false) {
+		cmdline, _ := data.GetString("source_qemu_cmdline")
+		s.migrateNumaNodes = qemu.CountNumaNodes(cmdline)
+	}
 	err = s.updateGuestDesc()
 	if err != nil {
 		return nil, errors.Wrap(err, "generate desc")
@@ -466,12 +481,21 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 	time.Sleep(100 * time.Millisecond)
 
 	var isStarted, tried = false, 0
+	// 资源不满足时直接返回失败原因, 避免 qemu 启动后才报错
+	if err = s.validateStartResources(); err != nil {
+		tried = MAX_TRY
+	}
 	for !isStarted && tried < MAX_TRY {
 		tried += 1
 
-		vncPort := s.manager.GetFreeVncPort()
-		defer s.manager.unsetPort(vncPort)
+		var vncPort int
+		if vncPort, err = s.manager.AllocVncPort(s.Id); err != nil {
+			goto finally
+		}
 		log.Infof("Use vnc port %d", vncPort)
+		if err = s.validateStartPorts(vncPort); err != nil {
+			goto finally
+		}
 		if err = s.saveVncPort(vncPort); err != nil {
 			goto finally
 		} else {
@@ -486,6 +510,7 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 			migratePortInt64 := int64(migratePort)
 			s.LiveMigrateDestPort = &migratePortInt64
 		}
+		s.manager.mgmtFirewall.AddGuestPorts(s.Id, s.getMgmtFirewallPorts(vncPort)...)
 
 		err = s.saveScripts(data)
 		if err != nil {
@@ -516,8 +541,16 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 		return nil, nil
 	}
 	log.Errorf("Async start server %s failed: %s!!!", s.GetName(), err)
+	s.manager.ReleaseVncPort(s.Id)
 	if ctx != nil && len(appctx.AppContextTaskId(ctx)) >= 0 {
-		hostutils.TaskFailed(ctx, fmt.Sprintf("Async start server failed: %s", err))
+		reason := fmt.Sprintf("Async start server failed: %s", err)
+		if checkErr, ok := err.(*SGuestStartCheckError); ok {
+			params := jsonutils.NewDict()
+			params.Set("start_check_failures", jsonutils.Marshal(checkErr.Failures))
+			hostutils.TaskFailed2(ctx, reason, params)
+		} else {
+			hostutils.TaskFailed(ctx, reason)
+		}
 	}
 	needMigrate := jsonutils.QueryBoolean(data, "need_migrate", false)
 	// do not syncstatus if need_migrate
@@ -563,11 +596,6 @@ func (s *SKVMGuestInstance) saveScripts(data *jsonutils.JSONDict) error {
 		}
 	}
 
-	launcher := fmt.Sprintf(guestLauncher, s.GetStartScriptPath(), s.LogFilePath())
-	if err := fileutils2.FilePutContents(s.pyLauncherPath(), launcher, false); err != nil {
-		return errors.Wrap(err, "generate guest launcher")
-	}
-
 	stopScript := s.generateStopScript(data)
 	return fileutils2.FilePutContents(s.GetStopScriptPath(), stopScript, false)
 }
@@ -590,6 +618,9 @@ func (s *SKVMGuestInstance) ImportServer(pendingDelete bool) {
 
 	s.manager.SaveServer(s.Id, s)
 	s.manager.RemoveCandidateServer(s)
+	for _, err := range s.reserveIsolatedDevices() {
+		log.Errorf("Guest %s reserve isolated device: %s", s.GetName(), err)
+	}
 
 	if (s.IsDirtyShotdown() || s.IsDaemon()) && !pendingDelete {
 		log.Infof("Server dirty shutdown or a daemon %s", s.GetName())
@@ -729,7 +760,7 @@ func (s *SKVMGuestInstance) StartMonitor(ctx context.Context, cb func()) error {
 			}, // on monitor connected
 			s.onReceiveQMPEvent, // on receive qmp event
 		)
-		err := mon.Connect("127.0.0.1", s.GetQmpMonitorPort(-1))
+		err := s.connectQmpMonitor(mon, s.GetQmpMonitorPort(-1))
 		if err != nil {
 			mon = nil
 			log.Errorf("Guest %s hmp monitor connect failed %s, something wrong", s.GetName(), err)
@@ -754,6 +785,8 @@ func (s *SKVMGuestInstance) onReceiveQMPEvent(event *monitor.Event) {
 		s.eventBlockJobCompleted(event)
 	case event.Event == `"GUEST_PANICKED"`:
 		s.eventGuestPaniced(event)
+	case event.Event == `"WATCHDOG"`:
+		s.eventWatchdog(event)
 	case event.Event == `"STOP"`:
 		if s.MigrateTask != nil {
 			s.MigrateTask.onMigrateReceivedStopEvent()
@@ -767,8 +800,12 @@ func (s *SKVMGuestInstance) eventBlockJobCompleted(event *monitor.Event) {
 		log.Errorf("BLOCK_JOB_COMPLETED missing event type")
 		return
 	}
-	// only dealwith event type mirror
 	stype, _ := itype.(string)
+	if stype == "backup" {
+		s.onBackupJobCompleted(event)
+		return
+	}
+	// only dealwith event type mirror
 	if stype != "mirror" {
 		return
 	}
@@ -813,6 +850,24 @@ func (s *SKVMGuestInstance) eventGuestPaniced(event *monitor.Event) {
 	}
 }
 
+func (s *SKVMGuestInstance) eventWatchdog(event *monitor.Event) {
+	params := jsonutils.NewDict()
+	if action, ok := event.Data["action"]; ok {
+		sAction, _ := action.(string)
+		params.Set("action", jsonutils.NewString(sAction))
+	}
+	if s.Desc.Watchdog != nil {
+		params.Set("model", jsonutils.NewString(s.Desc.Watchdog.Model))
+	}
+	params.Set("event", jsonutils.NewString(strings.Trim(event.Event, "\"")))
+	_, err := modules.Servers.PerformAction(
+		hostutils.GetComputeSession(context.Background()),
+		s.GetId(), "event", params)
+	if err != nil {
+		log.Errorf("Server %s send event watchdog got error %s", s.GetId(), err)
+	}
+}
+
 func (s *SKVMGuestInstance) eventBlockJobReady(event *monitor.Event) {
 	itype, ok := event.Data["type"]
 	if !ok {
@@ -924,23 +979,6 @@ func (s *SKVMGuestInstance) setDestMigrateTLS(ctx context.Context, data *jsonuti
 	})
 }
 
-func (s *SKVMGuestInstance) migrateEnableMultifd() error {
-	if version.LT(s.QemuVersion, "4.0.0") {
-		return nil
-	}
-	var err = make(chan error)
-	cb := func(res string) {
-		if len(res) > 0 {
-			err <- errors.Errorf("failed enable multifd %s", res)
-		} else {
-			err <- nil
-		}
-	}
-	log.Infof("migrate dest guest enable multifd")
-	s.Monitor.MigrateSetCapability("multifd", "on", cb)
-	return <-err
-}
-
 func (s *SKVMGuestInstance) onGetQemuVersion(ctx context.Context, version string) {
 	s.QemuVersion = version
 	log.Infof("Guest(%s) qemu version %s", s.Id, s.QemuVersion)
@@ -990,11 +1028,12 @@ func (s *SKVMGuestInstance) guestRun(ctx context.Context) {
 		// dest migrate guest
 		body := jsonutils.NewDict()
 		body.Set("live_migrate_dest_port", jsonutils.NewInt(*s.LiveMigrateDestPort))
-		err := s.migrateEnableMultifd()
+		err := s.migrateApplyDestTuning()
 		if err != nil {
 			hostutils.TaskFailed(ctx, err.Error())
 			return
 		}
+		body.Set("live_migrate_tuning", jsonutils.Marshal(s.LiveMigrateTuning))
 		if s.LiveMigrateUseTls {
 			s.setDestMigrateTLS(ctx, body)
 		} else {
@@ -1066,6 +1105,7 @@ func (s *SKVMGuestInstance) startQemuBuiltInNbdServer(ctx context.Context) {
 	if ctx != nil && len(appctx.AppContextTaskId(ctx)) > 0 {
 		nbdServerPort := s.manager.GetNBDServerFreePort()
 		defer s.manager.unsetPort(nbdServerPort)
+		s.manager.mgmtFirewall.AddGuestPorts(s.Id, nbdServerPort)
 		var onNbdServerStarted = func(res string) {
 			if len(res) > 0 {
 				log.Errorf("Start Qemu Builtin nbd server error %s", res)
@@ -1087,6 +1127,10 @@ func (s *SKVMGuestInstance) clearCgroup(pid int) {
 	cgrupName := s.GetCgroupName()
 	log.Infof("cgroup destroy %d %s", pid, cgrupName)
 	if pid > 0 && !options.HostOptions.DisableSetCgroup {
+		if cgrouputils.IsCgroupV2() {
+			s.clearCgroupV2()
+			return
+		}
 		cgrouputils.CgroupDestroy(strconv.Itoa(pid), cgrupName)
 	}
 }
@@ -1200,7 +1244,7 @@ func (s *SKVMGuestInstance) GetQmpMonitorPort(vncPort int) int {
 		vncPort = s.GetVncPort()
 	}
 	if vncPort > 0 {
-		return vncPort + MONITOR_PORT_BASE + 200
+		return vncPort + MONITOR_PORT_BASE + QMP_MONITOR_PORT_OFFSET
 	} else {
 		return -1
 	}
@@ -1417,6 +1461,7 @@ func (s *SKVMGuestInstance) StartDelete(ctx context.Context, migrated bool) erro
 }
 
 func (s *SKVMGuestInstance) ForceStop() bool {
+	s.markQemuExitExpected()
 	s.ExitCleanup(true)
 	if s.IsRunning() {
 		output, err := procutils.NewCommand("kill", "-9", fmt.Sprintf("%d", s.GetPid())).Output()
@@ -1447,9 +1492,16 @@ func (s *SKVMGuestInstance) ExitCleanup(clear bool) {
 		s.Monitor.Disconnect()
 		s.Monitor = nil
 	}
+	if s.manager != nil {
+		s.manager.mgmtFirewall.RemoveGuestPorts(s.Id)
+		s.manager.ReleaseVncPort(s.Id)
+	}
 }
 
 func (s *SKVMGuestInstance) CleanupCpuset() {
+	if cgrouputils.IsCgroupV2() {
+		return
+	}
 	task := cgrouputils.NewCGroupCPUSetTask(strconv.Itoa(s.GetPid()), s.GetCgroupName(), 0, "")
 	if !task.RemoveTask() {
 		log.Warningf("remove cpuset cgroup error: %s, pid: %d", s.Id, s.GetPid())
@@ -1514,10 +1566,12 @@ func (s *SKVMGuestInstance) Delete(ctx context.Context, migrated bool) error {
 	if err != nil {
 		return errors.Wrapf(err, "rm %s failed: %s", s.HomeDir(), output)
 	}
+	s.releaseIsolatedDevices()
 	return nil
 }
 
 func (s *SKVMGuestInstance) Stop() bool {
+	s.markQemuExitExpected()
 	s.ExitCleanup(true)
 	if s.scriptStop() {
 		return true
@@ -1530,51 +1584,32 @@ func (s *SKVMGuestInstance) LogFilePath() string {
 	return path.Join(s.manager.QemuLogDir(), s.Id)
 }
 
-func (s *SKVMGuestInstance) readQemuLogFileEnd(size int64) string {
-	fname := s.LogFilePath()
-	file, err := os.Open(fname)
-	if err != nil {
-		return fmt.Sprintf("failed open log file %s: %s", fname, err)
+// DiagnosticFiles 返回用于问题排查的文件, 包含 qemu 日志, 启停脚本及描述文件
+func (s *SKVMGuestInstance) DiagnosticFiles() map[string]string {
+	return map[string]string{
+		"qemu.log":     s.getQemuLogPath(),
+		"qemu-run.log": s.LogFilePath(),
+		"startvm":      s.GetStartScriptPath(),
+		"stopvm":       s.GetStopScriptPath(),
+		"desc":         s.GetDescFilePath(),
 	}
-	defer file.Close()
-
-	buf := make([]byte, size)
-	stat, err := os.Stat(fname)
-	if err != nil {
-		return fmt.Sprintf("failed stat file %s: %s", fname, err)
-	}
-	start := stat.Size() - size
-	_, err = file.ReadAt(buf, start)
-	if err != nil {
-		return fmt.Sprintf("failed read logfile %s: %s", fname, err)
-	}
-	return string(buf)
-}
-
-func (s *SKVMGuestInstance) pyLauncherPath() string {
-	return path.Join(s.HomeDir(), "startvm.py")
 }
 
 func (s *SKVMGuestInstance) scriptStart(ctx context.Context) error {
-	output, err := procutils.NewRemoteCommandAsFarAsPossible("python2", s.pyLauncherPath()).Output()
-	if err != nil {
-		return fmt.Errorf("Start VM Failed %s %s", output, err)
-	}
-	pid, err := strconv.Atoi(string(output))
-	if err != nil {
-		return errors.Wrapf(err, "failed parse pid %s", output)
-	}
-	proc, err := os.FindProcess(pid)
+	proc, err := s.startQemuProcess()
 	if err != nil {
-		return errors.Wrapf(err, "find process pid(%d)", pid)
+		return errors.Wrap(err, "start qemu process")
 	}
 	for {
-		err = proc.Signal(syscall.Signal(0))
-		if err != nil { // qemu process exited
-			log.Errorf("Guest %s check qemu(%d) process failed: %s", s.Id, pid, err)
-			return errors.Errorf(s.readQemuLogFileEnd(64))
+		select {
+		case <-proc.exited:
+			err = proc.startError()
+			log.Errorf("Guest %s qemu process exited during startup: %s", s.Id, err)
+			return err
+		default:
 		}
 		if err = s.StartMonitor(ctx, nil); err == nil {
+			proc.setRunning()
 			return nil
 		}
 		time.Sleep(time.Millisecond * 10)
@@ -1591,6 +1626,7 @@ func (s *SKVMGuestInstance) scriptStop() bool {
 }
 
 func (s *SKVMGuestInstance) forceScriptStop() bool {
+	s.markQemuExitExpected()
 	_, err := procutils.NewRemoteCommandAsFarAsPossible("bash", s.GetStopScriptPath(), "--force").Output()
 	if err != nil {
 		log.Errorln(err)
@@ -1699,6 +1735,29 @@ func (s *SKVMGuestInstance) compareDescIsolatedDevices(newDesc *desc.SGuestDesc,
 	return delDevs, addDevs
 }
 
+func (s *SKVMGuestInstance) compareDescSharedDirs(newDesc *desc.SGuestDesc,
+) ([]*desc.SGuestSharedDir, []*desc.SGuestSharedDir) {
+	var delDirs, addDirs = []*desc.SGuestSharedDir{}, []*desc.SGuestSharedDir{}
+	for _, dir := range newDesc.SharedDirs {
+		newDir := *dir
+		addDirs = append(addDirs, &newDir)
+	}
+	for _, oldDir := range s.Desc.SharedDirs {
+		var find = false
+		for idx, addDir := range addDirs {
+			if oldDir.GuestSharedDirJsonDesc == addDir.GuestSharedDirJsonDesc {
+				addDirs = append(addDirs[:idx], addDirs[idx+1:]...)
+				find = true
+				break
+			}
+		}
+		if !find {
+			delDirs = append(delDirs, oldDir)
+		}
+	}
+	return delDirs, addDirs
+}
+
 func (s *SKVMGuestInstance) compareDescCdroms(newDesc *desc.SGuestDesc) []*desc.SGuestCdrom {
 	var changeCdroms []*desc.SGuestCdrom
 	newCdroms := newDesc.Cdroms
@@ -1875,6 +1934,7 @@ func (s *SKVMGuestInstance) SyncConfig(
 	var delNetworks, addNetworks []*desc.SGuestNetwork
 	var changedNetworks [][2]*desc.SGuestNetwork
 	var delDevs, addDevs []*desc.SGuestIsolatedDevice
+	var delDirs, addDirs []*desc.SGuestSharedDir
 	var cdroms []*desc.SGuestCdrom
 	var floppys []*desc.SGuestFloppy
 
@@ -1888,6 +1948,7 @@ func (s *SKVMGuestInstance) SyncConfig(
 		floppys = s.compareDescFloppys(guestDesc)
 		delNetworks, addNetworks, changedNetworks = s.compareDescNetworks(guestDesc)
 		delDevs, addDevs = s.compareDescIsolatedDevices(guestDesc)
+		delDirs, addDirs = s.compareDescSharedDirs(guestDesc)
 	}
 
 	if len(changedNetworks) > 0 && s.IsRunning() {
@@ -1912,6 +1973,9 @@ func (s *SKVMGuestInstance) SyncConfig(
 	s.Desc.SGuestRegionDesc = guestDesc.SGuestRegionDesc
 	s.Desc.SGuestMetaDesc = guestDesc.SGuestMetaDesc
 	s.SaveLiveDesc(s.Desc)
+	if s.needVcpuPinReconcile() {
+		go s.manager.reconcileVcpuPins()
+	}
 
 	if fwOnly {
 		res := jsonutils.NewDict()
@@ -1933,6 +1997,12 @@ func (s *SKVMGuestInstance) SyncConfig(
 		tasks = append(tasks, task)
 	}
 
+	if len(delDirs)+len(addDirs) > 0 {
+		task := NewGuestSharedDirSyncTask(s, delDirs, addDirs)
+		runTaskNames = append(runTaskNames, jsonutils.NewString("shared_dir_sync"))
+		tasks = append(tasks, task)
+	}
+
 	// make sure network sync before isolated device
 	if len(delNetworks)+len(addNetworks) > 0 {
 		task := NewGuestNetworkSyncTask(s, delNetworks, addNetworks)
@@ -2003,6 +2073,12 @@ func (s *SKVMGuestInstance) GetCgroupName() string {
 
 func (s *SKVMGuestInstance) SetCgroup() {
 	s.cgroupPid = s.GetPid()
+	if cgrouputils.IsCgroupV2() {
+		if err := s.setCgroupV2(); err != nil {
+			log.Errorf("server %s set cgroup v2: %s", s.Id, err)
+		}
+		return
+	}
 	s.setCgroupIo()
 	s.setCgroupCpu()
 	s.setCgroupCPUSet()
@@ -2032,12 +2108,8 @@ func (s *SKVMGuestInstance) setCgroupIo() {
 }
 
 func (s *SKVMGuestInstance) setCgroupCpu() {
-	var (
-		cpu       = s.Desc.Cpu
-		cpuWeight = 1024
-	)
-
-	cgrouputils.CgroupSet(strconv.Itoa(s.cgroupPid), s.GetCgroupName(), int(cpu)*cpuWeight)
+	cpuShares, _, _ := guestCgroupWeights(s.Desc.QosClass, s.Desc.Cpu, s.getCgroupPriority())
+	cgrouputils.CgroupSet(strconv.Itoa(s.cgroupPid), s.GetCgroupName(), cpuShares)
 }
 
 func (s *SKVMGuestInstance) setCgroupCPUSet() {
@@ -2196,19 +2268,9 @@ func (s *SKVMGuestInstance) SyncQemuCmdline(cmdline string) {
 	s.SyncMetadata(meta)
 }
 
-func (s *SKVMGuestInstance) doBlockIoThrottle() {
-	disks := s.Desc.Disks
-	if len(disks) > 0 {
-		bps := disks[0].Bps
-		iops := disks[0].Iops
-		if bps > 0 || iops > 0 {
-			s.BlockIoThrottle(context.Background(), int64(bps), int64(iops))
-		}
-	}
-}
-
 func (s *SKVMGuestInstance) onGuestPrelaunch() error {
 	s.LiveMigrateDestPort = nil
+	s.LiveMigrateTuning = nil
 	if options.HostOptions.SetVncPassword {
 		s.SetVncPassword()
 	}
@@ -2219,8 +2281,10 @@ func (s *SKVMGuestInstance) onGuestPrelaunch() error {
 	}
 	s.OnResumeSyncMetadataInfo()
 	s.SetCgroup()
+	if s.needVcpuPinReconcile() {
+		go s.manager.reconcileVcpuPins()
+	}
 	s.optimizeOom()
-	s.doBlockIoThrottle()
 	return nil
 }
 
@@ -2508,8 +2572,8 @@ func (s *SKVMGuestInstance) onlineResizeDisk(ctx context.Context, diskId string,
 	task.Start()
 }
 
-func (s *SKVMGuestInstance) BlockIoThrottle(ctx context.Context, bps, iops int64) error {
-	task := SGuestBlockIoThrottleTask{s, ctx, bps, iops}
+func (s *SKVMGuestInstance) BlockIoThrottle(ctx context.Context, throttles map[string]SDiskIoThrottle) error {
+	task := SGuestBlockIoThrottleTask{SKVMGuestInstance: s, ctx: ctx, throttles: throttles}
 	return task.Start()
 }
 
@@ -2595,6 +2659,47 @@ func (s *SKVMGuestInstance) sriovNicAttachInitScript(networkIndex int8, dev isol
 	return "", errors.Errorf("no nic found for index %d", networkIndex)
 }
 
+func (s *SKVMGuestInstance) isSriovFailoverEnabled() bool {
+	return s.Desc.Metadata[api.VM_METADATA_SRIOV_FAILOVER] == "true"
+}
+
+// virtio-net failover: 为 VF 网卡创建同 MAC 的 virtio 备用网卡, 迁移时摘除 VF 后流量走备用网卡
+func (s *SKVMGuestInstance) initSriovFailoverStandby(nic *desc.SGuestNetwork, cont *desc.PCIController) error {
+	if len(nic.Bridge) == 0 {
+		log.Warningf("guest %s nic %s no bridge, skip sriov failover standby", s.GetName(), nic.Ifname)
+		return nil
+	}
+	if err := s.generateNicScripts(nic); err != nil {
+		return errors.Wrap(err, "generateNicScripts")
+	}
+	nic.UpscriptPath = s.getNicUpScriptPath(nic)
+	nic.DownscriptPath = s.getNicDownScriptPath(nic)
+	nic.Pci = desc.NewPCIDevice(cont.CType, "virtio-net-pci", fmt.Sprintf("netdev-%s", nic.Ifname))
+	nic.Pci.Options = map[string]string{"failover": "on"}
+	return nil
+}
+
+func (s *SKVMGuestInstance) hasSriovFailoverStandby(nic *desc.SGuestNetwork) bool {
+	return nic.Driver == api.NETWORK_DRIVER_VFIO && nic.Pci != nil
+}
+
+// 将 VF 设备与对应网卡的备用网卡配对
+func (s *SKVMGuestInstance) setSriovFailoverPair(dev *desc.SGuestIsolatedDevice) {
+	if dev.DevType != api.NIC_TYPE || len(dev.VfioDevs) == 0 {
+		return
+	}
+	for i := range s.Desc.Nics {
+		if s.Desc.Nics[i].Index != dev.NetworkIndex || !s.hasSriovFailoverStandby(s.Desc.Nics[i]) {
+			continue
+		}
+		if dev.VfioDevs[0].Options == nil {
+			dev.VfioDevs[0].Options = map[string]string{}
+		}
+		dev.VfioDevs[0].Options["failover_pair_id"] = s.Desc.Nics[i].Pci.Id
+		return
+	}
+}
+
 func (s *SKVMGuestInstance) generateSRIOVInitScripts() (string, error) {
 	var cmd = ""
 
@@ -2602,6 +2707,10 @@ func (s *SKVMGuestInstance) generateSRIOVInitScripts() (string, error) {
 		if s.Desc.Nics[i].Driver == "vfio-pci" {
 			dev, err := s.getSriovDeviceByNetworkIndex(s.Desc.Nics[i].Index)
 			if err != nil {
+				// 迁移目标端 VF 尚未分配, 由备用网卡承载流量
+				if s.hasSriovFailoverStandby(s.Desc.Nics[i]) {
+					continue
+				}
 				return "", err
 			}
 			cmd += fmt.Sprintf(
@@ -2642,11 +2751,18 @@ func (s *SKVMGuestInstance) CPUSet(ctx context.Context, input *api.ServerCPUSetI
 
 	var cpusetStr string
 	if input != nil {
-		cpus := []string{}
-		for _, id := range input.CPUS {
-			cpus = append(cpus, fmt.Sprintf("%d", id))
+		cpusetStr = cpusetString(input.CPUS)
+	}
+	if cgrouputils.IsCgroupV2() {
+		slice := cgrouputils.NewCgroupV2Slice(s.cgroupV2SliceName())
+		if len(cpusetStr) == 0 {
+			// 继承父 slice 的 cpuset
+			cpusetStr = cgrouputils.NewCgroupV2Slice(hostconsts.HOST_CGROUP).GetParam(cgrouputils.CPUSET_CPUS)
 		}
-		cpusetStr = strings.Join(cpus, ",")
+		if err := slice.SetParam(cgrouputils.CPUSET_CPUS, cpusetStr); err != nil {
+			return nil, errors.Wrap(err, "cgroup v2 cpuset")
+		}
+		return new(api.ServerCPUSetResp), nil
 	}
 
 	task := cgrouputils.NewCGroupCPUSetTask(
@@ -2666,6 +2782,10 @@ func (s *SKVMGuestInstance) CPUSetRemove(ctx context.Context) error {
 	if !s.IsRunning() {
 		return nil
 	}
+	if cgrouputils.IsCgroupV2() {
+		_, err := s.CPUSet(ctx, nil)
+		return err
+	}
 	task := cgrouputils.NewCGroupCPUSetTask(
 		strconv.Itoa(s.GetPid()), s.GetCgroupName(), 0, "",
 	)
@@ -61,56 +61,6 @@ const (
 	DISK_DRIVER_SATA   = qemu.DISK_DRIVER_SATA
 )
 
-const guestLauncher = `
-#!/usr/bin/env python
-import sys
-import os
-import time
-import subprocess
-
-with open(os.devnull, 'w')  as FNULL:
-    try:
-        cmd = subprocess.check_output(['bash', '%s'], stderr=FNULL).split()
-    except BaseException as e:
-        sys.stderr.write('%%s' %% e)
-        sys.exit(1)
-
-pid = os.fork()
-if pid < 0:
-    sys.stderr.write('failed fork child process')
-    sys.exit(1)
-
-if pid > 0:
-    status_encoded = os.waitpid(pid, 0)[1]
-    sys.exit((status_encoded>>8) & 0xff)
-else:
-    os.setsid()
-    os.chdir('/')
-
-    pid = os.fork()
-    if pid < 0:
-        sys.stderr.write('failed fork child process')
-        sys.exit(1)
-
-    if pid > 0:
-        sys.stdout.write('%%d' %% pid)
-        sys.exit(0)
-    else:
-        devnull = os.open('/dev/null', os.O_RDWR)
-        os.dup2(devnull, 0)
-        os.close(devnull)
-
-        logfd = os.open('%s', os.O_RDWR|os.O_CREAT|os.O_APPEND)
-        os.dup2(logfd, 1)
-        os.dup2(logfd, 2)
-        os.write(logfd, '%%s Run command: %%s\n' %% (time.strftime('%%Y-%%m-%%d %%H:%%M:%%S', time.localtime()), cmd))
-        os.close(logfd)
-
-        if os.execv(cmd[0], cmd) < 0:
-            sys.stderr.write('exec error')
-            sys.exit(1)
-`
-
 func (s *SKVMGuestInstance) IsKvmSupport() bool {
 	return s.manager.GetHost().IsKvmSupport()
 }
@@ -196,10 +146,78 @@ func (s *SKVMGuestInstance) isWindows10() bool {
 	return false
 }
 
+// windows desktop editions and old windows don't support cpu hot-add
+func (s *SKVMGuestInstance) isCpuHotplugSupported() bool {
+	if s.manager.host.IsAarch64() || s.GetOsName() == OS_NAME_MACOS {
+		return false
+	}
+	if s.GetOsName() == OS_NAME_WINDOWS {
+		if s.IsOldWindows() {
+			return false
+		}
+		distro := strings.ToLower(s.getOsDistribution())
+		if len(distro) > 0 && !strings.Contains(distro, "server") {
+			return false
+		}
+	}
+	return true
+}
+
+// getClockOption 合并 metadata 中的时钟策略与默认值, Windows 虚机默认开启 slew 漂移修正并关闭 HPET
+func (s *SKVMGuestInstance) getClockOption() *qemu.ClockOption {
+	policy := api.ServerSetClockPolicyInput{}
+	if val := s.Desc.Metadata[api.VM_METADATA_CLOCK_POLICY]; len(val) > 0 {
+		obj, err := jsonutils.ParseString(val)
+		if err == nil {
+			err = obj.Unmarshal(&policy)
+		}
+		if err != nil {
+			log.Errorf("failed parse server %s clock policy %s: %s", s.Id, val, err)
+		}
+	}
+	return resolveClockOption(policy, s.GetOsName() == OS_NAME_WINDOWS)
+}
+
+func resolveClockOption(policy api.ServerSetClockPolicyInput, isWindows bool) *qemu.ClockOption {
+	opt := &qemu.ClockOption{
+		Base:     api.VM_CLOCK_BASE_UTC,
+		Driftfix: api.VM_CLOCK_DRIFTFIX_NONE,
+	}
+	if isWindows {
+		opt.Driftfix = api.VM_CLOCK_DRIFTFIX_SLEW
+		opt.DisableHpet = true
+	}
+	if utils.IsInStringArray(policy.Base, []string{api.VM_CLOCK_BASE_UTC, api.VM_CLOCK_BASE_LOCALTIME}) {
+		opt.Base = policy.Base
+	}
+	if utils.IsInStringArray(policy.Driftfix, []string{api.VM_CLOCK_DRIFTFIX_NONE, api.VM_CLOCK_DRIFTFIX_SLEW}) {
+		opt.Driftfix = policy.Driftfix
+	}
+	switch policy.Hpet {
+	case api.VM_CLOCK_HPET_ON:
+		opt.DisableHpet = false
+	case api.VM_CLOCK_HPET_OFF:
+		opt.DisableHpet = true
+	}
+	return opt
+}
+
 func (s *SKVMGuestInstance) isMemcleanEnabled() bool {
 	return s.Desc.Metadata["enable_memclean"] == "true"
 }
 
+func (s *SKVMGuestInstance) isMemShareRequired() bool {
+	return len(s.Desc.SharedDirs) > 0
+}
+
+// guest started with non shared memory can't hotplug vhost-user devices
+func (s *SKVMGuestInstance) isMemShared() bool {
+	if s.Desc.MemDesc == nil || s.Desc.MemDesc.Mem == nil {
+		return false
+	}
+	return s.Desc.MemDesc.Mem.Options["share"] == "on"
+}
+
 func (s *SKVMGuestInstance) getMachine() string {
 	machine := s.Desc.Machine
 	if machine == "" {
@@ -263,6 +281,40 @@ func (s *SKVMGuestInstance) getNicDownScriptPath(nic *desc.SGuestNetwork) string
 	return path.Join(s.HomeDir(), fmt.Sprintf("if-down-%s-%s.sh", dev.Bridge(), nic.Ifname))
 }
 
+func (s *SKVMGuestInstance) getSharedDirSocketPath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofs-%s.sock", tag))
+}
+
+func (s *SKVMGuestInstance) getSharedDirPidPath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofs-%s.pid", tag))
+}
+
+// virtiofsd must be listening on the socket before qemu connects to it
+func (s *SKVMGuestInstance) generateSharedDirStartScript(dir *desc.SGuestSharedDir) string {
+	sock := s.getSharedDirSocketPath(dir.Tag)
+	pidFile := s.getSharedDirPidPath(dir.Tag)
+	args := fmt.Sprintf("--socket-path=%s --shared-dir=%s --cache=auto", sock, dir.HostPath)
+	if dir.ReadOnly {
+		args += " --readonly"
+	}
+	cmd := s.generateSharedDirStopScript(dir)
+	cmd += fmt.Sprintf("mkdir -p %s\n", dir.HostPath)
+	cmd += fmt.Sprintf("nohup %s %s > /dev/null 2>&1 &\n", options.HostOptions.VirtiofsdPath, args)
+	cmd += fmt.Sprintf("echo $! > %s\n", pidFile)
+	cmd += fmt.Sprintf("for i in $(seq 1 20); do [ -S %s ] && break; sleep 0.5; done\n", sock)
+	return cmd
+}
+
+func (s *SKVMGuestInstance) generateSharedDirStopScript(dir *desc.SGuestSharedDir) string {
+	pidFile := s.getSharedDirPidPath(dir.Tag)
+	cmd := fmt.Sprintf("if [ -f %s ]; then\n", pidFile)
+	cmd += fmt.Sprintf("  kill -9 $(cat %s) > /dev/null 2>&1\n", pidFile)
+	cmd += fmt.Sprintf("  rm -f %s\n", pidFile)
+	cmd += "fi\n"
+	cmd += fmt.Sprintf("rm -f %s\n", s.getSharedDirSocketPath(dir.Tag))
+	return cmd
+}
+
 func (s *SKVMGuestInstance) generateNicScripts(nic *desc.SGuestNetwork) error {
 	bridge := nic.Bridge
 	dev := s.manager.GetHost().GetBridgeDev(bridge)
@@ -309,6 +361,7 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 		HugepagesEnabled:     s.manager.host.IsHugepagesEnabled(),
 		EnableMemfd:          s.isMemcleanEnabled(),
 		PidFilePath:          s.GetPidFilePath(),
+		Clock:                s.getClockOption(),
 	}
 
 	if data.Contains("encrypt_key") {
@@ -342,7 +395,7 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 
 	for _, nic := range s.Desc.Nics {
-		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO && !s.hasSriovFailoverStandby(nic) {
 			continue
 		}
 		downscript := s.getNicDownScriptPath(nic)
@@ -350,10 +403,14 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 
 	if input.HugepagesEnabled {
+		if err := s.checkHugepages(); err != nil {
+			return "", errors.Wrap(err, "check hugepages")
+		}
 		cmd += fmt.Sprintf("mkdir -p /dev/hugepages/%s\n", s.Desc.Uuid)
 		cmd += fmt.Sprintf("mount -t hugetlbfs -o pagesize=%dK,size=%dM hugetlbfs-%s /dev/hugepages/%s\n",
-			s.manager.host.HugepageSizeKb(), s.Desc.Mem, s.Desc.Uuid, s.Desc.Uuid)
+			s.getHugepageSizeKb(), s.Desc.Mem, s.Desc.Uuid, s.Desc.Uuid)
 	}
+	cmd += s.generateVirtioMemStartScript()
 
 	cmd += "sleep 1\n"
 	cmd += fmt.Sprintf("echo %d > %s\n", input.VNCPort, s.GetVncFilePath())
@@ -370,6 +427,14 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 	cmd += sriovInitScripts
 
+	for _, dir := range s.Desc.SharedDirs {
+		cmd += s.generateSharedDirStartScript(dir)
+	}
+
+	if s.Desc.Tpm != nil {
+		cmd += s.generateTpmStartScript()
+	}
+
 	cmd += fmt.Sprintf("STATE_FILE=`ls -d %s* | head -n 1`\n", s.getStateFilePathRootPrefix())
 	cmd += fmt.Sprintf("PID_FILE=%s\n", input.PidFilePath)
 
@@ -448,12 +513,25 @@ function nic_mtu() {
 		Port: uint(s.GetQmpMonitorPort(int(input.VNCPort))),
 		Mode: MODE_CONTROL,
 	}
+	if err := s.prepareStartTls(input); err != nil {
+		return "", errors.Wrap(err, "prepare start tls")
+	}
 
 	input.EnableUUID = options.HostOptions.EnableVmUuid
+	input.BootOnce = s.Desc.Metadata[api.VM_METADATA_BOOT_ONCE]
 	if s.Desc.Bios == qemu.BIOS_UEFI {
-		if len(input.OVMFPath) == 0 {
-			input.OVMFPath = options.HostOptions.OvmfPath
+		if s.isSecureBootEnabled() {
+			input.SecureBoot = true
+			input.OVMFPath = options.HostOptions.OvmfSecbootCodePath
+			input.OVMFVarsTemplatePath = options.HostOptions.OvmfSecbootVarsPath
+		} else {
+			if len(input.OVMFPath) == 0 {
+				input.OVMFPath = options.HostOptions.OvmfPath
+			}
+			input.OVMFVarsTemplatePath = options.HostOptions.OvmfVarsPath
 		}
+		input.OVMFVarsPath = s.getOvmfVarsPath()
+		input.ConfidentialVmFirmware = s.getConfidentialVmFirmware()
 	}
 
 	// inject usb devices
@@ -494,6 +572,12 @@ function nic_mtu() {
 			s.LiveMigrateUseTls = true
 			input.LiveMigrateUseTLS = true
 		}
+		if data.Contains("live_migrate_tuning") {
+			s.LiveMigrateTuning = new(SLiveMigrateTuning)
+			if err := data.Unmarshal(s.LiveMigrateTuning, "live_migrate_tuning"); err != nil {
+				return "", errors.Wrap(err, "unmarshal live_migrate_tuning")
+			}
+		}
 	} else if s.Desc.IsSlave {
 		input.LiveMigratePort = uint(*s.LiveMigrateDestPort)
 	}
@@ -622,8 +706,15 @@ func (s *SKVMGuestInstance) generateStopScript(data *jsonutils.JSONDict) string
 	cmd += fmt.Sprintf("  fi\n")
 	cmd += fmt.Sprintf("done\n")
 
+	for _, dir := range s.Desc.SharedDirs {
+		cmd += s.generateSharedDirStopScript(dir)
+	}
+	if s.Desc.Tpm != nil {
+		cmd += s.generateTpmStopScript()
+	}
+
 	for _, nic := range nics {
-		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO && !s.hasSriovFailoverStandby(nic) {
 			continue
 		}
 		downscript := s.getNicDownScriptPath(nic)
@@ -773,12 +864,23 @@ func (s *SKVMGuestInstance) initMemDesc(memSizeMB int64) {
 			"size":     fmt.Sprintf("%dM", memSizeMB),
 			"share":    "on", "prealloc": "on",
 		}
+		if node := s.getHugepageNumaNode(); node >= 0 {
+			s.Desc.MemDesc.Mem.Options["host-nodes"] = strconv.Itoa(node)
+			s.Desc.MemDesc.Mem.Options["policy"] = "bind"
+		}
 	} else if s.isMemcleanEnabled() {
 		s.Desc.MemDesc.Mem = desc.NewObject("memory-backend-memfd", "mem")
 		s.Desc.MemDesc.Mem.Options = map[string]string{
 			"size":  fmt.Sprintf("%dM", memSizeMB),
 			"share": "on", "prealloc": "on",
 		}
+	} else if s.isMemShareRequired() {
+		// vhost-user devices require guest memory shared with backend process
+		s.Desc.MemDesc.Mem = desc.NewObject("memory-backend-memfd", "mem")
+		s.Desc.MemDesc.Mem.Options = map[string]string{
+			"size":  fmt.Sprintf("%dM", memSizeMB),
+			"share": "on",
+		}
 	} else {
 		s.Desc.MemDesc.Mem = desc.NewObject("memory-backend-ram", "mem")
 		s.Desc.MemDesc.Mem.Options = map[string]string{
@@ -791,7 +893,7 @@ func (s *SKVMGuestInstance) initMemDescFromMemoryInfo(memoryDevicesInfoList []mo
 	var objType string
 	if s.manager.host.IsHugepagesEnabled() {
 		objType = "memory-backend-file"
-	} else if s.isMemcleanEnabled() {
+	} else if s.isMemcleanEnabled() || s.isMemShareRequired() {
 		objType = "memory-backend-memfd"
 	} else {
 		objType = "memory-backend-ram"
@@ -800,6 +902,10 @@ func (s *SKVMGuestInstance) initMemDescFromMemoryInfo(memoryDevicesInfoList []mo
 	memSize := s.Desc.Mem
 	memSlots := make([]*desc.SMemSlot, 0)
 	for i := 0; i < len(memoryDevicesInfoList); i++ {
+		if memoryDevicesInfoList[i].Type == "virtio-mem" {
+			// virtio-mem plugged memory is accounted as boot memory after reinit
+			continue
+		}
 		if memoryDevicesInfoList[i].Type != "dimm" || memoryDevicesInfoList[i].Data.ID == nil {
 			return errors.Errorf("unsupported memory device type %s", memoryDevicesInfoList[i].Type)
 		}
@@ -837,6 +943,38 @@ func (s *SKVMGuestInstance) fixGuestMachineType() {
 
 func (s *SKVMGuestInstance) initMachineDesc() {
 	s.Desc.MachineDesc = s.archMan.GenerateMachineDesc(s.Desc.CpuDesc.Accel)
+	if s.isSecureBootEnabled() {
+		s.Desc.MachineDesc.Smm = true
+	}
+	if machineType := s.Desc.Metadata[api.VM_METADATA_MACHINE_TYPE]; len(machineType) > 0 {
+		if s.manager.resolveQemuMachine(machineType) == machineType {
+			s.Desc.MachineDesc.Type = machineType
+		} else {
+			log.Warningf("guest %s machine type %s not supported by qemu, use %s", s.GetName(), machineType, s.getMachine())
+		}
+	}
+}
+
+// applyPendingMachineType 冷启动时使用待升级的机型, 启动失败由 region 回滚
+func (s *SKVMGuestInstance) applyPendingMachineType() {
+	machineType := s.Desc.Metadata[api.VM_METADATA_PENDING_MACHINE_TYPE]
+	if len(machineType) == 0 {
+		return
+	}
+	if s.manager.resolveQemuMachine(machineType) != machineType {
+		log.Warningf("guest %s pending machine type %s not supported by qemu", s.GetName(), machineType)
+		return
+	}
+	log.Infof("guest %s upgrade machine type to %s", s.GetName(), machineType)
+	s.Desc.MachineDesc.Type = machineType
+}
+
+// getQemuMachineType 返回启动使用的版本化机型
+func (s *SKVMGuestInstance) getQemuMachineType() string {
+	if s.Desc.MachineDesc != nil && len(s.Desc.MachineDesc.Type) > 0 {
+		return s.Desc.MachineDesc.Type
+	}
+	return s.manager.resolveQemuMachine(s.getMachine())
 }
 
 func (s *SKVMGuestInstance) initQgaDesc() {
@@ -0,0 +1,105 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	pkiutil "yunion.io/x/onecloud/pkg/util/tls/pki"
+)
+
+// CreateDefaultCerts 在 dir 下生成 CA 及 server/client 证书, 已存在的证书会被复用
+func CreateDefaultCerts(dir string) error {
+	tree, err := GetDefaultCertList().AsMap().CertTree()
+	if err != nil {
+		return errors.Wrap(err, "construct cert tree")
+	}
+	if err := tree.CreateTree(dir); err != nil {
+		return errors.Wrap(err, "create certs")
+	}
+	return nil
+}
+
+func leafNeedRotate(cert *x509.Certificate, maxAge time.Duration, now time.Time) bool {
+	if now.After(cert.NotAfter.Add(-maxAge)) {
+		return true
+	}
+	return now.Sub(cert.NotBefore) > maxAge
+}
+
+// RotateLeafCerts 重新签发签发时间超过 maxAge 或即将过期的 server/client 证书,
+// CA 保持不变, 仍持有旧证书的 qemu 进程与新证书可以互相校验
+func RotateLeafCerts(dir string, maxAge time.Duration, now time.Time) (bool, error) {
+	if _, err := pkiutil.TryLoadKeyFromDisk(dir, CACertAndKeyBaseName); err != nil {
+		return false, errors.Wrap(err, "load ca key")
+	}
+	rotated := false
+	for _, leaf := range []*QemuCert{&QemuCertServer, &QemuCertClient} {
+		cert, err := pkiutil.TryLoadCertFromDisk(dir, leaf.BaseName)
+		if err == nil && !leafNeedRotate(cert, maxAge, now) {
+			continue
+		}
+		certPath, keyPath := pkiutil.PathsForCertAndKey(dir, leaf.BaseName)
+		for _, fp := range []string{certPath, keyPath} {
+			if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+				return false, errors.Wrapf(err, "remove %s", fp)
+			}
+		}
+		rotated = true
+	}
+	if !rotated {
+		return false, nil
+	}
+	return true, CreateDefaultCerts(dir)
+}
+
+// ClientTLSConfig 使用 client 证书连接 qemu 的 TLS 端点,
+// server 证书未签发 SAN, 因此只校验证书链, 不校验主机名
+func ClientTLSConfig(dir string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(filepath.Join(dir, CLIENT_CERT_NAME), filepath.Join(dir, CLIENT_KEY_NAME))
+	if err != nil {
+		return nil, errors.Wrap(err, "load client cert")
+	}
+	caCert, err := pkiutil.TryLoadCertFromDisk(dir, CACertAndKeyBaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "load ca cert")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	return &tls.Config{
+		Certificates:       []tls.Certificate{pair},
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.Errorf("no server certificate")
+			}
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return errors.Wrap(err, "parse server certificate")
+			}
+			_, err = cert.Verify(x509.VerifyOptions{
+				Roots:     pool,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			return err
+		},
+	}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkiutil "yunion.io/x/onecloud/pkg/util/tls/pki"
+)
+
+func TestRotateLeafCerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := CreateDefaultCerts(dir); err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := pkiutil.TryLoadCertFromDisk(dir, CACertAndKeyBaseName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldServer, err := pkiutil.TryLoadCertFromDisk(dir, ServerCertBaseName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := RotateLeafCerts(dir, 24*time.Hour, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated {
+		t.Errorf("fresh certs should not be rotated")
+	}
+
+	rotated, err = RotateLeafCerts(dir, 24*time.Hour, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated {
+		t.Fatalf("expired certs should be rotated")
+	}
+	newServer, err := pkiutil.TryLoadCertFromDisk(dir, ServerCertBaseName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newServer.SerialNumber.Cmp(oldServer.SerialNumber) == 0 {
+		t.Errorf("server cert not regenerated")
+	}
+	if err := newServer.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("rotated server cert not signed by origin ca: %v", err)
+	}
+	newCa, err := pkiutil.TryLoadCertFromDisk(dir, CACertAndKeyBaseName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newCa.Equal(caCert) {
+		t.Errorf("ca should be kept")
+	}
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := CreateDefaultCerts(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	serverPair, err := tls.LoadX509KeyPair(filepath.Join(dir, SERVER_CERT_NAME), filepath.Join(dir, SERVER_KEY_NAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverPair}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	cfg, err := ClientTLSConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := tls.Dial("tcp", ln.Addr().String(), cfg)
+	if err != nil {
+		t.Fatalf("dial with pki client cert: %v", err)
+	}
+	conn.Close()
+
+	// 其它 CA 签发的 server 证书应被拒绝
+	otherDir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(otherDir)
+	if err := CreateDefaultCerts(otherDir); err != nil {
+		t.Fatal(err)
+	}
+	otherCfg, err := ClientTLSConfig(otherDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := tls.Dial("tcp", ln.Addr().String(), otherCfg); err == nil {
+		conn.Close()
+		t.Errorf("server cert from foreign ca should be rejected")
+	}
+}
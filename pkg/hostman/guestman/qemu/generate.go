@@ -16,8 +16,10 @@ package qemu
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/utils"
 
@@ -25,10 +27,17 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 )
 
+const (
+	MONITOR_TLS_CREDS_ID = "tlsmon0"
+	DISPLAY_TLS_CREDS_ID = "tlsvnc0"
+)
+
 type Monitor struct {
 	Id   string
 	Port uint
 	Mode string
+	// 非空时 chardev 使用对应的 tls-creds-x509 对象加密
+	TlsCreds string
 }
 
 func generatePCIDeviceOption(dev *desc.PCIDevice) string {
@@ -73,19 +82,27 @@ func usbRedirOptions(usbredir *desc.UsbRedirctDesc) []string {
 	opts = append(opts, usbControllerOption(usbredir.UHCI1))
 	opts = append(opts, usbControllerOption(usbredir.UHCI2))
 	opts = append(opts, usbControllerOption(usbredir.UHCI3))
-	opts = append(opts, chardevOption(usbredir.UsbRedirDev1.Source))
-	opts = append(opts, fmt.Sprintf("-device usb-redir,chardev=%s,id=%s", usbredir.UsbRedirDev1.Source.Id, usbredir.UsbRedirDev1.Id))
-	opts = append(opts, chardevOption(usbredir.UsbRedirDev2.Source))
-	opts = append(opts, fmt.Sprintf("-device usb-redir,chardev=%s,id=%s", usbredir.UsbRedirDev2.Source.Id, usbredir.UsbRedirDev2.Id))
+	devs := []*desc.UsbRedir{usbredir.UsbRedirDev1, usbredir.UsbRedirDev2}
+	devs = append(devs, usbredir.ExtraUsbRedirDevs...)
+	for _, dev := range devs {
+		if dev == nil {
+			continue
+		}
+		opts = append(opts, chardevOption(dev.Source))
+		opts = append(opts, fmt.Sprintf("-device usb-redir,chardev=%s,id=%s", dev.Source.Id, dev.Id))
+	}
 
 	return opts
 }
 
-func generateSpiceOptions(port uint, spice *desc.SSpiceDesc) []string {
+func generateSpiceOptions(port uint, spice *desc.SSpiceDesc, tlsDir string) []string {
 	opts := make([]string, 0)
 
-	// spice
+	// spice, 开启 TLS 时只监听 tls-port
 	spiceCmd := fmt.Sprintf("-spice port=%d", port)
+	if len(tlsDir) > 0 {
+		spiceCmd = fmt.Sprintf("-spice tls-port=%d,x509-dir=%s", port, tlsDir)
+	}
 	spiceCmd += desc.OptionsToString(spice.Options)
 	opts = append(opts, spiceCmd)
 
@@ -135,32 +152,65 @@ func generatePciControllerOptions(controllers []*desc.PCIController) []string {
 	return opts
 }
 
+// CountNumaNodes 返回 qemu 命令行中虚机 NUMA 节点数
+func CountNumaNodes(cmdline string) int {
+	return strings.Count(cmdline, "-numa node")
+}
+
 func generateNumaOption(memId string) string {
 	return fmt.Sprintf("-numa node,memdev=%s", memId)
 }
 
+func generateNumaNodesOptions(nodes []*desc.SGuestNumaNode) []string {
+	cmds := []string{}
+	for _, node := range nodes {
+		cmds = append(cmds, generateObjectOption(node.MemObj))
+		cmds = append(cmds, fmt.Sprintf("-numa node,nodeid=%d,cpus=%s,memdev=%s", node.NodeId, node.Cpus, node.MemObj.Id))
+	}
+	return cmds
+}
+
 func generateMemoryOption(memDesc *desc.SGuestMem) string {
 	cmds := []string{}
 	cmds = append(cmds, fmt.Sprintf(
 		"-m %dM,slots=%d,maxmem=%dM",
 		memDesc.SizeMB, memDesc.Slots, memDesc.MaxMem,
 	))
-	cmds = append(cmds, generateObjectOption(memDesc.Mem))
-	cmds = append(cmds, generateNumaOption(memDesc.Mem.Id))
+	if len(memDesc.NumaNodes) > 0 {
+		cmds = append(cmds, generateNumaNodesOptions(memDesc.NumaNodes)...)
+	} else {
+		cmds = append(cmds, generateObjectOption(memDesc.Mem))
+		cmds = append(cmds, generateNumaOption(memDesc.Mem.Id))
+	}
 	for i := 0; i < len(memDesc.MemSlots); i++ {
 		memDev := memDesc.MemSlots[i].MemDev
 		memObj := memDesc.MemSlots[i].MemObj
 		cmds = append(cmds, generateObjectOption(memObj))
 		cmds = append(cmds, fmt.Sprintf("-device %s,id=%s,memdev=%s", memDev.Type, memDev.Id, memObj.Id))
 	}
+	if memDesc.VirtioMem != nil {
+		cmds = append(cmds, generateObjectOption(memDesc.VirtioMem.MemObj))
+	}
 	return strings.Join(cmds, " ")
 }
 
 func generateMachineOption(machine string, machineDesc *desc.SGuestMachine) string {
+	if len(machineDesc.Type) > 0 {
+		machine = machineDesc.Type
+	}
 	cmd := fmt.Sprintf("-machine %s,accel=%s", machine, machineDesc.Accel)
 	if machineDesc.GicVersion != nil {
 		cmd += fmt.Sprintf(",gic-version=%s", *machineDesc.GicVersion)
 	}
+	if machineDesc.Smm {
+		cmd += ",smm=on"
+	}
+	if len(machineDesc.ConfidentialGuestSupport) > 0 {
+		cmd += fmt.Sprintf(",confidential-guest-support=%s", machineDesc.ConfidentialGuestSupport)
+	}
+	if len(machineDesc.KernelIrqchip) > 0 {
+		cmd += fmt.Sprintf(",kernel-irqchip=%s", machineDesc.KernelIrqchip)
+	}
 
 	return cmd
 }
@@ -195,8 +245,12 @@ func getMonitorOptions(drvOpt QemuOptions, input *Monitor) []string {
 		return nil
 	}
 	idDev := input.Id + "dev"
+	chardev := drvOpt.MonitorChardev(idDev, input.Port, "127.0.0.1")
+	if len(input.TlsCreds) > 0 {
+		chardev += ",tls-creds=" + input.TlsCreds
+	}
 	opts := []string{
-		drvOpt.MonitorChardev(idDev, input.Port, "127.0.0.1"),
+		chardev,
 		drvOpt.Mon(idDev, input.Id, input.Mode),
 	}
 	return opts
@@ -228,7 +282,12 @@ func getDiskDriveOption(drvOpt QemuOptions, disk *desc.SGuestDisk, isEncrypt boo
 		opt += ",format=raw"
 	}
 	opt += fmt.Sprintf(",cache=%s", cacheMode)
-	if isLocalStorage(disk) {
+	if aioMode == api.DISK_AIO_MODE_NATIVE && !utils.IsInStringArray(cacheMode, api.DISK_DIRECT_CACHE_MODES) {
+		// aio=native 依赖 O_DIRECT, 否则 qemu 拒绝启动
+		log.Warningf("disk %s aio=native requires cache=%s, got cache=%s, fallback to threads", disk.DiskId, api.DISK_DIRECT_CACHE_MODES, cacheMode)
+		aioMode = api.DISK_AIO_MODE_THREADS
+	}
+	if isLocalStorage(disk) && len(aioMode) > 0 {
 		opt += fmt.Sprintf(",aio=%s", aioMode)
 	}
 	if len(disk.Url) > 0 { // # a remote file backed image
@@ -240,6 +299,12 @@ func getDiskDriveOption(drvOpt QemuOptions, disk *desc.SGuestDisk, isEncrypt boo
 	if isEncrypt {
 		opt += ",encrypt.format=luks,encrypt.key-secret=sec0"
 	}
+	throttling := disk.ThrottlingOptions()
+	for _, key := range []string{"throttling.bps-total", "throttling.iops-total"} {
+		if val, ok := throttling[key]; ok {
+			opt += fmt.Sprintf(",%s=%s", key, val)
+		}
+	}
 	// #opt += ",media=disk"
 	return drvOpt.Drive(opt)
 }
@@ -271,7 +336,11 @@ func getDiskDeviceOption(optDrv QemuOptions, disk *desc.SGuestDisk) string {
 			opt += fmt.Sprintf(",bus=%s,addr=%s", disk.Pci.BusStr(), disk.Pci.SlotFunc())
 		}
 		// opt += fmt.Sprintf(",num-queues=%d,vectors=%d,iothread=iothread0", numQueues, numQueues+1)
-		opt += ",iothread=iothread0"
+		iothread := disk.IOThread
+		if len(iothread) == 0 {
+			iothread = "iothread0"
+		}
+		opt += fmt.Sprintf(",iothread=%s", iothread)
 	} else if utils.IsInStringArray(diskDriver, []string{DISK_DRIVER_SCSI, DISK_DRIVER_PVSCSI}) {
 		opt += ",bus=scsi.0"
 	} else if diskDriver == DISK_DRIVER_IDE {
@@ -373,19 +442,26 @@ func generateNicOptions(drvOpt QemuOptions, input *GenerateStartOptionsInput) ([
 	nics := input.GuestDesc.Nics
 
 	for idx := range nics {
-		if nics[idx].Driver == api.NETWORK_DRIVER_VFIO {
-			continue
+		nic := nics[idx]
+		if nic.Driver == api.NETWORK_DRIVER_VFIO {
+			if nic.Pci == nil {
+				continue
+			}
+			// failover standby virtio nic paired with sriov vf
+			standby := *nic
+			standby.Driver = "virtio"
+			nic = &standby
 		}
 
-		netDevOpt, err := getNicNetdevOption(drvOpt, nics[idx], input.IsKVMSupport)
+		netDevOpt, err := getNicNetdevOption(drvOpt, nic, input.IsKVMSupport)
 		if err != nil {
-			return nil, errors.Wrapf(err, "getNicNetdevOption %v", nics[idx])
+			return nil, errors.Wrapf(err, "getNicNetdevOption %v", nic)
 		}
 		opts = append(opts,
 			netDevOpt,
 			// aarch64 with addr lead to:
 			// virtio_net: probe of virtioN failed with error -22
-			getNicDeviceOption(drvOpt, nics[idx], input))
+			getNicDeviceOption(drvOpt, nic, input))
 	}
 	return opts, nil
 }
@@ -451,6 +527,9 @@ func GetNicDeviceModel(name string) string {
 
 func generateUsbDeviceOption(usbControllerId string, usb *desc.UsbDevice) string {
 	cmd := fmt.Sprintf("-device %s,bus=%s.0", usb.DevType, usbControllerId)
+	if len(usb.Id) > 0 {
+		cmd += fmt.Sprintf(",id=%s", usb.Id)
+	}
 	cmd += desc.OptionsToString(usb.Options)
 	return cmd
 }
@@ -485,6 +564,36 @@ func generateIsolatedDeviceOptions(guestDesc *desc.SGuestDesc) []string {
 	return opts
 }
 
+func generateSharedDirOptions(dirs []*desc.SGuestSharedDir) []string {
+	opts := make([]string, 0)
+	for i := 0; i < len(dirs); i++ {
+		opts = append(opts, chardevOption(dirs[i].Socket))
+		opts = append(opts, generatePCIDeviceOption(dirs[i].Pci))
+	}
+	return opts
+}
+
+func generateTpmOptions(tpm *desc.SGuestTpm) []string {
+	return []string{
+		chardevOption(tpm.Socket),
+		fmt.Sprintf("-tpmdev emulator,id=%s,chardev=%s", tpm.Id, tpm.Socket.Id),
+		fmt.Sprintf("-device %s,tpmdev=%s", tpm.DevType, tpm.Id),
+	}
+}
+
+func generateWatchdogOptions(wd *desc.SGuestWatchdog) []string {
+	opts := make([]string, 0)
+	if wd.Pci != nil {
+		opts = append(opts, generatePCIDeviceOption(wd.Pci))
+	} else {
+		opts = append(opts, fmt.Sprintf("-device %s,id=%s", wd.Model, wd.Id))
+	}
+	if len(wd.Action) > 0 {
+		opts = append(opts, fmt.Sprintf("-watchdog-action %s", wd.Action))
+	}
+	return opts
+}
+
 func generateObjectOption(o *desc.Object) string {
 	cmd := fmt.Sprintf("-object %s,id=%s", o.ObjType, o.Id)
 	cmd += desc.OptionsToString(o.Options)
@@ -548,6 +657,10 @@ type GenerateStartOptionsInput struct {
 	OVNIntegrationBridge string
 	Devices              []string
 	OVMFPath             string
+	OVMFVarsTemplatePath string
+	OVMFVarsPath         string
+	SecureBoot           bool
+	BootOnce             string
 	VNCPort              uint
 	VNCPassword          bool
 	EnableLog            bool
@@ -564,8 +677,43 @@ type GenerateStartOptionsInput struct {
 	LiveMigratePort      uint
 	LiveMigrateUseTLS    bool
 	EnablePvpanic        bool
+	Clock                *ClockOption
 
 	EncryptKeyPath string
+
+	// 复用迁移证书所在的 pki 目录为 monitor 及 VNC/SPICE 开启 TLS
+	TlsPKIDir        string
+	EnableMonitorTls bool
+	EnableDisplayTls bool
+
+	// sev-snp/tdx 使用无状态固件, 通过 -bios 加载, 不使用 pflash 变量文件
+	ConfidentialVmFirmware string
+}
+
+type ClockOption struct {
+	Base        string
+	Driftfix    string
+	DisableHpet bool
+}
+
+func generateClockOptions(drvOpt QemuOptions, clock *ClockOption) []string {
+	if clock == nil {
+		clock = &ClockOption{}
+	}
+	base, driftfix := clock.Base, clock.Driftfix
+	if len(base) == 0 {
+		base = "utc"
+	}
+	if len(driftfix) == 0 {
+		driftfix = "none"
+	}
+	opts := []string{drvOpt.RTC(base, driftfix)}
+	if clock.DisableHpet {
+		if opt := drvOpt.NoHpet(); len(opt) > 0 {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
 }
 
 func (input *GenerateStartOptionsInput) HasBootIndex() bool {
@@ -601,14 +749,23 @@ func GenerateStartOptions(
 		opts = append(opts, drvOpt.Log(input.EnableLog))
 	}
 
+	if input.EnableMonitorTls {
+		opts = append(opts, drvOpt.TlsCredsX509(MONITOR_TLS_CREDS_ID, input.TlsPKIDir, "server", true))
+		for _, mon := range []*Monitor{input.HMPMonitor, input.QMPMonitor} {
+			if mon != nil {
+				mon.TlsCreds = MONITOR_TLS_CREDS_ID
+			}
+		}
+	}
+
 	// TODO hmp - -
 	opts = append(opts, getMonitorOptions(drvOpt, input.HMPMonitor)...)
 	if input.QMPMonitor != nil {
 		opts = append(opts, getMonitorOptions(drvOpt, input.QMPMonitor)...)
 	}
 
+	opts = append(opts, generateClockOptions(drvOpt, input.Clock)...)
 	opts = append(opts,
-		drvOpt.RTC(),
 		// drvOpt.Daemonize(),
 		drvOpt.Nodefaults(),
 		drvOpt.Nodefconfig(),
@@ -634,20 +791,37 @@ func GenerateStartOptions(
 	// with the "-boot order=..." (or "-boot once=...") parameter.
 	var bootOrder *string
 	if !input.HasBootIndex() {
-		bootOrder = &input.GuestDesc.BootOrder
+		order := input.GuestDesc.BootOrder
+		// once 仅对本次启动生效, 虚拟机重启后恢复 order
+		if len(input.BootOnce) > 0 && len(order) > 0 {
+			order = fmt.Sprintf("order=%s,once=%s", order, input.BootOnce)
+		} else if len(input.BootOnce) > 0 {
+			order = fmt.Sprintf("once=%s", input.BootOnce)
+		}
+		bootOrder = &order
 	}
 	opts = append(opts, drvOpt.Boot(bootOrder, enableMenu))
 
 	// bios
-	if input.GuestDesc.Bios == BIOS_UEFI {
+	if input.GuestDesc.Bios == BIOS_UEFI && len(input.ConfidentialVmFirmware) > 0 {
+		opts = append(opts, fmt.Sprintf("-bios %s", input.ConfidentialVmFirmware))
+	} else if input.GuestDesc.Bios == BIOS_UEFI {
 		if input.OVMFPath == "" {
 			return "", errors.Errorf("input OVMF path is empty")
 		}
-		fmOpt, err := drvOpt.BIOS(input.OVMFPath, input.HomeDir)
+		ovmfVarsPath := input.OVMFVarsPath
+		if len(ovmfVarsPath) == 0 {
+			ovmfVarsPath = path.Join(input.HomeDir, "OVMF_VARS.fd")
+		}
+		fmOpt, err := drvOpt.BIOS(input.OVMFPath, input.OVMFVarsTemplatePath, ovmfVarsPath)
 		if err != nil {
 			return "", errors.Wrap(err, "bios option")
 		}
 		opts = append(opts, fmOpt)
+		if input.SecureBoot {
+			// only smm code can write secure boot variables
+			opts = append(opts, "-global driver=cfi.pflash01,property=secure,value=on")
+		}
 	}
 
 	if input.OsName == OS_NAME_MACOS {
@@ -656,17 +830,40 @@ func GenerateStartOptions(
 
 	if input.GuestDesc.Vga != "none" {
 		opts = append(opts, generatePCIDeviceOption(input.GuestDesc.VgaDevice.PCIDevice))
+		for _, head := range input.GuestDesc.VgaDevice.Heads {
+			opts = append(opts, generatePCIDeviceOption(head))
+		}
 	}
 
 	// vdi spice
 	if input.IsVdiSpice {
-		opts = append(opts, generateSpiceOptions(input.SpicePort, input.GuestDesc.VdiDevice.Spice)...)
+		tlsDir := ""
+		if input.EnableDisplayTls {
+			tlsDir = input.TlsPKIDir
+		}
+		opts = append(opts, generateSpiceOptions(input.SpicePort, input.GuestDesc.VdiDevice.Spice, tlsDir)...)
+	} else if input.EnableDisplayTls {
+		opts = append(opts,
+			drvOpt.TlsCredsX509(DISPLAY_TLS_CREDS_ID, input.TlsPKIDir, "server", false),
+			drvOpt.VNC(input.VNCPort, input.VNCPassword)+",tls-creds="+DISPLAY_TLS_CREDS_ID,
+		)
 	} else {
 		opts = append(opts, drvOpt.VNC(input.VNCPort, input.VNCPassword))
 	}
 
 	// iothread object
-	opts = append(opts, drvOpt.Object("iothread", map[string]string{"id": "iothread0"}))
+	iothreads := input.GuestDesc.IOThreads
+	if len(iothreads) == 0 {
+		iothreads = []string{"iothread0"}
+	}
+	for _, id := range iothreads {
+		opts = append(opts, drvOpt.Object("iothread", map[string]string{"id": id}))
+	}
+
+	// sev-guest/tdx-guest object, referenced by machine confidential-guest-support
+	if input.GuestDesc.ConfidentialVm != nil {
+		opts = append(opts, generateObjectOption(input.GuestDesc.ConfidentialVm.Object))
+	}
 
 	isEncrypt := false
 	if len(input.EncryptKeyPath) > 0 {
@@ -719,6 +916,21 @@ func GenerateStartOptions(
 		opts = append(opts, generateIsolatedDeviceOptions(input.GuestDesc)...)
 	}
 
+	// virtio-fs shared dirs
+	if len(input.GuestDesc.SharedDirs) > 0 {
+		opts = append(opts, generateSharedDirOptions(input.GuestDesc.SharedDirs)...)
+	}
+
+	// vTPM
+	if input.GuestDesc.Tpm != nil {
+		opts = append(opts, generateTpmOptions(input.GuestDesc.Tpm)...)
+	}
+
+	// watchdog
+	if input.GuestDesc.Watchdog != nil {
+		opts = append(opts, generateWatchdogOptions(input.GuestDesc.Watchdog)...)
+	}
+
 	// pidfile
 	opts = append(opts, drvOpt.Pidfile(input.PidFilePath))
 
@@ -733,11 +945,21 @@ func GenerateStartOptions(
 		opts = append(opts, generateQgaOptions(input.GuestDesc)...)
 	}
 
+	// virtio-mem device
+	if input.GuestDesc.MemDesc != nil && input.GuestDesc.MemDesc.VirtioMem != nil {
+		opts = append(opts, generatePCIDeviceOption(input.GuestDesc.MemDesc.VirtioMem.PCIDevice))
+	}
+
 	// random device
 	if input.GuestDesc.Rng != nil {
 		opts = append(opts, getRNGRandomOptions(input.GuestDesc.Rng)...)
 	}
 
+	// balloon device
+	if input.GuestDesc.Balloon != nil {
+		opts = append(opts, generatePCIDeviceOption(input.GuestDesc.Balloon.PCIDevice))
+	}
+
 	// serial device
 	if input.GuestDesc.IsaSerial != nil {
 		opts = append(opts, generateISASerialOptions(input.GuestDesc.IsaSerial)...)
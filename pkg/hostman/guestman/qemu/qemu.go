@@ -16,7 +16,6 @@ package qemu
 
 import (
 	"fmt"
-	"path"
 	"strings"
 	"sync"
 
@@ -82,7 +81,8 @@ type CPUOption struct {
 type QemuOptions interface {
 	IsArm() bool
 	Log(enable bool) string
-	RTC() string
+	RTC(base, driftfix string) string
+	NoHpet() string
 	FreezeCPU() string
 	Daemonize() string
 	Nodefaults() string
@@ -95,13 +95,14 @@ type QemuOptions interface {
 	MemDev(sizeMB uint64) string
 	MemFd(sizeMB uint64) string
 	Boot(order *string, enableMenu bool) string
-	BIOS(ovmfPath, homedir string) (string, error)
+	BIOS(ovmfPath, ovmfVarsTemplatePath, ovmfVarsPath string) (string, error)
 	Device(devStr string) string
 	Drive(driveStr string) string
 	Chardev(backend string, id string, name string) string
 	MonitorChardev(id string, port uint, host string) string
 	Mon(chardev string, id string, mode string) string
 	Object(typeName string, props map[string]string) string
+	TlsCredsX509(id string, dir string, endpoint string, verifyPeer bool) string
 	Pidfile(file string) string
 	USB() string
 	VNC(port uint, usePasswd bool) string
@@ -189,8 +190,12 @@ func (o baseOptions) Log(enable bool) string {
 	return "-d all"
 }
 
-func (o baseOptions) RTC() string {
-	return "-rtc base=utc,clock=host,driftfix=none"
+func (o baseOptions) RTC(base, driftfix string) string {
+	return fmt.Sprintf("-rtc base=%s,clock=host,driftfix=%s", base, driftfix)
+}
+
+func (o baseOptions) NoHpet() string {
+	return "-no-hpet"
 }
 
 func (o baseOptions) Daemonize() string {
@@ -264,10 +269,13 @@ func (o baseOptions) Boot(order *string, enableMenu bool) string {
 	return fmt.Sprintf("-boot %s", strings.Join(opts, ","))
 }
 
-func (o baseOptions) BIOS(ovmfPath, homedir string) (string, error) {
-	ovmfVarsPath := path.Join(homedir, "OVMF_VARS.fd")
+// 每台虚拟机使用独立的 OVMF_VARS 副本保存 NVRAM 变量, 未指定模板时复制 OVMF.fd
+func (o baseOptions) BIOS(ovmfPath, ovmfVarsTemplatePath, ovmfVarsPath string) (string, error) {
+	if len(ovmfVarsTemplatePath) == 0 {
+		ovmfVarsTemplatePath = ovmfPath
+	}
 	if !fileutils2.Exists(ovmfVarsPath) {
-		err := procutils.NewRemoteCommandAsFarAsPossible("cp", "-f", ovmfPath, ovmfVarsPath).Run()
+		err := procutils.NewRemoteCommandAsFarAsPossible("cp", "-f", ovmfVarsTemplatePath, ovmfVarsPath).Run()
 		if err != nil {
 			return "", errors.Wrap(err, "failed copy ovmf vars")
 		}
@@ -315,6 +323,14 @@ func (o baseOptions) Object(typeName string, props map[string]string) string {
 	return opt
 }
 
+func (o baseOptions) TlsCredsX509(id string, dir string, endpoint string, verifyPeer bool) string {
+	verify := "no"
+	if verifyPeer {
+		verify = "yes"
+	}
+	return fmt.Sprintf("-object tls-creds-x509,id=%s,dir=%s,endpoint=%s,verify-peer=%s", id, dir, endpoint, verify)
+}
+
 func (o baseOptions) Pidfile(file string) string {
 	return "-pidfile " + file
 }
@@ -354,3 +370,8 @@ func newBaseOptions_aarch64() *baseOptions_aarch64 {
 func (o baseOptions_aarch64) Global() string {
 	return ""
 }
+
+// arm 平台没有 hpet 设备
+func (o baseOptions_aarch64) NoHpet() string {
+	return ""
+}
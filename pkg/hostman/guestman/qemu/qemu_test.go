@@ -15,9 +15,12 @@
 package qemu
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 )
 
 func Test_baseOptions(t *testing.T) {
@@ -38,9 +41,168 @@ func Test_baseOptions(t *testing.T) {
 		Port: 1234,
 		Mode: "readline",
 	}))
+	assert.Equal([]string{
+		"-chardev socket,id=testdev,port=1234,host=127.0.0.1,nodelay,server,nowait,tls-creds=tlsmon0",
+		"-mon chardev=testdev,id=test,mode=control",
+	}, getMonitorOptions(opt, &Monitor{
+		Id:       "test",
+		Port:     1234,
+		Mode:     "control",
+		TlsCreds: MONITOR_TLS_CREDS_ID,
+	}))
+	assert.Equal("-object tls-creds-x509,id=tlsmon0,dir=/pki,endpoint=server,verify-peer=yes", opt.TlsCredsX509(MONITOR_TLS_CREDS_ID, "/pki", "server", true))
+	assert.Equal("-object tls-creds-x509,id=tlsvnc0,dir=/pki,endpoint=server,verify-peer=no", opt.TlsCredsX509(DISPLAY_TLS_CREDS_ID, "/pki", "server", false))
 	// test device
 	assert.Equal("-device isa-applesmc,osk=ourhardworkbythesewordsguardedpleasedontsteal(c)AppleComputerInc", opt.Device("isa-applesmc,osk=ourhardworkbythesewordsguardedpleasedontsteal(c)AppleComputerInc"))
 	// test vnc
 	assert.Equal("-vnc :5900,password", opt.VNC(5900, true))
 	assert.Equal("-vnc :5900", opt.VNC(5900, false))
 }
+
+func Test_generateMemoryOption(t *testing.T) {
+	memDesc := &desc.SGuestMem{
+		Slots:  4,
+		MaxMem: 524288,
+		SizeMB: 2048,
+		Mem:    desc.NewObject("memory-backend-ram", "mem"),
+		VirtioMem: &desc.SGuestVirtioMem{
+			MemObj: desc.NewObject("memory-backend-ram", "vmemobj0"),
+			SizeMB: 4096,
+		},
+	}
+	memDesc.Mem.Options = map[string]string{"size": "2048M"}
+	memDesc.VirtioMem.MemObj.Options = map[string]string{"size": "4096M"}
+	assert.Equal(t,
+		"-m 2048M,slots=4,maxmem=524288M -object memory-backend-ram,id=mem,size=2048M -numa node,memdev=mem -object memory-backend-ram,id=vmemobj0,size=4096M",
+		generateMemoryOption(memDesc))
+
+	mem1 := desc.NewObject("memory-backend-ram", "numamem1")
+	mem1.Options = map[string]string{"size": "1024M"}
+	memDesc.Mem.Options = map[string]string{"size": "1024M"}
+	memDesc.VirtioMem = nil
+	memDesc.NumaNodes = []*desc.SGuestNumaNode{
+		{NodeId: 0, HostNode: 0, Cpus: "0-1", SizeMB: 1024, MemObj: memDesc.Mem},
+		{NodeId: 1, HostNode: 1, Cpus: "2-7", SizeMB: 1024, MemObj: mem1},
+	}
+	assert.Equal(t,
+		"-m 2048M,slots=4,maxmem=524288M -object memory-backend-ram,id=mem,size=1024M -numa node,nodeid=0,cpus=0-1,memdev=mem -object memory-backend-ram,id=numamem1,size=1024M -numa node,nodeid=1,cpus=2-7,memdev=numamem1",
+		generateMemoryOption(memDesc))
+	assert.Equal(t, 2, CountNumaNodes(generateMemoryOption(memDesc)))
+}
+
+func Test_getDiskDriveOptionThrottling(t *testing.T) {
+	disk := &desc.SGuestDisk{}
+	disk.Index = 1
+	disk.CacheMode = "none"
+	disk.AioMode = "native"
+	disk.Format = "qcow2"
+	disk.StorageType = "rbd"
+	disk.Bps = 10
+	disk.Iops = 100
+	assert.Equal(t,
+		"-drive file=$DISK_1,if=none,id=drive_1,cache=none,throttling.bps-total=10485760,throttling.iops-total=100",
+		getDiskDriveOption(newBaseOptions_x86_64(), disk, false))
+}
+
+func Test_getDiskDriveOptionAio(t *testing.T) {
+	disk := &desc.SGuestDisk{}
+	disk.Index = 0
+	disk.Format = "qcow2"
+	disk.StorageType = "local"
+	disk.CacheMode = "none"
+	disk.AioMode = "io_uring"
+	assert.Equal(t,
+		"-drive file=$DISK_0,if=none,id=drive_0,cache=none,aio=io_uring,file.locking=off",
+		getDiskDriveOption(newBaseOptions_x86_64(), disk, false))
+
+	disk.CacheMode = "writeback"
+	disk.AioMode = "native"
+	assert.Equal(t,
+		"-drive file=$DISK_0,if=none,id=drive_0,cache=writeback,aio=threads,file.locking=off",
+		getDiskDriveOption(newBaseOptions_x86_64(), disk, false))
+}
+
+func Test_generateMachineOptionSmm(t *testing.T) {
+	machineDesc := &desc.SGuestMachine{Accel: "kvm"}
+	assert.Equal(t, "-machine q35,accel=kvm", generateMachineOption("q35", machineDesc))
+	machineDesc.Smm = true
+	assert.Equal(t, "-machine q35,accel=kvm,smm=on", generateMachineOption("q35", machineDesc))
+}
+
+func Test_generateMachineOptionType(t *testing.T) {
+	machineDesc := &desc.SGuestMachine{Accel: "kvm", Type: "pc-q35-6.2"}
+	assert.Equal(t, "-machine pc-q35-6.2,accel=kvm", generateMachineOption("q35", machineDesc))
+}
+
+func Test_generateMachineOptionConfidentialVm(t *testing.T) {
+	machineDesc := &desc.SGuestMachine{Accel: "kvm", ConfidentialGuestSupport: "sev0"}
+	assert.Equal(t, "-machine q35,accel=kvm,confidential-guest-support=sev0", generateMachineOption("q35", machineDesc))
+	machineDesc = &desc.SGuestMachine{Accel: "kvm", ConfidentialGuestSupport: "tdx0", KernelIrqchip: "split"}
+	assert.Equal(t, "-machine q35,accel=kvm,confidential-guest-support=tdx0,kernel-irqchip=split", generateMachineOption("q35", machineDesc))
+}
+
+func Test_generateClockOptions(t *testing.T) {
+	assert.Equal(t, []string{"-rtc base=utc,clock=host,driftfix=none"}, generateClockOptions(newBaseOptions_x86_64(), nil))
+	clock := &ClockOption{Base: "localtime", Driftfix: "slew", DisableHpet: true}
+	assert.Equal(t, []string{"-rtc base=localtime,clock=host,driftfix=slew", "-no-hpet"}, generateClockOptions(newBaseOptions_x86_64(), clock))
+	assert.Equal(t, []string{"-rtc base=localtime,clock=host,driftfix=slew"}, generateClockOptions(newBaseOptions_aarch64(), clock))
+}
+
+func Test_usbRedirOptions(t *testing.T) {
+	usbredir := &desc.UsbRedirctDesc{
+		EHCI1: &desc.UsbController{PCIDevice: desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "ich9-usb-ehci1", "usbspice")},
+		UHCI1: &desc.UsbController{PCIDevice: desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "ich9-usb-uhci1", "uhci1")},
+		UHCI2: &desc.UsbController{PCIDevice: desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "ich9-usb-uhci2", "uhci2")},
+		UHCI3: &desc.UsbController{PCIDevice: desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "ich9-usb-uhci3", "uhci3")},
+	}
+	assert.Equal(t, 4, len(usbRedirOptions(usbredir)))
+
+	usbredir.UsbRedirDev1 = &desc.UsbRedir{Id: "usbredirdev1", Source: desc.NewCharDev("spicevmc", "usbredirchardev1", "usbredir")}
+	usbredir.ExtraUsbRedirDevs = []*desc.UsbRedir{
+		{Id: "usbredirdev3", Source: desc.NewCharDev("spicevmc", "usbredirchardev3", "usbredir")},
+	}
+	opts := usbRedirOptions(usbredir)
+	assert.Equal(t, 8, len(opts))
+	assert.Equal(t, "-device usb-redir,chardev=usbredirchardev3,id=usbredirdev3", opts[7])
+}
+
+func Test_generateWatchdogOptions(t *testing.T) {
+	pci := desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "i6300esb", "watchdog0")
+	pci.PCIAddr = &desc.PCIAddr{Bus: 0, Slot: 6}
+	opts := generateWatchdogOptions(&desc.SGuestWatchdog{Pci: pci, Id: "watchdog0", Model: "i6300esb", Action: "poweroff"})
+	assert.Equal(t, 2, len(opts))
+	assert.Equal(t, true, strings.HasPrefix(opts[0], "-device i6300esb,id=watchdog0,"))
+	assert.Equal(t, "-watchdog-action poweroff", opts[1])
+
+	opts = generateWatchdogOptions(&desc.SGuestWatchdog{Id: "watchdog0", Model: "ib700", Action: "reset"})
+	assert.Equal(t, []string{"-device ib700,id=watchdog0", "-watchdog-action reset"}, opts)
+}
+
+func Test_generateNicOptionsSriovFailover(t *testing.T) {
+	newNic := func(ifname, driver string) *desc.SGuestNetwork {
+		nic := new(desc.SGuestNetwork)
+		nic.Ifname = ifname
+		nic.Driver = driver
+		nic.Mac = "00:22:11:22:33:44"
+		nic.UpscriptPath = "/tmp/if-up.sh"
+		nic.DownscriptPath = "/tmp/if-down.sh"
+		return nic
+	}
+	vf := newNic("vnet-vf", "vfio-pci")
+	standby := newNic("vnet-standby", "vfio-pci")
+	standby.Pci = desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "virtio-net-pci", "netdev-vnet-standby")
+	standby.Pci.PCIAddr = &desc.PCIAddr{Bus: 0, Slot: 3}
+	standby.Pci.Options = map[string]string{"failover": "on"}
+
+	input := &GenerateStartOptionsInput{
+		GuestDesc:    &desc.SGuestDesc{},
+		IsKVMSupport: true,
+	}
+	input.GuestDesc.Nics = []*desc.SGuestNetwork{vf, standby}
+	opts, err := generateNicOptions(nil, input)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(opts))
+	assert.Equal(t, true, strings.HasPrefix(opts[0], "-netdev type=tap,id=vnet-standby,ifname=vnet-standby,vhost=on"))
+	assert.Equal(t, true, strings.HasPrefix(opts[1], "-device virtio-net-pci,id=netdev-vnet-standby,bus=pci.0,addr=0x03,failover=on,netdev=vnet-standby,mac=00:22:11:22:33:44"))
+	assert.Equal(t, "vfio-pci", standby.Driver)
+}
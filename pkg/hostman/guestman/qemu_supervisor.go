@@ -0,0 +1,302 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	QEMU_RESTART_POLICY_NO         = "no"
+	QEMU_RESTART_POLICY_ON_FAILURE = "on-failure"
+
+	// 启动失败时最多从日志中截取的 qemu 输出
+	qemuStartErrorMaxBytes = 4096
+)
+
+// SQemuStartError qemu 在 monitor 可用之前退出时返回的错误
+type SQemuStartError struct {
+	ExitCode int
+	Signal   string
+	Stderr   string
+}
+
+func (e *SQemuStartError) Error() string {
+	var status string
+	if len(e.Signal) > 0 {
+		status = fmt.Sprintf("killed by signal %s", e.Signal)
+	} else {
+		status = fmt.Sprintf("exit code %d", e.ExitCode)
+	}
+	if len(e.Stderr) == 0 {
+		return fmt.Sprintf("qemu exited during startup: %s", status)
+	}
+	return fmt.Sprintf("qemu exited during startup: %s: %s", status, e.Stderr)
+}
+
+// sQemuProcess 由 hostman 直接拉起并等待回收的 qemu 进程
+// hostman 重启后接管的 qemu 进程没有对应的 sQemuProcess, 不做崩溃重启
+type sQemuProcess struct {
+	cmdline   []string
+	logPath   string
+	logOffset int64
+
+	exited  chan struct{}
+	waitErr error
+
+	lock       sync.Mutex
+	running    bool
+	expectExit bool
+}
+
+func newQemuProcess(cmdline []string, logPath string) *sQemuProcess {
+	return &sQemuProcess{
+		cmdline: cmdline,
+		logPath: logPath,
+		exited:  make(chan struct{}),
+	}
+}
+
+func (p *sQemuProcess) setRunning() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.running = true
+}
+
+func (p *sQemuProcess) isRunning() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.running
+}
+
+func (p *sQemuProcess) markExpectExit() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.expectExit = true
+}
+
+func (p *sQemuProcess) isExpectExit() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.expectExit
+}
+
+func (p *sQemuProcess) waitStatus() (syscall.WaitStatus, bool) {
+	// exec.ExitError 和 executor 的 ExitError 都通过 Sys() 返回 WaitStatus
+	e, ok := p.waitErr.(interface{ Sys() interface{} })
+	if !ok {
+		return 0, false
+	}
+	ws, ok := e.Sys().(syscall.WaitStatus)
+	return ws, ok
+}
+
+// 非零退出或被信号杀死视为崩溃
+func (p *sQemuProcess) isCrashed() bool {
+	if p.waitErr == nil {
+		return false
+	}
+	ws, ok := p.waitStatus()
+	if !ok {
+		return true
+	}
+	return ws.Signaled() || ws.ExitStatus() != 0
+}
+
+func (p *sQemuProcess) readOutput() string {
+	f, err := os.Open(p.logPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	offset := p.logOffset
+	if stat.Size()-offset > qemuStartErrorMaxBytes {
+		offset = stat.Size() - qemuStartErrorMaxBytes
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return ""
+	}
+	output, _ := ioutil.ReadAll(f)
+	return string(bytes.TrimSpace(output))
+}
+
+func (p *sQemuProcess) startError() error {
+	ret := &SQemuStartError{
+		ExitCode: -1,
+		Stderr:   p.readOutput(),
+	}
+	if ws, ok := p.waitStatus(); ok {
+		if ws.Signaled() {
+			ret.Signal = ws.Signal().String()
+		} else {
+			ret.ExitCode = ws.ExitStatus()
+		}
+	} else if p.waitErr == nil {
+		ret.ExitCode = 0
+	} else if len(ret.Stderr) == 0 {
+		ret.Stderr = p.waitErr.Error()
+	}
+	return ret
+}
+
+// startvm 脚本只负责准备环境并输出 qemu 命令行
+func (s *SKVMGuestInstance) getQemuStartCmdline() ([]string, error) {
+	cmd := procutils.NewRemoteCommandAsFarAsPossible("bash", s.GetStartScriptPath())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "stdout pipe")
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "start")
+	}
+	output, err := ioutil.ReadAll(stdout)
+	if err != nil {
+		cmd.Wait()
+		return nil, errors.Wrap(err, "read output")
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, errors.Wrapf(err, "run %s", s.GetStartScriptPath())
+	}
+	cmdline := strings.Fields(string(output))
+	if len(cmdline) == 0 {
+		return nil, errors.Errorf("empty qemu cmdline from %s", s.GetStartScriptPath())
+	}
+	return cmdline, nil
+}
+
+func (s *SKVMGuestInstance) startQemuProcess() (*sQemuProcess, error) {
+	cmdline, err := s.getQemuStartCmdline()
+	if err != nil {
+		return nil, errors.Wrap(err, "get qemu cmdline")
+	}
+	proc := newQemuProcess(cmdline, s.LogFilePath())
+
+	logFile, err := os.OpenFile(proc.logPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open log file %s", proc.logPath)
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "%s Run command: %s\n", time.Now().Format("2006-01-02 15:04:05"), cmdline)
+	if stat, err := logFile.Stat(); err == nil {
+		proc.logOffset = stat.Size()
+	}
+
+	var wait func() error
+	if procutils.IsRemoteExecutor() {
+		// hostman 运行在容器中, 由 executor 在宿主机拉起, 输出重定向到日志文件
+		// 不使用管道, hostman 重启后 qemu 不会因为 SIGPIPE 退出
+		script := fmt.Sprintf(`exec "$0" "$@" </dev/null >>'%s' 2>&1`, proc.logPath)
+		args := append([]string{"-c", script}, cmdline...)
+		cmd := procutils.NewRemoteCommandAsFarAsPossible("sh", args...)
+		if err := cmd.Start(); err != nil {
+			return nil, errors.Wrap(err, "start qemu")
+		}
+		wait = cmd.Wait
+	} else {
+		cmd := exec.Command(cmdline[0], cmdline[1:]...)
+		cmd.Dir = "/"
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err := cmd.Start(); err != nil {
+			return nil, errors.Wrap(err, "start qemu")
+		}
+		wait = cmd.Wait
+	}
+
+	s.qemuProc = proc
+	go func() {
+		proc.waitErr = wait()
+		close(proc.exited)
+		s.onQemuProcessExited(proc)
+	}()
+	return proc, nil
+}
+
+func (s *SKVMGuestInstance) markQemuExitExpected() {
+	if proc := s.qemuProc; proc != nil {
+		proc.markExpectExit()
+	}
+}
+
+func (s *SKVMGuestInstance) onQemuProcessExited(proc *sQemuProcess) {
+	// 启动阶段的退出由 scriptStart 返回结构化错误
+	if !proc.isRunning() {
+		return
+	}
+	if proc.isExpectExit() || s.IsStopping() || !proc.isCrashed() {
+		log.Infof("Guest %s qemu process exited: %v", s.GetName(), proc.waitErr)
+		return
+	}
+	log.Errorf("Guest %s qemu process crashed: %v, output: %s", s.GetName(), proc.waitErr, proc.readOutput())
+
+	if options.HostOptions.QemuRestartPolicy != QEMU_RESTART_POLICY_ON_FAILURE {
+		return
+	}
+	if s.LiveMigrateDestPort != nil {
+		return
+	}
+	if _, ok := s.manager.GetServer(s.Id); !ok {
+		return
+	}
+	window := time.Duration(options.HostOptions.QemuRestartWindowSeconds) * time.Second
+	history, ok := qemuRestartAllowed(s.qemuRestarts, time.Now(), window, options.HostOptions.QemuRestartMaxRetries)
+	if !ok {
+		log.Errorf("Guest %s qemu crashed %d times in %s, give up restart", s.GetName(), len(history), window)
+		return
+	}
+	s.qemuRestarts = history
+
+	backoff := time.Duration(1<<uint(len(history)-1)) * time.Second
+	log.Infof("Guest %s restart crashed qemu after %s", s.GetName(), backoff)
+	time.Sleep(backoff)
+	if s.qemuProc != proc || s.IsRunning() || s.IsStopping() {
+		return
+	}
+	s.DirtyServerRequestStart()
+}
+
+// 统计窗口期内的重启次数, 返回追加本次重启后的记录
+func qemuRestartAllowed(history []time.Time, now time.Time, window time.Duration, maxRetries int) ([]time.Time, bool) {
+	ret := make([]time.Time, 0, len(history)+1)
+	for _, t := range history {
+		if now.Sub(t) < window {
+			ret = append(ret, t)
+		}
+	}
+	if len(ret) >= maxRetries {
+		return ret, false
+	}
+	return append(ret, now), true
+}
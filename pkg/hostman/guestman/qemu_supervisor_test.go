@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestQemuRestartAllowed(t *testing.T) {
+	now := time.Now()
+	window := 10 * time.Minute
+	history := []time.Time{now.Add(-20 * time.Minute), now.Add(-5 * time.Minute)}
+
+	ret, ok := qemuRestartAllowed(history, now, window, 3)
+	if !ok || len(ret) != 2 {
+		t.Fatalf("expect restart allowed with 2 records, got %v %d", ok, len(ret))
+	}
+	ret, ok = qemuRestartAllowed(ret, now, window, 2)
+	if ok || len(ret) != 2 {
+		t.Fatalf("expect restart denied with 2 records, got %v %d", ok, len(ret))
+	}
+}
+
+func TestSQemuProcessStartError(t *testing.T) {
+	cases := []struct {
+		cmd     []string
+		crashed bool
+		want    string
+	}{
+		{[]string{"true"}, false, "qemu exited during startup: exit code 0"},
+		{[]string{"sh", "-c", "exit 3"}, true, "qemu exited during startup: exit code 3"},
+		{[]string{"sh", "-c", "kill -9 $$"}, true, "qemu exited during startup: killed by signal killed"},
+	}
+	for _, c := range cases {
+		p := newQemuProcess(c.cmd, "/nonexistent")
+		p.waitErr = exec.Command(c.cmd[0], c.cmd[1:]...).Run()
+		if p.isCrashed() != c.crashed {
+			t.Errorf("%v crashed expect %v", c.cmd, c.crashed)
+		}
+		if got := p.startError().Error(); got != c.want {
+			t.Errorf("%v want %q got %q", c.cmd, c.want, got)
+		}
+	}
+}
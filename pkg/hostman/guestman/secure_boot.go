@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
+)
+
+const (
+	OVMF_VARS_FILE         = "OVMF_VARS.fd"
+	OVMF_SECBOOT_VARS_FILE = "OVMF_VARS.secboot.fd"
+)
+
+// secure boot 仅支持 x86 q35 + UEFI, 需要 smm 保护 NVRAM 变量
+func (s *SKVMGuestInstance) isSecureBootEnabled() bool {
+	if s.Desc.Metadata[api.VM_METADATA_ENABLE_SECURE_BOOT] != "true" {
+		return false
+	}
+	return s.Desc.Bios == qemu.BIOS_UEFI && !s.manager.host.IsAarch64() &&
+		s.getMachine() == api.VM_MACHINE_TYPE_Q35
+}
+
+// 开启 secure boot 后使用带有已注册密钥的变量模板, 与普通 UEFI 变量文件分开保存
+func (s *SKVMGuestInstance) getOvmfVarsPath() string {
+	if s.isSecureBootEnabled() {
+		return path.Join(s.HomeDir(), OVMF_SECBOOT_VARS_FILE)
+	}
+	return path.Join(s.HomeDir(), OVMF_VARS_FILE)
+}
+
+// PrepareNvram read per guest OVMF varstore for transferring to migrate target host
+func (s *SKVMGuestInstance) PrepareNvram() (map[string]string, error) {
+	ret := map[string]string{}
+	for _, name := range []string{OVMF_VARS_FILE, OVMF_SECBOOT_VARS_FILE} {
+		content, err := ioutil.ReadFile(path.Join(s.HomeDir(), name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "read %s", name)
+		}
+		ret[name] = base64.StdEncoding.EncodeToString(content)
+	}
+	return ret, nil
+}
+
+func (s *SKVMGuestInstance) WriteNvram(nvram map[string]string) error {
+	for name, content := range nvram {
+		if name != OVMF_VARS_FILE && name != OVMF_SECBOOT_VARS_FILE {
+			return errors.Errorf("invalid nvram file name %q", name)
+		}
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return errors.Wrapf(err, "decode %s", name)
+		}
+		if err := ioutil.WriteFile(path.Join(s.HomeDir(), name), data, 0600); err != nil {
+			return errors.Wrapf(err, "write %s", name)
+		}
+	}
+	return nil
+}
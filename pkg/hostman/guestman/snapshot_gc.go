@@ -0,0 +1,113 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// 最近修改过的文件可能属于正在进行中的快照任务, 不做清理
+const snapshotGcMinAge = time.Hour
+
+// 磁盘当前 backing chain 上的文件, 即使没有快照记录也不能删除
+func getDiskBackingChain(diskPath string) map[string]bool {
+	chain := map[string]bool{}
+	for p := diskPath; len(p) > 0 && !chain[p]; {
+		chain[p] = true
+		img, err := qemuimg.NewQemuImage(p)
+		if err != nil {
+			log.Errorf("open image %s: %v", p, err)
+			break
+		}
+		p = img.BackFilePath
+	}
+	return chain
+}
+
+func collectOrphanFiles(dir string, keep []string, inUse map[string]bool) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("read dir %s: %v", dir, err)
+		}
+		return nil
+	}
+	ret := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || utils.IsInStringArray(entry.Name(), keep) {
+			continue
+		}
+		fullPath := filepath.Join(dir, entry.Name())
+		if inUse[fullPath] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < snapshotGcMinAge {
+			continue
+		}
+		ret = append(ret, fullPath)
+	}
+	return ret
+}
+
+// SnapshotGc 删除磁盘快照目录及内存快照目录中已无记录引用的 overlay 文件
+func (m *SGuestManager) SnapshotGc(sid string, input *api.ServerSnapshotGcRequest) (*api.ServerSnapshotGcOutput, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found guest by id %s", sid)
+	}
+	orphans := []string{}
+	for _, d := range guest.Desc.Disks {
+		disk, err := storageman.GetManager().GetDiskByPath(d.Path)
+		if err != nil {
+			log.Errorf("failed find disk by path %s: %v", d.Path, err)
+			continue
+		}
+		keep, ok := input.Disks[disk.GetId()]
+		if !ok {
+			// compute 未提供该磁盘的快照列表, 跳过以免误删
+			continue
+		}
+		snapshotDir := disk.GetSnapshotDir()
+		if len(snapshotDir) == 0 {
+			continue
+		}
+		orphans = append(orphans, collectOrphanFiles(snapshotDir, keep, getDiskBackingChain(disk.GetPath()))...)
+	}
+	memSnapDir := filepath.Join(options.HostOptions.MemorySnapshotsPath, sid)
+	orphans = append(orphans, collectOrphanFiles(memSnapDir, input.MemorySnapshots, nil)...)
+
+	output := &api.ServerSnapshotGcOutput{Removed: []string{}}
+	for _, f := range orphans {
+		if err := os.Remove(f); err != nil {
+			return output, errors.Wrapf(err, "remove %s", f)
+		}
+		log.Infof("guest %s snapshot gc removed orphan file %s", sid, f)
+		output.Removed = append(output.Removed, f)
+	}
+	return output, nil
+}
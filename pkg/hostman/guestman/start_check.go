@@ -0,0 +1,218 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// SGuestStartCheckError 启动前资源检查失败, 返回给 compute 的结构化失败列表
+type SGuestStartCheckError struct {
+	Failures []host.GuestStartCheckFailure
+}
+
+func (e *SGuestStartCheckError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f.Item, f.Reason))
+	}
+	return fmt.Sprintf("start check failed: %s", strings.Join(msgs, "; "))
+}
+
+func newStartCheckError(failures []host.GuestStartCheckFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return &SGuestStartCheckError{Failures: failures}
+}
+
+// 启动前检查与端口无关的资源, 这些失败重试也无法恢复
+func (s *SKVMGuestInstance) validateStartResources() error {
+	failures := []host.GuestStartCheckFailure{}
+	failures = append(failures, s.checkStartHugepages()...)
+	failures = append(failures, s.checkStartBridges()...)
+	failures = append(failures, s.checkStartOvmf()...)
+	failures = append(failures, s.checkStartCgroup()...)
+	failures = append(failures, s.checkStartDisks()...)
+	failures = append(failures, s.checkStartIsolatedDevices()...)
+	return newStartCheckError(failures)
+}
+
+// vnc 及 monitor 端口可能被宿主机其它进程占用, 失败时换端口重试
+func (s *SKVMGuestInstance) validateStartPorts(vncPort int) error {
+	failures := []host.GuestStartCheckFailure{}
+	ports := []struct {
+		addr string
+		port int
+	}{
+		{"0.0.0.0", VNC_PORT_BASE + vncPort},
+		{"127.0.0.1", s.GetHmpMonitorPort(vncPort)},
+		{"127.0.0.1", s.GetQmpMonitorPort(vncPort)},
+	}
+	for _, p := range ports {
+		if netutils2.IsTcpPortUsed(p.addr, p.port) {
+			failures = append(failures, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_VNC_PORT,
+				Reason:     fmt.Sprintf("port %s:%d is in use", p.addr, p.port),
+				Suggestion: "release the port or retry start later",
+			})
+		}
+	}
+	return newStartCheckError(failures)
+}
+
+func (s *SKVMGuestInstance) checkStartHugepages() []host.GuestStartCheckFailure {
+	if !s.manager.host.IsHugepagesEnabled() {
+		return nil
+	}
+	if err := s.checkHugepages(); err != nil {
+		return []host.GuestStartCheckFailure{{
+			Item:       host.GUEST_START_CHECK_HUGEPAGE,
+			Reason:     fmt.Sprintf("insufficient hugepages for %dMB memory: %s", s.Desc.Mem, err),
+			Suggestion: "stop other guests or enlarge host hugepage pool",
+		}}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) checkStartBridges() []host.GuestStartCheckFailure {
+	ret := []host.GuestStartCheckFailure{}
+	for _, nic := range s.Desc.Nics {
+		if nic.Driver == api.NETWORK_DRIVER_VFIO || len(nic.Bridge) == 0 {
+			continue
+		}
+		if !fileutils2.Exists(path.Join("/sys/class/net", nic.Bridge)) {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_BRIDGE,
+				Reason:     fmt.Sprintf("bridge %s of nic %s not exists", nic.Bridge, nic.Mac),
+				Suggestion: "check host network config and restart host agent",
+			})
+		}
+	}
+	return ret
+}
+
+func (s *SKVMGuestInstance) checkStartOvmf() []host.GuestStartCheckFailure {
+	if s.Desc.Bios != qemu.BIOS_UEFI {
+		return nil
+	}
+	var files []string
+	if s.isSecureBootEnabled() {
+		files = []string{options.HostOptions.OvmfSecbootCodePath, options.HostOptions.OvmfSecbootVarsPath}
+	} else {
+		files = []string{options.HostOptions.OvmfPath, options.HostOptions.OvmfVarsPath}
+	}
+	if fw := s.getConfidentialVmFirmware(); len(fw) > 0 {
+		files = append(files, fw)
+	}
+	return checkFirmwareFiles(files)
+}
+
+func checkFirmwareFiles(files []string) []host.GuestStartCheckFailure {
+	ret := []host.GuestStartCheckFailure{}
+	for _, f := range files {
+		if len(f) == 0 {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_OVMF,
+				Reason:     "uefi firmware path not configured",
+				Suggestion: "set ovmf path in host options",
+			})
+		} else if !fileutils2.Exists(f) {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_OVMF,
+				Reason:     fmt.Sprintf("uefi firmware %s not found", f),
+				Suggestion: "install ovmf package on host",
+			})
+		}
+	}
+	return ret
+}
+
+func (s *SKVMGuestInstance) checkStartCgroup() []host.GuestStartCheckFailure {
+	if options.HostOptions.DisableSetCgroup {
+		return nil
+	}
+	ret := []host.GuestStartCheckFailure{}
+	isMounted := cgrouputils.ModuleIsMounted
+	if cgrouputils.IsCgroupV2() {
+		controllers := strings.Fields(cgrouputils.NewCgroupV2Slice("").GetParam(cgrouputils.CGROUP_V2_CONTROLLERS))
+		isMounted = func(module string) bool {
+			return utils.IsInStringArray(module, controllers)
+		}
+	}
+	for _, module := range []string{"cpu", "cpuset"} {
+		if !isMounted(module) {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_CGROUP,
+				Reason:     fmt.Sprintf("cgroup module %s not mounted", module),
+				Suggestion: "mount cgroup or set disable_set_cgroup in host options",
+			})
+		}
+	}
+	return ret
+}
+
+func (s *SKVMGuestInstance) checkStartDisks() []host.GuestStartCheckFailure {
+	ret := []host.GuestStartCheckFailure{}
+	for _, disk := range s.Desc.Disks {
+		// 非本地路径(如 rbd)由存储驱动保证
+		if !strings.HasPrefix(disk.Path, "/") {
+			continue
+		}
+		fi, err := os.Stat(disk.Path)
+		if err != nil {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_DISK,
+				Reason:     fmt.Sprintf("disk %d image %s not accessible: %s", disk.Index, disk.Path, err),
+				Suggestion: "check storage mount or sync disk status",
+			})
+			continue
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		img, err := qemuimg.NewQemuImage(disk.Path)
+		if err != nil {
+			log.Errorf("qemu-img info %s: %s", disk.Path, err)
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_DISK,
+				Reason:     fmt.Sprintf("disk %d image %s is corrupted: %s", disk.Index, disk.Path, err),
+				Suggestion: "repair image with qemu-img check or restore from snapshot",
+			})
+		} else if !img.IsValid() {
+			ret = append(ret, host.GuestStartCheckFailure{
+				Item:       host.GUEST_START_CHECK_DISK,
+				Reason:     fmt.Sprintf("disk %d image %s is invalid", disk.Index, disk.Path),
+				Suggestion: "repair image with qemu-img check or restore from snapshot",
+			})
+		}
+	}
+	return ret
+}
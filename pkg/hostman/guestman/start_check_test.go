@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/apis/host"
+)
+
+func TestCheckFirmwareFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ovmf")
+	if err != nil {
+		t.Fatalf("create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	code := filepath.Join(dir, "OVMF_CODE.fd")
+	if err := ioutil.WriteFile(code, []byte("fw"), 0644); err != nil {
+		t.Fatalf("write firmware: %s", err)
+	}
+
+	failures := checkFirmwareFiles([]string{code, filepath.Join(dir, "OVMF_VARS.fd"), ""})
+	if len(failures) != 2 {
+		t.Fatalf("want 2 failures, got %#v", failures)
+	}
+	for _, f := range failures {
+		if f.Item != host.GUEST_START_CHECK_OVMF || len(f.Suggestion) == 0 {
+			t.Errorf("unexpected failure %#v", f)
+		}
+	}
+}
+
+func TestSGuestStartCheckError(t *testing.T) {
+	if err := newStartCheckError(nil); err != nil {
+		t.Fatalf("want nil error, got %v", err)
+	}
+	err := newStartCheckError([]host.GuestStartCheckFailure{
+		{Item: host.GUEST_START_CHECK_BRIDGE, Reason: "bridge br0 not exists"},
+		{Item: host.GUEST_START_CHECK_DISK, Reason: "disk 0 image /a not accessible"},
+	})
+	want := "start check failed: bridge: bridge br0 not exists; disk: disk 0 image /a not accessible"
+	if err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
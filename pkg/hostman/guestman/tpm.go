@@ -0,0 +1,134 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func (s *SKVMGuestInstance) isTpmEnabled() bool {
+	return s.Desc.Metadata[api.VM_METADATA_ENABLE_TPM] == "true"
+}
+
+func (s *SKVMGuestInstance) getTpmStateDir() string {
+	return path.Join(s.HomeDir(), "tpm")
+}
+
+func (s *SKVMGuestInstance) getTpmSocketPath() string {
+	return path.Join(s.HomeDir(), "swtpm.sock")
+}
+
+func (s *SKVMGuestInstance) getTpmPidPath() string {
+	return path.Join(s.HomeDir(), "swtpm.pid")
+}
+
+func (s *SKVMGuestInstance) initTpmDesc() {
+	if !s.isTpmEnabled() {
+		s.Desc.Tpm = nil
+		return
+	}
+	devType := "tpm-crb"
+	if s.manager.host.IsAarch64() {
+		devType = "tpm-tis-device"
+	}
+	s.Desc.Tpm = &desc.SGuestTpm{
+		Id:      "tpm0",
+		DevType: devType,
+		Socket: &desc.CharDev{
+			Backend: "socket",
+			Id:      "chrtpm",
+			Options: map[string]string{
+				"path": s.getTpmSocketPath(),
+			},
+		},
+		StateDir: s.getTpmStateDir(),
+	}
+}
+
+// swtpm is started before qemu and terminates itself when qemu disconnects
+func (s *SKVMGuestInstance) generateTpmStartScript() string {
+	cmd := s.generateTpmStopScript()
+	cmd += fmt.Sprintf("mkdir -p %s\n", s.Desc.Tpm.StateDir)
+	cmd += fmt.Sprintf("%s socket --tpm2 --tpmstate dir=%s --ctrl type=unixio,path=%s --pid file=%s --log file=%s,level=1 --terminate --daemon\n",
+		options.HostOptions.SwtpmPath, s.Desc.Tpm.StateDir, s.getTpmSocketPath(), s.getTpmPidPath(), path.Join(s.HomeDir(), "swtpm.log"))
+	return cmd
+}
+
+// tpm state dir is kept, it must survive guest restart
+func (s *SKVMGuestInstance) generateTpmStopScript() string {
+	pidFile := s.getTpmPidPath()
+	cmd := fmt.Sprintf("if [ -f %s ]; then\n", pidFile)
+	cmd += fmt.Sprintf("  kill -9 $(cat %s) > /dev/null 2>&1\n", pidFile)
+	cmd += fmt.Sprintf("  rm -f %s\n", pidFile)
+	cmd += "fi\n"
+	cmd += fmt.Sprintf("rm -f %s\n", s.getTpmSocketPath())
+	return cmd
+}
+
+// PrepareTpmState read swtpm state files for transferring to migrate target host
+func (s *SKVMGuestInstance) PrepareTpmState() (map[string]string, error) {
+	stateDir := s.getTpmStateDir()
+	files, err := ioutil.ReadDir(stateDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "read dir %s", stateDir)
+	}
+	ret := map[string]string{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		content, err := ioutil.ReadFile(path.Join(stateDir, f.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", f.Name())
+		}
+		ret[f.Name()] = base64.StdEncoding.EncodeToString(content)
+	}
+	return ret, nil
+}
+
+func (s *SKVMGuestInstance) WriteTpmState(state map[string]string) error {
+	stateDir := s.getTpmStateDir()
+	output, err := procutils.NewCommand("mkdir", "-p", stateDir).Output()
+	if err != nil {
+		return errors.Wrapf(err, "mkdir %s failed: %s", stateDir, output)
+	}
+	for name, content := range state {
+		if name != path.Base(name) {
+			return errors.Errorf("invalid tpm state file name %q", name)
+		}
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return errors.Wrapf(err, "decode %s", name)
+		}
+		if err := ioutil.WriteFile(path.Join(stateDir, name), data, 0600); err != nil {
+			return errors.Wrapf(err, "write %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/cpupin"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils/hardware"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
+)
+
+const vcpuPinReconcileInterval = time.Minute
+
+func (s *SKVMGuestInstance) getCpuPinPolicy() string {
+	return s.Desc.Metadata[compute.VM_METADATA_CPU_PIN_POLICY]
+}
+
+// getVcpuPinPolicy 返回生效的绑定策略, 有 NUMA 节点的虚机至少在对应宿主机节点内浮动
+func (s *SKVMGuestInstance) getVcpuPinPolicy() string {
+	policy := s.getCpuPinPolicy()
+	if len(policy) == 0 && s.hasNumaNodes() {
+		policy = compute.VM_CPU_PIN_POLICY_SHARED
+	}
+	return policy
+}
+
+func (s *SKVMGuestInstance) needVcpuPinReconcile() bool {
+	return len(s.getVcpuPinPolicy()) > 0 || s.Desc.VcpuPin != nil
+}
+
+// getVcpuPins 返回当前的 vCPU 绑定, vCPU 数已变化或记录无效时返回 nil
+func (s *SKVMGuestInstance) getVcpuPins() []cpuset.CPUSet {
+	if s.Desc.VcpuPin == nil || len(s.Desc.VcpuPin.Vcpus) != int(s.Desc.Cpu) {
+		return nil
+	}
+	pins := make([]cpuset.CPUSet, len(s.Desc.VcpuPin.Vcpus))
+	for i, pin := range s.Desc.VcpuPin.Vcpus {
+		set, err := cpuset.Parse(pin.Pcpus)
+		if err != nil || pin.Vcpu != i {
+			return nil
+		}
+		pins[i] = set
+	}
+	return pins
+}
+
+func (s *SKVMGuestInstance) queryVcpuThreads() ([]monitor.CpuInfoFast, error) {
+	if s.Monitor == nil {
+		return nil, errors.Errorf("monitor not connected")
+	}
+	type result struct {
+		cpus []monitor.CpuInfoFast
+		err  string
+	}
+	ch := make(chan result, 1)
+	s.Monitor.QueryCpus(func(cpus []monitor.CpuInfoFast, err string) {
+		ch <- result{cpus, err}
+	})
+	select {
+	case res := <-ch:
+		if len(res.err) > 0 {
+			return nil, errors.Errorf("query cpus: %s", res.err)
+		}
+		return res.cpus, nil
+	case <-time.After(30 * time.Second):
+		return nil, errors.Errorf("query cpus timeout")
+	}
+}
+
+func setThreadAffinity(tid int, cpus cpuset.CPUSet) error {
+	set := unix.CPUSet{}
+	for _, cpu := range cpus.ToSlice() {
+		set.Set(cpu)
+	}
+	return unix.SchedSetaffinity(tid, &set)
+}
+
+// setVcpuAffinity 设置 vCPU 线程的 CPU 亲和性, pins 下标为 vCPU 序号,
+// emulator 不为空时 qemu 的其它线程(主线程, iothread 等)绑定到 emulator
+func (s *SKVMGuestInstance) setVcpuAffinity(pins []cpuset.CPUSet, emulator cpuset.CPUSet) error {
+	threads, err := s.queryVcpuThreads()
+	if err != nil {
+		return err
+	}
+	vcpuTids := map[int]bool{}
+	for _, thread := range threads {
+		if int(thread.CpuIndex) >= len(pins) {
+			return errors.Errorf("vcpu %d has no pin", thread.CpuIndex)
+		}
+		if err := setThreadAffinity(int(thread.ThreadId), pins[thread.CpuIndex]); err != nil {
+			return errors.Wrapf(err, "set vcpu %d thread %d affinity", thread.CpuIndex, thread.ThreadId)
+		}
+		vcpuTids[int(thread.ThreadId)] = true
+	}
+	if emulator.IsEmpty() {
+		return nil
+	}
+	pid := s.GetPid()
+	tasks, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return errors.Wrapf(err, "list qemu %d threads", pid)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil || vcpuTids[tid] {
+			continue
+		}
+		if err := setThreadAffinity(tid, emulator); err != nil && err != unix.ESRCH {
+			return errors.Wrapf(err, "set emulator thread %d affinity", tid)
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) applyVcpuPins(policy string, pins []cpuset.CPUSet) error {
+	if len(s.GetCgroupName()) > 0 {
+		// 线程亲和性受 cgroup cpuset 限制, 先将 cgroup cpuset 设为绑定 CPU 的并集
+		cpus := cpuset.NewCPUSet().UnionAll(pins)
+		if _, err := s.CPUSet(context.Background(), &compute.ServerCPUSetInput{CPUS: cpus.ToSlice()}); err != nil {
+			return errors.Wrap(err, "set cgroup cpuset")
+		}
+	}
+	if err := s.setVcpuAffinity(pins, cpuset.NewCPUSet()); err != nil {
+		return err
+	}
+	vcpuPin := &desc.SGuestVcpuPin{Policy: policy}
+	for i := range pins {
+		vcpuPin.Vcpus = append(vcpuPin.Vcpus, desc.SVcpuPin{Vcpu: i, Pcpus: pins[i].String()})
+	}
+	s.Desc.VcpuPin = vcpuPin
+	return s.SaveLiveDesc(s.Desc)
+}
+
+func (s *SKVMGuestInstance) removeVcpuPins(pool cpuset.CPUSet) error {
+	s.setCgroupCPUSet()
+	pins := make([]cpuset.CPUSet, s.Desc.Cpu)
+	for i := range pins {
+		pins[i] = pool
+	}
+	emulator := cpuset.NewCPUSet()
+	if len(s.Desc.VcpuPin.EmulatorPcpus) > 0 {
+		emulator = pool
+	}
+	if err := s.setVcpuAffinity(pins, emulator); err != nil {
+		return err
+	}
+	s.Desc.VcpuPin = nil
+	return s.SaveLiveDesc(s.Desc)
+}
+
+// getEmulatorCpus 返回 qemu 主线程及 iothread 应绑定的 CPU, 为空表示不绑定
+// 宿主机指定了 emulator CPU 时所有绑定 vCPU 的虚机均使用, 否则仅独占绑定的虚机避开 vCPU 所在 CPU
+func (s *SKVMGuestInstance) getEmulatorCpus(allocator *cpupin.SAllocator, pins []cpuset.CPUSet) (cpuset.CPUSet, error) {
+	if cpus := options.HostOptions.EmulatorThreadCpus; len(cpus) > 0 {
+		return cpuset.Parse(cpus)
+	}
+	if s.Desc.VcpuPin.Policy != compute.VM_CPU_PIN_POLICY_DEDICATED {
+		return cpuset.NewCPUSet(), nil
+	}
+	vcpus := cpuset.NewCPUSet().UnionAll(pins)
+	return allocator.EmulatorCpus(allocator.NodesOf(vcpus), vcpus), nil
+}
+
+func (s *SKVMGuestInstance) reconcileEmulatorPin(allocator *cpupin.SAllocator) error {
+	pins := s.getVcpuPins()
+	if pins == nil {
+		return nil
+	}
+	emulator, err := s.getEmulatorCpus(allocator, pins)
+	if err != nil {
+		return errors.Wrap(err, "get emulator cpus")
+	}
+	if emulator.String() == s.Desc.VcpuPin.EmulatorPcpus {
+		return nil
+	}
+	vcpus := cpuset.NewCPUSet().UnionAll(pins)
+	threadCpus := emulator
+	if emulator.IsEmpty() {
+		// 取消绑定时 qemu 线程恢复到 vCPU 可用的 CPU
+		threadCpus = vcpus
+	}
+	if len(s.GetCgroupName()) > 0 {
+		// cgroup cpuset 变更会重置线程亲和性, 需先于线程绑定设置
+		cpus := threadCpus.Union(vcpus)
+		if _, err := s.CPUSet(context.Background(), &compute.ServerCPUSetInput{CPUS: cpus.ToSlice()}); err != nil {
+			return errors.Wrap(err, "set cgroup cpuset")
+		}
+	}
+	if err := s.setVcpuAffinity(pins, threadCpus); err != nil {
+		return err
+	}
+	s.Desc.VcpuPin.EmulatorPcpus = emulator.String()
+	return s.SaveLiveDesc(s.Desc)
+}
+
+func (m *SGuestManager) getVcpuPinAllocator() (*cpupin.SAllocator, error) {
+	topo, err := hardware.GetTopology()
+	if err != nil {
+		return nil, errors.Wrap(err, "get host topology")
+	}
+	nodes := make([]cpupin.SNumaNode, 0, len(topo.Nodes))
+	for _, node := range topo.Nodes {
+		n := cpupin.SNumaNode{Id: node.ID}
+		for _, core := range node.Cores {
+			n.Cpus = append(n.Cpus, core.LogicalProcessors...)
+		}
+		nodes = append(nodes, n)
+	}
+	return cpupin.NewAllocator(nodes, m.host.GetReservedCpus()), nil
+}
+
+// reconcileVcpuPins 校验运行中虚机的 vCPU 绑定,
+// 对策略变更, vCPU 数变化或宿主机 CPU 拓扑变化后失效的绑定重新分配
+func (m *SGuestManager) reconcileVcpuPins() {
+	m.vcpuPinLock.Lock()
+	defer m.vcpuPinLock.Unlock()
+
+	policyGuests := map[string][]*SKVMGuestInstance{}
+	unpinGuests := []*SKVMGuestInstance{}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if !guest.IsRunning() || guest.Monitor == nil || !guest.needVcpuPinReconcile() {
+			return true
+		}
+		if policy := guest.getVcpuPinPolicy(); len(policy) > 0 {
+			policyGuests[policy] = append(policyGuests[policy], guest)
+		} else {
+			unpinGuests = append(unpinGuests, guest)
+		}
+		return true
+	})
+	if len(policyGuests)+len(unpinGuests) == 0 {
+		return
+	}
+
+	allocator, err := m.getVcpuPinAllocator()
+	if err != nil {
+		log.Errorf("reconcile vcpu pins: %s", err)
+		return
+	}
+	for _, guest := range unpinGuests {
+		if err := guest.removeVcpuPins(allocator.Pool()); err != nil {
+			log.Errorf("remove guest %s vcpu pins: %s", guest.GetName(), err)
+		}
+	}
+	// 先处理独占绑定, 共享绑定需避开被独占的 CPU
+	for _, policy := range []string{compute.VM_CPU_PIN_POLICY_DEDICATED, compute.VM_CPU_PIN_POLICY_SHARED} {
+		pending := []*SKVMGuestInstance{}
+		for _, guest := range policyGuests[policy] {
+			if guest.Desc.VcpuPin != nil && guest.Desc.VcpuPin.Policy == policy &&
+				guest.vcpuPinsMatchNuma(allocator, guest.getVcpuPins()) &&
+				allocator.Claim(policy, guest.getVcpuPins()) {
+				continue
+			}
+			pending = append(pending, guest)
+		}
+		for _, guest := range pending {
+			var pins []cpuset.CPUSet
+			if vcpuNodes := guest.getVcpuHostNodes(); len(vcpuNodes) > 0 {
+				pins, err = allocator.AllocateOnNodes(policy, vcpuNodes)
+			} else {
+				pins, err = allocator.Allocate(policy, int(guest.Desc.Cpu))
+			}
+			if err != nil {
+				log.Errorf("allocate guest %s %s vcpu pins: %s", guest.GetName(), policy, err)
+				continue
+			}
+			if err := guest.applyVcpuPins(policy, pins); err != nil {
+				log.Errorf("apply guest %s vcpu pins: %s", guest.GetName(), err)
+				continue
+			}
+			log.Infof("guest %s %s vcpu pins: %v", guest.GetName(), policy, guest.Desc.VcpuPin.Vcpus)
+		}
+	}
+	// 所有绑定分配完成后再确定 qemu 主线程及 iothread 的 CPU
+	for _, guests := range policyGuests {
+		for _, guest := range guests {
+			if err := guest.reconcileEmulatorPin(allocator); err != nil {
+				log.Errorf("pin guest %s emulator threads: %s", guest.GetName(), err)
+			}
+		}
+	}
+}
+
+// getClaimedVcpuPinAllocator 返回登记了其它运行中虚机绑定的分配器, 用于启动时选择宿主机 NUMA 节点
+func (m *SGuestManager) getClaimedVcpuPinAllocator(excludeId string) (*cpupin.SAllocator, error) {
+	m.vcpuPinLock.Lock()
+	defer m.vcpuPinLock.Unlock()
+
+	allocator, err := m.getVcpuPinAllocator()
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range []string{compute.VM_CPU_PIN_POLICY_DEDICATED, compute.VM_CPU_PIN_POLICY_SHARED} {
+		m.Servers.Range(func(k, v interface{}) bool {
+			guest, ok := v.(*SKVMGuestInstance)
+			if !ok || guest.Id == excludeId || !guest.IsRunning() || guest.Desc == nil ||
+				guest.Desc.VcpuPin == nil || guest.Desc.VcpuPin.Policy != policy {
+				return true
+			}
+			allocator.Claim(policy, guest.getVcpuPins())
+			return true
+		})
+	}
+	return allocator, nil
+}
+
+// getUnpinnedGuestPids 返回未绑定 vCPU 的运行中虚机进程, 存在绑定的虚机时 cpuset 均衡需跳过这些虚机
+func (m *SGuestManager) getUnpinnedGuestPids() ([]string, bool) {
+	pids := []string{}
+	pinned := false
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if !guest.IsRunning() {
+			return true
+		}
+		if guest.Desc.VcpuPin != nil {
+			pinned = true
+		} else if pid := guest.GetPid(); pid > 0 {
+			pids = append(pids, strconv.Itoa(pid))
+		}
+		return true
+	})
+	return pids, pinned
+}
+
+func (m *SGuestManager) startVcpuPinReconciler() {
+	defer func() {
+		if r := recover(); r != nil {
+			debug.PrintStack()
+			log.Errorf("vcpu pin reconciler failed %s", r)
+		}
+	}()
+	for {
+		time.Sleep(vcpuPinReconcileInterval)
+		m.reconcileVcpuPins()
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+)
+
+const (
+	VIRTIO_MEM_DEVICE_ID    = "vmem0"
+	VIRTIO_MEM_OBJECT_ID    = "vmemobj0"
+	VIRTIO_MEM_MIN_BLOCK_MB = 2
+)
+
+// 各机器类型下 virtio-mem 可在线调整的内存上限(MB)，未列出的机器类型不支持
+var virtioMemMachineMaxSizeMB = map[string]int64{
+	api.VM_MACHINE_TYPE_PC:       256 * 1024,
+	api.VM_MACHINE_TYPE_Q35:      512 * 1024,
+	api.VM_MACHINE_TYPE_ARM_VIRT: 256 * 1024,
+}
+
+func (s *SKVMGuestInstance) isVirtioMemEnabled() bool {
+	return s.Desc.Metadata[api.VM_METADATA_ENABLE_VIRTIO_MEM] == "true"
+}
+
+func (s *SKVMGuestInstance) getVirtioMemPath() string {
+	return fmt.Sprintf("/dev/hugepages/%s-vmem", s.Desc.Uuid)
+}
+
+// virtio-mem 区域大小受机器类型上限和 maxmem 剩余空间共同限制
+func getVirtioMemRegionSizeMB(machine string, maxMemMB, memMB, blockMB int64) int64 {
+	limit, ok := virtioMemMachineMaxSizeMB[machine]
+	if !ok {
+		return 0
+	}
+	if free := maxMemMB - memMB; free < limit {
+		limit = free
+	}
+	if blockMB > 0 {
+		limit = limit / blockMB * blockMB
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+func (s *SKVMGuestInstance) getVirtioMemBlockSizeMB() int64 {
+	blockMB := int64(VIRTIO_MEM_MIN_BLOCK_MB)
+	if s.manager.host.IsHugepagesEnabled() {
+		if hpMB := int64(s.getHugepageSizeKb() / 1024); hpMB > blockMB {
+			blockMB = hpMB
+		}
+	}
+	return blockMB
+}
+
+// 启动时全部内存作为基础内存，virtio-mem 区域初始 requested-size 为 0，
+// 之后通过 resize-virtio-mem 在线扩缩
+func (s *SKVMGuestInstance) initVirtioMemDesc(pciRoot *desc.PCIController) {
+	s.Desc.MemDesc.VirtioMem = nil
+	if !s.isVirtioMemEnabled() {
+		return
+	}
+	blockMB := s.getVirtioMemBlockSizeMB()
+	regionMB := getVirtioMemRegionSizeMB(s.getMachine(), int64(s.Desc.MemDesc.MaxMem), s.Desc.Mem, blockMB)
+	if regionMB <= 0 {
+		log.Warningf("guest %s machine %s not support virtio-mem", s.GetName(), s.getMachine())
+		return
+	}
+
+	var memObj *desc.Object
+	if s.manager.host.IsHugepagesEnabled() {
+		memObj = desc.NewObject("memory-backend-file", VIRTIO_MEM_OBJECT_ID)
+		memObj.Options = map[string]string{
+			"mem-path": s.getVirtioMemPath(),
+			"share":    "on",
+		}
+	} else if s.isMemcleanEnabled() || s.isMemShareRequired() {
+		memObj = desc.NewObject("memory-backend-memfd", VIRTIO_MEM_OBJECT_ID)
+		memObj.Options = map[string]string{
+			"share": "on",
+		}
+	} else {
+		memObj = desc.NewObject("memory-backend-ram", VIRTIO_MEM_OBJECT_ID)
+		memObj.Options = map[string]string{}
+	}
+	memObj.Options["size"] = fmt.Sprintf("%dM", regionMB)
+
+	vmem := &desc.SGuestVirtioMem{
+		PCIDevice:   desc.NewPCIDevice(pciRoot.CType, "virtio-mem-pci", VIRTIO_MEM_DEVICE_ID),
+		MemObj:      memObj,
+		SizeMB:      regionMB,
+		BlockSizeMB: blockMB,
+	}
+	vmem.Options = map[string]string{
+		"memdev":     VIRTIO_MEM_OBJECT_ID,
+		"node":       "0",
+		"block-size": fmt.Sprintf("%dM", blockMB),
+	}
+	s.Desc.MemDesc.VirtioMem = vmem
+}
+
+func (s *SKVMGuestInstance) generateVirtioMemStartScript() string {
+	vmem := s.Desc.MemDesc.VirtioMem
+	if vmem == nil || vmem.MemObj.ObjType != "memory-backend-file" {
+		return ""
+	}
+	memPath := s.getVirtioMemPath()
+	cmd := fmt.Sprintf("mkdir -p %s\n", memPath)
+	cmd += fmt.Sprintf("mount -t hugetlbfs -o pagesize=%dK,size=%dM hugetlbfs-%s-vmem %s\n",
+		s.getHugepageSizeKb(), vmem.SizeMB, s.Desc.Uuid, memPath)
+	return cmd
+}
+
+// 计算在线调整后 virtio-mem 的 requested-size
+func (s *SKVMGuestInstance) getVirtioMemRequestedSizeMB(memSizeMB int64) (int64, error) {
+	if s.Desc.MemDesc == nil || s.Desc.MemDesc.VirtioMem == nil {
+		return 0, errors.Wrap(errors.ErrNotSupported, "guest not started with virtio-mem")
+	}
+	vmem := s.Desc.MemDesc.VirtioMem
+	baseMB := s.Desc.Mem - vmem.RequestedSizeMB
+	requested := memSizeMB - baseMB
+	if requested < 0 {
+		return 0, errors.Errorf("can't shrink memory below boot memory %dM", baseMB)
+	}
+	if requested > vmem.SizeMB {
+		return 0, errors.Errorf("memory size %dM exceeds virtio-mem limit %dM", memSizeMB, baseMB+vmem.SizeMB)
+	}
+	if vmem.BlockSizeMB > 0 && requested%vmem.BlockSizeMB != 0 {
+		return 0, errors.Errorf("memory size change %dM not aligned to block size %dM", requested, vmem.BlockSizeMB)
+	}
+	return requested, nil
+}
+
+func (m *SGuestManager) ResizeVirtioMem(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	resizeParams, ok := params.(*SGuestResizeVirtioMem)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	guest, _ := m.GetServer(resizeParams.Sid)
+	requested, err := guest.getVirtioMemRequestedSizeMB(resizeParams.MemSize)
+	if err != nil {
+		return nil, err
+	}
+	vmem := guest.Desc.MemDesc.VirtioMem
+	path := fmt.Sprintf("/machine/peripheral/%s", vmem.Id)
+	guest.Monitor.QomSet(path, "requested-size", requested*1024*1024, func(reason string) {
+		if len(reason) > 0 {
+			hostutils.TaskFailed(ctx, fmt.Sprintf("qom-set %s requested-size: %s", path, reason))
+			return
+		}
+		guest.Desc.Mem += requested - vmem.RequestedSizeMB
+		vmem.RequestedSizeMB = requested
+		vmem.Options["requested-size"] = fmt.Sprintf("%dM", requested)
+		if err := guest.SaveLiveDesc(guest.Desc); err != nil {
+			log.Errorf("guest %s save live desc: %s", guest.GetName(), err)
+		}
+		data := jsonutils.NewDict()
+		data.Set("vnc_port", jsonutils.NewInt(int64(guest.GetVncPort())))
+		data.Set("sync_qemu_cmdline", jsonutils.JSONTrue)
+		if err := guest.saveScripts(data); err != nil {
+			log.Errorf("failed save script: %s", err)
+		}
+		res := jsonutils.NewDict()
+		res.Set("vmem_size", jsonutils.NewInt(guest.Desc.Mem))
+		hostutils.TaskComplete(ctx, res)
+	})
+	return nil, nil
+}
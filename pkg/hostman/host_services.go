@@ -18,6 +18,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	execlient "yunion.io/x/executor/client"
 	"yunion.io/x/log"
@@ -28,6 +29,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/service"
 	"yunion.io/x/onecloud/pkg/hostman/downloader"
+	"yunion.io/x/onecloud/pkg/hostman/flowsample"
 	"yunion.io/x/onecloud/pkg/hostman/guestman"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/guesthandlers"
@@ -39,6 +41,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/kubehandlers"
 	"yunion.io/x/onecloud/pkg/hostman/metadata"
+	"yunion.io/x/onecloud/pkg/hostman/netprobe"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/hostman/storageman/diskhandlers"
@@ -143,6 +146,14 @@ func (host *SHostService) RunService() {
 
 	cronManager.AddJobEveryFewDays(
 		"CleanRecycleDiskFiles", 1, 3, 0, 0, storageman.CleanRecycleDiskfiles, false)
+	if options.HostOptions.NetworkProbeIntervalSeconds > 0 {
+		cronManager.AddJobAtIntervals("NetworkProbe",
+			time.Duration(options.HostOptions.NetworkProbeIntervalSeconds)*time.Second, netprobe.Probe)
+	}
+	if options.HostOptions.FlowSampleIntervalSeconds > 0 {
+		cronManager.AddJobAtIntervals("FlowSample",
+			time.Duration(options.HostOptions.FlowSampleIntervalSeconds)*time.Second, flowsample.Sample)
+	}
 	cronManager.Start()
 
 	close(guestChan)
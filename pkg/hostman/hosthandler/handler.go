@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"yunion.io/x/jsonutils"
 
@@ -26,6 +27,8 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
 	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostconsts"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/netprobe"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 )
 
@@ -41,10 +44,15 @@ func AddHostHandler(prefix string, app *appsrv.Application) {
 			auth.Authenticate(setOnHostDown))
 		app.AddHandler("GET", fmt.Sprintf("%s/%s/health-status", prefix, keyword),
 			auth.Authenticate(getHealthManagerStatus))
+		app.AddHandler("GET", fmt.Sprintf("%s/%s/ovn-acl-stats", prefix, keyword),
+			auth.Authenticate(getOvnAclStats))
+		app.AddHandler("GET", fmt.Sprintf("%s/%s/network-probe-payload", prefix, keyword),
+			auth.Authenticate(getNetworkProbePayload))
 
 		for action, f := range map[string]actionFunc{
 			"sync":                   hostSync,
 			"probe-isolated-devices": hostProbeIsolatedDevices,
+			"collect-log-bundle":     hostCollectLogBundle,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyword, action),
@@ -67,6 +75,34 @@ func getHealthManagerStatus(ctx context.Context, w http.ResponseWriter, r *http.
 	hostutils.Response(ctx, w, map[string]string{"status": status})
 }
 
+func getOvnAclStats(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	stats, err := hostinfo.GetOvnAclStats()
+	if err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
+	hostutils.Response(ctx, w, map[string]interface{}{"stats": stats})
+}
+
+// 供对端宿主机下载以估算带宽
+func getNetworkProbePayload(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, query, _ := appsrv.FetchEnv(ctx, w, r)
+	sizeMb, _ := query.Int("size_mb")
+	if sizeMb <= 0 || sizeMb > netprobe.MAX_PAYLOAD_SIZE_MB {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError("size_mb should be in range 1-%d", netprobe.MAX_PAYLOAD_SIZE_MB))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(sizeMb*1024*1024, 10))
+	w.WriteHeader(http.StatusOK)
+	buf := make([]byte, 64*1024)
+	for i := int64(0); i < sizeMb*16; i++ {
+		if _, err := w.Write(buf); err != nil {
+			return
+		}
+	}
+}
+
 func hostActions(f actionFunc) appsrv.FilterHandler {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		params, _, body := appsrv.FetchEnv(ctx, w, r)
@@ -0,0 +1,110 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hosthandler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/logbundle"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func hostCollectLogBundle(ctx context.Context, hostId string, body jsonutils.JSONObject) (interface{}, error) {
+	input := api.HostLogBundleCollectInput{}
+	if err := body.Unmarshal(&input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal input: %v", err)
+	}
+	if len(input.UploadUrl) == 0 {
+		return nil, httperrors.NewMissingParameterError("upload_url")
+	}
+	hostutils.DelayTask(ctx, collectLogBundle, &input)
+	return nil, nil
+}
+
+func runRemoteCommand(name string, args ...string) ([]byte, error) {
+	return procutils.NewRemoteCommandAsFarAsPossible(name, args...).Output()
+}
+
+func collectLogBundle(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	input := params.(*api.HostLogBundleCollectInput)
+	collector := logbundle.NewCollector(input.Since, runRemoteCommand)
+
+	if len(input.ServerId) > 0 {
+		guest, ok := guestman.GetGuestManager().GetServer(input.ServerId)
+		if !ok {
+			return nil, httperrors.NewNotFoundError("server %s not found on host", input.ServerId)
+		}
+		files := guest.DiagnosticFiles()
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			collector.AddFile(filepath.Join("server", name), files[name])
+		}
+	}
+
+	if prefix := options.HostOptions.LogFilePrefix; len(prefix) > 0 {
+		dir, base := filepath.Split(prefix)
+		collector.AddFilesWithPrefix(dir, base, "hostagent")
+	}
+
+	if input.IncludeOvs {
+		collector.AddCommand("ovs/ovs-vsctl-show.txt", "ovs-vsctl", "show")
+		output, err := runRemoteCommand("ovs-vsctl", "list-br")
+		if err != nil {
+			log.Warningf("ovs-vsctl list-br: %s %v", output, err)
+		}
+		for _, br := range strings.Fields(string(output)) {
+			collector.AddCommand(fmt.Sprintf("ovs/%s-flows.txt", br), "ovs-ofctl", "dump-flows", br)
+			collector.AddCommand(fmt.Sprintf("ovs/%s-ports.txt", br), "ovs-ofctl", "show", br)
+		}
+	}
+
+	tmp, err := ioutil.TempFile("", "host-log-bundle-*.tar.gz")
+	if err != nil {
+		return nil, errors.Wrap(err, "create temp file")
+	}
+	defer os.Remove(tmp.Name())
+	files, err := collector.WriteTo(tmp)
+	tmp.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "write bundle")
+	}
+	size, err := logbundle.Upload(ctx, input.UploadUrl, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(api.HostLogBundleCollectOutput{
+		SizeBytes: size,
+		Files:     files,
+	}), nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/bwutils"
+)
+
+// 虚机入方向(宿主机->虚机)限速，单位mbit，0表示不限速
+func getNicIngressBw(nic *desc.SGuestNetwork) (int, error) {
+	if nic.BwIngress > 0 {
+		return nic.BwIngress, nil
+	}
+	return bwutils.GetDownloadBwValue(nic.Bw, nic.Ip, nic.Ifname, options.HostOptions.BwDownloadBandwidth)
+}
+
+// 虚机出方向(虚机->宿主机)的ovs ingress policing参数，单位kbit
+func getOvsEgressBwValues(nic *desc.SGuestNetwork) (int, int, error) {
+	if nic.BwEgress > 0 {
+		return nic.BwEgress * 1000, nic.BwEgress * 2000, nil
+	}
+	return bwutils.GetOvsBwValues(nic.Bw, nic.Ip)
+}
+
+// tap设备的发送方向即虚机的入方向，用htb限速
+func tcIngressScripts(limitMbit int) string {
+	s := fmt.Sprintf("LIMIT_DOWNLOAD='%dmbit'\n", limitMbit)
+	s += "tc qdisc del dev $IF root 2>/dev/null\n"
+	s += "if [ $LIMIT_DOWNLOAD != \"0mbit\" ]; then\n"
+	s += "    tc qdisc add dev $IF root handle 1: htb default 10\n"
+	s += "    tc class add dev $IF parent 1: classid 1:1 htb " +
+		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
+	s += "    tc class add dev $IF parent 1:1 classid 1:10 htb " +
+		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
+	s += "fi\n"
+	return s
+}
+
+// tap设备的接收方向即虚机的出方向，linux bridge下用ingress police限速
+func tcEgressScripts(limitMbit int) string {
+	s := fmt.Sprintf("LIMIT_UPLOAD='%dmbit'\n", limitMbit)
+	s += "tc qdisc del dev $IF ingress 2>/dev/null\n"
+	s += "if [ $LIMIT_UPLOAD != \"0mbit\" ]; then\n"
+	s += "    tc qdisc add dev $IF handle ffff: ingress\n"
+	s += fmt.Sprintf("    tc filter add dev $IF parent ffff: protocol all prio 1 u32 match u32 0 0 "+
+		"police rate $LIMIT_UPLOAD burst %dk drop flowid :1\n", limitMbit*2000/8)
+	s += "fi\n"
+	return s
+}
+
+func scriptsHeader(nic *desc.SGuestNetwork) string {
+	s := "#!/bin/bash\n\n"
+	s += fmt.Sprintf("IF='%s'\n", nic.Ifname)
+	return s
+}
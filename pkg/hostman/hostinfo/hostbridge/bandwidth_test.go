@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostbridge
+
+import (
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+)
+
+func TestLinuxBridgeBandwidthScripts(t *testing.T) {
+	nic := &desc.SGuestNetwork{
+		GuestnetworkJsonDesc: api.GuestnetworkJsonDesc{
+			Ifname:    "vnic-1",
+			BwIngress: 100,
+			BwEgress:  20,
+		},
+	}
+	l := &SLinuxBridgeDriver{}
+	s, err := l.GetBandwidthScripts(nic)
+	if err != nil {
+		t.Fatalf("GetBandwidthScripts: %v", err)
+	}
+	for _, want := range []string{
+		"IF='vnic-1'\n",
+		"LIMIT_DOWNLOAD='100mbit'\n",
+		"LIMIT_UPLOAD='20mbit'\n",
+		"police rate $LIMIT_UPLOAD burst 5000k drop",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("scripts missing %q:\n%s", want, s)
+		}
+	}
+
+	nic.BwIngress = 0
+	nic.BwEgress = 0
+	s, _ = l.GetBandwidthScripts(nic)
+	for _, want := range []string{
+		"tc qdisc del dev $IF root",
+		"tc qdisc del dev $IF ingress",
+		"LIMIT_UPLOAD='0mbit'\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("reset scripts missing %q:\n%s", want, s)
+		}
+	}
+}
@@ -56,6 +56,8 @@ type IBridgeDriver interface {
 	GenerateIfupScripts(scriptPath string, nic *desc.SGuestNetwork, isVolatileHost bool) error
 	GenerateIfdownScripts(scriptPath string, nic *desc.SGuestNetwork, isVolatileHost bool) error
 	RegisterHostlocalServer(mac, ip string) error
+	// 生成运行时调整网卡限速的脚本
+	GetBandwidthScripts(nic *desc.SGuestNetwork) (string, error)
 
 	getUpScripts(nic *desc.SGuestNetwork, isVolatileHost bool) (string, error)
 	getDownScripts(nic *desc.SGuestNetwork, isVolatileHost bool) (string, error)
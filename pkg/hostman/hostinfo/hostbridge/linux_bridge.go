@@ -104,9 +104,21 @@ func (l *SLinuxBridgeDriver) getUpScripts(nic *desc.SGuestNetwork, isVolatileHos
 	s += "ip address flush dev $1\n"
 	s += "ip link set dev $1 up\n"
 	s += "brctl addif ${switch} $1\n"
+	if nic.BwIngress > 0 || nic.BwEgress > 0 {
+		s += "IF=$1\n"
+		s += l.getBandwidthScripts(nic)
+	}
 	return s, nil
 }
 
+func (l *SLinuxBridgeDriver) getBandwidthScripts(nic *desc.SGuestNetwork) string {
+	return tcIngressScripts(nic.BwIngress) + tcEgressScripts(nic.BwEgress)
+}
+
+func (l *SLinuxBridgeDriver) GetBandwidthScripts(nic *desc.SGuestNetwork) (string, error) {
+	return scriptsHeader(nic) + l.getBandwidthScripts(nic), nil
+}
+
 func (l *SLinuxBridgeDriver) getDownScripts(nic *desc.SGuestNetwork, isVolatileHost bool) (string, error) {
 	s := "#!/bin/sh\n\n"
 	s += fmt.Sprintf("switch='%s'\n", l.bridge)
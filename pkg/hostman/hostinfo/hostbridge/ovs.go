@@ -26,7 +26,6 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/system_service"
-	"yunion.io/x/onecloud/pkg/util/bwutils"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 )
 
@@ -138,9 +137,16 @@ func (o *SOVSBridgeDriver) OnVolatileGuestResume(nic *desc.SGuestNetwork) error
 	return nil
 }
 
+func (o *SOVSBridgeDriver) getSwitch(nic *desc.SGuestNetwork) string {
+	if nic.Vpc.Provider == compute.VPC_PROVIDER_OVN {
+		return options.HostOptions.OvnIntegrationBridge
+	}
+	return o.bridge.String()
+}
+
 func (o *SOVSBridgeDriver) getUpScripts(nic *desc.SGuestNetwork, isVolatileHost bool) (string, error) {
 	var (
-		bridge      = o.bridge.String()
+		bridge      = o.getSwitch(nic)
 		ifname      = nic.Ifname
 		ip          = nic.Ip
 		mac         = nic.Mac
@@ -149,10 +155,6 @@ func (o *SOVSBridgeDriver) getUpScripts(nic *desc.SGuestNetwork, isVolatileHost
 		vpcProvider = nic.Vpc.Provider
 	)
 
-	if vpcProvider == compute.VPC_PROVIDER_OVN {
-		bridge = options.HostOptions.OvnIntegrationBridge
-	}
-
 	s := "#!/bin/bash\n\n"
 	s += fmt.Sprintf("SWITCH='%s'\n", bridge)
 	s += fmt.Sprintf("IF='%s'\n", ifname)
@@ -160,17 +162,6 @@ func (o *SOVSBridgeDriver) getUpScripts(nic *desc.SGuestNetwork, isVolatileHost
 	s += fmt.Sprintf("MAC='%s'\n", mac)
 	s += fmt.Sprintf("VLAN_ID=%d\n", vlan)
 	s += fmt.Sprintf("NET_ID=%s\n", netId)
-	limit, burst, err := bwutils.GetOvsBwValues(nic.Bw, nic.Ip)
-	if err != nil {
-		return "", err
-	}
-	s += fmt.Sprintf("LIMIT=%d\n", limit)
-	s += fmt.Sprintf("BURST=%d\n", burst)
-	bwDownload, err := bwutils.GetDownloadBwValue(nic.Bw, nic.Ip, nic.Ifname, options.HostOptions.BwDownloadBandwidth)
-	if err != nil {
-		return "", err
-	}
-	s += fmt.Sprintf("LIMIT_DOWNLOAD='%dmbit'\n", bwDownload)
 	if options.HostOptions.TunnelPaddingBytes > 0 {
 		s += fmt.Sprintf("ip link set dev $IF mtu %d\n",
 			1500+options.HostOptions.TunnelPaddingBytes)
@@ -190,22 +181,44 @@ func (o *SOVSBridgeDriver) getUpScripts(nic *desc.SGuestNetwork, isVolatileHost
 	}
 	s += "PORT=$(ovs-ofctl show $SWITCH | grep -w $IF)\n"
 	s += "PORT=$(echo $PORT | awk 'BEGIN{FS=\"(\"}{print $1}')\n"
+	bwScripts, err := o.getBandwidthScripts(nic)
+	if err != nil {
+		return "", err
+	}
+	s += bwScripts
+	return s, nil
+}
+
+func (o *SOVSBridgeDriver) getBandwidthScripts(nic *desc.SGuestNetwork) (string, error) {
+	limit, burst, err := getOvsEgressBwValues(nic)
+	if err != nil {
+		return "", err
+	}
+	bwDownload, err := getNicIngressBw(nic)
+	if err != nil {
+		return "", err
+	}
+	s := fmt.Sprintf("LIMIT=%d\n", limit)
+	s += fmt.Sprintf("BURST=%d\n", burst)
 	s += "OFCTL=$(ovs-vsctl get-controller $SWITCH)\n"
 	s += "if [ -z \"$OFCTL\" ]; then\n"
 	s += "    ovs-vsctl set Interface $IF ingress_policing_rate=$LIMIT\n"
 	s += "    ovs-vsctl set Interface $IF ingress_policing_burst=$BURST\n"
 	s += "fi\n"
-	s += "if [ $LIMIT_DOWNLOAD != \"0mbit\" ]; then\n"
-	s += "    tc qdisc del dev $IF root 2>/dev/null\n"
-	s += "    tc qdisc add dev $IF root handle 1: htb default 10\n"
-	s += "    tc class add dev $IF parent 1: classid 1:1 htb " +
-		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
-	s += "    tc class add dev $IF parent 1:1 classid 1:10 htb " +
-		"rate $LIMIT_DOWNLOAD ceil $LIMIT_DOWNLOAD\n"
-	s += "fi\n"
+	s += tcIngressScripts(bwDownload)
 	return s, nil
 }
 
+func (o *SOVSBridgeDriver) GetBandwidthScripts(nic *desc.SGuestNetwork) (string, error) {
+	s := scriptsHeader(nic)
+	s += fmt.Sprintf("SWITCH='%s'\n", o.getSwitch(nic))
+	bwScripts, err := o.getBandwidthScripts(nic)
+	if err != nil {
+		return "", err
+	}
+	return s + bwScripts, nil
+}
+
 func (o *SOVSBridgeDriver) getDownScripts(nic *desc.SGuestNetwork, isVolatileHost bool) (string, error) {
 	var (
 		bridge = o.bridge.String()
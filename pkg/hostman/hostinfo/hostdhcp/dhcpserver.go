@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/netutils"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/types"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	guestman "yunion.io/x/onecloud/pkg/hostman/guestman/types"
@@ -253,11 +254,39 @@ func (s *SGuestDHCPServer) getConfig(pkt dhcp.Packet) *dhcp.ResponseConfig {
 		guestDesc, guestNic = guestman.GuestDescGetter.GetGuestNicDesc(mac, ip, port, s.iface, !isCandidate)
 	}
 	if guestNic != nil && !guestNic.Virtual {
-		return s.getGuestConfig(guestDesc, guestNic)
+		conf := s.getGuestConfig(guestDesc, guestNic)
+		if conf != nil {
+			conf.BootServer, conf.BootFile = getGuestPxeBootConfig(pkt, guestDesc)
+		}
+		return conf
 	}
 	return nil
 }
 
+// 设置了链式加载地址的虚拟机, iPXE 直接下发链式加载地址,
+// 其余 PXE 固件先从 TFTP 服务(默认为 baremetal agent)加载 iPXE
+func getGuestPxeBootConfig(pkt dhcp.Packet, guestDesc *desc.SGuestDesc) (string, string) {
+	chainloadUrl := guestDesc.Metadata[api.VM_METADATA_PXE_CHAINLOAD_URL]
+	if len(chainloadUrl) == 0 || !dhcp.IsPXERequest(pkt) {
+		return "", ""
+	}
+	if dhcp.IsIPXERequest(pkt) {
+		return "", chainloadUrl
+	}
+	tftpServer := options.HostOptions.GuestPxeTftpServer
+	if len(tftpServer) == 0 && len(options.HostOptions.DhcpRelay) > 0 {
+		tftpServer = options.HostOptions.DhcpRelay[0]
+	}
+	if len(tftpServer) == 0 {
+		log.Warningf("guest %s request pxe boot, but no tftp server configured", guestDesc.Name)
+		return "", ""
+	}
+	if dhcp.IsUEFIPXERequest(pkt) {
+		return tftpServer, options.HostOptions.GuestPxeUefiBootFile
+	}
+	return tftpServer, options.HostOptions.GuestPxeBiosBootFile
+}
+
 func (s *SGuestDHCPServer) IsDhcpPacket(pkt dhcp.Packet) bool {
 	return pkt != nil && (pkt.Type() == dhcp.Request || pkt.Type() == dhcp.Discover)
 }
@@ -26,6 +26,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -114,6 +115,17 @@ type SHostInfo struct {
 	SysWarning map[string]string
 
 	IoScheduler string
+
+	// virtio-balloon 从虚机回收的内存(MB), 由 guestman 更新
+	balloonReclaimedMb int64
+}
+
+func (h *SHostInfo) SetBalloonReclaimedMb(mb int64) {
+	atomic.StoreInt64(&h.balloonReclaimedMb, mb)
+}
+
+func (h *SHostInfo) GetBalloonReclaimedMb() int64 {
+	return atomic.LoadInt64(&h.balloonReclaimedMb)
 }
 
 func (h *SHostInfo) GetIsolatedDeviceManager() isolated_device.IsolatedDeviceManager {
@@ -169,6 +181,10 @@ func (h *SHostInfo) IsKvmSupport() bool {
 	return sysutils.IsKvmSupport()
 }
 
+func (h *SHostInfo) IsConfidentialComputingSupported(cvmType string) bool {
+	return sysutils.IsConfidentialComputingSupported(cvmType)
+}
+
 func (h *SHostInfo) IsNestedVirtualization() bool {
 	return utils.IsInStringArray("hypervisor", h.Cpu.cpuFeatures)
 }
@@ -414,7 +430,7 @@ func (h *SHostInfo) prepareEnv() error {
 	if err != nil {
 		log.Warningf("modprobe vhost_net error: %s", output)
 	}
-	if !options.HostOptions.DisableSetCgroup {
+	if !options.HostOptions.DisableSetCgroup && !cgrouputils.IsCgroupV2() {
 		if !cgrouputils.Init(h.IoScheduler) {
 			return fmt.Errorf("Cannot initialize control group subsystem")
 		}
@@ -451,6 +467,7 @@ func (h *SHostInfo) prepareEnv() error {
 		if h.sysinfo.HugepageNr == nil || *h.sysinfo.HugepageNr == 0 {
 			return errors.Errorf("hugepage %d nr 0", options.HostOptions.HugepageSizeMb)
 		}
+		h.sysinfo.HugepageSizesKb = hp.PageSizes()
 	case "transparent":
 		h.EnableTransparentHugepages()
 	default:
@@ -479,6 +496,7 @@ func (h *SHostInfo) detectHostInfo() error {
 	h.detectKvmModuleSupport()
 	h.detectKVMMaxCpus()
 	h.detectNestSupport()
+	h.detectConfidentialComputingSupport()
 
 	if err := h.detectSyssoftwareInfo(); err != nil {
 		return err
@@ -662,6 +680,44 @@ func (h *SHostInfo) resetIptables() error {
 	return nil
 }
 
+func (h *SHostInfo) GetReservedCpus() cpuset.CPUSet {
+	if h.reservedCpusInfo == nil {
+		return cpuset.NewCPUSet()
+	}
+	reservedCpus, err := cpuset.Parse(h.reservedCpusInfo.Cpus)
+	if err != nil {
+		log.Errorf("failed parse reserved cpus %s: %s", h.reservedCpusInfo.Cpus, err)
+		return cpuset.NewCPUSet()
+	}
+	return reservedCpus
+}
+
+// initCgroupV2 cgroup v2 下虚机 slice 统一挂在 hostagent slice 下, 由其限制可用 CPU
+func (h *SHostInfo) initCgroupV2(hostCpusetStr string) error {
+	hostSlice := cgrouputils.NewCgroupV2Slice(hostconsts.HOST_CGROUP)
+	if err := hostSlice.Create(); err != nil {
+		return errors.Wrap(err, "create host cgroup v2 slice")
+	}
+	if err := hostSlice.SetParam(cgrouputils.CPUSET_CPUS, hostCpusetStr); err != nil {
+		return errors.Wrap(err, "init host cgroup v2 cpuset")
+	}
+	if h.reservedCpusInfo != nil {
+		reservedSlice := cgrouputils.NewCgroupV2Slice(hostconsts.HOST_RESERVED_CPUSET)
+		if err := reservedSlice.Create(); err != nil {
+			return errors.Wrap(err, "create host reserved cgroup v2 slice")
+		}
+		if err := reservedSlice.SetParam(cgrouputils.CPUSET_CPUS, h.reservedCpusInfo.Cpus); err != nil {
+			return errors.Wrapf(err, "init host reserved cpuset %s", h.reservedCpusInfo.Cpus)
+		}
+		if h.reservedCpusInfo.Mems != "" {
+			if err := reservedSlice.SetParam(cgrouputils.CPUSET_MEMS, h.reservedCpusInfo.Mems); err != nil {
+				return errors.Wrapf(err, "init host reserved cpuset mems %s", h.reservedCpusInfo.Mems)
+			}
+		}
+	}
+	return nil
+}
+
 func (h *SHostInfo) initCgroup() error {
 	reservedCpus := cpuset.NewCPUSet()
 	if h.reservedCpusInfo != nil {
@@ -681,6 +737,9 @@ func (h *SHostInfo) initCgroup() error {
 	}
 	hostCpuset := hostCpusetBuilder.Result()
 	hostCpusetStr := hostCpuset.String()
+	if cgrouputils.IsCgroupV2() {
+		return h.initCgroupV2(hostCpusetStr)
+	}
 	// init host cpuset root group
 	if !cgrouputils.NewCGroupCPUSetTask("", hostconsts.HOST_CGROUP, 0, hostCpusetStr).Configure() {
 		return fmt.Errorf("failed init host root cpuset")
@@ -721,6 +780,10 @@ func (h *SHostInfo) detectNestSupport() {
 	}
 }
 
+func (h *SHostInfo) detectConfidentialComputingSupport() {
+	h.sysinfo.ConfidentialComputing = sysutils.GetConfidentialComputingSupport()
+}
+
 func (h *SHostInfo) detectOsDist() {
 	files, err := procutils.NewRemoteCommandAsFarAsPossible("sh", "-c", "ls /etc/*elease").Output()
 	if err != nil {
@@ -819,7 +882,15 @@ func (h *SHostInfo) detectQemuVersion() error {
 			return fmt.Errorf("Failed to detect qemu version")
 		}
 	}
-	return h.detectQemuCapabilities(h.sysinfo.QemuVersion)
+	err = h.detectQemuCapabilities(h.sysinfo.QemuVersion)
+	if err != nil {
+		return err
+	}
+	h.sysinfo.QemuMachines = make([]string, 0, len(h.qemuMachineInfoList))
+	for i := range h.qemuMachineInfoList {
+		h.sysinfo.QemuMachines = append(h.sysinfo.QemuMachines, h.qemuMachineInfoList[i].Name)
+	}
+	return nil
 }
 
 const (
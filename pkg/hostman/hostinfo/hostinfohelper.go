@@ -317,11 +317,19 @@ type SSysInfo struct {
 	CpuModelName   string `json:"cpu_model_name"`
 	CpuMicrocode   string `json:"cpu_microcode"`
 
+	// qemu 支持的版本化机型
+	QemuMachines []string `json:"qemu_machines,omitempty"`
+
+	// 宿主机支持的机密计算类型: sev/sev-es/sev-snp/tdx
+	ConfidentialComputing []string `json:"confidential_computing,omitempty"`
+
 	StorageType string `json:"storage_type"`
 
 	HugepagesOption string `json:"hugepages_option"`
 	HugepageSizeKb  int    `json:"hugepage_size_kb"`
 	HugepageNr      *int   `json:"hugepage_nr"`
+	// 已预留大页池的所有大页大小, 虚机可按需选择
+	HugepageSizesKb []int `json:"hugepage_sizes_kb"`
 
 	Topology *hostapi.HostTopology `json:"topology"`
 	CPUInfo  *hostapi.HostCPUInfo  `json:"cpu_info"`
@@ -0,0 +1,103 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+var (
+	ovsFlowCookieRe     = regexp.MustCompile(`cookie=0x([0-9a-f]+)`)
+	ovsFlowPacketsRe    = regexp.MustCompile(`n_packets=(\d+)`)
+	ovnAclStageNameRe   = regexp.MustCompile(`stage-name="?ls_(in|out)_acl"?`)
+	ovnLflowStageHintRe = regexp.MustCompile(`stage-hint="?([0-9a-f]{8})"?`)
+)
+
+// 汇总 ovs-ofctl dump-flows 输出中每个 cookie 的报文数
+// ovn-controller 以逻辑流表 UUID 的前 32 位作为 OpenFlow cookie
+func parseOvsFlowPackets(output string) map[string]int64 {
+	ret := map[string]int64{}
+	for _, line := range strings.Split(output, "\n") {
+		cm := ovsFlowCookieRe.FindStringSubmatch(line)
+		pm := ovsFlowPacketsRe.FindStringSubmatch(line)
+		if len(cm) < 2 || len(pm) < 2 {
+			continue
+		}
+		cookie, err := strconv.ParseUint(cm[1], 16, 64)
+		if err != nil || cookie == 0 {
+			continue
+		}
+		packets, _ := strconv.ParseInt(pm[1], 10, 64)
+		ret[fmt.Sprintf("%08x", cookie&0xffffffff)] += packets
+	}
+	return ret
+}
+
+// 解析南向库 ACL 阶段的逻辑流表, 返回 cookie 到 stage-hint(北向 ACL UUID 前 8 位) 的映射
+func parseOvnAclLflows(output string) map[string]string {
+	ret := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ",", 2)
+		if len(parts) != 2 || len(parts[0]) < 8 {
+			continue
+		}
+		if !ovnAclStageNameRe.MatchString(parts[1]) {
+			continue
+		}
+		hm := ovnLflowStageHintRe.FindStringSubmatch(parts[1])
+		if len(hm) < 2 {
+			continue
+		}
+		ret[parts[0][:8]] = hm[1]
+	}
+	return ret
+}
+
+func aggregateOvnAclStats(flows map[string]int64, lflows map[string]string) map[string]int64 {
+	ret := map[string]int64{}
+	for cookie, packets := range flows {
+		if hint, ok := lflows[cookie]; ok {
+			ret[hint] += packets
+		}
+	}
+	return ret
+}
+
+// 本机 OVN ACL 命中报文数, key 为北向 ACL UUID 前 8 位
+func GetOvnAclStats() (map[string]int64, error) {
+	flowOutput, err := procutils.NewRemoteCommandAsFarAsPossible(
+		"ovs-ofctl", "-O", "OpenFlow13", "dump-flows", options.HostOptions.OvnIntegrationBridge,
+	).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "dump flows: %s", flowOutput)
+	}
+	lflowOutput, err := procutils.NewRemoteCommandAsFarAsPossible(
+		"ovn-sbctl", "--db="+options.HostOptions.OvnSouthDatabase,
+		"--format=csv", "--no-headings", "--data=bare",
+		"--columns=_uuid,external_ids", "list", "Logical_Flow",
+	).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "list logical flows: %s", lflowOutput)
+	}
+	return aggregateOvnAclStats(parseOvsFlowPackets(string(flowOutput)), parseOvnAclLflows(string(lflowOutput))), nil
+}
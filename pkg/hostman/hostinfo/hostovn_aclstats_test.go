@@ -0,0 +1,40 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOvnAclStats(t *testing.T) {
+	flows := ` cookie=0x3f2a1b0c, duration=12.3s, table=44, n_packets=10, n_bytes=980, priority=2002,ct_state=+new-est+trk,ip,reg15=0x2,metadata=0x1 actions=load:0x1->NXM_NX_XXREG0[97],resubmit(,45)
+ cookie=0x3f2a1b0c, duration=12.3s, table=44, n_packets=5, n_bytes=490, priority=2002,ct_state=+new-est+trk,ipv6,reg15=0x2,metadata=0x1 actions=load:0x1->NXM_NX_XXREG0[97],resubmit(,45)
+ cookie=0xa1b2c3d4, duration=12.3s, table=12, n_packets=7, n_bytes=700, priority=1001,ip,reg14=0x2,metadata=0x1 actions=resubmit(,13)
+ cookie=0x0, duration=12.3s, table=0, n_packets=100, n_bytes=9800, priority=0 actions=drop
+NXST_FLOW reply (xid=0x4):`
+	lflows := `3f2a1b0c-1111-2222-3333-444455556666,source=northd.c:5522 stage-hint=9e8d7c6b stage-name=ls_out_acl
+a1b2c3d4-1111-2222-3333-444455556666,source=northd.c:5460 stage-hint=5a4b3c2d stage-name=ls_in_acl
+b1b2c3d4-1111-2222-3333-444455556666,source=northd.c:4000 stage-name=ls_in_port_sec_l2
+`
+	got := aggregateOvnAclStats(parseOvsFlowPackets(flows), parseOvnAclLflows(lflows))
+	want := map[string]int64{
+		"9e8d7c6b": 15,
+		"5a4b3c2d": 7,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
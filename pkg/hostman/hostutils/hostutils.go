@@ -35,6 +35,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/k8s"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils/cpuset"
 )
 
 type IHost interface {
@@ -48,8 +49,13 @@ type IHost interface {
 	IsHugepagesEnabled() bool
 	HugepageSizeKb() int
 
+	// 预留给宿主机, 不可被虚机使用的 CPU
+	GetReservedCpus() cpuset.CPUSet
+
 	IsKvmSupport() bool
 	IsNestedVirtualization() bool
+	// 宿主机是否支持指定类型的机密计算虚机(sev/sev-es/sev-snp/tdx)
+	IsConfidentialComputingSupported(cvmType string) bool
 
 	PutHostOnline() error
 	StartDHCPServer()
@@ -17,6 +17,7 @@ package isolated_device
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -100,12 +101,20 @@ type IsolatedDeviceManager interface {
 	BatchCustomProbe() error
 	AppendDetachedDevice(dev *CloudDeviceInfo)
 	GetQemuParams(devAddrs []string) *QemuParams
+
+	// 设备预留, 防止多个虚机同时占用同一设备
+	ReserveDevice(addr, guestId string) error
+	ReleaseDevice(addr, guestId string)
+	GetDeviceReservation(addr string) string
 }
 
 type isolatedDeviceManager struct {
 	host            IHost
 	devices         []IDevice
 	DetachedDevices []*CloudDeviceInfo
+
+	reserveLock  sync.Mutex
+	reservations map[string]string
 }
 
 func NewManager(host IHost) IsolatedDeviceManager {
@@ -113,6 +122,7 @@ func NewManager(host IHost) IsolatedDeviceManager {
 		host:            host,
 		devices:         make([]IDevice, 0),
 		DetachedDevices: make([]*CloudDeviceInfo, 0),
+		reservations:    make(map[string]string),
 	}
 	// Do probe laster - Qiu Jian
 	return man
@@ -239,6 +249,30 @@ func (man *isolatedDeviceManager) GetQemuParams(devAddrs []string) *QemuParams {
 	return getQemuParams(man, devAddrs)
 }
 
+func (man *isolatedDeviceManager) ReserveDevice(addr, guestId string) error {
+	man.reserveLock.Lock()
+	defer man.reserveLock.Unlock()
+	if owner, ok := man.reservations[addr]; ok && owner != guestId {
+		return errors.Wrapf(httperrors.ErrConflict, "device %s reserved by guest %s", addr, owner)
+	}
+	man.reservations[addr] = guestId
+	return nil
+}
+
+func (man *isolatedDeviceManager) ReleaseDevice(addr, guestId string) {
+	man.reserveLock.Lock()
+	defer man.reserveLock.Unlock()
+	if owner, ok := man.reservations[addr]; ok && owner == guestId {
+		delete(man.reservations, addr)
+	}
+}
+
+func (man *isolatedDeviceManager) GetDeviceReservation(addr string) string {
+	man.reserveLock.Lock()
+	defer man.reserveLock.Unlock()
+	return man.reservations[addr]
+}
+
 type sBaseDevice struct {
 	dev            *PCIDevice
 	cloudId        string
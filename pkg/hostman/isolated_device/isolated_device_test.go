@@ -73,3 +73,24 @@ func Test_parseLspci(t *testing.T) {
 		})
 	}
 }
+
+func TestIsolatedDeviceManagerReservation(t *testing.T) {
+	man := NewManager(nil)
+	if err := man.ReserveDevice("001:002", "guest1"); err != nil {
+		t.Fatalf("reserve free device: %s", err)
+	}
+	if err := man.ReserveDevice("001:002", "guest1"); err != nil {
+		t.Fatalf("reserve by same guest: %s", err)
+	}
+	if err := man.ReserveDevice("001:002", "guest2"); err == nil {
+		t.Fatalf("reserve by another guest should fail")
+	}
+	man.ReleaseDevice("001:002", "guest2")
+	if owner := man.GetDeviceReservation("001:002"); owner != "guest1" {
+		t.Fatalf("release by non-owner should be ignored, owner %q", owner)
+	}
+	man.ReleaseDevice("001:002", "guest1")
+	if err := man.ReserveDevice("001:002", "guest2"); err != nil {
+		t.Fatalf("reserve released device: %s", err)
+	}
+}
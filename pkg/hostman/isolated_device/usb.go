@@ -126,6 +126,8 @@ func (dev *sUSBDevice) GetHotPlugOptions(*desc.SGuestIsolatedDevice) ([]*HotPlug
 	if err != nil {
 		return nil, errors.Wrap(err, "GetUSBDevQemuOptions")
 	}
+	// device_del 依赖设备 id
+	opts["id"] = dev.GetQemuId()
 	return []*HotPlugOption{
 		{
 			Device:  "usb-host",
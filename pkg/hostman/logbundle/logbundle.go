@@ -0,0 +1,193 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// 单个文件最多收集的字节数, 超出时仅保留末尾部分
+const MAX_FILE_BYTES = 32 * 1024 * 1024
+
+type CommandRunner func(name string, args ...string) ([]byte, error)
+
+type sItem struct {
+	name    string
+	path    string
+	command []string
+}
+
+// SCollector 收集文件及命令输出, 脱敏后打包为 tar.gz
+type SCollector struct {
+	Since  time.Time
+	Runner CommandRunner
+
+	items []sItem
+}
+
+func NewCollector(since time.Time, runner CommandRunner) *SCollector {
+	return &SCollector{
+		Since:  since,
+		Runner: runner,
+	}
+}
+
+func (c *SCollector) AddFile(name, path string) {
+	c.items = append(c.items, sItem{name: name, path: path})
+}
+
+// AddFilesWithPrefix 收集目录下指定前缀且在 Since 之后修改过的文件, 用于轮转的日志
+func (c *SCollector) AddFilesWithPrefix(dir, prefix, archiveDir string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasPrefix(info.Name(), prefix) {
+			continue
+		}
+		if !c.Since.IsZero() && info.ModTime().Before(c.Since) {
+			continue
+		}
+		c.AddFile(filepath.Join(archiveDir, info.Name()), filepath.Join(dir, info.Name()))
+	}
+}
+
+func (c *SCollector) AddCommand(name string, args ...string) {
+	c.items = append(c.items, sItem{name: name, command: args})
+}
+
+func readFileTail(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		if _, err := f.Seek(info.Size()-limit, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(io.LimitReader(f, limit))
+}
+
+func (c *SCollector) read(item sItem) ([]byte, error) {
+	if len(item.command) > 0 {
+		if c.Runner == nil {
+			return nil, errors.Errorf("no command runner")
+		}
+		return c.Runner(item.command[0], item.command[1:]...)
+	}
+	return readFileTail(item.path, MAX_FILE_BYTES)
+}
+
+// WriteTo 写入 tar.gz 格式的日志包, 单项收集失败记录到 errors.txt 中而不中断, 返回包内文件列表
+func (c *SCollector) WriteTo(w io.Writer) ([]string, error) {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return errors.Wrapf(err, "write header %s", name)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return errors.Wrapf(err, "write %s", name)
+		}
+		return nil
+	}
+	files := []string{}
+	failed := []string{}
+	for _, item := range c.items {
+		data, err := c.read(item)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", item.name, err))
+			if len(data) == 0 {
+				continue
+			}
+		}
+		if err := add(item.name, Sanitize(data)); err != nil {
+			return nil, err
+		}
+		files = append(files, item.name)
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		if err := add("errors.txt", []byte(strings.Join(failed, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+		files = append(files, "errors.txt")
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close tar")
+	}
+	if err := gw.Close(); err != nil {
+		return nil, errors.Wrap(err, "close gzip")
+	}
+	return files, nil
+}
+
+// Upload 通过预签名地址以 HTTP PUT 上传日志包
+func Upload(ctx context.Context, url string, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "open bundle")
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, errors.Wrap(err, "stat bundle")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, f)
+	if err != nil {
+		return 0, errors.Wrap(err, "new request")
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	resp, err := httputils.GetDefaultClient().Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "upload bundle")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, errors.Errorf("upload bundle status %d: %s", resp.StatusCode, body)
+	}
+	return info.Size(), nil
+}
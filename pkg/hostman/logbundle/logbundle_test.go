@@ -0,0 +1,134 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   `{"name":"vm1","password": "p@ss\"word"}`,
+			want: `{"name":"vm1","password": "******"}`,
+		},
+		{
+			in:   `login admin_password=abc123 user=root`,
+			want: `login admin_password=****** user=root`,
+		},
+		{
+			in:   `-object secret,id=sec0 --access-key AKID --verbose`,
+			want: `-object secret,id=sec0 --access-key ****** --verbose`,
+		},
+		{
+			in:   `{"AccessKey":"xyz","Token":"t"}`,
+			want: `{"AccessKey":"******","Token":"******"}`,
+		},
+		{
+			in:   `nothing to hide here`,
+			want: `nothing to hide here`,
+		},
+	}
+	for _, c := range cases {
+		got := string(Sanitize([]byte(c.in)))
+		if got != c.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	ret := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar next: %v", err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read %s: %v", hdr.Name, err)
+		}
+		ret[hdr.Name] = string(content)
+	}
+	return ret
+}
+
+func TestCollectorWriteTo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	desc := filepath.Join(dir, "desc")
+	if err := ioutil.WriteFile(desc, []byte(`{"password":"secret"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := func(name string, args ...string) ([]byte, error) {
+		if name == "fail" {
+			return nil, fmt.Errorf("boom")
+		}
+		return []byte(name + " " + strings.Join(args, " ")), nil
+	}
+	c := NewCollector(time.Time{}, runner)
+	c.AddFile("server/desc", desc)
+	c.AddFile("server/missing", filepath.Join(dir, "missing"))
+	c.AddCommand("ovs/show.txt", "ovs-vsctl", "show")
+	c.AddCommand("ovs/fail.txt", "fail")
+
+	buf := &bytes.Buffer{}
+	files, err := c.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	wantFiles := []string{"server/desc", "ovs/show.txt", "errors.txt"}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("files = %v, want %v", files, wantFiles)
+	}
+	contents := readBundle(t, buf.Bytes())
+	if got := contents["server/desc"]; got != `{"password":"******"}` {
+		t.Errorf("server/desc = %q", got)
+	}
+	if got := contents["ovs/show.txt"]; got != "ovs-vsctl show" {
+		t.Errorf("ovs/show.txt = %q", got)
+	}
+	errs := contents["errors.txt"]
+	if !strings.Contains(errs, "server/missing") || !strings.Contains(errs, "ovs/fail.txt: boom") {
+		t.Errorf("errors.txt = %q", errs)
+	}
+}
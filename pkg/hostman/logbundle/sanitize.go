@@ -0,0 +1,40 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logbundle
+
+import (
+	"regexp"
+)
+
+const sanitizedMask = "******"
+
+// 键名包含以下关键字的取值视为敏感信息
+const sensitiveKey = `[A-Za-z0-9_\-]*(?i:password|passwd|secret|token|access[_-]?key|private[_-]?key)[A-Za-z0-9_\-]*`
+
+var (
+	// "password": "xxx"
+	sanitizeJsonRe = regexp.MustCompile(`("` + sensitiveKey + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// password=xxx, --password xxx
+	sanitizeKvRe  = regexp.MustCompile(`(\b` + sensitiveKey + `=)[^\s,&"']+`)
+	sanitizeArgRe = regexp.MustCompile(`(--` + sensitiveKey + `\s+)[^\s-][^\s]*`)
+)
+
+// Sanitize 屏蔽日志及描述文件中的密码, 密钥等敏感信息
+func Sanitize(data []byte) []byte {
+	data = sanitizeJsonRe.ReplaceAll(data, []byte(`${1}"`+sanitizedMask+`"`))
+	data = sanitizeKvRe.ReplaceAll(data, []byte(`${1}`+sanitizedMask))
+	data = sanitizeArgRe.ReplaceAll(data, []byte(`${1}`+sanitizedMask))
+	return data
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	IMDS_TOKEN_HEADER     = "X-Aws-Ec2-Metadata-Token"
+	IMDS_TOKEN_TTL_HEADER = "X-Aws-Ec2-Metadata-Token-Ttl-Seconds"
+
+	IMDS_TOKEN_MAX_TTL = 21600
+)
+
+// 令牌签名密钥仅保存在内存中, 服务重启后已签发的令牌失效
+var imdsTokenKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+func imdsTokenSign(guestId string, expire int64) string {
+	mac := hmac.New(sha256.New, imdsTokenKey)
+	mac.Write([]byte(fmt.Sprintf("%s:%d", guestId, expire)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newImdsToken(guestId string, ttl int, now time.Time) string {
+	expire := now.Add(time.Duration(ttl) * time.Second).Unix()
+	return fmt.Sprintf("%d.%s", expire, imdsTokenSign(guestId, expire))
+}
+
+func verifyImdsToken(guestId, token string, now time.Time) error {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return errors.Wrap(httperrors.ErrInvalidCredential, "malformed token")
+	}
+	expire, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return errors.Wrap(httperrors.ErrInvalidCredential, "malformed token")
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(imdsTokenSign(guestId, expire))) {
+		return errors.Wrap(httperrors.ErrInvalidCredential, "invalid token")
+	}
+	if now.Unix() > expire {
+		return errors.Wrap(httperrors.ErrInvalidCredential, "token expired")
+	}
+	return nil
+}
+
+// 按虚拟机的元数据服务访问模式校验请求
+func checkImdsAccess(r *http.Request, guestDesc *desc.SGuestDesc) error {
+	switch guestDesc.Metadata[api.VM_METADATA_IMDS_MODE] {
+	case api.IMDS_MODE_DISABLED:
+		return httperrors.NewForbiddenError("metadata service is disabled")
+	case api.IMDS_MODE_TOKEN_REQUIRED:
+		token := r.Header.Get(IMDS_TOKEN_HEADER)
+		if len(token) == 0 {
+			return httperrors.NewUnauthorizedError("missing metadata token")
+		}
+		err := verifyImdsToken(guestDesc.Uuid, token, time.Now())
+		if err != nil {
+			return httperrors.NewUnauthorizedError("%v", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) imdsToken(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	guestDesc := s.getGuestDesc(r)
+	if guestDesc == nil {
+		hostutils.Response(ctx, w, httperrors.NewNotFoundError("vm not found"))
+		return
+	}
+	if guestDesc.Metadata[api.VM_METADATA_IMDS_MODE] == api.IMDS_MODE_DISABLED {
+		hostutils.Response(ctx, w, httperrors.NewForbiddenError("metadata service is disabled"))
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get(IMDS_TOKEN_TTL_HEADER))
+	if err != nil || ttl <= 0 || ttl > IMDS_TOKEN_MAX_TTL {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError("invalid %s, must be 1-%d", IMDS_TOKEN_TTL_HEADER, IMDS_TOKEN_MAX_TTL))
+		return
+	}
+	w.Header().Set(IMDS_TOKEN_TTL_HEADER, strconv.Itoa(ttl))
+	hostutils.Response(ctx, w, newImdsToken(guestDesc.Uuid, ttl, time.Now()))
+}
@@ -90,11 +90,19 @@ func (s *Service) addHandler(app *appsrv.Application) {
 		app.AddHandler(method, fmt.Sprintf("%s/<version:%s>/meta-data",
 			prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.metaData)
 	}
+	app.AddHandler("PUT", fmt.Sprintf("%s/<version:%s>/api/token",
+		prefix, `(latest|\d{4}-\d{2}-\d{2})`), s.imdsToken)
 
 	app.AddReverseProxyHandler(s.monitorPrefix(), s.monitorReverseEndpoint(), s.requestManipulator)
 }
 
 func (s *Service) versionOnly(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if guestDesc := s.getGuestDesc(r); guestDesc != nil {
+		if err := checkImdsAccess(r, guestDesc); err != nil {
+			hostutils.Response(ctx, w, err)
+			return
+		}
+	}
 	hostutils.Response(ctx, w, strings.Join([]string{"meta-data", "user-data"}, "\n"))
 }
 
@@ -104,6 +112,10 @@ func (s *Service) userData(ctx context.Context, w http.ResponseWriter, r *http.R
 		hostutils.Response(ctx, w, "")
 		return
 	}
+	if err := checkImdsAccess(r, guestDesc); err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
 
 	guestUserData := guestDesc.UserData
 	if guestUserData == "" {
@@ -126,6 +138,10 @@ func (s *Service) metaData(ctx context.Context, w http.ResponseWriter, r *http.R
 		hostutils.Response(ctx, w, "")
 		return
 	}
+	if err := checkImdsAccess(r, guestDesc); err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
 
 	req := appsrv.SplitPath(r.URL.Path)[2:]
 
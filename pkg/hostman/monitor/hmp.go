@@ -17,9 +17,11 @@ package monitor
 import (
 	"bufio"
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -181,6 +183,15 @@ func (m *HmpMonitor) ConnectWithSocket(address string) error {
 	return nil
 }
 
+func (m *HmpMonitor) ConnectTls(host string, port int, cfg *tls.Config) error {
+	err := m.SBaseMonitor.ConnectTls(host, port, cfg)
+	if err != nil {
+		return err
+	}
+	go m.read(m.rwc)
+	return nil
+}
+
 func (m *HmpMonitor) Connect(host string, port int) error {
 	err := m.SBaseMonitor.connect("tcp", fmt.Sprintf("%s:%d", host, port))
 	if err != nil {
@@ -277,6 +288,18 @@ func (m *HmpMonitor) ObjectDel(idstr string, callback StringCallback) {
 	m.Query(fmt.Sprintf("object_del %s", idstr), callback)
 }
 
+func (m *HmpMonitor) ChardevAdd(backend, id string, params map[string]string, callback StringCallback) {
+	var paramsKvs = []string{fmt.Sprintf("%s,id=%s", backend, id)}
+	for k, v := range params {
+		paramsKvs = append(paramsKvs, fmt.Sprintf("%s=%s", k, v))
+	}
+	m.Query(fmt.Sprintf("chardev-add %s", strings.Join(paramsKvs, ",")), callback)
+}
+
+func (m *HmpMonitor) ChardevRemove(id string, callback StringCallback) {
+	m.Query(fmt.Sprintf("chardev-remove %s", id), callback)
+}
+
 func (m *HmpMonitor) DriveAdd(bus string, params map[string]string, callback StringCallback) {
 	var paramsKvs = []string{}
 	for k, v := range params {
@@ -302,7 +325,7 @@ func (m *HmpMonitor) MigrateSetCapability(capability, state string, callback Str
 }
 
 func (m *HmpMonitor) MigrateSetParameter(key string, val interface{}, callback StringCallback) {
-	cmd := fmt.Sprintf("migrate_set_parameter %s %s", key, val)
+	cmd := fmt.Sprintf("migrate_set_parameter %s %v", key, val)
 	m.Query(cmd, callback)
 }
 
@@ -480,10 +503,55 @@ func (m *HmpMonitor) GeMemtSlotIndex(callback func(index int)) {
 	m.Query("info memory-devices", cb)
 }
 
+func (m *HmpMonitor) QueryHotpluggableCpus(callback QueryHotpluggableCpusCallback) {
+	go callback(nil, "not supported")
+}
+
+func (m *HmpMonitor) QueryCpus(callback QueryCpusCallback) {
+	go callback(nil, "not supported")
+}
+
+func (m *HmpMonitor) QomSet(path, property string, value interface{}, callback StringCallback) {
+	m.Query(fmt.Sprintf("qom-set %s %s %v", path, property, value), callback)
+}
+
 func (m *HmpMonitor) GetMemoryDevicesInfo(cb QueryMemoryDevicesCallback) {
 	go cb(nil, "not supported")
 }
 
+func (m *HmpMonitor) Balloon(sizeMB int64, callback StringCallback) {
+	m.Query(fmt.Sprintf("balloon %d", sizeMB), callback)
+}
+
+// parseHmpBalloonInfo 解析 info balloon 输出, 如 balloon: actual=1024
+func parseHmpBalloonInfo(res string) (int64, error) {
+	idx := strings.Index(res, "actual=")
+	if idx < 0 {
+		return 0, errors.Errorf("unexpected balloon info: %s", res)
+	}
+	val := res[idx+len("actual="):]
+	if end := strings.IndexFunc(val, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+		val = val[:end]
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func (m *HmpMonitor) QueryBalloon(callback QueryBalloonCallback) {
+	m.Query("info balloon", func(res string) {
+		actual, err := parseHmpBalloonInfo(res)
+		if err != nil {
+			callback(0, err.Error())
+			return
+		}
+		callback(actual, "")
+	})
+}
+
+// hmp 没有对应的命令, 需通过 qmp 获取
+func (m *HmpMonitor) QuerySevLaunchMeasure(callback QuerySevLaunchMeasureCallback) {
+	callback("", "query-sev-launch-measure is not supported by hmp")
+}
+
 func (m *HmpMonitor) ObjectAdd(objectType string, params map[string]string, callback StringCallback) {
 	var paramsKvs = []string{}
 	for k, v := range params {
@@ -498,6 +566,14 @@ func (m *HmpMonitor) BlockIoThrottle(driveName string, bps, iops int64, callback
 	m.Query(cmd, callback)
 }
 
+func (m *HmpMonitor) DriveBackup(drive, target, format, bitmap string, addBitmap bool, callback StringCallback) {
+	go callback("dirty bitmap backup not supported by hmp")
+}
+
+func (m *HmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	go callback("dirty bitmap not supported by hmp")
+}
+
 func (m *HmpMonitor) CancelBlockJob(driveName string, force bool, callback StringCallback) {
 	cmd := "block_job_cancel "
 	if force {
@@ -39,3 +39,21 @@ func TestHmpMonitor_Connect(t *testing.T) {
 	time.Sleep(3 * time.Second)
 	m.Disconnect()
 }
+
+func TestParseHmpBalloonInfo(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"balloon: actual=1024\r\n", 1024, false},
+		{"balloon: actual=2048 max_mem=4096", 2048, false},
+		{"No balloon device has been activated", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseHmpBalloonInfo(c.in)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("parse %q got %d %v, want %d", c.in, got, err, c.want)
+		}
+	}
+}
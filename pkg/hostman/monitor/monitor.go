@@ -15,6 +15,7 @@
 package monitor
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strings"
@@ -186,6 +187,7 @@ func (self *BlockJob) PreOffset(preOffset int64) {
 
 type Monitor interface {
 	Connect(host string, port int) error
+	ConnectTls(host string, port int, cfg *tls.Config) error
 	ConnectWithSocket(address string) error
 	Disconnect()
 	IsConnected() bool
@@ -203,8 +205,16 @@ type Monitor interface {
 
 	GetCpuCount(func(count int))
 	AddCpu(cpuIndex int, callback StringCallback)
+	QueryHotpluggableCpus(callback QueryHotpluggableCpusCallback)
+	QueryCpus(callback QueryCpusCallback)
 	GeMemtSlotIndex(func(index int))
 	GetMemoryDevicesInfo(QueryMemoryDevicesCallback)
+	QomSet(path, property string, value interface{}, callback StringCallback)
+	// virtio-balloon 目标内存大小及当前实际大小, 单位 MB
+	Balloon(sizeMB int64, callback StringCallback)
+	QueryBalloon(callback QueryBalloonCallback)
+	// SEV 虚机启动度量值, 用于远程证明
+	QuerySevLaunchMeasure(callback QuerySevLaunchMeasureCallback)
 
 	GetBlocks(callback func([]QemuBlock))
 	EjectCdrom(dev string, callback StringCallback)
@@ -218,6 +228,9 @@ type Monitor interface {
 	DriveAdd(bus string, params map[string]string, callback StringCallback)
 	DeviceAdd(dev string, params map[string]string, callback StringCallback)
 
+	ChardevAdd(backend, id string, params map[string]string, callback StringCallback)
+	ChardevRemove(id string, callback StringCallback)
+
 	BlockStream(drive string, idx, blkCnt int, callback StringCallback)
 	DriveMirror(callback StringCallback, drive, target, syncMode, format string, unmap, blockReplication bool)
 	BlockJobComplete(drive string, cb StringCallback)
@@ -240,6 +253,9 @@ type Monitor interface {
 	ResizeDisk(driveName string, sizeMB int64, callback StringCallback)
 	BlockIoThrottle(driveName string, bps, iops int64, callback StringCallback)
 	CancelBlockJob(driveName string, force bool, callback StringCallback)
+	// addBitmap 为 true 时在同一事务中创建持久化脏位图并做全量备份, 否则按位图做增量备份
+	DriveBackup(drive, target, format, bitmap string, addBitmap bool, callback StringCallback)
+	BlockDirtyBitmapRemove(node, name string, callback StringCallback)
 
 	NetdevAdd(id, netType string, params map[string]string, callback StringCallback)
 	NetdevDel(id string, callback StringCallback)
@@ -292,6 +308,16 @@ func (m *SBaseMonitor) connect(protocol, address string) error {
 	return nil
 }
 
+func (m *SBaseMonitor) connectTls(address string, cfg *tls.Config) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", address, cfg)
+	if err != nil {
+		return errors.Errorf("Connect to tls %s failed %s", address, err)
+	}
+	log.Infof("Connect tls %s success", address)
+	m.onConnectSuccess(conn)
+	return nil
+}
+
 func (m *SBaseMonitor) onConnectSuccess(conn net.Conn) {
 	// Setup reader timeout
 	conn.SetReadDeadline(time.Now().Add(90 * time.Second))
@@ -303,6 +329,10 @@ func (m *SBaseMonitor) Connect(host string, port int) error {
 	return m.connect("tcp", fmt.Sprintf("%s:%d", host, port))
 }
 
+func (m *SBaseMonitor) ConnectTls(host string, port int, cfg *tls.Config) error {
+	return m.connectTls(fmt.Sprintf("%s:%d", host, port), cfg)
+}
+
 func (m *SBaseMonitor) ConnectWithSocket(address string) error {
 	return m.connect("unix", address)
 }
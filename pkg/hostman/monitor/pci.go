@@ -109,10 +109,56 @@ type PcdimmDeviceInfo struct {
 	Memdev       string  `json:"memdev"`
 	Hotplugged   bool    `json:"hotplugged"`
 	Hotpluggable bool    `json:"hotpluggable"`
+
+	// virtio-mem
+	RequestedSize int64 `json:"requested-size,omitempty"`
+	BlockSize     int64 `json:"block-size,omitempty"`
 }
 
 type QueryMemoryDevicesCallback func(memoryDevicesInfoList []MemoryDeviceInfo, err string)
 
+type BalloonInfo struct {
+	// bytes
+	Actual int64 `json:"actual"`
+}
+
+type QueryBalloonCallback func(actualMB int64, err string)
+
+type SevLaunchMeasureInfo struct {
+	// base64 encoded
+	Data string `json:"data"`
+}
+
+type QuerySevLaunchMeasureCallback func(measurement string, err string)
+
+// HotpluggableCPU implements the "HotpluggableCPU" QMP API type.
+type HotpluggableCPU struct {
+	Type       string                `json:"type"`
+	VcpusCount int64                 `json:"vcpus-count"`
+	Props      CpuInstanceProperties `json:"props"`
+	QomPath    *string               `json:"qom-path,omitempty"`
+}
+
+// CpuInstanceProperties implements the "CpuInstanceProperties" QMP API type.
+type CpuInstanceProperties struct {
+	NodeId   *int64 `json:"node-id,omitempty"`
+	SocketId *int64 `json:"socket-id,omitempty"`
+	DieId    *int64 `json:"die-id,omitempty"`
+	CoreId   *int64 `json:"core-id,omitempty"`
+	ThreadId *int64 `json:"thread-id,omitempty"`
+}
+
+type QueryHotpluggableCpusCallback func(cpus []HotpluggableCPU, err string)
+
+// CpuInfoFast implements the "CpuInfoFast" QMP API type.
+type CpuInfoFast struct {
+	CpuIndex int64  `json:"cpu-index"`
+	QomPath  string `json:"qom-path"`
+	ThreadId int64  `json:"thread-id"`
+}
+
+type QueryCpusCallback func(cpus []CpuInfoFast, err string)
+
 // MachineInfo implements the "MachineInfo" QMP API type.
 type MachineInfo struct {
 	Name             string  `json:"name"`
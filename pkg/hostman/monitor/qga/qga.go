@@ -369,3 +369,187 @@ func (qga *QemuGuestAgent) GuestExecStatusCommand(pid int) (*GuestExecStatus, er
 	}
 	return res, nil
 }
+
+func (qga *QemuGuestAgent) execCmdUnmarshal(cmd *monitor.Command, res interface{}) error {
+	rawRes, err := qga.execCmd(cmd, true)
+	if err != nil {
+		return err
+	}
+	if rawRes == nil {
+		return errors.Errorf("qga no response")
+	}
+	if err := json.Unmarshal(*rawRes, res); err != nil {
+		return errors.Wrap(err, "unmarshal raw response")
+	}
+	return nil
+}
+
+/*
+##
+# @guest-file-open:
+#
+# Open a file in the guest and retrieve a file handle for it
+#
+# @path: Full path to the file in the guest to open.
+# @mode: open mode, as per fopen(), "r" is the default.
+#
+# Returns: Guest file handle on success.
+##
+*/
+func (qga *QemuGuestAgent) GuestFileOpen(path, mode string) (int, error) {
+	cmd := &monitor.Command{
+		Execute: "guest-file-open",
+		Args: map[string]interface{}{
+			"path": path,
+			"mode": mode,
+		},
+	}
+	var handle int
+	if err := qga.execCmdUnmarshal(cmd, &handle); err != nil {
+		return -1, err
+	}
+	return handle, nil
+}
+
+func (qga *QemuGuestAgent) GuestFileClose(handle int) error {
+	cmd := &monitor.Command{
+		Execute: "guest-file-close",
+		Args: map[string]interface{}{
+			"handle": handle,
+		},
+	}
+	_, err := qga.execCmd(cmd, true)
+	return err
+}
+
+type GuestFileRead struct {
+	Count  int    `json:"count"`
+	BufB64 string `json:"buf-b64"`
+	Eof    bool   `json:"eof"`
+}
+
+/*
+##
+# @guest-file-read:
+#
+# Read from an open file in the guest. Data will be base64-encoded.
+# As this command is just for limited, ad-hoc debugging, such as log
+# file access, the number of bytes to read is limited to 48 MB.
+##
+*/
+func (qga *QemuGuestAgent) GuestFileRead(handle, count int) (*GuestFileRead, error) {
+	cmd := &monitor.Command{
+		Execute: "guest-file-read",
+		Args: map[string]interface{}{
+			"handle": handle,
+			"count":  count,
+		},
+	}
+	res := new(GuestFileRead)
+	if err := qga.execCmdUnmarshal(cmd, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type GuestFileWrite struct {
+	Count int  `json:"count"`
+	Eof   bool `json:"eof"`
+}
+
+/*
+##
+# @guest-file-write:
+#
+# Write to an open file in the guest.
+#
+# @buf-b64: base64-encoded string representing data to be written
+##
+*/
+func (qga *QemuGuestAgent) GuestFileWrite(handle int, bufB64 string) (*GuestFileWrite, error) {
+	cmd := &monitor.Command{
+		Execute: "guest-file-write",
+		Args: map[string]interface{}{
+			"handle":  handle,
+			"buf-b64": bufB64,
+		},
+	}
+	res := new(GuestFileWrite)
+	if err := qga.execCmdUnmarshal(cmd, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+/*
+##
+# @guest-fsfreeze-freeze:
+#
+# Sync and freeze all freezable, local guest filesystems.
+#
+# Returns: Number of file systems currently frozen.
+##
+*/
+func (qga *QemuGuestAgent) GuestFsfreezeFreeze() (int, error) {
+	var count int
+	err := qga.execCmdUnmarshal(&monitor.Command{Execute: "guest-fsfreeze-freeze"}, &count)
+	return count, err
+}
+
+/*
+##
+# @guest-fsfreeze-thaw:
+#
+# Unfreeze all frozen guest filesystems
+#
+# Returns: Number of file systems thawed by this call
+##
+*/
+func (qga *QemuGuestAgent) GuestFsfreezeThaw() (int, error) {
+	var count int
+	err := qga.execCmdUnmarshal(&monitor.Command{Execute: "guest-fsfreeze-thaw"}, &count)
+	return count, err
+}
+
+/*
+##
+# @guest-fsfreeze-status:
+#
+# Get guest fsfreeze state.
+#
+# Returns: GuestFsfreezeStatus ("thawed", "frozen")
+##
+*/
+func (qga *QemuGuestAgent) GuestFsfreezeStatus() (string, error) {
+	var status string
+	err := qga.execCmdUnmarshal(&monitor.Command{Execute: "guest-fsfreeze-status"}, &status)
+	return status, err
+}
+
+type GuestIpAddress struct {
+	IpAddressType string `json:"ip-address-type"`
+	IpAddress     string `json:"ip-address"`
+	Prefix        int    `json:"prefix"`
+}
+
+type GuestNetworkInterface struct {
+	Name            string           `json:"name"`
+	HardwareAddress string           `json:"hardware-address"`
+	IpAddresses     []GuestIpAddress `json:"ip-addresses"`
+}
+
+/*
+##
+# @guest-network-get-interfaces:
+#
+# Get list of guest IP addresses, MAC addresses
+# and netmasks.
+##
+*/
+func (qga *QemuGuestAgent) GuestNetworkGetInterfaces() ([]GuestNetworkInterface, error) {
+	res := []GuestNetworkInterface{}
+	if err := qga.execCmdUnmarshal(&monitor.Command{Execute: "guest-network-get-interfaces"}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -16,6 +16,7 @@ package monitor
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -319,6 +320,15 @@ func (m *QmpMonitor) ConnectWithSocket(address string) error {
 	return nil
 }
 
+func (m *QmpMonitor) ConnectTls(host string, port int, cfg *tls.Config) error {
+	err := m.SBaseMonitor.ConnectTls(host, port, cfg)
+	if err != nil {
+		return err
+	}
+	go m.read(m.rwc)
+	return nil
+}
+
 func (m *QmpMonitor) Connect(host string, port int) error {
 	err := m.SBaseMonitor.connect("tcp", fmt.Sprintf("%s:%d", host, port))
 	if err != nil {
@@ -554,6 +564,18 @@ func (m *QmpMonitor) ObjectDel(idstr string, callback StringCallback) {
 	m.HumanMonitorCommand(fmt.Sprintf("object_del %s", idstr), callback)
 }
 
+func (m *QmpMonitor) ChardevAdd(backend, id string, params map[string]string, callback StringCallback) {
+	var paramsKvs = []string{fmt.Sprintf("%s,id=%s", backend, id)}
+	for k, v := range params {
+		paramsKvs = append(paramsKvs, fmt.Sprintf("%s=%s", k, v))
+	}
+	m.HumanMonitorCommand(fmt.Sprintf("chardev-add %s", strings.Join(paramsKvs, ",")), callback)
+}
+
+func (m *QmpMonitor) ChardevRemove(id string, callback StringCallback) {
+	m.HumanMonitorCommand(fmt.Sprintf("chardev-remove %s", id), callback)
+}
+
 func (m *QmpMonitor) DriveAdd(bus string, params map[string]string, callback StringCallback) {
 	var paramsKvs = []string{}
 	for k, v := range params {
@@ -976,6 +998,126 @@ func (m *QmpMonitor) AddCpu(cpuIndex int, callback StringCallback) {
 	m.Query(cmd, cb)
 }
 
+func (m *QmpMonitor) QueryCpus(callback QueryCpusCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(nil, res.ErrorVal.Error())
+				return
+			}
+			cpus := make([]CpuInfoFast, 0)
+			err := json.Unmarshal(res.Return, &cpus)
+			if err != nil {
+				callback(nil, err.Error())
+				return
+			}
+			callback(cpus, "")
+		}
+		cmd = &Command{
+			Execute: "query-cpus-fast",
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) QueryHotpluggableCpus(callback QueryHotpluggableCpusCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(nil, res.ErrorVal.Error())
+				return
+			}
+			cpus := make([]HotpluggableCPU, 0)
+			err := json.Unmarshal(res.Return, &cpus)
+			if err != nil {
+				callback(nil, err.Error())
+				return
+			}
+			callback(cpus, "")
+		}
+		cmd = &Command{
+			Execute: "query-hotpluggable-cpus",
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) QomSet(path, property string, value interface{}, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "qom-set",
+			Args: map[string]interface{}{
+				"path":     path,
+				"property": property,
+				"value":    value,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) Balloon(sizeMB int64, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "balloon",
+			Args: map[string]interface{}{
+				"value": sizeMB * 1024 * 1024,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) QueryBalloon(callback QueryBalloonCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(0, res.ErrorVal.Error())
+				return
+			}
+			info := BalloonInfo{}
+			err := json.Unmarshal(res.Return, &info)
+			if err != nil {
+				callback(0, err.Error())
+				return
+			}
+			callback(info.Actual/1024/1024, "")
+		}
+		cmd = &Command{
+			Execute: "query-balloon",
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) QuerySevLaunchMeasure(callback QuerySevLaunchMeasureCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback("", res.ErrorVal.Error())
+				return
+			}
+			info := SevLaunchMeasureInfo{}
+			err := json.Unmarshal(res.Return, &info)
+			if err != nil {
+				callback("", err.Error())
+				return
+			}
+			callback(info.Data, "")
+		}
+		cmd = &Command{
+			Execute: "query-sev-launch-measure",
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) ObjectAdd(objectType string, params map[string]string, callback StringCallback) {
 	var paramsKvs = []string{}
 	for k, v := range params {
@@ -1022,8 +1164,24 @@ func (m *QmpMonitor) GetMemoryDevicesInfo(callback QueryMemoryDevicesCallback) {
 }
 
 func (m *QmpMonitor) BlockIoThrottle(driveName string, bps, iops int64, callback StringCallback) {
-	cmd := fmt.Sprintf("block_set_io_throttle %s %d 0 0 %d 0 0", driveName, bps, iops)
-	m.HumanMonitorCommand(cmd, callback)
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block_set_io_throttle",
+			Args: map[string]interface{}{
+				"device":  driveName,
+				"bps":     bps,
+				"bps_rd":  0,
+				"bps_wr":  0,
+				"iops":    iops,
+				"iops_rd": 0,
+				"iops_wr": 0,
+			},
+		}
+	)
+	m.Query(cmd, cb)
 }
 
 func (m *QmpMonitor) CancelBlockJob(driveName string, force bool, callback StringCallback) {
@@ -1035,6 +1193,67 @@ func (m *QmpMonitor) CancelBlockJob(driveName string, force bool, callback Strin
 	m.HumanMonitorCommand(cmd, callback)
 }
 
+func (m *QmpMonitor) DriveBackup(drive, target, format, bitmap string, addBitmap bool, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		backupArgs = map[string]interface{}{
+			"device": drive,
+			"target": target,
+			"format": format,
+			"mode":   "existing",
+		}
+		cmd *Command
+	)
+	if addBitmap {
+		backupArgs["sync"] = "full"
+		cmd = &Command{
+			Execute: "transaction",
+			Args: map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{
+						"type": "block-dirty-bitmap-add",
+						"data": map[string]interface{}{
+							"node":       drive,
+							"name":       bitmap,
+							"persistent": true,
+						},
+					},
+					map[string]interface{}{
+						"type": "drive-backup",
+						"data": backupArgs,
+					},
+				},
+			},
+		}
+	} else {
+		backupArgs["sync"] = "incremental"
+		backupArgs["bitmap"] = bitmap
+		cmd = &Command{
+			Execute: "drive-backup",
+			Args:    backupArgs,
+		}
+	}
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block-dirty-bitmap-remove",
+			Args: map[string]interface{}{
+				"node": node,
+				"name": name,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) BlockJobComplete(drive string, callback StringCallback) {
 	m.HumanMonitorCommand(fmt.Sprintf("block_job_complete %s", drive), callback)
 }
@@ -0,0 +1,198 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netprobe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostinfo"
+	"yunion.io/x/onecloud/pkg/hostman/hostmetrics"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	PROBE_DIAL_TIMEOUT = 2 * time.Second
+	PROBE_DIAL_GAP     = 100 * time.Millisecond
+
+	MEASUREMENT = "host_network_probe"
+
+	// 单次带宽探测允许下载的最大数据量
+	MAX_PAYLOAD_SIZE_MB = 64
+)
+
+// Probe 探测到对端宿主机的时延/抖动/带宽, 上报 region 并写入监控
+func Probe(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	hostId := hostinfo.Instance().HostId
+	if len(hostId) == 0 {
+		return
+	}
+	session := hostutils.GetComputeSession(ctx)
+	ret, err := modules.Hosts.GetSpecific(session, hostId, "network-probe-targets", nil)
+	if err != nil {
+		log.Errorf("get network probe targets: %v", err)
+		return
+	}
+	output := api.HostNetworkProbeTargetsOutput{}
+	if err := ret.Unmarshal(&output); err != nil {
+		log.Errorf("unmarshal network probe targets: %v", err)
+		return
+	}
+	if len(output.Targets) == 0 {
+		return
+	}
+
+	results := make([]api.HostNetworkProbeResult, 0, len(output.Targets))
+	lines := make([]string, 0, len(output.Targets))
+	for _, target := range output.Targets {
+		result, err := probeTarget(session, target)
+		if err != nil {
+			log.Warningf("probe host %s(%s): %v", target.Name, target.ManagerUri, err)
+			continue
+		}
+		results = append(results, *result)
+		lines = append(lines, toTelegrafLine(hostId, target, result))
+	}
+
+	input := api.HostNetworkProbeReportInput{Results: results}
+	if _, err := modules.Hosts.PerformAction(session, hostId, "network-probe-report", jsonutils.Marshal(input)); err != nil {
+		log.Errorf("report network probe results: %v", err)
+	}
+	if options.HostOptions.EnableTelegraf && len(lines) > 0 {
+		reportToTelegraf(ctx, strings.Join(lines, "\n"))
+	}
+}
+
+func probeTarget(session *mcclient.ClientSession, target api.HostNetworkProbeTarget) (*api.HostNetworkProbeResult, error) {
+	u, err := url.Parse(target.ManagerUri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse manager uri %s", target.ManagerUri)
+	}
+	count := options.HostOptions.NetworkProbeCount
+	if count <= 0 {
+		count = 1
+	}
+	samples := make([]time.Duration, 0, count)
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", u.Host, PROBE_DIAL_TIMEOUT)
+		if err == nil {
+			samples = append(samples, time.Since(start))
+			conn.Close()
+		}
+		time.Sleep(PROBE_DIAL_GAP)
+	}
+	result := summarizeSamples(samples, count)
+	result.PeerHostId = target.HostId
+	if len(samples) == 0 {
+		return result, nil
+	}
+
+	if sizeMb := options.HostOptions.NetworkProbeBandwidthSizeMb; sizeMb > 0 {
+		bandwidth, err := probeBandwidth(session, target.ManagerUri, sizeMb)
+		if err != nil {
+			log.Warningf("probe bandwidth to host %s: %v", target.Name, err)
+		} else {
+			result.BandwidthMbps = bandwidth
+		}
+	}
+	return result, nil
+}
+
+// 时延取平均值, 抖动取相邻两次时延差的平均值
+func summarizeSamples(samples []time.Duration, count int) *api.HostNetworkProbeResult {
+	result := &api.HostNetworkProbeResult{}
+	if count > 0 {
+		result.LossRate = float64(count-len(samples)) / float64(count)
+	}
+	if len(samples) == 0 {
+		return result
+	}
+	var total, diff float64
+	for i, s := range samples {
+		ms := float64(s) / float64(time.Millisecond)
+		total += ms
+		if i > 0 {
+			diff += math.Abs(ms - float64(samples[i-1])/float64(time.Millisecond))
+		}
+	}
+	result.LatencyMs = total / float64(len(samples))
+	if len(samples) > 1 {
+		result.JitterMs = diff / float64(len(samples)-1)
+	}
+	return result
+}
+
+func probeBandwidth(session *mcclient.ClientSession, managerUri string, sizeMb int) (float64, error) {
+	if sizeMb > MAX_PAYLOAD_SIZE_MB {
+		sizeMb = MAX_PAYLOAD_SIZE_MB
+	}
+	urlStr := fmt.Sprintf("%s/hosts/network-probe-payload?size_mb=%d", strings.TrimSuffix(managerUri, "/"), sizeMb)
+	header := http.Header{}
+	header.Set("X-Auth-Token", session.GetToken().GetTokenString())
+	start := time.Now()
+	resp, err := httputils.Request(httputils.GetClient(true, time.Minute), context.Background(), "GET", urlStr, header, nil, false)
+	if err != nil {
+		return 0, errors.Wrap(err, "request payload")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("request payload status %d", resp.StatusCode)
+	}
+	n, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return 0, errors.Wrap(err, "read payload")
+	}
+	elapsed := time.Since(start).Seconds()
+	if elapsed <= 0 {
+		return 0, errors.Errorf("invalid elapsed time")
+	}
+	return float64(n) * 8 / 1000 / 1000 / elapsed, nil
+}
+
+func toTelegrafLine(hostId string, target api.HostNetworkProbeTarget, result *api.HostNetworkProbeResult) string {
+	return fmt.Sprintf("%s,host_id=%s,peer_host_id=%s,peer_cloudregion_id=%s latency_ms=%f,jitter_ms=%f,loss_rate=%f,bandwidth_mbps=%f",
+		MEASUREMENT, hostId, target.HostId, target.CloudregionId,
+		result.LatencyMs, result.JitterMs, result.LossRate, result.BandwidthMbps)
+}
+
+func reportToTelegraf(ctx context.Context, data string) {
+	resp, err := httputils.Request(httputils.GetDefaultClient(), ctx, "POST", hostmetrics.TelegrafServer, nil, strings.NewReader(data), false)
+	if err != nil {
+		log.Errorf("upload network probe metric failed: %s", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 204 {
+		log.Errorf("upload network probe metric failed %d", resp.StatusCode)
+	}
+}
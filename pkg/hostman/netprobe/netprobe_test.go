@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netprobe
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSummarizeSamples(t *testing.T) {
+	cases := []struct {
+		name    string
+		samples []time.Duration
+		count   int
+		latency float64
+		jitter  float64
+		loss    float64
+	}{
+		{
+			name:  "all lost",
+			count: 4,
+			loss:  1,
+		},
+		{
+			name:    "single sample",
+			samples: []time.Duration{2 * time.Millisecond},
+			count:   2,
+			latency: 2,
+			loss:    0.5,
+		},
+		{
+			name:    "jitter",
+			samples: []time.Duration{time.Millisecond, 3 * time.Millisecond, 2 * time.Millisecond},
+			count:   3,
+			latency: 2,
+			jitter:  1.5,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := summarizeSamples(c.samples, c.count)
+			if math.Abs(got.LatencyMs-c.latency) > 1e-9 || math.Abs(got.JitterMs-c.jitter) > 1e-9 || math.Abs(got.LossRate-c.loss) > 1e-9 {
+				t.Errorf("want latency %f jitter %f loss %f, got %#v", c.latency, c.jitter, c.loss, got)
+			}
+		})
+	}
+}
@@ -81,6 +81,18 @@ type SHostOptions struct {
 	ChntpwPath string `help:"path to chntpw tool" default:"/usr/local/bin/chntpw.static"`
 	OvmfPath   string `help:"Path to OVMF.fd" default:"/opt/cloud/contrib/OVMF.fd"`
 
+	OvmfVarsPath        string `help:"Path to OVMF_VARS.fd template, OvmfPath is used as OVMF_CODE when set"`
+	OvmfSecbootCodePath string `help:"Path to OVMF_CODE.secboot.fd for secure boot guests" default:"/opt/cloud/contrib/OVMF_CODE.secboot.fd"`
+	OvmfSecbootVarsPath string `help:"Path to OVMF_VARS.secboot.fd with enrolled keys for secure boot guests" default:"/opt/cloud/contrib/OVMF_VARS.secboot.fd"`
+
+	OvmfConfidentialPath string `help:"Path to stateless OVMF.fd for sev-snp and tdx confidential guests" default:"/opt/cloud/contrib/OVMF.cvm.fd"`
+
+	VirtiofsdPath string `help:"Path to virtiofsd binary used by virtio-fs shared dirs" default:"/usr/libexec/virtiofsd"`
+	SwtpmPath     string `help:"Path to swtpm binary used by guest vTPM" default:"/usr/bin/swtpm"`
+
+	SevCbitpos         int `help:"C-bit position in page table entry of AMD SEV guests, see cpuid 0x8000001f ebx[5:0]" default:"47"`
+	SevReducedPhysBits int `help:"Number of physical address bits reduced by AMD SEV memory encryption" default:"1"`
+
 	LinuxDefaultRootUser    bool `help:"Default account for linux system is root"`
 	WindowsDefaultAdminUser bool `default:"true" help:"Default account for Windows system is Administrator"`
 
@@ -97,6 +109,10 @@ type SHostOptions struct {
 	DhcpLeaseTime   int      `default:"100663296" help:"DHCP lease time in seconds"`
 	DhcpRenewalTime int      `default:"67108864" help:"DHCP renewal time in seconds"`
 
+	GuestPxeTftpServer   string `help:"TFTP server serving iPXE firmware for guest network boot, default is the dhcp relay upstream"`
+	GuestPxeBiosBootFile string `default:"undionly.kpxe" help:"iPXE firmware for legacy BIOS guest network boot"`
+	GuestPxeUefiBootFile string `default:"ipxe.efi" help:"iPXE firmware for UEFI guest network boot"`
+
 	TunnelPaddingBytes int64 `help:"Specify tunnel padding bytes" default:"0"`
 
 	CheckSystemServices bool `help:"Check system services (ntpd, telegraf) on startup" default:"true"`
@@ -125,11 +141,24 @@ type SHostOptions struct {
 	MigrateExpectRate        int `default:"32" help:"Expected memory migration rate in MB/sec, default 32MBps"`
 	MinMigrateTimeoutSeconds int `default:"30" help:"minimal timeout for a migration process, default 30 seconds"`
 
+	LiveMigrateMultifdChannels          int    `default:"2" help:"live migrate multifd channels, 0 disables multifd"`
+	LiveMigrateMultifdCompression       string `default:"none" help:"live migrate multifd compression method" choices:"none|zlib|zstd"`
+	LiveMigrateAutoConverge             bool   `default:"true" help:"live migrate throttle guest cpu when memory dirty rate is too high"`
+	LiveMigrateCpuThrottleInitial       int    `default:"20" help:"live migrate auto converge initial cpu throttle percentage"`
+	LiveMigrateCpuThrottleIncrement     int    `default:"10" help:"live migrate auto converge cpu throttle increment percentage"`
+	LiveMigratePostcopySwitchoverRounds int    `default:"0" help:"switch live migration to postcopy after this many dirty memory sync rounds, 0 disables postcopy"`
+
 	SnapshotDirSuffix  string `help:"Snapshot dir name equal diskId concat snapshot dir suffix" default:"_snap"`
 	SnapshotRecycleDay int    `default:"1" help:"Snapshot Recycle delete Duration day"`
 
 	EnableTelegraf bool `default:"true" help:"enable send monitoring data to telegraf"`
 
+	NetworkProbeIntervalSeconds int `default:"300" help:"interval of probing network latency to peer hosts, 0 disabled"`
+	NetworkProbeCount           int `default:"5" help:"tcp connect count of each latency probe"`
+	NetworkProbeBandwidthSizeMb int `default:"0" help:"data size downloaded from peer host to estimate bandwidth, 0 disabled"`
+
+	FlowSampleIntervalSeconds int `default:"0" help:"interval of sampling guest connections from ovs conntrack and reporting to monitor service, 0 disabled"`
+
 	DisableSetCgroup bool `default:"false" help:"disable cgroup for guests"`
 
 	MaxReservedMemory int `default:"10240" help:"host reserved memory"`
@@ -183,6 +212,16 @@ type SHostOptions struct {
 
 	EnableVirtioRngDevice bool `help:"enable qemu virtio-rng device" default:"true"`
 
+	EnableMemoryBalloon         bool `help:"enable virtio-balloon device and reclaim memory from low priority guests when host memory is low" default:"false"`
+	BalloonCheckIntervalSeconds int  `help:"interval seconds of checking host available memory for memory balloon" default:"30"`
+	BalloonLowFreeMemMb         int  `help:"reclaim guest memory when host available memory is lower than this value" default:"2048"`
+	BalloonHighFreeMemMb        int  `help:"return reclaimed memory to guests when host available memory is higher than this value" default:"8192"`
+	BalloonDefaultMinMemPercent int  `help:"percent of guest memory that can not be reclaimed if guest has no balloon_min_mem_mb metadata" default:"50"`
+
+	QemuRestartPolicy        string `help:"Restart policy of crashed qemu process, no|on-failure" default:"on-failure"`
+	QemuRestartMaxRetries    int    `help:"Max restart times of crashed qemu process in restart window" default:"3"`
+	QemuRestartWindowSeconds int    `help:"Window seconds of counting qemu crash restarts" default:"600"`
+
 	RestrictQemuImgConvertWorker bool `help:"restrict qemu-img convert worker" default:"false"`
 
 	DefaultLiveMigrateDowntime float32 `help:"allow downtime in seconds for live migrate" default:"5.0"`
@@ -195,6 +234,19 @@ type SHostOptions struct {
 	MaxHotplugVCpuCount int  `help:"maximal possible vCPU count that the platform kvm supports"`
 	PcieRootPortCount   int  `help:"pcie root port count" default:"2"`
 	EnableQemuDebugLog  bool `help:"enable qemu debug logs" default:"false"`
+
+	// 复用迁移使用的 pki 目录, 为 QMP/HMP monitor 及 VNC/SPICE 开启 TLS
+	EnableMonitorTls         bool `help:"wrap qemu QMP/HMP monitor sockets with TLS" default:"false"`
+	EnableDisplayTls         bool `help:"enable TLS on VNC/SPICE listeners, console proxy must support VeNCrypt/SPICE TLS" default:"false"`
+	MonitorTlsOnly           bool `help:"refuse plaintext monitor connections, guests started without TLS monitors must be restarted" default:"false"`
+	GuestTlsCertRotateDays   int  `help:"rotate guest qemu TLS server/client certificates older than this days, 0 to disable" default:"90"`
+	GuestTlsCertCheckMinutes int  `help:"interval minutes to check guest qemu TLS certificates for rotation" default:"60"`
+
+	// 通过 nftables 限制虚机 VNC/QMP/HMP/迁移/NBD 端口只允许平台服务网段访问
+	EnableMgmtFirewall     bool     `help:"restrict guest management ports with host nftables rules" default:"false"`
+	MgmtFirewallAllowCidrs []string `help:"cidrs allowed to access guest management ports, should contain control plane and host networks"`
+
+	EmulatorThreadCpus string `help:"host cpus for qemu emulator threads and iothreads of vcpu pinned guests, cpuset format e.g. 0-1, empty to use cpus not dedicated to vcpus"`
 }
 
 var (
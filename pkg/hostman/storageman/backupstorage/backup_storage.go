@@ -30,6 +30,7 @@ type IBackupStorage interface {
 	CopyBackupTo(targetFilename string, backupId string) error
 	RemoveBackup(backupId string) error
 	IsExists(backupId string) (bool, error)
+	GetBackupChecksum(backupId string) (string, error)
 	ConvertTo(destPath string, format qemuimg.TImageFormat, backupId string) error
 	ConvertFrom(srcPath string, format qemuimg.TImageFormat, backupId string) (int, error)
 	InstancePack(ctx context.Context, packageName string, backupIds []string, metadata *api.InstanceBackupPackMetadata) (string, error)
@@ -513,6 +513,20 @@ func (s *SNFSBackupStorage) IsExists(backupId string) (bool, error) {
 	return fileutils2.Exists(filename), nil
 }
 
+// GetBackupChecksum 计算备份文件的md5, 备份不存在时返回 errors.ErrNotFound
+func (s *SNFSBackupStorage) GetBackupChecksum(backupId string) (string, error) {
+	err := s.checkAndMount()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to checkAndMount")
+	}
+	defer s.unMount()
+	filename := path.Join(s.getBackupDir(), backupId)
+	if !fileutils2.Exists(filename) {
+		return "", errors.Wrapf(errors.ErrNotFound, "backup %s", filename)
+	}
+	return fileutils2.MD5(filename)
+}
+
 func (s *SNFSBackupStorage) IsOnline() (bool, string, error) {
 	err := s.checkAndMount()
 	if errors.Cause(err) == ErrorBackupStorageOffline {
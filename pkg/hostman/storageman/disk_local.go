@@ -443,6 +443,10 @@ func (d *SLocalDisk) DiskBackup(ctx context.Context, params interface{}) (jsonut
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to backup snapshot")
 	}
+	checksum, err := fileutils2.MD5(backupPath)
+	if err != nil {
+		log.Errorf("unable to calculate checksum of backup %s: %v", backupPath, err)
+	}
 	_, err = d.Storage.StorageBackup(ctx, &SStorageBackup{
 		BackupId:                diskBackup.BackupId,
 		BackupStorageId:         diskBackup.BackupStorageId,
@@ -453,6 +457,9 @@ func (d *SLocalDisk) DiskBackup(ctx context.Context, params interface{}) (jsonut
 	}
 	data := jsonutils.NewDict()
 	data.Set("size_mb", jsonutils.NewInt(int64(newImage.GetActualSizeMB())))
+	if len(checksum) > 0 {
+		data.Set("checksum", jsonutils.NewString(checksum))
+	}
 	return data, nil
 }
 
@@ -29,6 +29,7 @@ import (
 	"yunion.io/x/onecloud/pkg/appctx"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/backupstorage"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
@@ -250,6 +251,15 @@ func (d *SRBDDisk) DiskBackup(ctx context.Context, params interface{}) (jsonutil
 	}
 	data := jsonutils.NewDict()
 	data.Set("size_mb", jsonutils.NewInt(int64(sizeMb)))
+	backupStorage, err := backupstorage.GetBackupStorage(diskBackup.BackupStorageId, diskBackup.BackupStorageAccessInfo)
+	if err == nil {
+		checksum, err := backupStorage.GetBackupChecksum(diskBackup.BackupId)
+		if err != nil {
+			log.Errorf("unable to calculate checksum of backup %s: %v", diskBackup.BackupId, err)
+		} else {
+			data.Set("checksum", jsonutils.NewString(checksum))
+		}
+	}
 	return data, nil
 }
 
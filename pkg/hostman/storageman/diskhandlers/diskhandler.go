@@ -66,6 +66,12 @@ func AddDiskHandler(prefix string, app *appsrv.Application) {
 				fmt.Sprintf("%s/%s/%s", prefix, keyWord, seg),
 				auth.Authenticate(deleteImageCache))
 		}
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/image_cache/verify", prefix, keyWord),
+			auth.Authenticate(verifyImageCache))
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/image_cache/convert", prefix, keyWord),
+			auth.Authenticate(convertImageCache))
 
 		app.AddHandler("POST",
 			fmt.Sprintf("%s/%s/<storageId>/upload", prefix, keyWord),
@@ -112,9 +118,14 @@ func performImageCache(
 	}
 
 	var performTask workmanager.DelayTaskFunc
-	if performAction == "perfetch" {
+	switch performAction {
+	case "perfetch":
 		performTask = storagecache.PrefetchImageCache
-	} else {
+	case "verify":
+		performTask = storagecache.VerifyImageCache
+	case "convert":
+		performTask = storagecache.ConvertImageCache
+	default:
 		performTask = storagecache.DeleteImageCache
 	}
 
@@ -131,6 +142,14 @@ func deleteImageCache(ctx context.Context, w http.ResponseWriter, r *http.Reques
 
 }
 
+func verifyImageCache(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	performImageCache(ctx, w, r, "verify")
+}
+
+func convertImageCache(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	performImageCache(ctx, w, r, "convert")
+}
+
 func getDiskStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	params, _, _ := appsrv.FetchEnv(ctx, w, r)
 	var (
@@ -0,0 +1,206 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+// 本地缓存镜像支持转换的格式
+var convertibleImageFormats = []qemuimg.TImageFormat{qemuimg.QCOW2, qemuimg.RAW, qemuimg.VMDK}
+
+// 转换后镜像的描述, SourceChksum 用于判断源镜像重新下载后转换结果是否失效
+type SConvertedImageDesc struct {
+	Format       string `json:"format"`
+	Chksum       string `json:"chksum"`
+	SourceChksum string `json:"source_chksum"`
+	Size         int64  `json:"size"`
+}
+
+func (l *SLocalImageCache) getConvertedPath(format qemuimg.TImageFormat) string {
+	return fmt.Sprintf("%s.%s", l.GetPath(), format)
+}
+
+func (l *SLocalImageCache) loadConvertedDesc(format qemuimg.TImageFormat) *SConvertedImageDesc {
+	target := l.getConvertedPath(format)
+	if !fileutils2.Exists(target) {
+		return nil
+	}
+	content, err := fileutils2.FileGetContents(target + _INF_SUFFIX_)
+	if err != nil {
+		return nil
+	}
+	desc := &SConvertedImageDesc{}
+	if err := json.Unmarshal([]byte(content), desc); err != nil {
+		return nil
+	}
+	return desc
+}
+
+// ConvertTo 将缓存镜像转换为指定格式并缓存结果, 返回转换后的路径
+// 转换前校验源镜像md5, 转换后通过 qemu-img compare 校验内容, 输出raw时保持稀疏
+func (l *SLocalImageCache) ConvertTo(ctx context.Context, format qemuimg.TImageFormat) (string, error) {
+	if !isConvertibleImageFormat(format) {
+		return "", errors.Wrapf(errors.ErrNotSupported, "convert to %s", format)
+	}
+	desc := l.GetDesc()
+	if desc == nil {
+		return "", errors.Wrapf(errors.ErrInvalidStatus, "image cache %s not loaded", l.imageId)
+	}
+	srcFormat := qemuimg.String2ImageFormat(desc.Format)
+	if len(desc.Format) == 0 {
+		img, err := qemuimg.NewQemuImage(l.GetPath())
+		if err != nil {
+			return "", errors.Wrapf(err, "NewQemuImage(%s)", l.GetPath())
+		}
+		srcFormat = img.Format
+	}
+	if srcFormat == format {
+		return l.GetPath(), nil
+	}
+
+	l.convertLock.Lock()
+	defer l.convertLock.Unlock()
+
+	target := l.getConvertedPath(format)
+	if cDesc := l.loadConvertedDesc(format); cDesc != nil && cDesc.SourceChksum == desc.Chksum {
+		return target, nil
+	}
+
+	chksum, _, err := l.Verify()
+	if err != nil {
+		return "", errors.Wrap(err, "verify source")
+	}
+	if len(desc.Chksum) > 0 && chksum != desc.Chksum {
+		return "", errors.Wrapf(errors.ErrInvalidStatus, "image cache %s checksum mismatch, expect %s got %s", l.imageId, desc.Chksum, chksum)
+	}
+
+	tmpPath := target + _TMP_SUFFIX_
+	if fileutils2.Exists(tmpPath) {
+		syscall.Unlink(tmpPath)
+	}
+	srcInfo := qemuimg.SImageInfo{Path: l.GetPath(), Format: srcFormat, IoLevel: qemuimg.IONiceIdle}
+	destInfo := qemuimg.SImageInfo{Path: tmpPath, Format: format, IoLevel: qemuimg.IONiceIdle}
+	var workerOpts []string
+	if format == qemuimg.RAW {
+		// 跳过全零块, 生成稀疏文件
+		workerOpts = []string{"-W", "-S", "4k"}
+	}
+	log.Infof("convert image cache %s from %s to %s", l.imageId, srcFormat, format)
+	if err := qemuimg.Convert(srcInfo, destInfo, false, workerOpts); err != nil {
+		return "", errors.Wrapf(err, "convert %s to %s", l.imageId, format)
+	}
+	if err := qemuimg.Compare(srcInfo, destInfo); err != nil {
+		syscall.Unlink(tmpPath)
+		return "", errors.Wrapf(err, "verify converted %s", l.imageId)
+	}
+	targetChksum, err := fileutils2.MD5(tmpPath)
+	if err != nil {
+		syscall.Unlink(tmpPath)
+		return "", errors.Wrapf(err, "fileutils2.MD5(%s)", tmpPath)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		syscall.Unlink(tmpPath)
+		return "", errors.Wrapf(err, "rename %s", tmpPath)
+	}
+	cDesc := SConvertedImageDesc{
+		Format:       format.String(),
+		Chksum:       targetChksum,
+		SourceChksum: desc.Chksum,
+	}
+	if fi, err := os.Stat(target); err == nil {
+		cDesc.Size = fi.Size()
+	}
+	err = fileutils2.FilePutContents(target+_INF_SUFFIX_, jsonutils.Marshal(cDesc).PrettyString(), false)
+	if err != nil {
+		return "", errors.Wrapf(err, "fileutils2.FilePutContents(%s)", target+_INF_SUFFIX_)
+	}
+	return target, nil
+}
+
+// 删除所有格式转换的结果
+func (l *SLocalImageCache) removeConvertedImages() error {
+	for _, format := range convertibleImageFormats {
+		target := l.getConvertedPath(format)
+		for _, p := range []string{target, target + _INF_SUFFIX_, target + _TMP_SUFFIX_} {
+			if fileutils2.Exists(p) {
+				if err := syscall.Unlink(p); err != nil {
+					return errors.Wrapf(err, "unlink %s", p)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isConvertibleImageFormat(format qemuimg.TImageFormat) bool {
+	for _, f := range convertibleImageFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertImageCache 将缓存镜像转换为指定格式, 供LVM/RBD等存储直接使用, 避免重复从镜像服务下载
+func (c *SLocalImageCacheManager) ConvertImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	body, ok := data.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	input := api.CacheImageInput{}
+	body.Unmarshal(&input)
+	input.Zone = c.GetStorageManager().GetZoneId()
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+	format, _ := body.GetString("target_format")
+	if len(format) == 0 {
+		return nil, httperrors.NewMissingParameterError("target_format")
+	}
+	if !isConvertibleImageFormat(qemuimg.TImageFormat(format)) {
+		return nil, httperrors.NewInputParameterError("unsupported target_format %s", format)
+	}
+
+	imgCache, err := c.AcquireImage(ctx, input, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "AcquireImage")
+	}
+	defer imgCache.Release()
+
+	path, err := imgCache.(*SLocalImageCache).ConvertTo(ctx, qemuimg.TImageFormat(format))
+	if err != nil {
+		return nil, errors.Wrapf(err, "ConvertTo %s", format)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("image_id", jsonutils.NewString(input.ImageId))
+	ret.Set("format", jsonutils.NewString(format))
+	ret.Set("path", jsonutils.NewString(path))
+	return ret, nil
+}
@@ -57,6 +57,8 @@ type SLocalImageCache struct {
 	lastCheckTime time.Time
 
 	remoteFile *remotefile.SRemoteFile
+
+	convertLock sync.Mutex
 }
 
 func NewLocalImageCache(imageId string, imagecacheManager IImageCacheManger) *SLocalImageCache {
@@ -238,6 +240,37 @@ func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput,
 	return _fetch()
 }
 
+// Verify 重新计算缓存文件的md5和大小
+func (l *SLocalImageCache) Verify() (string, int64, error) {
+	if !fileutils2.Exists(l.GetPath()) {
+		return "", 0, nil
+	}
+	chksum, err := fileutils2.MD5(l.GetPath())
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "fileutils2.MD5(%s)", l.GetPath())
+	}
+	return chksum, l.GetSize(), nil
+}
+
+// Invalidate 删除损坏的缓存文件, 下次 Acquire 时会重新下载
+func (l *SLocalImageCache) Invalidate() error {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+
+	if l.consumerCount > 0 {
+		return errors.Errorf("image cache %s is in use by %d consumers", l.imageId, l.consumerCount)
+	}
+	for _, p := range []string{l.GetPath(), l.GetInfPath()} {
+		if fileutils2.Exists(p) {
+			if err := syscall.Unlink(p); err != nil {
+				return errors.Wrapf(err, "unlink %s", p)
+			}
+		}
+	}
+	l.lastCheckTime = time.Time{}
+	return l.removeConvertedImages()
+}
+
 func (l *SLocalImageCache) Remove(ctx context.Context) error {
 	if fileutils2.Exists(l.GetPath()) {
 		if err := syscall.Unlink(l.GetPath()); err != nil {
@@ -254,6 +287,9 @@ func (l *SLocalImageCache) Remove(ctx context.Context) error {
 			return err
 		}
 	}
+	if err := l.removeConvertedImages(); err != nil {
+		return err
+	}
 
 	go func() {
 		_, err := modules.Storagecachedimages.Detach(hostutils.GetComputeSession(ctx),
@@ -84,8 +84,22 @@ func (r *SRbdImageCache) Acquire(ctx context.Context, input api.CacheImageInput,
 	r.imageName = localImageCache.GetName()
 	if r.Load() != nil {
 		log.Infof("convert local image %s to rbd pool %s", r.imageId, r.Manager.GetPath())
-		err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(),
-			"convert", "-W", "-m", "16", "-O", "raw", localImageCache.GetPath(), r.GetPath()).Run()
+		// 优先使用本地已转换的raw镜像, 多个rbd存储可复用同一份转换结果
+		srcPath, srcFormat := localImageCache.GetPath(), ""
+		if localCache, ok := localImageCache.(*SLocalImageCache); ok {
+			rawPath, err := localCache.ConvertTo(ctx, qemuimg.RAW)
+			if err != nil {
+				log.Warningf("convert local image %s to raw: %v", r.imageId, err)
+			} else {
+				srcPath, srcFormat = rawPath, string(qemuimg.RAW)
+			}
+		}
+		args := []string{"convert", "-W", "-m", "16"}
+		if len(srcFormat) > 0 {
+			args = append(args, "-f", srcFormat)
+		}
+		args = append(args, "-O", "raw", srcPath, r.GetPath())
+		err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(), args...).Run()
 		if err != nil {
 			return errors.Wrapf(err, "convert loca image %s to rbd pool %s at host %s", r.imageId, r.Manager.GetPath(), options.HostOptions.Hostname)
 		}
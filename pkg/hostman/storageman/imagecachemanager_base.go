@@ -52,6 +52,8 @@ type IImageCacheManger interface {
 	// for diskhandler
 	PrefetchImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error)
 	DeleteImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error)
+	VerifyImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error)
+	ConvertImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error)
 
 	AcquireImage(ctx context.Context, input api.CacheImageInput, callback func(progress, progressMbps float64, totalSizeMb int64)) (IImageCache, error)
 	ReleaseImage(ctx context.Context, imageId string)
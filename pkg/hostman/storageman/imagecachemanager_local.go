@@ -16,6 +16,7 @@ package storageman
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 
@@ -114,6 +115,74 @@ func (c *SLocalImageCacheManager) DeleteImageCache(ctx context.Context, data int
 	return nil, c.removeImage(ctx, imageId)
 }
 
+// VerifyImageCache 校验缓存镜像的md5, 不一致时可选择从镜像服务重新下载
+func (c *SLocalImageCacheManager) VerifyImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	body, ok := data.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	input := api.CacheImageInput{}
+	body.Unmarshal(&input)
+	input.Zone = c.GetStorageManager().GetZoneId()
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+	repair := jsonutils.QueryBoolean(body, "repair", false)
+
+	ret := api.IntegrityVerifyResult{ImageId: input.ImageId}
+	c.lock.LockRawObject(ctx, "image-cache", input.ImageId)
+	img, ok := c.cachedImages[input.ImageId]
+	c.lock.ReleaseRawObject(ctx, "image-cache", input.ImageId)
+	if !ok {
+		ret.Status = api.INTEGRITY_VERIFY_MISSING
+	} else {
+		localImg := img.(*SLocalImageCache)
+		chksum, size, err := localImg.Verify()
+		if err != nil {
+			return nil, errors.Wrapf(err, "verify %s", input.ImageId)
+		}
+		ret.Checksum, ret.Size = chksum, size
+		if desc := localImg.GetDesc(); desc != nil {
+			ret.ExpectChecksum = desc.Chksum
+		}
+		if len(input.Checksum) > 0 {
+			ret.ExpectChecksum = input.Checksum
+		}
+		switch {
+		case size == 0:
+			ret.Status = api.INTEGRITY_VERIFY_MISSING
+		case len(ret.ExpectChecksum) > 0 && ret.ExpectChecksum != chksum:
+			ret.Status = api.INTEGRITY_VERIFY_CORRUPTED
+			ret.Reason = fmt.Sprintf("checksum mismatch, expect %s got %s", ret.ExpectChecksum, chksum)
+		default:
+			ret.Status = api.INTEGRITY_VERIFY_OK
+		}
+	}
+	if ret.Status == api.INTEGRITY_VERIFY_OK || !repair {
+		return jsonutils.Marshal(ret), nil
+	}
+
+	log.Warningf("image cache %s is %s, refetch from source", input.ImageId, ret.Status)
+	c.lock.LockRawObject(ctx, "image-cache", input.ImageId)
+	img, ok = c.cachedImages[input.ImageId]
+	c.lock.ReleaseRawObject(ctx, "image-cache", input.ImageId)
+	if ok {
+		if err := img.(*SLocalImageCache).Invalidate(); err != nil {
+			return nil, errors.Wrapf(err, "invalidate %s", input.ImageId)
+		}
+	}
+	imgCache, err := c.AcquireImage(ctx, input, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "refetch %s", input.ImageId)
+	}
+	defer imgCache.Release()
+	if desc := imgCache.GetDesc(); desc != nil {
+		ret.Checksum = desc.Chksum
+	}
+	ret.Status = api.INTEGRITY_VERIFY_REPAIRED
+	return jsonutils.Marshal(ret), nil
+}
+
 func (c *SLocalImageCacheManager) removeImage(ctx context.Context, imageId string) error {
 	c.lock.LockRawObject(ctx, "image-cache", imageId)
 	defer c.lock.ReleaseRawObject(ctx, "image-cache", imageId)
@@ -135,6 +135,14 @@ func (c *SRbdImageCacheManager) PrefetchImageCache(ctx context.Context, data int
 	return jsonutils.Marshal(ret), nil
 }
 
+func (c *SRbdImageCacheManager) VerifyImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	return nil, errors.Wrap(errors.ErrNotSupported, "VerifyImageCache")
+}
+
+func (c *SRbdImageCacheManager) ConvertImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
+	return nil, errors.Wrap(errors.ErrNotSupported, "ConvertImageCache")
+}
+
 func (c *SRbdImageCacheManager) DeleteImageCache(ctx context.Context, data interface{}) (jsonutils.JSONObject, error) {
 	body, ok := data.(*jsonutils.JSONDict)
 	if !ok {
@@ -175,8 +175,24 @@ func (s *SLocalStorage) storageBackupRecovery(ctx context.Context, sbParams *SSt
 	if err != nil {
 		return nil, err
 	}
-	backupPath := path.Join(s.GetBackupDir(), sbParams.BackupId)
-	return nil, backupStorage.CopyBackupTo(backupPath, sbParams.BackupId)
+	backupId := sbParams.BackupId
+	for len(backupId) > 0 {
+		backupPath := path.Join(s.GetBackupDir(), backupId)
+		err = backupStorage.CopyBackupTo(backupPath, backupId)
+		if err != nil {
+			return nil, err
+		}
+		// 增量备份需要同时恢复备份链上的父备份
+		backupId = ""
+		img, err := qemuimg.NewQemuImage(backupPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "NewQemuImage %s", backupPath)
+		}
+		if len(img.BackFilePath) > 0 && path.Dir(img.BackFilePath) == s.GetBackupDir() && !fileutils2.Exists(img.BackFilePath) {
+			backupId = path.Base(img.BackFilePath)
+		}
+	}
+	return nil, nil
 }
 
 func (s *SLocalStorage) StorageBackupRecovery(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
@@ -70,6 +70,9 @@ func AddStorageHandler(prefix string, app *appsrv.Application) {
 		app.AddHandler("POST",
 			fmt.Sprintf("%s/%s/sync-backup", prefix, keyWords),
 			auth.Authenticate(storageSyncBackup))
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/verify-backup", prefix, keyWords),
+			auth.Authenticate(storageVerifyBackup))
 		app.AddHandler("POST",
 			fmt.Sprintf("%s/%s/pack-instance-backup", prefix, keyWords),
 			auth.Authenticate(storagePackInstanceBackup))
@@ -427,6 +430,56 @@ func deleteBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject
 	return nil, nil
 }
 
+type sVerifyBackup struct {
+	storageman.SStorageBackup
+	Checksum string
+}
+
+func storageVerifyBackup(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, _, body := appsrv.FetchEnv(ctx, w, r)
+	if !checkOptions(ctx, w, body, "backup_id", "backup_storage_id", "backup_storage_access_info") {
+		return
+	}
+	backupId, _ := body.GetString("backup_id")
+	backupStorageId, _ := body.GetString("backup_storage_id")
+	backupStorageAccessInfo, _ := body.Get("backup_storage_access_info")
+	checksum, _ := body.GetString("checksum")
+	hostutils.DelayTask(ctx, verifyBackup, &sVerifyBackup{
+		SStorageBackup: storageman.SStorageBackup{
+			BackupId:                backupId,
+			BackupStorageId:         backupStorageId,
+			BackupStorageAccessInfo: backupStorageAccessInfo.(*jsonutils.JSONDict),
+		},
+		Checksum: checksum,
+	})
+	hostutils.ResponseOk(ctx, w)
+}
+
+func verifyBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	vbParams := params.(*sVerifyBackup)
+	backupStorage, err := backupstorage.GetBackupStorage(vbParams.BackupStorageId, vbParams.BackupStorageAccessInfo)
+	if err != nil {
+		return nil, err
+	}
+	ret := compute.IntegrityVerifyResult{
+		BackupId:       vbParams.BackupId,
+		ExpectChecksum: vbParams.Checksum,
+	}
+	ret.Checksum, err = backupStorage.GetBackupChecksum(vbParams.BackupId)
+	switch {
+	case errors.Cause(err) == errors.ErrNotFound:
+		ret.Status = compute.INTEGRITY_VERIFY_MISSING
+	case err != nil:
+		return nil, errors.Wrapf(err, "GetBackupChecksum %s", vbParams.BackupId)
+	case len(ret.ExpectChecksum) > 0 && ret.ExpectChecksum != ret.Checksum:
+		ret.Status = compute.INTEGRITY_VERIFY_CORRUPTED
+		ret.Reason = fmt.Sprintf("checksum mismatch, expect %s got %s", ret.ExpectChecksum, ret.Checksum)
+	default:
+		ret.Status = compute.INTEGRITY_VERIFY_OK
+	}
+	return jsonutils.Marshal(ret), nil
+}
+
 func storageDeleteSnapshots(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	params, _, body := appsrv.FetchEnv(ctx, w, r)
 	var storageId = params["<storageId>"]
@@ -0,0 +1,294 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// 多架构镜像, 一个镜像可关联其他架构的镜像版本, 创建主机时按宿主机/套餐架构自动选择
+type SImageArchVariantManager struct {
+	db.SResourceBaseManager
+}
+
+var ImageArchVariantManager *SImageArchVariantManager
+
+func init() {
+	ImageArchVariantManager = &SImageArchVariantManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SImageArchVariant{},
+			"image_arch_variants_tbl",
+			"image_arch_variant",
+			"image_arch_variants",
+		),
+	}
+	ImageArchVariantManager.SetVirtualObject(ImageArchVariantManager)
+}
+
+type SImageArchVariant struct {
+	db.SResourceBase
+
+	// 多架构镜像ID
+	ImageId string `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	// 归一化后的架构, x86 或 arm
+	OsArch string `width:"16" charset:"ascii" nullable:"false" primary:"true"`
+	// 对应架构的镜像ID
+	VariantImageId string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+}
+
+// normalizeImageArch 将 x86_64/aarch64 等具体架构归一化为 x86 或 arm
+func normalizeImageArch(osArch string) string {
+	osArch = strings.ToLower(osArch)
+	if apis.IsARM(osArch) || strings.Contains(osArch, "aarch") || strings.Contains(osArch, "arm") {
+		return apis.OS_ARCH_ARM
+	}
+	return apis.OS_ARCH_X86
+}
+
+func (self *SImage) getOsArch() string {
+	if len(self.OsArch) > 0 {
+		return self.OsArch
+	}
+	props, err := ImagePropertyManager.GetProperties(self.Id)
+	if err != nil {
+		return ""
+	}
+	return props[api.IMAGE_OS_ARCH]
+}
+
+func (manager *SImageArchVariantManager) fetchVariants(imageId string) ([]SImageArchVariant, error) {
+	variants := []SImageArchVariant{}
+	err := db.FetchModelObjects(manager, manager.Query().Equals("image_id", imageId).Asc("os_arch"), &variants)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch arch variants")
+	}
+	return variants, nil
+}
+
+func (manager *SImageArchVariantManager) fetchVariant(imageId, osArch string) (*SImageArchVariant, error) {
+	q := manager.Query().Equals("image_id", imageId).Equals("os_arch", normalizeImageArch(osArch))
+	variant := &SImageArchVariant{}
+	variant.SetModelManager(manager, variant)
+	err := q.First(variant)
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (manager *SImageArchVariantManager) isVariantOrManifest(imageId string) (bool, error) {
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.Equals(q.Field("image_id"), imageId),
+		sqlchemy.Equals(q.Field("variant_image_id"), imageId),
+	))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+func (manager *SImageArchVariantManager) removeImageVariants(ctx context.Context, userCred mcclient.TokenCredential, imageId string) error {
+	variants, err := manager.fetchVariants(imageId)
+	if err != nil {
+		return err
+	}
+	for i := range variants {
+		err := variants[i].Delete(ctx, userCred)
+		if err != nil {
+			return errors.Wrapf(err, "delete arch variant %s", variants[i].OsArch)
+		}
+	}
+	return nil
+}
+
+func (variant *SImageArchVariant) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return db.DeleteModel(ctx, userCred, variant)
+}
+
+func (self *SImage) getArchVariants() []api.ImageArchVariant {
+	variants, err := ImageArchVariantManager.fetchVariants(self.Id)
+	if err != nil || len(variants) == 0 {
+		return nil
+	}
+	ret := []api.ImageArchVariant{{
+		OsArch:    normalizeImageArch(self.getOsArch()),
+		ImageId:   self.Id,
+		ImageName: self.Name,
+	}}
+	for i := range variants {
+		v := api.ImageArchVariant{
+			OsArch:  variants[i].OsArch,
+			ImageId: variants[i].VariantImageId,
+		}
+		if img, _ := ImageManager.FetchById(variants[i].VariantImageId); img != nil {
+			v.ImageName = img.GetName()
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// 为镜像添加其他架构的版本
+func (self *SImage) PerformAddArchVariant(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageArchVariantAddInput,
+) (jsonutils.JSONObject, error) {
+	if len(input.VariantImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("variant_image_id")
+	}
+	imgObj, err := ImageManager.FetchByIdOrName(userCred, input.VariantImageId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(ImageManager.Keyword(), input.VariantImageId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	img := imgObj.(*SImage)
+	if img.Id == self.Id {
+		return nil, httperrors.NewInputParameterError("image cannot be a variant of itself")
+	}
+	if img.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("image %s status %s", img.Name, img.Status)
+	}
+	imgArch := img.getOsArch()
+	if len(imgArch) == 0 {
+		return nil, httperrors.NewInputParameterError("os_arch of image %s is not set", img.Name)
+	}
+	osArch := normalizeImageArch(imgArch)
+	if osArch == normalizeImageArch(self.getOsArch()) {
+		return nil, httperrors.NewConflictError("image %s has the same arch %s", img.Name, osArch)
+	}
+	// 不允许嵌套, 变体镜像本身不能是多架构镜像或其他镜像的变体
+	used, err := ImageArchVariantManager.isVariantOrManifest(img.Id)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if used {
+		return nil, httperrors.NewConflictError("image %s is already a multi-arch image or variant", img.Name)
+	}
+	cnt, err := ImageArchVariantManager.Query().Equals("variant_image_id", self.Id).CountWithError()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return nil, httperrors.NewConflictError("image %s is a variant of another image", self.Name)
+	}
+
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	if cur, err := ImageArchVariantManager.fetchVariant(self.Id, osArch); err == nil {
+		_, err := db.Update(cur, func() error {
+			cur.VariantImageId = img.Id
+			return nil
+		})
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	} else if errors.Cause(err) == sql.ErrNoRows {
+		variant := &SImageArchVariant{
+			ImageId:        self.Id,
+			OsArch:         osArch,
+			VariantImageId: img.Id,
+		}
+		variant.SetModelManager(ImageArchVariantManager, variant)
+		err := ImageArchVariantManager.TableSpec().Insert(ctx, variant)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(errors.Wrap(err, "insert arch variant"))
+		}
+	} else {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	notes := map[string]string{"os_arch": osArch, "variant_image_id": img.Id}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_UPDATE, notes, userCred, true)
+	return nil, nil
+}
+
+// 移除镜像的某个架构版本
+func (self *SImage) PerformRemoveArchVariant(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageArchVariantRemoveInput,
+) (jsonutils.JSONObject, error) {
+	if len(input.OsArch) == 0 {
+		return nil, httperrors.NewMissingParameterError("os_arch")
+	}
+	variant, err := ImageArchVariantManager.fetchVariant(self.Id, input.OsArch)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2("arch_variant", input.OsArch)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	err = variant.Delete(ctx, userCred)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	notes := map[string]string{"os_arch": variant.OsArch, "variant_image_id": variant.VariantImageId}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, notes, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_UPDATE, notes, userCred, true)
+	return nil, nil
+}
+
+// 获取与目标架构匹配的镜像, 镜像自身架构匹配时返回自身
+func (self *SImage) GetDetailsArchVariant(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	input api.ImageArchVariantInput,
+) (*api.ImageArchVariant, error) {
+	if len(input.OsArch) == 0 {
+		return nil, httperrors.NewMissingParameterError("os_arch")
+	}
+	osArch := normalizeImageArch(input.OsArch)
+	if normalizeImageArch(self.getOsArch()) == osArch {
+		return &api.ImageArchVariant{OsArch: osArch, ImageId: self.Id, ImageName: self.Name}, nil
+	}
+	variant, err := ImageArchVariantManager.fetchVariant(self.Id, osArch)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError("image %s has no variant for arch %s", self.Name, osArch)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	imgObj, err := ImageManager.FetchById(variant.VariantImageId)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrapf(err, "fetch variant image %s", variant.VariantImageId))
+	}
+	img := imgObj.(*SImage)
+	if img.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("variant image %s status %s", img.Name, img.Status)
+	}
+	return &api.ImageArchVariant{OsArch: osArch, ImageId: img.Id, ImageName: img.Name}, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestNormalizeImageArch(t *testing.T) {
+	cases := []struct {
+		arch string
+		want string
+	}{
+		{"", "x86"},
+		{"x86_64", "x86"},
+		{"i386", "x86"},
+		{"aarch64", "arm"},
+		{"AArch64", "arm"},
+		{"arm", "arm"},
+		{"armv7l", "arm"},
+	}
+	for _, c := range cases {
+		if got := normalizeImageArch(c.arch); got != c.want {
+			t.Errorf("normalizeImageArch(%q) = %q, want %q", c.arch, got, c.want)
+		}
+	}
+}
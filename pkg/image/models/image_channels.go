@@ -0,0 +1,611 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// 镜像发布频道, 镜像按阶段(例如 dev->staging->prod)逐级晋升,
+// 创建虚拟机时可以通过 <频道名称>-<阶段> 引用当前区域该阶段发布的镜像
+type SImageChannelManager struct {
+	db.SSharableVirtualResourceBaseManager
+}
+
+var ImageChannelManager *SImageChannelManager
+
+func init() {
+	ImageChannelManager = &SImageChannelManager{
+		SSharableVirtualResourceBaseManager: db.NewSharableVirtualResourceBaseManager(
+			SImageChannel{},
+			"image_channels_tbl",
+			"image_channel",
+			"image_channels",
+		),
+	}
+	ImageChannelManager.SetVirtualObject(ImageChannelManager)
+}
+
+type SImageChannel struct {
+	db.SSharableVirtualResourceBase
+
+	// 发布阶段, 逗号分隔, 按晋升顺序排列
+	Stages string `width:"256" charset:"ascii" nullable:"false" list:"user"`
+	// 需要审批的阶段, 逗号分隔
+	ApprovalStages string `width:"256" charset:"ascii" nullable:"true" list:"user"`
+}
+
+var imageChannelStageReg = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func validateImageChannelStages(stages []string) error {
+	for i, stage := range stages {
+		if !imageChannelStageReg.MatchString(stage) {
+			return httperrors.NewInputParameterError("invalid stage %q, only lowercase letters, digits and underscore allowed", stage)
+		}
+		if utils.IsInStringArray(stage, stages[:i]) {
+			return httperrors.NewDuplicateNameError("stage", stage)
+		}
+	}
+	return nil
+}
+
+func (manager *SImageChannelManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.ImageChannelCreateInput,
+) (api.ImageChannelCreateInput, error) {
+	var err error
+	input.SharableVirtualResourceCreateInput, err = manager.SSharableVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.SharableVirtualResourceCreateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SSharableVirtualResourceBaseManager.ValidateCreateData")
+	}
+	if len(input.Stages) == 0 {
+		input.Stages = api.IMAGE_CHANNEL_DEFAULT_STAGES
+	}
+	if err := validateImageChannelStages(input.Stages); err != nil {
+		return input, err
+	}
+	for _, stage := range input.ApprovalStages {
+		if !utils.IsInStringArray(stage, input.Stages) {
+			return input, httperrors.NewInputParameterError("approval stage %s not in stages", stage)
+		}
+	}
+	return input, nil
+}
+
+func (channel *SImageChannel) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	input := api.ImageChannelCreateInput{}
+	data.Unmarshal(&input)
+	channel.Stages = strings.Join(input.Stages, ",")
+	channel.ApprovalStages = strings.Join(input.ApprovalStages, ",")
+	return channel.SSharableVirtualResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (channel *SImageChannel) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageChannelUpdateInput) (api.ImageChannelUpdateInput, error) {
+	var err error
+	input.SharableVirtualResourceBaseUpdateInput, err = channel.SSharableVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.SharableVirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SSharableVirtualResourceBase.ValidateUpdateData")
+	}
+	if input.ApprovalStages != nil {
+		stages := channel.GetStages()
+		for _, stage := range input.ApprovalStages {
+			if !utils.IsInStringArray(stage, stages) {
+				return input, httperrors.NewInputParameterError("approval stage %s not in stages", stage)
+			}
+		}
+	}
+	return input, nil
+}
+
+func (channel *SImageChannel) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	channel.SSharableVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+	if !data.Contains("approval_stages") {
+		return
+	}
+	input := api.ImageChannelUpdateInput{}
+	data.Unmarshal(&input)
+	db.Update(channel, func() error {
+		channel.ApprovalStages = strings.Join(input.ApprovalStages, ",")
+		return nil
+	})
+}
+
+func (channel *SImageChannel) GetStages() []string {
+	if len(channel.Stages) == 0 {
+		return nil
+	}
+	return strings.Split(channel.Stages, ",")
+}
+
+func (channel *SImageChannel) isApprovalRequired(stage string) bool {
+	return len(channel.ApprovalStages) > 0 && utils.IsInStringArray(stage, strings.Split(channel.ApprovalStages, ","))
+}
+
+func (manager *SImageChannelManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageChannelListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SSharableVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.SharableVirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SSharableVirtualResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ImageId) > 0 {
+		sq := ImageChannelStageManager.Query("channel_id").Equals("image_id", query.ImageId).SubQuery()
+		q = q.In("id", sq)
+	}
+	return q, nil
+}
+
+func (manager *SImageChannelManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ImageChannelDetails {
+	rows := make([]api.ImageChannelDetails, len(objs))
+	virtRows := manager.SSharableVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.ImageChannelDetails{
+			SharableVirtualResourceDetails: virtRows[i],
+		}
+		channel := objs[i].(*SImageChannel)
+		rows[i].StageImages, _ = channel.getStageImages()
+		rows[i].PendingPromotions, _ = ImageChannelPromotionManager.Query().
+			Equals("channel_id", channel.Id).Equals("status", api.IMAGE_CHANNEL_PROMOTION_PENDING).CountWithError()
+	}
+	return rows
+}
+
+func (channel *SImageChannel) getStageImages() ([]api.ImageChannelStageImage, error) {
+	stages, err := ImageChannelStageManager.fetchChannelStages(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+	ret := []api.ImageChannelStageImage{}
+	for _, name := range channel.GetStages() {
+		stage, ok := stages[name]
+		if !ok {
+			continue
+		}
+		out := api.ImageChannelStageImage{
+			Stage:      name,
+			ImageId:    stage.ImageId,
+			PromotedAt: stage.PromotedAt,
+			PromotedBy: stage.PromotedBy,
+		}
+		if img, _ := ImageManager.FetchById(stage.ImageId); img != nil {
+			out.ImageName = img.GetName()
+		}
+		ret = append(ret, out)
+	}
+	return ret, nil
+}
+
+func (channel *SImageChannel) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	cnt, err := ImageChannelPromotionManager.Query().Equals("channel_id", channel.Id).
+		Equals("status", api.IMAGE_CHANNEL_PROMOTION_PENDING).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "query pending promotions")
+	}
+	if cnt > 0 {
+		return httperrors.NewNotEmptyError("channel has %d pending promotions", cnt)
+	}
+	return channel.SSharableVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (channel *SImageChannel) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	stages, err := ImageChannelStageManager.fetchChannelStages(channel.Id)
+	if err != nil {
+		return err
+	}
+	for _, stage := range stages {
+		if err := stage.Delete(ctx, userCred); err != nil {
+			return errors.Wrapf(err, "delete stage %s", stage.Stage)
+		}
+	}
+	promotions := []SImageChannelPromotion{}
+	err = db.FetchModelObjects(ImageChannelPromotionManager, ImageChannelPromotionManager.Query().Equals("channel_id", channel.Id), &promotions)
+	if err != nil {
+		return errors.Wrap(err, "fetch promotions")
+	}
+	for i := range promotions {
+		if err := promotions[i].Delete(ctx, userCred); err != nil {
+			return errors.Wrap(err, "delete promotion")
+		}
+	}
+	return channel.SSharableVirtualResourceBase.Delete(ctx, userCred)
+}
+
+// 晋升镜像到指定阶段, 需要审批的阶段生成待审批记录
+func (channel *SImageChannel) PerformPromote(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageChannelPromoteInput) (jsonutils.JSONObject, error) {
+	stages := channel.GetStages()
+	idx := -1
+	for i := range stages {
+		if stages[i] == input.Stage {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, httperrors.NewInputParameterError("invalid stage %s, available stages %s", input.Stage, channel.Stages)
+	}
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+	imgObj, err := ImageManager.FetchByIdOrName(userCred, input.ImageId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(ImageManager.Keyword(), input.ImageId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	img := imgObj.(*SImage)
+	if img.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("image %s status %s", img.Name, img.Status)
+	}
+
+	lockman.LockObject(ctx, channel)
+	defer lockman.ReleaseObject(ctx, channel)
+
+	current, err := ImageChannelStageManager.fetchChannelStages(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+	if cur, ok := current[input.Stage]; ok && cur.ImageId == img.Id {
+		return nil, httperrors.NewConflictError("image %s already promoted to %s", img.Name, input.Stage)
+	}
+	fromStage := ""
+	if idx > 0 {
+		fromStage = stages[idx-1]
+		if prev, ok := current[fromStage]; !ok || prev.ImageId != img.Id {
+			return nil, httperrors.NewBadRequestError("image %s must be promoted to %s before %s", img.Name, fromStage, input.Stage)
+		}
+	}
+	cnt, err := ImageChannelPromotionManager.Query().Equals("channel_id", channel.Id).Equals("stage", input.Stage).
+		Equals("status", api.IMAGE_CHANNEL_PROMOTION_PENDING).CountWithError()
+	if err != nil {
+		return nil, errors.Wrap(err, "query pending promotions")
+	}
+	if cnt > 0 {
+		return nil, httperrors.NewConflictError("stage %s has pending promotion", input.Stage)
+	}
+
+	promotion := &SImageChannelPromotion{
+		ChannelId:     channel.Id,
+		ImageId:       img.Id,
+		FromStage:     fromStage,
+		Stage:         input.Stage,
+		Status:        api.IMAGE_CHANNEL_PROMOTION_PENDING,
+		RequestedBy:   userCred.GetUserName(),
+		RequestedById: userCred.GetUserId(),
+		Reason:        input.Reason,
+	}
+	promotion.SetModelManager(ImageChannelPromotionManager, promotion)
+	if !channel.isApprovalRequired(input.Stage) {
+		promotion.Status = api.IMAGE_CHANNEL_PROMOTION_APPROVED
+		promotion.ReviewedBy = userCred.GetUserName()
+	}
+	if err := ImageChannelPromotionManager.TableSpec().Insert(ctx, promotion); err != nil {
+		return nil, errors.Wrap(err, "insert promotion")
+	}
+	if promotion.Status == api.IMAGE_CHANNEL_PROMOTION_APPROVED {
+		if err := channel.applyPromotion(ctx, userCred, promotion); err != nil {
+			return nil, err
+		}
+	} else {
+		db.OpsLog.LogEvent(channel, db.ACT_UPDATE, promotion.GetShortDesc(ctx), userCred)
+	}
+	return jsonutils.Marshal(promotion), nil
+}
+
+func (channel *SImageChannel) fetchPendingPromotion(promotionId string) (*SImageChannelPromotion, error) {
+	if len(promotionId) == 0 {
+		return nil, httperrors.NewMissingParameterError("promotion_id")
+	}
+	promotion := &SImageChannelPromotion{}
+	promotion.SetModelManager(ImageChannelPromotionManager, promotion)
+	err := ImageChannelPromotionManager.Query().Equals("id", promotionId).Equals("channel_id", channel.Id).First(promotion)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(ImageChannelPromotionManager.Keyword(), promotionId)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if promotion.Status != api.IMAGE_CHANNEL_PROMOTION_PENDING {
+		return nil, httperrors.NewInvalidStatusError("promotion is %s", promotion.Status)
+	}
+	return promotion, nil
+}
+
+// 审批通过待晋升的镜像, 申请人不能审批自己的晋升申请
+func (channel *SImageChannel) PerformApprove(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageChannelApproveInput) (jsonutils.JSONObject, error) {
+	lockman.LockObject(ctx, channel)
+	defer lockman.ReleaseObject(ctx, channel)
+
+	promotion, err := channel.fetchPendingPromotion(input.PromotionId)
+	if err != nil {
+		return nil, err
+	}
+	if promotion.RequestedById == userCred.GetUserId() {
+		return nil, httperrors.NewForbiddenError("promotion requester can not approve it")
+	}
+	// 审批期间上一阶段的镜像可能已被替换
+	if len(promotion.FromStage) > 0 {
+		current, err := ImageChannelStageManager.fetchChannelStages(channel.Id)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := current[promotion.FromStage]; !ok || prev.ImageId != promotion.ImageId {
+			return nil, httperrors.NewConflictError("image is no longer in stage %s", promotion.FromStage)
+		}
+	}
+	_, err = db.Update(promotion, func() error {
+		promotion.Status = api.IMAGE_CHANNEL_PROMOTION_APPROVED
+		promotion.ReviewedBy = userCred.GetUserName()
+		promotion.ReviewReason = input.Reason
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update promotion")
+	}
+	return nil, channel.applyPromotion(ctx, userCred, promotion)
+}
+
+func (channel *SImageChannel) PerformReject(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ImageChannelApproveInput) (jsonutils.JSONObject, error) {
+	promotion, err := channel.fetchPendingPromotion(input.PromotionId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Update(promotion, func() error {
+		promotion.Status = api.IMAGE_CHANNEL_PROMOTION_REJECTED
+		promotion.ReviewedBy = userCred.GetUserName()
+		promotion.ReviewReason = input.Reason
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update promotion")
+	}
+	logclient.AddActionLogWithContext(ctx, channel, logclient.ACT_REJECT_PROMOTION, promotion.GetShortDesc(ctx), userCred, true)
+	return nil, nil
+}
+
+func (channel *SImageChannel) applyPromotion(ctx context.Context, userCred mcclient.TokenCredential, promotion *SImageChannelPromotion) error {
+	err := ImageChannelStageManager.setStageImage(ctx, channel.Id, promotion.Stage, promotion.ImageId, userCred.GetUserName())
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, channel, logclient.ACT_PROMOTE_IMAGE, err, userCred, false)
+		return errors.Wrap(err, "setStageImage")
+	}
+	logclient.AddActionLogWithContext(ctx, channel, logclient.ACT_PROMOTE_IMAGE, promotion.GetShortDesc(ctx), userCred, true)
+	return nil
+}
+
+func (channel *SImageChannel) GetDetailsPromotions(ctx context.Context, userCred mcclient.TokenCredential, input api.ImageChannelPromotionsInput) (jsonutils.JSONObject, error) {
+	q := ImageChannelPromotionManager.Query().Equals("channel_id", channel.Id).Desc("created_at")
+	if len(input.Status) > 0 {
+		q = q.Equals("status", input.Status)
+	}
+	promotions := []SImageChannelPromotion{}
+	err := db.FetchModelObjects(ImageChannelPromotionManager, q, &promotions)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch promotions")
+	}
+	ret := make([]api.ImageChannelPromotionDetails, len(promotions))
+	for i := range promotions {
+		jsonutils.Update(&ret[i].SImageChannelPromotion, &promotions[i])
+		if img, _ := ImageManager.FetchById(promotions[i].ImageId); img != nil {
+			ret[i].ImageName = img.GetName()
+		}
+	}
+	out := jsonutils.NewDict()
+	out.Set("promotions", jsonutils.Marshal(ret))
+	out.Set("total", jsonutils.NewInt(int64(len(ret))))
+	return out, nil
+}
+
+// 解析 <频道名称>-<阶段> 形式的频道引用
+func parseImageChannelRef(ref string) (string, string, error) {
+	pos := strings.LastIndex(ref, "-")
+	if pos <= 0 || pos == len(ref)-1 {
+		return "", "", httperrors.NewInputParameterError("invalid channel reference %q, should be <channel>-<stage>", ref)
+	}
+	return ref[:pos], ref[pos+1:], nil
+}
+
+// GET /image_channels/resolve?ref=ubuntu22-prod
+func (manager *SImageChannelManager) GetPropertyResolve(ctx context.Context, userCred mcclient.TokenCredential, input api.ImageChannelResolveInput) (*api.ImageChannelResolveOutput, error) {
+	out := &api.ImageChannelResolveOutput{}
+	name, stage, err := parseImageChannelRef(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := manager.FetchByIdOrName(userCred, name)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(manager.Keyword(), name)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	channel := obj.(*SImageChannel)
+	if !utils.IsInStringArray(stage, channel.GetStages()) {
+		return nil, httperrors.NewInputParameterError("channel %s has no stage %s", channel.Name, stage)
+	}
+	stages, err := ImageChannelStageManager.fetchChannelStages(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur, ok := stages[stage]
+	if !ok {
+		return nil, httperrors.NewNotFoundError("no image promoted to %s of channel %s", stage, channel.Name)
+	}
+	out.ChannelId = channel.Id
+	out.Channel = channel.Name
+	out.Stage = stage
+	out.ImageId = cur.ImageId
+	if img, _ := ImageManager.FetchById(cur.ImageId); img != nil {
+		out.ImageName = img.GetName()
+	}
+	return out, nil
+}
+
+// 频道各阶段当前发布的镜像
+type SImageChannelStageManager struct {
+	db.SResourceBaseManager
+}
+
+var ImageChannelStageManager *SImageChannelStageManager
+
+func init() {
+	ImageChannelStageManager = &SImageChannelStageManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SImageChannelStage{},
+			"image_channel_stages_tbl",
+			"image_channel_stage",
+			"image_channel_stages",
+		),
+	}
+	ImageChannelStageManager.SetVirtualObject(ImageChannelStageManager)
+}
+
+type SImageChannelStage struct {
+	db.SResourceBase
+
+	ChannelId  string    `width:"36" charset:"ascii" nullable:"false" primary:"true"`
+	Stage      string    `width:"32" charset:"ascii" nullable:"false" primary:"true"`
+	ImageId    string    `width:"36" charset:"ascii" nullable:"false" index:"true"`
+	PromotedAt time.Time `nullable:"false"`
+	PromotedBy string    `width:"128" charset:"utf8" nullable:"true"`
+}
+
+func (manager *SImageChannelStageManager) fetchChannelStages(channelId string) (map[string]*SImageChannelStage, error) {
+	stages := []SImageChannelStage{}
+	err := db.FetchModelObjects(manager, manager.Query().Equals("channel_id", channelId), &stages)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch channel stages")
+	}
+	ret := map[string]*SImageChannelStage{}
+	for i := range stages {
+		ret[stages[i].Stage] = &stages[i]
+	}
+	return ret, nil
+}
+
+func (manager *SImageChannelStageManager) setStageImage(ctx context.Context, channelId, stage, imageId, user string) error {
+	current, err := manager.fetchChannelStages(channelId)
+	if err != nil {
+		return err
+	}
+	if cur, ok := current[stage]; ok {
+		_, err := db.Update(cur, func() error {
+			cur.ImageId = imageId
+			cur.PromotedAt = time.Now().UTC()
+			cur.PromotedBy = user
+			return nil
+		})
+		return err
+	}
+	s := &SImageChannelStage{
+		ChannelId:  channelId,
+		Stage:      stage,
+		ImageId:    imageId,
+		PromotedAt: time.Now().UTC(),
+		PromotedBy: user,
+	}
+	s.SetModelManager(manager, s)
+	return manager.TableSpec().Insert(ctx, s)
+}
+
+func (manager *SImageChannelStageManager) getImageChannelStages(imageId string) ([]SImageChannelStage, error) {
+	stages := []SImageChannelStage{}
+	err := db.FetchModelObjects(manager, manager.Query().Equals("image_id", imageId), &stages)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch channel stages")
+	}
+	return stages, nil
+}
+
+func (stage *SImageChannelStage) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return db.DeleteModel(ctx, userCred, stage)
+}
+
+// 镜像晋升记录, 包括待审批及已审批/拒绝的晋升
+type SImageChannelPromotionManager struct {
+	db.SStandaloneAnonResourceBaseManager
+}
+
+var ImageChannelPromotionManager *SImageChannelPromotionManager
+
+func init() {
+	ImageChannelPromotionManager = &SImageChannelPromotionManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SImageChannelPromotion{},
+			"image_channel_promotions_tbl",
+			"image_channel_promotion",
+			"image_channel_promotions",
+		),
+	}
+	ImageChannelPromotionManager.SetVirtualObject(ImageChannelPromotionManager)
+}
+
+type SImageChannelPromotion struct {
+	db.SStandaloneAnonResourceBase
+
+	ChannelId     string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+	ImageId       string `width:"36" charset:"ascii" nullable:"false"`
+	FromStage     string `width:"32" charset:"ascii" nullable:"true"`
+	Stage         string `width:"32" charset:"ascii" nullable:"false"`
+	Status        string `width:"16" charset:"ascii" nullable:"false"`
+	RequestedBy   string `width:"128" charset:"utf8" nullable:"true"`
+	RequestedById string `width:"128" charset:"ascii" nullable:"true"`
+	Reason        string `width:"256" charset:"utf8" nullable:"true"`
+	ReviewedBy    string `width:"128" charset:"utf8" nullable:"true"`
+	ReviewReason  string `width:"256" charset:"utf8" nullable:"true"`
+}
+
+func (promotion *SImageChannelPromotion) GetShortDesc(ctx context.Context) *jsonutils.JSONDict {
+	desc := jsonutils.NewDict()
+	desc.Set("promotion_id", jsonutils.NewString(promotion.Id))
+	desc.Set("image_id", jsonutils.NewString(promotion.ImageId))
+	desc.Set("stage", jsonutils.NewString(promotion.Stage))
+	desc.Set("status", jsonutils.NewString(promotion.Status))
+	return desc
+}
+
+func (promotion *SImageChannelPromotion) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return db.DeleteModel(ctx, userCred, promotion)
+}
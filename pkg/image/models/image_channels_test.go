@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestParseImageChannelRef(t *testing.T) {
+	cases := []struct {
+		ref     string
+		channel string
+		stage   string
+		wantErr bool
+	}{
+		{"ubuntu22-prod", "ubuntu22", "prod", false},
+		{"centos-7-staging", "centos-7", "staging", false},
+		{"ubuntu22", "", "", true},
+		{"ubuntu22-", "", "", true},
+		{"-prod", "", "", true},
+	}
+	for _, c := range cases {
+		channel, stage, err := parseImageChannelRef(c.ref)
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseImageChannelRef(%s) error %v", c.ref, err)
+			continue
+		}
+		if channel != c.channel || stage != c.stage {
+			t.Errorf("parseImageChannelRef(%s) = %s, %s", c.ref, channel, stage)
+		}
+	}
+}
+
+func TestValidateImageChannelStages(t *testing.T) {
+	cases := []struct {
+		stages  []string
+		wantErr bool
+	}{
+		{[]string{"dev", "staging", "prod"}, false},
+		{[]string{"dev", "pre_prod", "prod"}, false},
+		{[]string{"dev", "dev"}, true},
+		{[]string{"pre-prod"}, true},
+		{[]string{"Prod"}, true},
+	}
+	for _, c := range cases {
+		if err := validateImageChannelStages(c.stages); (err != nil) != c.wantErr {
+			t.Errorf("validateImageChannelStages(%v) error %v", c.stages, err)
+		}
+	}
+}
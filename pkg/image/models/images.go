@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
@@ -55,6 +56,7 @@ import (
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/notify"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/pinyinutils"
 	"yunion.io/x/onecloud/pkg/util/procutils"
@@ -255,9 +257,31 @@ func (self *SImage) CustomizedGetDetailsBody(ctx context.Context, userCred mccli
 	defer rc.Close()
 
 	appParams := appsrv.AppContextGetParams(ctx)
-	appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	appParams.Response.Header().Set("Accept-Ranges", "bytes")
+	var reader io.Reader = rc
+	// 支持分段下载, 便于调用方并行拉取及断点续传
+	if rangeHdr := appParams.Request.Header.Get("Range"); len(rangeHdr) > 0 {
+		start, end, err := httputils.ParseByteRange(rangeHdr, size)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("%v", err)
+		}
+		if seeker, ok := rc.(io.Seeker); ok {
+			_, err = seeker.Seek(start, io.SeekStart)
+		} else {
+			_, err = io.CopyN(ioutil.Discard, rc, start)
+		}
+		if err != nil {
+			return nil, httperrors.NewGeneralError(errors.Wrapf(err, "skip to %d", start))
+		}
+		reader = io.LimitReader(rc, end-start+1)
+		appParams.Response.Header().Set("Content-Range", httputils.FormatContentRange(start, end, size))
+		appParams.Response.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		appParams.Response.WriteHeader(http.StatusPartialContent)
+	} else {
+		appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
 
-	_, err = streamutils.StreamPipe(rc, appParams.Response, false, nil)
+	_, err = streamutils.StreamPipe(reader, appParams.Response, false, nil)
 	if err != nil {
 		return nil, httperrors.NewGeneralError(err)
 	}
@@ -283,6 +307,7 @@ func (self *SImage) getMoreDetails(out api.ImageDetails) api.ImageDetails {
 	}
 	out.OssChecksum = ossChksum
 	out.DisableDelete = self.Protected.Bool()
+	out.ArchVariants = self.getArchVariants()
 	return out
 }
 
@@ -763,6 +788,20 @@ func (self *SImage) ValidateDeleteCondition(ctx context.Context, info jsonutils.
 	if self.IsStandard.IsTrue() {
 		return httperrors.NewForbiddenError("image is standard")
 	}
+	stages, err := ImageChannelStageManager.getImageChannelStages(self.Id)
+	if err != nil {
+		return errors.Wrap(err, "getImageChannelStages")
+	}
+	if len(stages) > 0 {
+		return httperrors.NewForbiddenError("image is promoted to stage %s of image channel %s", stages[0].Stage, stages[0].ChannelId)
+	}
+	cnt, err := ImageArchVariantManager.Query().Equals("variant_image_id", self.Id).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "count arch variants")
+	}
+	if cnt > 0 {
+		return httperrors.NewForbiddenError("image is an arch variant of other image")
+	}
 	// if self.IsShared() {
 	// 	return httperrors.NewForbiddenError("image is shared")
 	// }
@@ -775,6 +814,10 @@ func (self *SImage) Delete(ctx context.Context, userCred mcclient.TokenCredentia
 }
 
 func (self *SImage) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := ImageArchVariantManager.removeImageVariants(ctx, userCred, self.Id)
+	if err != nil {
+		return errors.Wrap(err, "removeImageVariants")
+	}
 	return self.SSharableVirtualResourceBase.Delete(ctx, userCred)
 }
 
@@ -62,6 +62,11 @@ func InitHandlers(app *appsrv.Application) {
 
 		models.GuestImageJointManager,
 
+		models.ImageChannelStageManager,
+		models.ImageChannelPromotionManager,
+
+		models.ImageArchVariantManager,
+
 		models.QuotaManager,
 		models.QuotaUsageManager,
 		models.QuotaPendingUsageManager,
@@ -75,6 +80,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageManager,
 
 		models.GuestImageManager,
+		models.ImageChannelManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
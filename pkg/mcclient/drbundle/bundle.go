@@ -0,0 +1,238 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drbundle
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	BUNDLE_VERSION = "v1"
+
+	LIST_PAGE_SIZE = 1024
+
+	IMPORT_STATUS_CREATED = "created"
+	IMPORT_STATUS_EXISTS  = "exists"
+	IMPORT_STATUS_PLANNED = "planned"
+	IMPORT_STATUS_FAILED  = "failed"
+)
+
+type SResourceDefinitions struct {
+	Kind  string                 `json:"kind"`
+	Items []jsonutils.JSONObject `json:"items"`
+}
+
+type SBundle struct {
+	Version    string                 `json:"version"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Resources  []SResourceDefinitions `json:"resources"`
+}
+
+type SImportResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Id     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Export 导出指定类型的资源定义, kindList 为空时导出全部类型
+func Export(s *mcclient.ClientSession, kindList []string) (*SBundle, error) {
+	if len(kindList) == 0 {
+		kindList = GetKinds()
+	}
+	bundle := &SBundle{
+		Version:    BUNDLE_VERSION,
+		ExportedAt: time.Now().UTC(),
+	}
+	for _, kind := range sortKinds(kindList) {
+		rk := getKind(kind)
+		if rk == nil {
+			return nil, httperrors.NewInputParameterError("unsupported kind %s, supported kinds: %s", kind, GetKinds())
+		}
+		items, err := rk.export(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "export %s", kind)
+		}
+		bundle.Resources = append(bundle.Resources, SResourceDefinitions{Kind: kind, Items: items})
+	}
+	return bundle, nil
+}
+
+func (rk *sResourceKind) export(s *mcclient.ClientSession) ([]jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	params.Set("scope", jsonutils.NewString("system"))
+	params.Set("details", jsonutils.JSONTrue)
+	for k, v := range rk.ListParams {
+		params.Set(k, jsonutils.NewString(v))
+	}
+	objs, err := listAll(s, rk.Manager, params)
+	if err != nil {
+		return nil, err
+	}
+	ret := []jsonutils.JSONObject{}
+	for _, obj := range objs {
+		if rk.Filter != nil && !rk.Filter(obj) {
+			continue
+		}
+		def := extractDefinition(obj, rk.Fields)
+		if rk.Extra != nil {
+			err := rk.Extra(s, obj, def)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s extra definition", obj.String())
+			}
+		}
+		ret = append(ret, def)
+	}
+	return ret, nil
+}
+
+// Import 按依赖顺序在当前环境中重建资源, 同名资源已存在时跳过
+// dryRun 时只检查资源是否存在, 不做创建
+func Import(s *mcclient.ClientSession, bundle *SBundle, dryRun bool) ([]SImportResult, error) {
+	if bundle.Version != BUNDLE_VERSION {
+		return nil, httperrors.NewInputParameterError("unsupported bundle version %q", bundle.Version)
+	}
+	resources := make([]SResourceDefinitions, len(bundle.Resources))
+	copy(resources, bundle.Resources)
+	sort.SliceStable(resources, func(i, j int) bool {
+		return kindIndex(resources[i].Kind) < kindIndex(resources[j].Kind)
+	})
+	results := []SImportResult{}
+	for _, res := range resources {
+		rk := getKind(res.Kind)
+		if rk == nil {
+			return results, httperrors.NewInputParameterError("unsupported kind %s", res.Kind)
+		}
+		for _, item := range res.Items {
+			results = append(results, rk.importItem(s, item, dryRun))
+		}
+	}
+	return results, nil
+}
+
+func (rk *sResourceKind) importItem(s *mcclient.ClientSession, item jsonutils.JSONObject, dryRun bool) SImportResult {
+	name, _ := item.GetString("name")
+	result := SImportResult{Kind: rk.Kind, Name: name}
+	id, err := rk.Manager.GetId(s, name, ownerQuery(item))
+	if err == nil {
+		result.Id = id
+		result.Status = IMPORT_STATUS_EXISTS
+		return result
+	}
+	if httputils.ErrorCode(err) != http.StatusNotFound {
+		result.Status = IMPORT_STATUS_FAILED
+		result.Error = err.Error()
+		return result
+	}
+	if dryRun {
+		result.Status = IMPORT_STATUS_PLANNED
+		return result
+	}
+	obj, err := rk.Manager.Create(s, item)
+	if err != nil {
+		log.Errorf("import %s %s fail %s", rk.Kind, name, err)
+		result.Status = IMPORT_STATUS_FAILED
+		result.Error = err.Error()
+		return result
+	}
+	result.Id, _ = obj.GetString("id")
+	result.Status = IMPORT_STATUS_CREATED
+	return result
+}
+
+// ownerQuery 按归属的项目或域查找同名资源
+func ownerQuery(item jsonutils.JSONObject) jsonutils.JSONObject {
+	query := jsonutils.NewDict()
+	query.Set("scope", jsonutils.NewString("system"))
+	for _, key := range []string{"project_domain_id", "project_id"} {
+		if v, _ := item.GetString(key); len(v) > 0 {
+			query.Set(key, jsonutils.NewString(v))
+		}
+	}
+	return query
+}
+
+// extractDefinition 从列表详情中提取创建参数, 忽略空值
+func extractDefinition(obj jsonutils.JSONObject, fields []sField) *jsonutils.JSONDict {
+	def := jsonutils.NewDict()
+	for _, f := range fields {
+		v, err := obj.Get(f.Source)
+		if err != nil || v == jsonutils.JSONNull {
+			continue
+		}
+		if str, ok := v.(*jsonutils.JSONString); ok && len(str.Value()) == 0 {
+			continue
+		}
+		def.Set(f.Key, v)
+	}
+	return def
+}
+
+// sortKinds 去重并按依赖顺序排列, 未知类型排在最后以便报错
+func sortKinds(kindList []string) []string {
+	ret := []string{}
+	for _, kind := range kindList {
+		dup := false
+		for _, k := range ret {
+			if k == kind {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			ret = append(ret, kind)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		ii, ji := kindIndex(ret[i]), kindIndex(ret[j])
+		if ii < 0 {
+			return false
+		}
+		if ji < 0 {
+			return true
+		}
+		return ii < ji
+	})
+	return ret
+}
+
+func listAll(s *mcclient.ClientSession, man IResourceManager, params *jsonutils.JSONDict) ([]jsonutils.JSONObject, error) {
+	ret := []jsonutils.JSONObject{}
+	query := params.Copy()
+	query.Set("limit", jsonutils.NewInt(LIST_PAGE_SIZE))
+	for {
+		query.Set("offset", jsonutils.NewInt(int64(len(ret))))
+		result, err := man.List(s, query)
+		if err != nil {
+			return nil, errors.Wrap(err, "List")
+		}
+		ret = append(ret, result.Data...)
+		if len(result.Data) == 0 || len(ret) >= result.Total {
+			break
+		}
+	}
+	return ret, nil
+}
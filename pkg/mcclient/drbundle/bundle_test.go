@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drbundle
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+)
+
+func TestExtractDefinition(t *testing.T) {
+	obj := jsonutils.Marshal(map[string]interface{}{
+		"id":          "abc",
+		"name":        "net1",
+		"description": "",
+		"wire":        "bcast0",
+		"wire_id":     "4b3c6a7e",
+		"vlan_id":     1,
+		"tenant":      "system",
+	})
+	fields := []sField{field("name"), field("description"), field("vlan_id"), ref("wire_id", "wire"), ref("project_id", "tenant"), field("guest_dns")}
+	want := jsonutils.Marshal(map[string]interface{}{
+		"name":       "net1",
+		"vlan_id":    1,
+		"wire_id":    "bcast0",
+		"project_id": "system",
+	})
+	got := extractDefinition(obj, fields)
+	if !got.Equals(want) {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
+
+func TestSortKinds(t *testing.T) {
+	cases := []struct {
+		in   []string
+		want []string
+	}{
+		{
+			in:   []string{KIND_NETWORK, KIND_PROJECT, KIND_WIRE, KIND_NETWORK},
+			want: []string{KIND_PROJECT, KIND_WIRE, KIND_NETWORK},
+		},
+		{
+			in:   []string{"unknown", KIND_IMAGE, KIND_DOMAIN},
+			want: []string{KIND_DOMAIN, KIND_IMAGE, "unknown"},
+		},
+	}
+	for _, c := range cases {
+		got := sortKinds(c.in)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("sortKinds(%v) want %v got %v", c.in, c.want, got)
+		}
+	}
+}
+
+func TestSecgroupRuleDefinitions(t *testing.T) {
+	rules := []jsonutils.JSONObject{
+		jsonutils.Marshal(map[string]interface{}{"id": "r1", "priority": 1, "protocol": "tcp", "ports": "22", "direction": "in", "cidr": "10.0.0.0/8", "action": "allow"}),
+		jsonutils.Marshal(map[string]interface{}{"id": "r2", "priority": 2, "protocol": "any", "direction": "in", "action": "allow", "peer_secgroup_id": "sg2"}),
+	}
+	got := secgroupRuleDefinitions(rules)
+	if len(got) != 1 {
+		t.Fatalf("want 1 rule got %d", len(got))
+	}
+	want := jsonutils.Marshal(map[string]interface{}{"priority": 1, "protocol": "tcp", "ports": "22", "direction": "in", "cidr": "10.0.0.0/8", "action": "allow"})
+	if !got[0].Equals(want) {
+		t.Errorf("want %s got %s", want, got[0])
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drbundle
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/image"
+)
+
+const (
+	KIND_DOMAIN     = "domains"
+	KIND_PROJECT    = "projects"
+	KIND_POLICY     = "policies"
+	KIND_VPC        = "vpcs"
+	KIND_WIRE       = "wires"
+	KIND_NETWORK    = "networks"
+	KIND_SECGROUP   = "secgroups"
+	KIND_SERVERSKU  = "serverskus"
+	KIND_IMAGE      = "images"
+	DEFAULT_VPC_ID  = "default"
+	ONECLOUD_VENDOR = "OneCloud"
+)
+
+type IResourceManager interface {
+	List(session *mcclient.ClientSession, params jsonutils.JSONObject) (*modulebase.ListResult, error)
+	GetId(session *mcclient.ClientSession, id string, params jsonutils.JSONObject) (string, error)
+	Create(session *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error)
+}
+
+// sField 导出字段, Key 为导入时的创建参数名, Source 为列表详情中的字段名
+// 引用其他资源时导出名称而不是ID, 以便在新环境中重新解析
+type sField struct {
+	Key    string
+	Source string
+}
+
+func field(key string) sField {
+	return sField{Key: key, Source: key}
+}
+
+func ref(key, source string) sField {
+	return sField{Key: key, Source: source}
+}
+
+type sResourceKind struct {
+	Kind       string
+	Manager    IResourceManager
+	ListParams map[string]string
+	Fields     []sField
+	// 返回 false 的资源不导出, 如系统内置资源
+	Filter func(item jsonutils.JSONObject) bool
+	// 补充列表详情之外的定义, 如安全组规则
+	Extra func(s *mcclient.ClientSession, item jsonutils.JSONObject, def *jsonutils.JSONDict) error
+}
+
+// kinds 按依赖顺序排列, 导入时依次创建
+var kinds = []sResourceKind{
+	{
+		Kind:    KIND_DOMAIN,
+		Manager: &identity.Domains,
+		Fields: []sField{
+			field("name"), field("displayname"), field("description"), field("enabled"),
+		},
+	},
+	{
+		Kind:    KIND_PROJECT,
+		Manager: &identity.Projects,
+		Fields: []sField{
+			field("name"), field("displayname"), field("description"),
+			ref("project_domain_id", "project_domain"),
+		},
+	},
+	{
+		Kind:    KIND_POLICY,
+		Manager: &identity.Policies,
+		Fields: []sField{
+			field("name"), field("description"), field("scope"), field("blob"), field("enabled"),
+			field("object_tags"), field("project_tags"), field("domain_tags"),
+			ref("project_domain_id", "project_domain"),
+		},
+		Filter: func(item jsonutils.JSONObject) bool {
+			return !jsonutils.QueryBoolean(item, "is_system", false)
+		},
+	},
+	{
+		Kind:       KIND_VPC,
+		Manager:    &compute.Vpcs,
+		ListParams: map[string]string{"cloud_env": "onpremise"},
+		Fields: []sField{
+			field("name"), field("description"), field("cidr_block"),
+			ref("cloudregion_id", "region"),
+			ref("project_domain_id", "project_domain"),
+		},
+		Filter: func(item jsonutils.JSONObject) bool {
+			id, _ := item.GetString("id")
+			return id != DEFAULT_VPC_ID
+		},
+	},
+	{
+		Kind:       KIND_WIRE,
+		Manager:    &compute.Wires,
+		ListParams: map[string]string{"cloud_env": "onpremise"},
+		Fields: []sField{
+			field("name"), field("description"), field("bandwidth"), field("mtu"),
+			ref("vpc_id", "vpc"), ref("zone_id", "zone"),
+			ref("project_domain_id", "project_domain"),
+		},
+	},
+	{
+		Kind:       KIND_NETWORK,
+		Manager:    &compute.Networks,
+		ListParams: map[string]string{"cloud_env": "onpremise"},
+		Fields: []sField{
+			field("name"), field("description"),
+			field("guest_ip_start"), field("guest_ip_end"), field("guest_ip_mask"),
+			field("guest_gateway"), field("guest_dns"), field("guest_domain"), field("guest_dhcp"),
+			field("vlan_id"), field("server_type"), field("alloc_policy"),
+			field("is_public"), field("public_scope"),
+			ref("wire_id", "wire"),
+			ref("project_domain_id", "project_domain"), ref("project_id", "tenant"),
+		},
+	},
+	{
+		Kind:       KIND_SECGROUP,
+		Manager:    &compute.SecGroups,
+		ListParams: map[string]string{"cloud_env": "onpremise"},
+		Fields: []sField{
+			field("name"), field("description"), field("is_public"), field("public_scope"),
+			ref("project_domain_id", "project_domain"), ref("project_id", "tenant"),
+		},
+		Extra: fetchSecgroupRules,
+	},
+	{
+		Kind:       KIND_SERVERSKU,
+		Manager:    &compute.ServerSkus,
+		ListParams: map[string]string{"provider": ONECLOUD_VENDOR},
+		Fields: []sField{
+			field("name"), field("description"), field("enabled"),
+			field("cpu_core_count"), field("memory_size_mb"),
+			field("instance_type_category"), field("instance_type_family"),
+			field("os_name"), field("sys_disk_type"), field("data_disk_types"),
+			ref("cloudregion_id", "region"), ref("zone_id", "zone"),
+		},
+	},
+	{
+		// 仅导出镜像元数据, 导入后为 queued 状态, 需重新上传镜像内容
+		Kind:    KIND_IMAGE,
+		Manager: &image.Images,
+		ListParams: map[string]string{
+			"is_guest_image": "false",
+		},
+		Fields: []sField{
+			field("name"), field("description"), field("disk_format"), field("os_arch"),
+			field("min_disk"), field("min_ram"), field("protected"), field("properties"),
+			field("is_public"), field("public_scope"),
+			ref("project_domain_id", "project_domain"), ref("project_id", "tenant"),
+		},
+	},
+}
+
+func GetKinds() []string {
+	ret := make([]string, len(kinds))
+	for i := range kinds {
+		ret[i] = kinds[i].Kind
+	}
+	return ret
+}
+
+func getKind(kind string) *sResourceKind {
+	for i := range kinds {
+		if kinds[i].Kind == kind {
+			return &kinds[i]
+		}
+	}
+	return nil
+}
+
+// kindIndex 返回资源类型在依赖顺序中的位置, 未知类型返回 -1
+func kindIndex(kind string) int {
+	for i := range kinds {
+		if kinds[i].Kind == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func fetchSecgroupRules(s *mcclient.ClientSession, item jsonutils.JSONObject, def *jsonutils.JSONDict) error {
+	id, _ := item.GetString("id")
+	params := jsonutils.NewDict()
+	params.Set("scope", jsonutils.NewString("system"))
+	params.Set("secgroup_id", jsonutils.NewString(id))
+	rules, err := listAll(s, &compute.SecGroupRules, params)
+	if err != nil {
+		return err
+	}
+	def.Set("rules", jsonutils.NewArray(secgroupRuleDefinitions(rules)...))
+	return nil
+}
+
+// secgroupRuleDefinitions 转换安全组规则, 引用对端安全组的规则依赖资源ID, 无法跨环境重建, 忽略
+func secgroupRuleDefinitions(rules []jsonutils.JSONObject) []jsonutils.JSONObject {
+	fields := []sField{
+		field("priority"), field("protocol"), field("ports"), field("direction"),
+		field("cidr"), field("action"), field("description"),
+	}
+	ret := []jsonutils.JSONObject{}
+	for _, rule := range rules {
+		if peer, _ := rule.GetString("peer_secgroup_id"); len(peer) > 0 {
+			continue
+		}
+		ret = append(ret, extractDefinition(rule, fields))
+	}
+	return ret
+}
@@ -32,6 +32,7 @@ func init() {
 			"Enabled", "Sync_Status",
 			"Last_Sync", "Last_Sync_End_At", "Auto_Sync",
 			"last_deep_sync_at",
+			"Last_Sync_Cost_Seconds", "Last_Sync_Error",
 		},
 		[]string{},
 		&Cloudproviders,
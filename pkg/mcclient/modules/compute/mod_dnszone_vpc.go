@@ -0,0 +1,39 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	DnsZoneVpcs modulebase.JointResourceManager
+)
+
+func init() {
+	DnsZoneVpcs = modules.NewJointComputeManager(
+		"dns_zonevpc", "dns_zonevpcs",
+		[]string{
+			"Dns_Zone_ID", "Dns_Zone",
+			"Vpc_ID", "Vpc",
+		},
+		[]string{},
+		&DnsZones,
+		&Vpcs,
+	)
+
+	modules.RegisterCompute(&DnsZoneVpcs)
+}
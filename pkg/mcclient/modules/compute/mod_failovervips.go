@@ -0,0 +1,35 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	FailoverVips modulebase.ResourceManager
+)
+
+func init() {
+	FailoverVips = modules.NewComputeManager("failover_vip", "failover_vips",
+		[]string{"ID", "Name", "Status", "Network_Id", "Ip_Addr",
+			"Active_Guest_Id", "Standby_Guest_Id", "Auto_Failover",
+			"Health_Check_Type", "Last_Failover_At", "Tenant",
+		},
+		[]string{})
+
+	modules.RegisterCompute(&FailoverVips)
+}
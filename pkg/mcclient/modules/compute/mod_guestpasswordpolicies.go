@@ -0,0 +1,35 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	GuestPasswordPolicies modulebase.ResourceManager
+)
+
+func init() {
+	GuestPasswordPolicies = modules.NewComputeManager("guest_password_policy", "guest_password_policies",
+		[]string{"ID", "Name", "Project_Domain", "Min_Length",
+			"Require_Digits", "Require_Lowercases", "Require_Uppercases",
+			"Require_Punctuats", "Expire_Days", "Remind_Days",
+		},
+		[]string{})
+
+	modules.RegisterCompute(&GuestPasswordPolicies)
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+type SHaManager struct {
+	modulebase.ResourceManager
+}
+
+func (this *SHaManager) GetStatus(s *mcclient.ClientSession) (jsonutils.JSONObject, error) {
+	return modulebase.Get(this.ResourceManager, s, "/ha/status", "")
+}
+
+func (this *SHaManager) Failover(s *mcclient.ClientSession) (jsonutils.JSONObject, error) {
+	return modulebase.Post(this.ResourceManager, s, "/ha/failover", jsonutils.NewDict(), "")
+}
+
+var (
+	Ha SHaManager
+)
+
+func init() {
+	Ha = SHaManager{
+		ResourceManager: modules.NewComputeManager("ha", "ha", []string{}, []string{}),
+	}
+	modules.RegisterCompute(&Ha)
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	Runbooks          modulebase.ResourceManager
+	RunbookExecutions modulebase.ResourceManager
+)
+
+func init() {
+	Runbooks = modules.NewComputeManager("runbook", "runbooks",
+		[]string{"ID", "Name", "Status", "Enabled",
+			"Require_Approval", "Cooldown_Minutes", "Execution_Count",
+		},
+		[]string{"Steps"})
+
+	RunbookExecutions = modules.NewComputeManager("runbook_execution", "runbook_executions",
+		[]string{"ID", "Name", "Status", "Runbook_Id", "Runbook",
+			"Guest_Id", "Guest", "Alert_Id", "Step_Index",
+			"Approved_By", "Started_At", "Finished_At",
+		},
+		[]string{"Params", "Results"})
+
+	modules.RegisterCompute(&Runbooks)
+	modules.RegisterCompute(&RunbookExecutions)
+}
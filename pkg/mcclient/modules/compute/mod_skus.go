@@ -66,7 +66,8 @@ func init() {
 			"Sys_disk_min_size_mb", "Sys_disk_max_size_mb", "Attached_disk_type",
 			"Attached_disk_size_gb", "Attached_disk_count", "Data_disk_types",
 			"Data_disk_max_count", "Nic_max_count", "Cloudregion_id", "Zone_id",
-			"Provider", "Postpaid_status", "Prepaid_status", "Region", "Region_ext_id", "Zone", "Zone_ext_id"},
+			"Provider", "Postpaid_status", "Prepaid_status", "Region", "Region_ext_id", "Zone", "Zone_ext_id",
+			"Spot_price", "Spot_interruption_rate"},
 		[]string{"Total_guest_count"})}
 
 	ElasticcacheSkus = ElasticcacheSkusManager{modules.NewComputeManager("elasticcachesku", "elasticcacheskus",
@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	SnapshotArchivePolicies modulebase.ResourceManager
+	SnapshotArchives        modulebase.ResourceManager
+)
+
+func init() {
+	SnapshotArchivePolicies = modules.NewComputeManager("snapshot_archive_policy", "snapshot_archive_policies",
+		[]string{"ID", "Name", "Status", "Enabled",
+			"Vault_Cloudaccount_Id", "Vault_Cloudaccount", "Retention_Days",
+			"Disk_Count", "Archive_Count",
+		},
+		[]string{})
+
+	SnapshotArchives = modules.NewComputeManager("snapshot_archive", "snapshot_archives",
+		[]string{"ID", "Name", "Status", "External_Id", "Policy_Id", "Policy",
+			"Snapshot_Id", "Snapshot", "Disk_Id", "Disk",
+			"Vault_Cloudaccount", "Size_Mb", "Archived_At", "Retain_Until",
+		},
+		[]string{"Source_Cloudaccount_Id", "Vault_Cloudaccount_Id", "Vault_Cloudprovider_Id"})
+
+	modules.RegisterCompute(&SnapshotArchivePolicies)
+	modules.RegisterCompute(&SnapshotArchives)
+}
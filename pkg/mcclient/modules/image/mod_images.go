@@ -584,6 +584,35 @@ func (this *ImageManager) Download2(s *mcclient.ClientSession, id string, format
 	}
 }
 
+// DownloadRange 下载镜像 [start, end] 区间的数据, 返回数据流及镜像总大小
+func (this *ImageManager) DownloadRange(s *mcclient.ClientSession, id string, format string, start, end int64) (io.ReadCloser, int64, error) {
+	query := jsonutils.NewDict()
+	if len(format) > 0 {
+		query.Add(jsonutils.NewString(format), "format")
+	}
+	path := fmt.Sprintf("/%s/%s", this.URLPath(), url.PathEscape(id))
+	if queryString := query.QueryString(); len(queryString) > 0 {
+		path = fmt.Sprintf("%s?%s", path, queryString)
+	}
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	resp, err := modulebase.RawRequest(this.ResourceManager, s, "GET", path, header, nil)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _, err = s.ParseJSONResponse("", resp, err)
+		return nil, -1, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, -1, httperrors.ErrNotSupported
+	}
+	_, _, size, err := httputils.ParseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		resp.Body.Close()
+		return nil, -1, err
+	}
+	return resp.Body, size, nil
+}
+
 var (
 	Images ImageManager
 )
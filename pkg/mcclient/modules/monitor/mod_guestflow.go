@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	GuestFlowManager *SGuestFlowManager
+)
+
+type SGuestFlowManager struct {
+	*modulebase.ResourceManager
+}
+
+func init() {
+	GuestFlowManager = NewGuestFlowManager()
+	modules.Register(GuestFlowManager)
+}
+
+func NewGuestFlowManager() *SGuestFlowManager {
+	m := modules.NewMonitorV2Manager("guestflow", "guestflows",
+		[]string{"id", "guest_id", "ip_addr", "direction", "peer_ip", "protocol", "port", "connections", "last_seen"},
+		[]string{"host_id", "vpc_id", "first_seen"})
+	return &SGuestFlowManager{
+		ResourceManager: &m,
+	}
+}
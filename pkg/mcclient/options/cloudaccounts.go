@@ -1123,6 +1123,16 @@ func (opts *ClouaccountProjectMappingOptions) Params() (jsonutils.JSONObject, er
 	return jsonutils.Marshal(map[string]string{"project_mapping_id": opts.ProjectMappingId}), nil
 }
 
+type CloudaccountDiagnoseOptions struct {
+	SCloudAccountIdOptions
+	Cloudregion string `help:"only diagnose specific region"`
+	WriteCheck  bool   `help:"check write permission by rewriting tags of an existing instance"`
+}
+
+func (opts *CloudaccountDiagnoseOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
 type SNutanixCloudAccountCreateOptions struct {
 	SCloudAccountCreateBaseOptions
 	SNutanixCredentialWithEnvironment
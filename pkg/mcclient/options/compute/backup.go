@@ -25,6 +25,7 @@ type DiskBackupListOptions struct {
 	DiskId           string `help:"disk id" json:"disk_id"`
 	BackupStorageId  string `help:"backup storage id" json:"backup_storage_id"`
 	IsInstanceBackup *bool  `help:"if part of instance backup" json:"is_instance_backup"`
+	ParentBackupId   string `help:"parent backup id of incremental backup" json:"parent_backup_id"`
 }
 
 func (opts *DiskBackupListOptions) Params() (jsonutils.JSONObject, error) {
@@ -56,6 +57,7 @@ type DiskBackupCreateOptions struct {
 	options.BaseCreateOptions
 	DISKID          string `help:"disk id" json:"disk_id"`
 	BACKUPSTORAGEID string `help:"back storage id" json:"backup_storage_id"`
+	BackupMode      string `help:"backup mode" choices:"full|incremental|auto" json:"backup_mode"`
 }
 
 func (opts *DiskBackupCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -89,6 +91,10 @@ func (opt *DiskBackupSyncstatusOptions) Params() (jsonutils.JSONObject, error) {
 	return nil, nil
 }
 
+type DiskBackupVerifyOptions struct {
+	DiskBackupIdOptions
+}
+
 type BackupStorageListOptions struct {
 	options.BaseListOptions
 }
@@ -0,0 +1,54 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type CloudaccountSyncProfileListOptions struct {
+	options.BaseListOptions
+	Cloudaccount string   `help:"ID or Name of cloudaccount" json:"cloudaccount_id"`
+	Resource     []string `help:"resource type"`
+}
+
+func (opts *CloudaccountSyncProfileListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type CloudaccountSyncProfileCreateOptions struct {
+	CLOUDACCOUNT string `help:"ID or Name of cloudaccount" json:"cloudaccount_id"`
+	RESOURCE     string `help:"resource type to sync, same as resources of sync range, or sku"`
+	INTERVAL     int    `help:"sync interval in seconds, at least 60" json:"interval_seconds"`
+}
+
+func (opts *CloudaccountSyncProfileCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type CloudaccountSyncProfileUpdateOptions struct {
+	options.BaseIdOptions
+	Interval int `help:"sync interval in seconds, at least 60" json:"interval_seconds"`
+}
+
+func (opts *CloudaccountSyncProfileUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	if opts.Interval > 0 {
+		params.Set("interval_seconds", jsonutils.NewInt(int64(opts.Interval)))
+	}
+	return params, nil
+}
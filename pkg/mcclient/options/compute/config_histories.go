@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ConfigHistoryListOptions struct {
+	options.BaseListOptions
+	ResourceType string `help:"resource type" choices:"servers|secgroups|loadbalancerlisteners"`
+	ResourceId   string `help:"resource id"`
+	Trigger      string `help:"record trigger" choices:"change|schedule"`
+	Since        string `help:"recorded after this time, e.g. 2023-01-01T00:00:00Z"`
+	Until        string `help:"recorded before this time"`
+}
+
+func (opts *ConfigHistoryListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ConfigHistoryDiffOptions struct {
+	RESOURCE_TYPE string `help:"resource type" choices:"servers|secgroups|loadbalancerlisteners"`
+	RESOURCE_ID   string `help:"resource id"`
+	FromVersion   int    `help:"compare from this version, used with --to-version"`
+	ToVersion     int    `help:"compare to this version"`
+	Since         string `help:"compare the version effective at this time, e.g. 2023-01-01T00:00:00Z"`
+	Until         string `help:"compare to the version effective at this time, default now"`
+}
+
+func (opts *ConfigHistoryDiffOptions) Property() string {
+	return "diff"
+}
+
+func (opts *ConfigHistoryDiffOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
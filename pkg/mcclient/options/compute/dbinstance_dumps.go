@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type DBInstanceDumpListOptions struct {
+	options.BaseListOptions
+	DBInstance string `help:"ID or Name of source dbinstance" json:"dbinstance_id"`
+	Bucket     string `help:"ID or Name of bucket" json:"bucket_id"`
+	Format     string `help:"dump format" choices:"mysqldump|pg_dump"`
+}
+
+func (opts *DBInstanceDumpListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DBInstanceDumpCreateOptions struct {
+	options.BaseCreateOptions
+	DBINSTANCE string   `help:"ID or Name of source dbinstance" json:"dbinstance_id"`
+	ACCOUNT    string   `help:"ID or Name of dbinstance account used to dump" json:"dbinstanceaccount_id"`
+	BUCKET     string   `help:"ID or Name of bucket to store the dump" json:"bucket_id"`
+	WORKER     string   `help:"ID or Name of kvm server with qemu-guest-agent and database clients to run the dump" json:"worker_server_id"`
+	Database   []string `help:"databases to dump, default all" json:"databases"`
+	KeyPrefix  string   `help:"object key prefix of the dump"`
+	Timeout    int      `help:"timeout in seconds, default 6 hours"`
+}
+
+func (opts *DBInstanceDumpCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type DBInstanceDumpRestoreOptions struct {
+	options.BaseIdOptions
+	DBINSTANCE string `help:"ID or Name of target dbinstance, could be on another provider" json:"dbinstance_id"`
+	ACCOUNT    string `help:"ID or Name of target dbinstance account used to restore" json:"dbinstanceaccount_id"`
+	Worker     string `help:"ID or Name of worker server, default the one used to dump" json:"worker_server_id"`
+	Timeout    int    `help:"timeout in seconds, default 6 hours"`
+}
+
+func (opts *DBInstanceDumpRestoreOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type DedicatedHostListOptions struct {
+	options.BaseListOptions
+	Zone     string   `help:"filter by zone id or name"`
+	HostType []string `help:"filter by dedicated host type"`
+}
+
+func (opts *DedicatedHostListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DedicatedHostCreateOptions struct {
+	options.BaseCreateOptions
+	ZONE     string `help:"zone id or name" json:"zone_id"`
+	MANAGER  string `help:"cloudprovider id or name" json:"manager_id"`
+	HOSTTYPE string `help:"dedicated host type, e.g. ddh.g6 of aliyun or c5 of aws" json:"host_type"`
+}
+
+func (opts *DedicatedHostCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
@@ -43,6 +43,8 @@ type EipCreateOptions struct {
 	Network    *string `help:"Network of the EIP"`
 	BgpType    *string `help:"BgpType of the EIP" positional:"false"`
 	ChargeType *string `help:"bandwidth charge type" choices:"traffic|bandwidth"`
+
+	SharedBandwidthId *string `help:"External id of shared bandwidth to join"`
 }
 
 func (opts *EipCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -42,11 +42,43 @@ func (opts *ExternalProjectIdOption) Params() (jsonutils.JSONObject, error) {
 
 type ExterProjectChagneProjectOptions struct {
 	ExternalProjectIdOption
-	PROJECT string
+	PROJECT  string
+	Priority *int `help:"mapping priority, the highest one is used when creating resources"`
 }
 
 func (opts *ExterProjectChagneProjectOptions) Params() (jsonutils.JSONObject, error) {
-	return jsonutils.Marshal(map[string]string{"project": opts.PROJECT}), nil
+	params := jsonutils.NewDict()
+	params.Set("project", jsonutils.NewString(opts.PROJECT))
+	if opts.Priority != nil {
+		params.Set("priority", jsonutils.NewInt(int64(*opts.Priority)))
+	}
+	return params, nil
+}
+
+type ExternalProjectUpdateOptions struct {
+	ExternalProjectIdOption
+	Priority *int `help:"mapping priority, the highest one is used when creating resources"`
+}
+
+func (opts *ExternalProjectUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	if opts.Priority != nil {
+		params.Set("priority", jsonutils.NewInt(int64(*opts.Priority)))
+	}
+	return params, nil
+}
+
+type ExternalProjectMigrateResourcesOptions struct {
+	ExternalProjectIdOption
+	ResourceTypes []string `help:"resource types to migrate, default all" choices:"server|disk|eip"`
+}
+
+func (opts *ExternalProjectMigrateResourcesOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	if len(opts.ResourceTypes) > 0 {
+		params.Set("resource_types", jsonutils.NewStringArray(opts.ResourceTypes))
+	}
+	return params, nil
 }
 
 type ExternalProjectCreateOptions struct {
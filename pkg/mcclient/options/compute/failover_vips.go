@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type FailoverVipListOptions struct {
+	options.BaseListOptions
+	Network string   `help:"filter by network"`
+	IpAddr  []string `help:"filter by vip address"`
+	GuestId string   `help:"filter by active or standby guest"`
+}
+
+func (opts *FailoverVipListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type FailoverVipHealthCheckOptions struct {
+	AutoFailover        *bool  `help:"auto failover when active guest health check failed" negative:"no_auto_failover"`
+	HealthCheckType     string `help:"health check type" choices:"guest_status|tcp"`
+	HealthCheckPort     *int   `help:"port of tcp health check"`
+	HealthCheckFailures *int   `help:"consecutive failures before failover"`
+}
+
+type FailoverVipCreateOptions struct {
+	options.BaseCreateOptions
+	NETWORK string `help:"network of the vip" json:"network_id"`
+	IpAddr  string `help:"vip address, allocate automatically if not specified"`
+
+	FailoverVipHealthCheckOptions
+}
+
+func (opts *FailoverVipCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type FailoverVipUpdateOptions struct {
+	options.BaseUpdateOptions
+
+	FailoverVipHealthCheckOptions
+}
+
+func (opts *FailoverVipUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type FailoverVipAttachOptions struct {
+	options.BaseIdOptions
+	ACTIVE  string `help:"active guest which the vip is bound to" json:"active_guest_id"`
+	STANDBY string `help:"standby guest which the vip fails over to" json:"standby_guest_id"`
+}
+
+func (opts *FailoverVipAttachOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type FailoverVipFailoverOptions struct {
+	options.BaseIdOptions
+	Force bool `help:"failover even if standby guest is not running"`
+}
+
+func (opts *FailoverVipFailoverOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
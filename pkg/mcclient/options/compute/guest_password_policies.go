@@ -0,0 +1,71 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type GuestPasswordPolicyListOptions struct {
+	options.BaseListOptions
+}
+
+func (opts *GuestPasswordPolicyListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type GuestPasswordPolicyCreateOptions struct {
+	options.BaseCreateOptions
+	ProjectDomain     string `json:"project_domain" help:"target domain"`
+	MinLength         int    `help:"minimal password length, at least 12"`
+	RequireDigits     *bool  `help:"password must contain digits" negative:"no_require_digits"`
+	RequireLowercases *bool  `help:"password must contain lowercase letters" negative:"no_require_lowercases"`
+	RequireUppercases *bool  `help:"password must contain uppercase letters" negative:"no_require_uppercases"`
+	RequirePunctuats  *bool  `help:"password must contain punctuations" negative:"no_require_punctuats"`
+	ExpireDays        int    `help:"password expire days, 0 means never expire"`
+	RemindDays        int    `help:"remind days before password expires"`
+}
+
+func (opts *GuestPasswordPolicyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type GuestPasswordPolicyUpdateOptions struct {
+	options.BaseUpdateOptions
+	MinLength         *int  `help:"minimal password length, at least 12"`
+	RequireDigits     *bool `help:"password must contain digits" negative:"no_require_digits"`
+	RequireLowercases *bool `help:"password must contain lowercase letters" negative:"no_require_lowercases"`
+	RequireUppercases *bool `help:"password must contain uppercase letters" negative:"no_require_uppercases"`
+	RequirePunctuats  *bool `help:"password must contain punctuations" negative:"no_require_punctuats"`
+	ExpireDays        *int  `help:"password expire days, 0 means never expire"`
+	RemindDays        *int  `help:"remind days before password expires"`
+}
+
+func (opts *GuestPasswordPolicyUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type ServerBatchRotatePasswordOptions struct {
+	ProjectDomain string   `json:"project_domain" help:"target domain"`
+	ServerIds     []string `help:"servers to rotate password, default all password expired servers in domain" json:"server_ids"`
+	ExpiredOnly   bool     `help:"only rotate servers whose password has expired"`
+	AutoStart     bool     `help:"auto start servers after deploy"`
+}
+
+func (opts *ServerBatchRotatePasswordOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
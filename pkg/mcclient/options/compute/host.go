@@ -15,7 +15,13 @@
 package compute
 
 import (
+	"io/ioutil"
+	"strings"
+
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 )
@@ -106,6 +112,40 @@ func (o *HostAutoMigrateOnHostDownOptions) Params() (jsonutils.JSONObject, error
 	return options.StructToParams(o)
 }
 
+type HostAdoptUnmanagedGuestsOptions struct {
+	options.BaseIdOptions
+	Server []string `help:"Nics of guest to adopt, eg: --server 52:54:00:11:22:33=10.0.0.2,52:54:00:11:22:34=10.0.1.2" required:"true"`
+}
+
+func (o *HostAdoptUnmanagedGuestsOptions) Params() (jsonutils.JSONObject, error) {
+	input := api.HostAdoptUnmanagedGuestsInput{}
+	for _, server := range o.Server {
+		conf := api.SLibvirtServerConfig{MacIp: map[string]string{}}
+		for _, nic := range strings.Split(server, ",") {
+			parts := strings.SplitN(nic, "=", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid nic %q, require <mac>=<ip>", nic)
+			}
+			conf.MacIp[parts[0]] = parts[1]
+		}
+		input.Servers = append(input.Servers, conf)
+	}
+	return jsonutils.Marshal(input), nil
+}
+
+type HostLibvirtXmlToDescOptions struct {
+	options.BaseIdOptions
+	FILE string `help:"Path of libvirt domain xml file"`
+}
+
+func (o *HostLibvirtXmlToDescOptions) Params() (jsonutils.JSONObject, error) {
+	content, err := ioutil.ReadFile(o.FILE)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", o.FILE)
+	}
+	return jsonutils.Marshal(api.SLibvirtXmlToDescInput{Xml: string(content)}), nil
+}
+
 type HostStatusStatisticsOptions struct {
 	HostListOptions
 	options.StatusStatisticsOptions
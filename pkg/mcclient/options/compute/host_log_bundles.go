@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type HostLogBundleListOptions struct {
+	options.BaseListOptions
+	Host   string `help:"ID or Name of host" json:"host_id"`
+	Server string `help:"ID or Name of server" json:"server_id"`
+	Bucket string `help:"ID or Name of bucket" json:"bucket_id"`
+}
+
+func (opts *HostLogBundleListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type HostLogBundleCreateOptions struct {
+	NAME       string `help:"name of log bundle"`
+	HOST       string `help:"ID or Name of host" json:"host_id"`
+	BUCKET     string `help:"ID or Name of bucket to store the bundle" json:"bucket_id"`
+	Server     string `help:"collect qemu log and desc files of this server" json:"server_id"`
+	IncludeOvs *bool  `help:"collect openvswitch bridges and flows, default true" negative:"no_include_ovs" json:"include_ovs"`
+	SinceHours int    `help:"collect logs of recent hours, default 24" json:"since_hours"`
+	KeyPrefix  string `help:"object key prefix of the bundle" json:"key_prefix"`
+}
+
+func (opts *HostLogBundleCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
+
+type HostLogBundleDownloadUrlOptions struct {
+	options.BaseIdOptions
+	ExpireSeconds int `help:"expire seconds of the download url, default 3600" json:"expire_seconds"`
+}
+
+func (opts *HostLogBundleDownloadUrlOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
@@ -35,6 +35,8 @@ type LoadbalancerListenerRuleCreateOptions struct {
 	RedirectScheme *string `json:",allowempty" choices:"http|https|"`
 	RedirectHost   *string `json:",allowempty"`
 	RedirectPath   *string `json:",allowempty"`
+
+	Condition string `help:"forward conditions in json, e.g. [{\"field\":\"http-header\",\"httpHeaderConfig\":{\"HttpHeaderName\":\"X-Env\",\"values\":[\"gray\"]}}]" json:"conditon"`
 }
 
 type LoadbalancerListenerRuleListOptions struct {
@@ -220,6 +220,17 @@ func (opts *LoadbalancerListenerActionStatusOptions) Params() (jsonutils.JSONObj
 	return options.StructToParams(opts)
 }
 
+type LoadbalancerListenerSetAccessLogOptions struct {
+	ID               string `json:"-"`
+	AccessLogStatus  string `choices:"on|off" positional:"true"`
+	AccessLogProject string `help:"huawei lts log group id, aliyun sls project or aws s3 bucket"`
+	AccessLogTopic   string `help:"huawei lts log topic id, aliyun sls logstore or aws s3 prefix"`
+}
+
+func (opts *LoadbalancerListenerSetAccessLogOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
+
 type LoadbalancerListenerGetBackendStatusOptions struct {
 	ID string `json:"-"`
 }
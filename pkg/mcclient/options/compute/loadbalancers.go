@@ -44,6 +44,8 @@ type LoadbalancerCreateOptions struct {
 	EipChargeType    string `json:"eip_charge_type"`
 	EipBgpType       string `json:"eip_bgp_type"`
 	EipAutoDellocate *bool  `json:"eip_auto_dellocate"`
+
+	SharedBandwidthId string `json:"shared_bandwidth_id" help:"External id of shared bandwidth the EIP joins"`
 }
 
 func (opts *LoadbalancerCreateOptions) Params() (jsonutils.JSONObject, error) {
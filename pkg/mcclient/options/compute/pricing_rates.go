@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type PricingRateListOptions struct {
+	options.BaseListOptions
+	ResourceType []string `help:"filter by resource type" choices:"vcpu|memory|storage|gpu"`
+	Spec         []string `help:"filter by spec"`
+	EffectiveAt  string   `help:"only list rates effective at this time, e.g. 2026-01-01T00:00:00Z"`
+}
+
+func (opts *PricingRateListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type PricingRateCreateOptions struct {
+	options.BaseCreateOptions
+	ResourceType  string  `help:"resource type" choices:"vcpu|memory|storage|gpu" required:"true"`
+	Spec          string  `help:"storage type for storage, gpu model for gpu, empty for default price"`
+	Price         float64 `help:"unit price, per hour for vcpu/memory(GB)/gpu, per GB month for storage" required:"true"`
+	Currency      string  `help:"currency, default CNY"`
+	EffectiveDate string  `help:"effective date, default now, e.g. 2026-01-01T00:00:00Z"`
+}
+
+func (opts *PricingRateCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type PricingRateUpdateOptions struct {
+	options.BaseUpdateOptions
+}
+
+func (opts *PricingRateUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type PricingEstimateOptions struct {
+	VcpuCount int      `help:"vcpu count"`
+	VmemSize  int      `help:"memory size in MB"`
+	Disk      []string `help:"disk size_mb[:storage_type], e.g. --disk 30720:local" json:"-"`
+	Gpu       []string `help:"gpu model[:count], e.g. --gpu 'Tesla T4:2'" json:"-"`
+	Count     int      `help:"server count" default:"1"`
+	Sku       string   `help:"public cloud sku id, used with --spot"`
+	Spot      bool     `help:"estimate spot instance cost of --sku"`
+}
+
+func (opts *PricingEstimateOptions) Property() string {
+	return "estimate"
+}
+
+func (opts *PricingEstimateOptions) Params() (jsonutils.JSONObject, error) {
+	input := api.PricingEstimateInput{
+		VcpuCount: opts.VcpuCount,
+		VmemSize:  opts.VmemSize,
+		Count:     opts.Count,
+		SkuId:     opts.Sku,
+		Spot:      opts.Spot,
+	}
+	for _, disk := range opts.Disk {
+		parts := strings.SplitN(disk, ":", 2)
+		size, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid disk %s", disk)
+		}
+		d := api.PricingEstimateDisk{SizeMb: size}
+		if len(parts) > 1 {
+			d.StorageType = parts[1]
+		}
+		input.Disks = append(input.Disks, d)
+	}
+	for _, gpu := range opts.Gpu {
+		g := api.PricingEstimateGpu{Model: gpu, Count: 1}
+		if pos := strings.LastIndexByte(gpu, ':'); pos > 0 {
+			count, err := strconv.Atoi(gpu[pos+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid gpu %s", gpu)
+			}
+			g.Model, g.Count = gpu[:pos], count
+		}
+		input.Gpus = append(input.Gpus, g)
+	}
+	return jsonutils.Marshal(input), nil
+}
+
+type PricingChargebackOptions struct {
+	ProjectId string `help:"project id or name, default current project for non-admin"`
+	StartTime string `help:"start time, default 30 days ago, e.g. 2026-01-01T00:00:00Z"`
+	EndTime   string `help:"end time, default now"`
+}
+
+func (opts *PricingChargebackOptions) Property() string {
+	return "chargeback"
+}
+
+func (opts *PricingChargebackOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ProjectPostureListOptions struct {
+	options.BaseListOptions
+	ProjectId string `help:"filter by project"`
+}
+
+func (opts *ProjectPostureListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ProjectPostureCreateOptions struct {
+	options.BaseCreateOptions
+	ProjectId       string   `help:"apply to project only, default apply to whole domain"`
+	DefaultSecgroup []string `help:"default secgroup when none is specified" json:"default_secgroups"`
+	Tag             []string `help:"mandatory tag, empty value means required from user, e.g. --tag env=prod --tag owner=" json:"-"`
+	EncryptKeyId    string   `help:"default disk encryption key"`
+	ImdsMode        string   `help:"metadata service mode" choices:"enabled|token_required|disabled"`
+	AllowedImage    []string `help:"allowed image, default allow all" json:"allowed_images"`
+}
+
+func (opts *ProjectPostureCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	if len(opts.Tag) > 0 {
+		tags, err := parseTagPolicyTags(opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("mandatory_tags", jsonutils.Marshal(tags))
+	}
+	return params, nil
+}
+
+type ProjectPostureUpdateOptions struct {
+	options.BaseUpdateOptions
+	DefaultSecgroup []string `help:"default secgroup when none is specified" json:"default_secgroups"`
+	Tag             []string `help:"mandatory tag, empty value means required from user, e.g. --tag env=prod --tag owner=" json:"-"`
+	EncryptKeyId    *string  `help:"default disk encryption key, empty string to clear"`
+	ImdsMode        string   `help:"metadata service mode" choices:"enabled|token_required|disabled"`
+	AllowedImage    []string `help:"allowed image" json:"allowed_images"`
+}
+
+func (opts *ProjectPostureUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	if len(opts.Tag) > 0 {
+		tags, err := parseTagPolicyTags(opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("mandatory_tags", jsonutils.Marshal(tags))
+	}
+	return params, nil
+}
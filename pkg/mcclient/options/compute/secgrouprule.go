@@ -32,6 +32,7 @@ type SecGroupRulesListOptions struct {
 	Action       string   `help:"filter Actin of rule" choices:"allow|deny"`
 	Ports        string   `help:"filter Ports of rule"`
 	Ip           string   `help:"filter cidr of rule"`
+	NotHitSince  string   `help:"filter rules not hit since the time, e.g. 2023-01-01T00:00:00Z"`
 }
 
 func (opts *SecGroupRulesListOptions) Params() (jsonutils.JSONObject, error) {
@@ -15,6 +15,7 @@
 package compute
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -392,8 +393,16 @@ type ServerCreateOptions struct {
 type ServerCreateOptionalOptions struct {
 	ServerConfigs
 
-	MemSpec        string `help:"Memory size Or Instance Type" metavar:"MEMSPEC" json:"-"`
-	EnableMemclean bool   `help:"clean guest memory after guest exit" json:"enable_memclean"`
+	MemSpec          string `help:"Memory size Or Instance Type" metavar:"MEMSPEC" json:"-"`
+	EnableMemclean   bool   `help:"clean guest memory after guest exit" json:"enable_memclean"`
+	EnableTpm        bool   `help:"enable vTPM 2.0 device, kvm only" json:"enable_tpm"`
+	EnableVirtioMem  bool   `help:"enable virtio-mem online memory resize, kvm only" json:"enable_virtio_mem"`
+	EnableSecureBoot bool   `help:"enable UEFI secure boot, implies UEFI bios and q35 machine, kvm only" json:"enable_secure_boot"`
+	ImdsMode         string `help:"metadata service mode, kvm only" choices:"enabled|token_required|disabled" json:"imds_mode"`
+	QosClass         string `help:"QoS class of server, default to the class of instance flavor, kvm only" choices:"guaranteed|burstable|best-effort" json:"qos_class"`
+	DedicatedHost    string `help:"Id or name of public cloud dedicated host to place server on" json:"dedicated_host_id"`
+
+	NetworkLatencyPeerHost []string `help:"Prefer hosts with low network latency to these hosts, kvm only" json:"network_latency_peer_hosts"`
 
 	Keypair          string   `help:"SSH Keypair"`
 	Password         string   `help:"Default user password"`
@@ -415,6 +424,7 @@ type ServerCreateOptionalOptions struct {
 	AutoStart        bool     `help:"Auto start server after it is created"`
 	Deploy           []string `help:"Specify deploy files in virtual server file system" json:"-"`
 	DeployTelegraf   bool     `help:"Deploy telegraf agent if guest os is supported"`
+	DeployVerify     bool     `help:"Verify port, agent and cloud-init after server is started"`
 	Group            []string `help:"Group ID or Name of virtual server"`
 	System           bool     `help:"Create a system VM, sysadmin ONLY option" json:"is_system"`
 	TaskNotify       *bool    `help:"Setup task notify" json:"-"`
@@ -501,6 +511,9 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 	if err != nil {
 		return nil, err
 	}
+	config.QosClass = opts.QosClass
+	config.DedicatedHostId = opts.DedicatedHost
+	config.NetworkLatencyPeerHosts = opts.NetworkLatencyPeerHost
 
 	params := &computeapi.ServerCreateInput{
 		ServerConfigs:      config,
@@ -530,6 +543,10 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		GuestImageID:       opts.GuestImageID,
 		Secgroups:          opts.Secgroups,
 		EnableMemclean:     opts.EnableMemclean,
+		EnableTpm:          opts.EnableTpm,
+		EnableVirtioMem:    opts.EnableVirtioMem,
+		EnableSecureBoot:   opts.EnableSecureBoot,
+		ImdsMode:           opts.ImdsMode,
 	}
 
 	if len(opts.EncryptKey) > 0 {
@@ -552,6 +569,7 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 	}
 	params.DeployConfigs = deployInfos
 	params.DeployTelegraf = opts.DeployTelegraf
+	params.DeployVerify = opts.DeployVerify
 
 	if len(opts.Boot) > 0 {
 		if opts.Boot == "disk" {
@@ -824,6 +842,86 @@ func (o *ServerQgaCommand) Params() (jsonutils.JSONObject, error) {
 	return options.StructToParams(o)
 }
 
+type ServerQgaExecOptions struct {
+	ServerIdOptions
+
+	PATH    string   `help:"Path or name of executable in guest" json:"path"`
+	Arg     []string `help:"Arguments of executable" json:"args"`
+	Env     []string `help:"Environment variables, eg: --env A=1" json:"env"`
+	Input   string   `help:"Data passed to stdin of process" json:"input"`
+	Timeout int      `help:"Seconds to wait for process exit, default 30" json:"timeout"`
+}
+
+func (o *ServerQgaExecOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerQgaFileWriteOptions struct {
+	ServerIdOptions
+
+	PATH    string `help:"Path of file in guest" json:"path"`
+	Content string `help:"File content" json:"content"`
+	File    string `help:"Local file to upload, conflict with --content" json:"-"`
+	Append  bool   `help:"Append to file instead of truncating" json:"append"`
+}
+
+func (o *ServerQgaFileWriteOptions) Params() (jsonutils.JSONObject, error) {
+	input := computeapi.ServerQgaFileWriteInput{Path: o.PATH, Content: o.Content, Append: o.Append}
+	if len(o.File) > 0 {
+		content, err := ioutil.ReadFile(o.File)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %v", o.File, err)
+		}
+		input.Content = base64.StdEncoding.EncodeToString(content)
+		input.Base64 = true
+	}
+	return jsonutils.Marshal(input), nil
+}
+
+type ServerQgaFileReadOptions struct {
+	ServerIdOptions
+
+	PATH     string `help:"Path of file in guest" json:"path"`
+	MaxBytes int    `help:"Max bytes to read, default 1MB" json:"max_bytes"`
+	Output   string `help:"Save content to local file instead of printing" json:"-"`
+}
+
+func (o *ServerQgaFileReadOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerQgaFsfreezeOptions struct {
+	ServerIdOptions
+
+	ACTION string `help:"Fsfreeze action" choices:"freeze|thaw|status" json:"action"`
+}
+
+func (o *ServerQgaFsfreezeOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerMonitorAgentHeartbeatOptions struct {
+	ServerIdOptions
+
+	TOKEN   string `help:"Monitor agent register token" json:"token"`
+	Version string `help:"Monitor agent version" json:"version"`
+}
+
+func (o *ServerMonitorAgentHeartbeatOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetQgaFirewallOptions struct {
+	ServerIdOptions
+
+	Enable  bool   `help:"Enable push security group rules into guest firewall by qga, disable if not set" json:"enable"`
+	Backend string `help:"Guest firewall backend" choices:"iptables|nftables|windows" json:"backend"`
+}
+
+func (o *ServerSetQgaFirewallOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
 type ServerSetPasswordOptions struct {
 	ServerIdOptions
 
@@ -904,6 +1002,20 @@ func (o *ServerChangeOwnerOptions) Params() (jsonutils.JSONObject, error) {
 	return options.StructToParams(o)
 }
 
+type ServerTransferOwnershipOptions struct {
+	ID             string `help:"Server to transfer" json:"-"`
+	PROJECT        string `help:"Target project ID or name" json:"project_id"`
+	SkipDnsRecords bool   `help:"Do not move DNS records pointing to the server"`
+}
+
+func (o *ServerTransferOwnershipOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerTransferOwnershipOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
 type ServerRebuildRootOptions struct {
 	ID            string `help:"Server to rebuild root" json:"-"`
 	ImageId       string `help:"New root Image template ID" json:"image_id" token:"image"`
@@ -1030,6 +1142,20 @@ func (o *ServerMigrateOptions) Params() (jsonutils.JSONObject, error) {
 	return options.StructToParams(o)
 }
 
+type ServerColdMigrateWithDevicesOptions struct {
+	ID         string `help:"ID of server" json:"-"`
+	PreferHost string `help:"Server migration prefer host id or name, must have enough free isolated devices" json:"prefer_host"`
+	AutoStart  *bool  `help:"Server auto start after migrate, default keep the status before migrate" json:"auto_start"`
+}
+
+func (o *ServerColdMigrateWithDevicesOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerColdMigrateWithDevicesOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
 type ServerLiveMigrateOptions struct {
 	ID              string `help:"ID of server" json:"-"`
 	PreferHost      string `help:"Server migration prefer host id or name" json:"prefer_host"`
@@ -1040,6 +1166,13 @@ type ServerLiveMigrateOptions struct {
 	MaxBandwidthMb  *int64 `help:"live migrate downtime, unit MB"`
 
 	KeepDestGuestOnFailed *bool `help:"do not delete dest guest on migrate failed, for debug"`
+
+	MultifdChannels          *int   `help:"multifd channels, 0 disables multifd"`
+	MultifdCompression       string `help:"multifd compression method" choices:"none|zlib|zstd"`
+	AutoConverge             *bool  `help:"throttle guest cpu when memory dirty rate is too high" negative:"no-auto-converge"`
+	CpuThrottleInitial       *int   `help:"auto converge initial cpu throttle percentage"`
+	CpuThrottleIncrement     *int   `help:"auto converge cpu throttle increment percentage"`
+	PostcopySwitchoverRounds *int   `help:"switch to postcopy after this many dirty memory sync rounds, 0 disables postcopy"`
 }
 
 func (o *ServerLiveMigrateOptions) GetId() string {
@@ -1151,12 +1284,33 @@ func (o *ServerPrepaidRecycleOptions) Params() (jsonutils.JSONObject, error) {
 
 type ServerIoThrottle struct {
 	ServerIdOptions
-	BPS  int `help:"bps(MB) of throttle" json:"bps"`
-	IOPS int `help:"iops of throttle" json:"iops"`
+	BPS  int      `help:"bps(MB) of throttle" json:"bps"`
+	IOPS int      `help:"iops of throttle" json:"iops"`
+	Disk []string `help:"per disk throttle, override BPS and IOPS, e.g. <disk_id>:<bps(MB)>:<iops>" json:"-"`
 }
 
 func (o *ServerIoThrottle) Params() (jsonutils.JSONObject, error) {
-	return jsonutils.Marshal(o), nil
+	params := jsonutils.Marshal(o).(*jsonutils.JSONDict)
+	if len(o.Disk) > 0 {
+		disks := map[string]computeapi.ServerDiskIoThrottle{}
+		for _, d := range o.Disk {
+			parts := strings.Split(d, ":")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("invalid disk throttle %s", d)
+			}
+			bps, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid bps %s", parts[1])
+			}
+			iops, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid iops %s", parts[2])
+			}
+			disks[parts[0]] = computeapi.ServerDiskIoThrottle{Bps: bps, Iops: iops}
+		}
+		params.Set("disks", jsonutils.Marshal(disks))
+	}
+	return params, nil
 }
 
 func (o *ServerIoThrottle) Description() string {
@@ -1318,6 +1472,221 @@ func (o *ServerCPUSetOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(input), nil
 }
 
+type ServerAttachSharedDirOptions struct {
+	options.BaseIdOptions
+	TAG      string `help:"Mount tag used in guest, e.g. mount -t virtiofs <tag> /mnt" json:"tag"`
+	HOSTPATH string `help:"Absolute path of shared dir on host" json:"host_path"`
+	ReadOnly bool   `help:"Share dir as read only" json:"read_only"`
+}
+
+func (o *ServerAttachSharedDirOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerDetachSharedDirOptions struct {
+	options.BaseIdOptions
+	TAG string `help:"Mount tag of shared dir" json:"tag"`
+}
+
+func (o *ServerDetachSharedDirOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetTpmOptions struct {
+	options.BaseIdOptions
+	Enable bool `help:"Enable vTPM 2.0 device, disable if not set" json:"enable"`
+}
+
+func (o *ServerSetTpmOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetWatchdogOptions struct {
+	options.BaseIdOptions
+	Model  string `help:"Watchdog device model, remove watchdog if not set" choices:"i6300esb|ib700" json:"model"`
+	Action string `help:"Action when watchdog timer expired, default reset" choices:"reset|poweroff|shutdown|pause|none" json:"action"`
+	Notify bool   `help:"Notify owner when watchdog triggered" json:"notify"`
+}
+
+func (o *ServerSetWatchdogOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetSriovFailoverOptions struct {
+	options.BaseIdOptions
+	Enable bool `help:"Pair sriov nics with virtio standby nics to allow live migration, disable if not set" json:"enable"`
+}
+
+func (o *ServerSetSriovFailoverOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetHugepageOptions struct {
+	options.BaseIdOptions
+	PageSize string `help:"Hugepage size of server, use host default if not set" choices:"2M|1G" json:"-"`
+	NumaNode *int   `help:"Bind hugepage memory to host numa node" json:"numa_node"`
+}
+
+func (o *ServerSetHugepageOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(o).(*jsonutils.JSONDict)
+	switch o.PageSize {
+	case "2M":
+		params.Set("page_size_kb", jsonutils.NewInt(2048))
+	case "1G":
+		params.Set("page_size_kb", jsonutils.NewInt(1048576))
+	}
+	return params, nil
+}
+
+type ServerSetBalloonOptions struct {
+	options.BaseIdOptions
+	MinMemMb int `help:"Minimal memory size in MB that can be reclaimed to by balloon, 0 means host default percent"`
+}
+
+func (o *ServerSetBalloonOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(o).(*jsonutils.JSONDict)
+	params.Set("min_mem_mb", jsonutils.NewInt(int64(o.MinMemMb)))
+	return params, nil
+}
+
+type ServerSetNicBandwidthOptions struct {
+	options.BaseIdOptions
+	IpAddr    string `help:"IP address of the nic" json:"ip_addr"`
+	Mac       string `help:"Mac address of the nic" json:"mac"`
+	Index     *int64 `help:"Index of the nic" json:"index"`
+	BwIngress *int   `help:"Ingress (host to guest) bandwidth limit in mbps, 0 to follow nic bandwidth" json:"bw_ingress"`
+	BwEgress  *int   `help:"Egress (guest to host) bandwidth limit in mbps, 0 to follow nic bandwidth" json:"bw_egress"`
+}
+
+func (o *ServerSetNicBandwidthOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetVirtioMemOptions struct {
+	options.BaseIdOptions
+	Enable bool `help:"Enable virtio-mem online memory resize, disable if not set" json:"enable"`
+}
+
+func (o *ServerSetVirtioMemOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetSecureBootOptions struct {
+	options.BaseIdOptions
+	Enable bool `help:"Enable UEFI secure boot, disable if not set" json:"enable"`
+}
+
+func (o *ServerSetSecureBootOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetBootOrderOptions struct {
+	options.BaseIdOptions
+	DEVICES []string `help:"Boot devices in order" choices:"disk|cdrom|network" json:"devices"`
+}
+
+func (o *ServerSetBootOrderOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerBootOnceOptions struct {
+	options.BaseIdOptions
+	DEVICE  string `help:"Boot device for next start only" choices:"disk|cdrom|network" json:"device"`
+	Restart bool   `help:"Restart running guest to take effect immediately" json:"restart"`
+}
+
+func (o *ServerBootOnceOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerPxeBootOptions struct {
+	options.BaseIdOptions
+	ChainloadUrl string `help:"iPXE chainload url, http, https or tftp" json:"chainload_url"`
+	Once         bool   `help:"Boot from network for next start only" json:"once"`
+	Restart      bool   `help:"Restart running guest to take effect immediately" json:"restart"`
+	Disable      bool   `help:"Disable network boot and boot from disk first" json:"disable"`
+}
+
+func (o *ServerPxeBootOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetClockPolicyOptions struct {
+	options.BaseIdOptions
+	Base     string `help:"RTC base time, default utc" choices:"utc|localtime" json:"base"`
+	Driftfix string `help:"RTC drift fix policy, default slew for Windows and none for others" choices:"none|slew" json:"driftfix"`
+	Hpet     string `help:"enable HPET, default off for Windows" choices:"on|off" json:"hpet"`
+}
+
+func (o *ServerSetClockPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetConfidentialVmOptions struct {
+	options.BaseIdOptions
+	Type string `help:"Confidential computing type, disable if not set" choices:"sev|sev-es|sev-snp|tdx" json:"type"`
+}
+
+func (o *ServerSetConfidentialVmOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetVdiOptionsOptions struct {
+	options.BaseIdOptions
+	SpiceUsbredirChannels *int `help:"Count of spice usb redirection channels, 0 to disable" json:"spice_usbredir_channels"`
+	VdiMonitors           *int `help:"Count of monitors, requires spice and qxl or virtio vga" json:"vdi_monitors"`
+}
+
+func (o *ServerSetVdiOptionsOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetIothreadPolicyOptions struct {
+	options.BaseIdOptions
+	Policy string `help:"IO thread policy, empty to reset, applied at next start" choices:"shared|per-device" json:"policy"`
+}
+
+func (o *ServerSetIothreadPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetCpuPinPolicyOptions struct {
+	options.BaseIdOptions
+	Policy string `help:"vCPU pin policy, empty to unpin" choices:"dedicated|shared" json:"policy"`
+}
+
+func (o *ServerSetCpuPinPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSetNumaPolicyOptions struct {
+	options.BaseIdOptions
+	Policy string `help:"NUMA policy, empty to use single numa node, applied at next start" choices:"strict|preferred" json:"policy"`
+	Nodes  int    `help:"Guest numa node count, 0 to choose by vcpu count" json:"nodes"`
+}
+
+func (o *ServerSetNumaPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerUpgradeMachineTypeOptions struct {
+	options.BaseIdOptions
+	MACHINE_TYPE string `help:"Versioned qemu machine type, e.g. pc-i440fx-6.2 or pc-q35-6.2, applied at next cold start" json:"machine_type"`
+}
+
+func (o *ServerUpgradeMachineTypeOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerResizeMemoryOptions struct {
+	options.BaseIdOptions
+	VMEM_SIZE int `help:"Target memory size in MB" json:"vmem_size"`
+}
+
+func (o *ServerResizeMemoryOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
 type ServerVncOptions struct {
 	ServerIdOptions
 	Origin bool
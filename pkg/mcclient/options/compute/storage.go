@@ -56,6 +56,7 @@ type StorageUpdateOptions struct {
 	RbdKey                string  `help:"ceph rbd key"`
 	Reserved              string  `help:"Reserved storage space"`
 	Capacity              int     `help:"Capacity for storage"`
+	ImageCacheBudgetMb    *int64  `help:"Image prefetch cache budget in MB, 0 for default, -1 to disable prefetch"`
 }
 
 func (opts *StorageUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type TagBackfillJobListOptions struct {
+	options.BaseListOptions
+	CloudaccountId string `help:"filter by cloudaccount"`
+}
+
+func (opts *TagBackfillJobListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type TagBackfillJobCreateOptions struct {
+	options.BaseCreateOptions
+	CLOUDACCOUNT string   `help:"cloudaccount whose resources are backfilled" json:"cloudaccount_id"`
+	ResourceType []string `help:"resource types, default all" choices:"server|disk|eip|loadbalancer" json:"resource_types"`
+	Tag          []string `help:"tag to backfill, e.g. --tag cost_center=rd" json:"-"`
+	Overwrite    bool     `help:"overwrite existing tags with the same key" json:"-"`
+	KeyAlias     []string `help:"rename tag key alias to canonical key, e.g. --key-alias Env=env" json:"-"`
+	RateLimit    int      `help:"provider API calls per second, default 5"`
+}
+
+func (opts *TagBackfillJobCreateOptions) Params() (jsonutils.JSONObject, error) {
+	rule := api.TagBackfillRule{Overwrite: opts.Overwrite}
+	var err error
+	rule.Tags, err = parseTagPolicyTags(opts.Tag)
+	if err != nil {
+		return nil, err
+	}
+	rule.KeyAliases = map[string]string{}
+	for _, kv := range opts.KeyAlias {
+		pos := strings.IndexByte(kv, '=')
+		if pos <= 0 || pos == len(kv)-1 {
+			return nil, fmt.Errorf("invalid key alias %s, should be alias=key", kv)
+		}
+		rule.KeyAliases[kv[:pos]] = kv[pos+1:]
+	}
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	params.Set("rule", jsonutils.Marshal(rule))
+	return params, nil
+}
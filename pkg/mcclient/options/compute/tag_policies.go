@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type TagPolicyListOptions struct {
+	options.BaseListOptions
+	ProjectId string `help:"filter by project"`
+}
+
+func (opts *TagPolicyListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+func parseTagPolicyTags(tags []string) (map[string]string, error) {
+	ret := map[string]string{}
+	for _, tag := range tags {
+		pos := strings.IndexByte(tag, '=')
+		if pos <= 0 {
+			return nil, fmt.Errorf("invalid tag %s, should be key=value", tag)
+		}
+		ret[tag[:pos]] = tag[pos+1:]
+	}
+	return ret, nil
+}
+
+type TagPolicyCreateOptions struct {
+	options.BaseCreateOptions
+	ProjectId string   `help:"apply to project only, default apply to whole domain"`
+	Tag       []string `help:"mandatory tag, e.g. --tag cost_center=rd --tag env=prod" json:"-"`
+	Enforce   bool     `help:"overwrite tags set by users and restore them on remote update"`
+}
+
+func (opts *TagPolicyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	tags, err := parseTagPolicyTags(opts.Tag)
+	if err != nil {
+		return nil, err
+	}
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	params.Set("tags", jsonutils.Marshal(tags))
+	return params, nil
+}
+
+type TagPolicyUpdateOptions struct {
+	options.BaseUpdateOptions
+	Tag     []string `help:"mandatory tag, e.g. --tag cost_center=rd --tag env=prod" json:"-"`
+	Enforce *bool    `help:"overwrite tags set by users and restore them on remote update" negative:"no_enforce"`
+}
+
+func (opts *TagPolicyUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	if len(opts.Tag) > 0 {
+		tags, err := parseTagPolicyTags(opts.Tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("tags", jsonutils.Marshal(tags))
+	}
+	return params, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type GuestFlowListOptions struct {
+	options.BaseListOptions
+	GuestId   []string `help:"filter by guest id"`
+	HostId    string   `help:"filter by reporting host id"`
+	PeerIp    string   `help:"filter by peer ip address"`
+	Direction string   `help:"filter by direction" choices:"in|out"`
+	Protocol  string   `help:"filter by protocol"`
+	Port      []int    `help:"filter by service port"`
+}
+
+func (o *GuestFlowListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(o)
+}
+
+type GuestFlowTopologyOptions struct {
+	GUEST_ID string `help:"guest id" json:"guest_id"`
+	Hours    int    `help:"only count flows seen in recent hours, default 24"`
+}
+
+func (o *GuestFlowTopologyOptions) Property() string {
+	return "topology"
+}
+
+func (o *GuestFlowTopologyOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
@@ -165,3 +165,25 @@ func (opts *SecgroupImportRulesOptions) Params() (jsonutils.JSONObject, error) {
 	}
 	return jsonutils.Marshal(map[string]*jsonutils.JSONArray{"rules": rules}), nil
 }
+
+type SecgroupNormalizedRulesOptions struct {
+	SecgroupIdOptions
+	Provider []string `help:"show normalized rules for specified providers, default providers the secgroup has been synced to"`
+}
+
+func (opts *SecgroupNormalizedRulesOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string][]string{"provider": opts.Provider}), nil
+}
+
+type SecgroupIsolationAuditOptions struct {
+	DomainId string `help:"domain id or name, default current domain"`
+	Severity string `help:"only show findings not lower than this severity" choices:"high|medium|low"`
+}
+
+func (opts *SecgroupIsolationAuditOptions) Property() string {
+	return "isolation-audit"
+}
+
+func (opts *SecgroupIsolationAuditOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
@@ -14,7 +14,12 @@
 
 package options
 
-import "yunion.io/x/jsonutils"
+import (
+	"os"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
 
 type ServerSkusListOptions struct {
 	BaseListOptions
@@ -29,6 +34,8 @@ type ServerSkusListOptions struct {
 	PrepaidStatus  string  `help:"Prepaid status" choices:"soldout|available"`
 	Enabled        *bool   `help:"Filter enabled skus"`
 	Distinct       bool    `help:"distinct sku by name"`
+
+	MaxSpotInterruptionRate *float64 `help:"max spot interruption rate, 0-1"`
 }
 
 func (opts *ServerSkusListOptions) GetId() string {
@@ -51,6 +58,21 @@ func (opts *ServerSkusIdOptions) Params() (jsonutils.JSONObject, error) {
 	return nil, nil
 }
 
+type ServerSkuSpotPricesOptions struct {
+	ServerSkusIdOptions
+	StartTime string `help:"start time, e.g. 2024-01-01T00:00:00Z, default 7 days ago"`
+	EndTime   string `help:"end time, default now"`
+}
+
+func (opts *ServerSkuSpotPricesOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	params.Remove("id")
+	return params, nil
+}
+
 type ServerSkusCreateOptions struct {
 	Name         string `help:"ServerSku name"`
 	CpuCoreCount int    `help:"Cpu Count" required:"true" positional:"true"`
@@ -76,10 +98,14 @@ type ServerSkusCreateOptions struct {
 	GPUCount      *int    `help:"GPU count"`
 	GPUAttachable *bool   `help:"Allow attach GPU"`
 
+	QosClass *string `help:"QoS class of local flavor" choices:"guaranteed|burstable|best-effort"`
+
 	ZoneId        string `help:"Zone ID or name"`
 	CloudregionId string `help:"Cloudregion ID or name"`
 	Provider      string `help:"provider"`
 	Brand         string `help:"brand"`
+
+	IsCustom *bool `help:"custom private cloud sku, only recorded locally"`
 }
 
 func (opts *ServerSkusCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -112,6 +138,8 @@ type ServerSkusUpdateOptions struct {
 	GPUCount      *int    `help:"GPU count"`
 	GPUAttachable *bool   `help:"Allow attach GPU"`
 
+	QosClass *string `help:"QoS class of local flavor" choices:"guaranteed|burstable|best-effort"`
+
 	Zone   *string `help:"Zone ID or name"`
 	Region *string `help:"Region ID or name"`
 }
@@ -119,3 +147,31 @@ type ServerSkusUpdateOptions struct {
 func (opts *ServerSkusUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	return StructToParams(opts)
 }
+
+type ServerSkuImportCatalogOptions struct {
+	FILE string `help:"offline catalog file, json format of {region_external_id: [sku, ...]}"`
+}
+
+func (opts *ServerSkuImportCatalogOptions) Params() (jsonutils.JSONObject, error) {
+	content, err := os.ReadFile(opts.FILE)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", opts.FILE)
+	}
+	catalog, err := jsonutils.Parse(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", opts.FILE)
+	}
+	params := jsonutils.NewDict()
+	params.Set("catalog", catalog)
+	return params, nil
+}
+
+type ServerSkuExportCatalogOptions struct {
+	SkuSyncOptions
+
+	Output string `help:"write catalog to file"`
+}
+
+func (opts *ServerSkuExportCatalogOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts.SkuSyncOptions), nil
+}
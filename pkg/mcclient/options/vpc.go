@@ -51,6 +51,8 @@ type VpcCreateOptions struct {
 	Manager            string `help:"ID or Name of Cloud provider" json:"manager_id"`
 	ExternalAccessMode string `help:"Filter by external access mode" choices:"distgw|eip|eip-distgw" default:""`
 	GlobalvpcId        string `help:"Global vpc id, Only for Google Cloud"`
+	DnsDomain          string `help:"Internal dns domain suffix of the VPC, e.g. vpc1.internal"`
+	Mtu                int    `help:"MTU of guest nics in the VPC, default is computed from ovn underlay mtu"`
 }
 
 func (opts *VpcCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -76,6 +78,12 @@ func (opts *VpcCreateOptions) Params() (jsonutils.JSONObject, error) {
 	if len(opts.GlobalvpcId) > 0 {
 		params.Add(jsonutils.NewString(opts.GlobalvpcId), "globalvpc_id")
 	}
+	if len(opts.DnsDomain) > 0 {
+		params.Add(jsonutils.NewString(opts.DnsDomain), "dns_domain")
+	}
+	if opts.Mtu > 0 {
+		params.Add(jsonutils.NewInt(int64(opts.Mtu)), "mtu")
+	}
 	return params, nil
 }
 
@@ -95,11 +103,17 @@ type VpcUpdateOptions struct {
 	BaseUpdateOptions
 	ExternalAccessMode string `help:"Filter by external access mode" choices:"distgw|eip|eip-distgw"`
 	Direct             bool   `help:"Can it be connected directly"`
+	DnsDomain          string `help:"Internal dns domain suffix of the VPC"`
+	NoDnsDomain        bool   `help:"Clear internal dns domain suffix of the VPC" json:"-"`
+	Mtu                *int   `help:"MTU of guest nics in the VPC, 0 to compute from ovn underlay mtu"`
 }
 
 func (opts *VpcUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
 	params.Remove("id")
+	if opts.NoDnsDomain {
+		params.Set("dns_domain", jsonutils.NewString(""))
+	}
 	return params, nil
 }
 
@@ -62,11 +62,18 @@ func (opt *PodLogOptoins) Params() (*jsonutils.JSONDict, error) {
 
 type WebConsoleBaremetalOptions struct {
 	WebConsoleOptions
-	ID string `help:"Baremetal host id or name"`
+	ID     string `help:"Baremetal host id or name" json:"-"`
+	Method string `help:"Serial over lan connect method, ipmitool is preferred if not set" choices:"ipmitool|redfish"`
 }
 
 func (opt *WebConsoleBaremetalOptions) Params() (*jsonutils.JSONDict, error) {
-	return StructToParams(opt)
+	data, err := StructToParams(opt)
+	if err != nil {
+		return nil, err
+	}
+	params := jsonutils.NewDict()
+	params.Set("webconsole", data)
+	return params, nil
 }
 
 type WebConsoleSshOptions struct {
@@ -87,5 +94,16 @@ func (opt *WebConsoleSshOptions) Params() (*jsonutils.JSONDict, error) {
 
 type WebConsoleServerOptions struct {
 	WebConsoleOptions
-	ID string `help:"Server id or name"`
+	ID     string `help:"Server id or name" json:"-"`
+	Method string `help:"Serial over lan connect method for baremetal server" choices:"ipmitool|redfish"`
+}
+
+func (opt *WebConsoleServerOptions) Params() (*jsonutils.JSONDict, error) {
+	data, err := StructToParams(opt)
+	if err != nil {
+		return nil, err
+	}
+	params := jsonutils.NewDict()
+	params.Set("webconsole", data)
+	return params, nil
 }
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifiers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/monitor/alerting"
+	"yunion.io/x/onecloud/pkg/monitor/models"
+	"yunion.io/x/onecloud/pkg/monitor/options"
+)
+
+func init() {
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{
+		Type:    monitor.AlertNotificationTypeRunbook,
+		Factory: newRunbookNotifier,
+		ValidateCreateData: func(cred mcclient.IIdentityProvider, input monitor.NotificationCreateInput) (monitor.NotificationCreateInput, error) {
+			settings := new(monitor.NotificationSettingRunbook)
+			if input.Settings == nil {
+				return input, httperrors.NewMissingParameterError("settings")
+			}
+			if err := input.Settings.Unmarshal(settings); err != nil {
+				return input, errors.Wrap(err, "Unmarshal setting")
+			}
+			if len(settings.RunbookId) == 0 {
+				return input, httperrors.NewMissingParameterError("settings.runbook_id")
+			}
+			if len(settings.ResourceTag) == 0 {
+				settings.ResourceTag = "vm_id"
+			}
+			input.Settings = jsonutils.Marshal(settings)
+			return input, nil
+		},
+	})
+}
+
+// runbookNotifier 告警触发时对匹配到的每台虚拟机执行运维手册
+type runbookNotifier struct {
+	NotifierBase
+
+	Settings *monitor.NotificationSettingRunbook
+}
+
+func newRunbookNotifier(conf alerting.NotificationConfig) (alerting.Notifier, error) {
+	settings := new(monitor.NotificationSettingRunbook)
+	if err := conf.Settings.Unmarshal(settings); err != nil {
+		return nil, errors.Wrap(err, "unmarshal setting")
+	}
+	if len(settings.ResourceTag) == 0 {
+		settings.ResourceTag = "vm_id"
+	}
+	return &runbookNotifier{
+		NotifierBase: NewNotifierBase(conf),
+		Settings:     settings,
+	}, nil
+}
+
+func (rn *runbookNotifier) Notify(ctx *alerting.EvalContext, data jsonutils.JSONObject) error {
+	if !ctx.Firing {
+		return nil
+	}
+	s := auth.GetAdminSession(ctx.Ctx, options.Options.Region)
+	guestIds := map[string]bool{}
+	errs := []error{}
+	for _, match := range ctx.EvalMatches {
+		guestId := match.Tags[rn.Settings.ResourceTag]
+		if len(guestId) == 0 || guestIds[guestId] {
+			continue
+		}
+		guestIds[guestId] = true
+		input := api.RunbookTriggerInput{
+			GuestId: guestId,
+			AlertId: ctx.Rule.Id,
+			Params: map[string]string{
+				"alert_name": ctx.Rule.Name,
+				"metric":     match.Metric,
+				"value":      match.ValueStr,
+			},
+		}
+		if match.Value != nil && len(input.Params["value"]) == 0 {
+			input.Params["value"] = fmt.Sprintf("%v", *match.Value)
+		}
+		_, err := modules.Runbooks.PerformAction(s, rn.Settings.RunbookId, "trigger", jsonutils.Marshal(input))
+		if err != nil {
+			log.Errorf("trigger runbook %s for guest %s: %v", rn.Settings.RunbookId, guestId, err)
+			errs = append(errs, errors.Wrapf(err, "trigger runbook for guest %s", guestId))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (rn *runbookNotifier) ShouldNotify(ctx context.Context, evalContext *alerting.EvalContext,
+	notificationState *models.SAlertnotification) bool {
+	if evalContext.NoDataFound {
+		return false
+	}
+	return rn.NotifierBase.ShouldNotify(ctx, evalContext, notificationState)
+}
@@ -0,0 +1,337 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/monitor/options"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var (
+	GuestFlowManager *SGuestFlowManager
+)
+
+type SGuestFlowManager struct {
+	db.SStandaloneAnonResourceBaseManager
+}
+
+func init() {
+	GuestFlowManager = &SGuestFlowManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SGuestFlow{},
+			"guestflows_tbl",
+			"guestflow",
+			"guestflows",
+		),
+	}
+	GuestFlowManager.SetVirtualObject(GuestFlowManager)
+}
+
+// 宿主机采样上报的虚拟机流量, 同一条流量可能被两端宿主机分别上报
+type SGuestFlow struct {
+	db.SStandaloneAnonResourceBase
+
+	HostId    string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true" json:"host_id"`
+	GuestId   string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true" json:"guest_id"`
+	IpAddr    string `width:"64" charset:"ascii" nullable:"false" list:"admin" json:"ip_addr"`
+	VpcId     string `width:"36" charset:"ascii" nullable:"true" list:"admin" json:"vpc_id"`
+	Direction string `width:"8" charset:"ascii" nullable:"false" list:"admin" json:"direction"`
+	PeerIp    string `width:"64" charset:"ascii" nullable:"false" list:"admin" index:"true" json:"peer_ip"`
+	Protocol  string `width:"16" charset:"ascii" nullable:"false" list:"admin" json:"protocol"`
+	Port      int    `nullable:"false" list:"admin" json:"port"`
+
+	// 累计采样到的连接数
+	Connections int64     `nullable:"false" default:"0" list:"admin" json:"connections"`
+	FirstSeen   time.Time `list:"admin" json:"first_seen"`
+	LastSeen    time.Time `list:"admin" index:"true" json:"last_seen"`
+}
+
+func guestFlowKey(guestId, ipAddr, direction, peerIp, protocol string, port int) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%d", guestId, ipAddr, direction, peerIp, protocol, port)
+}
+
+func (flow *SGuestFlow) key() string {
+	return guestFlowKey(flow.GuestId, flow.IpAddr, flow.Direction, flow.PeerIp, flow.Protocol, flow.Port)
+}
+
+func (manager *SGuestFlowManager) ListItemFilter(
+	ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query monitor.GuestFlowListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneAnonResourceBaseManager.ListItemFilter")
+	}
+	if len(query.GuestId) > 0 {
+		q = q.In("guest_id", query.GuestId)
+	}
+	if len(query.HostId) > 0 {
+		q = q.Equals("host_id", query.HostId)
+	}
+	if len(query.PeerIp) > 0 {
+		q = q.Equals("peer_ip", query.PeerIp)
+	}
+	if len(query.Direction) > 0 {
+		q = q.Equals("direction", query.Direction)
+	}
+	if len(query.Protocol) > 0 {
+		q = q.Equals("protocol", query.Protocol)
+	}
+	if len(query.Port) > 0 {
+		q = q.In("port", query.Port)
+	}
+	return q, nil
+}
+
+func (manager *SGuestFlowManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []monitor.GuestFlowDetails {
+	rows := make([]monitor.GuestFlowDetails, len(objs))
+	stdRows := manager.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i].StandaloneAnonResourceDetails = stdRows[i]
+	}
+	return rows
+}
+
+// 宿主机上报流量采样
+func (manager *SGuestFlowManager) PerformReport(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input monitor.GuestFlowReportInput) (jsonutils.JSONObject, error) {
+	if len(input.HostId) == 0 {
+		return nil, httperrors.NewMissingParameterError("host_id")
+	}
+	guestIds := sets.NewString()
+	for _, sample := range input.Samples {
+		if len(sample.GuestId) == 0 || len(sample.PeerIp) == 0 {
+			return nil, httperrors.NewInputParameterError("guest_id and peer_ip are required")
+		}
+		if sample.Direction != monitor.GUEST_FLOW_DIRECTION_IN && sample.Direction != monitor.GUEST_FLOW_DIRECTION_OUT {
+			return nil, httperrors.NewInputParameterError("invalid direction %q", sample.Direction)
+		}
+		guestIds.Insert(sample.GuestId)
+	}
+	if guestIds.Len() == 0 {
+		return nil, nil
+	}
+
+	flows := []SGuestFlow{}
+	q := manager.Query().In("guest_id", guestIds.List())
+	err := db.FetchModelObjects(manager, q, &flows)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	exists := map[string]*SGuestFlow{}
+	for i := range flows {
+		exists[flows[i].key()] = &flows[i]
+	}
+
+	now := time.Now().UTC()
+	for _, sample := range input.Samples {
+		key := guestFlowKey(sample.GuestId, sample.IpAddr, sample.Direction, sample.PeerIp, sample.Protocol, sample.Port)
+		if flow, ok := exists[key]; ok {
+			_, err := db.Update(flow, func() error {
+				flow.HostId = input.HostId
+				flow.VpcId = sample.VpcId
+				flow.Connections += int64(sample.Connections)
+				flow.LastSeen = now
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "update flow %s", key)
+			}
+			continue
+		}
+		flow := &SGuestFlow{
+			HostId:      input.HostId,
+			GuestId:     sample.GuestId,
+			IpAddr:      sample.IpAddr,
+			VpcId:       sample.VpcId,
+			Direction:   sample.Direction,
+			PeerIp:      sample.PeerIp,
+			Protocol:    sample.Protocol,
+			Port:        sample.Port,
+			Connections: int64(sample.Connections),
+			FirstSeen:   now,
+			LastSeen:    now,
+		}
+		flow.SetModelManager(manager, flow)
+		err := manager.TableSpec().Insert(ctx, flow)
+		if err != nil {
+			return nil, errors.Wrapf(err, "insert flow %s", key)
+		}
+		exists[key] = flow
+	}
+	return nil, nil
+}
+
+// 汇总与指定虚拟机通信的对端及端口
+// 对端宿主机上报的反向流量也计入, 同一端口两端上报的连接数取较大值
+func buildGuestFlowTopology(guestId string, flows []SGuestFlow) []monitor.SGuestFlowPeer {
+	addrKey := func(vpcId, ip string) string {
+		return vpcId + "/" + ip
+	}
+	owners := map[string]string{}
+	mine := sets.NewString()
+	for i := range flows {
+		owners[addrKey(flows[i].VpcId, flows[i].IpAddr)] = flows[i].GuestId
+		if flows[i].GuestId == guestId {
+			mine.Insert(addrKey(flows[i].VpcId, flows[i].IpAddr))
+		}
+	}
+
+	type sPeerPorts struct {
+		peer  monitor.SGuestFlowPeer
+		ports map[int]int64
+	}
+	peers := map[string]*sPeerPorts{}
+	add := func(peerGuestId, peerIp, direction string, flow *SGuestFlow) {
+		key := fmt.Sprintf("%s/%s/%s", peerIp, direction, flow.Protocol)
+		if len(peerGuestId) > 0 {
+			key = fmt.Sprintf("%s/%s/%s", peerGuestId, direction, flow.Protocol)
+		}
+		p, ok := peers[key]
+		if !ok {
+			p = &sPeerPorts{
+				peer: monitor.SGuestFlowPeer{
+					PeerGuestId: peerGuestId,
+					PeerIp:      peerIp,
+					Direction:   direction,
+					Protocol:    flow.Protocol,
+				},
+				ports: map[int]int64{},
+			}
+			peers[key] = p
+		}
+		if cnt, ok := p.ports[flow.Port]; !ok || flow.Connections > cnt {
+			p.ports[flow.Port] = flow.Connections
+		}
+		if flow.LastSeen.After(p.peer.LastSeen) {
+			p.peer.LastSeen = flow.LastSeen
+		}
+	}
+	for i := range flows {
+		flow := &flows[i]
+		if flow.GuestId == guestId {
+			add(owners[addrKey(flow.VpcId, flow.PeerIp)], flow.PeerIp, flow.Direction, flow)
+		} else if mine.Has(addrKey(flow.VpcId, flow.PeerIp)) {
+			direction := monitor.GUEST_FLOW_DIRECTION_IN
+			if flow.Direction == monitor.GUEST_FLOW_DIRECTION_IN {
+				direction = monitor.GUEST_FLOW_DIRECTION_OUT
+			}
+			add(flow.GuestId, flow.IpAddr, direction, flow)
+		}
+	}
+
+	ret := []monitor.SGuestFlowPeer{}
+	for _, p := range peers {
+		for port, cnt := range p.ports {
+			p.peer.Ports = append(p.peer.Ports, port)
+			p.peer.Connections += cnt
+		}
+		sort.Ints(p.peer.Ports)
+		ret = append(ret, p.peer)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Connections != ret[j].Connections {
+			return ret[i].Connections > ret[j].Connections
+		}
+		if ret[i].PeerIp != ret[j].PeerIp {
+			return ret[i].PeerIp < ret[j].PeerIp
+		}
+		return ret[i].Direction < ret[j].Direction
+	})
+	return ret
+}
+
+// 查询与指定虚拟机通信的虚拟机及端口, 用于迁移或下线前梳理依赖关系
+func (manager *SGuestFlowManager) GetPropertyTopology(ctx context.Context, userCred mcclient.TokenCredential, query monitor.GuestFlowTopologyInput) (*monitor.GuestFlowTopologyOutput, error) {
+	if len(query.GuestId) == 0 {
+		return nil, httperrors.NewMissingParameterError("guest_id")
+	}
+	hours := query.Hours
+	if hours <= 0 {
+		hours = 24
+	}
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour)
+
+	flows := []SGuestFlow{}
+	q := manager.Query().Equals("guest_id", query.GuestId).GE("last_seen", since)
+	err := db.FetchModelObjects(manager, q, &flows)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	ips, peerIps := sets.NewString(), sets.NewString()
+	for i := range flows {
+		ips.Insert(flows[i].IpAddr)
+		peerIps.Insert(flows[i].PeerIp)
+	}
+	if ips.Len() > 0 {
+		others := []SGuestFlow{}
+		q = manager.Query().NotEquals("guest_id", query.GuestId).GE("last_seen", since)
+		q = q.Filter(sqlchemy.OR(
+			sqlchemy.In(q.Field("peer_ip"), ips.List()),
+			sqlchemy.In(q.Field("ip_addr"), peerIps.List()),
+		))
+		err = db.FetchModelObjects(manager, q, &others)
+		if err != nil {
+			return nil, errors.Wrap(err, "FetchModelObjects")
+		}
+		flows = append(flows, others...)
+	}
+	return &monitor.GuestFlowTopologyOutput{
+		GuestId: query.GuestId,
+		Peers:   buildGuestFlowTopology(query.GuestId, flows),
+	}, nil
+}
+
+func (manager *SGuestFlowManager) DeleteExpiredFlows(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	days := options.Options.GuestFlowRetentionDays
+	if days <= 0 {
+		return
+	}
+	flows := []SGuestFlow{}
+	q := manager.Query().LT("last_seen", time.Now().UTC().Add(-time.Duration(days)*24*time.Hour))
+	err := db.FetchModelObjects(manager, q, &flows)
+	if err != nil {
+		log.Errorf("fetch expired guest flows: %v", err)
+		return
+	}
+	for i := range flows {
+		err := db.DeleteModel(ctx, userCred, &flows[i])
+		if err != nil {
+			log.Errorf("delete expired guest flow %s: %v", flows[i].Id, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+func TestBuildGuestFlowTopology(t *testing.T) {
+	now := time.Now()
+	flow := func(guestId, ip, direction, peerIp, protocol string, port int, conns int64) SGuestFlow {
+		return SGuestFlow{
+			GuestId: guestId, IpAddr: ip, VpcId: "vpc1", Direction: direction,
+			PeerIp: peerIp, Protocol: protocol, Port: port, Connections: conns, LastSeen: now,
+		}
+	}
+	flows := []SGuestFlow{
+		// web 访问 db 的 3306, 两端宿主机各上报一次
+		flow("web", "10.0.0.2", monitor.GUEST_FLOW_DIRECTION_OUT, "10.0.0.3", "tcp", 3306, 5),
+		flow("db", "10.0.0.3", monitor.GUEST_FLOW_DIRECTION_IN, "10.0.0.2", "tcp", 3306, 7),
+		// 外部地址访问 web 的 443 和 80
+		flow("web", "10.0.0.2", monitor.GUEST_FLOW_DIRECTION_IN, "192.168.1.10", "tcp", 443, 10),
+		flow("web", "10.0.0.2", monitor.GUEST_FLOW_DIRECTION_IN, "192.168.1.10", "tcp", 80, 1),
+		// cron 访问 web 的 8080, 只有 cron 所在宿主机上报
+		flow("cron", "10.0.0.4", monitor.GUEST_FLOW_DIRECTION_OUT, "10.0.0.2", "tcp", 8080, 2),
+		// 与 web 无关的流量
+		flow("db", "10.0.0.3", monitor.GUEST_FLOW_DIRECTION_OUT, "10.0.0.9", "udp", 53, 3),
+	}
+
+	want := []monitor.SGuestFlowPeer{
+		{PeerIp: "192.168.1.10", Direction: monitor.GUEST_FLOW_DIRECTION_IN, Protocol: "tcp", Ports: []int{80, 443}, Connections: 11, LastSeen: now},
+		{PeerGuestId: "db", PeerIp: "10.0.0.3", Direction: monitor.GUEST_FLOW_DIRECTION_OUT, Protocol: "tcp", Ports: []int{3306}, Connections: 7, LastSeen: now},
+		{PeerGuestId: "cron", PeerIp: "10.0.0.4", Direction: monitor.GUEST_FLOW_DIRECTION_IN, Protocol: "tcp", Ports: []int{8080}, Connections: 2, LastSeen: now},
+	}
+	got := buildGuestFlowTopology("web", flows)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
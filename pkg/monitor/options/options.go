@@ -38,6 +38,8 @@ type AlerterOptions struct {
 	WorkerCheckInterval int `default:"180"`
 
 	AutoMigrationMustPair bool `default:"false" help:"result of auto migration source guests and target hosts must be paired"`
+
+	GuestFlowRetentionDays int `default:"7" help:"days to keep sampled guest flows, 0 keeps forever"`
 }
 
 var (
@@ -73,6 +73,7 @@ func StartService() {
 		models.AlertRecordManager.DeleteRecordsOfThirtyDaysAgo, false)
 	//cron.AddJobAtIntervalsWithStartRun("MonitorResourceSync", time.Duration(opts.MonitorResourceSyncIntervalSeconds)*time.Minute*60, models.MonitorResourceManager.SyncResources, true)
 	cron.AddJobEveryFewHour("AutoPurgeSplitable", 4, 30, 0, db.AutoPurgeSplitable, false)
+	cron.AddJobEveryFewHour("DeleteExpiredGuestFlows", 1, 10, 0, models.GuestFlowManager.DeleteExpiredFlows, false)
 
 	cron.Start()
 	defer cron.Stop()
@@ -552,6 +552,16 @@ func init() {
 			"expired and released",
 			"到期释放",
 		},
+		sI18nElme{
+			string(api.ActionPasswordExpireSoon),
+			"password will expire soon",
+			"密码即将过期",
+		},
+		sI18nElme{
+			string(api.ActionScheduledMaintenance),
+			"scheduled for maintenance",
+			"计划维护",
+		},
 		sI18nElme{
 			string(api.ActionExecute),
 			"executed",
@@ -193,6 +193,8 @@ func (sm *STopicManager) InitializeData() error {
 			t.addAction(notify.ActionRebuildRoot)
 			t.addAction(notify.ActionResetPassword)
 			t.addAction(notify.ActionChangeIpaddr)
+			t.addAction(notify.ActionPasswordExpireSoon)
+			t.addAction(notify.ActionScheduledMaintenance)
 			t.Type = notify.TOPIC_TYPE_RESOURCE
 		case DefaultResourceReleaseDue1Day:
 			t.addResources(
@@ -561,30 +563,32 @@ func init() {
 	)
 	converter.registerAction(
 		map[notify.SAction]int{
-			notify.ActionCreate:             0,
-			notify.ActionDelete:             1,
-			notify.ActionPendingDelete:      2,
-			notify.ActionUpdate:             3,
-			notify.ActionRebuildRoot:        4,
-			notify.ActionResetPassword:      5,
-			notify.ActionChangeConfig:       6,
-			notify.ActionExpiredRelease:     7,
-			notify.ActionExecute:            8,
-			notify.ActionChangeIpaddr:       9,
-			notify.ActionSyncStatus:         10,
-			notify.ActionCleanData:          11,
-			notify.ActionMigrate:            12,
-			notify.ActionCreateBackupServer: 13,
-			notify.ActionDelBackupServer:    14,
-			notify.ActionSyncCreate:         15,
-			notify.ActionSyncUpdate:         16,
-			notify.ActionSyncDelete:         17,
-			notify.ActionOffline:            18,
-			notify.ActionSystemPanic:        19,
-			notify.ActionSystemException:    20,
-			notify.ActionChecksumTest:       21,
-			notify.ActionLock:               22,
-			notify.ActionExceedCount:        23,
+			notify.ActionCreate:               0,
+			notify.ActionDelete:               1,
+			notify.ActionPendingDelete:        2,
+			notify.ActionUpdate:               3,
+			notify.ActionRebuildRoot:          4,
+			notify.ActionResetPassword:        5,
+			notify.ActionChangeConfig:         6,
+			notify.ActionExpiredRelease:       7,
+			notify.ActionExecute:              8,
+			notify.ActionChangeIpaddr:         9,
+			notify.ActionSyncStatus:           10,
+			notify.ActionCleanData:            11,
+			notify.ActionMigrate:              12,
+			notify.ActionCreateBackupServer:   13,
+			notify.ActionDelBackupServer:      14,
+			notify.ActionSyncCreate:           15,
+			notify.ActionSyncUpdate:           16,
+			notify.ActionSyncDelete:           17,
+			notify.ActionOffline:              18,
+			notify.ActionSystemPanic:          19,
+			notify.ActionSystemException:      20,
+			notify.ActionChecksumTest:         21,
+			notify.ActionLock:                 22,
+			notify.ActionExceedCount:          23,
+			notify.ActionPasswordExpireSoon:   24,
+			notify.ActionScheduledMaintenance: 25,
 		},
 	)
 }
@@ -17,6 +17,7 @@ package guest
 import (
 	"context"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
 	"yunion.io/x/onecloud/pkg/scheduler/core"
 )
@@ -58,6 +59,9 @@ func (p *MemoryPredicate) Execute(ctx context.Context, u *core.Unit, c core.Cand
 	getter := c.Getter()
 	freeMemSize := getter.FreeMemorySize(useRsvd)
 	reqMemSize := int64(d.Memory)
+	if len(d.QosClass) > 0 && getter.Host() != nil {
+		reqMemSize = computeapi.GetQosClassMemUsage(d.QosClass, reqMemSize, getter.Host().GetMemoryOvercommitBound())
+	}
 	if freeMemSize < reqMemSize {
 		totalMemSize := getter.TotalMemorySize(useRsvd)
 		h.AppendInsufficientResourceError(reqMemSize, totalMemSize, freeMemSize)
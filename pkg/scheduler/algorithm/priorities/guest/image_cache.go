@@ -0,0 +1,163 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+const (
+	IMAGE_CACHE_HIT_SCORE = 3
+	// 每缺失 10G 镜像缓存扣 1 分, 最多扣 IMAGE_CACHE_MAX_PENALTY 分
+	IMAGE_CACHE_PENALTY_UNIT_MB = 10 * 1024
+	IMAGE_CACHE_MAX_PENALTY     = 5
+)
+
+// ImageCachePriority 优先选择存储缓存中已有所需镜像的宿主机, 减少首次启动下载镜像的耗时
+type ImageCachePriority struct {
+	priorities.BasePriority
+
+	// image id => size(MB)
+	images map[string]int64
+	// image id => storagecache ids
+	cached map[string]map[string]bool
+}
+
+func (p *ImageCachePriority) Name() string {
+	return "image_cache"
+}
+
+func (p *ImageCachePriority) Clone() core.Priority {
+	return &ImageCachePriority{}
+}
+
+func (p *ImageCachePriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	schedData := u.SchedData()
+	if schedData.Hypervisor != computeapi.HYPERVISOR_KVM {
+		return false, nil, nil
+	}
+	imageIds := make([]string, 0)
+	for _, disk := range schedData.Disks {
+		if len(disk.ImageId) > 0 {
+			imageIds = append(imageIds, disk.ImageId)
+		}
+	}
+	if len(imageIds) == 0 {
+		return false, nil, nil
+	}
+
+	images := make([]models.SCachedimage, 0)
+	q := models.CachedimageManager.Query().In("id", imageIds)
+	if err := q.All(&images); err != nil {
+		log.Errorf("fetch cachedimages %v: %v", imageIds, err)
+		return false, nil, nil
+	}
+	p.images = map[string]int64{}
+	for _, img := range images {
+		p.images[img.Id] = img.Size / 1024 / 1024
+	}
+	// 镜像首次上传后还未同步到 cachedimages 的情况, 按未知大小处理
+	for _, id := range imageIds {
+		if _, ok := p.images[id]; !ok {
+			p.images[id] = 0
+		}
+	}
+
+	scis := make([]models.SStoragecachedimage, 0)
+	q = models.StoragecachedimageManager.Query().In("cachedimage_id", imageIds).
+		Equals("status", computeapi.CACHED_IMAGE_STATUS_ACTIVE)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsNullOrEmpty(q.Field("verify_status")),
+		sqlchemy.NotEquals(q.Field("verify_status"), computeapi.INTEGRITY_VERIFY_CORRUPTED),
+	))
+	if err := q.All(&scis); err != nil {
+		log.Errorf("fetch storagecachedimages %v: %v", imageIds, err)
+		return false, nil, nil
+	}
+	p.cached = map[string]map[string]bool{}
+	for _, sci := range scis {
+		if _, ok := p.cached[sci.CachedimageId]; !ok {
+			p.cached[sci.CachedimageId] = map[string]bool{}
+		}
+		p.cached[sci.CachedimageId][sci.StoragecacheId] = true
+	}
+	return true, nil, nil
+}
+
+// 返回未命中缓存的镜像及其总大小(MB)
+func (p *ImageCachePriority) getCacheMisses(c core.Candidater) ([]string, int64) {
+	cacheIds := map[string]bool{}
+	for _, s := range c.Getter().Storages() {
+		if len(s.StoragecacheId) > 0 {
+			cacheIds[s.StoragecacheId] = true
+		}
+	}
+	misses := make([]string, 0)
+	var missSizeMB int64
+	for imageId, sizeMB := range p.images {
+		hit := false
+		for cacheId := range p.cached[imageId] {
+			if cacheIds[cacheId] {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			misses = append(misses, imageId)
+			missSizeMB += sizeMB
+		}
+	}
+	return misses, missSizeMB
+}
+
+func getImageCachePenalty(missCount int, missSizeMB int64) int {
+	if missCount == 0 {
+		return 0
+	}
+	penalty := 1 + int(missSizeMB/IMAGE_CACHE_PENALTY_UNIT_MB)
+	if penalty > IMAGE_CACHE_MAX_PENALTY {
+		penalty = IMAGE_CACHE_MAX_PENALTY
+	}
+	return penalty
+}
+
+func (p *ImageCachePriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	misses, missSizeMB := p.getCacheMisses(c)
+	penalty := getImageCachePenalty(len(misses), missSizeMB)
+	if penalty == 0 {
+		h.SetScore(IMAGE_CACHE_HIT_SCORE)
+	} else {
+		h.SetScore(-penalty)
+		u.SetFiltedData(c.IndexKey(), p.Name(), map[string]interface{}{
+			core.IMAGE_CACHE_MISS_KEY:         misses,
+			core.IMAGE_CACHE_MISS_SIZE_KEY:    missSizeMB,
+			core.IMAGE_CACHE_MISS_PENALTY_KEY: penalty,
+		})
+	}
+	return h.GetResult()
+}
+
+func (p *ImageCachePriority) ScoreIntervals() score.Intervals {
+	return score.NewIntervals(0, 1, 3)
+}
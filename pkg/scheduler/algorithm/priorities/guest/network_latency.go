@@ -0,0 +1,112 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"time"
+
+	"yunion.io/x/log"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+const (
+	// 超过该时间未更新的探测结果不参与调度
+	NETWORK_PROBE_EXPIRE = 30 * time.Minute
+
+	NETWORK_LATENCY_MAX_SCORE = 5
+)
+
+// NetworkLatencyPriority 时延敏感虚机优先调度到与指定宿主机网络时延低的宿主机
+type NetworkLatencyPriority struct {
+	priorities.BasePriority
+
+	peers []string
+	// host id => peer host id => latency(ms)
+	latencies map[string]map[string]float64
+}
+
+func (p *NetworkLatencyPriority) Name() string {
+	return "network_latency"
+}
+
+func (p *NetworkLatencyPriority) Clone() core.Priority {
+	return &NetworkLatencyPriority{}
+}
+
+func (p *NetworkLatencyPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	schedData := u.SchedData()
+	if schedData.Hypervisor != computeapi.HYPERVISOR_KVM || schedData.ServerConfigs == nil ||
+		len(schedData.NetworkLatencyPeerHosts) == 0 {
+		return false, nil, nil
+	}
+	latencies, err := models.HostNetworkProbeManager.GetLatencies(schedData.NetworkLatencyPeerHosts, NETWORK_PROBE_EXPIRE)
+	if err != nil {
+		log.Errorf("fetch network probes of %v: %v", schedData.NetworkLatencyPeerHosts, err)
+		return false, nil, nil
+	}
+	p.peers = schedData.NetworkLatencyPeerHosts
+	p.latencies = latencies
+	return true, nil, nil
+}
+
+// 宿主机到各对端的平均时延, 缺少探测结果时返回 false
+func (p *NetworkLatencyPriority) getAvgLatency(hostId string) (float64, bool) {
+	var total float64
+	for _, peer := range p.peers {
+		if peer == hostId {
+			continue
+		}
+		latency, ok := p.latencies[hostId][peer]
+		if !ok {
+			return 0, false
+		}
+		total += latency
+	}
+	return total / float64(len(p.peers)), true
+}
+
+func getNetworkLatencyScore(latencyMs float64) int {
+	switch {
+	case latencyMs < 0.5:
+		return NETWORK_LATENCY_MAX_SCORE
+	case latencyMs < 1:
+		return 4
+	case latencyMs < 2:
+		return 3
+	case latencyMs < 5:
+		return 2
+	case latencyMs < 20:
+		return 1
+	}
+	return -1
+}
+
+func (p *NetworkLatencyPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	if latency, ok := p.getAvgLatency(c.IndexKey()); ok {
+		h.SetScore(getNetworkLatencyScore(latency))
+	}
+	return h.GetResult()
+}
+
+func (p *NetworkLatencyPriority) ScoreIntervals() score.Intervals {
+	return score.NewIntervals(0, 1, NETWORK_LATENCY_MAX_SCORE)
+}
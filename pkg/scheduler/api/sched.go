@@ -285,6 +285,14 @@ type FilteredCandidate struct {
 	Reasons    []string `json:"reasons"`
 }
 
+type ImageCacheMiss struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	ImageIds []string `json:"image_ids"`
+	SizeMb   int64    `json:"size_mb"`
+	Penalty  int      `json:"penalty"`
+}
+
 type SchedForecastResult struct {
 	CanCreate          bool                     `json:"can_create"`
 	Candidates         []*api.CandidateResource `json:"candidates"`
@@ -292,4 +300,6 @@ type SchedForecastResult struct {
 	AllowCount         int64                    `json:"allow_count"`
 	NotAllowReasons    []string                 `json:"not_allow_reasons"`
 	FilteredCandidates []FilteredCandidate      `json:"filtered_candidates"`
+	// 候选宿主机上未缓存的镜像及扣分, 镜像需在首次启动时下载
+	ImageCacheMisses []ImageCacheMiss `json:"image_cache_misses,omitempty"`
 }
@@ -17,6 +17,7 @@ package candidate
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	gosync "sync"
 	"sync/atomic"
@@ -119,6 +120,8 @@ type HostDesc struct {
 	CreatingMemSize    int64   `json:"creating_mem_size"`
 	RequiredMemSize    int64   `json:"required_mem_size"`
 	FakeDeletedMemSize int64   `json:"fake_deleted_mem_size"`
+	// 宿主机 virtio-balloon 从虚机回收的内存
+	BalloonReclaimedMemSize int64 `json:"balloon_reclaimed_mem_size"`
 
 	// storage
 	StorageTypes []string `json:"storage_types"`
@@ -826,6 +829,7 @@ func (b *HostBuilder) buildOne(host *computemodels.SHost) (interface{}, error) {
 		b.fillGuestsResourceInfo,
 		//b.fillResidentGroups,
 		b.fillMetadata,
+		b.fillBalloonReclaimedMem,
 		b.fillCPUIOLoads,
 	}
 
@@ -875,21 +879,24 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 
 	for _, gst := range guestsOnHost {
 		guest := gst.(computemodels.SGuest)
+		// 按QoS等级折算占用的超售后容量
+		guestMem := computeapi.GetQosClassMemUsage(guest.QosClass, int64(guest.VmemSize), desc.MemCmtbound)
+		guestCpu := computeapi.GetQosClassCpuUsage(guest.QosClass, int64(guest.VcpuCount), desc.CPUCmtbound)
 		if IsGuestRunning(guest) {
 			runningCount++
-			memSize += int64(guest.VmemSize)
-			cpuCount += int64(guest.VcpuCount)
+			memSize += guestMem
+			cpuCount += guestCpu
 		} else if IsGuestCreating(guest) {
 			creatingGuestCount++
-			creatingMemSize += int64(guest.VmemSize)
-			creatingCPUCount += int64(guest.VcpuCount)
+			creatingMemSize += guestMem
+			creatingCPUCount += guestCpu
 		} else if IsGuestPendingDelete(guest) {
-			memFakeDeletedSize += int64(guest.VmemSize)
-			cpuFakeDeletedCount += int64(guest.VcpuCount)
+			memFakeDeletedSize += guestMem
+			cpuFakeDeletedCount += guestCpu
 		}
 		guestCount++
-		cpuReqCount += int64(guest.VcpuCount)
-		memReqSize += int64(guest.VmemSize)
+		cpuReqCount += guestCpu
+		memReqSize += guestMem
 
 		//appTags := b.guestAppTags(guest)
 		//for _, tag := range appTags {
@@ -1015,6 +1022,23 @@ func (b *HostBuilder) fillMetadata(desc *HostDesc, host *computemodels.SHost) er
 	return nil
 }
 
+// 宿主机上报的 balloon 回收内存, 开启 BalloonReclaimedMemAsFree 时计入可用内存
+func (b *HostBuilder) fillBalloonReclaimedMem(desc *HostDesc, host *computemodels.SHost) error {
+	val, ok := desc.Metadata[computeapi.HOSTMETA_BALLOON_RECLAIMED_MB]
+	if !ok {
+		return nil
+	}
+	reclaimed, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || reclaimed <= 0 {
+		return nil
+	}
+	desc.BalloonReclaimedMemSize = reclaimed
+	if o.Options.BalloonReclaimedMemAsFree {
+		desc.FreeMemSize += reclaimed
+	}
+	return nil
+}
+
 func (b *HostBuilder) getUsedIsolatedDevices(hostID string) (devs []computemodels.SIsolatedDevice) {
 	devs = make([]computemodels.SIsolatedDevice, 0)
 	for _, dev := range b.getIsolatedDevices(hostID) {
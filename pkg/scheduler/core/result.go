@@ -23,6 +23,13 @@ import (
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 )
 
+// 调度结果 Data 中镜像缓存未命中相关的 key
+const (
+	IMAGE_CACHE_MISS_KEY         = "image_cache_miss"
+	IMAGE_CACHE_MISS_SIZE_KEY    = "image_cache_miss_size_mb"
+	IMAGE_CACHE_MISS_PENALTY_KEY = "image_cache_miss_penalty"
+)
+
 type ScheduleResult struct {
 	// Result is sync schedule result
 	Result *schedapi.ScheduleOutput
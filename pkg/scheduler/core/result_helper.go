@@ -151,6 +151,7 @@ func transToSchedForecastResult(result *SchedResultItemList) *api.SchedForecastR
 		filteredCandidates = append(filteredCandidates, filteredCandidate)
 	}
 	ret.FilteredCandidates = filteredCandidates
+	ret.ImageCacheMisses = getImageCacheMisses(result.Data)
 
 	var (
 		output     = transToSchedResult(result, schedData)
@@ -173,3 +174,25 @@ func transToSchedForecastResult(result *SchedResultItemList) *api.SchedForecastR
 	}
 	return ret
 }
+
+func getImageCacheMisses(items SchedResultItems) []api.ImageCacheMiss {
+	var ret []api.ImageCacheMiss
+	for _, item := range items {
+		if item.Capacity <= 0 || item.Data == nil {
+			continue
+		}
+		imageIds, ok := item.Data[IMAGE_CACHE_MISS_KEY].([]string)
+		if !ok {
+			continue
+		}
+		miss := api.ImageCacheMiss{
+			ID:       item.ID,
+			Name:     item.Name,
+			ImageIds: imageIds,
+		}
+		miss.SizeMb, _ = item.Data[IMAGE_CACHE_MISS_SIZE_KEY].(int64)
+		miss.Penalty, _ = item.Data[IMAGE_CACHE_MISS_PENALTY_KEY].(int)
+		ret = append(ret, miss)
+	}
+	return ret
+}
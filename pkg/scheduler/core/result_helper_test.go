@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+)
+
+func TestGetImageCacheMisses(t *testing.T) {
+	items := SchedResultItems{
+		{ID: "h1", Name: "host1", Capacity: 1, Data: map[string]interface{}{}},
+		{ID: "h2", Name: "host2", Capacity: 1, Data: map[string]interface{}{
+			IMAGE_CACHE_MISS_KEY:         []string{"img1"},
+			IMAGE_CACHE_MISS_SIZE_KEY:    int64(20480),
+			IMAGE_CACHE_MISS_PENALTY_KEY: 3,
+		}},
+		{ID: "h3", Name: "host3", Capacity: 0, Data: map[string]interface{}{
+			IMAGE_CACHE_MISS_KEY: []string{"img1"},
+		}},
+	}
+	want := []api.ImageCacheMiss{
+		{ID: "h2", Name: "host2", ImageIds: []string{"img1"}, SizeMb: 20480, Penalty: 3},
+	}
+	if got := getImageCacheMisses(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("getImageCacheMisses() = %#v, want %#v", got, want)
+	}
+}
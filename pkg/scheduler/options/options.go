@@ -34,6 +34,8 @@ type SchedulerOptions struct {
 type SchedOptions struct {
 	SchedulerPort           int  `help:"The port that the scheduler's http service runs on" default:"8897"`
 	IgnoreFakeDeletedGuests bool `help:"Ignore fake deleted guests when build host memory and cpu size" default:"false"`
+	// 宿主机开启内存气球后, 是否将回收的内存计入可调度内存
+	BalloonReclaimedMemAsFree bool `help:"Count memory reclaimed by host virtio-balloon as free memory when scheduling" default:"false"`
 
 	AlwaysCheckAllPredicates    bool   `help:"Excute all predicates when scheduling" default:"false"`
 	DisableBaremetalPredicates  bool   `help:"Switch to trigger baremetal related predicates" default:"false"`
@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgrouputils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+/**
+ *  cgroup v2 (unified hierarchy)
+ */
+
+const (
+	CGROUP_V2_CONTROLLERS     = "cgroup.controllers"
+	CGROUP_V2_SUBTREE_CONTROL = "cgroup.subtree_control"
+	CGROUP_V2_PROCS           = "cgroup.procs"
+
+	CPU_WEIGHT = "cpu.weight"
+	IO_WEIGHT  = "io.weight"
+
+	CgroupV2WeightMin     = 1
+	CgroupV2WeightMax     = 10000
+	CgroupV2WeightDefault = 100
+)
+
+var (
+	CgroupV2Controllers = []string{"cpu", "io", "memory", "cpuset"}
+)
+
+// IsCgroupV2 判断宿主机是否挂载 cgroup v2 统一层级
+func IsCgroupV2() bool {
+	return fileutils2.Exists(path.Join(cgroupsPath, CGROUP_V2_CONTROLLERS))
+}
+
+// CpuSharesToWeight 将 cgroup v1 cpu.shares 转换为 v2 cpu.weight, 与 systemd/runc 的换算一致
+func CpuSharesToWeight(shares int) int {
+	if shares <= 2 {
+		return CgroupV2WeightMin
+	}
+	return ClampCgroupV2Weight(1 + ((shares-2)*9999)/262142)
+}
+
+func ClampCgroupV2Weight(weight int) int {
+	if weight < CgroupV2WeightMin {
+		return CgroupV2WeightMin
+	}
+	if weight > CgroupV2WeightMax {
+		return CgroupV2WeightMax
+	}
+	return weight
+}
+
+type SCgroupV2Slice struct {
+	// 相对于 cgroup 挂载点的路径
+	name string
+}
+
+func NewCgroupV2Slice(name string) *SCgroupV2Slice {
+	return &SCgroupV2Slice{name: strings.Trim(name, "/")}
+}
+
+func (c *SCgroupV2Slice) Path() string {
+	return path.Join(cgroupsPath, c.name)
+}
+
+func (c *SCgroupV2Slice) Exists() bool {
+	return fileutils2.Exists(c.Path())
+}
+
+// Create 创建 slice 并逐级在父节点开启所需的控制器
+func (c *SCgroupV2Slice) Create() error {
+	if err := os.MkdirAll(c.Path(), 0755); err != nil {
+		return errors.Wrapf(err, "mkdir %s", c.Path())
+	}
+	parent := cgroupsPath
+	for _, seg := range strings.Split(c.name, "/") {
+		if err := enableSubtreeControllers(parent); err != nil {
+			return err
+		}
+		parent = path.Join(parent, seg)
+	}
+	return nil
+}
+
+func enableSubtreeControllers(dir string) error {
+	avail, err := fileutils2.FileGetContents(path.Join(dir, CGROUP_V2_CONTROLLERS))
+	if err != nil {
+		return errors.Wrapf(err, "read %s controllers", dir)
+	}
+	enabled, _ := fileutils2.FileGetContents(path.Join(dir, CGROUP_V2_SUBTREE_CONTROL))
+	for _, ctrl := range missingControllers(avail, enabled, CgroupV2Controllers) {
+		err := ioutil.WriteFile(path.Join(dir, CGROUP_V2_SUBTREE_CONTROL), []byte("+"+ctrl), 0644)
+		if err != nil {
+			// 部分控制器(如 cpuset)可能未被内核启用, 不影响其他控制器
+			log.Warningf("enable controller %s in %s: %s", ctrl, dir, err)
+		}
+	}
+	return nil
+}
+
+// missingControllers 返回可用但未在 subtree_control 开启的控制器
+func missingControllers(avail, enabled string, wants []string) []string {
+	availSet := map[string]bool{}
+	for _, c := range strings.Fields(avail) {
+		availSet[c] = true
+	}
+	enabledSet := map[string]bool{}
+	for _, c := range strings.Fields(enabled) {
+		enabledSet[c] = true
+	}
+	ret := []string{}
+	for _, c := range wants {
+		if availSet[c] && !enabledSet[c] {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func (c *SCgroupV2Slice) GetParam(name string) string {
+	param, err := fileutils2.FileGetContents(path.Join(c.Path(), name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(param)
+}
+
+func (c *SCgroupV2Slice) SetParam(name, value string) error {
+	if c.GetParam(name) == value {
+		return nil
+	}
+	err := ioutil.WriteFile(path.Join(c.Path(), name), []byte(value), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "set %s=%s for %s", name, value, c.name)
+	}
+	return nil
+}
+
+// AddProcess 将进程移入 slice, v2 下进程的所有线程随之迁移
+func (c *SCgroupV2Slice) AddProcess(pid string) error {
+	err := ioutil.WriteFile(path.Join(c.Path(), CGROUP_V2_PROCS), []byte(pid), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "move %s to %s", pid, c.name)
+	}
+	return nil
+}
+
+func (c *SCgroupV2Slice) GetProcesses() []string {
+	return strings.Fields(c.GetParam(CGROUP_V2_PROCS))
+}
+
+// Destroy 将残留进程移回根 cgroup 后删除 slice
+func (c *SCgroupV2Slice) Destroy() error {
+	if !c.Exists() {
+		return nil
+	}
+	root := NewCgroupV2Slice("")
+	for _, pid := range c.GetProcesses() {
+		if err := root.AddProcess(pid); err != nil {
+			log.Errorf("move process back to root: %s", err)
+		}
+	}
+	if err := os.Remove(c.Path()); err != nil {
+		return errors.Wrapf(err, "remove %s", c.Path())
+	}
+	return nil
+}
+
+// CleanupCgroupV2Slices 删除 parent 下已无进程的子 slice
+func CleanupCgroupV2Slices(parent string) {
+	parentSlice := NewCgroupV2Slice(parent)
+	files, err := ioutil.ReadDir(parentSlice.Path())
+	if err != nil {
+		log.Errorf("read cgroup v2 slice %s: %s", parent, err)
+		return
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		child := NewCgroupV2Slice(path.Join(parent, file.Name()))
+		if len(child.GetProcesses()) > 0 {
+			continue
+		}
+		log.Infof("Cgroup v2 cleanup %s", child.name)
+		if err := os.Remove(child.Path()); err != nil {
+			log.Errorf("remove cgroup v2 slice %s: %s", child.name, err)
+		}
+	}
+}
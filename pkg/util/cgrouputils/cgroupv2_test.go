@@ -0,0 +1,51 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgrouputils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCpuSharesToWeight(t *testing.T) {
+	cases := []struct {
+		shares int
+		want   int
+	}{
+		{0, 1},
+		{2, 1},
+		{1024, 39},
+		{2048, 79},
+		{262144, 10000},
+		{1000000, 10000},
+	}
+	for _, c := range cases {
+		if got := CpuSharesToWeight(c.shares); got != c.want {
+			t.Errorf("CpuSharesToWeight(%d) want %d got %d", c.shares, c.want, got)
+		}
+	}
+}
+
+func TestMissingControllers(t *testing.T) {
+	got := missingControllers("cpuset cpu io memory pids", "cpu memory", CgroupV2Controllers)
+	want := []string{"io", "cpuset"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+	got = missingControllers("cpu", "", CgroupV2Controllers)
+	if !reflect.DeepEqual(got, []string{"cpu"}) {
+		t.Errorf("want [cpu] got %v", got)
+	}
+}
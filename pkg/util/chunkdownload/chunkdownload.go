@@ -0,0 +1,260 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunkdownload
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	CHECKPOINT_SUFFIX = ".checkpoint"
+
+	DEFAULT_CHUNK_SIZE_MB = 64
+	DEFAULT_PARALLEL      = 4
+	DEFAULT_RETRY         = 5
+)
+
+// FetchRangeFunc 读取 [start, end] 闭区间内的数据
+type FetchRangeFunc func(ctx context.Context, start, end int64) (io.ReadCloser, error)
+
+type SOptions struct {
+	ChunkSizeMb int
+	Parallel    int
+	Retry       int
+	// 失败后的重试间隔, 每次重试翻倍
+	RetryInterval time.Duration
+}
+
+type SCheckpoint struct {
+	Size      int64 `json:"size"`
+	ChunkSize int64 `json:"chunk_size"`
+	Done      []bool
+}
+
+func (cp *SCheckpoint) Finished() bool {
+	for _, done := range cp.Done {
+		if !done {
+			return false
+		}
+	}
+	return true
+}
+
+func (cp *SCheckpoint) doneCount() int {
+	cnt := 0
+	for _, done := range cp.Done {
+		if done {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+func (opts *SOptions) fillDefault() {
+	if opts.ChunkSizeMb <= 0 {
+		opts.ChunkSizeMb = DEFAULT_CHUNK_SIZE_MB
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = DEFAULT_PARALLEL
+	}
+	if opts.Retry <= 0 {
+		opts.Retry = DEFAULT_RETRY
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+}
+
+func checkpointPath(dest string) string {
+	return dest + CHECKPOINT_SUFFIX
+}
+
+// 断点文件与本次下载参数不一致时重新下载
+func loadCheckpoint(dest string, size, chunkSize int64) *SCheckpoint {
+	cnt := int((size + chunkSize - 1) / chunkSize)
+	fresh := &SCheckpoint{Size: size, ChunkSize: chunkSize, Done: make([]bool, cnt)}
+	if _, err := os.Stat(dest); err != nil {
+		return fresh
+	}
+	content, err := ioutil.ReadFile(checkpointPath(dest))
+	if err != nil {
+		return fresh
+	}
+	cp := &SCheckpoint{}
+	if err := json.Unmarshal(content, cp); err != nil {
+		log.Warningf("invalid checkpoint of %s: %v", dest, err)
+		return fresh
+	}
+	if cp.Size != size || cp.ChunkSize != chunkSize || len(cp.Done) != cnt {
+		return fresh
+	}
+	return cp
+}
+
+func saveCheckpoint(dest string, cp *SCheckpoint) error {
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := checkpointPath(dest) + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, checkpointPath(dest))
+}
+
+// Cleanup 删除下载的文件及断点记录
+func Cleanup(dest string) {
+	for _, fn := range []string{dest, checkpointPath(dest)} {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			log.Warningf("remove %s: %v", fn, err)
+		}
+	}
+}
+
+type offsetWriter struct {
+	fd     *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.fd.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+func fetchChunk(ctx context.Context, fetch FetchRangeFunc, fd *os.File, start, end int64) error {
+	rc, err := fetch(ctx, start, end)
+	if err != nil {
+		return errors.Wrap(err, "fetch")
+	}
+	defer rc.Close()
+	n, err := io.Copy(&offsetWriter{fd: fd, offset: start}, io.LimitReader(rc, end-start+1))
+	if err != nil {
+		return errors.Wrap(err, "copy")
+	}
+	if n != end-start+1 {
+		return errors.Errorf("short read %d, expect %d", n, end-start+1)
+	}
+	return nil
+}
+
+// Download 将 size 字节的数据按块并行下载到 dest, 每完成一块即记录断点,
+// 中断后再次调用会跳过已完成的块, 单块失败按 Retry 次数重试
+func Download(ctx context.Context, fetch FetchRangeFunc, size int64, dest string, opts SOptions, progress func(float32)) error {
+	if size <= 0 {
+		return errors.Errorf("invalid size %d", size)
+	}
+	opts.fillDefault()
+	chunkSize := int64(opts.ChunkSizeMb) * 1024 * 1024
+	cp := loadCheckpoint(dest, size, chunkSize)
+	if cp.Finished() {
+		return nil
+	}
+
+	fd, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "open %s", dest)
+	}
+	defer fd.Close()
+	if err := fd.Truncate(size); err != nil {
+		return errors.Wrapf(err, "truncate %s", dest)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	chunks := make(chan int)
+	worker := func() {
+		defer wg.Done()
+		for idx := range chunks {
+			start := int64(idx) * chunkSize
+			end := start + chunkSize - 1
+			if end >= size {
+				end = size - 1
+			}
+			interval := opts.RetryInterval
+			var err error
+			for i := 0; i < opts.Retry; i++ {
+				err = fetchChunk(ctx, fetch, fd, start, end)
+				if err == nil || ctx.Err() != nil {
+					break
+				}
+				log.Warningf("fetch chunk %d [%d-%d] of %s failed %d times: %v", idx, start, end, dest, i+1, err)
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+				}
+				interval *= 2
+			}
+			lock.Lock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = errors.Wrapf(err, "chunk %d", idx)
+				}
+				cancel()
+			} else {
+				cp.Done[idx] = true
+				if err := saveCheckpoint(dest, cp); err != nil {
+					log.Warningf("save checkpoint of %s: %v", dest, err)
+				}
+				if progress != nil {
+					progress(float32(cp.doneCount()) * 100 / float32(len(cp.Done)))
+				}
+			}
+			lock.Unlock()
+		}
+	}
+	for i := 0; i < opts.Parallel; i++ {
+		wg.Add(1)
+		go worker()
+	}
+	for idx := range cp.Done {
+		if cp.Done[idx] {
+			continue
+		}
+		select {
+		case chunks <- idx:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fd.Sync()
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chunkdownload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chunkdownload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	size := int64(5*1024*1024 + 123)
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	var lock sync.Mutex
+	fails := map[int64]int{}
+	broken := false
+	fetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if broken && start >= 3*1024*1024 {
+			return nil, fmt.Errorf("network unreachable")
+		}
+		// 每块首次请求失败, 模拟网络抖动
+		if fails[start] == 0 {
+			fails[start]++
+			return nil, fmt.Errorf("connection reset")
+		}
+		return ioutil.NopCloser(bytes.NewReader(data[start : end+1])), nil
+	}
+	opts := SOptions{ChunkSizeMb: 1, Parallel: 3, Retry: 2, RetryInterval: time.Millisecond}
+	dest := path.Join(dir, "image")
+
+	broken = true
+	if err := Download(context.Background(), fetch, size, dest, opts, nil); err == nil {
+		t.Fatalf("expect error when network broken")
+	}
+	cp := loadCheckpoint(dest, size, 1024*1024)
+	if cp.Finished() || cp.doneCount() != 3 {
+		t.Fatalf("expect 3 chunks done, got %d", cp.doneCount())
+	}
+
+	broken = false
+	fetched := 0
+	countFetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+		lock.Lock()
+		fetched++
+		lock.Unlock()
+		return fetch(ctx, start, end)
+	}
+	if err := Download(context.Background(), countFetch, size, dest, opts, nil); err != nil {
+		t.Fatalf("resume download: %v", err)
+	}
+	// 仅重新拉取剩余 3 块, 每块首次失败一次
+	if fetched != 6 {
+		t.Errorf("expect 6 fetches on resume, got %d", fetched)
+	}
+	content, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Errorf("downloaded content mismatch")
+	}
+
+	Cleanup(dest)
+	if _, err := os.Stat(checkpointPath(dest)); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed")
+	}
+}
@@ -15,6 +15,7 @@
 package dhcp
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"net"
@@ -213,3 +214,17 @@ func IsPXERequest(pkt Packet) bool {
 	}
 	return true
 }
+
+// iPXE 固件在 user class (option 77) 中携带 iPXE 标识
+func IsIPXERequest(pkt Packet) bool {
+	return bytes.Contains(pkt.GetOptionValue(OptionUserClass), []byte("iPXE"))
+}
+
+// option 93 为 0 表示 x86 BIOS, 其余为各类 EFI 固件
+func IsUEFIPXERequest(pkt Packet) bool {
+	arch := pkt.GetOptionValue(OptionClientArchitecture)
+	if len(arch) < 2 {
+		return false
+	}
+	return binary.BigEndian.Uint16(arch) != 0
+}
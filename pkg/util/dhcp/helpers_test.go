@@ -16,6 +16,7 @@ package dhcp
 
 import (
 	"fmt"
+	"net"
 	"testing"
 )
 
@@ -66,3 +67,46 @@ func TestGetClasslessRoutePack(t *testing.T) {
 		}
 	}
 }
+
+func TestPXERequestType(t *testing.T) {
+	mac, _ := net.ParseMAC("00:22:0a:0b:0c:0d")
+	cases := []struct {
+		opts []Option
+		pxe  bool
+		ipxe bool
+		uefi bool
+	}{
+		{
+			opts: nil,
+		},
+		{
+			opts: []Option{{OptionClientArchitecture, []byte{0, 0}}},
+			pxe:  true,
+		},
+		{
+			opts: []Option{{OptionClientArchitecture, []byte{0, 7}}},
+			pxe:  true,
+			uefi: true,
+		},
+		{
+			opts: []Option{
+				{OptionClientArchitecture, []byte{0, 0}},
+				{OptionUserClass, []byte("iPXE")},
+			},
+			pxe:  true,
+			ipxe: true,
+		},
+	}
+	for i, c := range cases {
+		pkt := RequestPacket(Discover, mac, nil, []byte{1, 2, 3, 4}, false, c.opts)
+		if got := IsPXERequest(pkt); got != c.pxe {
+			t.Errorf("case %d: IsPXERequest = %v, want %v", i, got, c.pxe)
+		}
+		if got := IsIPXERequest(pkt); got != c.ipxe {
+			t.Errorf("case %d: IsIPXERequest = %v, want %v", i, got, c.ipxe)
+		}
+		if got := IsUEFIPXERequest(pkt); got != c.uefi {
+			t.Errorf("case %d: IsUEFIPXERequest = %v, want %v", i, got, c.uefi)
+		}
+	}
+}
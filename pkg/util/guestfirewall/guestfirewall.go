@@ -0,0 +1,251 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestfirewall
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/secrules"
+)
+
+const (
+	BACKEND_IPTABLES = "iptables"
+	BACKEND_NFTABLES = "nftables"
+	BACKEND_WINDOWS  = "windows"
+
+	// 虚机内规则所在的链/表/规则组名称, 每次下发时整体替换
+	CHAIN_IN  = "ONECLOUD-IN"
+	CHAIN_OUT = "ONECLOUD-OUT"
+	NFT_TABLE = "onecloud"
+	WIN_GROUP = "onecloud"
+)
+
+var BACKENDS = []string{BACKEND_IPTABLES, BACKEND_NFTABLES, BACKEND_WINDOWS}
+
+// Render 将按优先级降序排列的安全组规则渲染为虚机内执行的脚本.
+// 与平台安全组一致, 入方向未匹配的流量丢弃, 出方向未匹配的流量放行.
+func Render(backend string, rules []secrules.SecurityRule) (string, error) {
+	switch backend {
+	case BACKEND_IPTABLES:
+		return renderIptables(rules), nil
+	case BACKEND_NFTABLES:
+		return renderNftables(rules), nil
+	case BACKEND_WINDOWS:
+		return renderWindows(rules), nil
+	}
+	return "", errors.Errorf("unsupported firewall backend %q", backend)
+}
+
+func isIPv6(rule *secrules.SecurityRule) bool {
+	return rule.IPNet != nil && rule.IPNet.IP.To4() == nil
+}
+
+func isAnyNet(rule *secrules.SecurityRule) bool {
+	if rule.IPNet == nil {
+		return true
+	}
+	ones, _ := rule.IPNet.Mask.Size()
+	return ones == 0
+}
+
+func iptablesRule(rule *secrules.SecurityRule, ipv6 bool) string {
+	chain, addrOpt := CHAIN_IN, "-s"
+	if rule.Direction == secrules.SecurityRuleEgress {
+		chain, addrOpt = CHAIN_OUT, "-d"
+	}
+	args := []string{"-A", chain}
+	if !isAnyNet(rule) {
+		args = append(args, addrOpt, rule.IPNet.String())
+	}
+	switch rule.Protocol {
+	case secrules.PROTO_TCP, secrules.PROTO_UDP:
+		args = append(args, "-p", rule.Protocol)
+		if rule.PortStart > 0 && rule.PortEnd > 0 {
+			args = append(args, "--dport", strings.Replace(rule.GetPortsString(), "-", ":", 1))
+		} else if len(rule.Ports) > 0 {
+			args = append(args, "-m", "multiport", "--dports", rule.GetPortsString())
+		}
+	case secrules.PROTO_ICMP:
+		if ipv6 {
+			args = append(args, "-p", "ipv6-icmp")
+		} else {
+			args = append(args, "-p", "icmp")
+		}
+	}
+	target := "ACCEPT"
+	if rule.Action == secrules.SecurityRuleDeny {
+		target = "DROP"
+	}
+	return strings.Join(append(args, "-j", target), " ")
+}
+
+func renderIptables(rules []secrules.SecurityRule) string {
+	lines := []string{"set -e"}
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		for _, chain := range []string{CHAIN_IN, CHAIN_OUT} {
+			hook := "INPUT"
+			if chain == CHAIN_OUT {
+				hook = "OUTPUT"
+			}
+			lines = append(lines,
+				fmt.Sprintf("%s -N %s 2>/dev/null || %s -F %s", cmd, chain, cmd, chain),
+				fmt.Sprintf("%s -C %s -j %s 2>/dev/null || %s -I %s -j %s", cmd, hook, chain, cmd, hook, chain),
+			)
+		}
+		lines = append(lines,
+			fmt.Sprintf("%s -A %s -i lo -j ACCEPT", cmd, CHAIN_IN),
+			fmt.Sprintf("%s -A %s -m state --state ESTABLISHED,RELATED -j ACCEPT", cmd, CHAIN_IN),
+		)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if isAnyNet(rule) {
+			// 不限定地址的规则同时作用于 IPv4 和 IPv6
+			lines = append(lines, "iptables "+iptablesRule(rule, false), "ip6tables "+iptablesRule(rule, true))
+		} else if isIPv6(rule) {
+			lines = append(lines, "ip6tables "+iptablesRule(rule, true))
+		} else {
+			lines = append(lines, "iptables "+iptablesRule(rule, false))
+		}
+	}
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		lines = append(lines,
+			fmt.Sprintf("%s -A %s -j DROP", cmd, CHAIN_IN),
+			fmt.Sprintf("%s -A %s -j ACCEPT", cmd, CHAIN_OUT),
+		)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func nftRule(rule *secrules.SecurityRule) string {
+	parts := []string{}
+	if !isAnyNet(rule) {
+		family, addr := "ip", "saddr"
+		if isIPv6(rule) {
+			family = "ip6"
+		}
+		if rule.Direction == secrules.SecurityRuleEgress {
+			addr = "daddr"
+		}
+		parts = append(parts, family, addr, rule.IPNet.String())
+	}
+	switch rule.Protocol {
+	case secrules.PROTO_TCP, secrules.PROTO_UDP:
+		if rule.PortStart > 0 && rule.PortEnd > 0 {
+			parts = append(parts, rule.Protocol, "dport", rule.GetPortsString())
+		} else if len(rule.Ports) > 0 {
+			parts = append(parts, rule.Protocol, "dport", "{ "+strings.ReplaceAll(rule.GetPortsString(), ",", ", ")+" }")
+		} else {
+			parts = append(parts, "meta", "l4proto", rule.Protocol)
+		}
+	case secrules.PROTO_ICMP:
+		if isAnyNet(rule) {
+			parts = append(parts, "meta", "l4proto", "{ icmp, ipv6-icmp }")
+		} else if isIPv6(rule) {
+			parts = append(parts, "meta", "l4proto", "ipv6-icmp")
+		} else {
+			parts = append(parts, "meta", "l4proto", "icmp")
+		}
+	}
+	if rule.Action == secrules.SecurityRuleDeny {
+		parts = append(parts, "drop")
+	} else {
+		parts = append(parts, "accept")
+	}
+	return strings.Join(parts, " ")
+}
+
+func renderNftables(rules []secrules.SecurityRule) string {
+	in, out := []string{}, []string{}
+	for i := range rules {
+		if rules[i].Direction == secrules.SecurityRuleEgress {
+			out = append(out, "\t\t"+nftRule(&rules[i]))
+		} else {
+			in = append(in, "\t\t"+nftRule(&rules[i]))
+		}
+	}
+	lines := []string{
+		"set -e",
+		fmt.Sprintf("nft list table inet %s >/dev/null 2>&1 && nft delete table inet %s", NFT_TABLE, NFT_TABLE),
+		"nft -f - <<'EOF'",
+		fmt.Sprintf("table inet %s {", NFT_TABLE),
+		"\tchain input {",
+		"\t\ttype filter hook input priority 0; policy drop;",
+		"\t\tiif lo accept",
+		"\t\tct state established,related accept",
+	}
+	lines = append(lines, in...)
+	lines = append(lines,
+		"\t}",
+		"\tchain output {",
+		"\t\ttype filter hook output priority 0; policy accept;",
+	)
+	lines = append(lines, out...)
+	lines = append(lines, "\t}", "}", "EOF")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Windows 防火墙没有规则优先级, 阻止规则总是优先于允许规则,
+// 因此拒绝规则会覆盖所有同方向上与其重叠的允许规则
+func renderWindows(rules []secrules.SecurityRule) string {
+	lines := []string{
+		"$ErrorActionPreference = 'Stop'",
+		fmt.Sprintf("Get-NetFirewallRule -Group '%s' -ErrorAction SilentlyContinue | Remove-NetFirewallRule", WIN_GROUP),
+		"Set-NetFirewallProfile -All -Enabled True -DefaultInboundAction Block -DefaultOutboundAction Allow",
+	}
+	for i := range rules {
+		rule := &rules[i]
+		direction := "Inbound"
+		addrOpt := "-RemoteAddress"
+		if rule.Direction == secrules.SecurityRuleEgress {
+			direction = "Outbound"
+		}
+		action := "Allow"
+		if rule.Action == secrules.SecurityRuleDeny {
+			action = "Block"
+		}
+		args := []string{
+			"New-NetFirewallRule",
+			fmt.Sprintf("-Group '%s'", WIN_GROUP),
+			fmt.Sprintf("-DisplayName '%s-%d'", WIN_GROUP, i),
+			"-Direction", direction,
+			"-Action", action,
+		}
+		if !isAnyNet(rule) {
+			args = append(args, addrOpt, rule.IPNet.String())
+		}
+		switch rule.Protocol {
+		case secrules.PROTO_TCP, secrules.PROTO_UDP:
+			args = append(args, "-Protocol", strings.ToUpper(rule.Protocol))
+			if ports := rule.GetPortsString(); len(ports) > 0 {
+				portOpt := "-LocalPort"
+				if rule.Direction == secrules.SecurityRuleEgress {
+					portOpt = "-RemotePort"
+				}
+				args = append(args, portOpt, ports)
+			}
+		case secrules.PROTO_ICMP:
+			if isIPv6(rule) {
+				args = append(args, "-Protocol", "ICMPv6")
+			} else {
+				args = append(args, "-Protocol", "ICMPv4")
+			}
+		}
+		lines = append(lines, strings.Join(args, " ")+" | Out-Null")
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestfirewall
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/pkg/util/secrules"
+)
+
+func parseRules(t *testing.T, ss ...string) []secrules.SecurityRule {
+	ret := []secrules.SecurityRule{}
+	for _, s := range ss {
+		r, err := secrules.ParseSecurityRule(s)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s, err)
+		}
+		ret = append(ret, *r)
+	}
+	return ret
+}
+
+func TestRenderIptables(t *testing.T) {
+	rules := parseRules(t,
+		"in:deny 10.0.0.0/8 tcp 22",
+		"in:allow tcp 22",
+		"in:allow udp 53,123",
+		"in:allow 192.168.1.0/24 icmp",
+		"out:allow tcp 1000-2000",
+	)
+	script, err := Render(BACKEND_IPTABLES, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"iptables -A ONECLOUD-IN -s 10.0.0.0/8 -p tcp --dport 22 -j DROP",
+		"iptables -A ONECLOUD-IN -p tcp --dport 22 -j ACCEPT",
+		"ip6tables -A ONECLOUD-IN -p tcp --dport 22 -j ACCEPT",
+		"iptables -A ONECLOUD-IN -p udp -m multiport --dports 53,123 -j ACCEPT",
+		"iptables -A ONECLOUD-IN -s 192.168.1.0/24 -p icmp -j ACCEPT",
+		"iptables -A ONECLOUD-OUT -p tcp --dport 1000:2000 -j ACCEPT",
+		"iptables -A ONECLOUD-IN -j DROP",
+	} {
+		if !strings.Contains(script, want+"\n") {
+			t.Errorf("missing %q in\n%s", want, script)
+		}
+	}
+	// 拒绝规则优先级更高, 必须先于允许规则
+	if strings.Index(script, "-s 10.0.0.0/8") > strings.Index(script, "-p tcp --dport 22 -j ACCEPT") {
+		t.Errorf("rule order not kept:\n%s", script)
+	}
+	if strings.Contains(script, "ip6tables -A ONECLOUD-IN -s 192.168.1.0/24") {
+		t.Errorf("ipv4 rule rendered for ip6tables:\n%s", script)
+	}
+}
+
+func TestRenderNftables(t *testing.T) {
+	rules := parseRules(t,
+		"in:allow 10.0.0.1 tcp 22,80",
+		"in:allow icmp",
+		"out:deny 8.8.8.8/32 udp 53",
+	)
+	script, err := Render(BACKEND_NFTABLES, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ip saddr 10.0.0.1/32 tcp dport { 22, 80 } accept",
+		"meta l4proto { icmp, ipv6-icmp } accept",
+		"ip daddr 8.8.8.8/32 udp dport 53 drop",
+		"type filter hook input priority 0; policy drop;",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("missing %q in\n%s", want, script)
+		}
+	}
+}
+
+func TestRenderWindows(t *testing.T) {
+	rules := parseRules(t, "in:allow tcp 3389", "out:deny 1.1.1.1 any")
+	script, err := Render(BACKEND_WINDOWS, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"-Direction Inbound -Action Allow -Protocol TCP -LocalPort 3389",
+		"-Direction Outbound -Action Block -RemoteAddress 1.1.1.1/32",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("missing %q in\n%s", want, script)
+		}
+	}
+	if _, err := Render("pf", rules); err == nil {
+		t.Errorf("expect error for unsupported backend")
+	}
+}
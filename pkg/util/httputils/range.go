@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// ParseByteRange 解析单段 Range 请求头, 例如 bytes=0-1023, bytes=1024-, bytes=-512
+// 返回闭区间 [start, end], 不支持多段请求
+func ParseByteRange(header string, size int64) (int64, int64, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, errors.Errorf("invalid range %q", header)
+	}
+	spec := strings.TrimSpace(header[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return 0, 0, errors.Errorf("multiple ranges not supported")
+	}
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid range %q", header)
+	}
+	var start, end int64
+	var err error
+	if len(parts[0]) == 0 {
+		// suffix range
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, errors.Errorf("invalid range %q", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+	start, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, errors.Errorf("range %q not satisfiable for size %d", header, size)
+	}
+	end = size - 1
+	if len(parts[1]) > 0 {
+		end, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errors.Errorf("invalid range %q", header)
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, nil
+}
+
+func FormatContentRange(start, end, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", start, end, size)
+}
+
+// ParseContentRange 解析 Content-Range 响应头, 例如 bytes 0-1023/4096
+func ParseContentRange(header string) (int64, int64, int64, error) {
+	var start, end, size int64
+	_, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &size)
+	if err != nil {
+		return 0, 0, 0, errors.Wrapf(err, "invalid content range %q", header)
+	}
+	return start, end, size, nil
+}
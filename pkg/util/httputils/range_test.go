@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputils
+
+import "testing"
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		header string
+		size   int64
+		start  int64
+		end    int64
+		err    bool
+	}{
+		{"bytes=0-1023", 4096, 0, 1023, false},
+		{"bytes=1024-", 4096, 1024, 4095, false},
+		{"bytes=-512", 4096, 3584, 4095, false},
+		{"bytes=4000-9999", 4096, 4000, 4095, false},
+		{"bytes=-8192", 4096, 0, 4095, false},
+		{"bytes=4096-", 4096, 0, 0, true},
+		{"bytes=10-5", 4096, 0, 0, true},
+		{"bytes=0-1,4-5", 4096, 0, 0, true},
+		{"items=0-1", 4096, 0, 0, true},
+	}
+	for _, c := range cases {
+		start, end, err := ParseByteRange(c.header, c.size)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expect error", c.header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.header, err)
+			continue
+		}
+		if start != c.start || end != c.end {
+			t.Errorf("%s: got %d-%d, want %d-%d", c.header, start, end, c.start, c.end)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	hdr := FormatContentRange(0, 0, 53687091200)
+	start, end, size, err := ParseContentRange(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 0 || end != 0 || size != 53687091200 {
+		t.Errorf("got %d-%d/%d", start, end, size)
+	}
+}
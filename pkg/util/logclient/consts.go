@@ -75,6 +75,9 @@ const (
 	ACT_SYNC_CONF                    = "sync_conf"
 	ACT_CREATE_BACKUP                = "create_backup"
 	ACT_SWITCH_TO_BACKUP             = "switch_to_backup"
+	ACT_VIP_ATTACH                   = "vip_attach"
+	ACT_VIP_DETACH                   = "vip_detach"
+	ACT_VIP_FAILOVER                 = "vip_failover"
 	ACT_RENEW                        = "renew"
 	ACT_SAVE_IMAGE                   = "save_image"
 	ACT_SET_AUTO_RENEW               = "set_auto_renew"
@@ -110,8 +113,49 @@ const (
 	// 到期释放
 	ACT_SET_EXPIRED_TIME        = "set_expired_time"
 	ACT_VM_SYNC_ISOLATED_DEVICE = "vm_sync_isolated_device"
-
-	ACT_CACHED_IMAGE = "cached_image"
+	ACT_VM_ATTACH_SHARED_DIR    = "vm_attach_shared_dir"
+	ACT_VM_DETACH_SHARED_DIR    = "vm_detach_shared_dir"
+	ACT_VM_SET_TPM              = "vm_set_tpm"
+	ACT_VM_SET_WATCHDOG         = "vm_set_watchdog"
+	ACT_VM_SET_SRIOV_FAILOVER   = "vm_set_sriov_failover"
+	ACT_VM_SET_HUGEPAGE         = "vm_set_hugepage"
+	ACT_VM_SET_BALLOON          = "vm_set_balloon"
+	ACT_VM_SET_VIRTIO_MEM       = "vm_set_virtio_mem"
+	ACT_VM_RESIZE_MEMORY        = "vm_resize_memory"
+	ACT_VM_SET_SECURE_BOOT      = "vm_set_secure_boot"
+	ACT_VM_SET_BOOT_ORDER       = "vm_set_boot_order"
+	ACT_VM_BOOT_ONCE            = "vm_boot_once"
+	ACT_VM_PXE_BOOT             = "vm_pxe_boot"
+	ACT_VM_DEPLOY_VERIFY        = "vm_deploy_verify"
+	ACT_VM_SET_CONFIDENTIAL_VM  = "vm_set_confidential_vm"
+	ACT_VM_UPGRADE_MACHINE_TYPE = "vm_upgrade_machine_type"
+	ACT_VM_SET_VDI_OPTIONS      = "vm_set_vdi_options"
+	ACT_VM_SET_CLOCK_POLICY     = "vm_set_clock_policy"
+	ACT_VM_SET_IOTHREAD_POLICY  = "vm_set_iothread_policy"
+	ACT_VM_SET_CPU_PIN_POLICY   = "vm_set_cpu_pin_policy"
+	ACT_VM_SET_NUMA_POLICY      = "vm_set_numa_policy"
+	ACT_VM_QGA_EXEC             = "vm_qga_exec"
+	ACT_VM_QGA_FILE_WRITE       = "vm_qga_file_write"
+	ACT_VM_QGA_FSFREEZE         = "vm_qga_fsfreeze"
+	ACT_VM_QGA_SYNC_FIREWALL    = "vm_qga_sync_firewall"
+
+	ACT_VM_REMEDIATE_MAINTENANCE = "vm_remediate_maintenance"
+
+	ACT_CACHED_IMAGE  = "cached_image"
+	ACT_SHARE_IMAGE   = "share_image"
+	ACT_UNSHARE_IMAGE = "unshare_image"
+
+	ACT_SNAPSHOT_ARCHIVE        = "snapshot_archive"
+	ACT_SNAPSHOT_ARCHIVE_DELETE = "snapshot_archive_delete"
+
+	ACT_RUNBOOK_EXECUTE = "runbook_execute"
+	ACT_RUNBOOK_APPROVE = "runbook_approve"
+	ACT_RUNBOOK_REJECT  = "runbook_reject"
+
+	ACT_TAG_BACKFILL = "tag_backfill"
+
+	ACT_PROMOTE_IMAGE    = "promote_image"
+	ACT_REJECT_PROMOTION = "reject_promotion"
 
 	ACT_REBOOT        = "reboot"
 	ACT_CHANGE_CONFIG = "change_config"
@@ -127,6 +171,8 @@ const (
 
 	ACT_HEALTH_CHECK = "health_check"
 
+	ACT_INTEGRITY_VERIFY = "integrity_verify"
+
 	ACT_RECYCLE_PREPAID      = "recycle_prepaid"
 	ACT_UNDO_RECYCLE_PREPAID = "undo_recycle_prepaid"
 
@@ -137,7 +183,9 @@ const (
 	ACT_HOST_IMPORT_LIBVIRT_SERVERS = "host_import_libvirt_servers"
 	ACT_GUEST_CREATE_FROM_IMPORT    = "guest_create_from_import"
 	ACT_GUEST_PANICKED              = "guest_panicked"
+	ACT_GUEST_WATCHDOG              = "guest_watchdog"
 	ACT_HOST_MAINTAINING            = "host_maintaining"
+	ACT_HOST_COLLECT_LOG_BUNDLE     = "host_collect_log_bundle"
 
 	ACT_MKDIR          = "mkdir"
 	ACT_DELETE_OBJECT  = "delete_object"
@@ -159,6 +207,7 @@ const (
 	ACT_REVOKE_PRIVILEGE = "revoke_privilege"
 	ACT_SET_PRIVILEGES   = "set_privileges"
 	ACT_RESTORE          = "restore"
+	ACT_DUMP             = "dump"
 	ACT_RESET_PASSWORD   = "reset_password"
 
 	ACT_VM_ASSOCIATE            = "vm_associate"
@@ -197,6 +246,7 @@ const (
 
 	ACT_UPDATE_BILLING_OPTIONS = "update_billing_options"
 	ACT_UPDATE_CREDENTIAL      = "update_credential"
+	ACT_DIAGNOSE               = "diagnose"
 
 	ACT_PULL_SUBCONTACT   = "pull_subcontact"
 	ACT_SEND_NOTIFICATION = "send_notification"
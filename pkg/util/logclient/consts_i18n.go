@@ -112,6 +112,110 @@ func init() {
 		EN("Guest Sync Isolated Device").
 		CN("同步透传设备"),
 	)
+	t.Set(ACT_VM_ATTACH_SHARED_DIR, i18n.NewTableEntry().
+		EN("Guest Attach Shared Dir").
+		CN("挂载共享目录"),
+	)
+	t.Set(ACT_VM_DETACH_SHARED_DIR, i18n.NewTableEntry().
+		EN("Guest Detach Shared Dir").
+		CN("卸载共享目录"),
+	)
+	t.Set(ACT_VM_SET_TPM, i18n.NewTableEntry().
+		EN("Guest Set TPM").
+		CN("设置虚拟TPM"),
+	)
+	t.Set(ACT_VM_SET_WATCHDOG, i18n.NewTableEntry().
+		EN("Guest Set Watchdog").
+		CN("设置看门狗"),
+	)
+	t.Set(ACT_VM_SET_SRIOV_FAILOVER, i18n.NewTableEntry().
+		EN("Guest Set SR-IOV Failover").
+		CN("设置SR-IOV网卡备用切换"),
+	)
+	t.Set(ACT_VM_SET_HUGEPAGE, i18n.NewTableEntry().
+		EN("Guest Set Hugepage").
+		CN("设置虚机大页"),
+	)
+	t.Set(ACT_VM_SET_BALLOON, i18n.NewTableEntry().
+		EN("Guest Set Memory Balloon").
+		CN("设置内存气球"),
+	)
+	t.Set(ACT_VM_REMEDIATE_MAINTENANCE, i18n.NewTableEntry().
+		EN("Guest Remediate Maintenance Event").
+		CN("处理维护事件"),
+	)
+	t.Set(ACT_VM_SET_SECURE_BOOT, i18n.NewTableEntry().
+		EN("Guest Set Secure Boot").
+		CN("设置安全启动"),
+	)
+	t.Set(ACT_VM_SET_BOOT_ORDER, i18n.NewTableEntry().
+		EN("Guest Set Boot Order").
+		CN("设置启动顺序"),
+	)
+	t.Set(ACT_VM_BOOT_ONCE, i18n.NewTableEntry().
+		EN("Guest Boot Once").
+		CN("设置单次启动设备"),
+	)
+	t.Set(ACT_VM_PXE_BOOT, i18n.NewTableEntry().
+		EN("Guest PXE Boot").
+		CN("设置网络引导"),
+	)
+	t.Set(ACT_VM_DEPLOY_VERIFY, i18n.NewTableEntry().
+		EN("Guest Deploy Verify").
+		CN("部署验证"),
+	)
+	t.Set(ACT_VM_SET_CONFIDENTIAL_VM, i18n.NewTableEntry().
+		EN("Guest Set Confidential VM").
+		CN("设置机密计算"),
+	)
+	t.Set(ACT_VM_UPGRADE_MACHINE_TYPE, i18n.NewTableEntry().
+		EN("Guest Upgrade Machine Type").
+		CN("升级机型"),
+	)
+	t.Set(ACT_VM_SET_VDI_OPTIONS, i18n.NewTableEntry().
+		EN("Guest Set VDI Options").
+		CN("设置桌面协议选项"),
+	)
+	t.Set(ACT_VM_SET_CLOCK_POLICY, i18n.NewTableEntry().
+		EN("Guest Set Clock Policy").
+		CN("设置时钟策略"),
+	)
+	t.Set(ACT_VM_SET_IOTHREAD_POLICY, i18n.NewTableEntry().
+		EN("Guest Set IO Thread Policy").
+		CN("设置IO线程策略"),
+	)
+	t.Set(ACT_VM_SET_CPU_PIN_POLICY, i18n.NewTableEntry().
+		EN("Guest Set CPU Pin Policy").
+		CN("设置CPU绑定策略"),
+	)
+	t.Set(ACT_VM_SET_NUMA_POLICY, i18n.NewTableEntry().
+		EN("Guest Set NUMA Policy").
+		CN("设置NUMA策略"),
+	)
+	t.Set(ACT_VM_QGA_EXEC, i18n.NewTableEntry().
+		EN("Guest Agent Exec").
+		CN("通过QGA执行命令"),
+	)
+	t.Set(ACT_VM_QGA_FILE_WRITE, i18n.NewTableEntry().
+		EN("Guest Agent Write File").
+		CN("通过QGA写入文件"),
+	)
+	t.Set(ACT_VM_QGA_FSFREEZE, i18n.NewTableEntry().
+		EN("Guest Agent Filesystem Freeze").
+		CN("通过QGA冻结文件系统"),
+	)
+	t.Set(ACT_VM_QGA_SYNC_FIREWALL, i18n.NewTableEntry().
+		EN("Guest Agent Sync Firewall").
+		CN("通过QGA同步虚机内防火墙"),
+	)
+	t.Set(ACT_VM_SET_VIRTIO_MEM, i18n.NewTableEntry().
+		EN("Guest Set Virtio Mem").
+		CN("设置内存在线扩缩"),
+	)
+	t.Set(ACT_VM_RESIZE_MEMORY, i18n.NewTableEntry().
+		EN("Guest Resize Memory").
+		CN("在线调整内存"),
+	)
 	t.Set(ACT_MERGE, i18n.NewTableEntry().
 		EN("Merge").
 		CN("合并"),
@@ -256,6 +360,18 @@ func init() {
 		EN("Switch To Backup").
 		CN("主备切换"),
 	)
+	t.Set(ACT_VIP_ATTACH, i18n.NewTableEntry().
+		EN("Attach VIP").
+		CN("绑定VIP"),
+	)
+	t.Set(ACT_VIP_DETACH, i18n.NewTableEntry().
+		EN("Detach VIP").
+		CN("解绑VIP"),
+	)
+	t.Set(ACT_VIP_FAILOVER, i18n.NewTableEntry().
+		EN("VIP Failover").
+		CN("VIP切换"),
+	)
 	t.Set(ACT_RENEW, i18n.NewTableEntry().
 		EN("Renew").
 		CN("续费"),
@@ -369,6 +485,46 @@ func init() {
 		EN("Cached Image").
 		CN("缓存镜像"),
 	)
+	t.Set(ACT_SHARE_IMAGE, i18n.NewTableEntry().
+		EN("Share Image").
+		CN("共享镜像"),
+	)
+	t.Set(ACT_UNSHARE_IMAGE, i18n.NewTableEntry().
+		EN("Unshare Image").
+		CN("取消共享镜像"),
+	)
+	t.Set(ACT_SNAPSHOT_ARCHIVE, i18n.NewTableEntry().
+		EN("Archive Snapshot").
+		CN("归档快照"),
+	)
+	t.Set(ACT_SNAPSHOT_ARCHIVE_DELETE, i18n.NewTableEntry().
+		EN("Delete Snapshot Archive").
+		CN("删除快照归档"),
+	)
+	t.Set(ACT_RUNBOOK_EXECUTE, i18n.NewTableEntry().
+		EN("Execute Runbook").
+		CN("执行运维手册"),
+	)
+	t.Set(ACT_RUNBOOK_APPROVE, i18n.NewTableEntry().
+		EN("Approve Runbook Execution").
+		CN("审批通过运维手册执行"),
+	)
+	t.Set(ACT_RUNBOOK_REJECT, i18n.NewTableEntry().
+		EN("Reject Runbook Execution").
+		CN("驳回运维手册执行"),
+	)
+	t.Set(ACT_TAG_BACKFILL, i18n.NewTableEntry().
+		EN("Backfill Tags").
+		CN("批量补齐标签"),
+	)
+	t.Set(ACT_PROMOTE_IMAGE, i18n.NewTableEntry().
+		EN("Promote Image").
+		CN("晋升镜像"),
+	)
+	t.Set(ACT_REJECT_PROMOTION, i18n.NewTableEntry().
+		EN("Reject Image Promotion").
+		CN("拒绝镜像晋升"),
+	)
 
 	t.Set(ACT_REBOOT, i18n.NewTableEntry().
 		EN("Reboot").
@@ -443,6 +599,10 @@ func init() {
 		EN("Guest Panicked").
 		CN("GuestPanicked"),
 	)
+	t.Set(ACT_GUEST_WATCHDOG, i18n.NewTableEntry().
+		EN("Guest Watchdog Triggered").
+		CN("看门狗触发"),
+	)
 	t.Set(ACT_HOST_MAINTAINING, i18n.NewTableEntry().
 		EN("Host Maintaining").
 		CN("宿主机进入维护模式"),
@@ -628,6 +788,10 @@ func init() {
 		EN("Update Credential").
 		CN("更新账号密码"),
 	)
+	t.Set(ACT_DIAGNOSE, i18n.NewTableEntry().
+		EN("Diagnose").
+		CN("诊断"),
+	)
 
 	t.Set(ACT_PULL_SUBCONTACT, i18n.NewTableEntry().
 		EN("Pull Subcontact").
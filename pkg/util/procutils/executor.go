@@ -37,6 +37,10 @@ func SetRemoteExecutor() {
 	execInstance = _remoteExecutor
 }
 
+func IsRemoteExecutor() bool {
+	return execInstance == _remoteExecutor
+}
+
 type Cmd interface {
 	StdinPipe() (io.WriteCloser, error)
 	StdoutPipe() (io.ReadCloser, error)
@@ -310,6 +310,18 @@ func Convert(srcInfo, destInfo SImageInfo, compact bool, workerOpions []string)
 	}
 }
 
+// Compare 校验两个镜像的内容是否一致, 不同格式之间按虚拟磁盘内容比较
+func Compare(srcInfo, destInfo SImageInfo) error {
+	cmdline := []string{"-c", strconv.Itoa(int(srcInfo.IoLevel)),
+		qemutils.GetQemuImg(), "compare", "-f", srcInfo.Format.String(), "-F", destInfo.Format.String(),
+		srcInfo.Path, destInfo.Path}
+	output, err := procutils.NewRemoteCommandAsFarAsPossible("ionice", cmdline...).Output()
+	if err != nil {
+		return errors.Wrapf(err, "compare: %s", output)
+	}
+	return nil
+}
+
 func convertOther(srcInfo, destInfo SImageInfo, compact bool, workerOpions []string) error {
 	cmdline := []string{"-c", strconv.Itoa(int(srcInfo.IoLevel)),
 		qemutils.GetQemuImg(), "convert"}
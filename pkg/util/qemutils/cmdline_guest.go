@@ -0,0 +1,301 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemutils
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+// SCmdlineGuest 从运行中的 qemu 进程命令行还原出的虚机描述
+type SCmdlineGuest struct {
+	Name        string
+	Uuid        string
+	Cpu         int
+	MemSizeMb   int
+	Disks       []SCmdlineDisk
+	Nics        []SCmdlineNic
+	MonitorPath string
+}
+
+type SCmdlineDisk struct {
+	Index  int
+	File   string
+	Format string
+	Driver string
+}
+
+type SCmdlineNic struct {
+	Index  int
+	Mac    string
+	Driver string
+}
+
+// IsQemuBinary 判断命令行的可执行文件是否为 qemu
+func IsQemuBinary(content string) bool {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return false
+	}
+	bin := filepath.Base(fields[0])
+	return bin == "qemu-kvm" || strings.HasPrefix(bin, "qemu-system-")
+}
+
+// parseOptionArgs 解析 qemu 选项参数, 兼容 key=val,... 与 libvirt 新版本的 json 写法,
+// 第一个不带 '=' 的字段作为位置参数返回
+func parseOptionArgs(val string) (string, map[string]string) {
+	args := map[string]string{}
+	val = strings.TrimSpace(val)
+	if strings.HasPrefix(val, "{") {
+		obj, err := jsonutils.ParseString(val)
+		if err != nil {
+			return "", args
+		}
+		kvs, _ := obj.GetMap()
+		for k, v := range kvs {
+			args[k], _ = v.GetString()
+		}
+		return "", args
+	}
+	// ",," 为 qemu 参数中被转义的逗号
+	const escapedComma = "\x00"
+	var positional string
+	for i, seg := range strings.Split(strings.ReplaceAll(val, ",,", escapedComma), ",") {
+		seg = strings.ReplaceAll(seg, escapedComma, ",")
+		if idx := strings.Index(seg, "="); idx > 0 {
+			args[seg[:idx]] = seg[idx+1:]
+		} else if i == 0 {
+			positional = seg
+		}
+	}
+	return positional, args
+}
+
+// parseMemSizeMb 解析 -m 参数, 不带单位时为 MB
+func parseMemSizeMb(size string) (int, error) {
+	size = strings.TrimSpace(size)
+	if len(size) == 0 {
+		return 0, errors.Errorf("empty memory size")
+	}
+	factor := 1.0
+	if unit := size[len(size)-1]; unit < '0' || unit > '9' {
+		size = size[:len(size)-1]
+		switch unit {
+		case 'k', 'K':
+			factor = 1.0 / 1024
+		case 'm', 'M':
+			factor = 1
+		case 'g', 'G':
+			factor = 1024
+		case 't', 'T':
+			factor = 1024 * 1024
+		default:
+			return 0, errors.Errorf("unknown memory unit %q", unit)
+		}
+	}
+	val, err := strconv.ParseFloat(size, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse memory size %q", size)
+	}
+	return int(val*factor + 0.5), nil
+}
+
+func diskDriverOfDevice(driver string) string {
+	switch {
+	case strings.HasPrefix(driver, "virtio-blk"):
+		return "virtio"
+	case driver == "scsi-hd" || driver == "scsi-disk" || driver == "scsi-block":
+		return "scsi"
+	case driver == "ide-hd" || driver == "ide-drive":
+		return "ide"
+	}
+	return ""
+}
+
+func nicDriverOfDevice(driver string) string {
+	switch {
+	case strings.HasPrefix(driver, "virtio-net"):
+		return "virtio"
+	case driver == "e1000" || driver == "e1000e" || driver == "rtl8139" || driver == "vmxnet3":
+		return driver
+	}
+	return ""
+}
+
+type cmdlineBlockNode struct {
+	file   string
+	format string
+	// 格式层节点引用的存储层节点
+	child string
+}
+
+// ParseGuestFromCmdline 从 qemu 命令行解析虚机的名称, uuid, cpu, 内存, 磁盘, 网卡及 QMP 监控 socket
+func ParseGuestFromCmdline(content string) (*SCmdlineGuest, error) {
+	cl, err := NewCmdline(strings.TrimSpace(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "NewCmdline")
+	}
+	guest := &SCmdlineGuest{}
+	var (
+		drives       = map[string]*SCmdlineDisk{}
+		driveIds     = []string{}
+		blockNodes   = map[string]*cmdlineBlockNode{}
+		chardevPaths = map[string]string{}
+		monChardev   string
+		qmpPath      string
+	)
+	for _, opt := range cl.options {
+		pos, args := parseOptionArgs(opt.Value)
+		switch opt.Key {
+		case "name":
+			if name, ok := args["guest"]; ok {
+				guest.Name = name
+			} else {
+				guest.Name = pos
+			}
+		case "uuid":
+			guest.Uuid = strings.TrimSpace(opt.Value)
+		case "smp":
+			cpus := pos
+			if v, ok := args["cpus"]; ok {
+				cpus = v
+			}
+			guest.Cpu, err = strconv.Atoi(cpus)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse smp %q", opt.Value)
+			}
+		case "m":
+			size := pos
+			if v, ok := args["size"]; ok {
+				size = v
+			}
+			guest.MemSizeMb, err = parseMemSizeMb(size)
+			if err != nil {
+				return nil, err
+			}
+		case "drive":
+			if args["media"] == "cdrom" || len(args["file"]) == 0 {
+				continue
+			}
+			disk := &SCmdlineDisk{File: args["file"], Format: args["format"]}
+			switch args["if"] {
+			case "virtio", "scsi", "ide":
+				disk.Driver = args["if"]
+			}
+			id := args["id"]
+			if len(id) == 0 {
+				id = disk.File
+			}
+			drives[id] = disk
+			driveIds = append(driveIds, id)
+		case "blockdev":
+			nodeName := args["node-name"]
+			if len(nodeName) == 0 {
+				continue
+			}
+			node := &cmdlineBlockNode{}
+			if args["driver"] == "file" || args["driver"] == "host_device" {
+				node.file = args["filename"]
+			} else {
+				node.format = args["driver"]
+				node.child = args["file"]
+			}
+			blockNodes[nodeName] = node
+		case "net":
+			if pos == "nic" && len(args["macaddr"]) > 0 {
+				driver := nicDriverOfDevice(args["model"])
+				if len(driver) == 0 {
+					driver = "virtio"
+				}
+				guest.Nics = append(guest.Nics, SCmdlineNic{
+					Index:  len(guest.Nics),
+					Mac:    args["macaddr"],
+					Driver: driver,
+				})
+			}
+		case "chardev":
+			if pos == "socket" || args["backend"] == "socket" {
+				chardevPaths[args["id"]] = args["path"]
+			}
+		case "mon":
+			if args["mode"] == "control" {
+				monChardev = args["chardev"]
+			}
+		case "qmp":
+			if strings.HasPrefix(pos, "unix:") {
+				qmpPath = strings.TrimPrefix(pos, "unix:")
+			}
+		}
+	}
+	// 块设备可能先于 drive/blockdev 出现, 需在所有后端解析完成后再处理
+	for _, opt := range cl.options {
+		if opt.Key != "device" {
+			continue
+		}
+		pos, args := parseOptionArgs(opt.Value)
+		driver := pos
+		if v, ok := args["driver"]; ok {
+			driver = v
+		}
+		if nicDriver := nicDriverOfDevice(driver); len(nicDriver) > 0 {
+			if mac := args["mac"]; len(mac) > 0 {
+				guest.Nics = append(guest.Nics, SCmdlineNic{
+					Index:  len(guest.Nics),
+					Mac:    mac,
+					Driver: nicDriver,
+				})
+			}
+		} else if diskDriver := diskDriverOfDevice(driver); len(diskDriver) > 0 {
+			id := args["drive"]
+			if disk, ok := drives[id]; ok {
+				disk.Driver = diskDriver
+			} else if node, ok := blockNodes[id]; ok {
+				disk := &SCmdlineDisk{Driver: diskDriver, File: node.file, Format: "raw"}
+				if len(node.child) > 0 {
+					disk.Format = node.format
+					if child, ok := blockNodes[node.child]; ok {
+						disk.File = child.file
+					}
+				}
+				if len(disk.File) > 0 {
+					drives[id] = disk
+					driveIds = append(driveIds, id)
+				}
+			}
+		}
+	}
+
+	if len(guest.Uuid) == 0 {
+		return nil, errors.Errorf("cmdline missing uuid")
+	}
+	for _, id := range driveIds {
+		disk := drives[id]
+		if len(disk.Driver) == 0 {
+			// 未被任何块设备引用的 drive, 例如光驱
+			continue
+		}
+		disk.Index = len(guest.Disks)
+		guest.Disks = append(guest.Disks, *disk)
+	}
+	guest.MonitorPath = chardevPaths[monChardev]
+	if len(guest.MonitorPath) == 0 {
+		guest.MonitorPath = qmpPath
+	}
+	return guest, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGuestFromCmdline(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *SCmdlineGuest
+	}{
+		{
+			name:    "libvirt drive",
+			content: `/usr/libexec/qemu-kvm -name guest=centos7,debug-threads=on -S -machine pc-i440fx-rhel7.6.0,accel=kvm -m 2048 -smp 2,sockets=2,cores=1,threads=1 -uuid 6c8e7a3a-0f4e-4c3c-9a0e-1f5e2d0c1b2a -chardev socket,id=charmonitor,path=/var/lib/libvirt/qemu/domain-3-centos7/monitor.sock,server,nowait -mon chardev=charmonitor,id=monitor,mode=control -drive file=/var/lib/libvirt/images/centos7.qcow2,format=qcow2,if=none,id=drive-virtio-disk0 -device virtio-blk-pci,scsi=off,bus=pci.0,addr=0x6,drive=drive-virtio-disk0,id=virtio-disk0,bootindex=1 -drive if=none,id=drive-ide0-0-0,readonly=on -device ide-cd,bus=ide.0,unit=0,drive=drive-ide0-0-0,id=ide0-0-0 -netdev tap,fd=26,id=hostnet0,vhost=on,vhostfd=28 -device virtio-net-pci,netdev=hostnet0,id=net0,mac=52:54:00:8f:1a:2b,bus=pci.0,addr=0x3`,
+			want: &SCmdlineGuest{
+				Name:      "centos7",
+				Uuid:      "6c8e7a3a-0f4e-4c3c-9a0e-1f5e2d0c1b2a",
+				Cpu:       2,
+				MemSizeMb: 2048,
+				Disks: []SCmdlineDisk{
+					{Index: 0, File: "/var/lib/libvirt/images/centos7.qcow2", Format: "qcow2", Driver: "virtio"},
+				},
+				Nics: []SCmdlineNic{
+					{Index: 0, Mac: "52:54:00:8f:1a:2b", Driver: "virtio"},
+				},
+				MonitorPath: "/var/lib/libvirt/qemu/domain-3-centos7/monitor.sock",
+			},
+		},
+		{
+			name:    "libvirt blockdev json",
+			content: `/usr/bin/qemu-system-x86_64 -name guest=ubuntu,debug-threads=on -m size=4194304k -smp 4,sockets=4,cores=1,threads=1 -uuid 0b1d7c6e-3f2a-4d5b-8c9e-7a6b5c4d3e2f -chardev socket,id=charmonitor,path=/var/lib/libvirt/qemu/domain-1-ubuntu/monitor.sock,server=on,wait=off -mon chardev=charmonitor,id=monitor,mode=control -device {"driver":"virtio-blk-pci","bus":"pci.0","drive":"libvirt-1-format","id":"virtio-disk0"} -blockdev {"driver":"file","filename":"/data/ubuntu.qcow2","node-name":"libvirt-1-storage"} -blockdev {"node-name":"libvirt-1-format","driver":"qcow2","file":"libvirt-1-storage"} -device {"driver":"e1000","netdev":"hostnet0","id":"net0","mac":"52:54:00:11:22:33"}`,
+			want: &SCmdlineGuest{
+				Name:      "ubuntu",
+				Uuid:      "0b1d7c6e-3f2a-4d5b-8c9e-7a6b5c4d3e2f",
+				Cpu:       4,
+				MemSizeMb: 4096,
+				Disks: []SCmdlineDisk{
+					{Index: 0, File: "/data/ubuntu.qcow2", Format: "qcow2", Driver: "virtio"},
+				},
+				Nics: []SCmdlineNic{
+					{Index: 0, Mac: "52:54:00:11:22:33", Driver: "e1000"},
+				},
+				MonitorPath: "/var/lib/libvirt/qemu/domain-1-ubuntu/monitor.sock",
+			},
+		},
+		{
+			name:    "plain qemu",
+			content: `qemu-system-x86_64 -enable-kvm -name win10 -uuid 9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a -m 8G -smp cpus=8 -qmp unix:/run/qemu/win10.qmp,server,nowait -drive file=/vm/win10.img,,bak,if=ide,format=raw -drive file=/iso/virtio.iso,media=cdrom -net nic,macaddr=52:54:00:aa:bb:cc,model=rtl8139 -net tap`,
+			want: &SCmdlineGuest{
+				Name:      "win10",
+				Uuid:      "9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a",
+				Cpu:       8,
+				MemSizeMb: 8192,
+				Disks: []SCmdlineDisk{
+					{Index: 0, File: "/vm/win10.img,bak", Format: "raw", Driver: "ide"},
+				},
+				Nics: []SCmdlineNic{
+					{Index: 0, Mac: "52:54:00:aa:bb:cc", Driver: "rtl8139"},
+				},
+				MonitorPath: "/run/qemu/win10.qmp",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, IsQemuBinary(tt.content))
+			got, err := ParseGuestFromCmdline(tt.content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParseGuestFromCmdline(`qemu-kvm -name nouuid -m 512`)
+	assert.Error(t, err)
+	assert.False(t, IsQemuBinary("/usr/sbin/libvirtd --listen"))
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	DEFAULT_PASSWORD_LENGTH = 12
+)
+
+// SPasswordPolicy 密码复杂度策略, 用于校验及生成虚机登录密码
+type SPasswordPolicy struct {
+	// 最小长度
+	MinLength int
+	// 必须包含数字
+	RequireDigits bool
+	// 必须包含小写字母
+	RequireLowercases bool
+	// 必须包含大写字母
+	RequireUppercases bool
+	// 必须包含特殊字符
+	RequirePunctuats bool
+}
+
+func (p SPasswordPolicy) Validate(passwd string) error {
+	ps := AnalyzePasswordStrenth(passwd)
+	if len(ps.Invalid) > 0 {
+		return httperrors.NewInputParameterError("invalid characters %s", string(ps.Invalid))
+	}
+	if utils.IsInStringArray(passwd, WEAK_PASSWORDS) {
+		return httperrors.NewWeakPasswordError()
+	}
+	if ps.Len() < p.MinLength {
+		return httperrors.NewInputParameterError("password length must be at least %d", p.MinLength)
+	}
+	if p.RequireDigits && ps.Digits == 0 {
+		return httperrors.NewInputParameterError("password must contain digits")
+	}
+	if p.RequireLowercases && ps.Lowercases == 0 {
+		return httperrors.NewInputParameterError("password must contain lowercase letters")
+	}
+	if p.RequireUppercases && ps.Uppercases == 0 {
+		return httperrors.NewInputParameterError("password must contain uppercase letters")
+	}
+	if p.RequirePunctuats && ps.Punctuats == 0 {
+		return httperrors.NewInputParameterError("password must contain punctuations")
+	}
+	return nil
+}
+
+// RandomPassword 生成满足策略的随机密码, RandomPassword2 生成的密码总是包含全部四类字符
+func (p SPasswordPolicy) RandomPassword() string {
+	width := DEFAULT_PASSWORD_LENGTH
+	if p.MinLength > width {
+		width = p.MinLength
+	}
+	for {
+		passwd := RandomPassword2(width)
+		if p.Validate(passwd) == nil {
+			return passwd
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seclib2
+
+import (
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := SPasswordPolicy{
+		MinLength:         16,
+		RequireDigits:     true,
+		RequireLowercases: true,
+		RequireUppercases: true,
+		RequirePunctuats:  true,
+	}
+	cases := []struct {
+		in    string
+		valid bool
+	}{
+		{"123abcABC!@#", false},
+		{"123abcABC!@#xyzW", true},
+		{"1234abcdefghijklm", false},
+		{"Admin@123", false},
+		{"123abcABC!@#xyzW\t", false},
+	}
+	for _, c := range cases {
+		err := policy.Validate(c.in)
+		if (err == nil) != c.valid {
+			t.Errorf("%q valid %v, got error %v", c.in, c.valid, err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		passwd := policy.RandomPassword()
+		if len(passwd) != 16 {
+			t.Errorf("random password %s length %d", passwd, len(passwd))
+		}
+		if err := policy.Validate(passwd); err != nil {
+			t.Errorf("random password %s: %v", passwd, err)
+		}
+	}
+	if passwd := (SPasswordPolicy{}).RandomPassword(); len(passwd) != DEFAULT_PASSWORD_LENGTH {
+		t.Errorf("default random password %s", passwd)
+	}
+}
@@ -15,6 +15,7 @@
 package sysutils
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
@@ -79,8 +80,41 @@ func fetchHugepageInfo(sizeKb int, dir string) (SHugepageInfo, error) {
 	return info, nil
 }
 
+func (a THugepages) Get(sizeKb int) *SHugepageInfo {
+	for i := range a {
+		if a[i].SizeKb == sizeKb {
+			return &a[i]
+		}
+	}
+	return nil
+}
+
+// CheckFree 检查指定大小的大页池是否有足够空闲页容纳 memMb 内存
+func (a THugepages) CheckFree(sizeKb int, memMb int64) error {
+	info := a.Get(sizeKb)
+	if info == nil || info.Total == 0 {
+		return errors.Errorf("no %dKB hugepage pool", sizeKb)
+	}
+	if memMb*1024%int64(sizeKb) != 0 {
+		return errors.Errorf("memory %dMB is not aligned to hugepage size %dKB", memMb, sizeKb)
+	}
+	need := memMb * 1024 / int64(sizeKb)
+	if need > int64(info.Free) {
+		return errors.Errorf("%dKB hugepages not enough, need %d free %d", sizeKb, need, info.Free)
+	}
+	return nil
+}
+
 func GetHugepages() (THugepages, error) {
-	const hugepageDir = "/sys/kernel/mm/hugepages"
+	return getHugepagesFromDir("/sys/kernel/mm/hugepages")
+}
+
+// GetNodeHugepages 获取指定 NUMA 节点上的大页池
+func GetNodeHugepages(nodeId int) (THugepages, error) {
+	return getHugepagesFromDir(fmt.Sprintf("/sys/devices/system/node/node%d/hugepages", nodeId))
+}
+
+func getHugepagesFromDir(hugepageDir string) (THugepages, error) {
 	files, err := ioutil.ReadDir(hugepageDir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "ReadDir %s", hugepageDir)
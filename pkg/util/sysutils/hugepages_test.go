@@ -28,3 +28,29 @@ func TestGetHugepages(t *testing.T) {
 		t.Logf("%s: size: %dMb", jsonutils.Marshal(hp), hp.BytesMb())
 	}
 }
+
+func TestHugepagesCheckFree(t *testing.T) {
+	hp := THugepages{
+		{SizeKb: 2048, Total: 1024, Free: 512},
+		{SizeKb: 1048576, Total: 4, Free: 2},
+	}
+	cases := []struct {
+		sizeKb  int
+		memMb   int64
+		wantErr bool
+	}{
+		{2048, 1024, false},
+		{2048, 1026, true},
+		{2048, 1025, true},
+		{1048576, 2048, false},
+		{1048576, 3072, true},
+		{1048576, 1536, true},
+		{4, 1024, true},
+	}
+	for _, c := range cases {
+		err := hp.CheckFree(c.sizeKb, c.memMb)
+		if (err != nil) != c.wantErr {
+			t.Errorf("CheckFree(%d, %d) want err %v got %v", c.sizeKb, c.memMb, c.wantErr, err)
+		}
+	}
+}
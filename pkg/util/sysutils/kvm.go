@@ -38,11 +38,18 @@ const (
 	HOST_NEST_UNSUPPORT = "0"
 	HOST_NEST_SUPPORT   = "1"
 	HOST_NEST_ENABLE    = "3"
+
+	CONFIDENTIAL_SEV     = "sev"
+	CONFIDENTIAL_SEV_ES  = "sev-es"
+	CONFIDENTIAL_SEV_SNP = "sev-snp"
+	CONFIDENTIAL_TDX     = "tdx"
 )
 
 var (
 	kvmModuleSupport string
 	nestStatus       string
+
+	confidentialComputing []string
 )
 
 func GetKVMModuleSupport() string {
@@ -180,6 +187,59 @@ func unloadKvmModule(name string) bool {
 	return ModprobeKvmModule(name, true, false)
 }
 
+// GetConfidentialComputingSupport 返回宿主机支持的机密计算类型(sev/sev-es/sev-snp/tdx)
+func GetConfidentialComputingSupport() []string {
+	if confidentialComputing == nil {
+		confidentialComputing = detectConfidentialComputingSupport()
+	}
+	return confidentialComputing
+}
+
+func IsConfidentialComputingSupported(cvmType string) bool {
+	return utils.IsInStringArray(cvmType, GetConfidentialComputingSupport())
+}
+
+func detectConfidentialComputingSupport() []string {
+	ret := []string{}
+	switch GetKVMModuleSupport() {
+	case KVM_MODULE_AMD:
+		if !fileutils2.Exists("/dev/sev") {
+			break
+		}
+		ret = parseConfidentialComputingParams(map[string]string{
+			CONFIDENTIAL_SEV:     GetKernelModuleParameter(KVM_MODULE_AMD, "sev"),
+			CONFIDENTIAL_SEV_ES:  GetKernelModuleParameter(KVM_MODULE_AMD, "sev_es"),
+			CONFIDENTIAL_SEV_SNP: GetKernelModuleParameter(KVM_MODULE_AMD, "sev_snp"),
+		})
+	case KVM_MODULE_INTEL:
+		ret = parseConfidentialComputingParams(map[string]string{
+			CONFIDENTIAL_TDX: GetKernelModuleParameter(KVM_MODULE_INTEL, "tdx"),
+		})
+	}
+	if len(ret) > 0 {
+		log.Infof("Host support confidential computing: %v", ret)
+	}
+	return ret
+}
+
+func parseConfidentialComputingParams(params map[string]string) []string {
+	ret := []string{}
+	for _, t := range []string{CONFIDENTIAL_SEV, CONFIDENTIAL_SEV_ES, CONFIDENTIAL_SEV_SNP, CONFIDENTIAL_TDX} {
+		val, ok := params[t]
+		if !ok {
+			continue
+		}
+		if utils.IsInStringArray(strings.ToUpper(val), []string{"Y", "1"}) {
+			ret = append(ret, t)
+		}
+	}
+	// sev-es/sev-snp 依赖 sev
+	if len(ret) > 0 && ret[0] != CONFIDENTIAL_SEV && ret[0] != CONFIDENTIAL_TDX {
+		return []string{}
+	}
+	return ret
+}
+
 func GetKernelModuleParameter(name, moduel string) string {
 	pa := path.Join("/sys/module/", strings.Replace(name, "-", "_", -1), "/parameters/", moduel)
 	return GetSysConfig(pa)
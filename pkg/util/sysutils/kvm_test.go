@@ -15,6 +15,7 @@
 package sysutils
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -25,3 +26,37 @@ func TestIsHypervisor(t *testing.T) {
 		t.Logf("Running in a baremetal")
 	}
 }
+
+func TestParseConfidentialComputingParams(t *testing.T) {
+	cases := []struct {
+		params map[string]string
+		want   []string
+	}{
+		{
+			params: map[string]string{CONFIDENTIAL_SEV: "Y", CONFIDENTIAL_SEV_ES: "Y", CONFIDENTIAL_SEV_SNP: "N"},
+			want:   []string{CONFIDENTIAL_SEV, CONFIDENTIAL_SEV_ES},
+		},
+		{
+			params: map[string]string{CONFIDENTIAL_SEV: "1", CONFIDENTIAL_SEV_ES: "0"},
+			want:   []string{CONFIDENTIAL_SEV},
+		},
+		{
+			params: map[string]string{CONFIDENTIAL_SEV: "N", CONFIDENTIAL_SEV_ES: "Y"},
+			want:   []string{},
+		},
+		{
+			params: map[string]string{CONFIDENTIAL_TDX: "Y"},
+			want:   []string{CONFIDENTIAL_TDX},
+		},
+		{
+			params: map[string]string{CONFIDENTIAL_TDX: ""},
+			want:   []string{},
+		},
+	}
+	for _, c := range cases {
+		got := parseConfidentialComputingParams(c.params)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("params %v want %v got %v", c.params, c.want, got)
+		}
+	}
+}
@@ -30,6 +30,7 @@ type Vpc struct {
 
 	Wire     *Wire    `json:"-"`
 	Networks Networks `json:"-"`
+	DnsZones DnsZones `json:"-"`
 }
 
 func (el *Vpc) Copy() *Vpc {
@@ -79,6 +80,11 @@ func (el *Network) Copy() *Network {
 	}
 }
 
+// 配置了 IPv6 网关和前缀长度的网络按双栈处理
+func (el *Network) IsIPv6Enabled() bool {
+	return el.GuestGateway6 != "" && el.GuestIp6Mask > 0
+}
+
 type Guestnetwork struct {
 	compute_models.SGuestnetwork
 
@@ -95,6 +101,11 @@ func (el *Guestnetwork) Copy() *Guestnetwork {
 	}
 }
 
+// 网卡分配了 IPv6 地址且所在网络启用 IPv6
+func (el *Guestnetwork) IsDualStack() bool {
+	return el.Ip6Addr != "" && el.Network != nil && el.Network.IsIPv6Enabled()
+}
+
 type NetworkAddress struct {
 	compute_models.SNetworkAddress
 
@@ -211,6 +222,40 @@ func (el *DnsRecord) Copy() *DnsRecord {
 	}
 }
 
+type DnsZone struct {
+	compute_models.SDnsZone
+
+	RecordSets DnsRecordSets `json:"-"`
+}
+
+func (el *DnsZone) Copy() *DnsZone {
+	return &DnsZone{
+		SDnsZone: el.SDnsZone,
+	}
+}
+
+type DnsZoneVpc struct {
+	compute_models.SDnsZoneVpc
+}
+
+func (el *DnsZoneVpc) Copy() *DnsZoneVpc {
+	return &DnsZoneVpc{
+		SDnsZoneVpc: el.SDnsZoneVpc,
+	}
+}
+
+type DnsRecordSet struct {
+	compute_models.SDnsRecordSet
+
+	DnsZone *DnsZone `json:"-"`
+}
+
+func (el *DnsRecordSet) Copy() *DnsRecordSet {
+	return &DnsRecordSet{
+		SDnsRecordSet: el.SDnsRecordSet,
+	}
+}
+
 type Groupguest struct {
 	compute_models.SGroupguest
 
@@ -17,6 +17,7 @@ package models
 import (
 	"fmt"
 
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/apihelper"
@@ -40,7 +41,10 @@ type (
 	Guestnetworks  map[string]*Guestnetwork  // key: rowId
 	Guestsecgroups map[string]*Guestsecgroup // key: guestId/secgroupId
 
-	DnsRecords map[string]*DnsRecord
+	DnsRecords    map[string]*DnsRecord
+	DnsZones      map[string]*DnsZone
+	DnsZoneVpcs   map[string]*DnsZoneVpc // key: rowId
+	DnsRecordSets map[string]*DnsRecordSet
 
 	RouteTables map[string]*RouteTable
 
@@ -724,6 +728,110 @@ func (set DnsRecords) Copy() apihelper.IModelSet {
 	return setCopy
 }
 
+func (set DnsZones) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.DnsZones
+}
+
+func (set DnsZones) NewModel() db.IModel {
+	return &DnsZone{}
+}
+
+func (set DnsZones) AddModel(i db.IModel) {
+	m := i.(*DnsZone)
+	if m.ZoneType != string(cloudprovider.PrivateZone) {
+		// 仅私有解析域需要下发到 vpc 内
+		return
+	}
+	set[m.Id] = m
+}
+
+func (set DnsZones) Copy() apihelper.IModelSet {
+	setCopy := DnsZones{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms DnsZones) joinDnsRecordSets(subEntries DnsRecordSets) bool {
+	for _, m := range ms {
+		m.RecordSets = DnsRecordSets{}
+	}
+	for _, subEntry := range subEntries {
+		m, ok := ms[subEntry.DnsZoneId]
+		if !ok {
+			// 公有解析域的记录
+			subEntry.DnsZone = nil
+			continue
+		}
+		subEntry.DnsZone = m
+		m.RecordSets[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (set DnsZoneVpcs) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.DnsZoneVpcs
+}
+
+func (set DnsZoneVpcs) NewModel() db.IModel {
+	return &DnsZoneVpc{}
+}
+
+func (set DnsZoneVpcs) AddModel(i db.IModel) {
+	m := i.(*DnsZoneVpc)
+	k := fmt.Sprintf("%d", m.RowId)
+	set[k] = m
+}
+
+func (set DnsZoneVpcs) Copy() apihelper.IModelSet {
+	setCopy := DnsZoneVpcs{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms DnsZoneVpcs) join(dnsZones DnsZones, vpcs Vpcs) bool {
+	for _, vpc := range vpcs {
+		vpc.DnsZones = DnsZones{}
+	}
+	for _, m := range ms {
+		dnsZone, ok := dnsZones[m.DnsZoneId]
+		if !ok {
+			continue
+		}
+		vpc, ok := vpcs[m.VpcId]
+		if !ok {
+			// 非 ovn vpc
+			continue
+		}
+		vpc.DnsZones[dnsZone.Id] = dnsZone
+	}
+	return true
+}
+
+func (set DnsRecordSets) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.DnsRecordSets
+}
+
+func (set DnsRecordSets) NewModel() db.IModel {
+	return &DnsRecordSet{}
+}
+
+func (set DnsRecordSets) AddModel(i db.IModel) {
+	m := i.(*DnsRecordSet)
+	set[m.Id] = m
+}
+
+func (set DnsRecordSets) Copy() apihelper.IModelSet {
+	setCopy := DnsRecordSets{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
 func (set RouteTables) ModelManager() mcclient_modulebase.IBaseManager {
 	return &mcclient_modules.RouteTables
 }
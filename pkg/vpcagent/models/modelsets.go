@@ -36,7 +36,10 @@ type ModelSetsMaxUpdatedAt struct {
 	Elasticips         time.Time
 	NetworkAddresses   time.Time
 
-	DnsRecords time.Time
+	DnsRecords    time.Time
+	DnsZones      time.Time
+	DnsZoneVpcs   time.Time
+	DnsRecordSets time.Time
 
 	RouteTables time.Time
 
@@ -62,7 +65,10 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 		Elasticips:         apihelper.PseudoZeroTime,
 		NetworkAddresses:   apihelper.PseudoZeroTime,
 
-		DnsRecords: apihelper.PseudoZeroTime,
+		DnsRecords:    apihelper.PseudoZeroTime,
+		DnsZones:      apihelper.PseudoZeroTime,
+		DnsZoneVpcs:   apihelper.PseudoZeroTime,
+		DnsRecordSets: apihelper.PseudoZeroTime,
 
 		RouteTables: apihelper.PseudoZeroTime,
 
@@ -88,7 +94,10 @@ type ModelSets struct {
 	Elasticips         Elasticips
 	NetworkAddresses   NetworkAddresses
 
-	DnsRecords DnsRecords
+	DnsRecords    DnsRecords
+	DnsZones      DnsZones
+	DnsZoneVpcs   DnsZoneVpcs
+	DnsRecordSets DnsRecordSets
 
 	RouteTables RouteTables
 
@@ -115,7 +124,10 @@ func NewModelSets() *ModelSets {
 		Elasticips:         Elasticips{},
 		NetworkAddresses:   NetworkAddresses{},
 
-		DnsRecords: DnsRecords{},
+		DnsRecords:    DnsRecords{},
+		DnsZones:      DnsZones{},
+		DnsZoneVpcs:   DnsZoneVpcs{},
+		DnsRecordSets: DnsRecordSets{},
 
 		RouteTables: RouteTables{},
 
@@ -145,6 +157,9 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 		mss.NetworkAddresses,
 
 		mss.DnsRecords,
+		mss.DnsZones,
+		mss.DnsZoneVpcs,
+		mss.DnsRecordSets,
 
 		mss.RouteTables,
 
@@ -175,7 +190,10 @@ func (mss *ModelSets) copy_() *ModelSets {
 		Elasticips:         mss.Elasticips.Copy().(Elasticips),
 		NetworkAddresses:   mss.NetworkAddresses.Copy().(NetworkAddresses),
 
-		DnsRecords: mss.DnsRecords.Copy().(DnsRecords),
+		DnsRecords:    mss.DnsRecords.Copy().(DnsRecords),
+		DnsZones:      mss.DnsZones.Copy().(DnsZones),
+		DnsZoneVpcs:   mss.DnsZoneVpcs.Copy().(DnsZoneVpcs),
+		DnsRecordSets: mss.DnsRecordSets.Copy().(DnsRecordSets),
 
 		RouteTables: mss.RouteTables.Copy().(RouteTables),
 
@@ -266,6 +284,10 @@ func (mss *ModelSets) join() bool {
 	msg = append(msg, "mss.LoadbalancerNetworks.joinLoadbalancerListeners(mss.LoadbalancerListeners)")
 	p = append(p, mss.LoadbalancerListeners.joinLoadbalancerAcls(mss.LoadbalancerAcls))
 	msg = append(msg, "mss.LoadbalancerListeners.joinLoadbalancerAcls(mss.LoadbalancerAcls)")
+	p = append(p, mss.DnsZones.joinDnsRecordSets(mss.DnsRecordSets))
+	msg = append(msg, "mss.DnsZones.joinDnsRecordSets(mss.DnsRecordSets)")
+	p = append(p, mss.DnsZoneVpcs.join(mss.DnsZones, mss.Vpcs))
+	msg = append(msg, "mss.DnsZoneVpcs.join(mss.DnsZones, mss.Vpcs)")
 	ret := true
 	var failMsg []string
 	for i, b := range p {
@@ -22,9 +22,15 @@ import (
 	compute_models "yunion.io/x/onecloud/pkg/compute/models"
 )
 
-func (el *Guest) OrderedSecurityGroupRules() []*SecurityGroupRule {
+const (
+	SecurityGroupPriorityBase      = 100
+	AdminSecurityGroupPriorityBase = 1000
+)
+
+// DefaultSecurityGroupRules 返回所有虚机网卡共有的基础规则
+func DefaultSecurityGroupRules() []*SecurityGroupRule {
 	// deny any incoming traffic and allow ARP
-	rs := []*SecurityGroupRule{
+	return []*SecurityGroupRule{
 		{
 			// deny all in-bound traffic
 			SSecurityGroupRule: compute_models.SSecurityGroupRule{
@@ -43,17 +49,22 @@ func (el *Guest) OrderedSecurityGroupRules() []*SecurityGroupRule {
 			},
 		},
 	}
+}
+
+func (el *Guest) OrderedSecurityGroupRules() []*SecurityGroupRule {
+	rs := DefaultSecurityGroupRules()
 	for _, secgroup := range el.SecurityGroups {
-		rs = append(rs, secgroup.securityGroupRules(100)...)
+		rs = append(rs, secgroup.PrioritizedRules(SecurityGroupPriorityBase)...)
 	}
 	if el.AdminSecurityGroup != nil {
-		rs = append(rs, el.AdminSecurityGroup.securityGroupRules(1000)...)
+		rs = append(rs, el.AdminSecurityGroup.PrioritizedRules(AdminSecurityGroupPriorityBase)...)
 	}
 	sort.Slice(rs, SecurityGroupRuleLessFunc(rs))
 	return rs
 }
 
-func (el *SecurityGroup) securityGroupRules(basePriority int64) []*SecurityGroupRule {
+// PrioritizedRules 返回按 basePriority 偏移优先级后的规则副本
+func (el *SecurityGroup) PrioritizedRules(basePriority int64) []*SecurityGroupRule {
 	rs := make([]*SecurityGroupRule, 0, len(el.SecurityGroupRules))
 	for _, r := range el.SecurityGroupRules {
 		r = r.Copy()
@@ -15,11 +15,14 @@
 package options
 
 import (
+	"strings"
+
 	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/util/ovsutils"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovnutil"
 )
 
 const (
@@ -38,9 +41,17 @@ type VpcAgentOptions struct {
 	APIRunDelayMilliseconds int `default:"100"`
 	APIListBatchSize        int `default:"1024"`
 
+	APIFullSyncIntervalSeconds int `help:"interval of full re-list from region api besides incremental sync by updated_at watermark, 0 to disable" default:"3600"`
+
 	OvnWorkerCheckInterval int    `default:"180"`
-	OvnNorthDatabase       string `help:"address for accessing ovn north database.  Default to local unix socket"`
+	OvnNorthDatabase       string `help:"address for accessing ovn north database, comma separated for raft cluster.  Default to local unix socket"`
 	OvnUnderlayMtu         int    `help:"mtu of ovn underlay network" default:"1500"`
+
+	OvnNorthDatabaseCheckIntervalSeconds int `help:"interval for checking health and leadership of ovn north database" default:"30"`
+
+	OvnAclStatsIntervalSeconds int `help:"interval for collecting secgroup rule hit counts from ovn acl stats of hosts, 0 to disable" default:"600"`
+
+	OvnSecgroupPortGroup bool `help:"realize guest secgroups as ovn port groups with shared acls, instead of acls of each guest logical port"`
 }
 
 type Options struct {
@@ -70,10 +81,18 @@ func (opts *Options) ValidateThenInit() error {
 		opts.OvnUnderlayMtu = 576
 	}
 
-	if db, err := ovsutils.NormalizeDbHost(opts.OvnNorthDatabase); err != nil {
-		return err
-	} else {
-		opts.OvnNorthDatabase = db
+	if opts.OvnNorthDatabaseCheckIntervalSeconds <= 5 {
+		opts.OvnNorthDatabaseCheckIntervalSeconds = 5
+	}
+
+	dbs := ovnutil.SplitDbs(opts.OvnNorthDatabase)
+	for i := range dbs {
+		db, err := ovsutils.NormalizeDbHost(dbs[i])
+		if err != nil {
+			return err
+		}
+		dbs[i] = db
 	}
+	opts.OvnNorthDatabase = strings.Join(dbs, ",")
 	return nil
 }
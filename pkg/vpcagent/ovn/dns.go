@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"sort"
+	"strings"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+type dnsRecords map[string][]string
+
+func (recs dnsRecords) add(name string, addrs ...string) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return
+	}
+	for _, addr := range addrs {
+		if addr == "" {
+			continue
+		}
+		recs[name] = append(recs[name], addr)
+	}
+}
+
+// ovnRecords 转换为 OVN DNS 表 records 列, 同一名称的地址去重排序后以空格分隔
+func (recs dnsRecords) ovnRecords() map[string]string {
+	ret := map[string]string{}
+	for name, addrs := range recs {
+		if len(addrs) == 0 {
+			continue
+		}
+		sort.Strings(addrs)
+		uniq := addrs[:1]
+		for _, addr := range addrs[1:] {
+			if addr != uniq[len(uniq)-1] {
+				uniq = append(uniq, addr)
+			}
+		}
+		ret[name] = strings.Join(uniq, " ")
+	}
+	return ret
+}
+
+// vpcGuestDnsRecords 收集 vpc 内虚机名称及主机名的解析记录,
+// 设置了 vpc 域名后缀时同时生成 <名称>.<后缀> 记录
+func vpcGuestDnsRecords(vpc *agentmodels.Vpc, recs dnsRecords) {
+	suffix := strings.Trim(vpc.DnsDomain, ".")
+	for _, network := range vpc.Networks {
+		for _, guestnetwork := range network.Guestnetworks {
+			guest := guestnetwork.Guest
+			if guest == nil {
+				continue
+			}
+			addrs := []string{guestnetwork.IpAddr, guestnetwork.Ip6Addr}
+			names := []string{guest.Name}
+			if guest.Hostname != "" && guest.Hostname != guest.Name {
+				names = append(names, guest.Hostname)
+			}
+			for _, name := range names {
+				recs.add(name, addrs...)
+				if suffix != "" {
+					recs.add(name+"."+suffix, addrs...)
+				}
+			}
+		}
+	}
+}
+
+// vpcZoneDnsRecords 收集关联到 vpc 的私有解析域中的 A/AAAA 记录
+func vpcZoneDnsRecords(vpc *agentmodels.Vpc, recs dnsRecords) {
+	for _, zone := range vpc.DnsZones {
+		if !zone.Enabled.Bool() || zone.Status != api.DNS_ZONE_STATUS_AVAILABLE {
+			continue
+		}
+		for _, rs := range zone.RecordSets {
+			if !rs.Enabled.Bool() {
+				continue
+			}
+			if rs.DnsType != "A" && rs.DnsType != "AAAA" {
+				continue
+			}
+			name := zone.Name
+			if rs.Name != "" && rs.Name != "@" {
+				name = rs.Name + "." + zone.Name
+			}
+			recs.add(name, strings.Fields(strings.ReplaceAll(rs.DnsValue, ",", " "))...)
+		}
+	}
+}
+
+func vpcDnsRecords(vpc *agentmodels.Vpc) map[string]string {
+	recs := dnsRecords{}
+	vpcGuestDnsRecords(vpc, recs)
+	vpcZoneDnsRecords(vpc, recs)
+	return recs.ovnRecords()
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/pkg/tristate"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	compute_models "yunion.io/x/onecloud/pkg/compute/models"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestVpcDnsRecords(t *testing.T) {
+	guest1 := &agentmodels.Guest{}
+	guest1.Name = "web1"
+	guest1.Hostname = "Web-1"
+	guest2 := &agentmodels.Guest{}
+	guest2.Name = "db"
+
+	gn1 := &agentmodels.Guestnetwork{Guest: guest1}
+	gn1.IpAddr = "10.0.0.10"
+	gn1.Ip6Addr = "fd00::10"
+	gn2 := &agentmodels.Guestnetwork{Guest: guest2}
+	gn2.IpAddr = "10.0.1.20"
+	gn3 := &agentmodels.Guestnetwork{Guest: guest2}
+	gn3.IpAddr = "10.0.0.20"
+	gnPending := &agentmodels.Guestnetwork{}
+	gnPending.IpAddr = "10.0.0.30"
+
+	net1 := &agentmodels.Network{
+		Guestnetworks: agentmodels.Guestnetworks{"1": gn1, "3": gn3, "4": gnPending},
+	}
+	net2 := &agentmodels.Network{
+		Guestnetworks: agentmodels.Guestnetworks{"2": gn2},
+	}
+
+	zone := &agentmodels.DnsZone{
+		RecordSets: agentmodels.DnsRecordSets{
+			"rs1": &agentmodels.DnsRecordSet{SDnsRecordSet: compute_models.SDnsRecordSet{DnsType: "A", DnsValue: "10.0.0.100"}},
+			"rs2": &agentmodels.DnsRecordSet{SDnsRecordSet: compute_models.SDnsRecordSet{DnsType: "CNAME", DnsValue: "web1"}},
+			"rs3": &agentmodels.DnsRecordSet{SDnsRecordSet: compute_models.SDnsRecordSet{DnsType: "AAAA", DnsValue: "fd00::100"}},
+			"rs4": &agentmodels.DnsRecordSet{SDnsRecordSet: compute_models.SDnsRecordSet{DnsType: "A", DnsValue: "10.0.0.200"}},
+		},
+	}
+	zone.Name = "corp.example"
+	zone.Enabled = tristate.True
+	zone.Status = api.DNS_ZONE_STATUS_AVAILABLE
+	for id, name := range map[string]string{"rs1": "api", "rs2": "www", "rs3": "@", "rs4": "off"} {
+		rs := zone.RecordSets[id]
+		rs.Name = name
+		rs.Enabled = tristate.True
+	}
+	zone.RecordSets["rs4"].Enabled = tristate.False
+
+	disabledZone := &agentmodels.DnsZone{}
+	disabledZone.Name = "disabled.example"
+	disabledZone.Enabled = tristate.False
+	disabledZone.RecordSets = agentmodels.DnsRecordSets{
+		"rs5": &agentmodels.DnsRecordSet{SDnsRecordSet: compute_models.SDnsRecordSet{DnsType: "A", DnsValue: "10.0.0.5"}},
+	}
+
+	vpc := &agentmodels.Vpc{
+		Networks: agentmodels.Networks{"net1": net1, "net2": net2},
+		DnsZones: agentmodels.DnsZones{"zone": zone, "disabled": disabledZone},
+	}
+
+	want := map[string]string{
+		"web1":             "10.0.0.10 fd00::10",
+		"web-1":            "10.0.0.10 fd00::10",
+		"db":               "10.0.0.20 10.0.1.20",
+		"api.corp.example": "10.0.0.100",
+		"corp.example":     "fd00::100",
+	}
+	if got := vpcDnsRecords(vpc); !reflect.DeepEqual(got, want) {
+		t.Errorf("records without suffix\n got %v\nwant %v", got, want)
+	}
+
+	vpc.DnsDomain = "vpc1.internal."
+	want["web1.vpc1.internal"] = "10.0.0.10 fd00::10"
+	want["web-1.vpc1.internal"] = "10.0.0.10 fd00::10"
+	want["db.vpc1.internal"] = "10.0.0.20 10.0.1.20"
+	if got := vpcDnsRecords(vpc); !reflect.DeepEqual(got, want) {
+		t.Errorf("records with suffix\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestDnsRecordsDedup(t *testing.T) {
+	recs := dnsRecords{}
+	recs.add("Host.", "10.0.0.2", "10.0.0.1", "", "10.0.0.2")
+	recs.add("", "10.0.0.3")
+	got := recs.ovnRecords()
+	want := map[string]string{"host": "10.0.0.1 10.0.0.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/errors"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func ip6PrefixCidr(addr string, masklen int8) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() != nil {
+		return "", errors.Errorf("invalid ipv6 address %q", addr)
+	}
+	if masklen <= 0 || masklen > 127 {
+		return "", errors.Errorf("invalid ipv6 prefix length %d", masklen)
+	}
+	prefix := ip.Mask(net.CIDRMask(int(masklen), 128))
+	return fmt.Sprintf("%s/%d", prefix.String(), masklen), nil
+}
+
+// netDhcp6Options 生成子网 DHCPv6 选项, 虚机通过有状态 DHCPv6 获取地址,
+// server_id 与 DHCPv4 共用同一 MAC
+func netDhcp6Options(network *agentmodels.Network, dhcpMac string) (*ovn_nb.DHCPOptions, error) {
+	cidr, err := ip6PrefixCidr(network.GuestGateway6, network.GuestIp6Mask)
+	if err != nil {
+		return nil, errors.Wrapf(err, "network %s", network.Id)
+	}
+	dhcp6opts := &ovn_nb.DHCPOptions{
+		Cidr: cidr,
+		Options: map[string]string{
+			"server_id": dhcpMac,
+		},
+		ExternalIds: map[string]string{
+			externalKeyOcRef: dhcp6OptRef(network.Id),
+		},
+	}
+	if dnsSrvs := strings.TrimSpace(network.GuestDns6); dnsSrvs != "" {
+		dhcp6opts.Options["dns_server"] = "{" + dnsSrvs + "}"
+	}
+	domain := strings.TrimSpace(network.GuestDomain6)
+	if domain == "" && network.Vpc != nil {
+		domain = strings.Trim(network.Vpc.DnsDomain, ".")
+	}
+	if domain != "" {
+		dhcp6opts.Options["domain_search"] = fmt.Sprintf("%q", domain)
+	}
+	return dhcp6opts, nil
+}
+
+// netRnpIpv6RaConfigs 子网路由器端口的路由通告配置, 通告默认路由并指示虚机使用有状态 DHCPv6
+func netRnpIpv6RaConfigs(mtu int) map[string]string {
+	return map[string]string{
+		"address_mode":  "dhcpv6_stateful",
+		"send_periodic": "true",
+		"mtu":           fmt.Sprintf("%d", mtu),
+	}
+}
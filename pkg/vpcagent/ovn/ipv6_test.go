@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	compute_models "yunion.io/x/onecloud/pkg/compute/models"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestIp6PrefixCidr(t *testing.T) {
+	cases := []struct {
+		addr    string
+		masklen int8
+		want    string
+		wantErr bool
+	}{
+		{"2001:db8:1:2::1", 64, "2001:db8:1:2::/64", false},
+		{"fd00:10:20::1", 32, "fd00:10::/32", false},
+		{"192.168.0.1", 24, "", true},
+		{"2001:db8::1", 0, "", true},
+		{"bad", 64, "", true},
+	}
+	for _, c := range cases {
+		got, err := ip6PrefixCidr(c.addr, c.masklen)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ip6PrefixCidr(%s, %d) error %v", c.addr, c.masklen, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ip6PrefixCidr(%s, %d) = %s, want %s", c.addr, c.masklen, got, c.want)
+		}
+	}
+}
+
+func TestNetDhcp6Options(t *testing.T) {
+	network := &agentmodels.Network{
+		SNetwork: compute_models.SNetwork{
+			GuestGateway6: "2001:db8:1:2::1",
+			GuestIp6Mask:  64,
+			GuestDns6:     "2001:4860:4860::8888,2001:4860:4860::8844",
+		},
+	}
+	network.Id = "net0"
+	if !network.IsIPv6Enabled() {
+		t.Fatalf("network should be ipv6 enabled")
+	}
+	opts, err := netDhcp6Options(network, "0a:00:00:00:00:01")
+	if err != nil {
+		t.Fatalf("netDhcp6Options: %v", err)
+	}
+	if opts.Cidr != "2001:db8:1:2::/64" {
+		t.Errorf("cidr %s", opts.Cidr)
+	}
+	if opts.Options["server_id"] != "0a:00:00:00:00:01" {
+		t.Errorf("server_id %s", opts.Options["server_id"])
+	}
+	if opts.Options["dns_server"] != "{2001:4860:4860::8888,2001:4860:4860::8844}" {
+		t.Errorf("dns_server %s", opts.Options["dns_server"])
+	}
+	if opts.ExternalIds[externalKeyOcRef] != "dhcp6/net0" {
+		t.Errorf("oc-ref %s", opts.ExternalIds[externalKeyOcRef])
+	}
+	if _, ok := opts.Options["domain_search"]; ok {
+		t.Errorf("unexpected domain_search")
+	}
+}
@@ -38,6 +38,9 @@ import (
 const (
 	externalKeyOcVersion = "oc-version"
 	externalKeyOcRef     = "oc-ref"
+
+	// 安全组规则 ACL 对应的规则 ID, 用于汇总命中统计
+	externalKeyOcSgrId = "oc-sgr-id"
 )
 
 type OVNNorthboundKeeper struct {
@@ -57,6 +60,7 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.PortGroup,
 	}
 	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
@@ -293,6 +297,15 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 		netAddr := ipAddr.NetAddr(network.GuestIpMask)
 		netAddrCidr = fmt.Sprintf("%s/%d", netAddr, network.GuestIpMask)
 	}
+	var dhcp6opts *ovn_nb.DHCPOptions
+	if network.IsIPv6Enabled() {
+		opts, err := netDhcp6Options(network, dhcpMac)
+		if err != nil {
+			return err
+		}
+		dhcp6opts = opts
+		netRnp.Networks = append(netRnp.Networks, fmt.Sprintf("%s/%d", network.GuestGateway6, network.GuestIp6Mask))
+	}
 	vpcExtBackRoute := &ovn_nb.LogicalRouterStaticRoute{
 		Policy:     ptr("dst-ip"),
 		IpPrefix:   netAddrCidr,
@@ -304,8 +317,11 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 		mdIp, "0.0.0.0",
 		"0.0.0.0/0", network.GuestGateway,
 	}
-	mtu := opts.OvnUnderlayMtu
-	mtu -= apis.VPC_OVN_ENCAP_COST
+	mtu := apis.VpcOvnMtu(opts.OvnUnderlayMtu, vpc.Mtu)
+	netRnp.Options = netRnpMtuOptions(mtu)
+	if dhcp6opts != nil {
+		netRnp.Ipv6RaConfigs = netRnpIpv6RaConfigs(mtu)
+	}
 	const (
 		leaseTime  = 86400 * 365 * 3
 		renewTime  = 86400
@@ -345,6 +361,9 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 		}
 		dhcpopts.Options["dns_server"] = "{" + dnsSrvs + "}"
 	}
+	if domain := strings.Trim(vpc.DnsDomain, "."); domain != "" {
+		dhcpopts.Options["domain_name"] = fmt.Sprintf("%q", domain)
+	}
 	{
 		ntpSrvs := ""
 		if network.GuestNtp != "" {
@@ -367,14 +386,18 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 		args      []string
 		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
 	)
-	allFound, args := cmp(&keeper.DB, ocVersion,
+	irows := []types.IRow{
 		netLs,
 		netRnp,
 		netNrp,
 		netMdp,
 		dhcpopts,
 		vpcExtBackRoute,
-	)
+	}
+	if dhcp6opts != nil {
+		irows = append(irows, dhcp6opts)
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
 		return nil
 	}
@@ -383,6 +406,9 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	args = append(args, ovnCreateArgs(netNrp, netNrp.Name)...)
 	args = append(args, ovnCreateArgs(netMdp, netMdp.Name)...)
 	args = append(args, ovnCreateArgs(dhcpopts, "dhcpopts")...)
+	if dhcp6opts != nil {
+		args = append(args, ovnCreateArgs(dhcp6opts, "dhcp6opts")...)
+	}
 	args = append(args, ovnCreateArgs(vpcExtBackRoute, "vpcExtBackRoute")...)
 	args = append(args, "--", "add", "Logical_Switch", netLs.Name, "ports", "@"+netNrp.Name, "@"+netMdp.Name)
 	args = append(args, "--", "add", "Logical_Router", vpcLrName(vpc.Id), "ports", "@"+netRnp.Name)
@@ -447,7 +473,26 @@ func (keeper *OVNNorthboundKeeper) ClaimVpcEipgw(ctx context.Context, vpc *agent
 	return keeper.cli.Must(ctx, "ClaimVpcEipgw", args)
 }
 
-func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestnetwork *agentmodels.Guestnetwork) error {
+// findDhcpOptions returns uuid of DHCP_Options row with the oc-ref, or
+// empty string when not found
+func (keeper *OVNNorthboundKeeper) findDhcpOptions(ctx context.Context, ocRef string) string {
+	dhcpOptQuery := &ovn_nb.DHCPOptions{
+		ExternalIds: map[string]string{
+			externalKeyOcRef: ocRef,
+		},
+	}
+	if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
+		return m.OvsdbUuid()
+	}
+	args := []string{
+		"--bare", "--columns=_uuid", "find", "DHCP_Options",
+		fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, ocRef),
+	}
+	res := keeper.cli.Must(ctx, "find dhcpopt", args)
+	return strings.TrimSpace(res.Output)
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestnetwork *agentmodels.Guestnetwork, opts *options.Options) error {
 	var (
 		// Callers assure that guestnetwork.Guest is not nil
 		guest   = guestnetwork.Guest
@@ -463,26 +508,17 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		ocQosRef        = fmt.Sprintf("qos/%s/%s/%s", network.Id, guestnetwork.GuestId, guestnetwork.Ifname)
 		ocQosEipRef     = fmt.Sprintf("qos-eip/%s/%s/%s/v2", vpc.Id, guestnetwork.GuestId, guestnetwork.Ifname)
 		dhcpOpt         string
+		dhcp6Opt        string
 	)
 
-	{
-		dhcpOptQuery := &ovn_nb.DHCPOptions{
-			ExternalIds: map[string]string{
-				externalKeyOcRef: guestnetwork.NetworkId,
-			},
-		}
-		if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
-			dhcpOpt = m.OvsdbUuid()
-		} else {
-			args := []string{
-				"--bare", "--columns=_uuid", "find", "DHCP_Options",
-				fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, guestnetwork.NetworkId),
-			}
-			res := keeper.cli.Must(ctx, "find dhcpopt", args)
-			dhcpOpt = strings.TrimSpace(res.Output)
-		}
-		if dhcpOpt == "" {
-			return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	dhcpOpt = keeper.findDhcpOptions(ctx, guestnetwork.NetworkId)
+	if dhcpOpt == "" {
+		return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	}
+	if guestnetwork.IsDualStack() {
+		dhcp6Opt = keeper.findDhcpOptions(ctx, dhcp6OptRef(guestnetwork.NetworkId))
+		if dhcp6Opt == "" {
+			return fmt.Errorf("cannot find dhcp6opt for subnet %s", guestnetwork.NetworkId)
 		}
 	}
 
@@ -497,12 +533,19 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	subIPms = append(subIPms, guestnetwork.Guest.GetVips()...)
 	sort.Strings(subIPs[1:])
 	sort.Strings(subIPms[1:])
+	if dhcp6Opt != "" {
+		subIPs = append(subIPs, guestnetwork.Ip6Addr)
+		subIPms = append(subIPms, fmt.Sprintf("%s/%d", guestnetwork.Ip6Addr, network.GuestIp6Mask))
+	}
 	gnp := &ovn_nb.LogicalSwitchPort{
 		Name:          lportName,
 		Addresses:     []string{fmt.Sprintf("%s %s", guestnetwork.MacAddr, strings.Join(subIPs, " "))},
 		Dhcpv4Options: &dhcpOpt,
 		Options:       map[string]string{},
 	}
+	if dhcp6Opt != "" {
+		gnp.Dhcpv6Options = &dhcp6Opt
+	}
 	if guest.SrcMacCheck.IsFalse() {
 		gnp.Addresses = append(gnp.Addresses, "unknown")
 		// empty, not nil, as match condition
@@ -619,7 +662,8 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	}
 
 	var acls []*ovn_nb.ACL
-	{
+	// port group 模式下安全组规则由 ClaimSecgroupPortGroups 统一下发
+	if !opts.OvnSecgroupPortGroup {
 		sgrs := guest.OrderedSecurityGroupRules()
 		for _, sgr := range sgrs {
 			// kvm not support peer secgroup
@@ -633,7 +677,8 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 				break
 			}
 			acl.ExternalIds = map[string]string{
-				externalKeyOcRef: ocAclRef,
+				externalKeyOcRef:   ocAclRef,
+				externalKeyOcSgrId: sgr.Id,
 			}
 			acls = append(acls, acl)
 		}
@@ -839,53 +884,43 @@ func (keeper *OVNNorthboundKeeper) ClaimLoadbalancerNetwork(ctx context.Context,
 	return keeper.cli.Must(ctx, "ClaimLoadbalancerNetwork", args)
 }
 
+// ClaimVpcGuestDnsRecords 下发 vpc 内虚机名称及私有解析域记录到 OVN DNS 表
 func (keeper *OVNNorthboundKeeper) ClaimVpcGuestDnsRecords(ctx context.Context, vpc *agentmodels.Vpc) error {
-	var (
-		grs = map[string][]string{}
-		has = map[string]struct{}{}
-	)
+	has := map[string]struct{}{}
 	for _, network := range vpc.Networks {
-		hasValid := false
 		for _, guestnetwork := range network.Guestnetworks {
-			if guest := guestnetwork.Guest; guest != nil {
-				var (
-					name = guest.Name
-					ip   = guestnetwork.IpAddr
-				)
-				grs[name] = append(grs[name], ip)
-				if !hasValid {
-					hasValid = true
-				}
+			if guestnetwork.Guest != nil {
+				has[network.Id] = struct{}{}
+				break
 			}
 		}
-		if hasValid {
-			has[network.Id] = struct{}{}
-		}
+	}
+	if len(has) == 0 {
+		return nil
+	}
+	records := vpcDnsRecords(vpc)
+	if len(records) == 0 {
+		return nil
 	}
 
-	if len(has) > 0 {
-		var (
-			grs_      = map[string]string{}
-			ocVersion = fmt.Sprintf("%s.%d", vpc.Id, vpc.UpdateVersion)
-		)
-		for name, addrs := range grs {
-			sort.Strings(addrs)
-			grs_[name] = strings.Join(addrs, " ")
-		}
-		dns := &ovn_nb.DNS{
-			Records: grs_,
-		}
-		allFound, args := cmp(&keeper.DB, ocVersion, dns)
-		if allFound {
-			return nil
-		}
-		args = append(args, ovnCreateArgs(dns, "dns")...)
-		for networkId := range has {
-			args = append(args, "--", "add", "Logical_Switch", netLsName(networkId), "dns_records", "@dns")
-		}
-		return keeper.cli.Must(ctx, "ClaimVpcGuestDnsRecords", args)
+	var (
+		ocVersion = fmt.Sprintf("%s.%d", vpc.Id, vpc.UpdateVersion)
+	)
+	dns := &ovn_nb.DNS{
+		Records: records,
+		ExternalIds: map[string]string{
+			externalKeyOcRef: vpcDnsRef(vpc.Id),
+		},
 	}
-	return nil
+	allFound, args := cmp(&keeper.DB, ocVersion, dns)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(dns, "dns")...)
+	for networkId := range has {
+		args = append(args, "--", "add", "Logical_Switch", netLsName(networkId), "dns_records", "@dns")
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcGuestDnsRecords", args)
 }
 
 func (keeper *OVNNorthboundKeeper) ClaimDnsRecords(ctx context.Context, vpcs agentmodels.Vpcs, dnsrecords agentmodels.DnsRecords) error {
@@ -1090,6 +1125,7 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.PortGroup,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -1108,6 +1144,7 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.PortGroup,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
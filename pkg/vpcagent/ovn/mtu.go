@@ -0,0 +1,30 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+)
+
+// netRnpMtuOptions 子网路由器端口的 MTU 选项
+//
+// 设置 gateway_mtu 后, 经路由转发进入子网且超出 MTU 的报文由 OVN 直接回复
+// ICMP need-frag (IPv4) 或 Packet Too Big (IPv6), 使对端完成路径 MTU 探测.
+// 安全组 ACL 为有状态规则, 这些 ICMP 报文作为关联流量放行
+func netRnpMtuOptions(mtu int) map[string]string {
+	return map[string]string{
+		"gateway_mtu": fmt.Sprintf("%d", mtu),
+	}
+}
@@ -16,6 +16,7 @@ package ovn
 
 import (
 	"fmt"
+	"strings"
 )
 
 func vpcLrName(vpcId string) string {
@@ -97,6 +98,17 @@ func netMdpName(netId string) string {
 	return fmt.Sprintf("subnet-md/%s", netId)
 }
 
+// dhcp6OptRef returns oc-ref of subnet DHCPv6 options, the DHCPv4 one uses
+// network id directly
+func dhcp6OptRef(netId string) string {
+	return fmt.Sprintf("dhcp6/%s", netId)
+}
+
+// vpcDnsRef returns oc-ref of vpc DNS records
+func vpcDnsRef(vpcId string) string {
+	return fmt.Sprintf("dns/%s", vpcId)
+}
+
 // gnpName returns Logical_Switch_Port name for guestnetwork
 //
 // The name must match what's going to be set on each chassis
@@ -114,3 +126,32 @@ func vipName(netId string, groupId string, ipaddr string) string {
 func lbpName(lbId string) string {
 	return fmt.Sprintf("iface/lb/%s", lbId)
 }
+
+// Port_Group names can only contain alphanumerics and underscores, and are
+// also used as prefix of the address sets OVN maintains for them
+const secgroupBasePgName = "sgbase"
+
+func pgNameSafe(id string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// secgroupPgName returns Port_Group name for ports with the secgroup
+func secgroupPgName(secgroupId string) string {
+	return "sg_" + pgNameSafe(secgroupId)
+}
+
+// adminSecgroupPgName returns Port_Group name for ports with the secgroup as
+// admin secgroup
+func adminSecgroupPgName(secgroupId string) string {
+	return "sgadm_" + pgNameSafe(secgroupId)
+}
+
+// secgroupPgRef returns oc-ref of secgroup Port_Group and its ACLs
+func secgroupPgRef(pgName string) string {
+	return fmt.Sprintf("pg/%s", pgName)
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/util/sets"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// secgroupPg 为期望的安全组 port group, 同一安全组的规则只生成一份 ACL,
+// 由组内所有虚机端口共享
+type secgroupPg struct {
+	name     string
+	ports    sets.String
+	secgroup *agentmodels.SecurityGroup
+	base     int64
+}
+
+// secgroupPortGroups 按 VPC 内虚机网卡所属安全组生成 port group 及其 ACL.
+// 所有网卡加入基础组以应用默认的入方向拒绝规则; 普通安全组与管理员安全组
+// 分别成组, 以保持与逐端口 ACL 相同的优先级偏移
+func secgroupPortGroups(vpcs agentmodels.Vpcs) ([]string, map[string][]string, map[string][]*ovn_nb.ACL) {
+	pgs := map[string]*secgroupPg{}
+	claim := func(name string, secgroup *agentmodels.SecurityGroup, base int64, lport string) {
+		pg, ok := pgs[name]
+		if !ok {
+			pg = &secgroupPg{
+				name:     name,
+				ports:    sets.NewString(),
+				secgroup: secgroup,
+				base:     base,
+			}
+			pgs[name] = pg
+		}
+		pg.ports.Insert(lport)
+	}
+	for _, vpc := range vpcs {
+		if vpc.Id == apis.DEFAULT_VPC_ID {
+			continue
+		}
+		for _, network := range vpc.Networks {
+			for _, guestnetwork := range network.Guestnetworks {
+				guest := guestnetwork.Guest
+				if guest == nil {
+					continue
+				}
+				lport := gnpName(guestnetwork.NetworkId, guestnetwork.Ifname)
+				claim(secgroupBasePgName, nil, 0, lport)
+				for _, secgroup := range guest.SecurityGroups {
+					claim(secgroupPgName(secgroup.Id), secgroup, agentmodels.SecurityGroupPriorityBase, lport)
+				}
+				if secgroup := guest.AdminSecurityGroup; secgroup != nil {
+					claim(adminSecgroupPgName(secgroup.Id), secgroup, agentmodels.AdminSecurityGroupPriorityBase, lport)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(pgs))
+	ports := map[string][]string{}
+	acls := map[string][]*ovn_nb.ACL{}
+	for name, pg := range pgs {
+		names = append(names, name)
+		ports[name] = pg.ports.List()
+
+		var rules []*agentmodels.SecurityGroupRule
+		if pg.secgroup == nil {
+			rules = agentmodels.DefaultSecurityGroupRules()
+		} else {
+			rules = pg.secgroup.PrioritizedRules(pg.base)
+		}
+		for _, rule := range rules {
+			peerPg := ""
+			if rule.PeerSecgroupId != "" {
+				peerPg = secgroupPgName(rule.PeerSecgroupId)
+				if _, ok := pgs[peerPg]; !ok {
+					// 对端安全组没有 VPC 内的端口, 规则不会匹配任何流量
+					continue
+				}
+			}
+			acl, err := ruleToPgAcl(name, peerPg, rule)
+			if err != nil {
+				log.Errorf("converting security group rule %s to acl: %v", rule.Id, err)
+				continue
+			}
+			acl.ExternalIds = map[string]string{
+				externalKeyOcRef: secgroupPgRef(name),
+			}
+			if rule.Id != "" {
+				acl.ExternalIds[externalKeyOcSgrId] = rule.Id
+			}
+			acls[name] = append(acls[name], acl)
+		}
+		sort.Slice(acls[name], func(i, j int) bool {
+			return pgAclKey(acls[name][i]) < pgAclKey(acls[name][j])
+		})
+	}
+	sort.Strings(names)
+	return names, ports, acls
+}
+
+func pgAclKey(acl *ovn_nb.ACL) string {
+	return fmt.Sprintf("%s/%05d/%s/%s/%s",
+		acl.Direction, acl.Priority, acl.Action, acl.Match, acl.ExternalIds[externalKeyOcSgrId])
+}
+
+// diffPgAcls 按内容比较 port group 现有 ACL 与期望 ACL, 返回需保留、新建与
+// 移除的 ACL, 规则未变化的 ACL 保持不动, 其命中计数也得以延续
+func diffPgAcls(existing, desired []*ovn_nb.ACL) (found, toAdd, toRemove []*ovn_nb.ACL) {
+	byKey := map[string][]*ovn_nb.ACL{}
+	for _, acl := range existing {
+		key := pgAclKey(acl)
+		byKey[key] = append(byKey[key], acl)
+	}
+	for _, acl := range desired {
+		key := pgAclKey(acl)
+		if olds := byKey[key]; len(olds) > 0 {
+			found = append(found, olds[0])
+			byKey[key] = olds[1:]
+		} else {
+			toAdd = append(toAdd, acl)
+		}
+	}
+	for _, acl := range existing {
+		for _, old := range byKey[pgAclKey(acl)] {
+			if old == acl {
+				toRemove = append(toRemove, acl)
+				break
+			}
+		}
+	}
+	return found, toAdd, toRemove
+}
+
+// ClaimSecgroupPortGroups 增量同步安全组 port group: 仅更新成员有变化的组,
+// 仅增删内容有变化的 ACL
+func (keeper *OVNNorthboundKeeper) ClaimSecgroupPortGroups(ctx context.Context, vpcs agentmodels.Vpcs) error {
+	var (
+		db         = &keeper.DB
+		lspNames   = map[string]string{}
+		aclsByUuid = map[string]*ovn_nb.ACL{}
+		args       []string
+	)
+	for _, irow := range db.LogicalSwitchPort.Rows() {
+		lspNames[irow.OvsdbUuid()] = irow.(*ovn_nb.LogicalSwitchPort).Name
+	}
+	for _, irow := range db.ACL.Rows() {
+		aclsByUuid[irow.OvsdbUuid()] = irow.(*ovn_nb.ACL)
+	}
+
+	names, ports, acls := secgroupPortGroups(vpcs)
+	for i, name := range names {
+		var (
+			pg          = db.PortGroup.GetByName(&ovn_nb.PortGroup{Name: name})
+			curPorts    []string
+			curAcls     []*ovn_nb.ACL
+			portsChange bool
+		)
+		if pg == nil {
+			pgRow := &ovn_nb.PortGroup{
+				Name: name,
+				ExternalIds: map[string]string{
+					externalKeyOcRef: secgroupPgRef(name),
+				},
+			}
+			args = append(args, ovnCreateArgs(pgRow, fmt.Sprintf("pg%d", i))...)
+			portsChange = true
+		} else {
+			pg.SetExternalId(externalKeyOcVersion, secgroupPgRef(name))
+			for _, uuid := range pg.Ports {
+				if lspName, ok := lspNames[uuid]; ok {
+					curPorts = append(curPorts, lspName)
+				}
+			}
+			for _, uuid := range pg.Acls {
+				if acl, ok := aclsByUuid[uuid]; ok {
+					curAcls = append(curAcls, acl)
+				}
+			}
+			portsChange = !sets.NewString(curPorts...).Equal(sets.NewString(ports[name]...))
+		}
+		if portsChange {
+			args = append(args, "--", "pg-set-ports", name)
+			args = append(args, ports[name]...)
+		}
+
+		found, toAdd, toRemove := diffPgAcls(curAcls, acls[name])
+		for _, acl := range found {
+			acl.SetExternalId(externalKeyOcVersion, secgroupPgRef(name))
+		}
+		for _, acl := range toRemove {
+			args = append(args, "--", "remove", "Port_Group", name, "acls", acl.OvsdbUuid())
+		}
+		for j, acl := range toAdd {
+			ref := fmt.Sprintf("pg%dacl%d", i, j)
+			args = append(args, ovnCreateArgs(acl, ref)...)
+			args = append(args, "--", "add", "Port_Group", name, "acls", "@"+ref)
+		}
+	}
+	if len(args) > 0 {
+		keeper.cli.Must(ctx, "ClaimSecgroupPortGroups", args)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/util/secrules"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestSecgroupPortGroups(t *testing.T) {
+	newRule := func(id, dir, proto, ports, peer string, prio int64) *agentmodels.SecurityGroupRule {
+		r := &agentmodels.SecurityGroupRule{}
+		r.Id = id
+		r.Direction = dir
+		r.Protocol = proto
+		r.Ports = ports
+		r.PeerSecgroupId = peer
+		r.Action = string(secrules.SecurityRuleAllow)
+		r.Priority = prio
+		return r
+	}
+	web := &agentmodels.SecurityGroup{
+		SecurityGroupRules: agentmodels.SecurityGroupRules{
+			"r1": newRule("r1", string(secrules.SecurityRuleIngress), secrules.PROTO_TCP, "80", "", 1),
+		},
+	}
+	web.Id = "web-sg"
+	db := &agentmodels.SecurityGroup{
+		SecurityGroupRules: agentmodels.SecurityGroupRules{
+			"r2": newRule("r2", string(secrules.SecurityRuleIngress), secrules.PROTO_TCP, "3306", "web-sg", 1),
+			"r3": newRule("r3", string(secrules.SecurityRuleIngress), secrules.PROTO_TCP, "22", "ops-sg", 2),
+		},
+	}
+	db.Id = "db-sg"
+
+	guest1 := &agentmodels.Guest{SecurityGroups: agentmodels.SecurityGroups{"web-sg": web}}
+	guest2 := &agentmodels.Guest{
+		SecurityGroups:     agentmodels.SecurityGroups{"db-sg": db},
+		AdminSecurityGroup: web,
+	}
+	gn1 := &agentmodels.Guestnetwork{Guest: guest1}
+	gn1.NetworkId = "net1"
+	gn1.Ifname = "vnet1"
+	gn2 := &agentmodels.Guestnetwork{Guest: guest2}
+	gn2.NetworkId = "net1"
+	gn2.Ifname = "vnet2"
+	vpc := &agentmodels.Vpc{
+		Networks: agentmodels.Networks{
+			"net1": &agentmodels.Network{
+				Guestnetworks: agentmodels.Guestnetworks{"1": gn1, "2": gn2},
+			},
+		},
+	}
+	vpc.Id = "vpc1"
+
+	names, ports, acls := secgroupPortGroups(agentmodels.Vpcs{"vpc1": vpc})
+	wantNames := []string{"sg_db_sg", "sg_web_sg", "sgadm_web_sg", "sgbase"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("names: want %v, got %v", wantNames, names)
+	}
+	wantPorts := map[string][]string{
+		"sgbase":       {"iface-net1-vnet1", "iface-net1-vnet2"},
+		"sg_web_sg":    {"iface-net1-vnet1"},
+		"sg_db_sg":     {"iface-net1-vnet2"},
+		"sgadm_web_sg": {"iface-net1-vnet2"},
+	}
+	if !reflect.DeepEqual(ports, wantPorts) {
+		t.Errorf("ports: want %v, got %v", wantPorts, ports)
+	}
+	if n := len(acls["sgbase"]); n != 2 {
+		t.Errorf("sgbase: want 2 default acls, got %d", n)
+	}
+	// 对端安全组无端口的规则被忽略
+	if n := len(acls["sg_db_sg"]); n != 1 {
+		t.Fatalf("sg_db_sg: want 1 acl, got %d", n)
+	}
+	acl := acls["sg_db_sg"][0]
+	wantMatch := "outport == @sg_db_sg && ip4 && ip4.src == $sg_web_sg_ip4 && tcp && tcp.dst == 3306"
+	if acl.Match != wantMatch || acl.Priority != 101 || acl.Action != "allow-related" {
+		t.Errorf("sg_db_sg acl: got %#v", acl)
+	}
+	if acl.ExternalIds[externalKeyOcSgrId] != "r2" || acl.ExternalIds[externalKeyOcRef] != "pg/sg_db_sg" {
+		t.Errorf("sg_db_sg acl external ids: got %v", acl.ExternalIds)
+	}
+	if got := acls["sgadm_web_sg"][0].Priority; got != 1001 {
+		t.Errorf("sgadm_web_sg acl priority: want 1001, got %d", got)
+	}
+}
+
+func TestDiffPgAcls(t *testing.T) {
+	newAcl := func(uuid string, prio int64, match string) *ovn_nb.ACL {
+		return &ovn_nb.ACL{
+			Uuid:        uuid,
+			Direction:   aclDirToLport,
+			Priority:    prio,
+			Action:      "allow-related",
+			Match:       match,
+			ExternalIds: map[string]string{},
+		}
+	}
+	existing := []*ovn_nb.ACL{
+		newAcl("u1", 101, "tcp"),
+		newAcl("u2", 102, "udp"),
+		newAcl("u3", 102, "udp"),
+	}
+	desired := []*ovn_nb.ACL{
+		newAcl("", 101, "tcp"),
+		newAcl("", 102, "udp"),
+		newAcl("", 103, "icmp4"),
+	}
+	found, toAdd, toRemove := diffPgAcls(existing, desired)
+	if len(found) != 2 || found[0].Uuid != "u1" || found[1].Uuid != "u2" {
+		t.Errorf("found: got %v", found)
+	}
+	if len(toAdd) != 1 || toAdd[0].Match != "icmp4" {
+		t.Errorf("toAdd: got %v", toAdd)
+	}
+	if len(toRemove) != 1 || toRemove[0].Uuid != "u3" {
+		t.Errorf("toRemove: got %v", toRemove)
+	}
+}
+
+func TestSecgroupPgName(t *testing.T) {
+	if got := secgroupPgName("2f1c-9a.b"); got != "sg_2f1c_9a_b" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	mcclient_modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// 北向 ACL UUID 前 8 位(即南向逻辑流表的 stage-hint) 到安全组规则 ID 的映射
+func (keeper *OVNNorthboundKeeper) secgroupRuleAclHints() map[string]string {
+	ret := map[string]string{}
+	for _, irow := range keeper.DB.ACL.Rows() {
+		sgrId, ok := irow.GetExternalId(externalKeyOcSgrId)
+		if !ok || len(irow.OvsdbUuid()) < 8 {
+			continue
+		}
+		ret[irow.OvsdbUuid()[:8]] = sgrId
+	}
+	return ret
+}
+
+// 将各宿主机按 stage-hint 统计的报文数汇总到安全组规则
+func secgroupRuleHitCounts(hints map[string]string, hostStats []map[string]int64) []apis.SecgroupRuleHitCount {
+	counts := map[string]int64{}
+	for _, stats := range hostStats {
+		for hint, packets := range stats {
+			if sgrId, ok := hints[hint]; ok {
+				counts[sgrId] += packets
+			}
+		}
+	}
+	ret := make([]apis.SecgroupRuleHitCount, 0, len(counts))
+	for id, packets := range counts {
+		ret = append(ret, apis.SecgroupRuleHitCount{Id: id, Packets: packets})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret
+}
+
+func (w *Worker) reportSecgroupRuleHitCounts(ctx context.Context, mss *agentmodels.ModelSets, keeper *OVNNorthboundKeeper) {
+	hints := keeper.secgroupRuleAclHints()
+	if len(hints) == 0 {
+		return
+	}
+	s := auth.GetAdminSession(ctx, w.opts.Region)
+	header := mcclient.GetTokenHeaders(s.GetToken())
+	hostStats := []map[string]int64{}
+	for _, host := range mss.Hosts {
+		if host.OvnVersion == "" || host.ManagerUri == "" {
+			continue
+		}
+		url := fmt.Sprintf("%s/hosts/ovn-acl-stats", host.ManagerUri)
+		_, resp, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "GET", url, header, nil, false)
+		if err != nil {
+			log.Errorf("ovn: get acl stats of host %s(%s): %v", host.Name, host.Id, err)
+			continue
+		}
+		stats := map[string]int64{}
+		if err := resp.Unmarshal(&stats, "stats"); err != nil {
+			log.Errorf("ovn: unmarshal acl stats of host %s(%s): %v", host.Name, host.Id, err)
+			continue
+		}
+		hostStats = append(hostStats, stats)
+	}
+	rules := secgroupRuleHitCounts(hints, hostStats)
+	if len(rules) == 0 {
+		return
+	}
+	input := apis.SecgroupRuleReportHitCountsInput{
+		Source:     apis.VPC_PROVIDER_OVN,
+		Cumulative: true,
+		Rules:      rules,
+	}
+	if _, err := mcclient_modules.SecGroupRules.PerformClassAction(s, "report-hit-counts", jsonutils.Marshal(input)); err != nil {
+		log.Errorf("ovn: report secgroup rule hit counts: %v", err)
+	}
+
+	metrics := secgroupRuleHitMetrics(mss.SecurityGroupRules, rules, time.Now())
+	urls, err := s.GetServiceURLs("influxdb", w.opts.SessionEndpointType)
+	if err != nil {
+		log.Debugf("ovn: no influxdb endpoint for secgroup rule metrics: %v", err)
+		return
+	}
+	if err := influxdb.SendMetrics(urls, "telegraf", metrics, false); err != nil {
+		log.Errorf("ovn: send secgroup rule metrics: %v", err)
+	}
+}
+
+// 安全组规则命中计数指标, 供监控服务展示与告警, packets 为各宿主机流表的累计值
+func secgroupRuleHitMetrics(sgrs agentmodels.SecurityGroupRules, rules []apis.SecgroupRuleHitCount, now time.Time) []influxdb.SMetricData {
+	metrics := make([]influxdb.SMetricData, 0, len(rules))
+	for _, rule := range rules {
+		tags := []influxdb.SKeyValue{
+			{Key: "secgroup_rule_id", Value: rule.Id},
+		}
+		if sgr, ok := sgrs[rule.Id]; ok {
+			tags = append(tags,
+				influxdb.SKeyValue{Key: "secgroup_id", Value: sgr.SecgroupId},
+				influxdb.SKeyValue{Key: "direction", Value: sgr.Direction},
+				influxdb.SKeyValue{Key: "action", Value: sgr.Action},
+			)
+			if sgr.SecurityGroup != nil {
+				tags = append(tags, influxdb.SKeyValue{Key: "secgroup", Value: sgr.SecurityGroup.Name})
+			}
+		}
+		metrics = append(metrics, influxdb.SMetricData{
+			Name:      "vpc_secgroup_rule",
+			Timestamp: now,
+			Tags:      tags,
+			Metrics: []influxdb.SKeyValue{
+				{Key: "packets", Value: strconv.FormatInt(rule.Packets, 10)},
+			},
+		})
+	}
+	return metrics
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestSecgroupRuleHitCounts(t *testing.T) {
+	hints := map[string]string{
+		"9e8d7c6b": "rule-a",
+		"5a4b3c2d": "rule-b",
+		"11112222": "rule-a",
+	}
+	hostStats := []map[string]int64{
+		{"9e8d7c6b": 10, "5a4b3c2d": 3, "deadbeef": 99},
+		{"11112222": 5},
+	}
+	got := secgroupRuleHitCounts(hints, hostStats)
+	want := []apis.SecgroupRuleHitCount{
+		{Id: "rule-a", Packets: 15},
+		{Id: "rule-b", Packets: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestSecgroupRuleHitMetrics(t *testing.T) {
+	sg := &agentmodels.SecurityGroup{}
+	sg.Name = "web"
+	sgr := &agentmodels.SecurityGroupRule{SecurityGroup: sg}
+	sgr.SecgroupId = "sg-a"
+	sgr.Direction = "in"
+	sgr.Action = "allow"
+	sgrs := agentmodels.SecurityGroupRules{"rule-a": sgr}
+
+	now := time.Unix(1600000000, 0)
+	metrics := secgroupRuleHitMetrics(sgrs, []apis.SecgroupRuleHitCount{
+		{Id: "rule-a", Packets: 15},
+		{Id: "rule-gone", Packets: 3},
+	}, now)
+	if len(metrics) != 2 {
+		t.Fatalf("want 2 metrics, got %d", len(metrics))
+	}
+	want := "vpc_secgroup_rule,action=allow,direction=in,secgroup=web,secgroup_id=sg-a,secgroup_rule_id=rule-a packets=15"
+	if got := metrics[0].Line(); len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if n := len(metrics[1].Tags); n != 1 {
+		t.Errorf("rule not in model sets: want 1 tag, got %d", n)
+	}
+}
@@ -36,6 +36,20 @@ const (
 )
 
 func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL, error) {
+	return secgroupRuleToAcl(fmt.Sprintf("%q", lport), "", rule)
+}
+
+// ruleToPgAcl 生成作用于 port group 的 ACL, peerPg 非空时以对端安全组
+// port group 的地址集合作为匹配地址
+func ruleToPgAcl(pg string, peerPg string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL, error) {
+	peerAddrSet := ""
+	if peerPg != "" {
+		peerAddrSet = "$" + peerPg + "_ip4"
+	}
+	return secgroupRuleToAcl("@"+pg, peerAddrSet, rule)
+}
+
+func secgroupRuleToAcl(port string, peerAddrSet string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL, error) {
 	var (
 		dir    string
 		action string
@@ -52,12 +66,12 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		dir = aclDirToLport
 		l3subfn = "src"
 		l4subfn = "dst"
-		matches = append(matches, fmt.Sprintf("outport == %q", lport))
+		matches = append(matches, fmt.Sprintf("outport == %s", port))
 	case secrules.SecurityRuleEgress:
 		dir = aclDirFromLport
 		l3subfn = "dst"
 		l4subfn = "dst"
-		matches = append(matches, fmt.Sprintf("inport == %q", lport))
+		matches = append(matches, fmt.Sprintf("inport == %s", port))
 	default:
 		return nil, errors.Wrapf(errBadSecgroupRule, "unknown direction %q", rule.Direction)
 	}
@@ -73,7 +87,9 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 
 	addL3Match := func() {
 		matches = append(matches, "ip4")
-		if cidr := strings.TrimSpace(rule.CIDR); cidr != "" && cidr != "0.0.0.0/0" {
+		if peerAddrSet != "" {
+			matches = append(matches, fmt.Sprintf("ip4.%s == %s", l3subfn, peerAddrSet))
+		} else if cidr := strings.TrimSpace(rule.CIDR); cidr != "" && cidr != "0.0.0.0/0" {
 			matches = append(matches, fmt.Sprintf("ip4.%s == %s", l3subfn, cidr))
 		}
 	}
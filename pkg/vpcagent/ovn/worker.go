@@ -40,6 +40,11 @@ type Worker struct {
 	opts *options.Options
 
 	apih *apihelper.APIHelper
+
+	nbctl     *ovnutil.OvnNbCtl
+	nbHealthy bool
+
+	aclStatsAt time.Time
 }
 
 func NewWorker(opts *options.Options) worker.IWorker {
@@ -52,14 +57,18 @@ func NewWorker(opts *options.Options) worker.IWorker {
 		IncludeDetails:       false,
 
 		IncludeOtherCloudEnv: false,
+
+		FullSyncIntervalSeconds: opts.APIFullSyncIntervalSeconds,
 	}
 	apih, err := apihelper.NewAPIHelper(apiOpts, modelSets)
 	if err != nil {
 		return nil
 	}
 	w := &Worker{
-		opts: opts,
-		apih: apih,
+		opts:      opts,
+		apih:      apih,
+		nbctl:     ovnutil.NewOvnNbCtl(opts.OvnNorthDatabase),
+		nbHealthy: true,
 	}
 	return w
 }
@@ -78,6 +87,9 @@ func (w *Worker) Start(ctx context.Context, app *appsrv.Application, prefix stri
 	tick := time.NewTimer(tickDuration)
 	defer tick.Stop()
 
+	nbCheck := time.NewTicker(time.Duration(w.opts.OvnNorthDatabaseCheckIntervalSeconds) * time.Second)
+	defer nbCheck.Stop()
+
 	var mss *agentmodels.ModelSets
 	for {
 		select {
@@ -95,12 +107,35 @@ func (w *Worker) Start(ctx context.Context, app *appsrv.Application, prefix stri
 				}
 			}
 			tick.Reset(tickDuration)
+		case <-nbCheck.C:
+			// 北向数据库重启或 leader 切换恢复后立即全量同步一次
+			if w.checkNorthDatabase(ctx) && mss != nil {
+				log.Infof("ovn: north database %s recovered, resync", w.nbctl.Db())
+				if err := w.run(ctx, mss); err != nil {
+					log.Errorf("ovn: %v", err)
+				}
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// checkNorthDatabase 检查北向数据库连接, 返回是否刚从不可用状态恢复
+func (w *Worker) checkNorthDatabase(ctx context.Context) bool {
+	err := w.nbctl.SelectLeader(ctx)
+	if err != nil {
+		if w.nbHealthy {
+			log.Errorf("ovn: north database unavailable: %v", err)
+		}
+		w.nbHealthy = false
+		return false
+	}
+	recovered := !w.nbHealthy
+	w.nbHealthy = true
+	return recovered
+}
+
 func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error) {
 	defer func() {
 		if panicVal := recover(); panicVal != nil {
@@ -114,8 +149,11 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 		}
 	}()
 
-	ovnnbctl := ovnutil.NewOvnNbCtl(w.opts.OvnNorthDatabase)
-	ovndb, err := DumpOVNNorthbound(ctx, ovnnbctl)
+	if err := w.nbctl.SelectLeader(ctx); err != nil {
+		w.nbHealthy = false
+		return err
+	}
+	ovndb, err := DumpOVNNorthbound(ctx, w.nbctl)
 	if err != nil {
 		return err
 	}
@@ -167,7 +205,7 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 
 					ovndb.ClaimVpcHost(ctx, vpc, host)
 				}
-				ovndb.ClaimGuestnetwork(ctx, guestnetwork)
+				ovndb.ClaimGuestnetwork(ctx, guestnetwork, w.opts)
 			}
 			for _, groupnetwork := range network.Groupnetworks {
 				ovndb.ClaimGroupnetwork(ctx, groupnetwork)
@@ -186,6 +224,14 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 		ovndb.ClaimVpcGuestDnsRecords(ctx, vpc)
 	}
 	ovndb.ClaimDnsRecords(ctx, mss.Vpcs, mss.DnsRecords)
+	if w.opts.OvnSecgroupPortGroup {
+		ovndb.ClaimSecgroupPortGroups(ctx, mss.Vpcs)
+	}
 	ovndb.Sweep(ctx)
+
+	if w.opts.OvnAclStatsIntervalSeconds > 0 && time.Since(w.aclStatsAt) >= time.Duration(w.opts.OvnAclStatsIntervalSeconds)*time.Second {
+		w.aclStatsAt = time.Now()
+		w.reportSecgroupRuleHitCounts(ctx, mss, ovndb)
+	}
 	return nil
 }
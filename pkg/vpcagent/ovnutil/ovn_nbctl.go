@@ -20,6 +20,7 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/log"
@@ -39,22 +40,90 @@ func (res *CmdResult) Error() string {
 	return fmt.Sprintf("err: %v, output: %s", res.Err, res.Output)
 }
 
+// OvnNbCtl 封装 ovn-nbctl 命令
+//
+// db 可以是逗号分隔的多个北向数据库地址(RAFT 集群), 命令只发往当前选定的 leader,
+// 连接失败时重新探测 leader 并重试一次
 type OvnNbCtl struct {
-	db string
+	dbs []string
+
+	lock sync.Mutex
+	db   string
 }
 
 func NewOvnNbCtl(db string) *OvnNbCtl {
 	cli := &OvnNbCtl{
-		db: db,
+		dbs: SplitDbs(db),
+	}
+	if len(cli.dbs) > 0 {
+		cli.db = cli.dbs[0]
 	}
 	return cli
 }
 
+// SplitDbs 拆分逗号分隔的数据库地址列表
+func SplitDbs(db string) []string {
+	var r []string
+	for _, s := range strings.Split(db, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+// Db 返回当前使用的北向数据库地址
+func (cli *OvnNbCtl) Db() string {
+	cli.lock.Lock()
+	defer cli.lock.Unlock()
+	return cli.db
+}
+
+// SelectLeader 探测各数据库节点, 选定已连入集群的 leader
+func (cli *OvnNbCtl) SelectLeader(ctx context.Context) error {
+	if len(cli.dbs) <= 1 {
+		// 单节点或本地 unix socket, 由 ovn-nbctl 自行连接
+		return nil
+	}
+	cur := cli.Db()
+	start := 0
+	for i, db := range cli.dbs {
+		if db == cur {
+			start = i
+			break
+		}
+	}
+	var errs []error
+	for i := range cli.dbs {
+		db := cli.dbs[(start+i)%len(cli.dbs)]
+		status, err := ProbeNbServer(ctx, db)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !status.IsUsable() {
+			continue
+		}
+		if db != cur {
+			log.Infof("ovn north database switched %s -> %s", cur, db)
+			cli.lock.Lock()
+			cli.db = db
+			cli.lock.Unlock()
+		}
+		return nil
+	}
+	if len(errs) > 0 {
+		return errors.Wrapf(errors.NewAggregate(errs), "no leader found in %s", strings.Join(cli.dbs, ","))
+	}
+	return errors.Errorf("no leader found in %s", strings.Join(cli.dbs, ","))
+}
+
 func (cli *OvnNbCtl) prepArgs(args []string) []string {
 	var r []string
-	if cli.db != "" {
+	if db := cli.Db(); db != "" {
 		r = make([]string, len(args)+1)
-		r[0] = "--db=" + cli.db
+		r[0] = "--db=" + db
 		copy(r[1:], args)
 	} else {
 		r = args
@@ -63,6 +132,19 @@ func (cli *OvnNbCtl) prepArgs(args []string) []string {
 }
 
 func (cli *OvnNbCtl) run(ctx context.Context, args []string) *CmdResult {
+	res := cli.run1(ctx, args)
+	if res.Err != nil && len(cli.dbs) > 1 && isConnectionFailure(res.Output) {
+		// 连接失败时事务未提交, 重新选定 leader 后可安全重试
+		if err := cli.SelectLeader(ctx); err != nil {
+			log.Errorf("reselect ovn north database leader: %v", err)
+			return res
+		}
+		res = cli.run1(ctx, args)
+	}
+	return res
+}
+
+func (cli *OvnNbCtl) run1(ctx context.Context, args []string) *CmdResult {
 	ctx, cancel := context.WithTimeout(ctx, ovnNbCtlTimeout)
 	defer cancel()
 
@@ -76,6 +158,20 @@ func (cli *OvnNbCtl) run(ctx context.Context, args []string) *CmdResult {
 	return res
 }
 
+func isConnectionFailure(output string) bool {
+	for _, s := range []string{
+		"database connection failed",
+		"Connection refused",
+		"Connection reset",
+		"not leader",
+	} {
+		if strings.Contains(output, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func (cli *OvnNbCtl) Must(ctx context.Context, msg string, args []string) *CmdResult {
 	res := cli.run(ctx, args)
 	if res.Err != nil {
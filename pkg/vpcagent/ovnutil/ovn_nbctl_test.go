@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovnutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitDbs(t *testing.T) {
+	got := SplitDbs(" tcp:10.0.0.1:6641, tcp:10.0.0.2:6641,,tcp:10.0.0.3:6641 ")
+	want := []string{"tcp:10.0.0.1:6641", "tcp:10.0.0.2:6641", "tcp:10.0.0.3:6641"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+	if got := SplitDbs(""); len(got) != 0 {
+		t.Errorf("want empty got %v", got)
+	}
+}
+
+func TestParseDbServerStatus(t *testing.T) {
+	cases := []struct {
+		output string
+		usable bool
+	}{
+		{`[{"rows":[{"model":"clustered","connected":true,"leader":true}]}]`, true},
+		{`[{"rows":[{"model":"clustered","connected":true,"leader":false}]}]`, false},
+		{`[{"rows":[{"model":"clustered","connected":false,"leader":true}]}]`, false},
+		{`[{"rows":[{"model":"standalone","connected":true,"leader":true}]}]`, true},
+	}
+	for _, c := range cases {
+		st, err := parseDbServerStatus([]byte(c.output))
+		if err != nil {
+			t.Fatalf("%s: %v", c.output, err)
+		}
+		if st.IsUsable() != c.usable {
+			t.Errorf("%s: want usable %v", c.output, c.usable)
+		}
+	}
+	if _, err := parseDbServerStatus([]byte(`[{"rows":[]}]`)); err == nil {
+		t.Errorf("expect error for empty rows")
+	}
+}
+
+func TestIsConnectionFailure(t *testing.T) {
+	if !isConnectionFailure("ovn-nbctl: tcp:10.0.0.1:6641: database connection failed (Connection refused)") {
+		t.Errorf("expect connection failure")
+	}
+	if isConnectionFailure("ovn-nbctl: no row \"foo\" in table Logical_Switch") {
+		t.Errorf("expect not connection failure")
+	}
+}
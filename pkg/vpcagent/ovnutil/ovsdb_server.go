@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovnutil
+
+import (
+	"context"
+	"os/exec"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+const nbDatabaseName = "OVN_Northbound"
+
+// SDbServerStatus 对应 ovsdb-server _Server 数据库 Database 表中的一行
+type SDbServerStatus struct {
+	Model     string `json:"model"`
+	Connected bool   `json:"connected"`
+	Leader    bool   `json:"leader"`
+}
+
+// IsUsable 单机模式总是可用, 集群模式须已连入集群且为 leader
+func (st *SDbServerStatus) IsUsable() bool {
+	if st.Model != "clustered" {
+		return true
+	}
+	return st.Connected && st.Leader
+}
+
+// ProbeNbServer 查询数据库节点上北向数据库的集群状态
+func ProbeNbServer(ctx context.Context, db string) (*SDbServerStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, ovnNbCtlTimeout)
+	defer cancel()
+
+	query := jsonutils.NewArray(
+		jsonutils.NewString("_Server"),
+		jsonutils.Marshal(map[string]interface{}{
+			"op":      "select",
+			"table":   "Database",
+			"where":   [][]string{{"name", "==", nbDatabaseName}},
+			"columns": []string{"model", "connected", "leader"},
+		}),
+	)
+	cmd := exec.CommandContext(ctx, "ovsdb-client", "transact", db, query.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "probe %s: %s", db, output)
+	}
+	st, err := parseDbServerStatus(output)
+	if err != nil {
+		return nil, errors.Wrapf(err, "probe %s", db)
+	}
+	return st, nil
+}
+
+func parseDbServerStatus(output []byte) (*SDbServerStatus, error) {
+	obj, err := jsonutils.Parse(output)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", output)
+	}
+	results, err := obj.GetArray()
+	if err != nil || len(results) == 0 {
+		return nil, errors.Errorf("unexpected transact result %s", output)
+	}
+	rows, err := results[0].GetArray("rows")
+	if err != nil {
+		return nil, errors.Wrapf(err, "rows of %s", output)
+	}
+	if len(rows) == 0 {
+		return nil, errors.Wrapf(errors.ErrNotFound, "database %s", nbDatabaseName)
+	}
+	st := &SDbServerStatus{}
+	if err := rows[0].Unmarshal(st); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal %s", rows[0])
+	}
+	return st, nil
+}
@@ -17,24 +17,36 @@ package command
 import (
 	"fmt"
 	"os/exec"
+	"strings"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/webconsole/models"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
+	"yunion.io/x/onecloud/pkg/webconsole/recorder"
 )
 
 type IpmiInfo struct {
-	IpAddr   string `json:"ip_addr"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Present  bool   `json:"present"`
+	IpAddr     string `json:"ip_addr"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Present    bool   `json:"present"`
+	RedfishApi bool   `json:"redfish_api"`
 }
 
 type IpmitoolSol struct {
 	*BaseCommand
 	Info *IpmiInfo
 	s    *mcclient.ClientSession
+
+	Method string
+	object *recorder.Object
 }
 
 func NewIpmitoolSolCommand(info *IpmiInfo, s *mcclient.ClientSession) (*IpmitoolSol, error) {
@@ -76,3 +88,104 @@ func (c *IpmitoolSol) GetCommand() *exec.Cmd {
 func (c IpmitoolSol) GetProtocol() string {
 	return PROTOCOL_TTY
 }
+
+// SetRecordObject 设置会话审计关联的对象, 串口会话记录为 sol 类型
+func (c *IpmitoolSol) SetRecordObject(id, name, objType string, notes map[string]interface{}) {
+	if notes == nil {
+		notes = map[string]interface{}{}
+	}
+	notes["bmc_ip"] = c.Info.IpAddr
+	notes["method"] = c.Method
+	c.object = recorder.NewObject(id, name, objType, c.Info.Username, jsonutils.Marshal(notes))
+	c.object.CommandType = models.CommandTypeSOL
+}
+
+func (c *IpmitoolSol) GetRecordObject() *recorder.Object {
+	return c.object
+}
+
+// BMC ssh 登录后连接主机串口的命令, 对应 redfish Manager.SerialConsole 的 SSH 连接方式
+var redfishSshSolCommands = map[string]string{
+	"dell":   "console com2",
+	"hpe":    "vsp",
+	"hp":     "vsp",
+	"lenovo": "console 1",
+}
+
+func getRedfishSshSolCommand(manufacture string) string {
+	manufacture = strings.ToLower(manufacture)
+	for _, vendor := range []string{"dell", "hpe", "hp", "lenovo"} {
+		if strings.Contains(manufacture, vendor) {
+			return redfishSshSolCommands[vendor]
+		}
+	}
+	return ""
+}
+
+// 通过 ssh 登录 BMC 并连接串口, 适用于关闭了 IPMI over LAN 仅开放 redfish 的 BMC
+func NewRedfishSolCommand(info *IpmiInfo, manufacture string, s *mcclient.ClientSession) (*IpmitoolSol, error) {
+	if info.IpAddr == "" {
+		return nil, fmt.Errorf("Empty host ip address")
+	}
+	if !info.RedfishApi {
+		return nil, httperrors.NewUnsupportOperationError("BMC %s not support redfish", info.IpAddr)
+	}
+	solCmd := getRedfishSshSolCommand(manufacture)
+	if solCmd == "" {
+		return nil, httperrors.NewUnsupportOperationError("redfish serial console not supported for manufacture %q", manufacture)
+	}
+	cmd := NewBaseCommand(s, o.Options.SshpassToolPath,
+		"-p", info.Password,
+		o.Options.SshToolPath, "-tt",
+		"-oGlobalKnownHostsFile=/dev/null",
+		"-oUserKnownHostsFile=/dev/null",
+		"-oStrictHostKeyChecking=no",
+		"-oPubkeyAuthentication=no",
+		"-oNumberOfPasswordPrompts=1",
+		fmt.Sprintf("%s@%s", info.Username, info.IpAddr),
+		solCmd,
+	)
+	return &IpmitoolSol{
+		BaseCommand: cmd,
+		Info:        info,
+	}, nil
+}
+
+// NewBaremetalSolCommand 按 method 连接物理机串口控制台, 未指定时优先使用 ipmitool
+func NewBaremetalSolCommand(s *mcclient.ClientSession, hostId string, method string) (*IpmitoolSol, error) {
+	ret, err := modules.Hosts.GetSpecific(s, hostId, "ipmi", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get host ipmi info")
+	}
+	info := IpmiInfo{}
+	if err := ret.Unmarshal(&info); err != nil {
+		return nil, errors.Wrap(err, "unmarshal ipmi info")
+	}
+	host, err := modules.Hosts.Get(s, hostId, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "get host")
+	}
+	hostName, _ := host.GetString("name")
+	if len(method) == 0 {
+		method = api.BAREMETAL_SOL_METHOD_IPMITOOL
+		if !info.Present && info.RedfishApi {
+			method = api.BAREMETAL_SOL_METHOD_REDFISH
+		}
+	}
+	var cmd *IpmitoolSol
+	switch method {
+	case api.BAREMETAL_SOL_METHOD_IPMITOOL:
+		cmd, err = NewIpmitoolSolCommand(&info, s)
+	case api.BAREMETAL_SOL_METHOD_REDFISH:
+		manufacture, _ := host.GetString("sys_info", "manufacture")
+		cmd, err = NewRedfishSolCommand(&info, manufacture, s)
+	default:
+		return nil, httperrors.NewInputParameterError("invalid sol method %q", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cmd.Method = method
+	cmd.SetRecordObject(hostId, hostName, "host", nil)
+	return cmd, nil
+}
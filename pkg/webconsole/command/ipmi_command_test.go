@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+func TestGetRedfishSshSolCommand(t *testing.T) {
+	cases := map[string]string{
+		"Dell Inc.":  "console com2",
+		"HPE":        "vsp",
+		"HP":         "vsp",
+		"Lenovo":     "console 1",
+		"Inspur":     "",
+		"":           "",
+		"Supermicro": "",
+	}
+	for manufacture, want := range cases {
+		if got := getRedfishSshSolCommand(manufacture); got != want {
+			t.Errorf("manufacture %q want %q got %q", manufacture, want, got)
+		}
+	}
+}
+
+func TestNewRedfishSolCommand(t *testing.T) {
+	o.Options.SshpassToolPath = "/usr/bin/sshpass"
+	o.Options.SshToolPath = "/usr/bin/ssh"
+	info := &IpmiInfo{IpAddr: "10.0.0.2", Username: "root", Password: "passwd"}
+	if _, err := NewRedfishSolCommand(info, "Dell Inc.", nil); err == nil {
+		t.Errorf("redfish not supported BMC should fail")
+	}
+	info.RedfishApi = true
+	if _, err := NewRedfishSolCommand(info, "Inspur", nil); err == nil {
+		t.Errorf("unknown manufacture should fail")
+	}
+	cmd, err := NewRedfishSolCommand(info, "Dell Inc.", nil)
+	if err != nil {
+		t.Fatalf("NewRedfishSolCommand: %v", err)
+	}
+	args := strings.Join(cmd.GetCommand().Args, " ")
+	if !strings.HasSuffix(args, "root@10.0.0.2 console com2") {
+		t.Errorf("unexpected command %s", args)
+	}
+}
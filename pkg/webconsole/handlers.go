@@ -33,7 +33,6 @@ import (
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
-	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/k8s"
 	"yunion.io/x/onecloud/pkg/webconsole/command"
 	"yunion.io/x/onecloud/pkg/webconsole/models"
@@ -189,18 +188,11 @@ func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return
 	}
 	hostId := env.Params["<id>"]
-	ret, err := modules.Hosts.GetSpecific(env.ClientSessin, hostId, "ipmi", nil)
-	if err != nil {
-		httperrors.GeneralServerError(ctx, w, err)
-		return
-	}
-	info := command.IpmiInfo{}
-	err = ret.Unmarshal(&info)
-	if err != nil {
-		httperrors.GeneralServerError(ctx, w, err)
-		return
+	method := ""
+	if env.Body != nil {
+		method, _ = env.Body.GetString("webconsole", "method")
 	}
-	cmd, err := command.NewIpmitoolSolCommand(&info, env.ClientSessin)
+	cmd, err := command.NewBaremetalSolCommand(env.ClientSessin, hostId, method)
 	if err != nil {
 		httperrors.GeneralServerError(ctx, w, err)
 		return
@@ -231,12 +223,29 @@ func handleServerRemoteConsole(ctx context.Context, w http.ResponseWriter, r *ht
 		session.JDCLOUD, session.CLOUDPODS:
 		responsePublicCloudConsole(ctx, info, w)
 	case session.VNC, session.SPICE, session.WMKS:
-		handleDataSession(ctx, info, w, url.Values{"password": {info.GetPassword()}}, true)
+		handleDataSession(ctx, info, w, info.GetDataSessionParams(), true)
+	case session.SOL:
+		handleServerSolConsole(ctx, info, query, w)
 	default:
 		httperrors.NotAcceptableError(ctx, w, "Unspported remote console protocol: %s", info.Protocol)
 	}
 }
 
+// 物理机服务器通过所在宿主机 BMC 的串口控制台访问, 审计记录关联到服务器
+func handleServerSolConsole(ctx context.Context, info *session.RemoteConsoleInfo, query jsonutils.JSONObject, w http.ResponseWriter) {
+	method := ""
+	if query != nil {
+		method, _ = query.GetString("method")
+	}
+	cmd, err := command.NewBaremetalSolCommand(info.GetClientSession(), info.HostId, method)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	cmd.SetRecordObject(info.Id, info.InstanceName, "server", map[string]interface{}{"host_id": info.HostId})
+	handleCommandSession(ctx, cmd, w)
+}
+
 func responsePublicCloudConsole(ctx context.Context, info *session.RemoteConsoleInfo, w http.ResponseWriter) {
 	params, err := info.GetConnectParams()
 	if err != nil {
@@ -34,6 +34,7 @@ type CommandType string
 
 const (
 	CommandTypeSSH = "ssh"
+	CommandTypeSOL = "sol"
 )
 
 func InitCommandLog() {
@@ -40,6 +40,9 @@ type Object struct {
 	Type      string
 	LoginUser string
 	Notes     jsonutils.JSONObject
+
+	// 审计记录的会话类型, 为空时为 ssh
+	CommandType models.CommandType
 }
 
 func NewObject(id, name, oType, loginUser string, notes jsonutils.JSONObject) *Object {
@@ -166,6 +169,13 @@ func (r *cmdRecoder) save(command string) error {
 	return nil
 }
 
+func (r *cmdRecoder) getCommandType() models.CommandType {
+	if len(r.object.CommandType) > 0 {
+		return r.object.CommandType
+	}
+	return models.CommandTypeSSH
+}
+
 func (r *cmdRecoder) newModelInput(userCred mcclient.TokenCredential, command string) *models.CommandLogCreateInput {
 	return &models.CommandLogCreateInput{
 		ObjId:           r.object.Id,
@@ -185,7 +195,7 @@ func (r *cmdRecoder) newModelInput(userCred mcclient.TokenCredential, command st
 		SessionId:       r.sessionId,
 		AccessedAt:      r.accessedAt,
 		LoginUser:       r.object.LoginUser,
-		Type:            models.CommandTypeSSH,
+		Type:            r.getCommandType(),
 		StartTime:       time.Now(),
 		Ps1:             r.ps1,
 		Command:         command,
@@ -18,10 +18,12 @@ import (
 	"fmt"
 	"net/url"
 	"os/exec"
+	"strconv"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
@@ -44,11 +46,16 @@ const (
 	APSARA    = api.APSARA
 	JDCLOUD   = api.JDCLOUD
 	CLOUDPODS = api.CLOUDPODS
+	SOL       = api.SOL
 )
 
 type RemoteConsoleInfo struct {
 	cloudprovider.ServerVncOutput
 
+	// spice usb 重定向通道数及显示器数量
+	SpiceUsbredirChannels string `json:"spice_usbredir_channels"`
+	VdiMonitors           string `json:"vdi_monitors"`
+
 	s *mcclient.ClientSession
 }
 
@@ -64,15 +71,21 @@ func NewRemoteConsoleInfoByCloud(s *mcclient.ClientSession, serverId string, que
 	}
 	vncInfo.s = s
 
-	if len(vncInfo.OsName) == 0 || len(vncInfo.VncPassword) == 0 {
+	if len(vncInfo.OsName) == 0 || len(vncInfo.VncPassword) == 0 || vncInfo.Protocol == SPICE {
 		metadata, err := modules.Servers.GetSpecific(s, serverId, "metadata", nil)
 		if err != nil {
 			return nil, err
 		}
-		osName, _ := metadata.GetString("os_name")
-		vncPasswd, _ := metadata.GetString("__vnc_password")
-		vncInfo.OsName = osName
-		vncInfo.VncPassword = vncPasswd
+		if len(vncInfo.OsName) == 0 {
+			vncInfo.OsName, _ = metadata.GetString("os_name")
+		}
+		if len(vncInfo.VncPassword) == 0 {
+			vncInfo.VncPassword, _ = metadata.GetString("__vnc_password")
+		}
+		if vncInfo.Protocol == SPICE {
+			vncInfo.SpiceUsbredirChannels, _ = metadata.GetString(computeapi.VM_METADATA_SPICE_USBREDIR_CHANNELS)
+			vncInfo.VdiMonitors, _ = metadata.GetString(computeapi.VM_METADATA_VDI_MONITORS)
+		}
 	}
 
 	return &vncInfo, nil
@@ -122,6 +135,23 @@ func (info *RemoteConsoleInfo) GetClientSession() *mcclient.ClientSession {
 	return info.s
 }
 
+// GetDataSessionParams 返回连接参数, spice 额外告知客户端可用的 usb 重定向通道数及显示器数量
+func (info *RemoteConsoleInfo) GetDataSessionParams() url.Values {
+	params := url.Values{"password": {info.GetPassword()}}
+	if info.Protocol != SPICE {
+		return params
+	}
+	channels := info.SpiceUsbredirChannels
+	if len(channels) == 0 {
+		channels = strconv.Itoa(computeapi.VM_SPICE_USBREDIR_DEFAULT_CHANNELS)
+	}
+	params.Set("usbredir_channels", channels)
+	if len(info.VdiMonitors) > 0 {
+		params.Set("monitors", info.VdiMonitors)
+	}
+	return params
+}
+
 func (info *RemoteConsoleInfo) GetConnectParams() (string, error) {
 	switch info.Protocol {
 	case ALIYUN: